@@ -0,0 +1,30 @@
+//go:build !windows
+
+package collector
+
+import (
+	"os"
+	"syscall"
+)
+
+// openLogFile 打开日志文件读取
+func openLogFile(path string) (*os.File, error) {
+	return os.Open(path)
+}
+
+// statLogFile 获取日志文件信息和 inode
+func statLogFile(path string) (os.FileInfo, uint64, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return stat, fileInode(nil, stat), nil
+}
+
+// fileInode 获取文件 inode，用于识别日志轮转
+func fileInode(_ *os.File, stat os.FileInfo) uint64 {
+	if st, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package collector
+
+import (
+	"os"
+	"syscall"
+)
+
+// openLogFile 打开日志文件读取。Windows 上 os.Open 不允许其他进程删除或重命名已打开的文件，
+// 共享删除权限后 SmartDNS 轮转日志时不会因 Agent 正在读取而失败
+func openLogFile(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	handle, err := syscall.CreateFile(name,
+		syscall.GENERIC_READ,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil,
+		syscall.OPEN_EXISTING,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(handle), path), nil
+}
+
+// statLogFile 获取日志文件信息和文件索引号
+func statLogFile(path string) (os.FileInfo, uint64, error) {
+	file, err := openLogFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	return stat, fileInode(file, stat), nil
+}
+
+// fileInode Windows 没有 inode，使用 NTFS 文件索引号识别日志轮转
+func fileInode(file *os.File, _ os.FileInfo) uint64 {
+	if file == nil {
+		return 0
+	}
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(file.Fd()), &info); err != nil {
+		return 0
+	}
+	return uint64(info.FileIndexHigh)<<32 | uint64(info.FileIndexLow)
+}
@@ -2,18 +2,25 @@ package collector
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"smartdns-log-agent/config"
+	"smartdns-log-agent/enricher"
+	"smartdns-log-agent/filter"
 	"smartdns-log-agent/models"
+	"smartdns-log-agent/privacy"
 	"smartdns-log-agent/sender"
 	"smartdns-log-agent/utils"
 )
@@ -25,14 +32,68 @@ type PositionInfo struct {
 	LastModTime  time.Time `json:"last_mod_time"`
 	FileSize     int64     `json:"file_size"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	// 批次序号
+	FileInode uint64 `json:"file_inode"`
+	StreamID  string `json:"stream_id"`
+	NextSeq   uint64 `json:"next_seq"`
+}
+
+// RereadRequest 补读指定文件区间的请求
+type RereadRequest struct {
+	StreamID    string `json:"stream_id" binding:"required"`
+	Seq         uint64 `json:"seq"` // 补读批次使用的序号（缺失区间的第一个序号）
+	StartOffset int64  `json:"start_offset"`
+	EndOffset   int64  `json:"end_offset"`
+}
+
+// pendingBatch 已分配序号、等待写入的批次
+type pendingBatch struct {
+	records []models.DNSLogRecord
+	meta    models.IngestBatch
+}
+
+// QueueStats 发送队列统计，用于判断写入是否跟得上日志产生速度
+type QueueStats struct {
+	QueueDepth        int     `json:"queue_depth"`        // 等待写入的批次数
+	QueueCapacity     int     `json:"queue_capacity"`     // 队列容量，等于最大并发写入批次数
+	InflightBatches   int64   `json:"inflight_batches"`   // 正在写入的批次数
+	QueuedRows        int64   `json:"queued_rows"`        // 队列中和正在写入的日志条数
+	BackpressureWaits int64   `json:"backpressure_waits"` // 队列已满、暂停读取日志的次数
+	BackpressureMs    float64 `json:"backpressure_ms"`    // 暂停读取的累计时长（毫秒）
 }
 
 type LogCollector struct {
-	cfg      *config.Config
-	sender   *sender.ClickHouseSender
-	parser   *utils.LogParser
-	buffer   []models.DNSLogRecord
-	lastSize int64
+	cfg        *config.Config
+	sender     sender.LogSender
+	parser     *utils.LogParser
+	enricher   *enricher.Enricher
+	anonymizer *privacy.Anonymizer
+	filter     *filter.Filter
+	buffer     []models.DNSLogRecord
+	lastSize   int64
+
+	// 发送队列：刷新时切出的批次按序号入队，由 MaxInflightBatches 个协程并发写入。
+	// 队列满时读取协程阻塞、暂停读取日志文件，内存中最多保留 2×MaxInflightBatches+1 个批次
+	flushMu     sync.Mutex // 保证批次按序号顺序入队
+	queue       chan pendingBatch
+	queueClosed bool
+	senders     sync.WaitGroup
+	freeBuffers chan []models.DNSLogRecord // 写入成功后回收的缓冲区，避免每个批次重新分配
+	retryRows   int                        // 写入失败、等待原样重发的日志条数
+
+	inflightBatches   int64
+	queuedRows        int64
+	backpressureWaits int64
+	backpressureNanos int64
+
+	// 批次序号：同一文件内每个批次递增，并记录批次覆盖的文件偏移区间，
+	// 管理端据此检测缺失或乱序的批次
+	fileInode  uint64
+	streamID   string
+	nextSeq    uint64
+	batchStart int64 // 尚未分配批次的起始偏移
+	readOffset int64 // 已处理行的结束偏移
 
 	// 统计字段
 	processedLines int64
@@ -47,25 +108,47 @@ type LogCollector struct {
 	lastSavedPosition int64     // 上次保存的位置
 	positionDirty     bool      // 位置是否需要保存
 	lastPositionSave  time.Time // 上次保存位置的时间
+	saveMu            sync.Mutex
 }
 
-func NewLogCollector(cfg *config.Config, sender *sender.ClickHouseSender) (*LogCollector, error) {
+// DataDir Agent 的状态目录，保存读取位置和过滤规则，无法创建时使用系统临时目录
+func DataDir() string {
+	dir := config.DefaultDataDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("⚠️ 创建位置文件目录失败: %v", err)
+		return os.TempDir()
+	}
+	return dir
+}
+
+func NewLogCollector(cfg *config.Config, sender sender.LogSender, logFilter *filter.Filter) (*LogCollector, error) {
 	parser := utils.NewLogParser()
 
 	// 创建位置文件路径
-	positionDir := "/var/lib/smartdns-agent"
-	if err := os.MkdirAll(positionDir, 0755); err != nil {
-		log.Printf("⚠️ 创建位置文件目录失败: %v", err)
-		positionDir = "/tmp"
+	positionFile := filepath.Join(DataDir(), fmt.Sprintf("position-node-%d.json", cfg.NodeID))
+
+	// 客户端 IP 脱敏后不再反查 PTR，PTR 记录同样可以识别客户端
+	anonymizer := privacy.New(cfg.Privacy)
+	enrichment := cfg.Enrichment
+	if anonymizer.Enabled() {
+		enrichment.EnablePTR = false
 	}
 
-	positionFile := filepath.Join(positionDir, fmt.Sprintf("position-node-%d.json", cfg.NodeID))
+	inflight := cfg.MaxInflightBatches
+	if inflight < 1 {
+		inflight = 1
+	}
 
 	collector := &LogCollector{
 		cfg:          cfg,
 		sender:       sender,
 		parser:       parser,
+		enricher:     enricher.NewEnricher(enrichment),
+		anonymizer:   anonymizer,
+		filter:       logFilter,
 		buffer:       make([]models.DNSLogRecord, 0, cfg.BatchSize),
+		queue:        make(chan pendingBatch, inflight),
+		freeBuffers:  make(chan []models.DNSLogRecord, 2*inflight),
 		positionFile: positionFile,
 	}
 
@@ -81,8 +164,8 @@ func (c *LogCollector) loadPosition() {
 	if err != nil {
 		log.Printf("📍 位置文件不存在或读取失败，从文件末尾开始: %v", err)
 		// 设置从文件末尾开始读取
-		if stat, err := os.Stat(c.cfg.LogFile); err == nil {
-			c.lastSize = stat.Size()
+		if stat, inode, err := statLogFile(c.cfg.LogFile); err == nil {
+			c.resetStream(inode, stat.Size())
 			log.Printf("📍 从文件末尾开始读取，位置: %d", c.lastSize)
 		}
 		return
@@ -95,7 +178,7 @@ func (c *LogCollector) loadPosition() {
 	}
 
 	// 检查文件是否变化
-	stat, err := os.Stat(c.cfg.LogFile)
+	stat, inode, err := statLogFile(c.cfg.LogFile)
 	if err != nil {
 		log.Printf("⚠️ 检查日志文件失败: %v", err)
 		return
@@ -104,44 +187,71 @@ func (c *LogCollector) loadPosition() {
 	// 如果文件路径不匹配，重新开始
 	if pos.FilePath != c.cfg.LogFile {
 		log.Printf("📍 日志文件路径变化，重新开始: %s -> %s", pos.FilePath, c.cfg.LogFile)
-		c.lastSize = stat.Size() // 从末尾开始
+		c.resetStream(inode, stat.Size()) // 从末尾开始
 		return
 	}
 
-	// 如果文件被重新创建（修改时间更新且大小变小）
-	if stat.ModTime().After(pos.LastModTime) && stat.Size() < pos.LastPosition {
+	// 如果文件被重新创建（inode 变化，或修改时间更新且大小变小）
+	if (pos.FileInode != 0 && pos.FileInode != inode) ||
+		(stat.ModTime().After(pos.LastModTime) && stat.Size() < pos.LastPosition) {
 		log.Printf("📍 检测到日志文件重新创建，从头开始")
-		c.lastSize = 0
+		c.resetStream(inode, 0)
 		return
 	}
 
 	// 如果文件大小小于记录的位置，说明文件被截断
 	if stat.Size() < pos.LastPosition {
 		log.Printf("📍 文件被截断，从头开始: 当前大小=%d, 记录位置=%d", stat.Size(), pos.LastPosition)
-		c.lastSize = 0
+		c.resetStream(inode, 0)
 		return
 	}
 
-	// 恢复位置
-	c.lastSize = pos.LastPosition
+	// 恢复位置，同一文件继续使用原来的批次序号
+	c.resetStream(inode, pos.LastPosition)
+	if pos.StreamID != "" && pos.NextSeq > 0 {
+		c.streamID = pos.StreamID
+		c.nextSeq = pos.NextSeq
+	}
 	c.positionInfo = &pos
-	log.Printf("📍 恢复读取位置: %d (文件: %s)", c.lastSize, c.cfg.LogFile)
+	log.Printf("📍 恢复读取位置: %d (文件: %s, 批次序号: %d)", c.lastSize, c.cfg.LogFile, c.nextSeq)
+}
+
+// resetStream 开始一个新的批次序列（首次读取、文件轮转或截断时）
+func (c *LogCollector) resetStream(inode uint64, offset int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fileInode = inode
+	c.streamID = fmt.Sprintf("%d-%d", inode, time.Now().Unix())
+	c.nextSeq = 1
+	c.batchStart = offset
+	c.readOffset = offset
+	c.lastSize = offset
 }
 
 // savePosition 保存位置信息
 func (c *LogCollector) savePosition() {
+	// 多个写入协程可能同时保存
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
 	stat, err := os.Stat(c.cfg.LogFile)
 	if err != nil {
 		return
 	}
 
+	c.mu.RLock()
 	pos := PositionInfo{
 		FilePath:     c.cfg.LogFile,
 		LastPosition: c.lastSize,
 		LastModTime:  stat.ModTime(),
 		FileSize:     stat.Size(),
 		UpdatedAt:    time.Now(),
+		FileInode:    c.fileInode,
+		StreamID:     c.streamID,
+		NextSeq:      c.nextSeq,
 	}
+	c.mu.RUnlock()
 
 	data, err := json.Marshal(pos)
 	if err != nil {
@@ -165,6 +275,17 @@ func (c *LogCollector) Start(ctx context.Context) {
 	positionTicker := time.NewTicker(30 * time.Second) // 每10秒保存一次位置
 	defer positionTicker.Stop()
 
+	// 启动写入协程
+	for i := 0; i < cap(c.queue); i++ {
+		c.senders.Add(1)
+		go c.sendLoop(ctx)
+	}
+
+	// 定期刷新域名分类
+	c.refreshDomainCategories(ctx)
+	categoryTicker := time.NewTicker(5 * time.Minute)
+	defer categoryTicker.Stop()
+
 	go func() {
 		for {
 			select {
@@ -175,6 +296,8 @@ func (c *LogCollector) Start(ctx context.Context) {
 				c.flushBuffer()
 			case <-positionTicker.C:
 				c.savePositionIfNeeded()
+			case <-categoryTicker.C:
+				c.refreshDomainCategories(ctx)
 			}
 		}
 	}()
@@ -184,7 +307,10 @@ func (c *LogCollector) Start(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			c.flushBuffer()
+			c.closeQueue()
+			c.senders.Wait() // 等待已入队的批次写入完成
 			c.savePosition() // 退出前保存位置
+			c.enricher.Close()
 			return
 		default:
 			if err := c.readNewLines(ctx); err != nil {
@@ -203,8 +329,25 @@ func (c *LogCollector) Start(ctx context.Context) {
 	}
 }
 
+// refreshDomainCategories 从 ClickHouse 加载域名分类
+func (c *LogCollector) refreshDomainCategories(ctx context.Context) {
+	loadCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	categories, err := c.sender.LoadDomainCategories(loadCtx)
+	if err != nil {
+		log.Printf("⚠️ 加载域名分类失败: %v", err)
+		return
+	}
+
+	c.enricher.SetDomainCategories(categories)
+	if len(categories) > 0 {
+		log.Printf("🏷️ 已加载 %d 条域名分类", len(categories))
+	}
+}
+
 func (c *LogCollector) readNewLines(ctx context.Context) error {
-	file, err := os.Open(c.cfg.LogFile)
+	file, err := openLogFile(c.cfg.LogFile)
 	if err != nil {
 		return err
 	}
@@ -217,11 +360,13 @@ func (c *LogCollector) readNewLines(ctx context.Context) error {
 	}
 
 	currentSize := stat.Size()
+	inode := fileInode(file, stat)
 
-	// 如果文件被重新创建或截断
-	if currentSize < c.lastSize {
+	// 如果文件被重新创建或截断，先发送旧文件已读取的日志，再开始新的批次序列
+	if inode != c.fileInode || currentSize < c.lastSize {
 		log.Println("📝 检测到日志文件轮转或截断")
-		c.lastSize = 0
+		c.flushBuffer()
+		c.resetStream(inode, 0)
 	}
 
 	// 如果文件没有增长，直接返回
@@ -237,17 +382,21 @@ func (c *LogCollector) readNewLines(ctx context.Context) error {
 		}
 	}
 
+	offset := c.lastSize
 	scanner := bufio.NewScanner(file)
+	scanner.Split(completeLines(&offset))
 	lineCount := 0
 	parsedCount := 0
 
 	for scanner.Scan() {
 		select {
 		case <-ctx.Done():
+			c.lastSize = offset
 			return nil
 		default:
 			line := strings.TrimSpace(scanner.Text())
 			if line == "" {
+				c.setReadOffset(offset)
 				continue
 			}
 
@@ -258,12 +407,18 @@ func (c *LogCollector) readNewLines(ctx context.Context) error {
 			c.processedLines++
 			c.mu.Unlock()
 
-			// 解析日志行
-			if record := c.parser.Parse(line, c.cfg.NodeID); record != nil {
+			// 解析日志行，被过滤规则丢弃的行与无法解析的行一样只推进偏移
+			if record := c.parser.Parse(line, c.cfg.NodeID); record != nil && c.filter.Keep(record) {
 				parsedCount++
 
+				// 补充客户端子网、GeoIP、PTR 信息
+				c.enricher.Enrich(record)
+				c.anonymizer.Apply(record)
+
+				// 记录与其所在的偏移区间同时加入缓冲区，保证批次区间与内容一致
 				c.mu.Lock()
 				c.buffer = append(c.buffer, *record)
+				c.readOffset = offset
 				bufferLen := len(c.buffer)
 				c.mu.Unlock()
 
@@ -271,53 +426,323 @@ func (c *LogCollector) readNewLines(ctx context.Context) error {
 				if bufferLen >= c.cfg.BatchSize {
 					c.flushBuffer()
 				}
+			} else {
+				c.setReadOffset(offset)
 			}
 		}
 	}
 
-	// 更新文件位置
-	newPos, _ := file.Seek(0, 1)
-	c.lastSize = newPos
+	// 更新文件位置（只计算完整的行，未写完的行下次再读）
+	c.lastSize = offset
 
 	if lineCount > 0 {
-		log.Printf("📊 处理了 %d 行新日志, 成功解析 %d 行, 位置: %d", lineCount, parsedCount, c.lastSize)
+		log.Printf("📊 处理了 %d 行新日志, 成功解析并保留 %d 行, 位置: %d", lineCount, parsedCount, c.lastSize)
 	}
 
 	return scanner.Err()
 }
 
 func (c *LogCollector) flushBuffer() {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	if c.queueClosed {
+		return
+	}
+
 	c.mu.Lock()
-	if len(c.buffer) == 0 {
+	// 没有新日志且没有跳过的行时无需发送；只有无法解析的行时也会确认一个空批次，保证偏移区间连续
+	if len(c.buffer) == 0 && c.readOffset == c.batchStart {
 		c.mu.Unlock()
 		return
 	}
 
-	// 复制缓冲区数据
-	bufferCopy := make([]models.DNSLogRecord, len(c.buffer))
-	copy(bufferCopy, c.buffer)
-	c.buffer = c.buffer[:0] // 清空缓冲区
+	// 切出当前缓冲区作为一个批次，新日志写入另一块缓冲区
+	batch := pendingBatch{records: c.buffer}
+	c.buffer = c.takeBuffer()
+	batch.meta = c.nextBatch(len(batch.records))
 	c.mu.Unlock()
 
+	c.enqueue(batch)
+}
+
+// enqueue 批次入队，队列已满时阻塞直到有批次写入完成，读取协程随之暂停读取日志
+func (c *LogCollector) enqueue(batch pendingBatch) {
+	atomic.AddInt64(&c.queuedRows, int64(len(batch.records)))
+
+	select {
+	case c.queue <- batch:
+		return
+	default:
+	}
+
 	start := time.Now()
-	err := c.sender.SendBatch(bufferCopy)
+	c.queue <- batch
+	atomic.AddInt64(&c.backpressureWaits, 1)
+	atomic.AddInt64(&c.backpressureNanos, int64(time.Since(start)))
+}
+
+// closeQueue 停止接收新批次，写入协程发送完队列中剩余的批次后退出
+func (c *LogCollector) closeQueue() {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	if !c.queueClosed {
+		c.queueClosed = true
+		close(c.queue)
+	}
+}
+
+// sendLoop 写入协程，依次发送队列中的批次
+func (c *LogCollector) sendLoop(ctx context.Context) {
+	defer c.senders.Done()
+
+	for batch := range c.queue {
+		atomic.AddInt64(&c.inflightBatches, 1)
+		c.deliver(ctx, batch)
+		atomic.AddInt64(&c.inflightBatches, -1)
+		atomic.AddInt64(&c.queuedRows, -int64(len(batch.records)))
+	}
+}
+
+// deliver 发送批次直到成功，失败时每个刷新间隔原样重发一次，批次内容不变 ClickHouse 才能去重；
+// 收集器停止后不再重发
+func (c *LogCollector) deliver(ctx context.Context, batch pendingBatch) {
+	rows := len(batch.records)
+	retrying := false
+	for !c.sendRecords(batch.records, batch.meta) {
+		if !retrying {
+			retrying = true
+			c.addRetryRows(rows)
+		}
+		select {
+		case <-ctx.Done():
+			log.Printf("⚠️ 日志收集已停止，放弃未写入的批次 #%d（%d 条日志）", batch.meta.Seq, rows)
+			c.addRetryRows(-rows)
+			return
+		case <-time.After(c.cfg.FlushInterval):
+		}
+	}
+	if retrying {
+		c.addRetryRows(-rows)
+	}
+
+	// 清空记录引用后回收缓冲区
+	for i := range batch.records {
+		batch.records[i] = models.DNSLogRecord{}
+	}
+	select {
+	case c.freeBuffers <- batch.records[:0]:
+	default:
+	}
+
+	// 发送成功后保存位置
+	c.savePosition()
+}
+
+// takeBuffer 取一块回收的缓冲区，没有时新建
+func (c *LogCollector) takeBuffer() []models.DNSLogRecord {
+	select {
+	case buffer := <-c.freeBuffers:
+		return buffer
+	default:
+		return make([]models.DNSLogRecord, 0, c.cfg.BatchSize)
+	}
+}
+
+// nextBatch 为当前缓冲区分配批次序号和偏移区间，调用方需持有 c.mu
+func (c *LogCollector) nextBatch(rows int) models.IngestBatch {
+	meta := models.IngestBatch{
+		NodeID:      c.cfg.NodeID,
+		FilePath:    c.cfg.LogFile,
+		StreamID:    c.streamID,
+		Seq:         c.nextSeq,
+		StartOffset: c.batchStart,
+		EndOffset:   c.readOffset,
+		Rows:        uint32(rows),
+	}
+	c.nextSeq++
+	c.batchStart = c.readOffset
+	return meta
+}
+
+// setReadOffset 记录未加入缓冲区的行（空行或无法解析）的结束偏移
+func (c *LogCollector) setReadOffset(offset int64) {
+	c.mu.Lock()
+	c.readOffset = offset
+	c.mu.Unlock()
+}
+
+// writeBatch 写入日志并确认批次；确认失败时整批重发，日志由 ClickHouse 去重
+func (c *LogCollector) writeBatch(records []models.DNSLogRecord, meta models.IngestBatch) error {
+	if len(records) > 0 {
+		if err := c.sender.SendBatch(records); err != nil {
+			return err
+		}
+	}
+
+	meta.SentAt = time.Now()
+	if err := c.sender.AckBatch(meta); err != nil {
+		return fmt.Errorf("确认批次 #%d 失败: %w", meta.Seq, err)
+	}
+	return nil
+}
+
+// sendRecords 发送一个批次并更新统计
+func (c *LogCollector) sendRecords(records []models.DNSLogRecord, meta models.IngestBatch) bool {
+	start := time.Now()
+	err := c.writeBatch(records, meta)
 	duration := time.Since(start)
 
 	c.mu.Lock()
 	if err != nil {
 		c.errorCount++
 		c.mu.Unlock()
-		return
+		log.Printf("❌ 发送 %d 条日志失败，将在下次刷新时重发: %v", len(records), err)
+		return false
 	}
-	c.sentRecords += int64(len(bufferCopy))
+	c.sentRecords += int64(len(records))
 	c.lastSentTime = time.Now()
 	c.positionDirty = true // 标记需要保存位置
 	c.mu.Unlock()
 
-	log.Printf("✅ 发送 %d 条日志到 ClickHouse, 耗时: %v", len(bufferCopy), duration)
+	if len(records) > 0 {
+		log.Printf("✅ 发送 %d 条日志 (批次 #%d), 耗时: %v", len(records), meta.Seq, duration)
+	}
+	return true
+}
 
-	// 发送成功后保存位置
-	c.savePosition()
+// Reread 重新读取指定文件区间的日志并写入，用于补齐管理端检测到的缺失批次。
+// 文件已被删除或截断时返回错误
+func (c *LogCollector) Reread(req RereadRequest) (int, error) {
+	if req.StartOffset < 0 || req.EndOffset <= req.StartOffset {
+		return 0, fmt.Errorf("无效的偏移区间: %d-%d", req.StartOffset, req.EndOffset)
+	}
+
+	path, err := c.findStreamFile(req.StreamID, req.EndOffset)
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := openLogFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	offset := req.StartOffset
+	scanner := bufio.NewScanner(io.NewSectionReader(file, req.StartOffset, req.EndOffset-req.StartOffset))
+	scanner.Split(completeLines(&offset))
+
+	records := make([]models.DNSLogRecord, 0, c.cfg.BatchSize)
+	batchStart := req.StartOffset
+	total := 0
+
+	flush := func() error {
+		meta := models.IngestBatch{
+			NodeID:      c.cfg.NodeID,
+			FilePath:    path,
+			StreamID:    req.StreamID,
+			Seq:         req.Seq,
+			StartOffset: batchStart,
+			EndOffset:   offset,
+			Rows:        uint32(len(records)),
+			Reread:      true,
+		}
+		if err := c.writeBatch(records, meta); err != nil {
+			return err
+		}
+		total += len(records)
+		records = records[:0]
+		batchStart = offset
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if record := c.parser.Parse(line, c.cfg.NodeID); record != nil && c.filter.Keep(record) {
+			c.enricher.Enrich(record)
+			c.anonymizer.Apply(record)
+			records = append(records, *record)
+			if len(records) >= c.cfg.BatchSize {
+				if err := flush(); err != nil {
+					return total, err
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return total, err
+	}
+
+	if len(records) > 0 || offset > batchStart {
+		if err := flush(); err != nil {
+			return total, err
+		}
+	}
+
+	log.Printf("🔁 补读 %s [%d, %d) 完成，写入 %d 条日志", path, req.StartOffset, req.EndOffset, total)
+	return total, nil
+}
+
+// findStreamFile 根据 stream_id 中的 inode 查找日志文件（含已轮转未压缩的文件）
+func (c *LogCollector) findStreamFile(streamID string, endOffset int64) (string, error) {
+	inodeStr, _, _ := strings.Cut(streamID, "-")
+	inode, err := strconv.ParseUint(inodeStr, 10, 64)
+	if err != nil || inode == 0 {
+		return "", fmt.Errorf("无效的 stream_id: %s", streamID)
+	}
+
+	candidates := []string{c.cfg.LogFile}
+	if rotated, err := filepath.Glob(c.cfg.LogFile + ".*"); err == nil {
+		candidates = append(candidates, rotated...)
+	}
+
+	for _, path := range candidates {
+		if strings.HasSuffix(path, ".gz") {
+			continue
+		}
+		stat, id, err := statLogFile(path)
+		if err != nil || id != inode {
+			continue
+		}
+
+		// 当前文件 inode 相同但 stream_id 不同，说明文件在此期间被截断过
+		if path == c.cfg.LogFile {
+			c.mu.RLock()
+			current := c.streamID
+			c.mu.RUnlock()
+			if current != streamID {
+				return "", fmt.Errorf("日志文件已被截断，无法补读")
+			}
+		}
+		if stat.Size() < endOffset {
+			return "", fmt.Errorf("日志文件已被截断，无法补读")
+		}
+		return path, nil
+	}
+
+	return "", fmt.Errorf("日志文件已不存在，无法补读")
+}
+
+// completeLines 只返回以换行结尾的完整行，并累计已消费的字节数
+func completeLines(offset *int64) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			*offset += int64(i + 1)
+			return i + 1, bytes.TrimSuffix(data[:i], []byte{'\r'}), nil
+		}
+		return 0, nil, nil
+	}
+}
+
+func (c *LogCollector) addRetryRows(n int) {
+	c.mu.Lock()
+	c.retryRows += n
+	c.mu.Unlock()
 }
 
 func (c *LogCollector) savePositionIfNeeded() {
@@ -350,6 +775,30 @@ func (c *LogCollector) GetStats() (int64, int64, int64, time.Time) {
 	return c.processedLines, c.sentRecords, c.errorCount, c.lastSentTime
 }
 
+// GetParserStats 获取日志解析统计
+func (c *LogCollector) GetParserStats() utils.ParserStats {
+	return c.parser.Stats()
+}
+
+// GetRetryRows 获取等待重发的日志条数
+func (c *LogCollector) GetRetryRows() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.retryRows
+}
+
+// GetQueueStats 获取发送队列统计
+func (c *LogCollector) GetQueueStats() QueueStats {
+	return QueueStats{
+		QueueDepth:        len(c.queue),
+		QueueCapacity:     cap(c.queue),
+		InflightBatches:   atomic.LoadInt64(&c.inflightBatches),
+		QueuedRows:        atomic.LoadInt64(&c.queuedRows),
+		BackpressureWaits: atomic.LoadInt64(&c.backpressureWaits),
+		BackpressureMs:    float64(atomic.LoadInt64(&c.backpressureNanos)) / 1e6,
+	}
+}
+
 // GetBufferSize 获取缓冲区大小
 func (c *LogCollector) GetBufferSize() int {
 	c.mu.RLock()
@@ -366,5 +815,7 @@ func (c *LogCollector) GetPositionInfo() map[string]interface{} {
 		"position_file": c.positionFile,
 		"last_size":     c.lastSize,
 		"position_info": c.positionInfo,
+		"stream_id":     c.streamID,
+		"next_seq":      c.nextSeq,
 	}
 }
@@ -1,20 +1,131 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	NodeID        uint32           `json:"node_id"`
-	NodeName      string           `json:"node_name"`
-	LogFile       string           `json:"log_file"`
-	BatchSize     int              `json:"batch_size"`
-	FlushInterval time.Duration    `json:"flush_interval"`
-	ClickHouse    ClickHouseConfig `json:"clickhouse"`
-	LogConfig     LogConfig        `json:"log_config"`
+	NodeID             uint32              `json:"node_id"`
+	NodeName           string              `json:"node_name"`
+	LogFile            string              `json:"log_file"`
+	BatchSize          int                 `json:"batch_size"`
+	FlushInterval      time.Duration       `json:"flush_interval"`
+	MaxInflightBatches int                 `json:"max_inflight_batches"` // 同时写入的批次数，写入跟不上时最多再积压同样数量的批次，之后暂停读取日志
+	StorageType        string              `json:"storage_type"`         // clickhouse / timescaledb / http
+	ClickHouse         ClickHouseConfig    `json:"clickhouse"`
+	Postgres           PostgresConfig      `json:"postgres"`
+	Ingest             IngestConfig        `json:"ingest"`
+	Kafka              KafkaConfig         `json:"kafka"`
+	Loki               LokiConfig          `json:"loki"`
+	Elasticsearch      ElasticsearchConfig `json:"elasticsearch"`
+	LogConfig          LogConfig           `json:"log_config"`
+	Enrichment         EnrichmentConfig    `json:"enrichment"`
+	Privacy            PrivacyConfig       `json:"privacy"`
+	Probe              ProbeConfig         `json:"probe"`
+	Control            ControlConfig       `json:"control"`
+}
+
+// Validate 校验批量写入参数，启动时和运行时修改配置时使用
+func (c *Config) Validate() error {
+	if c.BatchSize < 1 {
+		return fmt.Errorf("批量大小必须大于 0")
+	}
+	if c.FlushInterval < 10*time.Millisecond {
+		return fmt.Errorf("刷新间隔不能小于 10 毫秒")
+	}
+	if c.MaxInflightBatches < 1 {
+		return fmt.Errorf("最大并发写入批次数必须大于 0")
+	}
+	if err := c.Privacy.Validate(); err != nil {
+		return err
+	}
+	if c.StorageType == StorageClickHouse && !c.ClickHouse.SupportsCompression(c.ClickHouse.Compression) {
+		return fmt.Errorf("ClickHouse %s 协议不支持压缩算法: %s", c.ClickHouse.Protocol, c.ClickHouse.Compression)
+	}
+	if c.StorageType == StorageHTTP && (c.Ingest.URL == "" || c.Ingest.Token == "") {
+		return fmt.Errorf("LOG_STORAGE_TYPE=http 时必须设置 INGEST_URL 和节点控制令牌（INGEST_TOKEN 或 CONTROL_TOKEN）")
+	}
+	if c.Kafka.Enabled() {
+		if err := c.Kafka.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Loki.Enabled() {
+		if _, err := c.Loki.ParseLabels(); err != nil {
+			return err
+		}
+		if c.Loki.LineFormat != LokiLineJSON && c.Loki.LineFormat != LokiLineRaw {
+			return fmt.Errorf("不支持的 Loki 日志行格式: %s", c.Loki.LineFormat)
+		}
+	}
+	if c.Elasticsearch.Enabled() && c.Elasticsearch.Index == "" {
+		return fmt.Errorf("ELASTICSEARCH_INDEX 不能为空")
+	}
+	return nil
+}
+
+// PrivacyConfig 客户端 IP 脱敏，在写入存储前处理，满足不长期保存原始客户端 IP 的合规要求
+type PrivacyConfig struct {
+	Mode     string `json:"mode"`      // off / truncate / hash
+	V4Prefix int    `json:"v4_prefix"` // truncate 模式保留的 IPv4 前缀长度
+	V6Prefix int    `json:"v6_prefix"` // truncate 模式保留的 IPv6 前缀长度
+	HashKey  string `json:"-"`         // hash 模式的 HMAC 密钥，与管理端 LOG_CLIENT_IP_HASH_KEY 一致时管理员可按原始 IP 检索
+}
+
+// 客户端 IP 脱敏模式
+const (
+	PrivacyOff      = "off"
+	PrivacyTruncate = "truncate" // 截断为网段地址，如 192.168.1.0
+	PrivacyHash     = "hash"     // HMAC-SHA256 哈希，同一 IP 得到相同的值，仍可按客户端统计
+)
+
+// Validate 校验脱敏配置
+func (c PrivacyConfig) Validate() error {
+	switch c.Mode {
+	case PrivacyOff, PrivacyTruncate:
+	case PrivacyHash:
+		if c.HashKey == "" {
+			return fmt.Errorf("CLIENT_IP_PRIVACY=hash 时必须设置 CLIENT_IP_HASH_KEY")
+		}
+	default:
+		return fmt.Errorf("不支持的客户端 IP 脱敏模式: %s", c.Mode)
+	}
+	if c.V4Prefix < 0 || c.V4Prefix > 32 || c.V6Prefix < 0 || c.V6Prefix > 128 {
+		return fmt.Errorf("客户端 IP 脱敏前缀长度无效: /%d, /%d", c.V4Prefix, c.V6Prefix)
+	}
+	return nil
+}
+
+// ControlConfig 到管理端的控制通道，由 Agent 主动连接，管理端无需访问 Agent 的 API 端口
+type ControlConfig struct {
+	URL                string        `json:"url"`                  // 控制通道地址，如 wss://manager.example.com/api/v1/agent/control，为空时不连接
+	Token              string        `json:"-"`                    // 节点控制令牌，在管理端生成
+	InsecureSkipVerify bool          `json:"insecure_skip_verify"` // 跳过证书校验，仅用于测试
+	ReconnectMax       time.Duration `json:"reconnect_max"`        // 断线重连的最长等待时间
+}
+
+// ProbeConfig 本地解析探测配置，从节点视角检测 SmartDNS 的解析可用性和延迟
+type ProbeConfig struct {
+	Domains  []string      `json:"domains"`  // 探测域名，为空时不探测（可由管理端下发）
+	Server   string        `json:"server"`   // 本地 SmartDNS 地址
+	Interval time.Duration `json:"interval"` // 探测间隔
+	Timeout  time.Duration `json:"timeout"`  // 单次解析超时
+}
+
+// EnrichmentConfig 客户端 IP 富化配置
+type EnrichmentConfig struct {
+	GeoIPCountryDB string `json:"geoip_country_db"` // MaxMind Country/City 数据库路径，为空则不查询国家
+	GeoIPASNDB     string `json:"geoip_asn_db"`     // MaxMind ASN 数据库路径，为空则不查询 ASN
+	EnablePTR      bool   `json:"enable_ptr"`       // 是否反查 PTR
+	PTRCacheTTL    int    `json:"ptr_cache_ttl"`    // PTR 缓存时间（秒）
+	SubnetV4Prefix int    `json:"subnet_v4_prefix"` // IPv4 子网前缀长度
+	SubnetV6Prefix int    `json:"subnet_v6_prefix"` // IPv6 子网前缀长度
 }
 
 type LogConfig struct {
@@ -29,8 +140,270 @@ type ClickHouseConfig struct {
 	Database string `json:"database"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+
+	// 连接协议与 TLS
+	Protocol           string `json:"protocol"`             // native / http
+	Secure             bool   `json:"secure"`               // 是否使用 TLS（原生安全端口或 HTTPS）
+	CACert             string `json:"ca_cert"`              // 自定义 CA 证书路径，为空时使用系统证书
+	ServerName         string `json:"server_name"`          // TLS 校验使用的主机名（SNI）
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"` // 跳过证书校验，仅用于测试
+
+	// 连接池与写入配置
+	MaxOpenConns  int           `json:"max_open_conns"` // 最大连接数
+	MaxIdleConns  int           `json:"max_idle_conns"` // 最大空闲连接数
+	InsertTimeout time.Duration `json:"insert_timeout"` // 单次批量写入超时
+	MaxRetries    int           `json:"max_retries"`    // 写入失败重试次数
+	RetryBackoff  time.Duration `json:"retry_backoff"`  // 首次重试等待时间，之后逐次翻倍
+	Compression   string        `json:"compression"`    // 写入压缩算法：none / lz4 / zstd，HTTP 协议另支持 gzip / deflate / br
+}
+
+// SupportsCompression 当前协议是否支持该压缩算法
+func (c ClickHouseConfig) SupportsCompression(codec string) bool {
+	switch codec {
+	case CompressionNone, CompressionLZ4, CompressionZSTD:
+		return true
+	case CompressionGZIP, CompressionDeflate, CompressionBrotli:
+		return c.Protocol == ClickHouseProtocolHTTP
+	}
+	return false
+}
+
+// TLSConfig 生成 TLS 配置，未启用 TLS 时返回 nil
+func (c ClickHouseConfig) TLSConfig() (*tls.Config, error) {
+	if !c.Secure {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = c.Host
+	}
+
+	if c.CACert != "" {
+		pem, err := os.ReadFile(c.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("读取 ClickHouse CA 证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ClickHouse CA 证书无效: %s", c.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// PostgresConfig 日志写入 PostgreSQL / TimescaleDB 时的配置
+type PostgresConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Database string `json:"database"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	SSLMode  string `json:"ssl_mode"`
+
+	MaxConns      int           `json:"max_conns"`
+	InsertTimeout time.Duration `json:"insert_timeout"`
+	MaxRetries    int           `json:"max_retries"`
+	RetryBackoff  time.Duration `json:"retry_backoff"`
+}
+
+// IngestConfig 经管理端写入日志，节点无法直接访问 ClickHouse 时使用
+type IngestConfig struct {
+	URL                string        `json:"url"`                  // 管理端 API 地址，如 https://manager.example.com/api/v1
+	Token              string        `json:"-"`                    // 节点控制令牌，未设置时使用 CONTROL_TOKEN
+	InsecureSkipVerify bool          `json:"insecure_skip_verify"` // 跳过证书校验，仅用于测试
+	Timeout            time.Duration `json:"timeout"`              // 单次上传超时
+	MaxRetries         int           `json:"max_retries"`          // 上传失败重试次数
+	RetryBackoff       time.Duration `json:"retry_backoff"`        // 首次重试等待时间，之后逐次翻倍
+}
+
+// KafkaConfig 在写入主存储的同时把日志写入 Kafka，供已有的流处理链路消费，无需再单独采集
+type KafkaConfig struct {
+	Brokers            []string      `json:"brokers"`             // 为空时不写入 Kafka
+	Cluster            string        `json:"cluster"`             // 节点所属的 SmartDNS 集群，替换主题中的 {cluster}
+	Topic              string        `json:"topic"`               // 主题，可包含 {cluster}，使每个集群写入各自的主题
+	Format             string        `json:"format"`              // 消息格式：json / avro
+	SchemaRegistryURL  string        `json:"schema_registry_url"` // Avro 格式时注册 Schema 的地址，设置后消息带 Confluent Schema ID 前缀
+	Compression        string        `json:"compression"`         // none / gzip / snappy / lz4 / zstd
+	SASLMechanism      string        `json:"sasl_mechanism"`      // 为空时不认证，支持 plain / scram-sha-256 / scram-sha-512
+	Username           string        `json:"username"`
+	Password           string        `json:"-"`
+	TLS                bool          `json:"tls"`
+	CACert             string        `json:"ca_cert"`              // 自定义 CA 证书路径，为空时使用系统证书
+	InsecureSkipVerify bool          `json:"insecure_skip_verify"` // 跳过证书校验，仅用于测试
+	Timeout            time.Duration `json:"timeout"`              // 单批写入超时，包含生产者内部的重试
+}
+
+// Kafka 消息格式
+const (
+	KafkaFormatJSON = "json"
+	KafkaFormatAvro = "avro"
+)
+
+// Enabled 是否同时写入 Kafka
+func (c KafkaConfig) Enabled() bool {
+	return len(c.Brokers) > 0
+}
+
+// TopicName 替换 {cluster} 后的主题名
+func (c KafkaConfig) TopicName() string {
+	return strings.ReplaceAll(c.Topic, "{cluster}", c.Cluster)
+}
+
+// Validate 校验 Kafka 配置
+func (c KafkaConfig) Validate() error {
+	if c.TopicName() == "" {
+		return fmt.Errorf("KAFKA_TOPIC 不能为空")
+	}
+	switch c.Format {
+	case KafkaFormatJSON, KafkaFormatAvro:
+	default:
+		return fmt.Errorf("不支持的 Kafka 消息格式: %s", c.Format)
+	}
+	switch c.Compression {
+	case CompressionNone, "gzip", "snappy", CompressionLZ4, CompressionZSTD:
+	default:
+		return fmt.Errorf("不支持的 Kafka 压缩算法: %s", c.Compression)
+	}
+	switch c.SASLMechanism {
+	case "", "plain", "scram-sha-256", "scram-sha-512":
+	default:
+		return fmt.Errorf("不支持的 Kafka SASL 认证方式: %s", c.SASLMechanism)
+	}
+	return nil
+}
+
+// TLSConfig 生成 TLS 配置，未启用 TLS 时返回 nil
+func (c KafkaConfig) TLSConfig() (*tls.Config, error) {
+	if !c.TLS {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if c.CACert != "" {
+		pem, err := os.ReadFile(c.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("读取 Kafka CA 证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Kafka CA 证书无效: %s", c.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// LokiConfig 同时把日志写入 Grafana Loki
+type LokiConfig struct {
+	URL                string        `json:"url"`                  // Loki 地址，如 http://loki:3100，可包含用户名和密码；为空时不写入
+	TenantID           string        `json:"tenant_id"`            // 多租户时的 X-Scope-OrgID
+	Labels             string        `json:"labels"`               // 流标签模板，如 job=smartdns,node={node_name}，标签值可使用日志字段占位符
+	LineFormat         string        `json:"line_format"`          // 日志行格式：json（完整记录）/ raw（SmartDNS 原始日志）
+	InsecureSkipVerify bool          `json:"insecure_skip_verify"` // 跳过证书校验，仅用于测试
+	Timeout            time.Duration `json:"timeout"`              // 单次推送超时
+	MaxRetries         int           `json:"max_retries"`          // 推送失败重试次数
+	RetryBackoff       time.Duration `json:"retry_backoff"`        // 首次重试等待时间，之后逐次翻倍
+}
+
+// Loki 日志行格式
+const (
+	LokiLineJSON = "json"
+	LokiLineRaw  = "raw"
+)
+
+// LokiLabel 流标签，Value 为可包含日志字段占位符的模板
+type LokiLabel struct {
+	Name  string
+	Value string
+}
+
+// Enabled 是否同时写入 Loki
+func (c LokiConfig) Enabled() bool {
+	return c.URL != ""
 }
 
+// ParseLabels 解析逗号分隔的 name=value 标签模板，标签名需符合 Prometheus 标签名规则
+func (c LokiConfig) ParseLabels() ([]LokiLabel, error) {
+	var labels []LokiLabel
+	for _, item := range strings.Split(c.Labels, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !isLabelName(name) {
+			return nil, fmt.Errorf("Loki 标签格式错误: %s", item)
+		}
+		labels = append(labels, LokiLabel{Name: name, Value: strings.TrimSpace(value)})
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("LOKI_LABELS 至少需要一个标签")
+	}
+	return labels, nil
+}
+
+// isLabelName 是否为合法的标签名：[a-zA-Z_][a-zA-Z0-9_]*
+func isLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// ElasticsearchConfig 同时以 Bulk API 把日志写入 Elasticsearch 或 OpenSearch
+type ElasticsearchConfig struct {
+	URL                string        `json:"url"`                  // 集群地址，如 https://es.example.com:9200，可包含用户名和密码；为空时不写入
+	Index              string        `json:"index"`                // 索引模板，如 smartdns-dns-logs-{date}，可使用日志字段占位符，写入时转为小写
+	APIKey             string        `json:"-"`                    // API Key 认证（Base64 编码的 id:api_key），设置后不使用地址中的用户名和密码
+	InsecureSkipVerify bool          `json:"insecure_skip_verify"` // 跳过证书校验，仅用于测试
+	Timeout            time.Duration `json:"timeout"`              // 单次 Bulk 请求超时
+	MaxRetries         int           `json:"max_retries"`          // 写入失败重试次数
+	RetryBackoff       time.Duration `json:"retry_backoff"`        // 首次重试等待时间，之后逐次翻倍
+}
+
+// Enabled 是否同时写入 Elasticsearch
+func (c ElasticsearchConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+const (
+	StorageClickHouse = "clickhouse"
+	StorageTimescale  = "timescaledb"
+	StorageHTTP       = "http" // 经管理端 /ingest/dns-logs 写入，由管理端写入 ClickHouse
+)
+
+// ClickHouse 连接协议
+const (
+	ClickHouseProtocolNative = "native"
+	ClickHouseProtocolHTTP   = "http"
+)
+
+// ClickHouse 写入压缩算法
+const (
+	CompressionNone    = "none"
+	CompressionLZ4     = "lz4"
+	CompressionZSTD    = "zstd"
+	CompressionGZIP    = "gzip"
+	CompressionDeflate = "deflate"
+	CompressionBrotli  = "br"
+)
+
 func Load() (*Config, error) {
 	nodeIDStr := getEnv("NODE_ID", "")
 	if nodeIDStr == "" {
@@ -42,25 +415,173 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("NODE_ID 必须是数字")
 	}
 
-	return &Config{
-		NodeID:        uint32(nodeID),
-		NodeName:      getEnv("NODE_NAME", fmt.Sprintf("node-%d", nodeID)),
-		LogFile:       getEnv("LOG_FILE", "/var/log/smartdns/audit.log"),
-		BatchSize:     getEnvInt("BATCH_SIZE", 1000),
-		FlushInterval: time.Duration(getEnvInt("FLUSH_INTERVAL_SEC", 2)) * time.Second,
+	chProtocol, chSecure := getClickHouseProtocol()
+
+	cfg := &Config{
+		NodeID:             uint32(nodeID),
+		NodeName:           getEnv("NODE_NAME", fmt.Sprintf("node-%d", nodeID)),
+		LogFile:            getEnv("LOG_FILE", defaultLogFile),
+		BatchSize:          getEnvInt("BATCH_SIZE", 1000),
+		FlushInterval:      getFlushInterval(),
+		MaxInflightBatches: getEnvInt("MAX_INFLIGHT_BATCHES", 1),
+		StorageType:        getStorageType(),
 		ClickHouse: ClickHouseConfig{
-			Host:     getEnv("CLICKHOUSE_HOST", "localhost"),
-			Port:     getEnvInt("CLICKHOUSE_PORT", 9000),
-			Database: getEnv("CLICKHOUSE_DB", "smartdns_logs"),
-			Username: getEnv("CLICKHOUSE_USER", "default"),
-			Password: getEnv("CLICKHOUSE_PASSWORD", ""),
+			Host:          getEnv("CLICKHOUSE_HOST", "localhost"),
+			Port:          getEnvInt("CLICKHOUSE_PORT", defaultClickHousePort(chProtocol, chSecure)),
+			Database:      getEnv("CLICKHOUSE_DB", "smartdns_logs"),
+			Username:      getEnv("CLICKHOUSE_USER", "default"),
+			Password:      getEnv("CLICKHOUSE_PASSWORD", ""),
+			MaxOpenConns:  getEnvInt("CLICKHOUSE_MAX_OPEN_CONNS", 4),
+			MaxIdleConns:  getEnvInt("CLICKHOUSE_MAX_IDLE_CONNS", 2),
+			InsertTimeout: time.Duration(getEnvInt("CLICKHOUSE_INSERT_TIMEOUT_SEC", 30)) * time.Second,
+			MaxRetries:    getEnvInt("CLICKHOUSE_MAX_RETRIES", 3),
+			RetryBackoff:  time.Duration(getEnvInt("CLICKHOUSE_RETRY_BACKOFF_MS", 500)) * time.Millisecond,
+			Compression:   strings.ToLower(getEnv("CLICKHOUSE_COMPRESSION", CompressionLZ4)),
+
+			Protocol:           chProtocol,
+			Secure:             chSecure,
+			CACert:             getEnv("CLICKHOUSE_CA_CERT", ""),
+			ServerName:         getEnv("CLICKHOUSE_TLS_SERVER_NAME", ""),
+			InsecureSkipVerify: getEnvBool("CLICKHOUSE_TLS_SKIP_VERIFY", false),
+		},
+		Postgres: PostgresConfig{
+			Host:          getEnv("POSTGRES_HOST", "localhost"),
+			Port:          getEnvInt("POSTGRES_PORT", 5432),
+			Database:      getEnv("POSTGRES_DB", "smartdns_logs"),
+			Username:      getEnv("POSTGRES_USER", "smartdns"),
+			Password:      getEnv("POSTGRES_PASSWORD", ""),
+			SSLMode:       getEnv("POSTGRES_SSLMODE", "disable"),
+			MaxConns:      getEnvInt("POSTGRES_MAX_CONNS", 4),
+			InsertTimeout: time.Duration(getEnvInt("POSTGRES_INSERT_TIMEOUT_SEC", 30)) * time.Second,
+			MaxRetries:    getEnvInt("POSTGRES_MAX_RETRIES", 3),
+			RetryBackoff:  time.Duration(getEnvInt("POSTGRES_RETRY_BACKOFF_MS", 500)) * time.Millisecond,
+		},
+		Ingest: IngestConfig{
+			URL:                strings.TrimSuffix(getEnv("INGEST_URL", ""), "/"),
+			Token:              getEnv("INGEST_TOKEN", getEnv("CONTROL_TOKEN", "")),
+			InsecureSkipVerify: getEnvBool("INGEST_TLS_SKIP_VERIFY", false),
+			Timeout:            time.Duration(getEnvInt("INGEST_TIMEOUT_SEC", 60)) * time.Second,
+			MaxRetries:         getEnvInt("INGEST_MAX_RETRIES", 5),
+			RetryBackoff:       time.Duration(getEnvInt("INGEST_RETRY_BACKOFF_MS", 1000)) * time.Millisecond,
+		},
+		Kafka: KafkaConfig{
+			Brokers:            getEnvList("KAFKA_BROKERS"),
+			Cluster:            getEnv("KAFKA_CLUSTER", "default"),
+			Topic:              getEnv("KAFKA_TOPIC", "smartdns-dns-logs-{cluster}"),
+			Format:             strings.ToLower(getEnv("KAFKA_FORMAT", KafkaFormatJSON)),
+			SchemaRegistryURL:  strings.TrimSuffix(getEnv("KAFKA_SCHEMA_REGISTRY_URL", ""), "/"),
+			Compression:        strings.ToLower(getEnv("KAFKA_COMPRESSION", CompressionLZ4)),
+			SASLMechanism:      strings.ToLower(getEnv("KAFKA_SASL_MECHANISM", "")),
+			Username:           getEnv("KAFKA_USERNAME", ""),
+			Password:           getEnv("KAFKA_PASSWORD", ""),
+			TLS:                getEnvBool("KAFKA_TLS", false),
+			CACert:             getEnv("KAFKA_CA_CERT", ""),
+			InsecureSkipVerify: getEnvBool("KAFKA_TLS_SKIP_VERIFY", false),
+			Timeout:            time.Duration(getEnvInt("KAFKA_PRODUCE_TIMEOUT_SEC", 30)) * time.Second,
+		},
+		Loki: LokiConfig{
+			URL:                strings.TrimSuffix(getEnv("LOKI_URL", ""), "/"),
+			TenantID:           getEnv("LOKI_TENANT_ID", ""),
+			Labels:             getEnv("LOKI_LABELS", "job=smartdns,node={node_name}"),
+			LineFormat:         strings.ToLower(getEnv("LOKI_LINE_FORMAT", LokiLineJSON)),
+			InsecureSkipVerify: getEnvBool("LOKI_TLS_SKIP_VERIFY", false),
+			Timeout:            time.Duration(getEnvInt("LOKI_TIMEOUT_SEC", 30)) * time.Second,
+			MaxRetries:         getEnvInt("LOKI_MAX_RETRIES", 3),
+			RetryBackoff:       time.Duration(getEnvInt("LOKI_RETRY_BACKOFF_MS", 1000)) * time.Millisecond,
+		},
+		Elasticsearch: ElasticsearchConfig{
+			URL:                strings.TrimSuffix(getEnv("ELASTICSEARCH_URL", ""), "/"),
+			Index:              getEnv("ELASTICSEARCH_INDEX", "smartdns-dns-logs-{date}"),
+			APIKey:             getEnv("ELASTICSEARCH_API_KEY", ""),
+			InsecureSkipVerify: getEnvBool("ELASTICSEARCH_TLS_SKIP_VERIFY", false),
+			Timeout:            time.Duration(getEnvInt("ELASTICSEARCH_TIMEOUT_SEC", 30)) * time.Second,
+			MaxRetries:         getEnvInt("ELASTICSEARCH_MAX_RETRIES", 3),
+			RetryBackoff:       time.Duration(getEnvInt("ELASTICSEARCH_RETRY_BACKOFF_MS", 1000)) * time.Millisecond,
 		},
 		LogConfig: LogConfig{
-			LogDir:     getEnv("AGENT_LOG_DIR", "/var/log/smartdns-agent"),
+			LogDir:     getEnv("AGENT_LOG_DIR", defaultAgentLogDir),
 			MaxDays:    getEnvInt("AGENT_LOG_MAX_DAYS", 7),
 			EnableFile: getEnvBool("AGENT_LOG_ENABLE_FILE", true),
 		},
-	}, nil
+		Enrichment: EnrichmentConfig{
+			GeoIPCountryDB: getEnv("GEOIP_COUNTRY_DB", ""),
+			GeoIPASNDB:     getEnv("GEOIP_ASN_DB", ""),
+			EnablePTR:      getEnvBool("ENABLE_PTR_LOOKUP", false),
+			PTRCacheTTL:    getEnvInt("PTR_CACHE_TTL_SEC", 3600),
+			SubnetV4Prefix: getEnvInt("CLIENT_SUBNET_V4_PREFIX", 24),
+			SubnetV6Prefix: getEnvInt("CLIENT_SUBNET_V6_PREFIX", 64),
+		},
+		Privacy: PrivacyConfig{
+			Mode:     strings.ToLower(getEnv("CLIENT_IP_PRIVACY", PrivacyOff)),
+			V4Prefix: getEnvInt("CLIENT_IP_PRIVACY_V4_PREFIX", 24),
+			V6Prefix: getEnvInt("CLIENT_IP_PRIVACY_V6_PREFIX", 56),
+			HashKey:  getEnv("CLIENT_IP_HASH_KEY", ""),
+		},
+		Probe: ProbeConfig{
+			Domains:  getEnvList("PROBE_DOMAINS"),
+			Server:   getEnv("PROBE_SERVER", "127.0.0.1:53"),
+			Interval: time.Duration(getEnvInt("PROBE_INTERVAL_SEC", 30)) * time.Second,
+			Timeout:  time.Duration(getEnvInt("PROBE_TIMEOUT_MS", 2000)) * time.Millisecond,
+		},
+		Control: ControlConfig{
+			URL:                getEnv("CONTROL_URL", ""),
+			Token:              getEnv("CONTROL_TOKEN", ""),
+			InsecureSkipVerify: getEnvBool("CONTROL_TLS_SKIP_VERIFY", false),
+			ReconnectMax:       time.Duration(getEnvInt("CONTROL_RECONNECT_MAX_SEC", 60)) * time.Second,
+		},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// getStorageType 日志写入的存储类型，直接写入时需与管理端 LOG_STORAGE_TYPE 一致，http 表示经管理端写入
+func getStorageType() string {
+	switch strings.ToLower(getEnv("LOG_STORAGE_TYPE", StorageClickHouse)) {
+	case "timescaledb", "timescale", "postgres", "postgresql":
+		return StorageTimescale
+	case "http", "https", "ingest":
+		return StorageHTTP
+	default:
+		return StorageClickHouse
+	}
+}
+
+// getClickHouseProtocol 连接协议和是否启用 TLS，CLICKHOUSE_PROTOCOL=https 等同于 http + CLICKHOUSE_SECURE=true
+func getClickHouseProtocol() (string, bool) {
+	secure := getEnvBool("CLICKHOUSE_SECURE", false)
+	switch strings.ToLower(getEnv("CLICKHOUSE_PROTOCOL", ClickHouseProtocolNative)) {
+	case "https":
+		return ClickHouseProtocolHTTP, true
+	case ClickHouseProtocolHTTP:
+		return ClickHouseProtocolHTTP, secure
+	default:
+		return ClickHouseProtocolNative, secure
+	}
+}
+
+// defaultClickHousePort 未指定端口时按协议和是否加密选择 ClickHouse 的默认端口
+func defaultClickHousePort(protocol string, secure bool) int {
+	switch {
+	case protocol == ClickHouseProtocolHTTP && secure:
+		return 8443
+	case protocol == ClickHouseProtocolHTTP:
+		return 8123
+	case secure:
+		return 9440
+	default:
+		return 9000
+	}
+}
+
+// getFlushInterval 刷新间隔，FLUSH_INTERVAL_MS 优先于 FLUSH_INTERVAL_SEC，便于高 QPS 节点使用亚秒级间隔
+func getFlushInterval() time.Duration {
+	if ms := getEnvInt("FLUSH_INTERVAL_MS", 0); ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return time.Duration(getEnvInt("FLUSH_INTERVAL_SEC", 2)) * time.Second
 }
 
 func getEnvBool(key string, defaultValue bool) bool {
@@ -79,6 +600,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList 逗号分隔的列表，忽略空项
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if valueStr := os.Getenv(key); valueStr != "" {
 		if value, err := strconv.Atoi(valueStr); err == nil {
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultDataDir 保存读取位置和过滤规则的状态目录
+func DefaultDataDir() string {
+	return defaultDataDir
+}
+
+// DefaultConfigFile 默认的配置文件，Windows 服务没有 systemd 的 EnvironmentFile，由 Agent 自己读取
+func DefaultConfigFile() string {
+	return defaultConfigFile
+}
+
+// LoadEnvFile 读取 KEY=VALUE 格式的配置文件并设置为环境变量，已设置的环境变量优先。
+// 忽略空行和 # 开头的注释，值两侧的引号会被去掉。文件不存在时返回的错误满足 os.IsNotExist
+func LoadEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s 第 %d 行格式错误，应为 KEY=VALUE", path, lineNo)
+		}
+		key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("设置环境变量 %s 失败: %w", key, err)
+		}
+	}
+	return scanner.Err()
+}
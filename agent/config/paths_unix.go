@@ -0,0 +1,11 @@
+//go:build !windows
+
+package config
+
+// Linux 上的默认路径，与 install.sh 和 systemd 服务文件一致
+const (
+	defaultLogFile     = "/var/log/smartdns/audit.log"
+	defaultAgentLogDir = "/var/log/smartdns-agent"
+	defaultDataDir     = "/var/lib/smartdns-agent"
+	defaultConfigFile  = "/etc/smartdns-log-agent/config"
+)
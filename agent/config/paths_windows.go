@@ -0,0 +1,23 @@
+//go:build windows
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Windows 上的默认路径都在 %ProgramData% 下，与 install.ps1 一致
+var (
+	defaultLogFile     = filepath.Join(programData(), "smartdns", "log", "audit.log")
+	defaultAgentLogDir = filepath.Join(programData(), "smartdns-log-agent", "logs")
+	defaultDataDir     = filepath.Join(programData(), "smartdns-log-agent", "data")
+	defaultConfigFile  = filepath.Join(programData(), "smartdns-log-agent", "config.env")
+)
+
+func programData() string {
+	if dir := os.Getenv("ProgramData"); dir != "" {
+		return dir
+	}
+	return `C:\ProgramData`
+}
@@ -0,0 +1,233 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"smartdns-log-agent/config"
+)
+
+const (
+	// pingInterval 心跳间隔，管理端超过 readTimeout 未收到任何消息即断开
+	pingInterval = 30 * time.Second
+	readTimeout  = 90 * time.Second
+	writeTimeout = 10 * time.Second
+	dialTimeout  = 10 * time.Second
+	// stableAfter 连接保持超过该时长后断开，重连等待从头计算
+	stableAfter = time.Minute
+)
+
+// 消息类型
+const (
+	MessageHello    = "hello"    // Agent 连接后发送的第一条消息
+	MessagePing     = "ping"     // 心跳
+	MessagePong     = "pong"     // 心跳应答
+	MessageRequest  = "request"  // 管理端下发的命令
+	MessageResponse = "response" // 命令执行结果
+)
+
+// Message 控制通道消息，命令按 Agent API 的方法和路径下发，结果为 API 的原始响应
+type Message struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`     // 请求 ID，响应原样带回
+	Method  string          `json:"method,omitempty"` // 请求方法
+	Path    string          `json:"path,omitempty"`   // 相对 /api/v1 的路径，可带查询参数，如 /probe/results?since=0
+	Body    json.RawMessage `json:"body,omitempty"`   // 请求体或响应体
+	Status  int             `json:"status,omitempty"` // 响应状态码
+	NodeID  uint32          `json:"node_id,omitempty"`
+	Version string          `json:"version,omitempty"`
+}
+
+// Client 主动连接管理端的控制通道，将下发的命令交给本地 API 处理，断线后自动重连
+//
+// 节点位于 NAT 之后、管理端无法访问 Agent API 端口时，管理端通过该通道管理 Agent
+type Client struct {
+	cfg       config.ControlConfig
+	nodeID    uint32
+	version   string
+	handler   http.Handler
+	connected atomic.Bool
+}
+
+// NewClient 创建控制通道客户端，handler 为 Agent 的 API 路由
+func NewClient(cfg config.ControlConfig, nodeID uint32, version string, handler http.Handler) *Client {
+	return &Client{cfg: cfg, nodeID: nodeID, version: version, handler: handler}
+}
+
+// Connected 当前是否已连接到管理端
+func (c *Client) Connected() bool {
+	return c.connected.Load()
+}
+
+// Run 保持与管理端的连接，直到 ctx 取消
+func (c *Client) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := c.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > stableAfter {
+			backoff = time.Second
+		}
+		log.Printf("⚠️ 控制通道断开: %v，%v 后重连", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > c.cfg.ReconnectMax {
+			backoff = c.cfg.ReconnectMax
+		}
+	}
+}
+
+// session 建立一次连接并处理消息，连接断开时返回
+func (c *Client) session(ctx context.Context) error {
+	ws, err := c.dial()
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	var sendMu sync.Mutex
+	send := func(msg Message) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return websocket.JSON.Send(ws, msg)
+	}
+
+	if err := send(Message{Type: MessageHello, NodeID: c.nodeID, Version: c.version}); err != nil {
+		return fmt.Errorf("发送握手消息失败: %w", err)
+	}
+	c.connected.Store(true)
+	defer c.connected.Store(false)
+	log.Printf("🔗 控制通道已连接: %s", c.cfg.URL)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := send(Message{Type: MessagePing}); err != nil {
+					ws.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		ws.SetReadDeadline(time.Now().Add(readTimeout))
+		var msg Message
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			return err
+		}
+		switch msg.Type {
+		case MessageRequest:
+			// 命令可能耗时较长（如停止收集需等待缓冲刷新），不阻塞后续消息
+			go func(msg Message) {
+				if err := send(c.dispatch(msg)); err != nil {
+					log.Printf("⚠️ 回复控制命令失败: %v", err)
+				}
+			}(msg)
+		case MessagePing:
+			send(Message{Type: MessagePong})
+		}
+	}
+}
+
+func (c *Client) dial() (*websocket.Conn, error) {
+	wsConfig, err := websocket.NewConfig(c.cfg.URL, originOf(c.cfg.URL))
+	if err != nil {
+		return nil, fmt.Errorf("控制通道地址无效: %w", err)
+	}
+	wsConfig.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	wsConfig.Dialer = &net.Dialer{Timeout: dialTimeout}
+	if wsConfig.Location.Scheme == "wss" {
+		wsConfig.TlsConfig = &tls.Config{
+			InsecureSkipVerify: c.cfg.InsecureSkipVerify,
+			MinVersion:         tls.VersionTLS12,
+		}
+	}
+
+	ws, err := websocket.DialConfig(wsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("连接管理端失败: %w", err)
+	}
+	return ws, nil
+}
+
+// dispatch 在本地 API 路由上执行命令
+func (c *Client) dispatch(msg Message) Message {
+	reply := Message{Type: MessageResponse, ID: msg.ID}
+
+	if !strings.HasPrefix(msg.Path, "/") {
+		reply.Status = http.StatusBadRequest
+		reply.Body = errorBody("无效的命令路径: " + msg.Path)
+		return reply
+	}
+	req, err := http.NewRequest(msg.Method, "/api/v1"+msg.Path, bytes.NewReader(msg.Body))
+	if err != nil {
+		reply.Status = http.StatusBadRequest
+		reply.Body = errorBody("无效的命令: " + err.Error())
+		return reply
+	}
+	if len(msg.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	recorder := httptest.NewRecorder()
+	c.handler.ServeHTTP(recorder, req)
+
+	reply.Status = recorder.Code
+	reply.Body = recorder.Body.Bytes()
+	if !json.Valid(reply.Body) {
+		reply.Body = errorBody(strings.TrimSpace(recorder.Body.String()))
+	}
+	return reply
+}
+
+// errorBody 与 Agent API 一致的错误响应
+func errorBody(message string) json.RawMessage {
+	body, _ := json.Marshal(map[string]interface{}{
+		"success": false,
+		"message": message,
+	})
+	return body
+}
+
+// originOf WebSocket 握手要求的 Origin，使用管理端地址本身
+func originOf(url string) string {
+	switch {
+	case strings.HasPrefix(url, "wss://"):
+		return "https://" + strings.TrimPrefix(url, "wss://")
+	case strings.HasPrefix(url, "ws://"):
+		return "http://" + strings.TrimPrefix(url, "ws://")
+	}
+	return url
+}
@@ -0,0 +1,220 @@
+package enricher
+
+import (
+	"context"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+
+	"smartdns-log-agent/config"
+	"smartdns-log-agent/models"
+)
+
+// ptrEntry PTR 缓存项
+type ptrEntry struct {
+	name      string
+	expiresAt time.Time
+}
+
+// Enricher 客户端 IP 富化器（子网、GeoIP 国家、ASN、PTR）
+type Enricher struct {
+	cfg        config.EnrichmentConfig
+	countryDB  *geoip2.Reader
+	asnDB      *geoip2.Reader
+	v4Mask     net.IPMask
+	v6Mask     net.IPMask
+	ptrCache   map[string]ptrEntry
+	ptrPending map[string]bool
+	ptrQueue   chan string
+	categories map[string]string // 域名 -> 分类
+	mu         sync.RWMutex
+	stopCh     chan struct{}
+}
+
+func NewEnricher(cfg config.EnrichmentConfig) *Enricher {
+	e := &Enricher{
+		cfg:        cfg,
+		v4Mask:     net.CIDRMask(clampPrefix(cfg.SubnetV4Prefix, 32, 24), 32),
+		v6Mask:     net.CIDRMask(clampPrefix(cfg.SubnetV6Prefix, 128, 64), 128),
+		ptrCache:   make(map[string]ptrEntry),
+		ptrPending: make(map[string]bool),
+		ptrQueue:   make(chan string, 1024),
+		stopCh:     make(chan struct{}),
+	}
+
+	if cfg.GeoIPCountryDB != "" {
+		db, err := geoip2.Open(cfg.GeoIPCountryDB)
+		if err != nil {
+			log.Printf("⚠️ 打开 GeoIP 国家数据库失败: %v", err)
+		} else {
+			e.countryDB = db
+			log.Printf("🌍 已加载 GeoIP 国家数据库: %s", cfg.GeoIPCountryDB)
+		}
+	}
+
+	if cfg.GeoIPASNDB != "" {
+		db, err := geoip2.Open(cfg.GeoIPASNDB)
+		if err != nil {
+			log.Printf("⚠️ 打开 GeoIP ASN 数据库失败: %v", err)
+		} else {
+			e.asnDB = db
+			log.Printf("🌍 已加载 GeoIP ASN 数据库: %s", cfg.GeoIPASNDB)
+		}
+	}
+
+	if cfg.EnablePTR {
+		// PTR 反查在后台进行，避免阻塞日志采集
+		for i := 0; i < 4; i++ {
+			go e.ptrWorker()
+		}
+	}
+
+	return e
+}
+
+// SetDomainCategories 更新域名分类表
+func (e *Enricher) SetDomainCategories(categories map[string]string) {
+	e.mu.Lock()
+	e.categories = categories
+	e.mu.Unlock()
+}
+
+// matchCategory 按域名后缀匹配分类（a.b.example.com -> b.example.com -> example.com）
+func (e *Enricher) matchCategory(domain string) string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if len(e.categories) == 0 {
+		return ""
+	}
+
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	for domain != "" {
+		if category, ok := e.categories[domain]; ok {
+			return category
+		}
+		idx := strings.IndexByte(domain, '.')
+		if idx < 0 {
+			break
+		}
+		domain = domain[idx+1:]
+	}
+	return ""
+}
+
+// Enrich 为日志记录补充域名分类和客户端信息
+func (e *Enricher) Enrich(record *models.DNSLogRecord) {
+	record.DomainCategory = e.matchCategory(record.Domain)
+
+	ip := net.ParseIP(record.ClientIP)
+	if ip == nil {
+		return
+	}
+
+	record.ClientSubnet = e.subnetOf(ip)
+
+	if e.countryDB != nil {
+		if country, err := e.countryDB.Country(ip); err == nil {
+			record.ClientCountry = country.Country.IsoCode
+		}
+	}
+
+	if e.asnDB != nil {
+		if asn, err := e.asnDB.ASN(ip); err == nil {
+			record.ClientASN = uint32(asn.AutonomousSystemNumber)
+			record.ClientASOrg = asn.AutonomousSystemOrganization
+		}
+	}
+
+	if e.cfg.EnablePTR {
+		record.ClientPTR = e.lookupPTR(record.ClientIP)
+	}
+}
+
+// subnetOf 计算客户端所属子网
+func (e *Enricher) subnetOf(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(e.v4Mask), Mask: e.v4Mask}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(e.v6Mask), Mask: e.v6Mask}).String()
+}
+
+// lookupPTR 从缓存获取 PTR，未命中时加入后台反查队列
+func (e *Enricher) lookupPTR(ip string) string {
+	e.mu.RLock()
+	entry, ok := e.ptrCache[ip]
+	pending := e.ptrPending[ip]
+	e.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.name
+	}
+
+	if !pending {
+		e.mu.Lock()
+		e.ptrPending[ip] = true
+		e.mu.Unlock()
+
+		select {
+		case e.ptrQueue <- ip:
+		default:
+			// 队列已满，下次再试
+			e.mu.Lock()
+			delete(e.ptrPending, ip)
+			e.mu.Unlock()
+		}
+	}
+
+	return entry.name
+}
+
+// ptrWorker 后台 PTR 反查
+func (e *Enricher) ptrWorker() {
+	ttl := time.Duration(e.cfg.PTRCacheTTL) * time.Second
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case ip := <-e.ptrQueue:
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+			cancel()
+
+			name := ""
+			if err == nil && len(names) > 0 {
+				name = strings.TrimSuffix(names[0], ".")
+			}
+
+			e.mu.Lock()
+			e.ptrCache[ip] = ptrEntry{name: name, expiresAt: time.Now().Add(ttl)}
+			delete(e.ptrPending, ip)
+			e.mu.Unlock()
+		}
+	}
+}
+
+// Close 释放资源
+func (e *Enricher) Close() {
+	close(e.stopCh)
+	if e.countryDB != nil {
+		e.countryDB.Close()
+	}
+	if e.asnDB != nil {
+		e.asnDB.Close()
+	}
+}
+
+func clampPrefix(prefix, max, def int) int {
+	if prefix <= 0 || prefix > max {
+		return def
+	}
+	return prefix
+}
@@ -0,0 +1,289 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/netip"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"smartdns-log-agent/models"
+)
+
+// Rules 发送前丢弃或采样日志的规则，由管理端下发
+type Rules struct {
+	IgnoreSuffixes []string     `json:"ignore_suffixes"` // 按域名后缀忽略，如 in-addr.arpa
+	IgnorePatterns []string     `json:"ignore_patterns"` // 按正则忽略，匹配小写域名
+	IgnoreClients  []string     `json:"ignore_clients"`  // 忽略的客户端 IP 或网段，如健康检查来源
+	SampleRules    []SampleRule `json:"sample_rules"`    // 按域名后缀采样
+}
+
+// SampleRule 匹配后缀的域名只保留 Rate 比例的日志，后缀为 * 时作用于未匹配其他规则的域名
+type SampleRule struct {
+	Suffix string  `json:"suffix"`
+	Rate   float64 `json:"rate"` // 保留比例，(0, 1]
+}
+
+// Stats 自 Agent 启动以来被丢弃的日志条数
+type Stats struct {
+	IgnoredBySuffix  int64 `json:"ignored_by_suffix"`
+	IgnoredByPattern int64 `json:"ignored_by_pattern"`
+	IgnoredByClient  int64 `json:"ignored_by_client"`
+	SampledOut       int64 `json:"sampled_out"`
+}
+
+// compiled 预处理后的规则，更新时整体替换
+type compiled struct {
+	rules    Rules
+	suffixes []string
+	patterns []*regexp.Regexp
+	clients  []netip.Prefix
+	samples  []SampleRule // 按后缀长度降序，优先匹配更具体的后缀
+	fallback float64      // * 规则的保留比例，为 0 时不采样
+}
+
+// Filter 在日志解析后、富化和发送前过滤，减少 PTR 风暴、健康检查等噪音对存储的占用
+type Filter struct {
+	path    string
+	current atomic.Pointer[compiled]
+	saveMu  sync.Mutex
+
+	ignoredBySuffix  atomic.Int64
+	ignoredByPattern atomic.Int64
+	ignoredByClient  atomic.Int64
+	sampledOut       atomic.Int64
+}
+
+// New 创建过滤器，从 path 加载上次下发的规则，文件不存在时不过滤
+func New(path string) *Filter {
+	f := &Filter{path: path}
+	f.current.Store(&compiled{})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("⚠️ 读取过滤规则失败: %v", err)
+		}
+		return f
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		log.Printf("⚠️ 解析过滤规则失败: %v", err)
+		return f
+	}
+	c, err := compile(rules)
+	if err != nil {
+		log.Printf("⚠️ 过滤规则无效: %v", err)
+		return f
+	}
+	f.current.Store(c)
+	log.Printf("🧹 已加载日志过滤规则: %s", path)
+	return f
+}
+
+// Rules 当前生效的规则
+func (f *Filter) Rules() Rules {
+	return f.current.Load().rules
+}
+
+// Update 校验并替换规则，保存到本地，Agent 重启后继续生效
+func (f *Filter) Update(rules Rules) error {
+	c, err := compile(rules)
+	if err != nil {
+		return err
+	}
+
+	f.saveMu.Lock()
+	defer f.saveMu.Unlock()
+
+	data, err := json.MarshalIndent(c.rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化过滤规则失败: %w", err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("保存过滤规则失败: %w", err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("保存过滤规则失败: %w", err)
+	}
+
+	f.current.Store(c)
+	return nil
+}
+
+// Stats 丢弃统计
+func (f *Filter) Stats() Stats {
+	return Stats{
+		IgnoredBySuffix:  f.ignoredBySuffix.Load(),
+		IgnoredByPattern: f.ignoredByPattern.Load(),
+		IgnoredByClient:  f.ignoredByClient.Load(),
+		SampledOut:       f.sampledOut.Load(),
+	}
+}
+
+// Keep 日志是否需要发送
+//
+// 采样按日志内容哈希决定，同一行日志补读时得到相同结果，批次内容不变才能被 ClickHouse 去重
+func (f *Filter) Keep(record *models.DNSLogRecord) bool {
+	c := f.current.Load()
+	if c.empty() {
+		return true
+	}
+
+	domain := normalizeDomain(record.Domain)
+	for _, suffix := range c.suffixes {
+		if hasSuffix(domain, suffix) {
+			f.ignoredBySuffix.Add(1)
+			return false
+		}
+	}
+	for _, pattern := range c.patterns {
+		if pattern.MatchString(domain) {
+			f.ignoredByPattern.Add(1)
+			return false
+		}
+	}
+	if len(c.clients) > 0 {
+		if addr, err := netip.ParseAddr(record.ClientIP); err == nil {
+			addr = addr.Unmap()
+			for _, prefix := range c.clients {
+				if prefix.Contains(addr) {
+					f.ignoredByClient.Add(1)
+					return false
+				}
+			}
+		}
+	}
+
+	rate := c.sampleRate(domain)
+	if rate > 0 && rate < 1 && sampleValue(record) >= rate {
+		f.sampledOut.Add(1)
+		return false
+	}
+	return true
+}
+
+func (c *compiled) empty() bool {
+	return len(c.suffixes) == 0 && len(c.patterns) == 0 && len(c.clients) == 0 &&
+		len(c.samples) == 0 && c.fallback == 0
+}
+
+// sampleRate 域名适用的保留比例，为 0 时不采样
+func (c *compiled) sampleRate(domain string) float64 {
+	for _, rule := range c.samples {
+		if hasSuffix(domain, rule.Suffix) {
+			return rule.Rate
+		}
+	}
+	return c.fallback
+}
+
+// sampleValue 将日志映射到 [0, 1) 的确定值
+func sampleValue(record *models.DNSLogRecord) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatInt(record.Timestamp.UnixNano(), 10)))
+	h.Write([]byte(record.ClientIP))
+	h.Write([]byte(record.Domain))
+	h.Write([]byte(strconv.Itoa(int(record.QueryType))))
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
+
+// compile 校验并预处理规则
+func compile(rules Rules) (*compiled, error) {
+	c := &compiled{}
+
+	for _, suffix := range rules.IgnoreSuffixes {
+		if suffix = normalizeSuffix(suffix); suffix != "" {
+			c.suffixes = append(c.suffixes, suffix)
+		}
+	}
+
+	for _, expr := range rules.IgnorePatterns {
+		if expr = strings.TrimSpace(expr); expr == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("正则表达式无效 %q: %w", expr, err)
+		}
+		c.patterns = append(c.patterns, pattern)
+		c.rules.IgnorePatterns = append(c.rules.IgnorePatterns, expr)
+	}
+
+	for _, client := range rules.IgnoreClients {
+		if client = strings.TrimSpace(client); client == "" {
+			continue
+		}
+		prefix, err := parsePrefix(client)
+		if err != nil {
+			return nil, fmt.Errorf("客户端地址无效 %q", client)
+		}
+		c.clients = append(c.clients, prefix)
+		c.rules.IgnoreClients = append(c.rules.IgnoreClients, client)
+	}
+
+	for _, rule := range rules.SampleRules {
+		if rule.Rate <= 0 || rule.Rate > 1 {
+			return nil, fmt.Errorf("采样比例必须在 (0, 1] 之间: %s=%v", rule.Suffix, rule.Rate)
+		}
+		suffix := strings.TrimSpace(rule.Suffix)
+		if suffix == "*" || suffix == "" {
+			c.fallback = rule.Rate
+			c.rules.SampleRules = append(c.rules.SampleRules, SampleRule{Suffix: "*", Rate: rule.Rate})
+			continue
+		}
+		rule.Suffix = normalizeSuffix(suffix)
+		c.samples = append(c.samples, rule)
+		c.rules.SampleRules = append(c.rules.SampleRules, rule)
+	}
+	sort.SliceStable(c.samples, func(i, j int) bool {
+		return len(c.samples[i].Suffix) > len(c.samples[j].Suffix)
+	})
+
+	c.rules.IgnoreSuffixes = c.suffixes
+	return c, nil
+}
+
+// parsePrefix 解析 IP 或 CIDR 网段，单个 IP 视为主机网段
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
+
+// normalizeSuffix 去掉 *. 和首尾的点，如 *.in-addr.arpa. -> in-addr.arpa
+func normalizeSuffix(suffix string) string {
+	suffix = strings.TrimPrefix(strings.TrimSpace(suffix), "*")
+	return strings.Trim(strings.ToLower(suffix), ".")
+}
+
+// hasSuffix 域名等于后缀或以 .后缀 结尾
+func hasSuffix(domain, suffix string) bool {
+	if !strings.HasSuffix(domain, suffix) {
+		return false
+	}
+	return len(domain) == len(suffix) || domain[len(domain)-len(suffix)-1] == '.'
+}
@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"net/http"
+	"net/url"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"smartdns-log-agent/collector"
 	"smartdns-log-agent/config"
+	"smartdns-log-agent/filter"
+	"smartdns-log-agent/prober"
 	"smartdns-log-agent/sender"
 )
 
@@ -16,15 +20,17 @@ import (
 type AgentHandler struct {
 	cfg             *config.Config
 	collector       *collector.LogCollector
-	sender          *sender.ClickHouseSender
+	sender          sender.LogSender
 	isRunning       bool
 	startTime       time.Time
 	getCollector    func() *collector.LogCollector
-	getSender       func() *sender.ClickHouseSender
+	getSender       func() sender.LogSender
 	getRunning      func() bool
 	startCollection func() error
 	stopCollection  func()
 	getAgentLogs    func(int) ([]string, error)
+	prober          *prober.Prober
+	filter          *filter.Filter
 }
 
 // AgentStatus API 状态响应
@@ -50,6 +56,44 @@ type AgentStats struct {
 	LastSentTime   string  `json:"last_sent_time"`
 	SendRate       float64 `json:"send_rate"`
 	BufferSize     int     `json:"buffer_size"`
+
+	// 解析指标
+	FastPathHits   int64 `json:"fast_path_hits"`
+	RegexFallbacks int64 `json:"regex_fallbacks"`
+	ParseFailures  int64 `json:"parse_failures"`
+
+	// 写入指标
+	RetryRows        int     `json:"retry_rows"` // 等待重发的日志条数
+	SentBatches      int64   `json:"sent_batches"`
+	FailedBatches    int64   `json:"failed_batches"`
+	BatchRetries     int64   `json:"batch_retries"`
+	AvgBatchLatency  float64 `json:"avg_batch_latency_ms"`
+	LastBatchLatency float64 `json:"last_batch_latency_ms"`
+	MaxBatchLatency  float64 `json:"max_batch_latency_ms"`
+
+	Outputs map[string]sender.SenderStats `json:"outputs,omitempty"` // 附加输出（Kafka、Loki、Elasticsearch）的写入指标
+
+	// 发送队列指标
+	QueueDepth        int     `json:"queue_depth"`        // 等待写入的批次数
+	QueueCapacity     int     `json:"queue_capacity"`     // 队列容量
+	InflightBatches   int64   `json:"inflight_batches"`   // 正在写入的批次数
+	QueuedRows        int64   `json:"queued_rows"`        // 队列中和正在写入的日志条数
+	BackpressureWaits int64   `json:"backpressure_waits"` // 队列已满、暂停读取日志的次数
+	BackpressureMs    float64 `json:"backpressure_ms"`    // 暂停读取的累计时长（毫秒）
+
+	// 过滤指标
+	IgnoredBySuffix  int64 `json:"ignored_by_suffix"`
+	IgnoredByPattern int64 `json:"ignored_by_pattern"`
+	IgnoredByClient  int64 `json:"ignored_by_client"`
+	SampledOut       int64 `json:"sampled_out"` // 被采样丢弃的日志条数
+}
+
+// ConfigUpdate 运行时可调整的批量写入参数，未传的字段保持不变
+type ConfigUpdate struct {
+	BatchSize          *int    `json:"batch_size"`
+	FlushIntervalMs    *int    `json:"flush_interval_ms"`
+	MaxInflightBatches *int    `json:"max_inflight_batches"`
+	Compression        *string `json:"compression"` // 仅 ClickHouse 存储
 }
 
 const Version = "1.0.0"
@@ -59,11 +103,13 @@ func NewAgentHandler(
 	cfg *config.Config,
 	startTime time.Time,
 	getCollector func() *collector.LogCollector,
-	getSender func() *sender.ClickHouseSender,
+	getSender func() sender.LogSender,
 	getRunning func() bool,
 	startCollection func() error,
 	stopCollection func(),
 	getAgentLogs func(int) ([]string, error),
+	probe *prober.Prober,
+	logFilter *filter.Filter,
 ) *AgentHandler {
 	return &AgentHandler{
 		cfg:             cfg,
@@ -74,6 +120,8 @@ func NewAgentHandler(
 		startCollection: startCollection,
 		stopCollection:  stopCollection,
 		getAgentLogs:    getAgentLogs,
+		prober:          probe,
+		filter:          logFilter,
 	}
 }
 
@@ -188,6 +236,37 @@ func (h *AgentHandler) GetStats(c *gin.Context) {
 		if uptime := time.Since(h.startTime).Seconds(); uptime > 0 {
 			stats.SendRate = float64(sentRecords) / uptime
 		}
+
+		parserStats := collector.GetParserStats()
+		stats.FastPathHits = parserStats.FastPathHits
+		stats.RegexFallbacks = parserStats.RegexFallbacks
+		stats.ParseFailures = parserStats.Failures
+		stats.RetryRows = collector.GetRetryRows()
+
+		queueStats := collector.GetQueueStats()
+		stats.QueueDepth = queueStats.QueueDepth
+		stats.QueueCapacity = queueStats.QueueCapacity
+		stats.InflightBatches = queueStats.InflightBatches
+		stats.QueuedRows = queueStats.QueuedRows
+		stats.BackpressureWaits = queueStats.BackpressureWaits
+		stats.BackpressureMs = queueStats.BackpressureMs
+	}
+
+	filterStats := h.filter.Stats()
+	stats.IgnoredBySuffix = filterStats.IgnoredBySuffix
+	stats.IgnoredByPattern = filterStats.IgnoredByPattern
+	stats.IgnoredByClient = filterStats.IgnoredByClient
+	stats.SampledOut = filterStats.SampledOut
+
+	if chSender := h.getSender(); chSender != nil {
+		senderStats := chSender.Stats()
+		stats.SentBatches = senderStats.SentBatches
+		stats.FailedBatches = senderStats.FailedBatches
+		stats.BatchRetries = senderStats.Retries
+		stats.AvgBatchLatency = senderStats.AvgBatchLatency
+		stats.LastBatchLatency = senderStats.LastBatchLatency
+		stats.MaxBatchLatency = senderStats.MaxBatchLatency
+		stats.Outputs = senderStats.Outputs
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -196,6 +275,122 @@ func (h *AgentHandler) GetStats(c *gin.Context) {
 	})
 }
 
+// Reread 补读管理端检测到的缺失区间
+// POST /api/v1/reread
+func (h *AgentHandler) Reread(c *gin.Context) {
+	var req collector.RereadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	logCollector := h.getCollector()
+	if logCollector == nil || !h.getRunning() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "日志收集未运行",
+		})
+		return
+	}
+
+	rows, err := logCollector.Reread(req)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "补读失败: " + err.Error(),
+			"data":    gin.H{"rows": rows},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "补读完成",
+		"data":    gin.H{"rows": rows},
+	})
+}
+
+// GetProbeResults 获取解析探测的聚合结果，管理端按上次拉取到的周期增量获取
+// GET /api/v1/probe/results?since=<unix>
+func (h *AgentHandler) GetProbeResults(c *gin.Context) {
+	since, _ := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"settings":    h.prober.Settings(),
+			"bucket_size": int(prober.BucketSize / time.Second),
+			"buckets":     h.prober.Buckets(time.Unix(since, 0)),
+		},
+	})
+}
+
+// UpdateProbeConfig 更新探测域名和间隔，仅在内存中生效，重启后恢复为环境变量配置
+// PUT /api/v1/probe/config
+func (h *AgentHandler) UpdateProbeConfig(c *gin.Context) {
+	var req struct {
+		Domains     []string `json:"domains"`
+		IntervalSec int      `json:"interval_sec"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	h.prober.Update(req.Domains, time.Duration(req.IntervalSec)*time.Second)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "探测配置已更新",
+		"data":    h.prober.Settings(),
+	})
+}
+
+// GetFilter 获取当前生效的过滤规则和丢弃统计
+// GET /api/v1/filter
+func (h *AgentHandler) GetFilter(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"rules": h.filter.Rules(),
+			"stats": h.filter.Stats(),
+		},
+	})
+}
+
+// UpdateFilter 替换过滤规则，立即生效并保存到本地，Agent 重启后继续使用
+// PUT /api/v1/filter
+func (h *AgentHandler) UpdateFilter(c *gin.Context) {
+	var rules filter.Rules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.filter.Update(rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "过滤规则已更新",
+		"data":    h.filter.Rules(),
+	})
+}
+
 func (h *AgentHandler) GetLogs(c *gin.Context) {
 	lines, _ := strconv.Atoi(c.DefaultQuery("lines", "100"))
 	if lines <= 0 || lines > 1000 {
@@ -222,16 +417,48 @@ func (h *AgentHandler) GetLogs(c *gin.Context) {
 // GetConfig 获取配置信息
 func (h *AgentHandler) GetConfig(c *gin.Context) {
 	config := map[string]interface{}{
-		"node_id":        h.cfg.NodeID,
-		"node_name":      h.cfg.NodeName,
-		"log_file":       h.cfg.LogFile,
-		"batch_size":     h.cfg.BatchSize,
-		"flush_interval": h.cfg.FlushInterval.Seconds(),
+		"node_id":              h.cfg.NodeID,
+		"node_name":            h.cfg.NodeName,
+		"log_file":             h.cfg.LogFile,
+		"batch_size":           h.cfg.BatchSize,
+		"flush_interval":       h.cfg.FlushInterval.Seconds(),
+		"flush_interval_ms":    h.cfg.FlushInterval.Milliseconds(),
+		"max_inflight_batches": h.cfg.MaxInflightBatches,
+		"storage_type":         h.cfg.StorageType,
 		"clickhouse": map[string]interface{}{
-			"host":     h.cfg.ClickHouse.Host,
-			"port":     h.cfg.ClickHouse.Port,
-			"database": h.cfg.ClickHouse.Database,
-			"user":     h.cfg.ClickHouse.Username,
+			"host":        h.cfg.ClickHouse.Host,
+			"port":        h.cfg.ClickHouse.Port,
+			"database":    h.cfg.ClickHouse.Database,
+			"user":        h.cfg.ClickHouse.Username,
+			"protocol":    h.cfg.ClickHouse.Protocol,
+			"compression": h.cfg.ClickHouse.Compression,
+		},
+		"privacy": map[string]interface{}{
+			"mode":      h.cfg.Privacy.Mode,
+			"v4_prefix": h.cfg.Privacy.V4Prefix,
+			"v6_prefix": h.cfg.Privacy.V6Prefix,
+		},
+		"postgres": map[string]interface{}{
+			"host":     h.cfg.Postgres.Host,
+			"port":     h.cfg.Postgres.Port,
+			"database": h.cfg.Postgres.Database,
+			"user":     h.cfg.Postgres.Username,
+		},
+		"ingest": map[string]interface{}{
+			"url": h.cfg.Ingest.URL,
+		},
+		"kafka": map[string]interface{}{
+			"brokers": h.cfg.Kafka.Brokers,
+			"topic":   h.cfg.Kafka.TopicName(),
+			"format":  h.cfg.Kafka.Format,
+		},
+		"loki": map[string]interface{}{
+			"url":    redactURL(h.cfg.Loki.URL),
+			"labels": h.cfg.Loki.Labels,
+		},
+		"elasticsearch": map[string]interface{}{
+			"url":   redactURL(h.cfg.Elasticsearch.URL),
+			"index": h.cfg.Elasticsearch.Index,
 		},
 	}
 
@@ -241,9 +468,12 @@ func (h *AgentHandler) GetConfig(c *gin.Context) {
 	})
 }
 
-// UpdateConfig 更新配置
+// UpdateConfig 调整批量大小、刷新间隔、并发写入批次数和压缩算法
+// PUT /api/v1/config
+//
+// 正在收集时会重启收集使配置生效，读取位置已保存，不会丢失或重复日志；仅在内存中生效，重启 Agent 后恢复为环境变量配置
 func (h *AgentHandler) UpdateConfig(c *gin.Context) {
-	var req map[string]interface{}
+	var req ConfigUpdate
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -252,9 +482,59 @@ func (h *AgentHandler) UpdateConfig(c *gin.Context) {
 		return
 	}
 
+	updated := *h.cfg
+	if req.BatchSize != nil {
+		updated.BatchSize = *req.BatchSize
+	}
+	if req.FlushIntervalMs != nil {
+		updated.FlushInterval = time.Duration(*req.FlushIntervalMs) * time.Millisecond
+	}
+	if req.MaxInflightBatches != nil {
+		updated.MaxInflightBatches = *req.MaxInflightBatches
+	}
+	if req.Compression != nil {
+		updated.ClickHouse.Compression = strings.ToLower(*req.Compression)
+	}
+	if err := updated.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	running := h.getRunning()
+	if running {
+		h.stopCollection()
+	}
+
+	h.cfg.BatchSize = updated.BatchSize
+	h.cfg.FlushInterval = updated.FlushInterval
+	h.cfg.MaxInflightBatches = updated.MaxInflightBatches
+	h.cfg.ClickHouse.Compression = updated.ClickHouse.Compression
+
+	settings := gin.H{
+		"batch_size":           h.cfg.BatchSize,
+		"flush_interval_ms":    h.cfg.FlushInterval.Milliseconds(),
+		"max_inflight_batches": h.cfg.MaxInflightBatches,
+		"compression":          h.cfg.ClickHouse.Compression,
+	}
+
+	if running {
+		if err := h.startCollection(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "配置已更新，但重启日志收集失败: " + err.Error(),
+				"data":    settings,
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "配置更新成功，需要重启生效",
+		"message": "配置已更新",
+		"data":    settings,
 	})
 }
 
@@ -272,3 +552,12 @@ func (h *AgentHandler) HealthCheck(c *gin.Context) {
 		"data":    health,
 	})
 }
+
+// redactURL 隐藏地址中的密码
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
@@ -7,16 +7,19 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"os/signal"
+	"path/filepath"
 	"sync"
-	"syscall"
 	"time"
 
 	"smartdns-log-agent/collector"
 	"smartdns-log-agent/config"
+	"smartdns-log-agent/control"
+	"smartdns-log-agent/filter"
 	"smartdns-log-agent/handlers"
 	"smartdns-log-agent/logger"
+	"smartdns-log-agent/prober"
 	"smartdns-log-agent/sender"
+	"smartdns-log-agent/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,7 +29,7 @@ const Version = "0.0.5"
 type AgentServer struct {
 	cfg        *config.Config
 	collector  *collector.LogCollector
-	sender     *sender.ClickHouseSender
+	sender     sender.LogSender
 	httpServer *http.Server
 	ctx        context.Context
 	cancel     context.CancelFunc
@@ -35,12 +38,19 @@ type AgentServer struct {
 	startTime  time.Time
 	handler    *handlers.AgentHandler
 	logger     *logger.Logger // 新增日志管理器
+	prober     *prober.Prober // 本地解析探测
+	filter     *filter.Filter // 发送前的日志过滤和采样
+	router     http.Handler   // API 路由，HTTP 服务和控制通道共用
+
+	collectCancel context.CancelFunc // 停止当前收集器
+	collectDone   chan struct{}      // 当前收集器退出（已保存读取位置）后关闭
 }
 
 func main() {
 	// 命令行参数处理
 	var showVersion = flag.Bool("version", false, "显示版本信息")
 	var showHelp = flag.Bool("help", false, "显示帮助信息")
+	var configFile = flag.String("config", config.DefaultConfigFile(), "KEY=VALUE 格式的配置文件，已设置的环境变量优先")
 	flag.Parse()
 
 	if *showVersion {
@@ -55,6 +65,11 @@ func main() {
 
 	log.Printf("🚀 SmartDNS Log Agent v%s 启动中...", Version)
 
+	// 读取配置文件，Windows 服务没有 EnvironmentFile，配置只能从文件读取
+	if err := config.LoadEnvFile(*configFile); err != nil && !os.IsNotExist(err) {
+		log.Printf("⚠️ 读取配置文件 %s 失败: %v", *configFile, err)
+	}
+
 	// 加载配置
 	cfg, err := config.Load()
 	if err != nil {
@@ -82,6 +97,8 @@ func main() {
 		cancel:    cancel,
 		startTime: time.Now(),
 		logger:    loggerInstance,
+		prober:    prober.NewProber(cfg.Probe),
+		filter:    filter.New(filepath.Join(collector.DataDir(), fmt.Sprintf("filter-node-%d.json", cfg.NodeID))),
 	}
 
 	// 创建 API 处理器
@@ -94,11 +111,26 @@ func main() {
 		agent.startLogCollection,
 		agent.stopLogCollection,
 		agent.getAgentLogs, // 新增获取日志方法
+		agent.prober,
+		agent.filter,
 	)
 
 	// 启动 HTTP API 服务器
+	agent.router = agent.newRouter()
 	go agent.startHTTPServer()
 
+	// 连接管理端控制通道，节点位于 NAT 之后时管理端通过该通道下发命令
+	if cfg.Control.URL != "" {
+		if cfg.Control.Token == "" {
+			log.Println("⚠️ 已设置 CONTROL_URL 但未设置 CONTROL_TOKEN，不连接控制通道")
+		} else {
+			go control.NewClient(cfg.Control, cfg.NodeID, Version, agent.router).Run(ctx)
+		}
+	}
+
+	// 启动解析探测，未配置域名时等待管理端下发
+	go agent.prober.Start(ctx)
+
 	// 启动日志收集
 	//if err := agent.startLogCollection(); err != nil {
 	//	log.Printf("❌ 启动日志收集失败: %v", err)
@@ -106,13 +138,14 @@ func main() {
 
 	log.Println("✅ Agent 启动成功")
 
-	// 等待退出信号
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
-
-	log.Println("🛑 正在优雅关闭...")
-	agent.shutdown()
+	// 等待退出信号，Windows 上作为服务运行时等待服务控制管理器的停止请求
+	err = service.WaitForShutdown("smartdns-log-agent", func() {
+		log.Println("🛑 正在优雅关闭...")
+		agent.shutdown()
+	})
+	if err != nil {
+		log.Fatal("❌ 运行 Windows 服务失败:", err)
+	}
 }
 
 // getAgentLogs 获取 Agent 日志
@@ -131,19 +164,61 @@ func printHelp() {
 	fmt.Println("\n选项:")
 	fmt.Println("  --version    显示版本信息")
 	fmt.Println("  --help       显示帮助信息")
+	fmt.Printf("  --config <文件>            KEY=VALUE 格式的配置文件 (默认: %s)\n", config.DefaultConfigFile())
 	fmt.Println("\n环境变量配置:")
 	fmt.Println("  NODE_ID                  节点ID")
 	fmt.Println("  NODE_NAME                节点名称")
 	fmt.Println("  LOG_FILE                 SmartDNS日志文件路径")
+	fmt.Println("  LOG_STORAGE_TYPE         日志存储类型 clickhouse / timescaledb (默认: clickhouse)")
 	fmt.Println("  CLICKHOUSE_HOST          ClickHouse 主机")
 	fmt.Println("  CLICKHOUSE_PORT          ClickHouse 端口")
 	fmt.Println("  CLICKHOUSE_DB            ClickHouse 数据库")
 	fmt.Println("  CLICKHOUSE_USER          ClickHouse 用户")
 	fmt.Println("  CLICKHOUSE_PASSWORD      ClickHouse 密码")
+	fmt.Println("  CLICKHOUSE_PROTOCOL      连接协议 native/http/https (默认: native)")
+	fmt.Println("  CLICKHOUSE_SECURE        启用 TLS，native 使用安全端口 9440 (默认: false)")
+	fmt.Println("  CLICKHOUSE_CA_CERT       自定义 CA 证书路径")
+	fmt.Println("  CLICKHOUSE_TLS_SERVER_NAME     TLS 校验使用的主机名 (默认: CLICKHOUSE_HOST)")
+	fmt.Println("  CLICKHOUSE_TLS_SKIP_VERIFY     跳过证书校验，仅用于测试 (默认: false)")
+	fmt.Println("  CLICKHOUSE_MAX_OPEN_CONNS      ClickHouse 最大连接数 (默认: 4)")
+	fmt.Println("  CLICKHOUSE_MAX_IDLE_CONNS      ClickHouse 最大空闲连接数 (默认: 2)")
+	fmt.Println("  CLICKHOUSE_INSERT_TIMEOUT_SEC  批量写入超时 (默认: 30)")
+	fmt.Println("  CLICKHOUSE_MAX_RETRIES         写入失败重试次数 (默认: 3)")
+	fmt.Println("  CLICKHOUSE_RETRY_BACKOFF_MS    首次重试等待毫秒数，之后逐次翻倍 (默认: 500)")
+	fmt.Println("  POSTGRES_HOST            PostgreSQL 主机 (默认: localhost)")
+	fmt.Println("  POSTGRES_PORT            PostgreSQL 端口 (默认: 5432)")
+	fmt.Println("  POSTGRES_DB              PostgreSQL 数据库 (默认: smartdns_logs)")
+	fmt.Println("  POSTGRES_USER            PostgreSQL 用户")
+	fmt.Println("  POSTGRES_PASSWORD        PostgreSQL 密码")
+	fmt.Println("  POSTGRES_SSLMODE         PostgreSQL SSL 模式 (默认: disable)")
+	fmt.Println("  POSTGRES_MAX_CONNS       PostgreSQL 最大连接数 (默认: 4)")
+	fmt.Println("  BATCH_SIZE               每批写入条数 (默认: 1000)")
+	fmt.Println("  FLUSH_INTERVAL_SEC       刷新间隔秒数 (默认: 2)")
+	fmt.Println("  FLUSH_INTERVAL_MS        刷新间隔毫秒数，设置后优先于 FLUSH_INTERVAL_SEC")
+	fmt.Println("  MAX_INFLIGHT_BATCHES     同时写入的批次数，队列满时暂停读取日志 (默认: 1)")
+	fmt.Println("  CLICKHOUSE_COMPRESSION   写入压缩算法 none/lz4/zstd，HTTP 另支持 gzip/deflate/br (默认: lz4)")
+	fmt.Println("  CLIENT_IP_PRIVACY        客户端 IP 脱敏: off/truncate/hash (默认: off)")
+	fmt.Println("  CLIENT_IP_PRIVACY_V4_PREFIX    truncate 保留的 IPv4 前缀 (默认: 24)")
+	fmt.Println("  CLIENT_IP_PRIVACY_V6_PREFIX    truncate 保留的 IPv6 前缀 (默认: 56)")
+	fmt.Println("  CLIENT_IP_HASH_KEY       hash 模式的 HMAC 密钥")
 	fmt.Println("  AGENT_API_PORT           API 端口 (默认: 8888)")
-	fmt.Println("  AGENT_LOG_DIR            Agent日志目录 (默认: /var/log/smartdns-agent)")
+	fmt.Println("  AGENT_LOG_DIR            Agent日志目录 (Linux 默认: /var/log/smartdns-agent)")
 	fmt.Println("  AGENT_LOG_MAX_DAYS       日志保留天数 (默认: 7)")
 	fmt.Println("  AGENT_LOG_ENABLE_FILE    是否启用文件日志 (默认: true)")
+	fmt.Println("  GEOIP_COUNTRY_DB         MaxMind Country/City 数据库路径")
+	fmt.Println("  GEOIP_ASN_DB             MaxMind ASN 数据库路径")
+	fmt.Println("  ENABLE_PTR_LOOKUP        是否反查客户端 PTR (默认: false)")
+	fmt.Println("  PTR_CACHE_TTL_SEC        PTR 缓存时间 (默认: 3600)")
+	fmt.Println("  CLIENT_SUBNET_V4_PREFIX  IPv4 子网前缀 (默认: 24)")
+	fmt.Println("  CLIENT_SUBNET_V6_PREFIX  IPv6 子网前缀 (默认: 64)")
+	fmt.Println("  PROBE_DOMAINS            解析探测域名，逗号分隔 (为空时等待管理端下发)")
+	fmt.Println("  PROBE_SERVER             探测使用的本地 SmartDNS 地址 (默认: 127.0.0.1:53)")
+	fmt.Println("  PROBE_INTERVAL_SEC       探测间隔秒数 (默认: 30)")
+	fmt.Println("  PROBE_TIMEOUT_MS         单次解析超时毫秒数 (默认: 2000)")
+	fmt.Println("  CONTROL_URL              管理端控制通道地址，如 wss://manager/api/v1/agent/control")
+	fmt.Println("  CONTROL_TOKEN            节点控制令牌，在管理端节点详情中生成")
+	fmt.Println("  CONTROL_TLS_SKIP_VERIFY  控制通道跳过证书校验，仅用于测试 (默认: false)")
+	fmt.Println("  CONTROL_RECONNECT_MAX_SEC  断线重连最长等待秒数 (默认: 60)")
 }
 
 func (a *AgentServer) shutdown() {
@@ -174,7 +249,7 @@ func (a *AgentServer) getCollector() *collector.LogCollector {
 	return a.collector
 }
 
-func (a *AgentServer) getSender() *sender.ClickHouseSender {
+func (a *AgentServer) getSender() sender.LogSender {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 	return a.sender
@@ -186,7 +261,8 @@ func (a *AgentServer) getRunning() bool {
 	return a.isRunning
 }
 
-func (a *AgentServer) startHTTPServer() {
+// newRouter 创建 API 路由
+func (a *AgentServer) newRouter() http.Handler {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(gin.Recovery())
@@ -203,8 +279,16 @@ func (a *AgentServer) startHTTPServer() {
 		api.GET("/config", a.handler.GetConfig)
 		api.PUT("/config", a.handler.UpdateConfig)
 		api.GET("/health", a.handler.HealthCheck)
+		api.POST("/reread", a.handler.Reread)
+		api.GET("/probe/results", a.handler.GetProbeResults)
+		api.PUT("/probe/config", a.handler.UpdateProbeConfig)
+		api.GET("/filter", a.handler.GetFilter)
+		api.PUT("/filter", a.handler.UpdateFilter)
 	}
+	return router
+}
 
+func (a *AgentServer) startHTTPServer() {
 	// 获取监听端口
 	port := os.Getenv("AGENT_API_PORT")
 	if port == "" {
@@ -213,7 +297,7 @@ func (a *AgentServer) startHTTPServer() {
 
 	a.httpServer = &http.Server{
 		Addr:    ":" + port,
-		Handler: router,
+		Handler: a.router,
 	}
 
 	log.Printf("🌐 HTTP API 服务器启动在端口: %s", port)
@@ -230,23 +314,31 @@ func (a *AgentServer) startLogCollection() error {
 		return nil
 	}
 
-	// 创建 ClickHouse 发送器
-	chSender, err := sender.NewClickHouseSender(a.cfg.ClickHouse)
+	// 按存储类型创建日志发送器
+	logSender, err := sender.New(a.cfg)
 	if err != nil {
 		return err
 	}
-	a.sender = chSender
+	a.sender = logSender
 
 	// 创建日志收集器
-	logCollector, err := collector.NewLogCollector(a.cfg, chSender)
+	logCollector, err := collector.NewLogCollector(a.cfg, logSender, a.filter)
 	if err != nil {
-		chSender.Close()
+		logSender.Close()
+		a.sender = nil
 		return err
 	}
 	a.collector = logCollector
 
-	// 启动收集器
-	go a.collector.Start(a.ctx)
+	// 启动收集器，每次启动使用独立的 context，停止时可单独取消
+	collectCtx, collectCancel := context.WithCancel(a.ctx)
+	done := make(chan struct{})
+	a.collectCancel = collectCancel
+	a.collectDone = done
+	go func(c *collector.LogCollector) {
+		defer close(done)
+		c.Start(collectCtx)
+	}(a.collector)
 
 	a.isRunning = true
 	log.Println("✅ 日志收集已启动")
@@ -261,6 +353,18 @@ func (a *AgentServer) stopLogCollection() {
 		return
 	}
 
+	// 等待收集器刷新缓冲并保存读取位置后再关闭发送器，重启后从该位置继续读取
+	if a.collectCancel != nil {
+		a.collectCancel()
+		select {
+		case <-a.collectDone:
+		case <-time.After(30 * time.Second):
+			log.Println("⚠️ 等待日志收集器退出超时")
+		}
+		a.collectCancel = nil
+		a.collectDone = nil
+	}
+
 	if a.sender != nil {
 		a.sender.Close()
 		a.sender = nil
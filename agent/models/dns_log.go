@@ -4,6 +4,9 @@ import (
 	"time"
 )
 
+// RcodeNoError 日志未输出响应码、但有应答时记录的响应码
+const RcodeNoError = "NOERROR"
+
 // DNSLogRecord DNS查询日志记录
 type DNSLogRecord struct {
 	Timestamp   time.Time `json:"timestamp"`
@@ -13,9 +16,23 @@ type DNSLogRecord struct {
 	Domain      string    `json:"domain"`
 	QueryType   uint16    `json:"query_type"`
 	Group       string    `json:"group"`
+	Upstream    string    `json:"upstream"`     // 应答该查询的上游服务器，日志未输出时为空
+	Rcode       string    `json:"rcode"`        // 响应码（NOERROR、NXDOMAIN、SERVFAIL 等），无法判断时为空
+	CacheHit    bool      `json:"cache_hit"`    // 是否由缓存应答
+	MatchedRule string    `json:"matched_rule"` // 命中的规则，日志未输出时为空
 	TimeMs      uint32    `json:"time_ms"`
 	SpeedMs     float32   `json:"speed_ms"`
 	ResultCount uint8     `json:"result_count"`
 	ResultIPs   []string  `json:"result_ips"`
 	RawLog      string    `json:"raw_log"`
+
+	// 域名分类（来自域名集）
+	DomainCategory string `json:"domain_category"`
+
+	// 客户端富化信息
+	ClientSubnet  string `json:"client_subnet"`
+	ClientCountry string `json:"client_country"`
+	ClientASN     uint32 `json:"client_asn"`
+	ClientASOrg   string `json:"client_as_org"`
+	ClientPTR     string `json:"client_ptr"`
 }
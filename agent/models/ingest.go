@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// IngestBatch 已写入批次的确认记录，管理端据此检测缺失或乱序的批次
+type IngestBatch struct {
+	NodeID      uint32    `json:"node_id"`
+	FilePath    string    `json:"file_path"`
+	StreamID    string    `json:"stream_id"` // 文件标识（inode-起始时间），文件轮转或截断后变化
+	Seq         uint64    `json:"seq"`       // 同一文件内从 1 开始递增
+	StartOffset int64     `json:"start_offset"`
+	EndOffset   int64     `json:"end_offset"`
+	Rows        uint32    `json:"rows"`
+	Reread      bool      `json:"reread"` // 是否为补读的批次
+	SentAt      time.Time `json:"sent_at"`
+}
@@ -0,0 +1,87 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"strings"
+
+	"smartdns-log-agent/config"
+	"smartdns-log-agent/models"
+)
+
+// HashPrefix 哈希后客户端 IP 的前缀，管理端据此区分哈希值和原始 IP，算法须与管理端一致
+const HashPrefix = "anon-"
+
+// Anonymizer 在写入存储前对客户端 IP 脱敏
+type Anonymizer struct {
+	mode     string
+	v4Prefix int
+	v6Prefix int
+	key      []byte
+}
+
+// New 创建脱敏器，配置已在加载时校验
+func New(cfg config.PrivacyConfig) *Anonymizer {
+	return &Anonymizer{
+		mode:     cfg.Mode,
+		v4Prefix: cfg.V4Prefix,
+		v6Prefix: cfg.V6Prefix,
+		key:      []byte(cfg.HashKey),
+	}
+}
+
+// Enabled 是否需要脱敏
+func (a *Anonymizer) Enabled() bool {
+	return a.mode == config.PrivacyTruncate || a.mode == config.PrivacyHash
+}
+
+// Apply 脱敏日志记录：替换客户端 IP 及原始日志中的客户端 IP，清除 PTR，子网不细于脱敏前缀
+//
+// 须在富化之后调用，GeoIP 国家和 ASN 仍按原始 IP 查询
+func (a *Anonymizer) Apply(record *models.DNSLogRecord) {
+	if !a.Enabled() || record.ClientIP == "" {
+		return
+	}
+
+	addr, err := netip.ParseAddr(record.ClientIP)
+	if err != nil {
+		return
+	}
+	addr = addr.Unmap()
+
+	masked := a.mask(addr)
+	record.RawLog = strings.Replace(record.RawLog, record.ClientIP, masked, 1)
+	record.ClientIP = masked
+	record.ClientPTR = ""
+	record.ClientSubnet = a.coarsenSubnet(record.ClientSubnet)
+}
+
+func (a *Anonymizer) mask(addr netip.Addr) string {
+	if a.mode == config.PrivacyHash {
+		mac := hmac.New(sha256.New, a.key)
+		mac.Write([]byte(addr.String()))
+		return HashPrefix + hex.EncodeToString(mac.Sum(nil))[:16]
+	}
+	return netip.PrefixFrom(addr, a.bits(addr)).Masked().Addr().String()
+}
+
+// coarsenSubnet 子网前缀比脱敏前缀更长时截断到脱敏前缀
+func (a *Anonymizer) coarsenSubnet(subnet string) string {
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return subnet
+	}
+	if bits := a.bits(prefix.Addr()); prefix.Bits() > bits {
+		return netip.PrefixFrom(prefix.Addr(), bits).Masked().String()
+	}
+	return subnet
+}
+
+func (a *Anonymizer) bits(addr netip.Addr) int {
+	if addr.Is4() {
+		return a.v4Prefix
+	}
+	return a.v6Prefix
+}
@@ -0,0 +1,235 @@
+package prober
+
+import (
+	"context"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"smartdns-log-agent/config"
+)
+
+const (
+	// BucketSize 探测结果按 5 分钟聚合，减少管理端拉取和存储的数据量
+	BucketSize = 5 * time.Minute
+	// retention Agent 本地保留的聚合时长，管理端停止拉取期间的数据不会无限增长
+	retention = 24 * time.Hour
+	// minInterval 允许的最短探测间隔
+	minInterval = 5 * time.Second
+)
+
+// Bucket 单个域名在一个聚合周期内的探测结果
+type Bucket struct {
+	Start        time.Time `json:"start"`
+	Domain       string    `json:"domain"`
+	Probes       int       `json:"probes"`
+	Failures     int       `json:"failures"`
+	LatencySumMs float64   `json:"latency_sum_ms"` // 成功探测的延迟总和
+	MaxLatencyMs float64   `json:"max_latency_ms"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+type bucketKey struct {
+	start  int64
+	domain string
+}
+
+// Settings 当前生效的探测配置
+type Settings struct {
+	Domains     []string `json:"domains"`
+	Server      string   `json:"server"`
+	IntervalSec int      `json:"interval_sec"`
+	TimeoutMs   int      `json:"timeout_ms"`
+}
+
+// Prober 定期通过本地 SmartDNS 解析探测域名，记录解析失败和延迟
+type Prober struct {
+	server   string
+	timeout  time.Duration
+	domains  []string
+	interval time.Duration
+	buckets  map[bucketKey]*Bucket
+	mu       sync.Mutex
+	updated  chan struct{}
+}
+
+// NewProber 创建探测器
+func NewProber(cfg config.ProbeConfig) *Prober {
+	interval := cfg.Interval
+	if interval < minInterval {
+		interval = minInterval
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Prober{
+		server:   cfg.Server,
+		timeout:  timeout,
+		domains:  normalizeDomains(cfg.Domains),
+		interval: interval,
+		buckets:  make(map[bucketKey]*Bucket),
+		updated:  make(chan struct{}, 1),
+	}
+}
+
+// normalizeDomains 去除空项、结尾的点和重复域名
+func normalizeDomains(domains []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if domain == "" || seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		result = append(result, domain)
+	}
+	return result
+}
+
+// Start 启动探测循环，直到 ctx 取消
+func (p *Prober) Start(ctx context.Context) {
+	log.Printf("📡 解析探测已启动: 服务器 %s", p.server)
+	for {
+		p.probeOnce(ctx)
+
+		p.mu.Lock()
+		interval := p.interval
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.updated:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// probeOnce 依次探测所有域名
+func (p *Prober) probeOnce(ctx context.Context) {
+	p.mu.Lock()
+	domains := p.domains
+	p.mu.Unlock()
+
+	for _, domain := range domains {
+		if ctx.Err() != nil {
+			return
+		}
+		latency, err := p.resolve(ctx, domain)
+		p.record(time.Now(), domain, latency, err)
+	}
+	p.prune(time.Now())
+}
+
+// resolve 直接向本地 SmartDNS 查询 A 记录，不经过系统解析器
+func (p *Prober) resolve(ctx context.Context, domain string) (time.Duration, error) {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: p.timeout}
+			return d.DialContext(ctx, network, p.server)
+		},
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := resolver.LookupIP(queryCtx, "ip4", domain+".")
+	return time.Since(start), err
+}
+
+// record 将一次探测结果计入所在的聚合周期
+func (p *Prober) record(at time.Time, domain string, latency time.Duration, err error) {
+	start := at.Truncate(BucketSize)
+	key := bucketKey{start: start.Unix(), domain: domain}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bucket, ok := p.buckets[key]
+	if !ok {
+		bucket = &Bucket{Start: start, Domain: domain}
+		p.buckets[key] = bucket
+	}
+	bucket.Probes++
+	if err != nil {
+		bucket.Failures++
+		bucket.LastError = err.Error()
+		return
+	}
+
+	ms := float64(latency.Microseconds()) / 1000
+	bucket.LatencySumMs += ms
+	if ms > bucket.MaxLatencyMs {
+		bucket.MaxLatencyMs = ms
+	}
+}
+
+// prune 删除超过保留时长的聚合结果
+func (p *Prober) prune(now time.Time) {
+	cutoff := now.Add(-retention).Unix()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key := range p.buckets {
+		if key.start < cutoff {
+			delete(p.buckets, key)
+		}
+	}
+}
+
+// Buckets 返回起始时间不早于 since 的聚合结果，包含尚未结束的当前周期
+func (p *Prober) Buckets(since time.Time) []Bucket {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make([]Bucket, 0, len(p.buckets))
+	for _, bucket := range p.buckets {
+		if !bucket.Start.Before(since) {
+			result = append(result, *bucket)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Start.Equal(result[j].Start) {
+			return result[i].Start.Before(result[j].Start)
+		}
+		return result[i].Domain < result[j].Domain
+	})
+	return result
+}
+
+// Settings 获取当前探测配置
+func (p *Prober) Settings() Settings {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Settings{
+		Domains:     append([]string{}, p.domains...),
+		Server:      p.server,
+		IntervalSec: int(p.interval / time.Second),
+		TimeoutMs:   int(p.timeout / time.Millisecond),
+	}
+}
+
+// Update 更新探测域名和间隔（interval 为 0 时保持不变），立即按新配置探测一次
+func (p *Prober) Update(domains []string, interval time.Duration) {
+	p.mu.Lock()
+	p.domains = normalizeDomains(domains)
+	if interval > 0 {
+		if interval < minInterval {
+			interval = minInterval
+		}
+		p.interval = interval
+	}
+	p.mu.Unlock()
+
+	select {
+	case p.updated <- struct{}{}:
+	default:
+	}
+}
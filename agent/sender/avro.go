@@ -0,0 +1,83 @@
+package sender
+
+import (
+	"encoding/binary"
+	"math"
+
+	"smartdns-log-agent/models"
+)
+
+// dnsLogAvroSchema Kafka 使用 Avro 格式时的消息 Schema，字段顺序与 appendAvroRecord 一致
+const dnsLogAvroSchema = `{
+  "type": "record",
+  "name": "DNSLog",
+  "namespace": "smartdns",
+  "fields": [
+    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "node_id", "type": "long"},
+    {"name": "client_ip", "type": "string"},
+    {"name": "domain", "type": "string"},
+    {"name": "query_type", "type": "int"},
+    {"name": "group", "type": "string"},
+    {"name": "upstream", "type": "string"},
+    {"name": "time_ms", "type": "long"},
+    {"name": "speed_ms", "type": "float"},
+    {"name": "result_count", "type": "int"},
+    {"name": "result_ips", "type": {"type": "array", "items": "string"}},
+    {"name": "domain_category", "type": "string"},
+    {"name": "client_subnet", "type": "string"},
+    {"name": "client_country", "type": "string"},
+    {"name": "client_asn", "type": "long"},
+    {"name": "client_as_org", "type": "string"},
+    {"name": "client_ptr", "type": "string"},
+    {"name": "raw_log", "type": "string"},
+    {"name": "rcode", "type": "string", "default": ""},
+    {"name": "cache_hit", "type": "boolean", "default": false},
+    {"name": "matched_rule", "type": "string", "default": ""}
+  ]
+}`
+
+// appendAvroRecord 按 dnsLogAvroSchema 以 Avro 二进制编码一条日志。
+// Avro 的 int / long 使用 zigzag 变长编码，与 binary.AppendVarint 相同
+func appendAvroRecord(buf []byte, r *models.DNSLogRecord) []byte {
+	buf = binary.AppendVarint(buf, r.Timestamp.UnixMilli())
+	buf = binary.AppendVarint(buf, int64(r.NodeID))
+	buf = appendAvroString(buf, r.ClientIP)
+	buf = appendAvroString(buf, r.Domain)
+	buf = binary.AppendVarint(buf, int64(r.QueryType))
+	buf = appendAvroString(buf, r.Group)
+	buf = appendAvroString(buf, r.Upstream)
+	buf = binary.AppendVarint(buf, int64(r.TimeMs))
+	buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(r.SpeedMs))
+	buf = binary.AppendVarint(buf, int64(r.ResultCount))
+
+	// 数组按块编码：元素个数 + 元素，以 0 结束；空数组只有结束标记
+	if len(r.ResultIPs) > 0 {
+		buf = binary.AppendVarint(buf, int64(len(r.ResultIPs)))
+		for _, ip := range r.ResultIPs {
+			buf = appendAvroString(buf, ip)
+		}
+	}
+	buf = binary.AppendVarint(buf, 0)
+
+	buf = appendAvroString(buf, r.DomainCategory)
+	buf = appendAvroString(buf, r.ClientSubnet)
+	buf = appendAvroString(buf, r.ClientCountry)
+	buf = binary.AppendVarint(buf, int64(r.ClientASN))
+	buf = appendAvroString(buf, r.ClientASOrg)
+	buf = appendAvroString(buf, r.ClientPTR)
+	buf = appendAvroString(buf, r.RawLog)
+	buf = appendAvroString(buf, r.Rcode)
+	if r.CacheHit {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	buf = appendAvroString(buf, r.MatchedRule)
+	return buf
+}
+
+func appendAvroString(buf []byte, s string) []byte {
+	buf = binary.AppendVarint(buf, int64(len(s)))
+	return append(buf, s...)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
@@ -14,20 +15,52 @@ import (
 )
 
 type ClickHouseSender struct {
+	batchMetrics
+
 	conn driver.Conn
+	cfg  config.ClickHouseConfig
+}
+
+// compressionMethods 压缩算法名称与驱动枚举的对应关系
+var compressionMethods = map[string]clickhouse.CompressionMethod{
+	config.CompressionNone:    clickhouse.CompressionNone,
+	config.CompressionLZ4:     clickhouse.CompressionLZ4,
+	config.CompressionZSTD:    clickhouse.CompressionZSTD,
+	config.CompressionGZIP:    clickhouse.CompressionGZIP,
+	config.CompressionDeflate: clickhouse.CompressionDeflate,
+	config.CompressionBrotli:  clickhouse.CompressionBrotli,
 }
 
 func NewClickHouseSender(cfg config.ClickHouseConfig) (*ClickHouseSender, error) {
+	tlsConfig, err := cfg.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	compression, ok := compressionMethods[cfg.Compression]
+	if !ok || !cfg.SupportsCompression(cfg.Compression) {
+		return nil, fmt.Errorf("ClickHouse %s 协议不支持压缩算法: %s", cfg.Protocol, cfg.Compression)
+	}
+
+	protocol := clickhouse.Native
+	if cfg.Protocol == config.ClickHouseProtocolHTTP {
+		protocol = clickhouse.HTTP
+	}
+
 	conn, err := clickhouse.Open(&clickhouse.Options{
-		Addr: []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)},
+		Protocol: protocol,
+		Addr:     []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)},
 		Auth: clickhouse.Auth{
 			Database: cfg.Database,
 			Username: cfg.Username,
 			Password: cfg.Password,
 		},
-		DialTimeout: 10 * time.Second,
+		TLS:          tlsConfig,
+		DialTimeout:  10 * time.Second,
+		MaxOpenConns: cfg.MaxOpenConns,
+		MaxIdleConns: cfg.MaxIdleConns,
 		Compression: &clickhouse.Compression{
-			Method: clickhouse.CompressionLZ4,
+			Method: compression,
 		},
 	})
 	if err != nil {
@@ -41,7 +74,7 @@ func NewClickHouseSender(cfg config.ClickHouseConfig) (*ClickHouseSender, error)
 		return nil, err
 	}
 
-	sender := &ClickHouseSender{conn: conn}
+	sender := &ClickHouseSender{conn: conn, cfg: cfg}
 
 	// 自动创建表
 	if err := sender.createTables(ctx); err != nil {
@@ -52,11 +85,31 @@ func NewClickHouseSender(cfg config.ClickHouseConfig) (*ClickHouseSender, error)
 	return sender, nil
 }
 
+// createIngestBatchTableSQL 批次确认表，每个写入成功的批次一行
+const createIngestBatchTableSQL = `
+    CREATE TABLE IF NOT EXISTS dns_ingest_batches (
+        node_id UInt32 COMMENT '节点ID',
+        file_path String COMMENT '日志文件路径',
+        stream_id String COMMENT '文件标识（inode-起始时间）',
+        seq UInt64 COMMENT '批次序号',
+        start_offset UInt64 COMMENT '起始偏移',
+        end_offset UInt64 COMMENT '结束偏移',
+        rows UInt32 COMMENT '日志条数',
+        reread UInt8 DEFAULT 0 COMMENT '是否为补读批次',
+        sent_at DateTime64(3) COMMENT '发送时间',
+        inserted_at DateTime64(3) DEFAULT now64(3) COMMENT '写入时间'
+    ) ENGINE = MergeTree()
+    PARTITION BY toYYYYMM(sent_at)
+    ORDER BY (node_id, stream_id, seq)
+    TTL toDate(sent_at) + INTERVAL 30 DAY
+    COMMENT 'DNS日志批次确认表'
+    `
+
 // createTables 创建必要的表
 func (s *ClickHouseSender) createTables(ctx context.Context) error {
 	log.Println("🔨 检查并创建 ClickHouse 表结构...")
 
-	// 创建 DNS 查询日志表
+	// 创建 DNS 查询日志表。表已存在时不修改表结构，旧表缺少的列由管理端的版本化迁移补齐
 	createDNSTableSQL := `
     CREATE TABLE IF NOT EXISTS dns_query_log (
         timestamp DateTime64(3) COMMENT '查询时间（毫秒精度）',
@@ -70,12 +123,22 @@ func (s *ClickHouseSender) createTables(ctx context.Context) error {
         result_count UInt8 COMMENT '返回IP数量',
         result_ips Array(String) COMMENT '返回的IP列表',
         raw_log String COMMENT '原始日志',
-        group String COMMENT '所属组'
+        "group" String DEFAULT '' COMMENT '所属组',
+        domain_category LowCardinality(String) DEFAULT '' COMMENT '域名分类',
+        client_subnet String DEFAULT '' COMMENT '客户端子网',
+        client_country LowCardinality(String) DEFAULT '' COMMENT '客户端国家（ISO代码）',
+        client_asn UInt32 DEFAULT 0 COMMENT '客户端ASN',
+        client_as_org String DEFAULT '' COMMENT '客户端ASN组织',
+        client_ptr String DEFAULT '' COMMENT '客户端PTR记录',
+        upstream LowCardinality(String) DEFAULT '' COMMENT '应答的上游服务器',
+        rcode LowCardinality(String) DEFAULT '' COMMENT '响应码',
+        cache_hit UInt8 DEFAULT 0 COMMENT '是否由缓存应答',
+        matched_rule String DEFAULT '' COMMENT '命中的规则'
     ) ENGINE = MergeTree()
     PARTITION BY toYYYYMM(date)
     ORDER BY (date, node_id, timestamp)
     TTL date + INTERVAL 30 DAY
-    SETTINGS index_granularity = 8192
+    SETTINGS index_granularity = 8192, non_replicated_deduplication_window = 100
     COMMENT 'DNS查询日志表'
     `
 
@@ -84,6 +147,16 @@ func (s *ClickHouseSender) createTables(ctx context.Context) error {
 	}
 	log.Println("✅ dns_query_log 表创建成功")
 
+	// 创建批次确认表
+	if err := s.conn.Exec(ctx, createIngestBatchTableSQL); err != nil {
+		return fmt.Errorf("创建 dns_ingest_batches 表失败: %w", err)
+	}
+
+	// 开启写入去重窗口，重试发送同一批次时不会重复写入
+	if err := s.conn.Exec(ctx, "ALTER TABLE dns_query_log MODIFY SETTING non_replicated_deduplication_window = 100"); err != nil {
+		log.Printf("⚠️ 开启写入去重失败（重试可能产生重复数据）: %v", err)
+	}
+
 	// 创建物化视图（可选，用于加速查询）
 	if err := s.createMaterializedViews(ctx); err != nil {
 		log.Printf("⚠️ 创建物化视图失败（可忽略）: %v", err)
@@ -223,44 +296,116 @@ func (s *ClickHouseSender) GetTableInfo(ctx context.Context) error {
 	return nil
 }
 
+// SendBatch 按列批量写入日志，失败时以相同的去重标识重试，避免重复写入
 func (s *ClickHouseSender) SendBatch(records []models.DNSLogRecord) error {
 	if len(records) == 0 {
 		return nil
 	}
 
+	block := getBlock(records)
+	defer putBlock(block)
+
+	token := dedupToken(records)
+	backoff := s.cfg.RetryBackoff
+
+	var err error
+	for attempt := 0; attempt <= s.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			atomic.AddInt64(&s.retries, 1)
+			log.Printf("⚠️ 写入 ClickHouse 失败，%v 后第 %d 次重试: %v", backoff, attempt, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		start := time.Now()
+		if err = s.sendBlock(block, token); err == nil {
+			s.recordSent(len(records), time.Since(start))
+			return nil
+		}
+	}
+
+	atomic.AddInt64(&s.failedBatches, 1)
+	return err
+}
+
+// sendBlock 发送一次批次
+func (s *ClickHouseSender) sendBlock(block *columnBlock, token string) error {
+	ctx := clickhouse.Context(context.Background(), clickhouse.WithSettings(clickhouse.Settings{
+		"insert_deduplicate":         1,
+		"insert_deduplication_token": token,
+	}))
+	if s.cfg.InsertTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.InsertTimeout)
+		defer cancel()
+	}
+
+	batch, err := s.conn.PrepareBatch(ctx, "INSERT INTO dns_query_log ("+insertColumns+")")
+	if err != nil {
+		return err
+	}
+
+	if err := block.appendTo(batch); err != nil {
+		batch.Abort()
+		return err
+	}
+
+	return batch.Send()
+}
+
+// AckBatch 记录已写入的批次，供管理端检测缺失和乱序
+func (s *ClickHouseSender) AckBatch(meta models.IngestBatch) error {
 	ctx := context.Background()
-	batch, err := s.conn.PrepareBatch(ctx,
-		`INSERT INTO dns_query_log (
-            timestamp, date, node_id, client_ip, domain, query_type, 
-            time_ms, speed_ms, result_count, result_ips, raw_log, group
-        )`)
+	if s.cfg.InsertTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.InsertTimeout)
+		defer cancel()
+	}
+
+	batch, err := s.conn.PrepareBatch(ctx, `INSERT INTO dns_ingest_batches
+        (node_id, file_path, stream_id, seq, start_offset, end_offset, rows, reread, sent_at)`)
 	if err != nil {
 		return err
 	}
 
-	for _, record := range records {
-		err := batch.Append(
-			record.Timestamp,
-			record.Date,
-			record.NodeID,
-			record.ClientIP,
-			record.Domain,
-			record.QueryType,
-			record.TimeMs,
-			record.SpeedMs,
-			record.ResultCount,
-			record.ResultIPs,
-			record.RawLog,
-			record.Group,
-		)
-		if err != nil {
-			return err
-		}
+	var reread uint8
+	if meta.Reread {
+		reread = 1
+	}
+	if err := batch.Append(meta.NodeID, meta.FilePath, meta.StreamID, meta.Seq,
+		uint64(meta.StartOffset), uint64(meta.EndOffset), meta.Rows, reread, meta.SentAt); err != nil {
+		batch.Abort()
+		return err
 	}
 
 	return batch.Send()
 }
 
+// LoadDomainCategories 加载域名分类表（由管理端从域名集同步）
+func (s *ClickHouseSender) LoadDomainCategories(ctx context.Context) (map[string]string, error) {
+	exists, err := s.checkTableExists(ctx, "domain_categories")
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := s.conn.Query(ctx, "SELECT domain, category FROM domain_categories")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make(map[string]string)
+	for rows.Next() {
+		var domain, category string
+		if err := rows.Scan(&domain, &category); err != nil {
+			continue
+		}
+		categories[domain] = category
+	}
+
+	return categories, rows.Err()
+}
+
 func (s *ClickHouseSender) Close() {
 	if s.conn != nil {
 		s.conn.Close()
@@ -0,0 +1,182 @@
+package sender
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"smartdns-log-agent/models"
+)
+
+// insertColumns 写入 dns_query_log 的列顺序，与 columnBlock.appendTo 保持一致；
+// group 是关键字需加引号，驱动按列名匹配时只去掉反引号
+const insertColumns = `timestamp, date, node_id, client_ip, domain, query_type,
+            time_ms, speed_ms, result_count, result_ips, raw_log, ` + "`group`" + `,
+            domain_category, client_subnet, client_country, client_asn, client_as_org, client_ptr, upstream,
+            rcode, cache_hit, matched_rule`
+
+// columnBlock 按列组织的一批日志，切片在批次之间复用，避免每次发送重新分配
+type columnBlock struct {
+	timestamps      []time.Time
+	dates           []time.Time
+	nodeIDs         []uint32
+	clientIPs       []string
+	domains         []string
+	queryTypes      []uint16
+	timeMs          []uint32
+	speedMs         []float32
+	resultCounts    []uint8
+	resultIPs       [][]string
+	rawLogs         []string
+	groups          []string
+	domainCategory  []string
+	clientSubnets   []string
+	clientCountries []string
+	clientASNs      []uint32
+	clientASOrgs    []string
+	clientPTRs      []string
+	upstreams       []string
+	rcodes          []string
+	cacheHits       []uint8
+	matchedRules    []string
+}
+
+var blockPool = sync.Pool{
+	New: func() interface{} {
+		return &columnBlock{}
+	},
+}
+
+// getBlock 从池中取出并填充列块
+func getBlock(records []models.DNSLogRecord) *columnBlock {
+	block := blockPool.Get().(*columnBlock)
+	block.fill(records)
+	return block
+}
+
+// putBlock 归还列块，清空引用以便回收日志内容
+func putBlock(block *columnBlock) {
+	block.reset()
+	blockPool.Put(block)
+}
+
+func (b *columnBlock) reset() {
+	b.timestamps = b.timestamps[:0]
+	b.dates = b.dates[:0]
+	b.nodeIDs = b.nodeIDs[:0]
+	b.clientIPs = clearStrings(b.clientIPs)
+	b.domains = clearStrings(b.domains)
+	b.queryTypes = b.queryTypes[:0]
+	b.timeMs = b.timeMs[:0]
+	b.speedMs = b.speedMs[:0]
+	b.resultCounts = b.resultCounts[:0]
+	for i := range b.resultIPs {
+		b.resultIPs[i] = nil
+	}
+	b.resultIPs = b.resultIPs[:0]
+	b.rawLogs = clearStrings(b.rawLogs)
+	b.groups = clearStrings(b.groups)
+	b.domainCategory = clearStrings(b.domainCategory)
+	b.clientSubnets = clearStrings(b.clientSubnets)
+	b.clientCountries = clearStrings(b.clientCountries)
+	b.clientASNs = b.clientASNs[:0]
+	b.clientASOrgs = clearStrings(b.clientASOrgs)
+	b.clientPTRs = clearStrings(b.clientPTRs)
+	b.upstreams = clearStrings(b.upstreams)
+	b.rcodes = clearStrings(b.rcodes)
+	b.cacheHits = b.cacheHits[:0]
+	b.matchedRules = clearStrings(b.matchedRules)
+}
+
+func clearStrings(values []string) []string {
+	for i := range values {
+		values[i] = ""
+	}
+	return values[:0]
+}
+
+// fill 将记录按列展开
+func (b *columnBlock) fill(records []models.DNSLogRecord) {
+	for i := range records {
+		r := &records[i]
+		b.timestamps = append(b.timestamps, r.Timestamp)
+		b.dates = append(b.dates, r.Date)
+		b.nodeIDs = append(b.nodeIDs, r.NodeID)
+		b.clientIPs = append(b.clientIPs, r.ClientIP)
+		b.domains = append(b.domains, r.Domain)
+		b.queryTypes = append(b.queryTypes, r.QueryType)
+		b.timeMs = append(b.timeMs, r.TimeMs)
+		b.speedMs = append(b.speedMs, r.SpeedMs)
+		b.resultCounts = append(b.resultCounts, r.ResultCount)
+		ips := r.ResultIPs
+		if ips == nil {
+			ips = []string{}
+		}
+		b.resultIPs = append(b.resultIPs, ips)
+		b.rawLogs = append(b.rawLogs, r.RawLog)
+		b.groups = append(b.groups, r.Group)
+		b.domainCategory = append(b.domainCategory, r.DomainCategory)
+		b.clientSubnets = append(b.clientSubnets, r.ClientSubnet)
+		b.clientCountries = append(b.clientCountries, r.ClientCountry)
+		b.clientASNs = append(b.clientASNs, r.ClientASN)
+		b.clientASOrgs = append(b.clientASOrgs, r.ClientASOrg)
+		b.clientPTRs = append(b.clientPTRs, r.ClientPTR)
+		b.upstreams = append(b.upstreams, r.Upstream)
+		b.rcodes = append(b.rcodes, r.Rcode)
+		var cacheHit uint8
+		if r.CacheHit {
+			cacheHit = 1
+		}
+		b.cacheHits = append(b.cacheHits, cacheHit)
+		b.matchedRules = append(b.matchedRules, r.MatchedRule)
+	}
+}
+
+// appendTo 按列写入批次
+func (b *columnBlock) appendTo(batch driver.Batch) error {
+	columns := []interface{}{
+		b.timestamps,
+		b.dates,
+		b.nodeIDs,
+		b.clientIPs,
+		b.domains,
+		b.queryTypes,
+		b.timeMs,
+		b.speedMs,
+		b.resultCounts,
+		b.resultIPs,
+		b.rawLogs,
+		b.groups,
+		b.domainCategory,
+		b.clientSubnets,
+		b.clientCountries,
+		b.clientASNs,
+		b.clientASOrgs,
+		b.clientPTRs,
+		b.upstreams,
+		b.rcodes,
+		b.cacheHits,
+		b.matchedRules,
+	}
+
+	for i, column := range columns {
+		if err := batch.Column(i).Append(column); err != nil {
+			return fmt.Errorf("写入第 %d 列失败: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// dedupToken 根据批次内容生成去重标识，同一批次重试时保持不变，
+// ClickHouse 据此丢弃已经写入成功的重复批次
+func dedupToken(records []models.DNSLogRecord) string {
+	h := fnv.New64a()
+	for i := range records {
+		h.Write([]byte(records[i].RawLog))
+		h.Write([]byte{'\n'})
+	}
+	return fmt.Sprintf("%d-%d-%x", records[0].NodeID, len(records), h.Sum64())
+}
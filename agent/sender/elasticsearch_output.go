@@ -0,0 +1,157 @@
+package sender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartdns-log-agent/config"
+	"smartdns-log-agent/models"
+)
+
+// ElasticsearchOutput 以 Bulk API 把日志写入 Elasticsearch 或 OpenSearch，索引名按模板生成（如按天分索引）
+type ElasticsearchOutput struct {
+	batchMetrics
+
+	client   *http.Client
+	cfg      config.ElasticsearchConfig
+	nodeName string
+}
+
+func NewElasticsearchOutput(cfg config.ElasticsearchConfig, nodeName string) (*ElasticsearchOutput, error) {
+	log.Printf("✅ 日志将同时写入 Elasticsearch: %s (索引 %s)", redactURL(cfg.URL), cfg.Index)
+	return &ElasticsearchOutput{
+		client:   newOutputClient(cfg.InsecureSkipVerify, cfg.Timeout),
+		cfg:      cfg,
+		nodeName: nodeName,
+	}, nil
+}
+
+func (s *ElasticsearchOutput) Name() string {
+	return "elasticsearch"
+}
+
+// esDocument 写入的文档，@timestamp 供 Kibana / OpenSearch Dashboards 按时间检索
+type esDocument struct {
+	Timestamp time.Time `json:"@timestamp"`
+	NodeName  string    `json:"node_name"`
+	*models.DNSLogRecord
+}
+
+// esBulkResponse Bulk API 的响应，只解析失败的条目
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// Write 以 create 操作写入一批日志，文档 ID 由批次去重标识和序号组成；
+// 重试或整批重发时已写入的文档返回 409，视为成功，不会重复写入
+func (s *ElasticsearchOutput) Write(records []models.DNSLogRecord, token string) error {
+	body, err := s.encode(records, token)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"Content-Type":     "application/x-ndjson",
+		"Content-Encoding": "gzip",
+	}
+	if s.cfg.APIKey != "" {
+		headers["Authorization"] = "ApiKey " + s.cfg.APIKey
+	}
+
+	return s.retryBatch("Elasticsearch", len(records), s.cfg.MaxRetries, s.cfg.RetryBackoff, func() error {
+		data, err := postOutput(s.client, s.cfg.URL+"/_bulk", body, headers)
+		if err != nil {
+			return err
+		}
+		return checkBulkResponse(data)
+	})
+}
+
+// encode 编码为 gzip 压缩的 Bulk 请求，每条日志一行操作、一行文档
+func (s *ElasticsearchOutput) encode(records []models.DNSLogRecord, token string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+
+	type bulkMeta struct {
+		Index string `json:"_index"`
+		ID    string `json:"_id"`
+	}
+	for i := range records {
+		r := &records[i]
+		action := map[string]bulkMeta{
+			"create": {
+				Index: strings.ToLower(expandTemplate(s.cfg.Index, r, s.nodeName)),
+				ID:    token + "-" + strconv.Itoa(i),
+			},
+		}
+		if err := encoder.Encode(action); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(esDocument{Timestamp: r.Timestamp, NodeName: s.nodeName, DNSLogRecord: r}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkBulkResponse 检查 Bulk 响应中失败的条目；409（文档已存在）视为成功，
+// 只有限流（429）和服务端错误可重试，映射错误等重试也不会成功
+func checkBulkResponse(data []byte) error {
+	var resp esBulkResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("解析 Bulk 响应失败: %w", err)
+	}
+	if !resp.Errors {
+		return nil
+	}
+
+	var failed int
+	var firstReason string
+	retryable := false
+	for _, item := range resp.Items {
+		for _, result := range item {
+			if result.Status < 300 || result.Status == http.StatusConflict {
+				continue
+			}
+			failed++
+			if result.Status == http.StatusTooManyRequests || result.Status >= 500 {
+				retryable = true
+			}
+			if firstReason == "" && result.Error != nil {
+				firstReason = result.Error.Type + ": " + result.Error.Reason
+			}
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("%d 条日志写入失败: %s", failed, firstReason)
+	if !retryable {
+		return &permanentError{err: err}
+	}
+	return err
+}
+
+func (s *ElasticsearchOutput) Close() {
+	s.client.CloseIdleConnections()
+}
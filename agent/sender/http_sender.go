@@ -0,0 +1,196 @@
+package sender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"smartdns-log-agent/config"
+	"smartdns-log-agent/models"
+)
+
+// HTTPSender 经管理端的 /ingest/dns-logs 写入日志，由管理端写入 ClickHouse，节点只需访问管理端
+type HTTPSender struct {
+	batchMetrics
+
+	client *http.Client
+	cfg    config.IngestConfig
+}
+
+// permanentError 管理端拒绝的请求（如令牌无效、请求体过大），重试不会成功
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func NewHTTPSender(cfg config.IngestConfig) (*HTTPSender, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	sender := &HTTPSender{
+		client: &http.Client{Transport: transport, Timeout: cfg.Timeout},
+		cfg:    cfg,
+	}
+
+	// 启动时确认管理端可达且令牌有效
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := sender.LoadDomainCategories(ctx); err != nil {
+		return nil, fmt.Errorf("连接管理端失败: %w", err)
+	}
+
+	log.Printf("✅ 日志将经管理端写入: %s", cfg.URL)
+	return sender, nil
+}
+
+// SendBatch 以 gzip 压缩的 NDJSON 上传日志，失败时以相同的去重标识重试，避免重复写入
+func (s *HTTPSender) SendBatch(records []models.DNSLogRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	body, err := encodeNDJSON(records)
+	if err != nil {
+		return err
+	}
+
+	token := dedupToken(records)
+	backoff := s.cfg.RetryBackoff
+
+	for attempt := 0; attempt <= s.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			atomic.AddInt64(&s.retries, 1)
+			log.Printf("⚠️ 上传日志失败，%v 后第 %d 次重试: %v", backoff, attempt, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		start := time.Now()
+		err = s.post("/ingest/dns-logs", body, map[string]string{
+			"Content-Type":     "application/x-ndjson",
+			"Content-Encoding": "gzip",
+			"X-Dedup-Token":    token,
+		})
+		if err == nil {
+			s.recordSent(len(records), time.Since(start))
+			return nil
+		}
+		if _, ok := err.(*permanentError); ok {
+			break
+		}
+	}
+
+	atomic.AddInt64(&s.failedBatches, 1)
+	return err
+}
+
+// encodeNDJSON 每行一条日志，整体 gzip 压缩
+func encodeNDJSON(records []models.DNSLogRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for i := range records {
+		if err := encoder.Encode(&records[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// AckBatch 记录已写入的批次，供管理端检测缺失和乱序
+func (s *HTTPSender) AckBatch(meta models.IngestBatch) error {
+	body, err := json.Marshal([]models.IngestBatch{meta})
+	if err != nil {
+		return err
+	}
+	return s.post("/ingest/dns-batches", body, map[string]string{"Content-Type": "application/json"})
+}
+
+// LoadDomainCategories 从管理端加载域名分类
+func (s *HTTPSender) LoadDomainCategories(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL+"/ingest/domain-categories", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool              `json:"success"`
+		Message string            `json:"message"`
+		Data    map[string]string `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析管理端响应失败 (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("管理端返回错误 (HTTP %d): %s", resp.StatusCode, result.Message)
+	}
+	return result.Data, nil
+}
+
+// post 发送一次请求，4xx（408、429 除外）视为不可重试的错误
+func (s *HTTPSender) post(path string, body []byte, headers map[string]string) error {
+	ctx := context.Background()
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var result struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result)
+	err = fmt.Errorf("管理端返回错误 (HTTP %d): %s", resp.StatusCode, result.Message)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err: err}
+	}
+	return err
+}
+
+func (s *HTTPSender) Close() {
+	s.client.CloseIdleConnections()
+}
@@ -0,0 +1,136 @@
+package sender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"smartdns-log-agent/config"
+	"smartdns-log-agent/models"
+)
+
+// LokiOutput 以 Push API 把日志写入 Grafana Loki，按标签模板把同一批日志分到不同的流。
+// 标签值应选择低基数的字段（节点、分组），客户端 IP 和域名等高基数字段请放在日志行中查询
+type LokiOutput struct {
+	batchMetrics
+
+	client   *http.Client
+	cfg      config.LokiConfig
+	labels   []config.LokiLabel
+	nodeName string
+}
+
+func NewLokiOutput(cfg config.LokiConfig, nodeName string) (*LokiOutput, error) {
+	labels, err := cfg.ParseLabels()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+	log.Printf("✅ 日志将同时写入 Loki: %s", redactURL(cfg.URL))
+	return &LokiOutput{
+		client:   newOutputClient(cfg.InsecureSkipVerify, cfg.Timeout),
+		cfg:      cfg,
+		labels:   labels,
+		nodeName: nodeName,
+	}, nil
+}
+
+func (s *LokiOutput) Name() string {
+	return "loki"
+}
+
+// lokiStream Push API 的一个流，values 为 [纳秒时间戳, 日志行]
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// Write 推送一批日志。Loki 丢弃同一流中时间戳和内容都相同的日志，重试不会产生重复
+func (s *LokiOutput) Write(records []models.DNSLogRecord, token string) error {
+	body, err := s.encode(records)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"Content-Type":     "application/json",
+		"Content-Encoding": "gzip",
+	}
+	if s.cfg.TenantID != "" {
+		headers["X-Scope-OrgID"] = s.cfg.TenantID
+	}
+
+	return s.retryBatch("Loki", len(records), s.cfg.MaxRetries, s.cfg.RetryBackoff, func() error {
+		_, err := postOutput(s.client, s.cfg.URL+"/loki/api/v1/push", body, headers)
+		return err
+	})
+}
+
+// encode 按标签分流并编码为 gzip 压缩的推送请求
+func (s *LokiOutput) encode(records []models.DNSLogRecord) ([]byte, error) {
+	streams := make(map[string]*lokiStream)
+	var order []string
+	var key strings.Builder
+
+	for i := range records {
+		r := &records[i]
+
+		key.Reset()
+		values := make([]string, len(s.labels))
+		for j, label := range s.labels {
+			values[j] = expandTemplate(label.Value, r, s.nodeName)
+			key.WriteString(values[j])
+			key.WriteByte(0)
+		}
+
+		stream, ok := streams[key.String()]
+		if !ok {
+			stream = &lokiStream{Stream: make(map[string]string, len(s.labels))}
+			for j, label := range s.labels {
+				// Loki 不接受空的标签值
+				if values[j] != "" {
+					stream.Stream[label.Name] = values[j]
+				}
+			}
+			streams[key.String()] = stream
+			order = append(order, key.String())
+		}
+
+		line := r.RawLog
+		if s.cfg.LineFormat == config.LokiLineJSON {
+			data, err := json.Marshal(r)
+			if err != nil {
+				return nil, err
+			}
+			line = string(data)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(r.Timestamp.UnixNano(), 10), line})
+	}
+
+	push := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{Streams: make([]*lokiStream, 0, len(order))}
+	for _, k := range order {
+		push.Streams = append(push.Streams, streams[k])
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(&push); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *LokiOutput) Close() {
+	s.client.CloseIdleConnections()
+}
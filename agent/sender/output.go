@@ -0,0 +1,271 @@
+package sender
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"smartdns-log-agent/config"
+	"smartdns-log-agent/models"
+)
+
+// Output 日志的附加输出，在主存储写入成功后写入，供已有的日志平台和流处理链路消费。
+// 附加输出不参与批次确认和域名分类，多个输出可同时启用
+type Output interface {
+	// Name 输出名称，用于日志和统计
+	Name() string
+	// Write 写入一批日志，token 为批次的去重标识，同一批次重发时保持不变
+	Write(records []models.DNSLogRecord, token string) error
+	// Stats 获取批量写入统计
+	Stats() SenderStats
+	Close()
+}
+
+// newOutputs 按配置创建附加输出，地址为空的输出不启用
+func newOutputs(cfg *config.Config) ([]Output, error) {
+	var outputs []Output
+	add := func(output Output, err error) error {
+		if err != nil {
+			for _, o := range outputs {
+				o.Close()
+			}
+			return err
+		}
+		outputs = append(outputs, output)
+		return nil
+	}
+
+	if cfg.Kafka.Enabled() {
+		if err := add(NewKafkaOutput(cfg.Kafka)); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Loki.Enabled() {
+		if err := add(NewLokiOutput(cfg.Loki, cfg.NodeName)); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Elasticsearch.Enabled() {
+		if err := add(NewElasticsearchOutput(cfg.Elasticsearch, cfg.NodeName)); err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
+
+// writtenBatchLimit 每个输出记录最近写入的批次数
+const writtenBatchLimit = 1024
+
+// outputState 附加输出及其最近写入的批次
+type outputState struct {
+	Output
+
+	mu      sync.Mutex
+	written map[string]struct{}
+	order   []string
+}
+
+func (o *outputState) wasWritten(token string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_, ok := o.written[token]
+	return ok
+}
+
+func (o *outputState) markWritten(token string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.written[token] = struct{}{}
+	o.order = append(o.order, token)
+	if len(o.order) > writtenBatchLimit {
+		delete(o.written, o.order[0])
+		o.order = o.order[1:]
+	}
+}
+
+// outputSender 先写入主存储，成功后再依次写入各附加输出。主存储失败时不写入附加输出；
+// 整批重发时（如确认批次失败或某个输出失败）主存储按去重标识去重，附加输出则跳过各自最近已写入的批次，避免重复
+type outputSender struct {
+	LogSender
+
+	outputs []*outputState
+}
+
+func newOutputSender(primary LogSender, outputs []Output) *outputSender {
+	sender := &outputSender{LogSender: primary}
+	for _, output := range outputs {
+		sender.outputs = append(sender.outputs, &outputState{
+			Output:  output,
+			written: make(map[string]struct{}, writtenBatchLimit),
+		})
+	}
+	return sender
+}
+
+// SendBatch 某个输出失败时其余输出照常写入，返回第一个错误，整批重发时只补写失败的输出
+func (s *outputSender) SendBatch(records []models.DNSLogRecord) error {
+	if err := s.LogSender.SendBatch(records); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	token := dedupToken(records)
+	var firstErr error
+	for _, output := range s.outputs {
+		if output.wasWritten(token) {
+			continue
+		}
+		if err := output.Write(records, token); err != nil {
+			log.Printf("❌ 写入 %s 失败: %v", output.Name(), err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("写入 %s 失败: %w", output.Name(), err)
+			}
+			continue
+		}
+		output.markWritten(token)
+	}
+	return firstErr
+}
+
+func (s *outputSender) Stats() SenderStats {
+	stats := s.LogSender.Stats()
+	stats.Outputs = make(map[string]SenderStats, len(s.outputs))
+	for _, output := range s.outputs {
+		stats.Outputs[output.Name()] = output.Stats()
+	}
+	return stats
+}
+
+func (s *outputSender) Close() {
+	s.LogSender.Close()
+	for _, output := range s.outputs {
+		output.Close()
+	}
+}
+
+// retryBatch 按退避重试写入一批日志，permanentError 不重试
+func (m *batchMetrics) retryBatch(name string, rows, maxRetries int, backoff time.Duration, send func() error) error {
+	var err error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			atomic.AddInt64(&m.retries, 1)
+			log.Printf("⚠️ 写入 %s 失败，%v 后第 %d 次重试: %v", name, backoff, attempt, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		start := time.Now()
+		if err = send(); err == nil {
+			m.recordSent(rows, time.Since(start))
+			return nil
+		}
+		if _, ok := err.(*permanentError); ok {
+			break
+		}
+	}
+
+	atomic.AddInt64(&m.failedBatches, 1)
+	return err
+}
+
+// newOutputClient HTTP 输出使用的客户端
+func newOutputClient(insecureSkipVerify bool, timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// postOutput 发送一次请求并返回响应体；4xx（408、429 除外）视为不可重试的错误。
+// 地址中的用户名和密码由 http.Client 以 Basic 认证发送
+func postOutput(client *http.Client, endpoint string, body []byte, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return respBody, nil
+	}
+
+	if len(respBody) > 512 {
+		respBody = respBody[:512]
+	}
+	err = fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return nil, &permanentError{err: err}
+	}
+	return nil, err
+}
+
+// redactURL 隐藏地址中的密码，用于日志输出
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+// expandTemplate 替换模板中的日志字段：{node_id}、{node_name}、{group}、{date}（2006.01.02）、{month}（2006.01），
+// 其他占位符原样保留
+func expandTemplate(tmpl string, r *models.DNSLogRecord, nodeName string) string {
+	start := strings.IndexByte(tmpl, '{')
+	if start < 0 {
+		return tmpl
+	}
+
+	buf := make([]byte, 0, len(tmpl)+32)
+	for start >= 0 {
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		buf = append(buf, tmpl[:start]...)
+		switch tmpl[start+1 : end] {
+		case "node_id":
+			buf = strconv.AppendUint(buf, uint64(r.NodeID), 10)
+		case "node_name":
+			buf = append(buf, nodeName...)
+		case "group":
+			buf = append(buf, r.Group...)
+		case "date":
+			buf = r.Timestamp.AppendFormat(buf, "2006.01.02")
+		case "month":
+			buf = r.Timestamp.AppendFormat(buf, "2006.01")
+		default:
+			buf = append(buf, tmpl[start:end+1]...)
+		}
+		tmpl = tmpl[end+1:]
+		start = strings.IndexByte(tmpl, '{')
+	}
+	return string(append(buf, tmpl...))
+}
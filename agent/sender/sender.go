@@ -0,0 +1,111 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"smartdns-log-agent/config"
+	"smartdns-log-agent/models"
+)
+
+// LogSender 日志写入接口，写入的存储需与管理端的日志存储驱动一致
+type LogSender interface {
+	// SendBatch 写入一批日志，失败时在内部重试
+	SendBatch(records []models.DNSLogRecord) error
+	// AckBatch 记录已写入的批次，供管理端检测缺失和乱序
+	AckBatch(meta models.IngestBatch) error
+	// LoadDomainCategories 加载管理端同步的域名分类，表不存在时返回空
+	LoadDomainCategories(ctx context.Context) (map[string]string, error)
+	// Stats 获取批量写入统计
+	Stats() SenderStats
+	Close()
+}
+
+// New 按配置创建日志写入器，配置了附加输出（Kafka、Loki、Elasticsearch）时同时写入
+func New(cfg *config.Config) (LogSender, error) {
+	primary, err := newPrimary(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs, err := newOutputs(cfg)
+	if err != nil {
+		primary.Close()
+		return nil, err
+	}
+	if len(outputs) == 0 {
+		return primary, nil
+	}
+	return newOutputSender(primary, outputs), nil
+}
+
+// newPrimary 创建主存储的写入器
+func newPrimary(cfg *config.Config) (LogSender, error) {
+	switch cfg.StorageType {
+	case config.StorageTimescale:
+		return NewTimescaleSender(cfg.Postgres)
+	case config.StorageHTTP:
+		return NewHTTPSender(cfg.Ingest)
+	case config.StorageClickHouse, "":
+		return NewClickHouseSender(cfg.ClickHouse)
+	default:
+		return nil, fmt.Errorf("不支持的日志存储类型: %s", cfg.StorageType)
+	}
+}
+
+// SenderStats 批量写入统计
+type SenderStats struct {
+	SentBatches      int64   `json:"sent_batches"`
+	FailedBatches    int64   `json:"failed_batches"` // 重试耗尽后仍失败的批次
+	Retries          int64   `json:"retries"`
+	SentRows         int64   `json:"sent_rows"`
+	AvgBatchLatency  float64 `json:"avg_batch_latency_ms"`
+	LastBatchLatency float64 `json:"last_batch_latency_ms"`
+	MaxBatchLatency  float64 `json:"max_batch_latency_ms"`
+
+	Outputs map[string]SenderStats `json:"outputs,omitempty"` // 各附加输出的统计，键为输出名称
+}
+
+// batchMetrics 各写入器共用的发送统计
+type batchMetrics struct {
+	sentBatches    int64
+	failedBatches  int64
+	retries        int64
+	sentRows       int64
+	latencyTotalUs int64
+	lastLatencyUs  int64
+	maxLatencyUs   int64
+}
+
+// recordSent 记录一次成功写入
+func (m *batchMetrics) recordSent(rows int, d time.Duration) {
+	us := d.Microseconds()
+	atomic.AddInt64(&m.sentBatches, 1)
+	atomic.AddInt64(&m.sentRows, int64(rows))
+	atomic.AddInt64(&m.latencyTotalUs, us)
+	atomic.StoreInt64(&m.lastLatencyUs, us)
+	for {
+		prev := atomic.LoadInt64(&m.maxLatencyUs)
+		if us <= prev || atomic.CompareAndSwapInt64(&m.maxLatencyUs, prev, us) {
+			return
+		}
+	}
+}
+
+// Stats 获取批量写入统计
+func (m *batchMetrics) Stats() SenderStats {
+	stats := SenderStats{
+		SentBatches:      atomic.LoadInt64(&m.sentBatches),
+		FailedBatches:    atomic.LoadInt64(&m.failedBatches),
+		Retries:          atomic.LoadInt64(&m.retries),
+		SentRows:         atomic.LoadInt64(&m.sentRows),
+		LastBatchLatency: float64(atomic.LoadInt64(&m.lastLatencyUs)) / 1e3,
+		MaxBatchLatency:  float64(atomic.LoadInt64(&m.maxLatencyUs)) / 1e3,
+	}
+	if stats.SentBatches > 0 {
+		stats.AvgBatchLatency = float64(atomic.LoadInt64(&m.latencyTotalUs)) / float64(stats.SentBatches) / 1e3
+	}
+	return stats
+}
@@ -0,0 +1,274 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"smartdns-log-agent/config"
+	"smartdns-log-agent/models"
+)
+
+// TimescaleSender 将日志写入 PostgreSQL / TimescaleDB
+// 表结构与管理端一致，hypertable 和保留策略由管理端创建
+type TimescaleSender struct {
+	batchMetrics
+
+	pool *pgxpool.Pool
+	cfg  config.PostgresConfig
+}
+
+// pgInsertColumns 写入 dns_query_log 的列顺序，与 pgRows 保持一致
+var pgInsertColumns = []string{
+	"timestamp", "node_id", "client_ip", "domain", "query_type", "time_ms", "speed_ms",
+	"result_count", "result_ips", "raw_log", "group", "domain_category",
+	"client_subnet", "client_country", "client_asn", "client_as_org", "client_ptr",
+	"upstream", "rcode", "cache_hit", "matched_rule",
+}
+
+func NewTimescaleSender(cfg config.PostgresConfig) (*TimescaleSender, error) {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Username, cfg.Password),
+		Host:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Path:     "/" + cfg.Database,
+		RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
+	}
+	poolConfig, err := pgxpool.ParseConfig(dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxConns)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	sender := &TimescaleSender{pool: pool, cfg: cfg}
+	if err := sender.createTables(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("创建表失败: %w", err)
+	}
+
+	return sender, nil
+}
+
+// createTables 创建必要的表
+func (s *TimescaleSender) createTables(ctx context.Context) error {
+	log.Println("🔨 检查并创建 PostgreSQL 表结构...")
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS dns_query_log (
+            timestamp TIMESTAMPTZ NOT NULL,
+            node_id BIGINT NOT NULL,
+            client_ip TEXT NOT NULL DEFAULT '',
+            domain TEXT NOT NULL DEFAULT '',
+            query_type INTEGER NOT NULL DEFAULT 0,
+            time_ms INTEGER NOT NULL DEFAULT 0,
+            speed_ms REAL NOT NULL DEFAULT 0,
+            result_count INTEGER NOT NULL DEFAULT 0,
+            result_ips TEXT[] NOT NULL DEFAULT '{}',
+            raw_log TEXT NOT NULL DEFAULT '',
+            "group" TEXT NOT NULL DEFAULT '',
+            domain_category TEXT NOT NULL DEFAULT '',
+            client_subnet TEXT NOT NULL DEFAULT '',
+            client_country TEXT NOT NULL DEFAULT '',
+            client_asn BIGINT NOT NULL DEFAULT 0,
+            client_as_org TEXT NOT NULL DEFAULT '',
+            client_ptr TEXT NOT NULL DEFAULT '',
+            upstream TEXT NOT NULL DEFAULT '',
+            rcode TEXT NOT NULL DEFAULT '',
+            cache_hit BOOLEAN NOT NULL DEFAULT false,
+            matched_rule TEXT NOT NULL DEFAULT ''
+        )`,
+		`ALTER TABLE dns_query_log ADD COLUMN IF NOT EXISTS upstream TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE dns_query_log ADD COLUMN IF NOT EXISTS rcode TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE dns_query_log ADD COLUMN IF NOT EXISTS cache_hit BOOLEAN NOT NULL DEFAULT false`,
+		`ALTER TABLE dns_query_log ADD COLUMN IF NOT EXISTS matched_rule TEXT NOT NULL DEFAULT ''`,
+		`CREATE TABLE IF NOT EXISTS dns_ingest_batches (
+            node_id BIGINT NOT NULL,
+            file_path TEXT NOT NULL DEFAULT '',
+            stream_id TEXT NOT NULL,
+            seq BIGINT NOT NULL,
+            start_offset BIGINT NOT NULL DEFAULT 0,
+            end_offset BIGINT NOT NULL DEFAULT 0,
+            rows INTEGER NOT NULL DEFAULT 0,
+            reread SMALLINT NOT NULL DEFAULT 0,
+            sent_at TIMESTAMPTZ NOT NULL,
+            inserted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY (node_id, stream_id, seq)
+        )`,
+		// 已写入批次的去重标识，作用与 ClickHouse 的 insert_deduplication_token 相同
+		`CREATE TABLE IF NOT EXISTS dns_insert_tokens (
+            token TEXT PRIMARY KEY,
+            inserted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+        )`,
+	}
+	for _, sql := range statements {
+		if _, err := s.pool.Exec(ctx, sql); err != nil {
+			return err
+		}
+	}
+
+	s.cleanTokens(ctx)
+
+	log.Println("✅ dns_query_log 表创建成功")
+	return nil
+}
+
+// tokenCleanEvery 每写入多少批次清理一次过期的去重标识
+const tokenCleanEvery = 1000
+
+// cleanTokens 去重只需覆盖重发窗口，清理一天前的标识
+func (s *TimescaleSender) cleanTokens(ctx context.Context) {
+	if _, err := s.pool.Exec(ctx, "DELETE FROM dns_insert_tokens WHERE inserted_at < now() - INTERVAL '1 day'"); err != nil {
+		log.Printf("⚠️ 清理去重标识失败: %v", err)
+	}
+}
+
+// SendBatch 批量写入日志，失败时以相同的去重标识重试，避免重复写入
+func (s *TimescaleSender) SendBatch(records []models.DNSLogRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	token := dedupToken(records)
+	backoff := s.cfg.RetryBackoff
+
+	var err error
+	for attempt := 0; attempt <= s.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			atomic.AddInt64(&s.retries, 1)
+			log.Printf("⚠️ 写入 PostgreSQL 失败，%v 后第 %d 次重试: %v", backoff, attempt, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		start := time.Now()
+		if err = s.sendRows(records, token); err == nil {
+			s.recordSent(len(records), time.Since(start))
+			if atomic.LoadInt64(&s.sentBatches)%tokenCleanEvery == 0 {
+				go s.cleanTokens(context.Background())
+			}
+			return nil
+		}
+	}
+
+	atomic.AddInt64(&s.failedBatches, 1)
+	return err
+}
+
+// sendRows 在一个事务内写入去重标识和日志，标识已存在说明该批次之前已写入成功
+func (s *TimescaleSender) sendRows(records []models.DNSLogRecord, token string) error {
+	ctx := context.Background()
+	if s.cfg.InsertTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.InsertTimeout)
+		defer cancel()
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "INSERT INTO dns_insert_tokens (token) VALUES ($1) ON CONFLICT DO NOTHING", token)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"dns_query_log"}, pgInsertColumns, pgRows(records)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// pgRows 按 pgInsertColumns 的顺序展开日志
+func pgRows(records []models.DNSLogRecord) pgx.CopyFromSource {
+	return pgx.CopyFromSlice(len(records), func(i int) ([]interface{}, error) {
+		r := &records[i]
+		ips := r.ResultIPs
+		if ips == nil {
+			ips = []string{}
+		}
+		return []interface{}{
+			r.Timestamp, int64(r.NodeID), r.ClientIP, r.Domain, int32(r.QueryType), int32(r.TimeMs), r.SpeedMs,
+			int32(r.ResultCount), ips, r.RawLog, r.Group, r.DomainCategory,
+			r.ClientSubnet, r.ClientCountry, int64(r.ClientASN), r.ClientASOrg, r.ClientPTR,
+			r.Upstream, r.Rcode, r.CacheHit, r.MatchedRule,
+		}, nil
+	})
+}
+
+// AckBatch 记录已写入的批次，供管理端检测缺失和乱序
+func (s *TimescaleSender) AckBatch(meta models.IngestBatch) error {
+	ctx := context.Background()
+	if s.cfg.InsertTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.InsertTimeout)
+		defer cancel()
+	}
+
+	var reread int16
+	if meta.Reread {
+		reread = 1
+	}
+	_, err := s.pool.Exec(ctx, `INSERT INTO dns_ingest_batches
+        (node_id, file_path, stream_id, seq, start_offset, end_offset, rows, reread, sent_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT (node_id, stream_id, seq) DO NOTHING`,
+		int64(meta.NodeID), meta.FilePath, meta.StreamID, int64(meta.Seq),
+		meta.StartOffset, meta.EndOffset, int32(meta.Rows), reread, meta.SentAt)
+	return err
+}
+
+// LoadDomainCategories 加载域名分类表（由管理端从域名集同步）
+func (s *TimescaleSender) LoadDomainCategories(ctx context.Context) (map[string]string, error) {
+	var exists bool
+	if err := s.pool.QueryRow(ctx, "SELECT to_regclass('domain_categories') IS NOT NULL").Scan(&exists); err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx, "SELECT domain, category FROM domain_categories")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make(map[string]string)
+	for rows.Next() {
+		var domain, category string
+		if err := rows.Scan(&domain, &category); err != nil {
+			continue
+		}
+		categories[domain] = category
+	}
+
+	return categories, rows.Err()
+}
+
+func (s *TimescaleSender) Close() {
+	if s.pool != nil {
+		s.pool.Close()
+	}
+}
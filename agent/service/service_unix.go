@@ -0,0 +1,19 @@
+//go:build !windows
+
+package service
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WaitForShutdown 阻塞到收到 SIGINT / SIGTERM，然后调用 stop 完成关闭
+func WaitForShutdown(name string, stop func()) error {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	stop()
+	return nil
+}
@@ -0,0 +1,53 @@
+//go:build windows
+
+package service
+
+import (
+	"os"
+	"os/signal"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// stopWaitHint 通知服务控制管理器关闭最多需要的时间，Agent 关闭时要等待收集器保存读取位置
+const stopWaitHint = 40 * time.Second
+
+// WaitForShutdown 由服务控制管理器（sc.exe 创建的服务）启动时按 Windows 服务运行，收到停止或关机请求后调用 stop；
+// 在控制台或 NSSM 下运行时等待 Ctrl+C
+func WaitForShutdown(name string, stop func()) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	if !isService {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt)
+		<-sigChan
+
+		stop()
+		return nil
+	}
+	return svc.Run(name, &handler{stop: stop})
+}
+
+type handler struct {
+	stop func()
+}
+
+// Execute 服务主循环，返回后服务控制管理器将服务标记为已停止
+func (h *handler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for req := range requests {
+		switch req.Cmd {
+		case svc.Interrogate:
+			status <- req.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending, WaitHint: uint32(stopWaitHint / time.Millisecond)}
+			h.stop()
+			return false, 0
+		}
+	}
+	return false, 0
+}
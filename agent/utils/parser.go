@@ -4,26 +4,66 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"smartdns-log-agent/models"
 )
 
-type LogParser struct {
-	regex          *regexp.Regexp
-	regexWithGroup *regexp.Regexp // 新增：支持带 group 字段的格式
+// 正则只在包初始化时编译一次，所有解析器实例共享
+var (
+	// speed 与 result 之间是若干个 "名称 值," 形式的可选字段（group、server、rcode、cache、rule），
+	// 旧版本 SmartDNS 不输出 group
+	logLineRegex = regexp.MustCompile(`\[([^\]]+)\]\s+(\S+)\s+query\s+(\S+),\s+type\s+(\d+),\s+time\s+(\d+)ms,\s+speed:\s+([-\d.]+)ms,((?:\s+[a-z_]+\s+\S+,)*)\s+result\s*(.*)`)
+
+	// 逐个取出可选字段
+	optionalFieldRegex = regexp.MustCompile(`([a-z_]+)\s+(\S+),`)
+)
+
+// logFields speed 与 result 之间的可选字段
+type logFields struct {
+	group       string
+	upstream    string // server：应答该查询的上游服务器
+	rcode       string // rcode：响应码，如 NOERROR、NXDOMAIN、SERVFAIL
+	cache       string // cache：hit 表示由缓存应答
+	matchedRule string // rule：命中的规则
 }
 
-func NewLogParser() *LogParser {
-	// 原始格式（不带 group）
-	regex := regexp.MustCompile(`\[([^\]]+)\]\s+(\S+)\s+query\s+(\S+),\s+type\s+(\d+),\s+time\s+(\d+)ms,\s+speed:\s+([-\d.]+)ms,\s+result\s*(.*)`)
+// set 按名称保存一个可选字段，不认识的字段忽略，以兼容新版本 SmartDNS 增加的字段
+func (f *logFields) set(name, value string) {
+	switch name {
+	case "group":
+		f.group = value
+	case "server":
+		f.upstream = value
+	case "rcode":
+		f.rcode = strings.ToUpper(value)
+	case "cache":
+		f.cache = strings.ToLower(value)
+	case "rule":
+		f.matchedRule = value
+	}
+}
+
+type LogParser struct {
+	regex *regexp.Regexp
+
+	// 解析统计
+	fastPathHits   int64
+	regexFallbacks int64
+	failures       int64
+}
 
-	// 新格式（带 group）
-	regexWithGroup := regexp.MustCompile(`\[([^\]]+)\]\s+(\S+)\s+query\s+(\S+),\s+type\s+(\d+),\s+time\s+(\d+)ms,\s+speed:\s+([-\d.]+)ms,\s+group\s+(\S+),\s+result\s*(.*)`)
+// ParserStats 解析器统计信息
+type ParserStats struct {
+	FastPathHits   int64 `json:"fast_path_hits"`  // 快速路径解析成功数
+	RegexFallbacks int64 `json:"regex_fallbacks"` // 回退到正则解析成功数
+	Failures       int64 `json:"failures"`        // 无法解析的行数
+}
 
+func NewLogParser() *LogParser {
 	return &LogParser{
-		regex:          regex,
-		regexWithGroup: regexWithGroup,
+		regex: logLineRegex,
 	}
 }
 
@@ -32,35 +72,132 @@ func (p *LogParser) Parse(line string, nodeID uint32) *models.DNSLogRecord {
 		return nil
 	}
 
-	// 先尝试匹配带 group 的格式
-	matches := p.regexWithGroup.FindStringSubmatch(line)
-	if matches != nil && len(matches) >= 9 {
-		return p.parseWithGroup(matches, nodeID, line)
+	// 标准格式走手写的快速路径
+	if record := p.parseFast(line, nodeID); record != nil {
+		atomic.AddInt64(&p.fastPathHits, 1)
+		return record
 	}
 
-	// 再尝试匹配不带 group 的格式
-	matches = p.regex.FindStringSubmatch(line)
-	if matches != nil && len(matches) >= 8 {
-		return p.parseWithoutGroup(matches, nodeID, line)
+	// 格式不标准的行（多余空格等）回退到正则
+	if record := p.parseRegex(line, nodeID); record != nil {
+		atomic.AddInt64(&p.regexFallbacks, 1)
+		return record
 	}
 
+	atomic.AddInt64(&p.failures, 1)
 	return nil
 }
 
-// parseWithGroup 解析带 group 字段的日志
-func (p *LogParser) parseWithGroup(matches []string, nodeID uint32, line string) *models.DNSLogRecord {
-	// 解析时间戳
-	timestamp, err := time.ParseInLocation("2006-01-02 15:04:05,000", matches[1], time.Local)
-	if err != nil {
-		timestamp, _ = time.ParseInLocation("2006-01-02 15:04:05", matches[1][:19], time.Local)
+// Stats 获取解析统计
+func (p *LogParser) Stats() ParserStats {
+	return ParserStats{
+		FastPathHits:   atomic.LoadInt64(&p.fastPathHits),
+		RegexFallbacks: atomic.LoadInt64(&p.regexFallbacks),
+		Failures:       atomic.LoadInt64(&p.failures),
+	}
+}
+
+// parseRegex 使用正则解析日志行
+func (p *LogParser) parseRegex(line string, nodeID uint32) *models.DNSLogRecord {
+	matches := p.regex.FindStringSubmatch(line)
+	if len(matches) < 9 {
+		return nil
 	}
 
 	queryType, _ := strconv.Atoi(matches[4])
 	timeMs, _ := strconv.Atoi(matches[5])
 	speedMs, _ := strconv.ParseFloat(matches[6], 32)
 
-	// 解析结果 IP（matches[8] 是 result 部分）
-	resultStr := strings.TrimSpace(matches[8])
+	// matches[7] 是可选字段，matches[8] 是 result 部分
+	var fields logFields
+	for _, field := range optionalFieldRegex.FindAllStringSubmatch(matches[7], -1) {
+		fields.set(field[1], field[2])
+	}
+	return buildRecord(line, nodeID, matches[1], matches[2], matches[3], matches[8], &fields, queryType, timeMs, speedMs)
+}
+
+// parseFast 手写分词解析标准格式：
+// [2024-01-01 12:00:00,123] 192.168.1.2 query example.com, type 1, time 3ms, speed: 12.5ms, group default, result 1.1.1.1, 2.2.2.2
+// speed 与 result 之间可带若干个 "名称 值, " 形式的可选字段，如 "server <上游地址>, "、"rcode NXDOMAIN, "、"cache hit, "
+// 任一位置不符合预期时返回 nil，由调用方回退到正则
+func (p *LogParser) parseFast(line string, nodeID uint32) *models.DNSLogRecord {
+	if len(line) < 2 || line[0] != '[' {
+		return nil
+	}
+	end := strings.IndexByte(line, ']')
+	if end < 0 || end+2 > len(line) || line[end+1] != ' ' {
+		return nil
+	}
+	timestampStr := line[1:end]
+	rest := line[end+2:]
+
+	// 客户端
+	sp := strings.IndexByte(rest, ' ')
+	if sp <= 0 {
+		return nil
+	}
+	clientIP := rest[:sp]
+	rest = rest[sp+1:]
+
+	// 域名
+	if !strings.HasPrefix(rest, "query ") {
+		return nil
+	}
+	rest = rest[len("query "):]
+	idx := strings.Index(rest, ", type ")
+	if idx <= 0 || strings.IndexByte(rest[:idx], ' ') >= 0 {
+		return nil
+	}
+	domain := rest[:idx]
+	rest = rest[idx+len(", type "):]
+
+	// 查询类型
+	queryType, rest, ok := cutUint(rest)
+	if !ok || !strings.HasPrefix(rest, ", time ") {
+		return nil
+	}
+	rest = rest[len(", time "):]
+
+	// 耗时
+	timeMs, rest, ok := cutUint(rest)
+	if !ok || !strings.HasPrefix(rest, "ms, speed: ") {
+		return nil
+	}
+	rest = rest[len("ms, speed: "):]
+
+	// 测速结果
+	idx = strings.Index(rest, "ms, ")
+	if idx <= 0 {
+		return nil
+	}
+	speedMs, err := strconv.ParseFloat(rest[:idx], 32)
+	if err != nil {
+		return nil
+	}
+	rest = rest[idx+len("ms, "):]
+
+	// 可选字段
+	var fields logFields
+	for !strings.HasPrefix(rest, "result") {
+		sp := strings.IndexByte(rest, ' ')
+		idx = strings.Index(rest, ", ")
+		if sp <= 0 || idx <= sp+1 || strings.IndexByte(rest[sp+1:idx], ' ') >= 0 {
+			return nil
+		}
+		fields.set(rest[:sp], rest[sp+1:idx])
+		rest = rest[idx+len(", "):]
+	}
+	result := rest[len("result"):]
+
+	return buildRecord(line, nodeID, timestampStr, clientIP, domain, result, &fields, int(queryType), int(timeMs), speedMs)
+}
+
+// buildRecord 根据解析出的字段构造日志记录
+func buildRecord(line string, nodeID uint32, timestampStr, clientIP, domain, result string, fields *logFields, queryType, timeMs int, speedMs float64) *models.DNSLogRecord {
+	timestamp := parseTimestamp(timestampStr)
+
+	// 解析结果 IP
+	resultStr := strings.TrimSpace(result)
 	var resultIPs []string
 	if resultStr != "" {
 		resultIPs = strings.Split(resultStr, ",")
@@ -69,55 +206,88 @@ func (p *LogParser) parseWithGroup(matches []string, nodeID uint32, line string)
 		}
 	}
 
+	// SmartDNS 的审计日志默认不输出响应码和缓存标记：有应答记为 NOERROR，
+	// 耗时 0ms 且有应答视为缓存命中；日志中带 rcode / cache 字段时以日志为准
+	rcode := fields.rcode
+	if rcode == "" && len(resultIPs) > 0 {
+		rcode = models.RcodeNoError
+	}
+	cacheHit := fields.cache == "hit"
+	if fields.cache == "" {
+		cacheHit = timeMs == 0 && len(resultIPs) > 0
+	}
+
 	return &models.DNSLogRecord{
 		Timestamp:   timestamp,
 		Date:        time.Date(timestamp.Year(), timestamp.Month(), timestamp.Day(), 0, 0, 0, 0, timestamp.Location()),
 		NodeID:      nodeID,
-		ClientIP:    matches[2],
-		Domain:      matches[3],
+		ClientIP:    clientIP,
+		Domain:      domain,
 		QueryType:   uint16(queryType),
 		TimeMs:      uint32(timeMs),
 		SpeedMs:     float32(speedMs),
 		ResultCount: uint8(len(resultIPs)),
 		ResultIPs:   resultIPs,
 		RawLog:      line,
-		Group:       strings.TrimSpace(matches[7]),
+		Group:       fields.group,
+		Upstream:    fields.upstream,
+		Rcode:       rcode,
+		CacheHit:    cacheHit,
+		MatchedRule: fields.matchedRule,
 	}
 }
 
-// parseWithoutGroup 解析不带 group 字段的日志
-func (p *LogParser) parseWithoutGroup(matches []string, nodeID uint32, line string) *models.DNSLogRecord {
-	// 解析时间戳
-	timestamp, err := time.ParseInLocation("2006-01-02 15:04:05,000", matches[1], time.Local)
-	if err != nil {
-		timestamp, _ = time.ParseInLocation("2006-01-02 15:04:05", matches[1][:19], time.Local)
+// parseTimestamp 解析 "2006-01-02 15:04:05,000" 格式的时间，标准格式按固定位置直接取数字
+func parseTimestamp(s string) time.Time {
+	if len(s) >= 19 && s[4] == '-' && s[7] == '-' && s[10] == ' ' && s[13] == ':' && s[16] == ':' {
+		year, ok1 := atoiFixed(s[0:4])
+		month, ok2 := atoiFixed(s[5:7])
+		day, ok3 := atoiFixed(s[8:10])
+		hour, ok4 := atoiFixed(s[11:13])
+		minute, ok5 := atoiFixed(s[14:16])
+		second, ok6 := atoiFixed(s[17:19])
+		millis := 0
+		ok7 := true
+		if len(s) == 23 && s[19] == ',' {
+			millis, ok7 = atoiFixed(s[20:23])
+		} else if len(s) != 19 {
+			ok7 = false
+		}
+		if ok1 && ok2 && ok3 && ok4 && ok5 && ok6 && ok7 {
+			return time.Date(year, time.Month(month), day, hour, minute, second, millis*int(time.Millisecond), time.Local)
+		}
 	}
 
-	queryType, _ := strconv.Atoi(matches[4])
-	timeMs, _ := strconv.Atoi(matches[5])
-	speedMs, _ := strconv.ParseFloat(matches[6], 32)
+	timestamp, err := time.ParseInLocation("2006-01-02 15:04:05,000", s, time.Local)
+	if err != nil && len(s) >= 19 {
+		timestamp, _ = time.ParseInLocation("2006-01-02 15:04:05", s[:19], time.Local)
+	}
+	return timestamp
+}
 
-	// 解析结果 IP（matches[7] 是 result 部分）
-	resultStr := strings.TrimSpace(matches[7])
-	var resultIPs []string
-	if resultStr != "" {
-		resultIPs = strings.Split(resultStr, ",")
-		for i := range resultIPs {
-			resultIPs[i] = strings.TrimSpace(resultIPs[i])
+// atoiFixed 解析定长的纯数字字符串
+func atoiFixed(s string) (int, bool) {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, false
 		}
+		n = n*10 + int(c-'0')
 	}
+	return n, true
+}
 
-	return &models.DNSLogRecord{
-		Timestamp:   timestamp,
-		Date:        time.Date(timestamp.Year(), timestamp.Month(), timestamp.Day(), 0, 0, 0, 0, timestamp.Location()),
-		NodeID:      nodeID,
-		ClientIP:    matches[2],
-		Domain:      matches[3],
-		QueryType:   uint16(queryType),
-		TimeMs:      uint32(timeMs),
-		SpeedMs:     float32(speedMs),
-		ResultCount: uint8(len(resultIPs)),
-		ResultIPs:   resultIPs,
-		RawLog:      line,
+// cutUint 读取开头的无符号整数，返回数值和剩余部分
+func cutUint(s string) (uint64, string, bool) {
+	i := 0
+	var n uint64
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		n = n*10 + uint64(s[i]-'0')
+		i++
+	}
+	if i == 0 {
+		return 0, s, false
 	}
+	return n, s[i:], true
 }
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+// parserCases 覆盖快速路径支持的各种日志格式，fast 表示该行应由快速路径解析
+var parserCases = []struct {
+	name string
+	line string
+	fast bool
+}{
+	{"旧版本无分组", "[2025-11-21 05:33:18,910] 10.1.102.201 query v2ray.com, type 1, time 63ms, speed: 29.4ms, result 172.67.149.148", true},
+	{"带分组", "[2025-11-21 05:33:19,011] 10.1.102.201 query v2raycn.com, type 1, time 99ms, speed: 28.8ms, group default, result 172.67.180.29", true},
+	{"带上游", "[2025-11-21 05:33:19,120] 10.1.102.201 query example.com, type 1, time 41ms, speed: 12.5ms, group default, server https://dns.google/dns-query, result 93.184.216.34", true},
+	{"响应码缓存规则", "[2025-11-21 05:33:19,230] 10.1.102.201 query ads.example.com, type 1, time 0ms, speed: -1.0ms, group default, rcode NXDOMAIN, cache miss, rule ads, result", true},
+	{"缓存命中", "[2025-11-21 05:33:19,240] 10.1.102.201 query example.com, type 1, time 0ms, speed: 12.5ms, cache hit, result 93.184.216.34", true},
+	{"多个结果", "[2025-11-21 05:33:19,300] 10.1.102.202 query example.org, type 28, time 5ms, speed: 3.1ms, result 2606:4700::1, 2606:4700::2", true},
+	{"无结果", "[2025-11-21 05:33:19,400] 10.1.102.203 query nx.example.org, type 1, time 12ms, speed: -1.0ms, result", true},
+	{"时间无毫秒", "[2025-11-21 05:33:19] 10.1.102.203 query example.net, type 65, time 7ms, speed: 2.0ms, result 1.2.3.4", true},
+	{"未知可选字段", "[2025-11-21 05:33:19,500] 10.1.102.204 query example.com, type 1, time 3ms, speed: 1.0ms, ttl 300, result 1.2.3.4", true},
+	{"多余空格", "[2025-11-21 05:33:19,600]  10.1.102.205 query example.com,  type 1, time 3ms, speed: 1.0ms, result 1.2.3.4", false},
+}
+
+// TestParseFastMatchesRegex 快速路径与正则解析的结果必须完全一致
+func TestParseFastMatchesRegex(t *testing.T) {
+	parser := NewLogParser()
+	for _, tc := range parserCases {
+		t.Run(tc.name, func(t *testing.T) {
+			regex := parser.parseRegex(tc.line, 7)
+			if regex == nil {
+				t.Fatalf("正则未能解析: %s", tc.line)
+			}
+
+			fast := parser.parseFast(tc.line, 7)
+			if !tc.fast {
+				if fast != nil {
+					t.Fatalf("非标准格式不应走快速路径: %s", tc.line)
+				}
+				return
+			}
+			if fast == nil {
+				t.Fatalf("快速路径未能解析: %s", tc.line)
+			}
+			if !reflect.DeepEqual(fast, regex) {
+				t.Errorf("解析结果不一致\nfast:  %+v\nregex: %+v", fast, regex)
+			}
+		})
+	}
+}
+
+// TestParseInvalid 无法识别的行计入失败数
+func TestParseInvalid(t *testing.T) {
+	parser := NewLogParser()
+	for _, line := range []string{
+		"smartdns starting",
+		"[2025-11-21 05:33:19,600] 10.1.102.205 query example.com",
+		"[2025-11-21 05:33:19,600] 10.1.102.205 query example.com, type x, time 3ms, speed: 1.0ms, result 1.2.3.4",
+	} {
+		if record := parser.Parse(line, 0); record != nil {
+			t.Errorf("不应解析成功: %s", line)
+		}
+	}
+	if stats := parser.Stats(); stats.Failures != 3 {
+		t.Errorf("失败数 = %d，期望 3", stats.Failures)
+	}
+}
+
+func benchmarkParse(b *testing.B, parse func(p *LogParser, line string) bool) {
+	parser := NewLogParser()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tc := parserCases[i%len(parserCases)]
+		if !parse(parser, tc.line) {
+			b.Fatalf("解析失败: %s", tc.line)
+		}
+	}
+}
+
+// BenchmarkParse 实际使用的解析入口：快速路径，失败时回退正则
+func BenchmarkParse(b *testing.B) {
+	benchmarkParse(b, func(p *LogParser, line string) bool { return p.Parse(line, 0) != nil })
+}
+
+// BenchmarkParseRegex 纯正则解析，作为快速路径的对照
+func BenchmarkParseRegex(b *testing.B) {
+	benchmarkParse(b, func(p *LogParser, line string) bool { return p.parseRegex(line, 0) != nil })
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm/logger"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// cliCommand 命令行子命令，name 可以包含空格（如 "backup now"）
+type cliCommand struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+var cliCommands []cliCommand
+
+func init() {
+	cliCommands = []cliCommand{
+		{"serve", "serve", "启动 Web 服务和后台任务（默认）", func(args []string) error {
+			runServe()
+			return nil
+		}},
+		{"migrate", "migrate [--dry-run]", "执行数据库迁移（SQLite 和 ClickHouse）后退出，--dry-run 只列出待执行的迁移", runMigrate},
+		{"backup now", "backup now [--config ID] [--output FILE]", "立即备份数据库：指定备份配置时按配置执行，否则导出到本地文件", runBackupNow},
+		{"restore", "restore FILE [--password PASS] | restore --history ID [--password PASS]", "从备份文件或备份历史恢复数据库（请先停止服务）", runRestore},
+		{"user create", "user create --username NAME [--password PASS] [--email EMAIL] [--role admin|user]", "创建本地用户", runUserCreate},
+		{"export config", "export config --node ID [--base FILE] [--output FILE]", "导出节点完整同步后的 smartdns.conf", runExportConfig},
+	}
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		runServe()
+		return
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage()
+		return
+	}
+
+	for _, cmd := range cliCommands {
+		words := strings.Fields(cmd.name)
+		if len(args) < len(words) || strings.Join(args[:len(words)], " ") != cmd.name {
+			continue
+		}
+		if cmd.name != "serve" {
+			database.LogLevel = logger.Warn
+		}
+		if err := cmd.run(args[len(words):]); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", strings.Join(args, " "))
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Println("SmartDNS Manager")
+	fmt.Println()
+	fmt.Println("用法:")
+	fmt.Println("  smartdns-manager [命令] [参数]")
+	fmt.Println()
+	fmt.Println("命令:")
+	for _, cmd := range cliCommands {
+		fmt.Printf("  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Println()
+	fmt.Println("参数:")
+	for _, cmd := range cliCommands {
+		fmt.Printf("  %s\n", cmd.usage)
+	}
+	fmt.Println()
+	fmt.Println("数据库路径等配置与服务相同，从环境变量或 .env 读取（如 DB_PATH）")
+}
+
+// parseFlags 解析参数，允许参数和位置参数交替出现，返回位置参数
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "只列出待执行的迁移，不修改数据库")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		database.OpenDB()
+		if _, err := database.RunSQLiteMigrations(true); err != nil {
+			return err
+		}
+		// 日志存储使用 TimescaleDB 时由 Agent 建表，没有版本化迁移
+		if config.GetLogStorageType() != config.LogStorageTimescale {
+			if err := database.OpenClickHouse(); err != nil {
+				return err
+			}
+			if _, err := database.RunClickHouseMigrations(context.Background(), database.CHConn, true); err != nil {
+				return err
+			}
+		}
+		for _, report := range database.MigrationReports() {
+			fmt.Printf("%s: 当前版本 v%d，最新版本 v%d，待执行 %d 个迁移\n",
+				report.Target, report.Current, report.Latest, len(report.Pending))
+			for _, m := range report.Pending {
+				fmt.Printf("  - v%d %s\n", m.Version, m.Description)
+			}
+		}
+		fmt.Println("新增的表和列由 AutoMigrate 在执行迁移时创建，不在预览中列出")
+		return nil
+	}
+
+	database.InitDB()
+	if config.GetLogStorageType() != config.LogStorageTimescale {
+		database.InitClickHouse()
+	}
+	for _, report := range database.MigrationReports() {
+		fmt.Printf("%s: 已执行 %d 个迁移，当前版本 v%d\n", report.Target, len(report.Applied), report.Latest)
+	}
+	fmt.Printf("✅ 数据库迁移完成: %s\n", config.GetConfig().DBPath)
+	return nil
+}
+
+func runBackupNow(args []string) error {
+	fs := flag.NewFlagSet("backup now", flag.ContinueOnError)
+	configID := fs.Uint("config", 0, "备份配置ID，按配置执行（压缩、上传 S3、保留策略）")
+	output := fs.String("output", "", "未指定备份配置时的输出文件，默认 smartdns_backup_<时间>.db")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	database.OpenDB()
+	if *configID != 0 {
+		history, err := services.NewDatabaseBackupService(database.DB, nil).BackupNow(*configID)
+		if err != nil {
+			return fmt.Errorf("备份失败: %w", err)
+		}
+		location := history.FilePath
+		if history.S3Key != "" {
+			location = "s3://" + history.S3Key
+		}
+		fmt.Printf("✅ 备份完成: %s (%d 字节) %s\n", history.FileName, history.FileSize, location)
+		return nil
+	}
+
+	path := *output
+	if path == "" {
+		path = fmt.Sprintf("smartdns_backup_%s.db", time.Now().Format("20060102_150405"))
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("文件已存在: %s", path)
+	}
+	// VACUUM INTO 在服务运行时也能得到一致的快照
+	if err := database.DB.Exec("VACUUM INTO ?", path).Error; err != nil {
+		return fmt.Errorf("备份失败: %w", err)
+	}
+	abs, _ := filepath.Abs(path)
+	fmt.Printf("✅ 备份完成: %s\n", abs)
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	historyID := fs.Uint("history", 0, "备份历史ID，从本地路径或 S3 下载备份")
+	password := fs.String("password", "", "加密备份的密码（使用托管密钥的备份无需填写）")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if (*historyID == 0) == (len(positional) == 0) {
+		return fmt.Errorf("请指定备份文件或 --history（二选一）")
+	}
+
+	var report *models.BackupRestoreReport
+	if *historyID != 0 {
+		database.OpenDB()
+		report, err = services.NewDatabaseBackupService(database.DB, nil).RestoreBackup(&models.BackupRestoreRequest{
+			BackupHistoryID: *historyID,
+			BackupPassword:  *password,
+		})
+	} else {
+		report, err = services.NewDatabaseBackupService(nil, nil).RestoreFile(positional[0], *password)
+	}
+	for _, step := range report.Steps {
+		fmt.Printf("  - %s\n", step)
+	}
+	if err != nil {
+		return fmt.Errorf("恢复失败: %w", err)
+	}
+	fmt.Printf("✅ 数据库已恢复到 %s，请重新启动服务\n", config.GetConfig().DBPath)
+	if report.SnapshotPath != "" {
+		fmt.Printf("恢复前的数据库已保存到 %s\n", report.SnapshotPath)
+	}
+	return nil
+}
+
+func runUserCreate(args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	username := fs.String("username", "", "用户名")
+	password := fs.String("password", "", "密码，不填时从标准输入读取")
+	email := fs.String("email", "", "邮箱")
+	role := fs.String("role", "admin", "角色：admin 或 user")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("请指定 --username")
+	}
+	if *role != "admin" && *role != "user" {
+		return fmt.Errorf("角色只能是 admin 或 user")
+	}
+
+	if *password == "" {
+		fmt.Fprint(os.Stderr, "密码: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("读取密码失败: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+	if len(*password) < 6 {
+		return fmt.Errorf("密码至少 6 位")
+	}
+
+	database.InitDB()
+	var count int64
+	database.DB.Model(&models.User{}).Where("username = ?", *username).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("用户名已存在: %s", *username)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("密码加密失败: %w", err)
+	}
+	user := models.User{
+		Username:   *username,
+		Password:   string(hashedPassword),
+		Role:       *role,
+		IsActive:   true,
+		AuthSource: models.AuthSourceLocal,
+	}
+	// 邮箱有唯一索引，未填写时保持 NULL 以免与其他用户冲突
+	query := database.DB
+	if *email != "" {
+		user.Email = *email
+	} else {
+		query = query.Omit("Email")
+	}
+	if err := query.Create(&user).Error; err != nil {
+		return fmt.Errorf("创建用户失败: %w", err)
+	}
+	fmt.Printf("✅ 已创建用户 %s（ID: %d，角色: %s）\n", user.Username, user.ID, user.Role)
+	return nil
+}
+
+func runExportConfig(args []string) error {
+	fs := flag.NewFlagSet("export config", flag.ContinueOnError)
+	nodeID := fs.Uint("node", 0, "节点ID")
+	base := fs.String("base", "", "作为基础的本地配置文件，不填时通过 SSH 读取节点当前配置")
+	output := fs.String("output", "", "输出文件，默认输出到标准输出")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *nodeID == 0 {
+		return fmt.Errorf("请指定 --node")
+	}
+
+	database.OpenDB()
+	var node models.Node
+	if err := database.DB.First(&node, *nodeID).Error; err != nil {
+		return fmt.Errorf("节点不存在: %d", *nodeID)
+	}
+
+	var content string
+	if *base != "" {
+		data, err := os.ReadFile(*base)
+		if err != nil {
+			return fmt.Errorf("读取配置文件失败: %w", err)
+		}
+		content = string(data)
+	} else {
+		client, err := services.NewSSHClient(&node)
+		if err != nil {
+			return fmt.Errorf("连接节点失败: %w", err)
+		}
+		content, err = client.ReadFile(node.ConfigPath)
+		client.Close()
+		if err != nil {
+			return fmt.Errorf("读取节点配置失败: %w", err)
+		}
+	}
+
+	parser := services.NewConfigParser()
+	parsed, err := parser.Parse(content)
+	if err != nil {
+		return fmt.Errorf("解析配置失败: %w", err)
+	}
+	result := parser.Generate(services.NewConfigSyncService().BuildExpectedConfig(parsed, node.ID))
+
+	if *output == "" {
+		fmt.Print(result)
+		return nil
+	}
+	if err := os.WriteFile(*output, []byte(result), 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "✅ 已导出节点 %s 的配置到 %s\n", node.Name, *output)
+	return nil
+}
@@ -0,0 +1,552 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+// typeRef 类型表达式及其所在的包
+type typeRef struct {
+	pkg  string
+	expr ast.Expr
+}
+
+// handlerInfo 从处理器源码推导出的接口信息
+type handlerInfo struct {
+	Summary     string
+	Description string
+	Request     *typeRef
+	Query       []string
+	Responses   map[int]schema // 2xx 状态码 -> 响应体模式
+	Errors      map[int]bool
+	Download    bool // 以文件或二进制流返回
+	Stream      bool // Server-Sent Events
+}
+
+// handlerAnalyzer 分析单个处理器函数，记录局部变量类型用于推导请求体和响应体
+type handlerAnalyzer struct {
+	src     *source
+	schemas *schemaBuilder
+	pkg     string
+	vars    map[string]typeRef
+	info    *handlerInfo
+	query   map[string]bool
+	visited map[*ast.FuncDecl]bool
+}
+
+var statusCodes = map[string]int{
+	"StatusOK":                    200,
+	"StatusCreated":               201,
+	"StatusAccepted":              202,
+	"StatusNoContent":             204,
+	"StatusPartialContent":        206,
+	"StatusBadRequest":            400,
+	"StatusUnauthorized":          401,
+	"StatusForbidden":             403,
+	"StatusNotFound":              404,
+	"StatusMethodNotAllowed":      405,
+	"StatusConflict":              409,
+	"StatusGone":                  410,
+	"StatusRequestEntityTooLarge": 413,
+	"StatusUnprocessableEntity":   422,
+	"StatusPreconditionRequired":  428,
+	"StatusTooManyRequests":       429,
+	"StatusInternalServerError":   500,
+	"StatusNotImplemented":        501,
+	"StatusBadGateway":            502,
+	"StatusServiceUnavailable":    503,
+	"StatusGatewayTimeout":        504,
+}
+
+// stdlibResults 常用标准库函数的第一个返回值类型
+var stdlibResults = map[string]ast.Expr{
+	"strconv.Atoi":       ast.NewIdent("int"),
+	"strconv.ParseInt":   ast.NewIdent("int64"),
+	"strconv.ParseUint":  ast.NewIdent("uint64"),
+	"strconv.ParseFloat": ast.NewIdent("float64"),
+	"strconv.ParseBool":  ast.NewIdent("bool"),
+	"strconv.Itoa":       ast.NewIdent("string"),
+	"fmt.Sprintf":        ast.NewIdent("string"),
+	"time.Now":           &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Time")},
+	"time.Since":         &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Duration")},
+}
+
+// analyzeHandler 分析处理器，fn 为 nil 时返回空信息
+func analyzeHandler(src *source, schemas *schemaBuilder, pkg string, fn *ast.FuncDecl) *handlerInfo {
+	info := &handlerInfo{Responses: make(map[int]schema), Errors: make(map[int]bool)}
+	if fn == nil {
+		return info
+	}
+	info.Summary, info.Description = docText(fn.Doc, fn.Name.Name)
+
+	a := &handlerAnalyzer{
+		src:     src,
+		schemas: schemas,
+		pkg:     pkg,
+		vars:    make(map[string]typeRef),
+		info:    info,
+		query:   make(map[string]bool),
+		visited: make(map[*ast.FuncDecl]bool),
+	}
+	a.walk(fn, true)
+	return info
+}
+
+// walk 遍历函数体，top 为 false 时是处理器调用的辅助函数，只收集查询参数和请求体
+func (a *handlerAnalyzer) walk(fn *ast.FuncDecl, top bool) {
+	if fn.Body == nil || a.visited[fn] {
+		return
+	}
+	a.visited[fn] = true
+
+	fields := fn.Type.Params.List
+	if fn.Recv != nil {
+		fields = append(append([]*ast.Field{}, fn.Recv.List...), fields...)
+	}
+	for _, field := range fields {
+		for _, name := range field.Names {
+			a.vars[name.Name] = typeRef{a.pkg, field.Type}
+		}
+	}
+
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.DeclStmt:
+			a.declare(node)
+		case *ast.AssignStmt:
+			a.assign(node)
+		case *ast.CallExpr:
+			a.call(node, top)
+		}
+		return true
+	})
+}
+
+func (a *handlerAnalyzer) declare(stmt *ast.DeclStmt) {
+	decl, ok := stmt.Decl.(*ast.GenDecl)
+	if !ok || decl.Tok != token.VAR {
+		return
+	}
+	for _, spec := range decl.Specs {
+		value, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		for i, name := range value.Names {
+			if value.Type != nil {
+				a.vars[name.Name] = typeRef{a.pkg, value.Type}
+			} else if i < len(value.Values) {
+				if ref, ok := a.infer(value.Values[i]); ok {
+					a.vars[name.Name] = ref
+				}
+			}
+		}
+	}
+}
+
+func (a *handlerAnalyzer) assign(stmt *ast.AssignStmt) {
+	for i, lhs := range stmt.Lhs {
+		ident, ok := lhs.(*ast.Ident)
+		if !ok || ident.Name == "_" {
+			continue
+		}
+		// 已声明的变量保持原类型
+		if _, exists := a.vars[ident.Name]; exists && stmt.Tok != token.DEFINE {
+			continue
+		}
+		var ref typeRef
+		var found bool
+		if len(stmt.Rhs) == len(stmt.Lhs) {
+			ref, found = a.infer(stmt.Rhs[i])
+		} else if call, isCall := stmt.Rhs[0].(*ast.CallExpr); isCall {
+			ref, found = a.result(call, i)
+		}
+		if found {
+			a.vars[ident.Name] = ref
+		}
+	}
+}
+
+func (a *handlerAnalyzer) call(call *ast.CallExpr, top bool) {
+	target, method := selector(call.Fun)
+	switch method {
+	case "ShouldBindJSON", "ShouldBind", "BindJSON", "ShouldBindBodyWith":
+		if a.info.Request == nil && len(call.Args) > 0 {
+			if ref, ok := a.infer(call.Args[0]); ok {
+				a.info.Request = &ref
+			}
+		}
+		return
+	case "Query", "DefaultQuery", "GetQuery", "QueryArray":
+		if name := stringLit(firstArg(call)); name != "" && target != "" {
+			a.addQuery(name)
+		}
+		return
+	}
+
+	if !top {
+		a.followHelper(call)
+		return
+	}
+
+	switch method {
+	case "JSON", "AbortWithStatusJSON", "IndentedJSON":
+		if len(call.Args) != 2 {
+			return
+		}
+		status := a.status(call.Args[0])
+		if status >= 200 && status < 300 {
+			if _, exists := a.info.Responses[status]; !exists {
+				a.info.Responses[status] = a.valueSchema(call.Args[1])
+			}
+		} else if status > 0 {
+			a.info.Errors[status] = true
+		}
+		return
+	case "File", "FileAttachment", "FileFromFS", "DataFromReader", "Data":
+		a.info.Download = true
+		return
+	case "SSEvent", "Stream":
+		a.info.Stream = true
+		return
+	}
+	a.followHelper(call)
+}
+
+// followHelper 辅助函数接收了 gin.Context 或 c.Query 时继续分析其中的查询参数
+func (a *handlerAnalyzer) followHelper(call *ast.CallExpr) {
+	fn, fnPkg := a.callee(call)
+	if fn == nil {
+		return
+	}
+	for i, arg := range call.Args {
+		if a.isQueryFunc(arg) {
+			a.queryFuncParams(fn, fnPkg, i, make(map[*ast.FuncDecl]bool))
+		}
+		if ident, ok := arg.(*ast.Ident); ok && ident.Name == "c" && fnPkg == "handlers" {
+			helper := &handlerAnalyzer{
+				src:     a.src,
+				schemas: a.schemas,
+				pkg:     fnPkg,
+				vars:    make(map[string]typeRef),
+				info:    a.info,
+				query:   a.query,
+				visited: a.visited,
+			}
+			helper.walk(fn, false)
+		}
+	}
+}
+
+// isQueryFunc 参数是 c.Query 或同类型的 func(string) string 变量
+func (a *handlerAnalyzer) isQueryFunc(arg ast.Expr) bool {
+	if _, name := selector(arg); name == "Query" {
+		return true
+	}
+	if ident, ok := arg.(*ast.Ident); ok {
+		_, isFunc := a.vars[ident.Name].expr.(*ast.FuncType)
+		return isFunc
+	}
+	return false
+}
+
+// queryFuncParams 参数是 func(string) string（传入 c.Query）时，收集对它的调用中的参数名，
+// 它再被传给同包的其他函数（如 queryTypeParam(query)）时继续收集
+func (a *handlerAnalyzer) queryFuncParams(fn *ast.FuncDecl, pkg string, index int, seen map[*ast.FuncDecl]bool) {
+	if seen[fn] {
+		return
+	}
+	seen[fn] = true
+	var param string
+	i := 0
+	for _, field := range fn.Type.Params.List {
+		for _, name := range field.Names {
+			if i == index {
+				param = name.Name
+			}
+			i++
+		}
+	}
+	if param == "" || fn.Body == nil {
+		return
+	}
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == param {
+				if name := stringLit(firstArg(call)); name != "" {
+					a.addQuery(name)
+				}
+			}
+			for i, arg := range call.Args {
+				if ident, ok := arg.(*ast.Ident); ok && ident.Name == param {
+					if fun, ok := call.Fun.(*ast.Ident); ok {
+						if next := a.src.funcs[pkg][fun.Name]; next != nil {
+							a.queryFuncParams(next, pkg, i, seen)
+						}
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+func (a *handlerAnalyzer) addQuery(name string) {
+	if !a.query[name] {
+		a.query[name] = true
+		a.info.Query = append(a.info.Query, name)
+	}
+}
+
+func (a *handlerAnalyzer) status(expr ast.Expr) int {
+	if _, name := selector(expr); name != "" {
+		return statusCodes[name]
+	}
+	if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.INT {
+		code, _ := strconv.Atoi(lit.Value)
+		return code
+	}
+	// 局部变量等无法确定取值的状态码忽略
+	return 0
+}
+
+// valueSchema 响应值的模式，gin.H 字面量按键展开，其他表达式按推导出的类型
+func (a *handlerAnalyzer) valueSchema(expr ast.Expr) schema {
+	if lit, ok := expr.(*ast.CompositeLit); ok && isGinH(lit.Type) {
+		properties := schema{}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			if key := stringLit(kv.Key); key != "" {
+				properties[key] = a.valueSchema(kv.Value)
+			}
+		}
+		return schema{"type": "object", "properties": properties}
+	}
+	ref, ok := a.infer(expr)
+	if !ok {
+		return schema{}
+	}
+	return a.schemas.typeSchema(ref.pkg, ref.expr)
+}
+
+// isGinH gin.H 或 map[string]interface{} 字面量
+func isGinH(expr ast.Expr) bool {
+	if pkg, name := selector(expr); pkg == "gin" && name == "H" {
+		return true
+	}
+	if m, ok := expr.(*ast.MapType); ok {
+		_, isInterface := m.Value.(*ast.InterfaceType)
+		return isInterface
+	}
+	return false
+}
+
+// infer 推导表达式的类型
+func (a *handlerAnalyzer) infer(expr ast.Expr) (typeRef, bool) {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if ref, ok := a.vars[expr.Name]; ok {
+			return ref, true
+		}
+		if expr.Name == "true" || expr.Name == "false" {
+			return typeRef{"", ast.NewIdent("bool")}, true
+		}
+	case *ast.CompositeLit:
+		if expr.Type != nil {
+			return typeRef{a.pkg, expr.Type}, true
+		}
+	case *ast.UnaryExpr:
+		if expr.Op == token.AND {
+			return a.infer(expr.X)
+		}
+	case *ast.StarExpr:
+		return a.infer(expr.X)
+	case *ast.ParenExpr:
+		return a.infer(expr.X)
+	case *ast.BasicLit:
+		switch expr.Kind {
+		case token.STRING:
+			return typeRef{"", ast.NewIdent("string")}, true
+		case token.INT:
+			return typeRef{"", ast.NewIdent("int")}, true
+		case token.FLOAT:
+			return typeRef{"", ast.NewIdent("float64")}, true
+		}
+	case *ast.BinaryExpr:
+		switch expr.Op {
+		case token.EQL, token.NEQ, token.LSS, token.GTR, token.LEQ, token.GEQ, token.LAND, token.LOR:
+			return typeRef{"", ast.NewIdent("bool")}, true
+		}
+		if ref, ok := a.infer(expr.X); ok {
+			return ref, true
+		}
+		return a.infer(expr.Y)
+	case *ast.SelectorExpr:
+		if base, ok := a.infer(expr.X); ok {
+			return a.field(base, expr.Sel.Name)
+		}
+	case *ast.IndexExpr:
+		if base, ok := a.infer(expr.X); ok {
+			switch t := a.underlying(base).expr.(type) {
+			case *ast.ArrayType:
+				return typeRef{base.pkg, t.Elt}, true
+			case *ast.MapType:
+				return typeRef{base.pkg, t.Value}, true
+			}
+		}
+	case *ast.SliceExpr:
+		return a.infer(expr.X)
+	case *ast.CallExpr:
+		return a.result(expr, 0)
+	}
+	return typeRef{}, false
+}
+
+// result 函数调用第 index 个返回值的类型
+func (a *handlerAnalyzer) result(call *ast.CallExpr, index int) (typeRef, bool) {
+	if ident, ok := call.Fun.(*ast.Ident); ok {
+		switch ident.Name {
+		case "make", "new":
+			if len(call.Args) > 0 && index == 0 {
+				return typeRef{a.pkg, call.Args[0]}, true
+			}
+			return typeRef{}, false
+		case "len", "cap":
+			return typeRef{"", ast.NewIdent("int")}, true
+		case "append":
+			if len(call.Args) > 0 {
+				return a.infer(call.Args[0])
+			}
+		case "string":
+			return typeRef{"", ast.NewIdent("string")}, true
+		}
+	}
+	if _, name := selector(call.Fun); name == "Error" || name == "Format" || name == "String" {
+		return typeRef{"", ast.NewIdent("string")}, true
+	}
+	if pkg, name := selector(call.Fun); index == 0 && stdlibResults[pkg+"."+name] != nil {
+		return typeRef{"", stdlibResults[pkg+"."+name]}, true
+	}
+
+	fn, fnPkg := a.callee(call)
+	if fn == nil || fn.Type.Results == nil {
+		return typeRef{}, false
+	}
+	i := 0
+	for _, field := range fn.Type.Results.List {
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+		for j := 0; j < count; j++ {
+			if i == index {
+				return typeRef{fnPkg, field.Type}, true
+			}
+			i++
+		}
+	}
+	return typeRef{}, false
+}
+
+// callee 被调用的函数声明：同包函数、pkg.Func 或变量的方法
+func (a *handlerAnalyzer) callee(call *ast.CallExpr) (*ast.FuncDecl, string) {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return a.src.funcs[a.pkg][fun.Name], a.pkg
+	case *ast.SelectorExpr:
+		if ident, ok := fun.X.(*ast.Ident); ok {
+			if funcs, isPkg := a.src.funcs[ident.Name]; isPkg {
+				if _, shadowed := a.vars[ident.Name]; !shadowed {
+					return funcs[fun.Sel.Name], ident.Name
+				}
+			}
+		}
+		recvPkg, recv := a.pkg, ""
+		if base, ok := a.infer(fun.X); ok {
+			recvPkg, recv = a.named(base)
+		}
+		if fn := a.src.findMethod(recvPkg, recv, fun.Sel.Name); fn != nil {
+			return fn, a.declPkg(fn)
+		}
+	}
+	return nil, ""
+}
+
+// declPkg 函数声明所在的包
+func (a *handlerAnalyzer) declPkg(fn *ast.FuncDecl) string {
+	for pkg, methods := range a.src.methods {
+		for _, m := range methods {
+			if m == fn {
+				return pkg
+			}
+		}
+	}
+	return a.pkg
+}
+
+// named 命名类型的包名和类型名
+func (a *handlerAnalyzer) named(ref typeRef) (string, string) {
+	expr := ref.expr
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return ref.pkg, expr.Name
+	case *ast.SelectorExpr:
+		if ident, ok := expr.X.(*ast.Ident); ok {
+			return ident.Name, expr.Sel.Name
+		}
+	}
+	return ref.pkg, ""
+}
+
+// underlying 展开指针和命名类型
+func (a *handlerAnalyzer) underlying(ref typeRef) typeRef {
+	for depth := 0; depth < 5; depth++ {
+		if star, ok := ref.expr.(*ast.StarExpr); ok {
+			ref.expr = star.X
+			continue
+		}
+		pkg, name := a.named(ref)
+		spec := a.src.types[pkg][name]
+		if name == "" || spec == nil {
+			return ref
+		}
+		ref = typeRef{pkg, spec.Type}
+	}
+	return ref
+}
+
+// field 结构体字段的类型，包括嵌入结构体的字段
+func (a *handlerAnalyzer) field(base typeRef, name string) (typeRef, bool) {
+	st, ok := a.underlying(base).expr.(*ast.StructType)
+	if !ok {
+		return typeRef{}, false
+	}
+	pkg := a.underlying(base).pkg
+	for _, field := range st.Fields.List {
+		for _, ident := range field.Names {
+			if ident.Name == name {
+				return typeRef{pkg, field.Type}, true
+			}
+		}
+		if len(field.Names) == 0 {
+			if ref, ok := a.field(typeRef{pkg, field.Type}, name); ok {
+				return ref, true
+			}
+		}
+	}
+	return typeRef{}, false
+}
+
+func firstArg(call *ast.CallExpr) ast.Expr {
+	if len(call.Args) == 0 {
+		return nil
+	}
+	return call.Args[0]
+}
@@ -0,0 +1,43 @@
+// openapi-gen 从路由注册代码和处理器源码生成 OpenAPI 3 接口文档。
+//
+// 路由取自 routes.go 的 registerRoutes，请求体取自处理器中 ShouldBindJSON 绑定的变量类型，
+// 查询参数取自 c.Query / c.DefaultQuery，响应取自 2xx 状态的 c.JSON，模型结构由 json 标签推导。
+// 只依赖标准库，不需要编译整个项目。
+//
+// 用法（在 backend 目录下执行）: go generate 或 go run ./cmd/openapi-gen -o docs/openapi.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	root := flag.String("root", ".", "backend 目录")
+	output := flag.String("o", "docs/openapi.json", "输出文件")
+	version := flag.String("version", "v1", "接口版本，写入 info.version")
+	flag.Parse()
+
+	src, err := loadSource(*root, "handlers", "models", "services", "middleware")
+	if err != nil {
+		log.Fatalf("解析源码失败: %v", err)
+	}
+	routes, err := parseRoutes(filepath.Join(*root, "routes.go"))
+	if err != nil {
+		log.Fatalf("解析路由失败: %v", err)
+	}
+
+	spec := newSpecBuilder(src, *version).build(routes)
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		log.Fatalf("序列化文档失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(*root, *output), append(data, '\n'), 0644); err != nil {
+		log.Fatalf("写入文档失败: %v", err)
+	}
+	fmt.Printf("已生成 %s：%d 个接口\n", *output, len(routes))
+}
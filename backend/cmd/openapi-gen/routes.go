@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// route 一条注册的接口
+type route struct {
+	Method      string
+	Path        string // gin 路由模板，不含 /api/v1 前缀
+	Handler     ast.Expr
+	Auth        bool // 需要登录（Bearer JWT 或 API 令牌）
+	Confirm     bool // 破坏性操作，需要 dry_run 预估后携带确认令牌提交
+	SuperAdmin  bool // 仅超级管理员
+	HandlerPkg  string
+	HandlerRecv string
+	HandlerName string
+}
+
+var httpMethods = map[string]bool{"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true}
+
+// group 路由分组的前缀和认证要求
+type group struct {
+	prefix string
+	auth   bool
+}
+
+// parseRoutes 解析 registerRoutes 中注册的路由，跟踪分组前缀和 Use 的认证中间件
+func parseRoutes(path string) ([]route, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var fn *ast.FuncDecl
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && decl.Name.Name == "registerRoutes" {
+			fn = decl
+		}
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("%s 中没有 registerRoutes", path)
+	}
+
+	groups := map[string]*group{}
+	receivers := map[string]string{} // 参数名 -> 处理器类型名
+	for _, param := range fn.Type.Params.List {
+		typeName := typeIdent(param.Type)
+		for _, name := range param.Names {
+			if typeName == "RouterGroup" {
+				groups[name.Name] = &group{}
+			} else {
+				receivers[name.Name] = typeName
+			}
+		}
+	}
+
+	var routes []route
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			// x := y.Group("/prefix")
+			if len(node.Lhs) != 1 || len(node.Rhs) != 1 {
+				return true
+			}
+			call, ok := node.Rhs[0].(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			parent, method := selector(call.Fun)
+			if method != "Group" || groups[parent] == nil || len(call.Args) == 0 {
+				return true
+			}
+			if name, ok := node.Lhs[0].(*ast.Ident); ok {
+				groups[name.Name] = &group{
+					prefix: groups[parent].prefix + stringLit(call.Args[0]),
+					auth:   groups[parent].auth,
+				}
+			}
+		case *ast.CallExpr:
+			target, method := selector(node.Fun)
+			g := groups[target]
+			if g == nil {
+				return true
+			}
+			if method == "Use" {
+				for _, arg := range node.Args {
+					if _, name := selector(callFun(arg)); name == "AuthMiddleware" {
+						g.auth = true
+					}
+				}
+				return true
+			}
+			if !httpMethods[method] || len(node.Args) < 2 {
+				return true
+			}
+			r := route{
+				Method:  method,
+				Path:    g.prefix + stringLit(node.Args[0]),
+				Handler: node.Args[len(node.Args)-1],
+				Auth:    g.auth,
+			}
+			for _, arg := range node.Args[1 : len(node.Args)-1] {
+				if ident, ok := callFun(arg).(*ast.Ident); ok && ident.Name == "confirm" {
+					r.Confirm = true
+				}
+				if _, name := selector(callFun(arg)); name == "SuperAdminRequired" {
+					r.SuperAdmin = true
+				}
+			}
+			r.HandlerPkg, r.HandlerRecv, r.HandlerName = resolveHandler(r.Handler, receivers)
+			routes = append(routes, r)
+		}
+		return true
+	})
+	return routes, nil
+}
+
+// resolveHandler 处理器所在的包、接收者类型和函数名
+//
+// 支持 handlers.X、处理器实例的方法 h.X，以及返回处理器的工厂函数 handlers.X(...)
+func resolveHandler(expr ast.Expr, receivers map[string]string) (pkg, recv, name string) {
+	if call, ok := expr.(*ast.CallExpr); ok {
+		expr = call.Fun
+	}
+	target, name := selector(expr)
+	if recvType, ok := receivers[target]; ok {
+		return "handlers", recvType, name
+	}
+	return target, "", name
+}
+
+// selector 解析 x.Name 形式的表达式
+func selector(expr ast.Expr) (string, string) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", ""
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", ""
+	}
+	return ident.Name, sel.Sel.Name
+}
+
+// callFun 函数调用表达式的被调用部分，非调用时返回原表达式
+func callFun(expr ast.Expr) ast.Expr {
+	if call, ok := expr.(*ast.CallExpr); ok {
+		return call.Fun
+	}
+	return expr
+}
+
+// typeIdent 类型表达式的类型名，忽略指针和包名
+func typeIdent(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return typeIdent(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+func stringLit(expr ast.Expr) string {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	value, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// openAPIPath 将 gin 路由模板转为 OpenAPI 路径，返回路径参数
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	if path == "" {
+		return "/", params
+	}
+	return strings.Join(segments, "/"), params
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// schema OpenAPI 模式对象
+type schema map[string]interface{}
+
+var basicSchemas = map[string]schema{
+	"string":      {"type": "string"},
+	"bool":        {"type": "boolean"},
+	"int":         {"type": "integer"},
+	"int8":        {"type": "integer"},
+	"int16":       {"type": "integer"},
+	"int32":       {"type": "integer", "format": "int32"},
+	"int64":       {"type": "integer", "format": "int64"},
+	"uint":        {"type": "integer", "minimum": 0},
+	"uint8":       {"type": "integer", "minimum": 0},
+	"uint16":      {"type": "integer", "minimum": 0},
+	"uint32":      {"type": "integer", "format": "int32", "minimum": 0},
+	"uint64":      {"type": "integer", "format": "int64", "minimum": 0},
+	"float32":     {"type": "number", "format": "float"},
+	"float64":     {"type": "number", "format": "double"},
+	"byte":        {"type": "integer"},
+	"rune":        {"type": "integer"},
+	"error":       {"type": "string"},
+	"any":         {},
+	"interface{}": {},
+}
+
+// externalSchemas 第三方和标准库类型
+var externalSchemas = map[string]schema{
+	"time.Time":       {"type": "string", "format": "date-time"},
+	"time.Duration":   {"type": "integer", "format": "int64", "description": "纳秒"},
+	"json.RawMessage": {},
+	"gorm.DeletedAt":  {"type": "string", "format": "date-time", "nullable": true},
+	"gorm.Model":      {"type": "object"},
+	"gin.H":           {"type": "object"},
+	"sql.NullString":  {"type": "string", "nullable": true},
+	"sql.NullTime":    {"type": "string", "format": "date-time", "nullable": true},
+	"sql.NullInt64":   {"type": "integer", "format": "int64", "nullable": true},
+}
+
+// schemaBuilder 将源码中的类型转换为 OpenAPI 模式，命名类型放入 components 并以 $ref 引用
+type schemaBuilder struct {
+	src        *source
+	components map[string]schema
+	names      map[string]string // "包名.类型名" -> 组件名
+}
+
+func newSchemaBuilder(src *source) *schemaBuilder {
+	return &schemaBuilder{src: src, components: make(map[string]schema), names: make(map[string]string)}
+}
+
+// componentName 组件名默认为类型名，多个包有同名类型时除 models 外加包名前缀
+func (b *schemaBuilder) componentName(pkg, name string) string {
+	if pkg == "models" {
+		return name
+	}
+	for other, types := range b.src.types {
+		if other != pkg && types[name] != nil {
+			return pkg + "." + name
+		}
+	}
+	return name
+}
+
+// typeSchema 类型表达式对应的模式，pkg 为表达式所在的包
+func (b *schemaBuilder) typeSchema(pkg string, expr ast.Expr) schema {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if s, ok := basicSchemas[expr.Name]; ok {
+			return copySchema(s)
+		}
+		return b.namedSchema(pkg, expr.Name)
+	case *ast.SelectorExpr:
+		qualifier, _ := expr.X.(*ast.Ident)
+		if qualifier == nil {
+			return schema{}
+		}
+		full := qualifier.Name + "." + expr.Sel.Name
+		if s, ok := externalSchemas[full]; ok {
+			return copySchema(s)
+		}
+		if b.src.types[qualifier.Name] != nil {
+			return b.namedSchema(qualifier.Name, expr.Sel.Name)
+		}
+		return schema{}
+	case *ast.StarExpr:
+		return b.typeSchema(pkg, expr.X)
+	case *ast.ArrayType:
+		if ident, ok := expr.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return schema{"type": "string", "format": "byte"}
+		}
+		return schema{"type": "array", "items": b.typeSchema(pkg, expr.Elt)}
+	case *ast.MapType:
+		return schema{"type": "object", "additionalProperties": b.typeSchema(pkg, expr.Value)}
+	case *ast.StructType:
+		return b.structSchema(pkg, expr)
+	case *ast.InterfaceType:
+		return schema{}
+	}
+	return schema{}
+}
+
+// namedSchema 命名类型：结构体放入 components 返回引用，其他类型展开为底层类型
+func (b *schemaBuilder) namedSchema(pkg, name string) schema {
+	spec := b.src.types[pkg][name]
+	if spec == nil {
+		return schema{}
+	}
+	if _, ok := spec.Type.(*ast.StructType); !ok {
+		s := b.typeSchema(pkg, spec.Type)
+		if summary, _ := docText(spec.Doc, name); summary != "" && s["$ref"] == nil {
+			s["description"] = summary
+		}
+		return s
+	}
+
+	key := pkg + "." + name
+	component, ok := b.names[key]
+	if !ok {
+		component = b.componentName(pkg, name)
+		b.names[key] = component
+		// 先占位，避免自引用类型无限递归
+		b.components[component] = schema{}
+		s := b.typeSchema(pkg, spec.Type)
+		if summary, description := docText(spec.Doc, name); summary != "" {
+			s["description"] = strings.TrimSpace(summary + "\n\n" + description)
+		}
+		b.components[component] = s
+	}
+	return schema{"$ref": "#/components/schemas/" + component}
+}
+
+// structSchema 按 json 标签生成对象模式，匿名嵌入的结构体字段展开到外层
+func (b *schemaBuilder) structSchema(pkg string, st *ast.StructType) schema {
+	properties := schema{}
+	var required []string
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			if value, err := strconv.Unquote(field.Tag.Value); err == nil {
+				tag = reflect.StructTag(value)
+			}
+		}
+		jsonName, jsonOpts := splitTag(tag.Get("json"))
+		if jsonName == "-" && jsonOpts == "" {
+			continue
+		}
+
+		names := field.Names
+		if len(names) == 0 {
+			if jsonName == "" {
+				for name, prop := range b.embeddedProperties(pkg, field.Type) {
+					properties[name] = prop
+				}
+				continue
+			}
+			names = []*ast.Ident{ast.NewIdent(typeIdent(field.Type))}
+		}
+
+		for _, ident := range names {
+			if !ident.IsExported() {
+				continue
+			}
+			name := jsonName
+			if name == "" {
+				name = ident.Name
+			}
+			prop := b.typeSchema(pkg, field.Type)
+			if comment := fieldComment(field); comment != "" {
+				if prop["$ref"] != nil {
+					// $ref 不能与其他关键字并列，描述放在 allOf 外层
+					prop = schema{"allOf": []interface{}{prop}, "description": comment}
+				} else {
+					prop["description"] = comment
+				}
+			}
+			properties[name] = prop
+			if strings.Contains(tag.Get("binding"), "required") {
+				required = append(required, name)
+			}
+		}
+	}
+
+	s := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// embeddedProperties 嵌入结构体的字段
+func (b *schemaBuilder) embeddedProperties(pkg string, expr ast.Expr) schema {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	typePkg, name := pkg, ""
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		name = expr.Name
+	case *ast.SelectorExpr:
+		if qualifier, ok := expr.X.(*ast.Ident); ok {
+			typePkg, name = qualifier.Name, expr.Sel.Name
+		}
+	}
+	if typePkg+"."+name == "gorm.Model" {
+		return schema{
+			"ID":        schema{"type": "integer", "minimum": 0},
+			"CreatedAt": schema{"type": "string", "format": "date-time"},
+			"UpdatedAt": schema{"type": "string", "format": "date-time"},
+			"DeletedAt": schema{"type": "string", "format": "date-time", "nullable": true},
+		}
+	}
+	spec := b.src.types[typePkg][name]
+	if spec == nil {
+		return schema{}
+	}
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return schema{}
+	}
+	properties, _ := b.structSchema(typePkg, st)["properties"].(schema)
+	return properties
+}
+
+func splitTag(tag string) (name, opts string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+// fieldComment 字段的行尾注释或上方注释
+func fieldComment(field *ast.Field) string {
+	for _, group := range []*ast.CommentGroup{field.Comment, field.Doc} {
+		if group != nil {
+			if text := strings.TrimSpace(group.Text()); text != "" {
+				return strings.ReplaceAll(text, "\n", " ")
+			}
+		}
+	}
+	return ""
+}
+
+func copySchema(s schema) schema {
+	c := make(schema, len(s))
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// source 已解析的包：类型声明、函数和方法，按包名索引
+type source struct {
+	types   map[string]map[string]*ast.TypeSpec // 包名 -> 类型名 -> 声明
+	funcs   map[string]map[string]*ast.FuncDecl // 包名 -> 函数名 -> 声明
+	methods map[string]map[string]*ast.FuncDecl // 包名 -> "接收者.方法名" -> 声明
+}
+
+// loadSource 解析 backend 下的指定包，跳过测试文件和带构建标签的文件
+func loadSource(root string, pkgs ...string) (*source, error) {
+	src := &source{
+		types:   make(map[string]map[string]*ast.TypeSpec),
+		funcs:   make(map[string]map[string]*ast.FuncDecl),
+		methods: make(map[string]map[string]*ast.FuncDecl),
+	}
+	fset := token.NewFileSet()
+	for _, pkg := range pkgs {
+		src.types[pkg] = make(map[string]*ast.TypeSpec)
+		src.funcs[pkg] = make(map[string]*ast.FuncDecl)
+		src.methods[pkg] = make(map[string]*ast.FuncDecl)
+
+		files, err := filepath.Glob(filepath.Join(root, pkg, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			if strings.HasSuffix(path, "_test.go") {
+				continue
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(string(content), "//go:build") {
+				continue
+			}
+			file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+			if err != nil {
+				return nil, err
+			}
+			src.index(pkg, file)
+		}
+	}
+	return src, nil
+}
+
+func (s *source) index(pkg string, file *ast.File) {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				// 单个类型声明的注释挂在 GenDecl 上
+				if typeSpec.Doc == nil && len(decl.Specs) == 1 {
+					typeSpec.Doc = decl.Doc
+				}
+				s.types[pkg][typeSpec.Name.Name] = typeSpec
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				s.funcs[pkg][decl.Name.Name] = decl
+				continue
+			}
+			if recv := receiverName(decl); recv != "" {
+				s.methods[pkg][recv+"."+decl.Name.Name] = decl
+			}
+		}
+	}
+}
+
+// findMethod 按方法名查找，先找指定接收者，找不到时在所有包中取第一个同名方法
+func (s *source) findMethod(pkg, recv, name string) *ast.FuncDecl {
+	if recv != "" {
+		if fn := s.methods[pkg][recv+"."+name]; fn != nil {
+			return fn
+		}
+	}
+	for _, p := range []string{pkg, "services", "handlers", "models", "middleware"} {
+		keys := make([]string, 0, len(s.methods[p]))
+		for key := range s.methods[p] {
+			if strings.HasSuffix(key, "."+name) {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			sort.Strings(keys)
+			return s.methods[p][keys[0]]
+		}
+	}
+	return nil
+}
+
+// receiverName 方法接收者的类型名，指针接收者去掉 *
+func receiverName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	expr := fn.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// docText 注释文本，去掉开头的标识符名称
+func docText(doc *ast.CommentGroup, name string) (summary, description string) {
+	if doc == nil {
+		return "", ""
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(doc.Text()), "\n") {
+		line = strings.TrimSpace(line)
+		// 跳过 "GET /api/xxx" 这类路由说明，路由已体现在文档中
+		if isRouteLine(line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "", ""
+	}
+	summary = strings.TrimSpace(strings.TrimPrefix(lines[0], name))
+	description = strings.TrimSpace(strings.Join(lines[1:], "\n"))
+	return summary, description
+}
+
+func isRouteLine(line string) bool {
+	for _, method := range []string{"GET ", "POST ", "PUT ", "PATCH ", "DELETE "} {
+		if strings.HasPrefix(line, method+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// errorDescriptions 常见错误状态码的说明
+var errorDescriptions = map[int]string{
+	400: "请求参数错误",
+	401: "未登录或令牌无效",
+	403: "权限不足",
+	404: "资源不存在",
+	409: "资源冲突",
+	413: "请求体过大",
+	428: "需要先以 dry_run=true 预估影响并携带确认令牌",
+	429: "请求过于频繁",
+	500: "服务器内部错误",
+	502: "上游服务错误",
+	503: "服务不可用",
+}
+
+// queryDescriptions 格式不能从源码推导的查询参数的说明
+var queryDescriptions = map[string]string{
+	"query_type": "查询类型，逗号分隔的编号或名称列表，如 1,28 或 A,AAAA（名称不区分大小写，也支持 TYPE65 形式）；可选值见 GET /dns-logs/query-types",
+	"type":       "query_type 的简写，同时指定时以 query_type 为准",
+}
+
+// specBuilder 组装 OpenAPI 文档
+type specBuilder struct {
+	src           *source
+	schemas       *schemaBuilder
+	version       string
+	errorStatuses map[int]bool
+}
+
+func newSpecBuilder(src *source, version string) *specBuilder {
+	return &specBuilder{src: src, schemas: newSchemaBuilder(src), version: version, errorStatuses: make(map[int]bool)}
+}
+
+func (b *specBuilder) build(routes []route) map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	operationIDs := map[string]int{}
+	tags := map[string]bool{}
+
+	for _, r := range routes {
+		path, pathParams := openAPIPath(r.Path)
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+
+		fn := b.handlerDecl(r)
+		info := analyzeHandler(b.src, b.schemas, r.HandlerPkg, fn)
+
+		tag := routeTag(r.Path)
+		tags[tag] = true
+		op := map[string]interface{}{
+			"tags":        []string{tag},
+			"operationId": b.operationID(r, operationIDs),
+			"parameters":  b.parameters(r, pathParams, info),
+			"responses":   b.responses(r, info),
+		}
+		if info.Summary != "" {
+			op["summary"] = info.Summary
+		}
+		if info.Description != "" {
+			op["description"] = info.Description
+		}
+		if !r.Auth {
+			op["security"] = []interface{}{}
+		}
+		if info.Request != nil && r.Method != "GET" && r.Method != "DELETE" {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": b.schemas.typeSchema(info.Request.pkg, info.Request.expr),
+					},
+				},
+			}
+		}
+		paths[path][strings.ToLower(r.Method)] = op
+	}
+
+	tagList := make([]string, 0, len(tags))
+	for tag := range tags {
+		tagList = append(tagList, tag)
+	}
+	sort.Strings(tagList)
+	tagObjects := make([]map[string]string, 0, len(tagList))
+	for _, tag := range tagList {
+		tagObjects = append(tagObjects, map[string]string{"name": tag})
+	}
+
+	schemas := map[string]interface{}{
+		"ErrorResponse": schema{
+			"type": "object",
+			"properties": schema{
+				"success": schema{"type": "boolean"},
+				"message": schema{"type": "string"},
+				"error":   schema{"type": "string"},
+			},
+		},
+	}
+	for name, s := range b.schemas.components {
+		schemas[name] = s
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "SmartDNS Manager API",
+			"version":     b.version,
+			"description": "SmartDNS Manager 管理端接口。响应统一为 {success, message, data} 结构；不带版本号的 /api 前缀为兼容旧客户端的别名，响应带 Deprecation 头。",
+		},
+		"servers":  []map[string]string{{"url": "/api/" + b.version}},
+		"security": []map[string][]string{{"bearerAuth": {}}},
+		"tags":     tagObjects,
+		"paths":    paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]string{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "登录返回的 JWT 或 API 令牌",
+				},
+			},
+			"schemas":   schemas,
+			"responses": b.errorResponses(),
+		},
+	}
+}
+
+// handlerDecl 路由对应的处理器声明
+func (b *specBuilder) handlerDecl(r route) *ast.FuncDecl {
+	if r.HandlerRecv != "" {
+		return b.src.methods[r.HandlerPkg][r.HandlerRecv+"."+r.HandlerName]
+	}
+	return b.src.funcs[r.HandlerPkg][r.HandlerName]
+}
+
+// operationID 由处理器名生成，同一处理器挂在多个路由上时追加序号
+func (b *specBuilder) operationID(r route, seen map[string]int) string {
+	id := r.HandlerName
+	if id == "" {
+		id = strings.ToLower(r.Method) + strings.ReplaceAll(r.Path, "/", "_")
+	}
+	id = strings.ToLower(id[:1]) + id[1:]
+	seen[id]++
+	if seen[id] > 1 {
+		id += strconv.Itoa(seen[id])
+	}
+	return id
+}
+
+func (b *specBuilder) parameters(r route, pathParams []string, info *handlerInfo) []interface{} {
+	params := []interface{}{}
+	for _, name := range pathParams {
+		s := schema{"type": "string"}
+		if name == "id" || strings.HasSuffix(name, "_id") {
+			s = schema{"type": "integer", "minimum": 0}
+		}
+		params = append(params, map[string]interface{}{
+			"name": name, "in": "path", "required": true, "schema": s,
+		})
+	}
+	for _, name := range info.Query {
+		param := map[string]interface{}{
+			"name": name, "in": "query", "schema": schema{"type": "string"},
+		}
+		if description := queryDescriptions[name]; description != "" {
+			param["description"] = description
+		}
+		params = append(params, param)
+	}
+	if r.Confirm {
+		params = append(params,
+			map[string]interface{}{
+				"name": "dry_run", "in": "query", "schema": schema{"type": "boolean"},
+				"description": "为 true 时只预估影响范围并返回确认令牌，不执行操作",
+			},
+			map[string]interface{}{
+				"name": "X-Confirm-Token", "in": "header", "schema": schema{"type": "string"},
+				"description": "dry_run 返回的确认令牌，破坏性操作提交时必须携带",
+			})
+	}
+	return params
+}
+
+func (b *specBuilder) responses(r route, info *handlerInfo) map[string]interface{} {
+	responses := map[string]interface{}{}
+	for status, s := range info.Responses {
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": "成功",
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": s}},
+		}
+	}
+	switch {
+	case info.Stream:
+		responses["200"] = map[string]interface{}{
+			"description": "Server-Sent Events 事件流",
+			"content":     map[string]interface{}{"text/event-stream": map[string]interface{}{"schema": schema{"type": "string"}}},
+		}
+	case info.Download && len(info.Responses) == 0:
+		responses["200"] = map[string]interface{}{
+			"description": "文件内容",
+			"content":     map[string]interface{}{"application/octet-stream": map[string]interface{}{"schema": schema{"type": "string", "format": "binary"}}},
+		}
+	case len(responses) == 0:
+		responses["200"] = map[string]interface{}{"description": "成功"}
+	}
+
+	errors := info.Errors
+	if r.Auth {
+		errors[401] = true
+	}
+	if r.SuperAdmin {
+		errors[403] = true
+	}
+	if r.Confirm {
+		errors[428] = true
+	}
+	for status := range errors {
+		responses[strconv.Itoa(status)] = map[string]string{"$ref": "#/components/responses/" + errorResponseName(status)}
+		b.errorStatuses[status] = true
+	}
+	return responses
+}
+
+// errorResponses 用到的错误响应，统一引用 ErrorResponse 模式
+func (b *specBuilder) errorResponses() map[string]interface{} {
+	responses := map[string]interface{}{}
+	for status := range b.errorStatuses {
+		description := errorDescriptions[status]
+		if description == "" {
+			description = "错误"
+		}
+		responses[errorResponseName(status)] = map[string]interface{}{
+			"description": description,
+			"content": map[string]interface{}{"application/json": map[string]interface{}{
+				"schema": schema{"$ref": "#/components/schemas/ErrorResponse"},
+			}},
+		}
+	}
+	return responses
+}
+
+func errorResponseName(status int) string {
+	return "Error" + strconv.Itoa(status)
+}
+
+// routeTag 按路径的第一段分组
+func routeTag(path string) string {
+	segment := strings.Split(strings.TrimPrefix(path, "/"), "/")[0]
+	if segment == "" || strings.HasPrefix(segment, ":") {
+		return "default"
+	}
+	// openapi.json 等文档类接口
+	if strings.Contains(segment, ".") {
+		return "docs"
+	}
+	return segment
+}
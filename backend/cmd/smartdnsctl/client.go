@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// apiClient 管理端 API 客户端，使用 API 令牌认证
+type apiClient struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// apiResponse 管理端统一的响应格式
+type apiResponse struct {
+	Success *bool           `json:"success"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+	Data    json.RawMessage `json:"data"`
+	Total   *int64          `json:"total"`
+
+	ConfirmRequired bool `json:"confirm_required"` // 破坏性操作需要先确认影响范围
+
+	status string
+}
+
+func newAPIClient(server, token string) (*apiClient, error) {
+	if server == "" {
+		return nil, fmt.Errorf("请通过 --server 或 SMARTDNS_SERVER 指定管理端地址")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("请通过 --token 或 SMARTDNS_TOKEN 指定 API 令牌（在“API 令牌”页面创建）")
+	}
+	return &apiClient{
+		server: strings.TrimSuffix(server, "/"),
+		token:  token,
+		http:   &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// do 发送请求，path 不含 /api/v1 前缀
+func (c *apiClient) do(method, path string, query url.Values, body interface{}) (*apiResponse, error) {
+	status, result, err := c.send(method, path, query, body, "")
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusPreconditionRequired && result.ConfirmRequired {
+		return c.confirm(method, path, query, body)
+	}
+	return checkResponse(status, result)
+}
+
+// confirm 破坏性操作的两阶段确认：先预估影响范围，确认后携带令牌重新提交
+func (c *apiClient) confirm(method, path string, query url.Values, body interface{}) (*apiResponse, error) {
+	dryQuery := url.Values{}
+	for key, values := range query {
+		dryQuery[key] = values
+	}
+	dryQuery.Set("dry_run", "true")
+
+	status, result, err := c.send(method, path, dryQuery, body, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkResponse(status, result); err != nil {
+		return nil, err
+	}
+	var preview struct {
+		Impact struct {
+			Summary string `json:"summary"`
+		} `json:"impact"`
+		ConfirmToken string `json:"confirm_token"`
+	}
+	if err := json.Unmarshal(result.Data, &preview); err != nil {
+		return nil, fmt.Errorf("解析影响预估失败: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "⚠️  %s\n", preview.Impact.Summary)
+	if !assumeYes {
+		fmt.Fprint(os.Stderr, "继续执行？[y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return nil, fmt.Errorf("操作已取消")
+		}
+	}
+
+	status, result, err = c.send(method, path, query, body, preview.ConfirmToken)
+	if err != nil {
+		return nil, err
+	}
+	return checkResponse(status, result)
+}
+
+// send 发送一次请求，返回状态码和解析后的响应
+func (c *apiClient) send(method, path string, query url.Values, body interface{}, confirmToken string) (int, *apiResponse, error) {
+	endpoint := c.server + "/api/v1" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if confirmToken != "" {
+		req.Header.Set("X-Confirm-Token", confirmToken)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	var result apiResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return 0, nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	result.status = resp.Status
+	return resp.StatusCode, &result, nil
+}
+
+// checkResponse 将失败的响应转换为错误
+func checkResponse(status int, result *apiResponse) (*apiResponse, error) {
+	if status >= 400 || (result.Success != nil && !*result.Success) {
+		msg := result.Message
+		if result.Error != "" {
+			if msg != "" {
+				msg += ": "
+			}
+			msg += result.Error
+		}
+		if msg == "" {
+			msg = result.status
+		}
+		return nil, fmt.Errorf("%s", msg)
+	}
+	return result, nil
+}
+
+// get 发送 GET 请求并将 data 解析到 v
+func (c *apiClient) get(path string, query url.Values, v interface{}) error {
+	resp, err := c.do(http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	if v == nil || len(resp.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, v)
+}
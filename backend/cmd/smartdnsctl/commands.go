@@ -0,0 +1,494 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type rows = []map[string]interface{}
+
+// parseIDs 解析位置参数中的 ID 列表
+func parseIDs(args []string) ([]uint, error) {
+	ids := make([]uint, 0, len(args))
+	for _, arg := range args {
+		for _, part := range strings.Split(arg, ",") {
+			if part == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(part, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("无效的ID: %s", part)
+			}
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, nil
+}
+
+// parseSingleID 解析唯一的位置参数 ID
+func parseSingleID(fs *flag.FlagSet, args []string, name string) (uint, error) {
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := parseIDs(positional)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) != 1 {
+		return 0, fmt.Errorf("请指定一个%s", name)
+	}
+	return ids[0], nil
+}
+
+// nodeIDsFlag 解析 --nodes 1,2，不填表示所有节点
+func nodeIDsFlag(value string) ([]uint, error) {
+	if value == "" {
+		return []uint{}, nil
+	}
+	return parseIDs([]string{value})
+}
+
+func nodesList(c *apiClient, args []string) error {
+	fs := flag.NewFlagSet("nodes list", flag.ContinueOnError)
+	tag := fs.String("tag", "", "按标签过滤")
+	status := fs.String("status", "", "按状态过滤（online/offline/error）")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if *tag != "" {
+		query.Set("tags", *tag)
+	}
+	if *status != "" {
+		query.Set("status", *status)
+	}
+	var nodes rows
+	if err := c.get("/nodes", query, &nodes); err != nil {
+		return err
+	}
+	return printRows(nodes, []column{
+		{"ID", "id"},
+		{"名称", "name"},
+		{"地址", "host"},
+		{"状态", "status"},
+		{"版本", "smartdns_version"},
+		{"AGENT", "agent_installed"},
+		{"标签", "tags"},
+		{"上次检查", "last_check"},
+	})
+}
+
+// logKey 用于去重同一时间戳下已输出的日志
+func logKey(entry map[string]interface{}) string {
+	return fmt.Sprint(entry["node_id"], "|", entry["timestamp"], "|", entry["client_ip"], "|", entry["domain"], "|", entry["query_type"])
+}
+
+func logsTail(c *apiClient, args []string) error {
+	fs := flag.NewFlagSet("logs tail", flag.ContinueOnError)
+	node := fs.String("node", "", "节点ID")
+	domain := fs.String("domain", "", "域名")
+	client := fs.String("client", "", "客户端IP")
+	queryType := fs.String("type", "", "查询类型，可用逗号分隔多个，如 A,AAAA")
+	interval := fs.Duration("interval", 2*time.Second, "轮询间隔")
+	lines := fs.Int("lines", 20, "启动时先输出的最近日志条数")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *interval < time.Second {
+		*interval = time.Second
+	}
+
+	query := url.Values{}
+	if *node != "" {
+		query.Set("node_id", *node)
+	}
+	if *domain != "" {
+		query.Set("domain", *domain)
+	}
+	if *client != "" {
+		query.Set("client_ip", *client)
+	}
+	if *queryType != "" {
+		query.Set("query_type", *queryType)
+	}
+	query.Set("sort_field", "timestamp")
+	query.Set("page_size", "50")
+
+	fetch := func(q url.Values) (rows, error) {
+		var data struct {
+			Logs rows `json:"logs"`
+		}
+		if err := c.get("/dns-logs", q, &data); err != nil {
+			return nil, err
+		}
+		return data.Logs, nil
+	}
+
+	printEntry := func(entry map[string]interface{}) {
+		if outputFormat == "json" {
+			data, _ := json.Marshal(entry)
+			fmt.Println(string(data))
+			return
+		}
+		fmt.Printf("%s  node=%s  %-15s  %-5s  %-40s  %sms  %s\n",
+			formatValue(entry["timestamp"]), formatValue(entry["node_id"]), formatValue(entry["client_ip"]),
+			queryTypeName(entry), formatValue(entry["domain"]), formatValue(entry["time_ms"]),
+			formatValue(entry["result_ips"]))
+	}
+
+	// 先输出最近的日志（按时间倒序取回后反转）
+	initial := cloneValues(query)
+	initial.Set("sort_order", "desc")
+	initial.Set("page_size", strconv.Itoa(max(1, min(*lines, 50))))
+	recent, err := fetch(initial)
+	if err != nil {
+		return err
+	}
+	var lastTimestamp string
+	seen := make(map[string]bool)
+	for i := len(recent) - 1; i >= 0; i-- {
+		if *lines > 0 {
+			printEntry(recent[i])
+		}
+		lastTimestamp, _ = recent[i]["timestamp"].(string)
+		seen[logKey(recent[i])] = true
+	}
+	if lastTimestamp == "" {
+		lastTimestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		q := cloneValues(query)
+		q.Set("sort_order", "asc")
+		q.Set("start_time", lastTimestamp)
+		entries, err := fetch(q)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️ %v\n", err)
+			continue
+		}
+		for _, entry := range entries {
+			key := logKey(entry)
+			if seen[key] {
+				continue
+			}
+			printEntry(entry)
+			if ts, _ := entry["timestamp"].(string); ts != lastTimestamp {
+				// 时间戳前进后只需记住新时间戳下的日志
+				lastTimestamp = ts
+				seen = make(map[string]bool)
+			}
+			seen[key] = true
+		}
+	}
+}
+
+func cloneValues(values url.Values) url.Values {
+	clone := url.Values{}
+	for k, v := range values {
+		clone[k] = append([]string(nil), v...)
+	}
+	return clone
+}
+
+// queryTypeName 日志的查询类型名称，旧版本管理端未返回 query_type_name 时显示编号
+func queryTypeName(entry map[string]interface{}) string {
+	if name, ok := entry["query_type_name"].(string); ok && name != "" {
+		return name
+	}
+	if f, ok := entry["query_type"].(float64); ok {
+		return fmt.Sprintf("%d", int(f))
+	}
+	return "-"
+}
+
+func syncNodes(c *apiClient, args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("请指定节点ID")
+	}
+	resp, err := c.do(http.MethodPost, "/sync/batch", nil, map[string]interface{}{"node_ids": ids})
+	if err != nil {
+		return err
+	}
+	return printMessage(resp)
+}
+
+func syncAll(c *apiClient, args []string) error {
+	var nodes []struct {
+		ID uint `json:"id"`
+	}
+	if err := c.get("/nodes", nil, &nodes); err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("没有节点")
+	}
+	ids := make([]uint, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID
+	}
+	resp, err := c.do(http.MethodPost, "/sync/batch", nil, map[string]interface{}{"node_ids": ids})
+	if err != nil {
+		return err
+	}
+	return printMessage(resp)
+}
+
+func syncLogs(c *apiClient, args []string) error {
+	fs := flag.NewFlagSet("sync logs", flag.ContinueOnError)
+	node := fs.String("node", "", "节点ID")
+	status := fs.String("status", "", "按状态过滤（success/failed/pending）")
+	limit := fs.Int("limit", 20, "条数")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := url.Values{"page_size": {strconv.Itoa(*limit)}}
+	if *node != "" {
+		query.Set("node_id", *node)
+	}
+	if *status != "" {
+		query.Set("status", *status)
+	}
+	var logs rows
+	if err := c.get("/sync/logs", query, &logs); err != nil {
+		return err
+	}
+	return printRows(logs, []column{
+		{"ID", "id"},
+		{"节点", "node_id"},
+		{"类型", "type"},
+		{"操作", "action"},
+		{"状态", "status"},
+		{"错误", "error"},
+		{"时间", "created_at"},
+	})
+}
+
+func backupRun(c *apiClient, args []string) error {
+	id, err := parseSingleID(flag.NewFlagSet("backup run", flag.ContinueOnError), args, "备份配置ID")
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(http.MethodPost, fmt.Sprintf("/database-backup/configs/%d/backup", id), nil, nil)
+	if err != nil {
+		return err
+	}
+	return printMessage(resp)
+}
+
+func backupList(c *apiClient, args []string) error {
+	fs := flag.NewFlagSet("backup list", flag.ContinueOnError)
+	configID := fs.String("config", "", "备份配置ID")
+	limit := fs.Int("limit", 20, "条数")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := url.Values{"page_size": {strconv.Itoa(*limit)}}
+	if *configID != "" {
+		query.Set("config_id", *configID)
+	}
+	var data struct {
+		History rows `json:"history"`
+	}
+	if err := c.get("/database-backup/history", query, &data); err != nil {
+		return err
+	}
+	return printRows(data.History, []column{
+		{"ID", "id"},
+		{"配置", "config_id"},
+		{"状态", "status"},
+		{"文件", "file_name"},
+		{"大小", "file_size"},
+		{"开始时间", "started_at"},
+		{"耗时(秒)", "duration"},
+		{"错误", "error_message"},
+	})
+}
+
+func rulesList(c *apiClient, args []string) error {
+	fs := flag.NewFlagSet("rules list", flag.ContinueOnError)
+	domain := fs.String("domain", "", "按域名过滤")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if *domain != "" {
+		query.Set("domain", *domain)
+	}
+	var rules rows
+	if err := c.get("/domain-rules", query, &rules); err != nil {
+		return err
+	}
+	return printRows(rules, []column{
+		{"ID", "id"},
+		{"域名", "domain"},
+		{"地址", "address"},
+		{"分组", "nameserver"},
+		{"测速", "speed_check_mode"},
+		{"其他选项", "other_options"},
+		{"优先级", "priority"},
+		{"节点", "node_ids"},
+		{"启用", "enabled"},
+	})
+}
+
+func rulesAdd(c *apiClient, args []string) error {
+	fs := flag.NewFlagSet("rules add", flag.ContinueOnError)
+	domain := fs.String("domain", "", "域名")
+	address := fs.String("address", "", "-address 参数")
+	nameserver := fs.String("nameserver", "", "-nameserver 分组")
+	speedCheck := fs.String("speed-check-mode", "", "-speed-check-mode 参数")
+	options := fs.String("options", "", "其他选项")
+	priority := fs.Int("priority", 0, "优先级")
+	nodes := fs.String("nodes", "", "生效节点ID，逗号分隔，不填表示所有节点")
+	desc := fs.String("desc", "", "描述")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *domain == "" {
+		return fmt.Errorf("请指定 --domain")
+	}
+	nodeIDs, err := nodeIDsFlag(*nodes)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(http.MethodPost, "/domain-rules", nil, map[string]interface{}{
+		"domain":           *domain,
+		"address":          *address,
+		"nameserver":       *nameserver,
+		"speed_check_mode": *speedCheck,
+		"other_options":    *options,
+		"priority":         *priority,
+		"description":      *desc,
+		"node_ids":         nodeIDs,
+	})
+	if err != nil {
+		return err
+	}
+	return printMessage(resp)
+}
+
+func rulesDelete(c *apiClient, args []string) error {
+	id, err := parseSingleID(flag.NewFlagSet("rules delete", flag.ContinueOnError), args, "规则ID")
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(http.MethodDelete, fmt.Sprintf("/domain-rules/%d", id), nil, nil)
+	if err != nil {
+		return err
+	}
+	return printMessage(resp)
+}
+
+func addressesList(c *apiClient, args []string) error {
+	fs := flag.NewFlagSet("addresses list", flag.ContinueOnError)
+	domain := fs.String("domain", "", "按域名过滤")
+	limit := fs.Int("limit", 50, "条数")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	query := url.Values{"page_size": {strconv.Itoa(*limit)}}
+	if *domain != "" {
+		query.Set("domain", *domain)
+	}
+	var addresses rows
+	if err := c.get("/addresses", query, &addresses); err != nil {
+		return err
+	}
+	return printRows(addresses, []column{
+		{"ID", "id"},
+		{"域名", "domain"},
+		{"类型", "type"},
+		{"IP", "ip"},
+		{"CNAME", "cname"},
+		{"TTL", "ttl"},
+		{"节点", "node_ids"},
+		{"启用", "enabled"},
+		{"备注", "comment"},
+	})
+}
+
+func addressesAdd(c *apiClient, args []string) error {
+	fs := flag.NewFlagSet("addresses add", flag.ContinueOnError)
+	domain := fs.String("domain", "", "域名")
+	ip := fs.String("ip", "", "IP 地址，多个以逗号分隔")
+	cname := fs.String("cname", "", "CNAME 别名")
+	ttl := fs.Int("ttl", 0, "应答 TTL，0 表示使用全局设置")
+	nodes := fs.String("nodes", "", "生效节点ID，逗号分隔，不填表示所有节点")
+	comment := fs.String("comment", "", "备注")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *domain == "" {
+		return fmt.Errorf("请指定 --domain")
+	}
+	if (*ip == "") == (*cname == "") {
+		return fmt.Errorf("请指定 --ip 或 --cname（二选一）")
+	}
+	nodeIDs, err := nodeIDsFlag(*nodes)
+	if err != nil {
+		return err
+	}
+	nodeIDsJSON, _ := json.Marshal(nodeIDs)
+
+	body := map[string]interface{}{
+		"domain":   *domain,
+		"ttl":      *ttl,
+		"comment":  *comment,
+		"node_ids": string(nodeIDsJSON),
+		"enabled":  true,
+	}
+	if *cname != "" {
+		body["type"] = "cname"
+		body["cname"] = *cname
+	} else {
+		body["type"] = "address"
+		body["ip"] = *ip
+	}
+	resp, err := c.do(http.MethodPost, "/addresses", nil, body)
+	if err != nil {
+		return err
+	}
+	return printMessage(resp)
+}
+
+func addressesDelete(c *apiClient, args []string) error {
+	id, err := parseSingleID(flag.NewFlagSet("addresses delete", flag.ContinueOnError), args, "地址映射ID")
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(http.MethodDelete, fmt.Sprintf("/addresses/%d", id), nil, nil)
+	if err != nil {
+		return err
+	}
+	return printMessage(resp)
+}
@@ -0,0 +1,125 @@
+// smartdnsctl 是 SmartDNS Manager 的命令行客户端，通过 API 令牌调用管理端接口，
+// 便于脚本和值班时在不打开 Web 界面的情况下查看节点、跟踪日志、触发同步和备份、管理规则。
+//
+// 安装: go install ./cmd/smartdnsctl（在 backend 目录下执行）
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 全局参数，可通过环境变量设置
+var (
+	serverURL    = os.Getenv("SMARTDNS_SERVER")
+	apiToken     = os.Getenv("SMARTDNS_TOKEN")
+	outputFormat = "table"
+	assumeYes    = false
+)
+
+// command 子命令，name 可以包含空格（如 "nodes list"）
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(c *apiClient, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"nodes list", "nodes list [--tag TAG] [--status STATUS]", "列出节点及状态", nodesList},
+		{"logs tail", "logs tail [--node ID] [--domain D] [--client IP] [--type A,AAAA] [--interval 2s] [--lines 20]", "持续输出最新的查询日志", logsTail},
+		{"sync node", "sync node ID...", "完整同步指定节点", syncNodes},
+		{"sync all", "sync all", "完整同步所有节点", syncAll},
+		{"sync logs", "sync logs [--node ID] [--limit 20]", "查看最近的同步日志", syncLogs},
+		{"backup run", "backup run CONFIG_ID", "按备份配置立即备份管理端数据库", backupRun},
+		{"backup list", "backup list [--config ID] [--limit 20]", "查看备份历史", backupList},
+		{"rules list", "rules list [--domain D]", "列出域名规则", rulesList},
+		{"rules add", "rules add --domain D [--address A] [--nameserver G] [--speed-check-mode M] [--options O] [--priority N] [--nodes 1,2] [--desc TEXT]", "新增域名规则", rulesAdd},
+		{"rules delete", "rules delete ID", "删除域名规则", rulesDelete},
+		{"addresses list", "addresses list [--domain D] [--limit 50]", "列出地址映射", addressesList},
+		{"addresses add", "addresses add --domain D (--ip IP | --cname NAME) [--ttl N] [--nodes 1,2] [--comment TEXT]", "新增地址映射", addressesAdd},
+		{"addresses delete", "addresses delete ID", "删除地址映射", addressesDelete},
+	}
+}
+
+func main() {
+	global := flag.NewFlagSet("smartdnsctl", flag.ContinueOnError)
+	global.StringVar(&serverURL, "server", serverURL, "管理端地址，如 https://dns-admin.example.com（SMARTDNS_SERVER）")
+	global.StringVar(&apiToken, "token", apiToken, "API 令牌（SMARTDNS_TOKEN）")
+	global.StringVar(&outputFormat, "o", outputFormat, "输出格式：table 或 json")
+	global.BoolVar(&assumeYes, "yes", assumeYes, "破坏性操作不再交互确认")
+	global.Usage = printUsage
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+	if outputFormat != "table" && outputFormat != "json" {
+		fmt.Fprintln(os.Stderr, "输出格式只能是 table 或 json")
+		os.Exit(2)
+	}
+
+	args := global.Args()
+	if len(args) == 0 || args[0] == "help" {
+		printUsage()
+		return
+	}
+
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) < len(words) || strings.Join(args[:len(words)], " ") != cmd.name {
+			continue
+		}
+		client, err := newAPIClient(serverURL, apiToken)
+		if err == nil {
+			err = cmd.run(client, args[len(words):])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", strings.Join(args, " "))
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Println("smartdnsctl - SmartDNS Manager 命令行客户端")
+	fmt.Println()
+	fmt.Println("用法:")
+	fmt.Println("  smartdnsctl [--server URL] [--token TOKEN] [-o table|json] [--yes] <命令> [参数]")
+	fmt.Println()
+	fmt.Println("命令:")
+	for _, cmd := range commands {
+		fmt.Printf("  %-18s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Println()
+	fmt.Println("参数:")
+	for _, cmd := range commands {
+		fmt.Printf("  %s\n", cmd.usage)
+	}
+	fmt.Println()
+	fmt.Println("环境变量 SMARTDNS_SERVER、SMARTDNS_TOKEN 可代替 --server、--token")
+	fmt.Println("管理端要求确认的删除等操作会先显示影响范围并询问是否继续，--yes 跳过询问")
+}
+
+// parseFlags 解析参数，允许参数和位置参数交替出现，返回位置参数
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
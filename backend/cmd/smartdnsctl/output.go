@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// column 表格列，field 支持点分隔路径
+type column struct {
+	title string
+	field string
+}
+
+// printRows 按输出格式打印记录列表
+func printRows(rows []map[string]interface{}, columns []column) error {
+	if outputFormat == "json" {
+		return printJSON(rows)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	titles := make([]string, len(columns))
+	for i, col := range columns {
+		titles[i] = col.title
+	}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+	for _, row := range rows {
+		values := make([]string, len(columns))
+		for i, col := range columns {
+			values[i] = formatValue(lookup(row, col.field))
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	return w.Flush()
+}
+
+// printJSON 以缩进格式输出 JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+// printMessage 输出操作结果，JSON 模式下输出完整响应
+func printMessage(resp *apiResponse) error {
+	if outputFormat == "json" {
+		return printJSON(resp)
+	}
+	fmt.Println("✅ " + resp.Message)
+	return nil
+}
+
+func lookup(row map[string]interface{}, path string) interface{} {
+	var current interface{} = row
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
+
+func formatValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "-"
+	case string:
+		if value == "" {
+			return "-"
+		}
+		// 时间字段按本地时间简写
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			if t.IsZero() || t.Year() <= 1 {
+				return "-"
+			}
+			return t.Local().Format("2006-01-02 15:04:05")
+		}
+		return value
+	case float64:
+		if value == float64(int64(value)) {
+			return fmt.Sprintf("%d", int64(value))
+		}
+		return fmt.Sprintf("%.2f", value)
+	case bool:
+		if value {
+			return "是"
+		}
+		return "否"
+	default:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+}
@@ -1,12 +1,21 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"log"
 	"os"
 	"strconv"
 	"strings"
 )
 
+// ClickHouse 连接协议
+const (
+	ClickHouseProtocolNative = "native" // 原生 TCP 协议，默认端口 9000，TLS 9440
+	ClickHouseProtocolHTTP   = "http"   // HTTP 接口，默认端口 8123，HTTPS 8443
+)
+
 type ClickHouseConfig struct {
 	Enabled  bool
 	Host     string
@@ -14,21 +23,99 @@ type ClickHouseConfig struct {
 	Database string
 	Username string
 	Password string
+
+	// 连接协议与 TLS
+	Protocol           string // native / http
+	Secure             bool   // 是否使用 TLS（原生安全端口或 HTTPS）
+	CACert             string // 自定义 CA 证书路径，为空时使用系统证书
+	ServerName         string // TLS 校验使用的主机名（SNI），通过 IP 或代理连接时指定
+	InsecureSkipVerify bool   // 跳过证书校验，仅用于测试
+
+	// 仪表盘统计查询缓存
+	QueryCacheTTL   int // 缓存有效秒数，0 表示不缓存
+	QueryCacheStale int // 过期后仍可返回旧结果（同时后台刷新）的秒数
 }
 
 func GetClickHouseConfig() *ClickHouseConfig {
 	logStorageType := strings.ToLower(getEnv("LOG_STORAGE_TYPE", "sqlite"))
 	clickhouseEnabled := logStorageType == "clickhouse"
+
+	protocol, secure := getClickHouseProtocol()
+
 	return &ClickHouseConfig{
-		Enabled:  clickhouseEnabled,
-		Host:     getEnv("CLICKHOUSE_HOST", "localhost"),
-		Port:     getEnvAsInt("CLICKHOUSE_PORT", 9000),
-		Database: getEnv("CLICKHOUSE_DB", "smartdns_logs"),
-		Username: getEnv("CLICKHOUSE_USER", "smartdns"),
-		Password: getEnv("CLICKHOUSE_PASSWORD", "smartdns"),
+		Enabled:            clickhouseEnabled,
+		Host:               getEnv("CLICKHOUSE_HOST", "localhost"),
+		Port:               getEnvAsInt("CLICKHOUSE_PORT", defaultClickHousePort(protocol, secure)),
+		Database:           getEnv("CLICKHOUSE_DB", "smartdns_logs"),
+		Username:           getEnv("CLICKHOUSE_USER", "smartdns"),
+		Password:           getEnv("CLICKHOUSE_PASSWORD", "smartdns"),
+		Protocol:           protocol,
+		Secure:             secure,
+		CACert:             os.Getenv("CLICKHOUSE_CA_CERT"),
+		ServerName:         os.Getenv("CLICKHOUSE_TLS_SERVER_NAME"),
+		InsecureSkipVerify: getEnvAsBool("CLICKHOUSE_TLS_SKIP_VERIFY", false),
+		QueryCacheTTL:      getEnvAsInt("CLICKHOUSE_QUERY_CACHE_TTL", 30),
+		QueryCacheStale:    getEnvAsInt("CLICKHOUSE_QUERY_CACHE_STALE", 300),
+	}
+}
+
+// getClickHouseProtocol 连接协议和是否启用 TLS，CLICKHOUSE_PROTOCOL=https 等同于 http + CLICKHOUSE_SECURE=true
+func getClickHouseProtocol() (string, bool) {
+	secure := getEnvAsBool("CLICKHOUSE_SECURE", false)
+	switch strings.ToLower(getEnv("CLICKHOUSE_PROTOCOL", ClickHouseProtocolNative)) {
+	case "https":
+		return ClickHouseProtocolHTTP, true
+	case ClickHouseProtocolHTTP:
+		return ClickHouseProtocolHTTP, secure
+	default:
+		return ClickHouseProtocolNative, secure
 	}
 }
 
+// defaultClickHousePort 未指定端口时按协议和是否加密选择 ClickHouse 的默认端口
+func defaultClickHousePort(protocol string, secure bool) int {
+	switch {
+	case protocol == ClickHouseProtocolHTTP && secure:
+		return 8443
+	case protocol == ClickHouseProtocolHTTP:
+		return 8123
+	case secure:
+		return 9440
+	default:
+		return 9000
+	}
+}
+
+// TLSConfig 生成 TLS 配置，未启用 TLS 时返回 nil
+func (c *ClickHouseConfig) TLSConfig() (*tls.Config, error) {
+	if !c.Secure {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = c.Host
+	}
+
+	if c.CACert != "" {
+		pem, err := os.ReadFile(c.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("读取 ClickHouse CA 证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ClickHouse CA 证书无效: %s", c.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
 // IsClickHouseEnabled 是否启用 ClickHouse
 func IsClickHouseEnabled() bool {
 	return GetClickHouseConfig().Enabled
@@ -49,3 +136,19 @@ func getEnvAsInt(key string, defaultValue int) int {
 
 	return value
 }
+
+// getEnvAsBool 获取环境变量并转换为布尔值
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		log.Printf("Warning: Invalid boolean value for %s: %s, using default %t", key, valueStr, defaultValue)
+		return defaultValue
+	}
+
+	return value
+}
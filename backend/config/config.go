@@ -17,6 +17,105 @@ type Config struct {
 	InitBaseURL    string
 	StatusTime     string
 	LogStorageType string
+	CDNRangesFile  string
+
+	HealthScoreWeights string
+	BackupMasterKey    string
+	ShareLinkSecret    string
+
+	NotificationAlarmMinutes string
+	LogMonitorAlertMinutes   string
+	ConfigAnnotations        string
+
+	// 单点登录
+	SSOUIURL               string
+	SSOGroupRoleMap        string
+	SSODefaultRole         string
+	SSOJITProvisioning     string
+	SSODefaultOrganization string
+	OIDCIssuer             string
+	OIDCClientID           string
+	OIDCClientSecret       string
+	OIDCRedirectURL        string
+	OIDCScopes             string
+	OIDCGroupsClaim        string
+	OIDCProviderName       string
+	LDAPURL                string
+	LDAPBindDN             string
+	LDAPBindPassword       string
+	LDAPBaseDN             string
+	LDAPUserAttribute      string
+	LDAPGroupAttribute     string
+	LDAPInsecureSkipVerify string
+
+	// 认证 Webhook（自定义认证服务）
+	AuthWebhookURL           string
+	AuthWebhookSecret        string
+	AuthWebhookTimeout       string
+	AuthWebhookCacheTTL      string
+	AuthWebhookExclusive     string
+	AuthWebhookSubjectClaim  string
+	AuthWebhookUsernameClaim string
+	AuthWebhookEmailClaim    string
+	AuthWebhookGroupsClaim   string
+	AuthWebhookRoleClaim     string
+	AuthBreakGlassUser       string
+
+	// HashiCorp Vault（节点 SSH 凭据）
+	VaultAddr       string
+	VaultToken      string
+	VaultRoleID     string
+	VaultSecretID   string
+	VaultNamespace  string
+	VaultKVMount    string
+	VaultSSHMount   string
+	VaultSSHCertTTL string
+
+	// 深度健康检查（通过节点 SmartDNS 实际解析探测域名）
+	HealthProbeDomain   string
+	HealthProbeExpect   string
+	HealthProbeProtocol string
+	HealthProbePort     string
+	HealthProbeTimeout  string
+
+	// 遥测 PING 每次检测发送的 ICMP 回显请求数
+	TelemetryPingCount string
+
+	// 破坏性操作确认：off 时确认令牌可选，enforce 时必须先预估影响再携带令牌提交
+	DestructiveConfirm string
+
+	// 客户端 IP 脱敏：分享链接查看日志时按该模式脱敏，管理端接收的日志写入前同样脱敏，与 Agent 的 CLIENT_IP_PRIVACY 配合使用
+	LogPrivacyMode     string
+	LogPrivacyV4Prefix string
+	LogPrivacyV6Prefix string
+	LogClientIPHashKey string
+
+	// DNS 查询日志默认保留天数，节点可单独设置
+	DNSLogRetentionDays string
+
+	// 收到 SIGTERM 后等待进行中的请求和后台任务结束的最长时间（秒）
+	ShutdownTimeout string
+
+	// 后端日志级别、输出格式和内存中保留的最近日志条数
+	LogLevel      string
+	LogFormat     string
+	LogBufferSize string
+
+	// 同时执行的后台任务数（节点初始化、Agent 部署、完整同步、日志导出等）
+	BackgroundJobWorkers string
+
+	// SmartDNS 版本目录（GitHub Releases API）地址，无法访问 GitHub 时可换成镜像
+	SmartDNSReleasesURL string
+
+	// docker 安装方式使用的 SmartDNS 镜像
+	SmartDNSDockerImage string
+
+	// 节点资源指标（CPU、内存、磁盘、负载和 SmartDNS 进程内存）的采样间隔（秒，0 表示不采集）和保留天数
+	NodeMetricsInterval      string
+	NodeMetricsRetentionDays string
+
+	// Agent 通过 /api/ingest/dns-logs 上传日志时单个请求解压后的最大大小（MB）
+	LogIngestMaxBodyMB string
 }
 
 var config *Config
@@ -41,6 +140,107 @@ func GetConfig() *Config {
 			StatusTime:     getEnv("STATUS_CHECK_TIME", "10"),
 			InitBaseURL:    getEnv("INIT_BASE_URL", "https://github.com/pymumu/smartdns/releases/download/Release46"),
 			LogStorageType: logStorageType,
+			CDNRangesFile:  getEnv("CDN_RANGES_FILE", ""),
+			// 格式: service=30,resource=15,ingestion_lag=15,sync_freshness=15,telemetry=10,error_rate=15
+			HealthScoreWeights: getEnv("HEALTH_SCORE_WEIGHTS", ""),
+			// 用于包装备份加密密钥，未设置时回退到 JWT_SECRET
+			BackupMasterKey: getEnv("BACKUP_MASTER_KEY", ""),
+			// 日志分享链接的签名密钥，未设置时回退到 JWT_SECRET
+			ShareLinkSecret: getEnv("SHARE_LINK_SECRET", ""),
+			// 通知渠道持续失败超过该分钟数时告警
+			NotificationAlarmMinutes: getEnv("NOTIFICATION_ALARM_MINUTES", "30"),
+			// 节点日志采集中断超过该分钟数时告警
+			LogMonitorAlertMinutes: getEnv("LOG_MONITOR_ALERT_MINUTES", "10"),
+			// 生成配置时在受管指令行尾写入溯源注释（记录 ID、修改时间、修改人）
+			ConfigAnnotations: getEnv("CONFIG_ANNOTATIONS", "false"),
+			// 单点登录完成后跳转的前端地址，前后端同域部署时保持默认
+			SSOUIURL: getEnv("SSO_UI_URL", ""),
+			// 格式: dns-admins=admin,dns-ops=user，按 IdP 分组名或 LDAP 组 DN/CN 匹配
+			SSOGroupRoleMap: getEnv("SSO_GROUP_ROLE_MAP", ""),
+			// 未匹配到任何分组时的角色，none 表示拒绝登录
+			SSODefaultRole:     getEnv("SSO_DEFAULT_ROLE", "user"),
+			SSOJITProvisioning: getEnv("SSO_JIT_PROVISIONING", "true"),
+			// 自动开通的账号所属组织（组织名称），未配置时不自动开通，避免新账号成为可查看所有组织的超级管理员
+			SSODefaultOrganization: getEnv("SSO_DEFAULT_ORGANIZATION", ""),
+			OIDCIssuer:             getEnv("OIDC_ISSUER", ""),
+			OIDCClientID:           getEnv("OIDC_CLIENT_ID", ""),
+			OIDCClientSecret:       getEnv("OIDC_CLIENT_SECRET", ""),
+			// IdP 回调地址，形如 https://dns.example.com/api/auth/oidc/callback
+			OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+			OIDCScopes:       getEnv("OIDC_SCOPES", "openid profile email groups"),
+			OIDCGroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			OIDCProviderName: getEnv("OIDC_PROVIDER_NAME", "SSO"),
+			// ldap://host:389 或 ldaps://host:636
+			LDAPURL:                getEnv("LDAP_URL", ""),
+			LDAPBindDN:             getEnv("LDAP_BIND_DN", ""),
+			LDAPBindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+			LDAPBaseDN:             getEnv("LDAP_BASE_DN", ""),
+			LDAPUserAttribute:      getEnv("LDAP_USER_ATTRIBUTE", "uid"),
+			LDAPGroupAttribute:     getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+			LDAPInsecureSkipVerify: getEnv("LDAP_INSECURE_SKIP_VERIFY", "false"),
+			// 认证 Webhook：将账号密码或令牌转发到该地址校验，返回的声明映射为本地用户和角色
+			AuthWebhookURL: getEnv("AUTH_WEBHOOK_URL", ""),
+			// 设置后请求体使用 HMAC-SHA256 签名，签名放在 X-SmartDNS-Signature 头
+			AuthWebhookSecret:   getEnv("AUTH_WEBHOOK_SECRET", ""),
+			AuthWebhookTimeout:  getEnv("AUTH_WEBHOOK_TIMEOUT", "5"),
+			AuthWebhookCacheTTL: getEnv("AUTH_WEBHOOK_CACHE_TTL", "300"),
+			// 为 true 时本地账号也必须经过 Webhook 认证，只有应急管理员可使用本地密码
+			AuthWebhookExclusive:     getEnv("AUTH_WEBHOOK_EXCLUSIVE", "false"),
+			AuthWebhookSubjectClaim:  getEnv("AUTH_WEBHOOK_SUBJECT_CLAIM", "sub"),
+			AuthWebhookUsernameClaim: getEnv("AUTH_WEBHOOK_USERNAME_CLAIM", "username"),
+			AuthWebhookEmailClaim:    getEnv("AUTH_WEBHOOK_EMAIL_CLAIM", "email"),
+			AuthWebhookGroupsClaim:   getEnv("AUTH_WEBHOOK_GROUPS_CLAIM", "groups"),
+			AuthWebhookRoleClaim:     getEnv("AUTH_WEBHOOK_ROLE_CLAIM", "role"),
+			// 应急管理员（本地账号），始终使用本地密码登录，不受外部认证服务故障影响
+			AuthBreakGlassUser: getEnv("AUTH_BREAK_GLASS_USER", ""),
+			VaultAddr:          getEnv("VAULT_ADDR", ""),
+			// 直接使用令牌，或通过 AppRole（VAULT_ROLE_ID / VAULT_SECRET_ID）登录
+			VaultToken:      getEnv("VAULT_TOKEN", ""),
+			VaultRoleID:     getEnv("VAULT_ROLE_ID", ""),
+			VaultSecretID:   getEnv("VAULT_SECRET_ID", ""),
+			VaultNamespace:  getEnv("VAULT_NAMESPACE", ""),
+			VaultKVMount:    getEnv("VAULT_KV_MOUNT", "secret"),
+			VaultSSHMount:   getEnv("VAULT_SSH_MOUNT", "ssh"),
+			VaultSSHCertTTL: getEnv("VAULT_SSH_CERT_TTL", "5m"),
+			// 为空时只检查 SSH 和服务状态，不做实际解析
+			HealthProbeDomain: getEnv("HEALTH_PROBE_DOMAIN", ""),
+			// 期望的 IP 或 CIDR 网段，逗号分隔；为空时只要求有应答
+			HealthProbeExpect: getEnv("HEALTH_PROBE_EXPECT", ""),
+			// udp、tcp 或 dot（DNS over TLS）
+			HealthProbeProtocol: getEnv("HEALTH_PROBE_PROTOCOL", "udp"),
+			// 为空时 udp/tcp 使用 53，dot 使用 853
+			HealthProbePort:    getEnv("HEALTH_PROBE_PORT", ""),
+			HealthProbeTimeout: getEnv("HEALTH_PROBE_TIMEOUT", "3"),
+			// 用于统计丢包率，无 ICMP 权限时回退到端口连通性检测
+			TelemetryPingCount: getEnv("TELEMETRY_PING_COUNT", "4"),
+			DestructiveConfirm: getEnv("DESTRUCTIVE_CONFIRM", "off"),
+			// off、truncate 或 hash，管理员始终查看存储中的原始值
+			LogPrivacyMode:     getEnv("LOG_PRIVACY_MODE", "off"),
+			LogPrivacyV4Prefix: getEnv("LOG_PRIVACY_V4_PREFIX", "24"),
+			LogPrivacyV6Prefix: getEnv("LOG_PRIVACY_V6_PREFIX", "56"),
+			// 与 Agent 的 CLIENT_IP_HASH_KEY 一致，管理员可按原始 IP 检索哈希后的日志
+			LogClientIPHashKey: getEnv("LOG_CLIENT_IP_HASH_KEY", ""),
+			// 未单独设置保留天数的节点使用该值，ClickHouse 表 TTL 按所有节点中最长的保留天数调整
+			DNSLogRetentionDays: getEnv("DNS_LOG_RETENTION_DAYS", "90"),
+			// 应小于容器编排的终止宽限期（Docker 默认 10 秒，可用 stop_grace_period 调整）
+			ShutdownTimeout: getEnv("SHUTDOWN_TIMEOUT", "30"),
+			// debug、info、warn 或 error，debug 时同时输出 SQL 语句
+			LogLevel: getEnv("LOG_LEVEL", "info"),
+			// text 或 json，日志由采集系统收集时建议使用 json
+			LogFormat: getEnv("LOG_FORMAT", "text"),
+			// 供 /api/system/logs 查询
+			LogBufferSize: getEnv("LOG_BUFFER_SIZE", "2000"),
+			// 超出的任务排队等待，排队中的任务在服务重启后继续执行
+			BackgroundJobWorkers: getEnv("BACKGROUND_JOB_WORKERS", "4"),
+			// 未按节点标签固定版本时，初始化安装 INIT_VERSION；INIT_VERSION 设为 latest 时使用目录中最新的正式版
+			SmartDNSReleasesURL: getEnv("SMARTDNS_RELEASES_URL", "https://api.github.com/repos/pymumu/smartdns/releases"),
+			// 使用主机网络运行，配置和日志目录与其他安装方式相同
+			SmartDNSDockerImage: getEnv("SMARTDNS_DOCKER_IMAGE", "pymumu/smartdns:latest"),
+			// 由节点健康检查顺带采样，间隔不小于 STATUS_CHECK_TIME
+			NodeMetricsInterval:      getEnv("NODE_METRICS_INTERVAL", "60"),
+			NodeMetricsRetentionDays: getEnv("NODE_METRICS_RETENTION_DAYS", "30"),
+			// 节点无法直接访问 ClickHouse 时 Agent 改为经管理端写入，由管理端以异步写入方式写入 ClickHouse
+			LogIngestMaxBodyMB: getEnv("LOG_INGEST_MAX_BODY_MB", "64"),
 		}
 
 		// 打印配置信息（生产环境可以去掉敏感信息）
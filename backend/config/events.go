@@ -48,6 +48,106 @@ var NotificationEvents = []NotificationEvent{
 		Name:        "配置恢复",
 		Description: "恢复配置备份时触发",
 	},
+	{
+		Key:         "client_abuse",
+		Name:        "客户端查询异常",
+		Description: "单个客户端查询速率超过阈值时触发",
+	},
+	{
+		Key:         "dns_threat",
+		Name:        "DNS威胁检测",
+		Description: "检测到疑似DNS隧道或DGA域名时触发",
+	},
+	{
+		Key:         "health_score_low",
+		Name:        "健康评分过低",
+		Description: "节点综合健康评分低于阈值或恢复时触发",
+	},
+	{
+		Key:         "task_failed",
+		Name:        "定时任务失败",
+		Description: "开启失败通知的定时任务执行失败或超时时触发",
+	},
+	{
+		Key:         "task_success",
+		Name:        "定时任务成功",
+		Description: "开启成功通知的定时任务执行成功时触发",
+	},
+	{
+		Key:         "database_backup",
+		Name:        "数据库备份",
+		Description: "数据库备份配置开启了成功或失败通知，备份完成或失败时触发",
+	},
+	{
+		Key:         "config_drift",
+		Name:        "配置漂移",
+		Description: "检测到节点配置被手动修改、与管理端不一致时触发",
+	},
+	{
+		Key:         "compliance_violation",
+		Name:        "配置合规违规",
+		Description: "节点配置未通过合规策略或被自动修复时触发",
+	},
+	{
+		Key:         "node_patch_alert",
+		Name:        "节点补丁告警",
+		Description: "节点存在待安装的安全更新、需要重启或运行的内核存在已知严重漏洞时触发",
+	},
+	{
+		Key:         "blocklist_refresh_failed",
+		Name:        "屏蔽列表刷新失败",
+		Description: "远程屏蔽列表订阅下载、解析或推送到节点失败时触发",
+	},
+	{
+		Key:         "log_monitor_down",
+		Name:        "日志采集中断",
+		Description: "节点 Agent 日志采集中断且自动恢复失败超过阈值时触发",
+	},
+	{
+		Key:         "log_monitor_recovered",
+		Name:        "日志采集恢复",
+		Description: "已告警的节点日志采集恢复正常时触发",
+	},
+	{
+		Key:         "telemetry_group_down",
+		Name:        "遥测分组异常",
+		Description: "遥测分组内检测失败的目标占比达到阈值时触发",
+	},
+	{
+		Key:         "telemetry_group_recovered",
+		Name:        "遥测分组恢复",
+		Description: "已告警的遥测分组检测恢复正常时触发",
+	},
+	{
+		Key:         "traffic_anomaly",
+		Name:        "查询量异常",
+		Description: "节点查询量或解析失败率明显偏离历史基线（如流量骤降为零）或恢复时触发",
+	},
+	{
+		Key:         "dns_answer_mismatch",
+		Name:        "DNS应答异常",
+		Description: "通过节点解析的结果不在期望地址或网段内（疑似劫持、上游污染）或恢复时触发",
+	},
+	{
+		Key:         "fleet_report",
+		Name:        "节点资产报告",
+		Description: "定时任务生成节点资产月度报告（按云厂商/区域/成本中心汇总）时触发",
+	},
+	{
+		Key:         "break_glass_login",
+		Name:        "应急管理员登录",
+		Description: "启用认证 Webhook 时，应急管理员使用本地密码登录时触发",
+	},
+	{
+		Key:         "notification_channel_failing",
+		Name:        "通知渠道故障",
+		Description: "通知渠道持续投递失败（如 Webhook 失效）时触发",
+	},
+	{
+		Key:         "alert_rule",
+		Name:        "告警规则",
+		Description: "未指定通知渠道的告警规则触发或恢复时触发",
+	},
 	{
 		Key:         "test",
 		Name:        "测试消息",
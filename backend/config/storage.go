@@ -8,12 +8,34 @@ import (
 
 // StorageConfig 存储配置
 type StorageConfig struct {
-	Type        string // local 或 s3
+	Type        string // local、s3、sftp、webdav、azure_blob 或 gcs
 	S3AccessKey string
 	S3SecretKey string
 	S3Region    string
 	S3Bucket    string
 	S3Endpoint  string // 可选，支持MinIO
+
+	SFTPHost           string
+	SFTPPort           string
+	SFTPUser           string
+	SFTPPassword       string
+	SFTPPrivateKeyFile string
+	SFTPHostKey        string // 服务器主机公钥或 SHA256 指纹
+	SFTPPath           string
+
+	WebDAVURL      string
+	WebDAVUser     string
+	WebDAVPassword string
+
+	AzureAccount    string
+	AzureAccountKey string
+	AzureSASToken   string
+	AzureContainer  string
+	AzureEndpoint   string
+
+	GCSBucket          string
+	GCSCredentialsFile string
+	GCSEndpoint        string
 }
 
 // LoadStorageConfig 从环境变量加载存储配置
@@ -30,12 +52,35 @@ func LoadStorageConfig() *StorageConfig {
 		S3Region:    getEnvOrDefault("S3_REGION", "us-east-1"),
 		S3Bucket:    os.Getenv("S3_BUCKET"),
 		S3Endpoint:  os.Getenv("S3_ENDPOINT"), // 支持MinIO等
+
+		SFTPHost:           os.Getenv("SFTP_HOST"),
+		SFTPPort:           getEnvOrDefault("SFTP_PORT", "22"),
+		SFTPUser:           os.Getenv("SFTP_USER"),
+		SFTPPassword:       os.Getenv("SFTP_PASSWORD"),
+		SFTPPrivateKeyFile: os.Getenv("SFTP_PRIVATE_KEY_FILE"),
+		SFTPHostKey:        os.Getenv("SFTP_HOST_KEY"),
+		SFTPPath:           getEnvOrDefault("SFTP_PATH", "smartdns-backups"),
+
+		WebDAVURL:      os.Getenv("WEBDAV_URL"),
+		WebDAVUser:     os.Getenv("WEBDAV_USER"),
+		WebDAVPassword: os.Getenv("WEBDAV_PASSWORD"),
+
+		AzureAccount:    os.Getenv("AZURE_STORAGE_ACCOUNT"),
+		AzureAccountKey: os.Getenv("AZURE_STORAGE_KEY"),
+		AzureSASToken:   os.Getenv("AZURE_STORAGE_SAS_TOKEN"),
+		AzureContainer:  os.Getenv("AZURE_STORAGE_CONTAINER"),
+		AzureEndpoint:   os.Getenv("AZURE_STORAGE_ENDPOINT"),
+
+		GCSBucket:          os.Getenv("GCS_BUCKET"),
+		GCSCredentialsFile: os.Getenv("GCS_CREDENTIALS_FILE"),
+		GCSEndpoint:        os.Getenv("GCS_ENDPOINT"),
 	}
 }
 
 // Validate 验证配置
 func (c *StorageConfig) Validate() error {
-	if c.Type == "s3" {
+	switch c.Type {
+	case "s3":
 		if c.S3AccessKey == "" {
 			return fmt.Errorf("S3_ACCESS_KEY 未设置")
 		}
@@ -45,6 +90,31 @@ func (c *StorageConfig) Validate() error {
 		if c.S3Bucket == "" {
 			return fmt.Errorf("S3_BUCKET 未设置")
 		}
+	case "sftp":
+		if c.SFTPHost == "" || c.SFTPUser == "" {
+			return fmt.Errorf("SFTP_HOST 或 SFTP_USER 未设置")
+		}
+		if c.SFTPPassword == "" && c.SFTPPrivateKeyFile == "" {
+			return fmt.Errorf("SFTP_PASSWORD 或 SFTP_PRIVATE_KEY_FILE 未设置")
+		}
+		if c.SFTPHostKey == "" {
+			return fmt.Errorf("SFTP_HOST_KEY 未设置")
+		}
+	case "webdav":
+		if c.WebDAVURL == "" {
+			return fmt.Errorf("WEBDAV_URL 未设置")
+		}
+	case "azure_blob":
+		if c.AzureAccount == "" || c.AzureContainer == "" {
+			return fmt.Errorf("AZURE_STORAGE_ACCOUNT 或 AZURE_STORAGE_CONTAINER 未设置")
+		}
+		if c.AzureAccountKey == "" && c.AzureSASToken == "" {
+			return fmt.Errorf("AZURE_STORAGE_KEY 或 AZURE_STORAGE_SAS_TOKEN 未设置")
+		}
+	case "gcs":
+		if c.GCSBucket == "" || c.GCSCredentialsFile == "" {
+			return fmt.Errorf("GCS_BUCKET 或 GCS_CREDENTIALS_FILE 未设置")
+		}
 	}
 	return nil
 }
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// LogStorageClickHouse 日志存储：ClickHouse
+	LogStorageClickHouse = "clickhouse"
+	// LogStorageTimescale 日志存储：PostgreSQL / TimescaleDB
+	LogStorageTimescale = "timescaledb"
+)
+
+type TimescaleConfig struct {
+	Host          string
+	Port          int
+	Database      string
+	Username      string
+	Password      string
+	SSLMode       string
+	RetentionDays int // 日志保留天数，依赖 TimescaleDB 保留策略
+}
+
+// GetLogStorageType 获取日志存储类型，未配置或无法识别时使用 ClickHouse
+func GetLogStorageType() string {
+	switch strings.ToLower(getEnv("LOG_STORAGE_TYPE", LogStorageClickHouse)) {
+	case "timescaledb", "timescale", "postgres", "postgresql":
+		return LogStorageTimescale
+	default:
+		return LogStorageClickHouse
+	}
+}
+
+func GetTimescaleConfig() *TimescaleConfig {
+	return &TimescaleConfig{
+		Host:          getEnv("POSTGRES_HOST", "localhost"),
+		Port:          getEnvAsInt("POSTGRES_PORT", 5432),
+		Database:      getEnv("POSTGRES_DB", "smartdns_logs"),
+		Username:      getEnv("POSTGRES_USER", "smartdns"),
+		Password:      getEnv("POSTGRES_PASSWORD", "smartdns"),
+		SSLMode:       getEnv("POSTGRES_SSLMODE", "disable"),
+		RetentionDays: getEnvAsInt("POSTGRES_LOG_RETENTION_DAYS", 30),
+	}
+}
+
+// DSN 生成 PostgreSQL 连接串
+func (c *TimescaleConfig) DSN() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Username, c.Password),
+		Host:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:     "/" + c.Database,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"time"
@@ -14,52 +15,42 @@ import (
 
 var CHConn driver.Conn
 
-// InitClickHouse 初始化 ClickHouse 连接
-func InitClickHouse() {
+// OpenClickHouse 连接 ClickHouse，不执行迁移
+func OpenClickHouse() error {
 	cfg := config.GetClickHouseConfig()
-	log.Printf("🔗 正在连接 ClickHouse: %s:%d", cfg.Host, cfg.Port)
+	log.Printf("🔗 正在连接 ClickHouse: %s:%d (%s, TLS: %t)", cfg.Host, cfg.Port, cfg.Protocol, cfg.Secure)
+
+	tlsConfig, err := cfg.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("ClickHouse TLS 配置错误: %w", err)
+	}
 
 	// 第一步：连接到 ClickHouse（不指定数据库）
-	conn, err := clickhouse.Open(&clickhouse.Options{
-		Addr: []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)},
-		Auth: clickhouse.Auth{
-			Username: cfg.Username,
-			Password: cfg.Password,
-		},
-		DialTimeout: 10 * time.Second,
-		Compression: &clickhouse.Compression{
-			Method: clickhouse.CompressionLZ4,
-		},
-		MaxOpenConns:    20, // 增加连接数
-		MaxIdleConns:    10, // 增加空闲连接数
-		ConnMaxLifetime: time.Hour,
-	})
+	conn, err := clickhouse.Open(clickhouseOptions(cfg, "", tlsConfig))
 	if err != nil {
-		log.Fatal("❌ 连接 ClickHouse 失败:", err)
+		return fmt.Errorf("连接 ClickHouse 失败: %w", err)
 	}
 
 	// 测试连接
-	ctx := context.Background()
 	log.Println("✅ ClickHouse 连接成功")
 	// 关闭初始连接
 	conn.Close()
-	CHConn, err = clickhouse.Open(&clickhouse.Options{
-		Addr: []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)},
-		Auth: clickhouse.Auth{
-			Database: cfg.Database,
-			Username: cfg.Username,
-			Password: cfg.Password,
-		},
-		DialTimeout: 10 * time.Second,
-		Compression: &clickhouse.Compression{
-			Method: clickhouse.CompressionLZ4,
-		},
-		MaxOpenConns:    20,
-		MaxIdleConns:    10,
-		ConnMaxLifetime: time.Hour,
-	})
-	// 执行迁移替代原来的 createTablesIfNotExists
-	if err := runMigrations(ctx, CHConn); err != nil {
+	CHConn, err = clickhouse.Open(clickhouseOptions(cfg, cfg.Database, tlsConfig))
+	if err != nil {
+		return fmt.Errorf("连接 ClickHouse 失败: %w", err)
+	}
+	return nil
+}
+
+// InitClickHouse 初始化 ClickHouse 连接
+func InitClickHouse() {
+	if err := OpenClickHouse(); err != nil {
+		log.Fatal("❌ ", err)
+	}
+
+	ctx := context.Background()
+	// 执行版本化迁移替代原来的 createTablesIfNotExists
+	if _, err := RunClickHouseMigrations(ctx, CHConn, false); err != nil {
 		CHConn.Close()
 		log.Fatal("❌ 数据库迁移失败:", err)
 	}
@@ -73,7 +64,33 @@ func InitClickHouse() {
 		log.Printf("⚠️ 创建物化视图失败（可忽略）: %v", err)
 	}
 
-	log.Printf("✅ ClickHouse 初始化完成 - 数据库: %s", cfg.Database)
+	log.Printf("✅ ClickHouse 初始化完成 - 数据库: %s", config.GetClickHouseConfig().Database)
+}
+
+// clickhouseOptions 按配置生成连接参数，tlsConfig 不为 nil 时使用原生安全端口或 HTTPS
+func clickhouseOptions(cfg *config.ClickHouseConfig, database string, tlsConfig *tls.Config) *clickhouse.Options {
+	protocol := clickhouse.Native
+	if cfg.Protocol == config.ClickHouseProtocolHTTP {
+		protocol = clickhouse.HTTP
+	}
+
+	return &clickhouse.Options{
+		Protocol: protocol,
+		Addr:     []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)},
+		Auth: clickhouse.Auth{
+			Database: database,
+			Username: cfg.Username,
+			Password: cfg.Password,
+		},
+		TLS:         tlsConfig,
+		DialTimeout: 10 * time.Second,
+		Compression: &clickhouse.Compression{
+			Method: clickhouse.CompressionLZ4,
+		},
+		MaxOpenConns:    20, // 增加连接数
+		MaxIdleConns:    10, // 增加空闲连接数
+		ConnMaxLifetime: time.Hour,
+	}
 }
 
 // createIndexes 创建索引
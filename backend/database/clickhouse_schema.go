@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// logColumn dns_query_log 中后续版本新增的列
+type logColumn struct {
+	Name    string
+	Type    string
+	Comment string
+	Since   int // 引入该列的迁移版本
+}
+
+// dnsQueryLogColumns dns_query_log 后续新增列的唯一定义，版本化迁移按 Since 添加，启动校对按实际列补齐。
+// 迁移记录与实际表结构可能不一致（如表被 Agent 用旧结构重建），所以两处都需要
+var dnsQueryLogColumns = []logColumn{
+	{Name: "group", Type: "String DEFAULT ''", Comment: "所属组", Since: 2},
+	{Name: "client_subnet", Type: "String DEFAULT ''", Comment: "客户端子网", Since: 3},
+	{Name: "client_country", Type: "LowCardinality(String) DEFAULT ''", Comment: "客户端国家（ISO代码）", Since: 3},
+	{Name: "client_asn", Type: "UInt32 DEFAULT 0", Comment: "客户端ASN", Since: 3},
+	{Name: "client_as_org", Type: "String DEFAULT ''", Comment: "客户端ASN组织", Since: 3},
+	{Name: "client_ptr", Type: "String DEFAULT ''", Comment: "客户端PTR记录", Since: 3},
+	{Name: "domain_category", Type: "LowCardinality(String) DEFAULT ''", Comment: "域名分类", Since: 4},
+	{Name: "upstream", Type: "LowCardinality(String) DEFAULT ''", Comment: "应答的上游服务器", Since: 5},
+	{Name: "rcode", Type: "LowCardinality(String) DEFAULT ''", Comment: "响应码", Since: 7},
+	{Name: "cache_hit", Type: "UInt8 DEFAULT 0", Comment: "是否命中缓存", Since: 7},
+	{Name: "matched_rule", Type: "String DEFAULT ''", Comment: "命中的规则", Since: 7},
+}
+
+// addColumnSQL 生成补充列的语句，可重复执行
+func (c logColumn) addColumnSQL() string {
+	return fmt.Sprintf("ALTER TABLE dns_query_log ADD COLUMN IF NOT EXISTS `%s` %s COMMENT '%s'", c.Name, c.Type, c.Comment)
+}
+
+// addLogColumns 添加指定迁移版本引入的列
+func addLogColumns(ctx context.Context, conn driver.Conn, version int) error {
+	for _, column := range dnsQueryLogColumns {
+		if column.Since != version {
+			continue
+		}
+		if err := conn.Exec(ctx, column.addColumnSQL()); err != nil {
+			return fmt.Errorf("添加列 %s 失败: %w", column.Name, err)
+		}
+	}
+	return nil
+}
+
+// SchemaReport 表结构校对结果
+type SchemaReport struct {
+	Table     string            `json:"table"`
+	CheckedAt time.Time         `json:"checked_at"`
+	Missing   []string          `json:"missing"` // 校对前缺少的列
+	Added     []string          `json:"added"`   // 本次补齐的列
+	Failed    map[string]string `json:"failed"`  // 补齐失败的列及原因
+}
+
+var (
+	schemaReportMu   sync.RWMutex
+	lastSchemaReport *SchemaReport
+)
+
+// LastSchemaReport 最近一次表结构校对结果，未校对时返回 nil
+func LastSchemaReport() *SchemaReport {
+	schemaReportMu.RLock()
+	defer schemaReportMu.RUnlock()
+	return lastSchemaReport
+}
+
+// ReconcileLogSchema 检查 dns_query_log 的实际列，缺少的列通过 ADD COLUMN IF NOT EXISTS 补齐，可重复执行
+func ReconcileLogSchema(ctx context.Context, conn driver.Conn) (*SchemaReport, error) {
+	report := &SchemaReport{
+		Table:     "dns_query_log",
+		CheckedAt: time.Now(),
+		Missing:   []string{},
+		Added:     []string{},
+		Failed:    map[string]string{},
+	}
+
+	rows, err := conn.Query(ctx,
+		"SELECT name FROM system.columns WHERE database = currentDatabase() AND table = ?", report.Table)
+	if err != nil {
+		return nil, fmt.Errorf("查询表结构失败: %w", err)
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("读取表结构失败: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	if len(existing) == 0 {
+		return nil, fmt.Errorf("表 %s 不存在", report.Table)
+	}
+
+	for _, column := range dnsQueryLogColumns {
+		if existing[column.Name] {
+			continue
+		}
+		report.Missing = append(report.Missing, column.Name)
+
+		if err := conn.Exec(ctx, column.addColumnSQL()); err != nil {
+			report.Failed[column.Name] = err.Error()
+			log.Printf("  ⚠️ 补充列 %s 失败: %v", column.Name, err)
+			continue
+		}
+		report.Added = append(report.Added, column.Name)
+		log.Printf("  ✅ 已补充列 %s %s", column.Name, column.Type)
+	}
+
+	schemaReportMu.Lock()
+	lastSchemaReport = report
+	schemaReportMu.Unlock()
+
+	if len(report.Failed) > 0 {
+		return report, fmt.Errorf("%d 个列补充失败", len(report.Failed))
+	}
+	return report, nil
+}
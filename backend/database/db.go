@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -15,8 +17,11 @@ import (
 
 var DB *gorm.DB
 
-// InitDB 初始化数据库
-func InitDB() {
+// LogLevel SQL 日志级别，命令行工具可调低以免输出过多；未设置时 LOG_LEVEL=debug 输出所有 SQL，否则只输出慢查询和错误
+var LogLevel logger.LogLevel
+
+// OpenDB 打开数据库连接，不执行迁移
+func OpenDB() {
 	var err error
 
 	// 获取数据库路径
@@ -29,14 +34,54 @@ func InitDB() {
 	}
 
 	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: newGormLogger(),
 	})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+}
+
+// newGormLogger SQL 日志写入标准库 log，由 logging 包转为结构化日志
+func newGormLogger() logger.Interface {
+	level := LogLevel
+	if level == 0 {
+		level = logger.Warn
+		if strings.EqualFold(config.GetConfig().LogLevel, "debug") {
+			level = logger.Info
+		}
+	}
+	return logger.New(log.Default(), logger.Config{
+		SlowThreshold:             200 * time.Millisecond,
+		LogLevel:                  level,
+		IgnoreRecordNotFoundError: true,
+	})
+}
+
+// Close 关闭 SQLite 和日志存储连接，服务退出前调用
+func Close() {
+	if CHConn != nil {
+		if err := CHConn.Close(); err != nil {
+			log.Printf("⚠️ 关闭 ClickHouse 连接失败: %v", err)
+		}
+	}
+	if PGLogConn != nil {
+		PGLogConn.Close()
+	}
+	if DB != nil {
+		if sqlDB, err := DB.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				log.Printf("⚠️ 关闭数据库失败: %v", err)
+			}
+		}
+	}
+}
+
+// InitDB 初始化数据库
+func InitDB() {
+	OpenDB()
 
 	// 自动迁移数据库结构
-	err = DB.AutoMigrate(
+	err := DB.AutoMigrate(
 		&models.User{},
 		&models.Node{},
 		&models.DNSServer{},
@@ -44,37 +89,67 @@ func InitDB() {
 		&models.DomainSet{},
 		&models.DNSGroup{},
 		&models.DomainSetItem{},
+		&models.GroupBlock{},
+		&models.ClientRule{},
+		&models.AuditLog{},
 		&models.DomainRule{},
 		&models.Nameserver{},
 		&models.ConfigSyncLog{},
 		&models.NotificationChannel{},
 		&models.NotificationLog{},
+		&models.NotificationDelivery{},
 		&models.InitLog{},
+		&models.SmartDNSVersionPin{},
 		&models.Backup{},
+		&models.NodeBackupRetention{},
+		&models.NodeLogRetention{},
 		&models.DNSLog{},
 		&models.BackupConfig{},
 		&models.BackupHistory{},
+		&models.BackupEncryptionKey{},
 		&models.File{},
 		// 调度任务相关表
 		&models.ScheduledTask{},
 		&models.TaskExecution{},
 		&models.TelemetryTarget{},
 		&models.TelemetryResult{},
+		&models.SecurityFinding{},
+		&models.ConfigDriftReport{},
+		&models.NodeConfigSnapshot{},
+		&models.CompliancePolicy{},
+		&models.ComplianceViolation{},
+		&models.ComplianceNodeCheck{},
+		&models.ChangeSet{},
+		&models.ChangeSetNode{},
+		&models.SyncJob{},
+		&models.SyncJobNode{},
+		&models.BackgroundJob{},
+		&models.DNSLogExport{},
+		&models.SavedSearch{},
+		&models.AlertRule{},
+		&models.Alert{},
+		&models.Organization{},
+		&models.ScriptTemplate{},
+		&models.NodeFacts{},
+		&models.LogShareLink{},
+		&models.BlocklistSubscription{},
+		&models.MaintenanceWindow{},
+		&models.DeferredAction{},
+		&models.APIToken{},
+		&models.FleetReport{},
+		&models.AgentProbeStat{},
+		&models.NodeMetric{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
-	// 为现有记录添加默认值
-	DB.Model(&models.AddressMap{}).Where("node_ids IS NULL").Update("node_ids", "[]")
-	DB.Model(&models.AddressMap{}).Where("enabled IS NULL").Update("enabled", true)
-	DB.Model(&models.DNSServer{}).Where("node_ids IS NULL").Update("node_ids", "[]")
-	DB.Model(&models.DNSServer{}).Where("enabled IS NULL").Update("enabled", true)
-
-	DB.Model(&models.Node{}).Where("log_path IS NULL OR log_path = ''").Update("log_path", "/var/log/smartdns/audit.log")
-	DB.Model(&models.Node{}).Where("log_monitor_enabled IS NULL").Update("log_monitor_enabled", false)
+	// 版本化迁移：数据回填、列改名等 AutoMigrate 做不到的变更
+	if _, err := RunSQLiteMigrations(false); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
 
-	log.Printf("Database initialized successfully at: %s", dbPath)
+	log.Printf("Database initialized successfully at: %s", config.GetConfig().DBPath)
 
 	// 初始化系统分组
 	initSystemGroups()
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// MigrationRecord 一个版本化迁移
+type MigrationRecord struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+}
+
+// MigrationReport 一个数据库的迁移结果；dry-run 时只列出待执行的迁移，不修改数据库
+type MigrationReport struct {
+	Target  string            `json:"target"`  // sqlite / clickhouse
+	DryRun  bool              `json:"dry_run"` // 是否为预览
+	Current int               `json:"current"` // 执行前已执行的最高版本
+	Latest  int               `json:"latest"`  // 已定义的最高版本
+	Applied []MigrationRecord `json:"applied"` // 本次执行的迁移
+	Pending []MigrationRecord `json:"pending"` // 未执行的迁移（dry-run 或执行失败时）
+}
+
+// migrationStep 迁移框架执行的一步，apply 执行迁移并写入迁移记录
+type migrationStep struct {
+	MigrationRecord
+	apply func(ctx context.Context) error
+}
+
+// migrationHistory 迁移记录表（schema_migrations），各数据库分别实现
+type migrationHistory interface {
+	exists(ctx context.Context) (bool, error)
+	create(ctx context.Context) error
+	versions(ctx context.Context) (map[int]bool, error)
+}
+
+var (
+	migrationReportsMu sync.RWMutex
+	migrationReports   = map[string]*MigrationReport{}
+)
+
+// MigrationReports 本次启动各数据库的迁移结果
+func MigrationReports() []*MigrationReport {
+	migrationReportsMu.RLock()
+	defer migrationReportsMu.RUnlock()
+
+	reports := make([]*MigrationReport, 0, len(migrationReports))
+	for _, report := range migrationReports {
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Target < reports[j].Target })
+	return reports
+}
+
+// applyMigrations 按版本号顺序执行未执行的迁移，遇到失败立即停止，之后的迁移不执行。
+// dryRun 时不创建迁移记录表，也不执行任何迁移
+func applyMigrations(ctx context.Context, target string, history migrationHistory, steps []migrationStep, dryRun bool) (*MigrationReport, error) {
+	report := &MigrationReport{
+		Target:  target,
+		DryRun:  dryRun,
+		Applied: []MigrationRecord{},
+		Pending: []MigrationRecord{},
+	}
+
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Version < steps[j].Version })
+	for i, step := range steps {
+		if i > 0 && steps[i-1].Version == step.Version {
+			return nil, fmt.Errorf("%s 迁移版本 v%d 重复定义", target, step.Version)
+		}
+		report.Latest = step.Version
+	}
+
+	executed := map[int]bool{}
+	exists, err := history.exists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("检查迁移记录表失败: %w", err)
+	}
+	if !exists && !dryRun {
+		if err := history.create(ctx); err != nil {
+			return nil, fmt.Errorf("创建迁移记录表失败: %w", err)
+		}
+		exists = true
+	}
+	if exists {
+		if executed, err = history.versions(ctx); err != nil {
+			return nil, fmt.Errorf("获取迁移记录失败: %w", err)
+		}
+	}
+	for version := range executed {
+		if version > report.Current {
+			report.Current = version
+		}
+	}
+
+	for _, step := range steps {
+		if !executed[step.Version] {
+			report.Pending = append(report.Pending, step.MigrationRecord)
+		}
+	}
+
+	defer func() {
+		migrationReportsMu.Lock()
+		migrationReports[target] = report
+		migrationReportsMu.Unlock()
+	}()
+
+	if dryRun {
+		for _, m := range report.Pending {
+			log.Printf("📋 [%s] 待执行迁移 v%d: %s", target, m.Version, m.Description)
+		}
+		return report, nil
+	}
+
+	pending := report.Pending
+	for _, step := range steps {
+		if executed[step.Version] {
+			continue
+		}
+
+		log.Printf("🚀 [%s] 执行迁移 v%d: %s", target, step.Version, step.Description)
+		if err := step.apply(ctx); err != nil {
+			report.Pending = pending
+			return report, fmt.Errorf("%s 迁移 v%d 执行失败: %w", target, step.Version, err)
+		}
+		report.Applied = append(report.Applied, step.MigrationRecord)
+		pending = pending[1:]
+		log.Printf("✅ [%s] 迁移 v%d 执行成功", target, step.Version)
+	}
+	report.Pending = pending
+
+	if len(report.Applied) == 0 {
+		log.Printf("✅ [%s] 数据库已是最新版本 v%d", target, report.Latest)
+	} else {
+		log.Printf("✅ [%s] 已执行 %d 个迁移，当前版本 v%d", target, len(report.Applied), report.Latest)
+	}
+	return report, nil
+}
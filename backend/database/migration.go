@@ -4,8 +4,6 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
-	"sort"
 
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 )
@@ -28,12 +26,65 @@ var migrations = []Migration{
 	{
 		Version:     2,
 		Description: "添加 group 字段",
-		SQL:         `ALTER TABLE dns_query_log ADD COLUMN IF NOT EXISTS group String DEFAULT '' COMMENT '所属组'`,
+		Execute:     logColumnsMigration(2),
 	},
+	{
+		Version:     3,
+		Description: "添加客户端富化字段（子网、GeoIP、ASN、PTR）",
+		Execute:     logColumnsMigration(3),
+	},
+	{
+		Version:     4,
+		Description: "添加域名分类字段和分类表",
+		Execute:     migration004AddDomainCategory,
+	},
+	{
+		Version:     5,
+		Description: "添加 upstream 字段（应答的上游服务器）",
+		Execute:     logColumnsMigration(5),
+	},
+	{
+		Version:     6,
+		Description: "创建批次确认表（Agent 经管理端写入日志时由管理端建表）",
+		SQL: `
+    CREATE TABLE IF NOT EXISTS dns_ingest_batches (
+        node_id UInt32 COMMENT '节点ID',
+        file_path String COMMENT '日志文件路径',
+        stream_id String COMMENT '文件标识（inode-起始时间）',
+        seq UInt64 COMMENT '批次序号',
+        start_offset UInt64 COMMENT '起始偏移',
+        end_offset UInt64 COMMENT '结束偏移',
+        rows UInt32 COMMENT '日志条数',
+        reread UInt8 DEFAULT 0 COMMENT '是否为补读批次',
+        sent_at DateTime64(3) COMMENT '发送时间',
+        inserted_at DateTime64(3) DEFAULT now64(3) COMMENT '写入时间'
+    ) ENGINE = MergeTree()
+    PARTITION BY toYYYYMM(sent_at)
+    ORDER BY (node_id, stream_id, seq)
+    TTL toDate(sent_at) + INTERVAL 30 DAY
+    COMMENT 'DNS日志批次确认表'
+    `,
+	},
+	{
+		Version:     7,
+		Description: "添加 rcode、cache_hit、matched_rule 字段（响应码、缓存命中、命中规则）",
+		Execute:     logColumnsMigration(7),
+	},
+}
+
+// clickHouseHistory ClickHouse 中的迁移记录表
+type clickHouseHistory struct {
+	conn driver.Conn
 }
 
-// 创建迁移记录表
-func createMigrationTable(ctx context.Context, conn driver.Conn) error {
+func (h clickHouseHistory) exists(ctx context.Context) (bool, error) {
+	var count uint64
+	err := h.conn.QueryRow(ctx,
+		"SELECT count() FROM system.tables WHERE database = currentDatabase() AND name = 'schema_migrations'").Scan(&count)
+	return count > 0, err
+}
+
+func (h clickHouseHistory) create(ctx context.Context) error {
 	sql := `
     CREATE TABLE IF NOT EXISTS schema_migrations (
         version UInt32,
@@ -42,74 +93,50 @@ func createMigrationTable(ctx context.Context, conn driver.Conn) error {
     ) ENGINE = MergeTree()
     ORDER BY version
     `
-	return conn.Exec(ctx, sql)
+	return h.conn.Exec(ctx, sql)
 }
 
-// 获取已执行的迁移版本
-func getExecutedMigrations(ctx context.Context, conn driver.Conn) (map[int]bool, error) {
+func (h clickHouseHistory) versions(ctx context.Context) (map[int]bool, error) {
 	executed := make(map[int]bool)
 
-	rows, err := conn.Query(ctx, "SELECT version FROM schema_migrations")
+	rows, err := h.conn.Query(ctx, "SELECT version FROM schema_migrations")
 	if err != nil {
 		return executed, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var version int
+		var version uint32
 		if err := rows.Scan(&version); err != nil {
 			continue
 		}
-		executed[version] = true
+		executed[int(version)] = true
 	}
 
-	return executed, nil
+	return executed, rows.Err()
 }
 
-// 执行迁移
-func runMigrations(ctx context.Context, conn driver.Conn) error {
-	log.Println("🔄 开始执行数据库迁移...")
-
-	// 创建迁移记录表
-	if err := createMigrationTable(ctx, conn); err != nil {
-		return fmt.Errorf("创建迁移记录表失败: %w", err)
-	}
-
-	// 获取已执行的迁移
-	executed, err := getExecutedMigrations(ctx, conn)
-	if err != nil {
-		return fmt.Errorf("获取迁移记录失败: %w", err)
-	}
-
-	// 按版本号排序
-	sort.Slice(migrations, func(i, j int) bool {
-		return migrations[i].Version < migrations[j].Version
-	})
-
-	// 执行未执行的迁移
+// RunClickHouseMigrations 执行 ClickHouse 的版本化迁移，dryRun 时只列出待执行的迁移
+func RunClickHouseMigrations(ctx context.Context, conn driver.Conn, dryRun bool) (*MigrationReport, error) {
+	steps := make([]migrationStep, 0, len(migrations))
 	for _, migration := range migrations {
-		if executed[migration.Version] {
-			log.Printf("⏭️  迁移 v%d 已执行，跳过", migration.Version)
-			continue
-		}
-
-		log.Printf("🚀 执行迁移 v%d: %s", migration.Version, migration.Description)
-
-		if err := executeMigration(ctx, conn, migration); err != nil {
-			return fmt.Errorf("迁移 v%d 执行失败: %w", migration.Version, err)
-		}
-
-		// 记录迁移执行
-		recordSQL := `INSERT INTO schema_migrations (version, description) VALUES (?, ?)`
-		if err := conn.Exec(ctx, recordSQL, migration.Version, migration.Description); err != nil {
-			return fmt.Errorf("记录迁移失败: %w", err)
-		}
-
-		log.Printf("✅ 迁移 v%d 执行成功", migration.Version)
+		migration := migration
+		steps = append(steps, migrationStep{
+			MigrationRecord: MigrationRecord{Version: migration.Version, Description: migration.Description},
+			apply: func(ctx context.Context) error {
+				if err := executeMigration(ctx, conn, migration); err != nil {
+					return err
+				}
+				// ClickHouse 不支持事务，迁移应可重复执行（IF NOT EXISTS），记录失败时下次启动重新执行
+				recordSQL := `INSERT INTO schema_migrations (version, description) VALUES (?, ?)`
+				if err := conn.Exec(ctx, recordSQL, uint32(migration.Version), migration.Description); err != nil {
+					return fmt.Errorf("记录迁移失败: %w", err)
+				}
+				return nil
+			},
+		})
 	}
-
-	log.Println("✅ 所有迁移执行完成")
-	return nil
+	return applyMigrations(ctx, "clickhouse", clickHouseHistory{conn: conn}, steps, dryRun)
 }
 
 // 执行单个迁移
@@ -157,3 +184,29 @@ func migration001CreateInitialTable(ctx context.Context, conn driver.Conn) error
     `
 	return conn.Exec(ctx, sql)
 }
+
+// logColumnsMigration 添加 dnsQueryLogColumns 中由该版本引入的列
+func logColumnsMigration(version int) func(ctx context.Context, conn driver.Conn) error {
+	return func(ctx context.Context, conn driver.Conn) error {
+		return addLogColumns(ctx, conn, version)
+	}
+}
+
+// 迁移 v4：添加域名分类字段和分类表
+func migration004AddDomainCategory(ctx context.Context, conn driver.Conn) error {
+	if err := addLogColumns(ctx, conn, 4); err != nil {
+		return err
+	}
+
+	sql := `
+    CREATE TABLE IF NOT EXISTS domain_categories (
+        domain String COMMENT '域名（后缀匹配）',
+        category LowCardinality(String) COMMENT '分类',
+        domain_set String COMMENT '来源域名集',
+        updated_at DateTime DEFAULT now() COMMENT '更新时间'
+    ) ENGINE = MergeTree()
+    ORDER BY domain
+    COMMENT '域名分类表（由域名集同步）'
+    `
+	return conn.Exec(ctx, sql)
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/models"
+)
+
+// SQLiteMigration SQLite 的版本化迁移。新增表和列仍由 AutoMigrate 处理，
+// 这里放 AutoMigrate 做不到的变更：数据回填、列改名、删除列和索引调整等
+type SQLiteMigration struct {
+	Version     int
+	Description string
+	Execute     func(tx *gorm.DB) error // 在事务中执行，与迁移记录一起提交
+}
+
+// sqliteMigrations 所有 SQLite 迁移，版本号只增不改，已发布的迁移不要修改
+var sqliteMigrations = []SQLiteMigration{
+	{
+		Version:     1,
+		Description: "旧记录补充默认值（节点 ID 列表、启用状态、日志路径）",
+		Execute:     sqliteMigration001BackfillDefaults,
+	},
+}
+
+// schemaMigration SQLite 中的迁移记录
+type schemaMigration struct {
+	Version     int `gorm:"primaryKey;autoIncrement:false"`
+	Description string
+	ExecutedAt  time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// sqliteHistory SQLite 中的迁移记录表
+type sqliteHistory struct {
+	db *gorm.DB
+}
+
+func (h sqliteHistory) exists(ctx context.Context) (bool, error) {
+	return h.db.WithContext(ctx).Migrator().HasTable(&schemaMigration{}), nil
+}
+
+func (h sqliteHistory) create(ctx context.Context) error {
+	return h.db.WithContext(ctx).AutoMigrate(&schemaMigration{})
+}
+
+func (h sqliteHistory) versions(ctx context.Context) (map[int]bool, error) {
+	var versions []int
+	if err := h.db.WithContext(ctx).Model(&schemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return nil, err
+	}
+	executed := make(map[int]bool, len(versions))
+	for _, version := range versions {
+		executed[version] = true
+	}
+	return executed, nil
+}
+
+// RunSQLiteMigrations 执行 SQLite 的版本化迁移，dryRun 时只列出待执行的迁移
+func RunSQLiteMigrations(dryRun bool) (*MigrationReport, error) {
+	steps := make([]migrationStep, 0, len(sqliteMigrations))
+	for _, migration := range sqliteMigrations {
+		migration := migration
+		steps = append(steps, migrationStep{
+			MigrationRecord: MigrationRecord{Version: migration.Version, Description: migration.Description},
+			apply: func(ctx context.Context) error {
+				return DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+					if err := migration.Execute(tx); err != nil {
+						return err
+					}
+					return tx.Create(&schemaMigration{
+						Version:     migration.Version,
+						Description: migration.Description,
+						ExecutedAt:  time.Now(),
+					}).Error
+				})
+			},
+		})
+	}
+	return applyMigrations(context.Background(), "sqlite", sqliteHistory{db: DB}, steps, dryRun)
+}
+
+// 迁移 v1：旧版本建的记录缺少后来新增字段的默认值
+func sqliteMigration001BackfillDefaults(tx *gorm.DB) error {
+	updates := []struct {
+		model  interface{}
+		where  string
+		column string
+		value  interface{}
+	}{
+		{&models.AddressMap{}, "node_ids IS NULL", "node_ids", "[]"},
+		{&models.AddressMap{}, "enabled IS NULL", "enabled", true},
+		{&models.DNSServer{}, "node_ids IS NULL", "node_ids", "[]"},
+		{&models.DNSServer{}, "enabled IS NULL", "enabled", true},
+		{&models.Node{}, "log_path IS NULL OR log_path = ''", "log_path", "/var/log/smartdns/audit.log"},
+		{&models.Node{}, "log_monitor_enabled IS NULL", "log_monitor_enabled", false},
+	}
+
+	for _, u := range updates {
+		if err := tx.Model(u.model).Where(u.where).Update(u.column, u.value).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"smartdns-manager/config"
+)
+
+// PGLogConn 日志存储使用 TimescaleDB 时的连接池
+var PGLogConn *pgxpool.Pool
+
+// InitTimescale 初始化 PostgreSQL / TimescaleDB 日志存储连接
+func InitTimescale() {
+	cfg := config.GetTimescaleConfig()
+	log.Printf("🔗 正在连接 PostgreSQL: %s:%d", cfg.Host, cfg.Port)
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN())
+	if err != nil {
+		log.Fatal("❌ PostgreSQL 连接配置错误:", err)
+	}
+	poolConfig.MaxConns = 20
+	poolConfig.MinConns = 2
+	poolConfig.MaxConnLifetime = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	PGLogConn, err = pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		log.Fatal("❌ 连接 PostgreSQL 失败:", err)
+	}
+	if err := PGLogConn.Ping(ctx); err != nil {
+		PGLogConn.Close()
+		log.Fatal("❌ 连接 PostgreSQL 失败:", err)
+	}
+
+	log.Printf("✅ PostgreSQL 初始化完成 - 数据库: %s", cfg.Database)
+}
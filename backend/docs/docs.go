@@ -0,0 +1,9 @@
+// Package docs 内嵌由 cmd/openapi-gen 生成的 OpenAPI 接口文档
+package docs
+
+import _ "embed"
+
+// OpenAPI OpenAPI 3 文档（JSON），修改路由或处理器后在 backend 目录执行 go generate 重新生成
+//
+//go:embed openapi.json
+var OpenAPI []byte
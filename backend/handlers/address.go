@@ -6,9 +6,9 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"regexp"
 	"smartdns-manager/services"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -17,7 +17,7 @@ import (
 	"smartdns-manager/models"
 )
 
-var syncService = services.NewConfigSyncService()
+var listImportService = services.NewListImportService()
 
 // AddAddress 添加地址映射
 func AddAddress(c *gin.Context) {
@@ -71,6 +71,14 @@ func AddAddress(c *gin.Context) {
 		return
 	}
 
+	if err := services.NormalizeAddressMap(&address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	// 检查是否已存在
 	var existing models.AddressMap
 	query := database.DB.Where("domain = ?", address.Domain)
@@ -88,8 +96,13 @@ func AddAddress(c *gin.Context) {
 		return
 	}
 
+	if !checkOrganizationNodeList(c, address.NodeIDs) {
+		return
+	}
+
 	// 默认启用
 	address.Enabled = true
+	address.OrganizationID = requestOrganizationID(c)
 
 	// 保存到数据库
 	if err := database.DB.Create(&address).Error; err != nil {
@@ -100,21 +113,28 @@ func AddAddress(c *gin.Context) {
 		})
 		return
 	}
+	recordAudit(c, models.AuditEntityAddress, address.ID, address.Domain, models.AuditActionCreate, nil, address)
 
 	// 自动同步到节点
-	go func() {
-		if err := syncService.SyncAddressToNodes(&address); err != nil {
-			log.Printf("同步到节点失败: %v", err)
-		}
-	}()
+	jobID := enqueueAddressJob(services.GetSyncJobQueue().EnqueueAddressSync(c.Request.Context(), "添加地址映射 "+address.Domain, address))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
 		"message": "添加成功，正在同步到节点...",
 		"data":    address,
+		"job_id":  jobID,
 	})
 }
 
+// enqueueAddressJob 返回同步任务ID，创建任务失败时记录日志并返回 0
+func enqueueAddressJob(job *models.SyncJob, err error) uint {
+	if err != nil {
+		log.Printf("创建同步任务失败: %v", err)
+		return 0
+	}
+	return job.ID
+}
+
 // UpdateAddress 更新地址映射
 func UpdateAddress(c *gin.Context) {
 	id := c.Param("id")
@@ -143,6 +163,8 @@ func UpdateAddress(c *gin.Context) {
 		return
 	}
 
+	before := address
+
 	var updateData models.AddressMap
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -162,8 +184,21 @@ func UpdateAddress(c *gin.Context) {
 	}
 	address.Tags = updateData.Tags
 	address.Comment = updateData.Comment
+	address.TTL = updateData.TTL
+	address.NoServeExpired = updateData.NoServeExpired
 	address.NodeIDs = updateData.NodeIDs
 	address.Enabled = updateData.Enabled
+	if !checkOrganizationNodeList(c, address.NodeIDs) {
+		return
+	}
+
+	if err := services.NormalizeAddressMap(&address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	if err := database.DB.Save(&address).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -173,18 +208,16 @@ func UpdateAddress(c *gin.Context) {
 		})
 		return
 	}
+	recordAudit(c, models.AuditEntityAddress, address.ID, address.Domain, models.AuditActionUpdate, before, address)
 
 	// ========== 自动同步到节点 ==========
-	go func() {
-		if err := syncService.SyncAddressToNodes(&address); err != nil {
-			log.Printf("同步地址映射到节点失败: %v", err)
-		}
-	}()
+	jobID := enqueueAddressJob(services.GetSyncJobQueue().EnqueueAddressSync(c.Request.Context(), "更新地址映射 "+address.Domain, address))
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "地址映射更新成功，正在同步到节点...",
 		"data":    address,
+		"job_id":  jobID,
 	})
 }
 
@@ -216,13 +249,6 @@ func DeleteAddress(c *gin.Context) {
 		return
 	}
 
-	// ========== 先从节点删除 ==========
-	go func() {
-		if err := syncService.DeleteAddressFromNodes(&address); err != nil {
-			log.Printf("从节点删除地址映射失败: %v", err)
-		}
-	}()
-
 	// 从数据库删除
 	if err := database.DB.Delete(&address).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -232,10 +258,15 @@ func DeleteAddress(c *gin.Context) {
 		})
 		return
 	}
+	recordAudit(c, models.AuditEntityAddress, address.ID, address.Domain, models.AuditActionDelete, address, nil)
+
+	// ========== 从节点删除 ==========
+	jobID := enqueueAddressJob(services.GetSyncJobQueue().EnqueueAddressDelete(c.Request.Context(), "删除地址映射 "+address.Domain, address))
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "地址映射删除成功，正在从节点移除...",
+		"job_id":  jobID,
 	})
 }
 
@@ -254,6 +285,9 @@ func BatchAddAddresses(c *gin.Context) {
 		})
 		return
 	}
+	if !checkOrganizationNodes(c, request.NodeIDs) {
+		return
+	}
 
 	successCount := 0
 	failCount := 0
@@ -274,6 +308,16 @@ func BatchAddAddresses(c *gin.Context) {
 			"success": false,
 		}
 
+		if addr.Type == "" {
+			addr.Type = "address"
+		}
+		if err := services.NormalizeAddressMap(&addr); err != nil {
+			result["error"] = err.Error()
+			failCount++
+			results = append(results, result)
+			continue
+		}
+
 		// 检查是否已存在
 		var existing models.AddressMap
 		if err := database.DB.Where("domain = ? AND ip = ?", addr.Domain, addr.IP).First(&existing).Error; err == nil {
@@ -286,6 +330,7 @@ func BatchAddAddresses(c *gin.Context) {
 		// 设置节点ID和启用状态
 		addr.NodeIDs = nodeIDsJSON
 		addr.Enabled = true
+		addr.OrganizationID = requestOrganizationID(c)
 
 		if err := database.DB.Create(&addr).Error; err != nil {
 			result["error"] = err.Error()
@@ -300,16 +345,10 @@ func BatchAddAddresses(c *gin.Context) {
 	}
 
 	// ========== 批量同步到节点 ==========
+	var jobID uint
 	if len(addedAddresses) > 0 {
-		go func() {
-			log.Printf("开始批量同步 %d 个地址映射到节点", len(addedAddresses))
-			for _, addr := range addedAddresses {
-				if err := syncService.SyncAddressToNodes(&addr); err != nil {
-					log.Printf("同步地址映射失败 (%s -> %s): %v", addr.Domain, addr.IP, err)
-				}
-			}
-			log.Printf("批量同步完成")
-		}()
+		jobID = enqueueAddressJob(services.GetSyncJobQueue().EnqueueAddressSync(c.Request.Context(),
+			fmt.Sprintf("批量添加 %d 个地址映射", len(addedAddresses)), addedAddresses...))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -318,17 +357,25 @@ func BatchAddAddresses(c *gin.Context) {
 		"success_count": successCount,
 		"fail_count":    failCount,
 		"results":       results,
+		"job_id":        jobID,
 	})
 }
 
-// ImportAddresses 从文件导入地址映射
-func ImportAddresses(c *gin.Context) {
-	var request struct {
-		Content string `json:"content" binding:"required"` // 配置文件内容
-		Format  string `json:"format"`                     // 格式：smartdns, hosts
-		NodeIDs []uint `json:"node_ids"`                   // 应用到的节点
-	}
+// ImportAddressesRequest 导入地址映射请求
+type ImportAddressesRequest struct {
+	Content        string `json:"content" binding:"required"` // 列表内容
+	Format         string `json:"format"`                     // auto / smartdns / hosts / dnsmasq / adguard / plain，默认自动识别
+	NodeIDs        []uint `json:"node_ids"`                   // 应用到的节点
+	ConflictMode   string `json:"conflict_mode"`              // 与现有记录冲突时：skip / overwrite / merge
+	BlockDomainSet string `json:"block_domain_set"`           // 屏蔽条目写入的域名集名称，为空时作为地址映射导入
+}
+
+var blockDomainSetNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
 
+// PreviewImportAddresses 预览导入内容，返回解析出的条目、冲突和无法识别的行，不修改数据
+// POST /api/addresses/import/preview
+func PreviewImportAddresses(c *gin.Context) {
+	var request ImportAddressesRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -338,16 +385,55 @@ func ImportAddresses(c *gin.Context) {
 		return
 	}
 
-	addresses, err := parseAddressesFromContent(request.Content, request.Format)
+	preview, err := listImportService.Preview(request.Content, request.Format)
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    preview,
+	})
+}
+
+// ImportAddresses 导入 hosts、dnsmasq、AdGuard 等格式的列表
+// POST /api/addresses/import
+func ImportAddresses(c *gin.Context) {
+	var request ImportAddressesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "解析内容失败",
+			"message": "请求参数错误",
 			"error":   err.Error(),
 		})
 		return
 	}
 
+	if request.BlockDomainSet != "" && !blockDomainSetNamePattern.MatchString(request.BlockDomainSet) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "域名集名称只能包含字母、数字、下划线和横线",
+		})
+		return
+	}
+
+	preview, err := listImportService.Preview(request.Content, request.Format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if !checkOrganizationNodes(c, request.NodeIDs) {
+		return
+	}
+
 	// 将 NodeIDs 转为 JSON
 	nodeIDsJSON := "[]"
 	if len(request.NodeIDs) > 0 {
@@ -355,36 +441,45 @@ func ImportAddresses(c *gin.Context) {
 		nodeIDsJSON = string(nodeIDsBytes)
 	}
 
-	successCount := 0
-	importedAddresses := []models.AddressMap{}
-
-	for _, addr := range addresses {
-		addr.NodeIDs = nodeIDsJSON
-		addr.Enabled = true
-
-		if err := database.DB.Create(&addr).Error; err == nil {
-			successCount++
-			importedAddresses = append(importedAddresses, addr)
-		}
+	result, err := listImportService.Commit(preview, services.ImportOptions{
+		NodeIDs:        nodeIDsJSON,
+		ConflictMode:   request.ConflictMode,
+		BlockDomainSet: request.BlockDomainSet,
+		OrganizationID: requestOrganizationID(c),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "导入失败",
+			"error":   err.Error(),
+		})
+		return
 	}
 
 	// ========== 批量同步到节点 ==========
-	if len(importedAddresses) > 0 {
-		go func() {
-			log.Printf("开始同步导入的 %d 个地址映射", len(importedAddresses))
-			for _, addr := range importedAddresses {
-				if err := syncService.SyncAddressToNodes(&addr); err != nil {
-					log.Printf("同步导入的地址映射失败: %v", err)
-				}
-			}
-		}()
+	var jobID uint
+	changed := append(result.Created, result.Updated...)
+	if len(changed) > 0 {
+		jobID = enqueueAddressJob(services.GetSyncJobQueue().EnqueueAddressSync(c.Request.Context(),
+			fmt.Sprintf("导入 %d 个地址映射", len(changed)), changed...))
+	}
+	if result.DomainSet != nil {
+		go listImportService.SyncBlockList(result.DomainSet, result.DomainRule, result.NewRule)
+		domainCategoryService.SyncToClickHouseAsync()
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":      true,
 		"message":      "导入完成，正在同步到节点...",
-		"total":        len(addresses),
-		"successCount": successCount,
+		"total":        len(preview.Entries),
+		"successCount": len(changed),
+		"created":      len(result.Created),
+		"updated":      len(result.Updated),
+		"skipped":      result.Skipped,
+		"blocked":      result.Blocked,
+		"issues":       len(preview.Issues),
+		"domain_set":   result.DomainSet,
+		"job_id":       jobID,
 	})
 }
 
@@ -392,7 +487,7 @@ func ImportAddresses(c *gin.Context) {
 func GetAddresses(c *gin.Context) {
 	var addresses []models.AddressMap
 
-	query := database.DB
+	query := scopeOrganization(c, database.DB)
 
 	// 支持标签筛选
 	if tags := c.Query("tags"); tags != "" {
@@ -429,39 +524,3 @@ func GetAddresses(c *gin.Context) {
 		"page_size": pageSize,
 	})
 }
-
-// 辅助函数：从内容解析地址映射
-func parseAddressesFromContent(content, format string) ([]models.AddressMap, error) {
-	addresses := make([]models.AddressMap, 0)
-	lines := strings.Split(content, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		parts := strings.Fields(line)
-		if len(parts) < 2 {
-			continue
-		}
-
-		// 检查是否是 CNAME 格式
-		if len(parts) >= 3 && strings.ToLower(parts[1]) == "cname" {
-			addresses = append(addresses, models.AddressMap{
-				Domain: parts[0],
-				CNAME:  parts[2],
-				Type:   "cname",
-			})
-		} else {
-			// Address 格式
-			addresses = append(addresses, models.AddressMap{
-				Domain: parts[0],
-				IP:     parts[1],
-				Type:   "address",
-			})
-		}
-	}
-
-	return addresses, nil
-}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// ConnectAgentControl Agent 主动建立控制通道（WebSocket），使用节点控制令牌认证
+// GET /api/agent/control
+//
+// 连接建立后管理端经该通道下发启停采集、配置下发、日志查看等命令，节点位于 NAT 之后时无需开放 Agent API 端口
+func ConnectAgentControl(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !strings.HasPrefix(token, models.AgentControlTokenPrefix) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "缺少节点控制令牌",
+		})
+		return
+	}
+
+	var node models.Node
+	if err := database.DB.Where("agent_control_token_hash = ?", models.HashAPIToken(token)).First(&node).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "节点控制令牌无效",
+		})
+		return
+	}
+
+	// 已通过令牌认证，不校验 Origin
+	remoteAddr := c.ClientIP()
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			services.GetAgentControlHub().Serve(&node, ws, remoteAddr)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// GetAgentControlStatus 获取节点控制通道的连接状态
+// GET /api/nodes/:id/agent/control
+func GetAgentControlStatus(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    services.GetAgentControlHub().Status(&node),
+	})
+}
+
+// CreateAgentControlToken 生成节点控制令牌，明文只返回一次，已有令牌立即失效并断开当前连接
+// POST /api/nodes/:id/agent/control/token
+func CreateAgentControlToken(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	plain, err := services.IssueAgentControlToken(&node)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "令牌已生成，请配置到 Agent 的 CONTROL_TOKEN 后重启 Agent，关闭后将无法再次查看",
+		"token":   plain,
+	})
+}
+
+// RevokeAgentControlToken 吊销节点控制令牌并断开当前连接，之后只能直接访问 Agent API 端口
+// DELETE /api/nodes/:id/agent/control/token
+func RevokeAgentControlToken(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	if err := database.DB.Model(&node).Update("agent_control_token_hash", "").Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "吊销令牌失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	services.GetAgentControlHub().Disconnect(node.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "控制令牌已吊销",
+	})
+}
@@ -33,7 +33,7 @@ type DeployAgentResponse struct {
 	AgentStatus string   `json:"agent_status"`
 }
 
-// DeployAgent 一键部署 Agent
+// DeployAgent 一键部署 Agent，返回后台任务，通过 GET /api/jobs/:id 查看进度和部署输出
 func DeployAgent(c *gin.Context) {
 	var req models.DeployAgentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -54,6 +54,22 @@ func DeployAgent(c *gin.Context) {
 		return
 	}
 
+	if req.ClickHouseHost == "" && req.IngestURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请填写 ClickHouse 地址，或设置经管理端写入的地址",
+		})
+		return
+	}
+
+	if req.KafkaFormat != "" && req.KafkaFormat != "json" && req.KafkaFormat != "avro" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "不支持的 Kafka 消息格式: " + req.KafkaFormat,
+		})
+		return
+	}
+
 	// 设置默认值
 	if req.ClickHousePort == 0 {
 		req.ClickHousePort = 9000
@@ -64,7 +80,8 @@ func DeployAgent(c *gin.Context) {
 	if req.ClickHouseUser == "" {
 		req.ClickHouseUser = "default"
 	}
-	if req.LogFilePath == "" {
+	// Windows 节点未指定时由安装脚本使用 %ProgramData%\smartdns\log\audit.log
+	if req.LogFilePath == "" && req.DeployMode != models.AgentDeployModeWindows {
 		req.LogFilePath = "/var/log/smartdns/audit.log"
 	}
 	if req.BatchSize == 0 {
@@ -73,30 +90,40 @@ func DeployAgent(c *gin.Context) {
 	if req.FlushInterval == 0 {
 		req.FlushInterval = 2
 	}
-
-	// 创建部署服务
-	deployService := services.NewAgentDeployService()
-
-	// 执行部署
-	response, err := deployService.DeployAgent(&node, &req)
+	if req.DeployMode == models.AgentDeployModeWindows {
+		if !models.ValidAgentTransport(req.Transport) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "不支持的连接方式: " + req.Transport,
+			})
+			return
+		}
+		if req.ServiceManager != "" && req.ServiceManager != "sc" && req.ServiceManager != "nssm" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Windows 服务托管方式只支持 sc 或 nssm",
+			})
+			return
+		}
+		if req.Transport == "" {
+			req.Transport = models.AgentTransportSSH
+		}
+	}
+
+	// 安装可能持续数分钟，作为后台任务执行，部署输出在任务结果中
+	job, err := services.StartJob(c.Request.Context(), models.BackgroundJobAgentDeploy, node.ID, "部署 Agent 到节点 "+node.Name, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusConflict, gin.H{
 			"success": false,
-			"message": "部署失败: " + err.Error(),
+			"message": err.Error(),
 		})
 		return
 	}
 
-	// 更新节点 Agent 状态
-	database.DB.Model(&node).Updates(map[string]interface{}{
-		"agent_installed": true,
-		"agent_version":   deployService.GetLatestVersion(),
-		"deploy_mode":     req.DeployMode,
-	})
-
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    response,
+		"message": "Agent 部署已开始",
+		"data":    job,
 	})
 }
 
@@ -171,7 +198,7 @@ func UninstallAgent(c *gin.Context) {
 	}
 
 	deployService := services.NewAgentDeployService()
-	err = deployService.UninstallAgent(&node)
+	err = deployService.UninstallAgent(c.Request.Context(), &node)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// GetAgentLogFilter 获取节点的日志过滤规则，以及 Agent 当前生效的规则和丢弃统计
+// GET /api/nodes/:id/agent/filter
+func GetAgentLogFilter(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	status, err := services.GetAgentLogFilterStatus(&node)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// UpdateAgentLogFilter 保存节点的日志过滤规则并下发给 Agent
+// PUT /api/nodes/:id/agent/filter
+//
+// Agent 在发送前按域名后缀、正则和客户端网段丢弃日志，并按域名后缀采样；下发失败时规则已保存，可稍后再次提交
+func UpdateAgentLogFilter(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	var req models.AgentLogFilter
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := services.SaveAgentLogFilter(&node, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := services.PushAgentLogFilter(&node, req); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "过滤规则已保存，但下发到 Agent 失败: " + err.Error(),
+			"data":    gin.H{"applied": false},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "过滤规则已保存并下发",
+		"data":    gin.H{"applied": true},
+	})
+}
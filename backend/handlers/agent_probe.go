@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var agentProbeService *services.AgentProbeService
+
+// InitAgentProbeHandler 初始化 Agent 解析探测处理器
+func InitAgentProbeHandler(service *services.AgentProbeService) {
+	agentProbeService = service
+}
+
+// parseProbeSince 解析统计时间范围，默认最近24小时，最多30天
+func parseProbeSince(c *gin.Context) time.Time {
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if hours < 1 || hours > 720 {
+		hours = 24
+	}
+	return time.Now().Add(-time.Duration(hours) * time.Hour)
+}
+
+// GetProbeSLA 获取各节点的解析 SLA
+// GET /api/probe-sla?hours=24&target=99.9
+func GetProbeSLA(c *gin.Context) {
+	target, err := strconv.ParseFloat(c.DefaultQuery("target", "99.9"), 64)
+	if err != nil || target <= 0 || target > 100 {
+		target = 99.9
+	}
+
+	sla, err := agentProbeService.GetSLA(parseProbeSince(c), target)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if nodeIDs, scoped := organizationNodeIDs(c); scoped {
+		owned := make(map[uint]bool, len(nodeIDs))
+		for _, id := range nodeIDs {
+			owned[id] = true
+		}
+		visible := make([]models.ProbeSLA, 0, len(sla))
+		for _, item := range sla {
+			if owned[item.NodeID] {
+				visible = append(visible, item)
+			}
+		}
+		sla = visible
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    sla,
+		"target":  target,
+	})
+}
+
+// GetNodeProbeStats 获取节点的探测明细
+// GET /api/nodes/:id/probe-stats?hours=24
+func GetNodeProbeStats(c *gin.Context) {
+	nodeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的节点ID",
+		})
+		return
+	}
+
+	stats, err := agentProbeService.GetNodeStats(uint(nodeID), parseProbeSince(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "查询探测结果失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// CollectProbeStats 立即拉取所有 Agent 的探测结果
+// POST /api/probe-sla/collect
+func CollectProbeStats(c *gin.Context) {
+	output, err := agentProbeService.Collect(c.Request.Context(), models.AgentProbeConfig{})
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": output,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": output,
+	})
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var alertRuleEngine *services.AlertRuleEngine
+
+// InitAlertRuleHandler 初始化告警规则处理器
+func InitAlertRuleHandler(engine *services.AlertRuleEngine) {
+	alertRuleEngine = engine
+}
+
+// GetAlertRules 获取告警规则及各规则未恢复的告警数
+// GET /api/alert-rules
+func GetAlertRules(c *gin.Context) {
+	var rules []models.AlertRule
+	database.DB.Order("name").Find(&rules)
+
+	var counts []struct {
+		RuleID uint
+		Count  int64
+	}
+	database.DB.Model(&models.Alert{}).
+		Select("rule_id, COUNT(*) AS count").
+		Where("status = ?", models.AlertStatusFiring).
+		Group("rule_id").Scan(&counts)
+	firing := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		firing[count.RuleID] = count.Count
+	}
+
+	data := make([]gin.H, 0, len(rules))
+	for _, rule := range rules {
+		data = append(data, gin.H{
+			"rule":          rule,
+			"firing_alerts": firing[rule.ID],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+		"total":   len(rules),
+	})
+}
+
+// CreateAlertRule 创建告警规则
+// POST /api/alert-rules
+func CreateAlertRule(c *gin.Context) {
+	var request models.AlertRuleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rule := models.AlertRule{CreatedBy: auditActor(c).Username}
+	if err := services.BuildAlertRule(&rule, &request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err := database.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "保存失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "告警规则已创建",
+		"data":    rule,
+	})
+}
+
+// UpdateAlertRule 修改告警规则，禁用规则时未恢复的告警标记为已恢复
+// PUT /api/alert-rules/:id
+func UpdateAlertRule(c *gin.Context) {
+	rule, ok := loadAlertRule(c)
+	if !ok {
+		return
+	}
+
+	var request models.AlertRuleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err := services.BuildAlertRule(rule, &request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err := database.DB.Save(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "保存失败: " + err.Error()})
+		return
+	}
+	if !rule.Enabled {
+		resolveRuleAlerts(rule.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "告警规则已更新",
+		"data":    rule,
+	})
+}
+
+// DeleteAlertRule 删除告警规则，保留历史告警
+// DELETE /api/alert-rules/:id
+func DeleteAlertRule(c *gin.Context) {
+	rule, ok := loadAlertRule(c)
+	if !ok {
+		return
+	}
+	resolveRuleAlerts(rule.ID)
+	database.DB.Delete(rule)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已删除",
+	})
+}
+
+// EvaluateAlertRule 立即评估规则，dry_run=true 时只返回指标值，不生成告警
+// POST /api/alert-rules/:id/evaluate
+func EvaluateAlertRule(c *gin.Context) {
+	rule, ok := loadAlertRule(c)
+	if !ok {
+		return
+	}
+
+	var values []models.AlertRuleValue
+	var err error
+	if c.Query("dry_run") == "true" {
+		values, err = alertRuleEngine.Evaluate(c.Request.Context(), rule)
+	} else {
+		values, err = alertRuleEngine.EvaluateRule(rule)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "评估失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    values,
+	})
+}
+
+// GetAlerts 获取告警列表，支持按状态、级别、规则和节点过滤
+// GET /api/alerts
+func GetAlerts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 20
+	}
+
+	query := scopeOrganizationNodes(c, database.DB.Model(&models.Alert{}), "node_id")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if severity := c.Query("severity"); severity != "" {
+		query = query.Where("severity = ?", severity)
+	}
+	if ruleID := c.Query("rule_id"); ruleID != "" {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+
+	var total int64
+	query.Count(&total)
+	var alerts []models.Alert
+	query.Order("started_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&alerts)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      alerts,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// AcknowledgeAlert 确认告警
+// POST /api/alerts/:id/ack
+func AcknowledgeAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的ID"})
+		return
+	}
+	var alert models.Alert
+	if err := database.DB.First(&alert, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "告警不存在"})
+		return
+	}
+	if alert.AcknowledgedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "告警已确认"})
+		return
+	}
+
+	now := time.Now()
+	database.DB.Model(&alert).Updates(map[string]interface{}{
+		"acknowledged_by": auditActor(c).Username,
+		"acknowledged_at": &now,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "告警已确认",
+		"data":    alert,
+	})
+}
+
+// loadAlertRule 按路径参数读取告警规则，失败时已写入响应
+func loadAlertRule(c *gin.Context) (*models.AlertRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的ID"})
+		return nil, false
+	}
+	var rule models.AlertRule
+	if err := database.DB.First(&rule, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "告警规则不存在"})
+		return nil, false
+	}
+	return &rule, true
+}
+
+// resolveRuleAlerts 规则禁用或删除时，将其未恢复的告警标记为已恢复
+func resolveRuleAlerts(ruleID uint) {
+	now := time.Now()
+	database.DB.Model(&models.Alert{}).
+		Where("rule_id = ? AND status = ?", ruleID, models.AlertStatusFiring).
+		Updates(map[string]interface{}{"status": models.AlertStatusResolved, "resolved_at": &now})
+}
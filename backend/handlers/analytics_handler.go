@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/services"
+)
+
+// 日志分析服务
+var analyticsService *services.LogAnalyticsService
+
+// InitAnalyticsHandler 初始化分析处理器
+func InitAnalyticsHandler(service *services.LogAnalyticsService) {
+	analyticsService = service
+}
+
+// parseAnalyticsParams 解析分析接口通用参数（节点、时间范围），默认最近24小时；
+// 节点不属于当前组织时返回 false，已写入响应
+func parseAnalyticsParams(c *gin.Context) (uint, time.Time, time.Time, bool) {
+	endTime := time.Now()
+	startTime := endTime.Add(-24 * time.Hour)
+
+	if st := c.Query("start_time"); st != "" {
+		if t, err := time.Parse(time.RFC3339, st); err == nil {
+			startTime = t
+		}
+	}
+	if et := c.Query("end_time"); et != "" {
+		if t, err := time.Parse(time.RFC3339, et); err == nil {
+			endTime = t
+		}
+	}
+
+	var nodeID uint
+	if nodeIDStr := c.Query("node_id"); nodeIDStr != "" {
+		if id, err := strconv.ParseUint(nodeIDStr, 10, 32); err == nil {
+			nodeID = uint(id)
+		}
+	}
+
+	return nodeID, startTime, endTime, checkOrganizationLogNode(c, nodeID)
+}
+
+// GetQueryTypeAnalytics 查询类型分布与趋势
+// GET /api/analytics/query-types?node_id=&start_time=&end_time=&interval=hour|day
+func GetQueryTypeAnalytics(c *gin.Context) {
+	if analyticsService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "日志分析服务未初始化",
+		})
+		return
+	}
+
+	nodeID, startTime, endTime, ok := parseAnalyticsParams(c)
+	if !ok {
+		return
+	}
+	if !endTime.After(startTime) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "结束时间必须晚于开始时间",
+		})
+		return
+	}
+
+	result, err := analyticsService.GetQueryTypeAnalytics(nodeID, startTime, endTime, c.DefaultQuery("interval", "hour"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取查询类型统计失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetResponseIPAnalytics 应答 IP 分析（Top IP/子网、CDN 识别）
+// GET /api/analytics/response-ips?node_id=&domain=&group_by=ip|subnet&limit=&start_time=&end_time=
+func GetResponseIPAnalytics(c *gin.Context) {
+	if analyticsService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "日志分析服务未初始化",
+		})
+		return
+	}
+
+	nodeID, startTime, endTime, ok := parseAnalyticsParams(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	result, err := analyticsService.GetResponseIPAnalytics(nodeID, c.Query("domain"), startTime, endTime, c.DefaultQuery("group_by", "ip"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取应答IP统计失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetClientProfile 客户端查询画像（查询速率、唯一域名、NXDOMAIN 占比、查询类型分布）
+// GET /api/dns-logs/clients/:ip/profile?node_id=&start_time=&end_time=
+func GetClientProfile(c *gin.Context) {
+	if analyticsService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "日志分析服务未初始化",
+		})
+		return
+	}
+
+	// 启用 hash 脱敏时存储的是哈希值，也可直接按哈希值查询
+	clientIP := c.Param("ip")
+	if net.ParseIP(clientIP) == nil && !strings.HasPrefix(clientIP, services.ClientIPHashPrefix) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的客户端IP",
+		})
+		return
+	}
+	clientIP = clientIPSearchValue(clientIP)
+
+	nodeID, startTime, endTime, ok := parseAnalyticsParams(c)
+	if !ok {
+		return
+	}
+	if !endTime.After(startTime) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "结束时间必须晚于开始时间",
+		})
+		return
+	}
+
+	profile, err := analyticsService.GetClientProfile(clientIP, nodeID, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取客户端画像失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    profile,
+	})
+}
+
+// GetSlowQueryAnalytics 慢查询排查（最慢查询明细、耗时分位数、按上游组/域名汇总）
+// GET /api/analytics/slow-queries?node_id=&group=&domain=&threshold_ms=&limit=&start_time=&end_time=
+func GetSlowQueryAnalytics(c *gin.Context) {
+	if analyticsService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "日志分析服务未初始化",
+		})
+		return
+	}
+
+	nodeID, startTime, endTime, ok := parseAnalyticsParams(c)
+	if !ok {
+		return
+	}
+	thresholdMs, _ := strconv.Atoi(c.DefaultQuery("threshold_ms", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	result, err := analyticsService.GetSlowQueries(nodeID, c.Query("group"), c.Query("domain"), startTime, endTime, thresholdMs, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取慢查询失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetUpstreamAnalytics 上游性能分析（各上游耗时、失败占比、被选中应答的比例）
+// GET /api/analytics/upstreams?node_id=&group=&interval=hour|day&limit=&start_time=&end_time=
+func GetUpstreamAnalytics(c *gin.Context) {
+	if analyticsService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "日志分析服务未初始化",
+		})
+		return
+	}
+
+	nodeID, startTime, endTime, ok := parseAnalyticsParams(c)
+	if !ok {
+		return
+	}
+	if !endTime.After(startTime) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "结束时间必须晚于开始时间",
+		})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	result, err := analyticsService.GetUpstreamAnalytics(nodeID, c.Query("group"), startTime, endTime, c.DefaultQuery("interval", "hour"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取上游统计失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// GetAPITokens 获取 API 令牌列表
+// GET /api/tokens
+func GetAPITokens(c *gin.Context) {
+	var tokens []models.APIToken
+	database.DB.Order("id desc").Find(&tokens)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tokens,
+	})
+}
+
+// CreateAPIToken 创建 API 令牌，明文只在创建时返回一次
+// POST /api/tokens
+func CreateAPIToken(c *gin.Context) {
+	var request models.APITokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if !models.ValidAPITokenScope(request.Scope) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "权限范围必须是 read、sync 或 full",
+		})
+		return
+	}
+	if request.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "有效期不能为负数",
+		})
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "生成令牌失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	plain := models.APITokenPrefix + hex.EncodeToString(secret)
+
+	actor := auditActor(c)
+	token := models.APIToken{
+		Name:      strings.TrimSpace(request.Name),
+		Scope:     request.Scope,
+		TokenHash: models.HashAPIToken(plain),
+		Prefix:    plain[:len(models.APITokenPrefix)+8],
+		UserID:    actor.UserID,
+		CreatedBy: actor.Username,
+	}
+	if request.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, request.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := database.DB.Create(&token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建令牌失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "令牌创建成功，请立即复制保存，关闭后将无法再次查看",
+		"data":    token,
+		"token":   plain,
+	})
+}
+
+// RevokeAPIToken 撤销 API 令牌，使用该令牌的请求立即失效
+// DELETE /api/tokens/:id
+func RevokeAPIToken(c *gin.Context) {
+	result := database.DB.Model(&models.APIToken{}).Where("id = ?", c.Param("id")).Update("revoked", true)
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "令牌不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "令牌已撤销",
+	})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/services"
+)
+
+var auditService = services.NewAuditService()
+
+// recordAudit 记录当前请求用户对实体的变更
+func recordAudit(c *gin.Context, entityType string, entityID uint, entityName, action string, before, after interface{}) {
+	auditService.Record(auditActor(c), entityType, entityID, entityName, action, before, after)
+}
+
+// auditActor 获取当前请求的操作人
+func auditActor(c *gin.Context) services.AuditActor {
+	actor := services.AuditActor{ClientIP: c.ClientIP()}
+	if userID, exists := c.Get("user_id"); exists {
+		actor.UserID, _ = userID.(uint)
+	}
+	if username, exists := c.Get("username"); exists {
+		actor.Username, _ = username.(string)
+	}
+	return actor
+}
+
+// GetEntityHistory 返回指定实体类型的变更历史处理函数，如 GET /addresses/:id/history
+func GetEntityHistory(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的ID",
+			})
+			return
+		}
+
+		history, err := auditService.History(entityType, uint(id))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取变更历史失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    history,
+			"total":   len(history),
+		})
+	}
+}
+
+// GetAuditLogs 查询审计日志（谁在什么时候改了什么）
+func GetAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	filters := map[string]interface{}{}
+	if userID := c.Query("user_id"); userID != "" {
+		filters["user_id"] = userID
+	}
+	if username := c.Query("username"); username != "" {
+		filters["username"] = username
+	}
+	if entityType := c.Query("entity_type"); entityType != "" {
+		filters["entity_type"] = entityType
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		filters["entity_id"] = entityID
+	}
+	if action := c.Query("action"); action != "" {
+		filters["action"] = action
+	}
+
+	var startTime, endTime *time.Time
+	if t, ok := parseNotificationTime(c.Query("start_time")); ok {
+		startTime = &t
+	}
+	if t, ok := parseNotificationTime(c.Query("end_time")); ok {
+		endTime = &t
+	}
+
+	logs, total, err := auditService.List(filters, startTime, endTime, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "查询审计日志失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      logs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetUserActivity 用户操作统计，默认最近 7 天
+func GetUserActivity(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if days <= 0 {
+		days = 7
+	}
+
+	activities, err := auditService.UserActivities(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取用户操作统计失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    activities,
+		"days":    days,
+	})
+}
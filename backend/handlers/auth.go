@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 
 	"smartdns-manager/database"
 	"smartdns-manager/models"
+	"smartdns-manager/services"
 )
 
 var jwtSecret = []byte("your-secret-key-change-in-production") // 生产环境使用环境变量
@@ -46,14 +49,8 @@ func Login(c *gin.Context) {
 
 	// 查找用户
 	var user models.User
-	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": "用户名或密码错误",
-			})
-			return
-		}
+	err := database.DB.Where("username = ?", req.Username).First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "数据库错误",
@@ -61,6 +58,40 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// 应急管理员始终使用本地密码，认证服务故障时仍可登录
+	breakGlass := services.IsBreakGlassUser(req.Username) && err == nil && user.Role == "admin" &&
+		(user.AuthSource == "" || user.AuthSource == models.AuthSourceLocal)
+
+	// Webhook 账号、本地不存在的用户，或独占模式下的非应急账号交给认证 Webhook
+	if services.AuthWebhookEnabled() && !breakGlass &&
+		(err == gorm.ErrRecordNotFound || user.AuthSource == models.AuthSourceWebhook || services.AuthWebhookExclusive()) {
+		loginWithWebhook(c, req)
+		return
+	}
+
+	// LDAP 账号或本地不存在的用户交给 LDAP 认证
+	if services.LDAPEnabled() && (err == gorm.ErrRecordNotFound || user.AuthSource == models.AuthSourceLDAP) {
+		loginWithLDAP(c, req)
+		return
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户名或密码错误",
+		})
+		return
+	}
+
+	// 单点登录账号没有可用的本地密码
+	if user.AuthSource != "" && user.AuthSource != models.AuthSourceLocal {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "该账号请使用单点登录",
+		})
+		return
+	}
+
 	// 检查用户状态
 	if !user.IsActive {
 		c.JSON(http.StatusForbidden, gin.H{
@@ -83,7 +114,18 @@ func Login(c *gin.Context) {
 	user.LastLogin = time.Now()
 	database.DB.Save(&user)
 
-	// 生成 JWT
+	if breakGlass && services.AuthWebhookEnabled() {
+		log.Printf("⚠️ 应急管理员 %s 使用本地密码登录 (IP: %s)", user.Username, c.ClientIP())
+		go notificationService.SendNotification(0, "break_glass_login", "⚠️ 应急管理员登录",
+			fmt.Sprintf("应急管理员 %s 使用本地密码登录\n来源 IP: %s\n时间: %s",
+				user.Username, c.ClientIP(), user.LastLogin.Format("2006-01-02 15:04:05")))
+	}
+
+	respondLogin(c, &user)
+}
+
+// respondLogin 签发 JWT 并返回登录结果，本地登录和单点登录共用
+func respondLogin(c *gin.Context, user *models.User) {
 	expiresAt := time.Now().Add(24 * time.Hour)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id":  user.ID,
@@ -106,7 +148,7 @@ func Login(c *gin.Context) {
 		"message": "登录成功",
 		"data": LoginResponse{
 			Token:     tokenString,
-			User:      &user,
+			User:      user,
 			ExpiresAt: expiresAt,
 		},
 	})
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// GetBackgroundJobs 获取后台任务列表
+// GET /api/jobs?status=interrupted&type=node_init&node_id=
+func GetBackgroundJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := scopeOrganizationNodes(c, database.DB.Model(&models.BackgroundJob{}), "node_id")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if jobType := c.Query("type"); jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var jobs []models.BackgroundJob
+	query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&jobs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      jobs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetBackgroundJob 获取后台任务详情
+// GET /api/jobs/:id
+func GetBackgroundJob(c *gin.Context) {
+	var job models.BackgroundJob
+	if err := database.DB.First(&job, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "后台任务不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// RetryBackgroundJob 从检查点重新执行失败或中断的后台任务
+// POST /api/jobs/:id/retry
+func RetryBackgroundJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的任务ID",
+		})
+		return
+	}
+
+	job, err := services.RetryJob(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "任务已重新开始",
+		"data":    job,
+	})
+}
+
+// CancelBackgroundJob 取消排队中或执行中的后台任务，执行中的任务在当前步骤完成后停止
+// POST /api/jobs/:id/cancel
+func CancelBackgroundJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的任务ID",
+		})
+		return
+	}
+
+	job, err := services.CancelJob(uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	message := "任务已取消"
+	if job.Status == models.BackgroundJobStatusRunning {
+		message = "已请求取消，任务将在当前步骤完成后停止"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    job,
+	})
+}
@@ -4,6 +4,8 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"sync"
@@ -65,6 +67,16 @@ func DownloadBackup(c *gin.Context) {
 	defaultBackupHandler.DownloadBackup(c)
 }
 
+func GetNodeBackupRetention(c *gin.Context) {
+	initBackupHandler()
+	defaultBackupHandler.GetNodeBackupRetention(c)
+}
+
+func UpdateNodeBackupRetention(c *gin.Context) {
+	initBackupHandler()
+	defaultBackupHandler.UpdateNodeBackupRetention(c)
+}
+
 func NewBackupHandler(db *gorm.DB) *BackupHandler {
 	return &BackupHandler{
 		db:             db,
@@ -132,6 +144,7 @@ func (h *BackupHandler) GetNodeBackups(c *gin.Context) {
 type CreateBackupRequest struct {
 	Comment string `json:"comment"`
 	Tags    string `json:"tags"`
+	Force   bool   `json:"force"` // 配置与最近一次备份相同时仍创建新备份
 }
 
 // CreateNodeBackup 创建节点备份
@@ -164,7 +177,16 @@ func (h *BackupHandler) CreateNodeBackup(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	backup, err := h.performBackup(ctx, &node, storage, storageType, req.Comment, req.Tags, false)
+	backup, err := h.performBackup(ctx, &node, storage, storageType, req.Comment, req.Tags, false, req.Force)
+	if err == services.ErrBackupUnchanged {
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "配置与最近一次备份相同，未创建新备份",
+			"data":      backup,
+			"unchanged": true,
+			"success":   true,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -186,11 +208,12 @@ func (h *BackupHandler) performBackup(
 	comment string,
 	tags string,
 	isAuto bool,
+	force bool,
 ) (*models.Backup, error) {
 	// 使用通用备份服务
-	backup, err := h.backupService.PerformNodeBackup(ctx, node, storage, storageType, comment, tags, isAuto)
+	backup, err := h.backupService.PerformNodeBackup(ctx, node, storage, storageType, comment, tags, isAuto, force)
 	if err != nil {
-		return nil, err
+		return backup, err
 	}
 
 	// S3 存储的额外信息（Handler 特有逻辑）
@@ -219,7 +242,8 @@ func (h *BackupHandler) performBackup(
 // }
 
 type PreviewBackupRequest struct {
-	BackupID uint `json:"backup_id" binding:"required"`
+	BackupID     uint `json:"backup_id" binding:"required"`
+	TargetNodeID uint `json:"target_node_id"` // 跨节点恢复的目标节点，预览调整后的配置
 }
 
 // PreviewBackup 预览备份内容
@@ -265,14 +289,30 @@ func (h *BackupHandler) PreviewBackup(c *gin.Context) {
 		return
 	}
 
+	data := gin.H{
+		"backup_id":  backup.ID,
+		"name":       backup.Name,
+		"content":    string(content),
+		"size":       backup.Size,
+		"created_at": backup.CreatedAt,
+	}
+
+	// 跨节点恢复预览：返回针对目标节点调整后的配置
+	if req.TargetNodeID != 0 && req.TargetNodeID != backup.NodeID {
+		var targetNode models.Node
+		if err := h.db.First(&targetNode, req.TargetNodeID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "目标节点不存在"})
+			return
+		}
+
+		adjusted, adjustments := h.backupService.AdjustConfigForNode(string(content), &backup.Node, &targetNode)
+		data["target_node_id"] = targetNode.ID
+		data["adjusted_content"] = adjusted
+		data["adjustments"] = adjustments
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"data": gin.H{
-			"backup_id":  backup.ID,
-			"name":       backup.Name,
-			"content":    string(content),
-			"size":       backup.Size,
-			"created_at": backup.CreatedAt,
-		},
+		"data":    data,
 		"success": true,
 	})
 }
@@ -282,10 +322,11 @@ func (h *BackupHandler) PreviewBackup(c *gin.Context) {
 // ═══════════════════════════════════════════════════════════════
 
 type RestoreBackupRequest struct {
-	BackupID uint `json:"backup_id" binding:"required"`
+	BackupID     uint `json:"backup_id" binding:"required"`
+	TargetNodeID uint `json:"target_node_id"` // 恢复到其他节点（如迁移到新硬件），为空时恢复到原节点
 }
 
-// RestoreNodeBackup 恢复节点备份
+// RestoreNodeBackup 恢复节点备份，指定 target_node_id 时恢复到其他节点并自动调整节点相关配置
 // POST /api/nodes/:id/backups/restore
 func (h *BackupHandler) RestoreNodeBackup(c *gin.Context) {
 	nodeID := c.Param("id")
@@ -327,8 +368,26 @@ func (h *BackupHandler) RestoreNodeBackup(c *gin.Context) {
 		return
 	}
 
+	// 确定目标节点，跨节点恢复时调整监听地址、日志路径等节点相关配置
+	targetNode := backup.Node
+	adjustments := make([]models.ConfigAdjustment, 0)
+	if req.TargetNodeID != 0 && req.TargetNodeID != backup.NodeID {
+		if err := h.db.First(&targetNode, req.TargetNodeID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "目标节点不存在"})
+			return
+		}
+		adjusted, changes := h.backupService.AdjustConfigForNode(string(content), &backup.Node, &targetNode)
+		content = []byte(adjusted)
+		adjustments = changes
+	}
+
+	configPath := targetNode.ConfigPath
+	if configPath == "" {
+		configPath = "/etc/smartdns/smartdns.conf"
+	}
+
 	// 创建 SSH 客户端
-	sshClient, err := services.NewSSHClient(&backup.Node)
+	sshClient, err := services.NewSSHClient(&targetNode)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建SSH连接失败: %v", err)})
 		return
@@ -342,22 +401,22 @@ func (h *BackupHandler) RestoreNodeBackup(c *gin.Context) {
 		return
 	}
 
-	// 备份当前配置
-	backupCmd := fmt.Sprintf("sudo cp /etc/smartdns/smartdns.conf /etc/smartdns/smartdns.conf.before-restore-%d", time.Now().Unix())
+	// 备份当前配置（新硬件上可能尚无配置文件）
+	backupCmd := fmt.Sprintf("if [ -f %s ]; then sudo cp %s %s.before-restore-%d; fi", configPath, configPath, configPath, time.Now().Unix())
 	if _, err := sshClient.ExecuteCommand(backupCmd); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("备份当前配置失败: %v", err)})
 		return
 	}
 
 	// 恢复配置
-	restoreCmd := fmt.Sprintf("sudo mv %s /etc/smartdns/smartdns.conf && sudo chmod 644 /etc/smartdns/smartdns.conf", tmpFile)
+	restoreCmd := fmt.Sprintf("sudo mv %s %s && sudo chmod 644 %s", tmpFile, configPath, configPath)
 	if _, err := sshClient.ExecuteCommand(restoreCmd); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("恢复配置失败: %v", err)})
 		return
 	}
 
 	// 重启 SmartDNS 服务
-	if _, err := sshClient.ExecuteCommand("sudo systemctl restart smartdns"); err != nil {
+	if err := sshClient.RestartService("smartdns"); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("重启服务失败: %v", err)})
 		return
 	}
@@ -365,8 +424,10 @@ func (h *BackupHandler) RestoreNodeBackup(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "备份恢复成功",
 		"data": gin.H{
-			"backup_id": backup.ID,
-			"node_id":   backup.NodeID,
+			"backup_id":      backup.ID,
+			"node_id":        backup.NodeID,
+			"target_node_id": targetNode.ID,
+			"adjustments":    adjustments,
 		},
 		"success": true,
 	})
@@ -444,6 +505,75 @@ func (h *BackupHandler) DeleteNodeBackup(c *gin.Context) {
 	})
 }
 
+// ═══════════════════════════════════════════════════════════════
+// 备份保留策略
+// ═══════════════════════════════════════════════════════════════
+
+type BackupRetentionRequest struct {
+	KeepLast   int `json:"keep_last" binding:"min=0"`
+	KeepDaily  int `json:"keep_daily" binding:"min=0"`
+	KeepWeekly int `json:"keep_weekly" binding:"min=0"`
+}
+
+// GetNodeBackupRetention 获取节点备份保留策略
+// GET /api/nodes/:id/backups/retention
+func (h *BackupHandler) GetNodeBackupRetention(c *gin.Context) {
+	var node models.Node
+	if err := h.db.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "节点不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    services.GetNodeBackupRetention(node.ID),
+		"success": true,
+	})
+}
+
+// UpdateNodeBackupRetention 更新节点备份保留策略，并立即按新策略清理自动备份
+// PUT /api/nodes/:id/backups/retention
+func (h *BackupHandler) UpdateNodeBackupRetention(c *gin.Context) {
+	var node models.Node
+	if err := h.db.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "节点不存在"})
+		return
+	}
+
+	var req BackupRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous := services.GetNodeBackupRetention(node.ID)
+	retention := models.NodeBackupRetention{
+		NodeID:     node.ID,
+		KeepLast:   req.KeepLast,
+		KeepDaily:  req.KeepDaily,
+		KeepWeekly: req.KeepWeekly,
+	}
+	if err := h.db.Save(&retention).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存保留策略失败: %v", err)})
+		return
+	}
+	recordAudit(c, models.AuditEntityBackupRetention, node.ID, node.Name, models.AuditActionUpdate, previous, retention)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	deleted, err := h.backupService.ApplyRetention(ctx, &node)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("清理过期备份失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("保留策略已更新，清理了 %d 个自动备份", deleted),
+		"data":    retention,
+		"deleted": deleted,
+		"success": true,
+	})
+}
+
 // ═══════════════════════════════════════════════════════════════
 // 下载备份
 // ═══════════════════════════════════════════════════════════════
@@ -486,22 +616,74 @@ func (h *BackupHandler) DownloadBackup(c *gin.Context) {
 	}
 	defer storage.Close()
 
-	// 读取备份内容
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
+	// 读取备份内容，客户端断开时随请求取消
+	ctx := c.Request.Context()
 
-	content, err := storage.Load(ctx, backup.Path)
+	// 指定 compress 时边读边压缩输出，不支持 Range
+	if format := c.Query("compress"); format != "" {
+		body, _, err := services.OpenBackupRange(ctx, storage, backup.Path, 0, -1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("读取备份失败: %v", err)})
+			return
+		}
+		defer body.Close()
+
+		encoder, ext, contentType, err := services.NewCompressionWriter(c.Writer, format)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s%s", backup.Name, ext))
+		c.Header("Content-Type", contentType)
+		c.Status(http.StatusOK)
+		if _, err := io.Copy(encoder, body); err != nil {
+			log.Printf("压缩下载备份 %d 失败: %v", backup.ID, err)
+		}
+		encoder.Close()
+		return
+	}
+
+	offset, length, partial := services.ParseByteRange(c.GetHeader("Range"))
+	body, total, err := services.OpenBackupRange(ctx, storage, backup.Path, offset, length)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("读取备份失败: %v", err)})
 		return
 	}
 
-	// 设置响应头
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", backup.Name))
-	c.Header("Content-Type", "application/octet-stream")
-	c.Header("Content-Length", fmt.Sprintf("%d", len(content)))
+	// 对象总大小未知时（如 SFTP）无法生成 Content-Range，改为按完整内容响应
+	if partial && total < 0 {
+		body.Close()
+		partial = false
+		body, total, err = services.OpenBackupRange(ctx, storage, backup.Path, 0, -1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("读取备份失败: %v", err)})
+			return
+		}
+	}
+	defer body.Close()
+
+	headers := map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%s", backup.Name),
+		"Accept-Ranges":       "bytes",
+	}
+	status := http.StatusOK
+	contentLength := total
+	if partial {
+		if offset >= total {
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", total))
+			c.JSON(http.StatusRequestedRangeNotSatisfiable, gin.H{"error": "请求范围超出文件大小"})
+			return
+		}
+		end := total - 1
+		if length >= 0 && offset+length-1 < end {
+			end = offset + length - 1
+		}
+		status = http.StatusPartialContent
+		contentLength = end - offset + 1
+		headers["Content-Range"] = fmt.Sprintf("bytes %d-%d/%d", offset, end, total)
+	}
 
-	c.Data(http.StatusOK, "application/octet-stream", content)
+	c.DataFromReader(status, contentLength, "application/octet-stream", body, headers)
 }
 
 // ═══════════════════════════════════════════════════════════════
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+type BackupKeyHandler struct {
+	keyService *services.BackupKeyService
+}
+
+func NewBackupKeyHandler(keyService *services.BackupKeyService) *BackupKeyHandler {
+	return &BackupKeyHandler{
+		keyService: keyService,
+	}
+}
+
+// GetBackupKeys 获取加密密钥列表
+// @Summary 获取加密密钥列表
+// @Tags DatabaseBackup
+// @Produce json
+// @Success 200 {array} models.BackupEncryptionKey
+// @Router /api/database-backup/keys [get]
+func (h *BackupKeyHandler) GetBackupKeys(c *gin.Context) {
+	keys, err := h.keyService.ListKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "查询成功",
+		"data":    keys,
+		"success": true,
+	})
+}
+
+// CreateBackupKey 创建加密密钥
+// @Summary 创建加密密钥
+// @Tags DatabaseBackup
+// @Accept json
+// @Produce json
+// @Param key body models.BackupKeyRequest true "加密密钥"
+// @Success 200 {object} models.BackupEncryptionKey
+// @Router /api/database-backup/keys [post]
+func (h *BackupKeyHandler) CreateBackupKey(c *gin.Context) {
+	var request models.BackupKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	key, err := h.keyService.CreateKey(&request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "创建加密密钥失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "加密密钥创建成功",
+		"data":    key,
+		"success": true,
+	})
+}
+
+// DeleteBackupKey 删除加密密钥
+// @Summary 删除加密密钥
+// @Tags DatabaseBackup
+// @Produce json
+// @Param id path int true "密钥ID"
+// @Success 200
+// @Router /api/database-backup/keys/{id} [delete]
+func (h *BackupKeyHandler) DeleteBackupKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key ID"})
+		return
+	}
+
+	if err := h.keyService.DeleteKey(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "加密密钥删除成功",
+		"success": true})
+}
+
+// RotateBackupKey 轮换加密密钥
+// @Summary 轮换加密密钥并重新包装依赖备份的数据密钥
+// @Tags DatabaseBackup
+// @Accept json
+// @Produce json
+// @Param id path int true "密钥ID"
+// @Param request body models.BackupKeyRotateRequest false "轮换参数"
+// @Success 200 {object} models.BackupEncryptionKey
+// @Router /api/database-backup/keys/{id}/rotate [post]
+func (h *BackupKeyHandler) RotateBackupKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key ID"})
+		return
+	}
+
+	var request models.BackupKeyRotateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+			return
+		}
+	}
+
+	key, rewrapped, err := h.keyService.RotateKey(uint(id), &request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "轮换加密密钥失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "加密密钥轮换成功",
+		"data":      key,
+		"rewrapped": rewrapped,
+		"success":   true,
+	})
+}
+
+// GetBackupKeyUsage 获取依赖加密密钥的备份配置和备份
+// @Summary 获取加密密钥的依赖情况
+// @Tags DatabaseBackup
+// @Produce json
+// @Param id path int true "密钥ID"
+// @Success 200 {object} models.BackupKeyUsage
+// @Router /api/database-backup/keys/{id}/usage [get]
+func (h *BackupKeyHandler) GetBackupKeyUsage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key ID"})
+		return
+	}
+
+	usage, err := h.keyService.GetKeyUsage(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "查询成功",
+		"data":    usage,
+		"success": true,
+	})
+}
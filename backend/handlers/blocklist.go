@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var blocklistService *services.BlocklistService
+
+// InitBlocklistHandler 初始化屏蔽列表订阅处理器
+func InitBlocklistHandler(service *services.BlocklistService) {
+	blocklistService = service
+}
+
+// blocklistFormats 订阅支持的列表格式
+var blocklistFormats = map[string]bool{
+	services.ImportFormatAuto:    true,
+	services.ImportFormatHosts:   true,
+	services.ImportFormatDnsmasq: true,
+	services.ImportFormatAdGuard: true,
+	services.ImportFormatPlain:   true,
+}
+
+// GetBlocklistSubscriptions 获取屏蔽列表订阅
+func GetBlocklistSubscriptions(c *gin.Context) {
+	var subs []models.BlocklistSubscription
+	database.DB.Order("name").Find(&subs)
+
+	var domainSets []models.DomainSet
+	database.DB.Select("id", "name").Find(&domainSets)
+	names := make(map[uint]string, len(domainSets))
+	for _, ds := range domainSets {
+		names[ds.ID] = ds.Name
+	}
+	for i := range subs {
+		subs[i].DomainSetName = names[subs[i].DomainSetID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    subs,
+		"total":   len(subs),
+	})
+}
+
+// AddBlocklistSubscription 添加屏蔽列表订阅，创建后立即在后台下载一次
+func AddBlocklistSubscription(c *gin.Context) {
+	var request models.BlocklistSubscriptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	sub := models.BlocklistSubscription{Enabled: true}
+	if !applyBlocklistRequest(c, &sub, &request) {
+		return
+	}
+
+	var existing models.BlocklistSubscription
+	if err := database.DB.Where("name = ?", sub.Name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "订阅名称已存在",
+		})
+		return
+	}
+
+	if err := database.DB.Create(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建订阅失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityBlocklist, sub.ID, sub.Name, models.AuditActionCreate, nil, sub)
+
+	if sub.Enabled {
+		go blocklistService.Refresh(&sub, true)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "订阅创建成功，正在下载列表...",
+		"data":    sub,
+	})
+}
+
+// UpdateBlocklistSubscription 更新屏蔽列表订阅
+func UpdateBlocklistSubscription(c *gin.Context) {
+	sub, ok := findBlocklistSubscription(c)
+	if !ok {
+		return
+	}
+
+	var request models.BlocklistSubscriptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	previous := *sub
+	if !applyBlocklistRequest(c, sub, &request) {
+		return
+	}
+
+	// 地址、格式或目标域名集变化后需要重新下载并写入
+	changed := previous.URL != sub.URL || previous.Format != sub.Format || previous.DomainSetID != sub.DomainSetID
+	if changed {
+		sub.Checksum, sub.ETag, sub.LastModified = "", "", ""
+	}
+
+	if err := database.DB.Save(sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "更新订阅失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityBlocklist, sub.ID, sub.Name, models.AuditActionUpdate, previous, sub)
+
+	if changed && sub.Enabled {
+		go blocklistService.Refresh(sub, true)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "订阅更新成功",
+		"data":    sub,
+	})
+}
+
+// DeleteBlocklistSubscription 删除屏蔽列表订阅，目标域名集保留当前内容
+func DeleteBlocklistSubscription(c *gin.Context) {
+	sub, ok := findBlocklistSubscription(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "删除订阅失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityBlocklist, sub.ID, sub.Name, models.AuditActionDelete, sub, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "订阅删除成功，域名集保留当前内容",
+	})
+}
+
+// RefreshBlocklistSubscription 立即刷新订阅并返回结果
+func RefreshBlocklistSubscription(c *gin.Context) {
+	sub, ok := findBlocklistSubscription(c)
+	if !ok {
+		return
+	}
+
+	result := blocklistService.Refresh(sub, c.Query("force") == "true")
+	if result.Status == models.BlocklistStatusFailed {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success": false,
+			"message": "刷新失败: " + result.Error,
+			"data":    result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "刷新完成",
+		"data":    result,
+	})
+}
+
+func findBlocklistSubscription(c *gin.Context) (*models.BlocklistSubscription, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的订阅ID",
+		})
+		return nil, false
+	}
+
+	var sub models.BlocklistSubscription
+	if err := database.DB.First(&sub, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "订阅不存在",
+		})
+		return nil, false
+	}
+
+	return &sub, true
+}
+
+// applyBlocklistRequest 校验请求并写入订阅，校验失败时已返回响应
+func applyBlocklistRequest(c *gin.Context, sub *models.BlocklistSubscription, request *models.BlocklistSubscriptionRequest) bool {
+	fail := func(message string) bool {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": message,
+		})
+		return false
+	}
+
+	request.URL = strings.TrimSpace(request.URL)
+	if u, err := url.Parse(request.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fail("订阅地址必须是 http 或 https URL")
+	}
+
+	if request.Format == "" {
+		request.Format = services.ImportFormatAuto
+	}
+	if !blocklistFormats[request.Format] {
+		return fail("不支持的列表格式: " + request.Format)
+	}
+
+	if request.RefreshInterval == 0 {
+		request.RefreshInterval = 1440
+	}
+	if request.RefreshInterval < 10 {
+		return fail("刷新间隔不能小于10分钟")
+	}
+
+	var domainSet models.DomainSet
+	if err := database.DB.First(&domainSet, request.DomainSetID).Error; err != nil {
+		return fail("目标域名集不存在")
+	}
+
+	// 订阅会整体替换域名集内容，一个域名集只能由一个订阅管理
+	var count int64
+	database.DB.Model(&models.BlocklistSubscription{}).
+		Where("domain_set_id = ? AND id <> ?", request.DomainSetID, sub.ID).Count(&count)
+	if count > 0 {
+		return fail("该域名集已被其他订阅使用")
+	}
+
+	sub.Name = strings.TrimSpace(request.Name)
+	sub.URL = request.URL
+	sub.Format = request.Format
+	sub.DomainSetID = request.DomainSetID
+	sub.RefreshInterval = request.RefreshInterval
+	if request.Enabled != nil {
+		sub.Enabled = *request.Enabled
+	}
+	sub.DomainSetName = domainSet.Name
+	return true
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var chBackupService *services.CHBackupService
+
+// InitCHBackupHandler 初始化 ClickHouse 备份处理器
+func InitCHBackupHandler(service *services.CHBackupService) {
+	chBackupService = service
+}
+
+// RestoreCHBackup 从 ClickHouse 备份恢复表
+// POST /api/database-backup/clickhouse/restore
+func RestoreCHBackup(c *gin.Context) {
+	var request models.CHBackupRestoreRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	// 大表恢复耗时较长
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	steps, err := chBackupService.Restore(ctx, &request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复 ClickHouse 备份失败: " + err.Error(), "data": steps})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true, "message": "ClickHouse 备份恢复成功", "data": steps})
+}
+
+// RestoreCHBackupImpact 预估恢复 ClickHouse 备份：不指定后缀时原表会被重命名后替换
+func RestoreCHBackupImpact(c *gin.Context) (*models.DestructiveImpact, error) {
+	var request models.CHBackupRestoreRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		return nil, err
+	}
+
+	var history models.BackupHistory
+	if err := database.DB.First(&history, request.BackupHistoryID).Error; err != nil {
+		return nil, err
+	}
+
+	summary := fmt.Sprintf("将从备份 %s（%s）恢复表 %s", history.FileName, history.CreatedAt.Format("2006-01-02 15:04:05"), history.Tables)
+	if request.TableSuffix == "" {
+		summary += "，原表会被重命名保留，查询将切换到恢复后的数据"
+	} else {
+		summary += fmt.Sprintf("，恢复为带后缀 %s 的新表", request.TableSuffix)
+	}
+	return &models.DestructiveImpact{
+		Summary: summary,
+		Counts:  map[string]int64{"bytes": history.FileSize},
+	}, nil
+}
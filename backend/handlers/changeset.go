@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var changeSetService = services.NewChangeSetService()
+
+// CreateChangeSetRequest 创建变更集请求
+type CreateChangeSetRequest struct {
+	Description string              `json:"description"`
+	Changes     []models.ChangeItem `json:"changes" binding:"required"`
+}
+
+// CreateChangeSet 计划批量变更，返回各节点的配置差异，不会修改任何数据
+// POST /api/changes
+func CreateChangeSet(c *gin.Context) {
+	var req CreateChangeSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	changeSet, err := changeSetService.Plan(req.Description, req.Changes, auditActor(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "变更计划已生成",
+		"data":    changeSet,
+	})
+}
+
+// GetChangeSets 获取变更集列表
+// GET /api/changes?status=pending
+func GetChangeSets(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := database.DB.Model(&models.ChangeSet{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var changeSets []models.ChangeSet
+	query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&changeSets)
+	for i := range changeSets {
+		services.DecodeChangeSet(&changeSets[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      changeSets,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetChangeSet 获取变更集详情，包含各节点差异与执行进度
+func GetChangeSet(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	changeSet, err := changeSetService.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "变更集不存在",
+		})
+		return
+	}
+
+	done := changeSet.SucceededNodes + changeSet.FailedNodes
+	progress := 100
+	if changeSet.TotalNodes > 0 {
+		progress = done * 100 / changeSet.TotalNodes
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"data":     changeSet,
+		"progress": progress,
+	})
+}
+
+// ApplyChangeSet 提交变更并应用到各节点，节点应用在后台进行
+// POST /api/changes/:id/apply
+func ApplyChangeSet(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	if err := changeSetService.Apply(uint(id), auditActor(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "变更已提交，正在应用到节点",
+	})
+}
+
+// RetryChangeSet 重新应用失败的节点
+// POST /api/changes/:id/retry
+func RetryChangeSet(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	if err := changeSetService.Retry(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "正在重试失败的节点",
+	})
+}
+
+// DiscardChangeSet 放弃待应用的变更集
+// DELETE /api/changes/:id
+func DiscardChangeSet(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	if err := changeSetService.Discard(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "变更集已放弃",
+	})
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"smartdns-manager/services"
+)
+
+// sharedClientIPPrivacy 分享链接返回结果使用的客户端 IP 脱敏器
+//
+// 日志、统计、分析和导出接口都要求管理员权限，返回存储中的值；启用 LOG_PRIVACY_MODE 后
+// 只有无需登录的分享链接访问者看到脱敏后的客户端 IP
+var sharedClientIPPrivacy = services.GetClientIPPrivacy
+
+// clientIPSearchValue 将检索的客户端 IP 转换为存储中的值，hash 模式下管理员可按原始 IP 检索
+func clientIPSearchValue(value string) string {
+	if value == "" {
+		return value
+	}
+	return services.GetClientIPPrivacy().SearchValue(value)
+}
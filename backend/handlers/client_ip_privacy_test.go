@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// privacyLogMonitor 返回固定日志和统计的日志存储，客户端 IP 为原始值
+type privacyLogMonitor struct {
+	services.LogMonitorInterface
+}
+
+func (privacyLogMonitor) GetLogs(page, pageSize int, filters map[string]interface{}) ([]models.DNSLog, int64, error) {
+	return []models.DNSLog{{ClientIP: "192.168.1.23", ClientPTR: "laptop.lan", RawLog: "192.168.1.23 example.com A"}}, 1, nil
+}
+
+func (privacyLogMonitor) GetStats(nodeID uint, startTime, endTime time.Time) (*models.DNSLogStats, error) {
+	return &models.DNSLogStats{TopClients: []models.ClientStat{{ClientIP: "192.168.1.23"}}}, nil
+}
+
+// TestSharedLogsMaskClientIPs 分享链接的访问者未登录，启用脱敏后只能看到截断后的客户端 IP，管理员查看存储中的值
+func TestSharedLogsMaskClientIPs(t *testing.T) {
+	f := setupOrganizationTest(t)
+
+	previousService, previousPrivacy := logMonitorService, sharedClientIPPrivacy
+	logMonitorService = privacyLogMonitor{}
+	sharedClientIPPrivacy = func() *services.ClientIPPrivacy {
+		return services.NewClientIPPrivacy(services.PrivacyTruncate, "24", "56", "")
+	}
+	t.Cleanup(func() {
+		logMonitorService, sharedClientIPPrivacy = previousService, previousPrivacy
+	})
+
+	router := organizationTestRouter(f.orgAdmin)
+	router.GET("/api/dns-logs", GetDNSLogs)
+	router.GET("/api/share/:token", ViewSharedLogs)
+
+	tokens := map[string]string{}
+	for _, kind := range []string{models.ShareKindLogs, models.ShareKindStats} {
+		link := models.LogShareLink{
+			Kind:           kind,
+			Nonce:          "nonce-" + kind,
+			Filters:        fmt.Sprintf(`{"node_id":"%d"}`, f.ownNode.ID),
+			StartTime:      time.Now().Add(-time.Hour),
+			EndTime:        time.Now(),
+			ExpiresAt:      time.Now().Add(time.Hour),
+			OrganizationID: f.ownNode.OrganizationID,
+		}
+		database.DB.Create(&link)
+		tokens[kind] = signShareLink(&link)
+	}
+
+	code, body := doOrganizationRequest(t, router, http.MethodGet, "/api/share/"+tokens[models.ShareKindLogs])
+	if code != http.StatusOK {
+		t.Fatalf("查看分享的日志状态码 %d", code)
+	}
+	var logs struct {
+		Logs []models.DNSLog `json:"logs"`
+	}
+	json.Unmarshal(body["data"], &logs)
+	if len(logs.Logs) != 1 {
+		t.Fatalf("期望 1 条日志，实际 %d", len(logs.Logs))
+	}
+	if entry := logs.Logs[0]; entry.ClientIP != "192.168.1.0" || entry.ClientPTR != "" || entry.RawLog != "192.168.1.0 example.com A" {
+		t.Errorf("分享链接返回的日志未脱敏: %+v", entry)
+	}
+
+	code, body = doOrganizationRequest(t, router, http.MethodGet, "/api/share/"+tokens[models.ShareKindStats])
+	if code != http.StatusOK {
+		t.Fatalf("查看分享的统计状态码 %d", code)
+	}
+	var stats models.DNSLogStats
+	json.Unmarshal(body["data"], &stats)
+	if len(stats.TopClients) != 1 || stats.TopClients[0].ClientIP != "192.168.1.0" {
+		t.Errorf("分享链接返回的统计未脱敏: %+v", stats.TopClients)
+	}
+
+	code, body = doOrganizationRequest(t, router, http.MethodGet, fmt.Sprintf("/api/dns-logs?node_id=%d", f.ownNode.ID))
+	if code != http.StatusOK {
+		t.Fatalf("管理员查看日志状态码 %d", code)
+	}
+	json.Unmarshal(body["data"], &logs)
+	if len(logs.Logs) != 1 || logs.Logs[0].ClientIP != "192.168.1.23" {
+		t.Errorf("管理员应看到存储中的客户端 IP: %+v", logs.Logs)
+	}
+}
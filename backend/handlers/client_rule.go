@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var clientRuleService = services.NewClientRuleService()
+
+// clientRuleRequest 客户端规则请求
+type clientRuleRequest struct {
+	Client      string `json:"client" binding:"required"`
+	Group       string `json:"group"`
+	Blocked     bool   `json:"blocked"`
+	Options     string `json:"options"`
+	Priority    int    `json:"priority"`
+	Description string `json:"description"`
+	NodeIDs     []uint `json:"node_ids"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// GetClientRules 获取客户端规则列表
+func GetClientRules(c *gin.Context) {
+	var rules []models.ClientRule
+
+	query := database.DB
+
+	if client := c.Query("client"); client != "" {
+		query = query.Where("client LIKE ?", "%"+client+"%")
+	}
+	if group := c.Query("group"); group != "" {
+		query = query.Where("`group` = ?", group)
+	}
+
+	query.Order("priority desc, created_at desc").Find(&rules)
+
+	// 附带生成的指令便于前端预览
+	data := make([]gin.H, 0, len(rules))
+	for i := range rules {
+		data = append(data, gin.H{
+			"rule":      rules[i],
+			"directive": clientRuleService.GenerateLine(&rules[i]),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+		"total":   len(rules),
+	})
+}
+
+// AddClientRule 添加客户端规则
+func AddClientRule(c *gin.Context) {
+	var request clientRuleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rule := models.ClientRule{Enabled: true}
+	if msg := applyClientRuleRequest(&rule, &request); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	var existing models.ClientRule
+	if err := database.DB.Where("client = ?", rule.Client).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "该客户端已存在规则",
+		})
+		return
+	}
+
+	if err := database.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建客户端规则失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityClientRule, rule.ID, rule.Client, models.AuditActionCreate, nil, rule)
+
+	go clientRuleService.SyncClientRuleToNodes(context.WithoutCancel(c.Request.Context()), &rule)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "客户端规则创建成功，正在同步到节点...",
+		"data":    rule,
+	})
+}
+
+// UpdateClientRule 更新客户端规则
+func UpdateClientRule(c *gin.Context) {
+	rule, ok := findClientRule(c)
+	if !ok {
+		return
+	}
+
+	var request clientRuleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	previous := *rule
+	if msg := applyClientRuleRequest(rule, &request); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	if rule.Client != previous.Client {
+		var existing models.ClientRule
+		if err := database.DB.Where("client = ? AND id <> ?", rule.Client, rule.ID).First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "该客户端已存在规则",
+			})
+			return
+		}
+	}
+
+	if err := database.DB.Save(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "更新客户端规则失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityClientRule, rule.ID, rule.Client, models.AuditActionUpdate, previous, rule)
+
+	go clientRuleService.ReplaceClientRuleOnNodes(context.WithoutCancel(c.Request.Context()), &previous, rule)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "客户端规则更新成功，正在同步到节点...",
+		"data":    rule,
+	})
+}
+
+// DeleteClientRule 删除客户端规则
+func DeleteClientRule(c *gin.Context) {
+	rule, ok := findClientRule(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "删除客户端规则失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityClientRule, rule.ID, rule.Client, models.AuditActionDelete, rule, nil)
+
+	go clientRuleService.DeleteClientRuleFromNodes(context.WithoutCancel(c.Request.Context()), rule)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "客户端规则删除成功",
+	})
+}
+
+// findClientRule 根据路径参数查找客户端规则
+func findClientRule(c *gin.Context) (*models.ClientRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的规则ID",
+		})
+		return nil, false
+	}
+
+	var rule models.ClientRule
+	if err := database.DB.First(&rule, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "客户端规则不存在",
+		})
+		return nil, false
+	}
+
+	return &rule, true
+}
+
+// applyClientRuleRequest 校验请求并写入规则，返回错误提示
+func applyClientRuleRequest(rule *models.ClientRule, request *clientRuleRequest) string {
+	client := strings.TrimSpace(request.Client)
+	if !isValidClient(client) {
+		return "客户端格式无效，应为 IP、CIDR 或 MAC 地址"
+	}
+
+	group := strings.TrimSpace(request.Group)
+	if !request.Blocked && group == "" {
+		return "请指定分组或选择阻断"
+	}
+	if group == models.ClientRuleBlockedGroup {
+		return "该分组名称为系统保留"
+	}
+	if group != "" {
+		if strings.IndexFunc(group, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+			return "分组名称不能包含空白或控制字符"
+		}
+		if !clientRuleGroupExists(group) {
+			return "分组不存在: " + group
+		}
+	}
+
+	// 选项会拼接进 client-rules 指令行，不允许换行注入额外指令
+	options := strings.TrimSpace(request.Options)
+	if strings.ContainsAny(options, "\r\n") {
+		return "规则选项不能包含换行"
+	}
+
+	rule.Client = client
+	rule.Group = group
+	rule.Blocked = request.Blocked
+	rule.Options = options
+	rule.Priority = request.Priority
+	rule.Description = request.Description
+
+	rule.NodeIDs = "[]"
+	if len(request.NodeIDs) > 0 {
+		nodeIDsBytes, _ := json.Marshal(request.NodeIDs)
+		rule.NodeIDs = string(nodeIDsBytes)
+	}
+
+	if request.Enabled != nil {
+		rule.Enabled = *request.Enabled
+	}
+
+	return ""
+}
+
+// clientRuleGroupExists 分组需已在分组管理或分组配置块中定义
+func clientRuleGroupExists(name string) bool {
+	var count int64
+	database.DB.Model(&models.DNSGroup{}).Where("name = ?", name).Count(&count)
+	if count > 0 {
+		return true
+	}
+	database.DB.Model(&models.GroupBlock{}).Where("name = ?", name).Count(&count)
+	return count > 0
+}
+
+// isValidClient 判断是否为合法的 IP、CIDR 或 MAC 地址
+func isValidClient(client string) bool {
+	if net.ParseIP(client) != nil {
+		return true
+	}
+	if _, _, err := net.ParseCIDR(client); err == nil {
+		return true
+	}
+	if _, err := net.ParseMAC(client); err == nil {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var complianceService *services.ComplianceService
+
+// InitComplianceHandler 初始化配置合规处理器
+func InitComplianceHandler(service *services.ComplianceService) {
+	complianceService = service
+}
+
+// GetCompliancePolicies 获取合规策略及各策略未解决的违规数
+func GetCompliancePolicies(c *gin.Context) {
+	var policies []models.CompliancePolicy
+	database.DB.Order("name").Find(&policies)
+
+	var counts []struct {
+		PolicyID uint
+		Count    int64
+	}
+	database.DB.Model(&models.ComplianceViolation{}).
+		Select("policy_id, COUNT(*) AS count").
+		Where("status = ?", models.ComplianceViolationOpen).
+		Group("policy_id").Scan(&counts)
+	openByPolicy := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		openByPolicy[count.PolicyID] = count.Count
+	}
+	for i := range policies {
+		policies[i].OpenViolations = openByPolicy[policies[i].ID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policies,
+		"total":   len(policies),
+	})
+}
+
+// AddCompliancePolicy 添加合规策略
+func AddCompliancePolicy(c *gin.Context) {
+	var request models.CompliancePolicyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	policy := models.CompliancePolicy{Enabled: true}
+	if !applyCompliancePolicyRequest(c, &policy, &request) {
+		return
+	}
+
+	var count int64
+	database.DB.Model(&models.CompliancePolicy{}).Where("name = ?", policy.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "策略名称已存在",
+		})
+		return
+	}
+
+	if err := database.DB.Create(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建合规策略失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityCompliance, policy.ID, policy.Name, models.AuditActionCreate, nil, policy)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "合规策略创建成功",
+		"data":    policy,
+	})
+}
+
+// UpdateCompliancePolicy 更新合规策略，下次检查时按新定义求值
+func UpdateCompliancePolicy(c *gin.Context) {
+	policy, ok := findCompliancePolicy(c)
+	if !ok {
+		return
+	}
+
+	var request models.CompliancePolicyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	previous := *policy
+	if !applyCompliancePolicyRequest(c, policy, &request) {
+		return
+	}
+
+	var count int64
+	database.DB.Model(&models.CompliancePolicy{}).
+		Where("name = ? AND id <> ?", policy.Name, policy.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "策略名称已存在",
+		})
+		return
+	}
+
+	if err := database.DB.Save(policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "更新合规策略失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityCompliance, policy.ID, policy.Name, models.AuditActionUpdate, previous, policy)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "合规策略更新成功",
+		"data":    policy,
+	})
+}
+
+// DeleteCompliancePolicy 删除合规策略及其违规记录
+func DeleteCompliancePolicy(c *gin.Context) {
+	policy, ok := findCompliancePolicy(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "删除合规策略失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	database.DB.Where("policy_id = ?", policy.ID).Delete(&models.ComplianceViolation{})
+
+	recordAudit(c, models.AuditEntityCompliance, policy.ID, policy.Name, models.AuditActionDelete, policy, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "合规策略删除成功",
+	})
+}
+
+// GetComplianceSummary 合规概览：各节点最近一次检查结果和未解决的违规统计
+func GetComplianceSummary(c *gin.Context) {
+	summary := models.ComplianceSummary{
+		BySeverity: map[string]int64{},
+		Nodes:      []models.ComplianceNodeSummary{},
+		ByPolicy:   []models.CompliancePolicySummary{},
+	}
+	database.DB.Model(&models.CompliancePolicy{}).Where("enabled = ?", true).Count(&summary.Policies)
+
+	var bySeverity []struct {
+		NodeID   uint
+		Severity string
+		Count    int64
+	}
+	database.DB.Model(&models.ComplianceViolation{}).
+		Select("node_id, severity, COUNT(*) AS count").
+		Where("status = ?", models.ComplianceViolationOpen).
+		Group("node_id, severity").Scan(&bySeverity)
+	nodeCounts := make(map[uint]map[string]int64)
+	for _, row := range bySeverity {
+		if nodeCounts[row.NodeID] == nil {
+			nodeCounts[row.NodeID] = map[string]int64{}
+		}
+		nodeCounts[row.NodeID][row.Severity] += row.Count
+		summary.BySeverity[row.Severity] += row.Count
+		summary.OpenViolations += row.Count
+	}
+
+	// 只展示仍存在的节点
+	var checks []models.ComplianceNodeCheck
+	database.DB.Where("node_id IN (?)", database.DB.Model(&models.Node{}).Select("id")).
+		Order("node_name").Find(&checks)
+	for _, check := range checks {
+		counts := nodeCounts[check.NodeID]
+		summary.Nodes = append(summary.Nodes, models.ComplianceNodeSummary{
+			ComplianceNodeCheck: check,
+			Critical:            counts[models.ComplianceSeverityCritical],
+			Warning:             counts[models.ComplianceSeverityWarning],
+			Info:                counts[models.ComplianceSeverityInfo],
+		})
+		summary.CheckedNodes++
+		switch {
+		case check.Error != "":
+			summary.FailedNodes++
+		case check.Violations == 0:
+			summary.CompliantNodes++
+		}
+	}
+
+	database.DB.Model(&models.ComplianceViolation{}).
+		Select("policy_id, policy_name, severity, COUNT(DISTINCT node_id) AS violating_nodes").
+		Where("status = ?", models.ComplianceViolationOpen).
+		Group("policy_id, policy_name, severity").
+		Order("violating_nodes DESC").
+		Scan(&summary.ByPolicy)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    summary,
+	})
+}
+
+// GetComplianceViolations 获取违规记录
+// GET /api/compliance/violations?node_id=&policy_id=&status=open
+func GetComplianceViolations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	query := database.DB.Model(&models.ComplianceViolation{})
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	if policyID := c.Query("policy_id"); policyID != "" {
+		query = query.Where("policy_id = ?", policyID)
+	}
+	if status := c.DefaultQuery("status", models.ComplianceViolationOpen); status != "all" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var violations []models.ComplianceViolation
+	query.Order("last_seen_at desc, id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&violations)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      violations,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// CheckNodeCompliance 立即按合规策略检查节点，开启自动修复的策略同样会修复
+// POST /api/nodes/:id/compliance-check
+func CheckNodeCompliance(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	check, err := complianceService.CheckNode(&node)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "合规检查失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	message := "节点配置符合所有策略"
+	if check.Error != "" {
+		message = "合规检查失败: " + check.Error
+	} else if check.Violations > 0 {
+		message = "节点有 " + strconv.Itoa(check.Violations) + " 条策略未通过"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    check,
+	})
+}
+
+func findCompliancePolicy(c *gin.Context) (*models.CompliancePolicy, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的策略ID",
+		})
+		return nil, false
+	}
+
+	var policy models.CompliancePolicy
+	if err := database.DB.First(&policy, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "合规策略不存在",
+		})
+		return nil, false
+	}
+
+	return &policy, true
+}
+
+// applyCompliancePolicyRequest 校验请求并写入策略，校验失败时已返回响应
+func applyCompliancePolicyRequest(c *gin.Context, policy *models.CompliancePolicy, request *models.CompliancePolicyRequest) bool {
+	nodeIDs := "[]"
+	if len(request.NodeIDs) > 0 {
+		data, _ := json.Marshal(request.NodeIDs)
+		nodeIDs = string(data)
+	}
+	if request.Severity == "" {
+		request.Severity = models.ComplianceSeverityWarning
+	}
+
+	policy.Name = strings.TrimSpace(request.Name)
+	policy.Description = request.Description
+	policy.Directive = strings.TrimSpace(request.Directive)
+	policy.Operator = request.Operator
+	policy.Value = strings.TrimSpace(request.Value)
+	policy.Severity = request.Severity
+	policy.NodeIDs = nodeIDs
+	policy.Tag = strings.TrimSpace(request.Tag)
+	policy.AutoRemediate = request.AutoRemediate
+	policy.FixValue = strings.TrimSpace(request.FixValue)
+	if request.Enabled != nil {
+		policy.Enabled = *request.Enabled
+	}
+
+	if err := services.ValidatePolicy(policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return false
+	}
+	return true
+}
@@ -78,6 +78,90 @@ func GetNodeConfig(c *gin.Context) {
 	})
 }
 
+// GetNodeStructuredConfig 以版本化的 JSON 格式返回节点当前配置和完整同步后应有的配置
+// GET /api/nodes/:id/config/structured?source=actual|desired，不指定时两者都返回
+func GetNodeStructuredConfig(c *gin.Context) {
+	source := c.Query("source")
+	if source != "" && source != models.StructuredConfigSourceActual && source != models.StructuredConfigSourceDesired {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "source 只能是 actual 或 desired",
+		})
+		return
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	client, err := services.NewSSHClient(&node)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "连接节点失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer client.Close()
+
+	content, err := client.ReadFile(node.ConfigPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "读取配置文件失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	result := models.NodeStructuredConfig{
+		SchemaVersion: models.StructuredConfigSchemaVersion,
+		NodeID:        node.ID,
+		NodeName:      node.Name,
+		ConfigPath:    node.ConfigPath,
+		Checksum:      fmt.Sprintf("%x", md5.Sum([]byte(content))),
+	}
+
+	parser := services.NewConfigParser()
+	if source != models.StructuredConfigSourceDesired {
+		actual, err := parser.Parse(content)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "解析配置失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		result.Actual = services.ToStructuredConfig(actual, models.StructuredConfigSourceActual)
+	}
+	if source != models.StructuredConfigSourceActual {
+		// 期望配置在节点现有配置基础上合并，单独解析一份避免修改 actual
+		base, err := parser.Parse(content)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "解析配置失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		desired := services.NewConfigSyncService().BuildExpectedConfig(base, node.ID)
+		result.Desired = services.ToStructuredConfig(desired, models.StructuredConfigSourceDesired)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
 // SaveNodeConfig 保存节点配置
 func SaveNodeConfig(c *gin.Context) {
 	id := c.Param("id")
@@ -184,6 +268,16 @@ func RestartNodeService(c *gin.Context) {
 		return
 	}
 
+	if deferred, next := services.NewMaintenanceService().DeferNodeAction(&node, models.DeferredActionRestart,
+		"手动重启", c.Query("override") == "true"); deferred {
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"deferred": true,
+			"message":  fmt.Sprintf("节点不在维护窗口内，重启已推迟到 %s 执行", services.FormatNextWindow(next)),
+		})
+		return
+	}
+
 	client, err := services.NewSSHClient(&node)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -210,6 +304,59 @@ func RestartNodeService(c *gin.Context) {
 	})
 }
 
+// ReloadNodeService 按节点的重载方式使配置生效，重载不可用时回退为重启
+func ReloadNodeService(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	if deferred, next := services.NewMaintenanceService().DeferNodeAction(&node, models.DeferredActionReload,
+		"手动重载", c.Query("override") == "true"); deferred {
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"deferred": true,
+			"message":  fmt.Sprintf("节点不在维护窗口内，重载已推迟到 %s 执行", services.FormatNextWindow(next)),
+		})
+		return
+	}
+
+	client, err := services.NewSSHClient(&node)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "连接节点失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer client.Close()
+
+	method, err := client.ReloadService("smartdns", node.ReloadMode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "重载服务失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	message := "服务重载成功"
+	if method == models.ReloadModeRestart {
+		message = "服务已重启"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"method":  method,
+	})
+}
+
 // GetNodeStatus 获取节点状态
 func GetNodeStatus(c *gin.Context) {
 	id := c.Param("id")
@@ -334,6 +481,9 @@ func BatchUpdateConfig(c *gin.Context) {
 		})
 		return
 	}
+	if !checkOrganizationNodes(c, request.NodeIDs) {
+		return
+	}
 
 	results := make(map[uint]map[string]interface{})
 	parser := services.NewConfigParser()
@@ -391,7 +541,8 @@ func BatchUpdateConfig(c *gin.Context) {
 // BatchRestart 批量重启服务
 func BatchRestart(c *gin.Context) {
 	var request struct {
-		NodeIDs []uint `json:"node_ids" binding:"required"`
+		NodeIDs  []uint `json:"node_ids" binding:"required"`
+		Override bool   `json:"override"` // 忽略维护窗口立即重启
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -402,7 +553,11 @@ func BatchRestart(c *gin.Context) {
 		})
 		return
 	}
+	if !checkOrganizationNodes(c, request.NodeIDs) {
+		return
+	}
 
+	maintenance := services.NewMaintenanceService()
 	results := make(map[uint]map[string]interface{})
 
 	for _, nodeID := range request.NodeIDs {
@@ -417,6 +572,15 @@ func BatchRestart(c *gin.Context) {
 			continue
 		}
 
+		if deferred, next := maintenance.DeferNodeAction(&node, models.DeferredActionRestart,
+			"批量重启", request.Override); deferred {
+			result["success"] = true
+			result["deferred"] = true
+			result["next_window"] = next
+			results[nodeID] = result
+			continue
+		}
+
 		client, err := services.NewSSHClient(&node)
 		if err != nil {
 			result["error"] = "连接失败: " + err.Error()
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var configSnapshotService *services.ConfigSnapshotService
+
+// InitConfigSnapshotHandler 初始化节点配置快照处理器
+func InitConfigSnapshotHandler(service *services.ConfigSnapshotService) {
+	configSnapshotService = service
+}
+
+// GetNodeConfigSnapshots 获取节点配置快照时间线（不含配置内容）
+// GET /api/nodes/:id/config-snapshots?out_of_band=true
+func GetNodeConfigSnapshots(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	query := database.DB.Model(&models.NodeConfigSnapshot{}).Where("node_id = ?", c.Param("id"))
+	if outOfBand := c.Query("out_of_band"); outOfBand != "" {
+		query = query.Where("out_of_band = ?", outOfBand == "true")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var snapshots []models.NodeConfigSnapshot
+	query.Omit("content").Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&snapshots)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      snapshots,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetConfigSnapshot 获取快照内容及与上一快照的差异
+func GetConfigSnapshot(c *gin.Context) {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	diff, err := configSnapshotService.GetDiff(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "配置快照不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    diff,
+	})
+}
+
+// CaptureNodeConfigSnapshot 立即采集节点配置快照
+// POST /api/nodes/:id/config-snapshots
+func CaptureNodeConfigSnapshot(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	snapshot, created, err := configSnapshotService.CaptureNode(&node)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "采集配置快照失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	message := "配置未变化"
+	if created {
+		message = "已保存新的配置快照"
+	}
+	snapshot.Content = ""
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    snapshot,
+		"created": created,
+	})
+}
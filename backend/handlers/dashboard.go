@@ -13,62 +13,65 @@ import (
 	"smartdns-manager/services"
 )
 
-// GetDashboardStats 获取仪表板统计信息
+// GetDashboardStats 获取仪表板统计信息，组织内只统计本组织的节点和规则
 func GetDashboardStats(c *gin.Context) {
 	stats := make(map[string]interface{})
 
 	// 节点统计
 	var nodeCount int64
-	database.DB.Model(&models.Node{}).Count(&nodeCount)
+	scopeOrganization(c, database.DB.Model(&models.Node{})).Count(&nodeCount)
 	stats["total_nodes"] = nodeCount
 
 	var onlineNodes int64
-	database.DB.Model(&models.Node{}).Where("status = ?", "online").Count(&onlineNodes)
+	scopeOrganization(c, database.DB.Model(&models.Node{})).Where("status = ?", "online").Count(&onlineNodes)
 	stats["online_nodes"] = onlineNodes
 
 	var offlineNodes int64
-	database.DB.Model(&models.Node{}).Where("status = ?", "offline").Count(&offlineNodes)
+	scopeOrganization(c, database.DB.Model(&models.Node{})).Where("status = ?", "offline").Count(&offlineNodes)
 	stats["offline_nodes"] = offlineNodes
 
-	// DNS服务器统计
-	var serverCount int64
-	database.DB.Model(&models.DNSServer{}).Count(&serverCount)
-	stats["total_servers"] = serverCount
-
-	// 按类型统计
-	var serversByType []struct {
-		Type  string
-		Count int64
+	// 上游服务器和域名集是全局配置，只对超级管理员统计
+	if requestOrganizationID(c) == 0 {
+		// DNS服务器统计
+		var serverCount int64
+		database.DB.Model(&models.DNSServer{}).Count(&serverCount)
+		stats["total_servers"] = serverCount
+
+		// 按类型统计
+		var serversByType []struct {
+			Type  string
+			Count int64
+		}
+		database.DB.Model(&models.DNSServer{}).
+			Select("type, count(*) as count").
+			Group("type").
+			Scan(&serversByType)
+		stats["servers_by_type"] = serversByType
+
+		// 域名集统计
+		var domainSetCount int64
+		database.DB.Model(&models.DomainSet{}).Count(&domainSetCount)
+		stats["total_domain_sets"] = domainSetCount
 	}
-	database.DB.Model(&models.DNSServer{}).
-		Select("type, count(*) as count").
-		Group("type").
-		Scan(&serversByType)
-	stats["servers_by_type"] = serversByType
 
 	// 地址映射统计
 	var addressCount int64
-	database.DB.Model(&models.AddressMap{}).Count(&addressCount)
+	scopeOrganization(c, database.DB.Model(&models.AddressMap{})).Count(&addressCount)
 	stats["total_addresses"] = addressCount
 
-	// 域名集统计
-	var domainSetCount int64
-	database.DB.Model(&models.DomainSet{}).Count(&domainSetCount)
-	stats["total_domain_sets"] = domainSetCount
-
 	// 域名规则统计
 	var domainRuleCount int64
-	database.DB.Model(&models.DomainRule{}).Count(&domainRuleCount)
+	scopeOrganization(c, database.DB.Model(&models.DomainRule{})).Count(&domainRuleCount)
 	stats["total_domain_rules"] = domainRuleCount
 
 	// 最近添加的地址映射
 	var recentAddresses []models.AddressMap
-	database.DB.Order("created_at desc").Limit(10).Find(&recentAddresses)
+	scopeOrganization(c, database.DB).Order("created_at desc").Limit(10).Find(&recentAddresses)
 	stats["recent_addresses"] = recentAddresses
 
 	// 最近添加的节点
 	var recentNodes []models.Node
-	database.DB.Order("created_at desc").Limit(5).Find(&recentNodes)
+	scopeOrganization(c, database.DB).Order("created_at desc").Limit(5).Find(&recentNodes)
 	stats["recent_nodes"] = recentNodes
 
 	// 系统信息
@@ -86,7 +89,7 @@ func GetDashboardStats(c *gin.Context) {
 // GetNodesHealth 获取所有节点健康状态
 func GetNodesHealth(c *gin.Context) {
 	var nodes []models.Node
-	if err := database.DB.Find(&nodes).Error; err != nil {
+	if err := scopeOrganization(c, database.DB).Find(&nodes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "获取节点列表失败",
@@ -179,10 +182,10 @@ func GetNodesHealth(c *gin.Context) {
 	})
 }
 
-// GetNodeMetrics 获取节点性能指标
+// GetNodeMetrics 获取节点资源指标的历史曲线
+// GET /api/nodes/:id/metrics?hours=24 或 ?start_time=...&end_time=...，可选 step（秒）
 func GetNodeMetrics(c *gin.Context) {
-	id := c.Param("id")
-	nodeID, err := strconv.ParseUint(id, 10, 32)
+	nodeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -192,7 +195,7 @@ func GetNodeMetrics(c *gin.Context) {
 	}
 
 	var node models.Node
-	if err := database.DB.First(&node, nodeID).Error; err != nil {
+	if err := database.DB.Select("id").First(&node, nodeID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"message": "节点不存在",
@@ -200,38 +203,46 @@ func GetNodeMetrics(c *gin.Context) {
 		return
 	}
 
-	client, err := services.NewSSHClient(&node)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "连接节点失败",
-			"error":   err.Error(),
-		})
-		return
-	}
-	defer client.Close()
-
-	// 收集多个时间点的数据
-	metrics := make([]models.NodeStatus, 0)
-	for i := 0; i < 5; i++ {
-		status, err := client.GetSystemInfo()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+	// 指定 start_time 时按起止时间查询，否则查询最近 hours 小时
+	end := time.Now()
+	start := parseProbeSince(c)
+	if value := c.Query("start_time"); value != "" {
+		t, ok := parseNotificationTime(value)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"message": "获取系统信息失败",
-				"error":   err.Error(),
+				"message": "无效的开始时间",
 			})
 			return
 		}
-		metrics = append(metrics, *status)
-		if i < 4 {
-			time.Sleep(2 * time.Second)
+		start = t
+	}
+	if value := c.Query("end_time"); value != "" {
+		t, ok := parseNotificationTime(value)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的结束时间",
+			})
+			return
 		}
+		end = t
+	}
+
+	step, _ := strconv.Atoi(c.DefaultQuery("step", "0"))
+
+	series, err := services.QueryNodeMetrics(uint(nodeID), start, end, step)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    metrics,
+		"data":    series,
 	})
 }
 
@@ -312,3 +323,69 @@ func GetSystemOverview(c *gin.Context) {
 		"data":    overview,
 	})
 }
+
+// 节点健康评分服务
+var healthScoreService *services.HealthScoreService
+
+// InitHealthScoreHandler 初始化健康评分处理器
+func InitHealthScoreHandler(service *services.HealthScoreService) {
+	healthScoreService = service
+}
+
+// GetNodesHealthScore 获取节点综合健康评分
+// GET /api/dashboard/health-scores?node_id=&check_resources=true
+func GetNodesHealthScore(c *gin.Context) {
+	if healthScoreService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "健康评分服务未初始化",
+		})
+		return
+	}
+
+	var nodeIDs []uint
+	if nodeIDStr := c.Query("node_id"); nodeIDStr != "" {
+		id, err := strconv.ParseUint(nodeIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的节点ID",
+			})
+			return
+		}
+		nodeIDs = append(nodeIDs, uint(id))
+	}
+	if len(nodeIDs) > 0 && !checkOrganizationNodes(c, nodeIDs) {
+		return
+	}
+	if orgNodeIDs, scoped := organizationNodeIDs(c); scoped && len(nodeIDs) == 0 {
+		// 追加不存在的节点 0，组织内没有节点时不会退化为计算全部节点
+		nodeIDs = append(orgNodeIDs, 0)
+	}
+	checkResources := c.Query("check_resources") == "true"
+
+	scores, err := healthScoreService.CalculateScores(c.Request.Context(), nodeIDs, checkResources, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "计算健康评分失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	summary := map[string]int{
+		models.HealthLevelHealthy:  0,
+		models.HealthLevelWarning:  0,
+		models.HealthLevelCritical: 0,
+	}
+	for _, s := range scores {
+		summary[s.Level]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    scores,
+		"summary": summary,
+	})
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -47,12 +50,32 @@ func (h *DatabaseBackupHandler) CreateBackupConfig(c *gin.Context) {
 		}
 	}
 
-	// 验证本地路径或S3至少启用一个
-	if !request.S3Enabled && request.LocalPath == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "必须启用S3存储或配置本地存储路径"})
+	// 验证其他远程存储配置
+	storageBackend, err := backupStorageBackendJSON(&request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 验证本地路径或远程存储至少启用一个
+	if !request.S3Enabled && request.StorageType == "" && request.LocalPath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "必须启用远程存储或配置本地存储路径"})
 		return
 	}
 
+	// 验证加密密钥
+	if request.EncryptionEnabled {
+		if request.EncryptionKeyID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "启用加密时必须选择加密密钥"})
+			return
+		}
+		var key models.BackupEncryptionKey
+		if err := h.db.First(&key, *request.EncryptionKeyID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "加密密钥不存在"})
+			return
+		}
+	}
+
 	// 转换通知渠道
 	var notificationChannels string
 	if len(request.NotificationChannels) > 0 {
@@ -73,11 +96,13 @@ func (h *DatabaseBackupHandler) CreateBackupConfig(c *gin.Context) {
 		S3Bucket:             request.S3Bucket,
 		S3Endpoint:           request.S3Endpoint,
 		S3Prefix:             request.S3Prefix,
+		StorageType:          request.StorageType,
+		StorageBackend:       storageBackend,
 		LocalPath:            request.LocalPath,
 		CompressionEnabled:   request.CompressionEnabled,
 		CompressionLevel:     request.CompressionLevel,
 		EncryptionEnabled:    request.EncryptionEnabled,
-		EncryptionKey:        request.EncryptionKey,
+		EncryptionKeyID:      request.EncryptionKeyID,
 		NotifyOnSuccess:      request.NotifyOnSuccess,
 		NotifyOnFailure:      request.NotifyOnFailure,
 		NotificationChannels: notificationChannels,
@@ -139,6 +164,26 @@ func (h *DatabaseBackupHandler) UpdateBackupConfig(c *gin.Context) {
 		}
 	}
 
+	// 验证其他远程存储配置
+	storageBackend, err := backupStorageBackendJSON(&request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 验证加密密钥
+	if request.EncryptionEnabled {
+		if request.EncryptionKeyID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "启用加密时必须选择加密密钥"})
+			return
+		}
+		var key models.BackupEncryptionKey
+		if err := h.db.First(&key, *request.EncryptionKeyID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "加密密钥不存在"})
+			return
+		}
+	}
+
 	// 转换通知渠道
 	var notificationChannels string
 	if len(request.NotificationChannels) > 0 {
@@ -159,11 +204,13 @@ func (h *DatabaseBackupHandler) UpdateBackupConfig(c *gin.Context) {
 	config.S3Bucket = request.S3Bucket
 	config.S3Endpoint = request.S3Endpoint
 	config.S3Prefix = request.S3Prefix
+	config.StorageType = request.StorageType
+	config.StorageBackend = storageBackend
 	config.LocalPath = request.LocalPath
 	config.CompressionEnabled = request.CompressionEnabled
 	config.CompressionLevel = request.CompressionLevel
 	config.EncryptionEnabled = request.EncryptionEnabled
-	config.EncryptionKey = request.EncryptionKey
+	config.EncryptionKeyID = request.EncryptionKeyID
 	config.NotifyOnSuccess = request.NotifyOnSuccess
 	config.NotifyOnFailure = request.NotifyOnFailure
 	config.NotificationChannels = notificationChannels
@@ -291,13 +338,14 @@ func (h *DatabaseBackupHandler) ManualBackup(c *gin.Context) {
 		return
 	}
 
-	if err := h.backupService.ManualBackup(uint(id)); err != nil {
+	job, err := h.backupService.ManualBackup(c.Request.Context(), uint(id))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "触发备份失败: " + err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true, "message": "备份任务已启动"})
+		"success": true, "message": "备份任务已提交", "data": job})
 }
 
 // GetBackupHistory 获取备份历史
@@ -305,6 +353,7 @@ func (h *DatabaseBackupHandler) ManualBackup(c *gin.Context) {
 // @Tags DatabaseBackup
 // @Produce json
 // @Param config_id query int false "配置ID"
+// @Param backup_type query string false "备份类型：database, clickhouse"
 // @Param page query int false "页码" default(1)
 // @Param page_size query int false "每页数量" default(20)
 // @Success 200
@@ -323,6 +372,9 @@ func (h *DatabaseBackupHandler) GetBackupHistory(c *gin.Context) {
 	if configID != "" {
 		query = query.Where("config_id = ?", configID)
 	}
+	if backupType := c.Query("backup_type"); backupType != "" {
+		query = query.Where("backup_type = ?", backupType)
+	}
 
 	if err := query.Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
@@ -352,7 +404,7 @@ func (h *DatabaseBackupHandler) GetBackupHistory(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body models.BackupRestoreRequest true "恢复请求"
-// @Success 200
+// @Success 200 {object} models.BackupRestoreReport
 // @Router /api/database-backup/restore [post]
 func (h *DatabaseBackupHandler) RestoreBackup(c *gin.Context) {
 	var request models.BackupRestoreRequest
@@ -361,13 +413,14 @@ func (h *DatabaseBackupHandler) RestoreBackup(c *gin.Context) {
 		return
 	}
 
-	if err := h.backupService.RestoreBackup(&request); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复备份失败: " + err.Error()})
+	report, err := h.backupService.RestoreBackup(&request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复备份失败: " + err.Error(), "data": report})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true, "message": "备份恢复成功"})
+		"success": true, "message": "备份恢复成功", "data": report})
 }
 
 // GetBackupStats 获取备份统计信息
@@ -429,3 +482,68 @@ func (h *DatabaseBackupHandler) TestS3Connection(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true, "message": "S3连接测试成功"})
 }
+
+// TestStorageConnection 测试远程存储连接
+// @Summary 测试远程存储连接（写入、读取并删除测试文件）
+// @Tags DatabaseBackup
+// @Accept json
+// @Produce json
+// @Param config body models.BackupConfigRequest true "存储配置"
+// @Success 200
+// @Router /api/database-backup/test-storage [post]
+func (h *DatabaseBackupHandler) TestStorageConnection(c *gin.Context) {
+	var request models.BackupConfigRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	if request.StorageType == "" || request.StorageBackend == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "存储类型和存储配置不能为空"})
+		return
+	}
+
+	storage, err := services.NewBackupStorage(request.StorageType, request.StorageBackend)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "创建存储客户端失败: " + err.Error()})
+		return
+	}
+	defer storage.Close()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+	if err := services.TestBackupStorage(ctx, storage); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "存储连接测试失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true, "message": "存储连接测试成功"})
+}
+
+// backupStorageBackendJSON 校验请求中的远程存储配置并序列化，S3 仍使用 S3 字段
+func backupStorageBackendJSON(request *models.BackupConfigRequest) (string, error) {
+	if request.StorageType == "" || request.StorageType == models.StorageTypeS3 {
+		return "", nil
+	}
+
+	supported := false
+	for _, storageType := range services.BackupStorageTypes() {
+		if storageType == request.StorageType {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return "", fmt.Errorf("不支持的存储类型: %s", request.StorageType)
+	}
+	if request.StorageBackend == nil {
+		return "", fmt.Errorf("缺少 %s 存储配置", request.StorageType)
+	}
+
+	data, err := json.Marshal(request.StorageBackend)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// 破坏性操作的影响预估，配合 middleware.ConfirmDestructive 使用
+
+// ImpactRelation 删除记录时一并展示的关联数据
+type ImpactRelation struct {
+	Key    string // 返回的计数键
+	Label  string // 说明中的名称
+	Model  interface{}
+	Column string // 关联到被删除记录 ID 的列
+	Where  string // 额外条件，可为空
+}
+
+// RecordImpact 按路径参数 id 删除单条记录的影响预估，记录不存在时返回 404
+func RecordImpact(model interface{}, label string, relations ...ImpactRelation) func(c *gin.Context) (*models.DestructiveImpact, error) {
+	return func(c *gin.Context) (*models.DestructiveImpact, error) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("无效的ID")
+		}
+
+		var count int64
+		if err := database.DB.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("%s不存在: %w", label, gorm.ErrRecordNotFound)
+		}
+
+		impact := &models.DestructiveImpact{
+			Summary: fmt.Sprintf("将删除 1 个%s", label),
+			Counts:  map[string]int64{"records": count},
+		}
+		for _, relation := range relations {
+			var related int64
+			query := database.DB.Model(relation.Model).Where(relation.Column+" = ?", id)
+			if relation.Where != "" {
+				query = query.Where(relation.Where)
+			}
+			query.Count(&related)
+			impact.Counts[relation.Key] = related
+			if related > 0 {
+				impact.Summary += fmt.Sprintf("，涉及 %d 条%s", related, relation.Label)
+			}
+		}
+		return impact, nil
+	}
+}
+
+// CleanOldLogsImpact 预估清理 DNS 查询日志的条数，日志存储不支持计数时只给出清理范围
+func CleanOldLogsImpact(c *gin.Context) (*models.DestructiveImpact, error) {
+	if logMonitorService == nil {
+		return nil, fmt.Errorf("日志监控服务未初始化")
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days <= 0 || days > 365 {
+		days = 30
+	}
+	var nodeID uint
+	if nodeIDStr := c.Query("node_id"); nodeIDStr != "" {
+		if id, err := strconv.ParseUint(nodeIDStr, 10, 32); err == nil {
+			nodeID = uint(id)
+		}
+	}
+
+	scope := "所有节点"
+	if nodeID > 0 {
+		scope = fmt.Sprintf("节点 #%d", nodeID)
+	}
+
+	estimator, ok := logMonitorService.(services.LogCleanupEstimator)
+	if !ok {
+		return &models.DestructiveImpact{
+			Summary: fmt.Sprintf("将删除%s %d 天前的查询日志（当前日志存储不支持预估条数）", scope, days),
+			Counts:  map[string]int64{},
+		}, nil
+	}
+
+	count, err := estimator.CountOldLogs(nodeID, days)
+	if err != nil {
+		return nil, fmt.Errorf("统计日志失败: %w", err)
+	}
+	return &models.DestructiveImpact{
+		Summary: fmt.Sprintf("将删除%s %d 天前的 %d 条查询日志", scope, days, count),
+		Counts:  map[string]int64{"query_logs": count},
+	}, nil
+}
+
+// DeleteNodeBackupImpact 预估删除节点备份的数量和大小
+func DeleteNodeBackupImpact(c *gin.Context) (*models.DestructiveImpact, error) {
+	var req DeleteBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Count int64
+		Size  int64
+	}
+	if err := database.DB.Model(&models.Backup{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(size), 0) AS size").
+		Where("id IN ? AND node_id = ? AND is_deleted = ?", req.BackupIDs, c.Param("id"), false).
+		Scan(&result).Error; err != nil {
+		return nil, err
+	}
+	if result.Count == 0 {
+		return nil, fmt.Errorf("未找到可删除的备份")
+	}
+
+	return &models.DestructiveImpact{
+		Summary: fmt.Sprintf("将删除 %d 个备份，共 %s", result.Count, formatBytes(result.Size)),
+		Counts:  map[string]int64{"backups": result.Count, "bytes": result.Size},
+	}, nil
+}
+
+// RestoreNodeBackupImpact 预估还原节点备份：当前配置会被覆盖
+func RestoreNodeBackupImpact(c *gin.Context) (*models.DestructiveImpact, error) {
+	var req RestoreBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, err
+	}
+
+	var backup models.Backup
+	if err := database.DB.Preload("Node").First(&backup, req.BackupID).Error; err != nil {
+		return nil, err
+	}
+	if strconv.Itoa(int(backup.NodeID)) != c.Param("id") {
+		return nil, fmt.Errorf("备份不属于该节点")
+	}
+
+	return &models.DestructiveImpact{
+		Summary: fmt.Sprintf("将用备份 %s（%s）覆盖节点 %s 的当前配置并重启服务",
+			backup.Name, backup.CreatedAt.Format("2006-01-02 15:04:05"), backup.Node.Name),
+		Counts: map[string]int64{"nodes": 1},
+	}, nil
+}
+
+// RestoreDatabaseBackupImpact 预估恢复数据库备份：当前数据会被备份内容覆盖
+func RestoreDatabaseBackupImpact(c *gin.Context) (*models.DestructiveImpact, error) {
+	var request models.BackupRestoreRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		return nil, err
+	}
+
+	var history models.BackupHistory
+	if err := database.DB.First(&history, request.BackupHistoryID).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.DestructiveImpact{
+		Summary: fmt.Sprintf("将用备份 %s（%s，%s）覆盖当前数据库，备份之后的修改会丢失",
+			history.FileName, history.CreatedAt.Format("2006-01-02 15:04:05"), formatBytes(history.FileSize)),
+		Counts: map[string]int64{"bytes": history.FileSize},
+	}, nil
+}
+
+// NodeUninstallImpact 预估卸载节点上的服务
+func NodeUninstallImpact(component string) func(c *gin.Context) (*models.DestructiveImpact, error) {
+	return func(c *gin.Context) (*models.DestructiveImpact, error) {
+		var node models.Node
+		if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+			return nil, err
+		}
+		return &models.DestructiveImpact{
+			Summary: fmt.Sprintf("将从节点 %s（%s）卸载 %s", node.Name, node.Host, component),
+			Counts:  map[string]int64{"nodes": 1},
+		}, nil
+	}
+}
+
+// formatBytes 格式化字节数
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
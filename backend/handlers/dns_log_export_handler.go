@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var dnsLogExportService *services.DNSLogExportService
+
+// InitDNSLogExportHandler 初始化日志导出处理器
+func InitDNSLogExportHandler(service *services.DNSLogExportService) {
+	dnsLogExportService = service
+}
+
+// CreateDNSLogExport 提交日志导出任务，过滤参数与 GET /api/dns-logs 相同，也可通过 saved_search_id 使用保存的搜索
+// POST /api/dns-logs/export?format=csv|jsonl&destination=local|remote&max_rows=0&node_id=...
+func CreateDNSLogExport(c *gin.Context) {
+	query, err := savedSearchQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err := services.ValidateDNSLogFilters(query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	params := make(map[string]string)
+	for _, key := range services.DNSLogFilterParams {
+		params[key] = query(key)
+	}
+	nodeID, _ := strconv.ParseUint(params["node_id"], 10, 32)
+	if !checkOrganizationLogNode(c, uint(nodeID)) {
+		return
+	}
+	params["client_ip"] = clientIPSearchValue(params["client_ip"])
+	maxRows, _ := strconv.ParseInt(c.DefaultQuery("max_rows", "0"), 10, 64)
+
+	export, err := dnsLogExportService.Create(c.Request.Context(), params, c.Query("format"), c.Query("destination"), maxRows, auditActor(c).Username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "导出任务已提交",
+		"data":    export,
+	})
+}
+
+// ListDNSLogExports 最近的导出记录，绑定组织的用户只能看到自己提交的导出
+// GET /api/dns-logs/exports
+func ListDNSLogExports(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	exports, err := dnsLogExportService.List(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if requestOrganizationID(c) > 0 {
+		visible := exports[:0]
+		for _, export := range exports {
+			if dnsLogExportVisible(c, &export) {
+				visible = append(visible, export)
+			}
+		}
+		exports = visible
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": exports})
+}
+
+// dnsLogExportVisible 导出记录没有组织归属，组织范围内只允许访问自己提交的导出
+func dnsLogExportVisible(c *gin.Context, export *models.DNSLogExport) bool {
+	return requestOrganizationID(c) == 0 || export.CreatedBy == auditActor(c).Username
+}
+
+// loadDNSLogExport 按路径参数读取当前用户可访问的导出记录，失败时已写入响应
+func loadDNSLogExport(c *gin.Context) (*models.DNSLogExport, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的ID"})
+		return nil, false
+	}
+	export, err := dnsLogExportService.Get(uint(id))
+	if err == nil && !dnsLogExportVisible(c, export) {
+		err = fmt.Errorf("导出记录不存在")
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": err.Error()})
+		return nil, false
+	}
+	return export, true
+}
+
+// GetDNSLogExport 导出状态和进度
+// GET /api/dns-logs/exports/:id
+func GetDNSLogExport(c *gin.Context) {
+	export, ok := loadDNSLogExport(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": export})
+}
+
+// DownloadDNSLogExport 下载导出文件
+// GET /api/dns-logs/exports/:id/download
+func DownloadDNSLogExport(c *gin.Context) {
+	export, ok := loadDNSLogExport(c)
+	if !ok {
+		return
+	}
+
+	body, size, err := dnsLogExportService.Open(c.Request.Context(), export)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	contentType := "text/csv; charset=utf-8"
+	if export.Format != models.DNSLogExportFormatCSV {
+		contentType = "application/x-ndjson"
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, body, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%s", export.FileName),
+	})
+}
+
+// DeleteDNSLogExport 删除导出记录和文件
+// DELETE /api/dns-logs/exports/:id
+func DeleteDNSLogExport(c *gin.Context) {
+	export, ok := loadDNSLogExport(c)
+	if !ok {
+		return
+	}
+	if err := dnsLogExportService.Delete(c.Request.Context(), export.ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "导出已删除"})
+}
@@ -18,7 +18,7 @@ var domainRuleService = services.NewDomainRuleService()
 func GetDomainRules(c *gin.Context) {
 	var rules []models.DomainRule
 
-	query := database.DB
+	query := scopeOrganization(c, database.DB)
 
 	if domain := c.Query("domain"); domain != "" {
 		query = query.Where("domain LIKE ?", "%"+domain+"%")
@@ -56,6 +56,9 @@ func AddDomainRule(c *gin.Context) {
 		})
 		return
 	}
+	if !checkOrganizationNodes(c, request.NodeIDs) {
+		return
+	}
 
 	nodeIDsJSON := "[]"
 	if len(request.NodeIDs) > 0 {
@@ -75,6 +78,7 @@ func AddDomainRule(c *gin.Context) {
 		Description:    request.Description,
 		NodeIDs:        nodeIDsJSON,
 		Enabled:        true,
+		OrganizationID: requestOrganizationID(c),
 	}
 
 	if err := database.DB.Create(&rule).Error; err != nil {
@@ -86,6 +90,8 @@ func AddDomainRule(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditEntityDomainRule, rule.ID, rule.Domain, models.AuditActionCreate, nil, rule)
+
 	// 同步到节点
 	go domainRuleService.SyncDomainRuleToNodes(&rule)
 
@@ -118,6 +124,8 @@ func UpdateDomainRule(c *gin.Context) {
 		return
 	}
 
+	before := rule
+
 	// 使用请求结构体接收数据
 	var req models.UpdateDomainRuleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -158,6 +166,9 @@ func UpdateDomainRule(c *gin.Context) {
 	} else {
 		rule.NodeIDs = "[]"
 	}
+	if !checkOrganizationNodeList(c, rule.NodeIDs) {
+		return
+	}
 
 	// 保存到数据库
 	if err := database.DB.Save(&rule).Error; err != nil {
@@ -168,6 +179,8 @@ func UpdateDomainRule(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditEntityDomainRule, rule.ID, rule.Domain, models.AuditActionUpdate, before, rule)
+
 	// 同步到节点
 	go domainRuleService.SyncDomainRuleToNodes(&rule)
 
@@ -200,6 +213,7 @@ func DeleteDomainRule(c *gin.Context) {
 	}
 
 	database.DB.Delete(&rule)
+	recordAudit(c, models.AuditEntityDomainRule, rule.ID, rule.Domain, models.AuditActionDelete, rule, nil)
 
 	// 从节点删除
 	go domainRuleService.DeleteDomainRuleFromNodes(&rule)
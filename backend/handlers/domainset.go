@@ -15,6 +15,7 @@ import (
 )
 
 var domainSetService = services.NewDomainSetService()
+var domainCategoryService = services.NewDomainCategoryService()
 
 // GetDomainSets 获取域名集列表
 func GetDomainSets(c *gin.Context) {
@@ -74,7 +75,8 @@ func AddDomainSet(c *gin.Context) {
 	var request struct {
 		Name        string   `json:"name" binding:"required"`
 		Description string   `json:"description"`
-		Domains     []string `json:"domains"` // 域名列表
+		Category    string   `json:"category"` // 分类（用于日志打标）
+		Domains     []string `json:"domains"`  // 域名列表
 		NodeIDs     []uint   `json:"node_ids"`
 	}
 
@@ -109,6 +111,7 @@ func AddDomainSet(c *gin.Context) {
 		Name:        request.Name,
 		FilePath:    fmt.Sprintf("/etc/smartdns/%s.conf", request.Name),
 		Description: request.Description,
+		Category:    strings.TrimSpace(request.Category),
 		NodeIDs:     nodeIDsJSON,
 		DomainCount: len(request.Domains),
 		Enabled:     true,
@@ -132,8 +135,11 @@ func AddDomainSet(c *gin.Context) {
 		database.DB.Create(&item)
 	}
 
+	recordAudit(c, models.AuditEntityDomainSet, domainSet.ID, domainSet.Name, models.AuditActionCreate, nil, domainSet)
+
 	// 同步到节点
 	go domainSetService.SyncDomainSetToNodes(&domainSet)
+	domainCategoryService.SyncToClickHouseAsync()
 
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
@@ -165,6 +171,7 @@ func UpdateDomainSet(c *gin.Context) {
 
 	var request struct {
 		Description string   `json:"description"`
+		Category    string   `json:"category"`
 		Domains     []string `json:"domains"`
 		NodeIDs     []uint   `json:"node_ids"`
 		Enabled     bool     `json:"enabled"`
@@ -178,8 +185,11 @@ func UpdateDomainSet(c *gin.Context) {
 		return
 	}
 
+	before := domainSet
+
 	// 更新基本信息
 	domainSet.Description = request.Description
+	domainSet.Category = strings.TrimSpace(request.Category)
 	domainSet.Enabled = request.Enabled
 	domainSet.DomainCount = len(request.Domains)
 
@@ -201,9 +211,11 @@ func UpdateDomainSet(c *gin.Context) {
 	}
 
 	database.DB.Save(&domainSet)
+	recordAudit(c, models.AuditEntityDomainSet, domainSet.ID, domainSet.Name, models.AuditActionUpdate, before, domainSet)
 
 	// 同步到节点
 	go domainSetService.SyncDomainSetToNodes(&domainSet)
+	domainCategoryService.SyncToClickHouseAsync()
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -238,9 +250,13 @@ func DeleteDomainSet(c *gin.Context) {
 
 	// 删除域名集
 	database.DB.Delete(&domainSet)
+	recordAudit(c, models.AuditEntityDomainSet, domainSet.ID, domainSet.Name, models.AuditActionDelete, domainSet, nil)
 
 	// 从节点删除
 	go domainSetService.DeleteDomainSetFromNodes(&domainSet)
+	if domainSet.Category != "" {
+		domainCategoryService.SyncToClickHouseAsync()
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -315,6 +331,7 @@ func ImportDomainSetFile(c *gin.Context) {
 
 	// 同步到节点
 	go domainSetService.SyncDomainSetToNodes(&domainSet)
+	domainCategoryService.SyncToClickHouseAsync()
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
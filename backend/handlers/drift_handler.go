@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var driftService *services.DriftService
+
+// InitDriftHandler 初始化配置漂移处理器
+func InitDriftHandler(service *services.DriftService) {
+	driftService = service
+}
+
+// GetDriftReports 获取配置漂移报告
+// GET /api/drift-reports?node_id=&has_drift=true
+func GetDriftReports(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 50
+	}
+
+	query := scopeOrganizationNodes(c, database.DB.Model(&models.ConfigDriftReport{}), "node_id")
+	if nodeID := c.Query("node_id"); nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	if hasDrift := c.Query("has_drift"); hasDrift != "" {
+		query = query.Where("has_drift = ?", hasDrift == "true")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var reports []models.ConfigDriftReport
+	query.Order("checked_at desc, id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&reports)
+	services.DecodeDriftReports(reports)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      reports,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetDriftReport 获取单个配置漂移报告
+func GetDriftReport(c *gin.Context) {
+	var report models.ConfigDriftReport
+	if err := database.DB.First(&report, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "漂移报告不存在",
+		})
+		return
+	}
+
+	reports := []models.ConfigDriftReport{report}
+	services.DecodeDriftReports(reports)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reports[0],
+	})
+}
+
+// CheckNodeDrift 立即检测节点配置漂移
+// POST /api/nodes/:id/drift-check
+func CheckNodeDrift(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	report := driftService.CheckNode(&node)
+	if report.Error != "" {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "配置漂移检测失败",
+			"error":   report.Error,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var fleetReportService *services.FleetReportService
+
+// InitFleetReportHandler 初始化节点资产报告处理器
+func InitFleetReportHandler(service *services.FleetReportService) {
+	fleetReportService = service
+}
+
+// writeFleetReport 按 format 参数返回 JSON 或 CSV 下载
+func writeFleetReport(c *gin.Context, data *models.FleetReportData) {
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    data,
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=fleet-report-%s.csv", data.Month))
+	// 写入 BOM，避免 Excel 打开中文乱码
+	c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	if err := services.WriteFleetReportCSV(c.Writer, data); err != nil {
+		c.Error(err)
+	}
+}
+
+// GetFleetReport 实时生成节点资产报告
+// GET /api/reports/fleet?month=2024-05&check_resources=true&format=csv
+func GetFleetReport(c *gin.Context) {
+	data, err := fleetReportService.Generate(c.Request.Context(), c.Query("month"), c.Query("check_resources") == "true")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	writeFleetReport(c, data)
+}
+
+// CreateFleetReport 生成并保存节点资产报告
+// POST /api/reports/fleet
+func CreateFleetReport(c *gin.Context) {
+	var req struct {
+		Month          string `json:"month"`
+		CheckResources bool   `json:"check_resources"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	data, err := fleetReportService.Generate(c.Request.Context(), req.Month, req.CheckResources)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	report, err := fleetReportService.SaveReport(data, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "资产报告已生成",
+		"data":    report,
+	})
+}
+
+// GetFleetReportHistory 获取已保存的资产报告列表（不含报告内容）
+// GET /api/reports/fleet/history
+func GetFleetReportHistory(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "24"))
+	if limit < 1 || limit > 100 {
+		limit = 24
+	}
+
+	var reports []models.FleetReport
+	database.DB.Omit("data").Order("generated_at desc, id desc").Limit(limit).Find(&reports)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reports,
+	})
+}
+
+// GetSavedFleetReport 获取或导出已保存的资产报告
+// GET /api/reports/fleet/history/:id?format=csv
+func GetSavedFleetReport(c *gin.Context) {
+	var report models.FleetReport
+	if err := database.DB.First(&report, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "资产报告不存在",
+		})
+		return
+	}
+
+	if err := services.DecodeFleetReport(&report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	writeFleetReport(c, report.Report)
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var groupBlockService = services.NewGroupBlockService()
+
+// GetGroupBlocks 获取分组配置块列表
+func GetGroupBlocks(c *gin.Context) {
+	var blocks []models.GroupBlock
+	database.DB.Order("name").Find(&blocks)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    blocks,
+		"total":   len(blocks),
+	})
+}
+
+// GetGroupBlock 获取单个分组配置块及生成的配置
+func GetGroupBlock(c *gin.Context) {
+	block, ok := findGroupBlock(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"group_block": block,
+			"preview":     groupBlockService.Preview(block),
+		},
+	})
+}
+
+// AddGroupBlock 添加分组配置块
+func AddGroupBlock(c *gin.Context) {
+	var request models.GroupBlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if strings.ContainsAny(request.Name, " \t") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "分组名称不能包含空白字符",
+		})
+		return
+	}
+
+	var existing models.GroupBlock
+	if err := database.DB.Where("name = ?", request.Name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "分组名称已存在",
+		})
+		return
+	}
+
+	block := models.GroupBlock{Name: request.Name, Enabled: true}
+	applyGroupBlockRequest(&block, &request)
+
+	if err := database.DB.Create(&block).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建分组配置块失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityGroupBlock, block.ID, block.Name, models.AuditActionCreate, nil, block)
+
+	go groupBlockService.SyncGroupBlockToNodes(context.WithoutCancel(c.Request.Context()), &block)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "分组配置块创建成功，正在同步到节点...",
+		"data":    block,
+	})
+}
+
+// UpdateGroupBlock 更新分组配置块
+func UpdateGroupBlock(c *gin.Context) {
+	block, ok := findGroupBlock(c)
+	if !ok {
+		return
+	}
+
+	var request models.GroupBlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// 名称作为节点配置中的标识，不允许修改
+	previous := *block
+	applyGroupBlockRequest(block, &request)
+
+	if err := database.DB.Save(block).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "更新分组配置块失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityGroupBlock, block.ID, block.Name, models.AuditActionUpdate, previous, block)
+
+	// 禁用时从原节点移除；节点范围变化时从不再适用的节点移除
+	if !block.Enabled {
+		go groupBlockService.DeleteGroupBlockFromNodes(context.WithoutCancel(c.Request.Context()), &previous)
+	} else {
+		if previous.NodeIDs != block.NodeIDs {
+			go groupBlockService.DeleteGroupBlockFromRemovedNodes(context.WithoutCancel(c.Request.Context()), &previous, block)
+		}
+		go groupBlockService.SyncGroupBlockToNodes(context.WithoutCancel(c.Request.Context()), block)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分组配置块更新成功，正在同步到节点...",
+		"data":    block,
+	})
+}
+
+// DeleteGroupBlock 删除分组配置块
+func DeleteGroupBlock(c *gin.Context) {
+	block, ok := findGroupBlock(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(block).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "删除分组配置块失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityGroupBlock, block.ID, block.Name, models.AuditActionDelete, block, nil)
+
+	go groupBlockService.DeleteGroupBlockFromNodes(context.WithoutCancel(c.Request.Context()), block)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分组配置块删除成功",
+	})
+}
+
+// PreviewGroupBlock 预览分组配置块生成的配置（不保存）
+func PreviewGroupBlock(c *gin.Context) {
+	var request models.GroupBlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	block := models.GroupBlock{Name: strings.TrimSpace(request.Name)}
+	applyGroupBlockRequest(&block, &request)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    groupBlockService.Preview(&block),
+	})
+}
+
+// ImportGroupBlocks 从节点现有配置导入 group 块
+func ImportGroupBlocks(c *gin.Context) {
+	nodeID, err := strconv.ParseUint(c.Param("node_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的节点ID",
+		})
+		return
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, nodeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	imported, err := groupBlockService.ImportFromNode(&node)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "导入失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "导入完成",
+		"data":    imported,
+		"total":   len(imported),
+	})
+}
+
+// findGroupBlock 根据路径参数查找分组配置块
+func findGroupBlock(c *gin.Context) (*models.GroupBlock, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的分组配置块ID",
+		})
+		return nil, false
+	}
+
+	var block models.GroupBlock
+	if err := database.DB.First(&block, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "分组配置块不存在",
+		})
+		return nil, false
+	}
+
+	return &block, true
+}
+
+// applyGroupBlockRequest 将请求内容写入分组配置块
+func applyGroupBlockRequest(block *models.GroupBlock, request *models.GroupBlockRequest) {
+	block.Description = request.Description
+	block.SpeedCheckMode = strings.TrimSpace(request.SpeedCheckMode)
+	block.ClientRules = marshalTrimmedList(request.ClientRules)
+	block.Servers = marshalTrimmedList(request.Servers)
+
+	var extraLines []string
+	for _, line := range request.ExtraLines {
+		if line = strings.TrimSpace(line); line != "" {
+			extraLines = append(extraLines, line)
+		}
+	}
+	block.ExtraLines = strings.Join(extraLines, "\n")
+
+	block.NodeIDs = "[]"
+	if len(request.NodeIDs) > 0 {
+		nodeIDsBytes, _ := json.Marshal(request.NodeIDs)
+		block.NodeIDs = string(nodeIDsBytes)
+	}
+
+	if request.Enabled != nil {
+		block.Enabled = *request.Enabled
+	}
+}
+
+// marshalTrimmedList 去除空白项后编码为 JSON 数组
+func marshalTrimmedList(values []string) string {
+	result := []string{}
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	data, _ := json.Marshal(result)
+	return string(data)
+}
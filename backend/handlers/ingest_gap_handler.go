@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var ingestGapService *services.IngestGapService
+
+// InitIngestGapHandler 初始化日志缺失检测处理器
+func InitIngestGapHandler(service *services.IngestGapService) {
+	ingestGapService = service
+}
+
+// parseIngestGapSince 解析检测时间范围，默认最近24小时，最多7天
+func parseIngestGapSince(c *gin.Context) time.Time {
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if hours < 1 || hours > 168 {
+		hours = 24
+	}
+	return time.Now().Add(-time.Duration(hours) * time.Hour)
+}
+
+// GetIngestGaps 获取节点日志写入缺失报告
+// GET /api/nodes/:id/ingest-gaps?hours=24
+func GetIngestGaps(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	report, err := ingestGapService.GetReport(&node, parseIngestGapSince(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取日志缺失报告失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// RereadIngestGaps 请求 Agent 补读缺失区间，未指定区间时补读报告中的全部缺失区间
+// POST /api/nodes/:id/ingest-gaps/reread
+func RereadIngestGaps(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	if !node.AgentInstalled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Agent 未安装",
+		})
+		return
+	}
+
+	var req struct {
+		Gaps []models.IngestGap `json:"gaps"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "参数错误",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	gaps := req.Gaps
+	if len(gaps) == 0 {
+		report, err := ingestGapService.GetReport(&node, parseIngestGapSince(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取日志缺失报告失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		for _, stream := range report.Streams {
+			gaps = append(gaps, stream.Gaps...)
+		}
+	}
+
+	if len(gaps) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "没有需要补读的区间",
+			"data":    []models.IngestRereadResult{},
+		})
+		return
+	}
+
+	results := ingestGapService.Reread(&node, gaps)
+	succeeded := 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "补读完成",
+		"data":      results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	})
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -45,16 +47,22 @@ func InitNode(c *gin.Context) {
 		return
 	}
 
-	// 异步执行初始化
-	go func() {
-		if err := initService.InitNode(uint(nodeID)); err != nil {
-			log.Printf("节点初始化失败: %v", err)
-		}
-	}()
+	// 异步执行初始化，服务重启后从已完成的步骤继续
+	// 任务排队期间连接进度流的页面等待本次初始化的事件，而不是收到上一次的结果
+	services.GetInitProgressBroker().Begin(node.ID)
+	job, err := services.StartJob(c.Request.Context(), models.BackgroundJobNodeInit, node.ID, "初始化节点 "+node.Name, nil)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "节点初始化已开始，请查看初始化日志",
+		"data":    job,
 	})
 }
 
@@ -81,8 +89,9 @@ func CheckNodeInit(c *gin.Context) {
 
 	// 如果状态是 unknown，尝试检测
 	if node.InitStatus == "unknown" || node.InitStatus == "not_installed" {
+		ctx := context.WithoutCancel(c.Request.Context())
 		go func() {
-			if err := initService.CheckAndUpdateNodeStatus(&node); err != nil {
+			if err := initService.CheckAndUpdateNodeStatus(ctx, &node); err != nil {
 				log.Printf("检测节点状态失败: %v", err)
 			}
 		}()
@@ -96,6 +105,9 @@ func CheckNodeInit(c *gin.Context) {
 			"os_type":          node.OSType,
 			"os_version":       node.OSVersion,
 			"architecture":     node.Architecture,
+			"install_mode":     node.InstallMode,
+			"installed_via":    node.InstalledVia,
+			"service_manager":  node.ServiceManager,
 		},
 	})
 }
@@ -121,6 +133,100 @@ func GetInitLogs(c *gin.Context) {
 	})
 }
 
+// StreamInitProgress 以 SSE 推送节点初始化进度
+// GET /api/nodes/:id/init/stream
+//
+// 事件类型：step（步骤状态变化，data.log 为步骤日志）、output（命令输出的一行）、done（初始化结束）。
+// 连接时先补发本次初始化已产生的事件；服务重启后没有内存中的事件，改为补发数据库中的步骤日志
+func StreamInitProgress(c *gin.Context) {
+	nodeID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的节点ID",
+		})
+		return
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, nodeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	history, events, unsubscribe := services.GetInitProgressBroker().Subscribe(node.ID)
+	defer unsubscribe()
+	if len(history) == 0 && events == nil {
+		history = initLogEvents(&node)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	for _, event := range history {
+		c.SSEvent(event.Type, event)
+	}
+	c.Writer.Flush()
+	if events == nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// 初始化已结束，或浏览器读取过慢被断开，由浏览器重新连接
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return event.Type != models.InitEventDone
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"time": time.Now()})
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// initLogEvents 把数据库中的步骤日志转为进度事件，初始化已结束时追加 done 事件
+func initLogEvents(node *models.Node) []models.InitProgressEvent {
+	var logs []models.InitLog
+	database.DB.Where("node_id = ?", node.ID).Order("created_at asc").Limit(50).Find(&logs)
+
+	events := make([]models.InitProgressEvent, 0, len(logs)+1)
+	for i := range logs {
+		event := models.InitProgressEvent{
+			Seq:    int64(i + 1),
+			Type:   models.InitEventStep,
+			Step:   logs[i].Step,
+			Status: logs[i].Status,
+			Log:    &logs[i],
+			Time:   logs[i].StartedAt,
+		}
+		if !logs[i].EndedAt.IsZero() {
+			event.Time = logs[i].EndedAt
+		}
+		events = append(events, event)
+	}
+	if node.InitStatus != "initializing" {
+		events = append(events, models.InitProgressEvent{
+			Seq:    int64(len(events) + 1),
+			Type:   models.InitEventDone,
+			Status: node.InitStatus,
+			Time:   time.Now(),
+		})
+	}
+	return events
+}
+
 // UninstallSmartDNS 卸载 SmartDNS
 func UninstallSmartDNS(c *gin.Context) {
 	id := c.Param("id")
@@ -143,15 +249,19 @@ func UninstallSmartDNS(c *gin.Context) {
 	}
 
 	// 异步执行卸载
-	go func() {
-		if err := initService.UninstallSmartDNS(uint(nodeID)); err != nil {
-			log.Printf("卸载失败: %v", err)
-		}
-	}()
+	job, err := services.StartJob(c.Request.Context(), models.BackgroundJobNodeUninstall, node.ID, "卸载节点 "+node.Name+" 的 SmartDNS", nil)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "卸载任务已开始",
+		"data":    job,
 	})
 }
 
@@ -167,22 +277,30 @@ func ReinstallSmartDNS(c *gin.Context) {
 		return
 	}
 
-	// 先卸载再安装
-	go func() {
-		if err := initService.UninstallSmartDNS(uint(nodeID)); err != nil {
-			log.Printf("卸载失败: %v", err)
-			return
-		}
-
-		time.Sleep(2 * time.Second)
+	var node models.Node
+	if err := database.DB.First(&node, nodeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
 
-		if err := initService.InitNode(uint(nodeID)); err != nil {
-			log.Printf("重新安装失败: %v", err)
-		}
-	}()
+	// 先卸载再安装
+	// 任务排队期间连接进度流的页面等待本次初始化的事件，而不是收到上一次的结果
+	services.GetInitProgressBroker().Begin(node.ID)
+	job, err := services.StartJob(c.Request.Context(), models.BackgroundJobNodeReinstall, node.ID, "重新安装节点 "+node.Name+" 的 SmartDNS", nil)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "重新安装任务已开始",
+		"data":    job,
 	})
 }
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// ingestNode 按请求中的节点控制令牌查找节点，失败时已写入响应
+func ingestNode(c *gin.Context) (*models.Node, bool) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !strings.HasPrefix(token, models.AgentControlTokenPrefix) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "缺少节点控制令牌",
+		})
+		return nil, false
+	}
+
+	var node models.Node
+	if err := database.DB.Select("id, name").Where("agent_control_token_hash = ?", models.HashAPIToken(token)).First(&node).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "节点控制令牌无效",
+		})
+		return nil, false
+	}
+	return &node, true
+}
+
+// logIngester 当前日志存储的写入接口，不支持时已写入响应
+func logIngester(c *gin.Context) (services.LogIngester, bool) {
+	ingester, ok := logMonitorService.(services.LogIngester)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"success": false,
+			"message": "当前日志存储不支持经管理端写入日志",
+		})
+		return nil, false
+	}
+	return ingester, true
+}
+
+// IngestDNSLogs 接收 Agent 上传的 DNS 日志并写入日志存储，使用节点控制令牌认证
+// POST /api/ingest/dns-logs
+//
+// 请求体为 JSON 数组或 NDJSON，可用 Content-Encoding: gzip 压缩；X-Dedup-Token 相同的批次只写入一次，
+// 日志的 node_id 以令牌所属节点为准。写入失败时返回 503，Agent 应以相同的 X-Dedup-Token 重试
+func IngestDNSLogs(c *gin.Context) {
+	node, ok := ingestNode(c)
+	if !ok {
+		return
+	}
+	ingester, ok := logIngester(c)
+	if !ok {
+		return
+	}
+
+	records, err := services.DecodeIngestBody(c.Request.Body, strings.ToLower(c.GetHeader("Content-Encoding")))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrIngestBodyTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	for i := range records {
+		records[i].NodeID = uint32(node.ID)
+	}
+	// 启用 LOG_PRIVACY_MODE 时不保存原始客户端 IP，Agent 未脱敏的日志在这里脱敏后再写入
+	services.GetClientIPPrivacy().MaskRecords(records)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	if err := ingester.IngestLogs(ctx, records, c.GetHeader("X-Dedup-Token")); err != nil {
+		log.Printf("❌ 写入节点 %s 上传的日志失败: %v", node.Name, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"rows": len(records),
+		},
+	})
+}
+
+// IngestDNSBatches 记录 Agent 已写入的批次，供检测日志缺失和乱序
+// POST /api/ingest/dns-batches
+//
+// 批次只包含日志文件位置和行数，不含客户端 IP，无需脱敏
+func IngestDNSBatches(c *gin.Context) {
+	node, ok := ingestNode(c)
+	if !ok {
+		return
+	}
+	ingester, ok := logIngester(c)
+	if !ok {
+		return
+	}
+
+	var batches []models.IngestBatch
+	if err := c.ShouldBindJSON(&batches); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := ingester.IngestBatchAcks(ctx, node.ID, batches); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// GetIngestDomainCategories Agent 加载域名分类
+// GET /api/ingest/domain-categories
+func GetIngestDomainCategories(c *gin.Context) {
+	if _, ok := ingestNode(c); !ok {
+		return
+	}
+	ingester, ok := logIngester(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	categories, err := ingester.DomainCategories(ctx)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    categories,
+	})
+}
@@ -65,10 +65,7 @@ func StartNodeLogMonitor(c *gin.Context) {
 	}
 
 	// 调用 Agent API 启动日志收集
-	agentPort := services.GetAgentPort(&node)
-	agentURL := fmt.Sprintf("http://%s:%d/api/v1/start", node.Host, agentPort)
-
-	err = services.CallAgentAPI("POST", agentURL, nil)
+	err = services.CallNodeAgent(&node, "POST", "/start", nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -110,10 +107,7 @@ func StopNodeLogMonitor(c *gin.Context) {
 	}
 
 	// 调用 Agent API 停止日志收集
-	agentPort := services.GetAgentPort(&node)
-	agentURL := fmt.Sprintf("http://%s:%d/api/v1/stop", node.Host, agentPort)
-
-	err = services.CallAgentAPI("POST", agentURL, nil)
+	err = services.CallNodeAgent(&node, "POST", "/stop", nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -124,7 +118,9 @@ func StopNodeLogMonitor(c *gin.Context) {
 
 	// 更新数据库状态
 	database.DB.Model(&node).Updates(map[string]interface{}{
-		"log_monitor_enabled": false,
+		"log_monitor_enabled":    false,
+		"log_monitor_status":     "",
+		"log_monitor_down_since": nil,
 	})
 
 	c.JSON(http.StatusOK, gin.H{
@@ -168,10 +164,7 @@ func GetNodeLogMonitorStatus(c *gin.Context) {
 	}
 
 	// 调用 Agent API 获取状态
-	agentPort := services.GetAgentPort(&node)
-	agentURL := fmt.Sprintf("http://%s:%d/api/v1/status", node.Host, agentPort)
-
-	response, err := services.CallAgentAPIWithResponse("GET", agentURL, nil)
+	response, err := services.CallNodeAgentWithResponse(&node, "GET", "/status", nil)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -221,10 +214,7 @@ func RestartNodeLogMonitor(c *gin.Context) {
 	}
 
 	// 调用 Agent API 重启日志收集
-	agentPort := services.GetAgentPort(&node)
-	agentURL := fmt.Sprintf("http://%s:%d/api/v1/restart", node.Host, agentPort)
-
-	err = services.CallAgentAPI("POST", agentURL, nil)
+	err = services.CallNodeAgent(&node, "POST", "/restart", nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -261,10 +251,7 @@ func GetAgentStats(c *gin.Context) {
 	}
 
 	// 调用 Agent API 获取统计信息
-	agentPort := services.GetAgentPort(&node)
-	agentURL := fmt.Sprintf("http://%s:%d/api/v1/stats", node.Host, agentPort)
-
-	response, err := services.CallAgentAPIWithResponse("GET", agentURL, nil)
+	response, err := services.CallNodeAgentWithResponse(&node, "GET", "/stats", nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -279,6 +266,72 @@ func GetAgentStats(c *gin.Context) {
 	})
 }
 
+// GetAgentConfig 获取 Agent 当前的运行配置（调用 Agent API）
+// GET /api/nodes/:id/agent/config
+func GetAgentConfig(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	response, err := services.CallNodeAgentWithResponse(&node, "GET", "/config", nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取配置失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response["data"],
+	})
+}
+
+// UpdateAgentConfig 调整 Agent 的批量大小、刷新间隔、并发写入批次数和压缩算法（调用 Agent API）
+// PUT /api/nodes/:id/agent/config
+//
+// 高 QPS 节点可增大批量和并发批次数，写入跟不上时 Agent 暂停读取日志，不会无限占用内存；配置仅在 Agent 内存中生效
+func UpdateAgentConfig(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	var req models.AgentConfigUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	response, err := services.CallNodeAgentWithResponse(&node, "PUT", "/config", req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "更新配置失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Agent 配置已更新",
+		"data":    response["data"],
+	})
+}
+
 func GetAgentLogs(c *gin.Context) {
 	id := c.Param("id")
 	nodeID, err := strconv.ParseUint(id, 10, 32)
@@ -299,11 +352,9 @@ func GetAgentLogs(c *gin.Context) {
 		return
 	}
 
-	// 调用 Agent API 获取统计信息
-	agentPort := services.GetAgentPort(&node)
-	agentURL := fmt.Sprintf("http://%s:%d/api/v1/logs", node.Host, agentPort)
-
-	response, err := services.CallAgentAPIWithResponse("GET", agentURL, nil)
+	// 调用 Agent API 获取最近的 Agent 日志
+	lines, _ := strconv.Atoi(c.DefaultQuery("lines", "100"))
+	response, err := services.CallNodeAgentWithResponse(&node, "GET", fmt.Sprintf("/logs?lines=%d", lines), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -346,74 +397,94 @@ func GetDNSLogs(c *gin.Context) {
 		pageSize = 50
 	}
 
-	// 构建过滤条件
-	filters := make(map[string]interface{})
-
-	// 节点ID
-	if nodeIDStr := c.Query("node_id"); nodeIDStr != "" {
-		if nodeID, err := strconv.ParseUint(nodeIDStr, 10, 32); err == nil {
-			filters["node_id"] = uint(nodeID)
-		}
+	// 构建过滤条件，指定 saved_search_id 时以保存的搜索为基础，请求参数优先
+	query, err := savedSearchQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
 	}
-
-	// 客户端IP
-	if clientIP := c.Query("client_ip"); clientIP != "" {
-		filters["client_ip"] = clientIP
+	if err := services.ValidateDNSLogFilters(query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
 	}
-
-	if group := c.Query("group"); group != "" {
-		filters["group"] = group
+	filters := services.ParseDNSLogFilters(query)
+	if nodeID, _ := filters["node_id"].(uint); !checkOrganizationLogNode(c, nodeID) {
+		return
 	}
-
-	// 域名
-	if domain := c.Query("domain"); domain != "" {
-		filters["domain"] = domain
+	if clientIP, ok := filters["client_ip"].(string); ok {
+		filters["client_ip"] = clientIPSearchValue(clientIP)
+	}
+	if sortField := query("sort_field"); sortField != "" {
+		filters["sort_field"] = sortField
+	}
+	if sortOrder := query("sort_order"); sortOrder != "" {
+		filters["sort_order"] = sortOrder
 	}
 
-	// 查询类型
-	if queryTypeStr := c.Query("query_type"); queryTypeStr != "" {
-		if queryType, err := strconv.Atoi(queryTypeStr); err == nil {
-			filters["query_type"] = queryType
-		}
+	// 携带 cursor 或指定 pagination=cursor 时使用游标翻页，不计算总数
+	if cursor := c.Query("cursor"); cursor != "" || c.Query("pagination") == "cursor" {
+		queryDNSLogsByCursor(c, cursor, pageSize, filters)
+		return
 	}
 
-	// 时间范围 - 改进时间解析
-	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
-		// 支持多种时间格式
-		formats := []string{
-			time.RFC3339,
-			"2006-01-02T15:04:05Z",
-			"2006-01-02 15:04:05",
-			"2006-01-02",
-		}
+	queryDNSLogs(c, page, pageSize, filters, nil, false)
+}
 
-		for _, format := range formats {
-			if startTime, err := time.Parse(format, startTimeStr); err == nil {
-				filters["start_time"] = startTime
-				break
-			}
-		}
+// queryDNSLogsByCursor 按游标翻页查询日志，响应中的 next_cursor 为空表示没有更多数据
+func queryDNSLogsByCursor(c *gin.Context, cursor string, pageSize int, filters map[string]interface{}) {
+	pager, ok := logMonitorService.(services.LogCursorPager)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "当前日志存储不支持游标翻页",
+		})
+		return
 	}
-
-	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
-		formats := []string{
-			time.RFC3339,
-			"2006-01-02T15:04:05Z",
-			"2006-01-02 15:04:05",
-			"2006-01-02",
+	if cursor != "" {
+		if _, err := services.DecodeLogCursor(cursor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
 		}
+	}
 
-		for _, format := range formats {
-			if endTime, err := time.Parse(format, endTimeStr); err == nil {
-				filters["end_time"] = endTime
-				break
-			}
-		}
+	logs, nextCursor, err := pager.GetLogsByCursor(cursor, pageSize, filters)
+	if err != nil {
+		log.Printf("❌ 获取日志失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取日志失败: " + err.Error(),
+		})
+		return
+	}
+	if logs == nil {
+		logs = make([]models.DNSLog, 0)
 	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"logs":        logs,
+			"page_size":   pageSize,
+			"next_cursor": nextCursor,
+			"has_more":    nextCursor != "",
+		},
+	})
+}
+
+// queryDNSLogs 按过滤条件查询日志并返回，排序参数从请求中读取（未指定时使用 filters 中的排序），
+// extra 中的字段附加到响应，maskClientIPs 为 true 时脱敏客户端 IP
+func queryDNSLogs(c *gin.Context, page, pageSize int, filters map[string]interface{}, extra gin.H, maskClientIPs bool) {
 	// 解析排序参数
-	sortField := c.DefaultQuery("sort_field", "timestamp")
-	sortOrder := c.DefaultQuery("sort_order", "desc")
+	defaultSortField, _ := filters["sort_field"].(string)
+	if defaultSortField == "" {
+		defaultSortField = "timestamp"
+	}
+	defaultSortOrder, _ := filters["sort_order"].(string)
+	if defaultSortOrder == "" {
+		defaultSortOrder = "desc"
+	}
+	sortField := c.DefaultQuery("sort_field", defaultSortField)
+	sortOrder := c.DefaultQuery("sort_order", defaultSortOrder)
 	
 	// 验证排序字段
 	allowedSortFields := map[string]bool{
@@ -466,8 +537,11 @@ func GetDNSLogs(c *gin.Context) {
 		if logs == nil {
 			logs = make([]models.DNSLog, 0)
 		}
+		if maskClientIPs {
+			sharedClientIPPrivacy().MaskLogs(logs)
+		}
 
-		c.JSON(http.StatusOK, gin.H{
+		response := gin.H{
 			"success": true,
 			"data": gin.H{
 				"logs":      logs,
@@ -475,7 +549,11 @@ func GetDNSLogs(c *gin.Context) {
 				"page":      page,
 				"page_size": pageSize,
 			},
-		})
+		}
+		for key, value := range extra {
+			response[key] = value
+		}
+		c.JSON(http.StatusOK, response)
 
 	case <-ctx.Done():
 		c.JSON(http.StatusRequestTimeout, gin.H{
@@ -534,6 +612,14 @@ func GetLogStats(c *gin.Context) {
 	})
 }
 
+// GetQueryTypes DNS 查询类型编号与名称，query_type 过滤参数可使用其中的编号或名称
+func GetQueryTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    models.QueryTypes(),
+	})
+}
+
 // SearchDomains 搜索域名（从 ClickHouse 查询）
 func SearchDomains(c *gin.Context) {
 	if logMonitorService == nil {
@@ -612,3 +698,50 @@ func CleanOldLogs(c *gin.Context) {
 		"message": "清理完成",
 	})
 }
+
+// GetLogStorageInfo 获取日志存储信息（连接状态、表统计、表结构校对结果）
+func GetLogStorageInfo(c *gin.Context) {
+	if logMonitorService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "日志监控服务未初始化",
+		})
+		return
+	}
+
+	info := logMonitorService.GetStorageInfo()
+	if stats, err := logMonitorService.GetTableStats(); err == nil {
+		info["table_stats"] = stats
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    info,
+	})
+}
+
+// ReconcileLogSchema 重新检查日志表并补齐缺少的列，返回校对后的存储信息
+func ReconcileLogSchema(c *gin.Context) {
+	if logMonitorService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "日志监控服务未初始化",
+		})
+		return
+	}
+
+	if err := logMonitorService.EnsureTables(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "表结构校对失败: " + err.Error(),
+			"data":    logMonitorService.GetStorageInfo(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "表结构校对完成",
+		"data":    logMonitorService.GetStorageInfo(),
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// GetLogRetentionOverview 日志保留策略概览：默认保留天数、表 TTL 使用的最长保留天数和单独设置的节点
+// GET /api/dns-logs/retention
+func GetLogRetentionOverview(c *gin.Context) {
+	overview, err := services.GetLogRetentionOverview(logMonitorService)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    overview,
+	})
+}
+
+// GetNodeLogRetention 获取节点的 DNS 查询日志保留天数
+// GET /api/dns-logs/:id/retention
+func GetNodeLogRetention(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    services.GetNodeLogRetention(node.ID),
+	})
+}
+
+// UpdateNodeLogRetention 设置节点的 DNS 查询日志保留天数，0 表示恢复默认
+// PUT /api/dns-logs/:id/retention
+//
+// 保存后立即按新策略执行一次清理，延长保留天数时 ClickHouse 表 TTL 随之调整，避免日志在下次定时任务前被 TTL 删除
+func UpdateNodeLogRetention(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	var req models.LogRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	previous := services.GetNodeLogRetention(node.ID)
+	if err := services.SaveNodeLogRetention(node.ID, req.Days); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	retention := services.GetNodeLogRetention(node.ID)
+	recordAudit(c, models.AuditEntityLogRetention, node.ID, node.Name, models.AuditActionUpdate, previous, retention)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	summary, err := services.ApplyLogRetention(ctx, logMonitorService)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "保留天数已保存，但立即清理失败，将由定时任务重试: " + err.Error(),
+			"data":    gin.H{"retention": retention, "applied": false, "summary": summary},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "保留天数已保存并生效",
+		"data":    gin.H{"retention": retention, "applied": true, "summary": summary},
+	})
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var maintenanceWorker *services.MaintenanceWorker
+
+// InitMaintenanceHandler 初始化维护窗口处理器
+func InitMaintenanceHandler(worker *services.MaintenanceWorker) {
+	maintenanceWorker = worker
+}
+
+// GetMaintenanceWindows 获取维护窗口及其当前状态
+func GetMaintenanceWindows(c *gin.Context) {
+	var windows []models.MaintenanceWindow
+	database.DB.Order("name").Find(&windows)
+
+	now := time.Now()
+	for i := range windows {
+		services.FillWindowState(&windows[i], now)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    windows,
+		"total":   len(windows),
+	})
+}
+
+// AddMaintenanceWindow 添加维护窗口
+func AddMaintenanceWindow(c *gin.Context) {
+	var request models.MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	window := models.MaintenanceWindow{Enabled: true}
+	if !applyMaintenanceRequest(c, &window, &request) {
+		return
+	}
+
+	var existing models.MaintenanceWindow
+	if err := database.DB.Where("name = ?", window.Name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "维护窗口名称已存在",
+		})
+		return
+	}
+
+	if err := database.DB.Create(&window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建维护窗口失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityMaintenance, window.ID, window.Name, models.AuditActionCreate, nil, window)
+	services.FillWindowState(&window, time.Now())
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "维护窗口创建成功",
+		"data":    window,
+	})
+}
+
+// UpdateMaintenanceWindow 更新维护窗口
+func UpdateMaintenanceWindow(c *gin.Context) {
+	window, ok := findMaintenanceWindow(c)
+	if !ok {
+		return
+	}
+
+	var request models.MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	previous := *window
+	if !applyMaintenanceRequest(c, window, &request) {
+		return
+	}
+
+	var count int64
+	database.DB.Model(&models.MaintenanceWindow{}).
+		Where("name = ? AND id <> ?", window.Name, window.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "维护窗口名称已存在",
+		})
+		return
+	}
+
+	if err := database.DB.Save(window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "更新维护窗口失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityMaintenance, window.ID, window.Name, models.AuditActionUpdate, previous, window)
+	services.FillWindowState(window, time.Now())
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "维护窗口更新成功",
+		"data":    window,
+	})
+}
+
+// DeleteMaintenanceWindow 删除维护窗口，已推迟的操作会在节点不再受限时执行
+func DeleteMaintenanceWindow(c *gin.Context) {
+	window, ok := findMaintenanceWindow(c)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "删除维护窗口失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityMaintenance, window.ID, window.Name, models.AuditActionDelete, window, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "维护窗口删除成功",
+	})
+}
+
+// GetDeferredActions 获取推迟的操作，默认只返回待执行的
+func GetDeferredActions(c *gin.Context) {
+	status := c.DefaultQuery("status", models.DeferredStatusPending)
+
+	query := database.DB.Order("created_at DESC")
+	if status != "all" {
+		query = query.Where("status = ?", status)
+	}
+
+	var actions []models.DeferredAction
+	query.Limit(200).Find(&actions)
+
+	var nodes []models.Node
+	database.DB.Select("id", "name").Find(&nodes)
+	nodeNames := make(map[uint]string, len(nodes))
+	for _, node := range nodes {
+		nodeNames[node.ID] = node.Name
+	}
+
+	var tasks []models.ScheduledTask
+	database.DB.Select("id", "name").Find(&tasks)
+	taskNames := make(map[uint]string, len(tasks))
+	for _, task := range tasks {
+		taskNames[task.ID] = task.Name
+	}
+
+	for i := range actions {
+		actions[i].NodeName = nodeNames[actions[i].NodeID]
+		actions[i].TaskName = taskNames[actions[i].TaskID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    actions,
+		"total":   len(actions),
+	})
+}
+
+// CancelDeferredAction 取消待执行的推迟操作
+func CancelDeferredAction(c *gin.Context) {
+	action, ok := findPendingDeferredAction(c)
+	if !ok {
+		return
+	}
+
+	database.DB.Model(action).Update("status", models.DeferredStatusCancelled)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已取消",
+	})
+}
+
+// RunDeferredAction 忽略维护窗口立即执行推迟的操作
+func RunDeferredAction(c *gin.Context) {
+	action, ok := findPendingDeferredAction(c)
+	if !ok {
+		return
+	}
+
+	err := maintenanceWorker.Execute(action)
+	maintenanceWorker.Finish(action, err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "执行失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "执行成功",
+	})
+}
+
+func findMaintenanceWindow(c *gin.Context) (*models.MaintenanceWindow, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的维护窗口ID",
+		})
+		return nil, false
+	}
+
+	var window models.MaintenanceWindow
+	if err := database.DB.First(&window, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "维护窗口不存在",
+		})
+		return nil, false
+	}
+
+	return &window, true
+}
+
+func findPendingDeferredAction(c *gin.Context) (*models.DeferredAction, bool) {
+	var action models.DeferredAction
+	if err := database.DB.First(&action, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "推迟的操作不存在",
+		})
+		return nil, false
+	}
+
+	if action.Status != models.DeferredStatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "操作已不是待执行状态",
+		})
+		return nil, false
+	}
+
+	return &action, true
+}
+
+// applyMaintenanceRequest 校验请求并写入维护窗口，校验失败时已返回响应
+func applyMaintenanceRequest(c *gin.Context, window *models.MaintenanceWindow, request *models.MaintenanceWindowRequest) bool {
+	fail := func(message string) bool {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": message,
+		})
+		return false
+	}
+
+	request.CronExpr = strings.TrimSpace(request.CronExpr)
+	if _, err := services.ParseWindowSchedule(request.CronExpr); err != nil {
+		return fail(err.Error())
+	}
+
+	if request.DurationMinutes == 0 {
+		request.DurationMinutes = 60
+	}
+	if request.DurationMinutes < 1 {
+		return fail("窗口时长至少为1分钟")
+	}
+
+	nodeIDs := "[]"
+	if len(request.NodeIDs) > 0 {
+		data, _ := json.Marshal(request.NodeIDs)
+		nodeIDs = string(data)
+	}
+
+	window.Name = strings.TrimSpace(request.Name)
+	window.Description = request.Description
+	window.CronExpr = request.CronExpr
+	window.DurationMinutes = request.DurationMinutes
+	window.NodeIDs = nodeIDs
+	window.Tag = strings.TrimSpace(request.Tag)
+	if request.Enabled != nil {
+		window.Enabled = *request.Enabled
+	}
+	return true
+}
@@ -80,6 +80,8 @@ func AddNameserver(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, models.AuditEntityNameserver, nameserver.ID, nameserver.Domain, models.AuditActionCreate, nil, nameserver)
+
 	// 同步到节点
 	go nameserverService.SyncNameserverToNodes(&nameserver)
 
@@ -111,6 +113,8 @@ func UpdateNameserver(c *gin.Context) {
 		return
 	}
 
+	before := nameserver
+
 	if err := c.ShouldBindJSON(&nameserver); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -120,6 +124,7 @@ func UpdateNameserver(c *gin.Context) {
 	}
 
 	database.DB.Save(&nameserver)
+	recordAudit(c, models.AuditEntityNameserver, nameserver.ID, nameserver.Domain, models.AuditActionUpdate, before, nameserver)
 
 	// 同步到节点
 	go nameserverService.SyncNameserverToNodes(&nameserver)
@@ -153,6 +158,7 @@ func DeleteNameserver(c *gin.Context) {
 	}
 
 	database.DB.Delete(&nameserver)
+	recordAudit(c, models.AuditEntityNameserver, nameserver.ID, nameserver.Domain, models.AuditActionDelete, nameserver, nil)
 
 	// 从节点删除
 	go nameserverService.DeleteNameserverFromNodes(&nameserver)
@@ -17,7 +17,7 @@ import (
 // GetNodes 获取所有节点
 func GetNodes(c *gin.Context) {
 	var nodes []models.Node
-	query := database.DB
+	query := scopeOrganization(c, database.DB)
 
 	if tags := c.Query("tags"); tags != "" {
 		query = query.Where("tags LIKE ?", "%"+tags+"%")
@@ -64,6 +64,28 @@ func AddNode(c *gin.Context) {
 	if node.LogPath == "" {
 		node.LogPath = "/var/log/smartdns/audit.log"
 	}
+	if !models.ValidReloadMode(node.ReloadMode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "不支持的重载方式: " + node.ReloadMode,
+		})
+		return
+	}
+	if node.ReloadMode == "" {
+		node.ReloadMode = models.ReloadModeRestart
+	}
+	if !models.ValidInstallMode(node.InstallMode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "不支持的安装方式: " + node.InstallMode,
+		})
+		return
+	}
+	node.InstalledVia = "" // 实际安装方式在初始化时记录
+	if !applyNodeCredentialSource(c, &node, &node) {
+		return
+	}
+	node.OrganizationID = requestOrganizationID(c)
 	node.Status = "unknown"
 	node.LogMonitorEnabled = false
 	node.LastCheck = time.Now()
@@ -138,6 +160,32 @@ func UpdateNode(c *gin.Context) {
 	node.ConfigPath = updateData.ConfigPath
 	node.Tags = updateData.Tags
 	node.Description = updateData.Description
+	node.Provider = updateData.Provider
+	node.Region = updateData.Region
+	node.CostCenter = updateData.CostCenter
+	if updateData.ReloadMode != "" {
+		if !models.ValidReloadMode(updateData.ReloadMode) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "不支持的重载方式: " + updateData.ReloadMode,
+			})
+			return
+		}
+		node.ReloadMode = updateData.ReloadMode
+	}
+	if updateData.InstallMode != "" {
+		if !models.ValidInstallMode(updateData.InstallMode) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "不支持的安装方式: " + updateData.InstallMode,
+			})
+			return
+		}
+		node.InstallMode = updateData.InstallMode
+	}
+	if !applyNodeCredentialSource(c, &node, &updateData) {
+		return
+	}
 
 	if err := database.DB.Save(&node).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -154,6 +202,37 @@ func UpdateNode(c *gin.Context) {
 	})
 }
 
+// applyNodeCredentialSource 校验并应用 SSH 凭据来源，使用 Vault 时清除本地保存的密码和私钥
+func applyNodeCredentialSource(c *gin.Context, node, input *models.Node) bool {
+	if !models.ValidCredentialSource(input.CredentialSource) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "不支持的凭据来源: " + input.CredentialSource,
+		})
+		return false
+	}
+
+	node.CredentialSource = input.CredentialSource
+	if node.CredentialSource == "" {
+		node.CredentialSource = models.CredentialSourceLocal
+	}
+	node.VaultSecretPath = strings.TrimSpace(input.VaultSecretPath)
+	node.VaultSSHRole = strings.TrimSpace(input.VaultSSHRole)
+
+	if node.CredentialSource == models.CredentialSourceVault {
+		if node.VaultSecretPath == "" && node.VaultSSHRole == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "使用 Vault 凭据时需要填写 SSH 签名角色或密钥路径",
+			})
+			return false
+		}
+		node.Password = ""
+		node.PrivateKey = ""
+	}
+	return true
+}
+
 // DeleteNode 删除节点
 func DeleteNode(c *gin.Context) {
 	id := c.Param("id")
@@ -270,8 +349,8 @@ func testAndUpdateNodeStatus(node *models.Node) {
 	}
 
 	// 检查 SmartDNS 服务状态
-	output, err := client.ExecuteCommand("systemctl is-active smartdns 2>&1")
-	if err != nil || strings.TrimSpace(output) != "active" {
+	running, err := client.GetServiceStatus("smartdns")
+	if err != nil || !running {
 		node.Status = "stopped" // 或 "error"
 		node.LastCheck = time.Now()
 		database.DB.Save(node)
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var patchService *services.PatchService
+
+// InitPatchHandler 初始化节点补丁检查处理器
+func InitPatchHandler(service *services.PatchService) {
+	patchService = service
+}
+
+// GetNodeFactsList 获取所有节点的补丁状态
+// GET /api/node-facts?reboot_required=true
+func GetNodeFactsList(c *gin.Context) {
+	query := scopeOrganizationNodes(c, database.DB.Model(&models.NodeFacts{}), "node_id")
+	if c.Query("reboot_required") == "true" {
+		query = query.Where("reboot_required = ?", true)
+	}
+	if c.Query("security") == "true" {
+		query = query.Where("security_updates > 0 OR kernel_updates > 0")
+	}
+
+	var facts []models.NodeFacts
+	query.Order("node_id").Find(&facts)
+	services.DecodeNodeFacts(facts)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    facts,
+	})
+}
+
+// GetNodeFacts 获取节点的补丁状态
+// GET /api/nodes/:id/facts
+func GetNodeFacts(c *gin.Context) {
+	var facts models.NodeFacts
+	if err := database.DB.Where("node_id = ?", c.Param("id")).First(&facts).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "尚未采集该节点的补丁状态",
+		})
+		return
+	}
+
+	list := []models.NodeFacts{facts}
+	services.DecodeNodeFacts(list)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list[0],
+	})
+}
+
+// RefreshNodeFacts 立即采集节点的补丁状态
+// POST /api/nodes/:id/facts/refresh
+func RefreshNodeFacts(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	facts := patchService.CollectNode(&node, models.PatchCheckConfig{})
+	if facts.Error != "" {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "补丁检查失败",
+			"error":   facts.Error,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    facts,
+	})
+}
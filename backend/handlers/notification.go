@@ -3,6 +3,8 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -18,7 +20,7 @@ var notificationService = services.NewNotificationService()
 func GetNotificationChannels(c *gin.Context) {
 	nodeID := c.Query("node_id")
 
-	query := database.DB.Model(&models.NotificationChannel{})
+	query := scopeOrganization(c, database.DB.Model(&models.NotificationChannel{}))
 
 	if nodeID != "" {
 		query = query.Where("node_id = ? OR node_id = 0", nodeID)
@@ -44,6 +46,10 @@ func AddNotificationChannel(c *gin.Context) {
 		})
 		return
 	}
+	if channel.NodeID > 0 && !checkOrganizationNodes(c, []uint{channel.NodeID}) {
+		return
+	}
+	channel.OrganizationID = requestOrganizationID(c)
 
 	if err := database.DB.Create(&channel).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -89,6 +95,7 @@ func UpdateNotificationChannel(c *gin.Context) {
 		return
 	}
 
+	orgID := channel.OrganizationID
 	if err := c.ShouldBindJSON(&channel); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -96,6 +103,11 @@ func UpdateNotificationChannel(c *gin.Context) {
 		})
 		return
 	}
+	// 所属组织只能通过分配接口修改
+	channel.OrganizationID = orgID
+	if channel.NodeID > 0 && !checkOrganizationNodes(c, []uint{channel.NodeID}) {
+		return
+	}
 
 	if err := database.DB.Save(&channel).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -191,37 +203,176 @@ func TestNotificationChannel(c *gin.Context) {
 }
 
 // GetNotificationLogs 获取通知日志
+// 支持按渠道、节点、事件类型、状态、时间范围过滤，keyword 对标题/内容/错误做模糊搜索
 func GetNotificationLogs(c *gin.Context) {
-	nodeID := c.Query("node_id")
-	channelID := c.Query("channel_id")
-	status := c.Query("status")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
 
-	query := database.DB.Model(&models.NotificationLog{})
+	query := filterNotificationLogs(c, database.DB.Model(&models.NotificationLog{}))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if keyword := strings.TrimSpace(c.Query("keyword")); keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("title LIKE ? OR content LIKE ? OR error LIKE ?", like, like, like)
+	}
 
-	if nodeID != "" {
+	var total int64
+	query.Count(&total)
+
+	var logs []models.NotificationLog
+	offset := (page - 1) * pageSize
+	query.Order("created_at desc").Offset(offset).Limit(pageSize).Find(&logs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      logs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetNotificationStats 按渠道统计通知投递成功率
+func GetNotificationStats(c *gin.Context) {
+	var rows []struct {
+		ChannelID  uint
+		Total      int64
+		Success    int64
+		LastSentAt string
+	}
+	filterNotificationLogs(c, database.DB.Model(&models.NotificationLog{})).
+		Select("channel_id, COUNT(*) AS total, SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END) AS success, MAX(sent_at) AS last_sent_at").
+		Group("channel_id").
+		Scan(&rows)
+
+	var channels []models.NotificationChannel
+	database.DB.Find(&channels)
+	channelNames := make(map[uint]string, len(channels))
+	for _, channel := range channels {
+		channelNames[channel.ID] = channel.Name
+	}
+
+	stats := make([]models.NotificationChannelStats, 0, len(rows))
+	for _, row := range rows {
+		item := models.NotificationChannelStats{
+			ChannelID:   row.ChannelID,
+			ChannelName: channelNames[row.ChannelID],
+			Total:       row.Total,
+			Success:     row.Success,
+			Failed:      row.Total - row.Success,
+			LastSentAt:  row.LastSentAt,
+		}
+		if row.Total > 0 {
+			item.SuccessRate = float64(row.Success) / float64(row.Total) * 100
+		}
+		stats = append(stats, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// filterNotificationLogs 应用通知日志的通用过滤条件
+func filterNotificationLogs(c *gin.Context, query *gorm.DB) *gorm.DB {
+	if nodeID := c.Query("node_id"); nodeID != "" {
 		query = query.Where("node_id = ?", nodeID)
 	}
-	if channelID != "" {
+	if channelID := c.Query("channel_id"); channelID != "" {
 		query = query.Where("channel_id = ?", channelID)
 	}
-	if status != "" {
-		query = query.Where("status = ?", status)
+	if eventType := c.Query("event_type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if startTime, ok := parseNotificationTime(c.Query("start_time")); ok {
+		query = query.Where("sent_at >= ?", startTime)
+	}
+	if endTime, ok := parseNotificationTime(c.Query("end_time")); ok {
+		query = query.Where("sent_at <= ?", endTime)
+	}
+	if orgID := requestOrganizationID(c); orgID > 0 {
+		query = query.Where("channel_id IN (?)",
+			database.DB.Model(&models.NotificationChannel{}).Select("id").Where("organization_id = ?", orgID))
+	}
+	return query
+}
+
+// parseNotificationTime 解析时间参数，支持多种格式
+func parseNotificationTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	formats := []string{
+		time.RFC3339,
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+	for _, format := range formats {
+		if t, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// GetNotificationDeadLetters 获取投递失败的死信记录
+func GetNotificationDeadLetters(c *gin.Context) {
+	channelID := c.Query("channel_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+
+	query := database.DB.Model(&models.NotificationDelivery{}).Where("status = ?", models.DeliveryStatusDead)
+	if channelID != "" {
+		query = query.Where("channel_id = ?", channelID)
 	}
 
 	var total int64
 	query.Count(&total)
 
-	var logs []models.NotificationLog
+	var deliveries []models.NotificationDelivery
 	offset := (page - 1) * pageSize
-	query.Order("created_at desc").Offset(offset).Limit(pageSize).Find(&logs)
+	query.Order("updated_at desc").Offset(offset).Limit(pageSize).Find(&deliveries)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":   true,
-		"data":      logs,
+		"data":      deliveries,
 		"total":     total,
 		"page":      page,
 		"page_size": pageSize,
 	})
 }
+
+// RetryNotificationDelivery 重新投递死信
+func RetryNotificationDelivery(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的记录 ID",
+		})
+		return
+	}
+
+	if err := notificationService.RetryDelivery(uint(id)); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "死信记录不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "重新投递失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已重新加入投递队列",
+	})
+}
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/docs"
+)
+
+// GetOpenAPISpec 返回 OpenAPI 3 接口文档，可用于生成客户端 SDK
+// GET /api/v1/openapi.json
+func GetOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", docs.OpenAPI)
+}
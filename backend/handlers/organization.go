@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// organizationResources 可分配到组织的资源
+var organizationResources = map[string]interface{}{
+	"node":                 &models.Node{},
+	"domain_rule":          &models.DomainRule{},
+	"address":              &models.AddressMap{},
+	"notification_channel": &models.NotificationChannel{},
+	"scheduled_task":       &models.ScheduledTask{},
+	"user":                 &models.User{},
+}
+
+// GetOrganizations 获取组织列表，绑定了组织的用户只返回本组织
+// GET /api/organizations
+func GetOrganizations(c *gin.Context) {
+	query := database.DB.Order("name")
+	if !c.GetBool("super_admin") {
+		query = query.Where("id = ?", requestOrganizationID(c))
+	}
+	var orgs []models.Organization
+	query.Find(&orgs)
+	fillOrganizationCounts(orgs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    orgs,
+		"total":   len(orgs),
+	})
+}
+
+// GetCurrentOrganization 当前请求所在的组织，超级管理员同时返回可切换的组织列表
+// GET /api/organizations/current
+func GetCurrentOrganization(c *gin.Context) {
+	superAdmin := c.GetBool("super_admin")
+	data := gin.H{
+		"organization_id": requestOrganizationID(c),
+		"super_admin":     superAdmin,
+		"header":          models.OrganizationHeader,
+	}
+	if orgID := requestOrganizationID(c); orgID > 0 {
+		var org models.Organization
+		if err := database.DB.First(&org, orgID).Error; err == nil {
+			data["organization"] = org
+		}
+	}
+	if superAdmin {
+		var orgs []models.Organization
+		database.DB.Order("name").Find(&orgs)
+		data["organizations"] = orgs
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// CreateOrganization 创建组织
+// POST /api/organizations
+func CreateOrganization(c *gin.Context) {
+	var request models.OrganizationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	org := models.Organization{Name: strings.TrimSpace(request.Name), Description: request.Description}
+	if !checkOrganizationName(c, org.Name, 0) {
+		return
+	}
+	if err := database.DB.Create(&org).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "创建组织失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "组织已创建",
+		"data":    org,
+	})
+}
+
+// UpdateOrganization 修改组织名称和描述
+// PUT /api/organizations/:id
+func UpdateOrganization(c *gin.Context) {
+	org, ok := loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	var request models.OrganizationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	org.Name = strings.TrimSpace(request.Name)
+	org.Description = request.Description
+	if !checkOrganizationName(c, org.Name, org.ID) {
+		return
+	}
+	if err := database.DB.Save(org).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "更新组织失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "组织已更新",
+		"data":    org,
+	})
+}
+
+// DeleteOrganization 删除组织，组织下仍有资源或成员时拒绝删除
+// DELETE /api/organizations/:id
+func DeleteOrganization(c *gin.Context) {
+	org, ok := loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	inUse := make(map[string]int64)
+	for name, model := range organizationResources {
+		var count int64
+		database.DB.Model(model).Where("organization_id = ?", org.ID).Count(&count)
+		if count > 0 {
+			inUse[name] = count
+		}
+	}
+	if len(inUse) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "组织下仍有资源或成员，请先移出后再删除",
+			"data":    inUse,
+		})
+		return
+	}
+
+	database.DB.Delete(org)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已删除",
+	})
+}
+
+// AssignOrganization 将节点、规则、通知渠道、定时任务或用户分配到组织，组织 ID 为 0 时取消分配
+// POST /api/organizations/:id/assign
+func AssignOrganization(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的组织ID"})
+		return
+	}
+	if id > 0 {
+		if _, ok := loadOrganization(c); !ok {
+			return
+		}
+	}
+
+	var request models.OrganizationAssignRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+	model, ok := organizationResources[request.Resource]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "不支持的资源类型: " + request.Resource})
+		return
+	}
+	if request.Resource == "user" {
+		// 不能把自己移入组织，避免超级管理员失去管理组织的权限
+		for _, userID := range request.IDs {
+			if userID == c.GetUint("user_id") {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "不能修改自己所属的组织"})
+				return
+			}
+		}
+	}
+
+	result := database.DB.Model(model).Where("id IN ?", request.IDs).Update("organization_id", uint(id))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "分配失败: " + result.Error.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分配完成",
+		"updated": result.RowsAffected,
+	})
+}
+
+// requestOrganizationID 当前请求的组织范围，0 表示不限制（超级管理员未切换组织）
+func requestOrganizationID(c *gin.Context) uint {
+	return c.GetUint("organization_id")
+}
+
+// scopeOrganization 按当前请求的组织范围过滤查询
+func scopeOrganization(c *gin.Context, query *gorm.DB) *gorm.DB {
+	if orgID := requestOrganizationID(c); orgID > 0 {
+		return query.Where("organization_id = ?", orgID)
+	}
+	return query
+}
+
+// organizationNodes 当前组织的节点 ID 子查询
+func organizationNodes(c *gin.Context) *gorm.DB {
+	return scopeOrganization(c, database.DB.Model(&models.Node{}).Select("id"))
+}
+
+// scopeOrganizationNodes 按记录关联节点的组织过滤没有组织列的查询，column 为记录中的节点列。
+// 未关联节点（跨节点汇总）的记录只有超级管理员可见
+func scopeOrganizationNodes(c *gin.Context, query *gorm.DB, column string) *gorm.DB {
+	if requestOrganizationID(c) > 0 {
+		return query.Where(column+" IN (?)", organizationNodes(c))
+	}
+	return query
+}
+
+// scopeOrganizationNodeSet 过滤由子表记录多个节点的查询（如同步任务），只保留节点全部属于当前组织的记录
+func scopeOrganizationNodeSet(c *gin.Context, query *gorm.DB, nodeModel interface{}, keyColumn string) *gorm.DB {
+	if requestOrganizationID(c) == 0 {
+		return query
+	}
+	return query.
+		Where("id IN (?)", database.DB.Model(nodeModel).Select(keyColumn)).
+		Where("id NOT IN (?)", database.DB.Model(nodeModel).Select(keyColumn).Where("node_id NOT IN (?)", organizationNodes(c)))
+}
+
+// organizationNodeIDs 当前组织的节点 ID 列表，scoped 为 false 表示不限制
+func organizationNodeIDs(c *gin.Context) (nodeIDs []uint, scoped bool) {
+	if requestOrganizationID(c) == 0 {
+		return nil, false
+	}
+	organizationNodes(c).Pluck("id", &nodeIDs)
+	return nodeIDs, true
+}
+
+// checkOrganizationNodes 校验节点都属于当前组织，失败时已写入响应
+func checkOrganizationNodes(c *gin.Context, nodeIDs []uint) bool {
+	orgID := requestOrganizationID(c)
+	if orgID == 0 || len(nodeIDs) == 0 {
+		return true
+	}
+	var count int64
+	database.DB.Model(&models.Node{}).Where("id IN ? AND organization_id <> ?", nodeIDs, orgID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return false
+	}
+	return true
+}
+
+// checkOrganizationLogNode 组织范围内查询日志和分析数据必须指定本组织的节点，失败时已写入响应
+func checkOrganizationLogNode(c *gin.Context, nodeID uint) bool {
+	if requestOrganizationID(c) == 0 {
+		return true
+	}
+	if nodeID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请指定本组织的节点（node_id）",
+		})
+		return false
+	}
+	return checkOrganizationNodes(c, []uint{nodeID})
+}
+
+// checkOrganizationNodeList 同 checkOrganizationNodes，节点列表为 JSON 数组字符串
+func checkOrganizationNodeList(c *gin.Context, nodeIDsJSON string) bool {
+	var nodeIDs []uint
+	if nodeIDsJSON != "" {
+		json.Unmarshal([]byte(nodeIDsJSON), &nodeIDs)
+	}
+	return checkOrganizationNodes(c, nodeIDs)
+}
+
+// loadOrganization 按路径参数读取组织，失败时已写入响应
+func loadOrganization(c *gin.Context) (*models.Organization, bool) {
+	var org models.Organization
+	if err := database.DB.First(&org, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "组织不存在"})
+		return nil, false
+	}
+	return &org, true
+}
+
+// checkOrganizationName 校验组织名称非空且不重复，失败时已写入响应
+func checkOrganizationName(c *gin.Context, name string, excludeID uint) bool {
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "组织名称不能为空"})
+		return false
+	}
+	var count int64
+	database.DB.Model(&models.Organization{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": "组织名称已存在"})
+		return false
+	}
+	return true
+}
+
+// fillOrganizationCounts 填充各组织的节点数和成员数
+func fillOrganizationCounts(orgs []models.Organization) {
+	for i := range orgs {
+		database.DB.Model(&models.Node{}).Where("organization_id = ?", orgs[i].ID).Count(&orgs[i].NodeCount)
+		database.DB.Model(&models.User{}).Where("organization_id = ?", orgs[i].ID).Count(&orgs[i].MemberCount)
+	}
+}
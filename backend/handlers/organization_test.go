@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"smartdns-manager/database"
+	"smartdns-manager/middleware"
+	"smartdns-manager/models"
+)
+
+// organizationFixture 两个组织各一个节点和管理员，以及一个超级管理员
+type organizationFixture struct {
+	ownNode, otherNode models.Node
+	orgAdmin, super    models.User
+}
+
+// setupOrganizationTest 使用内存 SQLite 替换 database.DB，并为两个组织的节点各写入一组关联记录
+func setupOrganizationTest(t *testing.T) *organizationFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(
+		&models.Organization{}, &models.User{}, &models.Node{},
+		&models.ConfigDriftReport{}, &models.NodeConfigSnapshot{}, &models.NodeFacts{},
+		&models.ConfigSyncLog{}, &models.SyncJob{}, &models.SyncJobNode{},
+		&models.SecurityFinding{}, &models.BackgroundJob{}, &models.Alert{},
+		&models.LogShareLink{}, &models.AddressMap{}, &models.DomainRule{},
+		&models.DNSServer{}, &models.DomainSet{},
+	); err != nil {
+		t.Fatalf("迁移测试数据库失败: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+	})
+
+	orgs := []models.Organization{{Name: "org-a"}, {Name: "org-b"}}
+	db.Create(&orgs)
+
+	f := &organizationFixture{
+		ownNode:   models.Node{Name: "node-b", Host: "10.0.0.2", Username: "root", OrganizationID: orgs[1].ID},
+		otherNode: models.Node{Name: "node-a", Host: "10.0.0.1", Username: "root", OrganizationID: orgs[0].ID},
+		orgAdmin:  models.User{Username: "admin-b", Password: "x", Email: "b@example.com", Role: "admin", OrganizationID: orgs[1].ID},
+		super:     models.User{Username: "root", Password: "x", Email: "root@example.com", Role: "admin"},
+	}
+	db.Create(&f.otherNode)
+	db.Create(&f.ownNode)
+	db.Create(&f.orgAdmin)
+	db.Create(&f.super)
+
+	for _, node := range []models.Node{f.otherNode, f.ownNode} {
+		db.Create(&models.ConfigDriftReport{NodeID: node.ID, NodeName: node.Name, HasDrift: true})
+		db.Create(&models.NodeConfigSnapshot{NodeID: node.ID, NodeName: node.Name})
+		db.Create(&models.NodeFacts{NodeID: node.ID, NodeName: node.Name})
+		db.Create(&models.ConfigSyncLog{NodeID: node.ID, Status: "failed", Type: "full_sync"})
+		db.Create(&models.SecurityFinding{NodeID: node.ID, Type: "dga"})
+		db.Create(&models.BackgroundJob{NodeID: node.ID, Type: "node_init"})
+		db.Create(&models.Alert{NodeID: node.ID, NodeName: node.Name, Status: "firing"})
+		db.Create(&models.AddressMap{Domain: node.Name + ".example.com", IP: "1.1.1.1", OrganizationID: node.OrganizationID})
+		db.Create(&models.LogShareLink{Kind: models.ShareKindLogs, OrganizationID: node.OrganizationID})
+
+		job := models.SyncJob{Type: "full_sync", TotalNodes: 1}
+		db.Create(&job)
+		db.Create(&models.SyncJobNode{JobID: job.ID, NodeID: node.ID, NodeName: node.Name})
+	}
+	// 汇总评估的告警和同时涉及两个组织的同步任务只有超级管理员可见
+	db.Create(&models.Alert{RuleName: "fleet", Status: "firing"})
+	mixed := models.SyncJob{Type: "full_sync", TotalNodes: 2}
+	db.Create(&mixed)
+	db.Create(&models.SyncJobNode{JobID: mixed.ID, NodeID: f.ownNode.ID})
+	db.Create(&models.SyncJobNode{JobID: mixed.ID, NodeID: f.otherNode.ID})
+
+	return f
+}
+
+// organizationTestRouter 注册待测路由，认证中间件由直接写入用户 ID 代替
+func organizationTestRouter(user models.User) *gin.Engine {
+	router := gin.New()
+	api := router.Group("/api")
+	api.Use(func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+	}, middleware.OrganizationScope())
+
+	api.GET("/drift-reports", GetDriftReports)
+	api.GET("/drift-reports/:id", GetDriftReport)
+	api.GET("/config-snapshots/:id", GetConfigSnapshot)
+	api.GET("/node-facts", GetNodeFactsList)
+	api.GET("/sync/logs", GetSyncLogs)
+	api.GET("/sync/stats", GetSyncStats)
+	api.POST("/sync/logs/:id/retry", RetrySyncLog)
+	api.GET("/sync/jobs", GetSyncJobs)
+	api.GET("/sync/jobs/:id", GetSyncJob)
+	api.GET("/security/findings", GetSecurityFindings)
+	api.POST("/security/findings/:id/ack", AcknowledgeSecurityFinding)
+	api.GET("/jobs", GetBackgroundJobs)
+	api.GET("/jobs/:id", GetBackgroundJob)
+	api.GET("/alerts", GetAlerts)
+	api.POST("/alerts/:id/ack", AcknowledgeAlert)
+	api.GET("/dashboard/stats", GetDashboardStats)
+	api.POST("/share-links", CreateShareLink)
+	api.GET("/share-links", GetShareLinks)
+	api.DELETE("/share-links/:id", RevokeShareLink)
+	return router
+}
+
+// doOrganizationRequest 发起请求并解析响应
+func doOrganizationRequest(t *testing.T, router *gin.Engine, method, path string) (int, map[string]json.RawMessage) {
+	return doOrganizationRequestBody(t, router, method, path, nil)
+}
+
+// doOrganizationRequestBody 发起带请求体的请求并解析响应
+func doOrganizationRequestBody(t *testing.T, router *gin.Engine, method, path string, payload io.Reader) (int, map[string]json.RawMessage) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, path, payload))
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s 响应不是 JSON: %s", method, path, recorder.Body.String())
+	}
+	return recorder.Code, body
+}
+
+// responseRows 解析列表响应的 data 字段
+func responseRows(t *testing.T, body map[string]json.RawMessage, key string) []map[string]interface{} {
+	t.Helper()
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(body[key], &rows); err != nil {
+		t.Fatalf("解析 %s 失败: %v", key, err)
+	}
+	return rows
+}
+
+func TestOrganizationListsHideOtherOrganizations(t *testing.T) {
+	f := setupOrganizationTest(t)
+	router := organizationTestRouter(f.orgAdmin)
+
+	cases := []struct {
+		path   string
+		key    string
+		column string
+		want   float64
+	}{
+		{"/api/drift-reports", "data", "node_id", float64(f.ownNode.ID)},
+		{"/api/node-facts", "data", "node_id", float64(f.ownNode.ID)},
+		{"/api/sync/logs", "data", "node_id", float64(f.ownNode.ID)},
+		{"/api/sync/stats", "recent_logs", "node_id", float64(f.ownNode.ID)},
+		{"/api/security/findings", "data", "node_id", float64(f.ownNode.ID)},
+		{"/api/jobs", "data", "node_id", float64(f.ownNode.ID)},
+		{"/api/alerts", "data", "node_id", float64(f.ownNode.ID)},
+		{"/api/share-links", "data", "organization_id", float64(f.ownNode.OrganizationID)},
+	}
+	for _, tc := range cases {
+		code, body := doOrganizationRequest(t, router, http.MethodGet, tc.path)
+		if code != http.StatusOK {
+			t.Fatalf("GET %s 状态码 %d", tc.path, code)
+		}
+		rows := responseRows(t, body, tc.key)
+		if len(rows) != 1 {
+			t.Errorf("GET %s 返回 %d 条记录，期望只有本组织的 1 条", tc.path, len(rows))
+		}
+		for _, row := range rows {
+			if row[tc.column] != tc.want {
+				t.Errorf("GET %s 返回了其他组织的记录: %s=%v", tc.path, tc.column, row[tc.column])
+			}
+		}
+	}
+
+	code, body := doOrganizationRequest(t, router, http.MethodGet, "/api/sync/jobs")
+	if code != http.StatusOK {
+		t.Fatalf("GET /api/sync/jobs 状态码 %d", code)
+	}
+	if jobs := responseRows(t, body, "data"); len(jobs) != 1 || jobs[0]["id"] != float64(2) {
+		t.Errorf("同步任务应只包含本组织节点的任务 2，实际 %v", jobs)
+	}
+
+	_, body = doOrganizationRequest(t, router, http.MethodGet, "/api/dashboard/stats")
+	var stats map[string]interface{}
+	json.Unmarshal(body["data"], &stats)
+	if stats["total_nodes"] != float64(1) || stats["total_addresses"] != float64(1) {
+		t.Errorf("仪表板应只统计本组织: nodes=%v addresses=%v", stats["total_nodes"], stats["total_addresses"])
+	}
+	if _, ok := stats["total_servers"]; ok {
+		t.Errorf("组织内不应返回全局上游服务器统计")
+	}
+}
+
+func TestOrganizationDetailsRejectOtherOrganizations(t *testing.T) {
+	f := setupOrganizationTest(t)
+	router := organizationTestRouter(f.orgAdmin)
+
+	// 第一个节点属于其他组织，其关联记录的 ID 均为 1；ID 为 3 的同步任务同时涉及两个组织
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/drift-reports/1"},
+		{http.MethodGet, "/api/config-snapshots/1"},
+		{http.MethodPost, "/api/sync/logs/1/retry"},
+		{http.MethodGet, "/api/sync/jobs/1"},
+		{http.MethodGet, "/api/sync/jobs/3"},
+		{http.MethodPost, "/api/security/findings/1/ack"},
+		{http.MethodGet, "/api/jobs/1"},
+		{http.MethodPost, "/api/alerts/1/ack"},
+		{http.MethodPost, "/api/alerts/3/ack"},
+		{http.MethodDelete, "/api/share-links/1"},
+	}
+	for _, r := range requests {
+		if code, _ := doOrganizationRequest(t, router, r.method, r.path); code != http.StatusNotFound {
+			t.Errorf("%s %s 状态码 %d，期望 404", r.method, r.path, code)
+		}
+	}
+
+	if code, _ := doOrganizationRequest(t, router, http.MethodGet, "/api/drift-reports/2"); code != http.StatusOK {
+		t.Errorf("本组织的漂移报告应可访问，状态码 %d", code)
+	}
+	var revoked bool
+	database.DB.Model(&models.LogShareLink{}).Where("id = ?", 1).Pluck("revoked", &revoked)
+	if revoked {
+		t.Errorf("其他组织的分享链接不应被撤销")
+	}
+}
+
+func TestOrganizationSuperAdminSeesAll(t *testing.T) {
+	f := setupOrganizationTest(t)
+	router := organizationTestRouter(f.super)
+
+	_, body := doOrganizationRequest(t, router, http.MethodGet, "/api/alerts")
+	if alerts := responseRows(t, body, "data"); len(alerts) != 3 {
+		t.Errorf("超级管理员应看到全部 3 条告警，实际 %d", len(alerts))
+	}
+	_, body = doOrganizationRequest(t, router, http.MethodGet, "/api/sync/jobs")
+	if jobs := responseRows(t, body, "data"); len(jobs) != 3 {
+		t.Errorf("超级管理员应看到全部 3 个同步任务，实际 %d", len(jobs))
+	}
+}
+
+func TestOrganizationShareLinkRequiresOwnNode(t *testing.T) {
+	f := setupOrganizationTest(t)
+	router := organizationTestRouter(f.orgAdmin)
+
+	requests := map[string]int{
+		`{"kind":"logs"}`: http.StatusBadRequest,
+		fmt.Sprintf(`{"kind":"logs","filters":{"node_id":"%d"}}`, f.otherNode.ID): http.StatusNotFound,
+		fmt.Sprintf(`{"kind":"logs","filters":{"node_id":"%d"}}`, f.ownNode.ID):   http.StatusOK,
+	}
+	for payload, want := range requests {
+		code, _ := doOrganizationRequestBody(t, router, http.MethodPost, "/api/share-links", strings.NewReader(payload))
+		if code != want {
+			t.Errorf("创建分享链接 %s 状态码 %d，期望 %d", payload, code, want)
+		}
+	}
+
+	var link models.LogShareLink
+	database.DB.Order("id desc").First(&link)
+	if link.OrganizationID != f.ownNode.OrganizationID {
+		t.Errorf("分享链接应记录创建者的组织，实际 %d", link.OrganizationID)
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// GetSavedSearches 获取自己创建的和共享的保存搜索
+// GET /api/dns-logs/saved-searches
+func GetSavedSearches(c *gin.Context) {
+	actor := auditActor(c)
+	var searches []models.SavedSearch
+	database.DB.Where("user_id = ? OR shared = ?", actor.UserID, true).Order("name").Find(&searches)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    searches,
+	})
+}
+
+// GetSavedSearch 获取保存搜索及展开后的查询参数
+// GET /api/dns-logs/saved-searches/:id
+func GetSavedSearch(c *gin.Context) {
+	search, ok := loadSavedSearchParam(c)
+	if !ok {
+		return
+	}
+	params, err := services.SavedSearchParams(search, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    search,
+		"params":  params,
+	})
+}
+
+// CreateSavedSearch 保存搜索条件
+// POST /api/dns-logs/saved-searches
+func CreateSavedSearch(c *gin.Context) {
+	var request models.SavedSearchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	actor := auditActor(c)
+	search := models.SavedSearch{UserID: actor.UserID, CreatedBy: actor.Username}
+	if err := services.BuildSavedSearch(&search, &request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err := database.DB.Create(&search).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "保存失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "搜索已保存",
+		"data":    search,
+	})
+}
+
+// UpdateSavedSearch 修改保存搜索，只能修改自己创建的
+// PUT /api/dns-logs/saved-searches/:id
+func UpdateSavedSearch(c *gin.Context) {
+	search, ok := loadOwnSavedSearch(c)
+	if !ok {
+		return
+	}
+
+	var request models.SavedSearchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err := services.BuildSavedSearch(search, &request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err := database.DB.Save(search).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "保存失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "搜索已更新",
+		"data":    search,
+	})
+}
+
+// DeleteSavedSearch 删除保存搜索，只能删除自己创建的
+// DELETE /api/dns-logs/saved-searches/:id
+func DeleteSavedSearch(c *gin.Context) {
+	search, ok := loadOwnSavedSearch(c)
+	if !ok {
+		return
+	}
+	database.DB.Delete(search)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已删除",
+	})
+}
+
+// savedSearchQuery 返回日志查询参数的读取函数：请求中指定了 saved_search_id 时，
+// 请求参数优先，未指定的参数使用保存搜索中的值
+func savedSearchQuery(c *gin.Context) (func(string) string, error) {
+	idStr := c.Query("saved_search_id")
+	if idStr == "" {
+		return c.Query, nil
+	}
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 saved_search_id")
+	}
+	search, err := services.LoadSavedSearch(uint(id), auditActor(c).UserID)
+	if err != nil {
+		return nil, err
+	}
+	params, err := services.SavedSearchParams(search, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return func(key string) string {
+		if value := c.Query(key); value != "" {
+			return value
+		}
+		return params[key]
+	}, nil
+}
+
+// loadSavedSearchParam 按路径参数读取当前用户可见的保存搜索，失败时已写入响应
+func loadSavedSearchParam(c *gin.Context) (*models.SavedSearch, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的ID"})
+		return nil, false
+	}
+	search, err := services.LoadSavedSearch(uint(id), auditActor(c).UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": err.Error()})
+		return nil, false
+	}
+	return search, true
+}
+
+// loadOwnSavedSearch 读取当前用户创建的保存搜索，共享给自己的不能修改
+func loadOwnSavedSearch(c *gin.Context) (*models.SavedSearch, bool) {
+	search, ok := loadSavedSearchParam(c)
+	if !ok {
+		return nil, false
+	}
+	if search.UserID != auditActor(c).UserID {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "只能修改自己创建的搜索"})
+		return nil, false
+	}
+	return search, true
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"smartdns-manager/models"
@@ -33,7 +34,7 @@ func (h *SchedulerHandler) GetTasks(c *gin.Context) {
 
 	offset := (page - 1) * pageSize
 
-	tasks, total, err := h.schedulerService.GetTasks(offset, pageSize, taskType, status)
+	tasks, total, err := h.schedulerService.GetTasks(offset, pageSize, taskType, status, requestOrganizationID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -67,12 +68,7 @@ func (h *SchedulerHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
-	// 验证cron表达式
-	if req.CronExpr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    400,
-			"message": "Cron表达式不能为空",
-		})
+	if !validateTaskSchedule(c, &req) || !validateTaskConfig(c, &req) || !applyTaskOrganization(c, &req, requestOrganizationID(c)) {
 		return
 	}
 
@@ -110,6 +106,20 @@ func (h *SchedulerHandler) UpdateTask(c *gin.Context) {
 
 	req.ID = uint(taskID)
 
+	// 所属组织只能通过分配接口修改
+	existing, err := h.schedulerService.GetTask(uint(taskID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "任务不存在",
+		})
+		return
+	}
+
+	if !validateTaskSchedule(c, &req) || !validateTaskConfig(c, &req) || !applyTaskOrganization(c, &req, existing.OrganizationID) {
+		return
+	}
+
 	// 更新任务
 	if err := h.schedulerService.UpdateTask(&req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -128,6 +138,83 @@ func (h *SchedulerHandler) UpdateTask(c *gin.Context) {
 	})
 }
 
+// applyTaskOrganization 设置任务所属组织，组织的任务只能是按节点执行的类型，且指定的节点须属于该组织
+func applyTaskOrganization(c *gin.Context, task *models.ScheduledTask, orgID uint) bool {
+	task.OrganizationID = orgID
+	if orgID == 0 {
+		return true
+	}
+	if !models.IsOrganizationTaskType(task.Type) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "该任务类型作用于整个系统，只能由超级管理员创建",
+		})
+		return false
+	}
+
+	var config struct {
+		NodeIDs []uint `json:"node_ids"`
+	}
+	if task.Config != "" {
+		json.Unmarshal([]byte(task.Config), &config)
+	}
+	return checkOrganizationNodes(c, config.NodeIDs)
+}
+
+// validateTaskSchedule 校验执行方式：Cron 表达式和一次性执行时间至少填写一个，超时不能为负数
+func validateTaskSchedule(c *gin.Context, task *models.ScheduledTask) bool {
+	task.CronExpr = strings.TrimSpace(task.CronExpr)
+	if task.CronExpr == "" && task.RunAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Cron表达式和执行时间不能同时为空",
+		})
+		return false
+	}
+	if task.RunAt == nil {
+		if _, err := services.NextCronRuns(task.CronExpr, 1); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Cron表达式无效（格式为 秒 分 时 日 月 周）",
+				"error":   err.Error(),
+			})
+			return false
+		}
+	}
+	if task.TimeoutSeconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "最长执行时间不能为负数",
+		})
+		return false
+	}
+	return true
+}
+
+// validateTaskConfig 保存前校验任务配置中会拼接进远程命令的字段，目前只有自定义脚本的环境变量名
+func validateTaskConfig(c *gin.Context, task *models.ScheduledTask) bool {
+	if task.Type != models.TaskTypeCustomScript || task.Config == "" {
+		return true
+	}
+	var config models.CustomScriptConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "任务配置格式错误",
+			"error":   err.Error(),
+		})
+		return false
+	}
+	if err := services.ValidateScriptEnvVars(config.EnvVars); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
 // DeleteTask 删除任务
 func (h *SchedulerHandler) DeleteTask(c *gin.Context) {
 	taskID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -273,13 +360,25 @@ func (h *SchedulerHandler) ToggleTask(c *gin.Context) {
 	})
 }
 
+// GetQuickTaskPresets 获取快速任务模板
+func (h *SchedulerHandler) GetQuickTaskPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"data":    h.schedulerService.GetQuickTaskPresets(),
+		"success": true,
+	})
+}
+
 // CreateQuickTask 创建快速任务（预定义模板）
+// 指定 preset 时由服务端根据 params 生成配置；否则按 type/config 直接创建
 func (h *SchedulerHandler) CreateQuickTask(c *gin.Context) {
 	var req struct {
-		Type   string          `json:"type" binding:"required"`
-		Name   string          `json:"name" binding:"required"`
-		Cron   string          `json:"cron" binding:"required"`
-		Config json.RawMessage `json:"config"`
+		Preset string                 `json:"preset"`
+		Params map[string]interface{} `json:"params"`
+		Type   string                 `json:"type"`
+		Name   string                 `json:"name"`
+		Cron   string                 `json:"cron"`
+		Config json.RawMessage        `json:"config"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -291,17 +390,44 @@ func (h *SchedulerHandler) CreateQuickTask(c *gin.Context) {
 		return
 	}
 
-	task := models.ScheduledTask{
-		Name:        req.Name,
-		Type:        models.TaskType(req.Type),
-		CronExpr:    req.Cron,
-		Config:      string(req.Config),
-		Enabled:     true,
-		Description: "快速创建的任务",
+	var task *models.ScheduledTask
+	if req.Preset != "" {
+		built, err := h.schedulerService.BuildQuickTask(req.Preset, req.Name, req.Cron, req.Params)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return
+		}
+		task = built
+	} else {
+		if req.Type == "" || req.Name == "" || req.Cron == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "请求参数错误",
+				"error":   "type、name、cron 不能为空",
+			})
+			return
+		}
+		task = &models.ScheduledTask{
+			Name:        req.Name,
+			Type:        models.TaskType(req.Type),
+			CronExpr:    req.Cron,
+			Config:      string(req.Config),
+			Enabled:     true,
+			Description: "快速创建的任务",
+		}
+		if !validateTaskSchedule(c, task) {
+			return
+		}
+	}
+	if !validateTaskConfig(c, task) || !applyTaskOrganization(c, task, requestOrganizationID(c)) {
+		return
 	}
 
 	// 创建任务
-	if err := h.schedulerService.CreateTask(&task); err != nil {
+	if err := h.schedulerService.CreateTask(task); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "创建任务失败",
@@ -318,6 +444,48 @@ func (h *SchedulerHandler) CreateQuickTask(c *gin.Context) {
 	})
 }
 
+// ValidateCron 校验 Cron 表达式并预览之后 5 次执行时间（服务器时区）
+// GET /api/scheduler/validate-cron?expr=0 0 3 * * *
+// 表达式无效时仍返回 200，由 valid 和 error 说明原因，便于编辑时实时校验
+func (h *SchedulerHandler) ValidateCron(c *gin.Context) {
+	expr := strings.TrimSpace(c.Query("expr"))
+	if expr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "Cron表达式不能为空",
+		})
+		return
+	}
+
+	runs, err := services.NextCronRuns(expr, 5)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
+			"data": gin.H{
+				"expr":  expr,
+				"valid": false,
+				"error": "Cron表达式无效（格式为 秒 分 时 日 月 周）: " + err.Error(),
+			},
+			"success": true,
+		})
+		return
+	}
+
+	zone, offset := time.Now().Zone()
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": gin.H{
+			"expr":      expr,
+			"valid":     true,
+			"next_runs": runs,
+			"timezone":  time.Local.String(),
+			"zone":      zone,
+			"offset":    offset,
+		},
+		"success": true,
+	})
+}
+
 // ExecuteTask 手动执行任务
 func (h *SchedulerHandler) ExecuteTask(c *gin.Context) {
 	taskID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -366,7 +534,12 @@ func (h *SchedulerHandler) ExecuteTask(c *gin.Context) {
 func (h *SchedulerHandler) GetTelemetryTargets(c *gin.Context) {
 	var targets []models.TelemetryTarget
 
-	if err := h.schedulerService.GetDB().Find(&targets).Error; err != nil {
+	query := h.schedulerService.GetDB()
+	if group, ok := c.GetQuery("group"); ok {
+		query = query.Where("`group` = ?", group)
+	}
+
+	if err := query.Find(&targets).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "查询遥测目标失败",
@@ -393,6 +566,9 @@ func (h *SchedulerHandler) CreateTelemetryTarget(c *gin.Context) {
 		})
 		return
 	}
+	if !prepareTelemetryScenario(c, &target) {
+		return
+	}
 
 	if err := h.schedulerService.GetDB().Create(&target).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -411,6 +587,96 @@ func (h *SchedulerHandler) CreateTelemetryTarget(c *gin.Context) {
 	})
 }
 
+// prepareTelemetryScenario 校验场景目标的步骤定义，目标地址留空时取场景中的域名；DNS 目标校验查询参数
+func prepareTelemetryScenario(c *gin.Context, target *models.TelemetryTarget) bool {
+	if target.Type == "dns" {
+		if _, err := services.ParseTelemetryDNSQuery(target.DNSQuery); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": err.Error(),
+			})
+			return false
+		}
+		return true
+	}
+	if target.Type != "scenario" {
+		return true
+	}
+
+	scenario, err := services.ParseTelemetryScenario(target.Scenario)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return false
+	}
+	if target.Target == "" {
+		target.Target = services.ScenarioSummary(scenario)
+	}
+	return true
+}
+
+// ImportTelemetryTargets 从 CSV/YAML 批量导入遥测目标
+func (h *SchedulerHandler) ImportTelemetryTargets(c *gin.Context) {
+	var req models.TelemetryImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	targets, parseErrors := services.ParseTelemetryTargets(req.Content, req.Format, strings.TrimSpace(req.Group))
+	if len(targets) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "没有可导入的遥测目标",
+			"data":    models.TelemetryImportResult{Errors: parseErrors},
+		})
+		return
+	}
+
+	result, err := h.schedulerService.GetTelemetryService().ImportTargets(targets)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "导入遥测目标失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+	result.Errors = append(result.Errors, parseErrors...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"data":    result,
+		"message": fmt.Sprintf("导入完成: 新增 %d 个，跳过 %d 个，错误 %d 条", result.Created, result.Skipped, len(result.Errors)),
+		"success": true,
+	})
+}
+
+// GetTelemetryGroups 获取遥测分组统计
+func (h *SchedulerHandler) GetTelemetryGroups(c *gin.Context) {
+	stats, err := h.schedulerService.GetTelemetryService().GetGroupStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询遥测分组失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"data":    stats,
+		"success": true,
+	})
+}
+
 // UpdateTelemetryTarget 更新遥测目标
 func (h *SchedulerHandler) UpdateTelemetryTarget(c *gin.Context) {
 	targetID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -426,6 +692,9 @@ func (h *SchedulerHandler) UpdateTelemetryTarget(c *gin.Context) {
 	}
 
 	target.ID = uint(targetID)
+	if !prepareTelemetryScenario(c, &target) {
+		return
+	}
 
 	if err := h.schedulerService.GetDB().Save(&target).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -670,12 +939,252 @@ func (h *SchedulerHandler) TestTelemetryTarget(c *gin.Context) {
 }
 
 // GetScriptTemplates 获取脚本模板列表
+// GET /api/scheduler/script-templates?category=
 func (h *SchedulerHandler) GetScriptTemplates(c *gin.Context) {
-	templates := h.schedulerService.GetCustomScriptService().GetScriptTemplates()
-	
+	query := h.schedulerService.GetDB().Model(&models.ScriptTemplate{})
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var templates []models.ScriptTemplate
+	if err := query.Order("category, name").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "查询脚本模板失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"data":    templates,
 		"success": true,
 	})
 }
+
+// CreateScriptTemplate 创建脚本模板
+func (h *SchedulerHandler) CreateScriptTemplate(c *gin.Context) {
+	var req models.ScriptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var template models.ScriptTemplate
+	if !applyScriptTemplateRequest(c, &template, &req) {
+		return
+	}
+
+	db := h.schedulerService.GetDB()
+	var count int64
+	db.Model(&models.ScriptTemplate{}).Where("name = ?", template.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "模板名称已存在",
+		})
+		return
+	}
+
+	if err := db.Create(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建脚本模板失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityScriptTemplate, template.ID, template.Name, models.AuditActionCreate, nil, template)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"data":    template,
+		"message": "脚本模板创建成功",
+		"success": true,
+	})
+}
+
+// UpdateScriptTemplate 更新脚本模板，引用该模板的任务下次执行时使用新内容
+func (h *SchedulerHandler) UpdateScriptTemplate(c *gin.Context) {
+	db := h.schedulerService.GetDB()
+	var template models.ScriptTemplate
+	if err := db.First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "脚本模板不存在",
+		})
+		return
+	}
+
+	var req models.ScriptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	previous := template
+	if !applyScriptTemplateRequest(c, &template, &req) {
+		return
+	}
+
+	var count int64
+	db.Model(&models.ScriptTemplate{}).Where("name = ? AND id <> ?", template.Name, template.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": "模板名称已存在",
+		})
+		return
+	}
+
+	if err := db.Save(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新脚本模板失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityScriptTemplate, template.ID, template.Name, models.AuditActionUpdate, previous, template)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"data":    template,
+		"message": "脚本模板更新成功",
+		"success": true,
+	})
+}
+
+// DeleteScriptTemplate 删除脚本模板，仍被任务引用时拒绝删除
+func (h *SchedulerHandler) DeleteScriptTemplate(c *gin.Context) {
+	db := h.schedulerService.GetDB()
+	var template models.ScriptTemplate
+	if err := db.First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "脚本模板不存在",
+		})
+		return
+	}
+
+	if tasks := scriptTemplateTasks(db, template.ID); len(tasks) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": fmt.Sprintf("模板仍被任务使用: %s", strings.Join(tasks, "、")),
+		})
+		return
+	}
+
+	if err := db.Delete(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除脚本模板失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, models.AuditEntityScriptTemplate, template.ID, template.Name, models.AuditActionDelete, template, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "脚本模板删除成功",
+		"success": true,
+	})
+}
+
+// RenderScriptTemplate 按参数预览渲染后的脚本
+// POST /api/scheduler/script-templates/:id/render {"params": {...}}
+func (h *SchedulerHandler) RenderScriptTemplate(c *gin.Context) {
+	var template models.ScriptTemplate
+	if err := h.schedulerService.GetDB().First(&template, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "脚本模板不存在",
+		})
+		return
+	}
+
+	var req struct {
+		Params map[string]interface{} `json:"params"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	script, err := services.RenderScriptTemplate(&template, req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"data":    gin.H{"script": script},
+		"success": true,
+	})
+}
+
+// applyScriptTemplateRequest 校验请求并写入模板，校验失败时已返回响应
+func applyScriptTemplateRequest(c *gin.Context, template *models.ScriptTemplate, req *models.ScriptTemplateRequest) bool {
+	for i := range req.Parameters {
+		req.Parameters[i].Name = strings.TrimSpace(req.Parameters[i].Name)
+		if req.Parameters[i].Type == "" {
+			req.Parameters[i].Type = models.ScriptParamString
+		}
+	}
+	if err := services.ValidateScriptTemplate(req.Script, req.Parameters); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	params := "[]"
+	if len(req.Parameters) > 0 {
+		data, _ := json.Marshal(req.Parameters)
+		params = string(data)
+	}
+
+	template.Name = strings.TrimSpace(req.Name)
+	template.Description = req.Description
+	template.Category = strings.TrimSpace(req.Category)
+	template.Script = req.Script
+	template.Parameters = params
+	return true
+}
+
+// scriptTemplateTasks 引用脚本模板的任务名称
+func scriptTemplateTasks(db *gorm.DB, templateID uint) []string {
+	var tasks []models.ScheduledTask
+	db.Where("type = ?", models.TaskTypeCustomScript).Find(&tasks)
+
+	var names []string
+	for _, task := range tasks {
+		var config models.CustomScriptConfig
+		if json.Unmarshal([]byte(task.Config), &config) == nil && config.TemplateID == templateID {
+			names = append(names, task.Name)
+		}
+	}
+	return names
+}
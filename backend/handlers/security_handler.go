@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// GetSecurityFindings 获取 DNS 安全检测结果
+func GetSecurityFindings(c *gin.Context) {
+	findingType := c.Query("type")
+	severity := c.Query("severity")
+	nodeID := c.Query("node_id")
+	acknowledged := c.Query("acknowledged")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+
+	query := scopeOrganizationNodes(c, database.DB.Model(&models.SecurityFinding{}), "node_id")
+
+	if findingType != "" {
+		query = query.Where("type = ?", findingType)
+	}
+	if severity != "" {
+		query = query.Where("severity = ?", severity)
+	}
+	if nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	if acknowledged != "" {
+		query = query.Where("acknowledged = ?", acknowledged == "true")
+	}
+	if domain := c.Query("domain"); domain != "" {
+		query = query.Where("domain LIKE ?", "%"+domain+"%")
+	}
+	if clientIP := c.Query("client_ip"); clientIP != "" {
+		query = query.Where("client_ip = ?", clientIP)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var findings []models.SecurityFinding
+	offset := (page - 1) * pageSize
+	query.Order("detected_at desc").Offset(offset).Limit(pageSize).Find(&findings)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      findings,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// AcknowledgeSecurityFinding 确认检测结果
+func AcknowledgeSecurityFinding(c *gin.Context) {
+	id := c.Param("id")
+
+	result := database.DB.Model(&models.SecurityFinding{}).Where("id = ?", id).Update("acknowledged", true)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "确认失败: " + result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "检测结果不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已确认",
+	})
+}
+
+// DeleteSecurityFinding 删除检测结果
+func DeleteSecurityFinding(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := database.DB.Delete(&models.SecurityFinding{}, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "删除失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "删除成功",
+	})
+}
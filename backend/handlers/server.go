@@ -11,6 +11,7 @@ import (
 
 	"smartdns-manager/database"
 	"smartdns-manager/models"
+	"smartdns-manager/services"
 )
 
 // AddServer 添加DNS服务器
@@ -76,6 +77,7 @@ func AddServer(c *gin.Context) {
 		})
 		return
 	}
+	recordAudit(c, models.AuditEntityServer, server.ID, server.Address, models.AuditActionCreate, nil, server)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
@@ -112,6 +114,11 @@ func UpdateServer(c *gin.Context) {
 		return
 	}
 
+	if server.GroupsStr != "" {
+		json.Unmarshal([]byte(server.GroupsStr), &server.Groups)
+	}
+	before := server
+
 	var updateData models.DNSServer
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -147,6 +154,7 @@ func UpdateServer(c *gin.Context) {
 		})
 		return
 	}
+	recordAudit(c, models.AuditEntityServer, server.ID, server.Address, models.AuditActionUpdate, before, server)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -191,6 +199,7 @@ func DeleteServer(c *gin.Context) {
 		})
 		return
 	}
+	recordAudit(c, models.AuditEntityServer, server.ID, server.Address, models.AuditActionDelete, server, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -239,15 +248,5 @@ func GetServers(c *gin.Context) {
 
 // 辅助函数：生成服务器选项字符串
 func generateServerOptions(server *models.DNSServer) string {
-	options := []string{}
-
-	for _, group := range server.Groups {
-		options = append(options, "-group "+group)
-	}
-
-	if server.ExcludeDefault {
-		options = append(options, "-exclude-default-group")
-	}
-
-	return strings.Join(options, " ")
+	return services.GenerateServerOptions(server)
 }
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+const (
+	// shareLinkDefaultTTL 分享链接默认有效期
+	shareLinkDefaultTTL = 24 * time.Hour
+	// shareLinkMaxTTL 分享链接最长有效期
+	shareLinkMaxTTL = 7 * 24 * time.Hour
+	// shareLinkMaxWindow 分享的时间窗口最大跨度
+	shareLinkMaxWindow = 7 * 24 * time.Hour
+)
+
+// shareLinkSecret 分享链接签名密钥，取自 SHARE_LINK_SECRET，未设置时由 JWT_SECRET 派生
+func shareLinkSecret() []byte {
+	cfg := config.GetConfig()
+	secret := cfg.ShareLinkSecret
+	if secret == "" {
+		secret = "log-share:" + cfg.JWTSecret
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// shareFilterKeys 分享链接允许携带的日志过滤条件
+var shareFilterKeys = map[string]bool{
+	"node_id": true, "client_ip": true, "group": true, "upstream": true, "rcode": true, "cache_hit": true,
+	"matched_rule": true, "domain_category": true,
+	"client_subnet": true, "client_country": true, "client_asn": true, "client_ptr": true,
+	"domain": true, "query_type": true, "type": true,
+}
+
+// CreateShareLinkRequest 创建分享链接请求
+type CreateShareLinkRequest struct {
+	Kind           string            `json:"kind"` // logs / stats
+	Description    string            `json:"description"`
+	Filters        map[string]string `json:"filters"`
+	StartTime      string            `json:"start_time"` // RFC3339，默认最近24小时
+	EndTime        string            `json:"end_time"`
+	ExpiresInHours int               `json:"expires_in_hours"` // 默认24小时，最长7天
+}
+
+// CreateShareLink 生成日志只读分享链接
+// POST /api/share-links
+func CreateShareLink(c *gin.Context) {
+	var req CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	link, err := buildShareLink(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	nodeID, _ := strconv.ParseUint(link.FilterMap["node_id"], 10, 32)
+	if !checkOrganizationLogNode(c, uint(nodeID)) {
+		return
+	}
+	link.OrganizationID = requestOrganizationID(c)
+	link.CreatedBy = auditActor(c).Username
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "生成分享令牌失败",
+		})
+		return
+	}
+	link.Nonce = hex.EncodeToString(nonce)
+
+	if err := database.DB.Create(link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建分享链接失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	token := signShareLink(link)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分享链接已生成",
+		"data":    link,
+		"token":   token,
+		"path":    "/share/" + token,
+	})
+}
+
+// buildShareLink 校验请求并生成分享记录
+func buildShareLink(req *CreateShareLinkRequest) (*models.LogShareLink, error) {
+	if req.Kind == "" {
+		req.Kind = models.ShareKindLogs
+	}
+	if req.Kind != models.ShareKindLogs && req.Kind != models.ShareKindStats {
+		return nil, fmt.Errorf("不支持的分享类型: %s", req.Kind)
+	}
+
+	filters := make(map[string]string)
+	for key, value := range req.Filters {
+		if value == "" {
+			continue
+		}
+		if !shareFilterKeys[key] {
+			return nil, fmt.Errorf("不支持的过滤条件: %s", key)
+		}
+		filters[key] = value
+	}
+	if err := services.ValidateDNSLogFilters(func(key string) string { return filters[key] }); err != nil {
+		return nil, err
+	}
+	if _, err := strconv.ParseUint(filters["node_id"], 10, 32); err != nil && req.Kind == models.ShareKindStats {
+		return nil, fmt.Errorf("分享统计快照需要指定节点")
+	}
+
+	end := time.Now()
+	if req.EndTime != "" {
+		t, err := time.Parse(time.RFC3339, req.EndTime)
+		if err != nil {
+			return nil, fmt.Errorf("结束时间格式错误")
+		}
+		end = t
+	}
+	start := end.Add(-24 * time.Hour)
+	if req.StartTime != "" {
+		t, err := time.Parse(time.RFC3339, req.StartTime)
+		if err != nil {
+			return nil, fmt.Errorf("开始时间格式错误")
+		}
+		start = t
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("开始时间必须早于结束时间")
+	}
+	if end.Sub(start) > shareLinkMaxWindow {
+		return nil, fmt.Errorf("时间窗口不能超过7天")
+	}
+
+	ttl := shareLinkDefaultTTL
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	if ttl > shareLinkMaxTTL {
+		return nil, fmt.Errorf("有效期不能超过7天")
+	}
+
+	encoded, _ := json.Marshal(filters)
+	return &models.LogShareLink{
+		Kind:        req.Kind,
+		Description: req.Description,
+		Filters:     string(encoded),
+		FilterMap:   filters,
+		StartTime:   start,
+		EndTime:     end,
+		ExpiresAt:   time.Now().Add(ttl),
+	}, nil
+}
+
+// signShareLink 生成分享令牌：<ID>.<随机数>.<签名>。令牌只用于定位记录，访问范围全部读取数据库
+func signShareLink(link *models.LogShareLink) string {
+	payload := fmt.Sprintf("%d.%s", link.ID, link.Nonce)
+	return payload + "." + shareLinkSignature(payload)
+}
+
+// shareLinkSignature 计算令牌中 ID 和随机数部分的签名
+func shareLinkSignature(payload string) string {
+	mac := hmac.New(sha256.New, shareLinkSecret())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseShareToken 校验分享令牌签名和随机数，返回对应的分享记录，有效期和撤销状态以记录为准
+func parseShareToken(token string) (*models.LogShareLink, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("分享链接无效")
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(shareLinkSignature(payload))) {
+		return nil, fmt.Errorf("分享链接无效")
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("分享链接无效")
+	}
+
+	var link models.LogShareLink
+	if err := database.DB.First(&link, id).Error; err != nil {
+		return nil, fmt.Errorf("分享链接不存在")
+	}
+	if link.Nonce == "" || subtle.ConstantTimeCompare([]byte(link.Nonce), []byte(parts[1])) != 1 {
+		return nil, fmt.Errorf("分享链接无效")
+	}
+	if link.Revoked {
+		return nil, fmt.Errorf("分享链接已被撤销")
+	}
+	if time.Now().After(link.ExpiresAt) {
+		return nil, fmt.Errorf("分享链接已过期")
+	}
+
+	link.FilterMap = map[string]string{}
+	if link.Filters != "" {
+		if err := json.Unmarshal([]byte(link.Filters), &link.FilterMap); err != nil {
+			return nil, fmt.Errorf("分享链接的过滤条件无效")
+		}
+	}
+	return &link, nil
+}
+
+// ViewSharedLogs 通过分享链接只读查看日志或统计，查询范围由链接决定，只接受分页和排序参数
+// GET /api/share/:token
+func ViewSharedLogs(c *gin.Context) {
+	link, err := parseShareToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if logMonitorService == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "日志监控服务未初始化",
+		})
+		return
+	}
+
+	// 组织内创建的链接只能查看该组织的节点，节点移出组织后链接随之失效
+	nodeID, _ := strconv.ParseUint(link.FilterMap["node_id"], 10, 32)
+	if link.OrganizationID > 0 {
+		var count int64
+		database.DB.Model(&models.Node{}).Where("id = ? AND organization_id = ?", nodeID, link.OrganizationID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "分享的节点已不属于该组织",
+			})
+			return
+		}
+	}
+
+	now := time.Now()
+	database.DB.Model(link).Updates(map[string]interface{}{
+		"view_count":     link.ViewCount + 1,
+		"last_viewed_at": &now,
+	})
+
+	if link.Kind == models.ShareKindStats {
+		stats, err := logMonitorService.GetStats(uint(nodeID), link.StartTime, link.EndTime)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取统计失败: " + err.Error(),
+			})
+			return
+		}
+		sharedClientIPPrivacy().MaskStats(stats)
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"share":   shareScope(link),
+			"data":    stats,
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 20
+	}
+
+	filters := services.ParseDNSLogFilters(func(key string) string {
+		return link.FilterMap[key]
+	})
+	filters["start_time"] = link.StartTime
+	filters["end_time"] = link.EndTime
+
+	queryDNSLogs(c, page, pageSize, filters, gin.H{"share": shareScope(link)}, true)
+}
+
+// shareScope 返回给访问者的分享范围说明
+func shareScope(link *models.LogShareLink) gin.H {
+	return gin.H{
+		"kind":        link.Kind,
+		"description": link.Description,
+		"filters":     link.FilterMap,
+		"start_time":  link.StartTime,
+		"end_time":    link.EndTime,
+		"expires_at":  link.ExpiresAt,
+	}
+}
+
+// GetShareLinks 获取分享链接列表
+// GET /api/share-links
+func GetShareLinks(c *gin.Context) {
+	var links []models.LogShareLink
+	scopeOrganization(c, database.DB).Order("id desc").Limit(200).Find(&links)
+	for i := range links {
+		json.Unmarshal([]byte(links[i].Filters), &links[i].FilterMap)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    links,
+	})
+}
+
+// RevokeShareLink 撤销分享链接，已发出的链接立即失效
+// DELETE /api/share-links/:id
+func RevokeShareLink(c *gin.Context) {
+	result := scopeOrganization(c, database.DB.Model(&models.LogShareLink{})).Where("id = ?", c.Param("id")).Update("revoked", true)
+	if result.Error != nil || result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "分享链接不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分享链接已撤销",
+	})
+}
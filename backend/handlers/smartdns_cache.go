@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var smartdnsCacheService *services.SmartDNSCacheService
+
+// InitSmartDNSCacheHandler 初始化 SmartDNS 缓存处理器
+func InitSmartDNSCacheHandler(service *services.SmartDNSCacheService) {
+	smartdnsCacheService = service
+}
+
+// GetNodeCacheStats 获取节点的 SmartDNS 缓存状态
+// GET /api/nodes/:id/cache/stats
+func GetNodeCacheStats(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	stats, err := smartdnsCacheService.GetStats(&node)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取缓存状态失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// FlushNodeCache 清空节点的 SmartDNS 缓存（会短暂重启服务）
+// POST /api/nodes/:id/cache/flush
+func FlushNodeCache(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	if deferred, next := services.NewMaintenanceService().DeferNodeAction(&node, models.DeferredActionCacheFlush,
+		"清空缓存", c.Query("override") == "true"); deferred {
+		c.JSON(http.StatusOK, gin.H{
+			"success":  true,
+			"deferred": true,
+			"message":  fmt.Sprintf("节点不在维护窗口内，清空缓存已推迟到 %s 执行", services.FormatNextWindow(next)),
+		})
+		return
+	}
+
+	if err := smartdnsCacheService.Flush(&node); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "清空缓存失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "缓存已清空",
+	})
+}
+
+// BatchFlushCache 批量清空节点缓存，可按节点 ID 或节点标签选择
+// POST /api/nodes/batch/cache/flush
+func BatchFlushCache(c *gin.Context) {
+	var request models.CacheFlushRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if len(request.NodeIDs) == 0 && request.Tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请指定节点或节点标签",
+		})
+		return
+	}
+
+	request.OrganizationID = requestOrganizationID(c)
+	results, err := smartdnsCacheService.BatchFlush(request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "批量清空缓存完成",
+		"data":    results,
+		"failed":  failed,
+	})
+}
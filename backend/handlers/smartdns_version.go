@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// GetSmartDNSReleases SmartDNS 版本目录
+// GET /api/smartdns/releases?refresh=true
+//
+// 目录每小时从 GitHub Releases 更新一次，refresh 为 true 时立即更新；获取失败时返回上次的目录并在 message 中说明
+func GetSmartDNSReleases(c *gin.Context) {
+	refresh, _ := strconv.ParseBool(c.Query("refresh"))
+	releases, err := services.GetSmartDNSReleaseCatalog().Releases(c.Request.Context(), refresh)
+	if err != nil && len(releases) == 0 {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success": false,
+			"message": "获取 SmartDNS 版本目录失败: " + err.Error(),
+		})
+		return
+	}
+
+	message := ""
+	if err != nil {
+		message = "获取最新版本目录失败，显示的是缓存或 INIT_VERSION 配置的版本: " + err.Error()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    releases,
+	})
+}
+
+// GetSmartDNSVersionPins 按节点标签固定的 SmartDNS 版本
+// GET /api/smartdns/version-pins
+func GetSmartDNSVersionPins(c *gin.Context) {
+	pins, err := services.ListSmartDNSVersionPins()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    pins,
+	})
+}
+
+// SaveSmartDNSVersionPin 为节点标签固定 SmartDNS 版本，标签已固定时修改版本
+// POST /api/smartdns/version-pins
+func SaveSmartDNSVersionPin(c *gin.Context) {
+	var req models.SmartDNSVersionPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	var previous *models.SmartDNSVersionPin
+	var existing models.SmartDNSVersionPin
+	if err := database.DB.Where("tag = ?", strings.TrimSpace(req.Tag)).First(&existing).Error; err == nil {
+		previous = &existing
+	}
+
+	pin, err := services.SaveSmartDNSVersionPin(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if previous != nil {
+		recordAudit(c, models.AuditEntityVersionPin, pin.ID, pin.Tag, models.AuditActionUpdate, previous, pin)
+	} else {
+		recordAudit(c, models.AuditEntityVersionPin, pin.ID, pin.Tag, models.AuditActionCreate, nil, pin)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "版本固定已保存",
+		"data":    pin,
+	})
+}
+
+// DeleteSmartDNSVersionPin 取消标签的版本固定
+// DELETE /api/smartdns/version-pins/:id
+func DeleteSmartDNSVersionPin(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的ID",
+		})
+		return
+	}
+
+	pin, err := services.DeleteSmartDNSVersionPin(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	recordAudit(c, models.AuditEntityVersionPin, pin.ID, pin.Tag, models.AuditActionDelete, pin, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已取消版本固定",
+	})
+}
+
+// GetNodeSmartDNSVersion 节点当前的 SmartDNS 版本、目标版本和是否可以升级
+// GET /api/nodes/:id/smartdns/version
+func GetNodeSmartDNSVersion(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	status, err := services.GetSmartDNSNodeVersion(c.Request.Context(), &node)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// UpgradeNodeSmartDNS 升级节点上的 SmartDNS，未指定版本时升级到节点的目标版本
+// POST /api/nodes/:id/smartdns/upgrade
+//
+// 升级作为后台任务执行：替换程序文件后检查服务状态、版本、配置和解析，检查失败时自动恢复原程序
+func UpgradeNodeSmartDNS(c *gin.Context) {
+	var node models.Node
+	if err := database.DB.First(&node, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "节点不存在",
+		})
+		return
+	}
+
+	var req models.SmartDNSUpgradeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	if node.InitStatus != "installed" {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "节点尚未安装 SmartDNS 或正在初始化，无法升级",
+		})
+		return
+	}
+
+	description := "升级节点 " + node.Name + " 的 SmartDNS"
+	if req.Version != "" {
+		description += " 到 " + req.Version
+	}
+	job, err := services.StartJob(c.Request.Context(), models.BackgroundJobSmartDNSUpgrade, node.ID, description, req)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "升级任务已开始，可在后台任务中查看进度",
+		"data":    job,
+	})
+}
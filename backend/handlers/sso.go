@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+var ssoService *services.SSOService
+
+// InitSSOHandler 初始化单点登录处理器
+func InitSSOHandler(service *services.SSOService) {
+	ssoService = service
+}
+
+// GetSSOConfig 登录页获取可用的单点登录方式
+// GET /api/auth/sso
+func GetSSOConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    ssoService.Config(),
+	})
+}
+
+// OIDCLogin 跳转到 IdP 授权页，state 同时写入 Cookie 与发起登录的浏览器绑定
+// GET /api/auth/oidc/login
+func OIDCLogin(c *gin.Context) {
+	authURL, state, err := ssoService.BeginOIDC(c.Request.Context())
+	if err != nil {
+		log.Printf("❌ 发起 OIDC 登录失败: %v", err)
+		redirectSSOResult(c, url.Values{"sso_error": {"发起单点登录失败: " + err.Error()}})
+		return
+	}
+	setSSOStateCookie(c, state, services.SSOStateCookieMaxAge)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// setSSOStateCookie 写入或清除（maxAge < 0）state Cookie；IdP 回调是跨站跳转，需使用 SameSite=Lax
+func setSSOStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(services.SSOStateCookie, state, maxAge, "/", "", secure, true)
+}
+
+// OIDCCallback IdP 回调，完成登录后带一次性登录码跳回前端
+// GET /api/auth/oidc/callback
+func OIDCCallback(c *gin.Context) {
+	// state Cookie 只用一次，无论成功与否都清除
+	cookieState, _ := c.Cookie(services.SSOStateCookie)
+	setSSOStateCookie(c, "", -1)
+
+	if idpError := c.Query("error"); idpError != "" {
+		message := idpError
+		if description := c.Query("error_description"); description != "" {
+			message += ": " + description
+		}
+		redirectSSOResult(c, url.Values{"sso_error": {"单点登录失败: " + message}})
+		return
+	}
+
+	user, err := ssoService.CompleteOIDC(c.Request.Context(), c.Query("state"), cookieState, c.Query("code"))
+	if err != nil {
+		log.Printf("❌ OIDC 登录失败: %v", err)
+		message := "单点登录失败"
+		if errors.Is(err, services.ErrSSOForbidden) {
+			message = err.Error()
+		}
+		redirectSSOResult(c, url.Values{"sso_error": {message}})
+		return
+	}
+
+	redirectSSOResult(c, url.Values{"sso_code": {ssoService.IssueLoginCode(user.ID)}})
+}
+
+// redirectSSOResult 跳回前端登录页
+func redirectSSOResult(c *gin.Context, query url.Values) {
+	target := strings.TrimSuffix(config.GetConfig().SSOUIURL, "/") + "/login?" + query.Encode()
+	c.Redirect(http.StatusFound, target)
+}
+
+// ExchangeSSOCode 前端用一次性登录码换取 JWT
+// POST /api/auth/sso/exchange
+func ExchangeSSOCode(c *gin.Context) {
+	var req models.SSOExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	userID, ok := ssoService.RedeemLoginCode(req.Code)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "登录码无效或已过期，请重新登录",
+		})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户不存在",
+		})
+		return
+	}
+
+	respondLogin(c, &user)
+}
+
+// loginWithLDAP 通过 LDAP 绑定验证密码，成功后按分组同步本地账号
+func loginWithLDAP(c *gin.Context, req LoginRequest) {
+	identity, err := services.LDAPAuthenticate(req.Username, req.Password)
+	if err != nil {
+		log.Printf("❌ LDAP 登录失败 [%s]: %v", req.Username, err)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户名或密码错误",
+		})
+		return
+	}
+
+	provisionAndLogin(c, identity)
+}
+
+// loginWithWebhook 通过认证 Webhook 校验账号密码，成功后按声明同步本地账号
+func loginWithWebhook(c *gin.Context, req LoginRequest) {
+	identity, err := services.WebhookAuthenticate(c.Request.Context(), req.Username, req.Password, c.ClientIP())
+	if err != nil {
+		respondWebhookError(c, req.Username, err)
+		return
+	}
+	provisionAndLogin(c, identity)
+}
+
+// WebhookTokenLogin 使用外部令牌登录，令牌由认证 Webhook 校验
+// POST /api/auth/webhook/token
+func WebhookTokenLogin(c *gin.Context) {
+	if !services.AuthWebhookEnabled() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "未启用认证 Webhook",
+		})
+		return
+	}
+
+	var req models.WebhookTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	identity, err := services.WebhookAuthenticateToken(c.Request.Context(), req.Token, c.ClientIP())
+	if err != nil {
+		respondWebhookError(c, "令牌登录", err)
+		return
+	}
+	provisionAndLogin(c, identity)
+}
+
+// respondWebhookError 认证服务不可用时返回 503，便于区分凭据错误
+func respondWebhookError(c *gin.Context, subject string, err error) {
+	log.Printf("❌ Webhook 登录失败 [%s]: %v", subject, err)
+	if errors.Is(err, services.ErrWebhookUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": "认证服务暂不可用，请稍后重试",
+		})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"success": false,
+		"message": "用户名或密码错误",
+	})
+}
+
+// provisionAndLogin 按外部身份同步本地账号并签发令牌
+func provisionAndLogin(c *gin.Context, identity *models.SSOIdentity) {
+	user, err := ssoService.ProvisionUser(identity)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrSSOForbidden) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	respondLogin(c, user)
+}
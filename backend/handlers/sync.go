@@ -2,12 +2,12 @@ package handlers
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"smartdns-manager/database"
 	"smartdns-manager/models"
@@ -29,15 +29,20 @@ func TriggerFullSync(c *gin.Context) {
 	}
 
 	// 异步执行完整同步
-	go func() {
-		if err := configSyncService.FullSyncToNode(uint(nodeID)); err != nil {
-			log.Printf("完整同步失败: %v", err)
-		}
-	}()
+	job, err := services.StartJob(c.Request.Context(), models.BackgroundJobFullSync, uint(nodeID),
+		fmt.Sprintf("完整同步节点 #%d", nodeID), []uint{uint(nodeID)})
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "已开始完整同步，请稍后查看同步日志",
+		"data":    job,
 	})
 }
 
@@ -55,17 +60,97 @@ func BatchFullSync(c *gin.Context) {
 		return
 	}
 
-	go func() {
-		for _, nodeID := range request.NodeIDs {
-			if err := configSyncService.FullSyncToNode(nodeID); err != nil {
-				log.Printf("节点 %d 完整同步失败: %v", nodeID, err)
-			}
-		}
-	}()
+	job, err := services.StartJob(c.Request.Context(), models.BackgroundJobFullSync, 0,
+		fmt.Sprintf("批量完整同步 %d 个节点", len(request.NodeIDs)), request.NodeIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": fmt.Sprintf("已开始同步 %d 个节点", len(request.NodeIDs)),
+		"data":    job,
+	})
+}
+
+// GetSyncJobs 获取同步任务列表
+// GET /api/sync/jobs?status=running
+func GetSyncJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := scopeOrganizationNodeSet(c, database.DB.Model(&models.SyncJob{}), &models.SyncJobNode{}, "job_id")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if jobType := c.Query("type"); jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var jobs []models.SyncJob
+	query.Order("id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&jobs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      jobs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetSyncJob 获取同步任务进度及各节点状态
+// GET /api/sync/jobs/:id
+func GetSyncJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的任务ID",
+		})
+		return
+	}
+
+	job, err := services.GetSyncJobQueue().GetJob(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "同步任务不存在",
+		})
+		return
+	}
+
+	progress := 100
+	if job.TotalNodes > 0 {
+		progress = (job.SucceededNodes + job.FailedNodes) * 100 / job.TotalNodes
+		if job.Status == models.SyncJobStatusRunning || job.Status == models.SyncJobStatusQueued {
+			done := 0
+			for _, n := range job.Nodes {
+				if n.Status == models.SyncNodeStatusSucceeded || n.Status == models.SyncNodeStatusFailed {
+					done++
+				}
+			}
+			progress = done * 100 / job.TotalNodes
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"data":     job,
+		"progress": progress,
 	})
 }
 
@@ -77,7 +162,7 @@ func GetSyncLogs(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
 
-	query := database.DB.Model(&models.ConfigSyncLog{})
+	query := scopeOrganizationNodes(c, database.DB.Model(&models.ConfigSyncLog{}), "node_id")
 
 	if nodeID != "" {
 		query = query.Where("node_id = ?", nodeID)
@@ -108,20 +193,25 @@ func GetSyncLogs(c *gin.Context) {
 // GetSyncStats 获取同步统计
 func GetSyncStats(c *gin.Context) {
 	var stats struct {
-		Total   int64 `json:"total"`
-		Success int64 `json:"success"`
-		Failed  int64 `json:"failed"`
-		Pending int64 `json:"pending"`
+		Total    int64 `json:"total"`
+		Success  int64 `json:"success"`
+		Failed   int64 `json:"failed"`
+		Pending  int64 `json:"pending"`
+		Retrying int64 `json:"retrying"` // 失败后等待自动重试
 	}
 
-	database.DB.Model(&models.ConfigSyncLog{}).Count(&stats.Total)
-	database.DB.Model(&models.ConfigSyncLog{}).Where("status = ?", "success").Count(&stats.Success)
-	database.DB.Model(&models.ConfigSyncLog{}).Where("status = ?", "failed").Count(&stats.Failed)
-	database.DB.Model(&models.ConfigSyncLog{}).Where("status = ?", "pending").Count(&stats.Pending)
+	logs := func() *gorm.DB {
+		return scopeOrganizationNodes(c, database.DB.Model(&models.ConfigSyncLog{}), "node_id")
+	}
+	logs().Count(&stats.Total)
+	logs().Where("status = ?", "success").Count(&stats.Success)
+	logs().Where("status = ?", "failed").Count(&stats.Failed)
+	logs().Where("status = ?", "pending").Count(&stats.Pending)
+	logs().Where("status = ? AND next_retry_at IS NOT NULL", "failed").Count(&stats.Retrying)
 
 	// 最近的同步记录
 	var recentLogs []models.ConfigSyncLog
-	database.DB.Order("created_at desc").Limit(10).Find(&recentLogs)
+	logs().Order("created_at desc").Limit(10).Find(&recentLogs)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":     true,
@@ -160,36 +250,58 @@ func RetrySyncLog(c *gin.Context) {
 		return
 	}
 
-	// 异步重试
-	go func() {
-		syncLog.Status = "pending"
-		syncLog.Error = ""
-		database.DB.Save(&syncLog)
-
-		var node models.Node
-		database.DB.First(&node, syncLog.NodeID)
-
-		switch syncLog.Type {
-		case "address":
-			// 解析内容，重新同步地址
-			// 这里需要根据 content 字段解析出 domain 和 ip
-			// 简化处理：触发完整同步
-			configSyncService.FullSyncToNode(syncLog.NodeID)
-		case "server":
-			configSyncService.FullSyncToNode(syncLog.NodeID)
-		case "full_sync":
-			configSyncService.FullSyncToNode(syncLog.NodeID)
-		}
-	}()
+	// 完整同步只能补齐管理端的记录，无法从节点上移除配置
+	if syncLog.Action == "delete" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "删除操作无法通过完整同步重试，请重新执行删除或到节点上手动移除",
+		})
+		return
+	}
+
+	// 交给同步任务队列，通过完整同步补齐该节点的配置
+	job, err := services.GetSyncJobQueue().EnqueueSyncLogRetry(c.Request.Context(), syncLog.NodeID, []models.ConfigSyncLog{syncLog})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建重试任务失败",
+			"error":   err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "已开始重试同步",
+		"data":    gin.H{"job_id": job.ID},
 	})
 }
 
 // ClearSyncLogs 清理同步日志
 func ClearSyncLogs(c *gin.Context) {
+	result := syncLogCleanupQuery(c).Delete(&models.ConfigSyncLog{})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已清理 %d 条日志", result.RowsAffected),
+		"deleted": result.RowsAffected,
+	})
+}
+
+// ClearSyncLogsImpact 预估清理同步日志影响的条数
+func ClearSyncLogsImpact(c *gin.Context) (*models.DestructiveImpact, error) {
+	var count int64
+	if err := syncLogCleanupQuery(c).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	return &models.DestructiveImpact{
+		Summary: fmt.Sprintf("将删除 %d 条同步日志", count),
+		Counts:  map[string]int64{"sync_logs": count},
+	}, nil
+}
+
+// syncLogCleanupQuery 按请求条件构造待清理的同步日志查询
+func syncLogCleanupQuery(c *gin.Context) *gorm.DB {
 	var request struct {
 		Days   int    `json:"days"`   // 清理多少天前的日志
 		Status string `json:"status"` // 只清理指定状态的日志
@@ -199,7 +311,7 @@ func ClearSyncLogs(c *gin.Context) {
 		request.Days = 30 // 默认30天
 	}
 
-	query := database.DB.Model(&models.ConfigSyncLog{})
+	query := scopeOrganizationNodes(c, database.DB.Model(&models.ConfigSyncLog{}), "node_id")
 
 	if request.Days > 0 {
 		cutoffTime := time.Now().AddDate(0, 0, -request.Days)
@@ -209,12 +321,5 @@ func ClearSyncLogs(c *gin.Context) {
 	if request.Status != "" {
 		query = query.Where("status = ?", request.Status)
 	}
-
-	result := query.Delete(&models.ConfigSyncLog{})
-
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": fmt.Sprintf("已清理 %d 条日志", result.RowsAffected),
-		"deleted": result.RowsAffected,
-	})
+	return query
 }
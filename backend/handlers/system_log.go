@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/logging"
+)
+
+// maxSystemLogLimit 单次最多返回的后端日志条数
+const maxSystemLogLimit = 1000
+
+// GetSystemLogs 查询内存中最近的后端日志，最新的排在前面
+// GET /api/system/logs?level=warn&request_id=xxx&keyword=xxx&limit=200
+//
+// 只保留最近 LOG_BUFFER_SIZE 条，服务重启后清空；更早的日志请到容器或采集系统中查看
+func GetSystemLogs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	if limit <= 0 || limit > maxSystemLogLimit {
+		limit = maxSystemLogLimit
+	}
+
+	entries := logging.Recent(logging.Filter{
+		Level:     logging.ParseLevel(c.DefaultQuery("level", "debug")),
+		RequestID: c.Query("request_id"),
+		Keyword:   c.Query("keyword"),
+		Limit:     limit,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entries,
+	})
+}
@@ -0,0 +1,286 @@
+// Package logging 后端结构化日志：按 LOG_LEVEL 过滤、按 LOG_FORMAT 输出文本或 JSON，
+// 并在内存中保留最近的日志供 /api/system/logs 查询
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Entry 内存中保留的一条日志
+type Entry struct {
+	Time      time.Time         `json:"time"`
+	Level     string            `json:"level"`
+	Message   string            `json:"message"`
+	RequestID string            `json:"request_id,omitempty"`
+	Attrs     map[string]string `json:"attrs,omitempty"`
+}
+
+// Filter 查询最近日志的条件
+type Filter struct {
+	Level     slog.Level // 只返回不低于该级别的日志
+	RequestID string
+	Keyword   string // 匹配消息和字段值
+	Limit     int
+}
+
+type requestIDKey struct{}
+
+// WithRequestID 在 ctx 中记录请求 ID，使用该 ctx 记录的日志会带上 request_id 字段
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 读取 ctx 中的请求 ID
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext 返回带 request_id 字段的 Logger，ctx 中没有请求 ID 时返回默认 Logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
+
+// ParseLevel 解析 debug、info、warn、error，无法识别时为 info
+func ParseLevel(value string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+var (
+	level  = new(slog.LevelVar)
+	buffer = &ringBuffer{}
+)
+
+// Init 设置默认 Logger，并把标准库 log 的输出转为结构化日志
+func Init(levelName, format string, bufferSize int) {
+	level.Set(ParseLevel(levelName))
+	buffer.resize(bufferSize)
+
+	opts := &slog.HandlerOptions{Level: level}
+	var out slog.Handler
+	if strings.ToLower(format) == FormatJSON {
+		out = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		out = slog.NewTextHandler(os.Stdout, opts)
+	}
+	logger := slog.New(&handler{out: out, buffer: buffer})
+	slog.SetDefault(logger)
+
+	// 现有代码大量使用 log.Printf，统一按 info 级别交给 slog；
+	// 需要级别和请求 ID 的日志直接使用 slog.InfoContext / WarnContext / ErrorContext
+	log.SetFlags(0)
+	log.SetOutput(&stdlogWriter{logger: logger})
+}
+
+// Recent 按条件返回内存中最近的日志，最新的排在前面
+func Recent(filter Filter) []Entry {
+	return buffer.query(filter)
+}
+
+// handler 输出日志并写入内存缓冲区
+type handler struct {
+	out    slog.Handler
+	buffer *ringBuffer
+	attrs  []slog.Attr
+	group  string
+}
+
+func (h *handler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.out.Enabled(ctx, l)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	entry := Entry{
+		Time:    r.Time,
+		Level:   r.Level.String(),
+		Message: r.Message,
+	}
+	// 通过 FromContext 创建的 Logger 已带 request_id 字段，不再重复添加
+	if id := RequestID(ctx); id != "" && !h.hasRequestID() {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+
+	attrs := make(map[string]string, len(h.attrs)+r.NumAttrs())
+	for _, a := range h.attrs {
+		addAttr(attrs, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(attrs, h.group, a)
+		return true
+	})
+	if id, ok := attrs["request_id"]; ok {
+		entry.RequestID = id
+		delete(attrs, "request_id")
+	}
+	if len(attrs) > 0 {
+		entry.Attrs = attrs
+	}
+	h.buffer.add(entry)
+
+	return h.out.Handle(ctx, r)
+}
+
+func (h *handler) hasRequestID() bool {
+	for _, a := range h.attrs {
+		if a.Key == "request_id" {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.out = h.out.WithAttrs(attrs)
+	next.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	next.attrs = append(next.attrs, h.attrs...)
+	for _, a := range attrs {
+		if h.group != "" {
+			a.Key = h.group + "." + a.Key
+		}
+		next.attrs = append(next.attrs, a)
+	}
+	return &next
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.out = h.out.WithGroup(name)
+	if h.group != "" {
+		name = h.group + "." + name
+	}
+	next.group = name
+	return &next
+}
+
+func addAttr(attrs map[string]string, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	key := a.Key
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, child := range a.Value.Group() {
+			addAttr(attrs, key, child)
+		}
+		return
+	}
+	if key != "" {
+		attrs[key] = a.Value.String()
+	}
+}
+
+// stdlogWriter 接收标准库 log 的输出，按 info 级别记录
+type stdlogWriter struct {
+	logger *slog.Logger
+}
+
+func (w *stdlogWriter) Write(p []byte) (int, error) {
+	w.logger.Info(string(bytes.TrimRight(p, "\n")))
+	return len(p), nil
+}
+
+// ringBuffer 固定容量的日志缓冲区
+type ringBuffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+func (b *ringBuffer) resize(size int) {
+	if size <= 0 {
+		size = 2000
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = make([]Entry, size)
+	b.next = 0
+	b.full = false
+}
+
+func (b *ringBuffer) add(entry Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.entries) == 0 {
+		return
+	}
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+func (b *ringBuffer) query(filter Filter) []Entry {
+	if filter.Limit <= 0 {
+		filter.Limit = 200
+	}
+	keyword := strings.ToLower(filter.Keyword)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.entries)
+	}
+	result := make([]Entry, 0, min(filter.Limit, count))
+	for i := 1; i <= count && len(result) < filter.Limit; i++ {
+		entry := b.entries[(b.next-i+len(b.entries))%len(b.entries)]
+		if ParseLevel(entry.Level) < filter.Level {
+			continue
+		}
+		if filter.RequestID != "" && entry.RequestID != filter.RequestID {
+			continue
+		}
+		if keyword != "" && !entryContains(entry, keyword) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func entryContains(entry Entry, keyword string) bool {
+	if strings.Contains(strings.ToLower(entry.Message), keyword) {
+		return true
+	}
+	for _, value := range entry.Attrs {
+		if strings.Contains(strings.ToLower(value), keyword) {
+			return true
+		}
+	}
+	return false
+}
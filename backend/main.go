@@ -1,33 +1,52 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"smartdns-manager/config"
 	"smartdns-manager/database"
 	"smartdns-manager/handlers"
+	"smartdns-manager/logging"
 	"smartdns-manager/middleware"
 	"smartdns-manager/services"
+	"smartdns-manager/web"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-func main() {
+// runServe 启动 Web 服务和后台任务
+func runServe() {
+	// 初始化结构化日志，之后 log.Printf 的输出也按 LOG_LEVEL 过滤
+	cfg := config.GetConfig()
+	logBufferSize, _ := strconv.Atoi(cfg.LogBufferSize)
+	logging.Init(cfg.LogLevel, cfg.LogFormat, logBufferSize)
+
 	// 初始化数据库
 	database.InitDB()
-	database.InitClickHouse()
+	if config.GetLogStorageType() == config.LogStorageTimescale {
+		database.InitTimescale()
+	} else {
+		database.InitClickHouse()
+	}
 
 	// 创建 Gin 路由
-	r := gin.Default()
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.Logger(), gin.Recovery())
 
 	// CORS 配置
 	r.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true, // 允许所有来源（仅开发环境）
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * 3600,
 	}))
@@ -40,6 +59,25 @@ func main() {
 	healthChecker := services.NewNodeHealthChecker(time.Duration(statusTime) * time.Second)
 	healthChecker.Start()
 
+	// 启动通知投递队列
+	notificationQueue := services.NewNotificationQueueWorker(15 * time.Second)
+	notificationQueue.Start()
+
+	// 启动配置同步任务队列（恢复未完成的任务，失败的同步在队列中按指数退避自动重试）
+	services.GetSyncJobQueue()
+
+	// 恢复服务重启时中断的后台任务，须在调度服务启动前标记中断的任务执行记录
+	services.RecoverBackgroundJobs()
+
+	// 启动日志采集看门狗，采集中断时自动重启并告警
+	logMonitorWatchdog := services.NewLogMonitorWatchdog(time.Minute)
+	logMonitorWatchdog.Start()
+
+	// 启动告警规则引擎
+	alertRuleEngine := services.NewAlertRuleEngine(time.Minute)
+	alertRuleEngine.Start()
+	handlers.InitAlertRuleHandler(alertRuleEngine)
+
 	// 创建日志监控服务
 	logMonitorService := services.NewLogMonitorService()
 
@@ -57,207 +95,152 @@ func main() {
 		log.Printf("启动调度服务失败: %v", err)
 	}
 
+	// 启动维护窗口推迟操作执行器
+	maintenanceWorker := services.NewMaintenanceWorker(time.Minute, schedulerService)
+	maintenanceWorker.Start()
+
 	// 创建数据库备份服务（保留兼容性）
 	databaseBackupService := services.NewDatabaseBackupService(database.DB, s3Service)
 
+	// 迁移备份配置中的明文加密密钥
+	backupKeyService := services.NewBackupKeyService(database.DB)
+	if err := backupKeyService.MigrateLegacyKeys(); err != nil {
+		log.Printf("迁移备份加密密钥失败: %v", err)
+	}
+
 	// 初始化处理器
 	handlers.InitLogMonitorHandler(logMonitorService)
-	databaseBackupHandler := handlers.NewDatabaseBackupHandler(database.DB, databaseBackupService)
-	schedulerHandler := handlers.NewSchedulerHandler(schedulerService)
+	handlers.InitDNSLogExportHandler(services.NewDNSLogExportService(database.DB, logMonitorService))
+	handlers.InitAnalyticsHandler(services.NewLogAnalyticsService(database.CHConn))
+	handlers.InitIngestGapHandler(services.NewIngestGapService(database.CHConn))
 
-	defer healthChecker.Stop()
-	defer schedulerService.Stop()
+	healthScoreService, err := services.NewHealthScoreService(database.DB, config.GetConfig())
+	if err != nil {
+		log.Fatalf("创建健康评分服务失败: %v", err)
+	}
+	handlers.InitHealthScoreHandler(healthScoreService)
 
-	// 公开路由
-	public := r.Group("/api")
-	{
-		public.POST("/login", handlers.Login)
-		public.POST("/register", handlers.Register)
+	driftService, err := services.NewDriftService(database.DB, config.GetConfig())
+	if err != nil {
+		log.Fatalf("创建配置漂移检测服务失败: %v", err)
 	}
+	handlers.InitDriftHandler(driftService)
 
-	// 注册路由
-	logGroup := r.Group("/api/dns-logs")
-	logGroup.Use(middleware.AuthMiddleware())
-	logGroup.Use(middleware.AdminRequired())
-	{
-		logGroup.POST("/:id/log-monitor/start", handlers.StartNodeLogMonitor)     // 启动监控
-		logGroup.POST("/:id/log-monitor/stop", handlers.StopNodeLogMonitor)       // 停止监控
-		logGroup.GET("/:id/log-monitor/status", handlers.GetNodeLogMonitorStatus) // 监控状态
-		logGroup.GET("/:id/logs/stats", handlers.GetLogStats)                     // 日志统计
-		logGroup.POST("/:id/logs/clean", handlers.CleanOldLogs)                   // 清理日志
-		logGroup.GET("", handlers.GetDNSLogs)                                     // 获取日志列表（支持按节点过滤）
+	configSnapshotService, err := services.NewConfigSnapshotService(database.DB, config.GetConfig())
+	if err != nil {
+		log.Fatalf("创建节点配置快照服务失败: %v", err)
 	}
+	handlers.InitConfigSnapshotHandler(configSnapshotService)
 
-	handlers.InitVersionHandler("docker-v0.0.3")
-	apiVersion := r.Group("/api")
-	apiVersion.Use(middleware.AuthMiddleware())
-	apiVersion.Use(middleware.AdminRequired())
-	{
-		// 版本管理相关路由
-		version := apiVersion.Group("/version")
-		{
-			version.GET("/check", handlers.CheckVersion)
-			version.GET("/info", handlers.GetSystemInfo)
-			version.GET("/history", handlers.GetVersionHistory)
-		}
+	complianceService, err := services.NewComplianceService(database.DB, config.GetConfig())
+	if err != nil {
+		log.Fatalf("创建配置合规检查服务失败: %v", err)
+	}
+	handlers.InitComplianceHandler(complianceService)
+
+	chBackupService, err := services.NewCHBackupService(database.DB, config.GetConfig())
+	if err != nil {
+		log.Fatalf("创建ClickHouse备份服务失败: %v", err)
+	}
+	handlers.InitCHBackupHandler(chBackupService)
+
+	patchService, err := services.NewPatchService(database.DB, config.GetConfig())
+	if err != nil {
+		log.Fatalf("创建节点补丁检查服务失败: %v", err)
+	}
+	handlers.InitPatchHandler(patchService)
+
+	fleetReportService, err := services.NewFleetReportService(database.DB, config.GetConfig())
+	if err != nil {
+		log.Fatalf("创建节点资产报告服务失败: %v", err)
 	}
+	handlers.InitFleetReportHandler(fleetReportService)
 
-	// 需要认证的路由
-	protected := r.Group("/api")
-	protected.Use(middleware.AuthMiddleware())
-	protected.Use(middleware.AdminRequired())
-	{
-		// 节点管理
-		protected.GET("/nodes", handlers.GetNodes)
-		protected.POST("/nodes", handlers.AddNode)
-		protected.PUT("/nodes/:id", handlers.UpdateNode)
-		protected.DELETE("/nodes/:id", handlers.DeleteNode)
-		protected.POST("/nodes/:id/test", handlers.TestNodeConnection)
-
-		// Agent 部署管理
-		protected.POST("/nodes/:id/agent/deploy", handlers.DeployAgent)     // 部署 Agent
-		protected.GET("/nodes/:id/agent/status", handlers.CheckAgentStatus) // 检查状态
-		protected.DELETE("/nodes/:id/agent", handlers.UninstallAgent)       // 卸载 Agent
-		protected.GET("/nodes/:id/agent/logs", handlers.GetAgentLogs)       // 获取日志
-
-		// 配置管理
-		protected.GET("/nodes/:id/config", handlers.GetNodeConfig)
-		protected.POST("/nodes/:id/config", handlers.SaveNodeConfig)
-		protected.POST("/nodes/:id/restart", handlers.RestartNodeService)
-		protected.GET("/nodes/:id/status", handlers.GetNodeStatus)
-		protected.GET("/nodes/:id/logs", handlers.GetNodeLogs)
-
-		// 批量操作
-		protected.POST("/nodes/batch/config", handlers.BatchUpdateConfig)
-		protected.POST("/nodes/batch/restart", handlers.BatchRestart)
-
-		// 地址映射管理
-		protected.POST("/addresses", handlers.AddAddress)
-		protected.PUT("/addresses/:id", handlers.UpdateAddress)
-		protected.DELETE("/addresses/:id", handlers.DeleteAddress)
-		protected.POST("/addresses/batch", handlers.BatchAddAddresses)
-		protected.GET("/addresses", handlers.GetAddresses)
-
-		// ========== 配置同步 ==========
-		protected.POST("/sync/node/:id/full", handlers.TriggerFullSync) // 完整同步单个节点
-		protected.POST("/sync/batch", handlers.BatchFullSync)           // 批量完整同步
-		protected.GET("/sync/logs", handlers.GetSyncLogs)               // 获取同步日志
-		protected.GET("/sync/stats", handlers.GetSyncStats)             // 同步统计
-		protected.POST("/sync/logs/:id/retry", handlers.RetrySyncLog)   // 重试失败的同步
-		protected.DELETE("/sync/logs", handlers.ClearSyncLogs)          // 清理日志
-
-		// ========== 通知管理 ==========
-		protected.GET("/notifications/channels", handlers.GetNotificationChannels)
-		protected.POST("/notifications/channels", handlers.AddNotificationChannel)
-		protected.PUT("/notifications/channels/:id", handlers.UpdateNotificationChannel)
-		protected.DELETE("/notifications/channels/:id", handlers.DeleteNotificationChannel)
-		protected.POST("/notifications/channels/:id/test", handlers.TestNotificationChannel)
-		protected.GET("/notifications/logs", handlers.GetNotificationLogs)
-
-		// ========== 节点初始化 ==========
-		protected.POST("/nodes/:id/init", handlers.InitNode)               // 初始化节点
-		protected.GET("/nodes/:id/init/status", handlers.CheckNodeInit)    // 检查初始化状态
-		protected.GET("/nodes/:id/init/logs", handlers.GetInitLogs)        // 获取初始化日志
-		protected.POST("/nodes/:id/uninstall", handlers.UninstallSmartDNS) // 卸载
-		protected.POST("/nodes/:id/reinstall", handlers.ReinstallSmartDNS) // 重新安装
-
-		// ========== 备份管理 ==========
-		protected.GET("/nodes/:id/backups", handlers.GetNodeBackups)             // 获取备份列表
-		protected.POST("/nodes/:id/backups", handlers.CreateNodeBackup)          // 创建备份（改为 /backups）
-		protected.POST("/nodes/:id/backups/preview", handlers.PreviewBackup)     // 预览备份
-		protected.POST("/nodes/:id/backups/restore", handlers.RestoreNodeBackup) // 还原备份（改为 /backups/restore）
-		protected.DELETE("/nodes/:id/backups", handlers.DeleteNodeBackup)        // 删除备份
-		protected.GET("/nodes/:id/backups/download", handlers.DownloadBackup)    // 下载备份
-
-		// DNS 服务器管理
-		protected.POST("/servers", handlers.AddServer)
-		protected.PUT("/servers/:id", handlers.UpdateServer)
-		protected.DELETE("/servers/:id", handlers.DeleteServer)
-		protected.GET("/servers", handlers.GetServers)
-
-		// 统计信息
-		protected.GET("/dashboard/stats", handlers.GetDashboardStats)
-		protected.GET("/dashboard/health", handlers.GetNodesHealth)
-
-		// ========== 域名集管理 ==========
-		protected.GET("/domain-sets", handlers.GetDomainSets)
-		protected.GET("/domain-sets/:id", handlers.GetDomainSet)
-		protected.POST("/domain-sets", handlers.AddDomainSet)
-		protected.PUT("/domain-sets/:id", handlers.UpdateDomainSet)
-		protected.DELETE("/domain-sets/:id", handlers.DeleteDomainSet)
-		protected.POST("/domain-sets/:id/import", handlers.ImportDomainSetFile)
-		protected.GET("/domain-sets/:id/export", handlers.ExportDomainSet)
-
-		// ========== 域名规则管理 ==========
-		protected.GET("/domain-rules", handlers.GetDomainRules)
-		protected.POST("/domain-rules", handlers.AddDomainRule)
-		protected.PUT("/domain-rules/:id", handlers.UpdateDomainRule)
-		protected.DELETE("/domain-rules/:id", handlers.DeleteDomainRule)
-
-		// DNS 分组管理
-		protected.GET("/groups", handlers.GetGroups)
-		protected.POST("/groups", handlers.AddGroup)
-		protected.PUT("/groups/:id", handlers.UpdateGroup)
-		protected.DELETE("/groups/:id", handlers.DeleteGroup)
-
-		// ========== 命名服务器规则管理 ==========
-		protected.GET("/nameservers", handlers.GetNameservers)
-		protected.POST("/nameservers", handlers.AddNameserver)
-		protected.PUT("/nameservers/:id", handlers.UpdateNameserver)
-		protected.DELETE("/nameservers/:id", handlers.DeleteNameserver)
-
-		// ========== 数据库备份管理 ==========
-		// 备份配置管理
-		protected.GET("/database-backup/configs", databaseBackupHandler.GetBackupConfigs)
-		protected.POST("/database-backup/configs", databaseBackupHandler.CreateBackupConfig)
-		protected.GET("/database-backup/configs/:id", databaseBackupHandler.GetBackupConfig)
-		protected.PUT("/database-backup/configs/:id", databaseBackupHandler.UpdateBackupConfig)
-		protected.DELETE("/database-backup/configs/:id", databaseBackupHandler.DeleteBackupConfig)
-		
-		// 备份操作
-		protected.POST("/database-backup/configs/:id/backup", databaseBackupHandler.ManualBackup)
-		protected.GET("/database-backup/history", databaseBackupHandler.GetBackupHistory)
-		protected.POST("/database-backup/restore", databaseBackupHandler.RestoreBackup)
-		protected.GET("/database-backup/stats", databaseBackupHandler.GetBackupStats)
-		protected.POST("/database-backup/test-s3", databaseBackupHandler.TestS3Connection)
-
-		// ========== 定时任务管理 ==========
-		// 任务管理
-		protected.GET("/scheduler/tasks", schedulerHandler.GetTasks)
-		protected.POST("/scheduler/tasks", schedulerHandler.CreateTask)
-		protected.GET("/scheduler/tasks/:id", schedulerHandler.GetTask)
-		protected.PUT("/scheduler/tasks/:id", schedulerHandler.UpdateTask)
-		protected.DELETE("/scheduler/tasks/:id", schedulerHandler.DeleteTask)
-		protected.POST("/scheduler/tasks/:id/toggle", schedulerHandler.ToggleTask)
-		protected.POST("/scheduler/tasks/:id/execute", schedulerHandler.ExecuteTask)
-		
-		// 任务执行历史
-		protected.GET("/scheduler/tasks/:id/executions", schedulerHandler.GetTaskExecutions)
-		protected.GET("/scheduler/running", schedulerHandler.GetRunningTasks)
-		protected.GET("/scheduler/stats", schedulerHandler.GetStats)
-		
-		// 快速任务创建
-		protected.POST("/scheduler/quick-task", schedulerHandler.CreateQuickTask)
-		
-		// 遥测目标管理
-		protected.GET("/scheduler/telemetry/targets", schedulerHandler.GetTelemetryTargets)
-		protected.POST("/scheduler/telemetry/targets", schedulerHandler.CreateTelemetryTarget)
-		protected.PUT("/scheduler/telemetry/targets/:id", schedulerHandler.UpdateTelemetryTarget)
-		protected.DELETE("/scheduler/telemetry/targets/:id", schedulerHandler.DeleteTelemetryTarget)
-		protected.POST("/scheduler/telemetry/targets/:id/test", schedulerHandler.TestTelemetryTarget)
-		
-		// 遥测结果和统计
-		protected.GET("/scheduler/telemetry/results", schedulerHandler.GetTelemetryResults)
-		protected.GET("/scheduler/telemetry/stats", schedulerHandler.GetTelemetryStats)
-		
-		// 脚本模板管理
-		protected.GET("/scheduler/script-templates", schedulerHandler.GetScriptTemplates)
+	agentProbeService, err := services.NewAgentProbeService(database.DB, config.GetConfig())
+	if err != nil {
+		log.Fatalf("创建 Agent 解析探测服务失败: %v", err)
 	}
+	handlers.InitAgentProbeHandler(agentProbeService)
+	handlers.InitBlocklistHandler(services.NewBlocklistService(database.DB))
+	handlers.InitSmartDNSCacheHandler(services.NewSmartDNSCacheService(database.DB, logMonitorService))
+	handlers.InitMaintenanceHandler(maintenanceWorker)
+	handlers.InitSSOHandler(services.NewSSOService())
+
+	// 同步域名分类到 ClickHouse
+	services.NewDomainCategoryService().SyncToClickHouseAsync()
+	databaseBackupHandler := handlers.NewDatabaseBackupHandler(database.DB, databaseBackupService)
+	backupKeyHandler := handlers.NewBackupKeyHandler(backupKeyService)
+	schedulerHandler := handlers.NewSchedulerHandler(schedulerService)
+
+	handlers.InitVersionHandler("docker-v0.0.3")
+
+	// 版本化接口，/api 保留为旧客户端的兼容别名，响应带 Deprecation 头
+	registerRoutes(r.Group(middleware.APIVersionPrefix), databaseBackupHandler, backupKeyHandler, schedulerHandler)
+	legacy := r.Group("/api")
+	legacy.Use(middleware.DeprecatedAPI(middleware.APIVersionPrefix))
+	registerRoutes(legacy, databaseBackupHandler, backupKeyHandler, schedulerHandler)
+
+	// 内嵌前端时由后端直接提供页面
+	if web.Enabled() {
+		web.Register(r)
+		log.Printf("已启用内嵌前端")
+	}
+
+	// 启动后台任务执行器，须在任务依赖的服务（如日志导出）初始化之后，执行排队中和恢复的任务
+	jobWorkers := services.GetJobWorkers()
+	jobWorkers.Start()
 
 	// 启动服务器
 	port := config.GetConfig().ServerPort
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run("0.0.0.0:" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	server := &http.Server{
+		Addr:    "0.0.0.0:" + port,
+		Handler: r,
+	}
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// 等待 SIGINT / SIGTERM（docker stop、supervisord 停止进程）后优雅关闭
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	signal.Stop(quit)
+
+	timeout, err := strconv.Atoi(config.GetConfig().ShutdownTimeout)
+	if err != nil || timeout <= 0 {
+		timeout = 30
 	}
+	log.Printf("🛑 收到信号 %s，开始关闭服务（最长等待 %d 秒）", sig, timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	// 不再接受新请求，等待进行中的请求完成
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("⚠️ 等待进行中的请求超时: %v", err)
+	}
+
+	// 停止后台任务，健康检查会写入尚未提交的节点状态更新
+	stopped := make(chan struct{})
+	go func() {
+		schedulerService.Stop()
+		maintenanceWorker.Stop()
+		alertRuleEngine.Stop()
+		logMonitorWatchdog.Stop()
+		notificationQueue.Stop()
+		healthChecker.Stop()
+		jobWorkers.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		log.Printf("✅ 后台任务已停止")
+	case <-ctx.Done():
+		log.Printf("⚠️ 后台任务未在 %d 秒内停止，直接关闭连接", timeout)
+	}
+
+	database.Close()
+	log.Printf("✅ 服务已关闭")
 }
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersionPrefix 当前版本的接口前缀
+const APIVersionPrefix = "/api/v1"
+
+// DeprecatedAPI 标记不带版本号的旧接口，响应带 Deprecation 头并通过 Link 指向新版本地址
+func DeprecatedAPI(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		if rest := strings.TrimPrefix(c.Request.URL.Path, "/api"); rest != c.Request.URL.Path {
+			c.Header("Link", "<"+successor+rest+">; rel=\"successor-version\"")
+		}
+		c.Next()
+	}
+}
+
+// routeTemplate 请求匹配的路由模板，/api/v1 下的接口去掉版本号，与旧接口共用同一份权限表
+func routeTemplate(c *gin.Context) string {
+	route := c.FullPath()
+	if strings.HasPrefix(route, APIVersionPrefix+"/") {
+		return "/api" + strings.TrimPrefix(route, APIVersionPrefix)
+	}
+	return route
+}
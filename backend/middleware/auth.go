@@ -6,6 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"smartdns-manager/models"
 )
 
 var jwtSecret = []byte("your-secret-key-change-in-production")
@@ -36,6 +38,16 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := parts[1]
 
+		// API 令牌用于自动化调用，与登录 JWT 分开校验
+		if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+			if !authenticateAPIToken(c, tokenString) {
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
 		// 解析 token
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
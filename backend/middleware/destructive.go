@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"smartdns-manager/models"
+	"smartdns-manager/services"
+)
+
+// ImpactEstimator 估算破坏性操作的影响范围，可读取路径参数、查询参数和请求体
+type ImpactEstimator func(c *gin.Context) (*models.DestructiveImpact, error)
+
+var destructiveConfirm = services.NewDestructiveConfirmService()
+
+// ConfirmDestructive 破坏性操作的两阶段确认：
+// 带 dry_run=true 时只返回影响预估和确认令牌，不执行操作；
+// 提交时通过 X-Confirm-Token 请求头（或 confirm_token 参数）携带令牌，
+// DESTRUCTIVE_CONFIRM=enforce 时没有有效令牌的请求返回 428
+func ConfirmDestructive(operation string, estimate ImpactEstimator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "读取请求失败",
+			})
+			return
+		}
+		resetBody := func() {
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		fingerprint := requestFingerprint(c, body)
+		userID := c.GetUint("user_id")
+
+		if c.Query("dry_run") == "true" {
+			resetBody()
+			impact, err := estimate(c)
+			if err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					status = http.StatusNotFound
+				}
+				c.AbortWithStatusJSON(status, gin.H{
+					"success": false,
+					"message": "预估影响失败",
+					"error":   err.Error(),
+				})
+				return
+			}
+
+			token, expiresAt := destructiveConfirm.Issue(operation, fingerprint, userID)
+			c.AbortWithStatusJSON(http.StatusOK, gin.H{
+				"success": true,
+				"dry_run": true,
+				"data": models.DestructivePreview{
+					Operation:    operation,
+					Impact:       *impact,
+					ConfirmToken: token,
+					ExpiresAt:    expiresAt,
+					Enforced:     services.DestructiveConfirmEnforced(),
+				},
+			})
+			return
+		}
+
+		token := c.GetHeader("X-Confirm-Token")
+		if token == "" {
+			token = c.Query("confirm_token")
+		}
+		switch {
+		case token != "":
+			// 携带了令牌就必须有效，避免确认的内容与实际提交的不一致
+			if !destructiveConfirm.Consume(token, operation, fingerprint, userID) {
+				c.AbortWithStatusJSON(http.StatusPreconditionRequired, gin.H{
+					"success":          false,
+					"confirm_required": true,
+					"operation":        operation,
+					"message":          "确认令牌无效或已过期，请重新预估影响后确认",
+				})
+				return
+			}
+		case services.DestructiveConfirmEnforced():
+			c.AbortWithStatusJSON(http.StatusPreconditionRequired, gin.H{
+				"success":          false,
+				"confirm_required": true,
+				"operation":        operation,
+				"message":          "该操作需要确认：请先以 dry_run=true 预估影响范围，再携带 X-Confirm-Token 提交",
+			})
+			return
+		}
+
+		resetBody()
+		c.Next()
+	}
+}
+
+// requestFingerprint 请求方法、路径、查询参数（不含确认相关参数）和请求体的摘要
+func requestFingerprint(c *gin.Context, body []byte) string {
+	query := c.Request.URL.Query()
+	query.Del("dry_run")
+	query.Del("confirm_token")
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(c.Request.Method + " " + c.Request.URL.Path + "?")
+	for _, key := range keys {
+		b.WriteString(key + "=" + strings.Join(query[key], ",") + "&")
+	}
+	sum := sha256.Sum256(append([]byte(b.String()+"\n"), bytes.TrimSpace(body)...))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,32 +1,42 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Logger 自定义日志中间件
+// Logger 访问日志中间件，须注册在 RequestID 之后
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		startTime := time.Now()
 
 		c.Next()
 
-		endTime := time.Now()
-		latency := endTime.Sub(startTime)
-		clientIP := c.ClientIP()
-		method := c.Request.Method
 		statusCode := c.Writer.Status()
-		path := c.Request.URL.Path
+		level := slog.LevelInfo
+		switch {
+		case statusCode >= 500:
+			level = slog.LevelError
+		case statusCode >= 400:
+			level = slog.LevelWarn
+		}
 
-		log.Printf("[%s] %s %s %d %v",
-			method,
-			clientIP,
-			path,
-			statusCode,
-			latency,
-		)
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", statusCode,
+			"latency", time.Since(startTime).String(),
+			"client_ip", c.ClientIP(),
+		}
+		if username := c.GetString("username"); username != "" {
+			attrs = append(attrs, "user", username)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "error", c.Errors.String())
+		}
+		// 请求 Context 中带有请求 ID，日志会自动加上 request_id 字段
+		slog.Log(c.Request.Context(), level, "HTTP 请求", attrs...)
 	}
 }
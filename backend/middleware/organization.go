@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// orgResourceRoute 带资源 ID 的路由前缀及其资源模型，访问时校验资源是否属于当前组织
+//
+// 资源没有组织列时设置 NodeColumn，按关联节点的组织判断归属；
+// KeyColumn 为与路径参数匹配的列，默认 id，资源由子表记录多个节点时指向子表的外键。
+type orgResourceRoute struct {
+	Prefix     string
+	Param      string
+	Model      interface{}
+	KeyColumn  string
+	NodeColumn string
+}
+
+var orgResourceRoutes = []orgResourceRoute{
+	{Prefix: "/api/nodes/:id", Param: "id", Model: &models.Node{}},
+	{Prefix: "/api/group-blocks/import/:node_id", Param: "node_id", Model: &models.Node{}},
+	{Prefix: "/api/sync/node/:id", Param: "id", Model: &models.Node{}},
+	{Prefix: "/api/dns-logs/:id", Param: "id", Model: &models.Node{}},
+	{Prefix: "/api/addresses/:id", Param: "id", Model: &models.AddressMap{}},
+	{Prefix: "/api/domain-rules/:id", Param: "id", Model: &models.DomainRule{}},
+	{Prefix: "/api/notifications/channels/:id", Param: "id", Model: &models.NotificationChannel{}},
+	{Prefix: "/api/scheduler/tasks/:id", Param: "id", Model: &models.ScheduledTask{}},
+	{Prefix: "/api/sync/logs/:id", Param: "id", Model: &models.ConfigSyncLog{}, NodeColumn: "node_id"},
+	{Prefix: "/api/sync/jobs/:id", Param: "id", Model: &models.SyncJobNode{}, KeyColumn: "job_id", NodeColumn: "node_id"},
+	{Prefix: "/api/jobs/:id", Param: "id", Model: &models.BackgroundJob{}, NodeColumn: "node_id"},
+	{Prefix: "/api/security/findings/:id", Param: "id", Model: &models.SecurityFinding{}, NodeColumn: "node_id"},
+	{Prefix: "/api/drift-reports/:id", Param: "id", Model: &models.ConfigDriftReport{}, NodeColumn: "node_id"},
+	{Prefix: "/api/config-snapshots/:id", Param: "id", Model: &models.NodeConfigSnapshot{}, NodeColumn: "node_id"},
+	{Prefix: "/api/alerts/:id", Param: "id", Model: &models.Alert{}, NodeColumn: "node_id"},
+}
+
+// OrganizationScope 确定请求的组织范围，须在认证中间件之后使用
+//
+// 绑定了组织的用户固定为本组织；超级管理员（未绑定组织）默认不限制，
+// 携带 X-Organization-ID 请求头时切换到该组织。
+// 结果写入上下文的 organization_id（0 表示不限制）和 super_admin，
+// 并校验路径中的节点、规则等资源属于当前组织，不属于时按不存在处理。
+func OrganizationScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if err := database.DB.Select("id", "organization_id").First(&user, c.GetUint("user_id")).Error; err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "用户不存在或已禁用",
+			})
+			return
+		}
+
+		orgID := user.OrganizationID
+		superAdmin := orgID == 0
+		if header := c.GetHeader(models.OrganizationHeader); header != "" && superAdmin {
+			id, err := strconv.ParseUint(header, 10, 32)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "无效的组织ID",
+				})
+				return
+			}
+			if id > 0 {
+				var count int64
+				database.DB.Model(&models.Organization{}).Where("id = ?", id).Count(&count)
+				if count == 0 {
+					c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+						"success": false,
+						"message": "组织不存在",
+					})
+					return
+				}
+			}
+			orgID = uint(id)
+		}
+
+		c.Set("organization_id", orgID)
+		c.Set("super_admin", superAdmin)
+
+		if orgID > 0 && !orgResourceAllowed(c, orgID) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "资源不存在",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SuperAdminRequired 只允许超级管理员（未绑定组织的管理员）访问，须在 OrganizationScope 之后使用
+func SuperAdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("super_admin") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "需要超级管理员权限",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// orgResourceAllowed 路径中的资源不存在时交给处理器返回 404，存在但属于其他组织时拒绝
+func orgResourceAllowed(c *gin.Context, orgID uint) bool {
+	route := routeTemplate(c)
+	for _, resource := range orgResourceRoutes {
+		if route != resource.Prefix && !strings.HasPrefix(route, resource.Prefix+"/") {
+			continue
+		}
+		id, err := strconv.ParseUint(c.Param(resource.Param), 10, 32)
+		if err != nil {
+			return true
+		}
+		if resource.NodeColumn != "" {
+			return nodeResourceAllowed(resource, uint(id), orgID)
+		}
+		var owners []uint
+		database.DB.Model(resource.Model).Where("id = ?", id).Pluck("organization_id", &owners)
+		return len(owners) == 0 || owners[0] == orgID
+	}
+	return true
+}
+
+// nodeResourceAllowed 资源关联的节点全部属于当前组织时允许，未关联节点（跨节点汇总）的资源只有超级管理员可见
+func nodeResourceAllowed(resource orgResourceRoute, id, orgID uint) bool {
+	keyColumn := resource.KeyColumn
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+	var nodeIDs []uint
+	database.DB.Model(resource.Model).Where(keyColumn+" = ?", id).Distinct().Pluck(resource.NodeColumn, &nodeIDs)
+	if len(nodeIDs) == 0 {
+		return true
+	}
+	var owned int64
+	database.DB.Model(&models.Node{}).Where("id IN ? AND organization_id = ?", nodeIDs, orgID).Count(&owned)
+	return owned == int64(len(nodeIDs))
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AgentControlTokenPrefix 节点控制令牌明文前缀
+const AgentControlTokenPrefix = "sda_"
+
+// 控制通道消息类型，与 Agent 的 control 包一致
+const (
+	AgentControlHello    = "hello"    // Agent 连接后发送的第一条消息
+	AgentControlPing     = "ping"     // 心跳
+	AgentControlPong     = "pong"     // 心跳应答
+	AgentControlRequest  = "request"  // 管理端下发的命令
+	AgentControlResponse = "response" // 命令执行结果
+)
+
+// AgentControlMessage 控制通道消息，命令按 Agent API 的方法和路径下发，结果为 API 的原始响应
+type AgentControlMessage struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`     // 请求 ID，响应原样带回
+	Method  string          `json:"method,omitempty"` // 请求方法
+	Path    string          `json:"path,omitempty"`   // 相对 Agent /api/v1 的路径，可带查询参数
+	Body    json.RawMessage `json:"body,omitempty"`   // 请求体或响应体
+	Status  int             `json:"status,omitempty"` // 响应状态码
+	NodeID  uint32          `json:"node_id,omitempty"`
+	Version string          `json:"version,omitempty"`
+}
+
+// AgentControlStatus 节点控制通道的连接状态
+type AgentControlStatus struct {
+	NodeID       uint       `json:"node_id"`
+	TokenIssued  bool       `json:"token_issued"` // 是否已生成控制令牌
+	Connected    bool       `json:"connected"`
+	AgentVersion string     `json:"agent_version,omitempty"`
+	RemoteAddr   string     `json:"remote_addr,omitempty"` // Agent 的出口地址
+	ConnectedAt  *time.Time `json:"connected_at,omitempty"`
+	LastSeenAt   *time.Time `json:"last_seen_at,omitempty"` // 最近一次收到消息的时间
+}
@@ -0,0 +1,23 @@
+package models
+
+// AgentLogFilter Agent 发送前丢弃或采样日志的规则，减少 PTR 风暴、健康检查等噪音对 ClickHouse 的占用
+type AgentLogFilter struct {
+	IgnoreSuffixes []string          `json:"ignore_suffixes"` // 按域名后缀忽略，如 in-addr.arpa
+	IgnorePatterns []string          `json:"ignore_patterns"` // 按正则忽略，匹配小写域名
+	IgnoreClients  []string          `json:"ignore_clients"`  // 忽略的客户端 IP 或 CIDR 网段
+	SampleRules    []AgentSampleRule `json:"sample_rules"`    // 按域名后缀采样
+}
+
+// AgentSampleRule 匹配后缀的域名只保留 Rate 比例的日志，后缀为 * 时作用于未匹配其他规则的域名
+type AgentSampleRule struct {
+	Suffix string  `json:"suffix"`
+	Rate   float64 `json:"rate"` // 保留比例，(0, 1]
+}
+
+// AgentLogFilterStatus 节点保存的过滤规则及下发结果
+type AgentLogFilterStatus struct {
+	Rules      AgentLogFilter         `json:"rules"`
+	AgentRules *AgentLogFilter        `json:"agent_rules,omitempty"` // Agent 当前生效的规则，无法连接 Agent 时为空
+	AgentStats map[string]interface{} `json:"agent_stats,omitempty"` // Agent 启动以来各规则丢弃的日志条数
+	AgentError string                 `json:"agent_error,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// AgentProbeStat Agent 在节点本机解析探测域名的 5 分钟聚合结果
+type AgentProbeStat struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	NodeID       uint      `json:"node_id" gorm:"uniqueIndex:idx_agent_probe_bucket"`
+	Domain       string    `json:"domain" gorm:"size:255;uniqueIndex:idx_agent_probe_bucket"`
+	BucketStart  time.Time `json:"bucket_start" gorm:"uniqueIndex:idx_agent_probe_bucket;index"`
+	Probes       int       `json:"probes"`
+	Failures     int       `json:"failures"`
+	LatencySumMs float64   `json:"latency_sum_ms"` // 成功探测的延迟总和
+	MaxLatencyMs float64   `json:"max_latency_ms"`
+	LastError    string    `json:"last_error"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (AgentProbeStat) TableName() string {
+	return "agent_probe_stats"
+}
+
+// ProbeSLADomain 单个探测域名的统计
+type ProbeSLADomain struct {
+	Domain       string  `json:"domain"`
+	Probes       int     `json:"probes"`
+	Failures     int     `json:"failures"`
+	SuccessRate  float64 `json:"success_rate"` // 百分比
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+}
+
+// ProbeSLA 节点在统计周期内的解析 SLA
+type ProbeSLA struct {
+	NodeID       uint             `json:"node_id"`
+	NodeName     string           `json:"node_name"`
+	Probes       int              `json:"probes"`
+	Failures     int              `json:"failures"`
+	SuccessRate  float64          `json:"success_rate"` // 百分比
+	AvgLatencyMs float64          `json:"avg_latency_ms"`
+	MaxLatencyMs float64          `json:"max_latency_ms"`
+	ErrorMinutes int              `json:"error_minutes"` // 半数以上探测失败的聚合周期累计时长
+	MeetsTarget  bool             `json:"meets_target"`
+	LastBucket   time.Time        `json:"last_bucket"`
+	Domains      []ProbeSLADomain `json:"domains"`
+}
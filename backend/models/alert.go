@@ -0,0 +1,113 @@
+package models
+
+import "time"
+
+// 告警规则指标
+const (
+	AlertMetricQueryCount    = "query_count"     // 窗口内查询数
+	AlertMetricNXDomainRatio = "nxdomain_ratio"  // NXDOMAIN 查询占比（%）
+	AlertMetricAvgLatency    = "avg_latency_ms"  // 平均解析耗时（毫秒）
+	AlertMetricP95Latency    = "p95_latency_ms"  // P95 解析耗时（毫秒）
+	AlertMetricUniqueClients = "unique_clients"  // 客户端数
+	AlertMetricDomainSetHits = "domain_set_hits" // 命中指定域名集（含子域名）的查询数
+)
+
+// 告警级别
+const (
+	AlertSeverityInfo     = "info"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+// 告警状态
+const (
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
+// AlertRule 基于 DNS 日志指标的告警规则，如"节点 X 最近 5 分钟 NXDOMAIN 比例 > 20%"
+type AlertRule struct {
+	ID          uint    `json:"id" gorm:"primarykey"`
+	Name        string  `json:"name" gorm:"not null"`
+	Description string  `json:"description"`
+	Enabled     bool    `json:"enabled" gorm:"default:true"`
+	Metric      string  `json:"metric" gorm:"not null"`
+	Operator    string  `json:"operator" gorm:"not null"` // >、>=、<、<=、==、!=
+	Threshold   float64 `json:"threshold"`
+	// WindowMinutes 统计最近多少分钟的日志
+	WindowMinutes int `json:"window_minutes" gorm:"default:5"`
+	// IntervalMinutes 评估间隔
+	IntervalMinutes int `json:"interval_minutes" gorm:"default:1"`
+	// NodeID 只统计指定节点，0 表示所有节点
+	NodeID uint `json:"node_id"`
+	// PerNode 按节点分别评估和告警，否则汇总所有节点评估
+	PerNode bool `json:"per_node"`
+	// MinQueries 窗口内查询数低于该值时不评估比例和耗时类指标，避免样本过少误报
+	MinQueries int64 `json:"min_queries"`
+	// DomainSetID domain_set_hits 指标使用的域名集
+	DomainSetID uint `json:"domain_set_id"`
+	// SavedSearchID 附加的过滤条件（保存的搜索，时间范围以规则窗口为准）
+	SavedSearchID uint   `json:"saved_search_id"`
+	Severity      string `json:"severity" gorm:"default:'warning'"`
+	// ChannelIDs JSON 数组，指定通知渠道；为空时发送到订阅了 alert_rule 事件的渠道
+	ChannelIDs     string `json:"channel_ids"`
+	NotifyResolved bool   `json:"notify_resolved" gorm:"default:true"`
+
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at"`
+	LastError       string     `json:"last_error" gorm:"type:text"`
+	CreatedBy       string     `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Alert 告警规则触发的告警，条件不再满足时标记为已恢复
+type Alert struct {
+	ID             uint       `json:"id" gorm:"primarykey"`
+	RuleID         uint       `json:"rule_id" gorm:"index"`
+	RuleName       string     `json:"rule_name"`
+	NodeID         uint       `json:"node_id" gorm:"index"` // 汇总评估时为 0
+	NodeName       string     `json:"node_name"`
+	Severity       string     `json:"severity" gorm:"index"`
+	Status         string     `json:"status" gorm:"index"`
+	Metric         string     `json:"metric"`
+	Value          float64    `json:"value"` // 最近一次评估的值
+	Threshold      float64    `json:"threshold"`
+	Message        string     `json:"message" gorm:"type:text"`
+	StartedAt      time.Time  `json:"started_at"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	AcknowledgedBy string     `json:"acknowledged_by"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// AlertRuleRequest 创建/更新告警规则请求
+type AlertRuleRequest struct {
+	Name            string  `json:"name" binding:"required"`
+	Description     string  `json:"description"`
+	Enabled         *bool   `json:"enabled"`
+	Metric          string  `json:"metric" binding:"required"`
+	Operator        string  `json:"operator" binding:"required"`
+	Threshold       float64 `json:"threshold"`
+	WindowMinutes   int     `json:"window_minutes"`
+	IntervalMinutes int     `json:"interval_minutes"`
+	NodeID          uint    `json:"node_id"`
+	PerNode         bool    `json:"per_node"`
+	MinQueries      int64   `json:"min_queries"`
+	DomainSetID     uint    `json:"domain_set_id"`
+	SavedSearchID   uint    `json:"saved_search_id"`
+	Severity        string  `json:"severity"`
+	ChannelIDs      []uint  `json:"channel_ids"`
+	NotifyResolved  *bool   `json:"notify_resolved"`
+}
+
+// AlertRuleValue 单次评估结果，汇总评估时 NodeID 为 0
+type AlertRuleValue struct {
+	NodeID   uint    `json:"node_id"`
+	NodeName string  `json:"node_name"`
+	Value    float64 `json:"value"`
+	Queries  int64   `json:"queries"`
+	Skipped  bool    `json:"skipped"` // 查询数低于 MinQueries，未评估
+	Firing   bool    `json:"firing"`
+}
@@ -0,0 +1,234 @@
+package models
+
+import "time"
+
+// QueryTypeStat 查询类型分布
+type QueryTypeStat struct {
+	QueryType     int     `json:"query_type"`
+	Name          string  `json:"name"`
+	Count         int64   `json:"count"`
+	Percent       float64 `json:"percent"`
+	PreviousCount int64   `json:"previous_count"` // 上一个同等时长周期的数量
+	ChangeRate    float64 `json:"change_rate"`    // 相对上一周期的变化率（%）
+}
+
+// NodeQueryTypeStat 节点维度的查询类型分布
+type NodeQueryTypeStat struct {
+	NodeID    uint   `json:"node_id"`
+	QueryType int    `json:"query_type"`
+	Name      string `json:"name"`
+	Count     int64  `json:"count"`
+}
+
+// QueryTypeTrendPoint 查询类型趋势点
+type QueryTypeTrendPoint struct {
+	Time      time.Time `json:"time"`
+	QueryType int       `json:"query_type"`
+	Name      string    `json:"name"`
+	Count     int64     `json:"count"`
+}
+
+// QueryTypeAnalytics 查询类型分析结果
+type QueryTypeAnalytics struct {
+	StartTime    time.Time             `json:"start_time"`
+	EndTime      time.Time             `json:"end_time"`
+	Interval     string                `json:"interval"`
+	Total        int64                 `json:"total"`
+	Distribution []QueryTypeStat       `json:"distribution"`
+	ByNode       []NodeQueryTypeStat   `json:"by_node"`
+	Trend        []QueryTypeTrendPoint `json:"trend"`
+}
+
+// ResponseIPStat 应答 IP 统计
+type ResponseIPStat struct {
+	IP            string  `json:"ip"`
+	Provider      string  `json:"provider"`
+	Count         int64   `json:"count"`
+	UniqueDomains int64   `json:"unique_domains"`
+	AvgTimeMs     float64 `json:"avg_time_ms"`
+	AvgSpeedMs    float64 `json:"avg_speed_ms"`
+}
+
+// NodeResponseIPStat 节点维度的应答 IP 统计
+type NodeResponseIPStat struct {
+	NodeID     uint    `json:"node_id"`
+	IP         string  `json:"ip"`
+	Provider   string  `json:"provider"`
+	Count      int64   `json:"count"`
+	AvgSpeedMs float64 `json:"avg_speed_ms"`
+}
+
+// DomainResponseIPStat 域名维度的应答 IP 统计
+type DomainResponseIPStat struct {
+	Domain     string  `json:"domain"`
+	IP         string  `json:"ip"`
+	Provider   string  `json:"provider"`
+	Count      int64   `json:"count"`
+	AvgSpeedMs float64 `json:"avg_speed_ms"`
+}
+
+// ProviderStat 服务商统计
+type ProviderStat struct {
+	Provider string `json:"provider"`
+	Count    int64  `json:"count"`
+}
+
+// ResponseIPAnalytics 应答 IP 分析结果
+type ResponseIPAnalytics struct {
+	StartTime time.Time              `json:"start_time"`
+	EndTime   time.Time              `json:"end_time"`
+	GroupBy   string                 `json:"group_by"` // ip 或 subnet
+	TopIPs    []ResponseIPStat       `json:"top_ips"`
+	ByNode    []NodeResponseIPStat   `json:"by_node"`
+	ByDomain  []DomainResponseIPStat `json:"by_domain"`
+	Providers []ProviderStat         `json:"providers"`
+}
+
+// ClientProfile 客户端查询画像
+type ClientProfile struct {
+	ClientIP      string          `json:"client_ip"`
+	StartTime     time.Time       `json:"start_time"`
+	EndTime       time.Time       `json:"end_time"`
+	ClientSubnet  string          `json:"client_subnet"`
+	ClientCountry string          `json:"client_country"`
+	ClientASN     uint32          `json:"client_asn"`
+	ClientPTR     string          `json:"client_ptr"`
+	TotalQueries  int64           `json:"total_queries"`
+	UniqueDomains int64           `json:"unique_domains"`
+	AvgQPS        float64         `json:"avg_qps"`
+	PeakQPS       float64         `json:"peak_qps"` // 峰值分钟的平均 QPS
+	NXDomainCount int64           `json:"nxdomain_count"`
+	NXDomainRate  float64         `json:"nxdomain_rate"` // NXDOMAIN 占比（%），旧日志无 rcode 时以无应答记录近似
+	AvgTimeMs     float64         `json:"avg_time_ms"`
+	QueryTypes    []QueryTypeStat `json:"query_types"`
+	TopDomains    []DomainStat    `json:"top_domains"`
+	Nodes         []NodeCountStat `json:"nodes"`
+}
+
+// NodeCountStat 节点维度计数
+type NodeCountStat struct {
+	NodeID uint  `json:"node_id"`
+	Count  int64 `json:"count"`
+}
+
+// ClientQPSStat 客户端 QPS 统计
+type ClientQPSStat struct {
+	ClientIP string  `json:"client_ip"`
+	NodeID   uint    `json:"node_id"`
+	Count    int64   `json:"count"`
+	QPS      float64 `json:"qps"`
+}
+
+// 查询量异常检测指标
+const (
+	TrafficMetricQPS          = "qps"
+	TrafficMetricFailureRatio = "failure_ratio"
+)
+
+// TrafficAnomaly 节点查询量或失败率偏离基线的检测结果
+type TrafficAnomaly struct {
+	NodeID   uint    `json:"node_id"`
+	NodeName string  `json:"node_name"`
+	Metric   string  `json:"metric"`
+	Current  float64 `json:"current"`
+	Baseline float64 `json:"baseline"` // 基线均值
+	StdDev   float64 `json:"std_dev"`
+	ZScore   float64 `json:"z_score"`
+	Samples  int     `json:"samples"` // 参与基线计算的窗口数
+	Reason   string  `json:"reason"`
+}
+
+// SlowQuery 慢查询记录
+type SlowQuery struct {
+	Timestamp     time.Time `json:"timestamp"`
+	NodeID        uint      `json:"node_id"`
+	ClientIP      string    `json:"client_ip"`
+	Domain        string    `json:"domain"`
+	QueryType     int       `json:"query_type"`
+	QueryTypeName string    `json:"query_type_name"`
+	Group         string    `json:"group"`
+	TimeMs        int       `json:"time_ms"`
+	SpeedMs       float64   `json:"speed_ms"`
+	ResultCount   int       `json:"result_count"`
+	ResultIPs     []string  `json:"result_ips"`
+}
+
+// LatencyPercentiles 查询耗时分位数（毫秒）
+type LatencyPercentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// SlowQueryGroupStat 按上游组（或域名）汇总的慢查询统计
+type SlowQueryGroupStat struct {
+	Key       string  `json:"key"`
+	Count     int64   `json:"count"`
+	SlowCount int64   `json:"slow_count"` // 超过阈值的查询数
+	AvgTimeMs float64 `json:"avg_time_ms"`
+	P95       float64 `json:"p95"`
+}
+
+// SlowQueryAnalytics 慢查询分析结果
+type SlowQueryAnalytics struct {
+	StartTime   time.Time            `json:"start_time"`
+	EndTime     time.Time            `json:"end_time"`
+	ThresholdMs int                  `json:"threshold_ms"` // 慢查询阈值，未指定时取 P95
+	Percentiles LatencyPercentiles   `json:"percentiles"`
+	Queries     []SlowQuery          `json:"queries"`
+	ByGroup     []SlowQueryGroupStat `json:"by_group"`
+	ByDomain    []SlowQueryGroupStat `json:"by_domain"`
+}
+
+// UpstreamStat 上游服务器的解析表现，日志未输出上游时 Upstream 为空，只按上游组统计
+type UpstreamStat struct {
+	Group         string  `json:"group"`
+	Upstream      string  `json:"upstream"`
+	Protocol      string  `json:"protocol"` // udp/tcp/tls/https/quic，按上游地址推断
+	Count         int64   `json:"count"`
+	SelectionRate float64 `json:"selection_rate"` // 在所属上游组查询中被选中应答的占比（%）
+	Share         float64 `json:"share"`          // 在全部查询中的占比（%）
+	Failures      int64   `json:"failures"`       // NXDOMAIN 查询数
+	FailureRate   float64 `json:"failure_rate"`   // 该上游应答中失败的占比（%）
+	FailureShare  float64 `json:"failure_share"`  // 占全部失败查询的比例（%）
+	AvgTimeMs     float64 `json:"avg_time_ms"`
+	P50           float64 `json:"p50"`
+	P95           float64 `json:"p95"`
+	P99           float64 `json:"p99"`
+	AvgSpeedMs    float64 `json:"avg_speed_ms"` // 测速结果均值，未测速的查询不计入
+}
+
+// NodeUpstreamStat 节点维度的上游选择情况
+type NodeUpstreamStat struct {
+	NodeID        uint    `json:"node_id"`
+	Group         string  `json:"group"`
+	Upstream      string  `json:"upstream"`
+	Count         int64   `json:"count"`
+	SelectionRate float64 `json:"selection_rate"` // 在该节点所属上游组查询中的占比（%）
+	AvgTimeMs     float64 `json:"avg_time_ms"`
+}
+
+// UpstreamTrendPoint 上游选择与耗时趋势点
+type UpstreamTrendPoint struct {
+	Time      time.Time `json:"time"`
+	Group     string    `json:"group"`
+	Upstream  string    `json:"upstream"`
+	Count     int64     `json:"count"`
+	Failures  int64     `json:"failures"`
+	AvgTimeMs float64   `json:"avg_time_ms"`
+}
+
+// UpstreamAnalytics 上游性能分析结果
+type UpstreamAnalytics struct {
+	StartTime   time.Time            `json:"start_time"`
+	EndTime     time.Time            `json:"end_time"`
+	Interval    string               `json:"interval"`
+	Total       int64                `json:"total"`
+	Failures    int64                `json:"failures"`
+	HasUpstream bool                 `json:"has_upstream"` // 日志中是否记录了应答上游，否则只有上游组维度
+	Upstreams   []UpstreamStat       `json:"upstreams"`
+	ByNode      []NodeUpstreamStat   `json:"by_node"`
+	Trend       []UpstreamTrendPoint `json:"trend"`
+}
@@ -0,0 +1,27 @@
+package models
+
+// 应答匹配方式
+const (
+	AnswerMatchAll = "all" // 所有应答都必须在期望范围内（默认，可发现混入的劫持地址）
+	AnswerMatchAny = "any" // 至少一个应答在期望范围内（适合 CDN 等地址不固定的域名）
+)
+
+// AnswerMonitor 通过节点解析域名并校验应答
+type AnswerMonitor struct {
+	Domain     string   `json:"domain"`
+	RecordType string   `json:"record_type"` // A 或 AAAA，默认 A
+	Expect     []string `json:"expect"`      // 期望的 IP 或 CIDR 网段
+	Match      string   `json:"match"`       // 见 AnswerMatch* 常量
+}
+
+// AnswerCheckResult 单个节点对单个域名的校验结果
+type AnswerCheckResult struct {
+	NodeID     uint     `json:"node_id"`
+	NodeName   string   `json:"node_name"`
+	Domain     string   `json:"domain"`
+	RecordType string   `json:"record_type"`
+	Answers    []string `json:"answers"`
+	Unexpected []string `json:"unexpected"` // 不在期望范围内的应答
+	Matched    bool     `json:"matched"`
+	Error      string   `json:"error,omitempty"` // 解析失败（超时、节点不可达等）时不判定为不匹配
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// API 令牌权限范围
+const (
+	APITokenScopeRead = "read" // 只读，仅允许 GET 请求
+	APITokenScopeSync = "sync" // 只读 + 触发配置同步、应用变更集、重载服务
+	APITokenScopeFull = "full" // 与创建者相同的全部权限（令牌管理除外）
+)
+
+// APITokenPrefix 令牌明文前缀，认证时据此区分 API 令牌和登录 JWT
+const APITokenPrefix = "sdm_"
+
+// APIToken 供 CI 流水线和外部工具使用的 API 令牌，只保存明文的 SHA-256 摘要
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	Name       string     `json:"name" gorm:"not null"` // 服务账号名称，如 gitlab-ci
+	Scope      string     `json:"scope" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	Prefix     string     `json:"prefix"` // 明文前几位，便于识别
+	UserID     uint       `json:"user_id" gorm:"index"`
+	CreatedBy  string     `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at"` // 为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	Revoked    bool       `json:"revoked"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APITokenRequest 创建 API 令牌请求
+type APITokenRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Scope         string `json:"scope" binding:"required"`
+	ExpiresInDays int    `json:"expires_in_days"` // 0 表示永不过期
+}
+
+// ValidAPITokenScope 是否为支持的令牌权限范围
+func ValidAPITokenScope(scope string) bool {
+	switch scope {
+	case APITokenScopeRead, APITokenScopeSync, APITokenScopeFull:
+		return true
+	}
+	return false
+}
+
+// HashAPIToken 计算令牌明文的摘要，数据库中只保存摘要
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
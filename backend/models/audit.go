@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// 审计操作类型
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// 审计实体类型
+const (
+	AuditEntityAddress         = "address"
+	AuditEntityServer          = "server"
+	AuditEntityDomainSet       = "domain_set"
+	AuditEntityDomainRule      = "domain_rule"
+	AuditEntityNameserver      = "nameserver"
+	AuditEntityClientRule      = "client_rule"
+	AuditEntityGroupBlock      = "group_block"
+	AuditEntityBlocklist       = "blocklist"
+	AuditEntityMaintenance     = "maintenance_window"
+	AuditEntityCompliance      = "compliance_policy"
+	AuditEntityScriptTemplate  = "script_template"
+	AuditEntityBackupRetention = "backup_retention"
+	AuditEntityLogRetention    = "log_retention"
+	AuditEntityVersionPin      = "smartdns_version_pin"
+)
+
+// AuditLog 配置变更审计记录
+type AuditLog struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	EntityType string    `json:"entity_type" gorm:"index:idx_audit_entity"` // address、server、domain_rule 等
+	EntityID   uint      `json:"entity_id" gorm:"index:idx_audit_entity"`
+	EntityName string    `json:"entity_name"` // 便于阅读的名称（域名、地址等）
+	Action     string    `json:"action" gorm:"index"`
+	UserID     uint      `json:"user_id" gorm:"index"`
+	Username   string    `json:"username"`
+	ClientIP   string    `json:"client_ip"`
+	Changes    string    `json:"-" gorm:"type:text"` // JSON 编码的 []FieldChange
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+
+	FieldChanges []FieldChange `json:"changes" gorm:"-"`
+}
+
+// FieldChange 字段级变更
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// UserActivity 用户操作统计
+type UserActivity struct {
+	UserID       uint      `json:"user_id"`
+	Username     string    `json:"username"`
+	Total        int64     `json:"total"`
+	Creates      int64     `json:"creates"`
+	Updates      int64     `json:"updates"`
+	Deletes      int64     `json:"deletes"`
+	LastActiveAt time.Time `json:"last_active_at"`
+}
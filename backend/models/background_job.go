@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 后台任务状态
+const (
+	BackgroundJobStatusQueued      = "queued"
+	BackgroundJobStatusRunning     = "running"
+	BackgroundJobStatusSucceeded   = "succeeded"
+	BackgroundJobStatusFailed      = "failed"
+	BackgroundJobStatusInterrupted = "interrupted" // 服务重启时中断且无法自动恢复，需要按 Guidance 人工处理
+	BackgroundJobStatusCancelled   = "cancelled"
+)
+
+// 后台任务类型
+const (
+	BackgroundJobNodeInit        = "node_init"        // 初始化节点（安装 SmartDNS）
+	BackgroundJobNodeUninstall   = "node_uninstall"   // 卸载节点上的 SmartDNS
+	BackgroundJobNodeReinstall   = "node_reinstall"   // 重新安装 SmartDNS
+	BackgroundJobFullSync        = "full_sync"        // 完整同步一个或多个节点
+	BackgroundJobDNSLogExport    = "dns_log_export"   // 导出 DNS 日志
+	BackgroundJobAgentDeploy     = "agent_deploy"     // 部署日志采集 Agent
+	BackgroundJobDatabaseBackup  = "database_backup"  // 按备份配置手动备份数据库
+	BackgroundJobSmartDNSUpgrade = "smartdns_upgrade" // 升级节点上的 SmartDNS
+)
+
+// BackgroundJob 持久化的后台异步操作，由固定数量的 worker 按提交顺序执行；
+// 服务重启后排队中的任务继续排队，可从检查点恢复的任务自动继续执行，不能恢复的任务标记为 interrupted 并给出处理建议
+type BackgroundJob struct {
+	ID              uint            `json:"id" gorm:"primarykey"`
+	Type            string          `json:"type" gorm:"index"`
+	NodeID          uint            `json:"node_id" gorm:"index"` // 不针对单个节点时为 0
+	Description     string          `json:"description"`
+	Payload         string          `json:"-" gorm:"type:text"` // JSON 编码的任务参数
+	Status          string          `json:"status" gorm:"index"`
+	Checkpoint      string          `json:"checkpoint"` // 最近完成的步骤，恢复时从下一步继续
+	Resumes         int             `json:"resumes"`    // 服务重启后自动恢复的次数
+	Progress        int             `json:"progress"`   // 执行进度 0-100
+	ProgressMessage string          `json:"progress_message"`
+	Result          json.RawMessage `json:"result,omitempty" gorm:"type:blob"` // JSON 编码的执行结果，如部署输出
+	Error           string          `json:"error" gorm:"type:text"`
+	Guidance        string          `json:"guidance" gorm:"type:text"` // 中断后的处理建议
+	RequestID       string          `json:"request_id" gorm:"index"`   // 提交任务的请求 ID，用于关联后端日志
+	StartedAt       *time.Time      `json:"started_at"`
+	FinishedAt      *time.Time      `json:"finished_at"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
@@ -5,22 +5,52 @@ import (
 )
 
 type Backup struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	NodeID      uint      `gorm:"not null;index" json:"node_id"`
-	Path        string    `gorm:"type:varchar(500);not null" json:"path"`
-	Name        string    `gorm:"type:varchar(255);not null" json:"name"`
-	Size        int64     `json:"size"`
-	IsAuto      bool      `gorm:"default:false" json:"is_auto"`
-	Comment     string    `gorm:"type:text" json:"comment"`
-	Tags        string    `gorm:"type:varchar(255)" json:"tags"`
-	IsDeleted   bool      `gorm:"default:false" json:"is_deleted"`
-	StorageType string    `gorm:"type:varchar(20);default:'local'" json:"storage_type"` // local 或 s3
-	S3Bucket    string    `gorm:"type:varchar(255)" json:"s3_bucket,omitempty"`
-	S3Key       string    `gorm:"type:varchar(500)" json:"s3_key,omitempty"`
-	S3Region    string    `gorm:"type:varchar(50)" json:"s3_region,omitempty"`
-	DownloadURL string    `gorm:"type:varchar(1000)" json:"download_url,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	NodeID      uint       `gorm:"not null;index" json:"node_id"`
+	Path        string     `gorm:"type:varchar(500);not null" json:"path"`
+	Name        string     `gorm:"type:varchar(255);not null" json:"name"`
+	Size        int64      `json:"size"`
+	IsAuto      bool       `gorm:"default:false" json:"is_auto"`
+	Comment     string     `gorm:"type:text" json:"comment"`
+	Tags        string     `gorm:"type:varchar(255)" json:"tags"`
+	IsDeleted   bool       `gorm:"default:false" json:"is_deleted"`
+	StorageType string     `gorm:"type:varchar(20);default:'local'" json:"storage_type"` // local、s3、sftp、webdav、azure_blob 或 gcs
+	S3Bucket    string     `gorm:"type:varchar(255)" json:"s3_bucket,omitempty"`
+	S3Key       string     `gorm:"type:varchar(500)" json:"s3_key,omitempty"`
+	S3Region    string     `gorm:"type:varchar(50)" json:"s3_region,omitempty"`
+	DownloadURL string     `gorm:"type:varchar(1000)" json:"download_url,omitempty"`
+	ContentHash string     `gorm:"type:varchar(64);index" json:"content_hash"` // 配置内容 SHA-256，内容未变化时不创建新备份
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`                     // 最近一次确认节点配置与该备份相同的时间
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 
 	Node Node `gorm:"foreignKey:NodeID" json:"node,omitempty"`
 }
+
+// 默认备份保留策略
+const (
+	DefaultBackupKeepLast   = 10
+	DefaultBackupKeepDaily  = 7
+	DefaultBackupKeepWeekly = 4
+)
+
+// NodeBackupRetention 节点备份保留策略，只清理自动备份，手动备份需手动删除
+//
+// 保留最近 KeepLast 个，另外最近 KeepDaily 天每天、最近 KeepWeekly 周每周各保留最新的一个；
+// 三项都为 0 时不清理。
+type NodeBackupRetention struct {
+	NodeID     uint      `gorm:"primaryKey;autoIncrement:false" json:"node_id"`
+	KeepLast   int       `json:"keep_last"`
+	KeepDaily  int       `json:"keep_daily"`
+	KeepWeekly int       `json:"keep_weekly"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ConfigAdjustment 跨节点恢复时对配置的自动调整
+type ConfigAdjustment struct {
+	LineNumber int    `json:"line_number"`
+	Directive  string `json:"directive"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	Note       string `json:"note,omitempty"` // 无法自动调整时的提示
+}
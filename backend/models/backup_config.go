@@ -24,6 +24,10 @@ type BackupConfig struct {
 	S3Endpoint        string    `gorm:"type:varchar(500)" json:"s3_endpoint,omitempty"`       // 自定义S3端点(如MinIO)
 	S3Prefix          string    `gorm:"type:varchar(255);default:'smartdns-backups'" json:"s3_prefix"` // S3存储前缀
 	
+	// 其他远程存储配置，StorageType 为空时按 S3Enabled 使用 S3
+	StorageType       string    `gorm:"type:varchar(20)" json:"storage_type,omitempty"`       // s3、sftp、webdav、azure_blob 或 gcs
+	StorageBackend    string    `gorm:"type:text" json:"storage_backend,omitempty"`           // 存储后端配置JSON
+	
 	// 本地存储配置
 	LocalPath         string    `gorm:"type:varchar(500)" json:"local_path,omitempty"`        // 本地备份路径
 	
@@ -33,7 +37,8 @@ type BackupConfig struct {
 	
 	// 加密配置
 	EncryptionEnabled bool      `gorm:"default:false" json:"encryption_enabled"`             // 是否加密
-	EncryptionKey     string    `gorm:"type:varchar(255)" json:"encryption_key,omitempty"`   // 加密密钥
+	EncryptionKeyID   *uint     `gorm:"index" json:"encryption_key_id,omitempty"`            // 引用的加密密钥ID
+	EncryptionKey     string    `gorm:"type:varchar(255)" json:"-"`                          // 已废弃：明文密钥，启动时迁移为命名密钥
 	
 	// 通知配置
 	NotifyOnSuccess   bool      `gorm:"default:false" json:"notify_on_success"`              // 成功时通知
@@ -55,7 +60,8 @@ type BackupConfig struct {
 type BackupHistory struct {
 	ID               uint      `gorm:"primaryKey" json:"id"`
 	ConfigID         uint      `gorm:"not null;index" json:"config_id"`                    // 关联的配置ID
-	BackupType       string    `gorm:"type:varchar(20)" json:"backup_type"`               // 备份类型
+	TaskID           uint      `gorm:"index" json:"task_id,omitempty"`                     // 产生该备份的定时任务ID（ClickHouse 备份）
+	BackupType       string    `gorm:"type:varchar(20)" json:"backup_type"`               // 备份类型：database, clickhouse
 	Status           string    `gorm:"type:varchar(20)" json:"status"`                    // 状态：running, success, failed
 	
 	// 文件信息
@@ -67,6 +73,11 @@ type BackupHistory struct {
 	S3Key            string    `gorm:"type:varchar(500)" json:"s3_key,omitempty"`        // S3对象键
 	S3Bucket         string    `gorm:"type:varchar(255)" json:"s3_bucket,omitempty"`     // S3存储桶
 	S3Region         string    `gorm:"type:varchar(100)" json:"s3_region,omitempty"`     // S3区域
+	Tables           string    `gorm:"type:varchar(500)" json:"tables,omitempty"`        // ClickHouse 备份包含的表，逗号分隔
+	
+	// 远程存储信息
+	StorageType      string    `gorm:"type:varchar(20)" json:"storage_type,omitempty"`  // 上传的远程存储类型
+	StoragePath      string    `gorm:"type:varchar(500)" json:"storage_path,omitempty"` // 远程存储中的路径
 	
 	// 执行信息
 	StartedAt        time.Time `json:"started_at"`                                        // 开始时间
@@ -74,9 +85,15 @@ type BackupHistory struct {
 	Duration         int64     `json:"duration,omitempty"`                               // 执行时长(秒)
 	ErrorMessage     string    `gorm:"type:text" json:"error_message,omitempty"`         // 错误消息
 	
+	// 加密信息（数据密钥由引用的加密密钥包装后存储）
+	EncryptionKeyID  *uint     `gorm:"index" json:"encryption_key_id,omitempty"`        // 加密密钥ID
+	KeyVersion       int       `json:"key_version,omitempty"`                            // 包装数据密钥时的密钥版本
+	WrappedDataKey   string    `gorm:"type:text" json:"-"`                               // 包装后的数据密钥
+	
 	// 备份内容摘要
 	DatabaseSize     int64     `json:"database_size,omitempty"`                          // 原始数据库大小
 	CompressionRatio float64   `json:"compression_ratio,omitempty"`                      // 压缩比
+	Checksum         string    `gorm:"type:varchar(64)" json:"checksum,omitempty"`      // 数据库文件 SHA-256，恢复时校验
 	
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
@@ -92,6 +109,30 @@ type BackupRestoreRequest struct {
 	BackupPassword  string `json:"backup_password,omitempty"`       // 备份密码（如果加密）
 }
 
+// CHBackupRestoreRequest ClickHouse 备份恢复请求
+type CHBackupRestoreRequest struct {
+	BackupHistoryID uint   `json:"backup_history_id" binding:"required"`
+	TableSuffix     string `json:"table_suffix"` // 恢复为 <表名><后缀> 的新表；为空时替换原表
+}
+
+// BackupRestoreReport 数据库恢复报告
+type BackupRestoreReport struct {
+	BackupHistoryID  uint      `json:"backup_history_id,omitempty"`
+	FileName         string    `json:"file_name"`
+	Checksum         string    `json:"checksum"`           // 解压解密后数据库文件的 SHA-256
+	ChecksumVerified bool      `json:"checksum_verified"`  // 与备份时记录的校验和一致，旧备份没有记录时为 false
+	IntegrityCheck   string    `json:"integrity_check"`    // 备份文件的 PRAGMA integrity_check 结果
+	Tables           int       `json:"tables"`             // 备份中的表数量
+	RestoredSize     int64     `json:"restored_size"`      // 恢复的数据库大小
+	SnapshotPath     string    `json:"snapshot_path"`      // 恢复前自动快照，可用于手动回退
+	SnapshotSize     int64     `json:"snapshot_size"`
+	PostRestoreCheck string    `json:"post_restore_check"` // 恢复后当前数据库的完整性检查结果
+	RolledBack       bool      `json:"rolled_back"`        // 恢复失败后已从快照回退
+	Steps            []string  `json:"steps"`
+	StartedAt        time.Time `json:"started_at"`
+	DurationMs       int64     `json:"duration_ms"`
+}
+
 // BackupConfigRequest 备份配置请求
 type BackupConfigRequest struct {
 	Name                 string   `json:"name" binding:"required"`
@@ -106,16 +147,61 @@ type BackupConfigRequest struct {
 	S3Bucket             string   `json:"s3_bucket,omitempty"`
 	S3Endpoint           string   `json:"s3_endpoint,omitempty"`
 	S3Prefix             string   `json:"s3_prefix,omitempty"`
+	StorageType          string   `json:"storage_type,omitempty"`
+	StorageBackend       *StorageBackendConfig `json:"storage_backend,omitempty"`
 	LocalPath            string   `json:"local_path,omitempty"`
 	CompressionEnabled   bool     `json:"compression_enabled"`
 	CompressionLevel     int      `json:"compression_level"`
 	EncryptionEnabled    bool     `json:"encryption_enabled"`
-	EncryptionKey        string   `json:"encryption_key,omitempty"`
+	EncryptionKeyID      *uint    `json:"encryption_key_id,omitempty"`
 	NotifyOnSuccess      bool     `json:"notify_on_success"`
 	NotifyOnFailure      bool     `json:"notify_on_failure"`
 	NotificationChannels []uint   `json:"notification_channels,omitempty"`
 }
 
+// 加密密钥提供方
+const (
+	BackupKeyProviderLocal = "local" // 系统生成，使用主密钥包装后存储
+	BackupKeyProviderEnv   = "env"   // 引用外部注入的环境变量（如 KMS/Vault Agent 下发）
+)
+
+// BackupEncryptionKey 备份加密密钥
+type BackupEncryptionKey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"type:varchar(100);uniqueIndex;not null" json:"name"` // 密钥名称
+	Description string     `gorm:"type:varchar(500)" json:"description"`
+	Provider    string     `gorm:"type:varchar(20);default:'local'" json:"provider"` // local, env
+	KeyRef      string     `gorm:"type:varchar(255)" json:"key_ref,omitempty"`       // env 提供方的环境变量名
+	WrappedKey  string     `gorm:"type:text" json:"-"`                               // local 提供方：主密钥包装后的密钥材料
+	Version     int        `gorm:"default:1" json:"version"`                         // 密钥版本，每次轮换递增
+	RotatedAt   *time.Time `json:"rotated_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	ConfigCount int64 `gorm:"-" json:"config_count"` // 引用该密钥的备份配置数
+	BackupCount int64 `gorm:"-" json:"backup_count"` // 依赖该密钥的备份数
+}
+
+// BackupKeyRequest 创建加密密钥请求
+type BackupKeyRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Provider    string `json:"provider"`
+	KeyRef      string `json:"key_ref"`
+}
+
+// BackupKeyRotateRequest 轮换加密密钥请求（env 提供方需指定新的环境变量名）
+type BackupKeyRotateRequest struct {
+	KeyRef string `json:"key_ref"`
+}
+
+// BackupKeyUsage 加密密钥的依赖情况
+type BackupKeyUsage struct {
+	Key     BackupEncryptionKey `json:"key"`
+	Configs []BackupConfig      `json:"configs"`
+	Backups []BackupHistory     `json:"backups"`
+}
+
 // BackupStats 备份统计信息
 type BackupStats struct {
 	TotalConfigs       int     `json:"total_configs"`
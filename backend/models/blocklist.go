@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// 订阅刷新状态
+const (
+	BlocklistStatusSuccess   = "success"   // 已下载并更新域名集
+	BlocklistStatusUnchanged = "unchanged" // 远端列表未变化
+	BlocklistStatusFailed    = "failed"    // 下载或解析失败
+)
+
+// BlocklistSubscription 远程屏蔽列表订阅，定时下载列表并替换目标域名集的内容
+type BlocklistSubscription struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	Name            string     `json:"name" gorm:"uniqueIndex;not null"`
+	URL             string     `json:"url" gorm:"not null"`
+	Format          string     `json:"format" gorm:"default:auto"`           // 列表格式，同地址导入（auto/hosts/adguard/plain 等）
+	DomainSetID     uint       `json:"domain_set_id" gorm:"not null;index"`  // 目标域名集，内容由订阅完全接管
+	RefreshInterval int        `json:"refresh_interval" gorm:"default:1440"` // 刷新间隔（分钟）
+	Enabled         bool       `json:"enabled" gorm:"default:true"`
+	LastFetchedAt   *time.Time `json:"last_fetched_at"`
+	LastSuccessAt   *time.Time `json:"last_success_at"`
+	LastStatus      string     `json:"last_status"`
+	LastError       string     `json:"last_error"`
+	ETag            string     `json:"-"`
+	LastModified    string     `json:"-"`
+	Checksum        string     `json:"checksum"`     // 规范化后域名列表的 SHA256
+	DomainCount     int        `json:"domain_count"` // 最近一次解析出的域名数
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	DomainSetName string `json:"domain_set_name" gorm:"-"`
+}
+
+// BlocklistSubscriptionRequest 订阅请求
+type BlocklistSubscriptionRequest struct {
+	Name            string `json:"name" binding:"required"`
+	URL             string `json:"url" binding:"required"`
+	Format          string `json:"format"`
+	DomainSetID     uint   `json:"domain_set_id" binding:"required"`
+	RefreshInterval int    `json:"refresh_interval"`
+	Enabled         *bool  `json:"enabled"`
+}
+
+// BlocklistRefreshResult 单个订阅的刷新结果
+type BlocklistRefreshResult struct {
+	SubscriptionID uint   `json:"subscription_id"`
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	DomainCount    int    `json:"domain_count"`
+	Added          int    `json:"added"`
+	Removed        int    `json:"removed"`
+	Error          string `json:"error,omitempty"`
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 变更集状态
+const (
+	ChangeSetStatusPending   = "pending"   // 已计划，等待应用
+	ChangeSetStatusApplying  = "applying"  // 正在应用到节点
+	ChangeSetStatusApplied   = "applied"   // 所有节点应用成功
+	ChangeSetStatusPartial   = "partial"   // 部分节点失败，可重试
+	ChangeSetStatusFailed    = "failed"    // 全部失败或数据库提交失败
+	ChangeSetStatusDiscarded = "discarded" // 已放弃
+)
+
+// 变更集节点状态
+const (
+	ChangeNodeStatusPending = "pending"
+	ChangeNodeStatusRunning = "running"
+	ChangeNodeStatusSuccess = "success"
+	ChangeNodeStatusFailed  = "failed"
+	ChangeNodeStatusSkipped = "skipped" // 该节点配置无变化
+)
+
+// 变更对象类型
+const (
+	ChangeKindAddress    = "address"
+	ChangeKindServer     = "server"
+	ChangeKindDomainRule = "domain_rule"
+)
+
+// ChangeSet 批量配置变更集，先计划（计算各节点差异），确认后统一应用
+type ChangeSet struct {
+	ID             uint       `json:"id" gorm:"primarykey"`
+	Description    string     `json:"description"`
+	Status         string     `json:"status" gorm:"index"`
+	Changes        string     `json:"-" gorm:"type:text"` // JSON 编码的 []ChangeItem
+	UserID         uint       `json:"user_id"`
+	Username       string     `json:"username"`
+	TotalNodes     int        `json:"total_nodes"`
+	SucceededNodes int        `json:"succeeded_nodes"`
+	FailedNodes    int        `json:"failed_nodes"`
+	Error          string     `json:"error" gorm:"type:text"`
+	AppliedAt      *time.Time `json:"applied_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Items []ChangeItem    `json:"changes" gorm:"-"`
+	Nodes []ChangeSetNode `json:"nodes,omitempty" gorm:"foreignKey:ChangeSetID"`
+}
+
+// ChangeItem 单项变更，Data 为变更后的对象，Before 为计划时的原对象（新增时为空）
+type ChangeItem struct {
+	Kind   string          `json:"kind"`   // address、server、domain_rule
+	Action string          `json:"action"` // create、update、delete
+	ID     uint            `json:"id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Before json.RawMessage `json:"before,omitempty"`
+}
+
+// ChangeSetNode 变更集在单个节点上的差异与执行结果
+type ChangeSetNode struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	ChangeSetID uint       `json:"change_set_id" gorm:"index"`
+	NodeID      uint       `json:"node_id"`
+	NodeName    string     `json:"node_name"`
+	Status      string     `json:"status"`
+	Added       string     `json:"-" gorm:"type:text"` // JSON 编码的 []string
+	Removed     string     `json:"-" gorm:"type:text"` // JSON 编码的 []string
+	Changed     string     `json:"-" gorm:"type:text"` // JSON 编码的 []ConfigLineChange
+	Error       string     `json:"error" gorm:"type:text"`
+	Attempts    int        `json:"attempts"`
+	StartedAt   *time.Time `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	AddedLines   []string           `json:"added" gorm:"-"`
+	RemovedLines []string           `json:"removed" gorm:"-"`
+	ChangedLines []ConfigLineChange `json:"changed" gorm:"-"`
+}
+
+// ConfigLineChange 配置项变更前后的行
+type ConfigLineChange struct {
+	Key    string `json:"key"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+func (ChangeSet) TableName() string {
+	return "change_sets"
+}
+
+func (ChangeSetNode) TableName() string {
+	return "change_set_nodes"
+}
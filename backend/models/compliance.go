@@ -0,0 +1,126 @@
+package models
+
+import "time"
+
+// 合规策略比较方式
+const (
+	ComplianceOpEquals    = "eq"        // 等于 Value
+	ComplianceOpNotEquals = "ne"        // 不等于 Value
+	ComplianceOpIn        = "in"        // 属于 Value 中逗号分隔的取值之一
+	ComplianceOpGTE       = "gte"       // 数值不小于 Value
+	ComplianceOpLTE       = "lte"       // 数值不大于 Value
+	ComplianceOpLevelGTE  = "level_gte" // 日志级别不低于 Value（debug < info < notice < warn < error < fatal）
+	ComplianceOpContains  = "contains"  // 多值指令中包含 Value，如 server-group 包含 internal
+	ComplianceOpPresent   = "present"   // 必须配置该指令
+	ComplianceOpAbsent    = "absent"    // 不允许配置该指令
+)
+
+// 合规策略严重程度
+const (
+	ComplianceSeverityInfo     = "info"
+	ComplianceSeverityWarning  = "warning"
+	ComplianceSeverityCritical = "critical"
+)
+
+// 违规状态
+const (
+	ComplianceViolationOpen     = "open"
+	ComplianceViolationResolved = "resolved"
+)
+
+// ComplianceDirectiveServerGroup 特殊指令名：所有 server 的 -group 分组
+const ComplianceDirectiveServerGroup = "server-group"
+
+// CompliancePolicy 节点配置合规策略，定时对每个节点解析后的配置求值
+//
+// NodeIDs 和 Tag 都为空时对所有节点生效。AutoRemediate 开启时，违规节点会执行一次完整同步，
+// 有 FixValue 的策略在同步时把指令改为 FixValue（absent 策略删除该指令）。
+type CompliancePolicy struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Name          string    `json:"name" gorm:"uniqueIndex;not null"`
+	Description   string    `json:"description"`
+	Directive     string    `json:"directive" gorm:"not null"` // 配置指令，如 log-level、cache-size、server-group
+	Operator      string    `json:"operator" gorm:"not null"`
+	Value         string    `json:"value"`
+	Severity      string    `json:"severity" gorm:"default:warning"`
+	NodeIDs       string    `json:"node_ids"` // JSON 数组
+	Tag           string    `json:"tag"`      // 按节点标签选择
+	AutoRemediate bool      `json:"auto_remediate" gorm:"default:false"`
+	FixValue      string    `json:"fix_value"` // 自动修复时写入的值
+	Enabled       bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	OpenViolations int64 `json:"open_violations" gorm:"-"`
+}
+
+// CompliancePolicyRequest 合规策略请求
+type CompliancePolicyRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Description   string `json:"description"`
+	Directive     string `json:"directive" binding:"required"`
+	Operator      string `json:"operator" binding:"required"`
+	Value         string `json:"value"`
+	Severity      string `json:"severity"`
+	NodeIDs       []uint `json:"node_ids"`
+	Tag           string `json:"tag"`
+	AutoRemediate bool   `json:"auto_remediate"`
+	FixValue      string `json:"fix_value"`
+	Enabled       *bool  `json:"enabled"`
+}
+
+// ComplianceViolation 节点违反合规策略的记录，同一策略和节点同时只有一条未解决的记录
+type ComplianceViolation struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	PolicyID         uint       `json:"policy_id" gorm:"index"`
+	PolicyName       string     `json:"policy_name"`
+	NodeID           uint       `json:"node_id" gorm:"index"`
+	NodeName         string     `json:"node_name"`
+	Severity         string     `json:"severity"`
+	Expected         string     `json:"expected"` // 策略要求，如 cache-size >= 4096
+	Actual           string     `json:"actual"`   // 节点上的实际值
+	Status           string     `json:"status" gorm:"index;default:open"`
+	FirstSeenAt      time.Time  `json:"first_seen_at"`
+	LastSeenAt       time.Time  `json:"last_seen_at"`
+	ResolvedAt       *time.Time `json:"resolved_at"`
+	RemediatedAt     *time.Time `json:"remediated_at"` // 由自动修复解决
+	RemediationError string     `json:"remediation_error" gorm:"type:text"`
+}
+
+// ComplianceNodeCheck 节点最近一次合规检查结果
+type ComplianceNodeCheck struct {
+	NodeID     uint      `json:"node_id" gorm:"primaryKey;autoIncrement:false"`
+	NodeName   string    `json:"node_name"`
+	Policies   int       `json:"policies"`   // 适用的策略数
+	Violations int       `json:"violations"` // 未通过的策略数
+	Error      string    `json:"error" gorm:"type:text"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// ComplianceSummary 合规概览
+type ComplianceSummary struct {
+	Policies       int64                     `json:"policies"`        // 启用的策略数
+	CheckedNodes   int                       `json:"checked_nodes"`   // 有检查结果的节点数
+	CompliantNodes int                       `json:"compliant_nodes"` // 全部通过的节点数
+	FailedNodes    int                       `json:"failed_nodes"`    // 检查失败的节点数
+	OpenViolations int64                     `json:"open_violations"`
+	BySeverity     map[string]int64          `json:"by_severity"`
+	Nodes          []ComplianceNodeSummary   `json:"nodes"`
+	ByPolicy       []CompliancePolicySummary `json:"by_policy"`
+}
+
+// ComplianceNodeSummary 单个节点的合规情况
+type ComplianceNodeSummary struct {
+	ComplianceNodeCheck
+	Critical int64 `json:"critical"`
+	Warning  int64 `json:"warning"`
+	Info     int64 `json:"info"`
+}
+
+// CompliancePolicySummary 单个策略的违规节点数
+type CompliancePolicySummary struct {
+	PolicyID       uint   `json:"policy_id"`
+	PolicyName     string `json:"policy_name"`
+	Severity       string `json:"severity"`
+	ViolatingNodes int64  `json:"violating_nodes"`
+}
@@ -12,7 +12,39 @@ type SmartDNSConfig struct {
 	DomainSets    []DomainSet       `json:"domain_sets"`
 	DomainRules   []DomainRule      `json:"domain_rules"`
 	Nameservers   []Nameserver      `json:"nameservers"`
+	ClientRules   []ClientRule      `json:"client_rules"`
 	BasicSettings map[string]string `json:"basic_settings"`
+	Directives    []ConfigDirective `json:"directives"`  // 其他指令（bind-tcp、ip-rules、proxy-server 等），按原顺序保留
+	Groups        []ConfigGroup     `json:"groups"`      // group-begin/group-end 配置块
+	ConfFiles     []string          `json:"conf_files"`  // conf-file 引入的配置文件
+	OtherLines    []ConfigLine      `json:"other_lines"` // 无法识别的指令，原样保留
+}
+
+// ConfigDirective 通用配置指令
+type ConfigDirective struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ConfigLine 原样保留的配置行
+type ConfigLine struct {
+	LineNumber int    `json:"line_number"` // 在原配置文件中的行号
+	Content    string `json:"content"`
+	After      string `json:"after,omitempty"` // 原文件中紧邻的前一条指令，生成时写回到它之后
+}
+
+// ConfigAnnotation 受管指令行尾的溯源注释，标明生成该行的管理记录
+type ConfigAnnotation struct {
+	Kind      string `json:"kind"` // 与审计实体类型一致：address、server、domain_rule 等
+	ID        uint   `json:"id"`
+	UpdatedAt string `json:"updated_at"` // 记录最后修改时间
+	Author    string `json:"author"`     // 最后修改人，来自审计日志
+}
+
+// ConfigGroup group-begin/group-end 配置块，块内指令原样保留
+type ConfigGroup struct {
+	Name  string   `json:"name"`
+	Lines []string `json:"lines"`
 }
 
 // DNSServer DNS服务器
@@ -32,18 +64,21 @@ type DNSServer struct {
 
 // AddressMap 地址映射
 type AddressMap struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	Domain    string         `gorm:"not null;index" json:"domain"`
-	IP        string         `json:"ip"`                          // 可以为空
-	CNAME     string         `json:"cname"`                       // 新增：CNAME别名
-	Type      string         `gorm:"default:address" json:"type"` // 新增：类型 address/cname
-	Tags      string         `json:"tags"`
-	Comment   string         `json:"comment"`
-	NodeIDs   string         `gorm:"default:[]" json:"node_ids"`
-	Enabled   bool           `gorm:"default:true" json:"enabled"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primarykey" json:"id"`
+	OrganizationID uint           `gorm:"index;default:0" json:"organization_id"` // 所属组织，0 表示未分配
+	Domain         string         `gorm:"not null;index" json:"domain"`
+	IP             string         `json:"ip"`                          // 可以为空，多个 IP（可同时包含 IPv4 和 IPv6）以逗号分隔
+	CNAME          string         `json:"cname"`                       // 新增：CNAME别名
+	Type           string         `gorm:"default:address" json:"type"` // 新增：类型 address/cname
+	Tags           string         `json:"tags"`
+	Comment        string         `json:"comment"`
+	TTL            int            `json:"ttl"`              // 应答 TTL，通过 domain-rules -rr-ttl 生成，0 表示使用全局设置
+	NoServeExpired bool           `json:"no_serve_expired"` // 不返回过期缓存（-no-serve-expired）
+	NodeIDs        string         `gorm:"default:[]" json:"node_ids"`
+	Enabled        bool           `gorm:"default:true" json:"enabled"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // DomainSet 域名集
@@ -53,7 +88,9 @@ type DomainSet struct {
 	FilePath    string    `json:"file_path" gorm:"not null"`
 	Description string    `json:"description"`
 	DomainCount int       `json:"domain_count" gorm:"default:0"`
-	NodeIDs     string    `json:"node_ids"` // JSON 数组，应用到哪些节点
+	Category    string    `json:"category" gorm:"index"` // 分类（ads/tracking/cdn/internal 等），用于日志打标
+	NodeIDs     string    `json:"node_ids"`              // JSON 数组，应用到哪些节点
+	Checksum    string    `json:"checksum"`              // 最近一次生成的域名集文件 SHA256
 	Enabled     bool      `json:"enabled" gorm:"default:true"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -71,6 +108,7 @@ type DomainSetItem struct {
 // DomainRule 域名规则
 type DomainRule struct {
 	ID             uint      `json:"id" gorm:"primarykey"`
+	OrganizationID uint      `json:"organization_id" gorm:"index;default:0"`
 	Domain         string    `json:"domain" gorm:"not null;index"`       // 域名或 domain-set:name
 	IsDomainSet    bool      `json:"is_domain_set" gorm:"default:false"` // 是否引用域名集
 	DomainSetName  string    `json:"domain_set_name"`                    // 域名集名称
@@ -101,6 +139,24 @@ type Nameserver struct {
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// ClientRuleBlockedGroup 被阻断客户端使用的分组，组内所有域名解析为 #
+const ClientRuleBlockedGroup = "blocked-clients"
+
+// ClientRule 客户端规则（按客户端 IP/CIDR/MAC 指定分组或阻断）
+type ClientRule struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Client      string    `json:"client" gorm:"not null;index"` // 客户端 IP、CIDR 或 MAC
+	Group       string    `json:"group"`                        // -group 参数
+	Blocked     bool      `json:"blocked" gorm:"default:false"` // 是否阻断该客户端的所有解析
+	Options     string    `json:"options"`                      // 其他选项，如 -no-cache
+	NodeIDs     string    `json:"node_ids"`                     // JSON 数组
+	Enabled     bool      `json:"enabled" gorm:"default:true"`
+	Priority    int       `json:"priority" gorm:"default:0"` // 优先级，数字越大越优先
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 // ConfigSyncLog 配置同步日志
 type ConfigSyncLog struct {
 	ID        uint      `json:"id" gorm:"primarykey"`
@@ -111,4 +167,9 @@ type ConfigSyncLog struct {
 	Status    string    `json:"status"` // pending, success, failed
 	Error     string    `json:"error"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// 自动重试：失败后由同步任务队列以完整同步重试（删除操作除外），NextRetryAt 为空表示不再自动重试
+	Attempts    int        `json:"attempts"`
+	NextRetryAt *time.Time `json:"next_retry_at" gorm:"index"`
+	RetryJobID  uint       `json:"retry_job_id" gorm:"index"` // 负责重试的同步任务
 }
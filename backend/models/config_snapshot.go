@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// 配置快照来源
+const (
+	ConfigSnapshotSourceManager   = "manager"   // 管理端写入配置时记录
+	ConfigSnapshotSourceScheduled = "scheduled" // 定时采集或漂移检测时读取
+)
+
+// NodeConfigSnapshot 节点配置快照，只在内容变化时保存新记录
+type NodeConfigSnapshot struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	NodeID       uint      `json:"node_id" gorm:"index"`
+	NodeName     string    `json:"node_name"`
+	Hash         string    `json:"hash" gorm:"size:64;index"` // 配置内容的 SHA-256
+	Content      string    `json:"content,omitempty" gorm:"type:text"`
+	Size         int       `json:"size"`
+	Source       string    `json:"source" gorm:"size:20"`
+	OutOfBand    bool      `json:"out_of_band" gorm:"index"` // 定时采集发现的非管理端修改
+	AddedCount   int       `json:"added_count"`              // 相比上一快照新增的行数
+	RemovedCount int       `json:"removed_count"`            // 相比上一快照删除的行数
+	ChangedCount int       `json:"changed_count"`            // 相比上一快照修改的行数
+	CapturedAt   time.Time `json:"captured_at" gorm:"index"`
+	LastSeenAt   time.Time `json:"last_seen_at"` // 最近一次确认内容未变化的时间
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (NodeConfigSnapshot) TableName() string {
+	return "node_config_snapshots"
+}
+
+// ConfigSnapshotDiff 快照与上一快照的差异
+type ConfigSnapshotDiff struct {
+	Snapshot NodeConfigSnapshot  `json:"snapshot"`
+	Previous *NodeConfigSnapshot `json:"previous"`
+	Added    []string            `json:"added"`
+	Removed  []string            `json:"removed"`
+	Changed  []DriftLineChange   `json:"changed"`
+}
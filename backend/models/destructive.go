@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// DestructiveImpact 破坏性操作的影响预估
+type DestructiveImpact struct {
+	Summary string           `json:"summary"` // 给用户确认的说明
+	Counts  map[string]int64 `json:"counts"`  // 受影响的对象数量，如 {"sync_logs": 120}
+}
+
+// DestructivePreview 预估请求（dry_run=true）的返回，提交时将 ConfirmToken 放在 X-Confirm-Token 请求头
+type DestructivePreview struct {
+	Operation    string            `json:"operation"`
+	Impact       DestructiveImpact `json:"impact"`
+	ConfirmToken string            `json:"confirm_token"`
+	ExpiresAt    time.Time         `json:"expires_at"`
+	Enforced     bool              `json:"enforced"` // 是否必须携带确认令牌
+}
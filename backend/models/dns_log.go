@@ -19,7 +19,23 @@ type DNSLog struct {
 	IPCount   int       `json:"ip_count"`
 	RawLog    string    `json:"raw_log" gorm:"type:text"`
 	Group     string    `json:"group" gorm:"text"`
+	Upstream  string    `json:"upstream"` // 应答该查询的上游服务器
 	CreatedAt time.Time `json:"created_at"`
+
+	Rcode       string `json:"rcode"`        // 响应码，如 NOERROR、NXDOMAIN、SERVFAIL
+	CacheHit    bool   `json:"cache_hit"`    // 是否由缓存应答
+	MatchedRule string `json:"matched_rule"` // 命中的规则
+
+	QueryTypeName string `json:"query_type_name" gorm:"-"` // 查询类型名称，如 AAAA，不入库
+
+	DomainCategory string `json:"domain_category"`
+
+	// 客户端富化信息
+	ClientSubnet  string `json:"client_subnet"`
+	ClientCountry string `json:"client_country"`
+	ClientASN     uint32 `json:"client_asn"`
+	ClientASOrg   string `json:"client_as_org"`
+	ClientPTR     string `json:"client_ptr"`
 }
 
 func (DNSLog) TableName() string {
@@ -40,6 +56,18 @@ type DNSLogCK struct {
 	ResultIPs   []string  `json:"result_ips"`
 	RawLog      string    `json:"raw_log"`
 	Group       string    `json:"group"`
+	Upstream    string    `json:"upstream"`
+	Rcode       string    `json:"rcode"`
+	CacheHit    bool      `json:"cache_hit"` // 表中为 UInt8
+	MatchedRule string    `json:"matched_rule"`
+
+	DomainCategory string `json:"domain_category"`
+
+	ClientSubnet  string `json:"client_subnet"`
+	ClientCountry string `json:"client_country"`
+	ClientASN     uint32 `json:"client_asn"`
+	ClientASOrg   string `json:"client_as_org"`
+	ClientPTR     string `json:"client_ptr"`
 }
 
 // DNSLogStats 统计信息（通用）
@@ -51,6 +79,15 @@ type DNSLogStats struct {
 	TopDomains    []DomainStat `json:"top_domains"`
 	TopClients    []ClientStat `json:"top_clients"`
 	HourlyStats   []HourlyStat `json:"hourly_stats"`
+
+	CategoryStats      []CategoryStat      `json:"category_stats"`
+	DailyCategoryStats []DailyCategoryStat `json:"daily_category_stats"`
+	GroupStats         []GroupStat         `json:"group_stats"`
+
+	// 响应码和缓存统计，日志未输出 rcode / cache 字段时由 Agent 推断
+	RcodeStats    []RcodeStat `json:"rcode_stats"`
+	NXDomainRate  float64     `json:"nxdomain_rate"`   // NXDOMAIN 占比（0-1）
+	CacheHitRatio float64     `json:"cache_hit_ratio"` // 缓存命中率（0-1）
 }
 
 type DomainStat struct {
@@ -68,11 +105,37 @@ type HourlyStat struct {
 	Count int64 `json:"count"`
 }
 
+// CategoryStat 域名分类统计
+type CategoryStat struct {
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+}
+
+// GroupStat 按 SmartDNS 分组统计，未匹配分组规则的查询 group 为空
+type GroupStat struct {
+	Group     string  `json:"group"`
+	Count     int64   `json:"count"`
+	AvgTimeMs float64 `json:"avg_time_ms"`
+}
+
+// RcodeStat 按响应码统计，rcode 为空表示日志未输出响应码且没有应答
+type RcodeStat struct {
+	Rcode string `json:"rcode"`
+	Count int64  `json:"count"`
+}
+
+// DailyCategoryStat 按天的域名分类统计
+type DailyCategoryStat struct {
+	Date     string `json:"date"`
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+}
+
 // DeployAgentRequest 部署请求结构
 type DeployAgentRequest struct {
 	NodeID             uint   `json:"node_id" binding:"required"`
-	DeployMode         string `json:"deploy_mode" binding:"required"` // systemd 或 docker
-	ClickHouseHost     string `json:"clickhouse_host" binding:"required"`
+	DeployMode         string `json:"deploy_mode" binding:"required"` // systemd、docker 或 windows
+	ClickHouseHost     string `json:"clickhouse_host"`                // 经管理端写入（设置 ingest_url）时可为空
 	ClickHousePort     int    `json:"clickhouse_port"`
 	ClickHouseDB       string `json:"clickhouse_db"`
 	ClickHouseUser     string `json:"clickhouse_user"`
@@ -81,9 +144,45 @@ type DeployAgentRequest struct {
 	BatchSize          int    `json:"batch_size"`
 	FlushInterval      int    `json:"flush_interval"`
 
+	// 管理端控制通道地址，如 wss://manager.example.com/api/v1/agent/control；
+	// 设置后部署时生成节点控制令牌，Agent 主动连接管理端，适用于管理端无法访问 Agent API 端口的节点
+	ControlURL string `json:"control_url"`
+	// 管理端 API 地址，如 https://manager.example.com/api/v1；设置后 Agent 经管理端的 /ingest/dns-logs 写入日志，
+	// 不直接连接 ClickHouse，适用于无法向节点开放 ClickHouse 端口的网络，与控制通道共用节点控制令牌
+	IngestURL string `json:"ingest_url"`
+
+	// 附加输出：主存储写入成功后 Agent 同时把日志写入以下平台，地址为空的输出不启用，可同时启用多个
+	KafkaBrokers string `json:"kafka_brokers"` // 逗号分隔的 broker 地址
+	KafkaCluster string `json:"kafka_cluster"` // 替换主题中的 {cluster}，默认 default
+	KafkaTopic   string `json:"kafka_topic"`   // 默认 smartdns-dns-logs-{cluster}
+	KafkaFormat  string `json:"kafka_format"`  // json / avro，默认 json
+	LokiURL      string `json:"loki_url"`      // 如 http://loki:3100
+	// Loki 流标签模板，如 job=smartdns,node={node_name}；值可使用 {node_id} {node_name} {group} {date} {month}
+	LokiLabels string `json:"loki_labels"`
+	LokiTenant string `json:"loki_tenant"` // 多租户 Loki 的 X-Scope-OrgID
+	// Elasticsearch / OpenSearch 地址，可包含用户名和密码
+	ElasticsearchURL    string `json:"elasticsearch_url"`
+	ElasticsearchIndex  string `json:"elasticsearch_index"` // 索引模板，默认 smartdns-dns-logs-{date}
+	ElasticsearchAPIKey string `json:"elasticsearch_api_key,omitempty"`
+
 	ProxyType string `json:"proxy_type"` // "socks5", "http", "ssh"
 	ProxyHost string `json:"proxy_host"`
 	ProxyPort int    `json:"proxy_port"`
 	ProxyUser string `json:"proxy_user,omitempty"`
 	ProxyPass string `json:"proxy_pass,omitempty"`
+
+	// Windows 节点（deploy_mode 为 windows）的连接方式和服务托管方式，连接参数保存到节点供后续管理使用
+	Transport      string `json:"transport"`       // ssh / winrm，默认 ssh
+	WinRMPort      int    `json:"winrm_port"`      // 默认 HTTPS 5986、HTTP 5985
+	WinRMHTTPS     *bool  `json:"winrm_https"`     // 使用 HTTPS 连接 WinRM，未传时默认 true
+	WinRMInsecure  bool   `json:"winrm_insecure"`  // HTTPS 不校验证书
+	ServiceManager string `json:"service_manager"` // sc / nssm，默认 sc
+}
+
+// AgentConfigUpdateRequest 调整 Agent 批量写入参数，未传的字段保持不变；正在收集时 Agent 会重启收集使其生效
+type AgentConfigUpdateRequest struct {
+	BatchSize          *int    `json:"batch_size,omitempty"`           // 每批日志条数
+	FlushIntervalMs    *int    `json:"flush_interval_ms,omitempty"`    // 刷新间隔（毫秒）
+	MaxInflightBatches *int    `json:"max_inflight_batches,omitempty"` // 同时写入的批次数，队列满时 Agent 暂停读取日志
+	Compression        *string `json:"compression,omitempty"`          // ClickHouse 压缩算法：none / lz4 / zstd，HTTP 协议另支持 gzip / deflate / br
 }
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// DNS 日志导出格式
+const (
+	DNSLogExportFormatCSV   = "csv"
+	DNSLogExportFormatJSONL = "jsonl"
+)
+
+// DNS 日志导出位置
+const (
+	DNSLogExportLocal  = "local"  // 保存在后端服务器磁盘
+	DNSLogExportRemote = "remote" // 上传到 BACKUP_STORAGE_TYPE 配置的远程存储（S3 等）
+)
+
+// DNSLogExport DNS 日志导出记录，导出由后台任务执行，状态与后台任务一致
+type DNSLogExport struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	JobID       uint       `json:"job_id" gorm:"index"`
+	Format      string     `json:"format"`
+	Filters     string     `json:"filters" gorm:"type:text"` // JSON 编码的过滤参数，与 GET /api/dns-logs 相同
+	MaxRows     int64      `json:"max_rows"`                 // 0 表示不限制
+	Destination string     `json:"destination"`
+	StorageType string     `json:"storage_type"`
+	FilePath    string     `json:"-"` // 本地文件路径
+	StoragePath string     `json:"-"` // 远程存储中的路径
+	FileName    string     `json:"file_name"`
+	Rows        int64      `json:"rows"`
+	SizeBytes   int64      `json:"size_bytes"`
+	Status      string     `json:"status" gorm:"index"`
+	Error       string     `json:"error" gorm:"type:text"`
+	CreatedBy   string     `json:"created_by"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ConfigDriftReport 节点配置漂移报告
+type ConfigDriftReport struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	NodeID       uint       `json:"node_id" gorm:"index"`
+	NodeName     string     `json:"node_name"`
+	HasDrift     bool       `json:"has_drift" gorm:"index"`
+	AddedCount   int        `json:"added_count"`        // 节点上多出、不在管理端记录中的行数
+	RemovedCount int        `json:"removed_count"`      // 节点上缺失的行数
+	ChangedCount int        `json:"changed_count"`      // 被修改的行数
+	Added        string     `json:"-" gorm:"type:text"` // JSON 编码的 []string
+	Removed      string     `json:"-" gorm:"type:text"` // JSON 编码的 []string
+	Changed      string     `json:"-" gorm:"type:text"` // JSON 编码的 []DriftLineChange
+	Error        string     `json:"error" gorm:"type:text"`
+	SnapshotID   uint       `json:"snapshot_id"`    // 本次读取到的配置对应的快照
+	OutOfBand    bool       `json:"out_of_band"`    // 最近一次管理端写入后，节点上有过直接修改
+	OutOfBandAt  *time.Time `json:"out_of_band_at"` // 最早发现直接修改的时间
+	CheckedAt    time.Time  `json:"checked_at" gorm:"index"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	AddedLines   []string          `json:"added" gorm:"-"`
+	RemovedLines []string          `json:"removed" gorm:"-"`
+	ChangedLines []DriftLineChange `json:"changed" gorm:"-"`
+}
+
+// DriftLineChange 同一配置项在节点上被修改
+type DriftLineChange struct {
+	Key      string `json:"key"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+func (ConfigDriftReport) TableName() string {
+	return "config_drift_reports"
+}
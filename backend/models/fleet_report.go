@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// FleetReport 节点资产月度报告，定时任务生成后保存，便于按月回看和导出
+type FleetReport struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Month       string    `json:"month" gorm:"index"` // 统计月份，如 2026-09
+	TotalNodes  int       `json:"total_nodes"`
+	Queries     uint64    `json:"queries"`
+	Data        string    `json:"-" gorm:"type:text"` // JSON 编码的 FleetReportData
+	GeneratedAt time.Time `json:"generated_at" gorm:"index"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	Report *FleetReportData `json:"report,omitempty" gorm:"-"`
+}
+
+func (FleetReport) TableName() string {
+	return "fleet_reports"
+}
+
+// FleetReportData 报告内容
+type FleetReportData struct {
+	Month        string           `json:"month"`
+	Start        time.Time        `json:"start"`
+	End          time.Time        `json:"end"`
+	GeneratedAt  time.Time        `json:"generated_at"`
+	TotalNodes   int              `json:"total_nodes"`
+	Queries      uint64           `json:"queries"`
+	QueryError   string           `json:"query_error,omitempty"` // ClickHouse 不可用时查询量为 0
+	ByProvider   []FleetGroupStat `json:"by_provider"`
+	ByRegion     []FleetGroupStat `json:"by_region"`
+	ByCostCenter []FleetGroupStat `json:"by_cost_center"`
+	Nodes        []FleetNodeRow   `json:"nodes"`
+}
+
+// FleetGroupStat 按云厂商、区域或成本中心汇总
+type FleetGroupStat struct {
+	Name    string  `json:"name"`
+	Nodes   int     `json:"nodes"`
+	Online  int     `json:"online"`
+	Queries uint64  `json:"queries"`
+	Share   float64 `json:"share"` // 查询量占比(0-1)
+}
+
+// FleetNodeRow 单个节点的资产和用量
+type FleetNodeRow struct {
+	NodeID      uint    `json:"node_id"`
+	Name        string  `json:"name"`
+	Host        string  `json:"host"`
+	Provider    string  `json:"provider"`
+	Region      string  `json:"region"`
+	CostCenter  string  `json:"cost_center"`
+	Status      string  `json:"status"`
+	Queries     uint64  `json:"queries"`
+	AvgQPS      float64 `json:"avg_qps"`
+	PeakDayQPS  float64 `json:"peak_day_qps"` // 查询量最高一天的平均QPS
+	CPUUsage    float64 `json:"cpu_usage"`    // 生成报告时的资源使用率，未采集时为 -1
+	MemoryUsage float64 `json:"memory_usage"`
+	DiskUsage   float64 `json:"disk_usage"`
+}
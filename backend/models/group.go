@@ -11,3 +11,30 @@ type DNSGroup struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// GroupBlock SmartDNS 二级分组配置块（group-begin NAME ... group-end）
+type GroupBlock struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Name           string    `json:"name" gorm:"uniqueIndex;not null"`
+	Description    string    `json:"description"`
+	ClientRules    string    `json:"client_rules"`     // JSON 数组，匹配的客户端（IP/CIDR/MAC）
+	Servers        string    `json:"servers"`          // JSON 数组，组内上游服务器（不含 server 前缀）
+	SpeedCheckMode string    `json:"speed_check_mode"` // 组内测速模式，如 ping,tcp:80
+	ExtraLines     string    `json:"extra_lines"`      // 其他组内指令，每行一条
+	NodeIDs        string    `json:"node_ids"`         // JSON 数组，应用到哪些节点
+	Enabled        bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// GroupBlockRequest 分组配置块请求
+type GroupBlockRequest struct {
+	Name           string   `json:"name" binding:"required"`
+	Description    string   `json:"description"`
+	ClientRules    []string `json:"client_rules"`
+	Servers        []string `json:"servers"`
+	SpeedCheckMode string   `json:"speed_check_mode"`
+	ExtraLines     []string `json:"extra_lines"`
+	NodeIDs        []uint   `json:"node_ids"`
+	Enabled        *bool    `json:"enabled"`
+}
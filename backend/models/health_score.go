@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// 健康评分等级
+const (
+	HealthLevelHealthy  = "healthy"
+	HealthLevelWarning  = "warning"
+	HealthLevelCritical = "critical"
+)
+
+// HealthScoreWeights 健康评分各项权重（会按可用项归一化）
+type HealthScoreWeights struct {
+	Service       float64 `json:"service"`        // 服务状态
+	Resource      float64 `json:"resource"`       // 资源使用率
+	IngestionLag  float64 `json:"ingestion_lag"`  // 日志采集延迟
+	SyncFreshness float64 `json:"sync_freshness"` // 配置同步状态
+	Telemetry     float64 `json:"telemetry"`      // 遥测延迟
+	ErrorRate     float64 `json:"error_rate"`     // 解析失败率
+}
+
+// HealthComponent 单项健康评分
+type HealthComponent struct {
+	Name      string  `json:"name"`
+	Score     float64 `json:"score"` // 0-100
+	Weight    float64 `json:"weight"`
+	Available bool    `json:"available"` // 无数据时不参与计算
+	Detail    string  `json:"detail"`
+}
+
+// NodeHealthScore 节点综合健康评分
+type NodeHealthScore struct {
+	NodeID       uint              `json:"node_id"`
+	NodeName     string            `json:"node_name"`
+	Score        float64           `json:"score"`
+	Level        string            `json:"level"`
+	Components   []HealthComponent `json:"components"`
+	CalculatedAt time.Time         `json:"calculated_at"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// IngestBatch Agent 写入 ClickHouse 的批次确认记录（dns_ingest_batches 表）
+type IngestBatch struct {
+	StreamID    string    `json:"stream_id"`
+	FilePath    string    `json:"file_path"`
+	Seq         uint64    `json:"seq"`
+	StartOffset uint64    `json:"start_offset"`
+	EndOffset   uint64    `json:"end_offset"`
+	Rows        uint32    `json:"rows"`
+	Reread      bool      `json:"reread"`
+	SentAt      time.Time `json:"sent_at"`
+	InsertedAt  time.Time `json:"inserted_at"`
+}
+
+// IngestGap 日志文件中未被任何批次覆盖的区间
+type IngestGap struct {
+	StreamID    string `json:"stream_id"`
+	FilePath    string `json:"file_path"`
+	StartOffset uint64 `json:"start_offset"`
+	EndOffset   uint64 `json:"end_offset"`
+	Bytes       uint64 `json:"bytes"`
+	AfterSeq    uint64 `json:"after_seq"`    // 缺失区间之前的批次序号
+	BeforeSeq   uint64 `json:"before_seq"`   // 缺失区间之后的批次序号
+	MissingSeqs uint64 `json:"missing_seqs"` // 缺失的批次数，为 0 表示序号连续但偏移不连续（如 Agent 异常退出）
+}
+
+// IngestStreamReport 单个日志文件（stream）的写入情况
+type IngestStreamReport struct {
+	StreamID    string      `json:"stream_id"`
+	FilePath    string      `json:"file_path"`
+	Batches     int         `json:"batches"`
+	Rows        uint64      `json:"rows"`
+	FirstSeq    uint64      `json:"first_seq"`
+	LastSeq     uint64      `json:"last_seq"`
+	MissingSeqs uint64      `json:"missing_seqs"` // 未收到的批次序号数（含已补读的）
+	OutOfOrder  int         `json:"out_of_order"` // 晚于后续序号写入的批次数
+	Duplicates  int         `json:"duplicates"`   // 重复确认的批次数
+	Rereads     int         `json:"rereads"`      // 补读批次数
+	LastSentAt  time.Time   `json:"last_sent_at"`
+	Gaps        []IngestGap `json:"gaps"`
+}
+
+// IngestGapReport 节点日志写入缺失报告
+type IngestGapReport struct {
+	NodeID     uint                 `json:"node_id"`
+	NodeName   string               `json:"node_name"`
+	Since      time.Time            `json:"since"`
+	TotalGaps  int                  `json:"total_gaps"`
+	GapBytes   uint64               `json:"gap_bytes"`
+	OutOfOrder int                  `json:"out_of_order"`
+	Streams    []IngestStreamReport `json:"streams"`
+}
+
+// IngestRereadResult 补读单个缺失区间的结果
+type IngestRereadResult struct {
+	StreamID    string `json:"stream_id"`
+	StartOffset uint64 `json:"start_offset"`
+	EndOffset   uint64 `json:"end_offset"`
+	Success     bool   `json:"success"`
+	Rows        int    `json:"rows"`
+	Message     string `json:"message,omitempty"`
+}
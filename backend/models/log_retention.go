@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// NodeLogRetention 节点 DNS 查询日志的保留天数，未设置的节点使用 DNS_LOG_RETENTION_DAYS
+type NodeLogRetention struct {
+	NodeID    uint      `gorm:"primaryKey;autoIncrement:false" json:"node_id"`
+	Days      int       `json:"days"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NodeLogRetentionStatus 节点当前生效的日志保留天数
+type NodeLogRetentionStatus struct {
+	NodeID      uint `json:"node_id"`
+	Days        int  `json:"days"`         // 生效的保留天数
+	DefaultDays int  `json:"default_days"` // 默认保留天数（DNS_LOG_RETENTION_DAYS）
+	Custom      bool `json:"custom"`       // 是否单独设置
+}
+
+// LogRetentionRequest 设置节点日志保留天数，0 表示恢复默认
+type LogRetentionRequest struct {
+	Days int `json:"days" binding:"min=0,max=3650"`
+}
+
+// LogRetentionOverview 日志保留策略概览
+type LogRetentionOverview struct {
+	StorageType string             `json:"storage_type"`
+	Supported   bool               `json:"supported"`    // 当前日志存储是否支持按节点保留
+	DefaultDays int                `json:"default_days"` // 默认保留天数
+	MaxDays     int                `json:"max_days"`     // 所有节点中最长的保留天数，ClickHouse 表 TTL 按该值设置
+	Nodes       []NodeLogRetention `json:"nodes"`        // 单独设置了保留天数的节点
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// MaintenanceWindow 维护窗口，从 CronExpr 每次触发时开始，持续 DurationMinutes 分钟
+//
+// NodeIDs 和 Tag 都为空时对所有节点生效（全局窗口）；节点未被任何启用的窗口覆盖时
+// 不受限制，重启等操作立即执行。
+type MaintenanceWindow struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	Name            string    `json:"name" gorm:"uniqueIndex;not null"`
+	Description     string    `json:"description"`
+	CronExpr        string    `json:"cron_expr" gorm:"not null"` // 6 段（含秒），与定时任务一致
+	DurationMinutes int       `json:"duration_minutes" gorm:"default:60"`
+	NodeIDs         string    `json:"node_ids"` // JSON 数组
+	Tag             string    `json:"tag"`      // 按节点标签选择
+	Enabled         bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	IsOpen   bool       `json:"is_open" gorm:"-"`
+	NextOpen *time.Time `json:"next_open" gorm:"-"`
+}
+
+// MaintenanceWindowRequest 维护窗口请求
+type MaintenanceWindowRequest struct {
+	Name            string `json:"name" binding:"required"`
+	Description     string `json:"description"`
+	CronExpr        string `json:"cron_expr" binding:"required"`
+	DurationMinutes int    `json:"duration_minutes"`
+	NodeIDs         []uint `json:"node_ids"`
+	Tag             string `json:"tag"`
+	Enabled         *bool  `json:"enabled"`
+}
+
+const (
+	DeferredActionRestart    = "restart"     // 重启 SmartDNS
+	DeferredActionReload     = "reload"      // 按节点重载方式使配置生效
+	DeferredActionCacheFlush = "cache_flush" // 清空缓存
+	DeferredActionTask       = "task"        // 执行定时任务
+)
+
+const (
+	DeferredStatusPending   = "pending"
+	DeferredStatusDone      = "done"
+	DeferredStatusFailed    = "failed"
+	DeferredStatusCancelled = "cancelled"
+)
+
+// DeferredAction 因不在维护窗口内而推迟的操作，窗口开启后自动执行
+type DeferredAction struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	NodeID     uint       `json:"node_id" gorm:"index"` // 定时任务为 0
+	TaskID     uint       `json:"task_id"`
+	Action     string     `json:"action" gorm:"not null"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status" gorm:"index;default:pending"`
+	Error      string     `json:"error" gorm:"type:text"`
+	NotBefore  *time.Time `json:"not_before"` // 推迟时计算的下一个窗口开始时间
+	ExecutedAt *time.Time `json:"executed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	NodeName string `json:"node_name" gorm:"-"`
+	TaskName string `json:"task_name" gorm:"-"`
+}
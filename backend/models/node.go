@@ -8,6 +8,7 @@ import (
 
 type Node struct {
 	ID                   uint                  `json:"id" gorm:"primarykey"`
+	OrganizationID       uint                  `json:"organization_id" gorm:"index;default:0"` // 所属组织，0 表示未分配
 	Name                 string                `json:"name" gorm:"not null"`
 	Host                 string                `json:"host" gorm:"not null"`
 	Port                 int                   `json:"port" gorm:"default:22"`
@@ -38,9 +39,130 @@ type Node struct {
 	DeployMode     string `json:"deploy_mode"`
 	AgentConfig    string `json:"agent_config" gorm:"type:text"`
 
+	// Agent 使用节点控制令牌主动连接管理端的控制通道，只保存令牌摘要
+	AgentControlTokenHash string `json:"-" gorm:"index"`
+
+	// Agent 发送前的日志过滤和采样规则，JSON 编码的 AgentLogFilter
+	AgentLogFilter string `json:"-" gorm:"type:text"`
+
+	// 日志采集状态由采集看门狗维护，见 LogMonitorStatus* 常量
+	LogMonitorStatus    string     `json:"log_monitor_status"`
+	LogMonitorDownSince *time.Time `json:"log_monitor_down_since"`
+
 	ProxyConfig *ProxyConfig `json:"proxy_config" gorm:"type:json"`
+
+	// 配置变更后使其生效的方式，见 ReloadMode* 常量
+	ReloadMode string `json:"reload_mode" gorm:"default:restart"`
+
+	// SSH 凭据来源，见 CredentialSource* 常量；使用 Vault 时不在本地保存密码和私钥
+	CredentialSource string `json:"credential_source" gorm:"default:local"`
+	VaultSecretPath  string `json:"vault_secret_path"` // KV v2 路径，读取 password / private_key
+	VaultSSHRole     string `json:"vault_ssh_role"`    // SSH 引擎角色，为临时密钥签发短期证书
+
+	// 资产信息，用于月度资产报告和成本分摊
+	Provider   string `json:"provider" gorm:"index"`    // 云厂商或机房，如 aliyun / aws / idc
+	Region     string `json:"region" gorm:"index"`      // 区域，如 cn-hangzhou
+	CostCenter string `json:"cost_center" gorm:"index"` // 成本中心
+
+	// 深度健康检查：最近一次通过节点 SmartDNS 解析探测域名的结果，未启用时 DNSProbeAt 为空
+	DNSProbeOK      bool       `json:"dns_probe_ok"`
+	DNSProbeLatency int64      `json:"dns_probe_latency"` // 毫秒
+	DNSProbeError   string     `json:"dns_probe_error"`
+	DNSProbeAt      *time.Time `json:"dns_probe_at"`
+
+	// SmartDNS 安装方式，见 InstallMode* 常量；为空或 auto 时按检测到的系统自动选择
+	InstallMode  string `json:"install_mode"`
+	InstalledVia string `json:"installed_via"` // 实际使用的安装方式，卸载、升级和服务控制据此执行
+
+	// 节点的服务管理方式，见 ServiceManager* 常量，首次控制服务时自动检测
+	ServiceManager string `json:"service_manager"`
+
+	// Windows 节点部署和管理 Agent 的连接方式，见 AgentTransport* 常量；WinRM 使用节点的用户名和密码
+	AgentTransport string `json:"agent_transport"`
+	WinRMPort      int    `json:"winrm_port"`
+	WinRMHTTPS     bool   `json:"winrm_https" gorm:"default:true"` // 默认 HTTPS，HTTP 连接只使用 NTLM 认证
+	WinRMInsecure  bool   `json:"winrm_insecure"`                  // HTTPS 不校验证书，WinRM 默认使用自签名证书
 }
 
+// AgentDeployModeWindows Agent 以 Windows 服务运行，其余部署方式为 systemd / docker
+const AgentDeployModeWindows = "windows"
+
+const (
+	AgentTransportSSH   = "ssh"   // 通过 OpenSSH Server 执行 PowerShell
+	AgentTransportWinRM = "winrm" // 通过 WinRM 执行 PowerShell，需启用 Basic 认证
+)
+
+// ValidAgentTransport 是否为支持的 Windows 连接方式，空值按 ssh 处理
+func ValidAgentTransport(transport string) bool {
+	switch transport {
+	case "", AgentTransportSSH, AgentTransportWinRM:
+		return true
+	}
+	return false
+}
+
+const (
+	LogMonitorStatusRunning    = "running"    // Agent 采集正常
+	LogMonitorStatusRecovering = "recovering" // 采集中断，正在自动重启
+	LogMonitorStatusDown       = "down"       // 中断超过告警阈值
+)
+
+const (
+	// ReloadModeRestart 完全重启服务，配置同步后不自动生效，需手动重启
+	ReloadModeRestart = "restart"
+	// ReloadModeReload 使用 systemctl reload（需服务单元配置 ExecReload），不支持时回退为重启
+	ReloadModeReload = "reload"
+	// ReloadModeSighup 向 SmartDNS 主进程发送 SIGHUP，进程未存活或无法通过解析确认新配置生效时回退为重启
+	ReloadModeSighup = "sighup"
+)
+
+// ValidReloadMode 是否为支持的重载方式，空值按 restart 处理
+func ValidReloadMode(mode string) bool {
+	switch mode {
+	case "", ReloadModeRestart, ReloadModeReload, ReloadModeSighup:
+		return true
+	}
+	return false
+}
+
+const (
+	CredentialSourceLocal = "local" // 使用节点上保存的密码或私钥
+	CredentialSourceVault = "vault" // 连接时从 HashiCorp Vault 获取
+)
+
+// ValidCredentialSource 是否为支持的凭据来源，空值按 local 处理
+func ValidCredentialSource(source string) bool {
+	switch source {
+	case "", CredentialSourceLocal, CredentialSourceVault:
+		return true
+	}
+	return false
+}
+
+const (
+	InstallModeAuto    = "auto"    // 按检测到的系统选择
+	InstallModeTarball = "tarball" // 官方 tar.gz 安装包和安装脚本，使用 systemd 管理
+	InstallModePackage = "package" // 发行版软件源中的 smartdns 包（apt / yum / apk）
+	InstallModeOpkg    = "opkg"    // OpenWrt 软件源中的 smartdns 包，使用 UCI 和 /etc/init.d 管理
+	InstallModeDocker  = "docker"  // docker compose 运行官方镜像，使用主机网络
+)
+
+// ValidInstallMode 是否为支持的安装方式，空值按 auto 处理
+func ValidInstallMode(mode string) bool {
+	switch mode {
+	case "", InstallModeAuto, InstallModeTarball, InstallModePackage, InstallModeOpkg, InstallModeDocker:
+		return true
+	}
+	return false
+}
+
+const (
+	ServiceManagerSystemd = "systemd" // systemctl
+	ServiceManagerOpenRC  = "openrc"  // rc-service / rc-update，Alpine
+	ServiceManagerProcd   = "procd"   // /etc/init.d 脚本，OpenWrt
+	ServiceManagerSysV    = "sysv"    // service / update-rc.d / chkconfig
+)
+
 type ProxyConfig struct {
 	Enabled   bool   `json:"enabled"`
 	ProxyType string `json:"proxy_type"` // "socks5", "http", "ssh"
@@ -69,6 +191,25 @@ type InitLog struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// 节点初始化进度事件类型
+const (
+	InitEventStep   = "step"   // 步骤开始或结束，Log 为该步骤的初始化日志
+	InitEventOutput = "output" // 步骤执行的命令输出，每行一个事件
+	InitEventDone   = "done"   // 初始化结束，Status 为节点的初始化状态
+)
+
+// InitProgressEvent 节点初始化进度，通过 /api/nodes/:id/init/stream 实时推送
+type InitProgressEvent struct {
+	Seq    int64     `json:"seq"` // 同一次初始化内递增
+	Type   string    `json:"type"`
+	Step   string    `json:"step,omitempty"`
+	Status string    `json:"status,omitempty"`
+	Output string    `json:"output,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	Log    *InitLog  `json:"log,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
 type NodeConfig struct {
 	NodeID    uint      `json:"node_id"`
 	Content   string    `json:"content"`
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// NodeFacts 节点系统补丁状态，由补丁检查任务定期采集，每个节点保留最新一条
+type NodeFacts struct {
+	ID              uint      `json:"id" gorm:"primarykey"`
+	NodeID          uint      `json:"node_id" gorm:"uniqueIndex"`
+	NodeName        string    `json:"node_name"`
+	OSName          string    `json:"os_name"`
+	KernelVersion   string    `json:"kernel_version"`
+	PackageManager  string    `json:"package_manager"`  // apt / dnf / yum / apk
+	PendingUpdates  int       `json:"pending_updates"`  // 待安装的更新数
+	SecurityUpdates int       `json:"security_updates"` // 其中的安全更新数，apk 无法区分时为0
+	KernelUpdates   int       `json:"kernel_updates"`   // 待安装的内核安全更新数
+	RebootRequired  bool      `json:"reboot_required"`
+	Issues          string    `json:"-" gorm:"type:text"` // JSON 编码的 []KernelIssue
+	AlertKey        string    `json:"-"`                  // 上次告警内容的摘要，用于避免重复通知
+	Error           string    `json:"error" gorm:"type:text"`
+	CollectedAt     time.Time `json:"collected_at" gorm:"index"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	KernelIssues []KernelIssue `json:"kernel_issues" gorm:"-"`
+}
+
+// KernelIssue 当前运行内核受影响的已知严重漏洞
+type KernelIssue struct {
+	CVE      string `json:"cve"`
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	FixedIn  string `json:"fixed_in"`
+}
+
+func (NodeFacts) TableName() string {
+	return "node_facts"
+}
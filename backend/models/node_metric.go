@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// NodeMetric 节点资源指标的一次采样，由节点健康检查定期采集
+type NodeMetric struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	NodeID      uint      `json:"node_id" gorm:"index:idx_node_metric_time"`
+	CPUUsage    float64   `json:"cpu_usage"`    // CPU 使用率（%）
+	MemoryUsage float64   `json:"memory_usage"` // 内存使用率（%），按 MemAvailable 计算
+	DiskUsage   float64   `json:"disk_usage"`   // 根分区使用率（%）
+	Load1       float64   `json:"load1"`
+	Load5       float64   `json:"load5"`
+	Load15      float64   `json:"load15"`
+	SmartDNSRSS int64     `json:"smartdns_rss"` // SmartDNS 进程常驻内存（字节），未运行时为 0
+	CreatedAt   time.Time `json:"created_at" gorm:"index:idx_node_metric_time"`
+}
+
+func (NodeMetric) TableName() string {
+	return "node_metrics"
+}
+
+// NodeMetricPoint 按时间桶聚合的指标，资源使用率和负载为桶内平均值，CPU 另给出最大值
+type NodeMetricPoint struct {
+	Time        time.Time `json:"time"`
+	Samples     int       `json:"samples"`
+	CPUUsage    float64   `json:"cpu_usage"`
+	CPUMax      float64   `json:"cpu_max"`
+	MemoryUsage float64   `json:"memory_usage"`
+	DiskUsage   float64   `json:"disk_usage"`
+	Load1       float64   `json:"load1"`
+	Load5       float64   `json:"load5"`
+	Load15      float64   `json:"load15"`
+	SmartDNSRSS int64     `json:"smartdns_rss"` // 桶内最大值
+}
+
+// NodeMetricSeries 节点在查询时间范围内的指标序列
+type NodeMetricSeries struct {
+	NodeID uint              `json:"node_id"`
+	Start  time.Time         `json:"start"`
+	End    time.Time         `json:"end"`
+	Step   int               `json:"step"` // 时间桶长度（秒）
+	Points []NodeMetricPoint `json:"points"`
+}
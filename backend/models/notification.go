@@ -6,28 +6,69 @@ import (
 
 // NotificationChannel 通知渠道
 type NotificationChannel struct {
-	ID         uint      `json:"id" gorm:"primarykey"`
-	NodeID     uint      `json:"node_id"`                     // 关联的节点ID，0表示全局
-	Name       string    `json:"name" gorm:"not null"`        // 渠道名称
-	Type       string    `json:"type" gorm:"not null"`        // wechat, dingtalk, feishu, slack
-	WebhookURL string    `json:"webhook_url" gorm:"not null"` // Webhook URL
-	Secret     string    `json:"secret"`                      // 签名密钥（钉钉、飞书需要）
-	Events     string    `json:"events"`                      // JSON数组，订阅的事件类型
-	Enabled    bool      `json:"enabled" gorm:"default:true"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID             uint      `json:"id" gorm:"primarykey"`
+	NodeID         uint      `json:"node_id"`                                // 关联的节点ID，0表示全局
+	OrganizationID uint      `json:"organization_id" gorm:"index;default:0"` // 所属组织，0 表示未分配（只发送未分配节点和全局的通知）
+	Name           string    `json:"name" gorm:"not null"`                   // 渠道名称
+	Type           string    `json:"type" gorm:"not null"`                   // wechat, dingtalk, feishu, slack
+	WebhookURL     string    `json:"webhook_url" gorm:"not null"`            // Webhook URL
+	Secret         string    `json:"secret"`                                 // 签名密钥（钉钉、飞书需要）
+	Events         string    `json:"events"`                                 // JSON数组，订阅的事件类型
+	Enabled        bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// 投递状态
+	FailingSince *time.Time `json:"failing_since,omitempty"` // 连续失败的起始时间，成功后清空
+	LastError    string     `json:"last_error,omitempty"`    // 最近一次投递错误
+	AlarmSent    bool       `json:"alarm_sent"`              // 本轮失败是否已告警
 }
 
 // NotificationLog 通知日志
 type NotificationLog struct {
 	ID        uint      `json:"id" gorm:"primarykey"`
-	ChannelID uint      `json:"channel_id"`
-	NodeID    uint      `json:"node_id"`
-	EventType string    `json:"event_type"` // sync_success, sync_failed, node_offline等
+	ChannelID uint      `json:"channel_id" gorm:"index"`
+	NodeID    uint      `json:"node_id" gorm:"index"`
+	EventType string    `json:"event_type" gorm:"index"` // sync_success, sync_failed, node_offline等
 	Title     string    `json:"title"`
 	Content   string    `json:"content"`
-	Status    string    `json:"status"` // success, failed
+	Status    string    `json:"status" gorm:"index"` // success, failed
 	Error     string    `json:"error"`
-	SentAt    time.Time `json:"sent_at"`
-	CreatedAt time.Time `json:"created_at"`
+	SentAt    time.Time `json:"sent_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// NotificationChannelStats 渠道投递统计
+type NotificationChannelStats struct {
+	ChannelID   uint    `json:"channel_id"`
+	ChannelName string  `json:"channel_name"`
+	Total       int64   `json:"total"`
+	Success     int64   `json:"success"`
+	Failed      int64   `json:"failed"`
+	SuccessRate float64 `json:"success_rate"` // 百分比
+	LastSentAt  string  `json:"last_sent_at"`
+}
+
+// 通知投递状态
+const (
+	DeliveryStatusPending = "pending" // 等待投递或重试
+	DeliveryStatusSuccess = "success" // 投递成功
+	DeliveryStatusDead    = "dead"    // 超过最大重试次数，进入死信
+)
+
+// NotificationDelivery 通知投递队列（持久化，失败后按退避策略重试）
+type NotificationDelivery struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	ChannelID     uint       `json:"channel_id" gorm:"index"`
+	NodeID        uint       `json:"node_id"`
+	EventType     string     `json:"event_type"`
+	Title         string     `json:"title"`
+	Content       string     `json:"content" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"index;default:pending"` // pending, success, dead
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastError     string     `json:"last_error" gorm:"type:text"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// OrganizationHeader 超级管理员切换组织时携带的请求头，值为组织 ID
+const OrganizationHeader = "X-Organization-ID"
+
+// Organization 组织（租户）
+//
+// 节点、域名规则、地址映射、通知渠道和定时任务归属于组织，
+// 绑定了组织的管理员只能看到本组织的资源；未绑定组织的管理员为超级管理员，
+// 可查看全部资源，或通过 X-Organization-ID 请求头切换到某个组织。
+type Organization struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Name        string    `json:"name" gorm:"not null;uniqueIndex"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// 统计信息（不落库）
+	NodeCount   int64 `json:"node_count" gorm:"-"`
+	MemberCount int64 `json:"member_count" gorm:"-"`
+}
+
+// OrganizationRequest 创建/更新组织请求
+type OrganizationRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// OrganizationAssignRequest 将资源或用户分配到组织，路径中的组织 ID 为 0 表示取消分配
+type OrganizationAssignRequest struct {
+	Resource string `json:"resource" binding:"required"` // node / domain_rule / address / notification_channel / scheduled_task / user
+	IDs      []uint `json:"ids" binding:"required"`
+}
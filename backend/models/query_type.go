@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// queryTypeNames DNS 查询类型名称，API 响应、过滤参数和统计共用
+var queryTypeNames = map[uint16]string{
+	1:     "A",
+	2:     "NS",
+	5:     "CNAME",
+	6:     "SOA",
+	12:    "PTR",
+	13:    "HINFO",
+	15:    "MX",
+	16:    "TXT",
+	28:    "AAAA",
+	33:    "SRV",
+	35:    "NAPTR",
+	43:    "DS",
+	46:    "RRSIG",
+	47:    "NSEC",
+	48:    "DNSKEY",
+	64:    "SVCB",
+	65:    "HTTPS",
+	99:    "SPF",
+	252:   "AXFR",
+	255:   "ANY",
+	257:   "CAA",
+	65281: "PRIVATE",
+}
+
+// queryTypeValues 查询类型名称到编号的反向索引
+var queryTypeValues = func() map[string]uint16 {
+	values := make(map[string]uint16, len(queryTypeNames))
+	for value, name := range queryTypeNames {
+		values[name] = value
+	}
+	return values
+}()
+
+// QueryTypeInfo 查询类型编号与名称
+type QueryTypeInfo struct {
+	Type uint16 `json:"type"`
+	Name string `json:"name"`
+}
+
+// QueryTypeName 获取查询类型的名称，未知类型返回 TYPE<n>
+func QueryTypeName(queryType uint16) string {
+	if name, ok := queryTypeNames[queryType]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", queryType)
+}
+
+// QueryTypes 所有已知的查询类型，按编号排序
+func QueryTypes() []QueryTypeInfo {
+	types := make([]QueryTypeInfo, 0, len(queryTypeNames))
+	for value, name := range queryTypeNames {
+		types = append(types, QueryTypeInfo{Type: value, Name: name})
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Type < types[j].Type })
+	return types
+}
+
+// ParseQueryType 解析查询类型，支持编号（28）、名称（AAAA，不区分大小写）和 TYPE<n> 形式
+func ParseQueryType(s string) (uint16, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if value, ok := queryTypeValues[s]; ok {
+		return value, nil
+	}
+	if value, err := strconv.ParseUint(strings.TrimPrefix(s, "TYPE"), 10, 16); err == nil {
+		return uint16(value), nil
+	}
+	return 0, fmt.Errorf("无效的查询类型: %s", s)
+}
+
+// ParseQueryTypes 解析逗号分隔的查询类型列表（如 A,AAAA 或 1,28），忽略空项和重复项
+func ParseQueryTypes(s string) ([]uint16, error) {
+	var types []uint16
+	seen := make(map[uint16]bool)
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		value, err := ParseQueryType(part)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[value] {
+			seen[value] = true
+			types = append(types, value)
+		}
+	}
+	return types, nil
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// SavedSearch 保存的 DNS 日志查询条件，可在日志查询、导出和告警规则中复用
+type SavedSearch struct {
+	ID          uint   `json:"id" gorm:"primarykey"`
+	Name        string `json:"name" gorm:"not null"`
+	Description string `json:"description"`
+	// Filters JSON 编码的过滤参数，参数名与 GET /api/dns-logs 相同（node_id、domain、client_ip 等）
+	Filters string `json:"filters" gorm:"type:text"`
+	// TimeWindow 相对时间窗口，如 15m、24h、7d，使用时换算为 [当前时间-窗口, 当前时间]；
+	// 为空时使用 Filters 中的 start_time/end_time
+	TimeWindow string    `json:"time_window"`
+	SortField  string    `json:"sort_field"`
+	SortOrder  string    `json:"sort_order"`
+	Shared     bool      `json:"shared"` // 共享给所有用户
+	UserID     uint      `json:"user_id" gorm:"index"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SavedSearchRequest 创建/更新保存搜索请求
+type SavedSearchRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	Filters     map[string]string `json:"filters"`
+	TimeWindow  string            `json:"time_window"`
+	SortField   string            `json:"sort_field"`
+	SortOrder   string            `json:"sort_order"`
+	Shared      bool              `json:"shared"`
+}
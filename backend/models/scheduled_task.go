@@ -9,13 +9,50 @@ import (
 type TaskType string
 
 const (
-	TaskTypeDBBackup      TaskType = "db_backup"      // 数据库备份
-	TaskTypeNodeBackup    TaskType = "node_backup"    // 节点配置备份
-	TaskTypeLogCleanup    TaskType = "log_cleanup"    // 日志清理
-	TaskTypeTelemetry     TaskType = "telemetry"      // 网络遥测
-	TaskTypeCustomScript  TaskType = "custom_script"  // 自定义脚本执行
+	TaskTypeDBBackup       TaskType = "db_backup"       // 数据库备份
+	TaskTypeNodeBackup     TaskType = "node_backup"     // 节点配置备份
+	TaskTypeLogCleanup     TaskType = "log_cleanup"     // 日志清理
+	TaskTypeTelemetry      TaskType = "telemetry"       // 网络遥测
+	TaskTypeCustomScript   TaskType = "custom_script"   // 自定义脚本执行
+	TaskTypeClientAbuse    TaskType = "client_abuse"    // 客户端异常查询检测
+	TaskTypeDNSThreat      TaskType = "dns_threat"      // DNS隧道/DGA检测
+	TaskTypeHealthScore    TaskType = "health_score"    // 节点健康评分告警
+	TaskTypeDriftCheck     TaskType = "drift_check"     // 节点配置漂移检测
+	TaskTypePatchCheck     TaskType = "patch_check"     // 节点系统补丁检查
+	TaskTypeBlocklist      TaskType = "blocklist"       // 远程屏蔽列表订阅刷新
+	TaskTypeTrafficAnomaly TaskType = "traffic_anomaly" // 节点查询量异常检测
+	TaskTypeAnswerCheck    TaskType = "answer_check"    // DNS 应答校验（劫持检测）
+	TaskTypeCHOptimize     TaskType = "ch_optimize"     // ClickHouse 表合并优化
+	TaskTypeFleetReport    TaskType = "fleet_report"    // 节点资产月度报告
+	TaskTypeAgentProbe     TaskType = "agent_probe"     // 拉取 Agent 本地解析探测结果
+	TaskTypeConfigSnapshot TaskType = "config_snapshot" // 节点配置快照
+	TaskTypeCompliance     TaskType = "compliance"      // 节点配置合规检查
+	TaskTypeCHBackup       TaskType = "ch_backup"       // ClickHouse 数据备份
+	TaskTypeLogRetention   TaskType = "log_retention"   // 按节点保留天数清理 DNS 查询日志
 )
 
+// organizationTaskTypes 按节点执行的任务类型，可归属于组织；其余类型作用于整个系统，只能由超级管理员创建
+var organizationTaskTypes = map[TaskType]bool{
+	TaskTypeNodeBackup:     true,
+	TaskTypeLogCleanup:     true,
+	TaskTypeCustomScript:   true,
+	TaskTypeClientAbuse:    true,
+	TaskTypeDNSThreat:      true,
+	TaskTypeHealthScore:    true,
+	TaskTypeDriftCheck:     true,
+	TaskTypePatchCheck:     true,
+	TaskTypeTrafficAnomaly: true,
+	TaskTypeAnswerCheck:    true,
+	TaskTypeAgentProbe:     true,
+	TaskTypeConfigSnapshot: true,
+	TaskTypeCompliance:     true,
+}
+
+// IsOrganizationTaskType 任务类型是否可归属于组织，这类任务的配置都带有 node_ids
+func IsOrganizationTaskType(taskType TaskType) bool {
+	return organizationTaskTypes[taskType]
+}
+
 // TaskStatus 任务状态枚举
 type TaskStatus string
 
@@ -25,17 +62,34 @@ const (
 	TaskStatusSuccess  TaskStatus = "success"  // 执行成功
 	TaskStatusFailed   TaskStatus = "failed"   // 执行失败
 	TaskStatusSkipped  TaskStatus = "skipped"  // 跳过执行
+	TaskStatusTimeout  TaskStatus = "timeout"  // 执行超时
+)
+
+// 任务执行的触发方式
+const (
+	TaskTriggerSchedule      = "schedule"       // 按计划调度
+	TaskTriggerManual        = "manual"         // 手动执行或维护窗口内补执行推迟的任务
+	TaskTriggerCatchUp       = "catch_up"       // 服务停机错过调度，启动后补执行
+	TaskTriggerResourceRetry = "resource_retry" // 节点资源占用过高被推迟，稍后对这些节点重试
 )
 
 // ScheduledTask 定时任务配置
 type ScheduledTask struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint   `json:"organization_id" gorm:"index;default:0;comment:所属组织"`
 	Name        string    `json:"name" gorm:"not null;size:100;comment:任务名称"`
 	Type        TaskType  `json:"type" gorm:"not null;comment:任务类型"`
 	Description string    `json:"description" gorm:"size:500;comment:任务描述"`
 	CronExpr    string    `json:"cron_expr" gorm:"not null;size:100;comment:Cron表达式"`
+	RunAt       *time.Time `json:"run_at" gorm:"comment:一次性任务执行时间，设置后忽略Cron表达式"`
+	TimeoutSeconds int    `json:"timeout_seconds" gorm:"default:0;comment:最长执行时间(秒)，0表示不限制"`
 	Config      string    `json:"config" gorm:"type:text;comment:任务配置JSON"`
 	Enabled     bool      `json:"enabled" gorm:"default:true;comment:是否启用"`
+	MaintenanceOnly bool  `json:"maintenance_only" gorm:"default:false;comment:仅在维护窗口内执行"`
+	CatchUp     bool      `json:"catch_up" gorm:"default:false;comment:服务停机错过调度时启动后补执行一次"`
+	NotifyOnSuccess  bool   `json:"notify_on_success" gorm:"default:false;comment:执行成功时通知"`
+	NotifyOnFailure  bool   `json:"notify_on_failure" gorm:"default:false;comment:执行失败或超时时通知"`
+	NotifyChannelIDs string `json:"notify_channel_ids" gorm:"type:text;comment:通知渠道ID列表JSON，为空时发送到订阅任务事件的全局渠道"`
 	
 	// 执行状态
 	LastRunAt    *time.Time `json:"last_run_at" gorm:"comment:上次执行时间"`
@@ -57,6 +111,7 @@ type TaskExecution struct {
 	Task     ScheduledTask `json:"task" gorm:"foreignKey:TaskID"`
 	
 	Status    TaskStatus `json:"status" gorm:"not null;comment:执行状态"`
+	Trigger   string     `json:"trigger" gorm:"size:20;default:schedule;comment:触发方式"`
 	StartedAt time.Time  `json:"started_at" gorm:"not null;comment:开始时间"`
 	EndedAt   *time.Time `json:"ended_at" gorm:"comment:结束时间"`
 	Duration  int64      `json:"duration" gorm:"comment:执行时长(毫秒)"`
@@ -74,11 +129,14 @@ type TaskExecution struct {
 type TelemetryTarget struct {
 	ID          uint   `json:"id" gorm:"primaryKey"`
 	Name        string `json:"name" gorm:"not null;size:100;comment:目标名称"`
-	Type        string `json:"type" gorm:"not null;size:20;comment:检测类型(ping/http/tcp)"`
+	Type        string `json:"type" gorm:"not null;size:20;comment:检测类型(ping/http/tcp/scenario/dns)"`
 	Target      string `json:"target" gorm:"not null;size:255;comment:目标地址"`
 	Timeout     int    `json:"timeout" gorm:"default:5000;comment:超时时间(毫秒)"`
 	Enabled     bool   `json:"enabled" gorm:"default:true;comment:是否启用"`
 	Description string `json:"description" gorm:"size:500;comment:描述"`
+	Group       string `json:"group" gorm:"size:100;index;comment:分组(按服务或区域)"`
+	Scenario    string `json:"scenario" gorm:"type:text;comment:多步检测场景(JSON)"`
+	DNSQuery    string `json:"dns_query" gorm:"type:text;comment:DNS检测参数(JSON)"`
 	
 	// 统计信息
 	LastCheckAt    *time.Time `json:"last_check_at" gorm:"comment:上次检测时间"`
@@ -104,6 +162,13 @@ type TelemetryResult struct {
 	Error     string  `json:"error" gorm:"type:text;comment:错误信息"`
 	Response  string  `json:"response" gorm:"type:text;comment:响应内容"`
 	CheckedAt time.Time `json:"checked_at" gorm:"not null;comment:检测时间"`
+
+	// DNS 检测分别记录建立连接（含 TLS 握手）和查询应答的耗时
+	ConnectLatency int64 `json:"connect_latency" gorm:"comment:连接耗时(毫秒)"`
+	ResolveLatency int64 `json:"resolve_latency" gorm:"comment:解析耗时(毫秒)"`
+
+	// PING 检测的丢包率（百分比），回退到端口连通性检测时不统计
+	PacketLoss float64 `json:"packet_loss" gorm:"default:0;comment:丢包率(%)"`
 	
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -145,14 +210,16 @@ type DBBackupConfig struct {
 	Compression  bool     `json:"compression"`
 	Encryption   bool     `json:"encryption"`
 	RetentionDays int     `json:"retention_days"`
+	EncryptionKeyID *uint `json:"encryption_key_id"` // 加密使用的密钥ID
 }
 
 // NodeBackupConfig 节点配置备份任务配置
 type NodeBackupConfig struct {
 	// 存储配置
-	StorageType   string   `json:"storage_type"`   // 存储类型: "local" 或 "s3"
+	StorageType   string   `json:"storage_type"`   // 存储类型: local、s3、sftp、webdav、azure_blob 或 gcs
 	LocalPath     string   `json:"local_path"`     // 本地存储路径
 	S3Config      S3Config `json:"s3_config"`      // S3存储配置
+	Storage       *StorageBackendConfig `json:"storage,omitempty"` // 其他远程存储配置，按 StorageType 使用对应的子配置
 	
 	// 备份内容配置
 	NodeIDs       []uint   `json:"node_ids"`       // 空表示所有节点
@@ -162,6 +229,8 @@ type NodeBackupConfig struct {
 	// 通用配置
 	Compression   bool     `json:"compression"`    // 是否压缩备份文件
 	RetentionDays int      `json:"retention_days"` // 备份保留天数
+
+	ResourceGuard *NodeResourceGuard `json:"resource_guard"` // 节点资源保护，为空时不检查
 }
 
 // LogCleanupConfig 日志清理任务配置
@@ -170,23 +239,169 @@ type LogCleanupConfig struct {
 	BackendLogDays  int      `json:"backend_log_days"`  // backend日志保留天数
 	SmartDNSLogDays int      `json:"smartdns_log_days"` // SmartDNS日志保留天数
 	LogPaths        []string `json:"log_paths"`         // 自定义日志路径
+	NodeIDs         []uint   `json:"node_ids"`          // 只清理这些节点的SmartDNS日志，空表示所有节点
+
+	NotificationLogDays int `json:"notification_log_days"` // 通知日志保留天数，默认90天
+
+	ResourceGuard *NodeResourceGuard `json:"resource_guard"` // 节点资源保护，为空时不检查
+}
+
+// NodeResourceGuard 重型任务执行前检查节点当前的 CPU 和磁盘使用率，超过阈值的节点推迟到稍后重试，
+// 避免维护任务在流量高峰时影响解析服务
+type NodeResourceGuard struct {
+	MaxCPU       float64 `json:"max_cpu"`           // CPU使用率上限（%），0表示不检查
+	MaxDisk      float64 `json:"max_disk"`          // 磁盘使用率上限（%），0表示不检查
+	DiskPath     string  `json:"disk_path"`         // 检查磁盘使用率的路径，默认 /
+	RetryMinutes int     `json:"retry_minutes"`     // 推迟后的重试间隔（分钟），默认15
+	MaxRetries   int     `json:"max_retries"`       // 最多重试次数，默认4，仍超过阈值时跳过这些节点
+	Attempt      int     `json:"attempt,omitempty"` // 当前重试次数，由调度器在重试时写入
 }
 
 // TelemetryConfig 遥测任务配置
 type TelemetryConfig struct {
-	Targets         []uint `json:"targets"`          // 遥测目标ID列表，空表示所有启用的目标
-	ResultRetention int    `json:"result_retention"` // 结果保留天数
-	AlertThreshold  int    `json:"alert_threshold"`  // 连续失败告警阈值
+	Targets           []uint   `json:"targets"`             // 遥测目标ID列表，空表示所有启用的目标
+	ResultRetention   int      `json:"result_retention"`    // 结果保留天数
+	AlertThreshold    int      `json:"alert_threshold"`     // 连续失败告警阈值
+	Groups            []string `json:"groups"`              // 只检测这些分组的目标，空表示不限
+	GroupAlertPercent int      `json:"group_alert_percent"` // 分组内失败目标占比达到该百分比时告警，0 使用默认值 50
 }
 
 // CustomScriptConfig 自定义脚本任务配置
+//
+// 脚本通过节点的 SSH 凭据在远程节点上执行，NodeIDs 和 Tag 都为空时在所有节点上执行。
 type CustomScriptConfig struct {
 	NodeIDs     []uint            `json:"node_ids"`     // 要执行脚本的节点ID列表，空表示所有节点
+	Tag         string            `json:"tag"`          // 按节点标签选择一组节点，与 node_ids 取并集
 	Script      string            `json:"script"`       // Shell脚本内容
-	Timeout     int               `json:"timeout"`      // 脚本执行超时时间（秒），默认300秒
+	Timeout     int               `json:"timeout"`      // 单个节点的脚本执行超时时间（秒），默认300秒
 	WorkingDir  string            `json:"working_dir"`  // 脚本执行的工作目录，默认/tmp
 	EnvVars     map[string]string `json:"env_vars"`     // 环境变量设置
-	RunAsUser   string            `json:"run_as_user"`  // 执行脚本的用户，默认root
+	RunAsUser   string            `json:"run_as_user"`  // 执行脚本的用户，为空时使用节点的 SSH 用户
+	Concurrency int               `json:"concurrency"`  // 同时执行的节点数，默认5
+
+	TemplateID uint                   `json:"template_id"` // 使用脚本模板时为模板ID，执行时按 params 渲染模板覆盖 script
+	Params     map[string]interface{} `json:"params"`      // 模板参数值，未填写的使用模板默认值
+}
+
+// ClientAbuseConfig 客户端异常查询检测任务配置
+type ClientAbuseConfig struct {
+	NodeIDs        []uint   `json:"node_ids"`        // 检测的节点ID列表，空表示所有节点
+	WindowMinutes  int      `json:"window_minutes"`  // 统计时间窗口（分钟），默认5分钟
+	QPSThreshold   float64  `json:"qps_threshold"`   // 单个客户端平均QPS告警阈值
+	MaxClients     int      `json:"max_clients"`     // 单次最多上报的客户端数量，默认20
+	ExcludeClients []string `json:"exclude_clients"` // 忽略的客户端IP（如内网网关）
+}
+
+// DNSThreatConfig DNS隧道/DGA检测任务配置
+type DNSThreatConfig struct {
+	NodeIDs                  []uint   `json:"node_ids"`                   // 检测的节点ID列表，空表示所有节点
+	WindowMinutes            int      `json:"window_minutes"`             // 扫描时间窗口（分钟），默认60分钟
+	EntropyThreshold         float64  `json:"entropy_threshold"`          // 子域名香农熵阈值，默认3.5
+	MinLabelLength           int      `json:"min_label_length"`           // 参与熵检测的最小子域名长度，默认20
+	MinHighEntropyNames      int      `json:"min_high_entropy_names"`     // 同一父域名下高熵子域名数量阈值，默认10
+	UniqueSubdomainThreshold int      `json:"unique_subdomain_threshold"` // 同一父域名唯一子域名数阈值，默认500
+	TXTQueryThreshold        int      `json:"txt_query_threshold"`        // 单客户端TXT查询数阈值，默认200
+	TXTRatioThreshold        float64  `json:"txt_ratio_threshold"`        // 单客户端TXT查询占比阈值(0-1)，默认0.3
+	IgnoreDomains            []string `json:"ignore_domains"`             // 忽略的父域名（如CDN、杀毒软件）
+	RetentionDays            int      `json:"retention_days"`             // 检测结果保留天数，默认30
+}
+
+// HealthScoreConfig 节点健康评分告警任务配置
+type HealthScoreConfig struct {
+	NodeIDs        []uint              `json:"node_ids"`        // 检查的节点ID列表，空表示所有节点
+	Threshold      float64             `json:"threshold"`       // 低于该分数时告警，默认60
+	CheckResources bool                `json:"check_resources"` // 是否通过SSH采集资源使用率
+	Weights        *HealthScoreWeights `json:"weights"`         // 自定义权重，为空时使用全局配置
+}
+
+// DriftCheckConfig 节点配置漂移检测任务配置
+type DriftCheckConfig struct {
+	NodeIDs       []uint `json:"node_ids"`       // 检测的节点ID列表，空表示所有节点
+	RetentionDays int    `json:"retention_days"` // 漂移报告保留天数，默认30
+}
+
+// PatchCheckConfig 节点系统补丁检查任务配置
+type PatchCheckConfig struct {
+	NodeIDs           []uint `json:"node_ids"`           // 检查的节点ID列表，空表示所有节点
+	SecurityThreshold int    `json:"security_threshold"` // 待安装安全更新达到该数量时告警，默认1
+	IgnoreReboot      bool   `json:"ignore_reboot"`      // 不因需要重启而告警
+}
+
+// BlocklistConfig 屏蔽列表订阅刷新任务配置
+type BlocklistConfig struct {
+	SubscriptionIDs []uint `json:"subscription_ids"` // 刷新的订阅ID列表，空表示所有启用的订阅
+	Force           bool   `json:"force"`            // 忽略刷新间隔，每次执行都下载
+}
+
+// TrafficAnomalyConfig 节点查询量异常检测任务配置
+type TrafficAnomalyConfig struct {
+	NodeIDs          []uint  `json:"node_ids"`           // 检测的节点ID列表，空表示所有节点
+	Method           string  `json:"method"`             // 基线算法：zscore（滚动窗口，默认）或 seasonal（过去几天同一时段）
+	WindowMinutes    int     `json:"window_minutes"`     // 统计窗口（分钟），默认15
+	BaselineHours    int     `json:"baseline_hours"`     // zscore 基线覆盖的小时数，默认24
+	SeasonalDays     int     `json:"seasonal_days"`      // seasonal 基线参考的天数，默认7
+	ZThreshold       float64 `json:"z_threshold"`        // 偏离基线的 z-score 告警阈值，默认3
+	MinBaselineQPS   float64 `json:"min_baseline_qps"`   // 基线平均QPS低于该值时不检测（避免低流量节点误报），默认0.1
+	MinFailureChange float64 `json:"min_failure_change"` // 失败率至少上升的比例(0-1)才告警，默认0.05
+}
+
+// AnswerCheckConfig DNS 应答校验任务配置
+type AnswerCheckConfig struct {
+	NodeIDs  []uint          `json:"node_ids"` // 校验的节点ID列表，空表示所有节点
+	Monitors []AnswerMonitor `json:"monitors"` // 需要校验的域名及期望应答
+	Timeout  int             `json:"timeout"`  // 单次解析超时时间（秒），默认5秒
+}
+
+// CHOptimizeConfig ClickHouse 表合并优化任务配置
+type CHOptimizeConfig struct {
+	Tables []string `json:"tables"` // 需要优化的表，空表示 dns_query_log
+	Final  bool     `json:"final"`  // 使用 OPTIMIZE ... FINAL 强制合并全部分区片段
+}
+
+// LogRetentionConfig DNS 查询日志保留任务配置，保留天数在节点上设置，任务本身没有参数
+type LogRetentionConfig struct{}
+
+// ClickHouse 备份方式
+const (
+	CHBackupMethodS3     = "s3"     // BACKUP TABLE ... TO S3，可通过 RESTORE 恢复
+	CHBackupMethodDisk   = "disk"   // BACKUP TABLE ... TO Disk，备份保存在 ClickHouse 服务器配置的备份磁盘上
+	CHBackupMethodFreeze = "freeze" // ALTER TABLE ... FREEZE，与 clickhouse-backup 相同的 shadow 目录布局，需在服务器上复制和恢复
+)
+
+// CHBackupConfig ClickHouse 数据备份任务配置
+type CHBackupConfig struct {
+	Tables        []string `json:"tables"`         // 备份的表，空表示 dns_query_log
+	Method        string   `json:"method"`         // s3（默认）、disk 或 freeze
+	S3Config      S3Config `json:"s3_config"`      // method 为 s3 时的目标存储
+	Disk          string   `json:"disk"`           // method 为 disk 时的备份磁盘名，默认 backups
+	RetentionDays int      `json:"retention_days"` // 备份保留天数，0 表示不清理
+}
+
+// FleetReportConfig 节点资产月度报告任务配置
+type FleetReportConfig struct {
+	Month          string `json:"month"`           // 统计月份(YYYY-MM)，为空时统计上个月
+	CheckResources bool   `json:"check_resources"` // 是否通过SSH采集当前资源使用率
+	RetentionCount int    `json:"retention_count"` // 保留的报告数量，默认24
+}
+
+// AgentProbeConfig Agent 本地解析探测任务配置
+type AgentProbeConfig struct {
+	NodeIDs       []uint   `json:"node_ids"`       // 为空时拉取所有已安装 Agent 的节点
+	Domains       []string `json:"domains"`        // 下发给 Agent 的探测域名，为空时使用 Agent 自身配置
+	IntervalSec   int      `json:"interval_sec"`   // 下发的探测间隔（秒），0 表示不修改
+	RetentionDays int      `json:"retention_days"` // 探测结果保留天数，默认7天
+}
+
+// ConfigSnapshotConfig 节点配置快照任务配置
+type ConfigSnapshotConfig struct {
+	NodeIDs       []uint `json:"node_ids"`       // 采集的节点ID列表，空表示所有节点
+	RetentionDays int    `json:"retention_days"` // 快照保留天数，默认90，每个节点的最新快照始终保留
+}
+
+// ComplianceCheckConfig 节点配置合规检查任务配置
+type ComplianceCheckConfig struct {
+	NodeIDs       []uint `json:"node_ids"`       // 检查的节点ID列表，空表示所有节点
+	RetentionDays int    `json:"retention_days"` // 已解决的违规记录保留天数，默认90
 }
 
 // TaskStats 任务统计信息
@@ -202,6 +417,85 @@ type TaskStats struct {
 	NextExecutionAt   *time.Time `json:"next_execution_at"`
 }
 
+// 多步检测场景的步骤类型
+const (
+	ScenarioStepResolve = "resolve" // 向节点解析域名
+	ScenarioStepConnect = "connect" // TCP 连接解析到的地址
+	ScenarioStepHTTP    = "http"    // 通过解析到的地址请求 HTTP 路径
+)
+
+// TelemetryScenario 多步检测场景，步骤按顺序执行，任一步失败即终止
+type TelemetryScenario struct {
+	Steps []TelemetryScenarioStep `json:"steps"`
+}
+
+// TelemetryScenarioStep 场景中的单个步骤，后续步骤沿用前面解析到的域名和地址
+type TelemetryScenarioStep struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+
+	// resolve
+	Hostname   string   `json:"hostname,omitempty"`
+	NodeID     uint     `json:"node_id,omitempty"`     // 向该节点的 SmartDNS 查询
+	Server     string   `json:"server,omitempty"`      // 或指定 DNS 服务器 ip[:port]，都不填使用系统解析
+	RecordType string   `json:"record_type,omitempty"` // A / AAAA，默认 A
+	ExpectIPs  []string `json:"expect_ips,omitempty"`  // 解析结果需包含其中之一，命中的地址供后续步骤使用
+
+	// connect / http
+	Port int `json:"port,omitempty"` // http 步骤默认沿用 connect 端口，否则按协议取 80/443
+
+	// http
+	Scheme       string `json:"scheme,omitempty"` // http / https，默认 https
+	Path         string `json:"path,omitempty"`
+	ExpectStatus int    `json:"expect_status,omitempty"` // 0 表示 2xx/3xx 均可
+	ExpectBody   string `json:"expect_body,omitempty"`   // 响应体需包含该字符串
+}
+
+// TelemetryStepResult 场景步骤的执行结果，序列化后保存在 TelemetryResult.Response
+type TelemetryStepResult struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Success bool   `json:"success"`
+	Latency int64  `json:"latency"`
+	Detail  string `json:"detail,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// TelemetryDNSQuery DNS 检测参数，目标地址为 DNS 服务器 ip[:port]
+type TelemetryDNSQuery struct {
+	Domain        string   `json:"domain"`
+	RecordType    string   `json:"record_type,omitempty"`    // A / AAAA / CNAME / MX / TXT / NS，默认 A
+	Protocol      string   `json:"protocol,omitempty"`       // udp / tcp / dot，默认 udp
+	ExpectRcode   string   `json:"expect_rcode,omitempty"`   // 期望的响应码，默认 NOERROR
+	ExpectAnswers []string `json:"expect_answers,omitempty"` // A/AAAA 填地址或网段，其他类型填应答需包含的内容，命中其一即可
+	AllowEmpty    bool     `json:"allow_empty,omitempty"`    // NOERROR 时允许没有应答记录
+}
+
+// TelemetryGroupStats 遥测分组统计
+type TelemetryGroupStats struct {
+	Group          string     `json:"group"`
+	TotalTargets   int        `json:"total_targets"`
+	EnabledTargets int        `json:"enabled_targets"`
+	OnlineTargets  int        `json:"online_targets"`  // 启用且最近一次检测成功
+	AvgLatency     float64    `json:"avg_latency"`     // 在线目标最近一次延迟的平均值
+	SuccessRate    float64    `json:"success_rate"`    // 累计检测成功率（百分比）
+	LastCheckAt    *time.Time `json:"last_check_at"`
+}
+
+// TelemetryImportRequest 批量导入遥测目标请求
+type TelemetryImportRequest struct {
+	Content string `json:"content" binding:"required"`
+	Format  string `json:"format"` // csv / yaml，留空自动识别
+	Group   string `json:"group"`  // 未指定分组的目标使用该分组
+}
+
+// TelemetryImportResult 批量导入遥测目标结果
+type TelemetryImportResult struct {
+	Created int      `json:"created"`
+	Skipped int      `json:"skipped"` // 已存在相同类型和地址的目标
+	Errors  []string `json:"errors"`
+}
+
 // TelemetryStats 遥测统计信息
 type TelemetryStats struct {
 	TargetID      uint       `json:"target_id"`
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// 脚本模板参数类型
+const (
+	ScriptParamString = "string" // 替换为单引号包裹的 shell 字符串
+	ScriptParamInt    = "int"    // 必须是整数
+	ScriptParamBool   = "bool"   // 替换为 true 或 false
+	ScriptParamEnum   = "enum"   // 必须是 Options 中的值
+)
+
+// ScriptTemplate 团队共享的维护脚本模板，脚本中的 {{参数名}} 在任务执行时按参数值渲染
+type ScriptTemplate struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex;not null"`
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	Script      string    `json:"script" gorm:"type:text;not null"`
+	Parameters  string    `json:"parameters" gorm:"type:text"` // JSON 编码的 []ScriptTemplateParam
+	BuiltIn     bool      `json:"built_in" gorm:"default:false"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ScriptTemplateParam 脚本模板参数定义
+type ScriptTemplateParam struct {
+	Name        string   `json:"name"`  // 占位符名称，脚本中写作 {{name}}
+	Label       string   `json:"label"` // 界面显示名称
+	Type        string   `json:"type"`
+	Default     string   `json:"default"`
+	Required    bool     `json:"required"`
+	Options     []string `json:"options,omitempty"` // enum 类型的可选值
+	Description string   `json:"description"`
+}
+
+// ScriptTemplateRequest 创建或更新脚本模板的请求
+type ScriptTemplateRequest struct {
+	Name        string                `json:"name" binding:"required"`
+	Description string                `json:"description"`
+	Category    string                `json:"category"`
+	Script      string                `json:"script" binding:"required"`
+	Parameters  []ScriptTemplateParam `json:"parameters"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// 安全检测发现类型
+const (
+	FindingTypeHighEntropy    = "high_entropy"     // 高熵子域名（疑似 DGA/隧道编码）
+	FindingTypeSubdomainBurst = "subdomain_burst"  // 同一父域名下子域名数量异常
+	FindingTypeTXTHeavyClient = "txt_heavy_client" // TXT 查询占比异常的客户端
+	FindingSeverityLow        = "low"
+	FindingSeverityMedium     = "medium"
+	FindingSeverityHigh       = "high"
+)
+
+// SecurityFinding DNS 安全检测发现
+type SecurityFinding struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	Type         string    `json:"type" gorm:"index;size:50"`
+	Severity     string    `json:"severity" gorm:"size:20"`
+	NodeID       uint      `json:"node_id" gorm:"index"` // 0 表示跨节点
+	ClientIP     string    `json:"client_ip" gorm:"size:64"`
+	Domain       string    `json:"domain" gorm:"index;size:255"` // 父域名
+	Score        float64   `json:"score"`                        // 熵值/子域名数/TXT占比等
+	Count        int64     `json:"count"`                        // 相关查询数
+	Detail       string    `json:"detail" gorm:"type:text"`
+	Samples      string    `json:"samples" gorm:"type:text"` // JSON数组，样例域名
+	Acknowledged bool      `json:"acknowledged" gorm:"default:false"`
+	DetectedAt   time.Time `json:"detected_at" gorm:"index"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (SecurityFinding) TableName() string {
+	return "security_findings"
+}
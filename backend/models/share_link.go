@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// 分享内容类型
+const (
+	ShareKindLogs  = "logs"  // 过滤后的日志列表
+	ShareKindStats = "stats" // 节点统计快照
+)
+
+// LogShareLink 日志只读分享链接。链接只携带记录 ID 和随机数的签名，访问范围、有效期以这里的记录为准
+type LogShareLink struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	Kind         string     `json:"kind"`
+	Description  string     `json:"description"`
+	Nonce        string     `json:"-" gorm:"size:64"`   // 随机数，与 ID 一起签名，令牌无法由 ID 推算
+	Filters      string     `json:"-" gorm:"type:text"` // JSON 编码的 map[string]string
+	StartTime    time.Time  `json:"start_time"`
+	EndTime      time.Time  `json:"end_time"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"index"`
+	CreatedBy    string     `json:"created_by"`
+	Revoked      bool       `json:"revoked"`
+	ViewCount    int        `json:"view_count"`
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	OrganizationID uint `json:"organization_id" gorm:"index;default:0"` // 创建者所在的组织，大于 0 时只能查看该组织的节点
+
+	FilterMap map[string]string `json:"filters" gorm:"-"`
+}
+
+func (LogShareLink) TableName() string {
+	return "log_share_links"
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// SmartDNSCacheStats 节点 SmartDNS 缓存状态
+type SmartDNSCacheStats struct {
+	NodeID       uint   `json:"node_id"`
+	NodeName     string `json:"node_name"`
+	ServiceUp    bool   `json:"service_up"`
+	CacheSize    int    `json:"cache_size"`    // cache-size 配置，0 表示未配置（使用 SmartDNS 默认值）
+	CachePersist string `json:"cache_persist"` // cache-persist 配置：yes / no / auto（未配置）
+	CacheFile    string `json:"cache_file"`
+	PrefetchOn   bool   `json:"prefetch_domain"`
+	ServeExpired bool   `json:"serve_expired"`
+	MemoryRSSKB  int64  `json:"memory_rss_kb"` // smartdns 进程常驻内存
+
+	// 缓存文件是 SmartDNS 停止或到达 cache-checkpoint-time 时写入的快照
+	CacheFileExists  bool       `json:"cache_file_exists"`
+	CacheFileBytes   int64      `json:"cache_file_bytes"`
+	CacheFileEntries int64      `json:"cache_file_entries"` // 快照中的缓存条目数，无法解析时为 -1
+	CacheFileSavedAt *time.Time `json:"cache_file_saved_at"`
+
+	// 命中率根据日志中命中缓存的查询估算，未启用日志或无数据时为 nil
+	HitRate       *float64 `json:"hit_rate"`
+	HitRateWindow string   `json:"hit_rate_window"`
+	SampleQueries int64    `json:"sample_queries"`
+
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// CacheFlushResult 单个节点的缓存清空结果
+type CacheFlushResult struct {
+	NodeID   uint   `json:"node_id"`
+	NodeName string `json:"node_name"`
+	Success  bool   `json:"success"`
+	Deferred bool   `json:"deferred"` // 不在维护窗口内，已推迟执行
+	Error    string `json:"error,omitempty"`
+}
+
+// CacheFlushRequest 批量清空缓存请求，node_ids 与 tag 至少指定一个
+type CacheFlushRequest struct {
+	NodeIDs  []uint `json:"node_ids"`
+	Tag      string `json:"tag"`      // 按节点标签选择
+	Override bool   `json:"override"` // 忽略维护窗口立即执行
+
+	OrganizationID uint `json:"-"` // 大于 0 时只在该组织的节点中选择
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// SmartDNSVersionPin 按节点标签固定 SmartDNS 目标版本，带该标签的节点初始化和升级时安装此版本
+type SmartDNSVersionPin struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Tag         string    `json:"tag" gorm:"uniqueIndex;not null"`
+	Version     string    `json:"version" gorm:"not null"` // 如 1.2024.06.12-2222
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SmartDNSVersionPinRequest 创建或修改版本固定
+type SmartDNSVersionPinRequest struct {
+	Tag         string `json:"tag" binding:"required"`
+	Version     string `json:"version" binding:"required"`
+	Description string `json:"description"`
+}
+
+// SmartDNSRelease 版本目录中的一个 SmartDNS 发行版
+type SmartDNSRelease struct {
+	Tag         string                 `json:"tag"`     // GitHub Release 标签，如 Release46
+	Version     string                 `json:"version"` // 安装包文件名中的版本号
+	Name        string                 `json:"name"`
+	PublishedAt *time.Time             `json:"published_at"`
+	Prerelease  bool                   `json:"prerelease"`
+	Notes       string                 `json:"notes,omitempty"`
+	Assets      []SmartDNSReleaseAsset `json:"assets"`
+}
+
+// SmartDNSReleaseAsset 发行版中某个架构的 Linux 安装包
+type SmartDNSReleaseAsset struct {
+	Architecture string `json:"architecture"` // x86_64, aarch64, arm 等
+	Name         string `json:"name"`
+	DownloadURL  string `json:"download_url"`
+	Size         int64  `json:"size"`
+}
+
+// SmartDNSNodeVersion 节点当前安装的版本与目标版本
+type SmartDNSNodeVersion struct {
+	NodeID           uint   `json:"node_id"`
+	CurrentVersion   string `json:"current_version"`
+	TargetVersion    string `json:"target_version"`
+	PinnedBy         string `json:"pinned_by,omitempty"` // 决定目标版本的标签，为空表示使用默认版本
+	LatestVersion    string `json:"latest_version,omitempty"`
+	UpgradeAvailable bool   `json:"upgrade_available"` // 当前版本与目标版本不同
+	InstalledVia     string `json:"installed_via"`     // 只有 tarball 安装的节点可以在线升级
+}
+
+// SmartDNSUpgradeRequest 升级节点 SmartDNS，未指定版本时升级到节点的目标版本
+type SmartDNSUpgradeRequest struct {
+	Version string `json:"version"`
+	Force   bool   `json:"force"` // 已是该版本时仍重新安装
+}
+
+// SmartDNSUpgradeResult 升级任务的结果
+type SmartDNSUpgradeResult struct {
+	FromVersion string   `json:"from_version"`
+	ToVersion   string   `json:"to_version"`
+	BinaryPath  string   `json:"binary_path"`
+	RolledBack  bool     `json:"rolled_back"` // 升级后健康检查失败，已恢复原程序
+	Checks      []string `json:"checks"`      // 健康检查结果
+	Error       string   `json:"error,omitempty"`
+}
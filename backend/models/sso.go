@@ -0,0 +1,40 @@
+package models
+
+// 账号来源
+const (
+	AuthSourceLocal   = "local"
+	AuthSourceOIDC    = "oidc"
+	AuthSourceLDAP    = "ldap"
+	AuthSourceWebhook = "webhook"
+)
+
+// SSORoleNone 分组映射结果为该值时拒绝登录
+const SSORoleNone = "none"
+
+// SSOIdentity 外部身份源认证通过后的用户信息
+type SSOIdentity struct {
+	Source   string // oidc / ldap / webhook
+	Subject  string // IdP subject 或 LDAP DN，用于关联本地账号
+	Username string
+	Email    string
+	Groups   []string
+	Role     string // 认证服务直接返回的角色，为空时按分组映射
+}
+
+// SSOConfigResponse 登录页展示的单点登录选项
+type SSOConfigResponse struct {
+	OIDCEnabled      bool   `json:"oidc_enabled"`
+	OIDCProviderName string `json:"oidc_provider_name"`
+	LDAPEnabled      bool   `json:"ldap_enabled"`
+	WebhookEnabled   bool   `json:"webhook_enabled"`
+}
+
+// WebhookTokenRequest 使用外部令牌登录，令牌交给认证 Webhook 校验
+type WebhookTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// SSOExchangeRequest 前端用一次性登录码换取令牌
+type SSOExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
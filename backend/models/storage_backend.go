@@ -0,0 +1,56 @@
+package models
+
+// 备份存储类型
+const (
+	StorageTypeLocal  = "local"
+	StorageTypeS3     = "s3"
+	StorageTypeSFTP   = "sftp"
+	StorageTypeWebDAV = "webdav"
+	StorageTypeAzure  = "azure_blob"
+	StorageTypeGCS    = "gcs"
+)
+
+// StorageBackendConfig 备份存储后端配置，按存储类型使用对应的子配置
+type StorageBackendConfig struct {
+	S3     *S3Config            `json:"s3,omitempty"`
+	SFTP   *SFTPStorageConfig   `json:"sftp,omitempty"`
+	WebDAV *WebDAVStorageConfig `json:"webdav,omitempty"`
+	Azure  *AzureStorageConfig  `json:"azure,omitempty"`
+	GCS    *GCSStorageConfig    `json:"gcs,omitempty"`
+}
+
+// SFTPStorageConfig SFTP 存储
+type SFTPStorageConfig struct {
+	Host       string `json:"host"`
+	Port       int    `json:"port"` // 默认 22
+	Username   string `json:"username"`
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"` // PEM 格式私钥，与密码二选一
+	HostKey    string `json:"host_key"`              // 服务器主机公钥（ssh-keyscan 输出的一行）或 SHA256 指纹，必填
+	Path       string `json:"path"`                  // 远程目录，如 /data/smartdns-backups
+}
+
+// WebDAVStorageConfig WebDAV 存储
+type WebDAVStorageConfig struct {
+	URL      string `json:"url"` // 目录地址，如 https://dav.example.com/remote.php/dav/files/admin/backups
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+// AzureStorageConfig Azure Blob 存储，AccountKey 与 SASToken 二选一
+type AzureStorageConfig struct {
+	Account    string `json:"account"`
+	AccountKey string `json:"account_key,omitempty"` // Base64 编码的访问密钥
+	SASToken   string `json:"sas_token,omitempty"`   // 容器级 SAS，需要读、写、删除权限
+	Container  string `json:"container"`
+	Endpoint   string `json:"endpoint,omitempty"` // 自定义端点（如 Azurite），默认 https://<account>.blob.core.windows.net
+	Prefix     string `json:"prefix,omitempty"`
+}
+
+// GCSStorageConfig Google Cloud Storage 存储
+type GCSStorageConfig struct {
+	Bucket          string `json:"bucket"`
+	CredentialsJSON string `json:"credentials_json,omitempty"` // 服务账号密钥 JSON
+	Endpoint        string `json:"endpoint,omitempty"`         // 自定义端点，默认 https://storage.googleapis.com
+	Prefix          string `json:"prefix,omitempty"`
+}
@@ -0,0 +1,114 @@
+package models
+
+// StructuredConfigSchemaVersion 结构化配置的 JSON 格式版本，字段只增不改，不兼容的修改会升级版本号
+const StructuredConfigSchemaVersion = "smartdns-config/v1"
+
+// 结构化配置来源
+const (
+	StructuredConfigSourceActual  = "actual"  // 节点上的当前配置
+	StructuredConfigSourceDesired = "desired" // 完整同步后应有的配置
+)
+
+// StructuredConfig 供外部工具使用的 SmartDNS 配置，与数据库模型解耦，格式稳定
+type StructuredConfig struct {
+	SchemaVersion string                       `json:"schema_version"`
+	Source        string                       `json:"source"`
+	Settings      map[string]string            `json:"settings"` // 基础设置，如 cache-size、log-level
+	Servers       []StructuredServer           `json:"servers"`
+	Addresses     []StructuredAddress          `json:"addresses"`
+	DomainSets    []StructuredDomainSet        `json:"domain_sets"`
+	DomainRules   []StructuredDomainRule       `json:"domain_rules"`
+	Nameservers   []StructuredNameserver       `json:"nameservers"`
+	ClientRules   []StructuredClientRule       `json:"client_rules"`
+	Groups        []StructuredGroup            `json:"groups"`     // group-begin/group-end 配置块
+	ConfFiles     []string                     `json:"conf_files"` // conf-file 引入的配置文件
+	Directives    []StructuredDirective        `json:"directives"` // 其他已识别的指令，按原顺序
+	Unknown       []StructuredUnknownDirective `json:"unknown"`    // 无法识别的指令，原样透传
+}
+
+// StructuredServer 上游服务器
+type StructuredServer struct {
+	ManagedID      uint     `json:"managed_id,omitempty"` // 对应的管理记录 ID，手工添加的为空
+	Address        string   `json:"address"`
+	Protocol       string   `json:"protocol"` // udp、tcp、tls、https
+	Groups         []string `json:"groups"`
+	ExcludeDefault bool     `json:"exclude_default"`
+	Options        string   `json:"options"` // 原始参数
+}
+
+// StructuredAddress address 或 cname 记录
+type StructuredAddress struct {
+	ManagedID      uint     `json:"managed_id,omitempty"`
+	Domain         string   `json:"domain"`
+	Type           string   `json:"type"` // address、cname
+	IPs            []string `json:"ips,omitempty"`
+	CNAME          string   `json:"cname,omitempty"`
+	TTL            int      `json:"ttl,omitempty"`
+	NoServeExpired bool     `json:"no_serve_expired,omitempty"`
+}
+
+// StructuredDomainSet 域名集
+type StructuredDomainSet struct {
+	ManagedID uint   `json:"managed_id,omitempty"`
+	Name      string `json:"name"`
+	File      string `json:"file"`
+}
+
+// StructuredDomainRule domain-rules 规则，Domain 与 DomainSet 二选一
+type StructuredDomainRule struct {
+	ManagedID      uint   `json:"managed_id,omitempty"`
+	Domain         string `json:"domain,omitempty"`
+	DomainSet      string `json:"domain_set,omitempty"`
+	Address        string `json:"address,omitempty"`
+	Nameserver     string `json:"nameserver,omitempty"`
+	SpeedCheckMode string `json:"speed_check_mode,omitempty"`
+	Options        string `json:"options,omitempty"`
+}
+
+// StructuredNameserver nameserver 规则，Domain 与 DomainSet 二选一
+type StructuredNameserver struct {
+	ManagedID uint   `json:"managed_id,omitempty"`
+	Domain    string `json:"domain,omitempty"`
+	DomainSet string `json:"domain_set,omitempty"`
+	Group     string `json:"group"`
+}
+
+// StructuredClientRule client-rules 规则
+type StructuredClientRule struct {
+	ManagedID uint   `json:"managed_id,omitempty"`
+	Client    string `json:"client"`
+	Group     string `json:"group,omitempty"`
+	Blocked   bool   `json:"blocked"`
+	Options   string `json:"options,omitempty"`
+}
+
+// StructuredGroup group-begin/group-end 配置块，块内指令原样保留
+type StructuredGroup struct {
+	Name  string   `json:"name"`
+	Lines []string `json:"lines"`
+}
+
+// StructuredDirective 通用指令
+type StructuredDirective struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// StructuredUnknownDirective 无法识别的指令
+type StructuredUnknownDirective struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Line    int    `json:"line,omitempty"` // 在节点配置文件中的行号，desired 配置中为空
+	Content string `json:"content"`        // 原始配置行
+}
+
+// NodeStructuredConfig 节点的结构化配置
+type NodeStructuredConfig struct {
+	SchemaVersion string            `json:"schema_version"`
+	NodeID        uint              `json:"node_id"`
+	NodeName      string            `json:"node_name"`
+	ConfigPath    string            `json:"config_path"`
+	Checksum      string            `json:"checksum"` // 节点配置文件的 MD5，与 GET /nodes/:id/config 一致
+	Actual        *StructuredConfig `json:"actual,omitempty"`
+	Desired       *StructuredConfig `json:"desired,omitempty"`
+}
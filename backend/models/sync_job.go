@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// 同步任务状态
+const (
+	SyncJobStatusQueued    = "queued"
+	SyncJobStatusRunning   = "running"
+	SyncJobStatusSucceeded = "succeeded"
+	SyncJobStatusPartial   = "partial" // 部分节点失败
+	SyncJobStatusFailed    = "failed"
+)
+
+// 同步任务节点状态
+const (
+	SyncNodeStatusQueued    = "queued"
+	SyncNodeStatusRunning   = "running"
+	SyncNodeStatusSucceeded = "succeeded"
+	SyncNodeStatusFailed    = "failed"
+	SyncNodeStatusRetrying  = "retrying" // 失败后等待重试
+)
+
+// 同步任务类型
+const (
+	SyncJobTypeAddressSync   = "address_sync"
+	SyncJobTypeAddressDelete = "address_delete"
+	SyncJobTypeSyncRetry     = "sync_retry" // 以完整同步重试失败的同步记录
+)
+
+// SyncJob 配置同步任务，记录一次变更同步到各节点的进度
+type SyncJob struct {
+	ID             uint       `json:"id" gorm:"primarykey"`
+	Type           string     `json:"type" gorm:"index"`
+	Description    string     `json:"description"`
+	Payload        string     `json:"-" gorm:"type:text"` // JSON 编码的同步内容，如 []AddressMap、重试的同步记录 ID
+	Status         string     `json:"status" gorm:"index"`
+	TotalNodes     int        `json:"total_nodes"`
+	SucceededNodes int        `json:"succeeded_nodes"`
+	FailedNodes    int        `json:"failed_nodes"`
+	StartedAt      *time.Time `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+	RequestID      string     `json:"request_id" gorm:"index"` // 创建任务的请求 ID，用于关联后端日志
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Nodes []SyncJobNode `json:"nodes,omitempty" gorm:"foreignKey:JobID"`
+}
+
+// SyncJobNode 同步任务在单个节点上的执行状态
+type SyncJobNode struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	JobID       uint       `json:"job_id" gorm:"index"`
+	NodeID      uint       `json:"node_id"`
+	NodeName    string     `json:"node_name"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	NextRetryAt *time.Time `json:"next_retry_at" gorm:"index"` // 失败后下次重试的时间，按指数退避
+	Error       string     `json:"error" gorm:"type:text"`
+	StartedAt   *time.Time `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (SyncJob) TableName() string {
+	return "sync_jobs"
+}
+
+func (SyncJobNode) TableName() string {
+	return "sync_job_nodes"
+}
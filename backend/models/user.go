@@ -6,14 +6,17 @@ import (
 )
 
 type User struct {
-	ID        uint           `json:"id" gorm:"primarykey"`
-	Username  string         `json:"username" gorm:"unique;not null"`
-	Password  string         `json:"-" gorm:"not null"` // 哈希后的密码
-	Email     string         `json:"email" gorm:"unique"`
-	Role      string         `json:"role" gorm:"default:user"` // admin, user
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	LastLogin time.Time      `json:"last_login"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID             uint           `json:"id" gorm:"primarykey"`
+	Username       string         `json:"username" gorm:"unique;not null"`
+	Password       string         `json:"-" gorm:"not null"` // 哈希后的密码
+	Email          string         `json:"email" gorm:"unique"`
+	Role           string         `json:"role" gorm:"default:user"`               // admin, user
+	OrganizationID uint           `json:"organization_id" gorm:"index;default:0"` // 所属组织，0 表示超级管理员（可查看和切换所有组织）
+	IsActive       bool           `json:"is_active" gorm:"default:true"`
+	AuthSource     string         `json:"auth_source" gorm:"size:20;default:local;index"` // local, oidc, ldap
+	ExternalID     string         `json:"-" gorm:"size:255;index"`                        // IdP subject 或 LDAP DN
+	LastLogin      time.Time      `json:"last_login"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 }
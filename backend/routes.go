@@ -0,0 +1,441 @@
+package main
+
+import (
+	"smartdns-manager/handlers"
+	"smartdns-manager/middleware"
+	"smartdns-manager/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate go run ./cmd/openapi-gen -o docs/openapi.json
+
+// registerRoutes 在 api 分组下注册全部接口，/api/v1 与兼容旧客户端的 /api 共用同一份路由
+//
+// 修改路由或处理器后执行 go generate 重新生成 docs/openapi.json
+func registerRoutes(api *gin.RouterGroup, databaseBackupHandler *handlers.DatabaseBackupHandler, backupKeyHandler *handlers.BackupKeyHandler, schedulerHandler *handlers.SchedulerHandler) {
+	// 公开路由
+	public := api.Group("")
+	{
+		public.POST("/login", handlers.Login)
+		public.POST("/register", handlers.Register)
+		public.GET("/auth/sso", handlers.GetSSOConfig)
+		public.GET("/auth/oidc/login", handlers.OIDCLogin)
+		public.GET("/auth/oidc/callback", handlers.OIDCCallback)
+		public.POST("/auth/sso/exchange", handlers.ExchangeSSOCode)
+		public.POST("/auth/webhook/token", handlers.WebhookTokenLogin)
+		public.GET("/openapi.json", handlers.GetOpenAPISpec)       // OpenAPI 3 接口文档，可用于生成客户端 SDK
+		public.GET("/agent/control", handlers.ConnectAgentControl) // Agent 主动建立的控制通道（WebSocket），使用节点控制令牌认证
+		// Agent 无法直接访问 ClickHouse 时经管理端写入日志，使用节点控制令牌认证
+		public.POST("/ingest/dns-logs", handlers.IngestDNSLogs)
+		public.POST("/ingest/dns-batches", handlers.IngestDNSBatches)
+		public.GET("/ingest/domain-categories", handlers.GetIngestDomainCategories)
+	}
+
+	// 日志分享链接，无需登录，访问范围由签名令牌决定
+	shareGroup := api.Group("/share")
+	shareGroup.Use(middleware.RateLimit(60))
+	{
+		shareGroup.GET("/:token", handlers.ViewSharedLogs)
+	}
+
+	// 破坏性操作两阶段确认：dry_run=true 预估影响并签发令牌，提交时携带 X-Confirm-Token
+	confirm := middleware.ConfirmDestructive
+
+	// 注册路由
+	logGroup := api.Group("/dns-logs")
+	logGroup.Use(middleware.AuthMiddleware())
+	logGroup.Use(middleware.AdminRequired())
+	logGroup.Use(middleware.OrganizationScope())
+	{
+		logGroup.POST("/:id/log-monitor/start", handlers.StartNodeLogMonitor)                                             // 启动监控
+		logGroup.POST("/:id/log-monitor/stop", handlers.StopNodeLogMonitor)                                               // 停止监控
+		logGroup.GET("/:id/log-monitor/status", handlers.GetNodeLogMonitorStatus)                                         // 监控状态
+		logGroup.GET("/:id/logs/stats", handlers.GetLogStats)                                                             // 日志统计
+		logGroup.POST("/:id/logs/clean", confirm("clean_query_logs", handlers.CleanOldLogsImpact), handlers.CleanOldLogs) // 清理日志
+		logGroup.GET("", handlers.GetDNSLogs)                                                                             // 获取日志列表（支持按节点过滤）
+		logGroup.GET("/query-types", handlers.GetQueryTypes)                                                              // 查询类型编号与名称
+		logGroup.GET("/clients/:ip/profile", handlers.GetClientProfile)                                                   // 客户端查询画像
+		logGroup.GET("/storage", middleware.SuperAdminRequired(), handlers.GetLogStorageInfo)                             // 存储信息与表结构校对结果
+		logGroup.POST("/storage/schema", middleware.SuperAdminRequired(), handlers.ReconcileLogSchema)                    // 重新校对日志表结构
+		logGroup.GET("/retention", middleware.SuperAdminRequired(), handlers.GetLogRetentionOverview)                     // 日志保留策略概览
+		logGroup.GET("/:id/retention", handlers.GetNodeLogRetention)                                                      // 节点日志保留天数
+		logGroup.PUT("/:id/retention", handlers.UpdateNodeLogRetention)                                                   // 设置节点日志保留天数并立即清理
+		logGroup.POST("/export", handlers.CreateDNSLogExport)                                                             // 异步导出日志（CSV/JSONL）
+		logGroup.GET("/exports", handlers.ListDNSLogExports)                                                              // 导出记录
+		logGroup.GET("/exports/:id", handlers.GetDNSLogExport)                                                            // 导出状态
+		logGroup.GET("/exports/:id/download", handlers.DownloadDNSLogExport)                                              // 下载导出文件
+		logGroup.DELETE("/exports/:id", handlers.DeleteDNSLogExport)                                                      // 删除导出
+		logGroup.GET("/saved-searches", handlers.GetSavedSearches)                                                        // 保存的搜索
+		logGroup.GET("/saved-searches/:id", handlers.GetSavedSearch)
+		logGroup.POST("/saved-searches", handlers.CreateSavedSearch)
+		logGroup.PUT("/saved-searches/:id", handlers.UpdateSavedSearch)
+		logGroup.DELETE("/saved-searches/:id", handlers.DeleteSavedSearch)
+	}
+
+	// DNS 日志分析
+	analyticsGroup := api.Group("/analytics")
+	analyticsGroup.Use(middleware.AuthMiddleware())
+	analyticsGroup.Use(middleware.AdminRequired())
+	analyticsGroup.Use(middleware.OrganizationScope())
+	{
+		analyticsGroup.GET("/query-types", handlers.GetQueryTypeAnalytics)   // 查询类型分布与趋势
+		analyticsGroup.GET("/response-ips", handlers.GetResponseIPAnalytics) // 应答IP与CDN分布
+		analyticsGroup.GET("/slow-queries", handlers.GetSlowQueryAnalytics)  // 慢查询排查
+		analyticsGroup.GET("/upstreams", handlers.GetUpstreamAnalytics)      // 上游耗时、失败占比与选中率
+	}
+
+	apiVersion := api.Group("")
+	apiVersion.Use(middleware.AuthMiddleware())
+	apiVersion.Use(middleware.AdminRequired())
+	apiVersion.Use(middleware.OrganizationScope())
+	{
+		// 版本管理相关路由
+		version := apiVersion.Group("/version")
+		{
+			version.GET("/check", handlers.CheckVersion)
+			version.GET("/info", handlers.GetSystemInfo)
+			version.GET("/history", handlers.GetVersionHistory)
+		}
+
+		// 最近的后端日志，可按请求 ID 排查单个请求
+		apiVersion.GET("/system/logs", middleware.SuperAdminRequired(), handlers.GetSystemLogs)
+	}
+
+	// 需要认证的路由
+	protected := api.Group("")
+	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AdminRequired())
+	protected.Use(middleware.OrganizationScope())
+	{
+		// 节点管理
+		protected.GET("/nodes", handlers.GetNodes)
+		protected.POST("/nodes", handlers.AddNode)
+		protected.PUT("/nodes/:id", handlers.UpdateNode)
+		protected.DELETE("/nodes/:id", confirm("delete_node", handlers.RecordImpact(&models.Node{}, "节点",
+			handlers.ImpactRelation{Key: "backups", Label: "节点备份", Model: &models.Backup{}, Column: "node_id", Where: "is_deleted = false"},
+			handlers.ImpactRelation{Key: "config_snapshots", Label: "配置快照", Model: &models.NodeConfigSnapshot{}, Column: "node_id"})), handlers.DeleteNode)
+		protected.POST("/nodes/:id/test", handlers.TestNodeConnection)
+
+		// Agent 部署管理
+		protected.POST("/nodes/:id/agent/deploy", handlers.DeployAgent)                                                                  // 部署 Agent
+		protected.GET("/nodes/:id/agent/status", handlers.CheckAgentStatus)                                                              // 检查状态
+		protected.DELETE("/nodes/:id/agent", confirm("uninstall_agent", handlers.NodeUninstallImpact("Agent")), handlers.UninstallAgent) // 卸载 Agent
+		protected.GET("/nodes/:id/agent/logs", handlers.GetAgentLogs)                                                                    // 获取日志
+		protected.GET("/nodes/:id/agent/stats", handlers.GetAgentStats)                                                                  // 采集与写入统计
+		protected.GET("/nodes/:id/agent/config", handlers.GetAgentConfig)                                                                // 运行配置
+		protected.PUT("/nodes/:id/agent/config", handlers.UpdateAgentConfig)                                                             // 调整批量写入参数
+		protected.GET("/nodes/:id/agent/filter", handlers.GetAgentLogFilter)                                                             // 日志过滤规则
+		protected.PUT("/nodes/:id/agent/filter", handlers.UpdateAgentLogFilter)                                                          // 保存并下发过滤规则
+		protected.GET("/nodes/:id/agent/control", handlers.GetAgentControlStatus)                                                        // 控制通道连接状态
+		protected.POST("/nodes/:id/agent/control/token", handlers.CreateAgentControlToken)                                               // 生成控制令牌
+		protected.DELETE("/nodes/:id/agent/control/token", handlers.RevokeAgentControlToken)                                             // 吊销控制令牌
+
+		// 日志写入缺失检测
+		protected.GET("/nodes/:id/ingest-gaps", handlers.GetIngestGaps)
+		protected.POST("/nodes/:id/ingest-gaps/reread", handlers.RereadIngestGaps)
+
+		// 配置管理
+		protected.GET("/nodes/:id/config", handlers.GetNodeConfig)
+		protected.GET("/nodes/:id/config/structured", handlers.GetNodeStructuredConfig)
+		protected.POST("/nodes/:id/config", handlers.SaveNodeConfig)
+		protected.POST("/nodes/:id/restart", handlers.RestartNodeService)
+		protected.POST("/nodes/:id/reload", handlers.ReloadNodeService)
+		protected.GET("/nodes/:id/status", handlers.GetNodeStatus)
+		protected.GET("/nodes/:id/metrics", handlers.GetNodeMetrics)
+		protected.GET("/nodes/:id/logs", handlers.GetNodeLogs)
+		protected.GET("/nodes/:id/cache/stats", handlers.GetNodeCacheStats)
+		protected.POST("/nodes/:id/cache/flush", handlers.FlushNodeCache)
+
+		// 批量操作
+		protected.POST("/nodes/batch/config", handlers.BatchUpdateConfig)
+		protected.POST("/nodes/batch/restart", handlers.BatchRestart)
+		protected.POST("/nodes/batch/cache/flush", handlers.BatchFlushCache)
+
+		// 地址映射管理
+		protected.POST("/addresses", handlers.AddAddress)
+		protected.PUT("/addresses/:id", handlers.UpdateAddress)
+		protected.DELETE("/addresses/:id", confirm("delete_address", handlers.RecordImpact(&models.AddressMap{}, "地址映射")), handlers.DeleteAddress)
+		protected.GET("/addresses/:id/history", handlers.GetEntityHistory(models.AuditEntityAddress))
+		protected.POST("/addresses/batch", handlers.BatchAddAddresses)
+		protected.POST("/addresses/import/preview", handlers.PreviewImportAddresses)
+		protected.POST("/addresses/import", handlers.ImportAddresses)
+		protected.GET("/addresses", handlers.GetAddresses)
+
+		// ========== 配置同步 ==========
+		protected.POST("/sync/node/:id/full", handlers.TriggerFullSync)                                                  // 完整同步单个节点
+		protected.POST("/sync/batch", handlers.BatchFullSync)                                                            // 批量完整同步
+		protected.GET("/sync/logs", handlers.GetSyncLogs)                                                                // 获取同步日志
+		protected.GET("/sync/stats", handlers.GetSyncStats)                                                              // 同步统计
+		protected.POST("/sync/logs/:id/retry", handlers.RetrySyncLog)                                                    // 重试失败的同步
+		protected.GET("/sync/jobs", handlers.GetSyncJobs)                                                                // 同步任务列表
+		protected.GET("/sync/jobs/:id", handlers.GetSyncJob)                                                             // 同步任务进度
+		protected.GET("/jobs", handlers.GetBackgroundJobs)                                                               // 后台任务列表
+		protected.GET("/jobs/:id", handlers.GetBackgroundJob)                                                            // 后台任务详情
+		protected.POST("/jobs/:id/retry", handlers.RetryBackgroundJob)                                                   // 从检查点重试失败或中断的任务
+		protected.POST("/jobs/:id/cancel", handlers.CancelBackgroundJob)                                                 // 取消排队中或执行中的任务
+		protected.DELETE("/sync/logs", confirm("clear_sync_logs", handlers.ClearSyncLogsImpact), handlers.ClearSyncLogs) // 清理日志
+
+		// ========== 通知管理 ==========
+		protected.GET("/notifications/channels", handlers.GetNotificationChannels)
+		protected.POST("/notifications/channels", handlers.AddNotificationChannel)
+		protected.PUT("/notifications/channels/:id", handlers.UpdateNotificationChannel)
+		protected.DELETE("/notifications/channels/:id", confirm("delete_notification_channel", handlers.RecordImpact(&models.NotificationChannel{}, "通知渠道")), handlers.DeleteNotificationChannel)
+		protected.POST("/notifications/channels/:id/test", handlers.TestNotificationChannel)
+		protected.GET("/notifications/logs", handlers.GetNotificationLogs)
+		protected.GET("/notifications/stats", handlers.GetNotificationStats)
+		protected.GET("/notifications/dead-letters", handlers.GetNotificationDeadLetters)
+		protected.POST("/notifications/dead-letters/:id/retry", handlers.RetryNotificationDelivery)
+
+		// ========== 安全检测 ==========
+		protected.GET("/security/findings", handlers.GetSecurityFindings)
+		protected.POST("/security/findings/:id/ack", handlers.AcknowledgeSecurityFinding)
+		protected.DELETE("/security/findings/:id", confirm("delete_security_finding", handlers.RecordImpact(&models.SecurityFinding{}, "安全发现")), handlers.DeleteSecurityFinding)
+
+		// ========== 配置漂移 ==========
+		protected.GET("/drift-reports", handlers.GetDriftReports)
+		protected.GET("/drift-reports/:id", handlers.GetDriftReport)
+		protected.POST("/nodes/:id/drift-check", handlers.CheckNodeDrift)
+		protected.GET("/nodes/:id/config-snapshots", handlers.GetNodeConfigSnapshots)
+		protected.POST("/nodes/:id/config-snapshots", handlers.CaptureNodeConfigSnapshot)
+		protected.GET("/config-snapshots/:id", handlers.GetConfigSnapshot)
+
+		// ========== 配置合规 ==========
+		protected.GET("/compliance/summary", handlers.GetComplianceSummary)
+		protected.GET("/compliance/violations", handlers.GetComplianceViolations)
+		protected.GET("/compliance/policies", handlers.GetCompliancePolicies)
+		protected.POST("/compliance/policies", handlers.AddCompliancePolicy)
+		protected.PUT("/compliance/policies/:id", handlers.UpdateCompliancePolicy)
+		protected.DELETE("/compliance/policies/:id", confirm("delete_compliance_policy", handlers.RecordImpact(&models.CompliancePolicy{}, "合规策略",
+			handlers.ImpactRelation{Key: "violations", Label: "违规记录", Model: &models.ComplianceViolation{}, Column: "policy_id"})), handlers.DeleteCompliancePolicy)
+		protected.GET("/compliance/policies/:id/history", handlers.GetEntityHistory(models.AuditEntityCompliance))
+		protected.POST("/nodes/:id/compliance-check", handlers.CheckNodeCompliance)
+
+		// ========== 节点资产报告（全局汇总，仅超级管理员） ==========
+		protected.GET("/reports/fleet", middleware.SuperAdminRequired(), handlers.GetFleetReport)
+		protected.POST("/reports/fleet", middleware.SuperAdminRequired(), handlers.CreateFleetReport)
+		protected.GET("/reports/fleet/history", middleware.SuperAdminRequired(), handlers.GetFleetReportHistory)
+		protected.GET("/reports/fleet/history/:id", middleware.SuperAdminRequired(), handlers.GetSavedFleetReport)
+
+		// ========== 节点解析 SLA（Agent 本地探测） ==========
+		protected.GET("/probe-sla", handlers.GetProbeSLA)
+		protected.POST("/probe-sla/collect", handlers.CollectProbeStats)
+		protected.GET("/nodes/:id/probe-stats", handlers.GetNodeProbeStats)
+
+		// ========== 日志分享 ==========
+		protected.POST("/share-links", handlers.CreateShareLink)
+		protected.GET("/share-links", handlers.GetShareLinks)
+		protected.DELETE("/share-links/:id", confirm("revoke_share_link", handlers.RecordImpact(&models.LogShareLink{}, "分享链接")), handlers.RevokeShareLink)
+
+		// ========== API 令牌 ==========
+		protected.GET("/tokens", middleware.SuperAdminRequired(), handlers.GetAPITokens)
+		protected.POST("/tokens", middleware.SuperAdminRequired(), handlers.CreateAPIToken)
+		protected.DELETE("/tokens/:id", middleware.SuperAdminRequired(), confirm("revoke_api_token", handlers.RecordImpact(&models.APIToken{}, "API 令牌")), handlers.RevokeAPIToken)
+
+		// ========== 节点补丁 ==========
+		protected.GET("/node-facts", handlers.GetNodeFactsList)
+		protected.GET("/nodes/:id/facts", handlers.GetNodeFacts)
+		protected.POST("/nodes/:id/facts/refresh", handlers.RefreshNodeFacts)
+
+		// ========== 批量变更（跨组织节点和全局上游，仅超级管理员） ==========
+		protected.POST("/changes", middleware.SuperAdminRequired(), handlers.CreateChangeSet)
+		protected.GET("/changes", middleware.SuperAdminRequired(), handlers.GetChangeSets)
+		protected.GET("/changes/:id", middleware.SuperAdminRequired(), handlers.GetChangeSet)
+		protected.POST("/changes/:id/apply", middleware.SuperAdminRequired(), handlers.ApplyChangeSet)
+		protected.POST("/changes/:id/retry", middleware.SuperAdminRequired(), handlers.RetryChangeSet)
+		protected.DELETE("/changes/:id", middleware.SuperAdminRequired(), confirm("discard_change_set", handlers.RecordImpact(&models.ChangeSet{}, "变更集")), handlers.DiscardChangeSet)
+
+		// ========== 节点初始化 ==========
+		protected.POST("/nodes/:id/init", handlers.InitNode)                                                                                        // 初始化节点
+		protected.GET("/nodes/:id/init/status", handlers.CheckNodeInit)                                                                             // 检查初始化状态
+		protected.GET("/nodes/:id/init/logs", handlers.GetInitLogs)                                                                                 // 获取初始化日志
+		protected.GET("/nodes/:id/init/stream", handlers.StreamInitProgress)                                                                        // 初始化进度实时推送（SSE）
+		protected.POST("/nodes/:id/uninstall", confirm("uninstall_smartdns", handlers.NodeUninstallImpact("SmartDNS")), handlers.UninstallSmartDNS) // 卸载
+		protected.POST("/nodes/:id/reinstall", handlers.ReinstallSmartDNS)                                                                          // 重新安装
+
+		// ========== SmartDNS 版本 ==========
+		protected.GET("/smartdns/releases", handlers.GetSmartDNSReleases)                 // 版本目录（GitHub Releases）
+		protected.GET("/smartdns/version-pins", handlers.GetSmartDNSVersionPins)          // 按节点标签固定的版本
+		protected.POST("/smartdns/version-pins", handlers.SaveSmartDNSVersionPin)         // 固定或修改标签的目标版本
+		protected.DELETE("/smartdns/version-pins/:id", handlers.DeleteSmartDNSVersionPin) // 取消版本固定
+		protected.GET("/nodes/:id/smartdns/version", handlers.GetNodeSmartDNSVersion)     // 节点当前版本与目标版本
+		protected.POST("/nodes/:id/smartdns/upgrade", handlers.UpgradeNodeSmartDNS)       // 升级 SmartDNS，失败时自动回退
+
+		// ========== 备份管理 ==========
+		protected.GET("/nodes/:id/backups", handlers.GetNodeBackups)                                                                               // 获取备份列表
+		protected.POST("/nodes/:id/backups", handlers.CreateNodeBackup)                                                                            // 创建备份（改为 /backups）
+		protected.POST("/nodes/:id/backups/preview", handlers.PreviewBackup)                                                                       // 预览备份
+		protected.POST("/nodes/:id/backups/restore", confirm("restore_node_backup", handlers.RestoreNodeBackupImpact), handlers.RestoreNodeBackup) // 还原备份（改为 /backups/restore）
+		protected.DELETE("/nodes/:id/backups", confirm("delete_node_backup", handlers.DeleteNodeBackupImpact), handlers.DeleteNodeBackup)          // 删除备份
+		protected.GET("/nodes/:id/backups/download", handlers.DownloadBackup)                                                                      // 下载备份
+		protected.GET("/nodes/:id/backups/retention", handlers.GetNodeBackupRetention)                                                             // 备份保留策略
+		protected.PUT("/nodes/:id/backups/retention", handlers.UpdateNodeBackupRetention)                                                          // 更新保留策略并清理
+
+		// DNS 服务器管理
+		protected.POST("/servers", middleware.SuperAdminRequired(), handlers.AddServer)
+		protected.PUT("/servers/:id", middleware.SuperAdminRequired(), handlers.UpdateServer)
+		protected.DELETE("/servers/:id", middleware.SuperAdminRequired(), confirm("delete_server", handlers.RecordImpact(&models.DNSServer{}, "上游服务器")), handlers.DeleteServer)
+		protected.GET("/servers/:id/history", middleware.SuperAdminRequired(), handlers.GetEntityHistory(models.AuditEntityServer))
+		protected.GET("/servers", middleware.SuperAdminRequired(), handlers.GetServers)
+
+		// 统计信息
+		protected.GET("/dashboard/stats", handlers.GetDashboardStats)
+		protected.GET("/dashboard/health", handlers.GetNodesHealth)
+		protected.GET("/dashboard/health-scores", handlers.GetNodesHealthScore)
+
+		// ========== 告警规则 ==========
+		protected.GET("/alert-rules", handlers.GetAlertRules)
+		protected.POST("/alert-rules", handlers.CreateAlertRule)
+		protected.PUT("/alert-rules/:id", handlers.UpdateAlertRule)
+		protected.DELETE("/alert-rules/:id", handlers.DeleteAlertRule)
+		protected.POST("/alert-rules/:id/evaluate", handlers.EvaluateAlertRule) // 立即评估，dry_run=true 时只返回指标值
+		protected.GET("/alerts", handlers.GetAlerts)
+		protected.POST("/alerts/:id/ack", handlers.AcknowledgeAlert)
+
+		// ========== 域名集管理 ==========
+		protected.GET("/domain-sets", middleware.SuperAdminRequired(), handlers.GetDomainSets)
+		protected.GET("/domain-sets/:id", middleware.SuperAdminRequired(), handlers.GetDomainSet)
+		protected.POST("/domain-sets", middleware.SuperAdminRequired(), handlers.AddDomainSet)
+		protected.PUT("/domain-sets/:id", middleware.SuperAdminRequired(), handlers.UpdateDomainSet)
+		protected.DELETE("/domain-sets/:id", middleware.SuperAdminRequired(), confirm("delete_domain_set", handlers.RecordImpact(&models.DomainSet{}, "域名集",
+			handlers.ImpactRelation{Key: "domains", Label: "域名", Model: &models.DomainSetItem{}, Column: "domain_set_id"})), handlers.DeleteDomainSet)
+		protected.GET("/domain-sets/:id/history", middleware.SuperAdminRequired(), handlers.GetEntityHistory(models.AuditEntityDomainSet))
+		protected.POST("/domain-sets/:id/import", middleware.SuperAdminRequired(), handlers.ImportDomainSetFile)
+		protected.GET("/domain-sets/:id/export", middleware.SuperAdminRequired(), handlers.ExportDomainSet)
+
+		// ========== 屏蔽列表订阅 ==========
+		protected.GET("/blocklist-subscriptions", handlers.GetBlocklistSubscriptions)
+		protected.POST("/blocklist-subscriptions", handlers.AddBlocklistSubscription)
+		protected.PUT("/blocklist-subscriptions/:id", handlers.UpdateBlocklistSubscription)
+		protected.DELETE("/blocklist-subscriptions/:id", confirm("delete_blocklist_subscription", handlers.RecordImpact(&models.BlocklistSubscription{}, "拦截列表订阅")), handlers.DeleteBlocklistSubscription)
+		protected.POST("/blocklist-subscriptions/:id/refresh", handlers.RefreshBlocklistSubscription)
+		protected.GET("/blocklist-subscriptions/:id/history", handlers.GetEntityHistory(models.AuditEntityBlocklist))
+
+		// ========== 维护窗口 ==========
+		protected.GET("/maintenance-windows", handlers.GetMaintenanceWindows)
+		protected.POST("/maintenance-windows", handlers.AddMaintenanceWindow)
+		protected.PUT("/maintenance-windows/:id", handlers.UpdateMaintenanceWindow)
+		protected.DELETE("/maintenance-windows/:id", confirm("delete_maintenance_window", handlers.RecordImpact(&models.MaintenanceWindow{}, "维护窗口")), handlers.DeleteMaintenanceWindow)
+		protected.GET("/maintenance-windows/:id/history", handlers.GetEntityHistory(models.AuditEntityMaintenance))
+		protected.GET("/deferred-actions", handlers.GetDeferredActions)
+		protected.DELETE("/deferred-actions/:id", confirm("cancel_deferred_action", handlers.RecordImpact(&models.DeferredAction{}, "延后操作")), handlers.CancelDeferredAction)
+		protected.POST("/deferred-actions/:id/run", handlers.RunDeferredAction)
+
+		// ========== 域名规则管理 ==========
+		protected.GET("/domain-rules", handlers.GetDomainRules)
+		protected.POST("/domain-rules", handlers.AddDomainRule)
+		protected.PUT("/domain-rules/:id", handlers.UpdateDomainRule)
+		protected.DELETE("/domain-rules/:id", confirm("delete_domain_rule", handlers.RecordImpact(&models.DomainRule{}, "域名规则")), handlers.DeleteDomainRule)
+		protected.GET("/domain-rules/:id/history", handlers.GetEntityHistory(models.AuditEntityDomainRule))
+
+		// DNS 分组管理
+		protected.GET("/groups", middleware.SuperAdminRequired(), handlers.GetGroups)
+		protected.POST("/groups", middleware.SuperAdminRequired(), handlers.AddGroup)
+		protected.PUT("/groups/:id", middleware.SuperAdminRequired(), handlers.UpdateGroup)
+		protected.DELETE("/groups/:id", middleware.SuperAdminRequired(), confirm("delete_group", handlers.RecordImpact(&models.DNSGroup{}, "分组")), handlers.DeleteGroup)
+
+		// 分组配置块（group-begin/group-end）
+		protected.GET("/group-blocks", handlers.GetGroupBlocks)
+		protected.GET("/group-blocks/:id", handlers.GetGroupBlock)
+		protected.POST("/group-blocks", handlers.AddGroupBlock)
+		protected.POST("/group-blocks/preview", handlers.PreviewGroupBlock)
+		protected.POST("/group-blocks/import/:node_id", handlers.ImportGroupBlocks)
+		protected.PUT("/group-blocks/:id", handlers.UpdateGroupBlock)
+		protected.DELETE("/group-blocks/:id", confirm("delete_group_block", handlers.RecordImpact(&models.GroupBlock{}, "分组配置块")), handlers.DeleteGroupBlock)
+		protected.GET("/group-blocks/:id/history", handlers.GetEntityHistory(models.AuditEntityGroupBlock))
+
+		// ========== 客户端规则管理 ==========
+		protected.GET("/client-rules", handlers.GetClientRules)
+		protected.POST("/client-rules", handlers.AddClientRule)
+		protected.PUT("/client-rules/:id", handlers.UpdateClientRule)
+		protected.DELETE("/client-rules/:id", confirm("delete_client_rule", handlers.RecordImpact(&models.ClientRule{}, "客户端规则")), handlers.DeleteClientRule)
+		protected.GET("/client-rules/:id/history", handlers.GetEntityHistory(models.AuditEntityClientRule))
+
+		// ========== 命名服务器规则管理 ==========
+		protected.GET("/nameservers", middleware.SuperAdminRequired(), handlers.GetNameservers)
+		protected.POST("/nameservers", middleware.SuperAdminRequired(), handlers.AddNameserver)
+		protected.PUT("/nameservers/:id", middleware.SuperAdminRequired(), handlers.UpdateNameserver)
+		protected.DELETE("/nameservers/:id", middleware.SuperAdminRequired(), confirm("delete_nameserver", handlers.RecordImpact(&models.Nameserver{}, "域名分流规则")), handlers.DeleteNameserver)
+		protected.GET("/nameservers/:id/history", middleware.SuperAdminRequired(), handlers.GetEntityHistory(models.AuditEntityNameserver))
+
+		// ========== 组织管理 ==========
+		protected.GET("/organizations", handlers.GetOrganizations)
+		protected.GET("/organizations/current", handlers.GetCurrentOrganization) // 当前组织与可切换的组织
+		protected.POST("/organizations", middleware.SuperAdminRequired(), handlers.CreateOrganization)
+		protected.PUT("/organizations/:id", middleware.SuperAdminRequired(), handlers.UpdateOrganization)
+		protected.DELETE("/organizations/:id", middleware.SuperAdminRequired(), handlers.DeleteOrganization)
+		protected.POST("/organizations/:id/assign", middleware.SuperAdminRequired(), handlers.AssignOrganization) // 分配节点、规则、渠道、任务或用户
+
+		// ========== 审计日志 ==========
+		protected.GET("/audit-logs", middleware.SuperAdminRequired(), handlers.GetAuditLogs)
+		protected.GET("/audit-logs/activity", middleware.SuperAdminRequired(), handlers.GetUserActivity)
+
+		// ========== 数据库备份管理 ==========
+		// 备份配置管理
+		protected.GET("/database-backup/configs", middleware.SuperAdminRequired(), databaseBackupHandler.GetBackupConfigs)
+		protected.POST("/database-backup/configs", middleware.SuperAdminRequired(), databaseBackupHandler.CreateBackupConfig)
+		protected.GET("/database-backup/configs/:id", middleware.SuperAdminRequired(), databaseBackupHandler.GetBackupConfig)
+		protected.PUT("/database-backup/configs/:id", middleware.SuperAdminRequired(), databaseBackupHandler.UpdateBackupConfig)
+		protected.DELETE("/database-backup/configs/:id", middleware.SuperAdminRequired(), confirm("delete_backup_config", handlers.RecordImpact(&models.BackupConfig{}, "数据库备份配置",
+			handlers.ImpactRelation{Key: "backup_history", Label: "备份记录", Model: &models.BackupHistory{}, Column: "config_id"})), databaseBackupHandler.DeleteBackupConfig)
+
+		// 备份操作
+		protected.POST("/database-backup/configs/:id/backup", middleware.SuperAdminRequired(), databaseBackupHandler.ManualBackup)
+		protected.GET("/database-backup/history", middleware.SuperAdminRequired(), databaseBackupHandler.GetBackupHistory)
+		protected.POST("/database-backup/restore", middleware.SuperAdminRequired(), confirm("restore_database_backup", handlers.RestoreDatabaseBackupImpact), databaseBackupHandler.RestoreBackup)
+		protected.POST("/database-backup/clickhouse/restore", middleware.SuperAdminRequired(), confirm("restore_clickhouse_backup", handlers.RestoreCHBackupImpact), handlers.RestoreCHBackup)
+		protected.GET("/database-backup/stats", middleware.SuperAdminRequired(), databaseBackupHandler.GetBackupStats)
+		protected.POST("/database-backup/test-s3", middleware.SuperAdminRequired(), databaseBackupHandler.TestS3Connection)
+		protected.POST("/database-backup/test-storage", middleware.SuperAdminRequired(), databaseBackupHandler.TestStorageConnection)
+
+		// 加密密钥管理
+		protected.GET("/database-backup/keys", middleware.SuperAdminRequired(), backupKeyHandler.GetBackupKeys)
+		protected.POST("/database-backup/keys", middleware.SuperAdminRequired(), backupKeyHandler.CreateBackupKey)
+		protected.DELETE("/database-backup/keys/:id", middleware.SuperAdminRequired(), confirm("delete_backup_key", handlers.RecordImpact(&models.BackupEncryptionKey{}, "备份加密密钥")), backupKeyHandler.DeleteBackupKey)
+		protected.POST("/database-backup/keys/:id/rotate", middleware.SuperAdminRequired(), backupKeyHandler.RotateBackupKey)
+		protected.GET("/database-backup/keys/:id/usage", middleware.SuperAdminRequired(), backupKeyHandler.GetBackupKeyUsage)
+
+		// ========== 定时任务管理 ==========
+		// 任务管理
+		protected.GET("/scheduler/tasks", schedulerHandler.GetTasks)
+		protected.POST("/scheduler/tasks", schedulerHandler.CreateTask)
+		protected.GET("/scheduler/tasks/:id", schedulerHandler.GetTask)
+		protected.PUT("/scheduler/tasks/:id", schedulerHandler.UpdateTask)
+		protected.DELETE("/scheduler/tasks/:id", confirm("delete_scheduled_task", handlers.RecordImpact(&models.ScheduledTask{}, "定时任务",
+			handlers.ImpactRelation{Key: "executions", Label: "执行记录", Model: &models.TaskExecution{}, Column: "task_id"})), schedulerHandler.DeleteTask)
+		protected.POST("/scheduler/tasks/:id/toggle", schedulerHandler.ToggleTask)
+		protected.POST("/scheduler/tasks/:id/execute", schedulerHandler.ExecuteTask)
+
+		// 任务执行历史
+		protected.GET("/scheduler/tasks/:id/executions", schedulerHandler.GetTaskExecutions)
+		protected.GET("/scheduler/running", schedulerHandler.GetRunningTasks)
+		protected.GET("/scheduler/stats", schedulerHandler.GetStats)
+		protected.GET("/scheduler/validate-cron", schedulerHandler.ValidateCron)
+
+		// 快速任务创建
+		protected.GET("/scheduler/quick-task/presets", schedulerHandler.GetQuickTaskPresets)
+		protected.POST("/scheduler/quick-task", schedulerHandler.CreateQuickTask)
+
+		// 遥测目标管理
+		protected.GET("/scheduler/telemetry/targets", schedulerHandler.GetTelemetryTargets)
+		protected.POST("/scheduler/telemetry/targets", schedulerHandler.CreateTelemetryTarget)
+		protected.POST("/scheduler/telemetry/targets/import", schedulerHandler.ImportTelemetryTargets)
+		protected.PUT("/scheduler/telemetry/targets/:id", schedulerHandler.UpdateTelemetryTarget)
+		protected.DELETE("/scheduler/telemetry/targets/:id", confirm("delete_telemetry_target", handlers.RecordImpact(&models.TelemetryTarget{}, "遥测目标",
+			handlers.ImpactRelation{Key: "results", Label: "遥测结果", Model: &models.TelemetryResult{}, Column: "target_id"})), schedulerHandler.DeleteTelemetryTarget)
+		protected.POST("/scheduler/telemetry/targets/:id/test", schedulerHandler.TestTelemetryTarget)
+
+		// 遥测结果和统计
+		protected.GET("/scheduler/telemetry/results", schedulerHandler.GetTelemetryResults)
+		protected.GET("/scheduler/telemetry/stats", schedulerHandler.GetTelemetryStats)
+		protected.GET("/scheduler/telemetry/groups", schedulerHandler.GetTelemetryGroups)
+
+		// 脚本模板管理
+		protected.GET("/scheduler/script-templates", schedulerHandler.GetScriptTemplates)
+		protected.POST("/scheduler/script-templates", schedulerHandler.CreateScriptTemplate)
+		protected.PUT("/scheduler/script-templates/:id", schedulerHandler.UpdateScriptTemplate)
+		protected.DELETE("/scheduler/script-templates/:id", confirm("delete_script_template", handlers.RecordImpact(&models.ScriptTemplate{}, "脚本模板")), schedulerHandler.DeleteScriptTemplate)
+		protected.POST("/scheduler/script-templates/:id/render", schedulerHandler.RenderScriptTemplate)
+		protected.GET("/scheduler/script-templates/:id/history", handlers.GetEntityHistory(models.AuditEntityScriptTemplate))
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"smartdns-manager/models"
+)
+
+// addressSpecialValues address 指令中表示屏蔽或忽略的特殊值，只能单独使用
+var addressSpecialValues = map[string]bool{
+	"#": true, "#4": true, "#6": true,
+	"-": true, "-4": true, "-6": true,
+}
+
+// NormalizeAddressMap 校验并规范化地址映射：
+// 域名支持 *.example.com 通配，IP 支持逗号或换行分隔的多个 IPv4/IPv6 地址，规范化为 IPv4 在前的逗号列表
+func NormalizeAddressMap(addr *models.AddressMap) error {
+	addr.Domain = strings.ToLower(strings.TrimSpace(addr.Domain))
+	if err := validateAddressDomain(addr.Domain); err != nil {
+		return err
+	}
+	if addr.TTL < 0 {
+		return fmt.Errorf("TTL 不能为负数")
+	}
+	if addr.Type == "cname" {
+		return nil
+	}
+
+	ips, err := normalizeAddressIPs(addr.IP)
+	if err != nil {
+		return err
+	}
+	addr.IP = ips
+	return nil
+}
+
+// validateAddressDomain 校验域名，* 只能作为 *. 前缀出现，表示仅匹配子域名
+func validateAddressDomain(domain string) error {
+	if domain == "" {
+		return fmt.Errorf("域名不能为空")
+	}
+	if domain == "#" {
+		return nil
+	}
+	name := strings.TrimPrefix(domain, "*.")
+	if strings.ContainsAny(name, "*/ \t") {
+		return fmt.Errorf("域名格式错误: %s，通配符只支持 *.example.com 形式", domain)
+	}
+	if name == "" || strings.HasPrefix(name, ".") || strings.Contains(name, "..") {
+		return fmt.Errorf("域名格式错误: %s", domain)
+	}
+	return nil
+}
+
+// normalizeAddressIPs 拆分、校验并去重 IP 列表
+func normalizeAddressIPs(value string) (string, error) {
+	fields := splitAddressIPs(value)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("IP地址不能为空")
+	}
+
+	if len(fields) == 1 && addressSpecialValues[fields[0]] {
+		return fields[0], nil
+	}
+
+	var v4, v6 []string
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if addressSpecialValues[field] {
+			return "", fmt.Errorf("%s 不能与其他 IP 同时使用", field)
+		}
+		ip := net.ParseIP(field)
+		if ip == nil {
+			return "", fmt.Errorf("无效的IP地址: %s", field)
+		}
+		normalized := ip.String()
+		if seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		if ip.To4() != nil {
+			v4 = append(v4, normalized)
+		} else {
+			v6 = append(v6, normalized)
+		}
+	}
+	return strings.Join(append(v4, v6...), ","), nil
+}
+
+// splitAddressIPs 按逗号、分号或空白拆分 IP 列表
+func splitAddressIPs(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
+
+// mergeAddressIPs 合并两个 IP 列表，任一方为特殊值时以后者为准
+func mergeAddressIPs(a, b string) string {
+	if a == "" || addressSpecialValues[a] || addressSpecialValues[b] {
+		return b
+	}
+	merged, err := normalizeAddressIPs(a + "," + b)
+	if err != nil {
+		return b
+	}
+	return merged
+}
+
+// removeAddressIPs 从 IP 列表中移除指定的 IP，返回剩余的列表
+func removeAddressIPs(value, remove string) string {
+	removed := make(map[string]bool)
+	for _, ip := range splitAddressIPs(remove) {
+		removed[ip] = true
+	}
+
+	var remaining []string
+	for _, ip := range splitAddressIPs(value) {
+		if !removed[ip] {
+			remaining = append(remaining, ip)
+		}
+	}
+	return strings.Join(remaining, ",")
+}
+
+// addressRuleOptions 生成地址映射对应的 domain-rules 选项，无选项时返回空
+func addressRuleOptions(addr *models.AddressMap) string {
+	var opts []string
+	if addr.TTL > 0 {
+		opts = append(opts, "-rr-ttl "+strconv.Itoa(addr.TTL))
+	}
+	if addr.NoServeExpired {
+		opts = append(opts, "-no-serve-expired")
+	}
+	return strings.Join(opts, " ")
+}
+
+// parseAddressRuleOptions 解析只包含地址映射选项的 domain-rules，包含其他选项时返回 false
+func parseAddressRuleOptions(options string, addr *models.AddressMap) bool {
+	fields := strings.Fields(options)
+	if len(fields) == 0 {
+		return false
+	}
+
+	ttl, noServeExpired := 0, false
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "-rr-ttl":
+			if i+1 >= len(fields) {
+				return false
+			}
+			value, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return false
+			}
+			ttl = value
+			i++
+		case "-no-serve-expired":
+			noServeExpired = true
+		default:
+			return false
+		}
+	}
+
+	addr.TTL = ttl
+	addr.NoServeExpired = noServeExpired
+	return true
+}
+
+// mergeParsedAddresses 合并同一域名的多条 address 记录，并把只包含地址映射选项的 domain-rules 归入对应记录
+func mergeParsedAddresses(config *models.SmartDNSConfig) {
+	index := make(map[string]int)
+	addresses := make([]models.AddressMap, 0, len(config.Addresses))
+	for _, addr := range config.Addresses {
+		key := addr.Type + "/" + addr.Domain
+		if i, ok := index[key]; ok && addr.Type != "cname" {
+			addresses[i].IP = mergeAddressIPs(addresses[i].IP, addr.IP)
+			if addresses[i].Comment == "" {
+				addresses[i].Comment = addr.Comment
+			}
+			continue
+		}
+		index[key] = len(addresses)
+		addresses = append(addresses, addr)
+	}
+	config.Addresses = addresses
+
+	rules := make([]models.DomainRule, 0, len(config.DomainRules))
+	for _, rule := range config.DomainRules {
+		if i, ok := index["address/"+rule.Domain]; ok && !rule.IsDomainSet && parseAddressRuleOptions(rule.OtherOptions, &config.Addresses[i]) {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	config.DomainRules = rules
+}
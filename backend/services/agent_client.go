@@ -107,21 +107,49 @@ func CallAgentAPIWithResponse(method, url string, data interface{}) (map[string]
 		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		message := "未知错误"
-		if msg, ok := result["message"].(string); ok {
-			message = msg
-		}
-		return nil, fmt.Errorf("Agent 返回错误状态 %d: %s", resp.StatusCode, message)
+	if err := checkAgentResult(resp.StatusCode, result); err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
+// checkAgentResult 检查 Agent API 的响应状态码和 success 字段
+func checkAgentResult(status int, result map[string]interface{}) error {
+	message := "未知错误"
+	if msg, ok := result["message"].(string); ok {
+		message = msg
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("Agent 返回错误状态 %d: %s", status, message)
+	}
 	if success, ok := result["success"].(bool); !ok || !success {
-		message := "未知错误"
-		if msg, ok := result["message"].(string); ok {
-			message = msg
+		return fmt.Errorf("Agent 操作失败: %s", message)
+	}
+	return nil
+}
+
+// CallNodeAgent 调用节点 Agent API（无返回数据），path 为相对 /api/v1 的路径
+func CallNodeAgent(node *models.Node, method, path string, data interface{}) error {
+	_, err := CallNodeAgentWithResponse(node, method, path, data)
+	return err
+}
+
+// CallNodeAgentWithResponse 调用节点 Agent API（返回响应数据），path 为相对 /api/v1 的路径
+//
+// Agent 已连接控制通道时经控制通道下发，否则直接访问节点的 Agent API 端口
+func CallNodeAgentWithResponse(node *models.Node, method, path string, data interface{}) (map[string]interface{}, error) {
+	hub := GetAgentControlHub()
+	if hub.Connected(node.ID) {
+		status, result, err := hub.Call(node.ID, method, path, data)
+		if err != nil {
+			return nil, fmt.Errorf("请求 Agent 失败: %w", err)
 		}
-		return nil, fmt.Errorf("Agent 操作失败: %s", message)
+		if err := checkAgentResult(status, result); err != nil {
+			return nil, err
+		}
+		return result, nil
 	}
 
-	return result, nil
+	url := fmt.Sprintf("http://%s:%d/api/v1%s", node.Host, GetAgentPort(node), path)
+	return CallAgentAPIWithResponse(method, url, data)
 }
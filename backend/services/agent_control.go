@@ -0,0 +1,247 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+const (
+	// agentControlHandshakeTimeout 连接后等待 Agent 握手消息的时间
+	agentControlHandshakeTimeout = 10 * time.Second
+	// agentControlReadTimeout Agent 每 30 秒发送一次心跳，超过该时间未收到任何消息即断开
+	agentControlReadTimeout = 90 * time.Second
+	// agentControlWriteTimeout 单条消息的发送超时
+	agentControlWriteTimeout = 10 * time.Second
+	// agentControlCallTimeout 等待命令结果的超时，与直接调用 Agent API 一致
+	agentControlCallTimeout = 30 * time.Second
+)
+
+// agentControlConn 一个 Agent 的控制通道连接
+type agentControlConn struct {
+	ws          *websocket.Conn
+	nodeID      uint
+	version     string
+	remoteAddr  string
+	connectedAt time.Time
+	lastSeen    atomic.Int64 // Unix 秒
+
+	sendMu  sync.Mutex
+	mu      sync.Mutex
+	pending map[string]chan models.AgentControlMessage
+	closed  chan struct{}
+}
+
+func (conn *agentControlConn) send(msg models.AgentControlMessage) error {
+	conn.sendMu.Lock()
+	defer conn.sendMu.Unlock()
+	conn.ws.SetWriteDeadline(time.Now().Add(agentControlWriteTimeout))
+	return websocket.JSON.Send(conn.ws, msg)
+}
+
+// AgentControlHub 管理 Agent 主动建立的控制通道，管理端经通道下发命令，无需访问 Agent 的 API 端口
+type AgentControlHub struct {
+	mu     sync.RWMutex
+	conns  map[uint]*agentControlConn
+	nextID atomic.Uint64
+}
+
+var (
+	defaultAgentControlHub *AgentControlHub
+	agentControlHubOnce    sync.Once
+)
+
+// GetAgentControlHub 获取全局控制通道连接表
+func GetAgentControlHub() *AgentControlHub {
+	agentControlHubOnce.Do(func() {
+		defaultAgentControlHub = &AgentControlHub{conns: make(map[uint]*agentControlConn)}
+	})
+	return defaultAgentControlHub
+}
+
+// Serve 处理一个 Agent 连接直到断开，同一节点的新连接会替换旧连接
+func (h *AgentControlHub) Serve(node *models.Node, ws *websocket.Conn, remoteAddr string) {
+	defer ws.Close()
+
+	// 第一条消息为握手，节点 ID 须与控制令牌所属节点一致
+	ws.SetReadDeadline(time.Now().Add(agentControlHandshakeTimeout))
+	var hello models.AgentControlMessage
+	if err := websocket.JSON.Receive(ws, &hello); err != nil || hello.Type != models.AgentControlHello {
+		log.Printf("⚠️ 节点 %s 控制通道握手失败", node.Name)
+		return
+	}
+	if uint(hello.NodeID) != node.ID {
+		log.Printf("⚠️ 节点 %s 控制通道握手的节点 ID 不一致: %d", node.Name, hello.NodeID)
+		return
+	}
+
+	conn := &agentControlConn{
+		ws:          ws,
+		nodeID:      node.ID,
+		version:     hello.Version,
+		remoteAddr:  remoteAddr,
+		connectedAt: time.Now(),
+		pending:     make(map[string]chan models.AgentControlMessage),
+		closed:      make(chan struct{}),
+	}
+	conn.lastSeen.Store(time.Now().Unix())
+
+	h.mu.Lock()
+	previous := h.conns[node.ID]
+	h.conns[node.ID] = conn
+	h.mu.Unlock()
+	if previous != nil {
+		previous.ws.Close()
+	}
+	log.Printf("🔗 节点 %s 的 Agent 已连接控制通道 (v%s, %s)", node.Name, conn.version, conn.remoteAddr)
+
+	defer func() {
+		close(conn.closed)
+		h.mu.Lock()
+		if h.conns[node.ID] == conn {
+			delete(h.conns, node.ID)
+		}
+		h.mu.Unlock()
+		log.Printf("节点 %s 的 Agent 控制通道已断开", node.Name)
+	}()
+
+	for {
+		ws.SetReadDeadline(time.Now().Add(agentControlReadTimeout))
+		var msg models.AgentControlMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			return
+		}
+		conn.lastSeen.Store(time.Now().Unix())
+
+		switch msg.Type {
+		case models.AgentControlPing:
+			conn.send(models.AgentControlMessage{Type: models.AgentControlPong})
+		case models.AgentControlResponse:
+			conn.mu.Lock()
+			reply := conn.pending[msg.ID]
+			delete(conn.pending, msg.ID)
+			conn.mu.Unlock()
+			if reply != nil {
+				reply <- msg
+			}
+		}
+	}
+}
+
+// Connected 节点的 Agent 当前是否已连接控制通道
+func (h *AgentControlHub) Connected(nodeID uint) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.conns[nodeID] != nil
+}
+
+// Disconnect 断开节点的控制通道，用于重新生成或吊销控制令牌
+func (h *AgentControlHub) Disconnect(nodeID uint) {
+	h.mu.RLock()
+	conn := h.conns[nodeID]
+	h.mu.RUnlock()
+	if conn != nil {
+		conn.ws.Close()
+	}
+}
+
+// Status 节点控制通道的连接状态
+func (h *AgentControlHub) Status(node *models.Node) models.AgentControlStatus {
+	status := models.AgentControlStatus{
+		NodeID:      node.ID,
+		TokenIssued: node.AgentControlTokenHash != "",
+	}
+
+	h.mu.RLock()
+	conn := h.conns[node.ID]
+	h.mu.RUnlock()
+	if conn == nil {
+		return status
+	}
+
+	connectedAt := conn.connectedAt
+	lastSeen := time.Unix(conn.lastSeen.Load(), 0)
+	status.Connected = true
+	status.AgentVersion = conn.version
+	status.RemoteAddr = conn.remoteAddr
+	status.ConnectedAt = &connectedAt
+	status.LastSeenAt = &lastSeen
+	return status
+}
+
+// Call 经控制通道调用 Agent API，path 为相对 /api/v1 的路径，返回 Agent 的响应状态码和响应体
+func (h *AgentControlHub) Call(nodeID uint, method, path string, data interface{}) (int, map[string]interface{}, error) {
+	h.mu.RLock()
+	conn := h.conns[nodeID]
+	h.mu.RUnlock()
+	if conn == nil {
+		return 0, nil, fmt.Errorf("Agent 未连接控制通道")
+	}
+
+	msg := models.AgentControlMessage{
+		Type:   models.AgentControlRequest,
+		ID:     strconv.FormatUint(h.nextID.Add(1), 10),
+		Method: method,
+		Path:   path,
+	}
+	if data != nil {
+		body, err := json.Marshal(data)
+		if err != nil {
+			return 0, nil, fmt.Errorf("序列化数据失败: %w", err)
+		}
+		msg.Body = body
+	}
+
+	reply := make(chan models.AgentControlMessage, 1)
+	conn.mu.Lock()
+	conn.pending[msg.ID] = reply
+	conn.mu.Unlock()
+	defer func() {
+		conn.mu.Lock()
+		delete(conn.pending, msg.ID)
+		conn.mu.Unlock()
+	}()
+
+	if err := conn.send(msg); err != nil {
+		return 0, nil, fmt.Errorf("下发命令失败: %w", err)
+	}
+
+	select {
+	case response := <-reply:
+		var result map[string]interface{}
+		if err := json.Unmarshal(response.Body, &result); err != nil {
+			return response.Status, nil, fmt.Errorf("解析响应失败: %w", err)
+		}
+		return response.Status, result, nil
+	case <-conn.closed:
+		return 0, nil, fmt.Errorf("控制通道已断开")
+	case <-time.After(agentControlCallTimeout):
+		return 0, nil, fmt.Errorf("等待 Agent 响应超时")
+	}
+}
+
+// IssueAgentControlToken 为节点生成新的控制令牌并返回明文，旧令牌立即失效并断开当前连接
+func IssueAgentControlToken(node *models.Node) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("生成令牌失败: %w", err)
+	}
+	plain := models.AgentControlTokenPrefix + hex.EncodeToString(secret)
+
+	if err := database.DB.Model(node).Update("agent_control_token_hash", models.HashAPIToken(plain)).Error; err != nil {
+		return "", fmt.Errorf("保存令牌失败: %w", err)
+	}
+	GetAgentControlHub().Disconnect(node.ID)
+	return plain, nil
+}
@@ -1,11 +1,13 @@
 package services
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
+	"smartdns-manager/database"
 	"smartdns-manager/models"
 )
 
@@ -34,18 +36,77 @@ type DeployResponse struct {
 	ConfigPath  string   `json:"config_path"`
 }
 
+func init() {
+	RegisterJobHandler(models.BackgroundJobAgentDeploy, JobHandler{
+		Run:      runAgentDeployJob,
+		Guidance: "Agent 部署在服务重启时中断，请在节点上检查 smartdns-log-agent 的运行状态后重新部署",
+		// 参数中有 ClickHouse 和代理密码
+		SensitivePayload: true,
+	})
+}
+
+// runAgentDeployJob 部署 Agent 并更新节点的 Agent 状态，部署输出记为任务结果
+func runAgentDeployJob(job *JobContext) error {
+	var req models.DeployAgentRequest
+	if err := job.Payload(&req); err != nil {
+		return fmt.Errorf("解析任务参数失败: %w", err)
+	}
+	var node models.Node
+	if err := database.DB.First(&node, job.NodeID()).Error; err != nil {
+		return fmt.Errorf("节点不存在: %w", err)
+	}
+
+	// Windows 节点的连接方式保存到节点，状态检查、卸载和查看日志使用同样的连接
+	if req.DeployMode == models.AgentDeployModeWindows {
+		node.AgentTransport = req.Transport
+		node.WinRMPort = req.WinRMPort
+		node.WinRMHTTPS = req.WinRMHTTPS == nil || *req.WinRMHTTPS
+		node.WinRMInsecure = req.WinRMInsecure
+		database.DB.Model(&node).Updates(map[string]interface{}{
+			"agent_transport": node.AgentTransport,
+			"winrm_port":      node.WinRMPort,
+			"winrm_https":     node.WinRMHTTPS,
+			"winrm_insecure":  node.WinRMInsecure,
+		})
+	}
+
+	job.SetProgress(10, "install")
+	deployService := NewAgentDeployService()
+	response, err := deployService.DeployAgent(job.Context(), &node, &req)
+	if err != nil {
+		return err
+	}
+	if err := job.SetResult(response); err != nil {
+		slog.WarnContext(job.Context(), "记录 Agent 部署输出失败", "error", err)
+	}
+
+	database.DB.Model(&node).Updates(map[string]interface{}{
+		"agent_installed": true,
+		"agent_version":   deployService.GetLatestVersion(),
+		"deploy_mode":     req.DeployMode,
+	})
+	if !response.Success {
+		return fmt.Errorf("%s", response.Message)
+	}
+	return nil
+}
+
 func NewAgentDeployService() *AgentDeployService {
 	return &AgentDeployService{
 		sshTimeout: 1200 * time.Second, // 5分钟超时
 	}
 }
 
-func (s *AgentDeployService) DeployAgent(node *models.Node, req *models.DeployAgentRequest) (*DeployResponse, error) {
-	log.Printf("开始部署 Agent 到节点 %s (%s)", node.Name, node.Host)
+func (s *AgentDeployService) DeployAgent(ctx context.Context, node *models.Node, req *models.DeployAgentRequest) (*DeployResponse, error) {
+	slog.InfoContext(ctx, "开始部署 Agent", "node", node.Name, "host", node.Host)
+
+	if req.DeployMode == models.AgentDeployModeWindows {
+		return s.deployWindowsAgent(ctx, node, req)
+	}
 
 	// 检查请求中是否配置了代理，如果有则赋值到node中
 	if req.ProxyHost != "" && req.ProxyPort > 0 {
-		log.Printf("检测到代理配置，代理类型: %s, 代理地址: %s:%d", req.ProxyType, req.ProxyHost, req.ProxyPort)
+		slog.InfoContext(ctx, "使用代理部署 Agent", "node", node.Name, "proxy_type", req.ProxyType, "proxy_host", req.ProxyHost, "proxy_port", req.ProxyPort)
 
 		node.ProxyConfig = &models.ProxyConfig{
 			Enabled:   true,
@@ -64,9 +125,22 @@ func (s *AgentDeployService) DeployAgent(node *models.Node, req *models.DeployAg
 	}
 	defer sshClient.Close()
 
+	// 需要控制通道或经管理端写入日志时先生成节点控制令牌，重新部署会使旧令牌失效
+	controlToken := ""
+	if req.ControlURL != "" || req.IngestURL != "" {
+		controlToken, err = IssueAgentControlToken(node)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// 生成安装命令
-	installCmd := s.generateInstallCommand(node, req)
-	log.Printf("执行安装命令: %s", installCmd)
+	installCmd := s.generateInstallCommand(ctx, node, req, controlToken)
+	if controlToken != "" {
+		slog.InfoContext(ctx, "执行 Agent 安装命令", "node", node.Name, "command", strings.ReplaceAll(installCmd, controlToken, "***"))
+	} else {
+		slog.InfoContext(ctx, "执行 Agent 安装命令", "node", node.Name, "command", installCmd)
+	}
 
 	// 执行安装
 	output, err := sshClient.ExecuteCommandWithTimeout(installCmd, s.sshTimeout)
@@ -99,7 +173,7 @@ func (s *AgentDeployService) DeployAgent(node *models.Node, req *models.DeployAg
 	// 验证安装
 	status, err := s.CheckAgentStatus(node)
 	if err != nil {
-		log.Printf("验证安装状态失败: %v", err)
+		slog.WarnContext(ctx, "验证 Agent 安装状态失败", "node", node.Name, "error", err)
 	} else if !status.Running {
 		response.Success = false
 		response.Message = "Agent 安装完成但未正常运行"
@@ -108,7 +182,7 @@ func (s *AgentDeployService) DeployAgent(node *models.Node, req *models.DeployAg
 	return response, nil
 }
 
-func (s *AgentDeployService) generateInstallCommand(node *models.Node, req *models.DeployAgentRequest) string {
+func (s *AgentDeployService) generateInstallCommand(ctx context.Context, node *models.Node, req *models.DeployAgentRequest, controlToken string) string {
 	// GitHub 仓库地址（需要替换为实际地址）
 	repoURL := "https://raw.githubusercontent.com/almightyyantao/smartdns-manager/main/agent/install.sh"
 
@@ -116,11 +190,15 @@ func (s *AgentDeployService) generateInstallCommand(node *models.Node, req *mode
 	params := []string{
 		fmt.Sprintf("-n %d", req.NodeID),
 		fmt.Sprintf("-N \"%s\"", node.Name),
-		fmt.Sprintf("-H %s", req.ClickHouseHost),
+	}
+	if req.ClickHouseHost != "" {
+		params = append(params, fmt.Sprintf("-H %s", req.ClickHouseHost))
+	}
+	params = append(params,
 		fmt.Sprintf("-P %d", req.ClickHousePort),
 		fmt.Sprintf("-d %s", req.ClickHouseDB),
 		fmt.Sprintf("-u %s", req.ClickHouseUser),
-	}
+	)
 
 	if req.ClickHousePassword != "" {
 		params = append(params, fmt.Sprintf("-p \"%s\"", req.ClickHousePassword))
@@ -131,11 +209,21 @@ func (s *AgentDeployService) generateInstallCommand(node *models.Node, req *mode
 	if req.DeployMode != "" {
 		params = append(params, fmt.Sprintf("--mode %s", req.DeployMode))
 	}
+	if controlToken != "" {
+		if req.ControlURL != "" {
+			params = append(params, fmt.Sprintf("--control-url \"%s\"", req.ControlURL))
+		}
+		params = append(params, fmt.Sprintf("--control-token %s", controlToken))
+	}
+	if req.IngestURL != "" {
+		params = append(params, fmt.Sprintf("--ingest-url \"%s\"", req.IngestURL))
+	}
+	params = append(params, agentOutputParams(req)...)
 
 	// 如果有代理配置，添加代理参数
 	if node.ProxyConfig != nil && node.ProxyConfig.Enabled {
 		proxyURL := s.buildProxyURL(node.ProxyConfig)
-		log.Printf(proxyURL)
+		slog.InfoContext(ctx, "下载 Agent 使用代理", "proxy", proxyURL)
 		params = append(params, fmt.Sprintf("--proxy \"%s\"", proxyURL))
 	}
 	//log.Printf(node.ProxyConfig.Enabled)
@@ -160,7 +248,7 @@ func (s *AgentDeployService) generateInstallCommand(node *models.Node, req *mode
 		curlCmd := fmt.Sprintf("curl -sSL --proxy %s %s", proxyURL, repoURL)
 		commands = append(commands, fmt.Sprintf("%s | sudo -E bash -s -- %s", curlCmd, paramStr))
 
-		log.Printf("使用代理 %s 下载并执行安装脚本", proxyURL)
+		slog.InfoContext(ctx, "使用代理下载并执行安装脚本", "proxy", proxyURL)
 	} else {
 		// 直接下载执行
 		curlCmd := fmt.Sprintf("curl -sSL %s", repoURL)
@@ -171,6 +259,42 @@ func (s *AgentDeployService) generateInstallCommand(node *models.Node, req *mode
 	return strings.Join(commands, " && ")
 }
 
+// agentOutputParams 附加输出（Kafka、Loki、Elasticsearch）的安装脚本参数，地址为空的输出不传
+func agentOutputParams(req *models.DeployAgentRequest) []string {
+	var params []string
+	if req.KafkaBrokers != "" {
+		params = append(params, fmt.Sprintf("--kafka-brokers \"%s\"", req.KafkaBrokers))
+		if req.KafkaCluster != "" {
+			params = append(params, fmt.Sprintf("--kafka-cluster \"%s\"", req.KafkaCluster))
+		}
+		if req.KafkaTopic != "" {
+			params = append(params, fmt.Sprintf("--kafka-topic \"%s\"", req.KafkaTopic))
+		}
+		if req.KafkaFormat != "" {
+			params = append(params, fmt.Sprintf("--kafka-format %s", req.KafkaFormat))
+		}
+	}
+	if req.LokiURL != "" {
+		params = append(params, fmt.Sprintf("--loki-url \"%s\"", req.LokiURL))
+		if req.LokiLabels != "" {
+			params = append(params, fmt.Sprintf("--loki-labels \"%s\"", req.LokiLabels))
+		}
+		if req.LokiTenant != "" {
+			params = append(params, fmt.Sprintf("--loki-tenant \"%s\"", req.LokiTenant))
+		}
+	}
+	if req.ElasticsearchURL != "" {
+		params = append(params, fmt.Sprintf("--es-url \"%s\"", req.ElasticsearchURL))
+		if req.ElasticsearchIndex != "" {
+			params = append(params, fmt.Sprintf("--es-index \"%s\"", req.ElasticsearchIndex))
+		}
+		if req.ElasticsearchAPIKey != "" {
+			params = append(params, fmt.Sprintf("--es-api-key \"%s\"", req.ElasticsearchAPIKey))
+		}
+	}
+	return params
+}
+
 func (s *AgentDeployService) buildProxyURL(proxyConfig *models.ProxyConfig) string {
 	var proxyURL string
 
@@ -199,6 +323,10 @@ func (s *AgentDeployService) buildProxyURL(proxyConfig *models.ProxyConfig) stri
 }
 
 func (s *AgentDeployService) CheckAgentStatus(node *models.Node) (*AgentStatus, error) {
+	if node.DeployMode == models.AgentDeployModeWindows {
+		return s.checkWindowsAgentStatus(node)
+	}
+
 	sshClient, err := NewSSHClient(node)
 	if err != nil {
 		return &AgentStatus{
@@ -288,7 +416,11 @@ func (s *AgentDeployService) CheckAgentStatus(node *models.Node) (*AgentStatus,
 	status.Running = false
 	return status, nil
 }
-func (s *AgentDeployService) UninstallAgent(node *models.Node) error {
+func (s *AgentDeployService) UninstallAgent(ctx context.Context, node *models.Node) error {
+	if node.DeployMode == models.AgentDeployModeWindows {
+		return s.uninstallWindowsAgent(ctx, node)
+	}
+
 	sshClient, err := NewSSHClient(node)
 	if err != nil {
 		return fmt.Errorf("SSH连接失败: %w", err)
@@ -303,11 +435,15 @@ func (s *AgentDeployService) UninstallAgent(node *models.Node) error {
 		return fmt.Errorf("卸载失败: %w", err)
 	}
 
-	log.Printf("卸载输出: %s", output)
+	slog.InfoContext(ctx, "Agent 卸载输出", "node", node.Name, "output", output)
 	return nil
 }
 
 func (s *AgentDeployService) GetAgentLogs(node *models.Node, lines string) ([]string, error) {
+	if node.DeployMode == models.AgentDeployModeWindows {
+		return s.getWindowsAgentLogs(node, lines)
+	}
+
 	sshClient, err := NewSSHClient(node)
 	if err != nil {
 		return nil, fmt.Errorf("SSH连接失败: %w", err)
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// GetAgentLogFilter 节点保存的过滤规则，未配置时返回空规则
+func GetAgentLogFilter(node *models.Node) (models.AgentLogFilter, error) {
+	var filter models.AgentLogFilter
+	if node.AgentLogFilter == "" {
+		return filter, nil
+	}
+	if err := json.Unmarshal([]byte(node.AgentLogFilter), &filter); err != nil {
+		return filter, fmt.Errorf("解析过滤规则失败: %w", err)
+	}
+	return filter, nil
+}
+
+// ValidateAgentLogFilter 校验过滤规则，规则与 Agent 的校验一致，避免下发时才发现错误
+func ValidateAgentLogFilter(filter *models.AgentLogFilter) error {
+	for _, expr := range filter.IgnorePatterns {
+		if _, err := regexp.Compile(strings.TrimSpace(expr)); err != nil {
+			return fmt.Errorf("正则表达式无效 %q: %w", expr, err)
+		}
+	}
+	for _, client := range filter.IgnoreClients {
+		client = strings.TrimSpace(client)
+		if client == "" {
+			continue
+		}
+		if strings.Contains(client, "/") {
+			if _, err := netip.ParsePrefix(client); err != nil {
+				return fmt.Errorf("客户端网段无效 %q", client)
+			}
+		} else if _, err := netip.ParseAddr(client); err != nil {
+			return fmt.Errorf("客户端地址无效 %q", client)
+		}
+	}
+	for _, rule := range filter.SampleRules {
+		if rule.Rate <= 0 || rule.Rate > 1 {
+			return fmt.Errorf("采样比例必须在 (0, 1] 之间: %s=%v", rule.Suffix, rule.Rate)
+		}
+	}
+	return nil
+}
+
+// SaveAgentLogFilter 校验并保存节点的过滤规则
+func SaveAgentLogFilter(node *models.Node, filter models.AgentLogFilter) error {
+	if err := ValidateAgentLogFilter(&filter); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return fmt.Errorf("序列化过滤规则失败: %w", err)
+	}
+	if err := database.DB.Model(node).Update("agent_log_filter", string(data)).Error; err != nil {
+		return fmt.Errorf("保存过滤规则失败: %w", err)
+	}
+	return nil
+}
+
+// PushAgentLogFilter 下发过滤规则，Agent 保存到本地，重启后继续生效
+func PushAgentLogFilter(node *models.Node, filter models.AgentLogFilter) error {
+	return CallNodeAgent(node, "PUT", "/filter", filter)
+}
+
+// GetAgentLogFilterStatus 节点保存的过滤规则，以及 Agent 当前生效的规则和丢弃统计
+func GetAgentLogFilterStatus(node *models.Node) (*models.AgentLogFilterStatus, error) {
+	rules, err := GetAgentLogFilter(node)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.AgentLogFilterStatus{Rules: rules}
+
+	response, err := CallNodeAgentWithResponse(node, "GET", "/filter", nil)
+	if err != nil {
+		status.AgentError = err.Error()
+		return status, nil
+	}
+	var result struct {
+		Rules models.AgentLogFilter  `json:"rules"`
+		Stats map[string]interface{} `json:"stats"`
+	}
+	if err := decodeAgentData(response, &result); err != nil {
+		status.AgentError = "解析 Agent 响应失败: " + err.Error()
+		return status, nil
+	}
+	status.AgentRules = &result.Rules
+	status.AgentStats = result.Stats
+	return status, nil
+}
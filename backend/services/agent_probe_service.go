@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// agentProbeBucketMinutes 与 Agent 的聚合周期一致
+const agentProbeBucketMinutes = 5
+
+// AgentProbeService 拉取各节点 Agent 的本地解析探测结果，汇总为节点解析 SLA
+type AgentProbeService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+// NewAgentProbeService 创建 Agent 解析探测服务
+func NewAgentProbeService(db *gorm.DB, config *config.Config) (*AgentProbeService, error) {
+	return &AgentProbeService{
+		db:     db,
+		config: config,
+	}, nil
+}
+
+// agentProbeSettings Agent 当前的探测配置
+type agentProbeSettings struct {
+	Domains     []string `json:"domains"`
+	IntervalSec int      `json:"interval_sec"`
+}
+
+// agentProbeBucket Agent 返回的聚合结果
+type agentProbeBucket struct {
+	Start        time.Time `json:"start"`
+	Domain       string    `json:"domain"`
+	Probes       int       `json:"probes"`
+	Failures     int       `json:"failures"`
+	LatencySumMs float64   `json:"latency_sum_ms"`
+	MaxLatencyMs float64   `json:"max_latency_ms"`
+	LastError    string    `json:"last_error"`
+}
+
+// decodeAgentData 将 Agent 响应中的 data 解析到结构体
+func decodeAgentData(response map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(response["data"])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// sameDomains 比较探测域名列表（忽略顺序和大小写）
+func sameDomains(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	normalize := func(list []string) []string {
+		result := make([]string, len(list))
+		for i, d := range list {
+			result[i] = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(d), "."))
+		}
+		sort.Strings(result)
+		return result
+	}
+	na, nb := normalize(a), normalize(b)
+	for i := range na {
+		if na[i] != nb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// collectNode 拉取单个节点的探测结果，必要时先下发探测配置，返回写入的条数
+func (s *AgentProbeService) collectNode(node *models.Node, cfg models.AgentProbeConfig) (int, error) {
+	// 从最近一个已保存的周期开始拉取，该周期可能在上次拉取时尚未结束
+	var last models.AgentProbeStat
+	since := time.Now().Add(-24 * time.Hour)
+	if err := s.db.Where("node_id = ?", node.ID).Order("bucket_start desc").First(&last).Error; err == nil && last.BucketStart.After(since) {
+		since = last.BucketStart
+	}
+
+	response, err := CallNodeAgentWithResponse(node, "GET", fmt.Sprintf("/probe/results?since=%d", since.Unix()), nil)
+	if err != nil {
+		return 0, err
+	}
+	var result struct {
+		Settings agentProbeSettings `json:"settings"`
+		Buckets  []agentProbeBucket `json:"buckets"`
+	}
+	if err := decodeAgentData(response, &result); err != nil {
+		return 0, fmt.Errorf("解析探测结果失败: %w", err)
+	}
+
+	if len(cfg.Domains) > 0 && (!sameDomains(cfg.Domains, result.Settings.Domains) ||
+		(cfg.IntervalSec > 0 && cfg.IntervalSec != result.Settings.IntervalSec)) {
+		if err := CallNodeAgent(node, "PUT", "/probe/config", map[string]interface{}{
+			"domains":      cfg.Domains,
+			"interval_sec": cfg.IntervalSec,
+		}); err != nil {
+			log.Printf("⚠️ 下发节点 %s 探测配置失败: %v", node.Name, err)
+		}
+	}
+
+	if len(result.Buckets) == 0 {
+		return 0, nil
+	}
+
+	stats := make([]models.AgentProbeStat, len(result.Buckets))
+	for i, b := range result.Buckets {
+		stats[i] = models.AgentProbeStat{
+			NodeID:       node.ID,
+			Domain:       b.Domain,
+			BucketStart:  b.Start.In(time.Local),
+			Probes:       b.Probes,
+			Failures:     b.Failures,
+			LatencySumMs: b.LatencySumMs,
+			MaxLatencyMs: b.MaxLatencyMs,
+			LastError:    b.LastError,
+		}
+	}
+	err = s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "node_id"}, {Name: "domain"}, {Name: "bucket_start"}},
+		DoUpdates: clause.AssignmentColumns([]string{"probes", "failures", "latency_sum_ms", "max_latency_ms", "last_error", "updated_at"}),
+	}).CreateInBatches(stats, 200).Error
+	if err != nil {
+		return 0, fmt.Errorf("保存探测结果失败: %w", err)
+	}
+	return len(stats), nil
+}
+
+// Collect 并发拉取节点的探测结果并清理过期数据
+func (s *AgentProbeService) Collect(ctx context.Context, cfg models.AgentProbeConfig) (string, error) {
+	var nodes []models.Node
+	query := s.db.Model(&models.Node{})
+	if len(cfg.NodeIDs) > 0 {
+		query = query.Where("id IN ?", cfg.NodeIDs)
+	} else {
+		query = query.Where("agent_installed = ?", true)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
+		return "", fmt.Errorf("查询节点失败: %w", err)
+	}
+	if len(nodes) == 0 {
+		return "没有已安装 Agent 的节点", nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		saved    int
+		failures []string
+	)
+	semaphore := make(chan struct{}, 5)
+	for i := range nodes {
+		wg.Add(1)
+		go func(node *models.Node) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			n, err := s.collectNode(node, cfg)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", node.Name, err))
+				return
+			}
+			saved += n
+		}(&nodes[i])
+	}
+	wg.Wait()
+
+	retention := cfg.RetentionDays
+	if retention <= 0 {
+		retention = 7
+	}
+	s.db.Where("bucket_start < ?", time.Now().AddDate(0, 0, -retention)).Delete(&models.AgentProbeStat{})
+
+	output := fmt.Sprintf("拉取 %d 个节点，保存 %d 条探测结果", len(nodes)-len(failures), saved)
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		output += fmt.Sprintf("，%d 个节点失败:\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+	if len(failures) == len(nodes) {
+		return output, fmt.Errorf("所有节点拉取失败")
+	}
+	return output, nil
+}
+
+// probeAggregate 聚合查询结果
+type probeAggregate struct {
+	NodeID       uint
+	Domain       string
+	Probes       int
+	Failures     int
+	LatencySum   float64
+	MaxLatency   float64
+	ErrorBuckets int
+	LastBucket   string
+}
+
+// successRate 成功率百分比，没有探测时视为 100%
+func successRate(probes, failures int) float64 {
+	if probes == 0 {
+		return 100
+	}
+	return float64(probes-failures) / float64(probes) * 100
+}
+
+// avgLatency 成功探测的平均延迟
+func avgLatency(sum float64, probes, failures int) float64 {
+	if ok := probes - failures; ok > 0 {
+		return sum / float64(ok)
+	}
+	return 0
+}
+
+// GetSLA 统计 since 之后各节点的解析成功率和延迟，target 为 SLA 目标成功率（百分比）
+func (s *AgentProbeService) GetSLA(since time.Time, target float64) ([]models.ProbeSLA, error) {
+	var rows []probeAggregate
+	err := s.db.Model(&models.AgentProbeStat{}).
+		Select(`node_id, domain, SUM(probes) AS probes, SUM(failures) AS failures,
+			SUM(latency_sum_ms) AS latency_sum, MAX(max_latency_ms) AS max_latency,
+			SUM(CASE WHEN failures * 2 > probes THEN 1 ELSE 0 END) AS error_buckets,
+			MAX(bucket_start) AS last_bucket`).
+		Where("bucket_start >= ?", since).
+		Group("node_id, domain").
+		Order("node_id, domain").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询探测结果失败: %w", err)
+	}
+
+	var nodes []models.Node
+	s.db.Select("id", "name").Find(&nodes)
+	names := make(map[uint]string, len(nodes))
+	for _, node := range nodes {
+		names[node.ID] = node.Name
+	}
+
+	var result []models.ProbeSLA
+	index := make(map[uint]int)
+	for _, row := range rows {
+		i, ok := index[row.NodeID]
+		if !ok {
+			i = len(result)
+			index[row.NodeID] = i
+			result = append(result, models.ProbeSLA{NodeID: row.NodeID, NodeName: names[row.NodeID]})
+		}
+		sla := &result[i]
+
+		sla.Probes += row.Probes
+		sla.Failures += row.Failures
+		sla.AvgLatencyMs += row.LatencySum // 先累加总和，最后换算为平均值
+		if row.MaxLatency > sla.MaxLatencyMs {
+			sla.MaxLatencyMs = row.MaxLatency
+		}
+		// 同一周期多个域名同时失败只计一次，取各域名的最大值作为近似
+		if minutes := row.ErrorBuckets * agentProbeBucketMinutes; minutes > sla.ErrorMinutes {
+			sla.ErrorMinutes = minutes
+		}
+		if last, err := parseDBTime(row.LastBucket); err == nil && last.After(sla.LastBucket) {
+			sla.LastBucket = last
+		}
+
+		sla.Domains = append(sla.Domains, models.ProbeSLADomain{
+			Domain:       row.Domain,
+			Probes:       row.Probes,
+			Failures:     row.Failures,
+			SuccessRate:  successRate(row.Probes, row.Failures),
+			AvgLatencyMs: avgLatency(row.LatencySum, row.Probes, row.Failures),
+			MaxLatencyMs: row.MaxLatency,
+		})
+	}
+
+	for i := range result {
+		sla := &result[i]
+		sla.AvgLatencyMs = avgLatency(sla.AvgLatencyMs, sla.Probes, sla.Failures)
+		sla.SuccessRate = successRate(sla.Probes, sla.Failures)
+		sla.MeetsTarget = sla.SuccessRate >= target
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SuccessRate < result[j].SuccessRate
+	})
+	return result, nil
+}
+
+// parseDBTime 解析聚合查询返回的时间字符串（SQLite 以文本保存时间）
+func parseDBTime(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", value)
+}
+
+// GetNodeStats 获取节点 since 之后的探测明细，用于绘制趋势
+func (s *AgentProbeService) GetNodeStats(nodeID uint, since time.Time) ([]models.AgentProbeStat, error) {
+	var stats []models.AgentProbeStat
+	err := s.db.Where("node_id = ? AND bucket_start >= ?", nodeID, since).
+		Order("bucket_start, domain").Find(&stats).Error
+	return stats, err
+}
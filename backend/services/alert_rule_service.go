@@ -0,0 +1,467 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// alertMetricExprs 各指标在 ClickHouse 中的聚合表达式
+var alertMetricExprs = map[string]string{
+	models.AlertMetricQueryCount:    "toFloat64(count())",
+	models.AlertMetricNXDomainRatio: "if(count() = 0, 0, countIf(" + nxdomainCondition + ") * 100 / count())",
+	models.AlertMetricAvgLatency:    "ifNotFinite(avg(time_ms), 0)",
+	models.AlertMetricP95Latency:    "ifNotFinite(quantile(0.95)(time_ms), 0)",
+	models.AlertMetricUniqueClients: "toFloat64(uniq(client_ip))",
+	// 域名集条目作为参数传入，匹配域名本身及其子域名
+	models.AlertMetricDomainSetHits: "toFloat64(countIf(arrayExists(d -> domain = d OR endsWith(domain, concat('.', d)), ?)))",
+}
+
+// alertCountMetrics 计数类指标，没有日志的节点按 0 计，可用于"查询量降为 0"之类的规则
+var alertCountMetrics = map[string]bool{
+	models.AlertMetricQueryCount:    true,
+	models.AlertMetricUniqueClients: true,
+	models.AlertMetricDomainSetHits: true,
+}
+
+// alertDomainSetMaxItems domain_set_hits 指标最多使用的域名集条目数
+const alertDomainSetMaxItems = 10000
+
+// AlertRuleEngine 告警规则引擎
+//
+// 定期评估启用的告警规则：按规则的统计窗口从 ClickHouse 聚合 DNS 日志指标，
+// 满足条件时生成告警并通知，同一规则同一节点持续满足条件只通知一次；
+// 条件不再满足时标记告警已恢复，按规则设置发送恢复通知。
+type AlertRuleEngine struct {
+	ticker              *time.Ticker
+	stopChan            chan bool
+	notificationService *NotificationService
+	mu                  sync.Mutex // 同一时间只执行一轮评估
+}
+
+// NewAlertRuleEngine 创建告警规则引擎，interval 为检查规则是否到期评估的间隔
+func NewAlertRuleEngine(interval time.Duration) *AlertRuleEngine {
+	return &AlertRuleEngine{
+		ticker:              time.NewTicker(interval),
+		stopChan:            make(chan bool),
+		notificationService: NewNotificationService(),
+	}
+}
+
+// Start 启动规则引擎
+func (e *AlertRuleEngine) Start() {
+	log.Println("告警规则引擎已启动")
+
+	go func() {
+		for {
+			select {
+			case <-e.ticker.C:
+				e.evaluateDue()
+			case <-e.stopChan:
+				log.Println("告警规则引擎已停止")
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止规则引擎
+func (e *AlertRuleEngine) Stop() {
+	e.ticker.Stop()
+	e.stopChan <- true
+}
+
+// BuildAlertRule 校验请求并填充规则字段
+func BuildAlertRule(rule *models.AlertRule, request *models.AlertRuleRequest) error {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return fmt.Errorf("名称不能为空")
+	}
+	if _, ok := alertMetricExprs[request.Metric]; !ok {
+		return fmt.Errorf("不支持的指标: %s", request.Metric)
+	}
+	if _, err := compareAlertValue(0, request.Operator, 0); err != nil {
+		return err
+	}
+	if request.Metric == models.AlertMetricDomainSetHits {
+		if request.DomainSetID == 0 {
+			return fmt.Errorf("domain_set_hits 指标需要指定域名集")
+		}
+		var count int64
+		database.DB.Model(&models.DomainSet{}).Where("id = ?", request.DomainSetID).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("域名集不存在")
+		}
+	}
+	if request.SavedSearchID > 0 {
+		if _, err := LoadSavedSearch(request.SavedSearchID, 0); err != nil {
+			return err
+		}
+	}
+
+	severity := request.Severity
+	if severity == "" {
+		severity = models.AlertSeverityWarning
+	}
+	switch severity {
+	case models.AlertSeverityInfo, models.AlertSeverityWarning, models.AlertSeverityCritical:
+	default:
+		return fmt.Errorf("告警级别必须是 info、warning 或 critical")
+	}
+
+	window := request.WindowMinutes
+	if window <= 0 {
+		window = 5
+	}
+	interval := request.IntervalMinutes
+	if interval <= 0 {
+		interval = 1
+	}
+	if window > 7*24*60 {
+		return fmt.Errorf("统计窗口不能超过 7 天")
+	}
+
+	channelIDs := "[]"
+	if len(request.ChannelIDs) > 0 {
+		data, _ := json.Marshal(request.ChannelIDs)
+		channelIDs = string(data)
+	}
+
+	rule.Name = name
+	rule.Description = request.Description
+	rule.Metric = request.Metric
+	rule.Operator = request.Operator
+	rule.Threshold = request.Threshold
+	rule.WindowMinutes = window
+	rule.IntervalMinutes = interval
+	rule.NodeID = request.NodeID
+	rule.PerNode = request.PerNode
+	rule.MinQueries = request.MinQueries
+	rule.DomainSetID = request.DomainSetID
+	rule.SavedSearchID = request.SavedSearchID
+	rule.Severity = severity
+	rule.ChannelIDs = channelIDs
+	rule.Enabled = request.Enabled == nil || *request.Enabled
+	rule.NotifyResolved = request.NotifyResolved == nil || *request.NotifyResolved
+	return nil
+}
+
+// compareAlertValue 按运算符比较指标值与阈值
+func compareAlertValue(value float64, operator string, threshold float64) (bool, error) {
+	switch operator {
+	case ">":
+		return value > threshold, nil
+	case ">=":
+		return value >= threshold, nil
+	case "<":
+		return value < threshold, nil
+	case "<=":
+		return value <= threshold, nil
+	case "==":
+		return value == threshold, nil
+	case "!=":
+		return value != threshold, nil
+	}
+	return false, fmt.Errorf("不支持的比较运算符: %s", operator)
+}
+
+// Evaluate 计算规则在当前窗口的指标值并判断是否满足告警条件，不生成告警
+func (e *AlertRuleEngine) Evaluate(ctx context.Context, rule *models.AlertRule) ([]models.AlertRuleValue, error) {
+	if database.CHConn == nil {
+		return nil, fmt.Errorf("ClickHouse 未连接，告警规则需要 ClickHouse 日志存储")
+	}
+	expr, ok := alertMetricExprs[rule.Metric]
+	if !ok {
+		return nil, fmt.Errorf("不支持的指标: %s", rule.Metric)
+	}
+
+	// 过滤条件：保存的搜索 + 规则的节点 + 统计窗口
+	now := time.Now()
+	filters := map[string]interface{}{}
+	if rule.SavedSearchID > 0 {
+		search, err := LoadSavedSearch(rule.SavedSearchID, 0)
+		if err != nil {
+			return nil, err
+		}
+		params, err := SavedSearchParams(search, now)
+		if err != nil {
+			return nil, err
+		}
+		filters = ParseDNSLogFilters(func(key string) string { return params[key] })
+	}
+	if rule.NodeID > 0 {
+		filters["node_id"] = rule.NodeID
+	}
+	filters["start_time"] = now.Add(-time.Duration(rule.WindowMinutes) * time.Minute)
+	filters["end_time"] = now
+	where, whereArgs := buildCHLogWhere(filters)
+
+	var args []interface{}
+	if rule.Metric == models.AlertMetricDomainSetHits {
+		var domains []string
+		database.DB.Model(&models.DomainSetItem{}).Where("domain_set_id = ?", rule.DomainSetID).
+			Limit(alertDomainSetMaxItems).Pluck("domain", &domains)
+		if len(domains) == 0 {
+			return nil, fmt.Errorf("域名集为空")
+		}
+		for i := range domains {
+			domains[i] = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domains[i])), "*.")
+		}
+		args = append(args, domains)
+	}
+	args = append(args, whereArgs...)
+
+	groupBy := ""
+	nodeExpr := "toUInt32(0)"
+	if rule.PerNode {
+		nodeExpr = "node_id"
+		groupBy = "GROUP BY node_id"
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	rows, err := database.CHConn.Query(queryCtx, fmt.Sprintf(
+		"SELECT %s, %s, count() FROM dns_query_log WHERE %s %s", nodeExpr, expr, where, groupBy), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询指标失败: %w", err)
+	}
+	defer rows.Close()
+
+	results := make(map[uint]models.AlertRuleValue)
+	for rows.Next() {
+		var nodeID uint32
+		var value float64
+		var queries uint64
+		if err := rows.Scan(&nodeID, &value, &queries); err != nil {
+			return nil, fmt.Errorf("读取指标失败: %w", err)
+		}
+		results[uint(nodeID)] = models.AlertRuleValue{NodeID: uint(nodeID), Value: value, Queries: int64(queries)}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 按节点评估时，计数类指标对没有日志的节点按 0 计
+	if rule.PerNode && alertCountMetrics[rule.Metric] {
+		var nodeIDs []uint
+		query := database.DB.Model(&models.Node{})
+		if rule.NodeID > 0 {
+			query = query.Where("id = ?", rule.NodeID)
+		}
+		query.Pluck("id", &nodeIDs)
+		for _, id := range nodeIDs {
+			if _, ok := results[id]; !ok {
+				results[id] = models.AlertRuleValue{NodeID: id}
+			}
+		}
+	} else if !rule.PerNode {
+		if _, ok := results[0]; !ok {
+			results[0] = models.AlertRuleValue{}
+		}
+	}
+
+	names := alertNodeNames()
+	values := make([]models.AlertRuleValue, 0, len(results))
+	for _, value := range results {
+		value.NodeName = names[value.NodeID]
+		if !alertCountMetrics[rule.Metric] && (value.Queries == 0 || value.Queries < rule.MinQueries) {
+			value.Skipped = true
+		} else {
+			value.Firing, _ = compareAlertValue(value.Value, rule.Operator, rule.Threshold)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// evaluateDue 评估到期的规则
+func (e *AlertRuleEngine) evaluateDue() {
+	if database.CHConn == nil {
+		return
+	}
+	if !e.mu.TryLock() {
+		return
+	}
+	defer e.mu.Unlock()
+
+	var rules []models.AlertRule
+	database.DB.Where("enabled = ?", true).Find(&rules)
+
+	now := time.Now()
+	for i := range rules {
+		rule := &rules[i]
+		if rule.LastEvaluatedAt != nil && now.Sub(*rule.LastEvaluatedAt) < time.Duration(rule.IntervalMinutes)*time.Minute-time.Second {
+			continue
+		}
+		e.EvaluateRule(rule)
+	}
+}
+
+// EvaluateRule 评估规则并更新告警状态
+func (e *AlertRuleEngine) EvaluateRule(rule *models.AlertRule) ([]models.AlertRuleValue, error) {
+	values, err := e.Evaluate(context.Background(), rule)
+
+	now := time.Now()
+	updates := map[string]interface{}{"last_evaluated_at": &now, "last_error": ""}
+	if err != nil {
+		updates["last_error"] = err.Error()
+		log.Printf("⚠️ 告警规则 %s 评估失败: %v", rule.Name, err)
+	}
+	database.DB.Model(rule).Updates(updates)
+	if err != nil {
+		return nil, err
+	}
+
+	var open []models.Alert
+	database.DB.Where("rule_id = ? AND status = ?", rule.ID, models.AlertStatusFiring).Find(&open)
+	openByNode := make(map[uint]*models.Alert, len(open))
+	for i := range open {
+		openByNode[open[i].NodeID] = &open[i]
+	}
+
+	for _, value := range values {
+		alert := openByNode[value.NodeID]
+		switch {
+		case value.Skipped:
+			// 样本不足，保持原状态
+		case value.Firing && alert == nil:
+			e.fire(rule, value, now)
+		case value.Firing:
+			database.DB.Model(alert).Updates(map[string]interface{}{"value": value.Value, "last_seen_at": now})
+		case alert != nil:
+			e.resolve(rule, alert, value, now)
+		}
+	}
+	return values, nil
+}
+
+// fire 生成告警并通知
+func (e *AlertRuleEngine) fire(rule *models.AlertRule, value models.AlertRuleValue, now time.Time) {
+	message := fmt.Sprintf("%s %s %s（当前 %s，最近 %d 分钟）",
+		alertScope(value), alertMetricLabel(rule.Metric), formatAlertCondition(rule), formatAlertValue(rule.Metric, value.Value), rule.WindowMinutes)
+	alert := models.Alert{
+		RuleID:     rule.ID,
+		RuleName:   rule.Name,
+		NodeID:     value.NodeID,
+		NodeName:   value.NodeName,
+		Severity:   rule.Severity,
+		Status:     models.AlertStatusFiring,
+		Metric:     rule.Metric,
+		Value:      value.Value,
+		Threshold:  rule.Threshold,
+		Message:    message,
+		StartedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := database.DB.Create(&alert).Error; err != nil {
+		log.Printf("⚠️ 保存告警失败: %v", err)
+		return
+	}
+
+	title := fmt.Sprintf("%s [%s] %s", alertSeverityIcon(rule.Severity), strings.ToUpper(rule.Severity), rule.Name)
+	e.notify(rule, value.NodeID, title, message)
+}
+
+// resolve 标记告警恢复，按规则设置发送恢复通知
+func (e *AlertRuleEngine) resolve(rule *models.AlertRule, alert *models.Alert, value models.AlertRuleValue, now time.Time) {
+	database.DB.Model(alert).Updates(map[string]interface{}{
+		"status":       models.AlertStatusResolved,
+		"value":        value.Value,
+		"last_seen_at": now,
+		"resolved_at":  &now,
+	})
+	if !rule.NotifyResolved {
+		return
+	}
+
+	content := fmt.Sprintf("%s %s 已恢复（当前 %s），持续 %s",
+		alertScope(value), alertMetricLabel(rule.Metric), formatAlertValue(rule.Metric, value.Value),
+		now.Sub(alert.StartedAt).Round(time.Minute))
+	e.notify(rule, value.NodeID, "✅ [RESOLVED] "+rule.Name, content)
+}
+
+// notify 发送到规则指定的渠道，未指定时按 alert_rule 事件发送
+func (e *AlertRuleEngine) notify(rule *models.AlertRule, nodeID uint, title, content string) {
+	var channelIDs []uint
+	json.Unmarshal([]byte(rule.ChannelIDs), &channelIDs)
+
+	var err error
+	if len(channelIDs) > 0 {
+		err = e.notificationService.SendToChannels(channelIDs, "alert_rule", title, content)
+	} else {
+		err = e.notificationService.SendNotification(nodeID, "alert_rule", title, content)
+	}
+	if err != nil {
+		log.Printf("⚠️ 发送告警通知失败: %v", err)
+	}
+}
+
+// alertNodeNames 节点 ID 到名称的映射
+func alertNodeNames() map[uint]string {
+	var nodes []models.Node
+	database.DB.Select("id", "name").Find(&nodes)
+	names := make(map[uint]string, len(nodes))
+	for _, node := range nodes {
+		names[node.ID] = node.Name
+	}
+	return names
+}
+
+func alertScope(value models.AlertRuleValue) string {
+	if value.NodeID == 0 {
+		return "所有节点"
+	}
+	if value.NodeName != "" {
+		return "节点 " + value.NodeName
+	}
+	return fmt.Sprintf("节点 %d", value.NodeID)
+}
+
+func alertMetricLabel(metric string) string {
+	switch metric {
+	case models.AlertMetricQueryCount:
+		return "查询数"
+	case models.AlertMetricNXDomainRatio:
+		return "NXDOMAIN 比例"
+	case models.AlertMetricAvgLatency:
+		return "平均解析耗时"
+	case models.AlertMetricP95Latency:
+		return "P95 解析耗时"
+	case models.AlertMetricUniqueClients:
+		return "客户端数"
+	case models.AlertMetricDomainSetHits:
+		return "域名集命中数"
+	}
+	return metric
+}
+
+func formatAlertCondition(rule *models.AlertRule) string {
+	return rule.Operator + " " + formatAlertValue(rule.Metric, rule.Threshold)
+}
+
+func formatAlertValue(metric string, value float64) string {
+	switch metric {
+	case models.AlertMetricNXDomainRatio:
+		return fmt.Sprintf("%.2f%%", value)
+	case models.AlertMetricAvgLatency, models.AlertMetricP95Latency:
+		return fmt.Sprintf("%.1fms", value)
+	}
+	return fmt.Sprintf("%.0f", value)
+}
+
+func alertSeverityIcon(severity string) string {
+	switch severity {
+	case models.AlertSeverityCritical:
+		return "🚨"
+	case models.AlertSeverityInfo:
+		return "ℹ️"
+	}
+	return "⚠️"
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// AnswerCheckService 通过各节点解析指定域名并校验应答，发现运营商劫持、上游污染或错误的地址规则
+type AnswerCheckService struct {
+	db                  *gorm.DB
+	config              *config.Config
+	notificationService *NotificationService
+
+	mutex    sync.Mutex
+	alerting map[string]bool // 当前处于不匹配状态的 节点|域名|记录类型
+}
+
+// NewAnswerCheckService 创建 DNS 应答校验服务
+func NewAnswerCheckService(db *gorm.DB, config *config.Config) (*AnswerCheckService, error) {
+	return &AnswerCheckService{
+		db:                  db,
+		config:              config,
+		notificationService: NewNotificationService(),
+		alerting:            make(map[string]bool),
+	}, nil
+}
+
+// answerMonitor 解析后的校验项
+type answerMonitor struct {
+	models.AnswerMonitor
+	ranges []*net.IPNet
+}
+
+// parseAnswerExpect 解析期望值，单个 IP 视为 /32 或 /128 网段
+func parseAnswerExpect(expect []string) ([]*net.IPNet, error) {
+	ranges := make([]*net.IPNet, 0, len(expect))
+	for _, item := range expect {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的期望地址: %s", item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			ranges = append(ranges, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("无效的期望网段: %s", item)
+		}
+		ranges = append(ranges, ipNet)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("至少需要一个期望地址或网段")
+	}
+	return ranges, nil
+}
+
+// parseAnswerMonitors 校验配置并补齐默认值
+func parseAnswerMonitors(monitors []models.AnswerMonitor) ([]answerMonitor, error) {
+	if len(monitors) == 0 {
+		return nil, fmt.Errorf("至少需要配置一个校验域名")
+	}
+
+	parsed := make([]answerMonitor, 0, len(monitors))
+	for i, m := range monitors {
+		m.Domain = strings.TrimSuffix(strings.TrimSpace(m.Domain), ".")
+		if m.Domain == "" {
+			return nil, fmt.Errorf("第 %d 项: 域名不能为空", i+1)
+		}
+		m.RecordType = strings.ToUpper(m.RecordType)
+		if m.RecordType == "" {
+			m.RecordType = "A"
+		}
+		if m.RecordType != "A" && m.RecordType != "AAAA" {
+			return nil, fmt.Errorf("第 %d 项: 记录类型只支持 A/AAAA", i+1)
+		}
+		if m.Match == "" {
+			m.Match = models.AnswerMatchAll
+		}
+		if m.Match != models.AnswerMatchAll && m.Match != models.AnswerMatchAny {
+			return nil, fmt.Errorf("第 %d 项: 匹配方式只支持 all/any", i+1)
+		}
+		ranges, err := parseAnswerExpect(m.Expect)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 项 %s: %w", i+1, m.Domain, err)
+		}
+		parsed = append(parsed, answerMonitor{AnswerMonitor: m, ranges: ranges})
+	}
+	return parsed, nil
+}
+
+// resolveViaNode 直接向节点的 53 端口查询，不经过系统解析器
+func resolveViaNode(ctx context.Context, node models.Node, monitor answerMonitor, timeout time.Duration) ([]string, error) {
+	server := net.JoinHostPort(node.Host, "53")
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, network, server)
+		},
+	}
+
+	network := "ip4"
+	if monitor.RecordType == "AAAA" {
+		network = "ip6"
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// 以 FQDN 查询，避免追加本机的 search 域
+	ips, err := resolver.LookupIP(queryCtx, network, monitor.Domain+".")
+	if err != nil {
+		return nil, err
+	}
+	answers := make([]string, len(ips))
+	for i, ip := range ips {
+		answers[i] = ip.String()
+	}
+	return answers, nil
+}
+
+// checkAnswer 校验单个节点的解析结果
+func checkAnswer(ctx context.Context, node models.Node, monitor answerMonitor, timeout time.Duration) models.AnswerCheckResult {
+	result := models.AnswerCheckResult{
+		NodeID:     node.ID,
+		NodeName:   node.Name,
+		Domain:     monitor.Domain,
+		RecordType: monitor.RecordType,
+		Answers:    []string{},
+		Unexpected: []string{},
+	}
+
+	answers, err := resolveViaNode(ctx, node, monitor, timeout)
+	if err != nil {
+		// 域名不存在可能是被污染，按不匹配处理；超时等网络错误交由节点健康检查告警
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			result.Unexpected = append(result.Unexpected, "NXDOMAIN")
+			return result
+		}
+		result.Error = err.Error()
+		return result
+	}
+	result.Answers = answers
+
+	for _, answer := range answers {
+		ip := net.ParseIP(answer)
+		inRange := false
+		for _, r := range monitor.ranges {
+			if r.Contains(ip) {
+				inRange = true
+				break
+			}
+		}
+		if !inRange {
+			result.Unexpected = append(result.Unexpected, answer)
+		}
+	}
+
+	if monitor.Match == models.AnswerMatchAny {
+		result.Matched = len(result.Unexpected) < len(answers)
+	} else {
+		result.Matched = len(result.Unexpected) == 0
+	}
+	return result
+}
+
+// CheckAnswers 依次通过每个节点解析所有校验域名
+func (s *AnswerCheckService) CheckAnswers(ctx context.Context, cfg models.AnswerCheckConfig) ([]models.AnswerCheckResult, error) {
+	monitors, err := parseAnswerMonitors(cfg.Monitors)
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	var nodes []models.Node
+	query := s.db.Select("id", "name", "host")
+	if len(cfg.NodeIDs) > 0 {
+		query = query.Where("id IN ?", cfg.NodeIDs)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
+		return nil, fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	results := make([]models.AnswerCheckResult, 0, len(nodes)*len(monitors))
+	for _, node := range nodes {
+		for _, monitor := range monitors {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+			results = append(results, checkAnswer(ctx, node, monitor, timeout))
+		}
+	}
+	return results, nil
+}
+
+// RunAnswerCheck 执行校验并发送通知，持续不匹配只通知一次，恢复时再通知
+func (s *AnswerCheckService) RunAnswerCheck(ctx context.Context, cfg models.AnswerCheckConfig) (string, error) {
+	results, err := s.CheckAnswers(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "没有找到需要校验的节点", nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	mismatchByNode := make(map[uint][]models.AnswerCheckResult)
+	recoveredByNode := make(map[uint][]models.AnswerCheckResult)
+	var nodeOrder []uint
+	seen := make(map[uint]bool)
+	addNode := func(nodeID uint) {
+		if !seen[nodeID] {
+			seen[nodeID] = true
+			nodeOrder = append(nodeOrder, nodeID)
+		}
+	}
+
+	var mismatched, failed int
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+			log.Printf("⚠️ 节点 %s 解析 %s 失败: %s", result.NodeName, result.Domain, result.Error)
+			continue
+		}
+
+		key := fmt.Sprintf("%d|%s|%s", result.NodeID, result.Domain, result.RecordType)
+		if result.Matched {
+			if s.alerting[key] {
+				delete(s.alerting, key)
+				addNode(result.NodeID)
+				recoveredByNode[result.NodeID] = append(recoveredByNode[result.NodeID], result)
+			}
+			continue
+		}
+
+		mismatched++
+		if s.alerting[key] {
+			continue
+		}
+		s.alerting[key] = true
+		addNode(result.NodeID)
+		mismatchByNode[result.NodeID] = append(mismatchByNode[result.NodeID], result)
+	}
+
+	for _, nodeID := range nodeOrder {
+		if mismatches := mismatchByNode[nodeID]; len(mismatches) > 0 {
+			var lines []string
+			for _, r := range mismatches {
+				if len(r.Answers) == 0 {
+					lines = append(lines, fmt.Sprintf("- %s %s: 域名不存在（NXDOMAIN）", r.Domain, r.RecordType))
+					continue
+				}
+				lines = append(lines, fmt.Sprintf("- %s %s: 应答 %s，其中 %s 不在期望范围内",
+					r.Domain, r.RecordType, strings.Join(r.Answers, ", "), strings.Join(r.Unexpected, ", ")))
+			}
+			content := fmt.Sprintf("节点 %s 的解析结果与期望不一致，可能存在劫持、上游污染或错误的地址规则：\n%s",
+				mismatches[0].NodeName, strings.Join(lines, "\n"))
+			if err := s.notificationService.SendNotification(nodeID, "dns_answer_mismatch", "🚨 DNS 应答异常", content); err != nil {
+				log.Printf("⚠️ 发送DNS应答异常通知失败: %v", err)
+			}
+		}
+
+		if recovered := recoveredByNode[nodeID]; len(recovered) > 0 {
+			var lines []string
+			for _, r := range recovered {
+				lines = append(lines, fmt.Sprintf("- %s %s: %s", r.Domain, r.RecordType, strings.Join(r.Answers, ", ")))
+			}
+			content := fmt.Sprintf("节点 %s 的解析结果已恢复正常：\n%s", recovered[0].NodeName, strings.Join(lines, "\n"))
+			if err := s.notificationService.SendNotification(nodeID, "dns_answer_mismatch", "✅ DNS 应答恢复", content); err != nil {
+				log.Printf("⚠️ 发送DNS应答恢复通知失败: %v", err)
+			}
+		}
+	}
+
+	output := fmt.Sprintf("校验 %d 项，%d 项不匹配", len(results), mismatched)
+	if failed > 0 {
+		output += fmt.Sprintf("，%d 项解析失败", failed)
+	}
+	return output, nil
+}
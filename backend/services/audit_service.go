@@ -0,0 +1,188 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// auditIgnoredFields 不参与字段对比的字段
+var auditIgnoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// AuditService 配置变更审计服务
+type AuditService struct{}
+
+func NewAuditService() *AuditService {
+	return &AuditService{}
+}
+
+// AuditActor 执行变更的用户
+type AuditActor struct {
+	UserID   uint
+	Username string
+	ClientIP string
+}
+
+// Record 记录一次变更，before/after 为变更前后的实体（新增时 before 为 nil，删除时 after 为 nil）
+func (s *AuditService) Record(actor AuditActor, entityType string, entityID uint, entityName, action string, before, after interface{}) {
+	changes := DiffFields(before, after)
+	if action == models.AuditActionUpdate && len(changes) == 0 {
+		return
+	}
+
+	data, _ := json.Marshal(changes)
+	entry := models.AuditLog{
+		EntityType: entityType,
+		EntityID:   entityID,
+		EntityName: entityName,
+		Action:     action,
+		UserID:     actor.UserID,
+		Username:   actor.Username,
+		ClientIP:   actor.ClientIP,
+		Changes:    string(data),
+	}
+	if err := database.DB.Create(&entry).Error; err != nil {
+		log.Printf("记录审计日志失败: %v", err)
+	}
+}
+
+// History 获取实体的变更历史，按时间倒序
+func (s *AuditService) History(entityType string, entityID uint) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	if err := database.DB.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("created_at desc, id desc").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	s.decode(logs)
+	return logs, nil
+}
+
+// List 分页查询审计日志
+func (s *AuditService) List(filters map[string]interface{}, startTime, endTime *time.Time, page, pageSize int) ([]models.AuditLog, int64, error) {
+	query := database.DB.Model(&models.AuditLog{})
+	for field, value := range filters {
+		query = query.Where(field+" = ?", value)
+	}
+	if startTime != nil {
+		query = query.Where("created_at >= ?", *startTime)
+	}
+	if endTime != nil {
+		query = query.Where("created_at <= ?", *endTime)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var logs []models.AuditLog
+	if err := query.Order("created_at desc, id desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	s.decode(logs)
+	return logs, total, nil
+}
+
+// UserActivities 统计时间范围内各用户的变更次数
+func (s *AuditService) UserActivities(since time.Time) ([]models.UserActivity, error) {
+	var logs []models.AuditLog
+	if err := database.DB.Select("user_id, username, action, created_at").
+		Where("created_at >= ?", since).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	activities := map[uint]*models.UserActivity{}
+	for _, entry := range logs {
+		activity, ok := activities[entry.UserID]
+		if !ok {
+			activity = &models.UserActivity{UserID: entry.UserID, Username: entry.Username}
+			activities[entry.UserID] = activity
+		}
+		activity.Total++
+		switch entry.Action {
+		case models.AuditActionCreate:
+			activity.Creates++
+		case models.AuditActionUpdate:
+			activity.Updates++
+		case models.AuditActionDelete:
+			activity.Deletes++
+		}
+		if entry.CreatedAt.After(activity.LastActiveAt) {
+			activity.LastActiveAt = entry.CreatedAt
+		}
+	}
+
+	result := make([]models.UserActivity, 0, len(activities))
+	for _, activity := range activities {
+		result = append(result, *activity)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Total > result[j].Total
+	})
+	return result, nil
+}
+
+// decode 解析变更内容
+func (s *AuditService) decode(logs []models.AuditLog) {
+	for i := range logs {
+		logs[i].FieldChanges = []models.FieldChange{}
+		if logs[i].Changes != "" {
+			json.Unmarshal([]byte(logs[i].Changes), &logs[i].FieldChanges)
+		}
+	}
+}
+
+// DiffFields 按 JSON 字段对比两个实体，返回发生变化的字段
+func DiffFields(before, after interface{}) []models.FieldChange {
+	beforeFields := toFieldMap(before)
+	afterFields := toFieldMap(after)
+
+	names := map[string]bool{}
+	for name := range beforeFields {
+		names[name] = true
+	}
+	for name := range afterFields {
+		names[name] = true
+	}
+
+	changes := []models.FieldChange{}
+	for name := range names {
+		if auditIgnoredFields[name] {
+			continue
+		}
+		b, a := beforeFields[name], afterFields[name]
+		if reflect.DeepEqual(b, a) {
+			continue
+		}
+		changes = append(changes, models.FieldChange{Field: name, Before: b, After: a})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// toFieldMap 将实体转换为 JSON 字段映射
+func toFieldMap(entity interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if entity == nil {
+		return fields
+	}
+	if v := reflect.ValueOf(entity); v.Kind() == reflect.Ptr && v.IsNil() {
+		return fields
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
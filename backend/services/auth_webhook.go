@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// 认证 Webhook 请求类型
+const (
+	webhookAuthPassword = "password"
+	webhookAuthToken    = "token"
+)
+
+// ErrWebhookUnavailable 认证服务无法访问或返回异常，与凭据错误区分以便提示用户
+var ErrWebhookUnavailable = errors.New("认证服务不可用")
+
+var errWebhookInvalidCredentials = errors.New("用户名或密码错误")
+
+// webhookCacheEntry 认证成功的缓存，避免每次登录都请求认证服务
+type webhookCacheEntry struct {
+	identity  models.SSOIdentity
+	expiresAt time.Time
+}
+
+var (
+	webhookCacheMu sync.Mutex
+	webhookCache   = make(map[string]webhookCacheEntry)
+)
+
+// AuthWebhookEnabled 是否配置了认证 Webhook
+func AuthWebhookEnabled() bool {
+	return config.GetConfig().AuthWebhookURL != ""
+}
+
+// AuthWebhookExclusive 是否所有密码登录（应急管理员除外）都必须经过 Webhook
+func AuthWebhookExclusive() bool {
+	exclusive, _ := strconv.ParseBool(config.GetConfig().AuthWebhookExclusive)
+	return exclusive && AuthWebhookEnabled()
+}
+
+// IsBreakGlassUser 是否为应急管理员账号
+func IsBreakGlassUser(username string) bool {
+	breakGlass := strings.TrimSpace(config.GetConfig().AuthBreakGlassUser)
+	return breakGlass != "" && username == breakGlass
+}
+
+// webhookCacheKey 以请求内容的哈希作为缓存键，不在内存中保存明文密码或令牌
+func webhookCacheKey(payload map[string]string) string {
+	h := sha256.New()
+	for _, key := range []string{"type", "username", "password", "token"} {
+		h.Write([]byte(payload[key]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// webhookCacheTTL 缓存时长，0 表示不缓存
+func webhookCacheTTL() time.Duration {
+	seconds, err := strconv.Atoi(config.GetConfig().AuthWebhookCacheTTL)
+	if err != nil || seconds < 0 {
+		seconds = 300
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// WebhookAuthenticate 将账号密码转发给认证服务校验
+func WebhookAuthenticate(ctx context.Context, username, password, clientIP string) (*models.SSOIdentity, error) {
+	if username == "" || password == "" {
+		return nil, errWebhookInvalidCredentials
+	}
+	return callAuthWebhook(ctx, map[string]string{
+		"type":      webhookAuthPassword,
+		"username":  username,
+		"password":  password,
+		"client_ip": clientIP,
+	})
+}
+
+// WebhookAuthenticateToken 将外部令牌（如内部门户签发的会话令牌）转发给认证服务校验
+func WebhookAuthenticateToken(ctx context.Context, token, clientIP string) (*models.SSOIdentity, error) {
+	if token == "" {
+		return nil, errWebhookInvalidCredentials
+	}
+	return callAuthWebhook(ctx, map[string]string{
+		"type":      webhookAuthToken,
+		"token":     token,
+		"client_ip": clientIP,
+	})
+}
+
+// callAuthWebhook 请求认证服务：2xx 且 authenticated 不为 false 视为认证通过，401/403 视为凭据错误
+func callAuthWebhook(ctx context.Context, payload map[string]string) (*models.SSOIdentity, error) {
+	cfg := config.GetConfig()
+	key := webhookCacheKey(payload)
+	now := time.Now()
+
+	webhookCacheMu.Lock()
+	for k, entry := range webhookCache {
+		if now.After(entry.expiresAt) {
+			delete(webhookCache, k)
+		}
+	}
+	cached, ok := webhookCache[key]
+	webhookCacheMu.Unlock()
+	if ok {
+		identity := cached.identity
+		return &identity, nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := 5 * time.Second
+	if seconds, err := strconv.Atoi(cfg.AuthWebhookTimeout); err == nil && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, cfg.AuthWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookUnavailable, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.AuthWebhookSecret != "" {
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(cfg.AuthWebhookSecret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		req.Header.Set("X-SmartDNS-Timestamp", timestamp)
+		req.Header.Set("X-SmartDNS-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, errWebhookInvalidCredentials
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: 返回状态 %d", ErrWebhookUnavailable, resp.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("%w: 解析响应失败: %v", ErrWebhookUnavailable, err)
+	}
+	if authenticated, ok := claims["authenticated"].(bool); ok && !authenticated {
+		return nil, errWebhookInvalidCredentials
+	}
+
+	identity, err := identityFromWebhookClaims(claims, payload["username"])
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl := webhookCacheTTL(); ttl > 0 {
+		webhookCacheMu.Lock()
+		webhookCache[key] = webhookCacheEntry{identity: *identity, expiresAt: now.Add(ttl)}
+		webhookCacheMu.Unlock()
+	}
+	return identity, nil
+}
+
+// webhookClaim 按点分隔的路径读取声明，如 user.name
+func webhookClaim(claims map[string]interface{}, path string) interface{} {
+	var current interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[part]
+	}
+	return current
+}
+
+// webhookClaimString 读取字符串声明，数字按十进制转换（部分系统的用户ID为数字）
+func webhookClaimString(claims map[string]interface{}, path string) string {
+	switch value := webhookClaim(claims, path).(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+// identityFromWebhookClaims 将认证服务返回的声明映射为外部身份，密码登录时用户名缺省取登录名
+func identityFromWebhookClaims(claims map[string]interface{}, loginName string) (*models.SSOIdentity, error) {
+	cfg := config.GetConfig()
+
+	identity := &models.SSOIdentity{
+		Source:   models.AuthSourceWebhook,
+		Subject:  webhookClaimString(claims, cfg.AuthWebhookSubjectClaim),
+		Username: webhookClaimString(claims, cfg.AuthWebhookUsernameClaim),
+		Email:    webhookClaimString(claims, cfg.AuthWebhookEmailClaim),
+	}
+	if identity.Username == "" {
+		identity.Username = loginName
+	}
+	if identity.Subject == "" {
+		identity.Subject = identity.Username
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: 响应缺少用户标识（%s / %s）", ErrWebhookUnavailable,
+			cfg.AuthWebhookSubjectClaim, cfg.AuthWebhookUsernameClaim)
+	}
+
+	switch groups := webhookClaim(claims, cfg.AuthWebhookGroupsClaim).(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	case string:
+		for _, name := range strings.FieldsFunc(groups, func(r rune) bool { return r == ',' || r == ' ' }) {
+			identity.Groups = append(identity.Groups, name)
+		}
+	}
+
+	// 只接受已知角色，其他值忽略并回退到分组映射
+	switch role := webhookClaimString(claims, cfg.AuthWebhookRoleClaim); role {
+	case "admin", "user", models.SSORoleNone:
+		identity.Role = role
+	}
+	return identity, nil
+}
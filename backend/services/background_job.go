@@ -0,0 +1,451 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"sync"
+	"time"
+
+	"smartdns-manager/database"
+	"smartdns-manager/logging"
+	"smartdns-manager/models"
+)
+
+// maxJobResumes 同一任务在服务重启后最多自动恢复的次数，避免任务导致服务反复崩溃时无限重试
+const maxJobResumes = 3
+
+// JobHandler 后台任务的执行方式
+type JobHandler struct {
+	// Run 执行任务，恢复执行时 job.Checkpoint() 返回上次完成的步骤
+	Run func(job *JobContext) error
+	// Resumable 服务重启后能否从检查点自动继续执行
+	Resumable bool
+	// Guidance 任务中断且无法自动恢复时给操作员的处理建议
+	Guidance string
+	// OnInterrupted 标记中断时清理相关状态，可为空
+	OnInterrupted func(job *models.BackgroundJob)
+	// Cancellable 执行中能否取消，能取消的任务须在步骤之间检查 job.Context()；排队中的任务总能取消
+	Cancellable bool
+	// OnCancelled 排队中的任务被取消时清理相关状态，可为空
+	OnCancelled func(job *models.BackgroundJob)
+	// SensitivePayload 任务参数含密码等敏感信息，成功后清空，失败时保留以便重试
+	SensitivePayload bool
+}
+
+// JobContext 任务执行过程中读取参数和记录检查点
+type JobContext struct {
+	job *models.BackgroundJob
+	ctx context.Context
+}
+
+// Context 带有提交任务的请求 ID，传给服务后记录的日志可与原请求关联；任务被取消时 Done
+func (j *JobContext) Context() context.Context {
+	return j.ctx
+}
+
+// SetProgress 记录执行进度（0-100）和当前步骤说明
+func (j *JobContext) SetProgress(percent int, message string) {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	j.job.Progress = percent
+	j.job.ProgressMessage = message
+	database.DB.Model(j.job).Updates(map[string]interface{}{
+		"progress":         percent,
+		"progress_message": message,
+	})
+}
+
+// SetResult 记录任务结果，任务失败时同样保留，供界面展示执行输出
+func (j *JobContext) SetResult(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("编码任务结果失败: %w", err)
+	}
+	j.job.Result = data
+	return database.DB.Model(j.job).Update("result", data).Error
+}
+
+// Logger 带有 request_id 和任务字段的 Logger
+func (j *JobContext) Logger() *slog.Logger {
+	return jobLogger(j.job)
+}
+
+// NodeID 任务针对的节点
+func (j *JobContext) NodeID() uint {
+	return j.job.NodeID
+}
+
+// Checkpoint 上次完成的步骤，首次执行时为空
+func (j *JobContext) Checkpoint() string {
+	return j.job.Checkpoint
+}
+
+// SaveCheckpoint 记录已完成的步骤
+func (j *JobContext) SaveCheckpoint(step string) {
+	j.job.Checkpoint = step
+	database.DB.Model(j.job).Update("checkpoint", step)
+}
+
+// Payload 解析任务参数
+func (j *JobContext) Payload(v interface{}) error {
+	if j.job.Payload == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(j.job.Payload), v)
+}
+
+var (
+	jobHandlers   = map[string]JobHandler{}
+	jobHandlersMu sync.RWMutex
+)
+
+// RegisterJobHandler 注册后台任务类型
+func RegisterJobHandler(jobType string, handler JobHandler) {
+	jobHandlersMu.Lock()
+	defer jobHandlersMu.Unlock()
+	jobHandlers[jobType] = handler
+}
+
+func getJobHandler(jobType string) (JobHandler, bool) {
+	jobHandlersMu.RLock()
+	defer jobHandlersMu.RUnlock()
+	handler, ok := jobHandlers[jobType]
+	return handler, ok
+}
+
+// StartJob 持久化后台任务并异步执行，同一节点同类任务未结束时拒绝重复提交，
+// ctx 中的请求 ID 随任务保存，任务日志可与提交任务的请求关联
+func StartJob(ctx context.Context, jobType string, nodeID uint, description string, payload interface{}) (*models.BackgroundJob, error) {
+	if _, ok := getJobHandler(jobType); !ok {
+		return nil, fmt.Errorf("未知的后台任务类型: %s", jobType)
+	}
+
+	if nodeID > 0 {
+		var count int64
+		database.DB.Model(&models.BackgroundJob{}).
+			Where("type = ? AND node_id = ? AND status IN ?", jobType, nodeID,
+				[]string{models.BackgroundJobStatusQueued, models.BackgroundJobStatusRunning}).
+			Count(&count)
+		if count > 0 {
+			return nil, fmt.Errorf("该节点已有进行中的同类任务")
+		}
+	}
+
+	job := &models.BackgroundJob{
+		Type:        jobType,
+		NodeID:      nodeID,
+		Description: description,
+		Status:      models.BackgroundJobStatusQueued,
+		RequestID:   logging.RequestID(ctx),
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("编码任务参数失败: %w", err)
+		}
+		job.Payload = string(data)
+	}
+	if err := database.DB.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("创建后台任务失败: %w", err)
+	}
+
+	notifyJobWorkers()
+	return job, nil
+}
+
+// RetryJob 重新执行失败或中断的任务，从检查点继续，任务改为关联本次重试的请求 ID
+func RetryJob(ctx context.Context, id uint) (*models.BackgroundJob, error) {
+	var job models.BackgroundJob
+	if err := database.DB.First(&job, id).Error; err != nil {
+		return nil, fmt.Errorf("后台任务不存在")
+	}
+	if job.Status != models.BackgroundJobStatusFailed && job.Status != models.BackgroundJobStatusInterrupted &&
+		job.Status != models.BackgroundJobStatusCancelled {
+		return nil, fmt.Errorf("任务当前状态为 %s，只能重试失败、中断或已取消的任务", job.Status)
+	}
+
+	job.Status = models.BackgroundJobStatusQueued
+	job.Resumes = 0
+	job.Error = ""
+	job.Guidance = ""
+	job.StartedAt = nil
+	job.FinishedAt = nil
+	job.Progress = 0
+	job.ProgressMessage = ""
+	if id := logging.RequestID(ctx); id != "" {
+		job.RequestID = id
+	}
+	database.DB.Save(&job)
+
+	notifyJobWorkers()
+	return &job, nil
+}
+
+// runJob 执行 worker 领取的任务并记录结果
+func runJob(job *models.BackgroundJob) {
+	handler, ok := getJobHandler(job.Type)
+	if !ok {
+		finishJob(job, handler, fmt.Errorf("未知的后台任务类型: %s", job.Type), false)
+		return
+	}
+
+	ctx := context.Background()
+	if job.RequestID != "" {
+		ctx = logging.WithRequestID(ctx, job.RequestID)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	registerRunningJob(job.ID, cancel)
+	defer func() {
+		unregisterRunningJob(job.ID)
+		cancel()
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			finishJob(job, handler, fmt.Errorf("任务异常退出: %v", r), false)
+		}
+	}()
+
+	jobLogger(job).Info("后台任务开始执行", "checkpoint", job.Checkpoint)
+	err := handler.Run(&JobContext{job: job, ctx: ctx})
+	finishJob(job, handler, err, ctx.Err() != nil)
+}
+
+// jobLogger 带有任务字段的 Logger，提交任务的请求 ID 记为 request_id
+func jobLogger(job *models.BackgroundJob) *slog.Logger {
+	logger := slog.Default().With("job_id", job.ID, "job_type", job.Type)
+	if job.NodeID > 0 {
+		logger = logger.With("node_id", job.NodeID)
+	}
+	if job.RequestID != "" {
+		logger = logger.With("request_id", job.RequestID)
+	}
+	return logger
+}
+
+// finishJob 记录任务结果，cancelled 表示执行期间收到了取消请求
+func finishJob(job *models.BackgroundJob, handler JobHandler, err error, cancelled bool) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      models.BackgroundJobStatusSucceeded,
+		"error":       "",
+		"finished_at": &now,
+	}
+	if cancelled && err != nil {
+		updates["status"] = models.BackgroundJobStatusCancelled
+		updates["error"] = "任务已取消: " + err.Error()
+		jobLogger(job).Info("后台任务已取消", "error", err)
+	} else if err != nil {
+		updates["status"] = models.BackgroundJobStatusFailed
+		updates["error"] = err.Error()
+		jobLogger(job).Error("后台任务失败", "error", err)
+	} else {
+		updates["progress"] = 100
+		if handler.SensitivePayload {
+			updates["payload"] = ""
+		}
+		jobLogger(job).Info("后台任务完成")
+	}
+	database.DB.Model(job).Updates(updates)
+}
+
+// RecoverBackgroundJobs 服务启动时处理上次运行中断的异步操作，须在 JobWorkers 启动前调用：
+// 尚未开始的任务继续排队，可恢复的后台任务重新排队并从检查点继续执行，其余标记为中断并给出处理建议
+func RecoverBackgroundJobs() {
+	var jobs []models.BackgroundJob
+	database.DB.Where("status = ? AND started_at IS NOT NULL", models.BackgroundJobStatusQueued).
+		Or("status = ?", models.BackgroundJobStatusRunning).
+		Order("id").Find(&jobs)
+
+	resumed, interrupted := 0, 0
+	for i := range jobs {
+		job := &jobs[i]
+		handler, ok := getJobHandler(job.Type)
+		if ok && handler.Resumable && job.Resumes < maxJobResumes {
+			job.Resumes++
+			database.DB.Model(job).Updates(map[string]interface{}{
+				"status":  models.BackgroundJobStatusQueued,
+				"resumes": job.Resumes,
+			})
+			log.Printf("🔁 恢复后台任务 #%d (%s)，检查点: %s", job.ID, job.Type, job.Checkpoint)
+			resumed++
+			continue
+		}
+
+		guidance := "任务在服务重启时中断，请确认目标状态后手动重试"
+		if ok && handler.Guidance != "" {
+			guidance = handler.Guidance
+		}
+		reason := "服务重启时任务正在执行，无法自动恢复"
+		if ok && handler.Resumable {
+			reason = fmt.Sprintf("服务重启时任务正在执行，已自动恢复 %d 次仍未完成", job.Resumes)
+		}
+		now := time.Now()
+		database.DB.Model(job).Updates(map[string]interface{}{
+			"status":      models.BackgroundJobStatusInterrupted,
+			"error":       reason,
+			"guidance":    guidance,
+			"finished_at": &now,
+		})
+		if ok && handler.OnInterrupted != nil {
+			handler.OnInterrupted(job)
+		}
+		interrupted++
+	}
+	if resumed > 0 || interrupted > 0 {
+		log.Printf("后台任务恢复: 继续执行 %d 个，标记中断 %d 个", resumed, interrupted)
+	}
+
+	markStaleNodeInit()
+	markInterruptedTaskExecutions()
+	markInterruptedDatabaseBackups()
+	NewChangeSetService().ResumeInterrupted()
+}
+
+// markStaleNodeInit 没有进行中的初始化任务却仍处于 initializing 的节点标记为失败
+func markStaleNodeInit() {
+	active := database.DB.Model(&models.BackgroundJob{}).Select("node_id").
+		Where("type IN ? AND status IN ?",
+			[]string{models.BackgroundJobNodeInit, models.BackgroundJobNodeReinstall},
+			[]string{models.BackgroundJobStatusQueued, models.BackgroundJobStatusRunning})
+	result := database.DB.Model(&models.Node{}).
+		Where("init_status = ? AND id NOT IN (?)", "initializing", active).
+		Update("init_status", "failed")
+	if result.RowsAffected > 0 {
+		log.Printf("⚠️ %d 个节点的初始化在服务重启时中断，已标记为失败", result.RowsAffected)
+	}
+}
+
+// markInterruptedTaskExecutions 服务重启时仍在执行的定时任务记为失败
+func markInterruptedTaskExecutions() {
+	now := time.Now()
+	result := database.DB.Model(&models.TaskExecution{}).
+		Where("status = ?", models.TaskStatusRunning).
+		Updates(map[string]interface{}{
+			"status":   models.TaskStatusFailed,
+			"ended_at": &now,
+			"error":    "服务重启时任务正在执行，已中断。请检查任务处理的节点或数据是否处于中间状态，再手动执行或等待下次调度",
+		})
+	if result.RowsAffected > 0 {
+		log.Printf("⚠️ %d 个定时任务执行记录在服务重启时中断", result.RowsAffected)
+	}
+}
+
+// markInterruptedDatabaseBackups 服务重启时仍在进行的数据库备份记为失败
+func markInterruptedDatabaseBackups() {
+	now := time.Now()
+	result := database.DB.Model(&models.BackupHistory{}).
+		Where("status = ?", "running").
+		Updates(map[string]interface{}{
+			"status":        "failed",
+			"completed_at":  &now,
+			"error_message": "服务重启时备份正在进行，已中断。备份文件可能不完整，请手动重新执行备份",
+		})
+	if result.RowsAffected > 0 {
+		log.Printf("⚠️ %d 个数据库备份在服务重启时中断", result.RowsAffected)
+	}
+}
+
+func init() {
+	RegisterJobHandler(models.BackgroundJobNodeInit, JobHandler{
+		Resumable: true,
+		Run: func(job *JobContext) error {
+			return NewInitService().InitNodeFrom(job.Context(), job.NodeID(), job.Checkpoint(), initProgress(job, 0))
+		},
+		Guidance:      "节点初始化多次中断，请在节点上检查 SmartDNS 的安装情况（systemctl status smartdns），必要时卸载后重新初始化",
+		OnInterrupted: markNodeInitFailed,
+	})
+
+	RegisterJobHandler(models.BackgroundJobNodeUninstall, JobHandler{
+		// 卸载的每一步都可以重复执行
+		Resumable: true,
+		Run: func(job *JobContext) error {
+			return NewInitService().UninstallSmartDNS(job.Context(), job.NodeID())
+		},
+		Guidance: "卸载多次中断，请在节点上确认 smartdns 服务已停止、/usr/sbin/smartdns 和 /etc/smartdns 已删除后再重试",
+	})
+
+	RegisterJobHandler(models.BackgroundJobNodeReinstall, JobHandler{
+		Resumable: true,
+		Run: func(job *JobContext) error {
+			service := NewInitService()
+			checkpoint := job.Checkpoint()
+			if checkpoint == "" {
+				if err := service.UninstallSmartDNS(job.Context(), job.NodeID()); err != nil {
+					publishInitDone(job.NodeID(), "failed", "uninstall: "+err.Error())
+					return fmt.Errorf("卸载失败: %w", err)
+				}
+				job.SaveCheckpoint("uninstall")
+				job.SetProgress(20, "uninstall")
+				time.Sleep(2 * time.Second)
+			}
+			if checkpoint == "uninstall" {
+				checkpoint = ""
+			}
+			return service.InitNodeFrom(job.Context(), job.NodeID(), checkpoint, initProgress(job, 20))
+		},
+		Guidance:      "重新安装多次中断，节点上的 SmartDNS 可能已被卸载，请检查节点后重新初始化",
+		OnInterrupted: markNodeInitFailed,
+	})
+
+	RegisterJobHandler(models.BackgroundJobFullSync, JobHandler{
+		// 完整同步按节点记录进度，已同步的节点不再重复同步
+		Resumable:   true,
+		Cancellable: true,
+		Run: func(job *JobContext) error {
+			var nodeIDs []uint
+			if err := job.Payload(&nodeIDs); err != nil {
+				return fmt.Errorf("解析任务参数失败: %w", err)
+			}
+			done := 0
+			fmt.Sscanf(job.Checkpoint(), "%d", &done)
+
+			syncService := NewConfigSyncService()
+			var failed []string
+			for i := done; i < len(nodeIDs); i++ {
+				// 取消时已同步的节点保持不变，重试时从下一个节点继续
+				if err := job.Context().Err(); err != nil {
+					return fmt.Errorf("已同步 %d/%d 个节点: %w", i, len(nodeIDs), err)
+				}
+				if err := syncService.FullSyncToNode(job.Context(), nodeIDs[i]); err != nil {
+					slog.ErrorContext(job.Context(), "节点完整同步失败", "node_id", nodeIDs[i], "error", err)
+					failed = append(failed, fmt.Sprintf("节点 %d: %v", nodeIDs[i], err))
+				}
+				job.SaveCheckpoint(fmt.Sprintf("%d", i+1))
+				job.SetProgress((i+1)*100/len(nodeIDs), fmt.Sprintf("已同步 %d/%d 个节点", i+1, len(nodeIDs)))
+			}
+			if len(failed) > 0 {
+				return fmt.Errorf("%d/%d 个节点同步失败: %v", len(failed), len(nodeIDs), failed)
+			}
+			return nil
+		},
+		Guidance: "完整同步多次中断，请在同步日志中确认各节点状态后重新同步",
+	})
+}
+
+// initProgress 记录初始化检查点，并按已完成的步骤换算进度，base 为之前步骤占用的进度
+func initProgress(job *JobContext, base int) func(step string) {
+	steps := append([]string{"detect"}, initSteps...)
+	return func(step string) {
+		job.SaveCheckpoint(step)
+		for i, name := range steps {
+			if name == step {
+				job.SetProgress(base+(100-base)*(i+1)/len(steps), step)
+				break
+			}
+		}
+	}
+}
+
+// markNodeInitFailed 初始化任务中断时节点不再停留在 initializing
+func markNodeInitFailed(job *models.BackgroundJob) {
+	database.DB.Model(&models.Node{}).
+		Where("id = ? AND init_status = ?", job.NodeID, "initializing").
+		Update("init_status", "failed")
+}
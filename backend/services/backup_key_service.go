@@ -0,0 +1,394 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// BackupKeyService 备份加密密钥管理服务
+//
+// 采用信封加密：每个备份生成独立的数据密钥，数据密钥由命名密钥包装后随备份记录保存，
+// 命名密钥本身由主密钥包装存储（local）或引用外部注入的密钥材料（env）。
+type BackupKeyService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+// NewBackupKeyService 创建备份加密密钥管理服务
+func NewBackupKeyService(db *gorm.DB) *BackupKeyService {
+	return &BackupKeyService{
+		db:     db,
+		config: config.GetConfig(),
+	}
+}
+
+// masterKey 获取用于包装 local 密钥的主密钥
+func (s *BackupKeyService) masterKey() []byte {
+	secret := s.config.BackupMasterKey
+	if secret == "" {
+		secret = s.config.JWTSecret
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// sealWithKey 使用 AES-GCM 加密并返回 base64 编码结果
+func sealWithKey(key, plaintext []byte) (string, error) {
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// openWithKey 解密 sealWithKey 生成的数据
+func openWithKey(key []byte, sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("密文长度无效")
+	}
+
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// randomKeyMaterial 生成随机密钥材料（hex 编码）
+func randomKeyMaterial() ([]byte, error) {
+	raw := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return nil, err
+	}
+	return []byte(hex.EncodeToString(raw)), nil
+}
+
+// envKeyMaterial 读取外部注入的密钥材料
+func envKeyMaterial(keyRef string) ([]byte, error) {
+	if keyRef == "" {
+		return nil, fmt.Errorf("env 提供方必须指定环境变量名")
+	}
+	value := os.Getenv(keyRef)
+	if value == "" {
+		return nil, fmt.Errorf("环境变量 %s 未设置", keyRef)
+	}
+	return []byte(value), nil
+}
+
+// keyMaterial 获取命名密钥的明文密钥材料
+func (s *BackupKeyService) keyMaterial(key *models.BackupEncryptionKey) ([]byte, error) {
+	switch key.Provider {
+	case models.BackupKeyProviderEnv:
+		return envKeyMaterial(key.KeyRef)
+	case models.BackupKeyProviderLocal, "":
+		material, err := openWithKey(s.masterKey(), key.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("解包密钥 %s 失败（主密钥是否变更？）: %w", key.Name, err)
+		}
+		return material, nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥提供方: %s", key.Provider)
+	}
+}
+
+// ListKeys 获取所有加密密钥及其引用数量
+func (s *BackupKeyService) ListKeys() ([]models.BackupEncryptionKey, error) {
+	var keys []models.BackupEncryptionKey
+	if err := s.db.Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("查询加密密钥失败: %w", err)
+	}
+
+	for i := range keys {
+		s.db.Model(&models.BackupConfig{}).Where("encryption_key_id = ?", keys[i].ID).Count(&keys[i].ConfigCount)
+		s.db.Model(&models.BackupHistory{}).Where("encryption_key_id = ?", keys[i].ID).Count(&keys[i].BackupCount)
+	}
+
+	return keys, nil
+}
+
+// CreateKey 创建命名加密密钥
+func (s *BackupKeyService) CreateKey(request *models.BackupKeyRequest) (*models.BackupEncryptionKey, error) {
+	key := &models.BackupEncryptionKey{
+		Name:        request.Name,
+		Description: request.Description,
+		Provider:    request.Provider,
+		Version:     1,
+	}
+	if key.Provider == "" {
+		key.Provider = models.BackupKeyProviderLocal
+	}
+
+	switch key.Provider {
+	case models.BackupKeyProviderLocal:
+		material, err := randomKeyMaterial()
+		if err != nil {
+			return nil, fmt.Errorf("生成密钥失败: %w", err)
+		}
+		if key.WrappedKey, err = sealWithKey(s.masterKey(), material); err != nil {
+			return nil, fmt.Errorf("包装密钥失败: %w", err)
+		}
+	case models.BackupKeyProviderEnv:
+		if _, err := envKeyMaterial(request.KeyRef); err != nil {
+			return nil, err
+		}
+		key.KeyRef = request.KeyRef
+	default:
+		return nil, fmt.Errorf("不支持的密钥提供方: %s", key.Provider)
+	}
+
+	if err := s.db.Create(key).Error; err != nil {
+		return nil, fmt.Errorf("保存加密密钥失败: %w", err)
+	}
+
+	return key, nil
+}
+
+// DeleteKey 删除加密密钥，仍被备份配置或备份引用时拒绝删除
+func (s *BackupKeyService) DeleteKey(id uint) error {
+	var configCount, backupCount int64
+	s.db.Model(&models.BackupConfig{}).Where("encryption_key_id = ?", id).Count(&configCount)
+	s.db.Model(&models.BackupHistory{}).Where("encryption_key_id = ?", id).Count(&backupCount)
+	if configCount > 0 || backupCount > 0 {
+		return fmt.Errorf("密钥仍被 %d 个备份配置和 %d 个备份引用，无法删除", configCount, backupCount)
+	}
+
+	result := s.db.Delete(&models.BackupEncryptionKey{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("删除加密密钥失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("加密密钥不存在")
+	}
+	return nil
+}
+
+// GetKeyUsage 获取依赖指定密钥的备份配置和备份
+func (s *BackupKeyService) GetKeyUsage(id uint) (*models.BackupKeyUsage, error) {
+	usage := &models.BackupKeyUsage{}
+	if err := s.db.First(&usage.Key, id).Error; err != nil {
+		return nil, fmt.Errorf("加密密钥不存在: %w", err)
+	}
+
+	if err := s.db.Where("encryption_key_id = ?", id).Find(&usage.Configs).Error; err != nil {
+		return nil, fmt.Errorf("查询备份配置失败: %w", err)
+	}
+	if err := s.db.Where("encryption_key_id = ?", id).Order("created_at DESC").Find(&usage.Backups).Error; err != nil {
+		return nil, fmt.Errorf("查询备份记录失败: %w", err)
+	}
+	usage.Key.ConfigCount = int64(len(usage.Configs))
+	usage.Key.BackupCount = int64(len(usage.Backups))
+
+	return usage, nil
+}
+
+// RotateKey 轮换密钥：生成新的密钥材料并重新包装所有依赖备份的数据密钥
+func (s *BackupKeyService) RotateKey(id uint, request *models.BackupKeyRotateRequest) (*models.BackupEncryptionKey, int, error) {
+	var key models.BackupEncryptionKey
+	if err := s.db.First(&key, id).Error; err != nil {
+		return nil, 0, fmt.Errorf("加密密钥不存在: %w", err)
+	}
+
+	oldMaterial, err := s.keyMaterial(&key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var newMaterial []byte
+	switch key.Provider {
+	case models.BackupKeyProviderEnv:
+		if request.KeyRef == "" || request.KeyRef == key.KeyRef {
+			return nil, 0, fmt.Errorf("轮换 env 密钥需要指定新的环境变量名")
+		}
+		if newMaterial, err = envKeyMaterial(request.KeyRef); err != nil {
+			return nil, 0, err
+		}
+		key.KeyRef = request.KeyRef
+	default:
+		if newMaterial, err = randomKeyMaterial(); err != nil {
+			return nil, 0, fmt.Errorf("生成密钥失败: %w", err)
+		}
+		if key.WrappedKey, err = sealWithKey(s.masterKey(), newMaterial); err != nil {
+			return nil, 0, fmt.Errorf("包装密钥失败: %w", err)
+		}
+	}
+
+	now := time.Now()
+	key.Version++
+	key.RotatedAt = &now
+
+	rewrapped := 0
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var histories []models.BackupHistory
+		if err := tx.Where("encryption_key_id = ? AND wrapped_data_key <> ''", id).Find(&histories).Error; err != nil {
+			return fmt.Errorf("查询依赖备份失败: %w", err)
+		}
+
+		for _, history := range histories {
+			dataKey, err := openWithKey(oldMaterial, history.WrappedDataKey)
+			if err != nil {
+				return fmt.Errorf("解包备份 %d 的数据密钥失败: %w", history.ID, err)
+			}
+			wrapped, err := sealWithKey(newMaterial, dataKey)
+			if err != nil {
+				return fmt.Errorf("重新包装备份 %d 的数据密钥失败: %w", history.ID, err)
+			}
+			if err := tx.Model(&models.BackupHistory{}).Where("id = ?", history.ID).Updates(map[string]interface{}{
+				"wrapped_data_key": wrapped,
+				"key_version":      key.Version,
+			}).Error; err != nil {
+				return fmt.Errorf("更新备份 %d 失败: %w", history.ID, err)
+			}
+			rewrapped++
+		}
+
+		return tx.Save(&key).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	log.Printf("🔑 加密密钥 %s 已轮换至版本 %d，重新包装 %d 个数据密钥", key.Name, key.Version, rewrapped)
+	return &key, rewrapped, nil
+}
+
+// GenerateDataKey 为一次备份生成数据密钥，返回明文数据密钥及包装后的数据密钥
+func (s *BackupKeyService) GenerateDataKey(keyID uint) (string, string, int, error) {
+	var key models.BackupEncryptionKey
+	if err := s.db.First(&key, keyID).Error; err != nil {
+		return "", "", 0, fmt.Errorf("加密密钥不存在: %w", err)
+	}
+
+	material, err := s.keyMaterial(&key)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	dataKey, err := randomKeyMaterial()
+	if err != nil {
+		return "", "", 0, fmt.Errorf("生成数据密钥失败: %w", err)
+	}
+	wrapped, err := sealWithKey(material, dataKey)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("包装数据密钥失败: %w", err)
+	}
+
+	return string(dataKey), wrapped, key.Version, nil
+}
+
+// UnwrapDataKey 解包备份的数据密钥
+func (s *BackupKeyService) UnwrapDataKey(history *models.BackupHistory) (string, error) {
+	if history.EncryptionKeyID == nil || history.WrappedDataKey == "" {
+		return "", fmt.Errorf("备份未使用命名密钥加密")
+	}
+
+	var key models.BackupEncryptionKey
+	if err := s.db.First(&key, *history.EncryptionKeyID).Error; err != nil {
+		return "", fmt.Errorf("加密密钥不存在: %w", err)
+	}
+
+	material, err := s.keyMaterial(&key)
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := openWithKey(material, history.WrappedDataKey)
+	if err != nil {
+		return "", fmt.Errorf("解包数据密钥失败: %w", err)
+	}
+	return string(dataKey), nil
+}
+
+// MigrateLegacyKeys 将备份配置中的明文密钥迁移为命名密钥
+//
+// 旧版备份直接使用明文密钥加密，迁移时将该密钥作为这些备份的数据密钥包装保存，
+// 之后即可随命名密钥一起轮换，恢复时也无需再手动输入密码。
+func (s *BackupKeyService) MigrateLegacyKeys() error {
+	var configs []models.BackupConfig
+	if err := s.db.Where("encryption_key <> '' AND encryption_key_id IS NULL").Find(&configs).Error; err != nil {
+		return fmt.Errorf("查询待迁移的备份配置失败: %w", err)
+	}
+
+	for _, cfg := range configs {
+		material, err := randomKeyMaterial()
+		if err != nil {
+			return fmt.Errorf("生成密钥失败: %w", err)
+		}
+		wrappedKey, err := sealWithKey(s.masterKey(), material)
+		if err != nil {
+			return fmt.Errorf("包装密钥失败: %w", err)
+		}
+		wrappedDataKey, err := sealWithKey(material, []byte(cfg.EncryptionKey))
+		if err != nil {
+			return fmt.Errorf("包装备份配置 %d 的密钥失败: %w", cfg.ID, err)
+		}
+
+		key := models.BackupEncryptionKey{
+			Name:        fmt.Sprintf("legacy-config-%d", cfg.ID),
+			Description: fmt.Sprintf("由备份配置「%s」的明文密钥迁移", cfg.Name),
+			Provider:    models.BackupKeyProviderLocal,
+			WrappedKey:  wrappedKey,
+			Version:     1,
+		}
+
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&key).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.BackupHistory{}).
+				Where("config_id = ? AND file_name LIKE ? AND (wrapped_data_key IS NULL OR wrapped_data_key = '')", cfg.ID, "%.enc").
+				Updates(map[string]interface{}{
+					"encryption_key_id": key.ID,
+					"key_version":       key.Version,
+					"wrapped_data_key":  wrappedDataKey,
+				}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.BackupConfig{}).Where("id = ?", cfg.ID).Updates(map[string]interface{}{
+				"encryption_key_id": key.ID,
+				"encryption_key":    "",
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("迁移备份配置 %d 的密钥失败: %w", cfg.ID, err)
+		}
+
+		log.Printf("🔑 备份配置 %s 的明文密钥已迁移为命名密钥 %s", cfg.Name, key.Name)
+	}
+
+	return nil
+}
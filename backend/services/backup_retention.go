@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// ErrBackupUnchanged 节点配置与最近一次备份相同，未创建新备份
+var ErrBackupUnchanged = errors.New("配置未变化，跳过备份")
+
+// confirmUnchanged 最近一次备份内容与 contentHash 相同时更新确认时间并返回该备份
+func (bs *BackupService) confirmUnchanged(nodeID uint, contentHash string) *models.Backup {
+	var latest models.Backup
+	if err := database.DB.Where("node_id = ? AND is_deleted = ?", nodeID, false).
+		Order("created_at desc, id desc").First(&latest).Error; err != nil {
+		return nil
+	}
+	if latest.ContentHash != contentHash {
+		return nil
+	}
+
+	now := time.Now()
+	latest.ConfirmedAt = &now
+	database.DB.Model(&latest).Update("confirmed_at", now)
+	return &latest
+}
+
+// GetNodeBackupRetention 获取节点的备份保留策略，未设置时返回默认策略
+func GetNodeBackupRetention(nodeID uint) models.NodeBackupRetention {
+	retention := models.NodeBackupRetention{
+		NodeID:     nodeID,
+		KeepLast:   models.DefaultBackupKeepLast,
+		KeepDaily:  models.DefaultBackupKeepDaily,
+		KeepWeekly: models.DefaultBackupKeepWeekly,
+	}
+	database.DB.Where("node_id = ?", nodeID).First(&retention)
+	return retention
+}
+
+// ApplyRetention 按保留策略清理节点的自动备份，返回删除的备份数
+func (bs *BackupService) ApplyRetention(ctx context.Context, node *models.Node) (int, error) {
+	retention := GetNodeBackupRetention(node.ID)
+	if retention.KeepLast <= 0 && retention.KeepDaily <= 0 && retention.KeepWeekly <= 0 {
+		return 0, nil
+	}
+
+	var backups []models.Backup
+	if err := database.DB.Where("node_id = ? AND is_auto = ? AND is_deleted = ?", node.ID, true, false).
+		Order("created_at desc, id desc").Find(&backups).Error; err != nil {
+		return 0, fmt.Errorf("查询备份失败: %w", err)
+	}
+
+	keep := selectBackupsToKeep(backups, retention)
+	deleted := 0
+	for i := range backups {
+		backup := &backups[i]
+		if keep[backup.ID] {
+			continue
+		}
+
+		if storage, err := bs.storageManager.GetStorageForBackup(backup, node); err == nil {
+			if err := storage.Delete(ctx, backup.Path); err != nil {
+				log.Printf("⚠️ 删除过期备份文件失败 [%s]: %v", backup.Path, err)
+			}
+			storage.Close()
+		}
+
+		if err := database.DB.Model(backup).Update("is_deleted", true).Error; err != nil {
+			return deleted, fmt.Errorf("更新备份记录失败: %w", err)
+		}
+		deleted++
+	}
+
+	if deleted > 0 {
+		log.Printf("🧹 节点 %s 按保留策略清理了 %d 个自动备份", node.Name, deleted)
+	}
+	return deleted, nil
+}
+
+// selectBackupsToKeep 按保留策略选出要保留的备份，backups 需按创建时间倒序
+func selectBackupsToKeep(backups []models.Backup, retention models.NodeBackupRetention) map[uint]bool {
+	keep := make(map[uint]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+
+	for i, backup := range backups {
+		if i < retention.KeepLast {
+			keep[backup.ID] = true
+		}
+
+		// 倒序遍历，每天/每周第一次出现的就是当天/当周最新的备份
+		created := backup.CreatedAt.Local()
+		day := created.Format("2006-01-02")
+		if !days[day] && len(days) < retention.KeepDaily {
+			days[day] = true
+			keep[backup.ID] = true
+		}
+
+		year, week := created.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[weekKey] && len(weeks) < retention.KeepWeekly {
+			weeks[weekKey] = true
+			keep[backup.ID] = true
+		}
+	}
+
+	return keep
+}
@@ -4,10 +4,15 @@ package services
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"io"
+	"net"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	appConfig "smartdns-manager/config"
@@ -190,16 +195,24 @@ func NewS3BackupStorage(cfg *appConfig.StorageConfig) (*S3BackupStorage, error)
 }
 
 func (s *S3BackupStorage) Save(ctx context.Context, content []byte, filename string) (string, error) {
+	return s.SaveStream(ctx, bytes.NewReader(content), int64(len(content)), filename)
+}
+
+// SaveStream 流式上传，需要预先知道内容长度
+func (s *S3BackupStorage) SaveStream(ctx context.Context, r io.Reader, size int64, filename string) (string, error) {
+	prefix := s.prefix
+	if prefix == "" {
+		prefix = "smartdns-backups"
+	}
 	// 生成 S3 Key，按日期组织
-	s3Key := fmt.Sprintf("smartdns-backups/%s/%s",
-		time.Now().Format("2006-01-02"), filename)
+	s3Key := backupObjectKey(prefix, filename)
 
 	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:        aws.String(s.bucket),
 		Key:           aws.String(s3Key),
-		Body:          bytes.NewReader(content),
+		Body:          r,
 		ContentType:   aws.String("application/octet-stream"),
-		ContentLength: aws.Int64(int64(len(content))),
+		ContentLength: aws.Int64(size),
 		Metadata: map[string]string{
 			"original-filename": filename,
 			"upload-time":       time.Now().Format(time.RFC3339),
@@ -212,6 +225,32 @@ func (s *S3BackupStorage) Save(ctx context.Context, content []byte, filename str
 	return s3Key, nil
 }
 
+func (s *S3BackupStorage) OpenRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, int64, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	}
+	if rangeHeader := httpRangeHeader(offset, length); rangeHeader != "" {
+		input.Range = aws.String(rangeHeader)
+	}
+
+	result, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		return nil, 0, fmt.Errorf("从S3下载失败 [bucket=%s, key=%s]: %w", s.bucket, path, err)
+	}
+
+	total := aws.ToInt64(result.ContentLength)
+	if contentRange := aws.ToString(result.ContentRange); contentRange != "" {
+		total = -1
+		if idx := strings.LastIndex(contentRange, "/"); idx >= 0 {
+			if size, err := strconv.ParseInt(contentRange[idx+1:], 10, 64); err == nil {
+				total = size
+			}
+		}
+	}
+	return result.Body, total, nil
+}
+
 func (s *S3BackupStorage) Load(ctx context.Context, path string) ([]byte, error) {
 	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
@@ -279,12 +318,12 @@ func NewBackupStorageManager() *BackupStorageManager {
 
 // GetStorage 获取存储实例
 func (m *BackupStorageManager) GetStorage(node *models.Node) (BackupStorage, string, error) {
-	if m.config.IsS3Enabled() {
-		storage, err := NewS3BackupStorage(m.config)
+	if m.config.Type != "" && m.config.Type != models.StorageTypeLocal {
+		storage, err := m.newRemoteStorage()
 		if err != nil {
-			return nil, "", fmt.Errorf("初始化S3存储失败: %w", err)
+			return nil, "", fmt.Errorf("初始化%s存储失败: %w", m.config.Type, err)
 		}
-		return storage, "s3", nil
+		return storage, m.config.Type, nil
 	}
 
 	// 默认使用本地存储
@@ -292,17 +331,29 @@ func (m *BackupStorageManager) GetStorage(node *models.Node) (BackupStorage, str
 	if err != nil {
 		return nil, "", fmt.Errorf("初始化本地存储失败: %w", err)
 	}
-	return storage, "local", nil
+	return storage, models.StorageTypeLocal, nil
 }
 
 // GetStorageForBackup 根据备份记录获取存储实例
 func (m *BackupStorageManager) GetStorageForBackup(backup *models.Backup, node *models.Node) (BackupStorage, error) {
-	if backup.StorageType == "s3" {
-		return NewS3BackupStorage(m.config)
+	if backup.StorageType != "" && backup.StorageType != models.StorageTypeLocal {
+		if backup.StorageType != m.config.Type {
+			return nil, fmt.Errorf("备份位于 %s 存储，当前配置的存储类型为 %s", backup.StorageType, m.config.Type)
+		}
+		return m.newRemoteStorage()
 	}
 	return NewLocalBackupStorageWithNode(node)
 }
 
+// newRemoteStorage 按环境变量配置创建远程存储实例
+func (m *BackupStorageManager) newRemoteStorage() (BackupStorage, error) {
+	backend, err := StorageBackendFromEnv(m.config)
+	if err != nil {
+		return nil, err
+	}
+	return NewBackupStorage(m.config.Type, backend)
+}
+
 // GetStorageType 获取当前存储类型
 func (m *BackupStorageManager) GetStorageType() string {
 	return m.config.Type
@@ -310,13 +361,27 @@ func (m *BackupStorageManager) GetStorageType() string {
 
 // GetConfig 获取存储配置（脱敏）
 func (m *BackupStorageManager) GetConfig() map[string]interface{} {
-	return map[string]interface{}{
-		"type":          m.config.Type,
-		"s3_region":     m.config.S3Region,
-		"s3_bucket":     m.config.S3Bucket,
-		"s3_endpoint":   m.config.S3Endpoint,
-		"is_s3_enabled": m.config.IsS3Enabled(),
+	info := map[string]interface{}{
+		"type":            m.config.Type,
+		"s3_region":       m.config.S3Region,
+		"s3_bucket":       m.config.S3Bucket,
+		"s3_endpoint":     m.config.S3Endpoint,
+		"is_s3_enabled":   m.config.IsS3Enabled(),
+		"supported_types": append([]string{models.StorageTypeLocal}, BackupStorageTypes()...),
+	}
+	switch m.config.Type {
+	case models.StorageTypeSFTP:
+		info["sftp_host"] = m.config.SFTPHost
+		info["sftp_path"] = m.config.SFTPPath
+	case models.StorageTypeWebDAV:
+		info["webdav_url"] = m.config.WebDAVURL
+	case models.StorageTypeAzure:
+		info["azure_account"] = m.config.AzureAccount
+		info["azure_container"] = m.config.AzureContainer
+	case models.StorageTypeGCS:
+		info["gcs_bucket"] = m.config.GCSBucket
 	}
+	return info
 }
 
 // ValidateConfig 验证配置
@@ -341,6 +406,8 @@ func NewBackupService() *BackupService {
 }
 
 // PerformNodeBackup 执行节点备份操作（通用方法）
+//
+// 配置与该节点最近一次备份相同且未指定 force 时不保存新备份，返回最近一次备份和 ErrBackupUnchanged。
 func (bs *BackupService) PerformNodeBackup(
 	ctx context.Context,
 	node *models.Node,
@@ -349,6 +416,7 @@ func (bs *BackupService) PerformNodeBackup(
 	comment string,
 	tags string,
 	isAuto bool,
+	force bool,
 ) (*models.Backup, error) {
 	// 生成备份文件名
 	timestamp := time.Now().Format("20060102_150405")
@@ -367,6 +435,13 @@ func (bs *BackupService) PerformNodeBackup(
 		return nil, fmt.Errorf("读取配置文件失败: %w", err)
 	}
 
+	contentHash := fmt.Sprintf("%x", sha256.Sum256([]byte(configContent)))
+	if !force {
+		if latest := bs.confirmUnchanged(node.ID, contentHash); latest != nil {
+			return latest, ErrBackupUnchanged
+		}
+	}
+
 	// 保存到存储
 	storagePath, err := storage.Save(ctx, []byte(configContent), filename)
 	if err != nil {
@@ -384,12 +459,15 @@ func (bs *BackupService) PerformNodeBackup(
 		Tags:        tags,
 		StorageType: storageType,
 		IsDeleted:   false,
+		ContentHash: contentHash,
 	}
 
-	// S3 存储的额外信息
-	if storageType == "s3" {
-		backup.S3Key = storagePath
-		// 生成下载 URL（24小时有效）
+	// 远程存储的额外信息
+	if storageType != models.StorageTypeLocal {
+		if storageType == models.StorageTypeS3 {
+			backup.S3Key = storagePath
+		}
+		// 生成下载 URL（24小时有效），不支持下载链接的存储通过 API 下载
 		downloadURL, err := storage.GetDownloadURL(ctx, storagePath, 24*time.Hour)
 		if err == nil {
 			backup.DownloadURL = downloadURL
@@ -398,3 +476,113 @@ func (bs *BackupService) PerformNodeBackup(
 
 	return backup, nil
 }
+
+// AdjustConfigForNode 将备份配置中与源节点相关的值（监听地址、日志路径、配置目录）替换为目标节点的值
+func (bs *BackupService) AdjustConfigForNode(content string, source, target *models.Node) (string, []models.ConfigAdjustment) {
+	adjustments := make([]models.ConfigAdjustment, 0)
+	lines := strings.Split(content, "\n")
+
+	sourceLogDir := path.Dir(source.LogPath)
+	targetLogDir := path.Dir(target.LogPath)
+	sourceConfDir := path.Dir(source.ConfigPath)
+	targetConfDir := path.Dir(target.ConfigPath)
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		name, value := splitDirective(trimmed)
+		var adjusted, note string
+
+		switch name {
+		case "bind", "bind-tcp", "bind-tls", "bind-https":
+			adjusted, note = adjustBindAddress(value, source.Host, target.Host)
+		case "audit-file", "log-file":
+			if name == "audit-file" && value == source.LogPath {
+				adjusted = target.LogPath
+			} else {
+				adjusted = replacePathPrefix(value, sourceLogDir, targetLogDir)
+			}
+		case "conf-file", "bind-cert-file", "bind-cert-key-file", "cache-file":
+			adjusted = replacePathPrefix(value, sourceConfDir, targetConfDir)
+		case "domain-set":
+			if idx := strings.Index(value, "-file "); idx >= 0 {
+				fileArg, rest := splitDirective(strings.TrimSpace(value[idx+len("-file "):]))
+				if newPath := replacePathPrefix(fileArg, sourceConfDir, targetConfDir); newPath != fileArg {
+					adjusted = strings.TrimSpace(value[:idx] + "-file " + newPath + " " + rest)
+				}
+			}
+		}
+
+		if note != "" {
+			adjustments = append(adjustments, models.ConfigAdjustment{
+				LineNumber: i + 1,
+				Directive:  name,
+				Before:     trimmed,
+				After:      trimmed,
+				Note:       note,
+			})
+			continue
+		}
+		if adjusted == "" || adjusted == value {
+			continue
+		}
+
+		newLine := name + " " + adjusted
+		adjustments = append(adjustments, models.ConfigAdjustment{
+			LineNumber: i + 1,
+			Directive:  name,
+			Before:     trimmed,
+			After:      newLine,
+		})
+		lines[i] = newLine
+	}
+
+	return strings.Join(lines, "\n"), adjustments
+}
+
+// adjustBindAddress 将监听地址中的源节点 IP 替换为目标节点 IP
+func adjustBindAddress(value, sourceHost, targetHost string) (string, string) {
+	addr, options := splitDirective(value)
+
+	// 监听地址格式: IP:端口@网卡，如 192.168.1.10:53、[::1]:53@eth0、:53
+	host := addr
+	if idx := strings.Index(host, "@"); idx >= 0 {
+		host = host[:idx]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" || host != sourceHost {
+		return "", ""
+	}
+
+	if net.ParseIP(targetHost) == nil {
+		return "", fmt.Sprintf("监听地址绑定了源节点 IP %s，但目标节点地址 %s 不是 IP，请手动调整", sourceHost, targetHost)
+	}
+
+	replacement := targetHost
+	if strings.Contains(targetHost, ":") {
+		replacement = "[" + targetHost + "]"
+	}
+	search := sourceHost
+	if strings.Contains(sourceHost, ":") {
+		search = "[" + sourceHost + "]"
+	}
+
+	adjusted := strings.Replace(addr, search, replacement, 1)
+	return strings.TrimSpace(adjusted + " " + options), ""
+}
+
+// replacePathPrefix 将路径中的源目录前缀替换为目标目录
+func replacePathPrefix(value, sourceDir, targetDir string) string {
+	if sourceDir == targetDir || sourceDir == "." || sourceDir == "/" {
+		return value
+	}
+	if value == sourceDir || strings.HasPrefix(value, sourceDir+"/") {
+		return targetDir + strings.TrimPrefix(value, sourceDir)
+	}
+	return value
+}
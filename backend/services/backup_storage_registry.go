@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appConfig "smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// BackupStorageFactory 根据后端配置创建存储实例
+type BackupStorageFactory func(cfg *models.StorageBackendConfig) (BackupStorage, error)
+
+var backupStorageFactories = map[string]BackupStorageFactory{}
+
+// RegisterBackupStorage 注册备份存储类型，在各存储实现的 init 中调用
+func RegisterBackupStorage(storageType string, factory BackupStorageFactory) {
+	backupStorageFactories[storageType] = factory
+}
+
+// NewBackupStorage 按类型创建存储实例；本地存储依赖节点连接，由 BackupStorageManager 单独处理
+func NewBackupStorage(storageType string, cfg *models.StorageBackendConfig) (BackupStorage, error) {
+	factory, ok := backupStorageFactories[storageType]
+	if !ok {
+		return nil, fmt.Errorf("不支持的存储类型: %s", storageType)
+	}
+	if cfg == nil {
+		cfg = &models.StorageBackendConfig{}
+	}
+	return factory(cfg)
+}
+
+// BackupStorageTypes 已注册的远程存储类型
+func BackupStorageTypes() []string {
+	types := make([]string, 0, len(backupStorageFactories))
+	for storageType := range backupStorageFactories {
+		types = append(types, storageType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// TestBackupStorage 写入、读取并删除一个测试文件，验证存储配置可用
+func TestBackupStorage(ctx context.Context, storage BackupStorage) error {
+	content := []byte("smartdns-manager storage test " + time.Now().Format(time.RFC3339))
+	storagePath, err := storage.Save(ctx, content, fmt.Sprintf(".storage_test_%d", time.Now().UnixNano()))
+	if err != nil {
+		return fmt.Errorf("写入失败: %w", err)
+	}
+
+	loaded, err := storage.Load(ctx, storagePath)
+	if err != nil {
+		storage.Delete(ctx, storagePath)
+		return fmt.Errorf("读取失败: %w", err)
+	}
+	if !bytes.Equal(loaded, content) {
+		storage.Delete(ctx, storagePath)
+		return fmt.Errorf("读取的内容与写入的不一致")
+	}
+
+	if err := storage.Delete(ctx, storagePath); err != nil {
+		return fmt.Errorf("删除失败: %w", err)
+	}
+	return nil
+}
+
+// backupObjectKey 按日期组织备份文件路径：prefix/2006-01-02/filename
+func backupObjectKey(prefix, filename string) string {
+	key := path.Join(time.Now().Format("2006-01-02"), filename)
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		key = prefix + "/" + key
+	}
+	return key
+}
+
+// StorageBackendFromEnv 把环境变量中的全局存储配置转换为后端配置
+func StorageBackendFromEnv(cfg *appConfig.StorageConfig) (*models.StorageBackendConfig, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	backend := &models.StorageBackendConfig{}
+	switch cfg.Type {
+	case models.StorageTypeS3:
+		backend.S3 = &models.S3Config{
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			Endpoint:  cfg.S3Endpoint,
+		}
+	case models.StorageTypeSFTP:
+		port, _ := strconv.Atoi(cfg.SFTPPort)
+		backend.SFTP = &models.SFTPStorageConfig{
+			Host:     cfg.SFTPHost,
+			Port:     port,
+			Username: cfg.SFTPUser,
+			Password: cfg.SFTPPassword,
+			HostKey:  cfg.SFTPHostKey,
+			Path:     cfg.SFTPPath,
+		}
+		if cfg.SFTPPrivateKeyFile != "" {
+			key, err := os.ReadFile(cfg.SFTPPrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("读取 SFTP 私钥失败: %w", err)
+			}
+			backend.SFTP.PrivateKey = string(key)
+		}
+	case models.StorageTypeWebDAV:
+		backend.WebDAV = &models.WebDAVStorageConfig{
+			URL:      cfg.WebDAVURL,
+			Username: cfg.WebDAVUser,
+			Password: cfg.WebDAVPassword,
+		}
+	case models.StorageTypeAzure:
+		backend.Azure = &models.AzureStorageConfig{
+			Account:    cfg.AzureAccount,
+			AccountKey: cfg.AzureAccountKey,
+			SASToken:   cfg.AzureSASToken,
+			Container:  cfg.AzureContainer,
+			Endpoint:   cfg.AzureEndpoint,
+		}
+	case models.StorageTypeGCS:
+		credentials, err := os.ReadFile(cfg.GCSCredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 GCS 服务账号密钥失败: %w", err)
+		}
+		backend.GCS = &models.GCSStorageConfig{
+			Bucket:          cfg.GCSBucket,
+			CredentialsJSON: string(credentials),
+			Endpoint:        cfg.GCSEndpoint,
+		}
+	}
+	return backend, nil
+}
+
+func init() {
+	RegisterBackupStorage(models.StorageTypeS3, func(cfg *models.StorageBackendConfig) (BackupStorage, error) {
+		if cfg.S3 == nil {
+			return nil, fmt.Errorf("缺少 S3 存储配置")
+		}
+		storage, err := NewS3BackupStorage(&appConfig.StorageConfig{
+			Type:        models.StorageTypeS3,
+			S3AccessKey: cfg.S3.AccessKey,
+			S3SecretKey: cfg.S3.SecretKey,
+			S3Region:    cfg.S3.Region,
+			S3Bucket:    cfg.S3.Bucket,
+			S3Endpoint:  cfg.S3.Endpoint,
+		})
+		if err != nil {
+			return nil, err
+		}
+		storage.prefix = cfg.S3.Prefix
+		return storage, nil
+	})
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// BackupStreamStorage 支持流式读写的存储，大文件不需要整体加载到内存
+type BackupStreamStorage interface {
+	BackupStorage
+	// SaveStream 上传 size 字节的内容
+	SaveStream(ctx context.Context, r io.Reader, size int64, filename string) (string, error)
+	// OpenRange 从 offset 开始读取 length 字节（-1 表示读到末尾），同时返回对象总大小（未知时为 -1）
+	OpenRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, int64, error)
+}
+
+// SaveBackupStream 流式保存备份，存储不支持流式写入时读入内存后保存
+func SaveBackupStream(ctx context.Context, storage BackupStorage, r io.Reader, size int64, filename string) (string, error) {
+	if streamer, ok := storage.(BackupStreamStorage); ok && size >= 0 {
+		return streamer.SaveStream(ctx, r, size, filename)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return storage.Save(ctx, content, filename)
+}
+
+// OpenBackupRange 流式读取备份的指定范围，存储不支持流式读取时整体加载后截取
+func OpenBackupRange(ctx context.Context, storage BackupStorage, path string, offset, length int64) (io.ReadCloser, int64, error) {
+	if streamer, ok := storage.(BackupStreamStorage); ok {
+		return streamer.OpenRange(ctx, path, offset, length)
+	}
+
+	content, err := storage.Load(ctx, path)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := int64(len(content))
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if length >= 0 && offset+length < total {
+		end = offset + length
+	}
+	return io.NopCloser(bytes.NewReader(content[offset:end])), total, nil
+}
+
+// ParseByteRange 解析单段 Range 请求头（bytes=start- 或 bytes=start-end），
+// 不支持的格式（多段、后缀范围）返回 false，调用方按完整内容响应
+func ParseByteRange(header string) (offset, length int64, ok bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, -1, false
+	}
+	startText, endText, found := strings.Cut(spec, "-")
+	if !found || startText == "" {
+		return 0, -1, false
+	}
+	start, err := strconv.ParseInt(startText, 10, 64)
+	if err != nil || start < 0 {
+		return 0, -1, false
+	}
+	if endText == "" {
+		return start, -1, true
+	}
+	end, err := strconv.ParseInt(endText, 10, 64)
+	if err != nil || end < start {
+		return 0, -1, false
+	}
+	return start, end - start + 1, true
+}
+
+// 下载时支持的压缩格式
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// NewCompressionWriter 创建边写边压缩的 writer，返回文件扩展名和 Content-Type
+func NewCompressionWriter(w io.Writer, format string) (io.WriteCloser, string, string, error) {
+	switch format {
+	case CompressionGzip:
+		return gzip.NewWriter(w), ".gz", "application/gzip", nil
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return encoder, ".zst", "application/zstd", nil
+	}
+	return nil, "", "", fmt.Errorf("不支持的压缩格式: %s", format)
+}
+
+// httpRangeHeader 生成 HTTP Range 请求头，读取完整内容时返回空
+func httpRangeHeader(offset, length int64) string {
+	if offset <= 0 && length < 0 {
+		return ""
+	}
+	if length < 0 {
+		return fmt.Sprintf("bytes=%d-", offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+}
+
+// httpObjectSize 从响应中获取对象总大小：206 取 Content-Range 的总长度，200 取 Content-Length
+func httpObjectSize(resp *http.Response) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		contentRange := resp.Header.Get("Content-Range")
+		if idx := strings.LastIndex(contentRange, "/"); idx >= 0 {
+			if total, err := strconv.ParseInt(contentRange[idx+1:], 10, 64); err == nil {
+				return total
+			}
+		}
+		return -1
+	}
+	return resp.ContentLength
+}
+
+// httpRangeBody 服务器忽略 Range 返回完整内容时，跳过 offset 字节并截取 length 字节
+func httpRangeBody(resp *http.Response, offset, length int64) (io.ReadCloser, error) {
+	if resp.StatusCode == http.StatusPartialContent || (offset <= 0 && length < 0) {
+		return resp.Body, nil
+	}
+	if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil && err != io.EOF {
+		resp.Body.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return resp.Body, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, length), resp.Body}, nil
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/models"
+)
+
+// blocklistMaxSize 单个订阅列表的最大下载大小
+const blocklistMaxSize = 64 << 20
+
+// blocklistRefreshMu 手动刷新和定时任务共用，避免同时改写同一个域名集
+var blocklistRefreshMu sync.Mutex
+
+// BlocklistService 远程屏蔽列表订阅服务
+// 下载列表后规范化、去重，整体替换目标域名集的条目，再推送到节点并重启 SmartDNS
+type BlocklistService struct {
+	db                    *gorm.DB
+	httpClient            *http.Client
+	parser                *ListImportService
+	domainSetService      *DomainSetService
+	domainCategoryService *DomainCategoryService
+	notificationService   *NotificationService
+}
+
+// NewBlocklistService 创建屏蔽列表订阅服务
+func NewBlocklistService(db *gorm.DB) *BlocklistService {
+	return &BlocklistService{
+		db:                    db,
+		httpClient:            &http.Client{Timeout: 60 * time.Second},
+		parser:                NewListImportService(),
+		domainSetService:      NewDomainSetService(),
+		domainCategoryService: NewDomainCategoryService(),
+		notificationService:   NewNotificationService(),
+	}
+}
+
+// RefreshDue 刷新到期的订阅，供定时任务调用
+func (s *BlocklistService) RefreshDue(ctx context.Context, cfg models.BlocklistConfig) (string, error) {
+	var subs []models.BlocklistSubscription
+	query := s.db.Where("enabled = ?", true)
+	if len(cfg.SubscriptionIDs) > 0 {
+		query = query.Where("id IN ?", cfg.SubscriptionIDs)
+	}
+	if err := query.Find(&subs).Error; err != nil {
+		return "", fmt.Errorf("查询屏蔽列表订阅失败: %w", err)
+	}
+
+	now := time.Now()
+	var lines []string
+	refreshed, failed := 0, 0
+	for i := range subs {
+		select {
+		case <-ctx.Done():
+			return strings.Join(lines, "\n"), ctx.Err()
+		default:
+		}
+
+		sub := &subs[i]
+		if !cfg.Force && !blocklistDue(sub, now) {
+			continue
+		}
+
+		result := s.Refresh(sub, cfg.Force)
+		refreshed++
+		if result.Status == models.BlocklistStatusFailed {
+			failed++
+			lines = append(lines, fmt.Sprintf("❌ %s: %s", result.Name, result.Error))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("✅ %s: %s，共 %d 个域名（+%d/-%d）",
+			result.Name, result.Status, result.DomainCount, result.Added, result.Removed))
+	}
+
+	lines = append(lines, fmt.Sprintf("共 %d 个订阅，本次刷新 %d 个，失败 %d 个", len(subs), refreshed, failed))
+	output := strings.Join(lines, "\n")
+	if failed > 0 {
+		return output, fmt.Errorf("%d 个屏蔽列表订阅刷新失败", failed)
+	}
+	return output, nil
+}
+
+// blocklistDue 判断订阅是否到达刷新时间
+func blocklistDue(sub *models.BlocklistSubscription, now time.Time) bool {
+	if sub.LastFetchedAt == nil {
+		return true
+	}
+	interval := sub.RefreshInterval
+	if interval <= 0 {
+		interval = 1440
+	}
+	return now.Sub(*sub.LastFetchedAt) >= time.Duration(interval)*time.Minute
+}
+
+// Refresh 下载并应用单个订阅，force 为 true 时忽略缓存校验
+func (s *BlocklistService) Refresh(sub *models.BlocklistSubscription, force bool) models.BlocklistRefreshResult {
+	blocklistRefreshMu.Lock()
+	defer blocklistRefreshMu.Unlock()
+
+	result := models.BlocklistRefreshResult{SubscriptionID: sub.ID, Name: sub.Name}
+	now := time.Now()
+	updates := map[string]interface{}{"last_fetched_at": &now}
+
+	err := s.apply(sub, force, &result, updates)
+	if err != nil {
+		result.Status = models.BlocklistStatusFailed
+		result.Error = err.Error()
+		log.Printf("❌ 屏蔽列表订阅 %s 刷新失败: %v", sub.Name, err)
+
+		// 同一错误只通知一次，避免每个周期重复告警
+		if sub.LastStatus != models.BlocklistStatusFailed || sub.LastError != result.Error {
+			s.notificationService.SendNotification(0, "blocklist_refresh_failed", "屏蔽列表刷新失败",
+				fmt.Sprintf("订阅 %s（%s）刷新失败: %s", sub.Name, sub.URL, result.Error))
+		}
+		updates["last_error"] = result.Error
+	} else {
+		updates["last_error"] = ""
+		updates["last_success_at"] = &now
+		log.Printf("✅ 屏蔽列表订阅 %s 刷新完成: %s，%d 个域名", sub.Name, result.Status, result.DomainCount)
+	}
+	updates["last_status"] = result.Status
+
+	s.db.Model(&models.BlocklistSubscription{}).Where("id = ?", sub.ID).Updates(updates)
+	return result
+}
+
+// apply 下载、解析并替换域名集条目，成功后才记录缓存标识和校验和，推送失败时下次会重新推送
+func (s *BlocklistService) apply(sub *models.BlocklistSubscription, force bool, result *models.BlocklistRefreshResult, updates map[string]interface{}) error {
+	var domainSet models.DomainSet
+	if err := s.db.First(&domainSet, sub.DomainSetID).Error; err != nil {
+		return fmt.Errorf("目标域名集不存在")
+	}
+
+	body, etag, lastModified, notModified, err := s.fetch(sub, force)
+	if err != nil {
+		return err
+	}
+	if notModified {
+		result.Status = models.BlocklistStatusUnchanged
+		result.DomainCount = sub.DomainCount
+		return nil
+	}
+
+	domains, err := s.normalize(body, sub.Format)
+	if err != nil {
+		return err
+	}
+	result.DomainCount = len(domains)
+
+	checksum := domainsChecksum(domains)
+	if !force && checksum == sub.Checksum {
+		result.Status = models.BlocklistStatusUnchanged
+		updates["etag"] = etag
+		updates["last_modified"] = lastModified
+		return nil
+	}
+
+	added, removed, err := s.replaceItems(&domainSet, domains)
+	if err != nil {
+		return err
+	}
+	result.Added, result.Removed = added, removed
+
+	if failed := s.domainSetService.PushDomainSetAndReload(&domainSet); len(failed) > 0 {
+		var reasons []string
+		for name, err := range failed {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", name, err))
+		}
+		sort.Strings(reasons)
+		return fmt.Errorf("域名集已更新，推送到节点失败: %s", strings.Join(reasons, "; "))
+	}
+
+	if domainSet.Category != "" {
+		s.domainCategoryService.SyncToClickHouseAsync()
+	}
+
+	result.Status = models.BlocklistStatusSuccess
+	updates["etag"] = etag
+	updates["last_modified"] = lastModified
+	updates["checksum"] = checksum
+	updates["domain_count"] = len(domains)
+	return nil
+}
+
+// fetch 下载列表，带上次的 ETag/Last-Modified 做条件请求
+func (s *BlocklistService) fetch(sub *models.BlocklistSubscription, force bool) (body, etag, lastModified string, notModified bool, err error) {
+	req, err := http.NewRequest(http.MethodGet, sub.URL, nil)
+	if err != nil {
+		return "", "", "", false, fmt.Errorf("无效的订阅地址: %w", err)
+	}
+	req.Header.Set("User-Agent", "smartdns-manager")
+	if !force && sub.Checksum != "" {
+		if sub.ETag != "" {
+			req.Header.Set("If-None-Match", sub.ETag)
+		}
+		if sub.LastModified != "" {
+			req.Header.Set("If-Modified-Since", sub.LastModified)
+		}
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", "", "", false, fmt.Errorf("下载列表失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return "", "", "", true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", "", false, fmt.Errorf("下载列表失败: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, blocklistMaxSize+1))
+	if err != nil {
+		return "", "", "", false, fmt.Errorf("读取列表失败: %w", err)
+	}
+	if len(data) > blocklistMaxSize {
+		return "", "", "", false, fmt.Errorf("列表超过 %d MB 限制", blocklistMaxSize>>20)
+	}
+
+	return string(data), resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"), false, nil
+}
+
+// normalize 解析列表并返回排序去重后的屏蔽域名
+// hosts 中指向回环地址的条目也视为屏蔽，白名单条目（@@）从结果中剔除
+func (s *BlocklistService) normalize(content, format string) ([]string, error) {
+	entries, _ := s.parser.Parse(content, format)
+
+	blocked := make(map[string]bool, len(entries))
+	allowed := make(map[string]bool)
+	for _, entry := range entries {
+		domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(entry.Domain)), ".")
+		if domain == "" {
+			continue
+		}
+		switch {
+		case entry.IP == "-":
+			allowed[domain] = true
+		case entry.IP == "#" || isLoopbackValue(entry.IP):
+			blocked[domain] = true
+		}
+	}
+
+	domains := make([]string, 0, len(blocked))
+	for domain := range blocked {
+		if !allowed[domain] {
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("列表中没有可用的屏蔽域名")
+	}
+	sort.Strings(domains)
+	return domains, nil
+}
+
+// isLoopbackValue 判断地址映射的值是否为回环地址
+func isLoopbackValue(value string) bool {
+	ip := net.ParseIP(value)
+	return ip != nil && ip.IsLoopback()
+}
+
+// replaceItems 用订阅内容整体替换域名集条目，返回新增和删除的域名数
+func (s *BlocklistService) replaceItems(domainSet *models.DomainSet, domains []string) (int, int, error) {
+	var existing []string
+	s.db.Model(&models.DomainSetItem{}).Where("domain_set_id = ?", domainSet.ID).Pluck("domain", &existing)
+
+	current := make(map[string]bool, len(existing))
+	for _, domain := range existing {
+		current[domain] = true
+	}
+	added := 0
+	next := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		next[domain] = true
+		if !current[domain] {
+			added++
+		}
+	}
+	removed := 0
+	for domain := range current {
+		if !next[domain] {
+			removed++
+		}
+	}
+
+	items := make([]models.DomainSetItem, 0, len(domains))
+	for _, domain := range domains {
+		items = append(items, models.DomainSetItem{DomainSetID: domainSet.ID, Domain: domain})
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("domain_set_id = ?", domainSet.ID).Delete(&models.DomainSetItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.CreateInBatches(&items, 500).Error; err != nil {
+			return err
+		}
+		domainSet.DomainCount = len(domains)
+		domainSet.UpdatedAt = time.Now()
+		return tx.Model(&models.DomainSet{}).Where("id = ?", domainSet.ID).Updates(map[string]interface{}{
+			"domain_count": domainSet.DomainCount,
+			"updated_at":   domainSet.UpdatedAt,
+		}).Error
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("更新域名集失败: %w", err)
+	}
+	return added, removed, nil
+}
+
+// domainsChecksum 规范化域名列表的校验和
+func domainsChecksum(domains []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(domains, "\n")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"smartdns-manager/config"
+)
+
+// builtinCDNRanges 内置的常见 CDN 网段（可通过 CDN_RANGES_FILE 扩展）
+var builtinCDNRanges = map[string][]string{
+	"Cloudflare": {
+		"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22",
+		"141.101.64.0/18", "108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20",
+		"197.234.240.0/22", "198.41.128.0/17", "162.158.0.0/15", "104.16.0.0/13",
+		"104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22",
+		"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32", "2405:b500::/32",
+		"2405:8100::/32", "2a06:98c0::/29", "2c0f:f248::/32",
+	},
+	"Fastly": {
+		"23.235.32.0/20", "43.249.72.0/22", "103.244.50.0/24", "103.245.222.0/23",
+		"103.245.224.0/24", "104.156.80.0/20", "140.248.64.0/18", "140.248.128.0/17",
+		"146.75.0.0/17", "151.101.0.0/16", "157.52.64.0/18", "167.82.0.0/17",
+		"167.82.128.0/20", "167.82.160.0/20", "167.82.224.0/20", "172.111.64.0/18",
+		"185.31.16.0/22", "199.27.72.0/21", "199.232.0.0/16",
+		"2a04:4e40::/32", "2a04:4e42::/32",
+	},
+}
+
+type cdnRange struct {
+	provider string
+	network  *net.IPNet
+}
+
+// CDNClassifier 根据 IP 网段识别 CDN/云服务商
+type CDNClassifier struct {
+	ranges []cdnRange
+}
+
+var (
+	defaultCDNClassifier *CDNClassifier
+	cdnClassifierOnce    sync.Once
+)
+
+// GetCDNClassifier 获取全局 CDN 识别器
+func GetCDNClassifier() *CDNClassifier {
+	cdnClassifierOnce.Do(func() {
+		defaultCDNClassifier = NewCDNClassifier(config.GetConfig().CDNRangesFile)
+	})
+	return defaultCDNClassifier
+}
+
+// NewCDNClassifier 创建 CDN 识别器，rangesFile 每行格式为 "<provider> <cidr>"，# 开头为注释
+func NewCDNClassifier(rangesFile string) *CDNClassifier {
+	classifier := &CDNClassifier{}
+
+	for provider, cidrs := range builtinCDNRanges {
+		for _, cidr := range cidrs {
+			classifier.add(provider, cidr)
+		}
+	}
+
+	if rangesFile != "" {
+		if err := classifier.loadFile(rangesFile); err != nil {
+			log.Printf("⚠️ 加载 CDN 网段文件失败: %v", err)
+		}
+	}
+
+	return classifier
+}
+
+func (c *CDNClassifier) add(provider, cidr string) {
+	_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+	if err != nil {
+		return
+	}
+	c.ranges = append(c.ranges, cdnRange{provider: provider, network: network})
+}
+
+func (c *CDNClassifier) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		c.add(fields[0], fields[1])
+	}
+	return scanner.Err()
+}
+
+// Classify 返回 IP 所属的服务商，未识别返回空字符串
+func (c *CDNClassifier) Classify(ipStr string) string {
+	// 子网形式（如 1.2.3.0/24）取网络地址判断
+	if idx := strings.IndexByte(ipStr, '/'); idx > 0 {
+		ipStr = ipStr[:idx]
+	}
+
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return ""
+	}
+
+	for _, r := range c.ranges {
+		if r.network.Contains(ip) {
+			return r.provider
+		}
+	}
+	return ""
+}
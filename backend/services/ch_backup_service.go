@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// CHBackupService ClickHouse 数据备份服务，备份记录保存在备份历史中（backup_type = clickhouse）
+type CHBackupService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+// NewCHBackupService 创建 ClickHouse 数据备份服务
+func NewCHBackupService(db *gorm.DB, config *config.Config) (*CHBackupService, error) {
+	return &CHBackupService{
+		db:     db,
+		config: config,
+	}, nil
+}
+
+// Backup 执行一次备份并记录到备份历史，随后按保留天数清理该任务的旧备份
+func (s *CHBackupService) Backup(ctx context.Context, taskID uint, cfg models.CHBackupConfig) (string, error) {
+	if database.CHConn == nil {
+		return "", fmt.Errorf("ClickHouse 未连接")
+	}
+
+	tables, err := s.resolveTables(ctx, cfg.Tables)
+	if err != nil {
+		return "", err
+	}
+
+	method := cfg.Method
+	if method == "" {
+		method = models.CHBackupMethodS3
+	}
+	name := "smartdns_ch_" + time.Now().Format("20060102_150405")
+
+	history := &models.BackupHistory{
+		TaskID:      taskID,
+		BackupType:  "clickhouse",
+		Status:      "running",
+		FileName:    name,
+		StorageType: method,
+		Tables:      strings.Join(tables, ","),
+		StartedAt:   time.Now(),
+	}
+
+	// 备份前的表大小，作为备份大小的参考
+	var size uint64
+	if err := database.CHConn.QueryRow(ctx,
+		"SELECT sum(bytes_on_disk) FROM system.parts WHERE database = currentDatabase() AND active AND table IN ?", tables).Scan(&size); err == nil {
+		history.DatabaseSize = int64(size)
+		history.FileSize = int64(size)
+	}
+
+	switch method {
+	case models.CHBackupMethodS3:
+		if cfg.S3Config.Bucket == "" || cfg.S3Config.AccessKey == "" || cfg.S3Config.SecretKey == "" {
+			return "", fmt.Errorf("S3 配置不完整")
+		}
+		key := chBackupS3Key(cfg.S3Config, name)
+		history.S3Key = key
+		history.S3Bucket = cfg.S3Config.Bucket
+		history.S3Region = cfg.S3Config.Region
+		history.StoragePath = chBackupS3URL(cfg.S3Config, key)
+		err = s.exec(ctx, fmt.Sprintf("BACKUP %s TO %s", chBackupTableList(tables), chBackupS3Target(cfg.S3Config, key)))
+	case models.CHBackupMethodDisk:
+		disk := cfg.Disk
+		if disk == "" {
+			disk = "backups"
+		}
+		history.StoragePath = disk + ":" + name + ".zip"
+		err = s.exec(ctx, fmt.Sprintf("BACKUP %s TO Disk(%s, %s)",
+			chBackupTableList(tables), chQuote(disk), chQuote(name+".zip")))
+	case models.CHBackupMethodFreeze:
+		// 每张表冻结到 shadow/<name>，由 clickhouse-backup 或运维脚本复制到异地
+		history.StoragePath = "shadow/" + name
+		for _, table := range tables {
+			if err = s.exec(ctx, fmt.Sprintf("ALTER TABLE `%s` FREEZE WITH NAME %s", table, chQuote(name))); err != nil {
+				break
+			}
+		}
+	default:
+		return "", fmt.Errorf("不支持的备份方式: %s", method)
+	}
+
+	completedAt := time.Now()
+	history.CompletedAt = &completedAt
+	history.Duration = int64(completedAt.Sub(history.StartedAt).Seconds())
+	if err != nil {
+		history.Status = "failed"
+		history.ErrorMessage = err.Error()
+	} else {
+		history.Status = "success"
+	}
+	if dbErr := s.db.Create(history).Error; dbErr != nil {
+		log.Printf("⚠️ 保存 ClickHouse 备份记录失败: %v", dbErr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("ClickHouse 备份失败: %w", err)
+	}
+
+	output := fmt.Sprintf("ClickHouse 备份完成: %s (%s, 表: %s, 大小: %d bytes)",
+		name, history.StoragePath, history.Tables, history.FileSize)
+	if cfg.RetentionDays > 0 {
+		if removed := s.cleanupExpired(ctx, taskID, cfg); removed > 0 {
+			output += fmt.Sprintf("\n清理过期备份 %d 个", removed)
+		}
+	}
+	return output, nil
+}
+
+// Restore 从备份历史恢复表。指定后缀时恢复为新表，否则先把原表重命名为 <表名>_before_restore_<时间> 再恢复
+func (s *CHBackupService) Restore(ctx context.Context, request *models.CHBackupRestoreRequest) ([]string, error) {
+	if database.CHConn == nil {
+		return nil, fmt.Errorf("ClickHouse 未连接")
+	}
+
+	var history models.BackupHistory
+	if err := s.db.First(&history, request.BackupHistoryID).Error; err != nil {
+		return nil, fmt.Errorf("备份记录不存在: %w", err)
+	}
+	if history.BackupType != "clickhouse" {
+		return nil, fmt.Errorf("该备份不是 ClickHouse 备份")
+	}
+	if history.Status != "success" {
+		return nil, fmt.Errorf("不能恢复失败的备份")
+	}
+
+	var source string
+	switch history.StorageType {
+	case models.CHBackupMethodS3:
+		var task models.ScheduledTask
+		if err := s.db.First(&task, history.TaskID).Error; err != nil {
+			return nil, fmt.Errorf("产生该备份的任务不存在，无法获取 S3 凭据: %w", err)
+		}
+		var cfg models.CHBackupConfig
+		if err := json.Unmarshal([]byte(task.Config), &cfg); err != nil {
+			return nil, fmt.Errorf("解析任务配置失败: %w", err)
+		}
+		cfg.S3Config.Bucket = history.S3Bucket
+		source = chBackupS3Target(cfg.S3Config, history.S3Key)
+	case models.CHBackupMethodDisk:
+		disk, file, _ := strings.Cut(history.StoragePath, ":")
+		source = fmt.Sprintf("Disk(%s, %s)", chQuote(disk), chQuote(file))
+	case models.CHBackupMethodFreeze:
+		return nil, fmt.Errorf("FREEZE 备份需要在 ClickHouse 服务器上用 clickhouse-backup 或 ATTACH PART 恢复，备份位于 %s", history.StoragePath)
+	default:
+		return nil, fmt.Errorf("不支持的备份方式: %s", history.StorageType)
+	}
+
+	var steps []string
+	suffix := strings.TrimSpace(request.TableSuffix)
+	for _, table := range strings.Split(history.Tables, ",") {
+		if table == "" {
+			continue
+		}
+		target := table + suffix
+		if suffix == "" {
+			renamed := fmt.Sprintf("%s_before_restore_%s", table, time.Now().Format("20060102150405"))
+			if err := s.exec(ctx, fmt.Sprintf("RENAME TABLE `%s` TO `%s`", table, renamed)); err != nil {
+				return steps, fmt.Errorf("重命名原表 %s 失败: %w", table, err)
+			}
+			steps = append(steps, fmt.Sprintf("原表 %s 已重命名为 %s", table, renamed))
+		}
+
+		statement := fmt.Sprintf("RESTORE TABLE `%s` AS `%s` FROM %s", table, target, source)
+		if err := s.exec(ctx, statement); err != nil {
+			return steps, fmt.Errorf("恢复表 %s 失败: %w", table, err)
+		}
+		steps = append(steps, fmt.Sprintf("已恢复表 %s -> %s", table, target))
+	}
+	return steps, nil
+}
+
+// cleanupExpired 删除该任务超过保留天数的备份及其数据，返回清理的数量
+func (s *CHBackupService) cleanupExpired(ctx context.Context, taskID uint, cfg models.CHBackupConfig) int {
+	cutoff := time.Now().AddDate(0, 0, -cfg.RetentionDays)
+
+	var expired []models.BackupHistory
+	s.db.Where("backup_type = ? AND task_id = ? AND created_at < ?", "clickhouse", taskID, cutoff).Find(&expired)
+
+	removed := 0
+	for _, history := range expired {
+		if history.Status == "success" {
+			if err := s.deleteData(ctx, &history, cfg); err != nil {
+				log.Printf("⚠️ 删除 ClickHouse 备份 %s 失败: %v", history.FileName, err)
+				continue
+			}
+		}
+		s.db.Delete(&history)
+		removed++
+	}
+	return removed
+}
+
+// deleteData 删除备份数据；Disk 方式的备份需要在服务器上清理
+func (s *CHBackupService) deleteData(ctx context.Context, history *models.BackupHistory, cfg models.CHBackupConfig) error {
+	switch history.StorageType {
+	case models.CHBackupMethodS3:
+		s3Service, err := NewS3Service(S3Config{
+			AccessKey: cfg.S3Config.AccessKey,
+			SecretKey: cfg.S3Config.SecretKey,
+			Region:    cfg.S3Config.Region,
+			Bucket:    history.S3Bucket,
+			Endpoint:  cfg.S3Config.Endpoint,
+		}, s.db)
+		if err != nil {
+			return err
+		}
+		_, err = s3Service.DeletePrefixFromS3(ctx, history.S3Key+"/")
+		return err
+	case models.CHBackupMethodFreeze:
+		for _, table := range strings.Split(history.Tables, ",") {
+			if table == "" {
+				continue
+			}
+			if err := s.exec(ctx, fmt.Sprintf("ALTER TABLE `%s` UNFREEZE WITH NAME %s", table, chQuote(history.FileName))); err != nil {
+				return err
+			}
+		}
+	case models.CHBackupMethodDisk:
+		log.Printf("⚠️ ClickHouse 不支持删除磁盘备份，请在服务器上手动删除 %s", history.StoragePath)
+	}
+	return nil
+}
+
+// resolveTables 校验表名存在于当前数据库中，表名无法参数化，避免拼接任意语句
+func (s *CHBackupService) resolveTables(ctx context.Context, tables []string) ([]string, error) {
+	if len(tables) == 0 {
+		tables = []string{"dns_query_log"}
+	}
+
+	resolved := make([]string, 0, len(tables))
+	for _, table := range tables {
+		table = strings.TrimSpace(table)
+		var count uint64
+		if err := database.CHConn.QueryRow(ctx,
+			"SELECT count() FROM system.tables WHERE database = currentDatabase() AND name = ?", table).Scan(&count); err != nil {
+			return nil, fmt.Errorf("查询表信息失败: %w", err)
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("表不存在: %s", table)
+		}
+		resolved = append(resolved, table)
+	}
+	return resolved, nil
+}
+
+func (s *CHBackupService) exec(ctx context.Context, statement string) error {
+	log.Printf("🗄️ ClickHouse 备份: %s", chRedactStatement(statement))
+	return database.CHConn.Exec(ctx, statement)
+}
+
+// chBackupTableList 生成 BACKUP 语句的表列表：TABLE `a`, TABLE `b`
+func chBackupTableList(tables []string) string {
+	items := make([]string, len(tables))
+	for i, table := range tables {
+		items[i] = fmt.Sprintf("TABLE `%s`", table)
+	}
+	return strings.Join(items, ", ")
+}
+
+// chBackupS3Key 备份在存储桶中的路径：prefix/name
+func chBackupS3Key(cfg models.S3Config, name string) string {
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix == "" {
+		prefix = "clickhouse-backups"
+	}
+	return prefix + "/" + name
+}
+
+// chBackupS3URL 自定义端点使用路径风格地址，否则使用 AWS 虚拟主机风格地址
+func chBackupS3URL(cfg models.S3Config, key string) string {
+	if cfg.Endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimRight(cfg.Endpoint, "/"), cfg.Bucket, key)
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", cfg.Bucket, region, key)
+}
+
+func chBackupS3Target(cfg models.S3Config, key string) string {
+	return fmt.Sprintf("S3(%s, %s, %s)", chQuote(chBackupS3URL(cfg, key)), chQuote(cfg.AccessKey), chQuote(cfg.SecretKey))
+}
+
+// chQuote 转义为 ClickHouse 字符串字面量
+func chQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+}
+
+// chRedactStatement 日志中隐藏 S3 凭据
+func chRedactStatement(statement string) string {
+	if idx := strings.Index(statement, " S3("); idx >= 0 {
+		return statement[:idx] + " S3(...)"
+	}
+	return statement
+}
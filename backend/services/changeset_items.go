@@ -0,0 +1,284 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"smartdns-manager/models"
+)
+
+// newChangeModel 根据变更类型创建对应的模型对象
+func newChangeModel(kind string) (interface{}, error) {
+	switch kind {
+	case models.ChangeKindAddress:
+		return &models.AddressMap{}, nil
+	case models.ChangeKindServer:
+		return &models.DNSServer{}, nil
+	case models.ChangeKindDomainRule:
+		return &models.DomainRule{}, nil
+	default:
+		return nil, fmt.Errorf("不支持的变更类型: %s", kind)
+	}
+}
+
+// validateChangeModel 校验并补全变更后的对象
+func validateChangeModel(obj interface{}) error {
+	switch v := obj.(type) {
+	case *models.AddressMap:
+		if v.Domain == "" {
+			return fmt.Errorf("域名不能为空")
+		}
+		if v.Type == "" {
+			v.Type = "address"
+		}
+		switch v.Type {
+		case "address":
+			if v.IP == "" {
+				return fmt.Errorf("IP地址不能为空")
+			}
+			v.CNAME = ""
+		case "cname":
+			if v.CNAME == "" {
+				return fmt.Errorf("CNAME别名不能为空")
+			}
+			v.IP = ""
+		default:
+			return fmt.Errorf("类型必须是 address 或 cname")
+		}
+		return NormalizeAddressMap(v)
+	case *models.DNSServer:
+		if v.Address == "" {
+			return fmt.Errorf("服务器地址不能为空")
+		}
+		if v.Type == "" {
+			switch {
+			case strings.HasPrefix(v.Address, "https://"):
+				v.Type = "https"
+			case strings.HasPrefix(v.Address, "tls://"):
+				v.Type = "tls"
+			case strings.Contains(v.Address, ":") && !strings.Contains(v.Address, "://"):
+				v.Type = "tcp"
+			default:
+				v.Type = "udp"
+			}
+		}
+		v.Options = GenerateServerOptions(v)
+	case *models.DomainRule:
+		if v.IsDomainSet && v.DomainSetName == "" {
+			return fmt.Errorf("域名集名称不能为空")
+		}
+		if !v.IsDomainSet && v.Domain == "" {
+			return fmt.Errorf("域名不能为空")
+		}
+	}
+	return nil
+}
+
+// GenerateServerOptions 根据分组和排除默认组生成 server 指令选项
+func GenerateServerOptions(server *models.DNSServer) string {
+	options := []string{}
+
+	for _, group := range server.Groups {
+		options = append(options, "-group "+group)
+	}
+
+	if server.ExcludeDefault {
+		options = append(options, "-exclude-default-group")
+	}
+
+	return strings.Join(options, " ")
+}
+
+// decodeChange 解析变更前后的对象，不存在时为 nil
+func decodeChange(item models.ChangeItem) (interface{}, interface{}, error) {
+	decode := func(data json.RawMessage) (interface{}, error) {
+		if len(data) == 0 {
+			return nil, nil
+		}
+		obj, err := newChangeModel(item.Kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, obj); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+
+	before, err := decode(item.Before)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := decode(item.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// afterLoadChangeModel 补全从数据库读出的对象
+func afterLoadChangeModel(obj interface{}) {
+	if server, ok := obj.(*models.DNSServer); ok && server.GroupsStr != "" {
+		json.Unmarshal([]byte(server.GroupsStr), &server.Groups)
+	}
+}
+
+// beforeSaveChangeModel 写入数据库前设置主键并序列化非数据库字段
+func beforeSaveChangeModel(obj interface{}, id uint) {
+	reflect.ValueOf(obj).Elem().FieldByName("ID").SetUint(uint64(id))
+	if server, ok := obj.(*models.DNSServer); ok {
+		server.GroupsStr = ""
+		if len(server.Groups) > 0 {
+			groupsJSON, _ := json.Marshal(server.Groups)
+			server.GroupsStr = string(groupsJSON)
+		}
+	}
+}
+
+// changeModelID 获取对象主键
+func changeModelID(obj interface{}) uint {
+	return uint(reflect.ValueOf(obj).Elem().FieldByName("ID").Uint())
+}
+
+// changedSincePlan 判断对象在计划后是否被修改
+func changedSincePlan(before json.RawMessage, current interface{}) bool {
+	var planned map[string]interface{}
+	if err := json.Unmarshal(before, &planned); err != nil {
+		return true
+	}
+	return !reflect.DeepEqual(planned["updated_at"], toFieldMap(current)["updated_at"])
+}
+
+// changeAuditInfo 获取审计记录的实体类型和名称
+func changeAuditInfo(kind string, before, after interface{}) (string, string) {
+	obj := after
+	if obj == nil {
+		obj = before
+	}
+
+	switch v := obj.(type) {
+	case *models.AddressMap:
+		return models.AuditEntityAddress, v.Domain
+	case *models.DNSServer:
+		return models.AuditEntityServer, v.Address
+	case *models.DomainRule:
+		return models.AuditEntityDomainRule, domainRuleKey(v)
+	}
+	return kind, ""
+}
+
+// changeTargetsNode 判断变更是否涉及指定节点（变更前或变更后作用于该节点）
+func changeTargetsNode(item models.ChangeItem, nodeID uint) bool {
+	before, after, err := decodeChange(item)
+	if err != nil {
+		return false
+	}
+	for _, obj := range []interface{}{before, after} {
+		if obj == nil {
+			continue
+		}
+		if nodeIDsContain(reflect.ValueOf(obj).Elem().FieldByName("NodeIDs").String(), nodeID) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyChangeToConfig 将单项变更应用到节点配置：先移除变更前的配置，再写入变更后的配置
+func applyChangeToConfig(config *models.SmartDNSConfig, item models.ChangeItem, nodeID uint) error {
+	before, after, err := decodeChange(item)
+	if err != nil {
+		return err
+	}
+
+	switch item.Kind {
+	case models.ChangeKindAddress:
+		if b, ok := before.(*models.AddressMap); ok && nodeIDsContain(b.NodeIDs, nodeID) {
+			kept := config.Addresses[:0]
+			for _, addr := range config.Addresses {
+				if addr.Domain != b.Domain {
+					kept = append(kept, addr)
+				}
+			}
+			config.Addresses = kept
+		}
+		if a, ok := after.(*models.AddressMap); ok && a.Enabled && nodeIDsContain(a.NodeIDs, nodeID) {
+			upsertAddress(config, *a)
+		}
+
+	case models.ChangeKindServer:
+		if b, ok := before.(*models.DNSServer); ok && nodeIDsContain(b.NodeIDs, nodeID) {
+			kept := config.Servers[:0]
+			for _, srv := range config.Servers {
+				if srv.Address != b.Address {
+					kept = append(kept, srv)
+				}
+			}
+			config.Servers = kept
+		}
+		if a, ok := after.(*models.DNSServer); ok && a.Enabled && nodeIDsContain(a.NodeIDs, nodeID) {
+			upsertServer(config, *a)
+		}
+
+	case models.ChangeKindDomainRule:
+		if b, ok := before.(*models.DomainRule); ok && nodeIDsContain(b.NodeIDs, nodeID) {
+			key := domainRuleKey(b)
+			kept := config.DomainRules[:0]
+			for i := range config.DomainRules {
+				if domainRuleKey(&config.DomainRules[i]) != key {
+					kept = append(kept, config.DomainRules[i])
+				}
+			}
+			config.DomainRules = kept
+		}
+		if a, ok := after.(*models.DomainRule); ok && a.Enabled && nodeIDsContain(a.NodeIDs, nodeID) {
+			upsertDomainRule(config, *a)
+		}
+	}
+
+	return nil
+}
+
+// upsertAddress 按域名更新或追加地址映射
+func upsertAddress(config *models.SmartDNSConfig, address models.AddressMap) {
+	for i := range config.Addresses {
+		if config.Addresses[i].Domain == address.Domain {
+			config.Addresses[i] = address
+			return
+		}
+	}
+	config.Addresses = append(config.Addresses, address)
+}
+
+// upsertServer 按地址更新或追加上游服务器
+func upsertServer(config *models.SmartDNSConfig, server models.DNSServer) {
+	for i := range config.Servers {
+		if config.Servers[i].Address == server.Address {
+			config.Servers[i] = server
+			return
+		}
+	}
+	config.Servers = append(config.Servers, server)
+}
+
+// upsertDomainRule 按域名（或域名集）更新或追加域名规则
+func upsertDomainRule(config *models.SmartDNSConfig, rule models.DomainRule) {
+	key := domainRuleKey(&rule)
+	for i := range config.DomainRules {
+		if domainRuleKey(&config.DomainRules[i]) == key {
+			config.DomainRules[i] = rule
+			return
+		}
+	}
+	config.DomainRules = append(config.DomainRules, rule)
+}
+
+// domainRuleKey 域名规则在配置中的标识
+func domainRuleKey(rule *models.DomainRule) string {
+	if rule.IsDomainSet {
+		return "domain-set:" + rule.DomainSetName
+	}
+	return rule.Domain
+}
@@ -0,0 +1,580 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// changeSetConcurrency 同时应用变更的节点数
+const changeSetConcurrency = 5
+
+// ChangeSetService 批量配置变更（计划/应用）服务
+type ChangeSetService struct {
+	notificationService *NotificationService
+	auditService        *AuditService
+	mutex               sync.Mutex // 保证同一变更集不会被重复应用
+}
+
+func NewChangeSetService() *ChangeSetService {
+	return &ChangeSetService{
+		notificationService: NewNotificationService(),
+		auditService:        NewAuditService(),
+	}
+}
+
+// Plan 创建变更集：记录变更前的对象快照，并计算每个受影响节点的配置差异
+func (s *ChangeSetService) Plan(description string, items []models.ChangeItem, actor AuditActor) (*models.ChangeSet, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("变更内容不能为空")
+	}
+
+	for i := range items {
+		if err := s.snapshot(&items[i]); err != nil {
+			return nil, fmt.Errorf("第 %d 项变更无效: %w", i+1, err)
+		}
+	}
+
+	nodes, err := s.affectedNodes(items)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(items)
+	changeSet := &models.ChangeSet{
+		Description: description,
+		Status:      models.ChangeSetStatusPending,
+		Changes:     string(data),
+		UserID:      actor.UserID,
+		Username:    actor.Username,
+	}
+	if err := database.DB.Create(changeSet).Error; err != nil {
+		return nil, err
+	}
+
+	// 并发读取各节点配置并计算差异
+	results := make([]models.ChangeSetNode, len(nodes))
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = s.planNode(changeSet.ID, &nodes[i], items)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range results {
+		if results[i].Status != models.ChangeNodeStatusSkipped {
+			changeSet.TotalNodes++
+		}
+		database.DB.Create(&results[i])
+	}
+	database.DB.Model(changeSet).UpdateColumn("total_nodes", changeSet.TotalNodes)
+
+	changeSet.Items = items
+	changeSet.Nodes = results
+	return changeSet, nil
+}
+
+// Get 获取变更集详情（含节点差异和进度）
+func (s *ChangeSetService) Get(id uint) (*models.ChangeSet, error) {
+	var changeSet models.ChangeSet
+	if err := database.DB.Preload("Nodes").First(&changeSet, id).Error; err != nil {
+		return nil, err
+	}
+	DecodeChangeSet(&changeSet)
+	return &changeSet, nil
+}
+
+// Apply 提交数据库变更并开始应用到节点
+func (s *ChangeSetService) Apply(id uint, actor AuditActor) error {
+	s.mutex.Lock()
+	var changeSet models.ChangeSet
+	if err := database.DB.First(&changeSet, id).Error; err != nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("变更集不存在")
+	}
+	if changeSet.Status != models.ChangeSetStatusPending {
+		s.mutex.Unlock()
+		return fmt.Errorf("变更集当前状态为 %s，无法应用", changeSet.Status)
+	}
+	database.DB.Model(&changeSet).Update("status", models.ChangeSetStatusApplying)
+	s.mutex.Unlock()
+
+	var items []models.ChangeItem
+	json.Unmarshal([]byte(changeSet.Changes), &items)
+
+	// 所有数据库变更在一个事务中提交，任一失败则整体回滚
+	var committed [][2]interface{}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		committed = committed[:0]
+		for i := range items {
+			before, after, err := s.commitItem(tx, &items[i])
+			if err != nil {
+				return fmt.Errorf("第 %d 项变更提交失败: %w", i+1, err)
+			}
+			committed = append(committed, [2]interface{}{before, after})
+		}
+		return nil
+	})
+	if err != nil {
+		database.DB.Model(&changeSet).Updates(map[string]interface{}{
+			"status": models.ChangeSetStatusFailed,
+			"error":  err.Error(),
+		})
+		return err
+	}
+
+	now := time.Now()
+	data, _ := json.Marshal(items)
+	database.DB.Model(&changeSet).Updates(map[string]interface{}{
+		"changes":    string(data),
+		"applied_at": &now,
+	})
+
+	for i, item := range items {
+		entityType, entityName := changeAuditInfo(item.Kind, committed[i][0], committed[i][1])
+		s.auditService.Record(actor, entityType, item.ID, entityName, item.Action, committed[i][0], committed[i][1])
+	}
+
+	go s.rollout(changeSet.ID, items)
+	return nil
+}
+
+// Retry 重新应用失败的节点
+func (s *ChangeSetService) Retry(id uint) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var changeSet models.ChangeSet
+	if err := database.DB.First(&changeSet, id).Error; err != nil {
+		return fmt.Errorf("变更集不存在")
+	}
+	if changeSet.AppliedAt == nil {
+		return fmt.Errorf("变更集尚未提交，无法重试")
+	}
+	if changeSet.Status != models.ChangeSetStatusPartial && changeSet.Status != models.ChangeSetStatusFailed {
+		return fmt.Errorf("变更集当前状态为 %s，没有需要重试的节点", changeSet.Status)
+	}
+	database.DB.Model(&changeSet).Update("status", models.ChangeSetStatusApplying)
+
+	var items []models.ChangeItem
+	json.Unmarshal([]byte(changeSet.Changes), &items)
+
+	go s.rollout(changeSet.ID, items)
+	return nil
+}
+
+// ResumeInterrupted 服务重启后继续应用中断的变更集：数据库变更已提交，只需对未完成的节点重新下发
+func (s *ChangeSetService) ResumeInterrupted() {
+	// 提交数据库变更时中断，无法确定事务是否已提交
+	database.DB.Model(&models.ChangeSet{}).
+		Where("status = ? AND applied_at IS NULL", models.ChangeSetStatusApplying).
+		Updates(map[string]interface{}{
+			"status": models.ChangeSetStatusFailed,
+			"error":  "服务重启时变更集正在提交，已中断。请在变更历史中核对各项变更是否已生效，未生效的重新创建变更集",
+		})
+
+	var changeSets []models.ChangeSet
+	database.DB.Where("status = ? AND applied_at IS NOT NULL", models.ChangeSetStatusApplying).Find(&changeSets)
+
+	for _, changeSet := range changeSets {
+		database.DB.Model(&models.ChangeSetNode{}).
+			Where("change_set_id = ? AND status = ?", changeSet.ID, models.ChangeNodeStatusRunning).
+			Update("status", models.ChangeNodeStatusPending)
+
+		var items []models.ChangeItem
+		json.Unmarshal([]byte(changeSet.Changes), &items)
+
+		log.Printf("🔁 恢复应用变更集 #%d", changeSet.ID)
+		go s.rollout(changeSet.ID, items)
+	}
+}
+
+// Discard 放弃尚未应用的变更集
+func (s *ChangeSetService) Discard(id uint) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var changeSet models.ChangeSet
+	if err := database.DB.First(&changeSet, id).Error; err != nil {
+		return fmt.Errorf("变更集不存在")
+	}
+	if changeSet.Status != models.ChangeSetStatusPending {
+		return fmt.Errorf("只能放弃待应用的变更集")
+	}
+	return database.DB.Model(&changeSet).Update("status", models.ChangeSetStatusDiscarded).Error
+}
+
+// rollout 将变更应用到待处理和失败的节点
+func (s *ChangeSetService) rollout(changeSetID uint, items []models.ChangeItem) {
+	var nodes []models.ChangeSetNode
+	database.DB.Where("change_set_id = ? AND status IN ?", changeSetID,
+		[]string{models.ChangeNodeStatusPending, models.ChangeNodeStatusFailed}).Find(&nodes)
+
+	sem := make(chan struct{}, changeSetConcurrency)
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(n *models.ChangeSetNode) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.applyNode(n, items)
+		}(&nodes[i])
+	}
+	wg.Wait()
+
+	s.finish(changeSetID)
+}
+
+// applyNode 在单个节点上应用变更
+func (s *ChangeSetService) applyNode(result *models.ChangeSetNode, items []models.ChangeItem) {
+	now := time.Now()
+	result.Status = models.ChangeNodeStatusRunning
+	result.StartedAt = &now
+	result.FinishedAt = nil
+	result.Error = ""
+	result.Attempts++
+	database.DB.Save(result)
+
+	err := s.writeNode(result, items)
+
+	finished := time.Now()
+	result.FinishedAt = &finished
+	if err != nil {
+		result.Status = models.ChangeNodeStatusFailed
+		result.Error = err.Error()
+		log.Printf("变更集 #%d 应用到节点 %s 失败: %v", result.ChangeSetID, result.NodeName, err)
+		s.notificationService.SendNotification(result.NodeID, "sync_failed", "❌ 配置同步失败",
+			fmt.Sprintf("变更集 #%d 应用失败\n\n错误: %s", result.ChangeSetID, err.Error()))
+	} else {
+		result.Status = models.ChangeNodeStatusSuccess
+	}
+	encodeNodeDiff(result)
+	database.DB.Save(result)
+}
+
+// writeNode 读取节点当前配置，应用变更后写回，并记录实际产生的差异
+func (s *ChangeSetService) writeNode(result *models.ChangeSetNode, items []models.ChangeItem) error {
+	var node models.Node
+	if err := database.DB.First(&node, result.NodeID).Error; err != nil {
+		return fmt.Errorf("节点不存在")
+	}
+
+	client, err := NewSSHClient(&node)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	content, err := client.ReadFile(node.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	current, updated, err := s.render(content, node.ID, items)
+	if err != nil {
+		return err
+	}
+
+	// 节点配置可能在计划后被修改，以实际写入的差异为准
+	setNodeDiff(result, current, updated)
+	if current == updated {
+		return nil
+	}
+
+	if _, err := client.CreateBackup(node.ConfigPath); err != nil {
+		log.Printf("警告: 创建备份失败: %v", err)
+	}
+	if err := client.WriteFile(node.ConfigPath, updated); err != nil {
+		return err
+	}
+
+	reloadAfterSync(context.Background(), client, &node)
+	return nil
+}
+
+// finish 汇总节点结果，更新变更集状态
+func (s *ChangeSetService) finish(changeSetID uint) {
+	var nodes []models.ChangeSetNode
+	database.DB.Where("change_set_id = ?", changeSetID).Find(&nodes)
+
+	succeeded, failed := 0, 0
+	for _, n := range nodes {
+		switch n.Status {
+		case models.ChangeNodeStatusSuccess:
+			succeeded++
+		case models.ChangeNodeStatusFailed:
+			failed++
+		}
+	}
+
+	status := models.ChangeSetStatusApplied
+	if failed > 0 && succeeded > 0 {
+		status = models.ChangeSetStatusPartial
+	} else if failed > 0 {
+		status = models.ChangeSetStatusFailed
+	}
+
+	database.DB.Model(&models.ChangeSet{}).Where("id = ?", changeSetID).Updates(map[string]interface{}{
+		"status":          status,
+		"succeeded_nodes": succeeded,
+		"failed_nodes":    failed,
+	})
+	log.Printf("变更集 #%d 应用完成: 成功 %d 个节点, 失败 %d 个节点", changeSetID, succeeded, failed)
+}
+
+// planNode 计算单个节点的配置差异
+func (s *ChangeSetService) planNode(changeSetID uint, node *models.Node, items []models.ChangeItem) models.ChangeSetNode {
+	result := models.ChangeSetNode{
+		ChangeSetID: changeSetID,
+		NodeID:      node.ID,
+		NodeName:    node.Name,
+		Status:      models.ChangeNodeStatusPending,
+	}
+
+	current, updated, err := s.renderNode(node, items)
+	if err != nil {
+		// 计划阶段读取失败不影响应用，应用时会重新读取
+		result.Error = fmt.Sprintf("读取节点配置失败: %v", err)
+	} else {
+		setNodeDiff(&result, current, updated)
+		if current == updated {
+			result.Status = models.ChangeNodeStatusSkipped
+		}
+	}
+
+	encodeNodeDiff(&result)
+	return result
+}
+
+// renderNode 读取节点配置并生成变更前后的配置
+func (s *ChangeSetService) renderNode(node *models.Node, items []models.ChangeItem) (string, string, error) {
+	client, err := NewSSHClient(node)
+	if err != nil {
+		return "", "", err
+	}
+	defer client.Close()
+
+	content, err := client.ReadFile(node.ConfigPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	return s.render(content, node.ID, items)
+}
+
+// render 返回规范化后的当前配置和应用变更后的配置
+func (s *ChangeSetService) render(content string, nodeID uint, items []models.ChangeItem) (string, string, error) {
+	parser := NewConfigParser()
+	config, err := parser.Parse(content)
+	if err != nil {
+		return "", "", err
+	}
+	current := parser.Generate(config)
+
+	for _, item := range items {
+		if err := applyChangeToConfig(config, item, nodeID); err != nil {
+			return "", "", err
+		}
+	}
+
+	return current, parser.Generate(config), nil
+}
+
+// affectedNodes 获取变更前后涉及的所有节点
+func (s *ChangeSetService) affectedNodes(items []models.ChangeItem) ([]models.Node, error) {
+	var nodes []models.Node
+	if err := database.DB.Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+
+	var affected []models.Node
+	for _, node := range nodes {
+		for _, item := range items {
+			if changeTargetsNode(item, node.ID) {
+				affected = append(affected, node)
+				break
+			}
+		}
+	}
+	return affected, nil
+}
+
+// snapshot 校验变更项并记录变更前的对象
+func (s *ChangeSetService) snapshot(item *models.ChangeItem) error {
+	if _, err := newChangeModel(item.Kind); err != nil {
+		return err
+	}
+
+	switch item.Action {
+	case models.AuditActionCreate:
+		if len(item.Data) == 0 {
+			return fmt.Errorf("缺少变更内容")
+		}
+		item.ID = 0
+		item.Before = nil
+
+		// 新建对象默认启用
+		obj, _ := newChangeModel(item.Kind)
+		reflect.ValueOf(obj).Elem().FieldByName("Enabled").SetBool(true)
+		return s.mergeData(item, obj)
+	case models.AuditActionUpdate, models.AuditActionDelete:
+		if item.ID == 0 {
+			return fmt.Errorf("缺少对象ID")
+		}
+		if item.Action == models.AuditActionUpdate && len(item.Data) == 0 {
+			return fmt.Errorf("缺少变更内容")
+		}
+
+		current, _ := newChangeModel(item.Kind)
+		if err := database.DB.First(current, item.ID).Error; err != nil {
+			return fmt.Errorf("%s #%d 不存在", item.Kind, item.ID)
+		}
+		afterLoadChangeModel(current)
+		item.Before, _ = json.Marshal(current)
+
+		if item.Action == models.AuditActionDelete {
+			item.Data = nil
+			return nil
+		}
+
+		// 未提供的字段保持原值
+		merged, _ := newChangeModel(item.Kind)
+		json.Unmarshal(item.Before, merged)
+		return s.mergeData(item, merged)
+	default:
+		return fmt.Errorf("不支持的操作: %s", item.Action)
+	}
+}
+
+// mergeData 将请求中的字段合并到 base 上，校验后写回 item.Data
+func (s *ChangeSetService) mergeData(item *models.ChangeItem, base interface{}) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(item.Data, &fields); err != nil {
+		return fmt.Errorf("变更内容格式错误: %w", err)
+	}
+
+	// 兼容 node_ids 以数组形式传入
+	if ids, ok := fields["node_ids"].([]interface{}); ok {
+		data, _ := json.Marshal(ids)
+		fields["node_ids"] = string(data)
+	}
+	delete(fields, "id")
+
+	data, _ := json.Marshal(fields)
+	if err := json.Unmarshal(data, base); err != nil {
+		return fmt.Errorf("变更内容格式错误: %w", err)
+	}
+	if err := validateChangeModel(base); err != nil {
+		return err
+	}
+
+	item.Data, _ = json.Marshal(base)
+	return nil
+}
+
+// commitItem 在事务中提交单项变更，返回变更前后的对象
+func (s *ChangeSetService) commitItem(tx *gorm.DB, item *models.ChangeItem) (interface{}, interface{}, error) {
+	var before interface{}
+	if item.Action != models.AuditActionCreate {
+		current, _ := newChangeModel(item.Kind)
+		if err := tx.First(current, item.ID).Error; err != nil {
+			return nil, nil, fmt.Errorf("%s #%d 已不存在", item.Kind, item.ID)
+		}
+		afterLoadChangeModel(current)
+
+		// 计划后对象被其他人修改过则拒绝提交，避免覆盖
+		if changedSincePlan(item.Before, current) {
+			return nil, nil, fmt.Errorf("%s #%d 在计划后已被修改，请重新计划", item.Kind, item.ID)
+		}
+		before = current
+	}
+
+	switch item.Action {
+	case models.AuditActionCreate, models.AuditActionUpdate:
+		after, _ := newChangeModel(item.Kind)
+		if err := json.Unmarshal(item.Data, after); err != nil {
+			return nil, nil, err
+		}
+		beforeSaveChangeModel(after, item.ID)
+
+		var err error
+		if item.Action == models.AuditActionCreate {
+			err = tx.Create(after).Error
+		} else {
+			err = tx.Save(after).Error
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		item.ID = changeModelID(after)
+		item.Data, _ = json.Marshal(after)
+		return before, after, nil
+	default:
+		if err := tx.Delete(before).Error; err != nil {
+			return nil, nil, err
+		}
+		return before, nil, nil
+	}
+}
+
+// DecodeChangeSet 解析变更集中的变更项和节点差异
+func DecodeChangeSet(changeSet *models.ChangeSet) {
+	changeSet.Items = []models.ChangeItem{}
+	if changeSet.Changes != "" {
+		json.Unmarshal([]byte(changeSet.Changes), &changeSet.Items)
+	}
+	for i := range changeSet.Nodes {
+		n := &changeSet.Nodes[i]
+		n.AddedLines = []string{}
+		n.RemovedLines = []string{}
+		n.ChangedLines = []models.ConfigLineChange{}
+		if n.Added != "" {
+			json.Unmarshal([]byte(n.Added), &n.AddedLines)
+		}
+		if n.Removed != "" {
+			json.Unmarshal([]byte(n.Removed), &n.RemovedLines)
+		}
+		if n.Changed != "" {
+			json.Unmarshal([]byte(n.Changed), &n.ChangedLines)
+		}
+	}
+}
+
+// setNodeDiff 计算节点变更前后的差异
+func setNodeDiff(result *models.ChangeSetNode, current, updated string) {
+	added, removed, changed := DiffConfigLines(current, updated)
+	result.AddedLines = added
+	result.RemovedLines = removed
+	result.ChangedLines = make([]models.ConfigLineChange, 0, len(changed))
+	for _, change := range changed {
+		result.ChangedLines = append(result.ChangedLines, models.ConfigLineChange{
+			Key:    change.Key,
+			Before: change.Expected,
+			After:  change.Actual,
+		})
+	}
+}
+
+// encodeNodeDiff 序列化节点差异
+func encodeNodeDiff(result *models.ChangeSetNode) {
+	added, _ := json.Marshal(result.AddedLines)
+	removed, _ := json.Marshal(result.RemovedLines)
+	changed, _ := json.Marshal(result.ChangedLines)
+	result.Added = string(added)
+	result.Removed = string(removed)
+	result.Changed = string(changed)
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// ClientAbuseService 客户端异常查询检测服务
+type ClientAbuseService struct {
+	db                  *gorm.DB
+	config              *config.Config
+	notificationService *NotificationService
+}
+
+// NewClientAbuseService 创建客户端异常查询检测服务
+func NewClientAbuseService(db *gorm.DB, config *config.Config) (*ClientAbuseService, error) {
+	return &ClientAbuseService{
+		db:                  db,
+		config:              config,
+		notificationService: NewNotificationService(),
+	}, nil
+}
+
+// CheckClients 检测超过 QPS 阈值的客户端并发送通知
+func (s *ClientAbuseService) CheckClients(ctx context.Context, cfg models.ClientAbuseConfig) (string, error) {
+	if database.CHConn == nil {
+		return "", fmt.Errorf("ClickHouse 未连接")
+	}
+	if cfg.QPSThreshold <= 0 {
+		return "", fmt.Errorf("QPS阈值必须大于0")
+	}
+	if cfg.WindowMinutes <= 0 {
+		cfg.WindowMinutes = 5
+	}
+	if cfg.MaxClients <= 0 {
+		cfg.MaxClients = 20
+	}
+
+	endTime := time.Now()
+	startTime := endTime.Add(-time.Duration(cfg.WindowMinutes) * time.Minute)
+
+	analytics := NewLogAnalyticsService(database.CHConn)
+	stats, err := analytics.GetClientsExceedingQPS(cfg.NodeIDs, startTime, endTime, cfg.QPSThreshold, cfg.MaxClients+len(cfg.ExcludeClients))
+	if err != nil {
+		return "", err
+	}
+
+	excluded := make(map[string]bool, len(cfg.ExcludeClients))
+	for _, ip := range cfg.ExcludeClients {
+		excluded[strings.TrimSpace(ip)] = true
+	}
+
+	// 按节点汇总，每个节点发送一条通知
+	byNode := make(map[uint][]models.ClientQPSStat)
+	var nodeOrder []uint
+	reported := 0
+	for _, stat := range stats {
+		if excluded[stat.ClientIP] || reported >= cfg.MaxClients {
+			continue
+		}
+		if _, ok := byNode[stat.NodeID]; !ok {
+			nodeOrder = append(nodeOrder, stat.NodeID)
+		}
+		byNode[stat.NodeID] = append(byNode[stat.NodeID], stat)
+		reported++
+	}
+
+	if reported == 0 {
+		return fmt.Sprintf("最近 %d 分钟内没有客户端超过 %.2f QPS", cfg.WindowMinutes, cfg.QPSThreshold), nil
+	}
+
+	for _, nodeID := range nodeOrder {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		var lines []string
+		for _, stat := range byNode[nodeID] {
+			lines = append(lines, fmt.Sprintf("- %s: %.2f QPS（%d 次查询）", stat.ClientIP, stat.QPS, stat.Count))
+		}
+		content := fmt.Sprintf("最近 %d 分钟内以下客户端超过 %.2f QPS：\n%s",
+			cfg.WindowMinutes, cfg.QPSThreshold, strings.Join(lines, "\n"))
+
+		if err := s.notificationService.SendNotification(nodeID, "client_abuse", "⚠️ 客户端查询异常", content); err != nil {
+			log.Printf("⚠️ 发送客户端异常通知失败: %v", err)
+		}
+	}
+
+	return fmt.Sprintf("发现 %d 个客户端超过 %.2f QPS，涉及 %d 个节点", reported, cfg.QPSThreshold, len(nodeOrder)), nil
+}
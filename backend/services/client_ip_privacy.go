@@ -0,0 +1,188 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// 客户端 IP 脱敏模式，与 Agent 的 CLIENT_IP_PRIVACY 一致
+const (
+	PrivacyOff      = "off"
+	PrivacyTruncate = "truncate"
+	PrivacyHash     = "hash"
+
+	// ClientIPHashPrefix 哈希后客户端 IP 的前缀，算法须与 Agent 一致
+	ClientIPHashPrefix = "anon-"
+)
+
+// ClientIPPrivacy 查询结果中的客户端 IP 脱敏
+//
+// Agent 可在写入前脱敏，经管理端接收的日志在写入前同样脱敏；对直接写入日志存储且仍保存原始 IP 的日志，
+// 通过分享链接查看时在这里按相同算法脱敏，三者结果一致
+type ClientIPPrivacy struct {
+	mode     string
+	v4Prefix int
+	v6Prefix int
+	key      []byte
+}
+
+var (
+	defaultClientIPPrivacy *ClientIPPrivacy
+	clientIPPrivacyOnce    sync.Once
+)
+
+// GetClientIPPrivacy 按 LOG_PRIVACY_MODE 等配置创建脱敏器
+func GetClientIPPrivacy() *ClientIPPrivacy {
+	clientIPPrivacyOnce.Do(func() {
+		cfg := config.GetConfig()
+		defaultClientIPPrivacy = NewClientIPPrivacy(cfg.LogPrivacyMode, cfg.LogPrivacyV4Prefix, cfg.LogPrivacyV6Prefix, cfg.LogClientIPHashKey)
+	})
+	return defaultClientIPPrivacy
+}
+
+// NewClientIPPrivacy 创建脱敏器，不支持的模式或 hash 模式缺少密钥时改为截断
+func NewClientIPPrivacy(mode, v4Prefix, v6Prefix, hashKey string) *ClientIPPrivacy {
+	p := &ClientIPPrivacy{
+		mode:     strings.ToLower(mode),
+		v4Prefix: parsePrivacyPrefix(v4Prefix, 32, 24),
+		v6Prefix: parsePrivacyPrefix(v6Prefix, 128, 56),
+		key:      []byte(hashKey),
+	}
+	switch p.mode {
+	case PrivacyOff, PrivacyTruncate:
+	case PrivacyHash:
+		if len(p.key) == 0 {
+			log.Printf("⚠️ LOG_PRIVACY_MODE=hash 但未设置 LOG_CLIENT_IP_HASH_KEY，改为截断客户端 IP")
+			p.mode = PrivacyTruncate
+		}
+	default:
+		log.Printf("⚠️ 不支持的 LOG_PRIVACY_MODE: %s，改为截断客户端 IP", p.mode)
+		p.mode = PrivacyTruncate
+	}
+	return p
+}
+
+func parsePrivacyPrefix(value string, max, def int) int {
+	bits, err := strconv.Atoi(value)
+	if err != nil || bits < 0 || bits > max {
+		return def
+	}
+	return bits
+}
+
+// Enabled 是否启用脱敏
+func (p *ClientIPPrivacy) Enabled() bool {
+	return p.mode == PrivacyTruncate || p.mode == PrivacyHash
+}
+
+// Mode 当前脱敏模式
+func (p *ClientIPPrivacy) Mode() string {
+	return p.mode
+}
+
+// HashIP 计算客户端 IP 的哈希值，不是合法 IP 时返回原值
+func (p *ClientIPPrivacy) HashIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(addr.Unmap().String()))
+	return ClientIPHashPrefix + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// MaskIP 按当前模式脱敏，已脱敏的值（哈希值、截断后的地址）保持不变
+func (p *ClientIPPrivacy) MaskIP(ip string) string {
+	if !p.Enabled() {
+		return ip
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	if p.mode == PrivacyHash {
+		return p.HashIP(ip)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, p.bits(addr)).Masked().Addr().String()
+}
+
+// SearchValue 管理员按原始 IP 检索时转换为存储中的值，hash 模式下存储的是哈希值
+func (p *ClientIPPrivacy) SearchValue(ip string) string {
+	if p.mode != PrivacyHash {
+		return ip
+	}
+	return p.HashIP(ip)
+}
+
+func (p *ClientIPPrivacy) bits(addr netip.Addr) int {
+	if addr.Is4() {
+		return p.v4Prefix
+	}
+	return p.v6Prefix
+}
+
+// maskSubnet 子网前缀比脱敏前缀更长时截断到脱敏前缀
+func (p *ClientIPPrivacy) maskSubnet(subnet string) string {
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return subnet
+	}
+	if bits := p.bits(prefix.Addr()); prefix.Bits() > bits {
+		return netip.PrefixFrom(prefix.Addr(), bits).Masked().String()
+	}
+	return subnet
+}
+
+// MaskLogs 脱敏日志列表中的客户端 IP、原始日志和 PTR
+func (p *ClientIPPrivacy) MaskLogs(logs []models.DNSLog) {
+	if !p.Enabled() {
+		return
+	}
+	for i := range logs {
+		entry := &logs[i]
+		masked := p.MaskIP(entry.ClientIP)
+		if masked != entry.ClientIP {
+			entry.RawLog = strings.Replace(entry.RawLog, entry.ClientIP, masked, 1)
+			entry.ClientIP = masked
+		}
+		entry.ClientPTR = ""
+		entry.ClientSubnet = p.maskSubnet(entry.ClientSubnet)
+	}
+}
+
+// MaskRecords 写入前脱敏 Agent 上传的日志，与 Agent 的 CLIENT_IP_PRIVACY 处理一致
+func (p *ClientIPPrivacy) MaskRecords(records []models.DNSLogCK) {
+	if !p.Enabled() {
+		return
+	}
+	for i := range records {
+		record := &records[i]
+		masked := p.MaskIP(record.ClientIP)
+		if masked != record.ClientIP {
+			record.RawLog = strings.Replace(record.RawLog, record.ClientIP, masked, 1)
+			record.ClientIP = masked
+		}
+		record.ClientPTR = ""
+		record.ClientSubnet = p.maskSubnet(record.ClientSubnet)
+	}
+}
+
+// MaskStats 脱敏统计中的客户端排行
+func (p *ClientIPPrivacy) MaskStats(stats *models.DNSLogStats) {
+	if !p.Enabled() || stats == nil {
+		return
+	}
+	for i := range stats.TopClients {
+		stats.TopClients[i].ClientIP = p.MaskIP(stats.TopClients[i].ClientIP)
+	}
+}
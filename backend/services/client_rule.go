@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// ClientRuleService 客户端规则（client-rules）管理服务
+type ClientRuleService struct {
+	notificationService *NotificationService
+	parser              *ConfigParser
+}
+
+func NewClientRuleService() *ClientRuleService {
+	return &ClientRuleService{
+		notificationService: NewNotificationService(),
+		parser:              NewConfigParser(),
+	}
+}
+
+// GenerateLine 生成客户端规则对应的 client-rules 指令
+func (s *ClientRuleService) GenerateLine(rule *models.ClientRule) string {
+	return s.parser.GenerateClientRule(rule)
+}
+
+// ApplyToConfig 将节点适用的客户端规则写入配置，按优先级从高到低排列
+func (s *ClientRuleService) ApplyToConfig(config *models.SmartDNSConfig, nodeID uint) int {
+	var rules []models.ClientRule
+	database.DB.Where("enabled = ?", true).Order("priority DESC, id ASC").Find(&rules)
+
+	applied := 0
+	for i := range rules {
+		if !nodeIDsContain(rules[i].NodeIDs, nodeID) {
+			continue
+		}
+		s.upsertRule(config, rules[i])
+		applied++
+	}
+	if applied > 0 {
+		s.ensureBlockedGroup(config)
+	}
+	return applied
+}
+
+// SyncClientRuleToNodes 同步客户端规则到节点
+func (s *ClientRuleService) SyncClientRuleToNodes(ctx context.Context, rule *models.ClientRule) error {
+	if !rule.Enabled {
+		return nil
+	}
+
+	nodes, err := s.getTargetNodes(rule.NodeIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		go updateNodeConfig(ctx, s.notificationService, &node, "client_rule", "update", rule.Client, func(config *models.SmartDNSConfig) {
+			s.upsertRule(config, *rule)
+			s.ensureBlockedGroup(config)
+		})
+	}
+
+	return nil
+}
+
+// DeleteClientRuleFromNodes 从节点删除客户端规则
+func (s *ClientRuleService) DeleteClientRuleFromNodes(ctx context.Context, rule *models.ClientRule) error {
+	nodes, err := s.getTargetNodes(rule.NodeIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		go updateNodeConfig(ctx, s.notificationService, &node, "client_rule", "delete", rule.Client, func(config *models.SmartDNSConfig) {
+			rules := []models.ClientRule{}
+			for _, r := range config.ClientRules {
+				if r.Client != rule.Client {
+					rules = append(rules, r)
+				}
+			}
+			config.ClientRules = rules
+			s.ensureBlockedGroup(config)
+		})
+	}
+
+	return nil
+}
+
+// ReplaceClientRuleOnNodes 更新规则后同步到节点：旧规则从原节点移除，新规则写入当前适用的节点
+func (s *ClientRuleService) ReplaceClientRuleOnNodes(ctx context.Context, previous, current *models.ClientRule) error {
+	previousNodes, err := s.getTargetNodes(previous.NodeIDs)
+	if err != nil {
+		return err
+	}
+	currentNodes, err := s.getTargetNodes(current.NodeIDs)
+	if err != nil {
+		return err
+	}
+
+	nodes := map[uint]models.Node{}
+	for _, node := range append(previousNodes, currentNodes...) {
+		nodes[node.ID] = node
+	}
+
+	for _, node := range nodes {
+		apply := current.Enabled && nodeIDsContain(current.NodeIDs, node.ID)
+		go updateNodeConfig(ctx, s.notificationService, &node, "client_rule", "update", current.Client, func(config *models.SmartDNSConfig) {
+			rules := []models.ClientRule{}
+			for _, r := range config.ClientRules {
+				if r.Client != previous.Client {
+					rules = append(rules, r)
+				}
+			}
+			config.ClientRules = rules
+			if apply {
+				s.upsertRule(config, *current)
+			}
+			s.ensureBlockedGroup(config)
+		})
+	}
+
+	return nil
+}
+
+// upsertRule 替换同一客户端的规则，不存在时追加
+func (s *ClientRuleService) upsertRule(config *models.SmartDNSConfig, rule models.ClientRule) {
+	for i, r := range config.ClientRules {
+		if r.Client == rule.Client {
+			config.ClientRules[i] = rule
+			return
+		}
+	}
+	config.ClientRules = append(config.ClientRules, rule)
+}
+
+// ensureBlockedGroup 存在阻断规则时添加阻断分组，不再需要时移除
+func (s *ClientRuleService) ensureBlockedGroup(config *models.SmartDNSConfig) {
+	needed := false
+	for _, r := range config.ClientRules {
+		if r.Blocked {
+			needed = true
+			break
+		}
+	}
+
+	groups := []models.ConfigGroup{}
+	for _, g := range config.Groups {
+		if g.Name != models.ClientRuleBlockedGroup {
+			groups = append(groups, g)
+		}
+	}
+	if needed {
+		groups = append(groups, models.ConfigGroup{
+			Name:  models.ClientRuleBlockedGroup,
+			Lines: []string{"address /#/#"},
+		})
+	}
+	config.Groups = groups
+}
+
+// getTargetNodes 获取目标节点列表
+func (s *ClientRuleService) getTargetNodes(nodeIDsJSON string) ([]models.Node, error) {
+	var nodes []models.Node
+
+	if nodeIDsJSON == "" || nodeIDsJSON == "[]" {
+		database.DB.Find(&nodes)
+	} else {
+		var nodeIDs []uint
+		if err := json.Unmarshal([]byte(nodeIDsJSON), &nodeIDs); err != nil {
+			return nil, err
+		}
+		database.DB.Where("id IN ?", nodeIDs).Find(&nodes)
+	}
+
+	return nodes, nil
+}
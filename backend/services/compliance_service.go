@@ -0,0 +1,572 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// smartdnsLogLevels SmartDNS 日志级别，从低到高
+var smartdnsLogLevels = []string{"debug", "info", "notice", "warn", "error", "fatal"}
+
+// ComplianceService 节点配置合规检查服务，按策略检查每个节点解析后的配置，记录违规并可自动修复
+type ComplianceService struct {
+	db                  *gorm.DB
+	config              *config.Config
+	notificationService *NotificationService
+	configSyncService   *ConfigSyncService
+}
+
+// NewComplianceService 创建配置合规检查服务
+func NewComplianceService(db *gorm.DB, config *config.Config) (*ComplianceService, error) {
+	return &ComplianceService{
+		db:                  db,
+		config:              config,
+		notificationService: NewNotificationService(),
+		configSyncService:   NewConfigSyncService(),
+	}, nil
+}
+
+// ValidatePolicy 校验策略定义
+func ValidatePolicy(policy *models.CompliancePolicy) error {
+	if strings.TrimSpace(policy.Directive) == "" {
+		return fmt.Errorf("请填写配置指令")
+	}
+	switch policy.Operator {
+	case models.ComplianceOpPresent, models.ComplianceOpAbsent:
+	case models.ComplianceOpEquals, models.ComplianceOpNotEquals, models.ComplianceOpIn, models.ComplianceOpContains:
+		if policy.Value == "" {
+			return fmt.Errorf("请填写比较值")
+		}
+	case models.ComplianceOpGTE, models.ComplianceOpLTE:
+		if _, err := strconv.ParseFloat(policy.Value, 64); err != nil {
+			return fmt.Errorf("比较值必须是数字")
+		}
+	case models.ComplianceOpLevelGTE:
+		if logLevelRank(policy.Value) < 0 {
+			return fmt.Errorf("日志级别只能是 %s", strings.Join(smartdnsLogLevels, "、"))
+		}
+	default:
+		return fmt.Errorf("不支持的比较方式: %s", policy.Operator)
+	}
+
+	switch policy.Severity {
+	case models.ComplianceSeverityInfo, models.ComplianceSeverityWarning, models.ComplianceSeverityCritical:
+	default:
+		return fmt.Errorf("严重程度只能是 info、warning 或 critical")
+	}
+
+	if policy.Directive == models.ComplianceDirectiveServerGroup && policy.FixValue != "" {
+		return fmt.Errorf("server-group 策略不支持修复值，自动修复时按管理端的上游配置完整同步")
+	}
+	return nil
+}
+
+// DescribePolicy 策略要求的可读描述
+func DescribePolicy(policy *models.CompliancePolicy) string {
+	switch policy.Operator {
+	case models.ComplianceOpEquals:
+		return fmt.Sprintf("%s = %s", policy.Directive, policy.Value)
+	case models.ComplianceOpNotEquals:
+		return fmt.Sprintf("%s != %s", policy.Directive, policy.Value)
+	case models.ComplianceOpIn:
+		return fmt.Sprintf("%s 属于 [%s]", policy.Directive, policy.Value)
+	case models.ComplianceOpGTE:
+		return fmt.Sprintf("%s >= %s", policy.Directive, policy.Value)
+	case models.ComplianceOpLTE:
+		return fmt.Sprintf("%s <= %s", policy.Directive, policy.Value)
+	case models.ComplianceOpLevelGTE:
+		return fmt.Sprintf("%s 不低于 %s", policy.Directive, policy.Value)
+	case models.ComplianceOpContains:
+		return fmt.Sprintf("%s 包含 %s", policy.Directive, policy.Value)
+	case models.ComplianceOpPresent:
+		return fmt.Sprintf("必须配置 %s", policy.Directive)
+	case models.ComplianceOpAbsent:
+		return fmt.Sprintf("不允许配置 %s", policy.Directive)
+	}
+	return policy.Directive
+}
+
+// EvaluatePolicy 对解析后的配置求值，返回是否通过和节点上的实际值
+func EvaluatePolicy(policy *models.CompliancePolicy, cfg *models.SmartDNSConfig) (bool, string) {
+	values := configDirectiveValues(cfg, policy.Directive)
+	actual := "未配置"
+	if len(values) > 0 {
+		actual = strings.Join(values, ", ")
+	}
+
+	switch policy.Operator {
+	case models.ComplianceOpPresent:
+		return len(values) > 0, actual
+	case models.ComplianceOpAbsent:
+		return len(values) == 0, actual
+	case models.ComplianceOpContains:
+		for _, value := range values {
+			if value == policy.Value {
+				return true, actual
+			}
+		}
+		return false, actual
+	}
+
+	// 其余比较方式针对单值指令，未配置视为不通过
+	if len(values) == 0 {
+		return false, actual
+	}
+	value := values[0]
+
+	switch policy.Operator {
+	case models.ComplianceOpEquals:
+		return value == policy.Value, actual
+	case models.ComplianceOpNotEquals:
+		return value != policy.Value, actual
+	case models.ComplianceOpIn:
+		for _, allowed := range strings.Split(policy.Value, ",") {
+			if strings.TrimSpace(allowed) == value {
+				return true, actual
+			}
+		}
+		return false, actual
+	case models.ComplianceOpGTE, models.ComplianceOpLTE:
+		number, err := parseConfigNumber(value)
+		if err != nil {
+			return false, actual + "（无法解析为数字）"
+		}
+		limit, _ := strconv.ParseFloat(policy.Value, 64)
+		if policy.Operator == models.ComplianceOpGTE {
+			return number >= limit, actual
+		}
+		return number <= limit, actual
+	case models.ComplianceOpLevelGTE:
+		rank := logLevelRank(value)
+		if rank < 0 {
+			return false, actual + "（未知级别）"
+		}
+		return rank >= logLevelRank(policy.Value), actual
+	}
+	return false, actual
+}
+
+// CheckCompliance 按启用的策略检查节点配置，并清理过期的已解决违规记录
+func (s *ComplianceService) CheckCompliance(ctx context.Context, cfg models.ComplianceCheckConfig) (string, error) {
+	if cfg.RetentionDays <= 0 {
+		cfg.RetentionDays = 90
+	}
+
+	var policies []models.CompliancePolicy
+	if err := s.db.Where("enabled = ?", true).Find(&policies).Error; err != nil {
+		return "", fmt.Errorf("查询合规策略失败: %w", err)
+	}
+	if len(policies) == 0 {
+		return "没有启用的合规策略", nil
+	}
+
+	var nodes []models.Node
+	query := s.db.Model(&models.Node{})
+	if len(cfg.NodeIDs) > 0 {
+		query = query.Where("id IN ?", cfg.NodeIDs)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
+		return "", fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	violating, remediated, failed := 0, 0, 0
+	for i := range nodes {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		check, fixed := s.checkNode(&nodes[i], policies)
+		if check.Error != "" {
+			failed++
+		} else if check.Violations > 0 {
+			violating++
+		}
+		remediated += fixed
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -cfg.RetentionDays)
+	if err := s.db.Where("status = ? AND resolved_at < ?", models.ComplianceViolationResolved, cutoff).
+		Delete(&models.ComplianceViolation{}).Error; err != nil {
+		log.Printf("⚠️ 清理过期合规违规记录失败: %v", err)
+	}
+
+	output := fmt.Sprintf("按 %d 条策略检查 %d 个节点，%d 个不合规", len(policies), len(nodes), violating)
+	if remediated > 0 {
+		output += fmt.Sprintf("，自动修复 %d 项", remediated)
+	}
+	if failed > 0 {
+		output += fmt.Sprintf("，%d 个检查失败", failed)
+	}
+	return output, nil
+}
+
+// CheckNode 按启用的策略立即检查单个节点
+func (s *ComplianceService) CheckNode(node *models.Node) (*models.ComplianceNodeCheck, error) {
+	var policies []models.CompliancePolicy
+	if err := s.db.Where("enabled = ?", true).Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("查询合规策略失败: %w", err)
+	}
+	check, _ := s.checkNode(node, policies)
+	return check, nil
+}
+
+// checkNode 检查节点并更新违规记录，返回检查结果和自动修复的违规数
+func (s *ComplianceService) checkNode(node *models.Node, policies []models.CompliancePolicy) (*models.ComplianceNodeCheck, int) {
+	check := &models.ComplianceNodeCheck{
+		NodeID:    node.ID,
+		NodeName:  node.Name,
+		CheckedAt: time.Now(),
+	}
+
+	var applicable []models.CompliancePolicy
+	for _, policy := range policies {
+		if policyAppliesTo(&policy, node) {
+			applicable = append(applicable, policy)
+		}
+	}
+	check.Policies = len(applicable)
+
+	before, err := s.evaluateNode(node, applicable)
+	if err != nil {
+		check.Error = err.Error()
+		log.Printf("⚠️ 节点 %s 合规检查失败: %v", node.Name, err)
+		if err := s.db.Save(check).Error; err != nil {
+			log.Printf("⚠️ 保存节点合规检查结果失败: %v", err)
+		}
+		return check, 0
+	}
+
+	// 自动修复：完整同步一次后重新检查，remediation 记录每条策略的修复结果（空字符串表示已修复）
+	after := before
+	remediation := make(map[uint]string)
+	fixed := 0
+	if remediable := remediablePolicies(applicable, before); len(remediable) > 0 {
+		if err := s.remediate(node, remediable); err != nil {
+			log.Printf("⚠️ 节点 %s 合规自动修复失败: %v", node.Name, err)
+			for _, policy := range remediable {
+				remediation[policy.ID] = "自动修复失败: " + err.Error()
+			}
+		} else if rechecked, err := s.evaluateNode(node, applicable); err == nil {
+			after = rechecked
+			for _, policy := range remediable {
+				if _, still := after[policy.ID]; still {
+					remediation[policy.ID] = "自动修复后仍不合规"
+				} else {
+					remediation[policy.ID] = ""
+					fixed++
+				}
+			}
+		}
+	}
+
+	s.reconcile(node, applicable, before, after, remediation)
+
+	check.Violations = len(after)
+	if err := s.db.Save(check).Error; err != nil {
+		log.Printf("⚠️ 保存节点合规检查结果失败: %v", err)
+	}
+	return check, fixed
+}
+
+// evaluateNode 读取并解析节点配置，返回未通过的策略及实际值
+func (s *ComplianceService) evaluateNode(node *models.Node, policies []models.CompliancePolicy) (map[uint]string, error) {
+	failures := make(map[uint]string)
+	if len(policies) == 0 {
+		return failures, nil
+	}
+
+	client, err := NewSSHClient(node)
+	if err != nil {
+		return nil, fmt.Errorf("连接节点失败: %w", err)
+	}
+	defer client.Close()
+
+	content, err := client.ReadFile(node.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置失败: %w", err)
+	}
+	cfg, err := NewConfigParser().Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("解析配置失败: %w", err)
+	}
+
+	for i := range policies {
+		if ok, actual := EvaluatePolicy(&policies[i], cfg); !ok {
+			failures[policies[i].ID] = actual
+		}
+	}
+	return failures, nil
+}
+
+// remediate 完整同步节点配置，同步时按策略的修复值修改指令
+func (s *ComplianceService) remediate(node *models.Node, policies []models.CompliancePolicy) error {
+	log.Printf("🔧 节点 %s 有 %d 条合规策略不通过，执行自动修复", node.Name, len(policies))
+	return s.configSyncService.FullSyncToNodeWith(context.Background(), node.ID, func(cfg *models.SmartDNSConfig) {
+		for _, policy := range policies {
+			if policy.Directive == models.ComplianceDirectiveServerGroup {
+				continue
+			}
+			if policy.Operator == models.ComplianceOpAbsent {
+				removeConfigDirective(cfg, policy.Directive)
+			} else if policy.FixValue != "" {
+				setConfigDirective(cfg, policy.Directive, policy.FixValue)
+			}
+		}
+	})
+}
+
+// reconcile 根据检查结果新建、更新或解决违规记录，并通知新出现的违规
+func (s *ComplianceService) reconcile(node *models.Node, policies []models.CompliancePolicy, before, after map[uint]string, remediation map[uint]string) {
+	now := time.Now()
+
+	var open []models.ComplianceViolation
+	s.db.Where("node_id = ? AND status = ?", node.ID, models.ComplianceViolationOpen).Find(&open)
+	openByPolicy := make(map[uint]*models.ComplianceViolation, len(open))
+	for i := range open {
+		openByPolicy[open[i].PolicyID] = &open[i]
+	}
+
+	var created, fixed []models.ComplianceViolation
+	for i := range policies {
+		policy := &policies[i]
+		existing := openByPolicy[policy.ID]
+		result, remediated := remediation[policy.ID]
+
+		if actual, failing := after[policy.ID]; failing {
+			if existing != nil {
+				existing.PolicyName = policy.Name
+				existing.Severity = policy.Severity
+				existing.Expected = DescribePolicy(policy)
+				existing.Actual = actual
+				existing.LastSeenAt = now
+				existing.RemediationError = result
+				s.db.Save(existing)
+				continue
+			}
+			violation := models.ComplianceViolation{
+				PolicyID:         policy.ID,
+				PolicyName:       policy.Name,
+				NodeID:           node.ID,
+				NodeName:         node.Name,
+				Severity:         policy.Severity,
+				Expected:         DescribePolicy(policy),
+				Actual:           actual,
+				Status:           models.ComplianceViolationOpen,
+				FirstSeenAt:      now,
+				LastSeenAt:       now,
+				RemediationError: result,
+			}
+			if err := s.db.Create(&violation).Error; err != nil {
+				log.Printf("⚠️ 保存合规违规记录失败: %v", err)
+				continue
+			}
+			created = append(created, violation)
+			continue
+		}
+
+		if !remediated || result != "" {
+			continue
+		}
+		// 本次检查发现并已自动修复，留下已解决的记录
+		if existing == nil {
+			violation := models.ComplianceViolation{
+				PolicyID:     policy.ID,
+				PolicyName:   policy.Name,
+				NodeID:       node.ID,
+				NodeName:     node.Name,
+				Severity:     policy.Severity,
+				Expected:     DescribePolicy(policy),
+				Actual:       before[policy.ID],
+				Status:       models.ComplianceViolationResolved,
+				FirstSeenAt:  now,
+				LastSeenAt:   now,
+				ResolvedAt:   &now,
+				RemediatedAt: &now,
+			}
+			if err := s.db.Create(&violation).Error; err == nil {
+				fixed = append(fixed, violation)
+			}
+		} else {
+			existing.RemediatedAt = &now
+			fixed = append(fixed, *existing)
+		}
+	}
+
+	// 不再违规（策略已通过、被停用、删除或不再适用于该节点）的记录标记为已解决
+	for i := range open {
+		if _, failing := after[open[i].PolicyID]; failing && policyListed(policies, open[i].PolicyID) {
+			continue
+		}
+		open[i].Status = models.ComplianceViolationResolved
+		open[i].ResolvedAt = &now
+		open[i].RemediationError = ""
+		s.db.Save(&open[i])
+	}
+
+	if len(created) > 0 || len(fixed) > 0 {
+		s.notify(node, created, fixed)
+	}
+}
+
+// notify 发送合规违规通知
+func (s *ComplianceService) notify(node *models.Node, created, fixed []models.ComplianceViolation) {
+	var b strings.Builder
+	if len(created) > 0 {
+		fmt.Fprintf(&b, "节点 %s 有 %d 条合规策略未通过：\n", node.Name, len(created))
+		for _, violation := range created {
+			fmt.Fprintf(&b, "[%s] %s：要求 %s，实际 %s\n", violation.Severity, violation.PolicyName, violation.Expected, violation.Actual)
+			if violation.RemediationError != "" {
+				fmt.Fprintf(&b, "  %s\n", violation.RemediationError)
+			}
+		}
+	}
+	if len(fixed) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "节点 %s 已通过完整同步自动修复 %d 项：\n", node.Name, len(fixed))
+		for _, violation := range fixed {
+			fmt.Fprintf(&b, "%s：%s（修复前 %s）\n", violation.PolicyName, violation.Expected, violation.Actual)
+		}
+	}
+
+	title := "⚠️ 配置合规检查未通过"
+	if len(created) == 0 {
+		title = "🔧 配置合规自动修复"
+	}
+	if err := s.notificationService.SendNotification(node.ID, "compliance_violation", title, b.String()); err != nil {
+		log.Printf("⚠️ 发送合规违规通知失败: %v", err)
+	}
+}
+
+// policyAppliesTo 策略是否适用于节点，未指定节点和标签时适用于所有节点
+func policyAppliesTo(policy *models.CompliancePolicy, node *models.Node) bool {
+	global := (policy.NodeIDs == "" || policy.NodeIDs == "[]") && policy.Tag == ""
+	byNode := policy.NodeIDs != "" && policy.NodeIDs != "[]" && nodeIDsContain(policy.NodeIDs, node.ID)
+	byTag := policy.Tag != "" && nodeHasTag(*node, policy.Tag)
+	return global || byNode || byTag
+}
+
+// policyListed 策略是否在本次检查的策略中
+func policyListed(policies []models.CompliancePolicy, policyID uint) bool {
+	for _, policy := range policies {
+		if policy.ID == policyID {
+			return true
+		}
+	}
+	return false
+}
+
+// remediablePolicies 未通过且开启自动修复的策略
+func remediablePolicies(policies []models.CompliancePolicy, failures map[uint]string) []models.CompliancePolicy {
+	var remediable []models.CompliancePolicy
+	for _, policy := range policies {
+		if _, failing := failures[policy.ID]; failing && policy.AutoRemediate {
+			remediable = append(remediable, policy)
+		}
+	}
+	return remediable
+}
+
+// configDirectiveValues 配置中某个指令的全部取值，server-group 返回所有 server 的分组
+func configDirectiveValues(cfg *models.SmartDNSConfig, name string) []string {
+	var values []string
+	if name == models.ComplianceDirectiveServerGroup {
+		for _, server := range cfg.Servers {
+			values = append(values, server.Groups...)
+		}
+		return values
+	}
+
+	if value, ok := cfg.BasicSettings[name]; ok {
+		values = append(values, value)
+	}
+	for _, directive := range cfg.Directives {
+		if directive.Name == name {
+			values = append(values, directive.Value)
+		}
+	}
+	for _, line := range cfg.OtherLines {
+		if directive, value := splitDirective(strings.TrimSpace(line.Content)); directive == name {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// setConfigDirective 将指令设置为指定值，已有的同名指令会被替换
+func setConfigDirective(cfg *models.SmartDNSConfig, name, value string) {
+	removeConfigDirective(cfg, name)
+	if cfg.BasicSettings == nil {
+		cfg.BasicSettings = make(map[string]string)
+	}
+	cfg.BasicSettings[name] = value
+}
+
+// removeConfigDirective 删除配置中的同名指令
+func removeConfigDirective(cfg *models.SmartDNSConfig, name string) {
+	delete(cfg.BasicSettings, name)
+
+	directives := cfg.Directives[:0]
+	for _, directive := range cfg.Directives {
+		if directive.Name != name {
+			directives = append(directives, directive)
+		}
+	}
+	cfg.Directives = directives
+
+	lines := cfg.OtherLines[:0]
+	for _, line := range cfg.OtherLines {
+		if directive, _ := splitDirective(strings.TrimSpace(line.Content)); directive != name {
+			lines = append(lines, line)
+		}
+	}
+	cfg.OtherLines = lines
+}
+
+// parseConfigNumber 解析配置中的数值，支持 k/m/g 后缀（如 log-size 128k）
+func parseConfigNumber(value string) (float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if fields := strings.Fields(value); len(fields) > 0 {
+		value = fields[0]
+	}
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(value, "k"):
+		multiplier, value = 1024, strings.TrimSuffix(value, "k")
+	case strings.HasSuffix(value, "m"):
+		multiplier, value = 1024*1024, strings.TrimSuffix(value, "m")
+	case strings.HasSuffix(value, "g"):
+		multiplier, value = 1024*1024*1024, strings.TrimSuffix(value, "g")
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	return number * multiplier, nil
+}
+
+// logLevelRank 日志级别的序号，未知级别返回 -1
+func logLevelRank(level string) int {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "warning" {
+		level = "warn"
+	}
+	for i, name := range smartdnsLogLevels {
+		if name == level {
+			return i
+		}
+	}
+	return -1
+}
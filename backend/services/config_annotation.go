@@ -0,0 +1,168 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// annotationMarker 受管指令行尾溯源注释的标记，形如:
+//
+//	address /a.com/1.2.3.4 #@sdm kind=address id=12 updated=2026-01-02T15:04:05 by=admin
+const annotationMarker = "#@sdm"
+
+// annotationTimeLayout 注释中修改时间的格式
+const annotationTimeLayout = "2006-01-02T15:04:05"
+
+// configAnnotationsEnabled 是否在生成的配置中写入溯源注释（CONFIG_ANNOTATIONS）
+func configAnnotationsEnabled() bool {
+	enabled, _ := strconv.ParseBool(config.GetConfig().ConfigAnnotations)
+	return enabled
+}
+
+// formatAnnotation 生成追加在指令行尾的溯源注释（含前导空格）
+func formatAnnotation(annotation models.ConfigAnnotation) string {
+	text := fmt.Sprintf(" %s kind=%s id=%d", annotationMarker, annotation.Kind, annotation.ID)
+	if annotation.UpdatedAt != "" {
+		text += " updated=" + annotation.UpdatedAt
+	}
+	if annotation.Author != "" {
+		text += " by=" + strings.ReplaceAll(annotation.Author, " ", "_")
+	}
+	return text
+}
+
+// stripAnnotation 去掉行尾的溯源注释，返回指令本身和解析出的注释
+func stripAnnotation(line string) (string, *models.ConfigAnnotation) {
+	idx := strings.Index(line, annotationMarker)
+	if idx < 0 {
+		return line, nil
+	}
+
+	annotation := &models.ConfigAnnotation{}
+	for _, field := range strings.Fields(line[idx+len(annotationMarker):]) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "kind":
+			annotation.Kind = value
+		case "id":
+			id, _ := strconv.ParseUint(value, 10, 32)
+			annotation.ID = uint(id)
+		case "updated":
+			annotation.UpdatedAt = value
+		case "by":
+			annotation.Author = value
+		}
+	}
+	return strings.TrimSpace(line[:idx]), annotation
+}
+
+// annotatedID 注释属于指定类型的记录时返回记录 ID
+func annotatedID(annotation *models.ConfigAnnotation, kind string) uint {
+	if annotation == nil || annotation.Kind != kind {
+		return 0
+	}
+	return annotation.ID
+}
+
+// annotationIndex 生成配置时按指令查找对应的管理记录
+type annotationIndex struct {
+	byKey   map[string]models.ConfigAnnotation // kind|匹配键（域名、地址、名称等）
+	byID    map[string]models.ConfigAnnotation // kind#id
+	authors map[string]string                  // kind#id -> 最后修改人
+}
+
+// loadAnnotationIndex 加载受管记录及其最后修改人
+func loadAnnotationIndex() *annotationIndex {
+	index := &annotationIndex{
+		byKey:   make(map[string]models.ConfigAnnotation),
+		byID:    make(map[string]models.ConfigAnnotation),
+		authors: make(map[string]string),
+	}
+	if database.DB == nil {
+		return index
+	}
+
+	var audits []models.AuditLog
+	database.DB.Select("entity_type", "entity_id", "username").
+		Where("id IN (?)", database.DB.Model(&models.AuditLog{}).Select("MAX(id)").Group("entity_type, entity_id")).
+		Find(&audits)
+	for _, audit := range audits {
+		index.authors[fmt.Sprintf("%s#%d", audit.EntityType, audit.EntityID)] = audit.Username
+	}
+
+	type record struct {
+		ID        uint
+		MatchKey  string
+		UpdatedAt time.Time
+	}
+	load := func(kind string, model interface{}, keyColumn string) {
+		var records []record
+		database.DB.Model(model).Select("id", keyColumn+" AS match_key", "updated_at").
+			Where("enabled = ?", true).Order("updated_at").Scan(&records)
+		for _, r := range records {
+			// 同一匹配键有多条记录时（如合并写入的地址映射）取最后修改的一条
+			index.add(kind, r.ID, r.MatchKey, r.UpdatedAt)
+		}
+	}
+	load(models.AuditEntityAddress, &models.AddressMap{}, "domain")
+	load(models.AuditEntityServer, &models.DNSServer{}, "address")
+	load(models.AuditEntityDomainSet, &models.DomainSet{}, "name")
+	load(models.AuditEntityDomainRule, &models.DomainRule{}, "domain")
+	load(models.AuditEntityNameserver, &models.Nameserver{}, "domain")
+	load(models.AuditEntityClientRule, &models.ClientRule{}, "client")
+
+	return index
+}
+
+func (i *annotationIndex) add(kind string, id uint, key string, updatedAt time.Time) {
+	idKey := fmt.Sprintf("%s#%d", kind, id)
+	annotation := models.ConfigAnnotation{
+		Kind:      kind,
+		ID:        id,
+		UpdatedAt: updatedAt.Format(annotationTimeLayout),
+		Author:    i.authors[idKey],
+	}
+	i.byKey[kind+"|"+key] = annotation
+	i.byID[idKey] = annotation
+}
+
+// suffix 返回指令对应记录的溯源注释，找不到受管记录时返回空字符串
+func (i *annotationIndex) suffix(kind string, id uint, key string) string {
+	if i == nil {
+		return ""
+	}
+	if id != 0 {
+		if annotation, ok := i.byID[fmt.Sprintf("%s#%d", kind, id)]; ok {
+			return formatAnnotation(annotation)
+		}
+	}
+	if annotation, ok := i.byKey[kind+"|"+key]; ok {
+		return formatAnnotation(annotation)
+	}
+	return ""
+}
+
+// recordAnnotation 单条记录逐行写入节点时使用的溯源注释，未启用时返回空字符串
+func recordAnnotation(kind string, id uint, updatedAt time.Time) string {
+	if !configAnnotationsEnabled() || id == 0 {
+		return ""
+	}
+
+	annotation := models.ConfigAnnotation{
+		Kind:      kind,
+		ID:        id,
+		UpdatedAt: updatedAt.Format(annotationTimeLayout),
+	}
+	var audit models.AuditLog
+	if err := database.DB.Where("entity_type = ? AND entity_id = ?", kind, id).
+		Order("id DESC").First(&audit).Error; err == nil {
+		annotation.Author = audit.Username
+	}
+	return formatAnnotation(annotation)
+}
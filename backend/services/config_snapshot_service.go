@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// ConfigSnapshotService 节点配置快照服务，定期读取节点配置，内容变化时保存快照，
+// 用于发现绕过管理端直接在节点上做的修改
+type ConfigSnapshotService struct {
+	db     *gorm.DB
+	config *config.Config
+}
+
+// NewConfigSnapshotService 创建节点配置快照服务
+func NewConfigSnapshotService(db *gorm.DB, config *config.Config) (*ConfigSnapshotService, error) {
+	return &ConfigSnapshotService{
+		db:     db,
+		config: config,
+	}, nil
+}
+
+// CaptureSnapshots 采集节点配置快照并清理过期快照
+func (s *ConfigSnapshotService) CaptureSnapshots(ctx context.Context, cfg models.ConfigSnapshotConfig) (string, error) {
+	if cfg.RetentionDays <= 0 {
+		cfg.RetentionDays = 90
+	}
+
+	var nodes []models.Node
+	query := s.db.Model(&models.Node{})
+	if len(cfg.NodeIDs) > 0 {
+		query = query.Where("id IN ?", cfg.NodeIDs)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
+		return "", fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	changed, outOfBand, failed := 0, 0, 0
+	for i := range nodes {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		snapshot, created, err := s.CaptureNode(&nodes[i])
+		if err != nil {
+			failed++
+			log.Printf("⚠️ 节点 %s 配置快照采集失败: %v", nodes[i].Name, err)
+			continue
+		}
+		if created {
+			changed++
+			if snapshot.OutOfBand {
+				outOfBand++
+			}
+		}
+	}
+
+	s.prune(cfg.RetentionDays)
+
+	output := fmt.Sprintf("采集 %d 个节点，%d 个配置有变化", len(nodes), changed)
+	if outOfBand > 0 {
+		output += fmt.Sprintf("（%d 个为节点上直接修改）", outOfBand)
+	}
+	if failed > 0 {
+		output += fmt.Sprintf("，%d 个采集失败", failed)
+	}
+	return output, nil
+}
+
+// CaptureNode 读取节点当前配置并记录快照
+func (s *ConfigSnapshotService) CaptureNode(node *models.Node) (*models.NodeConfigSnapshot, bool, error) {
+	client, err := NewSSHClient(node)
+	if err != nil {
+		return nil, false, fmt.Errorf("连接节点失败: %w", err)
+	}
+	defer client.Close()
+
+	content, err := client.ReadFile(node.ConfigPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("读取配置失败: %w", err)
+	}
+	return s.Record(node, content, models.ConfigSnapshotSourceScheduled)
+}
+
+// Record 记录节点配置，内容与最新快照相同时只更新确认时间，返回快照以及是否新建。
+// 读取到的配置与最新快照不同说明在管理端之外被修改过（管理端写入时会先记录快照）
+func (s *ConfigSnapshotService) Record(node *models.Node, content, source string) (*models.NodeConfigSnapshot, bool, error) {
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	var latest models.NodeConfigSnapshot
+	hasLatest := s.db.Where("node_id = ?", node.ID).Order("id desc").First(&latest).Error == nil
+	if hasLatest && latest.Hash == hash {
+		s.db.Model(&latest).Update("last_seen_at", now)
+		latest.LastSeenAt = now
+		return &latest, false, nil
+	}
+
+	snapshot := &models.NodeConfigSnapshot{
+		NodeID:     node.ID,
+		NodeName:   node.Name,
+		Hash:       hash,
+		Content:    content,
+		Size:       len(content),
+		Source:     source,
+		CapturedAt: now,
+		LastSeenAt: now,
+	}
+	if hasLatest {
+		added, removed, changed := DiffConfigLines(latest.Content, content)
+		snapshot.AddedCount = len(added)
+		snapshot.RemovedCount = len(removed)
+		snapshot.ChangedCount = len(changed)
+		// 第一份快照作为基线，不算直接修改
+		snapshot.OutOfBand = source == models.ConfigSnapshotSourceScheduled
+	}
+
+	if err := s.db.Create(snapshot).Error; err != nil {
+		return nil, false, fmt.Errorf("保存配置快照失败: %w", err)
+	}
+	if snapshot.OutOfBand {
+		log.Printf("📸 节点 %s 的配置在管理端之外被修改（快照 #%d）", node.Name, snapshot.ID)
+	}
+	return snapshot, true, nil
+}
+
+// OutOfBandSince 节点当前配置是否包含最近一次管理端写入之后的直接修改，返回最早发现修改的快照
+func (s *ConfigSnapshotService) OutOfBandSince(nodeID uint) *models.NodeConfigSnapshot {
+	var latest models.NodeConfigSnapshot
+	if err := s.db.Where("node_id = ?", nodeID).Order("id desc").First(&latest).Error; err != nil {
+		return nil
+	}
+
+	query := s.db.Where("node_id = ? AND out_of_band = ?", nodeID, true)
+	var manager models.NodeConfigSnapshot
+	if err := s.db.Where("node_id = ? AND source = ?", nodeID, models.ConfigSnapshotSourceManager).
+		Order("id desc").First(&manager).Error; err == nil {
+		// 直接修改后又被改回管理端写入的内容
+		if manager.Hash == latest.Hash {
+			return nil
+		}
+		query = query.Where("id > ?", manager.ID)
+	}
+
+	var first models.NodeConfigSnapshot
+	if err := query.Order("id asc").First(&first).Error; err != nil {
+		return nil
+	}
+	return &first
+}
+
+// GetDiff 获取快照内容及与上一快照的差异
+func (s *ConfigSnapshotService) GetDiff(id uint) (*models.ConfigSnapshotDiff, error) {
+	var snapshot models.NodeConfigSnapshot
+	if err := s.db.First(&snapshot, id).Error; err != nil {
+		return nil, err
+	}
+
+	diff := &models.ConfigSnapshotDiff{
+		Snapshot: snapshot,
+		Added:    []string{},
+		Removed:  []string{},
+		Changed:  []models.DriftLineChange{},
+	}
+	var previous models.NodeConfigSnapshot
+	if err := s.db.Where("node_id = ? AND id < ?", snapshot.NodeID, snapshot.ID).
+		Order("id desc").First(&previous).Error; err == nil {
+		diff.Previous = &previous
+		diff.Added, diff.Removed, diff.Changed = DiffConfigLines(previous.Content, snapshot.Content)
+	}
+	return diff, nil
+}
+
+// prune 清理过期快照，每个节点的最新快照始终保留
+func (s *ConfigSnapshotService) prune(retentionDays int) {
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	latest := s.db.Model(&models.NodeConfigSnapshot{}).Select("MAX(id)").Group("node_id")
+	if err := s.db.Where("captured_at < ? AND id NOT IN (?)", cutoff, latest).
+		Delete(&models.NodeConfigSnapshot{}).Error; err != nil {
+		log.Printf("⚠️ 清理过期配置快照失败: %v", err)
+	}
+}
+
+// recordManagerWrite 管理端写入节点配置后记录快照，之后采集到的不同内容即为直接修改
+func recordManagerWrite(node *models.Node, content string) {
+	if database.DB == nil {
+		return
+	}
+	service, _ := NewConfigSnapshotService(database.DB, config.GetConfig())
+	if _, _, err := service.Record(node, content, models.ConfigSnapshotSourceManager); err != nil {
+		log.Printf("⚠️ 记录节点 %s 配置快照失败: %v", node.Name, err)
+	}
+}
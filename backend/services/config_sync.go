@@ -1,25 +1,33 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"smartdns-manager/database"
 	"smartdns-manager/models"
 )
 
 type ConfigSyncService struct {
-	notificationService *NotificationService
+	notificationService  *NotificationService
+	domainSetFileService *DomainSetFileService
+	groupBlockService    *GroupBlockService
+	clientRuleService    *ClientRuleService
 }
 
 func NewConfigSyncService() *ConfigSyncService {
 	return &ConfigSyncService{
-		notificationService: NewNotificationService(),
+		notificationService:  NewNotificationService(),
+		domainSetFileService: NewDomainSetFileService(),
+		groupBlockService:    NewGroupBlockService(),
+		clientRuleService:    NewClientRuleService(),
 	}
 }
 
 // SyncAddressToNodes 同步地址映射到节点
-func (s *ConfigSyncService) SyncAddressToNodes(address *models.AddressMap) error {
+func (s *ConfigSyncService) SyncAddressToNodes(ctx context.Context, address *models.AddressMap) error {
 	if !address.Enabled {
 		return nil // 未启用的不同步
 	}
@@ -29,13 +37,14 @@ func (s *ConfigSyncService) SyncAddressToNodes(address *models.AddressMap) error
 	if err != nil {
 		return err
 	}
+	nodes = nodesInOrganization(nodes, address.OrganizationID)
 
 	// 并发同步到各个节点
 	errChan := make(chan error, len(nodes))
 
 	for _, node := range nodes {
 		go func(n models.Node) {
-			err := s.syncAddressToNode(address, &n)
+			err := s.syncAddressToNode(ctx, address, &n)
 			errChan <- err
 		}(node)
 	}
@@ -56,7 +65,7 @@ func (s *ConfigSyncService) SyncAddressToNodes(address *models.AddressMap) error
 }
 
 // syncAddressToNode 同步地址映射到单个节点
-func (s *ConfigSyncService) syncAddressToNode(address *models.AddressMap, node *models.Node) error {
+func (s *ConfigSyncService) syncAddressToNode(ctx context.Context, address *models.AddressMap, node *models.Node) error {
 	// 生成显示文本
 	var displayText string
 	if address.Type == "cname" {
@@ -65,7 +74,7 @@ func (s *ConfigSyncService) syncAddressToNode(address *models.AddressMap, node *
 		displayText = fmt.Sprintf("%s -> %s", address.Domain, address.IP)
 	}
 
-	log.Printf("开始同步到节点: %s (%s)", node.Name, displayText)
+	slog.InfoContext(ctx, "开始同步地址映射", "node", node.Name, "address", displayText)
 
 	syncLog := &models.ConfigSyncLog{
 		NodeID:  node.ID,
@@ -79,9 +88,7 @@ func (s *ConfigSyncService) syncAddressToNode(address *models.AddressMap, node *
 	// 连接节点
 	client, err := NewSSHClient(node)
 	if err != nil {
-		syncLog.Status = "failed"
-		syncLog.Error = err.Error()
-		database.DB.Save(syncLog)
+		failSyncLog(syncLog, err)
 		s.notificationService.SendNotification(node.ID, "sync_failed", "❌ 配置同步失败",
 			fmt.Sprintf("%s 同步失败\n\n错误: %s", displayText, err.Error()))
 		return err
@@ -91,9 +98,7 @@ func (s *ConfigSyncService) syncAddressToNode(address *models.AddressMap, node *
 	// 读取当前配置
 	currentConfig, err := client.ReadFile(node.ConfigPath)
 	if err != nil {
-		syncLog.Status = "failed"
-		syncLog.Error = err.Error()
-		database.DB.Save(syncLog)
+		failSyncLog(syncLog, err)
 		return err
 	}
 
@@ -101,20 +106,23 @@ func (s *ConfigSyncService) syncAddressToNode(address *models.AddressMap, node *
 	parser := NewConfigParser()
 	config, err := parser.Parse(currentConfig)
 	if err != nil {
-		syncLog.Status = "failed"
-		syncLog.Error = err.Error()
-		database.DB.Save(syncLog)
+		failSyncLog(syncLog, err)
 		return err
 	}
 
+	// 同一域名可能有多条地址映射，合并后整体写入
+	target := s.mergedAddressForNode(address, node.ID)
+
 	// 检查是否已存在并更新
 	found := false
 	for i, addr := range config.Addresses {
-		if addr.Domain == address.Domain {
+		if addr.Domain == target.Domain {
 			// 更新现有记录
-			config.Addresses[i].IP = address.IP
-			config.Addresses[i].CNAME = address.CNAME
-			config.Addresses[i].Type = address.Type
+			config.Addresses[i].IP = target.IP
+			config.Addresses[i].CNAME = target.CNAME
+			config.Addresses[i].Type = target.Type
+			config.Addresses[i].TTL = target.TTL
+			config.Addresses[i].NoServeExpired = target.NoServeExpired
 			found = true
 			break
 		}
@@ -122,7 +130,7 @@ func (s *ConfigSyncService) syncAddressToNode(address *models.AddressMap, node *
 
 	// 如果不存在，添加新记录
 	if !found {
-		config.Addresses = append(config.Addresses, *address)
+		config.Addresses = append(config.Addresses, target)
 	}
 
 	// 生成新配置
@@ -131,23 +139,17 @@ func (s *ConfigSyncService) syncAddressToNode(address *models.AddressMap, node *
 	// 创建备份
 	_, err = client.CreateBackup(node.ConfigPath)
 	if err != nil {
-		log.Printf("警告: 创建备份失败: %v", err)
+		slog.WarnContext(ctx, "同步前创建备份失败", "node", node.Name, "error", err)
 	}
 
 	// 写入新配置
 	err = client.WriteFile(node.ConfigPath, newConfig)
 	if err != nil {
-		syncLog.Status = "failed"
-		syncLog.Error = err.Error()
-		database.DB.Save(syncLog)
+		failSyncLog(syncLog, err)
 		return err
 	}
 
-	// 重载服务
-	// err = client.ReloadService("smartdns")
-	// if err != nil {
-	// 	log.Printf("警告: 重载服务失败: %v", err)
-	// }
+	reloadAfterSync(ctx, client, node)
 
 	syncLog.Status = "success"
 	database.DB.Save(syncLog)
@@ -155,12 +157,12 @@ func (s *ConfigSyncService) syncAddressToNode(address *models.AddressMap, node *
 	s.notificationService.SendNotification(node.ID, "sync_success", " 配置同步成功",
 		fmt.Sprintf("%s 已成功同步到节点", displayText))
 
-	log.Printf(" 成功同步到节点: %s", node.Name)
+	slog.InfoContext(ctx, "地址映射同步成功", "node", node.Name)
 	return nil
 }
 
 // SyncServerToNodes 同步DNS服务器到节点
-func (s *ConfigSyncService) SyncServerToNodes(server *models.DNSServer) error {
+func (s *ConfigSyncService) SyncServerToNodes(ctx context.Context, server *models.DNSServer) error {
 	if !server.Enabled {
 		return nil
 	}
@@ -174,7 +176,7 @@ func (s *ConfigSyncService) SyncServerToNodes(server *models.DNSServer) error {
 
 	for _, node := range nodes {
 		go func(n models.Node) {
-			err := s.syncServerToNode(server, &n)
+			err := s.syncServerToNode(ctx, server, &n)
 			errChan <- err
 		}(node)
 	}
@@ -194,8 +196,8 @@ func (s *ConfigSyncService) SyncServerToNodes(server *models.DNSServer) error {
 }
 
 // syncServerToNode 同步DNS服务器到单个节点
-func (s *ConfigSyncService) syncServerToNode(server *models.DNSServer, node *models.Node) error {
-	log.Printf("开始同步DNS服务器到节点: %s (%s)", node.Name, server.Address)
+func (s *ConfigSyncService) syncServerToNode(ctx context.Context, server *models.DNSServer, node *models.Node) error {
+	slog.InfoContext(ctx, "开始同步DNS服务器", "node", node.Name, "server", server.Address)
 
 	syncLog := &models.ConfigSyncLog{
 		NodeID:  node.ID,
@@ -208,27 +210,21 @@ func (s *ConfigSyncService) syncServerToNode(server *models.DNSServer, node *mod
 
 	client, err := NewSSHClient(node)
 	if err != nil {
-		syncLog.Status = "failed"
-		syncLog.Error = err.Error()
-		database.DB.Save(syncLog)
+		failSyncLog(syncLog, err)
 		return err
 	}
 	defer client.Close()
 
 	currentConfig, err := client.ReadFile(node.ConfigPath)
 	if err != nil {
-		syncLog.Status = "failed"
-		syncLog.Error = err.Error()
-		database.DB.Save(syncLog)
+		failSyncLog(syncLog, err)
 		return err
 	}
 
 	parser := NewConfigParser()
 	config, err := parser.Parse(currentConfig)
 	if err != nil {
-		syncLog.Status = "failed"
-		syncLog.Error = err.Error()
-		database.DB.Save(syncLog)
+		failSyncLog(syncLog, err)
 		return err
 	}
 
@@ -250,34 +246,35 @@ func (s *ConfigSyncService) syncServerToNode(server *models.DNSServer, node *mod
 
 	_, err = client.CreateBackup(node.ConfigPath)
 	if err != nil {
-		log.Printf("警告: 创建备份失败: %v", err)
+		slog.WarnContext(ctx, "同步前创建备份失败", "node", node.Name, "error", err)
 	}
 
 	err = client.WriteFile(node.ConfigPath, newConfig)
 	if err != nil {
-		syncLog.Status = "failed"
-		syncLog.Error = err.Error()
-		database.DB.Save(syncLog)
+		failSyncLog(syncLog, err)
 		return err
 	}
 
+	reloadAfterSync(ctx, client, node)
+
 	syncLog.Status = "success"
 	database.DB.Save(syncLog)
 
-	log.Printf(" 成功同步DNS服务器到节点: %s", node.Name)
+	slog.InfoContext(ctx, "DNS服务器同步成功", "node", node.Name)
 	return nil
 }
 
 // DeleteAddressFromNodes 从节点删除地址映射
-func (s *ConfigSyncService) DeleteAddressFromNodes(address *models.AddressMap) error {
+func (s *ConfigSyncService) DeleteAddressFromNodes(ctx context.Context, address *models.AddressMap) error {
 	nodes, err := s.getTargetNodes(address.NodeIDs)
 	if err != nil {
 		return err
 	}
+	nodes = nodesInOrganization(nodes, address.OrganizationID)
 
 	for _, node := range nodes {
-		if err := s.deleteAddressFromNode(address, &node); err != nil {
-			log.Printf("删除地址映射失败 (节点: %s): %v", node.Name, err)
+		if err := s.deleteAddressFromNode(ctx, address, &node); err != nil {
+			slog.ErrorContext(ctx, "删除地址映射失败", "node", node.Name, "error", err)
 		}
 	}
 
@@ -285,7 +282,7 @@ func (s *ConfigSyncService) DeleteAddressFromNodes(address *models.AddressMap) e
 }
 
 // deleteAddressFromNode 从单个节点删除地址映射
-func (s *ConfigSyncService) deleteAddressFromNode(address *models.AddressMap, node *models.Node) error {
+func (s *ConfigSyncService) deleteAddressFromNode(ctx context.Context, address *models.AddressMap, node *models.Node) error {
 	client, err := NewSSHClient(node)
 	if err != nil {
 		return err
@@ -303,10 +300,20 @@ func (s *ConfigSyncService) deleteAddressFromNode(address *models.AddressMap, no
 		return err
 	}
 
-	// 过滤掉要删除的地址
+	// 过滤掉要删除的地址，同一域名还有其他 IP 时只移除被删除的 IP
 	newAddresses := []models.AddressMap{}
 	for _, addr := range config.Addresses {
-		if addr.Domain != address.Domain || addr.IP != address.IP {
+		if addr.Domain != address.Domain {
+			newAddresses = append(newAddresses, addr)
+			continue
+		}
+		if addr.Type == "cname" || address.Type == "cname" {
+			if addr.Type != address.Type {
+				newAddresses = append(newAddresses, addr)
+			}
+			continue
+		}
+		if addr.IP = removeAddressIPs(addr.IP, address.IP); addr.IP != "" {
 			newAddresses = append(newAddresses, addr)
 		}
 	}
@@ -315,15 +322,25 @@ func (s *ConfigSyncService) deleteAddressFromNode(address *models.AddressMap, no
 	newConfig := parser.Generate(config)
 
 	client.CreateBackup(node.ConfigPath)
-	return client.WriteFile(node.ConfigPath, newConfig)
+	if err := client.WriteFile(node.ConfigPath, newConfig); err != nil {
+		return err
+	}
+
+	reloadAfterSync(ctx, client, node)
+	return nil
 }
 
-func (s *ConfigSyncService) FullSyncToNode(nodeID uint) error {
+func (s *ConfigSyncService) FullSyncToNode(ctx context.Context, nodeID uint) error {
+	return s.FullSyncToNodeWith(ctx, nodeID, nil)
+}
+
+// FullSyncToNodeWith 完整同步，mutate 不为空时在生成配置前对合并后的配置做额外修改（如合规修复）
+func (s *ConfigSyncService) FullSyncToNodeWith(ctx context.Context, nodeID uint, mutate func(config *models.SmartDNSConfig)) error {
 	var node models.Node
 	if err := database.DB.First(&node, nodeID).Error; err != nil {
 		return err
 	}
-	log.Printf("开始完整同步配置到节点: %s", node.Name)
+	slog.InfoContext(ctx, "开始完整同步配置", "node", node.Name)
 
 	// 连接节点
 	client, err := NewSSHClient(&node)
@@ -345,6 +362,42 @@ func (s *ConfigSyncService) FullSyncToNode(nodeID uint) error {
 		return err
 	}
 
+	// 合并数据库中的配置
+	config = s.BuildExpectedConfig(config, nodeID)
+	if mutate != nil {
+		mutate(config)
+	}
+
+	// 分发域名集文件并更新引用
+	fileResult := s.domainSetFileService.SyncFilesToNode(client, &node, config)
+	slog.InfoContext(ctx, "域名集文件同步完成", "node", node.Name,
+		"uploaded", len(fileResult.Uploaded), "skipped", len(fileResult.Skipped), "failed", len(fileResult.Failed))
+
+	// 创建备份
+	backupPath, err := client.CreateBackup(node.ConfigPath)
+	if err != nil {
+		slog.WarnContext(ctx, "同步前创建备份失败", "node", node.Name, "error", err)
+	} else {
+		slog.InfoContext(ctx, "配置已备份", "node", node.Name, "path", backupPath)
+	}
+
+	// 生成新配置
+	newConfig := parser.Generate(config)
+
+	// 写入配置
+	err = client.WriteFile(node.ConfigPath, newConfig)
+	if err != nil {
+		return err
+	}
+
+	reloadAfterSync(ctx, client, &node)
+
+	slog.InfoContext(ctx, "完整同步成功", "node", node.Name)
+	return nil
+}
+
+// BuildExpectedConfig 在节点现有配置基础上合并数据库中的配置，得到完整同步后应有的配置
+func (s *ConfigSyncService) BuildExpectedConfig(config *models.SmartDNSConfig, nodeID uint) *models.SmartDNSConfig {
 	// 获取数据库中的配置
 	var dbAddresses []models.AddressMap
 	database.DB.Where("enabled = ?", true).Find(&dbAddresses)
@@ -357,27 +410,104 @@ func (s *ConfigSyncService) FullSyncToNode(nodeID uint) error {
 	// 合并配置：保留现有的，添加数据库中的
 	config = s.mergeConfigs(config, targetServers, targetAddresses)
 
-	// 创建备份
-	backupPath, err := client.CreateBackup(node.ConfigPath)
-	if err != nil {
-		log.Printf("警告: 创建备份失败: %v", err)
-	} else {
-		log.Printf("配置已备份到: %s", backupPath)
+	// 写入分组配置块
+	if applied := s.groupBlockService.ApplyToConfig(config, nodeID); applied > 0 {
+		log.Printf("应用分组配置块: %d 个", applied)
 	}
 
-	// 生成新配置
-	newConfig := parser.Generate(config)
+	// 写入客户端规则
+	if applied := s.clientRuleService.ApplyToConfig(config, nodeID); applied > 0 {
+		log.Printf("应用客户端规则: %d 条", applied)
+	}
 
-	// 写入配置
-	err = client.WriteFile(node.ConfigPath, newConfig)
+	return config
+}
+
+// ApplyNodeConfig 按节点的重载方式使配置生效，重载不可用时回退为重启
+func ApplyNodeConfig(ctx context.Context, client *SSHClient, node *models.Node) error {
+	method, err := client.ReloadService("smartdns", node.ReloadMode)
 	if err != nil {
 		return err
 	}
-
-	log.Printf(" 完整同步成功: %s", node.Name)
+	if node.ReloadMode != "" && method != node.ReloadMode {
+		slog.WarnContext(ctx, "无法按配置方式重载，已回退为重启", "node", node.Name, "reload_mode", node.ReloadMode)
+	}
 	return nil
 }
 
+// reloadAfterSync 同步写入配置后，选择平滑重载的节点自动生效；restart 方式的节点仍需手动重启
+//
+// 节点不在维护窗口内时推迟到下一个窗口重载。
+func reloadAfterSync(ctx context.Context, client *SSHClient, node *models.Node) {
+	if node.ReloadMode == "" || node.ReloadMode == models.ReloadModeRestart {
+		return
+	}
+	if deferred, _ := NewMaintenanceService().DeferNodeAction(node, models.DeferredActionReload, "配置同步", false); deferred {
+		return
+	}
+	if err := ApplyNodeConfig(ctx, client, node); err != nil {
+		slog.WarnContext(ctx, "重载服务失败", "node", node.Name, "error", err)
+	}
+}
+
+// updateNodeConfig 读取节点配置，经 mutate 修改后写回，并记录同步日志
+func updateNodeConfig(ctx context.Context, notificationService *NotificationService, node *models.Node, syncType, action, name string, mutate func(config *models.SmartDNSConfig)) {
+	slog.InfoContext(ctx, "开始同步配置", "node", node.Name, "type", syncType, "name", name)
+
+	syncLog := &models.ConfigSyncLog{
+		NodeID:  node.ID,
+		Action:  action,
+		Type:    syncType,
+		Content: name,
+		Status:  "pending",
+	}
+	database.DB.Create(syncLog)
+
+	fail := func(err error) {
+		failSyncLogWithRetry(ctx, syncLog, err)
+		slog.ErrorContext(ctx, "同步配置失败", "node", node.Name, "type", syncType, "error", err)
+		notificationService.SendNotification(node.ID, "sync_failed", "❌ 配置同步失败",
+			fmt.Sprintf("%s 同步失败\n\n错误: %s", name, err.Error()))
+	}
+
+	client, err := NewSSHClient(node)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer client.Close()
+
+	content, err := client.ReadFile(node.ConfigPath)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	parser := NewConfigParser()
+	config, err := parser.Parse(content)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	mutate(config)
+
+	if _, err := client.CreateBackup(node.ConfigPath); err != nil {
+		slog.WarnContext(ctx, "同步前创建备份失败", "node", node.Name, "error", err)
+	}
+
+	if err := client.WriteFile(node.ConfigPath, parser.Generate(config)); err != nil {
+		fail(err)
+		return
+	}
+
+	reloadAfterSync(ctx, client, node)
+
+	syncLog.Status = "success"
+	database.DB.Save(syncLog)
+	slog.InfoContext(ctx, "配置同步成功", "node", node.Name, "type", syncType, "name", name)
+}
+
 // 合并配置的辅助方法
 func (s *ConfigSyncService) mergeConfigs(existingConfig *models.SmartDNSConfig, dbServers []models.DNSServer, dbAddresses []models.AddressMap) *models.SmartDNSConfig {
 	// 合并服务器配置
@@ -398,14 +528,20 @@ func (s *ConfigSyncService) mergeConfigs(existingConfig *models.SmartDNSConfig,
 		}
 	}
 
-	// 合并地址映射配置
+	// 合并地址映射配置，同一域名的多条记录合并为一条
+	fromDB := make(map[string]bool)
 	for _, dbAddr := range dbAddresses {
 		found := false
 		// 检查是否已存在（按域名匹配）
 		for i, existing := range existingConfig.Addresses {
 			if existing.Domain == dbAddr.Domain {
-				// 更新现有的
-				existingConfig.Addresses[i] = dbAddr
+				if fromDB[dbAddr.Domain] && existing.Type != "cname" && dbAddr.Type != "cname" {
+					existingConfig.Addresses[i].IP = mergeAddressIPs(existing.IP, dbAddr.IP)
+				} else {
+					// 更新现有的
+					existingConfig.Addresses[i] = dbAddr
+				}
+				fromDB[dbAddr.Domain] = true
 				found = true
 				break
 			}
@@ -413,12 +549,28 @@ func (s *ConfigSyncService) mergeConfigs(existingConfig *models.SmartDNSConfig,
 		// 如果不存在，添加新的
 		if !found {
 			existingConfig.Addresses = append(existingConfig.Addresses, dbAddr)
+			fromDB[dbAddr.Domain] = true
 		}
 	}
 
 	return existingConfig
 }
 
+// mergedAddressForNode 合并同一域名下应用到该节点的其他已启用地址映射的 IP
+func (s *ConfigSyncService) mergedAddressForNode(address *models.AddressMap, nodeID uint) models.AddressMap {
+	merged := *address
+	if address.Type == "cname" {
+		return merged
+	}
+
+	var others []models.AddressMap
+	database.DB.Where("domain = ? AND id <> ? AND enabled = ? AND type <> ?", address.Domain, address.ID, true, "cname").Find(&others)
+	for _, other := range s.filterConfigForNode(others, nodeID) {
+		merged.IP = mergeAddressIPs(merged.IP, other.IP)
+	}
+	return merged
+}
+
 // getTargetNodes 获取目标节点列表
 func (s *ConfigSyncService) getTargetNodes(nodeIDsJSON string) ([]models.Node, error) {
 	var nodes []models.Node
@@ -438,11 +590,31 @@ func (s *ConfigSyncService) getTargetNodes(nodeIDsJSON string) ([]models.Node, e
 	return nodes, nil
 }
 
-// filterConfigForNode 过滤出应用到指定节点的配置
+// nodesInOrganization 只保留属于指定组织的节点，orgID 为 0（未分配组织的配置）时不过滤
+func nodesInOrganization(nodes []models.Node, orgID uint) []models.Node {
+	if orgID == 0 {
+		return nodes
+	}
+	result := make([]models.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if node.OrganizationID == orgID {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// filterConfigForNode 过滤出应用到指定节点的配置，属于其他组织的配置不会应用到该节点
 func (s *ConfigSyncService) filterConfigForNode(addresses []models.AddressMap, nodeID uint) []models.AddressMap {
 	result := []models.AddressMap{}
 
+	var nodeOrg []uint
+	database.DB.Model(&models.Node{}).Where("id = ?", nodeID).Pluck("organization_id", &nodeOrg)
+
 	for _, addr := range addresses {
+		if addr.OrganizationID > 0 && (len(nodeOrg) == 0 || nodeOrg[0] != addr.OrganizationID) {
+			continue
+		}
 		if addr.NodeIDs == "" || addr.NodeIDs == "[]" {
 			// 空表示应用到所有节点
 			result = append(result, addr)
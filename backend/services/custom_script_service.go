@@ -2,10 +2,13 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
-	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"smartdns-manager/config"
@@ -22,56 +25,127 @@ type CustomScriptService struct {
 
 // NewCustomScriptService 创建自定义脚本服务
 func NewCustomScriptService(db *gorm.DB, config *config.Config) (*CustomScriptService, error) {
-	return &CustomScriptService{
+	service := &CustomScriptService{
 		db:     db,
 		config: config,
-	}, nil
+	}
+	service.seedScriptTemplates()
+	return service, nil
 }
 
-// ExecuteScript 执行自定义脚本
-func (s *CustomScriptService) ExecuteScript(ctx context.Context, scriptConfig models.CustomScriptConfig) (string, error) {
-	var nodes []models.Node
+// defaultScriptConcurrency 默认同时执行脚本的节点数
+const defaultScriptConcurrency = 5
+
+// scriptNodeResult 单个节点的脚本执行结果
+type scriptNodeResult struct {
+	node     models.Node
+	stdout   string
+	stderr   string
+	exitCode int
+	duration time.Duration
+	err      error // 连接失败、超时等未能拿到退出码的错误
+}
 
-	// 获取要执行脚本的节点列表
-	query := s.db.Where("enabled = ?", true)
-	if len(scriptConfig.NodeIDs) > 0 {
-		query = query.Where("id IN ?", scriptConfig.NodeIDs)
-	}
+func (r scriptNodeResult) succeeded() bool {
+	return r.err == nil && r.exitCode == 0
+}
 
-	if err := query.Find(&nodes).Error; err != nil {
-		return "", fmt.Errorf("查询节点失败: %w", err)
+// ExecuteScript 通过 SSH 在选定的节点上执行自定义脚本，汇总每个节点的 stdout、stderr 和退出码
+//
+// 有节点执行失败（退出码非 0、连接失败或超时）时返回汇总输出和错误，便于触发失败通知。
+func (s *CustomScriptService) ExecuteScript(ctx context.Context, scriptConfig models.CustomScriptConfig) (string, error) {
+	nodes, err := s.selectNodes(scriptConfig)
+	if err != nil {
+		return "", err
 	}
 
-	if len(nodes) == 0 {
-		return "", fmt.Errorf("没有找到可执行的节点")
+	concurrency := scriptConfig.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultScriptConcurrency
 	}
+	log.Printf("🎯 自定义脚本将在 %d 个节点上执行（并发 %d）", len(nodes), concurrency)
+
+	results := make([]scriptNodeResult, len(nodes))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node models.Node) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// 任务被取消或超时后不再在剩余节点上执行
+			if ctx.Err() != nil {
+				results[i] = scriptNodeResult{node: node, exitCode: -1, err: fmt.Errorf("未执行: %w", ctx.Err())}
+				return
+			}
+			results[i] = s.executeScriptOnNode(ctx, node, scriptConfig)
+			if results[i].succeeded() {
+				log.Printf("✅ 节点 %s 脚本执行成功", node.Name)
+			} else {
+				log.Printf("❌ 节点 %s 脚本执行失败: 退出码 %d %v", node.Name, results[i].exitCode, results[i].err)
+			}
+		}(i, node)
+	}
+	wg.Wait()
 
-	log.Printf("🎯 自定义脚本将在 %d 个节点上执行", len(nodes))
-
-	var results []string
-	var successCount, failCount int
-
-	for _, node := range nodes {
-		result, err := s.executeScriptOnNode(ctx, node, scriptConfig)
-		if err != nil {
-			failCount++
-			results = append(results, fmt.Sprintf("节点 %s: 执行失败 - %v", node.Name, err))
-			log.Printf("❌ 节点 %s 脚本执行失败: %v", node.Name, err)
-		} else {
+	var successCount int
+	sections := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.succeeded() {
 			successCount++
-			results = append(results, fmt.Sprintf("节点 %s: 执行成功\n%s", node.Name, result))
-			log.Printf("✅ 节点 %s 脚本执行成功", node.Name)
 		}
+		sections = append(sections, formatScriptNodeResult(result))
 	}
 
 	summary := fmt.Sprintf("脚本执行完成: 成功 %d/%d 个节点\n\n", successCount, len(nodes))
-	summary += strings.Join(results, "\n"+strings.Repeat("=", 50)+"\n")
+	summary += strings.Join(sections, "\n"+strings.Repeat("=", 50)+"\n")
 
+	if failed := len(nodes) - successCount; failed > 0 {
+		return summary, fmt.Errorf("脚本在 %d/%d 个节点上执行失败", failed, len(nodes))
+	}
 	return summary, nil
 }
 
-// executeScriptOnNode 在指定节点上执行脚本
-func (s *CustomScriptService) executeScriptOnNode(ctx context.Context, node models.Node, scriptConfig models.CustomScriptConfig) (string, error) {
+// selectNodes 按节点ID和标签选择执行脚本的节点，都为空时选择所有节点
+func (s *CustomScriptService) selectNodes(scriptConfig models.CustomScriptConfig) ([]models.Node, error) {
+	var all []models.Node
+	if err := s.db.Order("id").Find(&all).Error; err != nil {
+		return nil, fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	tag := strings.TrimSpace(scriptConfig.Tag)
+	if len(scriptConfig.NodeIDs) == 0 && tag == "" {
+		if len(all) == 0 {
+			return nil, fmt.Errorf("没有找到可执行的节点")
+		}
+		return all, nil
+	}
+
+	wanted := make(map[uint]bool, len(scriptConfig.NodeIDs))
+	for _, id := range scriptConfig.NodeIDs {
+		wanted[id] = true
+	}
+
+	var nodes []models.Node
+	for _, node := range all {
+		if wanted[node.ID] || (tag != "" && nodeHasTag(node, tag)) {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("没有找到可执行的节点")
+	}
+	return nodes, nil
+}
+
+// executeScriptOnNode 通过 SSH 在指定节点上执行脚本
+func (s *CustomScriptService) executeScriptOnNode(ctx context.Context, node models.Node, scriptConfig models.CustomScriptConfig) scriptNodeResult {
+	result := scriptNodeResult{node: node, exitCode: -1}
+	start := time.Now()
+	defer func() { result.duration = time.Since(start) }()
+
 	// 设置超时
 	timeout := time.Duration(scriptConfig.Timeout) * time.Second
 	if timeout <= 0 {
@@ -81,53 +155,37 @@ func (s *CustomScriptService) executeScriptOnNode(ctx context.Context, node mode
 	scriptCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// 构建SSH命令
-	sshCmd := s.buildSSHCommand(node, scriptConfig)
-
-	log.Printf("🔧 在节点 %s 执行脚本命令: %s", node.Name, strings.Join(sshCmd, " "))
-
-	// 执行命令
-	cmd := exec.CommandContext(scriptCtx, sshCmd[0], sshCmd[1:]...)
-	output, err := cmd.CombinedOutput()
-
+	client, err := NewSSHClient(&node)
 	if err != nil {
-		return "", fmt.Errorf("命令执行失败: %w, 输出: %s", err, string(output))
+		result.err = fmt.Errorf("SSH连接失败: %w", err)
+		return result
 	}
+	defer client.Close()
 
-	return string(output), nil
-}
-
-// buildSSHCommand 构建SSH执行命令
-func (s *CustomScriptService) buildSSHCommand(node models.Node, scriptConfig models.CustomScriptConfig) []string {
-	// 基础SSH命令
-	sshCmd := []string{
-		"ssh",
-		"-o", "ConnectTimeout=10",
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
+	command, err := s.buildRemoteCommand(node, scriptConfig)
+	if err != nil {
+		result.err = err
+		return result
 	}
+	log.Printf("🔧 在节点 %s 执行脚本", node.Name)
 
-	// 添加端口参数
-	if node.Port != 22 {
-		sshCmd = append(sshCmd, "-p", fmt.Sprintf("%d", node.Port))
+	result.stdout, result.stderr, result.exitCode, err = client.RunScript(scriptCtx, command, scriptConfig.Script)
+	if err != nil {
+		if scriptCtx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("执行超时（超过 %s）", timeout)
+		}
+		result.err = err
 	}
+	return result
+}
 
-	// 添加用户和主机
-	user := scriptConfig.RunAsUser
-	if user == "" {
-		user = "root"
+// buildRemoteCommand 构建远程执行命令：脚本从标准输入写入临时文件后执行，保留脚本自身的解释器声明
+func (s *CustomScriptService) buildRemoteCommand(node models.Node, scriptConfig models.CustomScriptConfig) (string, error) {
+	// 环境变量名直接拼接进命令，执行前再次校验，防止绕过保存时的校验
+	if err := ValidateScriptEnvVars(scriptConfig.EnvVars); err != nil {
+		return "", err
 	}
-	sshCmd = append(sshCmd, fmt.Sprintf("%s@%s", user, node.Host))
-
-	// 构建远程执行的脚本命令
-	remoteCmd := s.buildRemoteCommand(scriptConfig)
-	sshCmd = append(sshCmd, remoteCmd)
-
-	return sshCmd
-}
 
-// buildRemoteCommand 构建远程执行命令
-func (s *CustomScriptService) buildRemoteCommand(scriptConfig models.CustomScriptConfig) string {
 	var cmdParts []string
 
 	// 设置工作目录
@@ -135,26 +193,69 @@ func (s *CustomScriptService) buildRemoteCommand(scriptConfig models.CustomScrip
 	if workingDir == "" {
 		workingDir = "/tmp"
 	}
-	cmdParts = append(cmdParts, fmt.Sprintf("cd %s", workingDir))
+	cmdParts = append(cmdParts, fmt.Sprintf("cd %s", shellQuote(workingDir)))
 
 	// 设置环境变量
 	for key, value := range scriptConfig.EnvVars {
-		cmdParts = append(cmdParts, fmt.Sprintf("export %s='%s'", key, value))
+		cmdParts = append(cmdParts, fmt.Sprintf("export %s=%s", key, shellQuote(value)))
 	}
 
 	// 添加默认环境变量
 	if _, exists := scriptConfig.EnvVars["PATH"]; !exists {
-		cmdParts = append(cmdParts, "export PATH='/usr/local/bin:/usr/bin:/bin'")
+		cmdParts = append(cmdParts, "export PATH='/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin'")
+	}
+
+	run := "$script"
+	if user := scriptConfig.RunAsUser; user != "" && user != node.Username {
+		run = fmt.Sprintf("sudo -n -E -u %s $script", shellQuote(user))
+	}
+
+	cmdParts = append(cmdParts,
+		"script=$(mktemp /tmp/custom_script_XXXXXX)",
+		"cat > \"$script\"",
+		"chmod 755 \"$script\"",
+	)
+	// 无论脚本是否成功都清理临时文件，并保留脚本的退出码
+	return strings.Join(cmdParts, " && ") + fmt.Sprintf(" || exit 1; %s; code=$?; rm -f \"$script\"; exit $code", run), nil
+}
+
+// formatScriptNodeResult 格式化单个节点的执行结果
+func formatScriptNodeResult(result scriptNodeResult) string {
+	var b strings.Builder
+	status := "执行成功"
+	if !result.succeeded() {
+		status = "执行失败"
 	}
+	fmt.Fprintf(&b, "节点 %s (%s): %s，退出码 %d，耗时 %s\n",
+		result.node.Name, result.node.Host, status, result.exitCode, result.duration.Round(time.Millisecond))
+	if result.err != nil {
+		fmt.Fprintf(&b, "错误: %v\n", result.err)
+	}
+	if stdout := strings.TrimRight(result.stdout, "\n"); stdout != "" {
+		fmt.Fprintf(&b, "--- stdout ---\n%s\n", stdout)
+	}
+	if stderr := strings.TrimRight(result.stderr, "\n"); stderr != "" {
+		fmt.Fprintf(&b, "--- stderr ---\n%s\n", stderr)
+	}
+	return b.String()
+}
+
+// shellQuote 用单引号包裹参数，防止被远程 shell 解释
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
 
-	// 创建临时脚本文件并执行
-	scriptContent := strings.ReplaceAll(scriptConfig.Script, "'", "'\"'\"'") // 转义单引号
-	cmdParts = append(cmdParts, fmt.Sprintf("echo '%s' > /tmp/custom_script_$$.sh", scriptContent))
-	cmdParts = append(cmdParts, "chmod +x /tmp/custom_script_$$.sh")
-	cmdParts = append(cmdParts, "/tmp/custom_script_$$.sh")
-	cmdParts = append(cmdParts, "rm -f /tmp/custom_script_$$.sh") // 清理临时文件
+// envVarNamePattern 环境变量名，拼接进远程命令前必须校验
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 
-	return strings.Join(cmdParts, " && ")
+// ValidateScriptEnvVars 校验环境变量名只包含字母、数字和下划线且不以数字开头
+func ValidateScriptEnvVars(envVars map[string]string) error {
+	for key := range envVars {
+		if !envVarNamePattern.MatchString(key) {
+			return fmt.Errorf("环境变量名无效: %s", key)
+		}
+	}
+	return nil
 }
 
 // ValidateScript 验证脚本配置
@@ -188,23 +289,168 @@ func (s *CustomScriptService) ValidateScript(scriptConfig models.CustomScriptCon
 		}
 	}
 
-	// 验证节点ID
-	if len(scriptConfig.NodeIDs) > 0 {
-		var count int64
-		if err := s.db.Model(&models.Node{}).Where("id IN ? AND enabled = ?", scriptConfig.NodeIDs, true).Count(&count); err != nil {
-			return fmt.Errorf("验证节点ID失败: %w", err)
+	if scriptConfig.Concurrency < 0 {
+		return fmt.Errorf("并发数不能为负数")
+	}
+
+	if err := ValidateScriptEnvVars(scriptConfig.EnvVars); err != nil {
+		return err
+	}
+
+	// 验证目标节点
+	if _, err := s.selectNodes(scriptConfig); err != nil {
+		return fmt.Errorf("指定的节点ID或标签中没有可用的节点")
+	}
+
+	return nil
+}
+
+// scriptPlaceholderPattern 脚本模板中的参数占位符 {{name}}
+var scriptPlaceholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// seedScriptTemplates 模板库为空时写入内置模板
+func (s *CustomScriptService) seedScriptTemplates() {
+	var count int64
+	if err := s.db.Model(&models.ScriptTemplate{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	for _, template := range builtinScriptTemplates() {
+		template.BuiltIn = true
+		if err := s.db.Create(&template).Error; err != nil {
+			log.Printf("⚠️ 写入内置脚本模板 [%s] 失败: %v", template.Name, err)
 		}
-		if count == 0 {
-			return fmt.Errorf("指定的节点ID中没有可用的节点")
+	}
+}
+
+// ValidateScriptTemplate 校验模板参数定义，脚本中的占位符必须都已定义
+func ValidateScriptTemplate(script string, params []models.ScriptTemplateParam) error {
+	defined := make(map[string]bool, len(params))
+	for _, param := range params {
+		if !envVarNamePattern.MatchString(param.Name) {
+			return fmt.Errorf("参数名无效: %s（只能包含字母、数字和下划线）", param.Name)
+		}
+		if defined[param.Name] {
+			return fmt.Errorf("参数名重复: %s", param.Name)
+		}
+		defined[param.Name] = true
+
+		switch param.Type {
+		case models.ScriptParamString, models.ScriptParamInt, models.ScriptParamBool:
+		case models.ScriptParamEnum:
+			if len(param.Options) == 0 {
+				return fmt.Errorf("枚举参数 %s 没有可选值", param.Name)
+			}
+		default:
+			return fmt.Errorf("参数 %s 的类型无效: %s", param.Name, param.Type)
+		}
+		if param.Default != "" {
+			if _, err := renderScriptParam(param, param.Default); err != nil {
+				return fmt.Errorf("参数 %s 的默认值无效: %w", param.Name, err)
+			}
 		}
 	}
 
+	for _, match := range scriptPlaceholderPattern.FindAllStringSubmatch(script, -1) {
+		if !defined[match[1]] {
+			return fmt.Errorf("脚本中的占位符 {{%s}} 没有定义参数", match[1])
+		}
+	}
 	return nil
 }
 
-// GetScriptTemplates 获取脚本模板
-func (s *CustomScriptService) GetScriptTemplates() []ScriptTemplate {
-	return []ScriptTemplate{
+// RenderScriptTemplate 按参数值渲染模板脚本，未提供的参数使用默认值
+func RenderScriptTemplate(template *models.ScriptTemplate, values map[string]interface{}) (string, error) {
+	var params []models.ScriptTemplateParam
+	if template.Parameters != "" {
+		if err := json.Unmarshal([]byte(template.Parameters), &params); err != nil {
+			return "", fmt.Errorf("解析模板参数失败: %w", err)
+		}
+	}
+
+	rendered := make(map[string]string, len(params))
+	for _, param := range params {
+		value := param.Default
+		if v, ok := values[param.Name]; ok && v != nil {
+			value = fmt.Sprint(v)
+		}
+		if value == "" {
+			if param.Required {
+				return "", fmt.Errorf("缺少必填参数: %s", param.Name)
+			}
+			if param.Type != models.ScriptParamString {
+				return "", fmt.Errorf("参数 %s 没有取值", param.Name)
+			}
+		}
+		text, err := renderScriptParam(param, value)
+		if err != nil {
+			return "", fmt.Errorf("参数 %s: %w", param.Name, err)
+		}
+		rendered[param.Name] = text
+	}
+
+	var renderErr error
+	script := scriptPlaceholderPattern.ReplaceAllStringFunc(template.Script, func(placeholder string) string {
+		name := scriptPlaceholderPattern.FindStringSubmatch(placeholder)[1]
+		text, ok := rendered[name]
+		if !ok && renderErr == nil {
+			renderErr = fmt.Errorf("脚本中的占位符 {{%s}} 没有定义参数", name)
+		}
+		return text
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+	return script, nil
+}
+
+// renderScriptParam 校验参数值并转换为可以安全嵌入脚本的文本
+func renderScriptParam(param models.ScriptTemplateParam, value string) (string, error) {
+	switch param.Type {
+	case models.ScriptParamInt:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("不是整数: %s", value)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case models.ScriptParamBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("不是布尔值: %s", value)
+		}
+		return strconv.FormatBool(b), nil
+	case models.ScriptParamEnum:
+		for _, option := range param.Options {
+			if value == option {
+				return shellQuote(value), nil
+			}
+		}
+		return "", fmt.Errorf("不是可选值之一: %s", value)
+	default:
+		return shellQuote(value), nil
+	}
+}
+
+// ApplyScriptTemplate 任务使用脚本模板时，用模板的最新内容渲染脚本
+func (s *CustomScriptService) ApplyScriptTemplate(scriptConfig *models.CustomScriptConfig) error {
+	if scriptConfig.TemplateID == 0 {
+		return nil
+	}
+
+	var template models.ScriptTemplate
+	if err := s.db.First(&template, scriptConfig.TemplateID).Error; err != nil {
+		return fmt.Errorf("脚本模板 #%d 不存在", scriptConfig.TemplateID)
+	}
+	script, err := RenderScriptTemplate(&template, scriptConfig.Params)
+	if err != nil {
+		return fmt.Errorf("渲染脚本模板 [%s] 失败: %w", template.Name, err)
+	}
+	scriptConfig.Script = script
+	return nil
+}
+
+// builtinScriptTemplates 内置脚本模板，首次启动时写入模板库
+func builtinScriptTemplates() []models.ScriptTemplate {
+	return []models.ScriptTemplate{
 		{
 			Name:        "系统信息收集",
 			Description: "收集系统基本信息，包括硬件、内存、磁盘使用情况",
@@ -275,22 +521,23 @@ echo "=== SmartDNS服务管理完成 ==="`,
 			Name:        "日志清理",
 			Description: "清理系统和应用程序的旧日志文件",
 			Category:    "系统维护",
+			Parameters:  `[{"name":"retention_days","label":"日志保留天数","type":"int","default":"7","required":true}]`,
 			Script: `#!/bin/bash
 echo "=== 日志清理开始 ==="
 
-# 清理7天前的系统日志
+# 清理保留天数之前的系统日志
 echo "清理系统日志..."
-find /var/log -name "*.log" -mtime +7 -type f -exec rm -f {} \;
-find /var/log -name "*.log.*" -mtime +7 -type f -exec rm -f {} \;
+find /var/log -name "*.log" -mtime +{{retention_days}} -type f -exec rm -f {} \;
+find /var/log -name "*.log.*" -mtime +{{retention_days}} -type f -exec rm -f {} \;
 
 # 清理journal日志
 echo "清理journal日志..."
-journalctl --vacuum-time=7d
+journalctl --vacuum-time={{retention_days}}d
 
 # 清理SmartDNS日志
 if [ -d "/var/log/smartdns" ]; then
     echo "清理SmartDNS日志..."
-    find /var/log/smartdns -name "*.log" -mtime +7 -type f -exec rm -f {} \;
+    find /var/log/smartdns -name "*.log" -mtime +{{retention_days}} -type f -exec rm -f {} \;
 fi
 
 # 清理临时文件
@@ -357,11 +604,3 @@ echo "=== 系统更新检查完成 ==="`,
 		},
 	}
 }
-
-// ScriptTemplate 脚本模板
-type ScriptTemplate struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Category    string `json:"category"`
-	Script      string `json:"script"`
-}
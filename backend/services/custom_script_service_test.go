@@ -0,0 +1,31 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"smartdns-manager/models"
+)
+
+// TestBuildRemoteCommandRejectsInvalidEnvVarName 环境变量名不合法时不能拼接进远程命令
+func TestBuildRemoteCommandRejectsInvalidEnvVarName(t *testing.T) {
+	s := &CustomScriptService{}
+	node := models.Node{Name: "node-1", Username: "root"}
+
+	command, err := s.buildRemoteCommand(node, models.CustomScriptConfig{
+		EnvVars: map[string]string{"A=1; touch /tmp/pwned; B": "x"},
+	})
+	if err == nil {
+		t.Fatalf("期望拒绝非法的环境变量名，实际生成命令: %s", command)
+	}
+
+	command, err = s.buildRemoteCommand(node, models.CustomScriptConfig{
+		EnvVars: map[string]string{"_APP_ENV2": "prod; reboot"},
+	})
+	if err != nil {
+		t.Fatalf("合法的环境变量名被拒绝: %v", err)
+	}
+	if !strings.Contains(command, "export _APP_ENV2='prod; reboot'") {
+		t.Errorf("环境变量值没有被引号包裹: %s", command)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -19,12 +21,11 @@ import (
 	"compress/flate"
 
 	"github.com/robfig/cron/v3"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
 	"smartdns-manager/config"
+	"smartdns-manager/database"
 	"smartdns-manager/models"
 )
 
@@ -34,6 +35,7 @@ type DatabaseBackupService struct {
 	cron        *cron.Cron
 	activeJobs  map[uint]cron.EntryID // 配置ID -> cron任务ID的映射
 	config      *config.Config
+	keyService  *BackupKeyService
 }
 
 func NewDatabaseBackupService(db *gorm.DB, s3Service *S3Service) *DatabaseBackupService {
@@ -43,6 +45,7 @@ func NewDatabaseBackupService(db *gorm.DB, s3Service *S3Service) *DatabaseBackup
 		cron:        cron.New(cron.WithParser(cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor))),
 		activeJobs:  make(map[uint]cron.EntryID),
 		config:      config.GetConfig(),
+		keyService:  NewBackupKeyService(db),
 	}
 }
 
@@ -97,13 +100,19 @@ func (s *DatabaseBackupService) scheduleBackup(config *models.BackupConfig) erro
 
 // executeBackup 执行备份
 func (s *DatabaseBackupService) executeBackup(configID uint) {
+	if _, err := s.BackupNow(configID); err != nil {
+		fmt.Printf("Backup config %d failed: %v\n", configID, err)
+	}
+}
+
+// BackupNow 按备份配置同步执行一次备份，返回备份历史记录
+func (s *DatabaseBackupService) BackupNow(configID uint) (*models.BackupHistory, error) {
 	ctx := context.Background()
 	
 	// 获取配置
 	var config models.BackupConfig
 	if err := s.db.First(&config, configID).Error; err != nil {
-		fmt.Printf("Failed to load backup config %d: %v\n", configID, err)
-		return
+		return nil, fmt.Errorf("failed to load backup config %d: %w", configID, err)
 	}
 
 	// 创建备份历史记录
@@ -114,8 +123,7 @@ func (s *DatabaseBackupService) executeBackup(configID uint) {
 		StartedAt:   time.Now(),
 	}
 	if err := s.db.Create(history).Error; err != nil {
-		fmt.Printf("Failed to create backup history: %v\n", err)
-		return
+		return nil, fmt.Errorf("failed to create backup history: %w", err)
 	}
 
 	// 执行备份
@@ -147,6 +155,7 @@ func (s *DatabaseBackupService) executeBackup(configID uint) {
 
 	// 发送通知
 	s.sendNotification(&config, history, err)
+	return history, err
 }
 
 // performBackup 执行实际的备份操作
@@ -175,10 +184,10 @@ func (s *DatabaseBackupService) performBackup(ctx context.Context, config *model
 		history.FileSize = stat.Size()
 	}
 
-	// 上传到S3（如果启用）
-	if config.S3Enabled {
-		if err := s.uploadToS3(ctx, config, tempFile, history); err != nil {
-			return fmt.Errorf("failed to upload to S3: %w", err)
+	// 上传到远程存储（如果启用）
+	if storageType := backupConfigStorageType(config); storageType != "" {
+		if err := s.uploadToStorage(ctx, config, tempFile, history); err != nil {
+			return fmt.Errorf("failed to upload to %s: %w", storageType, err)
 		}
 	}
 
@@ -202,12 +211,12 @@ func (s *DatabaseBackupService) backupDatabase(config *models.BackupConfig, outp
 	if config.CompressionEnabled {
 		return s.backupDatabaseCompressed(config, outputPath, history)
 	} else {
-		return s.backupDatabaseRaw(outputPath)
+		return s.backupDatabaseRaw(outputPath, history)
 	}
 }
 
 // backupDatabaseRaw 原始备份（直接复制数据库文件）
-func (s *DatabaseBackupService) backupDatabaseRaw(outputPath string) error {
+func (s *DatabaseBackupService) backupDatabaseRaw(outputPath string, history *models.BackupHistory) error {
 	srcFile, err := os.Open(s.config.DBPath)
 	if err != nil {
 		return fmt.Errorf("failed to open source database: %w", err)
@@ -220,8 +229,12 @@ func (s *DatabaseBackupService) backupDatabaseRaw(outputPath string) error {
 	}
 	defer dstFile.Close()
 
-	_, err = io.Copy(dstFile, srcFile)
-	return err
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dstFile, hasher), srcFile); err != nil {
+		return err
+	}
+	history.Checksum = hex.EncodeToString(hasher.Sum(nil))
+	return nil
 }
 
 // backupDatabaseCompressed 压缩备份
@@ -254,10 +267,12 @@ func (s *DatabaseBackupService) backupDatabaseCompressed(config *models.BackupCo
 		return fmt.Errorf("failed to create zip entry: %w", err)
 	}
 
-	written, err := io.Copy(writer, dbFile)
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(writer, hasher), dbFile)
 	if err != nil {
 		return fmt.Errorf("failed to write to zip: %w", err)
 	}
+	history.Checksum = hex.EncodeToString(hasher.Sum(nil))
 
 	// 计算压缩比
 	if history.DatabaseSize > 0 {
@@ -267,62 +282,94 @@ func (s *DatabaseBackupService) backupDatabaseCompressed(config *models.BackupCo
 	return nil
 }
 
-// uploadToS3 上传备份到S3
-func (s *DatabaseBackupService) uploadToS3(ctx context.Context, config *models.BackupConfig, filePath string, history *models.BackupHistory) error {
-	// 创建S3客户端（使用配置中的凭据）
-	s3Config := S3Config{
-		AccessKey: config.S3AccessKey,
-		SecretKey: config.S3SecretKey,
-		Region:    config.S3Region,
-		Bucket:    config.S3Bucket,
-		Endpoint:  config.S3Endpoint,
+// backupConfigStorageType 备份配置使用的远程存储类型，未启用远程存储时返回空
+func backupConfigStorageType(config *models.BackupConfig) string {
+	if config.StorageType != "" {
+		return config.StorageType
+	}
+	if config.S3Enabled {
+		return models.StorageTypeS3
 	}
+	return ""
+}
 
-	s3Client, err := NewS3Service(s3Config, s.db)
+// newBackupConfigStorage 按备份配置创建远程存储实例，S3 使用配置表中的 S3 字段，其他类型使用 StorageBackend
+func newBackupConfigStorage(config *models.BackupConfig) (BackupStorage, error) {
+	storageType := backupConfigStorageType(config)
+	backend := &models.StorageBackendConfig{}
+	if config.StorageBackend != "" {
+		if err := json.Unmarshal([]byte(config.StorageBackend), backend); err != nil {
+			return nil, fmt.Errorf("解析存储配置失败: %w", err)
+		}
+	}
+	if storageType == models.StorageTypeS3 && backend.S3 == nil {
+		prefix := config.S3Prefix
+		if prefix == "" {
+			prefix = "smartdns-backups"
+		}
+		backend.S3 = &models.S3Config{
+			AccessKey: config.S3AccessKey,
+			SecretKey: config.S3SecretKey,
+			Region:    config.S3Region,
+			Bucket:    config.S3Bucket,
+			Endpoint:  config.S3Endpoint,
+			Prefix:    prefix,
+		}
+	}
+	return NewBackupStorage(storageType, backend)
+}
+
+// uploadToStorage 上传备份到远程存储
+func (s *DatabaseBackupService) uploadToStorage(ctx context.Context, config *models.BackupConfig, filePath string, history *models.BackupHistory) error {
+	storage, err := newBackupConfigStorage(config)
 	if err != nil {
-		return fmt.Errorf("failed to create S3 client: %w", err)
+		return fmt.Errorf("failed to create storage client: %w", err)
 	}
+	defer storage.Close()
 
-	// 打开文件
+	// 流式读取备份文件，避免大文件整体加载到内存
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open backup file: %w", err)
 	}
 	defer file.Close()
-
-	// 读取文件内容
-	fileContent, err := io.ReadAll(file)
+	stat, err := file.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to read backup file: %w", err)
+		return fmt.Errorf("failed to stat backup file: %w", err)
 	}
+	var content io.Reader = file
+	size := stat.Size()
 
-	// 加密（如果启用）
-	if config.EncryptionEnabled && config.EncryptionKey != "" {
-		encrypted, err := s.encryptData(fileContent, config.EncryptionKey)
+	// 加密（如果启用），每个备份使用独立的数据密钥
+	if config.EncryptionEnabled && config.EncryptionKeyID != nil {
+		dataKey, wrappedDataKey, keyVersion, err := s.keyService.GenerateDataKey(*config.EncryptionKeyID)
+		if err != nil {
+			return fmt.Errorf("failed to generate data key: %w", err)
+		}
+		content, err = s.encryptReader(file, dataKey)
 		if err != nil {
 			return fmt.Errorf("failed to encrypt backup: %w", err)
 		}
-		fileContent = encrypted
+		size += aes.BlockSize
 		history.FileName += ".enc"
+		history.EncryptionKeyID = config.EncryptionKeyID
+		history.KeyVersion = keyVersion
+		history.WrappedDataKey = wrappedDataKey
 	}
 
-	// 生成S3键
-	s3Key := fmt.Sprintf("%s/%s", strings.TrimSuffix(config.S3Prefix, "/"), history.FileName)
-	
-	// 上传到S3
-	_, err = s3Client.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(config.S3Bucket),
-		Key:    aws.String(s3Key),
-		Body:   bytes.NewReader(fileContent),
-	})
+	storagePath, err := SaveBackupStream(ctx, storage, content, size, history.FileName)
 	if err != nil {
-		return fmt.Errorf("failed to upload to S3: %w", err)
+		return err
 	}
 
 	// 更新历史记录
-	history.S3Key = s3Key
-	history.S3Bucket = config.S3Bucket
-	history.S3Region = config.S3Region
+	history.StorageType = backupConfigStorageType(config)
+	history.StoragePath = storagePath
+	if history.StorageType == models.StorageTypeS3 {
+		history.S3Key = storagePath
+		history.S3Bucket = config.S3Bucket
+		history.S3Region = config.S3Region
+	}
 
 	return nil
 }
@@ -359,15 +406,9 @@ func (s *DatabaseBackupService) saveToLocal(config *models.BackupConfig, srcPath
 	return nil
 }
 
-// encryptData 加密数据
-func (s *DatabaseBackupService) encryptData(data []byte, key string) ([]byte, error) {
-	// 生成32字节的密钥
-	hasher := sha256.New()
-	hasher.Write([]byte(key))
-	keyBytes := hasher.Sum(nil)
-
-	// 创建AES加密器
-	block, err := aes.NewCipher(keyBytes)
+// encryptReader 返回边读边加密的 reader，输出为 IV + AES-CFB 密文
+func (s *DatabaseBackupService) encryptReader(r io.Reader, key string) (io.Reader, error) {
+	block, err := aes.NewCipher(backupCipherKey(key))
 	if err != nil {
 		return nil, err
 	}
@@ -378,17 +419,8 @@ func (s *DatabaseBackupService) encryptData(data []byte, key string) ([]byte, er
 		return nil, err
 	}
 
-	// 加密数据
 	stream := cipher.NewCFBEncrypter(block, iv)
-	encrypted := make([]byte, len(data))
-	stream.XORKeyStream(encrypted, data)
-
-	// 将IV和加密数据合并
-	result := make([]byte, aes.BlockSize+len(encrypted))
-	copy(result[:aes.BlockSize], iv)
-	copy(result[aes.BlockSize:], encrypted)
-
-	return result, nil
+	return io.MultiReader(bytes.NewReader(iv), &cipher.StreamReader{S: stream, R: r}), nil
 }
 
 // cleanupExpiredBackups 清理过期备份
@@ -399,8 +431,10 @@ func (s *DatabaseBackupService) cleanupExpiredBackups(config *models.BackupConfi
 	s.db.Where("config_id = ? AND created_at < ?", config.ID, cutoffTime).Find(&expiredBackups)
 
 	for _, backup := range expiredBackups {
-		// 删除S3文件
-		if backup.S3Key != "" && config.S3Enabled {
+		// 删除远程存储中的文件，旧记录只有 S3Key
+		if backup.StoragePath != "" {
+			s.deleteStorageFile(config, backup.StoragePath)
+		} else if backup.S3Key != "" && config.S3Enabled {
 			s.deleteS3File(config, backup.S3Key)
 		}
 		
@@ -414,6 +448,20 @@ func (s *DatabaseBackupService) cleanupExpiredBackups(config *models.BackupConfi
 	}
 }
 
+// deleteStorageFile 删除远程存储中的文件
+func (s *DatabaseBackupService) deleteStorageFile(config *models.BackupConfig, storagePath string) {
+	storage, err := newBackupConfigStorage(config)
+	if err != nil {
+		fmt.Printf("Failed to create storage client for cleanup: %v\n", err)
+		return
+	}
+	defer storage.Close()
+
+	if err := storage.Delete(context.Background(), storagePath); err != nil {
+		fmt.Printf("Failed to delete backup file %s: %v\n", storagePath, err)
+	}
+}
+
 // deleteS3File 删除S3文件
 func (s *DatabaseBackupService) deleteS3File(config *models.BackupConfig, s3Key string) {
 	ctx := context.Background()
@@ -452,9 +500,17 @@ func (s *DatabaseBackupService) sendNotification(config *models.BackupConfig, hi
 		return
 	}
 
-	// TODO: 实现具体的通知逻辑
-	// 这里可以集成现有的通知系统
-	fmt.Printf("Backup notification: Config=%s, Status=%s\n", config.Name, history.Status)
+	title := fmt.Sprintf("✅ 数据库备份成功: %s", config.Name)
+	content := fmt.Sprintf("备份配置: %s\n文件: %s\n大小: %d 字节\n耗时: %d 秒",
+		config.Name, history.FileName, history.FileSize, history.Duration)
+	if backupErr != nil {
+		title = fmt.Sprintf("❌ 数据库备份失败: %s", config.Name)
+		content = fmt.Sprintf("备份配置: %s\n耗时: %d 秒\n错误: %s", config.Name, history.Duration, backupErr.Error())
+	}
+
+	if err := NewNotificationService().SendNotification(0, "database_backup", title, content); err != nil {
+		fmt.Printf("Failed to send backup notification: %v\n", err)
+	}
 }
 
 // UpdateBackupConfig 更新备份配置
@@ -478,17 +534,36 @@ func (s *DatabaseBackupService) UpdateBackupConfig(config *models.BackupConfig)
 	return nil
 }
 
-// ManualBackup 手动触发备份
-func (s *DatabaseBackupService) ManualBackup(configID uint) error {
+// ManualBackup 手动触发备份，作为后台任务排队执行
+func (s *DatabaseBackupService) ManualBackup(ctx context.Context, configID uint) (*models.BackgroundJob, error) {
 	var config models.BackupConfig
 	if err := s.db.First(&config, configID).Error; err != nil {
-		return fmt.Errorf("backup config not found: %w", err)
+		return nil, fmt.Errorf("backup config not found: %w", err)
 	}
 
-	// 异步执行备份
-	go s.executeBackup(configID)
-	
-	return nil
+	return StartJob(ctx, models.BackgroundJobDatabaseBackup, 0, fmt.Sprintf("备份数据库（%s）", config.Name), configID)
+}
+
+func init() {
+	RegisterJobHandler(models.BackgroundJobDatabaseBackup, JobHandler{
+		Run: func(job *JobContext) error {
+			var configID uint
+			if err := job.Payload(&configID); err != nil {
+				return fmt.Errorf("解析任务参数失败: %w", err)
+			}
+			history, err := NewDatabaseBackupService(database.DB, nil).BackupNow(configID)
+			if history != nil {
+				job.SetResult(map[string]interface{}{
+					"history_id": history.ID,
+					"file_name":  history.FileName,
+					"file_size":  history.FileSize,
+				})
+			}
+			return err
+		},
+		// 备份历史由 markInterruptedDatabaseBackups 标记为失败
+		Guidance: "数据库备份在服务重启时中断，备份文件可能不完整，请重新执行备份",
+	})
 }
 
 // GetBackupStats 获取备份统计信息
@@ -542,189 +617,195 @@ func (s *DatabaseBackupService) GetBackupStats() (*models.BackupStats, error) {
 	return &stats, nil
 }
 
-// RestoreBackup 恢复备份
-func (s *DatabaseBackupService) RestoreBackup(request *models.BackupRestoreRequest) error {
+// RestoreBackup 恢复备份，返回恢复报告；失败时报告中记录已完成的步骤
+func (s *DatabaseBackupService) RestoreBackup(request *models.BackupRestoreRequest) (*models.BackupRestoreReport, error) {
+	report := &models.BackupRestoreReport{
+		BackupHistoryID: request.BackupHistoryID,
+		StartedAt:       time.Now(),
+	}
+	defer func() { report.DurationMs = time.Since(report.StartedAt).Milliseconds() }()
+
 	// 获取备份历史
 	var history models.BackupHistory
 	if err := s.db.Preload("Config").First(&history, request.BackupHistoryID).Error; err != nil {
-		return fmt.Errorf("backup history not found: %w", err)
+		return report, fmt.Errorf("backup history not found: %w", err)
 	}
+	report.FileName = history.FileName
 
 	// 验证备份状态
 	if history.Status != "success" {
-		return fmt.Errorf("cannot restore failed backup")
+		return report, fmt.Errorf("cannot restore failed backup")
+	}
+	if history.BackupType == "clickhouse" {
+		return report, fmt.Errorf("ClickHouse backups must be restored via /api/database-backup/clickhouse/restore")
 	}
 
 	ctx := context.Background()
-	var backupData []byte
+	var source io.ReadCloser
 	var err error
 
-	// 从S3下载备份文件
-	if history.S3Key != "" {
-		backupData, err = s.downloadFromS3(ctx, &history.Config, history.S3Key)
+	// 从远程存储流式读取备份文件，避免把整个备份加载到内存
+	if history.StoragePath != "" {
+		source, err = s.openFromStorage(ctx, &history.Config, history.StoragePath)
+		if err != nil {
+			return report, fmt.Errorf("failed to download from %s: %w", history.StorageType, err)
+		}
+		report.Steps = append(report.Steps, fmt.Sprintf("从 %s 存储下载备份: %s", history.StorageType, history.StoragePath))
+	} else if history.S3Key != "" {
+		source, err = s.openFromS3(ctx, &history.Config, history.S3Key)
 		if err != nil {
-			return fmt.Errorf("failed to download from S3: %w", err)
+			return report, fmt.Errorf("failed to download from S3: %w", err)
 		}
+		report.Steps = append(report.Steps, "从 S3 下载备份: "+history.S3Key)
 	} else if history.FilePath != "" {
 		// 从本地文件读取
-		backupData, err = os.ReadFile(history.FilePath)
+		source, err = os.Open(history.FilePath)
 		if err != nil {
-			return fmt.Errorf("failed to read local backup file: %w", err)
+			return report, fmt.Errorf("failed to read local backup file: %w", err)
 		}
+		report.Steps = append(report.Steps, "读取本地备份: "+history.FilePath)
 	} else {
-		return fmt.Errorf("no backup file available")
+		return report, fmt.Errorf("no backup file available")
 	}
+	defer source.Close()
+
+	var reader io.Reader = source
 
 	// 解密（如果需要）
-	if history.Config.EncryptionEnabled && strings.HasSuffix(history.FileName, ".enc") {
-		if request.BackupPassword == "" {
-			return fmt.Errorf("backup password required for encrypted backup")
+	if strings.HasSuffix(history.FileName, ".enc") {
+		password := request.BackupPassword
+		if history.WrappedDataKey != "" {
+			password, err = s.keyService.UnwrapDataKey(&history)
+			if err != nil {
+				return report, fmt.Errorf("failed to unwrap data key: %w", err)
+			}
+		}
+		if password == "" {
+			return report, fmt.Errorf("backup password required for encrypted backup")
 		}
-		backupData, err = s.decryptData(backupData, request.BackupPassword)
+		reader, err = s.decryptReader(reader, password)
 		if err != nil {
-			return fmt.Errorf("failed to decrypt backup: %w", err)
+			return report, fmt.Errorf("failed to decrypt backup: %w", err)
 		}
+		report.Steps = append(report.Steps, "解密备份")
 	}
 
 	// 创建临时文件
-	tempDir := os.TempDir()
-	tempFile := filepath.Join(tempDir, "restore_"+history.FileName)
-	defer os.Remove(tempFile)
-
-	if err := os.WriteFile(tempFile, backupData, 0644); err != nil {
-		return fmt.Errorf("failed to write temp file: %w", err)
+	tempFile, err := writeRestoreTempFile(reader)
+	if err != nil {
+		return report, err
 	}
+	defer os.Remove(tempFile)
 
 	// 恢复数据库
-	return s.restoreDatabase(tempFile, history.Config.CompressionEnabled)
+	compressed := strings.HasSuffix(strings.TrimSuffix(history.FileName, ".enc"), ".zip")
+	return report, s.restoreDatabase(tempFile, compressed, history.Checksum, report)
 }
 
-// downloadFromS3 从S3下载文件
-func (s *DatabaseBackupService) downloadFromS3(ctx context.Context, config *models.BackupConfig, s3Key string) ([]byte, error) {
-	s3Config := S3Config{
-		AccessKey: config.S3AccessKey,
-		SecretKey: config.S3SecretKey,
-		Region:    config.S3Region,
-		Bucket:    config.S3Bucket,
-		Endpoint:  config.S3Endpoint,
+// RestoreFile 从本地备份文件恢复数据库，支持 .db、.zip 以及加密的 .enc 文件
+func (s *DatabaseBackupService) RestoreFile(path, password string) (*models.BackupRestoreReport, error) {
+	report := &models.BackupRestoreReport{
+		FileName:  filepath.Base(path),
+		StartedAt: time.Now(),
 	}
+	defer func() { report.DurationMs = time.Since(report.StartedAt).Milliseconds() }()
 
-	s3Client, err := NewS3Service(s3Config, s.db)
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+		return report, fmt.Errorf("failed to read backup file: %w", err)
 	}
+	defer file.Close()
 
-	return s3Client.DownloadFile(ctx, s3Key)
-}
-
-// decryptData 解密数据
-func (s *DatabaseBackupService) decryptData(data []byte, key string) ([]byte, error) {
-	if len(data) < aes.BlockSize {
-		return nil, fmt.Errorf("ciphertext too short")
+	var reader io.Reader = file
+	name := filepath.Base(path)
+	if strings.HasSuffix(name, ".enc") {
+		if password == "" {
+			return report, fmt.Errorf("backup password required for encrypted backup")
+		}
+		reader, err = s.decryptReader(reader, password)
+		if err != nil {
+			return report, fmt.Errorf("failed to decrypt backup: %w", err)
+		}
+		name = strings.TrimSuffix(name, ".enc")
+		report.Steps = append(report.Steps, "解密备份")
 	}
 
-	// 生成32字节的密钥
-	hasher := sha256.New()
-	hasher.Write([]byte(key))
-	keyBytes := hasher.Sum(nil)
-
-	// 创建AES解密器
-	block, err := aes.NewCipher(keyBytes)
+	tempFile, err := writeRestoreTempFile(reader)
 	if err != nil {
-		return nil, err
+		return report, err
 	}
+	defer os.Remove(tempFile)
 
-	// 提取IV和加密数据
-	iv := data[:aes.BlockSize]
-	encrypted := data[aes.BlockSize:]
-
-	// 解密数据
-	stream := cipher.NewCFBDecrypter(block, iv)
-	decrypted := make([]byte, len(encrypted))
-	stream.XORKeyStream(decrypted, encrypted)
-
-	return decrypted, nil
+	// 备份文件没有对应的历史记录，无法校验备份时的校验和
+	return report, s.restoreDatabase(tempFile, strings.HasSuffix(name, ".zip"), "", report)
 }
 
-// restoreDatabase 恢复数据库
-func (s *DatabaseBackupService) restoreDatabase(backupFile string, isCompressed bool) error {
-	// 停止当前的数据库连接（需要谨慎处理）
-	// 这是一个危险操作，实际使用时应该有更完善的停机流程
-	
-	var sourceFile string
-	if isCompressed {
-		// 解压缩
-		tempDir := os.TempDir()
-		extractDir := filepath.Join(tempDir, "restore_extract")
-		defer os.RemoveAll(extractDir)
-		
-		if err := s.extractZip(backupFile, extractDir); err != nil {
-			return fmt.Errorf("failed to extract backup: %w", err)
-		}
-		
-		// 查找数据库文件
-		dbFileName := filepath.Base(s.config.DBPath)
-		sourceFile = filepath.Join(extractDir, dbFileName)
-	} else {
-		sourceFile = backupFile
-	}
-
-	// 备份当前数据库
-	currentDBBackup := s.config.DBPath + ".restore_backup"
-	if err := s.copyFile(s.config.DBPath, currentDBBackup); err != nil {
-		return fmt.Errorf("failed to backup current database: %w", err)
+// openFromStorage 从远程存储流式读取文件，返回的 reader 关闭时一并释放存储连接
+func (s *DatabaseBackupService) openFromStorage(ctx context.Context, config *models.BackupConfig, storagePath string) (io.ReadCloser, error) {
+	storage, err := newBackupConfigStorage(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
 	}
 
-	// 恢复数据库文件
-	if err := s.copyFile(sourceFile, s.config.DBPath); err != nil {
-		// 恢复失败，还原原始文件
-		s.copyFile(currentDBBackup, s.config.DBPath)
-		return fmt.Errorf("failed to restore database: %w", err)
+	body, _, err := OpenBackupRange(ctx, storage, storagePath, 0, -1)
+	if err != nil {
+		storage.Close()
+		return nil, err
 	}
+	return &storageReadCloser{ReadCloser: body, storage: storage}, nil
+}
 
-	// 删除备份文件
-	os.Remove(currentDBBackup)
+// storageReadCloser 关闭读取流后再关闭存储连接（SFTP 等需要保持连接直到读完）
+type storageReadCloser struct {
+	io.ReadCloser
+	storage BackupStorage
+}
 
-	return nil
+func (r *storageReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.storage.Close()
+	return err
 }
 
-// extractZip 解压缩文件
-func (s *DatabaseBackupService) extractZip(src, dest string) error {
-	reader, err := zip.OpenReader(src)
-	if err != nil {
-		return err
+// openFromS3 从S3流式读取文件（旧版只记录了 S3Key 的备份）
+func (s *DatabaseBackupService) openFromS3(ctx context.Context, config *models.BackupConfig, s3Key string) (io.ReadCloser, error) {
+	s3Config := S3Config{
+		AccessKey: config.S3AccessKey,
+		SecretKey: config.S3SecretKey,
+		Region:    config.S3Region,
+		Bucket:    config.S3Bucket,
+		Endpoint:  config.S3Endpoint,
 	}
-	defer reader.Close()
-
-	os.MkdirAll(dest, 0755)
 
-	for _, file := range reader.File {
-		path := filepath.Join(dest, file.Name)
-		
-		if file.FileInfo().IsDir() {
-			os.MkdirAll(path, file.FileInfo().Mode())
-			continue
-		}
+	s3Client, err := NewS3Service(s3Config, s.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
 
-		rc, err := file.Open()
-		if err != nil {
-			return err
-		}
-		defer rc.Close()
+	return s3Client.OpenFile(ctx, s3Key)
+}
 
-		outFile, err := os.Create(path)
-		if err != nil {
-			return err
-		}
-		defer outFile.Close()
+// decryptReader 返回边读边解密的 reader，输入为 IV + AES-CFB 密文
+func (s *DatabaseBackupService) decryptReader(r io.Reader, key string) (io.Reader, error) {
+	// 提取IV
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(r, iv); err != nil {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
 
-		_, err = io.Copy(outFile, rc)
-		if err != nil {
-			return err
-		}
+	block, err := aes.NewCipher(backupCipherKey(key))
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	stream := cipher.NewCFBDecrypter(block, iv)
+	return &cipher.StreamReader{S: stream, R: r}, nil
+}
+
+// backupCipherKey 由密钥字符串生成32字节的 AES 密钥
+func backupCipherKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
 }
 
 // copyFile 复制文件
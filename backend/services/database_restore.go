@@ -0,0 +1,300 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"smartdns-manager/models"
+)
+
+const (
+	// preRestoreSnapshotKeep 保留的恢复前快照数量
+	preRestoreSnapshotKeep = 5
+	// restoreLockTimeout 等待正在执行的数据库操作结束的最长时间
+	restoreLockTimeout = 30 * time.Second
+)
+
+// writeRestoreTempFile 把下载或解密后的备份写入临时文件
+func writeRestoreTempFile(r io.Reader) (string, error) {
+	file, err := os.CreateTemp("", "smartdns_restore_*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// restoreDatabase 校验备份并恢复数据库
+//
+// 备份文件先校验 SHA-256 和 PRAGMA integrity_check，通过后独占当前数据库连接，
+// 给当前数据库做快照，再用 SQLite 在线备份接口整体替换数据库内容，
+// 不直接覆盖正在使用的数据库文件。替换失败时从快照回退。
+func (s *DatabaseBackupService) restoreDatabase(backupFile string, isCompressed bool, expectedChecksum string, report *models.BackupRestoreReport) error {
+	sourceFile := backupFile
+	if isCompressed {
+		extractDir, err := os.MkdirTemp("", "smartdns_restore_extract_*")
+		if err != nil {
+			return fmt.Errorf("failed to create extract directory: %w", err)
+		}
+		defer os.RemoveAll(extractDir)
+
+		sourceFile, err = s.extractDatabase(backupFile, extractDir)
+		if err != nil {
+			return fmt.Errorf("failed to extract backup: %w", err)
+		}
+		report.Steps = append(report.Steps, "解压备份")
+	}
+
+	// 校验和
+	checksum, size, err := fileChecksum(sourceFile)
+	if err != nil {
+		return fmt.Errorf("failed to checksum backup: %w", err)
+	}
+	report.Checksum = checksum
+	report.RestoredSize = size
+	if expectedChecksum != "" {
+		if checksum != expectedChecksum {
+			return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedChecksum, checksum)
+		}
+		report.ChecksumVerified = true
+		report.Steps = append(report.Steps, "校验和一致")
+	} else {
+		report.Steps = append(report.Steps, "备份未记录校验和，跳过校验")
+	}
+
+	// 完整性检查
+	tables, result, err := checkSQLiteFile(sourceFile)
+	report.IntegrityCheck = result
+	report.Tables = tables
+	if err != nil {
+		return fmt.Errorf("backup integrity check failed: %w", err)
+	}
+	report.Steps = append(report.Steps, fmt.Sprintf("完整性检查通过（%d 张表）", tables))
+
+	// 全新安装时可能还没有数据库文件
+	_, statErr := os.Stat(s.config.DBPath)
+	dbExists := statErr == nil
+	if err := os.MkdirAll(filepath.Dir(s.config.DBPath), 0755); err != nil {
+		return fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	live, release, err := s.liveDB()
+	if err != nil {
+		return fmt.Errorf("failed to open current database: %w", err)
+	}
+	defer release()
+
+	// 限制为单个连接并占住它：等待进行中的操作结束，恢复期间其他请求排队
+	maxOpen := live.Stats().MaxOpenConnections
+	live.SetMaxOpenConns(1)
+	defer live.SetMaxOpenConns(maxOpen)
+
+	ctx, cancel := context.WithTimeout(context.Background(), restoreLockTimeout)
+	conn, err := live.Conn(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to acquire database connection: %w", err)
+	}
+	defer conn.Close()
+	report.Steps = append(report.Steps, "已独占数据库连接")
+
+	ctx = context.Background()
+	if dbExists {
+		snapshotPath, err := s.snapshotDatabase(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("failed to snapshot current database: %w", err)
+		}
+		report.SnapshotPath = snapshotPath
+		if stat, err := os.Stat(snapshotPath); err == nil {
+			report.SnapshotSize = stat.Size()
+		}
+		report.Steps = append(report.Steps, "恢复前快照: "+snapshotPath)
+	}
+
+	if err := sqliteBackupInto(ctx, conn, sourceFile); err != nil {
+		if report.SnapshotPath != "" {
+			if rollbackErr := sqliteBackupInto(ctx, conn, report.SnapshotPath); rollbackErr != nil {
+				return fmt.Errorf("failed to restore database: %v; rollback failed: %w", err, rollbackErr)
+			}
+			report.RolledBack = true
+			report.Steps = append(report.Steps, "恢复失败，已从快照回退")
+		}
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+	report.Steps = append(report.Steps, "已替换数据库内容")
+
+	report.PostRestoreCheck, err = integrityCheck(ctx, conn)
+	if err != nil {
+		report.PostRestoreCheck = err.Error()
+	}
+	if report.PostRestoreCheck != "ok" {
+		return fmt.Errorf("restored database failed integrity check: %s", report.PostRestoreCheck)
+	}
+	report.Steps = append(report.Steps, "恢复后完整性检查通过")
+	return nil
+}
+
+// liveDB 返回当前数据库的连接池，命令行恢复时服务未打开数据库则单独打开
+func (s *DatabaseBackupService) liveDB() (*sql.DB, func(), error) {
+	if s.db != nil {
+		sqlDB, err := s.db.DB()
+		return sqlDB, func() {}, err
+	}
+
+	sqlDB, err := sql.Open("sqlite3", s.config.DBPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sqlDB, func() { sqlDB.Close() }, nil
+}
+
+// snapshotDatabase 用 VACUUM INTO 保存当前数据库的一致快照，并清理过旧的快照
+func (s *DatabaseBackupService) snapshotDatabase(ctx context.Context, conn *sql.Conn) (string, error) {
+	dir := filepath.Join(filepath.Dir(s.config.DBPath), "snapshots")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("pre_restore_%s.db", time.Now().Format("20060102_150405")))
+	os.Remove(path) // 同一秒内重复恢复时覆盖
+	if _, err := conn.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return "", err
+	}
+
+	snapshots, _ := filepath.Glob(filepath.Join(dir, "pre_restore_*.db"))
+	sort.Strings(snapshots)
+	for len(snapshots) > preRestoreSnapshotKeep {
+		os.Remove(snapshots[0])
+		snapshots = snapshots[1:]
+	}
+	return path, nil
+}
+
+// extractDatabase 从压缩备份中取出数据库文件，返回解压后的路径
+func (s *DatabaseBackupService) extractDatabase(src, dest string) (string, error) {
+	reader, err := zip.OpenReader(src)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var entry *zip.File
+	var files []*zip.File
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		files = append(files, file)
+		if filepath.Base(file.Name) == filepath.Base(s.config.DBPath) {
+			entry = file
+		}
+	}
+	// 数据库文件名改过时，只有一个文件的备份仍可恢复
+	if entry == nil && len(files) == 1 {
+		entry = files[0]
+	}
+	if entry == nil {
+		return "", fmt.Errorf("database file not found in backup")
+	}
+
+	rc, err := entry.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	path := filepath.Join(dest, "restore.db")
+	outFile, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer outFile.Close()
+
+	if _, err := io.Copy(outFile, rc); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// fileChecksum 计算文件的 SHA-256 和大小
+func fileChecksum(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
+
+// checkSQLiteFile 以只读方式打开备份文件执行完整性检查，返回表数量和检查结果
+func checkSQLiteFile(path string) (int, string, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, "", err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	result, err := integrityCheck(ctx, db)
+	if err != nil {
+		return 0, err.Error(), err
+	}
+	if result != "ok" {
+		return 0, result, fmt.Errorf("%s", result)
+	}
+
+	var tables int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables); err != nil {
+		return 0, result, err
+	}
+	if tables == 0 {
+		return 0, result, fmt.Errorf("backup contains no tables")
+	}
+	return tables, result, nil
+}
+
+// integrityCheck 执行 PRAGMA integrity_check，数据库完好时返回 ok
+func integrityCheck(ctx context.Context, db interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}) (string, error) {
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check(20)")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "; "), nil
+}
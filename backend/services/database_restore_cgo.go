@@ -0,0 +1,49 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteBackupInto 用 SQLite 在线备份接口把 srcPath 的内容整体写入 conn 所在的数据库
+func sqliteBackupInto(ctx context.Context, conn *sql.Conn, srcPath string) error {
+	srcDB, err := sql.Open("sqlite3", "file:"+srcPath+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer srcDB.Close()
+
+	srcConn, err := srcDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return conn.Raw(func(destDriverConn interface{}) error {
+		dest, ok := destDriverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unsupported database driver %T", destDriverConn)
+		}
+		return srcConn.Raw(func(srcDriverConn interface{}) error {
+			src, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unsupported database driver %T", srcDriverConn)
+			}
+
+			backup, err := dest.Backup("main", src, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
@@ -0,0 +1,114 @@
+//go:build !cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// sqliteBackupInto 不启用 cgo 编译时没有 SQLite 在线备份接口，改为 ATTACH 备份文件，
+// 在一个事务内删除当前数据库的表和视图，再按备份中的定义重建并复制数据
+func sqliteBackupInto(ctx context.Context, conn *sql.Conn, srcPath string) error {
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS restore_src", srcPath); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE restore_src")
+
+	// 外键检查不能在事务内切换，复制期间关闭，结束后恢复原设置
+	var foreignKeys int
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return err
+	}
+	if foreignKeys == 1 {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := sqliteObjects(ctx, tx, "main", "type IN ('view', 'table')")
+	if err != nil {
+		return err
+	}
+	// 倒序删除，先删除视图再删除表，索引和触发器随表删除
+	for i := len(current) - 1; i >= 0; i-- {
+		object := current[i]
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DROP %s main.%s", strings.ToUpper(object.Type), quoteSQLiteIdent(object.Name))); err != nil {
+			return fmt.Errorf("drop %s %s: %w", object.Type, object.Name, err)
+		}
+	}
+
+	backup, err := sqliteObjects(ctx, tx, "restore_src", "type IN ('table', 'index', 'view', 'trigger')")
+	if err != nil {
+		return err
+	}
+	// 先建表并复制数据，再建索引、视图和触发器
+	for _, object := range backup {
+		if _, err := tx.ExecContext(ctx, object.SQL); err != nil {
+			return fmt.Errorf("create %s %s: %w", object.Type, object.Name, err)
+		}
+		if object.Type != "table" {
+			continue
+		}
+		name := quoteSQLiteIdent(object.Name)
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO main.%s SELECT * FROM restore_src.%s", name, name)); err != nil {
+			return fmt.Errorf("copy table %s: %w", object.Name, err)
+		}
+	}
+
+	// 自增序列不在 sqlite_master 的定义中，按备份单独复制
+	var sequences int
+	tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM restore_src.sqlite_master WHERE name = 'sqlite_sequence'").Scan(&sequences)
+	if sequences > 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM main.sqlite_sequence"); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO main.sqlite_sequence SELECT * FROM restore_src.sqlite_sequence"); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// sqliteObject sqlite_master 中的一个对象
+type sqliteObject struct {
+	Type string
+	Name string
+	SQL  string
+}
+
+// sqliteObjects 列出 schema 中符合条件的对象，不包含 SQLite 内部表，按表、索引、视图、触发器排序
+func sqliteObjects(ctx context.Context, tx *sql.Tx, schema, where string) ([]sqliteObject, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT type, name, sql FROM %s.sqlite_master
+		WHERE %s AND sql IS NOT NULL AND name NOT LIKE 'sqlite_%%'
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 WHEN 'view' THEN 2 ELSE 3 END, rowid`, schema, where))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []sqliteObject
+	for rows.Next() {
+		var object sqliteObject
+		if err := rows.Scan(&object.Type, &object.Name, &object.SQL); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	return objects, rows.Err()
+}
+
+// quoteSQLiteIdent 为标识符加双引号
+func quoteSQLiteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
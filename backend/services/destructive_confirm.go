@@ -0,0 +1,81 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"smartdns-manager/config"
+)
+
+// destructiveConfirmTTL 确认令牌有效期
+const destructiveConfirmTTL = 5 * time.Minute
+
+// destructiveConfirm 预估影响后签发的确认令牌，绑定操作、请求内容和用户
+type destructiveConfirm struct {
+	operation   string
+	fingerprint string
+	userID      uint
+	expiresAt   time.Time
+}
+
+// DestructiveConfirmService 破坏性操作的两阶段确认：先预估影响范围并签发令牌，
+// 提交时携带令牌，令牌只能使用一次，请求内容变化后失效
+type DestructiveConfirmService struct {
+	mu     sync.Mutex
+	tokens map[string]destructiveConfirm
+}
+
+// NewDestructiveConfirmService 创建破坏性操作确认服务
+func NewDestructiveConfirmService() *DestructiveConfirmService {
+	return &DestructiveConfirmService{
+		tokens: make(map[string]destructiveConfirm),
+	}
+}
+
+// DestructiveConfirmEnforced 是否要求破坏性操作必须携带确认令牌
+func DestructiveConfirmEnforced() bool {
+	return strings.EqualFold(strings.TrimSpace(config.GetConfig().DestructiveConfirm), "enforce")
+}
+
+// cleanupLocked 清理过期令牌，调用方需持有锁
+func (s *DestructiveConfirmService) cleanupLocked(now time.Time) {
+	for token, confirm := range s.tokens {
+		if now.After(confirm.expiresAt) {
+			delete(s.tokens, token)
+		}
+	}
+}
+
+// Issue 为一次预估签发确认令牌
+func (s *DestructiveConfirmService) Issue(operation, fingerprint string, userID uint) (string, time.Time) {
+	token := randomToken(16)
+	expiresAt := time.Now().Add(destructiveConfirmTTL)
+
+	s.mu.Lock()
+	s.cleanupLocked(time.Now())
+	s.tokens[token] = destructiveConfirm{
+		operation:   operation,
+		fingerprint: fingerprint,
+		userID:      userID,
+		expiresAt:   expiresAt,
+	}
+	s.mu.Unlock()
+	return token, expiresAt
+}
+
+// Consume 校验并作废令牌，操作、请求内容和用户都必须与预估时一致
+func (s *DestructiveConfirmService) Consume(token, operation, fingerprint string, userID uint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	confirm, ok := s.tokens[token]
+	if !ok {
+		return false
+	}
+	delete(s.tokens, token)
+	return time.Now().Before(confirm.expiresAt) &&
+		confirm.operation == operation &&
+		confirm.fingerprint == fingerprint &&
+		confirm.userID == userID
+}
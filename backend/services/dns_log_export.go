@@ -0,0 +1,451 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// dnsLogExportBatchSize 每批从日志存储读取的条数
+const dnsLogExportBatchSize = 5000
+
+// dnsLogExportCSVHeader CSV 导出的列
+var dnsLogExportCSVHeader = []string{
+	"timestamp", "node_id", "client_ip", "domain", "query_type", "time_ms", "speed_ms", "result_ips",
+	"group", "domain_category", "client_subnet", "client_country", "client_asn", "client_as_org", "client_ptr",
+	"upstream", "rcode", "cache_hit", "matched_rule",
+}
+
+// DNSLogExportService 在后台把筛选后的 DNS 日志导出为 CSV 或 JSONL 文件
+//
+// 分页接口一次最多返回 50 条，分析大量日志时通过导出获取完整数据。
+// 支持游标翻页的存储按游标分批读取，避免深翻页时 OFFSET 越来越慢。
+type DNSLogExportService struct {
+	db         *gorm.DB
+	logStorage LogMonitorInterface
+	exportDir  string
+}
+
+// dnsLogExportService 供后台任务使用，由 NewDNSLogExportService 设置
+var dnsLogExportService *DNSLogExportService
+
+// NewDNSLogExportService 创建日志导出服务，导出文件保存在数据库目录下的 exports 目录
+func NewDNSLogExportService(db *gorm.DB, logStorage LogMonitorInterface) *DNSLogExportService {
+	service := &DNSLogExportService{
+		db:         db,
+		logStorage: logStorage,
+		exportDir:  filepath.Join(filepath.Dir(config.GetConfig().DBPath), "exports"),
+	}
+	dnsLogExportService = service
+	return service
+}
+
+func init() {
+	RegisterJobHandler(models.BackgroundJobDNSLogExport, JobHandler{
+		Run: func(job *JobContext) error {
+			if dnsLogExportService == nil {
+				return fmt.Errorf("日志导出服务未初始化")
+			}
+			var exportID uint
+			if err := job.Payload(&exportID); err != nil {
+				return fmt.Errorf("解析任务参数失败: %w", err)
+			}
+			return dnsLogExportService.run(job.Context(), exportID)
+		},
+		Guidance:      "日志导出在服务重启时中断，导出文件不完整，请重新提交导出",
+		OnInterrupted: markDNSLogExportInterrupted,
+		Cancellable:   true,
+		OnCancelled:   markDNSLogExportCancelled,
+	})
+}
+
+// Create 创建导出记录并提交后台任务，params 为 GET /api/dns-logs 的过滤参数
+func (s *DNSLogExportService) Create(ctx context.Context, params map[string]string, format, destination string, maxRows int64, createdBy string) (*models.DNSLogExport, error) {
+	if format == "" {
+		format = models.DNSLogExportFormatCSV
+	}
+	if format != models.DNSLogExportFormatCSV && format != models.DNSLogExportFormatJSONL {
+		return nil, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+	if destination == "" {
+		destination = models.DNSLogExportLocal
+	}
+
+	storageType := models.StorageTypeLocal
+	switch destination {
+	case models.DNSLogExportLocal:
+	case models.DNSLogExportRemote:
+		storageType = NewBackupStorageManager().GetStorageType()
+		if storageType == "" || storageType == models.StorageTypeLocal {
+			return nil, fmt.Errorf("未配置远程存储（BACKUP_STORAGE_TYPE），无法导出到远程存储")
+		}
+	default:
+		return nil, fmt.Errorf("不支持的导出位置: %s", destination)
+	}
+	if maxRows < 0 {
+		maxRows = 0
+	}
+
+	filters := make(map[string]string)
+	for _, key := range DNSLogFilterParams {
+		if value := params[key]; value != "" {
+			filters[key] = value
+		}
+	}
+	filtersJSON, _ := json.Marshal(filters)
+
+	export := &models.DNSLogExport{
+		Format:      format,
+		Filters:     string(filtersJSON),
+		MaxRows:     maxRows,
+		Destination: destination,
+		StorageType: storageType,
+		Status:      models.BackgroundJobStatusQueued,
+		CreatedBy:   createdBy,
+	}
+	if err := s.db.Create(export).Error; err != nil {
+		return nil, fmt.Errorf("创建导出记录失败: %w", err)
+	}
+
+	job, err := StartJob(ctx, models.BackgroundJobDNSLogExport, 0, fmt.Sprintf("导出 DNS 日志 #%d (%s)", export.ID, format), export.ID)
+	if err != nil {
+		s.db.Delete(export)
+		return nil, err
+	}
+	export.JobID = job.ID
+	s.db.Model(export).Update("job_id", job.ID)
+	return export, nil
+}
+
+// Get 获取导出记录
+func (s *DNSLogExportService) Get(id uint) (*models.DNSLogExport, error) {
+	var export models.DNSLogExport
+	if err := s.db.First(&export, id).Error; err != nil {
+		return nil, fmt.Errorf("导出记录不存在")
+	}
+	return &export, nil
+}
+
+// List 最近的导出记录
+func (s *DNSLogExportService) List(limit int) ([]models.DNSLogExport, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	var exports []models.DNSLogExport
+	err := s.db.Order("id DESC").Limit(limit).Find(&exports).Error
+	return exports, err
+}
+
+// Open 打开已完成的导出文件，返回内容和大小
+func (s *DNSLogExportService) Open(ctx context.Context, export *models.DNSLogExport) (io.ReadCloser, int64, error) {
+	if export.Status != models.BackgroundJobStatusSucceeded {
+		return nil, 0, fmt.Errorf("导出尚未完成，当前状态: %s", export.Status)
+	}
+
+	if export.Destination == models.DNSLogExportRemote {
+		storage, err := NewBackupStorageManager().newRemoteStorage()
+		if err != nil {
+			return nil, 0, fmt.Errorf("初始化%s存储失败: %w", export.StorageType, err)
+		}
+		body, _, err := OpenBackupRange(ctx, storage, export.StoragePath, 0, -1)
+		if err != nil {
+			storage.Close()
+			return nil, 0, err
+		}
+		return &storageReadCloser{ReadCloser: body, storage: storage}, export.SizeBytes, nil
+	}
+
+	file, err := os.Open(export.FilePath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("导出文件不存在: %w", err)
+	}
+	return file, export.SizeBytes, nil
+}
+
+// Delete 删除导出记录和文件，进行中的导出不能删除
+func (s *DNSLogExportService) Delete(ctx context.Context, id uint) error {
+	export, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if export.Status == models.BackgroundJobStatusQueued || export.Status == models.BackgroundJobStatusRunning {
+		return fmt.Errorf("导出正在进行，不能删除")
+	}
+
+	if export.FilePath != "" {
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除导出文件失败: %w", err)
+		}
+	}
+	if export.StoragePath != "" {
+		storage, err := NewBackupStorageManager().newRemoteStorage()
+		if err != nil {
+			return fmt.Errorf("初始化%s存储失败: %w", export.StorageType, err)
+		}
+		defer storage.Close()
+		if err := storage.Delete(ctx, export.StoragePath); err != nil {
+			return fmt.Errorf("删除远程导出文件失败: %w", err)
+		}
+	}
+	return s.db.Delete(export).Error
+}
+
+// run 执行导出：写入本地文件，导出到远程存储时上传后删除本地文件
+func (s *DNSLogExportService) run(ctx context.Context, exportID uint) (err error) {
+	export, err := s.Get(exportID)
+	if err != nil {
+		return err
+	}
+	s.db.Model(export).Update("status", models.BackgroundJobStatusRunning)
+
+	defer func() {
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":       models.BackgroundJobStatusSucceeded,
+			"rows":         export.Rows,
+			"size_bytes":   export.SizeBytes,
+			"file_path":    export.FilePath,
+			"storage_path": export.StoragePath,
+			"file_name":    export.FileName,
+			"finished_at":  &now,
+		}
+		if err != nil {
+			updates["status"] = models.BackgroundJobStatusFailed
+			if ctx.Err() != nil {
+				updates["status"] = models.BackgroundJobStatusCancelled
+			}
+			updates["error"] = err.Error()
+		}
+		s.db.Model(export).Updates(updates)
+	}()
+
+	var params map[string]string
+	if err := json.Unmarshal([]byte(export.Filters), &params); err != nil {
+		return fmt.Errorf("解析过滤条件失败: %w", err)
+	}
+	filters := ParseDNSLogFilters(func(key string) string { return params[key] })
+	// 按时间正序导出
+	filters["sort_field"] = "timestamp"
+	filters["sort_order"] = "asc"
+
+	if err := os.MkdirAll(s.exportDir, 0755); err != nil {
+		return fmt.Errorf("创建导出目录失败: %w", err)
+	}
+	export.FileName = fmt.Sprintf("dns-logs-%d-%s.%s", export.ID, export.CreatedAt.Format("20060102-150405"), export.Format)
+	localPath := filepath.Join(s.exportDir, export.FileName)
+	// 先记录文件路径，服务重启中断时可以清理不完整的文件
+	s.db.Model(export).Update("file_path", localPath)
+
+	if err := s.writeFile(ctx, export, filters, localPath); err != nil {
+		os.Remove(localPath)
+		return err
+	}
+
+	if export.Destination != models.DNSLogExportRemote {
+		export.FilePath = localPath
+		log.Printf("✅ DNS 日志导出 #%d 完成: %d 条, %s", export.ID, export.Rows, localPath)
+		return nil
+	}
+
+	defer os.Remove(localPath)
+	storagePath, err := s.upload(localPath, export)
+	if err != nil {
+		return fmt.Errorf("上传到%s存储失败: %w", export.StorageType, err)
+	}
+	export.StoragePath = storagePath
+	log.Printf("✅ DNS 日志导出 #%d 完成: %d 条, 已上传到 %s: %s", export.ID, export.Rows, export.StorageType, storagePath)
+	return nil
+}
+
+// writeFile 分批读取日志写入导出文件
+func (s *DNSLogExportService) writeFile(ctx context.Context, export *models.DNSLogExport, filters map[string]interface{}, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建导出文件失败: %w", err)
+	}
+	defer file.Close()
+
+	buffered := bufio.NewWriterSize(file, 1<<20)
+	var csvWriter *csv.Writer
+	var jsonEncoder *json.Encoder
+	if export.Format == models.DNSLogExportFormatCSV {
+		csvWriter = csv.NewWriter(buffered)
+		if err := csvWriter.Write(dnsLogExportCSVHeader); err != nil {
+			return err
+		}
+	} else {
+		jsonEncoder = json.NewEncoder(buffered)
+	}
+
+	err = s.eachBatch(filters, export.MaxRows, func(logs []models.DNSLog) error {
+		// 取消导出时在批次之间停止
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for i := range logs {
+			if csvWriter != nil {
+				if err := csvWriter.Write(dnsLogCSVRecord(&logs[i])); err != nil {
+					return err
+				}
+			} else if err := jsonEncoder.Encode(&logs[i]); err != nil {
+				return err
+			}
+		}
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		export.Rows += int64(len(logs))
+		// 记录进度，状态接口可以看到已导出的条数
+		s.db.Model(export).Update("rows", export.Rows)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("导出日志失败: %w", err)
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("写入导出文件失败: %w", err)
+	}
+	if stat, err := file.Stat(); err == nil {
+		export.SizeBytes = stat.Size()
+	}
+	return nil
+}
+
+// eachBatch 按批读取符合条件的日志，maxRows 为 0 时读取全部
+func (s *DNSLogExportService) eachBatch(filters map[string]interface{}, maxRows int64, handle func([]models.DNSLog) error) error {
+	var total int64
+	limit := func(logs []models.DNSLog) ([]models.DNSLog, bool) {
+		if maxRows > 0 && total+int64(len(logs)) >= maxRows {
+			return logs[:maxRows-total], true
+		}
+		return logs, false
+	}
+
+	if pager, ok := s.logStorage.(LogCursorPager); ok {
+		cursor := ""
+		for {
+			logs, next, err := pager.GetLogsByCursor(cursor, dnsLogExportBatchSize, filters)
+			if err != nil {
+				return err
+			}
+			logs, reached := limit(logs)
+			if err := handle(logs); err != nil {
+				return err
+			}
+			total += int64(len(logs))
+			if reached || next == "" {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	// 不支持游标的存储按页读取
+	for page := 1; ; page++ {
+		logs, _, err := s.logStorage.GetLogs(page, dnsLogExportBatchSize, filters)
+		if err != nil {
+			return err
+		}
+		logs, reached := limit(logs)
+		if err := handle(logs); err != nil {
+			return err
+		}
+		total += int64(len(logs))
+		if reached || len(logs) < dnsLogExportBatchSize {
+			return nil
+		}
+	}
+}
+
+// upload 把导出文件流式上传到远程存储
+func (s *DNSLogExportService) upload(localPath string, export *models.DNSLogExport) (string, error) {
+	storage, err := NewBackupStorageManager().newRemoteStorage()
+	if err != nil {
+		return "", err
+	}
+	defer storage.Close()
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+	return SaveBackupStream(ctx, storage, file, export.SizeBytes, export.FileName)
+}
+
+// dnsLogCSVRecord 转换为 CSV 行，列顺序与 dnsLogExportCSVHeader 一致
+func dnsLogCSVRecord(entry *models.DNSLog) []string {
+	return []string{
+		entry.Timestamp.Format(time.RFC3339Nano),
+		strconv.FormatUint(uint64(entry.NodeID), 10),
+		entry.ClientIP,
+		entry.Domain,
+		strconv.Itoa(entry.QueryType),
+		strconv.Itoa(entry.TimeMs),
+		strconv.FormatFloat(entry.SpeedMs, 'f', -1, 64),
+		entry.ResultIPs,
+		entry.Group,
+		entry.DomainCategory,
+		entry.ClientSubnet,
+		entry.ClientCountry,
+		strconv.FormatUint(uint64(entry.ClientASN), 10),
+		entry.ClientASOrg,
+		entry.ClientPTR,
+		entry.Upstream,
+		entry.Rcode,
+		strconv.FormatBool(entry.CacheHit),
+		entry.MatchedRule,
+	}
+}
+
+// markDNSLogExportInterrupted 导出任务中断时删除不完整的文件并标记导出记录
+func markDNSLogExportInterrupted(job *models.BackgroundJob) {
+	markDNSLogExport(job, models.BackgroundJobStatusInterrupted, "服务重启时导出正在进行，已中断")
+}
+
+// markDNSLogExportCancelled 排队中的导出任务被取消
+func markDNSLogExportCancelled(job *models.BackgroundJob) {
+	markDNSLogExport(job, models.BackgroundJobStatusCancelled, "导出在排队时被取消")
+}
+
+func markDNSLogExport(job *models.BackgroundJob, status, reason string) {
+	var exportID uint
+	if err := json.Unmarshal([]byte(job.Payload), &exportID); err != nil {
+		return
+	}
+	var export models.DNSLogExport
+	if err := database.DB.First(&export, exportID).Error; err != nil {
+		return
+	}
+	if export.FilePath != "" {
+		os.Remove(export.FilePath)
+	}
+	now := time.Now()
+	database.DB.Model(&export).Updates(map[string]interface{}{
+		"status":      status,
+		"error":       reason,
+		"file_path":   "",
+		"finished_at": &now,
+	})
+}
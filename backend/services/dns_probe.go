@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// DNS 探测协议
+const (
+	DNSProbeUDP = "udp"
+	DNSProbeTCP = "tcp"
+	DNSProbeDoT = "dot"
+)
+
+// DNSProbeResult 一次解析探测的结果
+type DNSProbeResult struct {
+	OK      bool
+	Latency time.Duration
+	Answers []string
+	Error   string
+}
+
+// DNSProbeEnabled 是否配置了深度健康检查的探测域名
+func DNSProbeEnabled() bool {
+	return strings.TrimSpace(config.GetConfig().HealthProbeDomain) != ""
+}
+
+// dnsProbeDialer 按协议创建连接，TCP/DoT 返回流式连接，Go 解析器会自动使用长度前缀帧
+func dnsProbeDialer(protocol, server string, timeout time.Duration) (func(ctx context.Context, network, address string) (net.Conn, error), error) {
+	switch protocol {
+	case DNSProbeUDP:
+		return func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, "udp", server)
+		}, nil
+	case DNSProbeTCP:
+		return func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, "tcp", server)
+		}, nil
+	case DNSProbeDoT:
+		return func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := tls.Dialer{
+				NetDialer: &net.Dialer{Timeout: timeout},
+				// 节点通常使用自签名证书，探测只关心是否能正常解析
+				Config: &tls.Config{InsecureSkipVerify: true},
+			}
+			return d.DialContext(ctx, "tcp", server)
+		}, nil
+	}
+	return nil, fmt.Errorf("不支持的探测协议: %s（可选 udp/tcp/dot）", protocol)
+}
+
+// nodeResolver 按健康检查的协议、端口和超时配置创建直接查询节点 SmartDNS 的解析器
+func nodeResolver(node *models.Node) (*net.Resolver, string, time.Duration, error) {
+	cfg := config.GetConfig()
+
+	protocol := strings.ToLower(strings.TrimSpace(cfg.HealthProbeProtocol))
+	if protocol == "" {
+		protocol = DNSProbeUDP
+	}
+	port := cfg.HealthProbePort
+	if port == "" {
+		port = "53"
+		if protocol == DNSProbeDoT {
+			port = "853"
+		}
+	}
+	timeout := 3 * time.Second
+	if seconds, err := strconv.Atoi(cfg.HealthProbeTimeout); err == nil && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	dial, err := dnsProbeDialer(protocol, net.JoinHostPort(node.Host, port), timeout)
+	if err != nil {
+		return nil, protocol, timeout, err
+	}
+	return &net.Resolver{PreferGo: true, Dial: dial}, protocol, timeout, nil
+}
+
+// ProbeNodeDNS 通过节点的 SmartDNS 解析探测域名，并校验应答是否在期望范围内
+func ProbeNodeDNS(ctx context.Context, node *models.Node) DNSProbeResult {
+	cfg := config.GetConfig()
+
+	resolver, protocol, timeout, err := nodeResolver(node)
+	if err != nil {
+		return DNSProbeResult{Error: err.Error()}
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	domain := strings.TrimSuffix(strings.TrimSpace(cfg.HealthProbeDomain), ".")
+	start := time.Now()
+	ips, err := resolver.LookupIP(queryCtx, "ip4", domain+".")
+	result := DNSProbeResult{Latency: time.Since(start)}
+	if err != nil {
+		result.Error = fmt.Sprintf("%s 解析 %s 失败: %v", strings.ToUpper(protocol), domain, err)
+		return result
+	}
+
+	for _, ip := range ips {
+		result.Answers = append(result.Answers, ip.String())
+	}
+
+	if expect := strings.TrimSpace(cfg.HealthProbeExpect); expect != "" {
+		ranges, err := parseAnswerExpect(strings.Split(expect, ","))
+		if err != nil {
+			result.Error = "HEALTH_PROBE_EXPECT 配置错误: " + err.Error()
+			return result
+		}
+		for _, ip := range ips {
+			for _, r := range ranges {
+				if r.Contains(ip) {
+					result.OK = true
+					return result
+				}
+			}
+		}
+		result.Error = fmt.Sprintf("%s 的应答 %s 不在期望范围 %s 内", domain, strings.Join(result.Answers, ", "), expect)
+		return result
+	}
+
+	result.OK = true
+	return result
+}
+
+// VerifyNodeConfig 确认节点正在按当前配置提供解析：优先解析一条下发到该节点的地址映射并核对应答，
+// 没有可用的地址映射时执行健康检查探测，两者都不可用时返回错误
+func VerifyNodeConfig(ctx context.Context, node *models.Node) error {
+	var addresses []models.AddressMap
+	database.DB.Where("enabled = ? AND type = ? AND ip <> ''", true, "address").Order("updated_at DESC").Find(&addresses)
+	for _, address := range addresses {
+		if strings.ContainsAny(address.Domain, "*/") || !nodeIDsContain(address.NodeIDs, node.ID) {
+			continue
+		}
+		expected := make(map[string]bool)
+		for _, ip := range strings.Split(address.IP, ",") {
+			if parsed := net.ParseIP(strings.TrimSpace(ip)); parsed != nil {
+				expected[parsed.String()] = true
+			}
+		}
+		if len(expected) == 0 {
+			continue
+		}
+
+		resolver, _, timeout, err := nodeResolver(node)
+		if err != nil {
+			return err
+		}
+		queryCtx, cancel := context.WithTimeout(ctx, timeout)
+		ips, err := resolver.LookupIP(queryCtx, "ip", strings.TrimSuffix(address.Domain, ".")+".")
+		cancel()
+		if err != nil {
+			return fmt.Errorf("解析 %s 失败: %w", address.Domain, err)
+		}
+		for _, ip := range ips {
+			if expected[ip.String()] {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s 的应答与地址映射 %s 不一致，配置未生效", address.Domain, address.IP)
+	}
+
+	if !DNSProbeEnabled() {
+		return fmt.Errorf("没有可用于核对的地址映射，也未配置健康检查探测域名")
+	}
+	if result := ProbeNodeDNS(ctx, node); !result.OK {
+		return fmt.Errorf("健康检查探测失败: %s", result.Error)
+	}
+	return nil
+}
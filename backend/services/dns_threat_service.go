@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// DNSThreatService DNS 隧道/DGA 启发式检测服务
+type DNSThreatService struct {
+	db                  *gorm.DB
+	config              *config.Config
+	notificationService *NotificationService
+}
+
+// NewDNSThreatService 创建 DNS 威胁检测服务
+func NewDNSThreatService(db *gorm.DB, config *config.Config) (*DNSThreatService, error) {
+	return &DNSThreatService{
+		db:                  db,
+		config:              config,
+		notificationService: NewNotificationService(),
+	}, nil
+}
+
+// applyDefaults 填充默认检测参数
+func (s *DNSThreatService) applyDefaults(cfg *models.DNSThreatConfig) {
+	if cfg.WindowMinutes <= 0 {
+		cfg.WindowMinutes = 60
+	}
+	if cfg.EntropyThreshold <= 0 {
+		cfg.EntropyThreshold = 3.5
+	}
+	if cfg.MinLabelLength <= 0 {
+		cfg.MinLabelLength = 20
+	}
+	if cfg.MinHighEntropyNames <= 0 {
+		cfg.MinHighEntropyNames = 10
+	}
+	if cfg.UniqueSubdomainThreshold <= 0 {
+		cfg.UniqueSubdomainThreshold = 500
+	}
+	if cfg.TXTQueryThreshold <= 0 {
+		cfg.TXTQueryThreshold = 200
+	}
+	if cfg.TXTRatioThreshold <= 0 {
+		cfg.TXTRatioThreshold = 0.3
+	}
+	if cfg.RetentionDays <= 0 {
+		cfg.RetentionDays = 30
+	}
+}
+
+// DetectThreats 扫描最近的查询日志，记录可疑发现并发送通知
+func (s *DNSThreatService) DetectThreats(ctx context.Context, cfg models.DNSThreatConfig) (string, error) {
+	if database.CHConn == nil {
+		return "", fmt.Errorf("ClickHouse 未连接")
+	}
+	s.applyDefaults(&cfg)
+
+	endTime := time.Now()
+	startTime := endTime.Add(-time.Duration(cfg.WindowMinutes) * time.Minute)
+
+	where := "timestamp BETWEEN ? AND ?"
+	args := []interface{}{startTime, endTime}
+	if len(cfg.NodeIDs) > 0 {
+		ids := make([]uint32, 0, len(cfg.NodeIDs))
+		for _, id := range cfg.NodeIDs {
+			ids = append(ids, uint32(id))
+		}
+		where += " AND node_id IN (?)"
+		args = append(args, ids)
+	}
+
+	ignore := make(map[string]bool, len(cfg.IgnoreDomains))
+	for _, domain := range cfg.IgnoreDomains {
+		ignore[strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")] = true
+	}
+
+	var findings []models.SecurityFinding
+
+	burst, err := s.detectSubdomainBurst(ctx, where, args, cfg, ignore)
+	if err != nil {
+		return "", err
+	}
+	findings = append(findings, burst...)
+
+	entropy, err := s.detectHighEntropy(ctx, where, args, cfg, ignore)
+	if err != nil {
+		return "", err
+	}
+	findings = append(findings, entropy...)
+
+	txt, err := s.detectTXTHeavyClients(ctx, where, args, cfg)
+	if err != nil {
+		return "", err
+	}
+	findings = append(findings, txt...)
+
+	// 清理过期的检测结果
+	cutoff := time.Now().AddDate(0, 0, -cfg.RetentionDays)
+	if err := s.db.Where("detected_at < ?", cutoff).Delete(&models.SecurityFinding{}).Error; err != nil {
+		log.Printf("⚠️ 清理过期检测结果失败: %v", err)
+	}
+
+	if len(findings) == 0 {
+		return fmt.Sprintf("最近 %d 分钟未发现可疑的 DNS 隧道/DGA 行为", cfg.WindowMinutes), nil
+	}
+
+	if err := s.db.Create(&findings).Error; err != nil {
+		return "", fmt.Errorf("保存检测结果失败: %w", err)
+	}
+
+	summary := fmt.Sprintf("最近 %d 分钟发现 %d 条可疑记录（子域名异常 %d，高熵子域名 %d，TXT异常客户端 %d）",
+		cfg.WindowMinutes, len(findings), len(burst), len(entropy), len(txt))
+
+	var lines []string
+	for i, finding := range findings {
+		if i >= 10 {
+			lines = append(lines, fmt.Sprintf("... 其余 %d 条请在安全检测页面查看", len(findings)-i))
+			break
+		}
+		lines = append(lines, "- "+finding.Detail)
+	}
+	if err := s.notificationService.SendNotification(0, "dns_threat", "🚨 检测到可疑DNS行为",
+		summary+"\n"+strings.Join(lines, "\n")); err != nil {
+		log.Printf("⚠️ 发送DNS威胁通知失败: %v", err)
+	}
+
+	return summary, nil
+}
+
+// detectSubdomainBurst 检测同一父域名下唯一子域名数量异常
+func (s *DNSThreatService) detectSubdomainBurst(ctx context.Context, where string, args []interface{}, cfg models.DNSThreatConfig, ignore map[string]bool) ([]models.SecurityFinding, error) {
+	rows, err := database.CHConn.Query(ctx, fmt.Sprintf(`
+        SELECT cutToFirstSignificantSubdomain(domain) AS parent, uniqExact(domain) AS subs, count() AS cnt,
+               uniqExact(node_id), any(node_id), uniqExact(client_ip), any(client_ip), groupUniqArray(5)(domain)
+        FROM dns_query_log WHERE %s AND parent != ''
+        GROUP BY parent HAVING subs >= %d
+        ORDER BY subs DESC LIMIT 50`, where, cfg.UniqueSubdomainThreshold), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询子域名分布失败: %w", err)
+	}
+	defer rows.Close()
+
+	findings := make([]models.SecurityFinding, 0)
+	for rows.Next() {
+		var parent, clientIP string
+		var subs, count, nodeCount, clientCount uint64
+		var nodeID uint32
+		var samples []string
+		if err := rows.Scan(&parent, &subs, &count, &nodeCount, &nodeID, &clientCount, &clientIP, &samples); err != nil {
+			log.Printf("⚠️ 扫描行失败: %v", err)
+			continue
+		}
+		if ignore[parent] {
+			continue
+		}
+
+		finding := newFinding(models.FindingTypeSubdomainBurst, parent, float64(subs), float64(cfg.UniqueSubdomainThreshold), int64(count), samples)
+		finding.Detail = fmt.Sprintf("%s 下出现 %d 个唯一子域名（%d 个客户端，%d 次查询）", parent, subs, clientCount, count)
+		if nodeCount == 1 {
+			finding.NodeID = uint(nodeID)
+		}
+		if clientCount == 1 {
+			finding.ClientIP = clientIP
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
+
+// highEntropyGroup 同一父域名下的高熵子域名汇总
+type highEntropyGroup struct {
+	names      int
+	queries    int64
+	maxEntropy float64
+	samples    []string
+	nodes      map[uint32]bool
+	clients    map[string]bool
+}
+
+// detectHighEntropy 检测高熵子域名（常见于 DGA 和隧道编码数据）
+func (s *DNSThreatService) detectHighEntropy(ctx context.Context, where string, args []interface{}, cfg models.DNSThreatConfig, ignore map[string]bool) ([]models.SecurityFinding, error) {
+	rows, err := database.CHConn.Query(ctx, fmt.Sprintf(`
+        SELECT cutToFirstSignificantSubdomain(domain) AS parent, domain, count() AS cnt, any(node_id), any(client_ip)
+        FROM dns_query_log WHERE %s AND parent != '' AND length(domain) - length(parent) > %d
+        GROUP BY parent, domain
+        LIMIT 20000`, where, cfg.MinLabelLength), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询长子域名失败: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[string]*highEntropyGroup)
+	var order []string
+	for rows.Next() {
+		var parent, domain, clientIP string
+		var count uint64
+		var nodeID uint32
+		if err := rows.Scan(&parent, &domain, &count, &nodeID, &clientIP); err != nil {
+			continue
+		}
+		if ignore[parent] {
+			continue
+		}
+
+		label := strings.ReplaceAll(strings.TrimSuffix(domain, "."+parent), ".", "")
+		entropy := shannonEntropy(label)
+		if entropy < cfg.EntropyThreshold {
+			continue
+		}
+
+		group, ok := groups[parent]
+		if !ok {
+			group = &highEntropyGroup{nodes: make(map[uint32]bool), clients: make(map[string]bool)}
+			groups[parent] = group
+			order = append(order, parent)
+		}
+		group.names++
+		group.queries += int64(count)
+		group.nodes[nodeID] = true
+		group.clients[clientIP] = true
+		if entropy > group.maxEntropy {
+			group.maxEntropy = entropy
+		}
+		if len(group.samples) < 5 {
+			group.samples = append(group.samples, domain)
+		}
+	}
+
+	findings := make([]models.SecurityFinding, 0)
+	for _, parent := range order {
+		group := groups[parent]
+		if group.names < cfg.MinHighEntropyNames {
+			continue
+		}
+
+		finding := newFinding(models.FindingTypeHighEntropy, parent, float64(group.names), float64(cfg.MinHighEntropyNames), group.queries, group.samples)
+		finding.Score = math.Round(group.maxEntropy*100) / 100
+		finding.Detail = fmt.Sprintf("%s 下出现 %d 个高熵子域名（最大熵 %.2f，%d 个客户端）", parent, group.names, group.maxEntropy, len(group.clients))
+		if len(group.nodes) == 1 {
+			for nodeID := range group.nodes {
+				finding.NodeID = uint(nodeID)
+			}
+		}
+		if len(group.clients) == 1 {
+			for clientIP := range group.clients {
+				finding.ClientIP = clientIP
+			}
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
+
+// detectTXTHeavyClients 检测 TXT 查询数量和占比异常的客户端
+func (s *DNSThreatService) detectTXTHeavyClients(ctx context.Context, where string, args []interface{}, cfg models.DNSThreatConfig) ([]models.SecurityFinding, error) {
+	rows, err := database.CHConn.Query(ctx, fmt.Sprintf(`
+        SELECT client_ip, node_id, count() AS total, countIf(query_type = 16) AS txt,
+               groupUniqArrayIf(5)(domain, query_type = 16)
+        FROM dns_query_log WHERE %s
+        GROUP BY client_ip, node_id HAVING txt >= %d AND txt / total >= %f
+        ORDER BY txt DESC LIMIT 50`, where, cfg.TXTQueryThreshold, cfg.TXTRatioThreshold), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询TXT查询分布失败: %w", err)
+	}
+	defer rows.Close()
+
+	findings := make([]models.SecurityFinding, 0)
+	for rows.Next() {
+		var clientIP string
+		var nodeID uint32
+		var total, txt uint64
+		var samples []string
+		if err := rows.Scan(&clientIP, &nodeID, &total, &txt, &samples); err != nil {
+			log.Printf("⚠️ 扫描行失败: %v", err)
+			continue
+		}
+
+		ratio := float64(txt) / float64(total)
+		finding := newFinding(models.FindingTypeTXTHeavyClient, "", float64(txt), float64(cfg.TXTQueryThreshold), int64(txt), samples)
+		finding.NodeID = uint(nodeID)
+		finding.ClientIP = clientIP
+		finding.Score = math.Round(ratio*10000) / 100
+		finding.Detail = fmt.Sprintf("客户端 %s 发起 %d 次TXT查询，占比 %.1f%%", clientIP, txt, ratio*100)
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
+
+// newFinding 创建检测结果，超过阈值两倍记为高危
+func newFinding(findingType, domain string, value, threshold float64, count int64, samples []string) models.SecurityFinding {
+	severity := models.FindingSeverityMedium
+	if value >= threshold*2 {
+		severity = models.FindingSeverityHigh
+	}
+
+	samplesJSON, _ := json.Marshal(samples)
+
+	return models.SecurityFinding{
+		Type:       findingType,
+		Severity:   severity,
+		Domain:     domain,
+		Score:      value,
+		Count:      count,
+		Samples:    string(samplesJSON),
+		DetectedAt: time.Now(),
+	}
+}
+
+// shannonEntropy 计算字符串的香农熵（bit/字符）
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	freq := make(map[rune]int)
+	total := 0
+	for _, r := range strings.ToLower(s) {
+		freq[r]++
+		total++
+	}
+
+	var entropy float64
+	for _, n := range freq {
+		p := float64(n) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// domainCategorySyncMu 防止并发同步分类表
+var domainCategorySyncMu sync.Mutex
+
+// DomainCategoryService 域名分类服务：将带分类的域名集同步到 ClickHouse，供 Agent 写入日志时打标
+type DomainCategoryService struct{}
+
+func NewDomainCategoryService() *DomainCategoryService {
+	return &DomainCategoryService{}
+}
+
+// NormalizeCategoryDomain 规范化域名集条目为后缀匹配用的域名
+func NormalizeCategoryDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(domain, "*.")
+	domain = strings.TrimPrefix(domain, ".")
+	return strings.TrimSuffix(domain, ".")
+}
+
+// SyncToClickHouse 重建日志存储中的 domain_categories 表
+// 日志存储使用 TimescaleDB 时写入 PostgreSQL 中的同名表
+func (s *DomainCategoryService) SyncToClickHouse() error {
+	if database.CHConn == nil && database.PGLogConn == nil {
+		return fmt.Errorf("ClickHouse 未连接")
+	}
+
+	domainCategorySyncMu.Lock()
+	defer domainCategorySyncMu.Unlock()
+
+	var domainSets []models.DomainSet
+	if err := database.DB.Where("enabled = ? AND category <> ''", true).Find(&domainSets).Error; err != nil {
+		return fmt.Errorf("查询域名集失败: %w", err)
+	}
+
+	var rows [][]interface{}
+	for _, domainSet := range domainSets {
+		var items []models.DomainSetItem
+		database.DB.Where("domain_set_id = ?", domainSet.ID).Find(&items)
+
+		for _, item := range items {
+			domain := NormalizeCategoryDomain(item.Domain)
+			if domain == "" {
+				continue
+			}
+			rows = append(rows, []interface{}{domain, domainSet.Category, domainSet.Name})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	if database.PGLogConn != nil {
+		if err := s.writeTimescale(ctx, rows); err != nil {
+			return err
+		}
+		log.Printf("✅ 域名分类已同步到 TimescaleDB: %d 个域名集, %d 条域名", len(domainSets), len(rows))
+		return nil
+	}
+
+	if err := s.writeClickHouse(ctx, rows); err != nil {
+		return err
+	}
+
+	log.Printf("✅ 域名分类已同步到 ClickHouse: %d 个域名集, %d 条域名", len(domainSets), len(rows))
+	return nil
+}
+
+// writeClickHouse 把分类写入新表后与 domain_categories 原子交换，Agent 和查询不会读到清空后的中间状态
+func (s *DomainCategoryService) writeClickHouse(ctx context.Context, rows [][]interface{}) error {
+	conn := database.CHConn
+	if err := conn.Exec(ctx, "DROP TABLE IF EXISTS domain_categories_new"); err != nil {
+		return fmt.Errorf("清理临时分类表失败: %w", err)
+	}
+	if err := conn.Exec(ctx, "CREATE TABLE domain_categories_new AS domain_categories"); err != nil {
+		return fmt.Errorf("创建临时分类表失败: %w", err)
+	}
+	defer conn.Exec(context.Background(), "DROP TABLE IF EXISTS domain_categories_new")
+
+	batch, err := conn.PrepareBatch(ctx, "INSERT INTO domain_categories_new (domain, category, domain_set)")
+	if err != nil {
+		return fmt.Errorf("准备批量写入失败: %w", err)
+	}
+	for _, row := range rows {
+		if err := batch.Append(row...); err != nil {
+			batch.Abort()
+			return fmt.Errorf("写入分类失败: %w", err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("提交分类失败: %w", err)
+	}
+
+	// EXCHANGE TABLES 需要 Atomic 数据库引擎，旧的 Ordinary 数据库退回到一条 RENAME 语句交换
+	if err := conn.Exec(ctx, "EXCHANGE TABLES domain_categories AND domain_categories_new"); err != nil {
+		log.Printf("⚠️ EXCHANGE TABLES 失败，改用 RENAME 交换分类表: %v", err)
+		if err := conn.Exec(ctx, "DROP TABLE IF EXISTS domain_categories_old"); err != nil {
+			return fmt.Errorf("清理旧分类表失败: %w", err)
+		}
+		if err := conn.Exec(ctx, "RENAME TABLE domain_categories TO domain_categories_old, domain_categories_new TO domain_categories"); err != nil {
+			return fmt.Errorf("替换分类表失败: %w", err)
+		}
+		conn.Exec(ctx, "DROP TABLE IF EXISTS domain_categories_old")
+	}
+	return nil
+}
+
+// writeTimescale 在一个事务内替换 PostgreSQL 中的分类表，Agent 不会读到清空后的中间状态
+func (s *DomainCategoryService) writeTimescale(ctx context.Context, rows [][]interface{}) error {
+	tx, err := database.PGLogConn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM domain_categories"); err != nil {
+		return fmt.Errorf("清空分类表失败: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"domain_categories"},
+		[]string{"domain", "category", "domain_set"}, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("写入分类失败: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("提交分类失败: %w", err)
+	}
+	return nil
+}
+
+// SyncToClickHouseAsync 异步同步，失败只记录日志
+func (s *DomainCategoryService) SyncToClickHouseAsync() {
+	go func() {
+		if err := s.SyncToClickHouse(); err != nil {
+			log.Printf("⚠️ 同步域名分类失败: %v", err)
+		}
+	}()
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -30,6 +31,7 @@ func (s *DomainRuleService) SyncDomainRuleToNodes(rule *models.DomainRule) error
 	if err != nil {
 		return err
 	}
+	nodes = nodesInOrganization(nodes, rule.OrganizationID)
 
 	for _, node := range nodes {
 		go s.syncDomainRuleToNode(rule, &node)
@@ -55,14 +57,16 @@ func (s *DomainRuleService) syncDomainRuleToNode(rule *models.DomainRule, node *
 	}
 
 	// 生成规则行
-	ruleLine := s.generateDomainRuleLine(rule)
+	ruleLine := s.generateDomainRuleLine(rule) + recordAnnotation(models.AuditEntityDomainRule, rule.ID, rule.UpdatedAt)
 
 	// 更新配置
 	newContent := s.updateDomainRulesInConfig(configContent, ruleLine, rule.Domain)
 
 	s.notificationService.SendNotification(node.ID, "domain_rule_sync", "域名规则同步", fmt.Sprintf("域名规则 %s 已同步到节点 %s", rule.Domain, node.Name))
 	// 写回配置
-	client.WriteFile(node.ConfigPath, newContent)
+	if err := client.WriteFile(node.ConfigPath, newContent); err == nil {
+		reloadAfterSync(context.Background(), client, node)
+	}
 }
 
 // generateDomainRuleLine 生成域名规则行
@@ -153,6 +157,7 @@ func (s *DomainRuleService) DeleteDomainRuleFromNodes(rule *models.DomainRule) e
 	if err != nil {
 		return err
 	}
+	nodes = nodesInOrganization(nodes, rule.OrganizationID)
 
 	for _, node := range nodes {
 		go s.deleteDomainRuleFromNode(rule, &node)
@@ -189,7 +194,9 @@ func (s *DomainRuleService) deleteDomainRuleFromNode(rule *models.DomainRule, no
 	}
 	s.notificationService.SendNotification(node.ID, "domain_rule_delete_sync", "域名规则删除同步", fmt.Sprintf("域名规则 %s 已删除 %s", rule.Domain, node.Name))
 
-	client.WriteFile(node.ConfigPath, strings.Join(newLines, "\n"))
+	if err := client.WriteFile(node.ConfigPath, strings.Join(newLines, "\n")); err == nil {
+		reloadAfterSync(context.Background(), client, node)
+	}
 }
 
 func (s *DomainRuleService) getTargetNodes(nodeIDsJSON string) ([]models.Node, error) {
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -12,11 +13,13 @@ import (
 
 type DomainSetService struct {
 	notificationService *NotificationService
+	fileService         *DomainSetFileService
 }
 
 func NewDomainSetService() *DomainSetService {
 	return &DomainSetService{
 		notificationService: NewNotificationService(),
+		fileService:         NewDomainSetFileService(),
 	}
 }
 
@@ -32,12 +35,8 @@ func (s *DomainSetService) SyncDomainSetToNodes(domainSet *models.DomainSet) err
 		return err
 	}
 
-	// 获取域名列表
-	var items []models.DomainSetItem
-	database.DB.Where("domain_set_id = ?", domainSet.ID).Find(&items)
-
 	// 生成文件内容
-	content := s.generateDomainSetFile(domainSet, items)
+	content := s.fileService.BuildContent(domainSet)
 
 	// 同步到各个节点
 	for _, node := range nodes {
@@ -58,12 +57,8 @@ func (s *DomainSetService) syncDomainSetToNode(domainSet *models.DomainSet, node
 	}
 	defer client.Close()
 
-	// 确保目录存在
-	dirPath := "/etc/smartdns"
-	client.ExecuteCommand(fmt.Sprintf("sudo mkdir -p %s", dirPath))
-
-	// 写入域名集文件
-	if err := client.WriteFile(domainSet.FilePath, content); err != nil {
+	// 上传域名集文件（内容未变化时跳过）
+	if _, err := s.fileService.UploadFile(client, domainSet, content); err != nil {
 		log.Printf("写入域名集文件失败: %v", err)
 		return
 	}
@@ -74,25 +69,51 @@ func (s *DomainSetService) syncDomainSetToNode(domainSet *models.DomainSet, node
 	log.Printf(" 域名集 %s 同步成功: %s", domainSet.Name, node.Name)
 }
 
-// generateDomainSetFile 生成域名集文件内容
-func (s *DomainSetService) generateDomainSetFile(domainSet *models.DomainSet, items []models.DomainSetItem) string {
-	var builder strings.Builder
+// PushDomainSetAndReload 依次推送域名集文件到各节点，文件有变化的节点按其重载方式使 SmartDNS 生效
+// 返回推送失败的节点名称及原因
+func (s *DomainSetService) PushDomainSetAndReload(domainSet *models.DomainSet) map[string]error {
+	failed := make(map[string]error)
+	if !domainSet.Enabled {
+		return failed
+	}
 
-	builder.WriteString(fmt.Sprintf("# Domain Set: %s\n", domainSet.Name))
-	if domainSet.Description != "" {
-		builder.WriteString(fmt.Sprintf("# Description: %s\n", domainSet.Description))
+	nodes, err := s.getTargetNodes(domainSet.NodeIDs)
+	if err != nil {
+		failed["*"] = fmt.Errorf("解析域名集节点失败: %w", err)
+		return failed
 	}
-	builder.WriteString(fmt.Sprintf("# Total: %d domains\n", len(items)))
-	builder.WriteString(fmt.Sprintf("# Generated at: %s\n\n", domainSet.UpdatedAt.Format("2006-01-02 15:04:05")))
 
-	for _, item := range items {
-		if item.Comment != "" {
-			builder.WriteString(fmt.Sprintf("# %s\n", item.Comment))
+	content := s.fileService.BuildContent(domainSet)
+	for i := range nodes {
+		node := &nodes[i]
+		client, err := NewSSHClient(node)
+		if err != nil {
+			failed[node.Name] = fmt.Errorf("连接节点失败: %w", err)
+			continue
+		}
+
+		uploaded, err := s.fileService.UploadFile(client, domainSet, content)
+		if err != nil {
+			client.Close()
+			failed[node.Name] = fmt.Errorf("写入域名集文件失败: %w", err)
+			continue
 		}
-		builder.WriteString(fmt.Sprintf("%s\n", item.Domain))
+		s.ensureDomainSetInConfig(client, node, domainSet)
+
+		if uploaded {
+			if deferred, _ := NewMaintenanceService().DeferNodeAction(node, models.DeferredActionReload,
+				"域名集更新: "+domainSet.Name, false); deferred {
+				log.Printf("域名集 %s 已更新，节点 %s 将在维护窗口内重载", domainSet.Name, node.Name)
+			} else if err := ApplyNodeConfig(context.Background(), client, node); err != nil {
+				failed[node.Name] = fmt.Errorf("重载 SmartDNS 失败: %w", err)
+			} else {
+				log.Printf("域名集 %s 已更新并重载 SmartDNS: %s", domainSet.Name, node.Name)
+			}
+		}
+		client.Close()
 	}
 
-	return builder.String()
+	return failed
 }
 
 // ensureDomainSetInConfig 确保主配置文件中引用了域名集
@@ -186,7 +207,9 @@ func (s *DomainSetService) deleteDomainSetFromNode(domainSet *models.DomainSet,
 
 	s.notificationService.SendNotification(node.ID, "domain_set_sync", "域名集删除同步", fmt.Sprintf("域名集 %s 已删除 %s", domainSet.Name, node.Name))
 
-	client.WriteFile(node.ConfigPath, strings.Join(newLines, "\n"))
+	if err := client.WriteFile(node.ConfigPath, strings.Join(newLines, "\n")); err == nil {
+		reloadAfterSync(context.Background(), client, node)
+	}
 }
 
 // getTargetNodes 获取目标节点列表
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// DomainSetFileService 域名集文件分发服务
+// 负责把域名列表文件上传到节点，并维护主配置中的 domain-set 引用
+type DomainSetFileService struct{}
+
+func NewDomainSetFileService() *DomainSetFileService {
+	return &DomainSetFileService{}
+}
+
+// DomainSetFileSyncResult 单个节点的域名集文件同步结果
+type DomainSetFileSyncResult struct {
+	Uploaded []string `json:"uploaded"` // 已上传的域名集
+	Skipped  []string `json:"skipped"`  // 校验和一致而跳过的域名集
+	Failed   []string `json:"failed"`   // 上传失败的域名集
+}
+
+// BuildContent 生成域名集文件内容
+func (s *DomainSetFileService) BuildContent(domainSet *models.DomainSet) string {
+	var items []models.DomainSetItem
+	database.DB.Where("domain_set_id = ?", domainSet.ID).Find(&items)
+
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("# Domain Set: %s\n", domainSet.Name))
+	if domainSet.Description != "" {
+		builder.WriteString(fmt.Sprintf("# Description: %s\n", domainSet.Description))
+	}
+	builder.WriteString(fmt.Sprintf("# Total: %d domains\n", len(items)))
+	builder.WriteString(fmt.Sprintf("# Generated at: %s\n\n", domainSet.UpdatedAt.Format("2006-01-02 15:04:05")))
+
+	for _, item := range items {
+		if item.Comment != "" {
+			builder.WriteString(fmt.Sprintf("# %s\n", item.Comment))
+		}
+		builder.WriteString(fmt.Sprintf("%s\n", item.Domain))
+	}
+
+	return builder.String()
+}
+
+// UploadFile 上传域名集文件到节点，远端文件校验和一致时跳过，返回是否实际写入
+func (s *DomainSetFileService) UploadFile(client *SSHClient, domainSet *models.DomainSet, content string) (bool, error) {
+	checksum := contentChecksum(content)
+
+	if domainSet.Checksum != checksum {
+		domainSet.Checksum = checksum
+		database.DB.Model(&models.DomainSet{}).Where("id = ?", domainSet.ID).UpdateColumn("checksum", checksum)
+	}
+
+	if remote := s.remoteChecksum(client, domainSet.FilePath); remote == checksum {
+		log.Printf("域名集文件未变化，跳过上传: %s", domainSet.FilePath)
+		return false, nil
+	}
+
+	// 确保目录存在
+	if _, err := client.ExecuteCommand(fmt.Sprintf("sudo mkdir -p %s", path.Dir(domainSet.FilePath))); err != nil {
+		return false, fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	if err := client.WriteFile(domainSet.FilePath, content); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SyncFilesToNode 上传节点适用的全部域名集文件，并重新生成配置中的文件引用
+func (s *DomainSetFileService) SyncFilesToNode(client *SSHClient, node *models.Node, config *models.SmartDNSConfig) *DomainSetFileSyncResult {
+	result := &DomainSetFileSyncResult{}
+
+	var domainSets []models.DomainSet
+	database.DB.Where("enabled = ?", true).Find(&domainSets)
+
+	for i := range domainSets {
+		domainSet := &domainSets[i]
+		if !nodeIDsContain(domainSet.NodeIDs, node.ID) {
+			continue
+		}
+
+		uploaded, err := s.UploadFile(client, domainSet, s.BuildContent(domainSet))
+		if err != nil {
+			log.Printf("上传域名集文件失败 (%s -> %s): %v", domainSet.Name, node.Name, err)
+			result.Failed = append(result.Failed, domainSet.Name)
+			continue
+		}
+		if uploaded {
+			result.Uploaded = append(result.Uploaded, domainSet.Name)
+		} else {
+			result.Skipped = append(result.Skipped, domainSet.Name)
+		}
+
+		s.applyReference(config, domainSet)
+	}
+
+	return result
+}
+
+// applyReference 更新配置中的 domain-set 引用
+// 同名域名集统一指向当前文件路径；域名列表文件不能作为 conf-file 引入，存在时移除
+func (s *DomainSetFileService) applyReference(config *models.SmartDNSConfig, domainSet *models.DomainSet) {
+	found := false
+	for i, ds := range config.DomainSets {
+		if ds.Name == domainSet.Name {
+			config.DomainSets[i].FilePath = domainSet.FilePath
+			found = true
+		}
+	}
+	if !found {
+		config.DomainSets = append(config.DomainSets, models.DomainSet{
+			Name:     domainSet.Name,
+			FilePath: domainSet.FilePath,
+		})
+	}
+
+	confFiles := config.ConfFiles[:0]
+	for _, file := range config.ConfFiles {
+		if file != domainSet.FilePath {
+			confFiles = append(confFiles, file)
+		}
+	}
+	config.ConfFiles = confFiles
+}
+
+// remoteChecksum 读取节点上文件的 SHA256，文件不存在时返回空字符串
+func (s *DomainSetFileService) remoteChecksum(client *SSHClient, filePath string) string {
+	output, err := client.ExecuteCommand(fmt.Sprintf("sudo sha256sum %s 2>/dev/null", filePath))
+	if err != nil {
+		return ""
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// contentChecksum 计算内容的 SHA256
+func contentChecksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// nodeIDsContain 判断 JSON 节点列表是否包含指定节点，空列表表示所有节点
+func nodeIDsContain(nodeIDsJSON string, nodeID uint) bool {
+	if nodeIDsJSON == "" || nodeIDsJSON == "[]" {
+		return true
+	}
+
+	var nodeIDs []uint
+	if err := json.Unmarshal([]byte(nodeIDsJSON), &nodeIDs); err != nil {
+		return false
+	}
+	for _, id := range nodeIDs {
+		if id == nodeID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// driftNotifyMaxLines 通知中每类变更最多列出的行数
+const driftNotifyMaxLines = 10
+
+// DriftService 节点配置漂移检测服务
+type DriftService struct {
+	db                  *gorm.DB
+	config              *config.Config
+	notificationService *NotificationService
+	configSyncService   *ConfigSyncService
+	snapshotService     *ConfigSnapshotService
+}
+
+// NewDriftService 创建配置漂移检测服务
+func NewDriftService(db *gorm.DB, config *config.Config) (*DriftService, error) {
+	snapshotService, err := NewConfigSnapshotService(db, config)
+	if err != nil {
+		return nil, err
+	}
+	return &DriftService{
+		db:                  db,
+		config:              config,
+		notificationService: NewNotificationService(),
+		configSyncService:   NewConfigSyncService(),
+		snapshotService:     snapshotService,
+	}, nil
+}
+
+// CheckDrift 检测节点实际配置与期望配置的差异，保存报告并在出现新的漂移时通知
+func (s *DriftService) CheckDrift(ctx context.Context, cfg models.DriftCheckConfig) (string, error) {
+	if cfg.RetentionDays <= 0 {
+		cfg.RetentionDays = 30
+	}
+
+	var nodes []models.Node
+	query := s.db.Model(&models.Node{})
+	if len(cfg.NodeIDs) > 0 {
+		query = query.Where("id IN ?", cfg.NodeIDs)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
+		return "", fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	drifted, failed := 0, 0
+	for i := range nodes {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		report := s.CheckNode(&nodes[i])
+		if report.Error != "" {
+			failed++
+		} else if report.HasDrift {
+			drifted++
+		}
+	}
+
+	// 清理过期报告
+	cutoff := time.Now().AddDate(0, 0, -cfg.RetentionDays)
+	if err := s.db.Where("checked_at < ?", cutoff).Delete(&models.ConfigDriftReport{}).Error; err != nil {
+		log.Printf("⚠️ 清理过期漂移报告失败: %v", err)
+	}
+
+	output := fmt.Sprintf("检测 %d 个节点，%d 个存在配置漂移", len(nodes), drifted)
+	if failed > 0 {
+		output += fmt.Sprintf("，%d 个检测失败", failed)
+	}
+	return output, nil
+}
+
+// CheckNode 检测单个节点并保存报告
+func (s *DriftService) CheckNode(node *models.Node) *models.ConfigDriftReport {
+	report := &models.ConfigDriftReport{
+		NodeID:    node.ID,
+		NodeName:  node.Name,
+		CheckedAt: time.Now(),
+	}
+
+	content, actual, expected, err := s.renderConfigs(node)
+	if content != "" {
+		// 检测时读取的配置同样记录快照，直接修改会出现在配置历史中
+		if snapshot, _, err := s.snapshotService.Record(node, content, models.ConfigSnapshotSourceScheduled); err == nil {
+			report.SnapshotID = snapshot.ID
+		}
+		if first := s.snapshotService.OutOfBandSince(node.ID); first != nil {
+			report.OutOfBand = true
+			report.OutOfBandAt = &first.CapturedAt
+		}
+	}
+	if err != nil {
+		report.Error = err.Error()
+		log.Printf("⚠️ 节点 %s 配置漂移检测失败: %v", node.Name, err)
+	} else {
+		report.AddedLines, report.RemovedLines, report.ChangedLines = DiffConfigLines(expected, actual)
+		report.AddedCount = len(report.AddedLines)
+		report.RemovedCount = len(report.RemovedLines)
+		report.ChangedCount = len(report.ChangedLines)
+		report.HasDrift = report.AddedCount+report.RemovedCount+report.ChangedCount > 0
+	}
+
+	s.encode(report)
+
+	var previous models.ConfigDriftReport
+	hasPrevious := s.db.Where("node_id = ? AND error = ?", node.ID, "").
+		Order("checked_at desc, id desc").First(&previous).Error == nil
+
+	if err := s.db.Create(report).Error; err != nil {
+		log.Printf("⚠️ 保存漂移报告失败: %v", err)
+	}
+
+	// 只有漂移内容与上次不同时才通知，避免定时任务重复告警
+	if report.HasDrift && !(hasPrevious && sameDrift(&previous, report)) {
+		s.notify(node, report)
+	}
+
+	return report
+}
+
+// renderConfigs 读取节点配置，返回原始配置、规范化后的实际配置与管理端期望的配置。
+// 期望配置只由数据库中的记录生成，节点上手工添加的行会作为未受管的行报告出来
+func (s *DriftService) renderConfigs(node *models.Node) (string, string, string, error) {
+	client, err := NewSSHClient(node)
+	if err != nil {
+		return "", "", "", fmt.Errorf("连接节点失败: %w", err)
+	}
+	defer client.Close()
+
+	content, err := client.ReadFile(node.ConfigPath)
+	if err != nil {
+		return "", "", "", fmt.Errorf("读取配置失败: %w", err)
+	}
+
+	// 两边都经过解析和重新生成，排除注释、空行、顺序等格式差异
+	parser := NewConfigParser()
+	actualConfig, err := parser.Parse(content)
+	if err != nil {
+		return content, "", "", fmt.Errorf("解析配置失败: %w", err)
+	}
+	expectedConfig := s.managedConfig(node)
+
+	return content, parser.Generate(actualConfig), parser.Generate(expectedConfig), nil
+}
+
+// managedConfig 只根据数据库中应用到该节点的记录生成配置，不读取节点上的文件
+func (s *DriftService) managedConfig(node *models.Node) *models.SmartDNSConfig {
+	config := s.configSyncService.BuildExpectedConfig(&models.SmartDNSConfig{
+		BasicSettings: make(map[string]string),
+	}, node.ID)
+
+	// 域名集、域名规则和 nameserver 规则由各自的服务单独下发，同样属于受管配置
+	var domainSets []models.DomainSet
+	s.db.Where("enabled = ?", true).Find(&domainSets)
+	for _, domainSet := range domainSets {
+		if nodeIDsContain(domainSet.NodeIDs, node.ID) {
+			config.DomainSets = append(config.DomainSets, domainSet)
+		}
+	}
+
+	var domainRules []models.DomainRule
+	s.db.Where("enabled = ?", true).Order("priority desc, id").Find(&domainRules)
+	for _, rule := range domainRules {
+		if nodeIDsContain(rule.NodeIDs, node.ID) &&
+			(rule.OrganizationID == 0 || rule.OrganizationID == node.OrganizationID) {
+			config.DomainRules = append(config.DomainRules, rule)
+		}
+	}
+
+	var nameservers []models.Nameserver
+	s.db.Where("enabled = ?", true).Order("priority desc, id").Find(&nameservers)
+	for _, ns := range nameservers {
+		if nodeIDsContain(ns.NodeIDs, node.ID) {
+			config.Nameservers = append(config.Nameservers, ns)
+		}
+	}
+
+	return config
+}
+
+// notify 发送配置漂移通知
+func (s *DriftService) notify(node *models.Node, report *models.ConfigDriftReport) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "节点 %s 的配置与管理端不一致（未受管 %d 行，缺失 %d 行，修改 %d 行）。\n",
+		node.Name, report.AddedCount, report.RemovedCount, report.ChangedCount)
+	if report.RemovedCount+report.ChangedCount > 0 {
+		b.WriteString("缺失和修改的行会在下次完整同步时按管理端记录恢复。\n")
+	}
+	if report.AddedCount > 0 {
+		b.WriteString("未受管的行不在管理端记录中，完整同步会保留它们，需要在管理端录入或到节点上手动清理。\n")
+	}
+	if report.OutOfBand && report.OutOfBandAt != nil {
+		fmt.Fprintf(&b, "配置快照显示节点上的直接修改最早发现于 %s，可在配置历史中查看。\n", report.OutOfBandAt.Format("2006-01-02 15:04"))
+	}
+
+	writeLines := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s：\n", title)
+		for i, line := range lines {
+			if i >= driftNotifyMaxLines {
+				fmt.Fprintf(&b, "... 共 %d 行\n", len(lines))
+				break
+			}
+			fmt.Fprintf(&b, "%s\n", line)
+		}
+	}
+
+	changed := make([]string, 0, len(report.ChangedLines))
+	for _, change := range report.ChangedLines {
+		changed = append(changed, fmt.Sprintf("~ %s\n  期望: %s", change.Actual, change.Expected))
+	}
+
+	added := make([]string, 0, len(report.AddedLines))
+	for _, line := range report.AddedLines {
+		added = append(added, "+ "+line)
+	}
+	removed := make([]string, 0, len(report.RemovedLines))
+	for _, line := range report.RemovedLines {
+		removed = append(removed, "- "+line)
+	}
+
+	writeLines("修改", changed)
+	writeLines("未受管", added)
+	writeLines("缺失", removed)
+
+	if err := s.notificationService.SendNotification(node.ID, "config_drift", "⚠️ 配置漂移", b.String()); err != nil {
+		log.Printf("⚠️ 发送配置漂移通知失败: %v", err)
+	}
+}
+
+// encode 序列化变更明细
+func (s *DriftService) encode(report *models.ConfigDriftReport) {
+	added, _ := json.Marshal(report.AddedLines)
+	removed, _ := json.Marshal(report.RemovedLines)
+	changed, _ := json.Marshal(report.ChangedLines)
+	report.Added = string(added)
+	report.Removed = string(removed)
+	report.Changed = string(changed)
+}
+
+// DecodeDriftReports 解析报告中的变更明细
+func DecodeDriftReports(reports []models.ConfigDriftReport) {
+	for i := range reports {
+		reports[i].AddedLines = []string{}
+		reports[i].RemovedLines = []string{}
+		reports[i].ChangedLines = []models.DriftLineChange{}
+		if reports[i].Added != "" {
+			json.Unmarshal([]byte(reports[i].Added), &reports[i].AddedLines)
+		}
+		if reports[i].Removed != "" {
+			json.Unmarshal([]byte(reports[i].Removed), &reports[i].RemovedLines)
+		}
+		if reports[i].Changed != "" {
+			json.Unmarshal([]byte(reports[i].Changed), &reports[i].ChangedLines)
+		}
+	}
+}
+
+// sameDrift 判断两次报告的漂移内容是否一致
+func sameDrift(a, b *models.ConfigDriftReport) bool {
+	return a.HasDrift == b.HasDrift && a.Added == b.Added && a.Removed == b.Removed && a.Changed == b.Changed
+}
+
+// DiffConfigLines 按行对比期望配置与实际配置。
+// 同一配置项（如同一域名的 address）内容不同记为修改，其余分别记为节点上新增或缺失的行
+func DiffConfigLines(expected, actual string) ([]string, []string, []models.DriftLineChange) {
+	expectedLines := configLines(expected)
+	actualLines := configLines(actual)
+
+	// 按出现次数抵消两边相同的行
+	counts := map[string]int{}
+	for _, line := range expectedLines {
+		counts[line]++
+	}
+	var onlyActual []string
+	for _, line := range actualLines {
+		if counts[line] > 0 {
+			counts[line]--
+			continue
+		}
+		onlyActual = append(onlyActual, line)
+	}
+	var onlyExpected []string
+	for _, line := range expectedLines {
+		if counts[line] > 0 {
+			counts[line]--
+			onlyExpected = append(onlyExpected, line)
+		}
+	}
+
+	// 同一配置项的行配对为修改
+	actualByKey := map[string][]int{}
+	for i, line := range onlyActual {
+		key := driftLineKey(line)
+		actualByKey[key] = append(actualByKey[key], i)
+	}
+	paired := map[int]bool{}
+	changed := []models.DriftLineChange{}
+	removed := []string{}
+	for _, line := range onlyExpected {
+		key := driftLineKey(line)
+		if indexes := actualByKey[key]; len(indexes) > 0 {
+			actualByKey[key] = indexes[1:]
+			paired[indexes[0]] = true
+			changed = append(changed, models.DriftLineChange{Key: key, Expected: line, Actual: onlyActual[indexes[0]]})
+			continue
+		}
+		removed = append(removed, line)
+	}
+
+	added := []string{}
+	for i, line := range onlyActual {
+		if !paired[i] {
+			added = append(added, line)
+		}
+	}
+
+	return added, removed, changed
+}
+
+// configLines 提取有效配置行（忽略空行和注释）
+func configLines(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// driftLineKey 获取配置行对应的配置项标识：
+// address /example.com/1.2.3.4 -> address /example.com/
+// server 1.1.1.1 -group cn -> server 1.1.1.1
+func driftLineKey(line string) string {
+	directive, value := splitDirective(line)
+	if strings.HasPrefix(value, "/") {
+		if end := strings.Index(value[1:], "/"); end >= 0 {
+			return directive + " " + value[:end+2]
+		}
+	}
+	if fields := strings.Fields(value); len(fields) > 0 {
+		return directive + " " + fields[0]
+	}
+	return directive
+}
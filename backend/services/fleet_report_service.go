@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// fleetUnset 资产字段未填写时的分组名
+const fleetUnset = "未设置"
+
+// FleetReportService 节点资产月度报告服务：按云厂商/区域/成本中心汇总节点数量、资源使用和查询量
+type FleetReportService struct {
+	db                  *gorm.DB
+	config              *config.Config
+	notificationService *NotificationService
+}
+
+// NewFleetReportService 创建节点资产报告服务
+func NewFleetReportService(db *gorm.DB, config *config.Config) (*FleetReportService, error) {
+	return &FleetReportService{
+		db:                  db,
+		config:              config,
+		notificationService: NewNotificationService(),
+	}, nil
+}
+
+// ParseReportMonth 解析统计月份(YYYY-MM)，为空时返回上个月，当月的结束时间截止到当前
+func ParseReportMonth(month string) (string, time.Time, time.Time, error) {
+	now := time.Now()
+	var start time.Time
+	if month == "" {
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -1, 0)
+	} else {
+		parsed, err := time.ParseInLocation("2006-01", month, now.Location())
+		if err != nil {
+			return "", time.Time{}, time.Time{}, fmt.Errorf("月份格式错误，应为 YYYY-MM")
+		}
+		start = parsed
+	}
+	if start.After(now) {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("不能统计未来的月份")
+	}
+
+	end := start.AddDate(0, 1, 0)
+	if end.After(now) {
+		end = now
+	}
+	return start.Format("2006-01"), start, end, nil
+}
+
+// nodeVolume 节点在统计周期内的查询量
+type nodeVolume struct {
+	total   uint64
+	peakDay uint64
+}
+
+// queryVolumes 从 ClickHouse 统计各节点查询总量和单日最高查询量
+func (s *FleetReportService) queryVolumes(ctx context.Context, start, end time.Time) (map[uint]nodeVolume, error) {
+	if database.CHConn == nil {
+		return nil, fmt.Errorf("ClickHouse 未连接")
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	rows, err := database.CHConn.Query(queryCtx, `
+        SELECT node_id, sum(cnt), max(cnt)
+        FROM (
+            SELECT node_id, toDate(timestamp) AS day, count() AS cnt
+            FROM dns_query_log
+            WHERE timestamp >= ? AND timestamp < ?
+            GROUP BY node_id, day
+        )
+        GROUP BY node_id`, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("查询节点查询量失败: %w", err)
+	}
+	defer rows.Close()
+
+	volumes := make(map[uint]nodeVolume)
+	for rows.Next() {
+		var nodeID uint32
+		var volume nodeVolume
+		if err := rows.Scan(&nodeID, &volume.total, &volume.peakDay); err != nil {
+			log.Printf("⚠️ 扫描行失败: %v", err)
+			continue
+		}
+		volumes[uint(nodeID)] = volume
+	}
+	return volumes, rows.Err()
+}
+
+// collectResources 通过 SSH 采集节点当前资源使用率，最多 5 个并发
+func collectResources(nodes []models.Node, rows []models.FleetNodeRow) {
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 5)
+	for i := range nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			client, err := NewSSHClient(&nodes[i])
+			if err != nil {
+				log.Printf("⚠️ 资产报告采集节点 %s 资源失败: %v", nodes[i].Name, err)
+				return
+			}
+			defer client.Close()
+
+			status, err := client.GetSystemInfo()
+			if err != nil {
+				return
+			}
+			rows[i].CPUUsage = status.CPUUsage
+			rows[i].MemoryUsage = status.MemoryUsage
+			rows[i].DiskUsage = status.DiskUsage
+		}(i)
+	}
+	wg.Wait()
+}
+
+// fleetGroupStats 按 key 汇总节点，查询量从高到低排序
+func fleetGroupStats(rows []models.FleetNodeRow, total uint64, key func(models.FleetNodeRow) string) []models.FleetGroupStat {
+	groups := make(map[string]*models.FleetGroupStat)
+	for _, row := range rows {
+		name := key(row)
+		if name == "" {
+			name = fleetUnset
+		}
+		group, ok := groups[name]
+		if !ok {
+			group = &models.FleetGroupStat{Name: name}
+			groups[name] = group
+		}
+		group.Nodes++
+		group.Queries += row.Queries
+		if row.Status == "online" {
+			group.Online++
+		}
+	}
+
+	stats := make([]models.FleetGroupStat, 0, len(groups))
+	for _, group := range groups {
+		if total > 0 {
+			group.Share = float64(group.Queries) / float64(total)
+		}
+		stats = append(stats, *group)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Queries != stats[j].Queries {
+			return stats[i].Queries > stats[j].Queries
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// Generate 生成指定月份的资产报告，ClickHouse 不可用时仍生成节点构成部分
+func (s *FleetReportService) Generate(ctx context.Context, month string, checkResources bool) (*models.FleetReportData, error) {
+	month, start, end, err := ParseReportMonth(month)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []models.Node
+	if err := s.db.Order("id").Find(&nodes).Error; err != nil {
+		return nil, fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	data := &models.FleetReportData{
+		Month:       month,
+		Start:       start,
+		End:         end,
+		GeneratedAt: time.Now(),
+		TotalNodes:  len(nodes),
+		Nodes:       make([]models.FleetNodeRow, len(nodes)),
+	}
+
+	volumes, err := s.queryVolumes(ctx, start, end)
+	if err != nil {
+		data.QueryError = err.Error()
+	}
+
+	seconds := end.Sub(start).Seconds()
+	for i, node := range nodes {
+		volume := volumes[node.ID]
+		data.Nodes[i] = models.FleetNodeRow{
+			NodeID:      node.ID,
+			Name:        node.Name,
+			Host:        node.Host,
+			Provider:    node.Provider,
+			Region:      node.Region,
+			CostCenter:  node.CostCenter,
+			Status:      node.Status,
+			Queries:     volume.total,
+			AvgQPS:      float64(volume.total) / seconds,
+			PeakDayQPS:  float64(volume.peakDay) / 86400,
+			CPUUsage:    -1,
+			MemoryUsage: -1,
+			DiskUsage:   -1,
+		}
+		data.Queries += volume.total
+	}
+
+	if checkResources {
+		collectResources(nodes, data.Nodes)
+	}
+
+	data.ByProvider = fleetGroupStats(data.Nodes, data.Queries, func(r models.FleetNodeRow) string { return r.Provider })
+	data.ByRegion = fleetGroupStats(data.Nodes, data.Queries, func(r models.FleetNodeRow) string { return r.Region })
+	data.ByCostCenter = fleetGroupStats(data.Nodes, data.Queries, func(r models.FleetNodeRow) string { return r.CostCenter })
+	return data, nil
+}
+
+// SaveReport 保存报告，超过 keep 份时删除最早的报告
+func (s *FleetReportService) SaveReport(data *models.FleetReportData, keep int) (*models.FleetReport, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.FleetReport{
+		Month:       data.Month,
+		TotalNodes:  data.TotalNodes,
+		Queries:     data.Queries,
+		Data:        string(encoded),
+		GeneratedAt: data.GeneratedAt,
+	}
+	if err := s.db.Create(report).Error; err != nil {
+		return nil, fmt.Errorf("保存资产报告失败: %w", err)
+	}
+
+	if keep <= 0 {
+		keep = 24
+	}
+	var stale []uint
+	s.db.Model(&models.FleetReport{}).Order("generated_at desc, id desc").Offset(keep).Pluck("id", &stale)
+	if len(stale) > 0 {
+		s.db.Delete(&models.FleetReport{}, stale)
+	}
+
+	report.Report = data
+	return report, nil
+}
+
+// DecodeFleetReport 解析保存的报告内容
+func DecodeFleetReport(report *models.FleetReport) error {
+	var data models.FleetReportData
+	if err := json.Unmarshal([]byte(report.Data), &data); err != nil {
+		return fmt.Errorf("解析资产报告失败: %w", err)
+	}
+	report.Report = &data
+	return nil
+}
+
+// RunScheduled 定时生成并保存报告，发送汇总通知
+func (s *FleetReportService) RunScheduled(ctx context.Context, cfg models.FleetReportConfig) (string, error) {
+	data, err := s.Generate(ctx, cfg.Month, cfg.CheckResources)
+	if err != nil {
+		return "", err
+	}
+	report, err := s.SaveReport(data, cfg.RetentionCount)
+	if err != nil {
+		return "", err
+	}
+
+	summary := fmt.Sprintf("%s 节点资产报告：共 %d 个节点，服务查询 %d 次", data.Month, data.TotalNodes, data.Queries)
+	content := summary + "\n按云厂商："
+	for _, group := range data.ByProvider {
+		content += fmt.Sprintf("\n- %s: %d 个节点，查询占比 %.1f%%", group.Name, group.Nodes, group.Share*100)
+	}
+	if data.QueryError != "" {
+		content += "\n（查询量统计失败: " + data.QueryError + "）"
+	}
+	if err := s.notificationService.SendNotification(0, "fleet_report", "📊 节点资产月度报告", content); err != nil {
+		log.Printf("⚠️ 发送资产报告通知失败: %v", err)
+	}
+
+	return fmt.Sprintf("%s（报告ID %d）", summary, report.ID), nil
+}
+
+// WriteFleetReportCSV 以节点为行导出报告，便于在表格中按成本中心分摊
+func WriteFleetReportCSV(w io.Writer, data *models.FleetReportData) error {
+	writer := csv.NewWriter(w)
+	header := []string{"月份", "节点ID", "节点名称", "地址", "云厂商", "区域", "成本中心", "状态",
+		"查询量", "平均QPS", "峰值日平均QPS", "查询占比(%)", "CPU(%)", "内存(%)", "磁盘(%)"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	usage := func(v float64) string {
+		if v < 0 {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', 1, 64)
+	}
+	for _, row := range data.Nodes {
+		share := 0.0
+		if data.Queries > 0 {
+			share = float64(row.Queries) / float64(data.Queries) * 100
+		}
+		record := []string{
+			data.Month,
+			strconv.FormatUint(uint64(row.NodeID), 10),
+			row.Name,
+			row.Host,
+			row.Provider,
+			row.Region,
+			row.CostCenter,
+			row.Status,
+			strconv.FormatUint(row.Queries, 10),
+			strconv.FormatFloat(row.AvgQPS, 'f', 2, 64),
+			strconv.FormatFloat(row.PeakDayQPS, 'f', 2, 64),
+			strconv.FormatFloat(share, 'f', 2, 64),
+			usage(row.CPUUsage),
+			usage(row.MemoryUsage),
+			usage(row.DiskUsage),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
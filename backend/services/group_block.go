@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// GroupBlockService 二级分组配置块（group-begin/group-end）管理服务
+type GroupBlockService struct {
+	notificationService *NotificationService
+	parser              *ConfigParser
+}
+
+func NewGroupBlockService() *GroupBlockService {
+	return &GroupBlockService{
+		notificationService: NewNotificationService(),
+		parser:              NewConfigParser(),
+	}
+}
+
+// Preview 生成分组配置块的配置文本
+func (s *GroupBlockService) Preview(block *models.GroupBlock) string {
+	config := &models.SmartDNSConfig{
+		BasicSettings: map[string]string{},
+		Groups:        []models.ConfigGroup{s.parser.GenerateGroupBlock(block)},
+	}
+	return s.parser.Generate(config)
+}
+
+// ApplyToConfig 将节点适用的分组配置块写入配置，同名块整体替换
+func (s *GroupBlockService) ApplyToConfig(config *models.SmartDNSConfig, nodeID uint) int {
+	var blocks []models.GroupBlock
+	database.DB.Where("enabled = ?", true).Order("name").Find(&blocks)
+
+	applied := 0
+	for i := range blocks {
+		if !nodeIDsContain(blocks[i].NodeIDs, nodeID) {
+			continue
+		}
+		s.upsertGroup(config, s.parser.GenerateGroupBlock(&blocks[i]))
+		applied++
+	}
+	return applied
+}
+
+// ImportFromNode 从节点配置中导入 group 块，已存在的同名分组跳过
+func (s *GroupBlockService) ImportFromNode(node *models.Node) ([]models.GroupBlock, error) {
+	client, err := NewSSHClient(node)
+	if err != nil {
+		return nil, fmt.Errorf("连接节点失败: %w", err)
+	}
+	defer client.Close()
+
+	content, err := client.ReadFile(node.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置失败: %w", err)
+	}
+
+	config, err := s.parser.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("解析配置失败: %w", err)
+	}
+
+	imported := []models.GroupBlock{}
+	for _, group := range config.Groups {
+		var count int64
+		database.DB.Model(&models.GroupBlock{}).Where("name = ?", group.Name).Count(&count)
+		if count > 0 || group.Name == "" {
+			continue
+		}
+
+		block := s.parser.ParseGroupBlock(group)
+		block.NodeIDs = fmt.Sprintf("[%d]", node.ID)
+		if err := database.DB.Create(block).Error; err != nil {
+			log.Printf("导入分组配置块 %s 失败: %v", group.Name, err)
+			continue
+		}
+		imported = append(imported, *block)
+	}
+
+	return imported, nil
+}
+
+// SyncGroupBlockToNodes 同步分组配置块到节点
+func (s *GroupBlockService) SyncGroupBlockToNodes(ctx context.Context, block *models.GroupBlock) error {
+	if !block.Enabled {
+		return nil
+	}
+
+	nodes, err := s.getTargetNodes(block.NodeIDs)
+	if err != nil {
+		return err
+	}
+
+	group := s.parser.GenerateGroupBlock(block)
+	for _, node := range nodes {
+		go updateNodeConfig(ctx, s.notificationService, &node, "group", "update", block.Name, func(config *models.SmartDNSConfig) {
+			s.upsertGroup(config, group)
+		})
+	}
+
+	return nil
+}
+
+// DeleteGroupBlockFromNodes 从节点删除分组配置块
+func (s *GroupBlockService) DeleteGroupBlockFromNodes(ctx context.Context, block *models.GroupBlock) error {
+	nodes, err := s.getTargetNodes(block.NodeIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		go updateNodeConfig(ctx, s.notificationService, &node, "group", "delete", block.Name, func(config *models.SmartDNSConfig) {
+			groups := []models.ConfigGroup{}
+			for _, g := range config.Groups {
+				if g.Name != block.Name {
+					groups = append(groups, g)
+				}
+			}
+			config.Groups = groups
+		})
+	}
+
+	return nil
+}
+
+// DeleteGroupBlockFromRemovedNodes 从不再适用的节点删除分组配置块
+func (s *GroupBlockService) DeleteGroupBlockFromRemovedNodes(ctx context.Context, previous, current *models.GroupBlock) error {
+	nodes, err := s.getTargetNodes(previous.NodeIDs)
+	if err != nil {
+		return err
+	}
+
+	removed := []uint{}
+	for _, node := range nodes {
+		if !nodeIDsContain(current.NodeIDs, node.ID) {
+			removed = append(removed, node.ID)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	data, _ := json.Marshal(removed)
+	stale := *previous
+	stale.NodeIDs = string(data)
+	return s.DeleteGroupBlockFromNodes(ctx, &stale)
+}
+
+// upsertGroup 替换同名 group 块，不存在时追加
+func (s *GroupBlockService) upsertGroup(config *models.SmartDNSConfig, group models.ConfigGroup) {
+	for i, g := range config.Groups {
+		if g.Name == group.Name {
+			config.Groups[i] = group
+			return
+		}
+	}
+	config.Groups = append(config.Groups, group)
+}
+
+// getTargetNodes 获取目标节点列表
+func (s *GroupBlockService) getTargetNodes(nodeIDsJSON string) ([]models.Node, error) {
+	var nodes []models.Node
+
+	if nodeIDsJSON == "" || nodeIDsJSON == "[]" {
+		database.DB.Find(&nodes)
+	} else {
+		var nodeIDs []uint
+		if err := json.Unmarshal([]byte(nodeIDsJSON), &nodeIDs); err != nil {
+			return nil, err
+		}
+		database.DB.Where("id IN ?", nodeIDs).Find(&nodes)
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// DefaultHealthScoreWeights 默认健康评分权重
+var DefaultHealthScoreWeights = models.HealthScoreWeights{
+	Service:       30,
+	Resource:      15,
+	IngestionLag:  15,
+	SyncFreshness: 15,
+	Telemetry:     10,
+	ErrorRate:     15,
+}
+
+// HealthScoreService 节点综合健康评分服务
+type HealthScoreService struct {
+	db                  *gorm.DB
+	config              *config.Config
+	notificationService *NotificationService
+
+	mutex    sync.Mutex
+	alerting map[uint]bool // 当前处于低分告警状态的节点
+}
+
+// NewHealthScoreService 创建节点健康评分服务
+func NewHealthScoreService(db *gorm.DB, config *config.Config) (*HealthScoreService, error) {
+	return &HealthScoreService{
+		db:                  db,
+		config:              config,
+		notificationService: NewNotificationService(),
+		alerting:            make(map[uint]bool),
+	}, nil
+}
+
+// ParseHealthScoreWeights 解析权重配置，格式: service=30,resource=15,...，未配置的项使用默认值
+func ParseHealthScoreWeights(raw string) models.HealthScoreWeights {
+	weights := DefaultHealthScoreWeights
+	for _, pair := range strings.Split(raw, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || value < 0 {
+			log.Printf("⚠️ 忽略无效的健康评分权重: %s", pair)
+			continue
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "service":
+			weights.Service = value
+		case "resource":
+			weights.Resource = value
+		case "ingestion_lag":
+			weights.IngestionLag = value
+		case "sync_freshness":
+			weights.SyncFreshness = value
+		case "telemetry":
+			weights.Telemetry = value
+		case "error_rate":
+			weights.ErrorRate = value
+		default:
+			log.Printf("⚠️ 未知的健康评分权重项: %s", kv[0])
+		}
+	}
+	return weights
+}
+
+// resolveWeights 获取生效的权重（任务配置优先于全局配置）
+func (s *HealthScoreService) resolveWeights(custom *models.HealthScoreWeights) models.HealthScoreWeights {
+	if custom != nil {
+		return *custom
+	}
+	return ParseHealthScoreWeights(s.config.HealthScoreWeights)
+}
+
+// CalculateScores 计算节点综合健康评分，nodeIDs 为空时计算所有节点
+func (s *HealthScoreService) CalculateScores(ctx context.Context, nodeIDs []uint, checkResources bool, custom *models.HealthScoreWeights) ([]models.NodeHealthScore, error) {
+	var nodes []models.Node
+	query := s.db.Model(&models.Node{})
+	if len(nodeIDs) > 0 {
+		query = query.Where("id IN ?", nodeIDs)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
+		return nil, fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	weights := s.resolveWeights(custom)
+	// 遥测目标不区分节点，所有节点共用同一项评分
+	telemetry := s.telemetryComponent(weights.Telemetry)
+
+	scores := make([]models.NodeHealthScore, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, n models.Node) {
+			defer wg.Done()
+			scores[i] = s.calculateNodeScore(ctx, n, weights, telemetry, checkResources)
+		}(i, node)
+	}
+	wg.Wait()
+
+	return scores, nil
+}
+
+// calculateNodeScore 计算单个节点的评分
+func (s *HealthScoreService) calculateNodeScore(ctx context.Context, node models.Node, weights models.HealthScoreWeights, telemetry models.HealthComponent, checkResources bool) models.NodeHealthScore {
+	var status *models.NodeStatus
+	var sshErr error
+	if checkResources {
+		client, err := NewSSHClient(&node)
+		if err != nil {
+			sshErr = err
+		} else {
+			status, sshErr = client.GetSystemInfo()
+			client.Close()
+		}
+	}
+
+	components := []models.HealthComponent{
+		s.serviceComponent(node, status, sshErr, weights.Service),
+		s.resourceComponent(status, weights.Resource),
+		s.ingestionLagComponent(ctx, node, weights.IngestionLag),
+		s.syncFreshnessComponent(node, weights.SyncFreshness),
+		telemetry,
+		s.errorRateComponent(ctx, node, weights.ErrorRate),
+	}
+
+	var total, weightSum float64
+	for _, c := range components {
+		if c.Available && c.Weight > 0 {
+			total += c.Score * c.Weight
+			weightSum += c.Weight
+		}
+	}
+
+	score := 0.0
+	if weightSum > 0 {
+		score = math.Round(total/weightSum*10) / 10
+	}
+
+	return models.NodeHealthScore{
+		NodeID:       node.ID,
+		NodeName:     node.Name,
+		Score:        score,
+		Level:        healthLevel(score),
+		Components:   components,
+		CalculatedAt: time.Now(),
+	}
+}
+
+// healthLevel 根据分数确定健康等级
+func healthLevel(score float64) string {
+	switch {
+	case score >= 80:
+		return models.HealthLevelHealthy
+	case score >= 60:
+		return models.HealthLevelWarning
+	default:
+		return models.HealthLevelCritical
+	}
+}
+
+// clampScore 将分数限制在 0-100 之间
+func clampScore(score float64) float64 {
+	return math.Max(0, math.Min(100, score))
+}
+
+// serviceComponent 服务状态评分
+func (s *HealthScoreService) serviceComponent(node models.Node, status *models.NodeStatus, sshErr error, weight float64) models.HealthComponent {
+	c := models.HealthComponent{Name: "service", Weight: weight, Available: true}
+
+	switch {
+	case sshErr != nil:
+		c.Detail = "无法连接节点: " + sshErr.Error()
+	case status != nil && status.ServiceUp:
+		c.Score = 100
+		c.Detail = "SmartDNS 服务运行中"
+	case status != nil:
+		c.Detail = "SmartDNS 服务未运行"
+	case node.Status == "online":
+		c.Score = 100
+		c.Detail = "节点在线"
+	case node.Status == "offline":
+		c.Detail = "节点离线"
+	case node.Status == "error":
+		c.Score = 30
+		c.Detail = "节点状态异常"
+	default:
+		c.Available = false
+		c.Detail = "节点状态未知"
+	}
+
+	return c
+}
+
+// resourceComponent 资源使用率评分（使用率 50% 以下满分，100% 时为 0）
+func (s *HealthScoreService) resourceComponent(status *models.NodeStatus, weight float64) models.HealthComponent {
+	c := models.HealthComponent{Name: "resource", Weight: weight}
+	if status == nil {
+		c.Detail = "未采集资源使用率"
+		return c
+	}
+
+	peak := math.Max(status.CPUUsage, math.Max(status.MemoryUsage, status.DiskUsage))
+	c.Available = true
+	c.Score = clampScore(100 - (peak-50)*2)
+	c.Detail = fmt.Sprintf("CPU %.1f%%，内存 %.1f%%，磁盘 %.1f%%", status.CPUUsage, status.MemoryUsage, status.DiskUsage)
+	return c
+}
+
+// ingestionLagComponent 日志采集延迟评分（2 分钟内满分，60 分钟以上为 0）
+func (s *HealthScoreService) ingestionLagComponent(ctx context.Context, node models.Node, weight float64) models.HealthComponent {
+	c := models.HealthComponent{Name: "ingestion_lag", Weight: weight}
+	if !node.LogMonitorEnabled || database.CHConn == nil {
+		c.Detail = "未启用日志采集"
+		return c
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var latest time.Time
+	if err := database.CHConn.QueryRow(queryCtx,
+		"SELECT max(timestamp) FROM dns_query_log WHERE node_id = ?", uint32(node.ID)).Scan(&latest); err != nil {
+		c.Detail = "查询采集延迟失败: " + err.Error()
+		return c
+	}
+
+	c.Available = true
+	if latest.Unix() <= 0 {
+		c.Detail = "尚未采集到日志"
+		return c
+	}
+
+	lag := time.Since(latest)
+	c.Score = clampScore(100 - (lag.Minutes()-2)*100/58)
+	c.Detail = fmt.Sprintf("最新日志于 %s 前", lag.Truncate(time.Second))
+	return c
+}
+
+// syncFreshnessComponent 配置同步评分（最近 24 小时同步成功率，最近一次失败时最高 50 分）
+func (s *HealthScoreService) syncFreshnessComponent(node models.Node, weight float64) models.HealthComponent {
+	c := models.HealthComponent{Name: "sync_freshness", Weight: weight}
+
+	var last models.ConfigSyncLog
+	if err := s.db.Where("node_id = ? AND status <> ?", node.ID, "pending").
+		Order("created_at desc").First(&last).Error; err != nil {
+		c.Detail = "暂无同步记录"
+		return c
+	}
+
+	since := time.Now().Add(-24 * time.Hour)
+	var total, failed int64
+	s.db.Model(&models.ConfigSyncLog{}).Where("node_id = ? AND created_at >= ? AND status <> ?", node.ID, since, "pending").Count(&total)
+	s.db.Model(&models.ConfigSyncLog{}).Where("node_id = ? AND created_at >= ? AND status = ?", node.ID, since, "failed").Count(&failed)
+
+	c.Available = true
+	c.Score = 100
+	if total > 0 {
+		c.Score = float64(total-failed) / float64(total) * 100
+	}
+	if last.Status == "failed" {
+		c.Score = math.Min(c.Score, 50)
+		c.Detail = fmt.Sprintf("最近一次同步失败: %s", last.Error)
+	} else {
+		c.Detail = fmt.Sprintf("最近 24 小时同步 %d 次，失败 %d 次", total, failed)
+	}
+	return c
+}
+
+// telemetryComponent 遥测评分（最近 1 小时成功率，平均延迟超过 200ms 时扣分）
+func (s *HealthScoreService) telemetryComponent(weight float64) models.HealthComponent {
+	c := models.HealthComponent{Name: "telemetry", Weight: weight}
+
+	var stats struct {
+		Total      int64
+		Success    int64
+		AvgLatency float64
+	}
+	err := s.db.Model(&models.TelemetryResult{}).
+		Select("COUNT(*) AS total, SUM(CASE WHEN success THEN 1 ELSE 0 END) AS success, AVG(CASE WHEN success THEN latency END) AS avg_latency").
+		Where("checked_at >= ?", time.Now().Add(-time.Hour)).
+		Scan(&stats).Error
+	if err != nil || stats.Total == 0 {
+		c.Detail = "最近 1 小时无遥测数据"
+		return c
+	}
+
+	successRate := float64(stats.Success) / float64(stats.Total)
+	penalty := math.Min(30, math.Max(0, (stats.AvgLatency-200)*30/800))
+	c.Available = true
+	c.Score = clampScore(successRate*100 - penalty)
+	c.Detail = fmt.Sprintf("成功率 %.1f%%，平均延迟 %.0fms", successRate*100, stats.AvgLatency)
+	return c
+}
+
+// errorRateComponent 解析失败率评分（最近 15 分钟 NXDOMAIN 占比，50% 以上为 0）
+func (s *HealthScoreService) errorRateComponent(ctx context.Context, node models.Node, weight float64) models.HealthComponent {
+	c := models.HealthComponent{Name: "error_rate", Weight: weight}
+	if database.CHConn == nil {
+		c.Detail = "ClickHouse 未连接"
+		return c
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var total, failed uint64
+	if err := database.CHConn.QueryRow(queryCtx, `
+        SELECT count(), countIf(`+nxdomainCondition+`)
+        FROM dns_query_log WHERE node_id = ? AND timestamp >= ?`,
+		uint32(node.ID), time.Now().Add(-15*time.Minute)).Scan(&total, &failed); err != nil {
+		c.Detail = "查询解析失败率失败: " + err.Error()
+		return c
+	}
+	if total == 0 {
+		c.Detail = "最近 15 分钟无查询记录"
+		return c
+	}
+
+	rate := float64(failed) / float64(total)
+	c.Available = true
+	c.Score = clampScore(100 - rate*200)
+	c.Detail = fmt.Sprintf("最近 15 分钟 %d 次查询，失败率 %.2f%%", total, rate*100)
+	return c
+}
+
+// CheckHealthScores 计算节点健康评分，低于阈值或恢复时发送通知
+func (s *HealthScoreService) CheckHealthScores(ctx context.Context, cfg models.HealthScoreConfig) (string, error) {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 60
+	}
+
+	scores, err := s.CalculateScores(ctx, cfg.NodeIDs, cfg.CheckResources, cfg.Weights)
+	if err != nil {
+		return "", err
+	}
+	if len(scores) == 0 {
+		return "没有找到需要检查的节点", nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var lowCount, recovered int
+	for _, score := range scores {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		wasAlerting := s.alerting[score.NodeID]
+		if score.Score < cfg.Threshold {
+			lowCount++
+			if wasAlerting {
+				continue
+			}
+			s.alerting[score.NodeID] = true
+
+			var lines []string
+			for _, c := range score.Components {
+				if c.Available {
+					lines = append(lines, fmt.Sprintf("- %s: %.0f（%s）", c.Name, c.Score, c.Detail))
+				}
+			}
+			content := fmt.Sprintf("节点综合健康评分 %.1f，低于阈值 %.0f：\n%s", score.Score, cfg.Threshold, strings.Join(lines, "\n"))
+			if err := s.notificationService.SendNotification(score.NodeID, "health_score_low", "⚠️ 节点健康评分过低", content); err != nil {
+				log.Printf("⚠️ 发送健康评分通知失败: %v", err)
+			}
+		} else if wasAlerting {
+			recovered++
+			delete(s.alerting, score.NodeID)
+
+			content := fmt.Sprintf("节点综合健康评分已恢复至 %.1f（阈值 %.0f）", score.Score, cfg.Threshold)
+			if err := s.notificationService.SendNotification(score.NodeID, "health_score_low", "✅ 节点健康评分恢复", content); err != nil {
+				log.Printf("⚠️ 发送健康评分通知失败: %v", err)
+			}
+		}
+	}
+
+	return fmt.Sprintf("检查 %d 个节点，%d 个低于阈值 %.0f，%d 个已恢复", len(scores), lowCount, cfg.Threshold, recovered), nil
+}
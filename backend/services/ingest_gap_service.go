@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"smartdns-manager/models"
+)
+
+// IngestGapService 根据 Agent 写入的批次确认记录检测日志缺失与乱序
+type IngestGapService struct {
+	conn driver.Conn
+}
+
+// NewIngestGapService 创建日志缺失检测服务
+func NewIngestGapService(conn driver.Conn) *IngestGapService {
+	return &IngestGapService{conn: conn}
+}
+
+// GetReport 生成节点自 since 以来的缺失报告
+func (s *IngestGapService) GetReport(node *models.Node, since time.Time) (*models.IngestGapReport, error) {
+	if s.conn == nil {
+		return nil, fmt.Errorf("ClickHouse 未连接")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.conn.Query(ctx, `
+        SELECT stream_id, file_path, seq, start_offset, end_offset, rows, reread, sent_at, inserted_at
+        FROM dns_ingest_batches
+        WHERE node_id = ? AND sent_at >= ?
+        ORDER BY stream_id, seq, inserted_at
+    `, uint32(node.ID), since)
+	if err != nil {
+		return nil, fmt.Errorf("查询批次确认记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.IngestGapReport{
+		NodeID:   node.ID,
+		NodeName: node.Name,
+		Since:    since,
+		Streams:  []models.IngestStreamReport{},
+	}
+
+	var stream []models.IngestBatch
+	addStream := func() {
+		if len(stream) == 0 {
+			return
+		}
+		streamReport := analyzeIngestStream(stream)
+		report.Streams = append(report.Streams, streamReport)
+		report.TotalGaps += len(streamReport.Gaps)
+		report.OutOfOrder += streamReport.OutOfOrder
+		for _, gap := range streamReport.Gaps {
+			report.GapBytes += gap.Bytes
+		}
+		stream = nil
+	}
+
+	for rows.Next() {
+		var b models.IngestBatch
+		var reread uint8
+		if err := rows.Scan(&b.StreamID, &b.FilePath, &b.Seq, &b.StartOffset, &b.EndOffset,
+			&b.Rows, &reread, &b.SentAt, &b.InsertedAt); err != nil {
+			return nil, err
+		}
+		b.Reread = reread == 1
+
+		if len(stream) > 0 && stream[0].StreamID != b.StreamID {
+			addStream()
+		}
+		stream = append(stream, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	addStream()
+
+	sort.Slice(report.Streams, func(i, j int) bool {
+		return report.Streams[i].LastSentAt.After(report.Streams[j].LastSentAt)
+	})
+
+	return report, nil
+}
+
+// Reread 请求 Agent 补读缺失区间，文件已不存在的区间会返回失败
+func (s *IngestGapService) Reread(node *models.Node, gaps []models.IngestGap) []models.IngestRereadResult {
+	results := make([]models.IngestRereadResult, 0, len(gaps))
+	for _, gap := range gaps {
+		result := models.IngestRereadResult{
+			StreamID:    gap.StreamID,
+			StartOffset: gap.StartOffset,
+			EndOffset:   gap.EndOffset,
+		}
+
+		response, err := CallNodeAgentWithResponse(node, "POST", "/reread", map[string]interface{}{
+			"stream_id":    gap.StreamID,
+			"seq":          gap.AfterSeq + 1,
+			"start_offset": gap.StartOffset,
+			"end_offset":   gap.EndOffset,
+		})
+		if err != nil {
+			result.Message = err.Error()
+		} else {
+			result.Success = true
+			if data, ok := response["data"].(map[string]interface{}); ok {
+				if n, ok := data["rows"].(float64); ok {
+					result.Rows = int(n)
+				}
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// analyzeIngestStream 分析单个 stream 的批次（按 seq、写入时间排序）：
+// 统计缺失序号、重复和乱序，并以偏移区间覆盖情况找出缺失区间，补读批次也计入覆盖
+func analyzeIngestStream(batches []models.IngestBatch) models.IngestStreamReport {
+	report := models.IngestStreamReport{
+		StreamID: batches[0].StreamID,
+		FilePath: batches[0].FilePath,
+		Gaps:     []models.IngestGap{},
+	}
+
+	var primary []models.IngestBatch
+	var intervals [][2]uint64
+	var latestInsert time.Time
+	seen := make(map[uint64]bool)
+
+	for _, b := range batches {
+		if b.EndOffset > b.StartOffset {
+			intervals = append(intervals, [2]uint64{b.StartOffset, b.EndOffset})
+		}
+		if b.Reread {
+			report.Rereads++
+			continue
+		}
+		if seen[b.Seq] {
+			report.Duplicates++
+			continue
+		}
+		seen[b.Seq] = true
+
+		if len(primary) == 0 {
+			report.FirstSeq = b.Seq
+			report.FilePath = b.FilePath
+		}
+		primary = append(primary, b)
+		report.Batches++
+		report.Rows += uint64(b.Rows)
+		report.LastSeq = b.Seq
+		if b.SentAt.After(report.LastSentAt) {
+			report.LastSentAt = b.SentAt
+		}
+
+		// 序号更小的批次反而更晚写入
+		if b.InsertedAt.Before(latestInsert) {
+			report.OutOfOrder++
+		} else {
+			latestInsert = b.InsertedAt
+		}
+	}
+
+	if report.Batches > 0 {
+		report.MissingSeqs = report.LastSeq - report.FirstSeq + 1 - uint64(report.Batches)
+	}
+	if len(intervals) == 0 {
+		return report
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i][0] < intervals[j][0]
+	})
+
+	covered := intervals[0][1]
+	for _, iv := range intervals[1:] {
+		if iv[0] > covered {
+			report.Gaps = append(report.Gaps, newIngestGap(report, primary, covered, iv[0]))
+		}
+		if iv[1] > covered {
+			covered = iv[1]
+		}
+	}
+
+	return report
+}
+
+// newIngestGap 构造缺失区间，并找出区间前后相邻的批次序号
+func newIngestGap(report models.IngestStreamReport, primary []models.IngestBatch, start, end uint64) models.IngestGap {
+	gap := models.IngestGap{
+		StreamID:    report.StreamID,
+		FilePath:    report.FilePath,
+		StartOffset: start,
+		EndOffset:   end,
+		Bytes:       end - start,
+	}
+
+	for _, b := range primary {
+		if b.EndOffset <= start && b.Seq > gap.AfterSeq {
+			gap.AfterSeq = b.Seq
+		}
+		if b.StartOffset >= end && (gap.BeforeSeq == 0 || b.Seq < gap.BeforeSeq) {
+			gap.BeforeSeq = b.Seq
+		}
+	}
+	if gap.BeforeSeq > gap.AfterSeq+1 {
+		gap.MissingSeqs = gap.BeforeSeq - gap.AfterSeq - 1
+	}
+
+	return gap
+}
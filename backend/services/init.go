@@ -1,13 +1,13 @@
 package services
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"regexp"
 	"strings"
 	"time"
 
-	"smartdns-manager/config"
 	"smartdns-manager/database"
 	"smartdns-manager/models"
 )
@@ -22,103 +22,88 @@ func NewInitService() *InitService {
 	}
 }
 
-// SmartDNSRelease SmartDNS 发行版信息
-type SmartDNSRelease struct {
-	Version      string
-	DownloadURL  string
-	Architecture string
-	OSType       string
-}
+// initSteps 安装 SmartDNS 的步骤，每步完成后记录检查点，服务重启后从下一步继续
+var initSteps = []string{"download", "install", "configure", "start"}
 
-// GetLatestReleases 获取最新版本的下载链接
-func (s *InitService) GetLatestReleases() map[string]SmartDNSRelease {
-	// 使用固定版本，也可以从 GitHub API 动态获取
-	version := config.GetConfig().InitVersion
-	baseURL := config.GetConfig().InitBaseURL
-
-	return map[string]SmartDNSRelease{
-		"x86_64-linux": {
-			Version:      version,
-			DownloadURL:  fmt.Sprintf("%s/smartdns.%s.x86_64-linux-all.tar.gz", baseURL, version),
-			Architecture: "x86_64",
-			OSType:       "linux",
-		},
-		"aarch64-linux": {
-			Version:      version,
-			DownloadURL:  fmt.Sprintf("%s/smartdns.%s.aarch64-linux-all.tar.gz", baseURL, version),
-			Architecture: "aarch64",
-			OSType:       "linux",
-		},
-		"arm-linux": {
-			Version:      version,
-			DownloadURL:  fmt.Sprintf("%s/smartdns.%s.arm-linux-all.tar.gz", baseURL, version),
-			Architecture: "arm",
-			OSType:       "linux",
-		},
-	}
+// InitNode 初始化节点
+func (s *InitService) InitNode(ctx context.Context, nodeID uint) error {
+	return s.InitNodeFrom(ctx, nodeID, "", nil)
 }
 
-// InitNode 初始化节点
-func (s *InitService) InitNode(nodeID uint) error {
+// InitNodeFrom 初始化节点，resumeAfter 为上次完成的步骤（为空时从头开始），
+// 每完成一步调用 checkpoint 记录进度
+func (s *InitService) InitNodeFrom(ctx context.Context, nodeID uint, resumeAfter string, checkpoint func(step string)) error {
 	var node models.Node
 	if err := database.DB.First(&node, nodeID).Error; err != nil {
 		return fmt.Errorf("节点不存在: %w", err)
 	}
+	GetInitProgressBroker().Begin(node.ID)
 
-	log.Printf("🚀 开始初始化节点: %s (%s)", node.Name, node.Host)
+	if resumeAfter == "" {
+		slog.InfoContext(ctx, "开始初始化节点", "node", node.Name, "host", node.Host)
+	} else {
+		slog.InfoContext(ctx, "恢复初始化节点", "node", node.Name, "host", node.Host, "resume_after", resumeAfter)
+	}
 
 	// 更新初始化状态
 	node.InitStatus = "initializing"
 	database.DB.Save(&node)
 
-	// 发送通知
-	s.notificationService.SendNotification(
-		node.ID,
-		"node_init_start",
-		"🚀 节点初始化开始",
-		fmt.Sprintf("节点 `%s` 开始初始化 SmartDNS", node.Name),
-	)
-
-	// 步骤1: 检测系统环境
-	if err := s.detectSystem(&node); err != nil {
-		return s.handleInitError(&node, "detect", err)
-	}
-
-	// 步骤2: 检查 SmartDNS 是否已安装
-	installed, version := s.checkSmartDNSInstalled(&node)
-	if installed {
-		log.Printf(" SmartDNS 已安装，版本: %s", version)
-		node.InitStatus = "installed"
-		node.SmartDNSVersion = version
-		database.DB.Save(&node)
-
+	if resumeAfter == "" {
+		// 发送通知
 		s.notificationService.SendNotification(
 			node.ID,
-			"node_init_success",
-			" 节点已安装 SmartDNS",
-			fmt.Sprintf("节点 `%s` 已安装 SmartDNS %s", node.Name, version),
+			"node_init_start",
+			"🚀 节点初始化开始",
+			fmt.Sprintf("节点 `%s` 开始初始化 SmartDNS", node.Name),
 		)
-		return nil
-	}
 
-	// 步骤3: 下载 SmartDNS
-	if err := s.downloadSmartDNS(&node); err != nil {
-		return s.handleInitError(&node, "download", err)
-	}
+		// 步骤1: 检测系统环境
+		if err := s.detectSystem(ctx, &node); err != nil {
+			return s.handleInitError(ctx, &node, "detect", err)
+		}
 
-	// 步骤4: 安装 SmartDNS
-	if err := s.installSmartDNS(&node); err != nil {
-		return s.handleInitError(&node, "install", err)
+		// 步骤2: 检查 SmartDNS 是否已安装
+		installed, version := s.checkSmartDNSInstalled(ctx, &node)
+		if installed {
+			slog.InfoContext(ctx, "SmartDNS 已安装", "node", node.Name, "version", version)
+			node.InitStatus = "installed"
+			node.SmartDNSVersion = version
+			database.DB.Save(&node)
+
+			s.notificationService.SendNotification(
+				node.ID,
+				"node_init_success",
+				" 节点已安装 SmartDNS",
+				fmt.Sprintf("节点 `%s` 已安装 SmartDNS %s", node.Name, version),
+			)
+			publishInitDone(node.ID, node.InitStatus, "")
+			return nil
+		}
+		if checkpoint != nil {
+			checkpoint("detect")
+		}
 	}
 
-	// 步骤5: 初始化配置
-	if err := s.initConfig(&node); err != nil {
-		return s.handleInitError(&node, "configure", err)
+	// 步骤3-6: 下载、安装、初始化配置、启动服务
+	run := map[string]func(context.Context, *models.Node) error{
+		"download":  s.downloadSmartDNS,
+		"install":   s.installSmartDNS,
+		"configure": s.initConfig,
+		"start":     s.startService,
 	}
-
-	// 步骤6: 启动服务
-	if err := s.startService(&node); err != nil {
-		return s.handleInitError(&node, "start", err)
+	skipping := resumeAfter != "" && resumeAfter != "detect"
+	for _, step := range initSteps {
+		if skipping {
+			skipping = step != resumeAfter
+			continue
+		}
+		if err := run[step](ctx, &node); err != nil {
+			return s.handleInitError(ctx, &node, step, err)
+		}
+		if checkpoint != nil {
+			checkpoint(step)
+		}
 	}
 
 	// 更新状态
@@ -126,7 +111,7 @@ func (s *InitService) InitNode(nodeID uint) error {
 	node.Status = "online"
 	database.DB.Save(&node)
 
-	log.Printf(" 节点初始化完成: %s", node.Name)
+	slog.InfoContext(ctx, "节点初始化完成", "node", node.Name)
 
 	// 发送成功通知
 	s.notificationService.SendNotification(
@@ -136,12 +121,13 @@ func (s *InitService) InitNode(nodeID uint) error {
 		fmt.Sprintf("节点 `%s` SmartDNS 安装完成\n版本: %s", node.Name, node.SmartDNSVersion),
 	)
 
+	publishInitDone(node.ID, node.InitStatus, "")
 	return nil
 }
 
 // detectSystem 检测系统环境
-func (s *InitService) detectSystem(node *models.Node) error {
-	log.Printf("📋 步骤1: 检测系统环境...")
+func (s *InitService) detectSystem(ctx context.Context, node *models.Node) error {
+	slog.InfoContext(ctx, "检测系统环境", "node", node.Name, "step", 1)
 
 	initLog := s.createInitLog(node.ID, "detect", "running", "检测系统环境")
 
@@ -161,7 +147,9 @@ func (s *InitService) detectSystem(node *models.Node) error {
 
 	// 解析系统类型
 	osInfoLower := strings.ToLower(osInfo)
-	if strings.Contains(osInfoLower, "ubuntu") {
+	if strings.Contains(osInfoLower, "openwrt") {
+		node.OSType = "openwrt"
+	} else if strings.Contains(osInfoLower, "ubuntu") {
 		node.OSType = "ubuntu"
 	} else if strings.Contains(osInfoLower, "debian") {
 		node.OSType = "debian"
@@ -186,70 +174,82 @@ func (s *InitService) detectSystem(node *models.Node) error {
 		return fmt.Errorf("获取系统架构失败: %w", err)
 	}
 	node.Architecture = strings.TrimSpace(arch)
+	node.ServiceManager = detectServiceManager(client)
 
-	// 检查并安装依赖
-	dependencies := []string{"wget", "tar"}
+	// 选择安装方式，检查节点状态时不改变已安装节点的安装方式
+	if node.InitStatus == "initializing" {
+		node.InstalledVia = resolveInstallMode(ctx, node)
+	}
+
+	// 检查并安装依赖，OpenWrt 默认以 root 登录且没有 sudo，安装后其余命令可以照常使用 sudo
+	var dependencies []string
+	switch smartDNSInstalledVia(node) {
+	case models.InstallModeTarball:
+		dependencies = []string{"wget", "tar"}
+	case models.InstallModeOpkg:
+		dependencies = []string{"sudo"}
+	}
 	for _, dep := range dependencies {
 		if _, err := client.ExecuteCommand(fmt.Sprintf("which %s", dep)); err != nil {
-			log.Printf("⚠️  缺少依赖: %s，尝试安装...", dep)
-			if err := s.installDependency(client, node.OSType, dep); err != nil {
-				log.Printf("⚠️  安装依赖 %s 失败: %v", dep, err)
+			slog.WarnContext(ctx, "缺少依赖，尝试安装", "node", node.Name, "dependency", dep)
+			if err := s.installDependency(client, node, dep); err != nil {
+				slog.WarnContext(ctx, "安装依赖失败", "node", node.Name, "dependency", dep, "error", err)
 			}
 		}
 	}
 
 	database.DB.Save(node)
 
-	detail := fmt.Sprintf("OS: %s %s\nArchitecture: %s", node.OSType, node.OSVersion, node.Architecture)
+	detail := fmt.Sprintf("OS: %s %s\nArchitecture: %s\nInstall: %s\nService: %s",
+		node.OSType, node.OSVersion, node.Architecture, smartDNSInstalledVia(node), node.ServiceManager)
 	s.updateInitLog(initLog, "success", detail, "")
 
-	log.Printf(" 系统检测完成: %s %s (%s)", node.OSType, node.OSVersion, node.Architecture)
+	slog.InfoContext(ctx, "系统检测完成", "node", node.Name, "os", node.OSType, "os_version", node.OSVersion, "arch", node.Architecture)
 	return nil
 }
 
 // checkSmartDNSInstalled 检查 SmartDNS 是否已安装
-func (s *InitService) checkSmartDNSInstalled(node *models.Node) (bool, string) {
+func (s *InitService) checkSmartDNSInstalled(ctx context.Context, node *models.Node) (bool, string) {
 	client, err := NewSSHClient(node)
 	if err != nil {
-		log.Printf("SSH连接失败: %v", err)
+		slog.ErrorContext(ctx, "SSH连接失败", "node", node.Name, "error", err)
 		return false, ""
 	}
 	defer client.Close()
 
 	// 检查 smartdns 命令是否存在
-	output, err := client.ExecuteCommand("smartdns -v 2>&1 || /usr/sbin/smartdns -v 2>&1")
+	output, err := client.ExecuteCommand(smartDNSVersionCommand(node))
 	if err != nil {
-		log.Printf("执行命令失败: %v", err)
+		slog.ErrorContext(ctx, "检查 SmartDNS 版本失败", "node", node.Name, "error", err)
 		return false, ""
 	}
 
 	// 打印原始输出，看看实际收到了什么
-	log.Printf("原始输出: [%s]", output)
-	log.Printf("输出长度: %d", len(output))
+	slog.DebugContext(ctx, "SmartDNS 版本命令输出", "node", node.Name, "output", output, "length", len(output))
 
 	// 简单检查是否包含 smartdns
 	if !strings.Contains(strings.ToLower(output), "smartdns") {
-		log.Printf("输出中不包含 smartdns")
+		slog.DebugContext(ctx, "输出中不包含 smartdns", "node", node.Name)
 		return false, ""
 	}
 
 	// 提取版本号
 	versionRe := regexp.MustCompile(`(?i)smartdns\s+([\d.]+[^\s]*)`)
 	match := versionRe.FindStringSubmatch(output)
-	log.Printf("正则匹配结果: %v", match)
+	slog.DebugContext(ctx, "版本号匹配结果", "node", node.Name, "match", match)
 
 	if len(match) > 1 {
-		log.Printf("找到版本: %s", match[1])
+		slog.DebugContext(ctx, "找到 SmartDNS 版本", "node", node.Name, "version", match[1])
 		return true, match[1]
 	}
 
-	log.Printf("正则匹配失败")
+	slog.DebugContext(ctx, "无法从输出中提取版本号", "node", node.Name)
 	return false, ""
 }
 
 // downloadSmartDNS 下载 SmartDNS
-func (s *InitService) downloadSmartDNS(node *models.Node) error {
-	log.Printf("📥 步骤2: 下载 SmartDNS...")
+func (s *InitService) downloadSmartDNS(ctx context.Context, node *models.Node) error {
+	slog.InfoContext(ctx, "下载 SmartDNS", "node", node.Name, "step", 2)
 
 	initLog := s.createInitLog(node.ID, "download", "running", "下载 SmartDNS")
 
@@ -260,30 +260,21 @@ func (s *InitService) downloadSmartDNS(node *models.Node) error {
 	}
 	defer client.Close()
 
-	// 获取下载链接
-	releases := s.GetLatestReleases()
-	var release SmartDNSRelease
-	found := false
-
-	// 根据架构选择合适的版本
-	archKey := fmt.Sprintf("%s-linux", node.Architecture)
-	if r, ok := releases[archKey]; ok {
-		release = r
-		found = true
-	} else {
-		// 尝试兼容性匹配
-		for key, r := range releases {
-			if strings.Contains(key, node.Architecture) {
-				release = r
-				found = true
-				break
-			}
+	if via := smartDNSInstalledVia(node); via != models.InstallModeTarball {
+		output, err := s.prepareSmartDNSPackage(client, node)
+		if err != nil {
+			s.updateInitLog(initLog, "failed", output, err.Error())
+			return fmt.Errorf("下载失败: %w", err)
 		}
+		s.updateInitLog(initLog, "success", "安装方式: "+via, "")
+		return nil
 	}
 
-	if !found {
-		s.updateInitLog(initLog, "failed", "", "不支持的系统架构: "+node.Architecture)
-		return fmt.Errorf("不支持的系统架构: %s", node.Architecture)
+	// 按节点标签固定的版本或 INIT_VERSION 选择安装包
+	release, asset, err := resolveSmartDNSPackage(ctx, node, "")
+	if err != nil {
+		s.updateInitLog(initLog, "failed", "", err.Error())
+		return err
 	}
 
 	// 创建临时目录
@@ -291,16 +282,15 @@ func (s *InitService) downloadSmartDNS(node *models.Node) error {
 	client.ExecuteCommand(fmt.Sprintf("mkdir -p %s", tmpDir))
 
 	// 下载文件
-	fileName := fmt.Sprintf("smartdns.%s.%s-linux-all.tar.gz", release.Version, node.Architecture)
+	fileName := asset.Name
 	//downloadPath := fmt.Sprintf("%s/%s", tmpDir, fileName)
 
-	log.Printf("下载地址: %s", release.DownloadURL)
+	slog.InfoContext(ctx, "SmartDNS 下载地址", "node", node.Name, "url", asset.DownloadURL)
 
 	// 使用 wget 下载，添加重试和超时
-	downloadCmd := fmt.Sprintf("cd %s && (wget --tries=3 --timeout=30 -q '%s' -O %s 2>&1 || curl -sSL --retry 3 --max-time 30 -o %s '%s' 2>&1)",
-		tmpDir, release.DownloadURL, fileName, fileName, release.DownloadURL)
+	downloadCmd := smartDNSDownloadCommand(tmpDir, asset)
 
-	output, err := client.ExecuteCommand(downloadCmd)
+	output, err := s.executeStreamed(client, node.ID, "download", downloadCmd)
 	if err != nil {
 		s.updateInitLog(initLog, "failed", output, "下载失败: "+err.Error())
 		return fmt.Errorf("下载失败: %w", err)
@@ -318,13 +308,13 @@ func (s *InitService) downloadSmartDNS(node *models.Node) error {
 
 	s.updateInitLog(initLog, "success", fmt.Sprintf("版本: %s", release.Version), "")
 
-	log.Printf(" SmartDNS 下载完成: %s", release.Version)
+	slog.InfoContext(ctx, "SmartDNS 下载完成", "node", node.Name, "version", release.Version)
 	return nil
 }
 
 // installSmartDNS 安装 SmartDNS
-func (s *InitService) installSmartDNS(node *models.Node) error {
-	log.Printf("📦 步骤3: 安装 SmartDNS...")
+func (s *InitService) installSmartDNS(ctx context.Context, node *models.Node) error {
+	slog.InfoContext(ctx, "安装 SmartDNS", "node", node.Name, "step", 3)
 
 	initLog := s.createInitLog(node.ID, "install", "running", "安装 SmartDNS")
 
@@ -335,11 +325,27 @@ func (s *InitService) installSmartDNS(node *models.Node) error {
 	}
 	defer client.Close()
 
+	if smartDNSInstalledVia(node) != models.InstallModeTarball {
+		output, err := s.installSmartDNSPackage(client, node)
+		if err != nil {
+			s.updateInitLog(initLog, "failed", output, err.Error())
+			return fmt.Errorf("安装失败: %w", err)
+		}
+		// 软件源中的版本由发行版决定，按实际安装的版本记录
+		if _, version := s.checkSmartDNSInstalled(ctx, node); version != "" {
+			node.SmartDNSVersion = version
+			database.DB.Save(node)
+		}
+		s.updateInitLog(initLog, "success", output, "")
+		slog.InfoContext(ctx, "SmartDNS 安装完成", "node", node.Name, "installed_via", node.InstalledVia)
+		return nil
+	}
+
 	tmpDir := "/tmp/smartdns-install"
 
 	// 进入解压目录并执行安装
 	installCmd := fmt.Sprintf("cd %s/smartdns && chmod +x ./install && sudo ./install -i", tmpDir)
-	output, err := client.ExecuteCommand(installCmd)
+	output, err := s.executeStreamed(client, node.ID, "install", installCmd)
 
 	if err != nil {
 		s.updateInitLog(initLog, "failed", output, err.Error())
@@ -348,13 +354,13 @@ func (s *InitService) installSmartDNS(node *models.Node) error {
 
 	s.updateInitLog(initLog, "success", output, "")
 
-	log.Printf(" SmartDNS 安装完成")
+	slog.InfoContext(ctx, "SmartDNS 安装完成", "node", node.Name)
 	return nil
 }
 
 // initConfig 初始化配置
-func (s *InitService) initConfig(node *models.Node) error {
-	log.Printf("⚙️  步骤4: 初始化配置...")
+func (s *InitService) initConfig(ctx context.Context, node *models.Node) error {
+	slog.InfoContext(ctx, "初始化配置", "node", node.Name, "step", 4)
 
 	initLog := s.createInitLog(node.ID, "configure", "running", "初始化配置文件")
 
@@ -408,6 +414,14 @@ server 114.114.114.114
 		configPath = "/etc/smartdns/smartdns.conf"
 		node.ConfigPath = configPath
 	}
+	if smartDNSInstalledVia(node) == models.InstallModeOpkg {
+		// OpenWrt 的主配置由 UCI 生成，管理 custom.conf
+		if configPath == "/etc/smartdns/smartdns.conf" {
+			configPath = "/etc/smartdns/custom.conf"
+			node.ConfigPath = configPath
+		}
+		defaultConfig = opkgSmartDNSConfig(defaultConfig)
+	}
 
 	// 备份原配置（如果存在）
 	backupCmd := fmt.Sprintf("sudo cp %s %s.bak.$(date +%%s) 2>/dev/null || true", configPath, configPath)
@@ -423,13 +437,13 @@ server 114.114.114.114
 
 	s.updateInitLog(initLog, "success", "配置文件: "+configPath, "")
 
-	log.Printf(" 配置初始化完成")
+	slog.InfoContext(ctx, "配置初始化完成", "node", node.Name)
 	return nil
 }
 
 // startService 启动服务
-func (s *InitService) startService(node *models.Node) error {
-	log.Printf("🚀 步骤5: 启动 SmartDNS 服务...")
+func (s *InitService) startService(ctx context.Context, node *models.Node) error {
+	slog.InfoContext(ctx, "启动 SmartDNS 服务", "node", node.Name, "step", 5)
 
 	initLog := s.createInitLog(node.ID, "start", "running", "启动 SmartDNS 服务")
 
@@ -440,16 +454,17 @@ func (s *InitService) startService(node *models.Node) error {
 	}
 	defer client.Close()
 
-	// 重新加载 systemd
-	client.ExecuteCommand("sudo systemctl daemon-reload")
-
-	// 启用开机自启
-	if _, err := client.ExecuteCommand("sudo systemctl enable smartdns"); err != nil {
-		log.Printf("⚠️  启用开机自启失败: %v", err)
+	if output, err := s.startSmartDNSPackage(client, node); err != nil {
+		s.updateInitLog(initLog, "failed", output, "启动服务失败: "+err.Error())
+		return fmt.Errorf("启动服务失败: %w", err)
 	}
 
-	// 启动服务
-	if err := client.RestartService("smartdns"); err != nil {
+	// 按节点的 init 系统启用开机自启并启动
+	manager := client.ServiceManager("smartdns")
+	if err := manager.Enable("smartdns"); err != nil {
+		slog.WarnContext(ctx, "启用开机自启失败", "node", node.Name, "error", err)
+	}
+	if err := manager.Restart("smartdns"); err != nil {
 		s.updateInitLog(initLog, "failed", "", "启动服务失败: "+err.Error())
 		return fmt.Errorf("启动服务失败: %w", err)
 	}
@@ -464,39 +479,56 @@ func (s *InitService) startService(node *models.Node) error {
 		return fmt.Errorf("服务未正常运行")
 	}
 
+	// docker 方式的版本由镜像决定，容器启动后才能获取
+	if smartDNSInstalledVia(node) == models.InstallModeDocker {
+		if _, version := s.checkSmartDNSInstalled(ctx, node); version != "" {
+			node.SmartDNSVersion = version
+			database.DB.Save(node)
+		}
+	}
+
 	s.updateInitLog(initLog, "success", "SmartDNS 服务已启动", "")
 
-	log.Printf(" SmartDNS 服务启动成功")
+	slog.InfoContext(ctx, "SmartDNS 服务启动成功", "node", node.Name)
 	return nil
 }
 
 // installDependency 安装依赖
-func (s *InitService) installDependency(client *SSHClient, osType, packageName string) error {
+func (s *InitService) installDependency(client *SSHClient, node *models.Node, packageName string) error {
 	var installCmd string
 
-	switch osType {
+	switch osType := node.OSType; osType {
 	case "ubuntu", "debian":
 		installCmd = fmt.Sprintf("sudo apt-get update -qq && sudo apt-get install -y %s", packageName)
 	case "centos":
 		installCmd = fmt.Sprintf("sudo yum install -y %s", packageName)
 	case "alpine":
 		installCmd = fmt.Sprintf("sudo apk add --no-cache %s", packageName)
+	case "openwrt":
+		installCmd = fmt.Sprintf("opkg update && opkg install %s", packageName)
 	default:
 		return fmt.Errorf("不支持的操作系统: %s", osType)
 	}
 
-	_, err := client.ExecuteCommand(installCmd)
+	_, err := s.executeStreamed(client, node.ID, "detect", installCmd)
 	return err
 }
 
+// executeStreamed 执行命令，同时把输出按行推送给正在查看初始化进度的页面
+func (s *InitService) executeStreamed(client *SSHClient, nodeID uint, step, cmd string) (string, error) {
+	writer := &initOutputWriter{nodeID: nodeID, step: step}
+	defer writer.Flush()
+	return client.ExecuteCommandStream(cmd, writer)
+}
+
 // UninstallSmartDNS 卸载 SmartDNS
-func (s *InitService) UninstallSmartDNS(nodeID uint) error {
+func (s *InitService) UninstallSmartDNS(ctx context.Context, nodeID uint) error {
 	var node models.Node
 	if err := database.DB.First(&node, nodeID).Error; err != nil {
 		return fmt.Errorf("节点不存在: %w", err)
 	}
 
-	log.Printf("🗑️  开始卸载 SmartDNS: %s", node.Name)
+	slog.InfoContext(ctx, "开始卸载 SmartDNS", "node", node.Name)
 
 	client, err := NewSSHClient(&node)
 	if err != nil {
@@ -505,18 +537,27 @@ func (s *InitService) UninstallSmartDNS(nodeID uint) error {
 	defer client.Close()
 
 	// 停止服务
-	log.Printf("停止 SmartDNS 服务...")
-	client.ExecuteCommand("sudo systemctl stop smartdns")
-	client.ExecuteCommand("sudo systemctl disable smartdns")
+	slog.InfoContext(ctx, "停止 SmartDNS 服务", "node", node.Name)
+	manager := client.ServiceManager("smartdns")
+	manager.Stop("smartdns")
+	manager.Disable("smartdns")
+
+	if commands := smartDNSUninstallCommands(&node); commands != nil {
+		slog.InfoContext(ctx, "按安装方式卸载", "node", node.Name, "installed_via", node.InstalledVia)
+		for _, cmd := range commands {
+			client.ExecuteCommand(cmd)
+		}
+		return s.finishUninstall(ctx, &node)
+	}
 
 	// 执行卸载脚本
-	log.Printf("执行卸载...")
+	slog.InfoContext(ctx, "执行卸载", "node", node.Name)
 	tmpDir := "/tmp/smartdns-install"
 	uninstallCmd := fmt.Sprintf("cd %s/smartdns 2>/dev/null && sudo ./install -u || true", tmpDir)
 	client.ExecuteCommand(uninstallCmd)
 
 	// 手动清理
-	log.Printf("清理文件...")
+	slog.InfoContext(ctx, "清理文件", "node", node.Name)
 	client.ExecuteCommand("sudo rm -f /usr/sbin/smartdns")
 	client.ExecuteCommand("sudo rm -f /etc/systemd/system/smartdns.service")
 	client.ExecuteCommand("sudo rm -rf /etc/smartdns")
@@ -524,14 +565,22 @@ func (s *InitService) UninstallSmartDNS(nodeID uint) error {
 	client.ExecuteCommand(fmt.Sprintf("sudo rm -rf %s", tmpDir))
 
 	// 重新加载 systemd
-	client.ExecuteCommand("sudo systemctl daemon-reload")
+	if manager.Name() == models.ServiceManagerSystemd {
+		client.ExecuteCommand("sudo systemctl daemon-reload")
+	}
+
+	return s.finishUninstall(ctx, &node)
+}
 
+// finishUninstall 卸载后更新节点状态并发送通知
+func (s *InitService) finishUninstall(ctx context.Context, node *models.Node) error {
 	// 更新节点状态
 	node.InitStatus = "not_installed"
 	node.SmartDNSVersion = ""
-	database.DB.Save(&node)
+	node.InstalledVia = ""
+	database.DB.Save(node)
 
-	log.Printf(" SmartDNS 卸载完成")
+	slog.InfoContext(ctx, "SmartDNS 卸载完成", "node", node.Name)
 
 	// 发送通知
 	s.notificationService.SendNotification(
@@ -545,7 +594,7 @@ func (s *InitService) UninstallSmartDNS(nodeID uint) error {
 }
 
 // CheckAndUpdateNodeStatus 检查并更新节点状态
-func (s *InitService) CheckAndUpdateNodeStatus(node *models.Node) error {
+func (s *InitService) CheckAndUpdateNodeStatus(ctx context.Context, node *models.Node) error {
 	client, err := NewSSHClient(node)
 	if err != nil {
 		node.InitStatus = "unknown"
@@ -555,14 +604,14 @@ func (s *InitService) CheckAndUpdateNodeStatus(node *models.Node) error {
 	defer client.Close()
 
 	// 检查是否安装
-	installed, version := s.checkSmartDNSInstalled(node)
+	installed, version := s.checkSmartDNSInstalled(ctx, node)
 	if installed {
 		node.InitStatus = "installed"
 		node.SmartDNSVersion = version
 
 		// 检测系统信息（如果未检测过）
 		if node.OSType == "" {
-			s.detectSystem(node)
+			s.detectSystem(ctx, node)
 		}
 	} else {
 		node.InitStatus = "not_installed"
@@ -574,11 +623,12 @@ func (s *InitService) CheckAndUpdateNodeStatus(node *models.Node) error {
 }
 
 // handleInitError 处理初始化错误
-func (s *InitService) handleInitError(node *models.Node, step string, err error) error {
-	log.Printf("❌ 初始化失败 (%s): %v", step, err)
+func (s *InitService) handleInitError(ctx context.Context, node *models.Node, step string, err error) error {
+	slog.ErrorContext(ctx, "初始化失败", "node", node.Name, "step", step, "error", err)
 
 	node.InitStatus = "failed"
 	database.DB.Save(node)
+	publishInitDone(node.ID, node.InitStatus, fmt.Sprintf("%s: %v", step, err))
 
 	// 发送失败通知
 	s.notificationService.SendNotification(
@@ -601,6 +651,7 @@ func (s *InitService) createInitLog(nodeID uint, step, status, message string) *
 		StartedAt: time.Now(),
 	}
 	database.DB.Create(initLog)
+	s.publishInitStep(initLog)
 	return initLog
 }
 
@@ -611,4 +662,16 @@ func (s *InitService) updateInitLog(initLog *models.InitLog, status, detail, err
 	initLog.Error = errorMsg
 	initLog.EndedAt = time.Now()
 	database.DB.Save(initLog)
+	s.publishInitStep(initLog)
+}
+
+// publishInitStep 推送步骤状态，事件中的日志是副本，之后的修改不影响已推送的事件
+func (s *InitService) publishInitStep(initLog *models.InitLog) {
+	snapshot := *initLog
+	GetInitProgressBroker().Publish(initLog.NodeID, models.InitProgressEvent{
+		Type:   models.InitEventStep,
+		Step:   initLog.Step,
+		Status: initLog.Status,
+		Log:    &snapshot,
+	})
 }
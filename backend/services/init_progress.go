@@ -0,0 +1,172 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"smartdns-manager/models"
+)
+
+const (
+	// initProgressHistory 每个节点保留的最近事件数，后连接的订阅者先收到这些事件
+	initProgressHistory = 1000
+	// initProgressBuffer 订阅者的事件缓冲，浏览器读取过慢时丢弃超出的命令输出
+	initProgressBuffer = 256
+)
+
+// InitProgressBroker 按节点分发初始化进度事件，只保存在内存中，
+// 服务重启后由 /api/nodes/:id/init/logs 的步骤日志代替
+type InitProgressBroker struct {
+	mu   sync.Mutex
+	runs map[uint]*initProgressRun
+}
+
+// initProgressRun 一次初始化的事件和订阅者
+type initProgressRun struct {
+	events      []models.InitProgressEvent
+	subscribers map[chan models.InitProgressEvent]struct{}
+	seq         int64
+	done        bool
+}
+
+var (
+	defaultInitProgressBroker *InitProgressBroker
+	initProgressBrokerOnce    sync.Once
+)
+
+// GetInitProgressBroker 获取初始化进度分发器
+func GetInitProgressBroker() *InitProgressBroker {
+	initProgressBrokerOnce.Do(func() {
+		defaultInitProgressBroker = &InitProgressBroker{runs: make(map[uint]*initProgressRun)}
+	})
+	return defaultInitProgressBroker
+}
+
+// Begin 开始新一次初始化，清空上一次的事件；上一次还未结束时保留已有事件，
+// 因此提交任务时和任务开始执行时都可以调用
+func (b *InitProgressBroker) Begin(nodeID uint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	run, ok := b.runs[nodeID]
+	if !ok {
+		b.runs[nodeID] = &initProgressRun{subscribers: make(map[chan models.InitProgressEvent]struct{})}
+		return
+	}
+	if !run.done {
+		return
+	}
+	run.events = nil
+	run.seq = 0
+	run.done = false
+}
+
+// Publish 记录事件并发送给订阅者，done 事件发送后关闭所有订阅
+func (b *InitProgressBroker) Publish(nodeID uint, event models.InitProgressEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	run, ok := b.runs[nodeID]
+	if !ok {
+		run = &initProgressRun{subscribers: make(map[chan models.InitProgressEvent]struct{})}
+		b.runs[nodeID] = run
+	}
+
+	run.seq++
+	event.Seq = run.seq
+	event.Time = time.Now()
+	run.events = append(run.events, event)
+	if len(run.events) > initProgressHistory {
+		run.events = run.events[len(run.events)-initProgressHistory:]
+	}
+
+	for ch := range run.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// 步骤和结束事件不能丢，缓冲已满时断开该订阅，浏览器重连后从历史事件补齐
+			if event.Type != models.InitEventOutput {
+				close(ch)
+				delete(run.subscribers, ch)
+			}
+		}
+	}
+
+	if event.Type == models.InitEventDone {
+		run.done = true
+		for ch := range run.subscribers {
+			close(ch)
+			delete(run.subscribers, ch)
+		}
+	}
+}
+
+// Subscribe 返回当前的事件历史和后续事件的通道，初始化已结束时通道为 nil；
+// 本次服务启动后节点没有执行过初始化时 history 为空
+func (b *InitProgressBroker) Subscribe(nodeID uint) (history []models.InitProgressEvent, events <-chan models.InitProgressEvent, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	run, ok := b.runs[nodeID]
+	if !ok {
+		return nil, nil, func() {}
+	}
+
+	history = append([]models.InitProgressEvent(nil), run.events...)
+	if run.done {
+		return history, nil, func() {}
+	}
+
+	ch := make(chan models.InitProgressEvent, initProgressBuffer)
+	run.subscribers[ch] = struct{}{}
+	return history, ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := run.subscribers[ch]; ok {
+			delete(run.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publishInitDone 推送初始化结束事件，status 为节点的 init_status
+func publishInitDone(nodeID uint, status, errMsg string) {
+	GetInitProgressBroker().Publish(nodeID, models.InitProgressEvent{
+		Type:   models.InitEventDone,
+		Status: status,
+		Error:  errMsg,
+	})
+}
+
+// initOutputWriter 把命令输出按行发布为进度事件
+type initOutputWriter struct {
+	nodeID  uint
+	step    string
+	partial []byte
+}
+
+func (w *initOutputWriter) Write(p []byte) (int, error) {
+	data := append(w.partial, p...)
+	start := 0
+	for i, b := range data {
+		if b == '\n' {
+			w.publish(string(data[start:i]))
+			start = i + 1
+		}
+	}
+	w.partial = append([]byte(nil), data[start:]...)
+	return len(p), nil
+}
+
+// Flush 发布最后一行没有换行符的输出
+func (w *initOutputWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.publish(string(w.partial))
+		w.partial = nil
+	}
+}
+
+func (w *initOutputWriter) publish(line string) {
+	GetInitProgressBroker().Publish(w.nodeID, models.InitProgressEvent{
+		Type:   models.InitEventOutput,
+		Step:   w.step,
+		Output: line,
+	})
+}
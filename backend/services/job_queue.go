@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// jobPollInterval 没有收到提交通知时 worker 检查排队任务的间隔
+const jobPollInterval = 10 * time.Second
+
+// JobWorkers 后台任务执行器：固定数量的 worker 按提交顺序从数据库领取排队的任务，
+// 排队中的任务随数据库持久化，服务重启后继续排队
+type JobWorkers struct {
+	workers int
+	wake    chan struct{}
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+var (
+	jobWorkers     *JobWorkers
+	jobWorkersOnce sync.Once
+
+	// runningJobs 正在执行的任务的取消函数
+	runningJobs   = map[uint]context.CancelFunc{}
+	runningJobsMu sync.Mutex
+)
+
+// GetJobWorkers 按 BACKGROUND_JOB_WORKERS 创建执行器，须在 RecoverBackgroundJobs 之后调用 Start
+func GetJobWorkers() *JobWorkers {
+	jobWorkersOnce.Do(func() {
+		workers, err := strconv.Atoi(config.GetConfig().BackgroundJobWorkers)
+		if err != nil || workers <= 0 {
+			workers = 4
+		}
+		jobWorkers = &JobWorkers{
+			workers: workers,
+			wake:    make(chan struct{}, 1),
+			stop:    make(chan struct{}),
+		}
+	})
+	return jobWorkers
+}
+
+// Start 启动 worker
+func (w *JobWorkers) Start() {
+	for i := 0; i < w.workers; i++ {
+		w.wg.Add(1)
+		go w.loop()
+	}
+	log.Printf("✅ 后台任务执行器已启动，worker 数量: %d", w.workers)
+}
+
+// Stop 不再领取新任务，等待执行中的任务结束；超时退出时执行中的任务在下次启动时按检查点恢复
+func (w *JobWorkers) Stop() {
+	close(w.stop)
+	w.wg.Wait()
+	log.Printf("后台任务执行器已停止")
+}
+
+// notify 通知空闲的 worker 领取新提交的任务
+func (w *JobWorkers) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *JobWorkers) loop() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		if job := claimQueuedJob(); job != nil {
+			// 可能还有排队的任务，唤醒其他空闲的 worker
+			w.notify()
+			runJob(job)
+			continue
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-w.wake:
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// notifyJobWorkers 执行器未创建（如命令行工具）时任务保持排队，由服务启动后执行
+func notifyJobWorkers() {
+	if jobWorkers != nil {
+		jobWorkers.notify()
+	}
+}
+
+// claimQueuedJob 领取最早排队的任务，多个 worker 同时领取时只有一个能把状态改为 running
+func claimQueuedJob() *models.BackgroundJob {
+	for attempt := 0; attempt < 3; attempt++ {
+		var job models.BackgroundJob
+		if err := database.DB.Where("status = ?", models.BackgroundJobStatusQueued).
+			Order("id").First(&job).Error; err != nil {
+			return nil
+		}
+
+		now := time.Now()
+		if job.StartedAt == nil {
+			job.StartedAt = &now
+		}
+		result := database.DB.Model(&models.BackgroundJob{}).
+			Where("id = ? AND status = ?", job.ID, models.BackgroundJobStatusQueued).
+			Updates(map[string]interface{}{
+				"status":     models.BackgroundJobStatusRunning,
+				"started_at": job.StartedAt,
+			})
+		if result.Error != nil {
+			log.Printf("❌ 领取后台任务 #%d 失败: %v", job.ID, result.Error)
+			return nil
+		}
+		if result.RowsAffected == 1 {
+			job.Status = models.BackgroundJobStatusRunning
+			return &job
+		}
+	}
+	return nil
+}
+
+func registerRunningJob(id uint, cancel context.CancelFunc) {
+	runningJobsMu.Lock()
+	defer runningJobsMu.Unlock()
+	runningJobs[id] = cancel
+}
+
+func unregisterRunningJob(id uint) {
+	runningJobsMu.Lock()
+	defer runningJobsMu.Unlock()
+	delete(runningJobs, id)
+}
+
+// CancelJob 取消任务：排队中的任务直接取消；执行中的任务须支持取消，在当前步骤完成后停止
+func CancelJob(id uint) (*models.BackgroundJob, error) {
+	var job models.BackgroundJob
+	if err := database.DB.First(&job, id).Error; err != nil {
+		return nil, fmt.Errorf("后台任务不存在")
+	}
+	handler, _ := getJobHandler(job.Type)
+
+	switch job.Status {
+	case models.BackgroundJobStatusQueued:
+		now := time.Now()
+		result := database.DB.Model(&job).
+			Where("status = ?", models.BackgroundJobStatusQueued).
+			Updates(map[string]interface{}{
+				"status":      models.BackgroundJobStatusCancelled,
+				"error":       "任务在排队时被取消",
+				"finished_at": &now,
+			})
+		if result.Error != nil {
+			return nil, fmt.Errorf("取消任务失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			// 刚被 worker 领取，按执行中的任务处理
+			return CancelJob(id)
+		}
+		if handler.OnCancelled != nil {
+			handler.OnCancelled(&job)
+		}
+		jobLogger(&job).Info("后台任务已取消（排队中）")
+		return &job, nil
+
+	case models.BackgroundJobStatusRunning:
+		if !handler.Cancellable {
+			return nil, fmt.Errorf("该任务正在执行且不能中途取消，请等待执行结束")
+		}
+		runningJobsMu.Lock()
+		cancel, ok := runningJobs[job.ID]
+		runningJobsMu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("任务不在本实例中执行，无法取消")
+		}
+		cancel()
+		jobLogger(&job).Info("已请求取消后台任务")
+		return &job, nil
+
+	default:
+		return nil, fmt.Errorf("任务当前状态为 %s，只能取消排队中或执行中的任务", job.Status)
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"smartdns-manager/models"
+)
+
+// kernelIssueRule 已知严重内核漏洞，Fixed 为各稳定分支的首个修复版本
+type kernelIssueRule struct {
+	CVE        string
+	Name       string
+	Severity   string
+	Introduced string
+	Fixed      []string
+}
+
+// knownKernelIssues 可被本地提权的严重内核漏洞，DNS 节点多直接暴露在公网，需要优先处理
+var knownKernelIssues = []kernelIssueRule{
+	{
+		CVE:        "CVE-2016-5195",
+		Name:       "Dirty COW",
+		Severity:   "critical",
+		Introduced: "2.6.22",
+		Fixed:      []string{"3.2.83", "3.10.104", "3.12.66", "3.16.38", "3.18.44", "4.1.35", "4.4.26", "4.7.9", "4.8.3"},
+	},
+	{
+		CVE:        "CVE-2022-0847",
+		Name:       "Dirty Pipe",
+		Severity:   "critical",
+		Introduced: "5.8",
+		Fixed:      []string{"5.10.102", "5.15.25", "5.16.11"},
+	},
+	{
+		CVE:        "CVE-2024-1086",
+		Name:       "nf_tables use-after-free",
+		Severity:   "critical",
+		Introduced: "3.15",
+		Fixed:      []string{"4.19.307", "5.4.269", "5.10.209", "5.15.149", "6.1.76", "6.6.15", "6.7.3", "6.8"},
+	},
+}
+
+var kernelReleasePattern = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?(.*)$`)
+
+// CheckKernelIssues 根据内核版本号匹配已知严重漏洞
+//
+// 发行版内核（如 Ubuntu 的 5.15.0-91-generic、RHEL 的 4.18.0-477.el8）会回移补丁而不改变上游版本号，
+// 无法通过版本判断，这类内核只依赖包管理器报告的内核安全更新
+func CheckKernelIssues(release string) []models.KernelIssue {
+	version, distro := parseKernelRelease(release)
+	if version == nil || distro {
+		return nil
+	}
+
+	var issues []models.KernelIssue
+	for _, rule := range knownKernelIssues {
+		if compareKernelVersion(version, parseKernelVersion(rule.Introduced)) < 0 {
+			continue
+		}
+		if fixed, ok := kernelFixedIn(version, rule.Fixed); ok {
+			issues = append(issues, models.KernelIssue{
+				CVE:      rule.CVE,
+				Name:     rule.Name,
+				Severity: rule.Severity,
+				FixedIn:  fixed,
+			})
+		}
+	}
+	return issues
+}
+
+// kernelFixedIn 判断版本是否仍受影响，返回应升级到的版本
+// 同一分支有修复版本时以该分支为准，否则需要升级到比当前版本更新的首个修复版本
+func kernelFixedIn(version []int, fixed []string) (string, bool) {
+	for _, f := range fixed {
+		fv := parseKernelVersion(f)
+		if fv[0] == version[0] && fv[1] == version[1] {
+			return f, compareKernelVersion(version, fv) < 0
+		}
+	}
+	for _, f := range fixed {
+		if compareKernelVersion(version, parseKernelVersion(f)) < 0 {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// parseKernelRelease 解析 uname -r 输出，第二个返回值表示是否为打补丁不改版本号的发行版内核
+func parseKernelRelease(release string) ([]int, bool) {
+	m := kernelReleasePattern.FindStringSubmatch(strings.TrimSpace(release))
+	if m == nil {
+		return nil, false
+	}
+	version := parseKernelVersion(m[1] + "." + m[2] + "." + m[3])
+	suffix := m[4]
+	distro := version[2] == 0 && suffix != "" && !strings.HasPrefix(suffix, "-rc")
+	return version, distro
+}
+
+// parseKernelVersion 解析 major.minor[.patch]，缺失部分按0处理
+func parseKernelVersion(v string) []int {
+	parts := strings.SplitN(v, ".", 3)
+	version := make([]int, 3)
+	for i, p := range parts {
+		version[i], _ = strconv.Atoi(p)
+	}
+	return version
+}
+
+func compareKernelVersion(a, b []int) int {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+var errLDAPInvalidCredentials = errors.New("用户名或密码错误")
+
+// dialLDAP 连接 ldap:// 或 ldaps:// 地址，协议编解码由 go-ldap 完成
+func dialLDAP(rawURL string, insecureSkipVerify bool) (*ldap.Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP 地址无效: %w", err)
+	}
+	if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return nil, fmt.Errorf("不支持的 LDAP 协议: %s", u.Scheme)
+	}
+
+	conn, err := ldap.DialURL(rawURL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}),
+		ldap.DialWithTLSConfig(&tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: insecureSkipVerify,
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("连接 LDAP 服务器失败: %w", err)
+	}
+	conn.SetTimeout(30 * time.Second)
+	return conn, nil
+}
+
+// ldapBind 简单绑定，password 为空时服务端会视为匿名绑定，因此调用方需提前拒绝空密码
+func ldapBind(conn *ldap.Conn, dn, password string) error {
+	err := conn.Bind(dn, password)
+	switch {
+	case err == nil:
+		return nil
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials):
+		return errLDAPInvalidCredentials
+	default:
+		return fmt.Errorf("LDAP 绑定失败: %w", err)
+	}
+}
+
+// ldapSearchEqual 在 baseDN 子树中按 attribute=value 精确查找，最多返回 2 条用于判断是否唯一
+func ldapSearchEqual(conn *ldap.Conn, baseDN, attribute, value string, attributes []string) ([]*ldap.Entry, error) {
+	request := ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 10, false,
+		fmt.Sprintf("(%s=%s)", ldap.EscapeFilter(attribute), ldap.EscapeFilter(value)), attributes, nil)
+	result, err := conn.Search(request)
+	// sizeLimitExceeded 说明匹配到多个条目，交给调用方按数量判断
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("LDAP 查找失败: %w", err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+	return result.Entries, nil
+}
+
+// LDAPEnabled 是否配置了 LDAP 登录
+func LDAPEnabled() bool {
+	cfg := config.GetConfig()
+	return cfg.LDAPURL != "" && cfg.LDAPBaseDN != ""
+}
+
+// LDAPAuthenticate 先用服务账号查找用户 DN，再以用户身份绑定验证密码
+func LDAPAuthenticate(username, password string) (*models.SSOIdentity, error) {
+	if username == "" || password == "" {
+		return nil, errLDAPInvalidCredentials
+	}
+
+	cfg := config.GetConfig()
+	insecure, _ := strconv.ParseBool(cfg.LDAPInsecureSkipVerify)
+	conn, err := dialLDAP(cfg.LDAPURL, insecure)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if cfg.LDAPBindDN != "" {
+		if err := ldapBind(conn, cfg.LDAPBindDN, cfg.LDAPBindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP 服务账号绑定失败: %w", err)
+		}
+	}
+
+	entries, err := ldapSearchEqual(conn, cfg.LDAPBaseDN, cfg.LDAPUserAttribute, username,
+		[]string{cfg.LDAPUserAttribute, "mail", cfg.LDAPGroupAttribute})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 {
+		// 找不到或不唯一都按认证失败处理，不暴露用户是否存在
+		return nil, errLDAPInvalidCredentials
+	}
+	entry := entries[0]
+
+	if err := ldapBind(conn, entry.DN, password); err != nil {
+		return nil, err
+	}
+
+	identity := &models.SSOIdentity{
+		Source:   models.AuthSourceLDAP,
+		Subject:  entry.DN,
+		Username: username,
+		Groups:   entry.GetEqualFoldAttributeValues(cfg.LDAPGroupAttribute),
+	}
+	if values := entry.GetEqualFoldAttributeValues(cfg.LDAPUserAttribute); len(values) > 0 {
+		identity.Username = values[0]
+	}
+	if values := entry.GetEqualFoldAttributeValues("mail"); len(values) > 0 {
+		identity.Email = values[0]
+	}
+	return identity, nil
+}
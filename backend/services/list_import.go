@@ -0,0 +1,544 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// 导入格式
+const (
+	ImportFormatAuto     = "auto"     // 按行自动识别
+	ImportFormatSmartDNS = "smartdns" // address /example.com/1.2.3.4
+	ImportFormatHosts    = "hosts"    // 1.2.3.4 example.com www.example.com
+	ImportFormatDnsmasq  = "dnsmasq"  // address=/example.com/1.2.3.4
+	ImportFormatAdGuard  = "adguard"  // ||example.com^，@@||example.com^
+	ImportFormatPlain    = "plain"    // example.com 1.2.3.4，或只有域名的屏蔽列表
+)
+
+// 导入条目与现有数据的比较结果
+const (
+	ImportStatusNew       = "new"       // 新增
+	ImportStatusDuplicate = "duplicate" // 与现有记录相同，提交时跳过
+	ImportStatusConflict  = "conflict"  // 域名已存在但值不同
+)
+
+// 冲突处理方式
+const (
+	ImportConflictSkip      = "skip"      // 保留现有记录
+	ImportConflictOverwrite = "overwrite" // 使用导入的值
+	ImportConflictMerge     = "merge"     // 合并 IP 列表
+)
+
+// ImportEntry 解析出的地址映射条目
+type ImportEntry struct {
+	Line     int    `json:"line"`
+	Format   string `json:"format"`
+	Domain   string `json:"domain"`
+	Type     string `json:"type"` // address / cname
+	IP       string `json:"ip,omitempty"`
+	CNAME    string `json:"cname,omitempty"`
+	Status   string `json:"status"`
+	Existing string `json:"existing,omitempty"` // 冲突时现有记录的值
+}
+
+// ImportIssue 无法导入的行
+type ImportIssue struct {
+	Line    int    `json:"line"`
+	Content string `json:"content"`
+	Reason  string `json:"reason"`
+}
+
+// ImportPreview 导入预览
+type ImportPreview struct {
+	Entries   []ImportEntry  `json:"entries"`
+	Issues    []ImportIssue  `json:"issues"`
+	Formats   map[string]int `json:"formats"` // 各格式识别出的条目数
+	New       int            `json:"new"`
+	Duplicate int            `json:"duplicate"`
+	Conflict  int            `json:"conflict"`
+	Blocked   int            `json:"blocked"` // 屏蔽类条目（#）
+}
+
+// ImportOptions 提交导入的选项
+type ImportOptions struct {
+	NodeIDs        string // JSON 数组，应用到的节点
+	ConflictMode   string // skip / overwrite / merge，默认 skip
+	BlockDomainSet string // 非空时屏蔽条目写入该域名集，通过一条 domain-rules -address # 生效
+	OrganizationID uint   // 新建记录所属组织，大于 0 时不会修改其他组织的记录
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Created    []models.AddressMap `json:"-"`
+	Updated    []models.AddressMap `json:"-"`
+	Skipped    int                 `json:"skipped"`
+	Blocked    int                 `json:"blocked"` // 写入域名集的屏蔽域名数
+	DomainSet  *models.DomainSet   `json:"domain_set,omitempty"`
+	DomainRule *models.DomainRule  `json:"domain_rule,omitempty"`
+	NewRule    bool                `json:"-"`
+}
+
+// ListImportService 解析 hosts、dnsmasq、AdGuard 等常见列表格式并导入为地址映射
+type ListImportService struct {
+	domainSetService  *DomainSetService
+	domainRuleService *DomainRuleService
+}
+
+// NewListImportService 创建列表导入服务
+func NewListImportService() *ListImportService {
+	return &ListImportService{
+		domainSetService:  NewDomainSetService(),
+		domainRuleService: NewDomainRuleService(),
+	}
+}
+
+var (
+	smartdnsLinePattern = regexp.MustCompile(`^(address|cname)\s+/(.*?)/(.*)$`)
+	adguardRulePattern  = regexp.MustCompile(`^(@@)?\|\|([^\^$|/]+)\^\|?(\$important)?$`)
+)
+
+// hostsIgnoredNames hosts 文件中与本机相关、不应导入的名称
+var hostsIgnoredNames = map[string]bool{
+	"localhost": true, "localhost.localdomain": true, "local": true, "broadcasthost": true,
+	"ip6-localhost": true, "ip6-loopback": true, "ip6-localnet": true, "ip6-mcastprefix": true,
+	"ip6-allnodes": true, "ip6-allrouters": true, "ip6-allhosts": true, "0.0.0.0": true,
+}
+
+// Preview 解析内容并与现有地址映射比较，不修改任何数据
+func (s *ListImportService) Preview(content, format string) (*ImportPreview, error) {
+	entries, issues := s.Parse(content, format)
+	if entries == nil {
+		entries = []ImportEntry{}
+	}
+	if issues == nil {
+		issues = []ImportIssue{}
+	}
+
+	var existing []models.AddressMap
+	if err := database.DB.Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("查询地址映射失败: %w", err)
+	}
+	byDomain := make(map[string]models.AddressMap, len(existing))
+	for _, addr := range existing {
+		if _, ok := byDomain[addr.Domain]; !ok {
+			byDomain[addr.Domain] = addr
+		}
+	}
+
+	preview := &ImportPreview{Entries: entries, Issues: issues, Formats: make(map[string]int)}
+	for i := range preview.Entries {
+		entry := &preview.Entries[i]
+		entry.Status = ImportStatusNew
+		if current, ok := byDomain[entry.Domain]; ok {
+			if current.Type == entry.Type && current.IP == entry.IP && current.CNAME == entry.CNAME {
+				entry.Status = ImportStatusDuplicate
+			} else {
+				entry.Status = ImportStatusConflict
+				entry.Existing = current.IP
+				if current.Type == "cname" {
+					entry.Existing = "CNAME " + current.CNAME
+				}
+			}
+		}
+
+		preview.Formats[entry.Format]++
+		switch entry.Status {
+		case ImportStatusNew:
+			preview.New++
+		case ImportStatusDuplicate:
+			preview.Duplicate++
+		case ImportStatusConflict:
+			preview.Conflict++
+		}
+		if entry.IP == "#" {
+			preview.Blocked++
+		}
+	}
+	return preview, nil
+}
+
+// Commit 按预览结果写入数据库，返回需要同步到节点的记录
+func (s *ListImportService) Commit(preview *ImportPreview, opts ImportOptions) (*ImportResult, error) {
+	if opts.NodeIDs == "" {
+		opts.NodeIDs = "[]"
+	}
+	result := &ImportResult{}
+
+	var blocked []string
+	for _, entry := range preview.Entries {
+		if opts.BlockDomainSet != "" && entry.IP == "#" {
+			blocked = append(blocked, entry.Domain)
+			continue
+		}
+
+		switch entry.Status {
+		case ImportStatusNew:
+			result.Created = append(result.Created, models.AddressMap{
+				Domain:         entry.Domain,
+				Type:           entry.Type,
+				IP:             entry.IP,
+				CNAME:          entry.CNAME,
+				NodeIDs:        opts.NodeIDs,
+				Enabled:        true,
+				OrganizationID: opts.OrganizationID,
+			})
+		case ImportStatusConflict:
+			if updated, ok := s.resolveConflict(entry, opts); ok {
+				result.Updated = append(result.Updated, updated)
+			} else {
+				result.Skipped++
+			}
+		default:
+			result.Skipped++
+		}
+	}
+
+	if len(result.Created) > 0 {
+		if err := database.DB.CreateInBatches(&result.Created, 500).Error; err != nil {
+			return nil, fmt.Errorf("保存地址映射失败: %w", err)
+		}
+	}
+
+	if len(blocked) > 0 {
+		if err := s.saveBlockList(opts, blocked, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// resolveConflict 按冲突处理方式更新现有记录
+func (s *ListImportService) resolveConflict(entry ImportEntry, opts ImportOptions) (models.AddressMap, bool) {
+	var current models.AddressMap
+	if err := database.DB.Where("domain = ?", entry.Domain).First(&current).Error; err != nil {
+		return current, false
+	}
+	if opts.OrganizationID > 0 && current.OrganizationID != opts.OrganizationID {
+		return current, false
+	}
+
+	switch opts.ConflictMode {
+	case ImportConflictOverwrite:
+		current.Type, current.IP, current.CNAME = entry.Type, entry.IP, entry.CNAME
+	case ImportConflictMerge:
+		if current.Type != "address" || entry.Type != "address" {
+			return current, false
+		}
+		current.IP = mergeAddressIPs(current.IP, entry.IP)
+	default:
+		return current, false
+	}
+
+	if err := database.DB.Save(&current).Error; err != nil {
+		log.Printf("更新地址映射 %s 失败: %v", current.Domain, err)
+		return current, false
+	}
+	return current, true
+}
+
+// saveBlockList 将屏蔽域名追加到域名集，并确保存在引用该域名集的屏蔽规则
+func (s *ListImportService) saveBlockList(opts ImportOptions, domains []string, result *ImportResult) error {
+	var domainSet models.DomainSet
+	if err := database.DB.Where("name = ?", opts.BlockDomainSet).First(&domainSet).Error; err != nil {
+		domainSet = models.DomainSet{
+			Name:        opts.BlockDomainSet,
+			FilePath:    fmt.Sprintf("/etc/smartdns/%s.conf", opts.BlockDomainSet),
+			Description: "导入的屏蔽列表",
+			NodeIDs:     opts.NodeIDs,
+			Enabled:     true,
+		}
+		if err := database.DB.Create(&domainSet).Error; err != nil {
+			return fmt.Errorf("创建域名集失败: %w", err)
+		}
+	}
+
+	var existing []string
+	database.DB.Model(&models.DomainSetItem{}).Where("domain_set_id = ?", domainSet.ID).Pluck("domain", &existing)
+	seen := make(map[string]bool, len(existing))
+	for _, domain := range existing {
+		seen[domain] = true
+	}
+
+	var items []models.DomainSetItem
+	for _, domain := range domains {
+		if !seen[domain] {
+			seen[domain] = true
+			items = append(items, models.DomainSetItem{DomainSetID: domainSet.ID, Domain: domain})
+		}
+	}
+	if len(items) > 0 {
+		if err := database.DB.CreateInBatches(&items, 500).Error; err != nil {
+			return fmt.Errorf("保存域名集条目失败: %w", err)
+		}
+	}
+	domainSet.DomainCount = len(seen)
+	database.DB.Save(&domainSet)
+
+	ruleDomain := "domain-set:" + domainSet.Name
+	var rule models.DomainRule
+	if err := database.DB.Where("domain = ?", ruleDomain).First(&rule).Error; err != nil {
+		rule = models.DomainRule{
+			Domain:         ruleDomain,
+			IsDomainSet:    true,
+			DomainSetName:  domainSet.Name,
+			Address:        "#",
+			NodeIDs:        domainSet.NodeIDs,
+			Enabled:        true,
+			Description:    "屏蔽域名集 " + domainSet.Name,
+			OrganizationID: opts.OrganizationID,
+		}
+		if err := database.DB.Create(&rule).Error; err != nil {
+			return fmt.Errorf("创建屏蔽规则失败: %w", err)
+		}
+		result.NewRule = true
+	}
+
+	result.Blocked = len(items)
+	result.DomainSet = &domainSet
+	result.DomainRule = &rule
+	return nil
+}
+
+// SyncBlockList 依次同步域名集文件和屏蔽规则到各节点，避免并发修改同一份配置
+func (s *ListImportService) SyncBlockList(domainSet *models.DomainSet, rule *models.DomainRule, syncRule bool) {
+	nodes, err := s.domainSetService.getTargetNodes(domainSet.NodeIDs)
+	if err != nil {
+		log.Printf("解析域名集节点失败: %v", err)
+		return
+	}
+
+	content := s.domainSetService.fileService.BuildContent(domainSet)
+	for i := range nodes {
+		s.domainSetService.syncDomainSetToNode(domainSet, &nodes[i], content)
+		if syncRule && rule.Enabled {
+			s.domainRuleService.syncDomainRuleToNode(rule, &nodes[i])
+		}
+	}
+}
+
+// Parse 解析列表内容，同一域名的多条 IP 合并为一条；format 为空或 auto 时按行自动识别
+func (s *ListImportService) Parse(content, format string) ([]ImportEntry, []ImportIssue) {
+	if format == "" {
+		format = ImportFormatAuto
+	}
+
+	var entries []ImportEntry
+	var issues []ImportIssue
+	index := make(map[string]int)
+
+	for i, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "[") {
+			continue
+		}
+
+		lineFormat := format
+		if lineFormat == ImportFormatAuto {
+			lineFormat = detectImportFormat(line)
+		}
+
+		parsed, err := parseImportLine(line, lineFormat)
+		if err != nil {
+			issues = append(issues, ImportIssue{Line: i + 1, Content: line, Reason: err.Error()})
+			continue
+		}
+
+		for _, entry := range parsed {
+			entry.Line = i + 1
+			entry.Format = lineFormat
+
+			addr := models.AddressMap{Domain: entry.Domain, Type: entry.Type, IP: entry.IP, CNAME: entry.CNAME}
+			if err := NormalizeAddressMap(&addr); err != nil {
+				issues = append(issues, ImportIssue{Line: i + 1, Content: line, Reason: err.Error()})
+				continue
+			}
+			entry.Domain, entry.IP = addr.Domain, addr.IP
+
+			pos, seen := index[entry.Domain]
+			if !seen {
+				index[entry.Domain] = len(entries)
+				entries = append(entries, entry)
+				continue
+			}
+			if reason := mergeImportEntry(&entries[pos], entry); reason != "" {
+				issues = append(issues, ImportIssue{Line: i + 1, Content: line, Reason: reason})
+			}
+		}
+	}
+	return entries, issues
+}
+
+// mergeImportEntry 合并同一域名的重复条目，无法合并时返回原因
+func mergeImportEntry(current *ImportEntry, next ImportEntry) string {
+	switch {
+	case current.Type != next.Type:
+		return fmt.Sprintf("与第 %d 行的 %s 记录冲突", current.Line, current.Type)
+	case current.Type == "cname":
+		if current.CNAME != next.CNAME {
+			return fmt.Sprintf("与第 %d 行的 CNAME 冲突", current.Line)
+		}
+	case next.IP == "-":
+		// 白名单规则覆盖同域名的屏蔽规则
+		current.IP = "-"
+	case current.IP == "-":
+	case addressSpecialValues[current.IP] || addressSpecialValues[next.IP]:
+		if current.IP != next.IP {
+			return fmt.Sprintf("与第 %d 行的 %s 冲突", current.Line, current.IP)
+		}
+	default:
+		current.IP = mergeAddressIPs(current.IP, next.IP)
+	}
+	return ""
+}
+
+// detectImportFormat 根据行内容识别格式
+func detectImportFormat(line string) string {
+	switch {
+	case strings.HasPrefix(line, "address /"), strings.HasPrefix(line, "cname /"):
+		return ImportFormatSmartDNS
+	case strings.Contains(line, "=/"), strings.HasPrefix(line, "cname="):
+		return ImportFormatDnsmasq
+	case strings.HasPrefix(line, "||"), strings.HasPrefix(line, "@@"), strings.HasPrefix(line, "|"), strings.HasPrefix(line, "/"):
+		return ImportFormatAdGuard
+	}
+	if fields := strings.Fields(line); len(fields) >= 2 && net.ParseIP(fields[0]) != nil {
+		return ImportFormatHosts
+	}
+	return ImportFormatPlain
+}
+
+// parseImportLine 按指定格式解析一行，hosts 一行可包含多个域名
+func parseImportLine(line, format string) ([]ImportEntry, error) {
+	switch format {
+	case ImportFormatSmartDNS:
+		return parseSmartDNSImportLine(line)
+	case ImportFormatHosts:
+		return parseHostsImportLine(line)
+	case ImportFormatDnsmasq:
+		return parseDnsmasqImportLine(line)
+	case ImportFormatAdGuard:
+		return parseAdGuardImportLine(line)
+	case ImportFormatPlain:
+		return parsePlainImportLine(line)
+	}
+	return nil, fmt.Errorf("不支持的格式: %s", format)
+}
+
+func parseSmartDNSImportLine(line string) ([]ImportEntry, error) {
+	m := smartdnsLinePattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("不是有效的 SmartDNS address/cname 指令")
+	}
+	value, _, _ := strings.Cut(m[3], " #")
+	value = strings.TrimSpace(value)
+	if m[1] == "cname" {
+		return []ImportEntry{{Domain: m[2], Type: "cname", CNAME: value}}, nil
+	}
+	return []ImportEntry{{Domain: m[2], Type: "address", IP: value}}, nil
+}
+
+// parseHostsImportLine 解析 hosts 行，0.0.0.0 和 :: 视为屏蔽
+func parseHostsImportLine(line string) ([]ImportEntry, error) {
+	line, _, _ = strings.Cut(line, "#")
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("hosts 行缺少域名")
+	}
+	ip := net.ParseIP(fields[0])
+	if ip == nil {
+		return nil, fmt.Errorf("无效的IP地址: %s", fields[0])
+	}
+
+	value := ip.String()
+	if ip.IsUnspecified() {
+		value = "#"
+	}
+
+	var entries []ImportEntry
+	for _, name := range fields[1:] {
+		if hostsIgnoredNames[strings.ToLower(name)] {
+			continue
+		}
+		entries = append(entries, ImportEntry{Domain: name, Type: "address", IP: value})
+	}
+	return entries, nil
+}
+
+// parseDnsmasqImportLine 解析 dnsmasq 的 address=、local= 和 cname= 指令
+func parseDnsmasqImportLine(line string) ([]ImportEntry, error) {
+	line, _, _ = strings.Cut(line, " #")
+	key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+	if !ok {
+		return nil, fmt.Errorf("不是有效的 dnsmasq 指令")
+	}
+
+	switch key {
+	case "cname":
+		parts := strings.Split(value, ",")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("cname 缺少目标域名")
+		}
+		target := strings.TrimSpace(parts[len(parts)-1])
+		var entries []ImportEntry
+		for _, alias := range parts[:len(parts)-1] {
+			entries = append(entries, ImportEntry{Domain: strings.TrimSpace(alias), Type: "cname", CNAME: target})
+		}
+		return entries, nil
+	case "address", "local":
+		// address=/a.com/b.com/1.2.3.4，最后一段为空或 # 表示屏蔽
+		if !strings.HasPrefix(value, "/") {
+			return nil, fmt.Errorf("%s 格式错误", key)
+		}
+		parts := strings.Split(value[1:], "/")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("%s 格式错误", key)
+		}
+		ip := strings.TrimSpace(parts[len(parts)-1])
+		if key == "local" || ip == "" || ip == "#" {
+			ip = "#"
+		}
+		var entries []ImportEntry
+		for _, domain := range parts[:len(parts)-1] {
+			if domain = strings.TrimSpace(domain); domain != "" {
+				entries = append(entries, ImportEntry{Domain: domain, Type: "address", IP: ip})
+			}
+		}
+		return entries, nil
+	}
+	return nil, fmt.Errorf("不支持的 dnsmasq 指令: %s，上游服务器请通过命名服务器规则配置", key)
+}
+
+// parseAdGuardImportLine 解析 AdGuard/ABP 域名规则，只支持不带修饰符（$important 除外）的 ||domain^ 形式
+func parseAdGuardImportLine(line string) ([]ImportEntry, error) {
+	m := adguardRulePattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("不支持的 AdGuard 规则，仅支持 ||example.com^ 和 @@||example.com^")
+	}
+
+	ip := "#"
+	if m[1] == "@@" {
+		ip = "-"
+	}
+	return []ImportEntry{{Domain: m[2], Type: "address", IP: ip}}, nil
+}
+
+// parsePlainImportLine 解析 "域名 IP"、"域名 cname 目标" 或只有域名的屏蔽列表
+func parsePlainImportLine(line string) ([]ImportEntry, error) {
+	line, _, _ = strings.Cut(line, " #")
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 1:
+		return []ImportEntry{{Domain: fields[0], Type: "address", IP: "#"}}, nil
+	case len(fields) >= 3 && strings.EqualFold(fields[1], "cname"):
+		return []ImportEntry{{Domain: fields[0], Type: "cname", CNAME: fields[2]}}, nil
+	case len(fields) == 2:
+		return []ImportEntry{{Domain: fields[0], Type: "address", IP: fields[1]}}, nil
+	}
+	return nil, fmt.Errorf("无法识别的格式")
+}
@@ -0,0 +1,794 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"smartdns-manager/models"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// NXDOMAIN 判定条件，所有统计共用：有 rcode 字段时以响应码为准，
+// 旧版 Agent 写入的日志 rcode 为空，退回以无应答记录近似
+const (
+	nxdomainCondition   = "if(rcode != '', rcode = 'NXDOMAIN', result_count = 0)"
+	nxdomainConditionPG = "CASE WHEN rcode <> '' THEN rcode = 'NXDOMAIN' ELSE result_count = 0 END"
+)
+
+// LogAnalyticsService DNS 日志分析服务（基于 ClickHouse）
+type LogAnalyticsService struct {
+	conn  driver.Conn
+	cache *QueryCache // 报表查询结果缓存
+}
+
+// NewLogAnalyticsService 创建日志分析服务
+func NewLogAnalyticsService(conn driver.Conn) *LogAnalyticsService {
+	return &LogAnalyticsService{conn: conn, cache: NewClickHouseQueryCache()}
+}
+
+// buildTimeWhere 构建时间与节点过滤条件
+func (s *LogAnalyticsService) buildTimeWhere(nodeID uint, startTime, endTime time.Time) (string, []interface{}) {
+	where := "timestamp BETWEEN ? AND ?"
+	args := []interface{}{startTime, endTime}
+
+	if nodeID > 0 {
+		where += " AND node_id = ?"
+		args = append(args, uint32(nodeID))
+	}
+
+	return where, args
+}
+
+// GetQueryTypeAnalytics 获取查询类型分布和趋势，结果按参数短时缓存
+func (s *LogAnalyticsService) GetQueryTypeAnalytics(nodeID uint, startTime, endTime time.Time, interval string) (*models.QueryTypeAnalytics, error) {
+	value, err := s.cache.Get(s.cache.Key("query_type", nodeID, startTime, endTime, interval), func() (interface{}, error) {
+		return s.loadQueryTypeAnalytics(nodeID, startTime, endTime, interval)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := *value.(*models.QueryTypeAnalytics)
+	return &result, nil
+}
+
+// loadQueryTypeAnalytics 从 ClickHouse 查询类型分布和趋势
+func (s *LogAnalyticsService) loadQueryTypeAnalytics(nodeID uint, startTime, endTime time.Time, interval string) (*models.QueryTypeAnalytics, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	bucketFunc := "toStartOfHour"
+	if interval == "day" {
+		bucketFunc = "toStartOfDay"
+	} else {
+		interval = "hour"
+	}
+
+	result := &models.QueryTypeAnalytics{
+		StartTime:    startTime,
+		EndTime:      endTime,
+		Interval:     interval,
+		Distribution: make([]models.QueryTypeStat, 0),
+		ByNode:       make([]models.NodeQueryTypeStat, 0),
+		Trend:        make([]models.QueryTypeTrendPoint, 0),
+	}
+
+	where, args := s.buildTimeWhere(nodeID, startTime, endTime)
+
+	// 1. 当前周期分布
+	rows, err := s.conn.Query(ctx,
+		fmt.Sprintf("SELECT query_type, count() AS cnt FROM dns_query_log WHERE %s GROUP BY query_type ORDER BY cnt DESC", where),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询类型分布失败: %w", err)
+	}
+	for rows.Next() {
+		var queryType uint16
+		var count uint64
+		if err := rows.Scan(&queryType, &count); err != nil {
+			log.Printf("⚠️ 扫描行失败: %v", err)
+			continue
+		}
+		result.Total += int64(count)
+		result.Distribution = append(result.Distribution, models.QueryTypeStat{
+			QueryType: int(queryType),
+			Name:      models.QueryTypeName(queryType),
+			Count:     int64(count),
+		})
+	}
+	rows.Close()
+
+	// 2. 上一周期分布（用于发现突增）
+	duration := endTime.Sub(startTime)
+	prevWhere, prevArgs := s.buildTimeWhere(nodeID, startTime.Add(-duration), startTime)
+	previous := make(map[int]int64)
+	rows, err = s.conn.Query(ctx,
+		fmt.Sprintf("SELECT query_type, count() AS cnt FROM dns_query_log WHERE %s GROUP BY query_type", prevWhere),
+		prevArgs...)
+	if err == nil {
+		for rows.Next() {
+			var queryType uint16
+			var count uint64
+			if err := rows.Scan(&queryType, &count); err == nil {
+				previous[int(queryType)] = int64(count)
+			}
+		}
+		rows.Close()
+	} else {
+		log.Printf("⚠️ 查询上一周期类型分布失败: %v", err)
+	}
+
+	for i := range result.Distribution {
+		stat := &result.Distribution[i]
+		if result.Total > 0 {
+			stat.Percent = float64(stat.Count) / float64(result.Total) * 100
+		}
+		stat.PreviousCount = previous[stat.QueryType]
+		if stat.PreviousCount > 0 {
+			stat.ChangeRate = float64(stat.Count-stat.PreviousCount) / float64(stat.PreviousCount) * 100
+		} else if stat.Count > 0 {
+			stat.ChangeRate = 100
+		}
+	}
+
+	// 3. 节点维度分布
+	rows, err = s.conn.Query(ctx,
+		fmt.Sprintf("SELECT node_id, query_type, count() AS cnt FROM dns_query_log WHERE %s GROUP BY node_id, query_type ORDER BY node_id, cnt DESC", where),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询节点类型分布失败: %w", err)
+	}
+	for rows.Next() {
+		var nid uint32
+		var queryType uint16
+		var count uint64
+		if err := rows.Scan(&nid, &queryType, &count); err != nil {
+			continue
+		}
+		result.ByNode = append(result.ByNode, models.NodeQueryTypeStat{
+			NodeID:    uint(nid),
+			QueryType: int(queryType),
+			Name:      models.QueryTypeName(queryType),
+			Count:     int64(count),
+		})
+	}
+	rows.Close()
+
+	// 4. 趋势
+	rows, err = s.conn.Query(ctx,
+		fmt.Sprintf("SELECT %s(timestamp) AS bucket, query_type, count() AS cnt FROM dns_query_log WHERE %s GROUP BY bucket, query_type ORDER BY bucket, query_type", bucketFunc, where),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询类型趋势失败: %w", err)
+	}
+	for rows.Next() {
+		var bucket time.Time
+		var queryType uint16
+		var count uint64
+		if err := rows.Scan(&bucket, &queryType, &count); err != nil {
+			continue
+		}
+		result.Trend = append(result.Trend, models.QueryTypeTrendPoint{
+			Time:      bucket,
+			QueryType: int(queryType),
+			Name:      models.QueryTypeName(queryType),
+			Count:     int64(count),
+		})
+	}
+	rows.Close()
+
+	return result, nil
+}
+
+// GetResponseIPAnalytics 获取应答 IP 分析（按 IP 或子网聚合，并识别 CDN 服务商），结果按参数短时缓存
+func (s *LogAnalyticsService) GetResponseIPAnalytics(nodeID uint, domain string, startTime, endTime time.Time, groupBy string, limit int) (*models.ResponseIPAnalytics, error) {
+	value, err := s.cache.Get(s.cache.Key("response_ip", nodeID, domain, startTime, endTime, groupBy, limit), func() (interface{}, error) {
+		return s.loadResponseIPAnalytics(nodeID, domain, startTime, endTime, groupBy, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := *value.(*models.ResponseIPAnalytics)
+	return &result, nil
+}
+
+// loadResponseIPAnalytics 从 ClickHouse 查询应答 IP 分析（按 IP 或子网聚合，并识别 CDN 服务商）
+func (s *LogAnalyticsService) loadResponseIPAnalytics(nodeID uint, domain string, startTime, endTime time.Time, groupBy string, limit int) (*models.ResponseIPAnalytics, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	// IPv4 按 /24 聚合，IPv6 按 /48 聚合
+	ipExpr := "ip"
+	if groupBy == "subnet" {
+		ipExpr = "if(isIPv4String(ip), concat(IPv4NumToString(bitAnd(IPv4StringToNum(ip), 4294967040)), '/24'), " +
+			"if(isIPv6String(ip), concat(IPv6NumToString(IPv6CIDRToRange(toIPv6(ip), 48).1), '/48'), ip))"
+	} else {
+		groupBy = "ip"
+	}
+
+	result := &models.ResponseIPAnalytics{
+		StartTime: startTime,
+		EndTime:   endTime,
+		GroupBy:   groupBy,
+		TopIPs:    make([]models.ResponseIPStat, 0),
+		ByNode:    make([]models.NodeResponseIPStat, 0),
+		ByDomain:  make([]models.DomainResponseIPStat, 0),
+		Providers: make([]models.ProviderStat, 0),
+	}
+
+	where, args := s.buildTimeWhere(nodeID, startTime, endTime)
+	where += " AND result_count > 0"
+	if domain != "" {
+		where += " AND domain = ?"
+		args = append(args, domain)
+	}
+
+	classifier := GetCDNClassifier()
+	providerCounts := make(map[string]int64)
+
+	// 1. 全局 Top IP
+	rows, err := s.conn.Query(ctx, fmt.Sprintf(`
+        SELECT %s AS target, count() AS cnt, uniqExact(domain), avg(time_ms), avg(speed_ms)
+        FROM dns_query_log ARRAY JOIN result_ips AS ip
+        WHERE %s
+        GROUP BY target ORDER BY cnt DESC LIMIT %d`, ipExpr, where, limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询应答IP失败: %w", err)
+	}
+	for rows.Next() {
+		var stat models.ResponseIPStat
+		var count, uniqueDomains uint64
+		if err := rows.Scan(&stat.IP, &count, &uniqueDomains, &stat.AvgTimeMs, &stat.AvgSpeedMs); err != nil {
+			log.Printf("⚠️ 扫描行失败: %v", err)
+			continue
+		}
+		stat.Count = int64(count)
+		stat.UniqueDomains = int64(uniqueDomains)
+		stat.Provider = classifier.Classify(stat.IP)
+		result.TopIPs = append(result.TopIPs, stat)
+	}
+	rows.Close()
+
+	// 2. 每个节点的 Top IP
+	rows, err = s.conn.Query(ctx, fmt.Sprintf(`
+        SELECT node_id, %s AS target, count() AS cnt, avg(speed_ms)
+        FROM dns_query_log ARRAY JOIN result_ips AS ip
+        WHERE %s
+        GROUP BY node_id, target ORDER BY node_id, cnt DESC LIMIT %d BY node_id`, ipExpr, where, limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询节点应答IP失败: %w", err)
+	}
+	for rows.Next() {
+		var stat models.NodeResponseIPStat
+		var nid uint32
+		var count uint64
+		if err := rows.Scan(&nid, &stat.IP, &count, &stat.AvgSpeedMs); err != nil {
+			continue
+		}
+		stat.NodeID = uint(nid)
+		stat.Count = int64(count)
+		stat.Provider = classifier.Classify(stat.IP)
+		result.ByNode = append(result.ByNode, stat)
+	}
+	rows.Close()
+
+	// 3. 热门域名的 Top IP（每个域名取前5）
+	rows, err = s.conn.Query(ctx, fmt.Sprintf(`
+        SELECT domain, %s AS target, count() AS cnt, avg(speed_ms)
+        FROM dns_query_log ARRAY JOIN result_ips AS ip
+        WHERE %s AND domain IN (
+            SELECT domain FROM dns_query_log WHERE %s GROUP BY domain ORDER BY count() DESC LIMIT %d
+        )
+        GROUP BY domain, target ORDER BY domain, cnt DESC LIMIT 5 BY domain`, ipExpr, where, where, limit),
+		append(append([]interface{}{}, args...), args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("查询域名应答IP失败: %w", err)
+	}
+	for rows.Next() {
+		var stat models.DomainResponseIPStat
+		var count uint64
+		if err := rows.Scan(&stat.Domain, &stat.IP, &count, &stat.AvgSpeedMs); err != nil {
+			continue
+		}
+		stat.Count = int64(count)
+		stat.Provider = classifier.Classify(stat.IP)
+		result.ByDomain = append(result.ByDomain, stat)
+	}
+	rows.Close()
+
+	// 4. 服务商汇总（基于 Top IP）
+	for _, stat := range result.TopIPs {
+		provider := stat.Provider
+		if provider == "" {
+			provider = "other"
+		}
+		providerCounts[provider] += stat.Count
+	}
+	for provider, count := range providerCounts {
+		result.Providers = append(result.Providers, models.ProviderStat{Provider: provider, Count: count})
+	}
+	sort.Slice(result.Providers, func(i, j int) bool {
+		return result.Providers[i].Count > result.Providers[j].Count
+	})
+
+	return result, nil
+}
+
+// GetClientProfile 获取单个客户端在时间范围内的查询画像
+func (s *LogAnalyticsService) GetClientProfile(clientIP string, nodeID uint, startTime, endTime time.Time) (*models.ClientProfile, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	profile := &models.ClientProfile{
+		ClientIP:   clientIP,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		QueryTypes: make([]models.QueryTypeStat, 0),
+		TopDomains: make([]models.DomainStat, 0),
+		Nodes:      make([]models.NodeCountStat, 0),
+	}
+
+	where, args := s.buildTimeWhere(nodeID, startTime, endTime)
+	where += " AND client_ip = ?"
+	args = append(args, clientIP)
+
+	// 1. 汇总信息
+	var total, uniqueDomains, nxCount uint64
+	var avgTime float64
+	row := s.conn.QueryRow(ctx, fmt.Sprintf(`
+        SELECT count(), uniqExact(domain), countIf(%s), avg(time_ms),
+               argMax(client_subnet, timestamp), argMax(client_country, timestamp),
+               argMax(client_asn, timestamp), argMax(client_ptr, timestamp)
+        FROM dns_query_log WHERE %s`, nxdomainCondition, where), args...)
+	if err := row.Scan(&total, &uniqueDomains, &nxCount, &avgTime,
+		&profile.ClientSubnet, &profile.ClientCountry, &profile.ClientASN, &profile.ClientPTR); err != nil {
+		return nil, fmt.Errorf("查询客户端汇总失败: %w", err)
+	}
+
+	profile.TotalQueries = int64(total)
+	profile.UniqueDomains = int64(uniqueDomains)
+	profile.NXDomainCount = int64(nxCount)
+	if total > 0 {
+		profile.NXDomainRate = float64(nxCount) / float64(total) * 100
+		profile.AvgTimeMs = avgTime
+	}
+	if seconds := endTime.Sub(startTime).Seconds(); seconds > 0 {
+		profile.AvgQPS = float64(total) / seconds
+	}
+
+	if total == 0 {
+		return profile, nil
+	}
+
+	// 2. 峰值 QPS（按分钟聚合）
+	var peak uint64
+	row = s.conn.QueryRow(ctx, fmt.Sprintf(`
+        SELECT max(cnt) FROM (
+            SELECT toStartOfMinute(timestamp) AS minute, count() AS cnt
+            FROM dns_query_log WHERE %s GROUP BY minute
+        )`, where), args...)
+	if err := row.Scan(&peak); err != nil {
+		log.Printf("⚠️ 查询客户端峰值QPS失败: %v", err)
+	}
+	profile.PeakQPS = float64(peak) / 60
+
+	// 3. 查询类型分布
+	rows, err := s.conn.Query(ctx,
+		fmt.Sprintf("SELECT query_type, count() AS cnt FROM dns_query_log WHERE %s GROUP BY query_type ORDER BY cnt DESC", where),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询客户端类型分布失败: %w", err)
+	}
+	for rows.Next() {
+		var queryType uint16
+		var count uint64
+		if err := rows.Scan(&queryType, &count); err != nil {
+			continue
+		}
+		profile.QueryTypes = append(profile.QueryTypes, models.QueryTypeStat{
+			QueryType: int(queryType),
+			Name:      models.QueryTypeName(queryType),
+			Count:     int64(count),
+			Percent:   float64(count) / float64(total) * 100,
+		})
+	}
+	rows.Close()
+
+	// 4. Top 域名
+	rows, err = s.conn.Query(ctx,
+		fmt.Sprintf("SELECT domain, count() AS cnt FROM dns_query_log WHERE %s GROUP BY domain ORDER BY cnt DESC LIMIT 20", where),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询客户端Top域名失败: %w", err)
+	}
+	for rows.Next() {
+		var stat models.DomainStat
+		var count uint64
+		if err := rows.Scan(&stat.Domain, &count); err != nil {
+			continue
+		}
+		stat.Count = int64(count)
+		profile.TopDomains = append(profile.TopDomains, stat)
+	}
+	rows.Close()
+
+	// 5. 节点分布
+	rows, err = s.conn.Query(ctx,
+		fmt.Sprintf("SELECT node_id, count() AS cnt FROM dns_query_log WHERE %s GROUP BY node_id ORDER BY cnt DESC", where),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询客户端节点分布失败: %w", err)
+	}
+	for rows.Next() {
+		var nid uint32
+		var count uint64
+		if err := rows.Scan(&nid, &count); err != nil {
+			continue
+		}
+		profile.Nodes = append(profile.Nodes, models.NodeCountStat{NodeID: uint(nid), Count: int64(count)})
+	}
+	rows.Close()
+
+	return profile, nil
+}
+
+// GetClientsExceedingQPS 获取时间窗口内平均 QPS 超过阈值的客户端（按节点区分）
+func (s *LogAnalyticsService) GetClientsExceedingQPS(nodeIDs []uint, startTime, endTime time.Time, threshold float64, limit int) ([]models.ClientQPSStat, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	seconds := endTime.Sub(startTime).Seconds()
+	if seconds <= 0 {
+		return nil, fmt.Errorf("无效的时间窗口")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	where, args := s.buildTimeWhere(0, startTime, endTime)
+	if len(nodeIDs) > 0 {
+		ids := make([]uint32, 0, len(nodeIDs))
+		for _, id := range nodeIDs {
+			ids = append(ids, uint32(id))
+		}
+		where += " AND node_id IN (?)"
+		args = append(args, ids)
+	}
+
+	minCount := uint64(threshold * seconds)
+	rows, err := s.conn.Query(ctx, fmt.Sprintf(`
+        SELECT client_ip, node_id, count() AS cnt
+        FROM dns_query_log WHERE %s
+        GROUP BY client_ip, node_id HAVING cnt > %d
+        ORDER BY cnt DESC LIMIT %d`, where, minCount, limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询客户端QPS失败: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]models.ClientQPSStat, 0)
+	for rows.Next() {
+		var stat models.ClientQPSStat
+		var nid uint32
+		var count uint64
+		if err := rows.Scan(&stat.ClientIP, &nid, &count); err != nil {
+			log.Printf("⚠️ 扫描行失败: %v", err)
+			continue
+		}
+		stat.NodeID = uint(nid)
+		stat.Count = int64(count)
+		stat.QPS = float64(count) / seconds
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+// GetSlowQueries 获取慢查询及耗时分位数，thresholdMs 为 0 时以 P95 作为慢查询阈值，结果按参数短时缓存
+func (s *LogAnalyticsService) GetSlowQueries(nodeID uint, group, domain string, startTime, endTime time.Time, thresholdMs, limit int) (*models.SlowQueryAnalytics, error) {
+	value, err := s.cache.Get(s.cache.Key("slow_queries", nodeID, group, domain, startTime, endTime, thresholdMs, limit), func() (interface{}, error) {
+		return s.loadSlowQueries(nodeID, group, domain, startTime, endTime, thresholdMs, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := *value.(*models.SlowQueryAnalytics)
+	return &result, nil
+}
+
+// loadSlowQueries 从 ClickHouse 查询慢查询及耗时分位数，thresholdMs 为 0 时以 P95 作为慢查询阈值
+func (s *LogAnalyticsService) loadSlowQueries(nodeID uint, group, domain string, startTime, endTime time.Time, thresholdMs, limit int) (*models.SlowQueryAnalytics, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	result := &models.SlowQueryAnalytics{
+		StartTime: startTime,
+		EndTime:   endTime,
+		Queries:   make([]models.SlowQuery, 0),
+		ByGroup:   make([]models.SlowQueryGroupStat, 0),
+		ByDomain:  make([]models.SlowQueryGroupStat, 0),
+	}
+
+	where, args := s.buildTimeWhere(nodeID, startTime, endTime)
+	if group != "" {
+		where += " AND `group` = ?"
+		args = append(args, group)
+	}
+	if domain != "" {
+		where += " AND domain LIKE ?"
+		args = append(args, "%"+domain+"%")
+	}
+
+	// 1. 分位数
+	var quantiles []float64
+	var maxTime uint32
+	row := s.conn.QueryRow(ctx, fmt.Sprintf(
+		"SELECT quantiles(0.5, 0.9, 0.95, 0.99)(time_ms), max(time_ms) FROM dns_query_log WHERE %s", where), args...)
+	if err := row.Scan(&quantiles, &maxTime); err != nil {
+		return nil, fmt.Errorf("查询耗时分位数失败: %w", err)
+	}
+	if len(quantiles) == 4 {
+		result.Percentiles = models.LatencyPercentiles{
+			P50: quantiles[0],
+			P90: quantiles[1],
+			P95: quantiles[2],
+			P99: quantiles[3],
+		}
+	}
+	result.Percentiles.Max = float64(maxTime)
+
+	if thresholdMs <= 0 {
+		thresholdMs = int(result.Percentiles.P95)
+	}
+	result.ThresholdMs = thresholdMs
+
+	// 2. 最慢的查询明细
+	rows, err := s.conn.Query(ctx, fmt.Sprintf(`
+        SELECT timestamp, node_id, client_ip, domain, query_type, "group", time_ms, speed_ms, result_count, result_ips
+        FROM dns_query_log WHERE %s AND time_ms >= ?
+        ORDER BY time_ms DESC LIMIT %d`, where, limit), append(append([]interface{}{}, args...), uint32(thresholdMs))...)
+	if err != nil {
+		return nil, fmt.Errorf("查询慢查询失败: %w", err)
+	}
+	for rows.Next() {
+		var q models.SlowQuery
+		var nid, timeMs uint32
+		var queryType uint16
+		var speedMs float32
+		var resultCount uint8
+		if err := rows.Scan(&q.Timestamp, &nid, &q.ClientIP, &q.Domain, &queryType, &q.Group,
+			&timeMs, &speedMs, &resultCount, &q.ResultIPs); err != nil {
+			log.Printf("⚠️ 扫描行失败: %v", err)
+			continue
+		}
+		q.NodeID = uint(nid)
+		q.QueryType = int(queryType)
+		q.QueryTypeName = models.QueryTypeName(queryType)
+		q.TimeMs = int(timeMs)
+		q.SpeedMs = float64(speedMs)
+		q.ResultCount = int(resultCount)
+		result.Queries = append(result.Queries, q)
+	}
+	rows.Close()
+
+	// 3. 按上游组、域名汇总
+	byGroup, err := s.slowQueryBreakdown(ctx, "`group`", where, args, thresholdMs, 20)
+	if err != nil {
+		return nil, err
+	}
+	result.ByGroup = byGroup
+
+	byDomain, err := s.slowQueryBreakdown(ctx, "domain", where, args, thresholdMs, 20)
+	if err != nil {
+		return nil, err
+	}
+	result.ByDomain = byDomain
+
+	return result, nil
+}
+
+// slowQueryBreakdown 按指定字段汇总慢查询数量，按慢查询数倒序
+func (s *LogAnalyticsService) slowQueryBreakdown(ctx context.Context, field, where string, args []interface{}, thresholdMs, limit int) ([]models.SlowQueryGroupStat, error) {
+	rows, err := s.conn.Query(ctx, fmt.Sprintf(`
+        SELECT %s AS key, count() AS cnt, countIf(time_ms >= %d) AS slow_cnt, avg(time_ms), quantile(0.95)(time_ms)
+        FROM dns_query_log WHERE %s
+        GROUP BY key HAVING slow_cnt > 0
+        ORDER BY slow_cnt DESC LIMIT %d`, field, thresholdMs, where, limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("按%s汇总慢查询失败: %w", field, err)
+	}
+	defer rows.Close()
+
+	stats := make([]models.SlowQueryGroupStat, 0)
+	for rows.Next() {
+		var stat models.SlowQueryGroupStat
+		var count, slowCount uint64
+		if err := rows.Scan(&stat.Key, &count, &slowCount, &stat.AvgTimeMs, &stat.P95); err != nil {
+			continue
+		}
+		stat.Count = int64(count)
+		stat.SlowCount = int64(slowCount)
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+// GetUpstreamAnalytics 获取上游性能分析（各上游的耗时、失败占比和被选中应答的比例），结果按参数短时缓存
+func (s *LogAnalyticsService) GetUpstreamAnalytics(nodeID uint, group string, startTime, endTime time.Time, interval string, limit int) (*models.UpstreamAnalytics, error) {
+	value, err := s.cache.Get(s.cache.Key("upstream", nodeID, group, startTime, endTime, interval, limit), func() (interface{}, error) {
+		return s.loadUpstreamAnalytics(nodeID, group, startTime, endTime, interval, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := *value.(*models.UpstreamAnalytics)
+	return &result, nil
+}
+
+// loadUpstreamAnalytics 从 ClickHouse 按上游组和上游服务器汇总查询表现
+//
+// 选中率按上游组计算：同组上游中谁被选中应答的比例，开启测速时可据此判断测速是否选中了预期的上游。
+// 失败按 nxdomainCondition 判定（与 NXDOMAIN 统计一致）。
+func (s *LogAnalyticsService) loadUpstreamAnalytics(nodeID uint, group string, startTime, endTime time.Time, interval string, limit int) (*models.UpstreamAnalytics, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	bucketFunc := "toStartOfHour"
+	if interval == "day" {
+		bucketFunc = "toStartOfDay"
+	} else {
+		interval = "hour"
+	}
+
+	result := &models.UpstreamAnalytics{
+		StartTime: startTime,
+		EndTime:   endTime,
+		Interval:  interval,
+		Upstreams: make([]models.UpstreamStat, 0),
+		ByNode:    make([]models.NodeUpstreamStat, 0),
+		Trend:     make([]models.UpstreamTrendPoint, 0),
+	}
+
+	where, args := s.buildTimeWhere(nodeID, startTime, endTime)
+	if group != "" {
+		where += " AND `group` = ?"
+		args = append(args, group)
+	}
+
+	// 1. 各上游汇总
+	rows, err := s.conn.Query(ctx, fmt.Sprintf(`
+        SELECT "group", upstream, count() AS cnt, countIf(%s),
+               avg(time_ms), quantiles(0.5, 0.95, 0.99)(time_ms), ifNotFinite(avgIf(speed_ms, speed_ms > 0), 0)
+        FROM dns_query_log WHERE %s
+        GROUP BY "group", upstream ORDER BY cnt DESC LIMIT %d`, nxdomainCondition, where, limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询上游统计失败: %w", err)
+	}
+	groupTotals := make(map[string]int64)
+	for rows.Next() {
+		var stat models.UpstreamStat
+		var count, failures uint64
+		var quantiles []float64
+		if err := rows.Scan(&stat.Group, &stat.Upstream, &count, &failures, &stat.AvgTimeMs, &quantiles, &stat.AvgSpeedMs); err != nil {
+			log.Printf("⚠️ 扫描行失败: %v", err)
+			continue
+		}
+		stat.Count = int64(count)
+		stat.Failures = int64(failures)
+		stat.Protocol = upstreamProtocol(stat.Upstream)
+		if len(quantiles) == 3 {
+			stat.P50, stat.P95, stat.P99 = quantiles[0], quantiles[1], quantiles[2]
+		}
+		if stat.Count > 0 {
+			stat.FailureRate = float64(stat.Failures) / float64(stat.Count) * 100
+		}
+		if stat.Upstream != "" {
+			result.HasUpstream = true
+		}
+		groupTotals[stat.Group] += stat.Count
+		result.Total += stat.Count
+		result.Failures += stat.Failures
+		result.Upstreams = append(result.Upstreams, stat)
+	}
+	rows.Close()
+
+	for i := range result.Upstreams {
+		stat := &result.Upstreams[i]
+		if total := groupTotals[stat.Group]; total > 0 {
+			stat.SelectionRate = float64(stat.Count) / float64(total) * 100
+		}
+		if result.Total > 0 {
+			stat.Share = float64(stat.Count) / float64(result.Total) * 100
+		}
+		if result.Failures > 0 {
+			stat.FailureShare = float64(stat.Failures) / float64(result.Failures) * 100
+		}
+	}
+
+	// 2. 节点维度的上游选择
+	rows, err = s.conn.Query(ctx, fmt.Sprintf(`
+        SELECT node_id, "group", upstream, count() AS cnt, avg(time_ms)
+        FROM dns_query_log WHERE %s
+        GROUP BY node_id, "group", upstream
+        ORDER BY node_id, cnt DESC LIMIT %d BY node_id`, where, limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询节点上游统计失败: %w", err)
+	}
+	nodeGroupTotals := make(map[string]int64)
+	for rows.Next() {
+		var stat models.NodeUpstreamStat
+		var nid uint32
+		var count uint64
+		if err := rows.Scan(&nid, &stat.Group, &stat.Upstream, &count, &stat.AvgTimeMs); err != nil {
+			continue
+		}
+		stat.NodeID = uint(nid)
+		stat.Count = int64(count)
+		nodeGroupTotals[fmt.Sprintf("%d/%s", nid, stat.Group)] += stat.Count
+		result.ByNode = append(result.ByNode, stat)
+	}
+	rows.Close()
+
+	for i := range result.ByNode {
+		stat := &result.ByNode[i]
+		if total := nodeGroupTotals[fmt.Sprintf("%d/%s", stat.NodeID, stat.Group)]; total > 0 {
+			stat.SelectionRate = float64(stat.Count) / float64(total) * 100
+		}
+	}
+
+	// 3. 趋势
+	rows, err = s.conn.Query(ctx, fmt.Sprintf(`
+        SELECT %s(timestamp) AS bucket, "group", upstream, count() AS cnt, countIf(%s), avg(time_ms)
+        FROM dns_query_log WHERE %s
+        GROUP BY bucket, "group", upstream
+        ORDER BY bucket, cnt DESC LIMIT %d BY bucket`, bucketFunc, nxdomainCondition, where, limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询上游趋势失败: %w", err)
+	}
+	for rows.Next() {
+		var point models.UpstreamTrendPoint
+		var count, failures uint64
+		if err := rows.Scan(&point.Time, &point.Group, &point.Upstream, &count, &failures, &point.AvgTimeMs); err != nil {
+			continue
+		}
+		point.Count = int64(count)
+		point.Failures = int64(failures)
+		result.Trend = append(result.Trend, point)
+	}
+	rows.Close()
+
+	return result, nil
+}
+
+// upstreamProtocol 按 SmartDNS 上游地址的写法推断协议
+func upstreamProtocol(upstream string) string {
+	switch {
+	case upstream == "":
+		return ""
+	case strings.HasPrefix(upstream, "https://"):
+		return "https"
+	case strings.HasPrefix(upstream, "tls://"), strings.HasSuffix(upstream, ":853"):
+		return "tls"
+	case strings.HasPrefix(upstream, "quic://"):
+		return "quic"
+	case strings.HasPrefix(upstream, "tcp://"):
+		return "tcp"
+	}
+	return "udp"
+}
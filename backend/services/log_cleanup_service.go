@@ -68,7 +68,7 @@ func (s *LogCleanupService) CleanupLogs(ctx context.Context, config models.LogCl
 
 	// 清理SmartDNS日志
 	if config.SmartDNSLogDays > 0 {
-		deleted, size, err := s.cleanupSmartDNSLogs(config.SmartDNSLogDays)
+		deleted, size, err := s.cleanupSmartDNSLogs(config.SmartDNSLogDays, config.NodeIDs)
 		if err != nil {
 			log.Printf("❌ 清理SmartDNS日志失败: %v", err)
 			results = append(results, fmt.Sprintf("SmartDNS日志清理失败: %v", err))
@@ -100,6 +100,18 @@ func (s *LogCleanupService) CleanupLogs(ctx context.Context, config models.LogCl
 		results = append(results, "遥测结果清理完成")
 	}
 
+	// 清理数据库中的通知日志
+	notificationLogDays := config.NotificationLogDays
+	if notificationLogDays <= 0 {
+		notificationLogDays = 90
+	}
+	if deleted, err := s.cleanupNotificationLogs(notificationLogDays); err != nil {
+		log.Printf("❌ 清理通知日志失败: %v", err)
+		results = append(results, fmt.Sprintf("通知日志清理失败: %v", err))
+	} else {
+		results = append(results, fmt.Sprintf("通知日志: 删除 %d 条记录", deleted))
+	}
+
 	summary := fmt.Sprintf("日志清理完成: 总共删除 %d 个文件, 释放 %.2f MB 空间", totalDeleted, float64(totalSize)/(1024*1024))
 	if len(results) > 0 {
 		summary += "; 详情: " + strings.Join(results, "; ")
@@ -157,23 +169,37 @@ func (s *LogCleanupService) cleanupBackendLogs(retentionDays int) (int, int64, e
 }
 
 // cleanupSmartDNSLogs 清理SmartDNS日志
-func (s *LogCleanupService) cleanupSmartDNSLogs(retentionDays int) (int, int64, error) {
+func (s *LogCleanupService) cleanupSmartDNSLogs(retentionDays int, nodeIDs []uint) (int, int64, error) {
 	if s.logMonitorService == nil {
 		log.Printf("⚠️ 日志监控服务未初始化，跳过SmartDNS日志清理")
 		return 0, 0, nil
 	}
 
-	// 获取所有节点
+	// 获取要清理的节点，未指定时为所有节点
 	var nodes []models.Node
-	if err := s.db.Find(&nodes).Error; err != nil {
+	query := s.db
+	if len(nodeIDs) > 0 {
+		query = query.Where("id IN ?", nodeIDs)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
 		return 0, 0, fmt.Errorf("查询节点列表失败: %w", err)
 	}
 
 	totalCleaned := 0
 	totalSize := int64(0)
 
+	// 单独设置了保留天数的节点由DNS查询日志保留任务按其保留天数清理
+	policy, err := LoadLogRetentionPolicy()
+	if err != nil {
+		return 0, 0, err
+	}
+
 	// 对每个节点清理DNS日志
 	for _, node := range nodes {
+		if days, ok := policy.NodeDays[node.ID]; ok {
+			log.Printf("⏭️ 节点 %s 单独设置了日志保留天数（%d 天），跳过", node.Name, days)
+			continue
+		}
 		log.Printf("🧹 开始清理节点 %s (ID: %d) 的DNS日志", node.Name, node.ID)
 
 		// 调用日志监控服务清理指定节点的旧日志
@@ -189,7 +215,7 @@ func (s *LogCleanupService) cleanupSmartDNSLogs(retentionDays int) (int, int64,
 	}
 
 	// 如果没有指定节点，清理所有DNS日志
-	if len(nodes) == 0 {
+	if len(nodes) == 0 && len(nodeIDs) == 0 {
 		log.Printf("🧹 清理所有DNS日志（无节点限制）")
 		if err := s.logMonitorService.CleanOldLogs(0, retentionDays); err != nil {
 			return 0, 0, fmt.Errorf("清理所有DNS日志失败: %w", err)
@@ -263,3 +289,19 @@ func (s *LogCleanupService) cleanupTelemetryResults(retentionDays int) error {
 
 	return nil
 }
+
+// cleanupNotificationLogs 清理过期的通知日志
+func (s *LogCleanupService) cleanupNotificationLogs(retentionDays int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+
+	result := s.db.Where("created_at < ?", cutoff).Delete(&models.NotificationLog{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("清理通知日志失败: %w", result.Error)
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("🗑️ 清理通知日志: 删除 %d 条记录", result.RowsAffected)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"smartdns-manager/models"
+)
+
+// LogCursor 游标翻页的位置：上一页最后一条日志的排序键和排序方向
+//
+// 对外以 base64 编码的不透明字符串传递，客户端只需原样带回 next_cursor。
+type LogCursor struct {
+	TimestampMs int64  `json:"t"`
+	NodeID      uint   `json:"n"`
+	ClientIP    string `json:"c"`
+	Domain      string `json:"d"`
+	QueryType   int    `json:"q"`
+	RowHash     uint64 `json:"h"` // cityHash64(raw_log)，排序键相同的日志按它区分，避免页边界上并列的日志被跳过
+	Order       string `json:"o"` // ASC / DESC
+}
+
+// EncodeLogCursor 以日志的排序键生成游标，rowHash 为该日志的 cityHash64(raw_log)
+func EncodeLogCursor(entry models.DNSLog, rowHash uint64, order string) string {
+	data, _ := json.Marshal(LogCursor{
+		TimestampMs: entry.Timestamp.UnixMilli(),
+		NodeID:      entry.NodeID,
+		ClientIP:    entry.ClientIP,
+		Domain:      entry.Domain,
+		QueryType:   entry.QueryType,
+		RowHash:     rowHash,
+		Order:       order,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeLogCursor 解析游标
+func DecodeLogCursor(cursor string) (*LogCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("无效的游标")
+	}
+	var position LogCursor
+	if err := json.Unmarshal(data, &position); err != nil {
+		return nil, fmt.Errorf("无效的游标")
+	}
+	if position.Order != "ASC" && position.Order != "DESC" {
+		return nil, fmt.Errorf("无效的游标")
+	}
+	return &position, nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"smartdns-manager/models"
+)
+
+// DNSLogFilterParams 日志查询支持的过滤参数名
+var DNSLogFilterParams = []string{
+	"node_id", "client_ip", "group", "upstream", "rcode", "cache_hit", "matched_rule", "domain_category",
+	"client_subnet", "client_country", "client_asn", "client_ptr", "domain", "query_type", "type", "start_time", "end_time",
+}
+
+// queryTypeParam 查询类型过滤参数，type 为 query_type 的简写
+func queryTypeParam(query func(string) string) string {
+	if value := query("query_type"); value != "" {
+		return value
+	}
+	return query("type")
+}
+
+// ValidateDNSLogFilters 检查无效时需要明确报错的过滤参数；其余参数无效时由 ParseDNSLogFilters 忽略
+func ValidateDNSLogFilters(query func(string) string) error {
+	if value := queryTypeParam(query); value != "" {
+		if _, err := models.ParseQueryTypes(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseDNSLogFilters 从查询参数解析日志过滤条件，结果用于 LogMonitorInterface.GetLogs
+func ParseDNSLogFilters(query func(string) string) map[string]interface{} {
+	filters := make(map[string]interface{})
+
+	// 节点ID
+	if nodeIDStr := query("node_id"); nodeIDStr != "" {
+		if nodeID, err := strconv.ParseUint(nodeIDStr, 10, 32); err == nil {
+			filters["node_id"] = uint(nodeID)
+		}
+	}
+
+	// 客户端IP
+	if clientIP := query("client_ip"); clientIP != "" {
+		filters["client_ip"] = clientIP
+	}
+
+	if group := query("group"); group != "" {
+		filters["group"] = group
+	}
+
+	// 应答的上游服务器
+	if upstream := query("upstream"); upstream != "" {
+		filters["upstream"] = upstream
+	}
+
+	// 响应码、是否命中缓存、命中的规则
+	if rcode := query("rcode"); rcode != "" {
+		filters["rcode"] = rcode
+	}
+	if cacheHitStr := query("cache_hit"); cacheHitStr != "" {
+		if cacheHit, err := strconv.ParseBool(cacheHitStr); err == nil {
+			filters["cache_hit"] = cacheHit
+		}
+	}
+	if rule := query("matched_rule"); rule != "" {
+		filters["matched_rule"] = rule
+	}
+
+	// 域名分类
+	if category := query("domain_category"); category != "" {
+		filters["domain_category"] = category
+	}
+
+	// 客户端富化信息
+	if subnet := query("client_subnet"); subnet != "" {
+		filters["client_subnet"] = subnet
+	}
+	if country := query("client_country"); country != "" {
+		filters["client_country"] = country
+	}
+	if asnStr := query("client_asn"); asnStr != "" {
+		if asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(asnStr), "AS"), 10, 32); err == nil {
+			filters["client_asn"] = uint32(asn)
+		}
+	}
+	if ptr := query("client_ptr"); ptr != "" {
+		filters["client_ptr"] = ptr
+	}
+
+	// 域名
+	if domain := query("domain"); domain != "" {
+		filters["domain"] = domain
+	}
+
+	// 查询类型，支持编号和名称的列表，如 1,28 或 A,AAAA
+	if queryTypeStr := queryTypeParam(query); queryTypeStr != "" {
+		if queryTypes, err := models.ParseQueryTypes(queryTypeStr); err == nil && len(queryTypes) > 0 {
+			filters["query_type"] = queryTypes
+		}
+	}
+
+	// 时间范围 - 改进时间解析
+	if startTimeStr := query("start_time"); startTimeStr != "" {
+		// 支持多种时间格式
+		formats := []string{
+			time.RFC3339,
+			"2006-01-02T15:04:05Z",
+			"2006-01-02 15:04:05",
+			"2006-01-02",
+		}
+
+		for _, format := range formats {
+			if startTime, err := time.Parse(format, startTimeStr); err == nil {
+				filters["start_time"] = startTime
+				break
+			}
+		}
+	}
+
+	if endTimeStr := query("end_time"); endTimeStr != "" {
+		formats := []string{
+			time.RFC3339,
+			"2006-01-02T15:04:05Z",
+			"2006-01-02 15:04:05",
+			"2006-01-02",
+		}
+
+		for _, format := range formats {
+			if endTime, err := time.Parse(format, endTimeStr); err == nil {
+				filters["end_time"] = endTime
+				break
+			}
+		}
+	}
+
+	return filters
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// ErrIngestBodyTooLarge 上传的日志解压后超过 LOG_INGEST_MAX_BODY_MB
+var ErrIngestBodyTooLarge = errors.New("请求体超过大小限制")
+
+// IngestMaxBodyBytes 单个上传请求解压后的最大字节数
+func IngestMaxBodyBytes() int64 {
+	mb, err := strconv.ParseInt(config.GetConfig().LogIngestMaxBodyMB, 10, 64)
+	if err != nil || mb < 1 {
+		mb = 64
+	}
+	return mb << 20
+}
+
+// limitedReader 超过上限时返回 ErrIngestBodyTooLarge，而不是像 io.LimitReader 一样静默截断
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, ErrIngestBodyTooLarge
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// DecodeIngestBody 解码 Agent 上传的日志，支持 gzip 压缩，内容可以是 JSON 数组或 NDJSON（每行一条）
+func DecodeIngestBody(body io.Reader, contentEncoding string) ([]models.DNSLogCK, error) {
+	var reader io.Reader = body
+	switch contentEncoding {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("解压请求体失败: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	default:
+		return nil, fmt.Errorf("不支持的压缩方式: %s", contentEncoding)
+	}
+
+	buffered := bufio.NewReader(&limitedReader{r: reader, remaining: IngestMaxBodyBytes()})
+	decoder := json.NewDecoder(buffered)
+
+	// 以 [ 开头时按 JSON 数组解码，否则按连续的 JSON 对象解码
+	first, err := peekNonSpace(buffered)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []models.DNSLogCK
+	if first == '[' {
+		if err := decoder.Decode(&records); err != nil {
+			return nil, fmt.Errorf("解析日志失败: %w", err)
+		}
+		return records, nil
+	}
+
+	for {
+		var record models.DNSLogCK
+		if err := decoder.Decode(&record); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("解析第 %d 条日志失败: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// peekNonSpace 跳过空白后查看第一个字符
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return b, r.UnreadByte()
+		}
+	}
+}
+
+// IngestLogs 以异步写入方式写入日志，由 ClickHouse 合并多个节点的小批次，等待落盘后返回，失败时 Agent 会重试
+func (s *LogMonitorServiceCH) IngestLogs(ctx context.Context, records []models.DNSLogCK, dedupToken string) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	settings := clickhouse.Settings{
+		"async_insert":          1,
+		"wait_for_async_insert": 1,
+	}
+	if dedupToken != "" {
+		settings["async_insert_deduplicate"] = 1
+		settings["insert_deduplication_token"] = dedupToken
+	}
+	ctx = clickhouse.Context(ctx, clickhouse.WithSettings(settings))
+
+	// group 是关键字需加引号，驱动按列名匹配时只去掉反引号
+	batch, err := s.conn.PrepareBatch(ctx, `INSERT INTO dns_query_log (timestamp, date, node_id, client_ip, domain, query_type,
+        time_ms, speed_ms, result_count, result_ips, raw_log, `+"`group`"+`,
+        domain_category, client_subnet, client_country, client_asn, client_as_org, client_ptr, upstream,
+        rcode, cache_hit, matched_rule)`)
+	if err != nil {
+		return fmt.Errorf("准备写入失败: %w", err)
+	}
+
+	for i := range records {
+		r := &records[i]
+		date := r.Date
+		if date.IsZero() {
+			date = r.Timestamp
+		}
+		ips := r.ResultIPs
+		if ips == nil {
+			ips = []string{}
+		}
+		if err := batch.Append(r.Timestamp, date, r.NodeID, r.ClientIP, r.Domain, r.QueryType,
+			r.TimeMs, r.SpeedMs, r.ResultCount, ips, r.RawLog, r.Group,
+			r.DomainCategory, r.ClientSubnet, r.ClientCountry, r.ClientASN, r.ClientASOrg, r.ClientPTR, r.Upstream,
+			r.Rcode, r.CacheHit, r.MatchedRule); err != nil {
+			batch.Abort()
+			return fmt.Errorf("写入第 %d 条日志失败: %w", i+1, err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("写入 ClickHouse 失败: %w", err)
+	}
+	return nil
+}
+
+// IngestBatchAcks 记录节点已写入的批次
+func (s *LogMonitorServiceCH) IngestBatchAcks(ctx context.Context, nodeID uint, batches []models.IngestBatch) error {
+	if len(batches) == 0 {
+		return nil
+	}
+
+	batch, err := s.conn.PrepareBatch(ctx, `INSERT INTO dns_ingest_batches
+        (node_id, file_path, stream_id, seq, start_offset, end_offset, rows, reread, sent_at)`)
+	if err != nil {
+		return fmt.Errorf("准备写入失败: %w", err)
+	}
+
+	for _, b := range batches {
+		var reread uint8
+		if b.Reread {
+			reread = 1
+		}
+		if b.SentAt.IsZero() {
+			b.SentAt = time.Now()
+		}
+		if err := batch.Append(uint32(nodeID), b.FilePath, b.StreamID, b.Seq,
+			b.StartOffset, b.EndOffset, b.Rows, reread, b.SentAt); err != nil {
+			batch.Abort()
+			return err
+		}
+	}
+	return batch.Send()
+}
+
+// DomainCategories 读取管理端同步到 ClickHouse 的域名分类表
+func (s *LogMonitorServiceCH) DomainCategories(ctx context.Context) (map[string]string, error) {
+	rows, err := s.conn.Query(ctx, "SELECT domain, category FROM domain_categories")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make(map[string]string)
+	for rows.Next() {
+		var domain, category string
+		if err := rows.Scan(&domain, &category); err != nil {
+			continue
+		}
+		categories[domain] = category
+	}
+	return categories, rows.Err()
+}
@@ -5,17 +5,32 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"smartdns-manager/config"
+	"smartdns-manager/database"
 	"smartdns-manager/models"
 
+	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 )
 
 // LogMonitorServiceCH ClickHouse 实现
 type LogMonitorServiceCH struct {
-	conn driver.Conn // 使用 ClickHouse driver.Conn
+	conn  driver.Conn // 使用 ClickHouse driver.Conn
+	cache *QueryCache // 仪表盘统计结果缓存
+}
+
+func init() {
+	RegisterLogStorageDriver(config.LogStorageClickHouse, func() (LogMonitorInterface, error) {
+		if database.CHConn == nil {
+			return nil, fmt.Errorf("ClickHouse 未连接")
+		}
+		return NewLogMonitorServiceCH(database.CHConn), nil
+	})
 }
 
 // NewLogMonitorServiceCH 创建 ClickHouse 日志监控服务
@@ -25,7 +40,8 @@ func NewLogMonitorServiceCH(conn driver.Conn) LogMonitorInterface {
 	}
 
 	service := &LogMonitorServiceCH{
-		conn: conn,
+		conn:  conn,
+		cache: NewClickHouseQueryCache(),
 	}
 
 	// 确保表存在
@@ -42,57 +58,7 @@ func (s *LogMonitorServiceCH) GetLogs(page, pageSize int, filters map[string]int
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// 构建查询条件
-	where := []string{"1=1"}
-	args := []interface{}{}
-
-	if nodeID, ok := filters["node_id"].(uint); ok {
-		where = append(where, "node_id = ?")
-		args = append(args, uint32(nodeID))
-	}
-
-	if clientIP, ok := filters["client_ip"].(string); ok && clientIP != "" {
-		where = append(where, "client_ip = ?")
-		args = append(args, clientIP)
-	}
-
-	if group, ok := filters["group"].(string); ok && group != "" {
-		where = append(where, "group = ?")
-		args = append(args, group)
-	}
-
-	if domain, ok := filters["domain"].(string); ok && domain != "" {
-		// 优化模糊查询
-		where = append(where, "domain ILIKE ?")
-		args = append(args, "%"+domain+"%")
-	}
-
-	if queryType, ok := filters["query_type"].(int); ok {
-		where = append(where, "query_type = ?")
-		args = append(args, uint16(queryType))
-	}
-
-	// 时间范围查询优化 - 确保有时间范围限制
-	hasTimeFilter := false
-	if startTime, ok := filters["start_time"].(time.Time); ok {
-		where = append(where, "timestamp >= ?")
-		args = append(args, startTime)
-		hasTimeFilter = true
-	}
-
-	if endTime, ok := filters["end_time"].(time.Time); ok {
-		where = append(where, "timestamp <= ?")
-		args = append(args, endTime)
-		hasTimeFilter = true
-	}
-
-	// 如果没有时间过滤条件，默认查询最近24小时
-	if !hasTimeFilter {
-		where = append(where, "timestamp >= ?")
-		args = append(args, time.Now().Add(-24*time.Hour))
-	}
-
-	whereClause := strings.Join(where, " AND ")
+	whereClause, args := buildCHLogWhere(filters)
 
 	// 使用估算总数来提高性能（对于大数据集）
 	var total uint64
@@ -145,24 +111,13 @@ func (s *LogMonitorServiceCH) GetLogs(page, pageSize int, filters map[string]int
 	offset := (page - 1) * pageSize
 
 	dataQuery := fmt.Sprintf(`
-	       SELECT
-	           timestamp,
-	           node_id,
-	           client_ip,
-	           domain,
-	           query_type,
-	           time_ms,
-	           speed_ms,
-	           result_count,
-	           result_ips,
-	           raw_log,
-	           group
+	       SELECT%s
 	       FROM dns_query_log
 	       WHERE %s
 	       ORDER BY %s %s
 	       LIMIT %d OFFSET %d
 	       SETTINGS max_execution_time = 25
-	   `, whereClause, sortField, sortOrder, pageSize, offset)
+	   `, chLogColumns, whereClause, sortField, sortOrder, pageSize, offset)
 
 	rows, err := s.conn.Query(ctx, dataQuery, args...)
 	if err != nil {
@@ -171,13 +126,149 @@ func (s *LogMonitorServiceCH) GetLogs(page, pageSize int, filters map[string]int
 	}
 	defer rows.Close()
 
-	var logs []models.DNSLog
+	logs, err := scanCHLogRows(rows, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	log.Printf("✅ 成功查询 %d 条日志，总数: %d", len(logs), total)
+	return logs, int64(total), nil
+}
+
+// buildCHLogWhere 把日志过滤条件转换为 WHERE 子句，GetLogs 和游标翻页共用
+func buildCHLogWhere(filters map[string]interface{}) (string, []interface{}) {
+	where := []string{"1=1"}
+	args := []interface{}{}
 
+	if nodeID, ok := filters["node_id"].(uint); ok {
+		where = append(where, "node_id = ?")
+		args = append(args, uint32(nodeID))
+	}
+
+	if clientIP, ok := filters["client_ip"].(string); ok && clientIP != "" {
+		where = append(where, "client_ip = ?")
+		args = append(args, clientIP)
+	}
+
+	if group, ok := filters["group"].(string); ok && group != "" {
+		where = append(where, "`group` = ?")
+		args = append(args, group)
+	}
+
+	if upstream, ok := filters["upstream"].(string); ok && upstream != "" {
+		where = append(where, "upstream = ?")
+		args = append(args, upstream)
+	}
+
+	if rcode, ok := filters["rcode"].(string); ok && rcode != "" {
+		where = append(where, "rcode = ?")
+		args = append(args, strings.ToUpper(rcode))
+	}
+
+	if cacheHit, ok := filters["cache_hit"].(bool); ok {
+		if cacheHit {
+			where = append(where, "cache_hit = 1")
+		} else {
+			where = append(where, "cache_hit = 0")
+		}
+	}
+
+	if rule, ok := filters["matched_rule"].(string); ok && rule != "" {
+		where = append(where, "matched_rule = ?")
+		args = append(args, rule)
+	}
+
+	if category, ok := filters["domain_category"].(string); ok && category != "" {
+		where = append(where, "domain_category = ?")
+		args = append(args, category)
+	}
+
+	if subnet, ok := filters["client_subnet"].(string); ok && subnet != "" {
+		where = append(where, "client_subnet = ?")
+		args = append(args, subnet)
+	}
+
+	if country, ok := filters["client_country"].(string); ok && country != "" {
+		where = append(where, "client_country = ?")
+		args = append(args, strings.ToUpper(country))
+	}
+
+	if asn, ok := filters["client_asn"].(uint32); ok {
+		where = append(where, "client_asn = ?")
+		args = append(args, asn)
+	}
+
+	if ptr, ok := filters["client_ptr"].(string); ok && ptr != "" {
+		where = append(where, "client_ptr ILIKE ?")
+		args = append(args, "%"+ptr+"%")
+	}
+
+	if domain, ok := filters["domain"].(string); ok && domain != "" {
+		// 优化模糊查询
+		where = append(where, "domain ILIKE ?")
+		args = append(args, "%"+domain+"%")
+	}
+
+	if queryTypes, ok := filters["query_type"].([]uint16); ok && len(queryTypes) > 0 {
+		where = append(where, "query_type IN (?)")
+		args = append(args, queryTypes)
+	}
+
+	// 时间范围查询优化 - 确保有时间范围限制
+	hasTimeFilter := false
+	if startTime, ok := filters["start_time"].(time.Time); ok {
+		where = append(where, "timestamp >= ?")
+		args = append(args, startTime)
+		hasTimeFilter = true
+	}
+
+	if endTime, ok := filters["end_time"].(time.Time); ok {
+		where = append(where, "timestamp <= ?")
+		args = append(args, endTime)
+		hasTimeFilter = true
+	}
+
+	// 如果没有时间过滤条件，默认查询最近24小时
+	if !hasTimeFilter {
+		where = append(where, "timestamp >= ?")
+		args = append(args, time.Now().Add(-24*time.Hour))
+	}
+
+	return strings.Join(where, " AND "), args
+}
+
+// chLogColumns 日志查询的列，与 scanCHLogRows 的扫描顺序一致；group 是关键字，需加引号
+const chLogColumns = `
+	           timestamp,
+	           node_id,
+	           client_ip,
+	           domain,
+	           query_type,
+	           time_ms,
+	           speed_ms,
+	           result_count,
+	           result_ips,
+	           raw_log,
+	           "group",
+	           domain_category,
+	           client_subnet,
+	           client_country,
+	           client_asn,
+	           client_as_org,
+	           client_ptr,
+	           upstream,
+	           rcode,
+	           cache_hit,
+	           matched_rule`
+
+// scanCHLogRows 读取日志查询结果并转换为通用格式
+func scanCHLogRows(rows driver.Rows, capacity int) ([]models.DNSLog, error) {
 	// 预分配切片容量
-	logs = make([]models.DNSLog, 0, pageSize)
+	logs := make([]models.DNSLog, 0, capacity)
 
 	for rows.Next() {
 		var logCK models.DNSLogCK
+		var cacheHit uint8
 		err := rows.Scan(
 			&logCK.Timestamp,
 			&logCK.NodeID,
@@ -190,6 +281,16 @@ func (s *LogMonitorServiceCH) GetLogs(page, pageSize int, filters map[string]int
 			&logCK.ResultIPs,
 			&logCK.RawLog,
 			&logCK.Group,
+			&logCK.DomainCategory,
+			&logCK.ClientSubnet,
+			&logCK.ClientCountry,
+			&logCK.ClientASN,
+			&logCK.ClientASOrg,
+			&logCK.ClientPTR,
+			&logCK.Upstream,
+			&logCK.Rcode,
+			&cacheHit,
+			&logCK.MatchedRule,
 		)
 		if err != nil {
 			log.Printf("⚠️ 扫描行失败: %v", err)
@@ -210,6 +311,21 @@ func (s *LogMonitorServiceCH) GetLogs(page, pageSize int, filters map[string]int
 			IPCount:   int(logCK.ResultCount),
 			RawLog:    logCK.RawLog,
 			Group:     logCK.Group,
+			Upstream:  logCK.Upstream,
+
+			Rcode:       logCK.Rcode,
+			CacheHit:    cacheHit == 1,
+			MatchedRule: logCK.MatchedRule,
+
+			QueryTypeName: models.QueryTypeName(logCK.QueryType),
+
+			DomainCategory: logCK.DomainCategory,
+
+			ClientSubnet:  logCK.ClientSubnet,
+			ClientCountry: logCK.ClientCountry,
+			ClientASN:     logCK.ClientASN,
+			ClientASOrg:   logCK.ClientASOrg,
+			ClientPTR:     logCK.ClientPTR,
 		}
 		logs = append(logs, logEntry)
 	}
@@ -217,20 +333,100 @@ func (s *LogMonitorServiceCH) GetLogs(page, pageSize int, filters map[string]int
 	// 检查是否有行扫描错误
 	if rows.Err() != nil {
 		log.Printf("❌ 行迭代错误: %v", rows.Err())
-		return nil, 0, rows.Err()
+		return nil, rows.Err()
 	}
 
-	log.Printf("✅ 成功查询 %d 条日志，总数: %d", len(logs), total)
-	return logs, int64(total), nil
+	return logs, nil
 }
 
-// GetStats 获取统计信息（实现接口）
+// GetLogsByCursor 基于游标（keyset）翻页查询日志（实现 LogCursorPager）
+//
+// 按 (timestamp, node_id, client_ip, domain, query_type, cityHash64(raw_log)) 排序，下一页从上一页最后一条之后开始，
+// 不使用 OFFSET，深翻页也只扫描需要的数据。同一毫秒内同一客户端的重复查询前五列相同，
+// 以原始日志的哈希作为最后的排序键，保证排序键唯一，页边界上的并列日志不会被跳过。
+func (s *LogMonitorServiceCH) GetLogsByCursor(cursor string, pageSize int, filters map[string]interface{}) ([]models.DNSLog, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	sortOrder := "DESC"
+	if order, ok := filters["sort_order"].(string); ok && strings.ToUpper(order) == "ASC" {
+		sortOrder = "ASC"
+	}
+
+	whereClause, args := buildCHLogWhere(filters)
+	if cursor != "" {
+		position, err := DecodeLogCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		// 游标中记录了首次查询时的排序方向，翻页过程中保持不变
+		sortOrder = position.Order
+		op := "<"
+		if sortOrder == "ASC" {
+			op = ">"
+		}
+		whereClause += fmt.Sprintf(" AND (timestamp, node_id, client_ip, domain, query_type, cityHash64(raw_log)) %s (fromUnixTimestamp64Milli(?), ?, ?, ?, ?, ?)", op)
+		args = append(args, position.TimestampMs, uint32(position.NodeID), position.ClientIP, position.Domain, uint16(position.QueryType), position.RowHash)
+	}
+
+	// 多取一条判断是否还有下一页
+	dataQuery := fmt.Sprintf(`
+	       SELECT%s
+	       FROM dns_query_log
+	       WHERE %s
+	       ORDER BY timestamp %[3]s, node_id %[3]s, client_ip %[3]s, domain %[3]s, query_type %[3]s, cityHash64(raw_log) %[3]s
+	       LIMIT %d
+	       SETTINGS max_execution_time = 25
+	   `, chLogColumns, whereClause, sortOrder, pageSize+1)
+
+	rows, err := s.conn.Query(ctx, dataQuery, args...)
+	if err != nil {
+		log.Printf("❌ 查询数据失败: %v", err)
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	logs, err := scanCHLogRows(rows, pageSize+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(logs) <= pageSize {
+		return logs, "", nil
+	}
+	logs = logs[:pageSize]
+
+	// 哈希由 ClickHouse 计算，与翻页条件中的 cityHash64(raw_log) 一致
+	var rowHash uint64
+	if err := s.conn.QueryRow(ctx, "SELECT cityHash64(?)", logs[pageSize-1].RawLog).Scan(&rowHash); err != nil {
+		return nil, "", fmt.Errorf("生成游标失败: %w", err)
+	}
+	return logs, EncodeLogCursor(logs[pageSize-1], rowHash, sortOrder), nil
+}
+
+// GetStats 获取统计信息（实现接口），结果按节点和时间范围短时缓存
 func (s *LogMonitorServiceCH) GetStats(nodeID uint, startTime, endTime time.Time) (*models.DNSLogStats, error) {
+	value, err := s.cache.Get(s.cache.Key("stats", nodeID, startTime, endTime), func() (interface{}, error) {
+		return s.loadStats(nodeID, startTime, endTime)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 返回副本，调用方修改字段不影响缓存
+	stats := *value.(*models.DNSLogStats)
+	return &stats, nil
+}
+
+// loadStats 从 ClickHouse 查询统计信息
+func (s *LogMonitorServiceCH) loadStats(nodeID uint, startTime, endTime time.Time) (*models.DNSLogStats, error) {
 	ctx := context.Background()
 	stats := &models.DNSLogStats{
-		TopDomains:  make([]models.DomainStat, 0),
-		TopClients:  make([]models.ClientStat, 0),
-		HourlyStats: make([]models.HourlyStat, 0),
+		TopDomains:         make([]models.DomainStat, 0),
+		TopClients:         make([]models.ClientStat, 0),
+		HourlyStats:        make([]models.HourlyStat, 0),
+		CategoryStats:      make([]models.CategoryStat, 0),
+		DailyCategoryStats: make([]models.DailyCategoryStat, 0),
+		RcodeStats:         make([]models.RcodeStat, 0),
 	}
 
 	// 构建查询条件
@@ -324,6 +520,75 @@ func (s *LogMonitorServiceCH) GetStats(nodeID uint, startTime, endTime time.Time
 		rows.Close()
 	}
 
+	// 按域名分类统计
+	rows, err = s.conn.Query(ctx,
+		fmt.Sprintf("SELECT domain_category, count() as count FROM dns_query_log WHERE %s AND domain_category != '' GROUP BY domain_category ORDER BY count DESC", where),
+		args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.CategoryStat
+			var count uint64
+			rows.Scan(&stat.Category, &count)
+			stat.Count = int64(count)
+			stats.CategoryStats = append(stats.CategoryStats, stat)
+		}
+		rows.Close()
+	}
+
+	// 按天的域名分类统计（如每天拦截的广告数）
+	rows, err = s.conn.Query(ctx,
+		fmt.Sprintf("SELECT toString(toDate(timestamp)) as day, domain_category, count() as count FROM dns_query_log WHERE %s AND domain_category != '' GROUP BY day, domain_category ORDER BY day, domain_category", where),
+		args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.DailyCategoryStat
+			var count uint64
+			rows.Scan(&stat.Date, &stat.Category, &count)
+			stat.Count = int64(count)
+			stats.DailyCategoryStats = append(stats.DailyCategoryStats, stat)
+		}
+		rows.Close()
+	}
+
+	// 按分组统计
+	rows, err = s.conn.Query(ctx,
+		fmt.Sprintf("SELECT `group`, count() as count, avg(time_ms) FROM dns_query_log WHERE %s GROUP BY `group` ORDER BY count DESC LIMIT 20", where),
+		args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.GroupStat
+			var count uint64
+			rows.Scan(&stat.Group, &count, &stat.AvgTimeMs)
+			stat.Count = int64(count)
+			stats.GroupStats = append(stats.GroupStats, stat)
+		}
+		rows.Close()
+	}
+
+	// 按响应码统计
+	rows, err = s.conn.Query(ctx,
+		fmt.Sprintf("SELECT rcode, count() as count FROM dns_query_log WHERE %s GROUP BY rcode ORDER BY count DESC", where),
+		args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.RcodeStat
+			var count uint64
+			rows.Scan(&stat.Rcode, &count)
+			stat.Count = int64(count)
+			stats.RcodeStats = append(stats.RcodeStats, stat)
+		}
+		rows.Close()
+	}
+
+	// NXDOMAIN 占比和缓存命中率
+	var nxdomain, cacheHits uint64
+	if err := s.conn.QueryRow(ctx,
+		fmt.Sprintf("SELECT countIf(%s), countIf(cache_hit = 1) FROM dns_query_log WHERE %s", nxdomainCondition, where),
+		args...).Scan(&nxdomain, &cacheHits); err == nil {
+		stats.NXDomainRate = float64(nxdomain) / float64(totalQueries)
+		stats.CacheHitRatio = float64(cacheHits) / float64(totalQueries)
+	}
+
 	return stats, nil
 }
 
@@ -369,10 +634,173 @@ func (s *LogMonitorServiceCH) CleanOldLogs(nodeID uint, days int) error {
 		return err
 	}
 
+	s.cache.Purge()
 	log.Printf("✅ 清理完成，删除 %d 天前的日志", days)
 	return nil
 }
 
+// CountOldLogs 统计将被清理的日志条数（实现 LogCleanupEstimator）
+func (s *LogMonitorServiceCH) CountOldLogs(nodeID uint, days int) (int64, error) {
+	ctx := context.Background()
+	cutoffTime := time.Now().AddDate(0, 0, -days)
+
+	where := "timestamp < ?"
+	args := []interface{}{cutoffTime}
+
+	if nodeID > 0 {
+		where += " AND node_id = ?"
+		args = append(args, uint32(nodeID))
+	}
+
+	var count uint64
+	if err := s.conn.QueryRow(ctx, fmt.Sprintf("SELECT count() FROM dns_query_log WHERE %s", where), args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return int64(count), nil
+}
+
+// chRetentionTTLPattern 匹配 system.tables.engine_full 中按日期的表 TTL
+var chRetentionTTLPattern = regexp.MustCompile(`TTL date \+ toIntervalDay\((\d+)\)`)
+
+// ApplyRetention 按节点保留天数清理日志（实现 LogRetentionApplier）
+//
+// 表 TTL 调整为最长的保留天数，整个分区都超过该天数时直接删除分区；
+// 保留天数更短的节点按分组执行 ALTER TABLE ... DELETE，没有过期日志的分组不提交 mutation
+func (s *LogMonitorServiceCH) ApplyRetention(ctx context.Context, policy LogRetentionPolicy) (string, error) {
+	maxDays := policy.MaxDays()
+	var results []string
+
+	ttlResult, err := s.syncRetentionTTL(ctx, maxDays)
+	if err != nil {
+		return "", err
+	}
+	results = append(results, ttlResult)
+
+	dropped, err := s.dropExpiredPartitions(ctx, maxDays)
+	if err != nil {
+		return strings.Join(results, "\n"), err
+	}
+	if dropped > 0 {
+		results = append(results, fmt.Sprintf("删除 %d 个超过 %d 天的分区", dropped, maxDays))
+	}
+
+	var failed int
+	for _, group := range policy.Groups() {
+		// 最长保留天数的日志由 TTL 和分区删除清理
+		if group.Days >= maxDays {
+			continue
+		}
+
+		where := "date < ?"
+		args := []interface{}{time.Now().AddDate(0, 0, -group.Days).Format("2006-01-02")}
+		nodeIDs := make([]uint32, len(group.NodeIDs))
+		for i, id := range group.NodeIDs {
+			nodeIDs[i] = uint32(id)
+		}
+		if !group.Default {
+			where += " AND has(?, node_id)"
+			args = append(args, nodeIDs)
+		} else if len(nodeIDs) > 0 {
+			where += " AND NOT has(?, node_id)"
+			args = append(args, nodeIDs)
+		}
+
+		var count uint64
+		if err := s.conn.QueryRow(ctx, "SELECT count() FROM dns_query_log WHERE "+where, args...).Scan(&count); err != nil {
+			failed++
+			results = append(results, fmt.Sprintf("%s: 统计过期日志失败 %v", group, err))
+			continue
+		}
+		if count == 0 {
+			results = append(results, fmt.Sprintf("%s: 没有过期日志", group))
+			continue
+		}
+		if err := s.conn.Exec(ctx, "ALTER TABLE dns_query_log DELETE WHERE "+where, args...); err != nil {
+			failed++
+			log.Printf("❌ 清理 %s 的过期日志失败: %v", group, err)
+			results = append(results, fmt.Sprintf("%s: 删除失败 %v", group, err))
+			continue
+		}
+		results = append(results, fmt.Sprintf("%s: 已提交删除 %d 条过期日志", group, count))
+	}
+
+	s.cache.Purge()
+	output := strings.Join(results, "\n")
+	if failed > 0 {
+		return output, fmt.Errorf("%d 组节点的过期日志清理失败", failed)
+	}
+	return output, nil
+}
+
+// syncRetentionTTL 表 TTL 与最长保留天数不一致时修改 TTL
+//
+// 修改时不物化 TTL，避免重写所有分区；过期数据在后台合并时或由分区删除清理
+func (s *LogMonitorServiceCH) syncRetentionTTL(ctx context.Context, days int) (string, error) {
+	var engine string
+	if err := s.conn.QueryRow(ctx,
+		"SELECT engine_full FROM system.tables WHERE database = currentDatabase() AND name = 'dns_query_log'").Scan(&engine); err != nil {
+		return "", fmt.Errorf("查询表 TTL 失败: %w", err)
+	}
+	if match := chRetentionTTLPattern.FindStringSubmatch(engine); match != nil && match[1] == strconv.Itoa(days) {
+		return fmt.Sprintf("表 TTL 为 %d 天，无需调整", days), nil
+	}
+
+	ttlCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"materialize_ttl_after_modify": 0,
+	}))
+	if err := s.conn.Exec(ttlCtx, fmt.Sprintf("ALTER TABLE dns_query_log MODIFY TTL date + INTERVAL %d DAY", days)); err != nil {
+		return "", fmt.Errorf("修改表 TTL 失败: %w", err)
+	}
+	log.Printf("✅ dns_query_log 表 TTL 已调整为 %d 天", days)
+	return fmt.Sprintf("表 TTL 调整为 %d 天", days), nil
+}
+
+// dropExpiredPartitions 删除所有日志都超过 days 天的分区，返回删除的分区数
+func (s *LogMonitorServiceCH) dropExpiredPartitions(ctx context.Context, days int) (int, error) {
+	// 分区键不含日期时 min_date/max_date 为 1970-01-01，排除这种情况以免误删
+	rows, err := s.conn.Query(ctx, `
+        SELECT partition_id FROM system.parts
+        WHERE database = currentDatabase() AND table = 'dns_query_log' AND active
+        GROUP BY partition_id
+        HAVING max(max_date) < ? AND min(min_date) > '1970-01-01'`,
+		time.Now().AddDate(0, 0, -days).Format("2006-01-02"))
+	if err != nil {
+		return 0, fmt.Errorf("查询过期分区失败: %w", err)
+	}
+	var partitions []string
+	for rows.Next() {
+		var partition string
+		if err := rows.Scan(&partition); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		partitions = append(partitions, partition)
+	}
+	rows.Close()
+
+	for i, partition := range partitions {
+		if err := s.conn.Exec(ctx, fmt.Sprintf("ALTER TABLE dns_query_log DROP PARTITION ID '%s'", partition)); err != nil {
+			return i, fmt.Errorf("删除分区 %s 失败: %w", partition, err)
+		}
+		log.Printf("🗑️ 删除过期分区 dns_query_log %s", partition)
+	}
+	return len(partitions), nil
+}
+
+// GetCacheHitCounts 统计节点的查询总数和缓存命中数（实现 CacheHitRateProvider）
+func (s *LogMonitorServiceCH) GetCacheHitCounts(nodeID uint, startTime, endTime time.Time) (int64, int64, error) {
+	ctx := context.Background()
+
+	var total, hits uint64
+	err := s.conn.QueryRow(ctx,
+		"SELECT count(), countIf(cache_hit = 1) FROM dns_query_log WHERE timestamp BETWEEN ? AND ? AND node_id = ?",
+		startTime, endTime, uint32(nodeID)).Scan(&total, &hits)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(total), int64(hits), nil
+}
+
 // CheckHealth 检查服务健康状态（实现接口）
 func (s *LogMonitorServiceCH) CheckHealth() error {
 	ctx := context.Background()
@@ -381,22 +809,35 @@ func (s *LogMonitorServiceCH) CheckHealth() error {
 
 // GetStorageType 获取存储类型（实现接口）
 func (s *LogMonitorServiceCH) GetStorageType() string {
-	return "clickhouse"
+	return config.LogStorageClickHouse
 }
 
 // GetStorageInfo 获取存储信息（实现接口）
 func (s *LogMonitorServiceCH) GetStorageInfo() map[string]interface{} {
 	info := map[string]interface{}{
-		"type":      "clickhouse",
+		"type":      config.LogStorageClickHouse,
 		"connected": false,
 		"host":      os.Getenv("CLICKHOUSE_HOST"),
 		"database":  os.Getenv("CLICKHOUSE_DB"),
 	}
+	cfg := config.GetClickHouseConfig()
+	info["port"] = cfg.Port
+	info["protocol"] = cfg.Protocol
+	info["secure"] = cfg.Secure
+	info["query_cache"] = map[string]interface{}{
+		"ttl_seconds":   cfg.QueryCacheTTL,
+		"stale_seconds": cfg.QueryCacheStale,
+		"entries":       s.cache.Len(),
+	}
 
 	// 检查连接
 	if err := s.CheckHealth(); err == nil {
 		info["connected"] = true
 	}
+	if report := database.LastSchemaReport(); report != nil {
+		info["schema"] = report
+	}
+	info["migrations"] = database.MigrationReports()
 
 	return info
 }
@@ -417,14 +858,27 @@ func (s *LogMonitorServiceCH) EnsureTables() error {
         speed_ms Float32,
         result_count UInt8,
         result_ips Array(String),
-        raw_log String
+        raw_log String,
+        "group" String DEFAULT '' COMMENT '所属组'
     ) ENGINE = MergeTree()
     PARTITION BY date
     ORDER BY (node_id, timestamp)
     TTL date + INTERVAL 30 DAY
     SETTINGS index_granularity = 8192`
 
-	return s.conn.Exec(ctx, createTableSQL)
+	if err := s.conn.Exec(ctx, createTableSQL); err != nil {
+		return err
+	}
+
+	// 按实际表结构补齐缺少的列，旧表升级后查询 group 等字段不会报错
+	report, err := database.ReconcileLogSchema(ctx, s.conn)
+	if err != nil {
+		return fmt.Errorf("表结构校对失败: %w", err)
+	}
+	if len(report.Added) > 0 {
+		log.Printf("✅ dns_query_log 表结构校对完成，已补充列: %v", report.Added)
+	}
+	return nil
 }
 
 // GetTableStats 获取表统计信息（实现接口）
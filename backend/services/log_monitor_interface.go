@@ -1,25 +1,111 @@
 package services
 
 import (
-	"smartdns-manager/database"
-	"smartdns-manager/models"
+	"context"
+	"fmt"
+	"log"
+	"sort"
 	"time"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
 )
 
-// LogMonitorInterface 日志监控服务接口
+// LogMonitorInterface DNS 日志存储驱动接口
+//
+// 日志由各节点的 Agent 直接写入存储，驱动只负责建表、查询和维护。
+// 新增存储时实现该接口并通过 RegisterLogStorageDriver 注册，
+// 由 LOG_STORAGE_TYPE 环境变量选择使用的驱动。
 type LogMonitorInterface interface {
+	// GetLogs 分页查询日志，filters 支持的键：
+	//   node_id(uint)、client_ip、group、upstream、domain_category、client_subnet、client_country、
+	//   client_ptr(模糊)、domain(模糊)、client_asn(uint32)、query_type(int)、
+	//   start_time/end_time(time.Time，均未指定时默认最近24小时)、
+	//   sort_field(timestamp/time_ms/speed_ms/domain/client_ip)、sort_order(asc/desc)
+	// 返回当前页日志和符合条件的总数
 	GetLogs(page, pageSize int, filters map[string]interface{}) ([]models.DNSLog, int64, error)
+	// GetStats 统计时间范围内的查询情况，nodeID 为 0 时统计所有节点；
+	// 无数据时返回各列表为空（非 nil）的统计
 	GetStats(nodeID uint, startTime, endTime time.Time) (*models.DNSLogStats, error)
+	// SearchDomains 按关键字模糊搜索出现过的域名
 	SearchDomains(keyword string, limit int) ([]string, error)
+	// CleanOldLogs 删除 days 天前的日志，nodeID 为 0 时清理所有节点
 	CleanOldLogs(nodeID uint, days int) error
+	// CheckHealth 检查存储连接是否可用
 	CheckHealth() error
+	// GetStorageType 驱动名称，与注册名一致
 	GetStorageType() string
+	// GetStorageInfo 存储连接信息，至少包含 type 和 connected
 	GetStorageInfo() map[string]interface{}
+	// EnsureTables 创建日志表，需可重复执行
 	EnsureTables() error
+	// GetTableStats 日志表统计，至少包含 total_records 和 table_size_bytes
 	GetTableStats() (map[string]interface{}, error)
 }
 
-// NewLogMonitorService 创建日志监控服务工厂函数
+// CacheHitRateProvider 可选接口，驱动实现后可根据日志估算节点的缓存命中率
+type CacheHitRateProvider interface {
+	// GetCacheHitCounts 返回时间范围内节点的查询总数和命中缓存（cache_hit）的查询数
+	GetCacheHitCounts(nodeID uint, startTime, endTime time.Time) (total, hits int64, err error)
+}
+
+// LogCleanupEstimator 可选接口，驱动实现后清理日志前可预估将删除的日志条数
+type LogCleanupEstimator interface {
+	// CountOldLogs 统计 days 天前的日志条数，nodeID 为 0 时统计所有节点
+	CountOldLogs(nodeID uint, days int) (int64, error)
+}
+
+// LogCursorPager 可选接口，驱动实现后支持基于游标的翻页，深翻页时不使用 OFFSET
+type LogCursorPager interface {
+	// GetLogsByCursor 读取 cursor 之后的 pageSize 条日志，cursor 为空表示第一页；
+	// filters 与 GetLogs 相同（只按时间排序，忽略 sort_field），没有更多数据时 nextCursor 为空
+	GetLogsByCursor(cursor string, pageSize int, filters map[string]interface{}) (logs []models.DNSLog, nextCursor string, err error)
+}
+
+// LogIngester 可选接口，驱动实现后 Agent 可以经管理端的 HTTP 接收端写入日志，节点无需直接访问日志存储
+type LogIngester interface {
+	// IngestLogs 写入一批日志，dedupToken 相同的批次只写入一次（为空时不去重）
+	IngestLogs(ctx context.Context, records []models.DNSLogCK, dedupToken string) error
+	// IngestBatchAcks 记录节点已写入的批次，供检测缺失和乱序
+	IngestBatchAcks(ctx context.Context, nodeID uint, batches []models.IngestBatch) error
+	// DomainCategories 读取域名分类表，供 Agent 写入前为日志打标
+	DomainCategories(ctx context.Context) (map[string]string, error)
+}
+
+// LogStorageDriverFactory 创建日志存储驱动，连接不可用时返回错误
+type LogStorageDriverFactory func() (LogMonitorInterface, error)
+
+var logStorageDrivers = make(map[string]LogStorageDriverFactory)
+
+// RegisterLogStorageDriver 注册日志存储驱动，通常在驱动文件的 init 中调用
+func RegisterLogStorageDriver(name string, factory LogStorageDriverFactory) {
+	if _, exists := logStorageDrivers[name]; exists {
+		panic(fmt.Sprintf("日志存储驱动重复注册: %s", name))
+	}
+	logStorageDrivers[name] = factory
+}
+
+// LogStorageDrivers 已注册的驱动名称
+func LogStorageDrivers() []string {
+	names := make([]string, 0, len(logStorageDrivers))
+	for name := range logStorageDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewLogMonitorService 按配置创建日志存储驱动
 func NewLogMonitorService() LogMonitorInterface {
-	return NewLogMonitorServiceCH(database.CHConn)
+	storageType := config.GetLogStorageType()
+	factory, ok := logStorageDrivers[storageType]
+	if !ok {
+		log.Fatalf("❌ 不支持的日志存储类型: %s（可用: %v）", storageType, LogStorageDrivers())
+	}
+
+	service, err := factory()
+	if err != nil {
+		log.Fatalf("❌ 初始化日志存储 %s 失败: %v", storageType, err)
+	}
+	return service
 }
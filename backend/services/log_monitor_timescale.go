@@ -0,0 +1,631 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// LogMonitorServiceTS PostgreSQL / TimescaleDB 实现
+// 未安装 timescaledb 扩展时退化为普通 PostgreSQL 表，过期日志由清理任务删除
+type LogMonitorServiceTS struct {
+	pool          *pgxpool.Pool
+	cfg           *config.TimescaleConfig
+	hypertable    bool
+	retentionDays int
+}
+
+func init() {
+	RegisterLogStorageDriver(config.LogStorageTimescale, func() (LogMonitorInterface, error) {
+		if database.PGLogConn == nil {
+			return nil, fmt.Errorf("PostgreSQL 未连接")
+		}
+		return NewLogMonitorServiceTS(database.PGLogConn, config.GetTimescaleConfig()), nil
+	})
+}
+
+// NewLogMonitorServiceTS 创建 TimescaleDB 日志监控服务
+func NewLogMonitorServiceTS(pool *pgxpool.Pool, cfg *config.TimescaleConfig) LogMonitorInterface {
+	service := &LogMonitorServiceTS{
+		pool:          pool,
+		cfg:           cfg,
+		retentionDays: cfg.RetentionDays,
+	}
+
+	// 确保表存在
+	if err := service.EnsureTables(); err != nil {
+		log.Printf("❌ 初始化表失败: %v", err)
+	}
+
+	log.Printf("✅ TimescaleDB 日志监控服务初始化成功（hypertable: %v）", service.hypertable)
+	return service
+}
+
+// tsWhere 拼接 PostgreSQL 占位符形式的查询条件
+type tsWhere struct {
+	conds []string
+	args  []interface{}
+}
+
+func (w *tsWhere) add(cond string, arg interface{}) {
+	w.args = append(w.args, arg)
+	w.conds = append(w.conds, strings.Replace(cond, "?", fmt.Sprintf("$%d", len(w.args)), 1))
+}
+
+func (w *tsWhere) String() string {
+	if len(w.conds) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(w.conds, " AND ")
+}
+
+// GetLogs 获取DNS日志列表（实现接口）
+func (s *LogMonitorServiceTS) GetLogs(page, pageSize int, filters map[string]interface{}) ([]models.DNSLog, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	where := &tsWhere{}
+
+	if nodeID, ok := filters["node_id"].(uint); ok {
+		where.add("node_id = ?", int64(nodeID))
+	}
+	if clientIP, ok := filters["client_ip"].(string); ok && clientIP != "" {
+		where.add("client_ip = ?", clientIP)
+	}
+	if group, ok := filters["group"].(string); ok && group != "" {
+		where.add(`"group" = ?`, group)
+	}
+	if upstream, ok := filters["upstream"].(string); ok && upstream != "" {
+		where.add("upstream = ?", upstream)
+	}
+	if rcode, ok := filters["rcode"].(string); ok && rcode != "" {
+		where.add("rcode = ?", strings.ToUpper(rcode))
+	}
+	if cacheHit, ok := filters["cache_hit"].(bool); ok {
+		where.add("cache_hit = ?", cacheHit)
+	}
+	if rule, ok := filters["matched_rule"].(string); ok && rule != "" {
+		where.add("matched_rule = ?", rule)
+	}
+	if category, ok := filters["domain_category"].(string); ok && category != "" {
+		where.add("domain_category = ?", category)
+	}
+	if subnet, ok := filters["client_subnet"].(string); ok && subnet != "" {
+		where.add("client_subnet = ?", subnet)
+	}
+	if country, ok := filters["client_country"].(string); ok && country != "" {
+		where.add("client_country = ?", strings.ToUpper(country))
+	}
+	if asn, ok := filters["client_asn"].(uint32); ok {
+		where.add("client_asn = ?", int64(asn))
+	}
+	if ptr, ok := filters["client_ptr"].(string); ok && ptr != "" {
+		where.add("client_ptr ILIKE ?", "%"+ptr+"%")
+	}
+	if domain, ok := filters["domain"].(string); ok && domain != "" {
+		where.add("domain ILIKE ?", "%"+domain+"%")
+	}
+	if queryTypes, ok := filters["query_type"].([]uint16); ok && len(queryTypes) > 0 {
+		values := make([]int32, len(queryTypes))
+		for i, queryType := range queryTypes {
+			values[i] = int32(queryType)
+		}
+		where.add("query_type = ANY(?)", values)
+	}
+
+	hasTimeFilter := false
+	if startTime, ok := filters["start_time"].(time.Time); ok {
+		where.add("timestamp >= ?", startTime)
+		hasTimeFilter = true
+	}
+	if endTime, ok := filters["end_time"].(time.Time); ok {
+		where.add("timestamp <= ?", endTime)
+		hasTimeFilter = true
+	}
+	// 如果没有时间过滤条件，默认查询最近24小时
+	if !hasTimeFilter {
+		where.add("timestamp >= ?", time.Now().Add(-24*time.Hour))
+	}
+
+	var total int64
+	countQuery := fmt.Sprintf("SELECT count(*) FROM dns_query_log WHERE %s", where)
+	if err := s.pool.QueryRow(ctx, countQuery, where.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	sortField := "timestamp"
+	sortOrder := "DESC"
+	if field, ok := filters["sort_field"].(string); ok {
+		fieldMap := map[string]string{
+			"timestamp": "timestamp",
+			"time_ms":   "time_ms",
+			"speed_ms":  "speed_ms",
+			"domain":    "domain",
+			"client_ip": "client_ip",
+		}
+		if dbField, exists := fieldMap[field]; exists {
+			sortField = dbField
+		}
+	}
+	if order, ok := filters["sort_order"].(string); ok && strings.ToUpper(order) == "ASC" {
+		sortOrder = "ASC"
+	}
+
+	offset := (page - 1) * pageSize
+	dataQuery := fmt.Sprintf(`
+        SELECT timestamp, node_id, client_ip, domain, query_type, time_ms, speed_ms,
+               result_count, result_ips, raw_log, "group", domain_category,
+               client_subnet, client_country, client_asn, client_as_org, client_ptr, upstream,
+               rcode, cache_hit, matched_rule
+        FROM dns_query_log
+        WHERE %s
+        ORDER BY %s %s
+        LIMIT %d OFFSET %d`, where, sortField, sortOrder, pageSize, offset)
+
+	rows, err := s.pool.Query(ctx, dataQuery, where.args...)
+	if err != nil {
+		log.Printf("❌ 查询数据失败: %v", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	logs := make([]models.DNSLog, 0, pageSize)
+	for rows.Next() {
+		var (
+			entry                      models.DNSLog
+			nodeID, clientASN          int64
+			queryType, timeMs, ipCount int32
+			speedMs                    float32
+			resultIPs                  []string
+		)
+		if err := rows.Scan(
+			&entry.Timestamp, &nodeID, &entry.ClientIP, &entry.Domain, &queryType, &timeMs, &speedMs,
+			&ipCount, &resultIPs, &entry.RawLog, &entry.Group, &entry.DomainCategory,
+			&entry.ClientSubnet, &entry.ClientCountry, &clientASN, &entry.ClientASOrg, &entry.ClientPTR,
+			&entry.Upstream, &entry.Rcode, &entry.CacheHit, &entry.MatchedRule,
+		); err != nil {
+			log.Printf("⚠️ 扫描行失败: %v", err)
+			continue
+		}
+
+		entry.NodeID = uint(nodeID)
+		entry.QueryType = int(queryType)
+		entry.QueryTypeName = models.QueryTypeName(uint16(queryType))
+		entry.TimeMs = int(timeMs)
+		entry.SpeedMs = float64(speedMs)
+		entry.IPCount = int(ipCount)
+		entry.Result = strings.Join(resultIPs, ", ")
+		entry.ResultIPs = strings.Join(resultIPs, ",")
+		entry.ClientASN = uint32(clientASN)
+		logs = append(logs, entry)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("❌ 行迭代错误: %v", err)
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// GetStats 获取统计信息（实现接口）
+func (s *LogMonitorServiceTS) GetStats(nodeID uint, startTime, endTime time.Time) (*models.DNSLogStats, error) {
+	ctx := context.Background()
+	stats := &models.DNSLogStats{
+		TopDomains:         make([]models.DomainStat, 0),
+		TopClients:         make([]models.ClientStat, 0),
+		HourlyStats:        make([]models.HourlyStat, 0),
+		CategoryStats:      make([]models.CategoryStat, 0),
+		DailyCategoryStats: make([]models.DailyCategoryStat, 0),
+		RcodeStats:         make([]models.RcodeStat, 0),
+	}
+
+	where := &tsWhere{}
+	where.add("timestamp >= ?", startTime)
+	where.add("timestamp <= ?", endTime)
+	if nodeID > 0 {
+		where.add("node_id = ?", int64(nodeID))
+	}
+
+	var avgQueryTime *float64
+	err := s.pool.QueryRow(ctx, fmt.Sprintf(`
+        SELECT count(*), count(DISTINCT client_ip), count(DISTINCT domain), avg(time_ms)::float8
+        FROM dns_query_log WHERE %s`, where), where.args...).
+		Scan(&stats.TotalQueries, &stats.UniqueClients, &stats.UniqueDomains, &avgQueryTime)
+	if err != nil {
+		return nil, err
+	}
+	if avgQueryTime != nil {
+		stats.AvgQueryTime = *avgQueryTime
+	}
+	if stats.TotalQueries == 0 {
+		return stats, nil
+	}
+
+	// 热门域名
+	rows, err := s.pool.Query(ctx,
+		fmt.Sprintf("SELECT domain, count(*) AS count FROM dns_query_log WHERE %s GROUP BY domain ORDER BY count DESC LIMIT 10", where),
+		where.args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.DomainStat
+			rows.Scan(&stat.Domain, &stat.Count)
+			stats.TopDomains = append(stats.TopDomains, stat)
+		}
+		rows.Close()
+	}
+
+	// 热门客户端
+	rows, err = s.pool.Query(ctx,
+		fmt.Sprintf("SELECT client_ip, count(*) AS count FROM dns_query_log WHERE %s GROUP BY client_ip ORDER BY count DESC LIMIT 10", where),
+		where.args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.ClientStat
+			rows.Scan(&stat.ClientIP, &stat.Count)
+			stats.TopClients = append(stats.TopClients, stat)
+		}
+		rows.Close()
+	}
+
+	// 按小时统计
+	rows, err = s.pool.Query(ctx,
+		fmt.Sprintf("SELECT extract(hour FROM timestamp)::int AS hour, count(*) AS count FROM dns_query_log WHERE %s GROUP BY hour ORDER BY hour", where),
+		where.args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.HourlyStat
+			var hour int32
+			rows.Scan(&hour, &stat.Count)
+			stat.Hour = int(hour)
+			stats.HourlyStats = append(stats.HourlyStats, stat)
+		}
+		rows.Close()
+	}
+
+	// 按域名分类统计
+	rows, err = s.pool.Query(ctx,
+		fmt.Sprintf("SELECT domain_category, count(*) AS count FROM dns_query_log WHERE %s AND domain_category <> '' GROUP BY domain_category ORDER BY count DESC", where),
+		where.args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.CategoryStat
+			rows.Scan(&stat.Category, &stat.Count)
+			stats.CategoryStats = append(stats.CategoryStats, stat)
+		}
+		rows.Close()
+	}
+
+	// 按天的域名分类统计
+	rows, err = s.pool.Query(ctx,
+		fmt.Sprintf("SELECT to_char(timestamp::date, 'YYYY-MM-DD') AS day, domain_category, count(*) AS count FROM dns_query_log WHERE %s AND domain_category <> '' GROUP BY day, domain_category ORDER BY day, domain_category", where),
+		where.args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.DailyCategoryStat
+			rows.Scan(&stat.Date, &stat.Category, &stat.Count)
+			stats.DailyCategoryStats = append(stats.DailyCategoryStats, stat)
+		}
+		rows.Close()
+	}
+
+	// 按分组统计
+	rows, err = s.pool.Query(ctx,
+		fmt.Sprintf(`SELECT "group", count(*) AS count, avg(time_ms)::float8 FROM dns_query_log WHERE %s GROUP BY "group" ORDER BY count DESC LIMIT 20`, where),
+		where.args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.GroupStat
+			rows.Scan(&stat.Group, &stat.Count, &stat.AvgTimeMs)
+			stats.GroupStats = append(stats.GroupStats, stat)
+		}
+		rows.Close()
+	}
+
+	// 按响应码统计
+	rows, err = s.pool.Query(ctx,
+		fmt.Sprintf("SELECT rcode, count(*) AS count FROM dns_query_log WHERE %s GROUP BY rcode ORDER BY count DESC", where),
+		where.args...)
+	if err == nil {
+		for rows.Next() {
+			var stat models.RcodeStat
+			rows.Scan(&stat.Rcode, &stat.Count)
+			stats.RcodeStats = append(stats.RcodeStats, stat)
+		}
+		rows.Close()
+	}
+
+	// NXDOMAIN 占比和缓存命中率
+	var nxdomain, cacheHits int64
+	if err := s.pool.QueryRow(ctx,
+		fmt.Sprintf("SELECT count(*) FILTER (WHERE %s), count(*) FILTER (WHERE cache_hit) FROM dns_query_log WHERE %s", nxdomainConditionPG, where),
+		where.args...).Scan(&nxdomain, &cacheHits); err == nil {
+		stats.NXDomainRate = float64(nxdomain) / float64(stats.TotalQueries)
+		stats.CacheHitRatio = float64(cacheHits) / float64(stats.TotalQueries)
+	}
+
+	return stats, nil
+}
+
+// SearchDomains 搜索域名（实现接口）
+func (s *LogMonitorServiceTS) SearchDomains(keyword string, limit int) ([]string, error) {
+	ctx := context.Background()
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT DISTINCT domain FROM dns_query_log WHERE domain LIKE $1 ORDER BY domain LIMIT $2",
+		"%"+keyword+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var domains []string
+	for rows.Next() {
+		var domain string
+		if err := rows.Scan(&domain); err != nil {
+			continue
+		}
+		domains = append(domains, domain)
+	}
+
+	return domains, nil
+}
+
+// CleanOldLogs 清理旧日志（实现接口）
+func (s *LogMonitorServiceTS) CleanOldLogs(nodeID uint, days int) error {
+	ctx := context.Background()
+	cutoffTime := time.Now().AddDate(0, 0, -days)
+
+	// 清理所有节点时直接删除整块的 chunk，比逐行删除快得多
+	if nodeID == 0 && s.hypertable {
+		if _, err := s.pool.Exec(ctx, "SELECT drop_chunks('dns_query_log', older_than => $1::timestamptz)", cutoffTime); err != nil {
+			return err
+		}
+		log.Printf("✅ 清理完成，删除 %d 天前的日志", days)
+		return nil
+	}
+
+	where := &tsWhere{}
+	where.add("timestamp < ?", cutoffTime)
+	if nodeID > 0 {
+		where.add("node_id = ?", int64(nodeID))
+	}
+
+	tag, err := s.pool.Exec(ctx, fmt.Sprintf("DELETE FROM dns_query_log WHERE %s", where), where.args...)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ 清理完成，删除 %d 天前的日志 %d 条", days, tag.RowsAffected())
+	return nil
+}
+
+// ApplyRetention 按节点保留天数清理日志（实现 LogRetentionApplier）
+//
+// hypertable 先删除超过最长保留天数的 chunk，再按分组删除保留天数更短的节点的日志
+func (s *LogMonitorServiceTS) ApplyRetention(ctx context.Context, policy LogRetentionPolicy) (string, error) {
+	maxDays := policy.MaxDays()
+	var results []string
+
+	if s.hypertable {
+		// TimescaleDB 保留策略按整个表删除 chunk，比最长保留天数短时会提前删除单独设置了更长保留天数的节点的日志
+		if s.retentionDays > 0 && s.retentionDays < maxDays {
+			results = append(results, fmt.Sprintf("⚠️ POSTGRES_LOG_RETENTION_DAYS=%d 短于最长保留天数 %d，请调大该值", s.retentionDays, maxDays))
+		}
+		if _, err := s.pool.Exec(ctx, "SELECT drop_chunks('dns_query_log', older_than => $1::timestamptz)",
+			time.Now().AddDate(0, 0, -maxDays)); err != nil {
+			return "", fmt.Errorf("删除过期 chunk 失败: %w", err)
+		}
+	}
+
+	var failed int
+	for _, group := range policy.Groups() {
+		where := &tsWhere{}
+		where.add("timestamp < ?", time.Now().AddDate(0, 0, -group.Days))
+		nodeIDs := make([]int64, len(group.NodeIDs))
+		for i, id := range group.NodeIDs {
+			nodeIDs[i] = int64(id)
+		}
+		if !group.Default {
+			where.add("node_id = ANY(?)", nodeIDs)
+		} else if len(nodeIDs) > 0 {
+			where.add("NOT (node_id = ANY(?))", nodeIDs)
+		}
+
+		tag, err := s.pool.Exec(ctx, fmt.Sprintf("DELETE FROM dns_query_log WHERE %s", where), where.args...)
+		if err != nil {
+			failed++
+			log.Printf("❌ 清理 %s 的过期日志失败: %v", group, err)
+			results = append(results, fmt.Sprintf("%s: 删除失败 %v", group, err))
+			continue
+		}
+		results = append(results, fmt.Sprintf("%s: 删除 %d 条过期日志", group, tag.RowsAffected()))
+	}
+
+	output := strings.Join(results, "\n")
+	if failed > 0 {
+		return output, fmt.Errorf("%d 组节点的过期日志清理失败", failed)
+	}
+	return output, nil
+}
+
+// CountOldLogs 统计将被清理的日志条数（实现 LogCleanupEstimator）
+func (s *LogMonitorServiceTS) CountOldLogs(nodeID uint, days int) (int64, error) {
+	ctx := context.Background()
+
+	where := &tsWhere{}
+	where.add("timestamp < ?", time.Now().AddDate(0, 0, -days))
+	if nodeID > 0 {
+		where.add("node_id = ?", int64(nodeID))
+	}
+
+	var count int64
+	if err := s.pool.QueryRow(ctx, fmt.Sprintf("SELECT count(*) FROM dns_query_log WHERE %s", where), where.args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetCacheHitCounts 统计节点的查询总数和缓存命中数（实现 CacheHitRateProvider）
+func (s *LogMonitorServiceTS) GetCacheHitCounts(nodeID uint, startTime, endTime time.Time) (int64, int64, error) {
+	ctx := context.Background()
+
+	var total, hits int64
+	err := s.pool.QueryRow(ctx,
+		"SELECT count(*), count(*) FILTER (WHERE cache_hit) FROM dns_query_log WHERE timestamp >= $1 AND timestamp <= $2 AND node_id = $3",
+		startTime, endTime, int64(nodeID)).Scan(&total, &hits)
+	if err != nil {
+		return 0, 0, err
+	}
+	return total, hits, nil
+}
+
+// CheckHealth 检查服务健康状态（实现接口）
+func (s *LogMonitorServiceTS) CheckHealth() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.pool.Ping(ctx)
+}
+
+// GetStorageType 获取存储类型（实现接口）
+func (s *LogMonitorServiceTS) GetStorageType() string {
+	return config.LogStorageTimescale
+}
+
+// GetStorageInfo 获取存储信息（实现接口）
+func (s *LogMonitorServiceTS) GetStorageInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"type":           config.LogStorageTimescale,
+		"connected":      false,
+		"host":           s.cfg.Host,
+		"database":       s.cfg.Database,
+		"hypertable":     s.hypertable,
+		"retention_days": s.retentionDays,
+	}
+
+	if err := s.CheckHealth(); err == nil {
+		info["connected"] = true
+	}
+
+	return info
+}
+
+// EnsureTables 确保数据库表存在（实现接口）
+// 表结构与 Agent 写入的 ClickHouse 表一致，Agent 使用 TimescaleDB 时写入同名表
+func (s *LogMonitorServiceTS) EnsureTables() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS dns_query_log (
+            timestamp TIMESTAMPTZ NOT NULL,
+            node_id BIGINT NOT NULL,
+            client_ip TEXT NOT NULL DEFAULT '',
+            domain TEXT NOT NULL DEFAULT '',
+            query_type INTEGER NOT NULL DEFAULT 0,
+            time_ms INTEGER NOT NULL DEFAULT 0,
+            speed_ms REAL NOT NULL DEFAULT 0,
+            result_count INTEGER NOT NULL DEFAULT 0,
+            result_ips TEXT[] NOT NULL DEFAULT '{}',
+            raw_log TEXT NOT NULL DEFAULT '',
+            "group" TEXT NOT NULL DEFAULT '',
+            domain_category TEXT NOT NULL DEFAULT '',
+            client_subnet TEXT NOT NULL DEFAULT '',
+            client_country TEXT NOT NULL DEFAULT '',
+            client_asn BIGINT NOT NULL DEFAULT 0,
+            client_as_org TEXT NOT NULL DEFAULT '',
+            client_ptr TEXT NOT NULL DEFAULT '',
+            upstream TEXT NOT NULL DEFAULT '',
+            rcode TEXT NOT NULL DEFAULT '',
+            cache_hit BOOLEAN NOT NULL DEFAULT false,
+            matched_rule TEXT NOT NULL DEFAULT ''
+        )`,
+		`ALTER TABLE dns_query_log ADD COLUMN IF NOT EXISTS upstream TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE dns_query_log ADD COLUMN IF NOT EXISTS rcode TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE dns_query_log ADD COLUMN IF NOT EXISTS cache_hit BOOLEAN NOT NULL DEFAULT false`,
+		`ALTER TABLE dns_query_log ADD COLUMN IF NOT EXISTS matched_rule TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_dns_query_log_node_time ON dns_query_log (node_id, timestamp DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_dns_query_log_client_time ON dns_query_log (client_ip, timestamp DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_dns_query_log_domain_time ON dns_query_log (domain, timestamp DESC)`,
+		`CREATE TABLE IF NOT EXISTS dns_ingest_batches (
+            node_id BIGINT NOT NULL,
+            file_path TEXT NOT NULL DEFAULT '',
+            stream_id TEXT NOT NULL,
+            seq BIGINT NOT NULL,
+            start_offset BIGINT NOT NULL DEFAULT 0,
+            end_offset BIGINT NOT NULL DEFAULT 0,
+            rows INTEGER NOT NULL DEFAULT 0,
+            reread SMALLINT NOT NULL DEFAULT 0,
+            sent_at TIMESTAMPTZ NOT NULL,
+            inserted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY (node_id, stream_id, seq)
+        )`,
+		`CREATE TABLE IF NOT EXISTS domain_categories (
+            domain TEXT NOT NULL,
+            category TEXT NOT NULL,
+            domain_set TEXT NOT NULL DEFAULT ''
+        )`,
+	}
+	for _, sql := range statements {
+		if _, err := s.pool.Exec(ctx, sql); err != nil {
+			return err
+		}
+	}
+
+	s.hypertable = s.ensureHypertable(ctx)
+	return nil
+}
+
+// ensureHypertable 有 timescaledb 扩展时将日志表转为按天分块的 hypertable 并设置保留策略
+func (s *LogMonitorServiceTS) ensureHypertable(ctx context.Context) bool {
+	if _, err := s.pool.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS timescaledb"); err != nil {
+		log.Printf("⚠️ timescaledb 扩展不可用，使用普通 PostgreSQL 表: %v", err)
+		return false
+	}
+
+	if _, err := s.pool.Exec(ctx,
+		"SELECT create_hypertable('dns_query_log', 'timestamp', chunk_time_interval => INTERVAL '1 day', if_not_exists => TRUE, migrate_data => TRUE)"); err != nil {
+		log.Printf("⚠️ 创建 hypertable 失败: %v", err)
+		return false
+	}
+
+	if s.retentionDays > 0 {
+		// 先移除旧策略，保留天数变化后重新生效
+		s.pool.Exec(ctx, "SELECT remove_retention_policy('dns_query_log', if_exists => TRUE)")
+		if _, err := s.pool.Exec(ctx,
+			fmt.Sprintf("SELECT add_retention_policy('dns_query_log', INTERVAL '%d days', if_not_exists => TRUE)", s.retentionDays)); err != nil {
+			log.Printf("⚠️ 设置日志保留策略失败: %v", err)
+		}
+	}
+	return true
+}
+
+// GetTableStats 获取表统计信息（实现接口）
+func (s *LogMonitorServiceTS) GetTableStats() (map[string]interface{}, error) {
+	ctx := context.Background()
+	stats := make(map[string]interface{})
+
+	var totalRecords int64
+	if err := s.pool.QueryRow(ctx, "SELECT count(*) FROM dns_query_log").Scan(&totalRecords); err != nil {
+		return nil, err
+	}
+	stats["total_records"] = totalRecords
+
+	// hypertable 的数据存放在各个 chunk 中，需要用 TimescaleDB 的函数统计大小
+	sizeQuery := "SELECT pg_total_relation_size('dns_query_log')"
+	if s.hypertable {
+		sizeQuery = "SELECT hypertable_size('dns_query_log')"
+	}
+	var tableSize int64
+	s.pool.QueryRow(ctx, sizeQuery).Scan(&tableSize)
+	stats["table_size_bytes"] = tableSize
+
+	return stats, nil
+}
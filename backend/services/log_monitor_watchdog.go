@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+const (
+	// logMonitorBaseBackoff 首次自动重启采集前的等待时间，之后按指数增长
+	logMonitorBaseBackoff = 30 * time.Second
+	// logMonitorMaxBackoff 自动重启间隔上限
+	logMonitorMaxBackoff = 30 * time.Minute
+)
+
+// logMonitorState 单个节点采集中断期间的恢复状态
+type logMonitorState struct {
+	downSince   time.Time
+	attempts    int
+	nextAttempt time.Time
+	alerted     bool
+	lastError   string
+}
+
+// LogMonitorWatchdog 日志采集看门狗
+//
+// 定期检查已安装 Agent 的节点采集是否在运行，中断时调用 Agent 的重启接口
+// 自动恢复（Agent 从保存的文件位置继续读取），重启间隔按指数退避；
+// 中断超过 LOG_MONITOR_ALERT_MINUTES 分钟时标记为 down 并通知，恢复后再通知一次。
+type LogMonitorWatchdog struct {
+	ticker              *time.Ticker
+	stopChan            chan bool
+	notificationService *NotificationService
+	mu                  sync.Mutex
+	states              map[uint]*logMonitorState
+}
+
+// NewLogMonitorWatchdog 创建日志采集看门狗
+func NewLogMonitorWatchdog(interval time.Duration) *LogMonitorWatchdog {
+	return &LogMonitorWatchdog{
+		ticker:              time.NewTicker(interval),
+		stopChan:            make(chan bool),
+		notificationService: NewNotificationService(),
+		states:              make(map[uint]*logMonitorState),
+	}
+}
+
+// Start 启动看门狗
+func (w *LogMonitorWatchdog) Start() {
+	log.Println("日志采集看门狗已启动")
+
+	go func() {
+		for {
+			select {
+			case <-w.ticker.C:
+				w.checkAll()
+			case <-w.stopChan:
+				log.Println("日志采集看门狗已停止")
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止看门狗
+func (w *LogMonitorWatchdog) Stop() {
+	w.ticker.Stop()
+	w.stopChan <- true
+}
+
+// checkAll 并发检查已安装 Agent 且启用了日志监控的节点，手动停止的节点不会被自动重启
+func (w *LogMonitorWatchdog) checkAll() {
+	var nodes []models.Node
+	if err := database.DB.Where("agent_installed = ? AND log_monitor_enabled = ?", true, true).
+		Find(&nodes).Error; err != nil {
+		log.Printf("查询 Agent 节点失败: %v", err)
+		return
+	}
+
+	watched := make(map[uint]bool, len(nodes))
+	for _, node := range nodes {
+		watched[node.ID] = true
+	}
+	w.mu.Lock()
+	for id := range w.states {
+		if !watched[id] {
+			delete(w.states, id)
+		}
+	}
+	w.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func(node *models.Node) {
+			defer wg.Done()
+			w.checkNode(node)
+		}(&nodes[i])
+	}
+	wg.Wait()
+}
+
+// checkNode 检查节点采集状态，中断时按退避间隔尝试重启
+func (w *LogMonitorWatchdog) checkNode(node *models.Node) {
+	err := checkAgentCollecting(node)
+	if err == nil {
+		w.markRunning(node)
+		return
+	}
+
+	now := time.Now()
+	w.mu.Lock()
+	state, exists := w.states[node.ID]
+	if !exists {
+		state = &logMonitorState{downSince: now, nextAttempt: now}
+		if node.LogMonitorDownSince != nil {
+			// 管理端重启前已中断的节点沿用原来的中断时间，避免重复告警
+			state.downSince = *node.LogMonitorDownSince
+			state.alerted = node.LogMonitorStatus == models.LogMonitorStatusDown
+		}
+		w.states[node.ID] = state
+	}
+	state.lastError = err.Error()
+	due := !now.Before(state.nextAttempt)
+	if due {
+		state.attempts++
+		state.nextAttempt = now.Add(logMonitorBackoff(state.attempts))
+	}
+	w.mu.Unlock()
+
+	if due {
+		log.Printf("⚠️ 节点 %s 日志采集中断（%v），第 %d 次尝试重启", node.Name, err, state.attempts)
+		if restartErr := CallNodeAgent(node, "POST", "/restart", nil); restartErr != nil {
+			state.lastError = restartErr.Error()
+		} else if checkAgentCollecting(node) == nil {
+			w.markRunning(node)
+			return
+		}
+	}
+
+	status := models.LogMonitorStatusRecovering
+	if now.Sub(state.downSince) >= logMonitorAlertThreshold() {
+		status = models.LogMonitorStatusDown
+		if !state.alerted {
+			state.alerted = true
+			w.notificationService.SendNotification(node.ID, "log_monitor_down", "🚨 日志采集中断",
+				fmt.Sprintf("节点 %s 的日志采集已中断 %d 分钟，自动重启 %d 次未恢复\n中断开始: %s\n最近错误: %s",
+					node.Name, int(now.Sub(state.downSince).Minutes()), state.attempts,
+					state.downSince.Format("2006-01-02 15:04:05"), state.lastError))
+		}
+	}
+
+	downSince := state.downSince
+	database.DB.Model(node).Updates(map[string]interface{}{
+		"log_monitor_status":     status,
+		"log_monitor_down_since": &downSince,
+	})
+}
+
+// markRunning 标记采集正常，已告警过的节点发送恢复通知
+func (w *LogMonitorWatchdog) markRunning(node *models.Node) {
+	w.mu.Lock()
+	state := w.states[node.ID]
+	delete(w.states, node.ID)
+	w.mu.Unlock()
+
+	alerted := node.LogMonitorStatus == models.LogMonitorStatusDown
+	if state != nil {
+		alerted = alerted || state.alerted
+	}
+
+	if node.LogMonitorStatus == models.LogMonitorStatusRunning && node.LogMonitorDownSince == nil {
+		return
+	}
+
+	database.DB.Model(node).Updates(map[string]interface{}{
+		"log_monitor_status":     models.LogMonitorStatusRunning,
+		"log_monitor_down_since": nil,
+	})
+
+	if alerted {
+		downtime := ""
+		if node.LogMonitorDownSince != nil {
+			downtime = fmt.Sprintf("，中断约 %d 分钟", int(time.Since(*node.LogMonitorDownSince).Minutes()))
+		}
+		w.notificationService.SendNotification(node.ID, "log_monitor_recovered", "✅ 日志采集已恢复",
+			fmt.Sprintf("节点 %s 的日志采集已恢复%s", node.Name, downtime))
+	}
+	log.Printf("节点 %s 日志采集正常", node.Name)
+}
+
+// checkAgentCollecting Agent 可访问且采集在运行时返回 nil
+func checkAgentCollecting(node *models.Node) error {
+	response, err := CallNodeAgentWithResponse(node, "GET", "/status", nil)
+	if err != nil {
+		return err
+	}
+	data, _ := response["data"].(map[string]interface{})
+	if running, _ := data["is_running"].(bool); !running {
+		return fmt.Errorf("Agent 未在采集日志")
+	}
+	return nil
+}
+
+// logMonitorBackoff 计算第 attempts 次重启后的等待时间
+func logMonitorBackoff(attempts int) time.Duration {
+	backoff := logMonitorBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= logMonitorMaxBackoff {
+			return logMonitorMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// logMonitorAlertThreshold 采集中断告警阈值
+func logMonitorAlertThreshold() time.Duration {
+	minutes, err := strconv.Atoi(config.GetConfig().LogMonitorAlertMinutes)
+	if err != nil || minutes <= 0 {
+		minutes = 10
+	}
+	return time.Duration(minutes) * time.Minute
+}
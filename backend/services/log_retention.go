@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// MaxLogRetentionDays 节点日志保留天数上限
+const MaxLogRetentionDays = 3650
+
+// LogRetentionApplier 可选接口，驱动实现后支持按节点设置 DNS 日志保留天数
+type LogRetentionApplier interface {
+	// ApplyRetention 按策略删除各节点的过期日志，并把存储自身的过期设置（如 ClickHouse 表 TTL）
+	// 调整为不早于最长的保留天数，返回执行摘要
+	ApplyRetention(ctx context.Context, policy LogRetentionPolicy) (string, error)
+}
+
+// LogRetentionPolicy 所有节点的日志保留天数
+type LogRetentionPolicy struct {
+	DefaultDays int          // 未单独设置的节点
+	NodeDays    map[uint]int // 单独设置了保留天数的节点
+}
+
+// LogRetentionGroup 保留天数相同的一组节点
+type LogRetentionGroup struct {
+	Days int
+	// Default 为 false 时是 NodeIDs 中的节点；为 true 时是 NodeIDs 以外的所有节点，
+	// 包括已删除节点留下的日志
+	NodeIDs []uint
+	Default bool
+}
+
+// String 分组说明，用于执行摘要
+func (g LogRetentionGroup) String() string {
+	if g.Default {
+		return fmt.Sprintf("其余节点（%d 天）", g.Days)
+	}
+	return fmt.Sprintf("节点 %v（%d 天）", g.NodeIDs, g.Days)
+}
+
+// DefaultLogRetentionDays 默认日志保留天数，DNS_LOG_RETENTION_DAYS 无效时为 90 天
+func DefaultLogRetentionDays() int {
+	days, err := strconv.Atoi(config.GetConfig().DNSLogRetentionDays)
+	if err != nil || days <= 0 || days > MaxLogRetentionDays {
+		return 90
+	}
+	return days
+}
+
+// LoadLogRetentionPolicy 读取默认保留天数和节点单独设置的保留天数
+func LoadLogRetentionPolicy() (LogRetentionPolicy, error) {
+	policy := LogRetentionPolicy{
+		DefaultDays: DefaultLogRetentionDays(),
+		NodeDays:    make(map[uint]int),
+	}
+
+	var retentions []models.NodeLogRetention
+	if err := database.DB.Find(&retentions).Error; err != nil {
+		return policy, fmt.Errorf("查询节点日志保留策略失败: %w", err)
+	}
+	for _, retention := range retentions {
+		if retention.Days > 0 {
+			policy.NodeDays[retention.NodeID] = retention.Days
+		}
+	}
+	return policy, nil
+}
+
+// MaxDays 所有节点中最长的保留天数
+func (p LogRetentionPolicy) MaxDays() int {
+	max := p.DefaultDays
+	for _, days := range p.NodeDays {
+		if days > max {
+			max = days
+		}
+	}
+	return max
+}
+
+// Groups 按保留天数分组，默认分组排在最后
+func (p LogRetentionPolicy) Groups() []LogRetentionGroup {
+	byDays := make(map[int][]uint)
+	custom := make([]uint, 0, len(p.NodeDays))
+	for nodeID, days := range p.NodeDays {
+		byDays[days] = append(byDays[days], nodeID)
+		custom = append(custom, nodeID)
+	}
+
+	groups := make([]LogRetentionGroup, 0, len(byDays)+1)
+	for days, nodeIDs := range byDays {
+		sortNodeIDs(nodeIDs)
+		groups = append(groups, LogRetentionGroup{Days: days, NodeIDs: nodeIDs})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Days < groups[j].Days })
+
+	sortNodeIDs(custom)
+	return append(groups, LogRetentionGroup{Days: p.DefaultDays, NodeIDs: custom, Default: true})
+}
+
+func sortNodeIDs(ids []uint) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
+
+// GetNodeLogRetention 获取节点生效的日志保留天数
+func GetNodeLogRetention(nodeID uint) models.NodeLogRetentionStatus {
+	status := models.NodeLogRetentionStatus{
+		NodeID:      nodeID,
+		DefaultDays: DefaultLogRetentionDays(),
+	}
+	status.Days = status.DefaultDays
+
+	var retention models.NodeLogRetention
+	if err := database.DB.Where("node_id = ?", nodeID).First(&retention).Error; err == nil && retention.Days > 0 {
+		status.Days = retention.Days
+		status.Custom = true
+	}
+	return status
+}
+
+// SaveNodeLogRetention 保存节点的日志保留天数，days 为 0 时恢复默认
+func SaveNodeLogRetention(nodeID uint, days int) error {
+	if days < 0 || days > MaxLogRetentionDays {
+		return fmt.Errorf("保留天数须在 1-%d 之间，0 表示使用默认值", MaxLogRetentionDays)
+	}
+	if days == 0 {
+		return database.DB.Where("node_id = ?", nodeID).Delete(&models.NodeLogRetention{}).Error
+	}
+	return database.DB.Save(&models.NodeLogRetention{NodeID: nodeID, Days: days}).Error
+}
+
+// GetLogRetentionOverview 日志保留策略概览
+func GetLogRetentionOverview(storage LogMonitorInterface) (*models.LogRetentionOverview, error) {
+	policy, err := LoadLogRetentionPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &models.LogRetentionOverview{
+		DefaultDays: policy.DefaultDays,
+		MaxDays:     policy.MaxDays(),
+		Nodes:       []models.NodeLogRetention{},
+	}
+	if storage != nil {
+		overview.StorageType = storage.GetStorageType()
+		_, overview.Supported = storage.(LogRetentionApplier)
+	}
+	if err := database.DB.Where("days > 0").Order("node_id").Find(&overview.Nodes).Error; err != nil {
+		return nil, fmt.Errorf("查询节点日志保留策略失败: %w", err)
+	}
+	return overview, nil
+}
+
+// ApplyLogRetention 按当前策略清理各节点的过期日志
+func ApplyLogRetention(ctx context.Context, storage LogMonitorInterface) (string, error) {
+	if storage == nil {
+		return "", fmt.Errorf("日志监控服务未初始化")
+	}
+	applier, ok := storage.(LogRetentionApplier)
+	if !ok {
+		return "", fmt.Errorf("日志存储 %s 不支持按节点设置保留天数", storage.GetStorageType())
+	}
+
+	policy, err := LoadLogRetentionPolicy()
+	if err != nil {
+		return "", err
+	}
+	return applier.ApplyRetention(ctx, policy)
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// maintenanceCronParser 与定时任务一致，使用含秒的 6 段表达式
+var maintenanceCronParser = cron.NewParser(
+	cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// MaintenanceService 维护窗口判断和推迟操作管理
+type MaintenanceService struct{}
+
+func NewMaintenanceService() *MaintenanceService {
+	return &MaintenanceService{}
+}
+
+// ParseWindowSchedule 校验并解析维护窗口的 Cron 表达式
+func ParseWindowSchedule(expr string) (cron.Schedule, error) {
+	schedule, err := maintenanceCronParser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 Cron 表达式: %w", err)
+	}
+	return schedule, nil
+}
+
+// FillWindowState 计算窗口当前是否开启以及下次开启时间
+func FillWindowState(window *models.MaintenanceWindow, now time.Time) {
+	window.IsOpen = false
+	window.NextOpen = nil
+
+	schedule, err := ParseWindowSchedule(window.CronExpr)
+	if err != nil {
+		return
+	}
+	duration := time.Duration(window.DurationMinutes) * time.Minute
+
+	// 从 now-duration 之后的第一次触发不晚于 now，说明当前处于窗口内
+	if start := schedule.Next(now.Add(-duration)); !start.After(now) {
+		window.IsOpen = true
+		window.NextOpen = &now
+		return
+	}
+	next := schedule.Next(now)
+	window.NextOpen = &next
+}
+
+// windowsForNode 覆盖该节点的启用窗口
+func (s *MaintenanceService) windowsForNode(node *models.Node) []models.MaintenanceWindow {
+	var windows []models.MaintenanceWindow
+	database.DB.Where("enabled = ?", true).Find(&windows)
+
+	var matched []models.MaintenanceWindow
+	for _, window := range windows {
+		global := (window.NodeIDs == "" || window.NodeIDs == "[]") && window.Tag == ""
+		byNode := window.NodeIDs != "" && window.NodeIDs != "[]" && nodeIDsContain(window.NodeIDs, node.ID)
+		byTag := window.Tag != "" && nodeHasTag(*node, window.Tag)
+		if global || byNode || byTag {
+			matched = append(matched, window)
+		}
+	}
+	return matched
+}
+
+// globalWindows 对所有节点生效的启用窗口，用于不针对单个节点的定时任务
+func (s *MaintenanceService) globalWindows() []models.MaintenanceWindow {
+	var windows []models.MaintenanceWindow
+	database.DB.Where("enabled = ? AND (node_ids = '' OR node_ids = '[]' OR node_ids IS NULL) AND tag = ''", true).
+		Find(&windows)
+	return windows
+}
+
+// checkWindows 没有窗口时不受限制；否则任一窗口开启即允许，并返回最早的下次开启时间
+func checkWindows(windows []models.MaintenanceWindow, now time.Time) (bool, *time.Time) {
+	if len(windows) == 0 {
+		return true, nil
+	}
+
+	var next *time.Time
+	for i := range windows {
+		FillWindowState(&windows[i], now)
+		if windows[i].IsOpen {
+			return true, &now
+		}
+		if windows[i].NextOpen != nil && (next == nil || windows[i].NextOpen.Before(*next)) {
+			next = windows[i].NextOpen
+		}
+	}
+	return false, next
+}
+
+// NodeAllowed 节点当前是否允许执行中断性操作
+func (s *MaintenanceService) NodeAllowed(node *models.Node) (bool, *time.Time) {
+	return checkWindows(s.windowsForNode(node), time.Now())
+}
+
+// GlobalAllowed 当前是否处于全局维护窗口内（未配置全局窗口时始终允许）
+func (s *MaintenanceService) GlobalAllowed() (bool, *time.Time) {
+	return checkWindows(s.globalWindows(), time.Now())
+}
+
+// DeferNodeAction 节点不在维护窗口内时推迟操作并返回 true；override 为 true 时始终立即执行
+//
+// 同一节点的待执行操作会合并：已有重启时不再追加重载，已有重载时升级为重启。
+func (s *MaintenanceService) DeferNodeAction(node *models.Node, action, reason string, override bool) (bool, *time.Time) {
+	if override {
+		return false, nil
+	}
+	allowed, next := s.NodeAllowed(node)
+	if allowed {
+		return false, nil
+	}
+
+	var pending []models.DeferredAction
+	database.DB.Where("node_id = ? AND status = ? AND action IN ?", node.ID, models.DeferredStatusPending,
+		[]string{models.DeferredActionRestart, models.DeferredActionReload, action}).Find(&pending)
+	for i := range pending {
+		existing := &pending[i]
+		switch {
+		case existing.Action == action,
+			existing.Action == models.DeferredActionRestart && action == models.DeferredActionReload:
+			return true, next
+		case existing.Action == models.DeferredActionReload && action == models.DeferredActionRestart:
+			database.DB.Model(existing).Updates(map[string]interface{}{
+				"action": models.DeferredActionRestart,
+				"reason": reason,
+			})
+			return true, next
+		}
+	}
+
+	database.DB.Create(&models.DeferredAction{
+		NodeID:    node.ID,
+		Action:    action,
+		Reason:    reason,
+		Status:    models.DeferredStatusPending,
+		NotBefore: next,
+	})
+	log.Printf("⏸️ 节点 %s 不在维护窗口内，%s 已推迟: %s", node.Name, action, reason)
+	return true, next
+}
+
+// DeferTask 定时任务不在全局维护窗口内时推迟，同一任务只保留一条待执行记录
+func (s *MaintenanceService) DeferTask(task *models.ScheduledTask) (bool, *time.Time) {
+	allowed, next := s.GlobalAllowed()
+	if allowed {
+		return false, nil
+	}
+
+	var count int64
+	database.DB.Model(&models.DeferredAction{}).
+		Where("task_id = ? AND action = ? AND status = ?", task.ID, models.DeferredActionTask, models.DeferredStatusPending).
+		Count(&count)
+	if count == 0 {
+		database.DB.Create(&models.DeferredAction{
+			TaskID:    task.ID,
+			Action:    models.DeferredActionTask,
+			Reason:    "定时任务: " + task.Name,
+			Status:    models.DeferredStatusPending,
+			NotBefore: next,
+		})
+	}
+	log.Printf("⏸️ 任务 [%s] 不在维护窗口内，已推迟", task.Name)
+	return true, next
+}
+
+// FormatNextWindow 推迟提示中显示的下次窗口时间
+func FormatNextWindow(next *time.Time) string {
+	if next == nil {
+		return "下一个维护窗口"
+	}
+	return next.Format("2006-01-02 15:04")
+}
+
+// MaintenanceWorker 在维护窗口开启后执行推迟的操作
+type MaintenanceWorker struct {
+	ticker      *time.Ticker
+	stopChan    chan bool
+	maintenance *MaintenanceService
+	scheduler   *SchedulerService
+}
+
+// NewMaintenanceWorker 创建推迟操作执行器，scheduler 用于执行推迟的定时任务
+func NewMaintenanceWorker(interval time.Duration, scheduler *SchedulerService) *MaintenanceWorker {
+	return &MaintenanceWorker{
+		ticker:      time.NewTicker(interval),
+		stopChan:    make(chan bool),
+		maintenance: NewMaintenanceService(),
+		scheduler:   scheduler,
+	}
+}
+
+// Start 启动推迟操作执行器
+func (w *MaintenanceWorker) Start() {
+	log.Println("维护窗口推迟操作执行器已启动")
+
+	go func() {
+		for {
+			select {
+			case <-w.ticker.C:
+				w.processDue()
+			case <-w.stopChan:
+				log.Println("维护窗口推迟操作执行器已停止")
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止推迟操作执行器
+func (w *MaintenanceWorker) Stop() {
+	w.ticker.Stop()
+	w.stopChan <- true
+}
+
+// processDue 执行所在窗口已开启的推迟操作
+func (w *MaintenanceWorker) processDue() {
+	var actions []models.DeferredAction
+	if err := database.DB.Where("status = ?", models.DeferredStatusPending).
+		Order("created_at ASC").Find(&actions).Error; err != nil {
+		log.Printf("查询推迟操作失败: %v", err)
+		return
+	}
+
+	for i := range actions {
+		action := &actions[i]
+		if action.Action == models.DeferredActionTask {
+			if allowed, _ := w.maintenance.GlobalAllowed(); !allowed {
+				continue
+			}
+		} else {
+			var node models.Node
+			if err := database.DB.First(&node, action.NodeID).Error; err != nil {
+				w.finish(action, fmt.Errorf("节点不存在"))
+				continue
+			}
+			if allowed, _ := w.maintenance.NodeAllowed(&node); !allowed {
+				continue
+			}
+		}
+		w.finish(action, w.Execute(action))
+	}
+}
+
+// Execute 立即执行推迟的操作（窗口开启或手动提前执行）
+func (w *MaintenanceWorker) Execute(action *models.DeferredAction) error {
+	if action.Action == models.DeferredActionTask {
+		task, err := w.scheduler.GetTask(action.TaskID)
+		if err != nil {
+			return fmt.Errorf("任务不存在")
+		}
+		return w.scheduler.ExecuteTaskManually(*task)
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, action.NodeID).Error; err != nil {
+		return fmt.Errorf("节点不存在")
+	}
+
+	if action.Action == models.DeferredActionCacheFlush {
+		return NewSmartDNSCacheService(database.DB, nil).Flush(&node)
+	}
+
+	client, err := NewSSHClient(&node)
+	if err != nil {
+		return fmt.Errorf("连接节点失败: %w", err)
+	}
+	defer client.Close()
+
+	switch action.Action {
+	case models.DeferredActionRestart:
+		return client.RestartService("smartdns")
+	case models.DeferredActionReload:
+		_, err := client.ReloadService("smartdns", node.ReloadMode)
+		return err
+	}
+	return fmt.Errorf("未知的操作类型: %s", action.Action)
+}
+
+func (w *MaintenanceWorker) finish(action *models.DeferredAction, err error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      models.DeferredStatusDone,
+		"error":       "",
+		"executed_at": &now,
+	}
+	if err != nil {
+		updates["status"] = models.DeferredStatusFailed
+		updates["error"] = err.Error()
+		log.Printf("❌ 执行推迟操作 #%d (%s) 失败: %v", action.ID, action.Action, err)
+	} else {
+		log.Printf("✅ 已在维护窗口内执行推迟操作 #%d (%s)", action.ID, action.Action)
+	}
+	database.DB.Model(action).Updates(updates)
+}
+
+// Finish 记录手动执行推迟操作的结果
+func (w *MaintenanceWorker) Finish(action *models.DeferredAction, err error) {
+	w.finish(action, err)
+}
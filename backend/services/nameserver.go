@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -50,12 +51,14 @@ func (s *NameserverService) syncNameserverToNode(nameserver *models.Nameserver,
 	}
 
 	// 生成规则行
-	ruleLine := s.generateNameserverLine(nameserver)
+	ruleLine := s.generateNameserverLine(nameserver) + recordAnnotation(models.AuditEntityNameserver, nameserver.ID, nameserver.UpdatedAt)
 
 	// 更新配置
 	newContent := s.updateNameserversInConfig(configContent, ruleLine, nameserver.Domain)
 
-	client.WriteFile(node.ConfigPath, newContent)
+	if err := client.WriteFile(node.ConfigPath, newContent); err == nil {
+		reloadAfterSync(context.Background(), client, node)
+	}
 }
 
 // generateNameserverLine 生成命名服务器规则行
@@ -147,7 +150,9 @@ func (s *NameserverService) deleteNameserverFromNode(nameserver *models.Nameserv
 		}
 	}
 
-	client.WriteFile(node.ConfigPath, strings.Join(newLines, "\n"))
+	if err := client.WriteFile(node.ConfigPath, strings.Join(newLines, "\n")); err == nil {
+		reloadAfterSync(context.Background(), client, node)
+	}
 }
 
 func (s *NameserverService) getTargetNodes(nodeIDsJSON string) ([]models.Node, error) {
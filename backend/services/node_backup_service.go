@@ -47,10 +47,17 @@ func (s *NodeBackupService) BackupNodes(ctx context.Context, config models.NodeB
 	}
 	
 	backedUpCount := 0
+	unchangedCount := 0
 	var errors []string
 	
 	for _, node := range nodes {
-		if err := s.backupSingleNode(ctx, node, config); err != nil {
+		err := s.backupSingleNode(ctx, node, config)
+		if err == ErrBackupUnchanged {
+			unchangedCount++
+			log.Printf("⏭️ 节点配置未变化，跳过备份: %s", node.Name)
+			continue
+		}
+		if err != nil {
 			log.Printf("❌ 备份节点失败 [%s]: %v", node.Name, err)
 			errors = append(errors, fmt.Sprintf("%s: %v", node.Name, err))
 		} else {
@@ -60,6 +67,9 @@ func (s *NodeBackupService) BackupNodes(ctx context.Context, config models.NodeB
 	}
 	
 	result := fmt.Sprintf("节点备份完成: 成功 %d/%d", backedUpCount, len(nodes))
+	if unchangedCount > 0 {
+		result += fmt.Sprintf(", 配置未变化跳过 %d", unchangedCount)
+	}
 	if len(errors) > 0 {
 		result += fmt.Sprintf(", 失败: %v", errors)
 	}
@@ -74,34 +84,35 @@ func (s *NodeBackupService) backupSingleNode(ctx context.Context, node models.No
 	var storageType string
 	var err error
 	
-	if config.StorageType == "s3" && (config.S3Config != models.S3Config{}) {
+	switch {
+	case config.StorageType == models.StorageTypeS3 && (config.S3Config != models.S3Config{}):
 		// 使用配置中的S3设置创建临时S3存储
-		tempS3Config := &appConfig.StorageConfig{
-			Type:        "s3",
-			S3Region:    config.S3Config.Region,
-			S3Bucket:    config.S3Config.Bucket,
-			S3AccessKey: config.S3Config.AccessKey,
-			S3SecretKey: config.S3Config.SecretKey,
-			S3Endpoint:  config.S3Config.Endpoint,
-		}
-		
-		storage, err = NewS3BackupStorage(tempS3Config)
+		storage, err = NewBackupStorage(models.StorageTypeS3, &models.StorageBackendConfig{S3: &config.S3Config})
 		if err != nil {
 			return fmt.Errorf("初始化S3存储失败: %w", err)
 		}
-		storageType = "s3"
-	} else {
+		storageType = models.StorageTypeS3
+	case config.StorageType != "" && config.StorageType != models.StorageTypeLocal && config.StorageType != models.StorageTypeS3:
+		storage, err = NewBackupStorage(config.StorageType, config.Storage)
+		if err != nil {
+			return fmt.Errorf("初始化%s存储失败: %w", config.StorageType, err)
+		}
+		storageType = config.StorageType
+	default:
 		// 使用本地存储
 		storage, err = NewLocalBackupStorageWithNode(&node)
 		if err != nil {
 			return fmt.Errorf("初始化本地存储失败: %w", err)
 		}
-		storageType = "local"
+		storageType = models.StorageTypeLocal
 	}
 	defer storage.Close()
 	
-	// 使用通用备份服务执行备份
-	backup, err := s.backupService.PerformNodeBackup(ctx, &node, storage, storageType, "自动任务备份", "", true)
+	// 使用通用备份服务执行备份，配置未变化时不重复保存
+	backup, err := s.backupService.PerformNodeBackup(ctx, &node, storage, storageType, "自动任务备份", "", true, false)
+	if err == ErrBackupUnchanged {
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("执行备份失败: %w", err)
 	}
@@ -114,5 +125,9 @@ func (s *NodeBackupService) backupSingleNode(ctx context.Context, node models.No
 	}
 	
 	log.Printf("✅ 节点备份成功: %s -> %s (存储类型: %s)", node.Name, backup.Path, storageType)
+
+	if _, err := s.backupService.ApplyRetention(ctx, &node); err != nil {
+		log.Printf("⚠️ 清理节点 %s 的过期备份失败: %v", node.Name, err)
+	}
 	return nil
 }
\ No newline at end of file
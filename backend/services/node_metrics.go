@@ -0,0 +1,204 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// nodeMetricsCommand 一次 SSH 命令采集全部指标，各部分以 --- 分隔；只读取 /proc，BusyBox 系统同样可用
+const nodeMetricsCommand = `head -1 /proc/stat; sleep 1; head -1 /proc/stat; echo ---; ` +
+	`cat /proc/loadavg; echo ---; ` +
+	`grep -E '^(MemTotal|MemFree|MemAvailable|Buffers|Cached):' /proc/meminfo; echo ---; ` +
+	`df -P / | tail -1; echo ---; ` +
+	`for pid in $(pidof smartdns 2>/dev/null); do grep VmRSS /proc/$pid/status 2>/dev/null; done; true`
+
+// maxNodeMetricPoints 查询时时间桶数量上限，超过时自动加大桶长度
+const maxNodeMetricPoints = 1000
+
+// nodeMetricsInterval 资源指标采样间隔，为 0 时不采集
+func nodeMetricsInterval() time.Duration {
+	seconds, err := strconv.Atoi(config.GetConfig().NodeMetricsInterval)
+	if err != nil || seconds < 0 {
+		seconds = 60
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// nodeMetricsRetentionDays 资源指标保留天数
+func nodeMetricsRetentionDays() int {
+	days, err := strconv.Atoi(config.GetConfig().NodeMetricsRetentionDays)
+	if err != nil || days < 1 {
+		days = 30
+	}
+	return days
+}
+
+// CollectNodeMetric 采集节点当前的资源指标
+func CollectNodeMetric(client *SSHClient) (*models.NodeMetric, error) {
+	output, err := client.ExecuteCommand(nodeMetricsCommand)
+	if err != nil {
+		return nil, fmt.Errorf("采集资源指标失败: %w", err)
+	}
+	return parseNodeMetrics(output)
+}
+
+// parseNodeMetrics 解析 nodeMetricsCommand 的输出
+func parseNodeMetrics(output string) (*models.NodeMetric, error) {
+	sections := strings.Split(output, "---\n")
+	if len(sections) < 5 {
+		return nil, fmt.Errorf("无法解析资源指标: %s", output)
+	}
+	metric := &models.NodeMetric{CreatedAt: time.Now()}
+
+	// CPU：两次 /proc/stat 采样之差
+	cpuLines := strings.Split(strings.TrimSpace(sections[0]), "\n")
+	if len(cpuLines) < 2 {
+		return nil, fmt.Errorf("无法解析CPU使用率: %s", sections[0])
+	}
+	idle1, total1, err := parseProcStatCPU(cpuLines[0])
+	if err != nil {
+		return nil, err
+	}
+	idle2, total2, err := parseProcStatCPU(cpuLines[1])
+	if err != nil {
+		return nil, err
+	}
+	if total2 > total1 {
+		metric.CPUUsage = 100 * (1 - float64(idle2-idle1)/float64(total2-total1))
+	}
+
+	// 负载：/proc/loadavg 的前三列
+	loads := strings.Fields(sections[1])
+	if len(loads) < 3 {
+		return nil, fmt.Errorf("无法解析系统负载: %s", sections[1])
+	}
+	metric.Load1, _ = strconv.ParseFloat(loads[0], 64)
+	metric.Load5, _ = strconv.ParseFloat(loads[1], 64)
+	metric.Load15, _ = strconv.ParseFloat(loads[2], 64)
+
+	// 内存：3.14 之前的内核没有 MemAvailable，按 MemFree + Buffers + Cached 估算
+	mem := map[string]float64{}
+	for _, line := range strings.Split(strings.TrimSpace(sections[2]), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			value, _ := strconv.ParseFloat(fields[1], 64)
+			mem[strings.TrimSuffix(fields[0], ":")] = value
+		}
+	}
+	available, ok := mem["MemAvailable"]
+	if !ok {
+		available = mem["MemFree"] + mem["Buffers"] + mem["Cached"]
+	}
+	if total := mem["MemTotal"]; total > 0 {
+		metric.MemoryUsage = 100 * (total - available) / total
+	}
+
+	// 磁盘：df -P 的第 5 列
+	if fields := strings.Fields(sections[3]); len(fields) >= 5 {
+		metric.DiskUsage, _ = strconv.ParseFloat(strings.TrimSuffix(fields[4], "%"), 64)
+	}
+
+	// SmartDNS 进程内存：VmRSS 单位为 kB，多个进程时取最大值
+	for _, line := range strings.Split(strings.TrimSpace(sections[4]), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			if kb, err := strconv.ParseInt(fields[1], 10, 64); err == nil && kb*1024 > metric.SmartDNSRSS {
+				metric.SmartDNSRSS = kb * 1024
+			}
+		}
+	}
+
+	return metric, nil
+}
+
+// QueryNodeMetrics 按时间桶聚合节点在 [start, end) 内的资源指标，step 为 0 时按时间范围自动选择
+func QueryNodeMetrics(nodeID uint, start, end time.Time, step int) (*models.NodeMetricSeries, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("结束时间必须晚于开始时间")
+	}
+
+	span := int(end.Sub(start).Seconds())
+	minStep := int(nodeMetricsInterval().Seconds())
+	if minStep < 1 {
+		minStep = 60
+	}
+	if step <= 0 {
+		step = minStep
+	}
+	if step < span/maxNodeMetricPoints {
+		step = span / maxNodeMetricPoints
+	}
+
+	var samples []models.NodeMetric
+	if err := database.DB.Where("node_id = ? AND created_at >= ? AND created_at < ?", nodeID, start, end).
+		Order("created_at").Find(&samples).Error; err != nil {
+		return nil, fmt.Errorf("查询资源指标失败: %w", err)
+	}
+
+	series := &models.NodeMetricSeries{
+		NodeID: nodeID,
+		Start:  start,
+		End:    end,
+		Step:   step,
+		Points: []models.NodeMetricPoint{},
+	}
+
+	bucketSize := time.Duration(step) * time.Second
+	var current *models.NodeMetricPoint
+	flush := func() {
+		if current == nil {
+			return
+		}
+		n := float64(current.Samples)
+		current.CPUUsage /= n
+		current.MemoryUsage /= n
+		current.DiskUsage /= n
+		current.Load1 /= n
+		current.Load5 /= n
+		current.Load15 /= n
+		series.Points = append(series.Points, *current)
+	}
+	for _, sample := range samples {
+		bucket := start.Add(sample.CreatedAt.Sub(start) / bucketSize * bucketSize)
+		if current == nil || !current.Time.Equal(bucket) {
+			flush()
+			current = &models.NodeMetricPoint{Time: bucket}
+		}
+		current.Samples++
+		current.CPUUsage += sample.CPUUsage
+		current.MemoryUsage += sample.MemoryUsage
+		current.DiskUsage += sample.DiskUsage
+		current.Load1 += sample.Load1
+		current.Load5 += sample.Load5
+		current.Load15 += sample.Load15
+		if sample.CPUUsage > current.CPUMax {
+			current.CPUMax = sample.CPUUsage
+		}
+		if sample.SmartDNSRSS > current.SmartDNSRSS {
+			current.SmartDNSRSS = sample.SmartDNSRSS
+		}
+	}
+	flush()
+
+	return series, nil
+}
+
+// PurgeExpiredNodeMetrics 删除超过保留天数的资源指标
+func PurgeExpiredNodeMetrics() {
+	cutoff := time.Now().AddDate(0, 0, -nodeMetricsRetentionDays())
+	result := database.DB.Where("created_at < ?", cutoff).Delete(&models.NodeMetric{})
+	if result.Error != nil {
+		log.Printf("清理节点资源指标失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("🗑️ 清理节点资源指标: 删除 %d 条记录", result.RowsAffected)
+	}
+}
@@ -24,31 +24,20 @@ func NewNotificationService() *NotificationService {
 
 // SendNotification 发送通知
 func (s *NotificationService) SendNotification(nodeID uint, eventType, title, content string) error {
-	// 获取节点信息（如果 nodeID > 0）
-	var node models.Node
-	if nodeID > 0 {
-		if err := database.DB.First(&node, nodeID).Error; err != nil {
-			log.Printf("获取节点信息失败: %v", err)
-			// 即使节点不存在，也继续发送通知（使用默认信息）
-			node.ID = nodeID
-			node.Name = "未知节点"
-			node.Host = "N/A"
-		}
-	} else {
-		// nodeID = 0 表示全局通知，使用默认值
-		node.ID = 0
-		node.Name = "系统全局"
-		node.Host = "N/A"
-	}
-
 	// 获取所有启用的通知渠道（包括节点专属和全局渠道）
 	var channels []models.NotificationChannel
 	if nodeID > 0 {
-		// 查询该节点的专属渠道 + 全局渠道
-		database.DB.Where("(node_id = ? OR node_id = 0) AND enabled = ?", nodeID, true).Find(&channels)
+		// 查询该节点的专属渠道 + 全局渠道，组织的渠道只接收本组织节点的事件
+		var nodeOrg []uint
+		database.DB.Model(&models.Node{}).Where("id = ?", nodeID).Pluck("organization_id", &nodeOrg)
+		orgIDs := []uint{0}
+		if len(nodeOrg) > 0 && nodeOrg[0] > 0 {
+			orgIDs = append(orgIDs, nodeOrg[0])
+		}
+		database.DB.Where("(node_id = ? OR node_id = 0) AND enabled = ? AND organization_id IN ?", nodeID, true, orgIDs).Find(&channels)
 	} else {
-		// 只查询全局渠道
-		database.DB.Where("node_id = 0 AND enabled = ?", true).Find(&channels)
+		// 只查询全局渠道，与节点无关的系统事件不发给组织的渠道
+		database.DB.Where("node_id = 0 AND enabled = ? AND organization_id = 0", true).Find(&channels)
 	}
 
 	if len(channels) == 0 {
@@ -64,16 +53,77 @@ func (s *NotificationService) SendNotification(nodeID uint, eventType, title, co
 		return nil
 	}
 
-	// 发送到所有订阅的渠道
+	// 写入投递队列，由队列负责失败重试
 	for _, channel := range subscribedChannels {
-		go s.sendToChannel(&channel, &node, eventType, title, content)
+		s.enqueue(&channel, nodeID, eventType, title, content)
 	}
 
 	return nil
 }
 
-// sendToChannel 发送到指定渠道
-func (s *NotificationService) sendToChannel(channel *models.NotificationChannel, node *models.Node, eventType, title, content string) {
+// SendToChannels 发送到指定的通知渠道，不再按渠道订阅的事件过滤；channelIDs 为空时按事件发送到全局渠道
+func (s *NotificationService) SendToChannels(channelIDs []uint, eventType, title, content string) error {
+	if len(channelIDs) == 0 {
+		return s.SendNotification(0, eventType, title, content)
+	}
+
+	var channels []models.NotificationChannel
+	if err := database.DB.Where("id IN ? AND enabled = ?", channelIDs, true).Find(&channels).Error; err != nil {
+		return fmt.Errorf("查询通知渠道失败: %w", err)
+	}
+	if len(channels) == 0 {
+		log.Printf("指定的通知渠道均不可用 (event: %s)", eventType)
+		return nil
+	}
+
+	for _, channel := range channels {
+		s.enqueue(&channel, 0, eventType, title, content)
+	}
+	return nil
+}
+
+// resolveNode 获取通知中展示的节点信息，nodeID = 0 表示全局通知
+func (s *NotificationService) resolveNode(nodeID uint) models.Node {
+	var node models.Node
+	if nodeID > 0 {
+		if err := database.DB.First(&node, nodeID).Error; err != nil {
+			log.Printf("获取节点信息失败: %v", err)
+			// 即使节点不存在，也继续发送通知（使用默认信息）
+			node.ID = nodeID
+			node.Name = "未知节点"
+			node.Host = "N/A"
+		}
+	} else {
+		// nodeID = 0 表示全局通知，使用默认值
+		node.ID = 0
+		node.Name = "系统全局"
+		node.Host = "N/A"
+	}
+
+	return node
+}
+
+// enqueue 创建投递记录并立即尝试投递
+func (s *NotificationService) enqueue(channel *models.NotificationChannel, nodeID uint, eventType, title, content string) {
+	delivery := models.NotificationDelivery{
+		ChannelID:     channel.ID,
+		NodeID:        nodeID,
+		EventType:     eventType,
+		Title:         title,
+		Content:       content,
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := database.DB.Create(&delivery).Error; err != nil {
+		log.Printf("创建通知投递记录失败: %v", err)
+		return
+	}
+
+	go s.attemptDelivery(delivery.ID)
+}
+
+// sendToChannel 发送到指定渠道并记录日志
+func (s *NotificationService) sendToChannel(channel *models.NotificationChannel, node *models.Node, eventType, title, content string) error {
 	log.Printf("发送通知到 %s (%s): %s", channel.Name, channel.Type, title)
 
 	var err error
@@ -89,8 +139,7 @@ func (s *NotificationService) sendToChannel(channel *models.NotificationChannel,
 	case "slack":
 		payload = s.buildSlackPayload(node, title, content)
 	default:
-		log.Printf("不支持的通知类型: %s", channel.Type)
-		return
+		return fmt.Errorf("不支持的通知类型: %s", channel.Type)
 	}
 
 	// 发送 HTTP 请求
@@ -116,6 +165,7 @@ func (s *NotificationService) sendToChannel(channel *models.NotificationChannel,
 	}
 
 	database.DB.Create(&notifLog)
+	return err
 }
 
 // buildWeChatPayload 构建企业微信消息
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// deliveryMaxAttempts 最大投递次数，超过后进入死信
+	deliveryMaxAttempts = 6
+	// deliveryBaseBackoff 首次重试间隔，之后按指数增长
+	deliveryBaseBackoff = 30 * time.Second
+	// deliveryMaxBackoff 重试间隔上限
+	deliveryMaxBackoff = 30 * time.Minute
+	// deliveryLease 投递处理中的占用时长，防止重复投递
+	deliveryLease = 2 * time.Minute
+	// deliveryRetention 成功投递记录保留时长
+	deliveryRetention = 7 * 24 * time.Hour
+)
+
+// deliveryBackoff 计算第 attempts 次失败后的重试间隔
+func deliveryBackoff(attempts int) time.Duration {
+	backoff := deliveryBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= deliveryMaxBackoff {
+			return deliveryMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// claimDelivery 占用一条到期的投递记录，返回是否占用成功
+func (s *NotificationService) claimDelivery(id uint) bool {
+	now := time.Now()
+	result := database.DB.Model(&models.NotificationDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.DeliveryStatusPending, now).
+		Update("next_attempt_at", now.Add(deliveryLease))
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// attemptDelivery 执行一次投递并根据结果更新队列状态
+func (s *NotificationService) attemptDelivery(id uint) {
+	if !s.claimDelivery(id) {
+		return
+	}
+
+	var delivery models.NotificationDelivery
+	if err := database.DB.First(&delivery, id).Error; err != nil {
+		log.Printf("获取通知投递记录失败: %v", err)
+		return
+	}
+
+	var channel models.NotificationChannel
+	if err := database.DB.First(&channel, delivery.ChannelID).Error; err != nil {
+		s.markDead(&delivery, "通知渠道不存在")
+		return
+	}
+	if !channel.Enabled {
+		s.markDead(&delivery, "通知渠道已禁用")
+		return
+	}
+
+	node := s.resolveNode(delivery.NodeID)
+	err := s.sendToChannel(&channel, &node, delivery.EventType, delivery.Title, delivery.Content)
+	delivery.Attempts++
+
+	if err == nil {
+		now := time.Now()
+		database.DB.Model(&delivery).Updates(map[string]interface{}{
+			"status":       models.DeliveryStatusSuccess,
+			"attempts":     delivery.Attempts,
+			"last_error":   "",
+			"delivered_at": &now,
+		})
+		s.markChannelHealthy(&channel)
+		return
+	}
+
+	if delivery.Attempts >= deliveryMaxAttempts {
+		s.markDead(&delivery, err.Error())
+	} else {
+		database.DB.Model(&delivery).Updates(map[string]interface{}{
+			"attempts":        delivery.Attempts,
+			"last_error":      err.Error(),
+			"next_attempt_at": time.Now().Add(deliveryBackoff(delivery.Attempts)),
+		})
+	}
+	s.markChannelFailing(&channel, err)
+}
+
+// markDead 将投递记录标记为死信
+func (s *NotificationService) markDead(delivery *models.NotificationDelivery, reason string) {
+	log.Printf("通知投递失败已进入死信 (delivery: %d, channel: %d): %s", delivery.ID, delivery.ChannelID, reason)
+	database.DB.Model(delivery).Updates(map[string]interface{}{
+		"status":     models.DeliveryStatusDead,
+		"attempts":   delivery.Attempts,
+		"last_error": reason,
+	})
+}
+
+// markChannelHealthy 投递成功后清除渠道的故障状态
+func (s *NotificationService) markChannelHealthy(channel *models.NotificationChannel) {
+	if channel.FailingSince == nil && !channel.AlarmSent {
+		return
+	}
+	database.DB.Model(channel).Updates(map[string]interface{}{
+		"failing_since": nil,
+		"last_error":    "",
+		"alarm_sent":    false,
+	})
+}
+
+// markChannelFailing 记录渠道故障，持续时间超过阈值时发送告警
+func (s *NotificationService) markChannelFailing(channel *models.NotificationChannel, sendErr error) {
+	now := time.Now()
+	updates := map[string]interface{}{"last_error": sendErr.Error()}
+	if channel.FailingSince == nil {
+		channel.FailingSince = &now
+		updates["failing_since"] = &now
+	}
+
+	threshold := notificationAlarmThreshold()
+	if !channel.AlarmSent && now.Sub(*channel.FailingSince) >= threshold {
+		updates["alarm_sent"] = true
+		defer s.sendChannelFailingAlarm(channel, sendErr, threshold)
+	}
+
+	database.DB.Model(channel).Updates(updates)
+}
+
+// sendChannelFailingAlarm 通过其他全局渠道发送渠道故障告警
+func (s *NotificationService) sendChannelFailingAlarm(channel *models.NotificationChannel, sendErr error, threshold time.Duration) {
+	var channels []models.NotificationChannel
+	database.DB.Where("node_id = 0 AND enabled = ? AND id <> ?", true, channel.ID).Find(&channels)
+
+	subscribedChannels := s.filterChannelsByEvent(channels, "notification_channel_failing")
+	if len(subscribedChannels) == 0 {
+		log.Printf("通知渠道 %s 持续故障，但没有其他可用渠道发送告警", channel.Name)
+		return
+	}
+
+	title := "🚨 通知渠道故障"
+	content := fmt.Sprintf(
+		"通知渠道「%s」(%s) 已连续发送失败超过 %d 分钟\n故障开始: %s\n最近错误: %s\n\n请检查 Webhook 是否已失效",
+		channel.Name,
+		channel.Type,
+		int(threshold.Minutes()),
+		channel.FailingSince.Format("2006-01-02 15:04:05"),
+		sendErr.Error(),
+	)
+
+	for _, c := range subscribedChannels {
+		s.enqueue(&c, 0, "notification_channel_failing", title, content)
+	}
+}
+
+// notificationAlarmThreshold 渠道故障告警阈值
+func notificationAlarmThreshold() time.Duration {
+	minutes, err := strconv.Atoi(config.GetConfig().NotificationAlarmMinutes)
+	if err != nil || minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// RetryDelivery 将死信重新放回投递队列
+func (s *NotificationService) RetryDelivery(id uint) error {
+	result := database.DB.Model(&models.NotificationDelivery{}).
+		Where("id = ? AND status = ?", id, models.DeliveryStatusDead).
+		Updates(map[string]interface{}{
+			"status":          models.DeliveryStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	go s.attemptDelivery(id)
+	return nil
+}
+
+// NotificationQueueWorker 通知投递队列处理器
+type NotificationQueueWorker struct {
+	ticker              *time.Ticker
+	stopChan            chan bool
+	notificationService *NotificationService
+}
+
+// NewNotificationQueueWorker 创建投递队列处理器
+func NewNotificationQueueWorker(interval time.Duration) *NotificationQueueWorker {
+	return &NotificationQueueWorker{
+		ticker:              time.NewTicker(interval),
+		stopChan:            make(chan bool),
+		notificationService: NewNotificationService(),
+	}
+}
+
+// Start 启动队列处理
+func (w *NotificationQueueWorker) Start() {
+	log.Println("通知投递队列已启动")
+
+	go func() {
+		for {
+			select {
+			case <-w.ticker.C:
+				w.processDue()
+			case <-w.stopChan:
+				log.Println("通知投递队列已停止")
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止队列处理
+func (w *NotificationQueueWorker) Stop() {
+	w.ticker.Stop()
+	w.stopChan <- true
+}
+
+// processDue 处理到期的投递记录并清理历史记录
+func (w *NotificationQueueWorker) processDue() {
+	var ids []uint
+	if err := database.DB.Model(&models.NotificationDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryStatusPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(50).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("查询待投递通知失败: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		w.notificationService.attemptDelivery(id)
+	}
+
+	database.DB.Where("status = ? AND updated_at < ?", models.DeliveryStatusSuccess, time.Now().Add(-deliveryRetention)).
+		Delete(&models.NotificationDelivery{})
+}
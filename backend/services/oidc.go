@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// oidcDiscovery OpenID Provider 元数据
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcJWK 签名公钥
+type oidcJWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// OIDCProvider 授权码模式（带 PKCE）的 OIDC 客户端
+type OIDCProvider struct {
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	discoveryAt time.Time
+	keys        map[string]interface{}
+	keysAt      time.Time
+}
+
+// oidcMetadataTTL 元数据和公钥缓存时间
+const oidcMetadataTTL = time.Hour
+
+// NewOIDCProvider 创建 OIDC 客户端
+func NewOIDCProvider() *OIDCProvider {
+	return &OIDCProvider{
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// OIDCEnabled 是否配置了 OIDC 登录
+func OIDCEnabled() bool {
+	cfg := config.GetConfig()
+	return cfg.OIDCIssuer != "" && cfg.OIDCClientID != "" && cfg.OIDCRedirectURL != ""
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, endpoint string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// metadata 获取并缓存 Provider 元数据
+func (p *OIDCProvider) metadata(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveryAt) < oidcMetadataTTL {
+		return p.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(config.GetConfig().OIDCIssuer, "/")
+	var discovery oidcDiscovery
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("获取 OIDC 元数据失败: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC 元数据 issuer 不匹配: %s", discovery.Issuer)
+	}
+
+	p.discovery = &discovery
+	p.discoveryAt = time.Now()
+	return p.discovery, nil
+}
+
+// signingKey 按 kid 查找签名公钥，找不到时刷新一次（应对 IdP 轮换密钥）
+func (p *OIDCProvider) signingKey(ctx context.Context, kid string) (interface{}, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok && time.Since(p.keysAt) < oidcMetadataTTL {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []oidcJWK `json:"keys"`
+	}
+	if err := p.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("获取 OIDC 公钥失败: %w", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	p.keys = keys
+	p.keysAt = time.Now()
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("未找到 ID Token 签名公钥: %s", kid)
+	}
+	return key, nil
+}
+
+func (k oidcJWK) publicKey() (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型: %s", k.Kty)
+	}
+}
+
+// pkceChallenge 计算 S256 code_challenge
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthURL 生成跳转到 IdP 的授权地址
+func (p *OIDCProvider) AuthURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := config.GetConfig()
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.OIDCClientID},
+		"redirect_uri":          {cfg.OIDCRedirectURL},
+		"scope":                 {cfg.OIDCScopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange 用授权码换取令牌，校验 ID Token 后返回用户身份
+func (p *OIDCProvider) Exchange(ctx context.Context, code, nonce, verifier string) (*models.SSOIdentity, error) {
+	discovery, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.GetConfig()
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {cfg.OIDCRedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(cfg.OIDCClientID), url.QueryEscape(cfg.OIDCClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求令牌失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求令牌失败: HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("解析令牌响应失败: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("令牌响应中缺少 id_token")
+	}
+
+	claims, err := p.verifyIDToken(ctx, tokens.IDToken, discovery.Issuer, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// ID Token 不含分组时尝试从 userinfo 补充
+	if _, ok := claims[cfg.OIDCGroupsClaim]; !ok && discovery.UserinfoEndpoint != "" && tokens.AccessToken != "" {
+		if userinfo, err := p.userinfo(ctx, discovery.UserinfoEndpoint, tokens.AccessToken); err == nil {
+			if subject, _ := userinfo["sub"].(string); subject == claims["sub"] {
+				for key, value := range userinfo {
+					if _, exists := claims[key]; !exists {
+						claims[key] = value
+					}
+				}
+			}
+		}
+	}
+
+	return identityFromClaims(claims, cfg.OIDCGroupsClaim)
+}
+
+// verifyIDToken 校验签名、issuer、audience、过期时间和 nonce
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, raw, issuer, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(config.GetConfig().OIDCClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ID Token 校验失败: %w", err)
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, fmt.Errorf("ID Token nonce 不匹配")
+	}
+	return claims, nil
+}
+
+func (p *OIDCProvider) userinfo(ctx context.Context, endpoint, accessToken string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var userinfo map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&userinfo); err != nil {
+		return nil, err
+	}
+	return userinfo, nil
+}
+
+// identityFromClaims 用户名依次取 preferred_username、email、sub
+func identityFromClaims(claims map[string]interface{}, groupsClaim string) (*models.SSOIdentity, error) {
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("ID Token 缺少 sub")
+	}
+
+	identity := &models.SSOIdentity{Source: models.AuthSourceOIDC, Subject: subject}
+	identity.Email, _ = claims["email"].(string)
+	identity.Username, _ = claims["preferred_username"].(string)
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		identity.Username = subject
+	}
+
+	switch groups := claims[groupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	case string:
+		for _, name := range strings.FieldsFunc(groups, func(r rune) bool { return r == ',' || r == ' ' }) {
+			identity.Groups = append(identity.Groups, name)
+		}
+	}
+	return identity, nil
+}
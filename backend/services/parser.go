@@ -1,17 +1,21 @@
 package services
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"smartdns-manager/models"
+	"sort"
 	"strings"
 	"time"
 )
 
-type ConfigParser struct{}
+type ConfigParser struct {
+	annotate bool // 生成时写入溯源注释
+}
 
 func NewConfigParser() *ConfigParser {
-	return &ConfigParser{}
+	return &ConfigParser{annotate: configAnnotationsEnabled()}
 }
 
 func (p *ConfigParser) Parse(content string) (*models.SmartDNSConfig, error) {
@@ -21,66 +25,231 @@ func (p *ConfigParser) Parse(content string) (*models.SmartDNSConfig, error) {
 		DomainSets:    []models.DomainSet{},
 		DomainRules:   []models.DomainRule{},
 		Nameservers:   []models.Nameserver{},
+		ClientRules:   []models.ClientRule{},
 		BasicSettings: make(map[string]string),
 	}
 
 	lines := strings.Split(content, "\n")
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	// 当前所在的 group-begin/group-end 块
+	var group *models.ConfigGroup
+	// 前一条已识别的指令，原样保留的行生成时写回到它之后
+	var prev string
+
+	for i, rawLine := range lines {
+		line := strings.TrimSpace(rawLine)
 
 		// 跳过空行和注释
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 
+		// 行尾的溯源注释只用于还原对应的管理记录 ID
+		line, annotation := stripAnnotation(line)
+
+		name, value := splitDirective(line)
+
+		// group 块内的指令原样保留，避免被提升为全局配置
+		if group != nil {
+			if name == "group-end" {
+				config.Groups = append(config.Groups, *group)
+				prev = directiveKey("group-begin " + group.Name)
+				group = nil
+			} else {
+				group.Lines = append(group.Lines, line)
+			}
+			continue
+		}
+
+		after := prev
+		prev = directiveKey(line)
+
+		switch name {
+		case "group-begin":
+			group = &models.ConfigGroup{Name: value}
+			continue
+		case "conf-file":
+			config.ConfFiles = append(config.ConfFiles, value)
+			continue
+		}
+
 		// 解析 server
 		if strings.HasPrefix(line, "server ") {
-			server := p.parseServer(line)
-			if server != nil {
+			if server := p.parseServer(line); server != nil {
+				server.ID = annotatedID(annotation, models.AuditEntityServer)
 				config.Servers = append(config.Servers, *server)
+				continue
 			}
 		}
 
 		// 解析 address
 		if strings.HasPrefix(line, "address /") {
-			address := p.parseAddress(line)
-			if address != nil {
+			if address := p.parseAddress(line); address != nil {
+				address.ID = annotatedID(annotation, models.AuditEntityAddress)
 				config.Addresses = append(config.Addresses, *address)
+				continue
+			}
+		}
+
+		// 解析 cname
+		if strings.HasPrefix(line, "cname /") {
+			if cname := p.parseCNAME(line); cname != nil {
+				cname.ID = annotatedID(annotation, models.AuditEntityAddress)
+				config.Addresses = append(config.Addresses, *cname)
+				continue
 			}
 		}
 
 		// 解析 domain-set
 		if strings.HasPrefix(line, "domain-set ") {
-			domainSet := p.parseDomainSet(line)
-			if domainSet != nil {
+			if domainSet := p.parseDomainSet(line); domainSet != nil {
+				domainSet.ID = annotatedID(annotation, models.AuditEntityDomainSet)
 				config.DomainSets = append(config.DomainSets, *domainSet)
+				continue
 			}
 		}
 
 		// 解析 domain-rules
 		if strings.HasPrefix(line, "domain-rules /") {
-			rule := p.parseDomainRule(line)
-			if rule != nil {
+			if rule := p.parseDomainRule(line); rule != nil {
+				rule.ID = annotatedID(annotation, models.AuditEntityDomainRule)
 				config.DomainRules = append(config.DomainRules, *rule)
+				continue
 			}
 		}
 
 		// 解析 nameserver
 		if strings.HasPrefix(line, "nameserver /") {
-			ns := p.parseNameserver(line)
-			if ns != nil {
+			if ns := p.parseNameserver(line); ns != nil {
+				ns.ID = annotatedID(annotation, models.AuditEntityNameserver)
 				config.Nameservers = append(config.Nameservers, *ns)
+				continue
+			}
+		}
+
+		// 解析 client-rules
+		if name == "client-rules" {
+			if rule := p.parseClientRule(value); rule != nil {
+				rule.ID = annotatedID(annotation, models.AuditEntityClientRule)
+				config.ClientRules = append(config.ClientRules, *rule)
+				continue
 			}
 		}
 
 		// 解析基础设置
-		p.parseBasicSetting(line, config.BasicSettings)
+		if p.parseBasicSetting(line, config.BasicSettings) {
+			continue
+		}
+
+		// 已知的其他指令（bind-tcp、bind-tls、ip-rules、proxy-server 等）按原顺序保留
+		if knownDirectives[name] {
+			config.Directives = append(config.Directives, models.ConfigDirective{Name: name, Value: value})
+			continue
+		}
+
+		// 无法识别的指令（如 nftset、ipset）原样保留，避免同步时丢失
+		config.OtherLines = append(config.OtherLines, models.ConfigLine{
+			LineNumber: i + 1,
+			Content:    strings.TrimRight(rawLine, " \t\r"),
+			After:      after,
+		})
 	}
 
+	// 未闭合的 group 块同样保留
+	if group != nil {
+		config.Groups = append(config.Groups, *group)
+	}
+
+	mergeParsedAddresses(config)
+
 	return config, nil
 }
 
+// knownDirectives 作为通用指令解析的已知指令
+var knownDirectives = map[string]bool{
+	"bind": true, "bind-tcp": true, "bind-tls": true, "bind-https": true,
+	"bind-cert-file": true, "bind-cert-key-file": true, "bind-cert-key-pass": true,
+	"server-tcp": true, "server-tls": true, "server-https": true,
+	"ip-alias": true, "ip-rules": true,
+	"bogus-nxdomain": true, "blacklist-ip": true, "whitelist-ip": true, "ignore-ip": true,
+	"proxy-server": true,
+}
+
+// splitDirective 拆分指令名和参数
+func splitDirective(line string) (string, string) {
+	if idx := strings.IndexAny(line, " \t"); idx > 0 {
+		return line[:idx], strings.TrimSpace(line[idx+1:])
+	}
+	return line, ""
+}
+
+// ParseGroupBlock 将 group 块解析为结构化的分组配置
+// client-rules、server、speed-check-mode 拆分为独立字段，其余指令保留到 ExtraLines
+func (p *ConfigParser) ParseGroupBlock(group models.ConfigGroup) *models.GroupBlock {
+	var clientRules, servers, extraLines []string
+	block := &models.GroupBlock{Name: group.Name, Enabled: true}
+
+	for _, line := range group.Lines {
+		name, value := splitDirective(line)
+		switch name {
+		case "client-rules":
+			clientRules = append(clientRules, value)
+		case "server":
+			servers = append(servers, value)
+		case "speed-check-mode":
+			block.SpeedCheckMode = value
+		default:
+			extraLines = append(extraLines, line)
+		}
+	}
+
+	block.ClientRules = marshalStringList(clientRules)
+	block.Servers = marshalStringList(servers)
+	block.ExtraLines = strings.Join(extraLines, "\n")
+	return block
+}
+
+// GenerateGroupBlock 根据分组配置生成 group 块
+func (p *ConfigParser) GenerateGroupBlock(block *models.GroupBlock) models.ConfigGroup {
+	group := models.ConfigGroup{Name: block.Name}
+
+	for _, rule := range unmarshalStringList(block.ClientRules) {
+		group.Lines = append(group.Lines, "client-rules "+rule)
+	}
+	for _, server := range unmarshalStringList(block.Servers) {
+		group.Lines = append(group.Lines, "server "+server)
+	}
+	if block.SpeedCheckMode != "" {
+		group.Lines = append(group.Lines, "speed-check-mode "+block.SpeedCheckMode)
+	}
+	for _, line := range strings.Split(block.ExtraLines, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			group.Lines = append(group.Lines, line)
+		}
+	}
+
+	return group
+}
+
+// marshalStringList 将字符串列表编码为 JSON 数组
+func marshalStringList(values []string) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// unmarshalStringList 解析 JSON 数组，格式错误时返回空列表
+func unmarshalStringList(value string) []string {
+	var values []string
+	if value == "" {
+		return values
+	}
+	json.Unmarshal([]byte(value), &values)
+	return values
+}
+
 func (p *ConfigParser) parseServer(line string) *models.DNSServer {
 	re := regexp.MustCompile(`server\s+(\S+)(?:\s+(.*))?`)
 	matches := re.FindStringSubmatch(line)
@@ -129,9 +298,30 @@ func (p *ConfigParser) parseAddress(line string) *models.AddressMap {
 		return nil
 	}
 
+	// 值后面可能带有生成配置时写入的注释：address /example.com/1.2.3.4,::1 # 注释
+	address := &models.AddressMap{
+		Domain: matches[1],
+		Type:   "address",
+	}
+	value, comment, _ := strings.Cut(matches[2], " #")
+	address.IP = strings.Join(splitAddressIPs(value), ",")
+	address.Comment = strings.TrimSpace(comment)
+
+	return address
+}
+
+func (p *ConfigParser) parseCNAME(line string) *models.AddressMap {
+	re := regexp.MustCompile(`cname\s+/(.*?)/(.*)`)
+	matches := re.FindStringSubmatch(line)
+
+	if len(matches) != 3 {
+		return nil
+	}
+
 	return &models.AddressMap{
 		Domain: matches[1],
-		IP:     matches[2],
+		CNAME:  strings.TrimSpace(matches[2]),
+		Type:   "cname",
 	}
 }
 
@@ -183,28 +373,31 @@ func (p *ConfigParser) parseDomainRule(line string) *models.DomainRule {
 		rule.Domain = domain
 	}
 
-	// 解析选项
+	// 解析选项，已提取到字段的选项从 OtherOptions 中移除，避免生成时重复写出
 	if options != "" {
 		// 提取 -address
-		addressRe := regexp.MustCompile(`-address\s+(\S+)`)
+		addressRe := regexp.MustCompile(`(?:^|\s)-address\s+(\S+)`)
 		if addressMatch := addressRe.FindStringSubmatch(options); len(addressMatch) > 1 {
 			rule.Address = addressMatch[1]
+			options = addressRe.ReplaceAllString(options, " ")
 		}
 
 		// 提取 -nameserver
-		nameserverRe := regexp.MustCompile(`-nameserver\s+(\S+)`)
+		nameserverRe := regexp.MustCompile(`(?:^|\s)-nameserver\s+(\S+)`)
 		if nsMatch := nameserverRe.FindStringSubmatch(options); len(nsMatch) > 1 {
 			rule.Nameserver = nsMatch[1]
+			options = nameserverRe.ReplaceAllString(options, " ")
 		}
 
 		// 提取 -speed-check-mode
-		speedRe := regexp.MustCompile(`-speed-check-mode\s+(\S+)`)
+		speedRe := regexp.MustCompile(`(?:^|\s)-speed-check-mode\s+(\S+)`)
 		if speedMatch := speedRe.FindStringSubmatch(options); len(speedMatch) > 1 {
 			rule.SpeedCheckMode = speedMatch[1]
+			options = speedRe.ReplaceAllString(options, " ")
 		}
 
 		// 保存其他选项
-		rule.OtherOptions = options
+		rule.OtherOptions = strings.Join(strings.Fields(options), " ")
 	}
 
 	return rule
@@ -240,7 +433,53 @@ func (p *ConfigParser) parseNameserver(line string) *models.Nameserver {
 	return ns
 }
 
-func (p *ConfigParser) parseBasicSetting(line string, settings map[string]string) {
+// parseClientRule 解析 client-rules 参数：client [-group name] [options]
+func (p *ConfigParser) parseClientRule(value string) *models.ClientRule {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	rule := &models.ClientRule{Client: fields[0], Enabled: true}
+	var options []string
+	for i := 1; i < len(fields); i++ {
+		if fields[i] == "-group" && i+1 < len(fields) {
+			rule.Group = fields[i+1]
+			i++
+			continue
+		}
+		options = append(options, fields[i])
+	}
+	rule.Options = strings.Join(options, " ")
+
+	if rule.Group == models.ClientRuleBlockedGroup {
+		rule.Blocked = true
+		rule.Group = ""
+	}
+
+	return rule
+}
+
+// GenerateClientRule 生成 client-rules 指令
+func (p *ConfigParser) GenerateClientRule(rule *models.ClientRule) string {
+	line := "client-rules " + rule.Client
+
+	group := rule.Group
+	if rule.Blocked {
+		group = models.ClientRuleBlockedGroup
+	}
+	if group != "" {
+		line += " -group " + group
+	}
+	if rule.Options != "" {
+		line += " " + rule.Options
+	}
+
+	return line
+}
+
+// parseBasicSetting 解析基础设置，重复出现的设置（如多个 bind）交由调用方作为通用指令保留
+func (p *ConfigParser) parseBasicSetting(line string, settings map[string]string) bool {
 	basicKeys := []string{
 		"bind", "cache-size", "prefetch-domain", "serve-expired",
 		"force-AAAA-SOA", "dualstack-ip-selection", "rr-ttl-min",
@@ -251,19 +490,108 @@ func (p *ConfigParser) parseBasicSetting(line string, settings map[string]string
 
 	for _, key := range basicKeys {
 		if strings.HasPrefix(line, key+" ") || strings.HasPrefix(line, key+":") {
+			if _, exists := settings[key]; exists {
+				return false
+			}
 			value := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, key), ":"))
 			settings[key] = strings.TrimSpace(value)
-			break
+			return true
+		}
+	}
+
+	return false
+}
+
+// directiveKey 规范化指令行（去掉溯源注释、合并空白），用于定位原样保留行的前一条指令
+func directiveKey(line string) string {
+	line, _ = stripAnnotation(strings.TrimSpace(line))
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// configWriter 生成配置时把原样保留的行写回到原文件中紧随的那条指令之后，
+// 保持 nftset、ipset 等指令与所引用的 domain-set、group 之间的先后顺序
+type configWriter struct {
+	strings.Builder
+	preserved map[string][]string // 前一条指令 -> 紧随其后的保留行
+}
+
+func newConfigWriter(otherLines []models.ConfigLine) *configWriter {
+	lines := make([]models.ConfigLine, len(otherLines))
+	copy(lines, otherLines)
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].LineNumber < lines[j].LineNumber
+	})
+
+	w := &configWriter{preserved: make(map[string][]string)}
+	for _, line := range lines {
+		w.preserved[line.After] = append(w.preserved[line.After], line.Content)
+	}
+	return w
+}
+
+// directive 写入一条指令，随后写入原文件中紧随其后的保留行
+func (w *configWriter) directive(line, suffix string) {
+	w.WriteString(line + suffix + "\n")
+	w.flushAfter(line)
+}
+
+// flushAfter 写入锚定在指定指令之后的保留行，连续的保留行依次锚定在前一行上
+func (w *configWriter) flushAfter(line string) {
+	key := directiveKey(line)
+	lines, ok := w.preserved[key]
+	if !ok {
+		return
+	}
+	delete(w.preserved, key)
+	for _, content := range lines {
+		w.WriteString(content + "\n")
+		w.flushAfter(content)
+	}
+}
+
+// flushOrphans 写入前一条指令已不存在的保留行（如对应记录已被删除），
+// 放在所有受管指令之后，保证其引用的 domain-set、group 已先定义
+func (w *configWriter) flushOrphans(otherLines []models.ConfigLine) {
+	lines := make([]models.ConfigLine, len(otherLines))
+	copy(lines, otherLines)
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].LineNumber < lines[j].LineNumber
+	})
+
+	header := false
+	for _, line := range lines {
+		if _, ok := w.preserved[line.After]; !ok {
+			continue
+		}
+		if !header {
+			w.WriteString("# Preserved Directives\n")
+			header = true
 		}
+		w.flushAfter(line.After)
+	}
+	if header {
+		w.WriteString("\n")
 	}
 }
 
 func (p *ConfigParser) Generate(config *models.SmartDNSConfig) string {
-	var builder strings.Builder
+	builder := newConfigWriter(config.OtherLines)
 	builder.WriteString("# SmartDNS Configuration\n")
 	builder.WriteString("# Auto-generated by SmartDNS Manager\n")
 	builder.WriteString(fmt.Sprintf("# Generated at: %s\n\n", time.Now().Format("2006-01-02 15:04:05")))
 
+	// 原文件中位于所有已识别指令之前的保留行
+	if _, ok := builder.preserved[""]; ok {
+		builder.flushAfter("")
+		builder.WriteString("\n")
+	}
+
+	// 启用溯源注释时，受管指令行尾标明对应的记录 ID、修改时间和修改人
+	var annotations *annotationIndex
+	if p.annotate {
+		annotations = loadAnnotationIndex()
+	}
+
 	// 基础设置
 	if len(config.BasicSettings) > 0 {
 		builder.WriteString("# Basic Settings\n")
@@ -277,7 +605,7 @@ func (p *ConfigParser) Generate(config *models.SmartDNSConfig) string {
 		}
 		for _, key := range orderedKeys {
 			if value, ok := config.BasicSettings[key]; ok {
-				builder.WriteString(fmt.Sprintf("%s %s\n", key, value))
+				builder.directive(fmt.Sprintf("%s %s", key, value), "")
 			}
 		}
 		// 输出其他设置
@@ -290,7 +618,20 @@ func (p *ConfigParser) Generate(config *models.SmartDNSConfig) string {
 				}
 			}
 			if !found {
-				builder.WriteString(fmt.Sprintf("%s %s\n", key, value))
+				builder.directive(fmt.Sprintf("%s %s", key, value), "")
+			}
+		}
+		builder.WriteString("\n")
+	}
+
+	// 其他指令（监听、证书、代理、IP 规则等需在 server 之前定义）
+	if len(config.Directives) > 0 {
+		builder.WriteString("# Additional Directives\n")
+		for _, directive := range config.Directives {
+			if directive.Value != "" {
+				builder.directive(fmt.Sprintf("%s %s", directive.Name, directive.Value), "")
+			} else {
+				builder.directive(directive.Name, "")
 			}
 		}
 		builder.WriteString("\n")
@@ -300,11 +641,11 @@ func (p *ConfigParser) Generate(config *models.SmartDNSConfig) string {
 	if len(config.Servers) > 0 {
 		builder.WriteString("# DNS Servers\n")
 		for _, server := range config.Servers {
-			builder.WriteString(fmt.Sprintf("server %s", server.Address))
+			line := fmt.Sprintf("server %s", server.Address)
 			if server.Options != "" {
-				builder.WriteString(fmt.Sprintf(" %s", server.Options))
+				line += fmt.Sprintf(" %s", server.Options)
 			}
-			builder.WriteString("\n")
+			builder.directive(line, annotations.suffix(models.AuditEntityServer, server.ID, server.Address))
 		}
 		builder.WriteString("\n")
 	}
@@ -313,18 +654,23 @@ func (p *ConfigParser) Generate(config *models.SmartDNSConfig) string {
 	if len(config.Addresses) > 0 {
 		builder.WriteString("# Address Mappings\n")
 		for _, addr := range config.Addresses {
+			var line string
 			if addr.Type == "cname" {
 				// CNAME 格式
-				builder.WriteString(fmt.Sprintf("cname /%s/%s", addr.Domain, addr.CNAME))
+				line = fmt.Sprintf("cname /%s/%s", addr.Domain, addr.CNAME)
 			} else {
-				// Address 格式
-				builder.WriteString(fmt.Sprintf("address /%s/%s", addr.Domain, addr.IP))
+				// Address 格式，多个 IP 以逗号分隔
+				line = fmt.Sprintf("address /%s/%s", addr.Domain, addr.IP)
 			}
 			// 添加注释
 			if addr.Comment != "" {
-				builder.WriteString(fmt.Sprintf(" # %s", addr.Comment))
+				line += fmt.Sprintf(" # %s", addr.Comment)
+			}
+			builder.directive(line, annotations.suffix(models.AuditEntityAddress, addr.ID, addr.Domain))
+			// TTL 等选项 address 指令不支持，通过同域名的 domain-rules 设置
+			if opts := addressRuleOptions(&addr); opts != "" {
+				builder.directive(fmt.Sprintf("domain-rules /%s/ %s", addr.Domain, opts), "")
 			}
-			builder.WriteString("\n")
 		}
 		builder.WriteString("\n")
 	}
@@ -333,7 +679,8 @@ func (p *ConfigParser) Generate(config *models.SmartDNSConfig) string {
 	if len(config.DomainSets) > 0 {
 		builder.WriteString("# Domain Sets\n")
 		for _, ds := range config.DomainSets {
-			builder.WriteString(fmt.Sprintf("domain-set -name %s -file %s\n", ds.Name, ds.FilePath))
+			builder.directive(fmt.Sprintf("domain-set -name %s -file %s", ds.Name, ds.FilePath),
+				annotations.suffix(models.AuditEntityDomainSet, ds.ID, ds.Name))
 		}
 		builder.WriteString("\n")
 	}
@@ -377,7 +724,7 @@ func (p *ConfigParser) Generate(config *models.SmartDNSConfig) string {
 			if len(opts) > 0 {
 				line += " " + strings.Join(opts, " ")
 			}
-			builder.WriteString(line + "\n")
+			builder.directive(line, annotations.suffix(models.AuditEntityDomainRule, rule.ID, rule.Domain))
 		}
 		builder.WriteString("\n")
 	}
@@ -392,10 +739,49 @@ func (p *ConfigParser) Generate(config *models.SmartDNSConfig) string {
 			} else {
 				domain = ns.Domain
 			}
-			builder.WriteString(fmt.Sprintf("nameserver /%s/%s\n", domain, ns.Group))
+			builder.directive(fmt.Sprintf("nameserver /%s/%s", domain, ns.Group),
+				annotations.suffix(models.AuditEntityNameserver, ns.ID, ns.Domain))
 		}
 		builder.WriteString("\n")
 	}
 
+	// Client Rules
+	if len(config.ClientRules) > 0 {
+		builder.WriteString("# Client Rules\n")
+		for _, rule := range config.ClientRules {
+			builder.directive(p.GenerateClientRule(&rule),
+				annotations.suffix(models.AuditEntityClientRule, rule.ID, rule.Client))
+		}
+		builder.WriteString("\n")
+	}
+
+	// Group 配置块
+	if len(config.Groups) > 0 {
+		builder.WriteString("# Groups\n")
+		for _, group := range config.Groups {
+			begin := strings.TrimSpace("group-begin " + group.Name)
+			builder.WriteString(begin + "\n")
+			for _, line := range group.Lines {
+				builder.WriteString(line + "\n")
+			}
+			builder.WriteString("group-end\n")
+			// 原文件中紧跟在 group 块之后的保留行锚定在 group-begin 上
+			builder.flushAfter(begin)
+		}
+		builder.WriteString("\n")
+	}
+
+	// 引入的配置文件
+	if len(config.ConfFiles) > 0 {
+		builder.WriteString("# Included Files\n")
+		for _, file := range config.ConfFiles {
+			builder.directive(fmt.Sprintf("conf-file %s", file), "")
+		}
+		builder.WriteString("\n")
+	}
+
+	// 前一条指令已不存在的保留行
+	builder.flushOrphans(config.OtherLines)
+
 	return builder.String()
 }
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDomainRuleRoundTrip 解析再生成 domain-rules 不应重复已提取到字段的选项
+func TestDomainRuleRoundTrip(t *testing.T) {
+	cases := []struct {
+		name  string
+		line  string
+		other string
+	}{
+		{"只有地址", "domain-rules /ads.example.com/ -address #", ""},
+		{"地址与其他选项", "domain-rules /example.com/ -address 1.2.3.4 -nameserver office -speed-check-mode ping -no-cache", "-no-cache"},
+		{"其他选项在前", "domain-rules /domain-set:cn/ -dualstack-ip-selection no -address 10.0.0.1", "-dualstack-ip-selection no"},
+	}
+
+	parser := NewConfigParser()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := parser.Parse(tc.line + "\n")
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			if len(config.DomainRules) != 1 {
+				t.Fatalf("期望 1 条域名规则，实际 %d", len(config.DomainRules))
+			}
+			if got := config.DomainRules[0].OtherOptions; got != tc.other {
+				t.Errorf("OtherOptions = %q，期望 %q", got, tc.other)
+			}
+
+			generated := parser.Generate(config)
+			if strings.Count(generated, "-address ") != 1 {
+				t.Errorf("生成的配置重复了 -address:\n%s", generated)
+			}
+
+			again, err := parser.Parse(generated)
+			if err != nil {
+				t.Fatalf("再次解析失败: %v", err)
+			}
+			if len(again.DomainRules) != 1 {
+				t.Fatalf("再次解析期望 1 条域名规则，实际 %d", len(again.DomainRules))
+			}
+			if again.DomainRules[0] != config.DomainRules[0] {
+				t.Errorf("往返后规则不一致\n第一次: %+v\n第二次: %+v", config.DomainRules[0], again.DomainRules[0])
+			}
+			if regenerated := parser.Generate(again); regenerated != generated {
+				t.Errorf("第二次生成的配置与第一次不同\n第一次:\n%s\n第二次:\n%s", generated, regenerated)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// patchCheckTimeout 单个节点检查更新的超时时间，yum/dnf 需要刷新元数据时较慢
+const patchCheckTimeout = 3 * time.Minute
+
+// patchCheckScript 在节点上检查待安装更新与重启状态，每行输出一个 key=value
+const patchCheckScript = `exec 2>/dev/null
+echo "kernel=$(uname -r)"
+if [ -r /etc/os-release ]; then . /etc/os-release; echo "os=$PRETTY_NAME"; fi
+if command -v apt-get >/dev/null; then
+  echo "pm=apt"
+  upgrades=$(apt-get -s -o Debug::NoLocking=1 upgrade | grep '^Inst ')
+  echo "pending=$(printf '%s\n' "$upgrades" | grep -c '^Inst ')"
+  echo "security=$(printf '%s\n' "$upgrades" | grep -ci 'security')"
+  echo "kernel_updates=$(printf '%s\n' "$upgrades" | grep -i 'security' | grep -cE '^Inst linux-(image|modules)')"
+  if [ -f /var/run/reboot-required ]; then echo "reboot=1"; else echo "reboot=0"; fi
+elif command -v dnf >/dev/null || command -v yum >/dev/null; then
+  pm=yum; command -v dnf >/dev/null && pm=dnf
+  echo "pm=$pm"
+  echo "pending=$($pm -q check-update | grep -cE '^[[:alnum:]_.+-]+\.[[:alnum:]_]+[[:space:]]')"
+  security=$($pm -q updateinfo list --security | grep -E '^[^[:space:]]')
+  echo "security=$(printf '%s\n' "$security" | grep -c .)"
+  echo "kernel_updates=$(printf '%s\n' "$security" | grep -cE '[[:space:]]kernel(-core)?-[0-9]')"
+  if command -v needs-restarting >/dev/null; then
+    needs-restarting -r >/dev/null
+    if [ $? -eq 1 ]; then echo "reboot=1"; else echo "reboot=0"; fi
+  else
+    latest=$(rpm -q --last kernel kernel-core | head -1 | awk '{print $1}' | sed -E 's/^kernel(-core)?-//')
+    if [ -n "$latest" ] && [ "$latest" != "$(uname -r)" ]; then echo "reboot=1"; else echo "reboot=0"; fi
+  fi
+elif command -v apk >/dev/null; then
+  echo "pm=apk"
+  echo "pending=$(apk -u list | grep -c .)"
+  echo "reboot=0"
+else
+  echo "pm=unknown"
+fi
+exit 0`
+
+// PatchService 节点系统补丁检查服务
+type PatchService struct {
+	db                  *gorm.DB
+	config              *config.Config
+	notificationService *NotificationService
+}
+
+// NewPatchService 创建节点补丁检查服务
+func NewPatchService(db *gorm.DB, config *config.Config) (*PatchService, error) {
+	return &PatchService{
+		db:                  db,
+		config:              config,
+		notificationService: NewNotificationService(),
+	}, nil
+}
+
+// CheckPatches 采集各节点的补丁状态，存在待处理的安全问题且与上次不同时通知
+func (s *PatchService) CheckPatches(ctx context.Context, cfg models.PatchCheckConfig) (string, error) {
+	var nodes []models.Node
+	query := s.db.Model(&models.Node{})
+	if len(cfg.NodeIDs) > 0 {
+		query = query.Where("id IN ?", cfg.NodeIDs)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
+		return "", fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	alerted, failed := 0, 0
+	for i := range nodes {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		facts := s.CollectNode(&nodes[i], cfg)
+		if facts.Error != "" {
+			failed++
+		} else if facts.AlertKey != "" {
+			alerted++
+		}
+	}
+
+	output := fmt.Sprintf("检查 %d 个节点，%d 个需要处理补丁", len(nodes), alerted)
+	if failed > 0 {
+		output += fmt.Sprintf("，%d 个检查失败", failed)
+	}
+	return output, nil
+}
+
+// CollectNode 采集单个节点的补丁状态并保存
+func (s *PatchService) CollectNode(node *models.Node, cfg models.PatchCheckConfig) *models.NodeFacts {
+	if cfg.SecurityThreshold <= 0 {
+		cfg.SecurityThreshold = 1
+	}
+
+	var previous models.NodeFacts
+	s.db.Where("node_id = ?", node.ID).First(&previous)
+
+	facts := &models.NodeFacts{
+		ID:          previous.ID,
+		NodeID:      node.ID,
+		NodeName:    node.Name,
+		CollectedAt: time.Now(),
+	}
+
+	output, err := s.runCheck(node)
+	if err != nil {
+		// 采集失败时保留上次的结果，只记录错误
+		previous.NodeName = node.Name
+		previous.Error = err.Error()
+		previous.CollectedAt = facts.CollectedAt
+		facts = &previous
+		log.Printf("⚠️ 节点 %s 补丁检查失败: %v", node.Name, err)
+	} else {
+		parsePatchFacts(output, facts)
+		facts.KernelIssues = CheckKernelIssues(facts.KernelVersion)
+		issues, _ := json.Marshal(facts.KernelIssues)
+		facts.Issues = string(issues)
+
+		alerts := patchAlerts(facts, cfg)
+		facts.AlertKey = strings.Join(alerts, "\n")
+		// 只有告警内容变化时才通知，避免定时任务重复告警
+		if facts.AlertKey != "" && facts.AlertKey != previous.AlertKey {
+			s.notify(node, facts, alerts)
+		}
+	}
+
+	if err := s.db.Save(facts).Error; err != nil {
+		log.Printf("⚠️ 保存节点补丁状态失败: %v", err)
+	}
+	if facts.KernelIssues == nil && facts.Issues != "" {
+		json.Unmarshal([]byte(facts.Issues), &facts.KernelIssues)
+	}
+	return facts
+}
+
+// runCheck 通过 SSH 执行检查脚本
+func (s *PatchService) runCheck(node *models.Node) (string, error) {
+	client, err := NewSSHClient(node)
+	if err != nil {
+		return "", fmt.Errorf("连接节点失败: %w", err)
+	}
+	defer client.Close()
+
+	output, err := client.ExecuteCommandWithTimeout(patchCheckScript, patchCheckTimeout)
+	if err != nil {
+		return "", fmt.Errorf("检查更新失败: %w", err)
+	}
+	return output, nil
+}
+
+// parsePatchFacts 解析检查脚本的 key=value 输出
+func parsePatchFacts(output string, facts *models.NodeFacts) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		count, _ := strconv.Atoi(value)
+		switch key {
+		case "kernel":
+			facts.KernelVersion = value
+		case "os":
+			facts.OSName = value
+		case "pm":
+			facts.PackageManager = value
+		case "pending":
+			facts.PendingUpdates = count
+		case "security":
+			facts.SecurityUpdates = count
+		case "kernel_updates":
+			facts.KernelUpdates = count
+		case "reboot":
+			facts.RebootRequired = value == "1"
+		}
+	}
+}
+
+// patchAlerts 根据阈值生成告警项，返回空表示无需处理
+func patchAlerts(facts *models.NodeFacts, cfg models.PatchCheckConfig) []string {
+	var alerts []string
+	for _, issue := range facts.KernelIssues {
+		alerts = append(alerts, fmt.Sprintf("内核 %s 受 %s (%s) 影响，需升级到 %s 或以上", facts.KernelVersion, issue.CVE, issue.Name, issue.FixedIn))
+	}
+	if facts.KernelUpdates > 0 {
+		alerts = append(alerts, fmt.Sprintf("有 %d 个待安装的内核安全更新", facts.KernelUpdates))
+	}
+	if facts.SecurityUpdates >= cfg.SecurityThreshold {
+		alerts = append(alerts, fmt.Sprintf("有 %d 个待安装的安全更新", facts.SecurityUpdates))
+	}
+	if facts.RebootRequired && !cfg.IgnoreReboot {
+		alerts = append(alerts, "已安装的更新需要重启才能生效")
+	}
+	return alerts
+}
+
+// notify 发送补丁告警通知
+func (s *PatchService) notify(node *models.Node, facts *models.NodeFacts, alerts []string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "节点 %s（%s，内核 %s）需要处理系统补丁：\n", node.Name, facts.OSName, facts.KernelVersion)
+	for _, alert := range alerts {
+		fmt.Fprintf(&b, "- %s\n", alert)
+	}
+
+	if err := s.notificationService.SendNotification(node.ID, "node_patch_alert", "⚠️ 节点补丁告警", b.String()); err != nil {
+		log.Printf("⚠️ 发送节点补丁告警失败: %v", err)
+	}
+}
+
+// DecodeNodeFacts 反序列化内核漏洞列表
+func DecodeNodeFacts(facts []models.NodeFacts) {
+	for i := range facts {
+		if facts[i].Issues != "" {
+			json.Unmarshal([]byte(facts[i].Issues), &facts[i].KernelIssues)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"smartdns-manager/config"
+)
+
+// QueryCache 统计查询结果的内存缓存
+//
+// 有效期（ttl）内直接返回缓存结果；过期后在 stale 窗口内先返回旧结果，同时在后台刷新；
+// 超过 stale 窗口或没有缓存时同步查询。同一个键的并发请求只会查询一次数据库，
+// 多人同时打开仪表盘时不会把相同的聚合查询重复压到 ClickHouse 上。
+type QueryCache struct {
+	ttl   time.Duration
+	stale time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*queryCacheEntry
+}
+
+type queryCacheEntry struct {
+	value     interface{}
+	fetchedAt time.Time // 为零表示还没有成功查询过
+	call      *queryCacheCall
+}
+
+// queryCacheCall 正在进行的查询，等待者通过 done 获取结果
+type queryCacheCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// NewQueryCache 创建查询缓存，ttl <= 0 时不缓存
+func NewQueryCache(ttl, stale time.Duration) *QueryCache {
+	if stale < 0 {
+		stale = 0
+	}
+	return &QueryCache{
+		ttl:     ttl,
+		stale:   stale,
+		entries: make(map[string]*queryCacheEntry),
+	}
+}
+
+// NewClickHouseQueryCache 按 CLICKHOUSE_QUERY_CACHE_TTL / CLICKHOUSE_QUERY_CACHE_STALE 创建缓存
+func NewClickHouseQueryCache() *QueryCache {
+	cfg := config.GetClickHouseConfig()
+	return NewQueryCache(time.Duration(cfg.QueryCacheTTL)*time.Second, time.Duration(cfg.QueryCacheStale)*time.Second)
+}
+
+// Key 生成缓存键，时间参数按 ttl 取整，"最近 24 小时"这类每次请求都在变化的时间范围也能命中缓存
+func (c *QueryCache) Key(name string, parts ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, part := range parts {
+		b.WriteByte('|')
+		if t, ok := part.(time.Time); ok && c != nil && c.ttl > 0 {
+			fmt.Fprintf(&b, "%d", t.Truncate(c.ttl).Unix())
+			continue
+		}
+		fmt.Fprintf(&b, "%v", part)
+	}
+	return b.String()
+}
+
+// Get 获取缓存结果，没有可用缓存时调用 load 查询；查询失败不缓存
+func (c *QueryCache) Get(key string, load func() (interface{}, error)) (interface{}, error) {
+	if c == nil || c.ttl <= 0 {
+		return load()
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && !entry.fetchedAt.IsZero() {
+		age := time.Since(entry.fetchedAt)
+		if age < c.ttl {
+			value := entry.value
+			c.mu.Unlock()
+			return value, nil
+		}
+		if age < c.ttl+c.stale {
+			// 先返回旧结果，后台刷新
+			if entry.call == nil {
+				c.startLoad(key, entry, load)
+			}
+			value := entry.value
+			c.mu.Unlock()
+			return value, nil
+		}
+	}
+
+	if !ok {
+		c.evictExpired()
+		entry = &queryCacheEntry{}
+		c.entries[key] = entry
+	}
+	call := entry.call
+	if call == nil {
+		call = c.startLoad(key, entry, load)
+	}
+	c.mu.Unlock()
+
+	<-call.done
+	return call.value, call.err
+}
+
+// Purge 清空缓存，日志被删除等数据变化后调用
+func (c *QueryCache) Purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 正在进行的查询完成后写回已丢弃的条目，不会再被命中
+	c.entries = make(map[string]*queryCacheEntry)
+}
+
+// Len 当前缓存的条目数
+func (c *QueryCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// startLoad 在后台执行查询，调用方需持有锁
+func (c *QueryCache) startLoad(key string, entry *queryCacheEntry, load func() (interface{}, error)) *queryCacheCall {
+	call := &queryCacheCall{done: make(chan struct{})}
+	entry.call = call
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				call.err = fmt.Errorf("查询异常: %v", r)
+				log.Printf("⚠️ 统计查询 %s 异常: %v", key, r)
+			}
+			c.mu.Lock()
+			entry.call = nil
+			if call.err == nil {
+				entry.value = call.value
+				entry.fetchedAt = time.Now()
+			} else if entry.fetchedAt.IsZero() && c.entries[key] == entry {
+				delete(c.entries, key)
+			}
+			c.mu.Unlock()
+			close(call.done)
+		}()
+		call.value, call.err = load()
+	}()
+	return call
+}
+
+// evictExpired 删除超过 stale 窗口的条目，调用方需持有锁
+func (c *QueryCache) evictExpired() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if entry.call == nil && now.Sub(entry.fetchedAt) >= c.ttl+c.stale {
+			delete(c.entries, key)
+		}
+	}
+}
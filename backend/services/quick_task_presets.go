@@ -0,0 +1,312 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+
+	"smartdns-manager/models"
+)
+
+// 快速任务参数类型
+const (
+	QuickParamString  = "string"
+	QuickParamInt     = "int"
+	QuickParamBool    = "bool"
+	QuickParamNodeTag = "node_tag" // 节点标签，创建时解析为节点ID列表
+)
+
+// taskCronParser 与调度器一致的六段式 cron（含秒）
+var taskCronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// QuickTaskParam 快速任务模板的输入参数
+type QuickTaskParam struct {
+	Key         string      `json:"key"`
+	Label       string      `json:"label"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default,omitempty"`
+	Required    bool        `json:"required"`
+	Description string      `json:"description,omitempty"`
+}
+
+// QuickTaskPreset 快速任务模板，任务配置由服务端根据参数生成
+type QuickTaskPreset struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	TaskType    models.TaskType  `json:"task_type"`
+	DefaultCron string           `json:"default_cron"`
+	Params      []QuickTaskParam `json:"params"`
+
+	build func(s *SchedulerService, params quickTaskParams) (interface{}, error)
+}
+
+// quickTaskParams 合并默认值后的参数
+type quickTaskParams map[string]interface{}
+
+func (p quickTaskParams) String(key string) string {
+	switch v := p[key].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (p quickTaskParams) Int(key string) int {
+	switch v := p[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		n, _ := strconv.Atoi(strings.TrimSpace(v))
+		return n
+	}
+	return 0
+}
+
+func (p quickTaskParams) Bool(key string) bool {
+	switch v := p[key].(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+// quickTaskPresets 内置的快速任务模板
+var quickTaskPresets = []QuickTaskPreset{
+	{
+		ID:          "nightly_node_backup",
+		Name:        "夜间节点备份",
+		Description: "每天凌晨备份指定标签节点的配置文件，节点范围在创建时按标签确定",
+		TaskType:    models.TaskTypeNodeBackup,
+		DefaultCron: "0 0 2 * * *",
+		Params: []QuickTaskParam{
+			{Key: "node_tag", Label: "节点标签", Type: QuickParamNodeTag, Description: "为空时备份所有节点"},
+			{Key: "local_path", Label: "备份目录", Type: QuickParamString, Default: "/etc/smartdns/backups", Required: true},
+			{Key: "retention_days", Label: "保留天数", Type: QuickParamInt, Default: 30, Required: true},
+		},
+		build: func(s *SchedulerService, p quickTaskParams) (interface{}, error) {
+			nodeIDs, err := s.nodeIDsByTag(p.String("node_tag"))
+			if err != nil {
+				return nil, err
+			}
+			if p.Int("retention_days") <= 0 {
+				return nil, fmt.Errorf("保留天数必须大于0")
+			}
+			return models.NodeBackupConfig{
+				StorageType:   "local",
+				LocalPath:     p.String("local_path"),
+				NodeIDs:       nodeIDs,
+				BackupConfigs: true,
+				Compression:   true,
+				RetentionDays: p.Int("retention_days"),
+			}, nil
+		},
+	},
+	{
+		ID:          "hourly_resolver_telemetry",
+		Name:        "节点解析器遥测（每小时）",
+		Description: "为每个节点创建向其 SmartDNS 解析探测域名的场景检测目标，每小时检测一次",
+		TaskType:    models.TaskTypeTelemetry,
+		DefaultCron: "0 0 * * * *",
+		Params: []QuickTaskParam{
+			{Key: "node_tag", Label: "节点标签", Type: QuickParamNodeTag, Description: "为空时包含所有节点"},
+			{Key: "probe_domain", Label: "探测域名", Type: QuickParamString, Default: "www.baidu.com", Required: true},
+			{Key: "alert_threshold", Label: "连续失败告警次数", Type: QuickParamInt, Default: 3, Required: true},
+		},
+		build: func(s *SchedulerService, p quickTaskParams) (interface{}, error) {
+			domain := p.String("probe_domain")
+			if domain == "" {
+				return nil, fmt.Errorf("探测域名不能为空")
+			}
+			targetIDs, err := s.ensureResolverTargets(p.String("node_tag"), domain)
+			if err != nil {
+				return nil, err
+			}
+			return models.TelemetryConfig{
+				Targets:         targetIDs,
+				ResultRetention: 7,
+				AlertThreshold:  p.Int("alert_threshold"),
+			}, nil
+		},
+	},
+	{
+		ID:          "weekly_clickhouse_optimize",
+		Name:        "每周 ClickHouse 表优化",
+		Description: "每周日凌晨合并 ClickHouse 日志表的分区片段，减少查询扫描的文件数",
+		TaskType:    models.TaskTypeCHOptimize,
+		DefaultCron: "0 0 4 * * 0",
+		Params: []QuickTaskParam{
+			{Key: "tables", Label: "表名", Type: QuickParamString, Default: "dns_query_log", Required: true, Description: "多个表用逗号分隔"},
+			{Key: "final", Label: "强制完全合并（FINAL）", Type: QuickParamBool, Default: true},
+		},
+		build: func(s *SchedulerService, p quickTaskParams) (interface{}, error) {
+			var tables []string
+			for _, table := range strings.Split(p.String("tables"), ",") {
+				if table = strings.TrimSpace(table); table != "" {
+					tables = append(tables, table)
+				}
+			}
+			if len(tables) == 0 {
+				return nil, fmt.Errorf("至少需要一个表名")
+			}
+			return models.CHOptimizeConfig{Tables: tables, Final: p.Bool("final")}, nil
+		},
+	},
+	{
+		ID:          "daily_log_cleanup",
+		Name:        "每日日志清理",
+		Description: "每天清理过期的 Agent、后端、SmartDNS 和通知日志",
+		TaskType:    models.TaskTypeLogCleanup,
+		DefaultCron: "0 0 4 * * *",
+		Params: []QuickTaskParam{
+			{Key: "retention_days", Label: "日志保留天数", Type: QuickParamInt, Default: 7, Required: true},
+			{Key: "notification_log_days", Label: "通知日志保留天数", Type: QuickParamInt, Default: 90, Required: true},
+		},
+		build: func(s *SchedulerService, p quickTaskParams) (interface{}, error) {
+			days := p.Int("retention_days")
+			if days <= 0 {
+				return nil, fmt.Errorf("保留天数必须大于0")
+			}
+			return models.LogCleanupConfig{
+				AgentLogDays:        days,
+				BackendLogDays:      days,
+				SmartDNSLogDays:     days,
+				NotificationLogDays: p.Int("notification_log_days"),
+			}, nil
+		},
+	},
+}
+
+// GetQuickTaskPresets 获取快速任务模板列表
+func (s *SchedulerService) GetQuickTaskPresets() []QuickTaskPreset {
+	return quickTaskPresets
+}
+
+// BuildQuickTask 按模板和参数生成任务，name/cronExpr 为空时使用模板默认值
+func (s *SchedulerService) BuildQuickTask(presetID, name, cronExpr string, params map[string]interface{}) (*models.ScheduledTask, error) {
+	var preset *QuickTaskPreset
+	for i := range quickTaskPresets {
+		if quickTaskPresets[i].ID == presetID {
+			preset = &quickTaskPresets[i]
+			break
+		}
+	}
+	if preset == nil {
+		return nil, fmt.Errorf("快速任务模板不存在: %s", presetID)
+	}
+
+	merged := make(quickTaskParams, len(preset.Params))
+	for _, param := range preset.Params {
+		value, ok := params[param.Key]
+		if !ok || value == nil || value == "" {
+			value = param.Default
+		}
+		if param.Required && (value == nil || value == "") {
+			return nil, fmt.Errorf("参数 %s 不能为空", param.Label)
+		}
+		merged[param.Key] = value
+	}
+
+	if cronExpr == "" {
+		cronExpr = preset.DefaultCron
+	}
+	if _, err := taskCronParser.Parse(cronExpr); err != nil {
+		return nil, fmt.Errorf("Cron表达式无效（需包含秒）: %w", err)
+	}
+	if name == "" {
+		name = preset.Name
+	}
+
+	config, err := preset.build(s, merged)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ScheduledTask{
+		Name:        name,
+		Type:        preset.TaskType,
+		CronExpr:    cronExpr,
+		Config:      string(data),
+		Enabled:     true,
+		Description: preset.Description,
+	}, nil
+}
+
+// nodeIDsByTag 按标签查找节点，标签为空时返回 nil 表示所有节点
+func (s *SchedulerService) nodeIDsByTag(tag string) ([]uint, error) {
+	if tag == "" {
+		return nil, nil
+	}
+
+	var nodes []models.Node
+	if err := s.db.Select("id", "tags").Find(&nodes).Error; err != nil {
+		return nil, fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	var ids []uint
+	for _, node := range nodes {
+		if nodeHasTag(node, tag) {
+			ids = append(ids, node.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("没有带标签 %s 的节点", tag)
+	}
+	return ids, nil
+}
+
+// ensureResolverTargets 为节点创建解析探测场景目标（已存在时复用），返回目标ID
+func (s *SchedulerService) ensureResolverTargets(tag, domain string) ([]uint, error) {
+	var nodes []models.Node
+	if err := s.db.Select("id", "name", "tags").Find(&nodes).Error; err != nil {
+		return nil, fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	const group = "node-resolvers"
+	var ids []uint
+	for _, node := range nodes {
+		if tag != "" && !nodeHasTag(node, tag) {
+			continue
+		}
+
+		scenario, _ := json.Marshal(models.TelemetryScenario{Steps: []models.TelemetryScenarioStep{{
+			Type:     models.ScenarioStepResolve,
+			Name:     "解析 " + domain,
+			Hostname: domain,
+			NodeID:   node.ID,
+		}}})
+		target := models.TelemetryTarget{
+			Name:        fmt.Sprintf("%s 解析 %s", node.Name, domain),
+			Type:        "scenario",
+			Target:      domain,
+			Timeout:     5000,
+			Enabled:     true,
+			Description: "快速任务创建的节点解析器检测",
+			Group:       group,
+			Scenario:    string(scenario),
+		}
+		if err := s.db.Where("`group` = ? AND name = ?", group, target.Name).FirstOrCreate(&target).Error; err != nil {
+			return nil, fmt.Errorf("创建遥测目标失败: %w", err)
+		}
+		ids = append(ids, target.ID)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("没有匹配的节点")
+	}
+	return ids, nil
+}
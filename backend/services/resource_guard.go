@@ -0,0 +1,197 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/models"
+)
+
+// 节点资源保护默认值
+const (
+	defaultGuardRetryMinutes = 15
+	defaultGuardMaxRetries   = 4
+)
+
+// busyNode 资源占用超过阈值的节点
+type busyNode struct {
+	node   models.Node
+	reason string
+}
+
+// guardTargetNodes 任务要处理的节点，nodeIDs 为空时为所有节点
+func guardTargetNodes(db *gorm.DB, nodeIDs []uint) ([]models.Node, error) {
+	query := db.Order("id")
+	if len(nodeIDs) > 0 {
+		query = query.Where("id IN ?", nodeIDs)
+	}
+	var nodes []models.Node
+	if err := query.Find(&nodes).Error; err != nil {
+		return nil, fmt.Errorf("查询节点失败: %w", err)
+	}
+	return nodes, nil
+}
+
+// splitNodesByResource 检查节点资源，返回可以立即执行的节点和需要推迟的节点
+//
+// 无法获取资源使用率（如 SSH 连接失败）时不推迟，由任务本身报告错误。
+func splitNodesByResource(nodes []models.Node, guard *models.NodeResourceGuard) ([]models.Node, []busyNode) {
+	var ready []models.Node
+	var busy []busyNode
+	for _, node := range nodes {
+		reason, err := checkNodeResources(node, guard)
+		if err != nil {
+			log.Printf("⚠️ 获取节点 %s 资源使用率失败，不做资源保护: %v", node.Name, err)
+		}
+		if reason == "" {
+			ready = append(ready, node)
+			continue
+		}
+		log.Printf("⏸️ 节点 %s %s，推迟执行", node.Name, reason)
+		busy = append(busy, busyNode{node: node, reason: reason})
+	}
+	return ready, busy
+}
+
+// checkNodeResources 通过 SSH 读取节点 CPU 和磁盘使用率，超过阈值时返回原因
+func checkNodeResources(node models.Node, guard *models.NodeResourceGuard) (string, error) {
+	if guard.MaxCPU <= 0 && guard.MaxDisk <= 0 {
+		return "", nil
+	}
+
+	client, err := NewSSHClient(&node)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	diskPath := guard.DiskPath
+	if diskPath == "" {
+		diskPath = "/"
+	}
+	cpu, disk, err := client.GetResourceUsage(diskPath)
+	if err != nil {
+		return "", err
+	}
+
+	var reasons []string
+	if guard.MaxCPU > 0 && cpu > guard.MaxCPU {
+		reasons = append(reasons, fmt.Sprintf("CPU使用率 %.1f%% 超过 %.0f%%", cpu, guard.MaxCPU))
+	}
+	if guard.MaxDisk > 0 && disk > guard.MaxDisk {
+		reasons = append(reasons, fmt.Sprintf("磁盘 %s 使用率 %.0f%% 超过 %.0f%%", diskPath, disk, guard.MaxDisk))
+	}
+	return strings.Join(reasons, "，"), nil
+}
+
+// GetResourceUsage 采样 1 秒内的 CPU 使用率和指定路径所在磁盘的使用率（%）
+func (c *SSHClient) GetResourceUsage(diskPath string) (float64, float64, error) {
+	output, err := c.ExecuteCommand(fmt.Sprintf(
+		"head -1 /proc/stat; sleep 1; head -1 /proc/stat; df -P %s | tail -1", shellQuote(diskPath)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 3 {
+		return 0, 0, fmt.Errorf("无法解析资源使用率: %s", output)
+	}
+	idle1, total1, err := parseProcStatCPU(lines[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	idle2, total2, err := parseProcStatCPU(lines[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	var cpu float64
+	if total2 > total1 {
+		cpu = 100 * (1 - float64(idle2-idle1)/float64(total2-total1))
+	}
+
+	fields := strings.Fields(lines[2])
+	if len(fields) < 5 {
+		return 0, 0, fmt.Errorf("无法解析磁盘使用率: %s", lines[2])
+	}
+	disk, err := strconv.ParseFloat(strings.TrimSuffix(fields[4], "%"), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("无法解析磁盘使用率: %s", lines[2])
+	}
+	return cpu, disk, nil
+}
+
+// parseProcStatCPU 解析 /proc/stat 的 cpu 行，返回空闲（idle + iowait）和总时间片
+func parseProcStatCPU(line string) (uint64, uint64, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("无法解析CPU使用率: %s", line)
+	}
+	var idle, total uint64
+	for i, field := range fields[1:] {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("无法解析CPU使用率: %s", line)
+		}
+		total += value
+		if i == 3 || i == 4 {
+			idle += value
+		}
+	}
+	return idle, total, nil
+}
+
+// deferBusyNodes 稍后只对被推迟的节点重试任务，超过重试次数时跳过这些节点；
+// withNodes 返回只处理指定节点的任务配置。返回追加到任务输出中的说明
+func (s *SchedulerService) deferBusyNodes(task models.ScheduledTask, busy []busyNode, guard *models.NodeResourceGuard, withNodes func(nodeIDs []uint, guard *models.NodeResourceGuard) interface{}) string {
+	if len(busy) == 0 {
+		return ""
+	}
+
+	retryMinutes := guard.RetryMinutes
+	if retryMinutes <= 0 {
+		retryMinutes = defaultGuardRetryMinutes
+	}
+	maxRetries := guard.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultGuardMaxRetries
+	}
+
+	var b strings.Builder
+	nodeIDs := make([]uint, 0, len(busy))
+	for _, n := range busy {
+		nodeIDs = append(nodeIDs, n.node.ID)
+		fmt.Fprintf(&b, "\n- %s: %s", n.node.Name, n.reason)
+	}
+
+	if guard.Attempt >= maxRetries {
+		return fmt.Sprintf("\n\n%d 个节点资源占用过高，已重试 %d 次仍未恢复，本次跳过:%s", len(busy), guard.Attempt, b.String())
+	}
+
+	retryGuard := *guard
+	retryGuard.Attempt++
+	config, err := json.Marshal(withNodes(nodeIDs, &retryGuard))
+	if err != nil {
+		return fmt.Sprintf("\n\n%d 个节点资源占用过高，安排重试失败: %v", len(busy), err)
+	}
+
+	delay := time.Duration(retryMinutes) * time.Minute
+	time.AfterFunc(delay, func() {
+		current, err := s.GetTask(task.ID)
+		if err != nil || !current.Enabled {
+			log.Printf("⚠️ 任务 [%s] 已删除或停用，取消资源保护重试", task.Name)
+			return
+		}
+		retry := *current
+		retry.Config = string(config)
+		s.executeTask(retry, models.TaskTriggerResourceRetry)
+	})
+
+	return fmt.Sprintf("\n\n%d 个节点资源占用过高，%d 分钟后重试（第 %d/%d 次）:%s",
+		len(busy), retryMinutes, retryGuard.Attempt, maxRetries, b.String())
+}
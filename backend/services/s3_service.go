@@ -178,6 +178,18 @@ func (s *S3Service) DownloadFile(ctx context.Context, s3Key string) ([]byte, err
 	return data, nil
 }
 
+// OpenFile 以流的方式读取文件，调用方负责关闭
+func (s *S3Service) OpenFile(ctx context.Context, s3Key string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s3Key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
+	}
+	return result.Body, nil
+}
+
 // DeleteFile 删除文件（软删除）
 func (s *S3Service) DeleteFile(ctx context.Context, fileID uint) error {
 	var file models.File
@@ -228,6 +240,28 @@ func (s *S3Service) DeleteFileFromS3(ctx context.Context, s3Key string) error {
 	return nil
 }
 
+// DeletePrefixFromS3 删除指定前缀下的所有对象，返回删除的对象数
+func (s *S3Service) DeletePrefixFromS3(ctx context.Context, prefix string) (int, error) {
+	deleted := 0
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to list S3 objects: %w", err)
+		}
+		for _, object := range page.Contents {
+			if err := s.DeleteFileFromS3(ctx, aws.ToString(object.Key)); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
 // ListFiles 列出文件
 func (s *S3Service) ListFiles(ctx context.Context, userID uint, page, pageSize int) ([]models.File, int64, error) {
 	var files []models.File
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// savedSearchSortFields 保存搜索支持的排序字段，与日志查询一致
+var savedSearchSortFields = map[string]bool{
+	"timestamp": true,
+	"time_ms":   true,
+	"speed_ms":  true,
+	"domain":    true,
+	"client_ip": true,
+}
+
+// ParseTimeWindow 解析相对时间窗口，支持 Go 时长格式（15m、24h）和天数（7d）
+func ParseTimeWindow(window string) (time.Duration, error) {
+	window = strings.TrimSpace(window)
+	if days, found := strings.CutSuffix(window, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("无效的时间窗口: %s", window)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	duration, err := time.ParseDuration(window)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("无效的时间窗口: %s", window)
+	}
+	return duration, nil
+}
+
+// BuildSavedSearch 校验请求并填充保存搜索的字段
+func BuildSavedSearch(search *models.SavedSearch, request *models.SavedSearchRequest) error {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return fmt.Errorf("名称不能为空")
+	}
+
+	filters := make(map[string]string)
+	for key, value := range request.Filters {
+		if !isDNSLogFilterParam(key) {
+			return fmt.Errorf("不支持的过滤参数: %s", key)
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			filters[key] = value
+		}
+	}
+	if request.TimeWindow != "" {
+		if _, err := ParseTimeWindow(request.TimeWindow); err != nil {
+			return err
+		}
+	}
+	if request.SortField != "" && !savedSearchSortFields[request.SortField] {
+		return fmt.Errorf("不支持的排序字段: %s", request.SortField)
+	}
+	if request.SortOrder != "" && request.SortOrder != "asc" && request.SortOrder != "desc" {
+		return fmt.Errorf("排序方向必须是 asc 或 desc")
+	}
+
+	data, _ := json.Marshal(filters)
+	search.Name = name
+	search.Description = request.Description
+	search.Filters = string(data)
+	search.TimeWindow = request.TimeWindow
+	search.SortField = request.SortField
+	search.SortOrder = request.SortOrder
+	search.Shared = request.Shared
+	return nil
+}
+
+// LoadSavedSearch 读取保存搜索，userID 不为 0 时只能读取自己创建的或共享的
+func LoadSavedSearch(id, userID uint) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	if err := database.DB.First(&search, id).Error; err != nil {
+		return nil, fmt.Errorf("保存的搜索不存在")
+	}
+	if userID != 0 && search.UserID != userID && !search.Shared {
+		return nil, fmt.Errorf("保存的搜索不存在")
+	}
+	return &search, nil
+}
+
+// SavedSearchParams 展开保存搜索的查询参数（过滤条件和排序），
+// 设置了相对时间窗口时按 now 换算为 start_time/end_time
+func SavedSearchParams(search *models.SavedSearch, now time.Time) (map[string]string, error) {
+	params := make(map[string]string)
+	if search.Filters != "" {
+		if err := json.Unmarshal([]byte(search.Filters), &params); err != nil {
+			return nil, fmt.Errorf("解析保存的过滤条件失败: %w", err)
+		}
+	}
+	if search.TimeWindow != "" {
+		window, err := ParseTimeWindow(search.TimeWindow)
+		if err != nil {
+			return nil, err
+		}
+		params["start_time"] = now.Add(-window).Format(time.RFC3339)
+		params["end_time"] = now.Format(time.RFC3339)
+	}
+	if search.SortField != "" {
+		params["sort_field"] = search.SortField
+	}
+	if search.SortOrder != "" {
+		params["sort_order"] = search.SortOrder
+	}
+	return params, nil
+}
+
+func isDNSLogFilterParam(key string) bool {
+	for _, param := range DNSLogFilterParams {
+		if param == key {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -32,6 +33,21 @@ type SchedulerService struct {
 	logCleanup   *LogCleanupService
 	telemetry    *TelemetryService
 	customScript *CustomScriptService
+	clientAbuse  *ClientAbuseService
+	dnsThreat    *DNSThreatService
+	healthScore  *HealthScoreService
+	drift        *DriftService
+	patch        *PatchService
+	blocklist    *BlocklistService
+	traffic      *TrafficAnomalyService
+	answerCheck  *AnswerCheckService
+	chOptimize   *CHOptimizeService
+	chBackup     *CHBackupService
+	fleetReport  *FleetReportService
+	agentProbe   *AgentProbeService
+	snapshot     *ConfigSnapshotService
+	compliance   *ComplianceService
+	notification *NotificationService
 }
 
 // NewSchedulerService 创建调度服务
@@ -42,6 +58,7 @@ func NewSchedulerService(db *gorm.DB, config *config.Config, s3 *S3Service) (*Sc
 		s3:        s3,
 		cron:      cron.New(cron.WithSeconds()),
 		taskExecs: make(map[uint]context.CancelFunc),
+		notification: NewNotificationService(),
 	}
 
 	// 初始化子服务
@@ -72,6 +89,86 @@ func NewSchedulerService(db *gorm.DB, config *config.Config, s3 *S3Service) (*Sc
 	}
 	scheduler.customScript = customScriptService
 
+	clientAbuseService, err := NewClientAbuseService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化客户端异常检测服务失败: %w", err)
+	}
+	scheduler.clientAbuse = clientAbuseService
+
+	dnsThreatService, err := NewDNSThreatService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化DNS威胁检测服务失败: %w", err)
+	}
+	scheduler.dnsThreat = dnsThreatService
+
+	healthScoreService, err := NewHealthScoreService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化健康评分服务失败: %w", err)
+	}
+	scheduler.healthScore = healthScoreService
+
+	driftService, err := NewDriftService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化配置漂移检测服务失败: %w", err)
+	}
+	scheduler.drift = driftService
+
+	patchService, err := NewPatchService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化节点补丁检查服务失败: %w", err)
+	}
+	scheduler.patch = patchService
+
+	scheduler.blocklist = NewBlocklistService(db)
+
+	trafficAnomalyService, err := NewTrafficAnomalyService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化查询量异常检测服务失败: %w", err)
+	}
+	scheduler.traffic = trafficAnomalyService
+
+	answerCheckService, err := NewAnswerCheckService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化DNS应答校验服务失败: %w", err)
+	}
+	scheduler.answerCheck = answerCheckService
+
+	chOptimizeService, err := NewCHOptimizeService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化ClickHouse表优化服务失败: %w", err)
+	}
+	scheduler.chOptimize = chOptimizeService
+
+	chBackupService, err := NewCHBackupService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化ClickHouse备份服务失败: %w", err)
+	}
+	scheduler.chBackup = chBackupService
+
+	fleetReportService, err := NewFleetReportService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化节点资产报告服务失败: %w", err)
+	}
+	scheduler.fleetReport = fleetReportService
+
+	agentProbeService, err := NewAgentProbeService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化Agent解析探测服务失败: %w", err)
+	}
+	scheduler.agentProbe = agentProbeService
+
+	snapshotService, err := NewConfigSnapshotService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化节点配置快照服务失败: %w", err)
+	}
+	scheduler.snapshot = snapshotService
+
+	complianceService, err := NewComplianceService(db, config)
+	if err != nil {
+		return nil, fmt.Errorf("初始化配置合规检查服务失败: %w", err)
+	}
+	scheduler.compliance = complianceService
+
 	return scheduler, nil
 }
 
@@ -98,6 +195,9 @@ func (s *SchedulerService) Start() error {
 	s.cron.Start()
 	s.running = true
 
+	// 补执行服务停机期间错过的调度
+	s.catchUpMissedRuns()
+
 	log.Printf("✅ 定时任务调度服务启动成功")
 	return nil
 }
@@ -105,15 +205,13 @@ func (s *SchedulerService) Start() error {
 // Stop 停止调度服务
 func (s *SchedulerService) Stop() {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
 	if !s.running {
+		s.mutex.Unlock()
 		return
 	}
 
-	// 停止cron调度器
+	// 停止调度新的任务
 	ctx := s.cron.Stop()
-	<-ctx.Done()
 
 	// 取消所有正在执行的任务
 	for taskID, cancel := range s.taskExecs {
@@ -122,6 +220,10 @@ func (s *SchedulerService) Stop() {
 	}
 
 	s.running = false
+	s.mutex.Unlock()
+
+	// 等待已取消的任务写完执行记录，任务结束时需要获取锁，不能持锁等待
+	<-ctx.Done()
 	log.Printf("🛑 定时任务调度服务已停止")
 }
 
@@ -140,7 +242,11 @@ func (s *SchedulerService) loadTasks() error {
 			log.Printf("❌ 添加任务失败 [%s]: %v", task.Name, err)
 			continue
 		}
-		log.Printf("📅 添加定时任务: %s (%s)", task.Name, task.CronExpr)
+		if task.RunAt != nil {
+			log.Printf("📅 添加一次性任务: %s (%s)", task.Name, task.RunAt.Format("2006-01-02 15:04:05"))
+		} else {
+			log.Printf("📅 添加定时任务: %s (%s)", task.Name, task.CronExpr)
+		}
 		successCount++
 	}
 
@@ -148,13 +254,64 @@ func (s *SchedulerService) loadTasks() error {
 	return nil
 }
 
+// NextCronRuns 校验 Cron 表达式（与调度器相同的六段式解析），返回之后 count 次执行时间（服务器时区）
+func NextCronRuns(expr string, count int) ([]time.Time, error) {
+	schedule, err := taskCronParser.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]time.Time, 0, count)
+	next := time.Now()
+	for i := 0; i < count; i++ {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next)
+	}
+	return runs, nil
+}
+
+// onceSchedule 一次性任务的调度，到达执行时间后不再触发
+type onceSchedule struct {
+	at time.Time
+}
+
+// Next 实现 cron.Schedule，返回零值表示不再执行
+func (o onceSchedule) Next(t time.Time) time.Time {
+	if o.at.After(t) {
+		return o.at
+	}
+	return time.Time{}
+}
+
 // addTaskToCron 添加任务到cron调度器
 func (s *SchedulerService) addTaskToCron(task models.ScheduledTask) error {
-	entryID, err := s.cron.AddFunc(task.CronExpr, func() {
-		s.executeTask(task)
+	trigger := models.TaskTriggerSchedule
+	job := cron.FuncJob(func() {
+		if task.RunAt != nil {
+			// 一次性任务触发后即停用，推迟到维护窗口的也只执行一次
+			defer s.finishOnceTask(task)
+		}
+		s.runScheduled(task, trigger)
 	})
-	if err != nil {
-		return err
+
+	var entryID cron.EntryID
+	if task.RunAt != nil {
+		// 服务停机期间错过的一次性任务在启动后补执行
+		at := *task.RunAt
+		if earliest := time.Now().Add(time.Second); at.Before(earliest) {
+			at = earliest
+			trigger = models.TaskTriggerCatchUp
+		}
+		entryID = s.cron.Schedule(onceSchedule{at: at}, job)
+	} else {
+		var err error
+		entryID, err = s.cron.AddJob(task.CronExpr, job)
+		if err != nil {
+			return err
+		}
 	}
 
 	// 获取下次执行时间
@@ -171,8 +328,71 @@ func (s *SchedulerService) addTaskToCron(task models.ScheduledTask) error {
 	return nil
 }
 
+// runScheduled 执行调度触发的任务，仅限维护窗口的任务不在窗口内时推迟
+func (s *SchedulerService) runScheduled(task models.ScheduledTask, trigger string) {
+	if task.MaintenanceOnly {
+		if deferred, next := NewMaintenanceService().DeferTask(&task); deferred {
+			s.recordDeferredExecution(task, trigger, next)
+			return
+		}
+	}
+	s.executeTask(task, trigger)
+}
+
+// catchUpMissedRuns 服务停机期间错过调度的任务（开启补执行）在启动后执行一次，
+// 以上次执行时间（从未执行过时为创建时间）之后的第一个调度时间判断是否错过
+func (s *SchedulerService) catchUpMissedRuns() {
+	var tasks []models.ScheduledTask
+	if err := s.db.Where("enabled = ? AND catch_up = ? AND run_at IS NULL", true, true).Find(&tasks).Error; err != nil {
+		log.Printf("⚠️ 查询需要补执行的任务失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, task := range tasks {
+		schedule, err := taskCronParser.Parse(task.CronExpr)
+		if err != nil {
+			continue
+		}
+		since := task.CreatedAt
+		if task.LastRunAt != nil {
+			since = *task.LastRunAt
+		}
+		missed := schedule.Next(since)
+		if missed.IsZero() || !missed.Before(now) {
+			continue
+		}
+
+		log.Printf("⏰ 任务 [%s] 错过了 %s 的调度，启动后补执行", task.Name, missed.Format("2006-01-02 15:04:05"))
+		go s.runScheduled(task, models.TaskTriggerCatchUp)
+	}
+}
+
+// finishOnceTask 一次性任务执行后停用，避免重启或重新加载时再次执行
+func (s *SchedulerService) finishOnceTask(task models.ScheduledTask) {
+	if err := s.db.Model(&task).Updates(map[string]interface{}{
+		"enabled":     false,
+		"next_run_at": nil,
+	}).Error; err != nil {
+		log.Printf("❌ 停用一次性任务失败 [%s]: %v", task.Name, err)
+	}
+}
+
+// recordDeferredExecution 记录因不在维护窗口内而推迟的调度
+func (s *SchedulerService) recordDeferredExecution(task models.ScheduledTask, trigger string, next *time.Time) {
+	now := time.Now()
+	s.db.Create(&models.TaskExecution{
+		TaskID:    task.ID,
+		Status:    models.TaskStatusSkipped,
+		Trigger:   trigger,
+		StartedAt: now,
+		EndedAt:   &now,
+		Output:    fmt.Sprintf("不在维护窗口内，推迟到 %s 执行", FormatNextWindow(next)),
+	})
+}
+
 // executeTask 执行任务
-func (s *SchedulerService) executeTask(task models.ScheduledTask) {
+func (s *SchedulerService) executeTask(task models.ScheduledTask, trigger string) {
 	s.mutex.Lock()
 	// 检查任务是否已在执行
 	if _, exists := s.taskExecs[task.ID]; exists {
@@ -181,13 +401,20 @@ func (s *SchedulerService) executeTask(task models.ScheduledTask) {
 		return
 	}
 
-	// 创建可取消的上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	// 创建可取消的上下文，设置了最长执行时间时到期自动取消
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if task.TimeoutSeconds > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(task.TimeoutSeconds)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
 	s.taskExecs[task.ID] = cancel
 	s.mutex.Unlock()
 
 	// 执行完成后清理
 	defer func() {
+		cancel()
 		s.mutex.Lock()
 		delete(s.taskExecs, task.ID)
 		s.mutex.Unlock()
@@ -197,6 +424,7 @@ func (s *SchedulerService) executeTask(task models.ScheduledTask) {
 	execution := &models.TaskExecution{
 		TaskID:    task.ID,
 		Status:    models.TaskStatusRunning,
+		Trigger:   trigger,
 		StartedAt: time.Now(),
 	}
 
@@ -205,25 +433,38 @@ func (s *SchedulerService) executeTask(task models.ScheduledTask) {
 		return
 	}
 
-	log.Printf("🚀 开始执行任务: %s", task.Name)
+	taskLog := slog.Default().With("task_id", task.ID, "task_type", task.Type, "task", task.Name, "execution_id", execution.ID, "trigger", trigger)
+	taskLog.Info("开始执行任务")
+
+	// 执行具体任务，超时后不再等待未响应取消的任务
+	type taskResult struct {
+		output string
+		err    error
+	}
+	resultCh := make(chan taskResult, 1)
+	go func() {
+		output, err := s.runTask(ctx, task)
+		resultCh <- taskResult{output: output, err: err}
+	}()
 
-	// 执行具体任务
 	var err error
 	var output string
-
-	switch task.Type {
-	case models.TaskTypeDBBackup:
-		output, err = s.executeDBBackup(ctx, task)
-	case models.TaskTypeNodeBackup:
-		output, err = s.executeNodeBackup(ctx, task)
-	case models.TaskTypeLogCleanup:
-		output, err = s.executeLogCleanup(ctx, task)
-	case models.TaskTypeTelemetry:
-		output, err = s.executeTelemetry(ctx, task)
-	case models.TaskTypeCustomScript:
-		output, err = s.executeCustomScript(ctx, task)
-	default:
-		err = fmt.Errorf("未知的任务类型: %s", task.Type)
+	timedOut := false
+
+	select {
+	case result := <-resultCh:
+		output, err = result.output, result.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			timedOut = true
+		} else {
+			result := <-resultCh
+			output, err = result.output, result.err
+		}
+	}
+	// 任务在超时取消后才返回错误，同样视为超时
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		timedOut = true
 	}
 
 	// 更新执行记录
@@ -236,13 +477,18 @@ func (s *SchedulerService) executeTask(task models.ScheduledTask) {
 		"output":   output,
 	}
 
-	if err != nil {
+	if timedOut {
+		err = fmt.Errorf("任务执行超时（超过 %d 秒），已取消", task.TimeoutSeconds)
+		updates["status"] = models.TaskStatusTimeout
+		updates["error"] = err.Error()
+		taskLog.Error("任务执行超时", "timeout_seconds", task.TimeoutSeconds)
+	} else if err != nil {
 		updates["status"] = models.TaskStatusFailed
 		updates["error"] = err.Error()
-		log.Printf("❌ 任务执行失败 [%s]: %v", task.Name, err)
+		taskLog.Error("任务执行失败", "error", err)
 	} else {
 		updates["status"] = models.TaskStatusSuccess
-		log.Printf("✅ 任务执行成功 [%s]: 耗时%dms", task.Name, duration)
+		taskLog.Info("任务执行成功", "duration_ms", duration)
 	}
 
 	// 更新执行记录
@@ -267,6 +513,118 @@ func (s *SchedulerService) executeTask(task models.ScheduledTask) {
 	if err := s.db.Model(&task).Updates(taskUpdates).Error; err != nil {
 		log.Printf("❌ 更新任务状态失败: %v", err)
 	}
+
+	s.notifyTaskResult(task, trigger, updates["status"].(models.TaskStatus), output, err, endTime.Sub(execution.StartedAt))
+}
+
+// runTask 按任务类型执行具体任务
+func (s *SchedulerService) runTask(ctx context.Context, task models.ScheduledTask) (string, error) {
+	if task.OrganizationID > 0 {
+		if err := s.scopeTaskToOrganization(&task); err != nil {
+			return "", err
+		}
+	}
+
+	switch task.Type {
+	case models.TaskTypeDBBackup:
+		return s.executeDBBackup(ctx, task)
+	case models.TaskTypeNodeBackup:
+		return s.executeNodeBackup(ctx, task)
+	case models.TaskTypeLogCleanup:
+		return s.executeLogCleanup(ctx, task)
+	case models.TaskTypeTelemetry:
+		return s.executeTelemetry(ctx, task)
+	case models.TaskTypeCustomScript:
+		return s.executeCustomScript(ctx, task)
+	case models.TaskTypeClientAbuse:
+		return s.executeClientAbuse(ctx, task)
+	case models.TaskTypeDNSThreat:
+		return s.executeDNSThreat(ctx, task)
+	case models.TaskTypeHealthScore:
+		return s.executeHealthScore(ctx, task)
+	case models.TaskTypeDriftCheck:
+		return s.executeDriftCheck(ctx, task)
+	case models.TaskTypePatchCheck:
+		return s.executePatchCheck(ctx, task)
+	case models.TaskTypeBlocklist:
+		return s.executeBlocklist(ctx, task)
+	case models.TaskTypeTrafficAnomaly:
+		return s.executeTrafficAnomaly(ctx, task)
+	case models.TaskTypeAnswerCheck:
+		return s.executeAnswerCheck(ctx, task)
+	case models.TaskTypeCHOptimize:
+		return s.executeCHOptimize(ctx, task)
+	case models.TaskTypeFleetReport:
+		return s.executeFleetReport(ctx, task)
+	case models.TaskTypeAgentProbe:
+		return s.executeAgentProbe(ctx, task)
+	case models.TaskTypeConfigSnapshot:
+		return s.executeConfigSnapshot(ctx, task)
+	case models.TaskTypeCompliance:
+		return s.executeCompliance(ctx, task)
+	case models.TaskTypeCHBackup:
+		return s.executeCHBackup(ctx, task)
+	case models.TaskTypeLogRetention:
+		return s.executeLogRetention(ctx, task)
+	default:
+		return "", fmt.Errorf("未知的任务类型: %s", task.Type)
+	}
+}
+
+// scopeTaskToOrganization 组织的任务只作用于本组织的节点：配置中指定的节点只保留本组织的，
+// 未指定节点（表示所有节点）时改为本组织的全部节点，自定义脚本的标签也只在本组织内匹配
+func (s *SchedulerService) scopeTaskToOrganization(task *models.ScheduledTask) error {
+	if !models.IsOrganizationTaskType(task.Type) {
+		return fmt.Errorf("任务类型 %s 不能归属于组织", task.Type)
+	}
+
+	config := map[string]interface{}{}
+	var scoped struct {
+		NodeIDs []uint `json:"node_ids"`
+		Tag     string `json:"tag"`
+	}
+	if task.Config != "" {
+		if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+			return fmt.Errorf("解析任务配置失败: %w", err)
+		}
+		json.Unmarshal([]byte(task.Config), &scoped)
+	}
+
+	var nodes []models.Node
+	if err := s.db.Where("organization_id = ?", task.OrganizationID).Order("id").Find(&nodes).Error; err != nil {
+		return fmt.Errorf("查询组织节点失败: %w", err)
+	}
+
+	wanted := make(map[uint]bool, len(scoped.NodeIDs))
+	for _, id := range scoped.NodeIDs {
+		wanted[id] = true
+	}
+	tag := ""
+	if task.Type == models.TaskTypeCustomScript {
+		tag = scoped.Tag
+	}
+	all := len(wanted) == 0 && tag == ""
+
+	nodeIDs := []uint{}
+	for _, node := range nodes {
+		if all || wanted[node.ID] || (tag != "" && nodeHasTag(node, tag)) {
+			nodeIDs = append(nodeIDs, node.ID)
+		}
+	}
+	if len(nodeIDs) == 0 {
+		return fmt.Errorf("组织内没有可执行的节点")
+	}
+
+	config["node_ids"] = nodeIDs
+	if tag != "" {
+		delete(config, "tag")
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("序列化任务配置失败: %w", err)
+	}
+	task.Config = string(data)
+	return nil
 }
 
 // executeDBBackup 执行数据库备份任务
@@ -292,6 +650,7 @@ func (s *SchedulerService) executeDBBackup(ctx context.Context, task models.Sche
 		S3Prefix:           config.S3Config.Prefix,
 		CompressionEnabled: config.Compression,
 		EncryptionEnabled:  config.Encryption,
+		EncryptionKeyID:    config.EncryptionKeyID,
 	}
 
 	// 创建备份历史记录
@@ -318,7 +677,37 @@ func (s *SchedulerService) executeNodeBackup(ctx context.Context, task models.Sc
 		return "", fmt.Errorf("解析任务配置失败: %w", err)
 	}
 
-	return s.nodeBackup.BackupNodes(ctx, config)
+	if config.ResourceGuard == nil {
+		return s.nodeBackup.BackupNodes(ctx, config)
+	}
+
+	nodes, err := guardTargetNodes(s.db, config.NodeIDs)
+	if err != nil {
+		return "", err
+	}
+	ready, busy := splitNodesByResource(nodes, config.ResourceGuard)
+	deferred := s.deferBusyNodes(task, busy, config.ResourceGuard, func(nodeIDs []uint, guard *models.NodeResourceGuard) interface{} {
+		retry := config
+		retry.NodeIDs = nodeIDs
+		retry.ResourceGuard = guard
+		return retry
+	})
+	if len(ready) == 0 {
+		return "所有节点资源占用过高，本次未备份" + deferred, nil
+	}
+
+	config.NodeIDs = nodeIDsOf(ready)
+	output, err := s.nodeBackup.BackupNodes(ctx, config)
+	return output + deferred, err
+}
+
+// nodeIDsOf 节点ID列表
+func nodeIDsOf(nodes []models.Node) []uint {
+	ids := make([]uint, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
 }
 
 // executeLogCleanup 执行日志清理任务
@@ -328,7 +717,33 @@ func (s *SchedulerService) executeLogCleanup(ctx context.Context, task models.Sc
 		return "", fmt.Errorf("解析任务配置失败: %w", err)
 	}
 
-	return s.logCleanup.CleanupLogs(ctx, config)
+	// 资源保护只作用于按节点清理的SmartDNS日志
+	if config.ResourceGuard == nil || config.SmartDNSLogDays <= 0 {
+		return s.logCleanup.CleanupLogs(ctx, config)
+	}
+
+	nodes, err := guardTargetNodes(s.db, config.NodeIDs)
+	if err != nil {
+		return "", err
+	}
+	ready, busy := splitNodesByResource(nodes, config.ResourceGuard)
+	deferred := s.deferBusyNodes(task, busy, config.ResourceGuard, func(nodeIDs []uint, guard *models.NodeResourceGuard) interface{} {
+		// 重试时只清理被推迟节点的SmartDNS日志
+		return models.LogCleanupConfig{
+			SmartDNSLogDays:     config.SmartDNSLogDays,
+			NodeIDs:             nodeIDs,
+			NotificationLogDays: config.NotificationLogDays,
+			ResourceGuard:       guard,
+		}
+	})
+	if len(ready) == 0 {
+		config.SmartDNSLogDays = 0
+	} else {
+		config.NodeIDs = nodeIDsOf(ready)
+	}
+
+	output, err := s.logCleanup.CleanupLogs(ctx, config)
+	return output + deferred, err
 }
 
 // executeTelemetry 执行遥测任务
@@ -348,6 +763,11 @@ func (s *SchedulerService) executeCustomScript(ctx context.Context, task models.
 		return "", fmt.Errorf("解析任务配置失败: %w", err)
 	}
 
+	// 使用脚本模板时按参数渲染
+	if err := s.customScript.ApplyScriptTemplate(&config); err != nil {
+		return "", err
+	}
+
 	// 验证脚本配置
 	if err := s.customScript.ValidateScript(config); err != nil {
 		return "", fmt.Errorf("脚本配置验证失败: %w", err)
@@ -356,6 +776,151 @@ func (s *SchedulerService) executeCustomScript(ctx context.Context, task models.
 	return s.customScript.ExecuteScript(ctx, config)
 }
 
+// executeClientAbuse 执行客户端异常查询检测任务
+func (s *SchedulerService) executeClientAbuse(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.ClientAbuseConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.clientAbuse.CheckClients(ctx, config)
+}
+
+// executeDNSThreat 执行DNS隧道/DGA检测任务
+func (s *SchedulerService) executeDNSThreat(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.DNSThreatConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.dnsThreat.DetectThreats(ctx, config)
+}
+
+// executeHealthScore 执行节点健康评分告警任务
+func (s *SchedulerService) executeHealthScore(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.HealthScoreConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.healthScore.CheckHealthScores(ctx, config)
+}
+
+// executeDriftCheck 执行节点配置漂移检测任务
+func (s *SchedulerService) executeDriftCheck(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.DriftCheckConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.drift.CheckDrift(ctx, config)
+}
+
+// executePatchCheck 执行节点系统补丁检查任务
+func (s *SchedulerService) executePatchCheck(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.PatchCheckConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.patch.CheckPatches(ctx, config)
+}
+
+// executeBlocklist 执行屏蔽列表订阅刷新任务
+func (s *SchedulerService) executeBlocklist(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.BlocklistConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.blocklist.RefreshDue(ctx, config)
+}
+
+// executeTrafficAnomaly 执行节点查询量异常检测任务
+func (s *SchedulerService) executeTrafficAnomaly(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.TrafficAnomalyConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.traffic.CheckAnomalies(ctx, config)
+}
+
+// executeAnswerCheck 执行DNS应答校验任务
+func (s *SchedulerService) executeAnswerCheck(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.AnswerCheckConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.answerCheck.RunAnswerCheck(ctx, config)
+}
+
+// executeCHOptimize 执行ClickHouse表合并优化任务
+func (s *SchedulerService) executeCHOptimize(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.CHOptimizeConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.chOptimize.Optimize(ctx, config)
+}
+
+// executeLogRetention 按节点保留天数清理DNS查询日志
+func (s *SchedulerService) executeLogRetention(ctx context.Context, task models.ScheduledTask) (string, error) {
+	return ApplyLogRetention(ctx, s.logCleanup.logMonitorService)
+}
+
+// executeCHBackup 执行ClickHouse数据备份任务
+func (s *SchedulerService) executeCHBackup(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.CHBackupConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.chBackup.Backup(ctx, task.ID, config)
+}
+
+// executeFleetReport 执行节点资产月度报告任务
+func (s *SchedulerService) executeFleetReport(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.FleetReportConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.fleetReport.RunScheduled(ctx, config)
+}
+
+// executeAgentProbe 执行Agent本地解析探测结果拉取任务
+func (s *SchedulerService) executeAgentProbe(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.AgentProbeConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.agentProbe.Collect(ctx, config)
+}
+
+// executeConfigSnapshot 执行节点配置快照任务
+func (s *SchedulerService) executeConfigSnapshot(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.ConfigSnapshotConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.snapshot.CaptureSnapshots(ctx, config)
+}
+
+// executeCompliance 执行节点配置合规检查任务
+func (s *SchedulerService) executeCompliance(ctx context.Context, task models.ScheduledTask) (string, error) {
+	var config models.ComplianceCheckConfig
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return "", fmt.Errorf("解析任务配置失败: %w", err)
+	}
+
+	return s.compliance.CheckCompliance(ctx, config)
+}
+
 // ReloadTasks 重新加载任务
 func (s *SchedulerService) ReloadTasks() error {
 	s.mutex.Lock()
@@ -521,12 +1086,15 @@ func (s *SchedulerService) GetTask(taskID uint) (*models.ScheduledTask, error) {
 	return &task, nil
 }
 
-// GetTasks 获取任务列表
-func (s *SchedulerService) GetTasks(offset, limit int, taskType, status string) ([]models.ScheduledTask, int64, error) {
+// GetTasks 获取任务列表，orgID 大于 0 时只返回该组织的任务
+func (s *SchedulerService) GetTasks(offset, limit int, taskType, status string, orgID uint) ([]models.ScheduledTask, int64, error) {
 	var tasks []models.ScheduledTask
 	var total int64
 
 	query := s.db.Model(&models.ScheduledTask{})
+	if orgID > 0 {
+		query = query.Where("organization_id = ?", orgID)
+	}
 
 	if taskType != "" {
 		query = query.Where("type = ?", taskType)
@@ -570,7 +1138,7 @@ func (s *SchedulerService) ExecuteTaskManually(task models.ScheduledTask) error
 	s.mutex.RUnlock()
 
 	// 在后台执行任务
-	go s.executeTask(task)
+	go s.executeTask(task, models.TaskTriggerManual)
 	return nil
 }
 
@@ -585,6 +1153,26 @@ func (s *SchedulerService) initializeDefaultTasks() error {
 	if err := s.createDefaultLogCleanupTask(); err != nil {
 		log.Printf("⚠️ 创建默认日志清理任务失败: %v", err)
 	}
+
+	// 创建默认屏蔽列表订阅刷新任务
+	if err := s.createDefaultBlocklistTask(); err != nil {
+		log.Printf("⚠️ 创建默认屏蔽列表订阅刷新任务失败: %v", err)
+	}
+
+	// 创建默认节点配置快照任务
+	if err := s.createDefaultConfigSnapshotTask(); err != nil {
+		log.Printf("⚠️ 创建默认节点配置快照任务失败: %v", err)
+	}
+
+	// 创建默认配置合规检查任务
+	if err := s.createDefaultComplianceTask(); err != nil {
+		log.Printf("⚠️ 创建默认配置合规检查任务失败: %v", err)
+	}
+
+	// 创建默认DNS查询日志保留任务
+	if err := s.createDefaultLogRetentionTask(); err != nil {
+		log.Printf("⚠️ 创建默认DNS查询日志保留任务失败: %v", err)
+	}
 	
 	return nil
 }
@@ -687,3 +1275,139 @@ func (s *SchedulerService) createDefaultLogCleanupTask() error {
 	log.Printf("✅ 已创建默认SmartDNS日志清理任务 (ID: %d)", defaultTask.ID)
 	return nil
 }
+
+// createDefaultBlocklistTask 创建默认屏蔽列表订阅刷新任务
+// 任务每10分钟检查一次，各订阅按自己的刷新间隔决定是否下载
+func (s *SchedulerService) createDefaultBlocklistTask() error {
+	var count int64
+	if err := s.db.Model(&models.ScheduledTask{}).
+		Where("type = ?", models.TaskTypeBlocklist).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("检查屏蔽列表订阅刷新任务失败: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	configJSON, err := json.Marshal(models.BlocklistConfig{SubscriptionIDs: []uint{}})
+	if err != nil {
+		return fmt.Errorf("序列化屏蔽列表订阅刷新配置失败: %w", err)
+	}
+
+	defaultTask := &models.ScheduledTask{
+		Name:        "屏蔽列表订阅刷新",
+		Type:        models.TaskTypeBlocklist,
+		Description: "系统默认创建的任务，每10分钟检查一次，按各订阅的刷新间隔下载远程屏蔽列表并推送到节点",
+		CronExpr:    "0 */10 * * * *",
+		Config:      string(configJSON),
+		Enabled:     true,
+	}
+	if err := s.db.Create(defaultTask).Error; err != nil {
+		return fmt.Errorf("创建屏蔽列表订阅刷新任务失败: %w", err)
+	}
+
+	log.Printf("✅ 已创建默认屏蔽列表订阅刷新任务 (ID: %d)", defaultTask.ID)
+	return nil
+}
+
+// createDefaultConfigSnapshotTask 创建默认节点配置快照任务
+// 每小时读取一次所有节点的配置，内容变化时才保存快照
+func (s *SchedulerService) createDefaultConfigSnapshotTask() error {
+	var count int64
+	if err := s.db.Model(&models.ScheduledTask{}).
+		Where("type = ?", models.TaskTypeConfigSnapshot).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("检查节点配置快照任务失败: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	configJSON, err := json.Marshal(models.ConfigSnapshotConfig{NodeIDs: []uint{}, RetentionDays: 90})
+	if err != nil {
+		return fmt.Errorf("序列化节点配置快照配置失败: %w", err)
+	}
+
+	defaultTask := &models.ScheduledTask{
+		Name:        "节点配置快照",
+		Type:        models.TaskTypeConfigSnapshot,
+		Description: "系统默认创建的任务，每小时读取所有节点的配置，内容变化时保存快照，用于发现直接在节点上做的修改",
+		CronExpr:    "0 15 * * * *",
+		Config:      string(configJSON),
+		Enabled:     true,
+	}
+	if err := s.db.Create(defaultTask).Error; err != nil {
+		return fmt.Errorf("创建节点配置快照任务失败: %w", err)
+	}
+
+	log.Printf("✅ 已创建默认节点配置快照任务 (ID: %d)", defaultTask.ID)
+	return nil
+}
+
+// createDefaultComplianceTask 创建默认配置合规检查任务
+// 每小时按启用的合规策略检查所有节点，没有策略时不连接节点
+func (s *SchedulerService) createDefaultComplianceTask() error {
+	var count int64
+	if err := s.db.Model(&models.ScheduledTask{}).
+		Where("type = ?", models.TaskTypeCompliance).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("检查配置合规检查任务失败: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	configJSON, err := json.Marshal(models.ComplianceCheckConfig{NodeIDs: []uint{}, RetentionDays: 90})
+	if err != nil {
+		return fmt.Errorf("序列化配置合规检查配置失败: %w", err)
+	}
+
+	defaultTask := &models.ScheduledTask{
+		Name:        "配置合规检查",
+		Type:        models.TaskTypeCompliance,
+		Description: "系统默认创建的任务，每小时按合规策略检查所有节点的配置，记录违规并按策略自动修复",
+		CronExpr:    "0 45 * * * *",
+		Config:      string(configJSON),
+		Enabled:     true,
+	}
+	if err := s.db.Create(defaultTask).Error; err != nil {
+		return fmt.Errorf("创建配置合规检查任务失败: %w", err)
+	}
+
+	log.Printf("✅ 已创建默认配置合规检查任务 (ID: %d)", defaultTask.ID)
+	return nil
+}
+
+// createDefaultLogRetentionTask 创建默认DNS查询日志保留任务
+// 每天按各节点的保留天数清理DNS查询日志，并把 ClickHouse 表 TTL 调整为最长的保留天数
+func (s *SchedulerService) createDefaultLogRetentionTask() error {
+	var count int64
+	if err := s.db.Model(&models.ScheduledTask{}).
+		Where("type = ?", models.TaskTypeLogRetention).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("检查DNS查询日志保留任务失败: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	configJSON, err := json.Marshal(models.LogRetentionConfig{})
+	if err != nil {
+		return fmt.Errorf("序列化DNS查询日志保留配置失败: %w", err)
+	}
+
+	defaultTask := &models.ScheduledTask{
+		Name:        "DNS查询日志保留",
+		Type:        models.TaskTypeLogRetention,
+		Description: "系统默认创建的任务，每天凌晨按各节点的保留天数清理DNS查询日志，未单独设置的节点使用 DNS_LOG_RETENTION_DAYS",
+		CronExpr:    "0 30 2 * * *",
+		Config:      string(configJSON),
+		Enabled:     true,
+	}
+	if err := s.db.Create(defaultTask).Error; err != nil {
+		return fmt.Errorf("创建DNS查询日志保留任务失败: %w", err)
+	}
+
+	log.Printf("✅ 已创建默认DNS查询日志保留任务 (ID: %d)", defaultTask.ID)
+	return nil
+}
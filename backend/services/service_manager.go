@@ -0,0 +1,320 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// errReloadUnsupported 服务不支持平滑重载，调用方应回退为重启
+var errReloadUnsupported = errors.New("服务不支持重载")
+
+// ServiceManager 节点上控制服务的方式，不同的 init 系统命令不同
+type ServiceManager interface {
+	Name() string
+	Start(service string) error
+	Stop(service string) error
+	Restart(service string) error
+	// Reload 平滑重载配置，不支持时返回 errReloadUnsupported
+	Reload(service string) error
+	// Enable 设置开机自启
+	Enable(service string) error
+	Disable(service string) error
+	IsActive(service string) (bool, error)
+	// MainPID 服务主进程 PID，未运行时为空
+	MainPID(service string) string
+}
+
+// serviceManagerDetectCommand 输出节点使用的 init 系统
+const serviceManagerDetectCommand = `if [ -d /run/systemd/system ]; then echo systemd; ` +
+	`elif [ -f /etc/openwrt_release ] || [ -x /sbin/procd ]; then echo procd; ` +
+	`elif command -v rc-service >/dev/null 2>&1; then echo openrc; ` +
+	`else echo sysv; fi`
+
+// detectServiceManager 检测节点的 init 系统，检测失败时按 systemd 处理
+func detectServiceManager(client *SSHClient) string {
+	output, err := client.ExecuteCommand(serviceManagerDetectCommand)
+	if err != nil {
+		return models.ServiceManagerSystemd
+	}
+	switch name := strings.TrimSpace(output); name {
+	case models.ServiceManagerSystemd, models.ServiceManagerOpenRC, models.ServiceManagerProcd, models.ServiceManagerSysV:
+		return name
+	}
+	return models.ServiceManagerSystemd
+}
+
+// ServiceManager 控制 service 使用的服务管理方式：docker 安装的 SmartDNS 由容器管理，
+// 其余按节点的 init 系统。节点未记录 init 系统时检测一次并保存
+func (c *SSHClient) ServiceManager(service string) ServiceManager {
+	if service == "smartdns" && smartDNSInstalledVia(c.node) == models.InstallModeDocker {
+		return &dockerServiceManager{client: c}
+	}
+
+	name := models.ServiceManagerSystemd
+	if c.node != nil {
+		if c.node.ServiceManager == "" {
+			c.node.ServiceManager = detectServiceManager(c)
+			if c.node.ID != 0 {
+				database.DB.Model(&models.Node{}).Where("id = ?", c.node.ID).Update("service_manager", c.node.ServiceManager)
+			}
+			log.Printf("节点 %s 使用 %s 管理服务", c.node.Name, c.node.ServiceManager)
+		}
+		name = c.node.ServiceManager
+	}
+
+	switch name {
+	case models.ServiceManagerOpenRC:
+		return &openRCServiceManager{client: c}
+	case models.ServiceManagerProcd:
+		return &procdServiceManager{client: c}
+	case models.ServiceManagerSysV:
+		return &sysVServiceManager{client: c}
+	}
+	return &systemdServiceManager{client: c}
+}
+
+// runServiceCommand 执行服务控制命令，只关心是否成功
+func runServiceCommand(client *SSHClient, format string, args ...interface{}) error {
+	_, err := client.ExecuteCommand(fmt.Sprintf(format, args...))
+	return err
+}
+
+// pidOf 按进程名查找主进程 PID，供没有 PID 查询接口的 init 系统使用
+func pidOf(client *SSHClient, service string) string {
+	output, err := client.ExecuteCommand(fmt.Sprintf("pidof %s 2>/dev/null | awk '{print $1}'", service))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
+
+type systemdServiceManager struct{ client *SSHClient }
+
+func (m *systemdServiceManager) Name() string { return models.ServiceManagerSystemd }
+
+func (m *systemdServiceManager) Start(service string) error {
+	return runServiceCommand(m.client, "sudo systemctl start %s", service)
+}
+
+func (m *systemdServiceManager) Stop(service string) error {
+	return runServiceCommand(m.client, "sudo systemctl stop %s", service)
+}
+
+func (m *systemdServiceManager) Restart(service string) error {
+	return runServiceCommand(m.client, "sudo systemctl restart %s", service)
+}
+
+// Reload 需服务单元配置 ExecReload
+func (m *systemdServiceManager) Reload(service string) error {
+	output, _ := m.client.ExecuteCommand(fmt.Sprintf("systemctl show -p CanReload --value %s", service))
+	if strings.TrimSpace(output) != "yes" {
+		return errReloadUnsupported
+	}
+	return runServiceCommand(m.client, "sudo systemctl reload %s", service)
+}
+
+func (m *systemdServiceManager) Enable(service string) error {
+	return runServiceCommand(m.client, "sudo systemctl daemon-reload && sudo systemctl enable %s", service)
+}
+
+func (m *systemdServiceManager) Disable(service string) error {
+	return runServiceCommand(m.client, "sudo systemctl disable %s", service)
+}
+
+func (m *systemdServiceManager) IsActive(service string) (bool, error) {
+	output, err := m.client.ExecuteCommand(fmt.Sprintf("systemctl is-active %s", service))
+	if err != nil {
+		return false, nil
+	}
+	return strings.TrimSpace(output) == "active", nil
+}
+
+func (m *systemdServiceManager) MainPID(service string) string {
+	output, err := m.client.ExecuteCommand(fmt.Sprintf("systemctl show -p MainPID --value %s", service))
+	if err != nil {
+		return ""
+	}
+	pid := strings.TrimSpace(output)
+	if pid == "0" {
+		return ""
+	}
+	return pid
+}
+
+// openRCServiceManager Alpine 等使用 OpenRC 的系统
+type openRCServiceManager struct{ client *SSHClient }
+
+func (m *openRCServiceManager) Name() string { return models.ServiceManagerOpenRC }
+
+func (m *openRCServiceManager) Start(service string) error {
+	return runServiceCommand(m.client, "sudo rc-service %s start", service)
+}
+
+func (m *openRCServiceManager) Stop(service string) error {
+	return runServiceCommand(m.client, "sudo rc-service %s stop", service)
+}
+
+func (m *openRCServiceManager) Restart(service string) error {
+	return runServiceCommand(m.client, "sudo rc-service %s restart", service)
+}
+
+// Reload 服务脚本未定义 reload 时 rc-service 返回失败
+func (m *openRCServiceManager) Reload(service string) error {
+	if err := runServiceCommand(m.client, "sudo rc-service %s reload", service); err != nil {
+		return errReloadUnsupported
+	}
+	return nil
+}
+
+func (m *openRCServiceManager) Enable(service string) error {
+	return runServiceCommand(m.client, "sudo rc-update add %s default", service)
+}
+
+func (m *openRCServiceManager) Disable(service string) error {
+	return runServiceCommand(m.client, "sudo rc-update del %s default", service)
+}
+
+func (m *openRCServiceManager) IsActive(service string) (bool, error) {
+	return runServiceCommand(m.client, "rc-service %s status >/dev/null 2>&1", service) == nil, nil
+}
+
+func (m *openRCServiceManager) MainPID(service string) string {
+	return pidOf(m.client, service)
+}
+
+// procdServiceManager OpenWrt 的 procd，通常以 root 登录，不使用 sudo
+type procdServiceManager struct{ client *SSHClient }
+
+func (m *procdServiceManager) Name() string { return models.ServiceManagerProcd }
+
+func (m *procdServiceManager) Start(service string) error {
+	return runServiceCommand(m.client, "/etc/init.d/%s start", service)
+}
+
+func (m *procdServiceManager) Stop(service string) error {
+	return runServiceCommand(m.client, "/etc/init.d/%s stop", service)
+}
+
+func (m *procdServiceManager) Restart(service string) error {
+	return runServiceCommand(m.client, "/etc/init.d/%s restart", service)
+}
+
+func (m *procdServiceManager) Reload(service string) error {
+	if err := runServiceCommand(m.client, "/etc/init.d/%s reload", service); err != nil {
+		return errReloadUnsupported
+	}
+	return nil
+}
+
+func (m *procdServiceManager) Enable(service string) error {
+	return runServiceCommand(m.client, "/etc/init.d/%s enable", service)
+}
+
+func (m *procdServiceManager) Disable(service string) error {
+	return runServiceCommand(m.client, "/etc/init.d/%s disable", service)
+}
+
+// IsActive 旧版本 OpenWrt 的服务脚本没有 running 命令，按进程判断
+func (m *procdServiceManager) IsActive(service string) (bool, error) {
+	return runServiceCommand(m.client, "/etc/init.d/%s running 2>/dev/null || pgrep -x %s >/dev/null", service, service) == nil, nil
+}
+
+func (m *procdServiceManager) MainPID(service string) string {
+	return pidOf(m.client, service)
+}
+
+// sysVServiceManager 没有 systemd 的 Debian / CentOS 6 等，开机自启 Debian 系用 update-rc.d，RedHat 系用 chkconfig
+type sysVServiceManager struct{ client *SSHClient }
+
+func (m *sysVServiceManager) Name() string { return models.ServiceManagerSysV }
+
+func (m *sysVServiceManager) Start(service string) error {
+	return runServiceCommand(m.client, "sudo service %s start", service)
+}
+
+func (m *sysVServiceManager) Stop(service string) error {
+	return runServiceCommand(m.client, "sudo service %s stop", service)
+}
+
+func (m *sysVServiceManager) Restart(service string) error {
+	return runServiceCommand(m.client, "sudo service %s restart", service)
+}
+
+func (m *sysVServiceManager) Reload(service string) error {
+	if err := runServiceCommand(m.client, "sudo service %s reload", service); err != nil {
+		return errReloadUnsupported
+	}
+	return nil
+}
+
+func (m *sysVServiceManager) Enable(service string) error {
+	return runServiceCommand(m.client, "sudo update-rc.d %s defaults 2>/dev/null || sudo chkconfig %s on", service, service)
+}
+
+func (m *sysVServiceManager) Disable(service string) error {
+	return runServiceCommand(m.client, "sudo update-rc.d -f %s remove 2>/dev/null || sudo chkconfig %s off", service, service)
+}
+
+// IsActive LSB 脚本的 status 在服务运行时返回 0
+func (m *sysVServiceManager) IsActive(service string) (bool, error) {
+	return runServiceCommand(m.client, "sudo service %s status >/dev/null 2>&1", service) == nil, nil
+}
+
+func (m *sysVServiceManager) MainPID(service string) string {
+	return pidOf(m.client, service)
+}
+
+// dockerServiceManager docker 安装方式的 SmartDNS 容器，开机自启由容器的重启策略决定
+type dockerServiceManager struct{ client *SSHClient }
+
+func (m *dockerServiceManager) Name() string { return models.InstallModeDocker }
+
+func (m *dockerServiceManager) Start(service string) error {
+	return runServiceCommand(m.client, "sudo docker start %s", service)
+}
+
+func (m *dockerServiceManager) Stop(service string) error {
+	return runServiceCommand(m.client, "sudo docker stop %s", service)
+}
+
+func (m *dockerServiceManager) Restart(service string) error {
+	return runServiceCommand(m.client, "sudo docker restart %s", service)
+}
+
+func (m *dockerServiceManager) Reload(service string) error {
+	return errReloadUnsupported
+}
+
+func (m *dockerServiceManager) Enable(service string) error {
+	return runServiceCommand(m.client, "sudo docker update --restart=always %s", service)
+}
+
+func (m *dockerServiceManager) Disable(service string) error {
+	return runServiceCommand(m.client, "sudo docker update --restart=no %s", service)
+}
+
+func (m *dockerServiceManager) IsActive(service string) (bool, error) {
+	output, err := m.client.ExecuteCommand(fmt.Sprintf("sudo docker inspect -f '{{.State.Running}}' %s", service))
+	if err != nil {
+		return false, nil
+	}
+	return strings.TrimSpace(output) == "true", nil
+}
+
+// MainPID 容器主进程在宿主机上的 PID，可直接发送 SIGHUP
+func (m *dockerServiceManager) MainPID(service string) string {
+	output, err := m.client.ExecuteCommand(fmt.Sprintf("sudo docker inspect -f '{{.State.Pid}}' %s", service))
+	if err != nil {
+		return ""
+	}
+	pid := strings.TrimSpace(output)
+	if pid == "0" {
+		return ""
+	}
+	return pid
+}
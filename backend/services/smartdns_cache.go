@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/models"
+)
+
+// SmartDNS 未配置 cache-file 时的默认缓存文件，旧版本写在 /tmp 下
+var defaultCacheFiles = []string{"/var/cache/smartdns/smartdns.cache", "/tmp/smartdns.cache"}
+
+// cacheHitRateWindow 估算命中率使用的日志时间范围
+const cacheHitRateWindow = time.Hour
+
+// SmartDNSCacheService 通过 SSH 查看和清空节点的 SmartDNS 缓存
+//
+// SmartDNS 没有可远程调用的缓存管理接口，清空缓存的方式是停止服务、
+// 删除持久化的缓存文件后再启动；缓存条目数读取自缓存文件快照头部。
+type SmartDNSCacheService struct {
+	db         *gorm.DB
+	logStorage LogMonitorInterface
+}
+
+func NewSmartDNSCacheService(db *gorm.DB, logStorage LogMonitorInterface) *SmartDNSCacheService {
+	return &SmartDNSCacheService{
+		db:         db,
+		logStorage: logStorage,
+	}
+}
+
+// GetStats 采集节点的缓存配置、缓存文件快照和估算命中率
+func (s *SmartDNSCacheService) GetStats(node *models.Node) (*models.SmartDNSCacheStats, error) {
+	client, err := NewSSHClient(node)
+	if err != nil {
+		return nil, fmt.Errorf("连接节点失败: %w", err)
+	}
+	defer client.Close()
+
+	stats := &models.SmartDNSCacheStats{
+		NodeID:           node.ID,
+		NodeName:         node.Name,
+		CachePersist:     "auto",
+		CacheFileEntries: -1,
+		CollectedAt:      time.Now(),
+	}
+
+	stats.ServiceUp, _ = client.GetServiceStatus("smartdns")
+
+	content, err := client.ExecuteCommand("sudo cat " + shellQuote(node.ConfigPath))
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	parseCacheOptions(content, stats)
+
+	if output, err := client.ExecuteCommand("ps -o rss= -C smartdns | head -1"); err == nil {
+		stats.MemoryRSSKB, _ = strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	}
+
+	s.inspectCacheFile(client, stats)
+	s.fillHitRate(node.ID, stats)
+
+	return stats, nil
+}
+
+// Flush 停止 SmartDNS、删除缓存文件并重新启动，清空节点的全部缓存
+func (s *SmartDNSCacheService) Flush(node *models.Node) error {
+	client, err := NewSSHClient(node)
+	if err != nil {
+		return fmt.Errorf("连接节点失败: %w", err)
+	}
+	defer client.Close()
+
+	stats := &models.SmartDNSCacheStats{}
+	if content, err := client.ExecuteCommand("sudo cat " + shellQuote(node.ConfigPath)); err == nil {
+		parseCacheOptions(content, stats)
+	}
+
+	files := defaultCacheFiles
+	if stats.CacheFile != "" {
+		files = []string{stats.CacheFile}
+	}
+
+	// 停止时 SmartDNS 会把内存中的缓存写入文件，因此需在停止后删除，无论删除是否成功都要重新启动
+	// 缓存文件路径来自节点配置文件，逐个加引号，避免路径中的空格或 shell 元字符被解释
+	quoted := make([]string, len(files))
+	for i, file := range files {
+		quoted[i] = shellQuote(file)
+	}
+	manager := client.ServiceManager("smartdns")
+	manager.Stop("smartdns")
+	_, removeErr := client.ExecuteCommandWithTimeout("sudo rm -f -- "+strings.Join(quoted, " "), 60*time.Second)
+	manager.Start("smartdns")
+	if removeErr != nil {
+		return fmt.Errorf("清空缓存失败: %w", removeErr)
+	}
+
+	if up, _ := client.GetServiceStatus("smartdns"); !up {
+		return fmt.Errorf("缓存已清空，但 SmartDNS 未能重新启动")
+	}
+
+	log.Printf("🧹 节点 %s 的 SmartDNS 缓存已清空", node.Name)
+	return nil
+}
+
+// BatchFlush 依次清空多个节点的缓存，tag 不为空时追加带该标签的节点
+func (s *SmartDNSCacheService) BatchFlush(request models.CacheFlushRequest) ([]models.CacheFlushResult, error) {
+	nodes, err := s.selectNodes(request)
+	if err != nil {
+		return nil, err
+	}
+
+	maintenance := NewMaintenanceService()
+	results := make([]models.CacheFlushResult, 0, len(nodes))
+	for i := range nodes {
+		result := models.CacheFlushResult{
+			NodeID:   nodes[i].ID,
+			NodeName: nodes[i].Name,
+			Success:  true,
+		}
+		if deferred, _ := maintenance.DeferNodeAction(&nodes[i], models.DeferredActionCacheFlush,
+			"批量清空缓存", request.Override); deferred {
+			result.Deferred = true
+		} else if err := s.Flush(&nodes[i]); err != nil {
+			result.Success = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *SmartDNSCacheService) selectNodes(request models.CacheFlushRequest) ([]models.Node, error) {
+	var all []models.Node
+	query := s.db.Order("id")
+	if request.OrganizationID > 0 {
+		query = query.Where("organization_id = ?", request.OrganizationID)
+	}
+	if err := query.Find(&all).Error; err != nil {
+		return nil, fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	wanted := make(map[uint]bool, len(request.NodeIDs))
+	for _, id := range request.NodeIDs {
+		wanted[id] = true
+	}
+
+	var nodes []models.Node
+	for _, node := range all {
+		if wanted[node.ID] || (request.Tag != "" && nodeHasTag(node, request.Tag)) {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("没有匹配的节点")
+	}
+	return nodes, nil
+}
+
+// nodeHasTag 节点标签可能是 JSON 数组，也可能是表单填写的逗号分隔文本
+func nodeHasTag(node models.Node, tag string) bool {
+	var tags []string
+	if err := json.Unmarshal([]byte(node.Tags), &tags); err != nil {
+		tags = strings.FieldsFunc(node.Tags, func(r rune) bool {
+			return r == ',' || r == '，'
+		})
+	}
+	for _, t := range tags {
+		if strings.TrimSpace(t) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// inspectCacheFile 读取缓存文件大小、修改时间和头部记录的条目数
+func (s *SmartDNSCacheService) inspectCacheFile(client *SSHClient, stats *models.SmartDNSCacheStats) {
+	files := defaultCacheFiles
+	if stats.CacheFile != "" {
+		files = []string{stats.CacheFile}
+	}
+
+	for _, file := range files {
+		output, err := client.ExecuteCommand(fmt.Sprintf("sudo stat -c '%%s %%Y' %s 2>/dev/null", shellQuote(file)))
+		if err != nil {
+			continue
+		}
+		var size, mtime int64
+		if _, err := fmt.Sscanf(strings.TrimSpace(output), "%d %d", &size, &mtime); err != nil {
+			continue
+		}
+
+		stats.CacheFile = file
+		stats.CacheFileExists = true
+		stats.CacheFileBytes = size
+		savedAt := time.Unix(mtime, 0)
+		stats.CacheFileSavedAt = &savedAt
+
+		// 文件头: magic(4) + cache_version(4) + version(32) + cache_number(4)，小端序
+		output, err = client.ExecuteCommand(fmt.Sprintf("sudo od -An -t u4 -j 40 -N 4 %s", shellQuote(file)))
+		if err == nil {
+			if n, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64); err == nil {
+				stats.CacheFileEntries = n
+			}
+		}
+		return
+	}
+}
+
+// fillHitRate 根据最近一小时日志中命中缓存（cache_hit）的查询占比估算命中率
+func (s *SmartDNSCacheService) fillHitRate(nodeID uint, stats *models.SmartDNSCacheStats) {
+	provider, ok := s.logStorage.(CacheHitRateProvider)
+	if !ok {
+		return
+	}
+
+	end := time.Now()
+	total, hits, err := provider.GetCacheHitCounts(nodeID, end.Add(-cacheHitRateWindow), end)
+	if err != nil {
+		log.Printf("⚠️ 统计节点 %d 缓存命中率失败: %v", nodeID, err)
+		return
+	}
+
+	stats.HitRateWindow = cacheHitRateWindow.String()
+	stats.SampleQueries = total
+	if total > 0 {
+		rate := float64(hits) / float64(total) * 100
+		stats.HitRate = &rate
+	}
+}
+
+// parseCacheOptions 从配置内容中读取缓存相关选项
+func parseCacheOptions(content string, stats *models.SmartDNSCacheStats) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "cache-size":
+			stats.CacheSize, _ = strconv.Atoi(fields[1])
+		case "cache-persist":
+			stats.CachePersist = fields[1]
+		case "cache-file":
+			stats.CacheFile = fields[1]
+		case "prefetch-domain":
+			stats.PrefetchOn = fields[1] == "yes"
+		case "serve-expired":
+			stats.ServeExpired = fields[1] == "yes"
+		}
+	}
+}
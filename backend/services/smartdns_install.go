@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// smartDNSComposeDir docker 安装方式的 compose 文件目录
+const smartDNSComposeDir = "/opt/smartdns"
+
+// resolveInstallMode 节点初始化使用的安装方式：节点指定的方式优先，自动选择时 OpenWrt 使用 opkg，
+// 其他系统有对应架构的官方安装包时使用安装包，没有时使用系统软件源。docker 方式只在节点指定时使用
+func resolveInstallMode(ctx context.Context, node *models.Node) string {
+	if node.InstallMode != "" && node.InstallMode != models.InstallModeAuto {
+		return node.InstallMode
+	}
+	if node.OSType == "openwrt" {
+		return models.InstallModeOpkg
+	}
+	if _, _, err := resolveSmartDNSPackage(ctx, node, ""); err != nil {
+		if _, _, ok := smartDNSPackageCommands(node.OSType); ok {
+			slog.WarnContext(ctx, "没有适用的官方安装包，改用系统软件源安装", "node", node.Name, "arch", node.Architecture, "error", err)
+			return models.InstallModePackage
+		}
+	}
+	return models.InstallModeTarball
+}
+
+// smartDNSInstalledVia 节点实际使用的安装方式，之前版本安装的节点没有记录，按官方安装包处理
+func smartDNSInstalledVia(node *models.Node) string {
+	if node == nil || node.InstalledVia == "" {
+		return models.InstallModeTarball
+	}
+	return node.InstalledVia
+}
+
+// smartDNSPackageCommands 系统软件源刷新、安装和卸载 smartdns 的命令，不支持的系统 ok 为 false
+func smartDNSPackageCommands(osType string) (update, install string, ok bool) {
+	switch osType {
+	case "ubuntu", "debian":
+		return "sudo apt-get update -qq", "sudo DEBIAN_FRONTEND=noninteractive apt-get install -y smartdns", true
+	case "centos":
+		// smartdns 在 EPEL 中
+		return "sudo yum install -y epel-release", "sudo yum install -y smartdns", true
+	}
+	return "", "", false
+}
+
+// smartDNSComposeFile docker 安装方式的 compose 文件，配置和日志目录挂载到容器中的默认位置
+func smartDNSComposeFile(node *models.Node) string {
+	configDir := "/etc/smartdns"
+	if node.ConfigPath != "" {
+		configDir = path.Dir(node.ConfigPath)
+	}
+	logDir := "/var/log/smartdns"
+	if node.LogPath != "" {
+		logDir = path.Dir(node.LogPath)
+	}
+	return fmt.Sprintf(`# 由 SmartDNS Manager 自动生成
+services:
+  smartdns:
+    image: %s
+    container_name: smartdns
+    network_mode: host
+    restart: always
+    volumes:
+      - %s:/etc/smartdns
+      - %s:/var/log/smartdns
+`, config.GetConfig().SmartDNSDockerImage, configDir, logDir)
+}
+
+// smartDNSComposeCommand 在 compose 目录执行 docker compose 子命令，没有 compose 插件时使用 docker-compose
+func smartDNSComposeCommand(args string) string {
+	return fmt.Sprintf("cd %s && (sudo docker compose %s 2>&1 || sudo docker-compose %s 2>&1)", smartDNSComposeDir, args, args)
+}
+
+// smartDNSVersionCommand 查看节点上 SmartDNS 版本的命令，docker 安装时在容器内执行
+func smartDNSVersionCommand(node *models.Node) string {
+	if smartDNSInstalledVia(node) == models.InstallModeDocker {
+		return "sudo docker exec smartdns smartdns -v 2>&1"
+	}
+	return "smartdns -v 2>&1 || /usr/sbin/smartdns -v 2>&1"
+}
+
+// smartDNSUninstallCommands 按安装方式卸载 SmartDNS 的命令，执行前服务已停止；官方安装包的卸载由 UninstallSmartDNS 处理
+func smartDNSUninstallCommands(node *models.Node) []string {
+	switch smartDNSInstalledVia(node) {
+	case models.InstallModeOpkg:
+		return []string{
+			"opkg remove smartdns",
+			"rm -rf /etc/smartdns",
+		}
+	case models.InstallModeDocker:
+		return []string{
+			smartDNSComposeCommand("down"),
+			fmt.Sprintf("sudo rm -rf %s", smartDNSComposeDir),
+			"sudo rm -rf /etc/smartdns",
+			"sudo rm -rf /var/log/smartdns",
+		}
+	case models.InstallModePackage:
+		remove := "sudo apt-get remove -y smartdns"
+		if node.OSType == "centos" {
+			remove = "sudo yum remove -y smartdns"
+		}
+		return []string{
+			remove,
+			"sudo rm -rf /etc/smartdns",
+			"sudo rm -rf /var/log/smartdns",
+		}
+	}
+	return nil
+}
+
+// smartDNSUpgradeHint 不支持在线升级的安装方式的升级说明
+func smartDNSUpgradeHint(installedVia string) string {
+	switch installedVia {
+	case models.InstallModePackage:
+		return "节点通过系统软件源安装 SmartDNS，请使用 apt / yum 升级"
+	case models.InstallModeOpkg:
+		return "节点通过 opkg 安装 SmartDNS，请在路由器上执行 opkg upgrade smartdns"
+	case models.InstallModeDocker:
+		return fmt.Sprintf("节点使用 Docker 运行 SmartDNS，请在 %s 目录执行 docker compose pull && docker compose up -d", smartDNSComposeDir)
+	}
+	return ""
+}
+
+// prepareSmartDNSPackage 非官方安装包方式的下载步骤：刷新软件源或拉取镜像
+func (s *InitService) prepareSmartDNSPackage(client *SSHClient, node *models.Node) (string, error) {
+	switch node.InstalledVia {
+	case models.InstallModeOpkg:
+		return s.executeStreamed(client, node.ID, "download", "opkg update 2>&1")
+	case models.InstallModeDocker:
+		if output, err := client.ExecuteCommand("sudo docker version --format '{{.Server.Version}}' 2>&1"); err != nil {
+			return output, fmt.Errorf("节点未安装 Docker 或 Docker 未运行: %v", err)
+		}
+		return s.executeStreamed(client, node.ID, "download", "sudo docker pull "+config.GetConfig().SmartDNSDockerImage+" 2>&1")
+	default:
+		update, _, ok := smartDNSPackageCommands(node.OSType)
+		if !ok {
+			return "", fmt.Errorf("%s 不支持通过系统软件源安装 SmartDNS", node.OSType)
+		}
+		return s.executeStreamed(client, node.ID, "download", update+" 2>&1")
+	}
+}
+
+// installSmartDNSPackage 非官方安装包方式的安装步骤，docker 方式写入 compose 文件，在启动步骤创建容器
+func (s *InitService) installSmartDNSPackage(client *SSHClient, node *models.Node) (string, error) {
+	switch node.InstalledVia {
+	case models.InstallModeOpkg:
+		return s.executeStreamed(client, node.ID, "install", "opkg install smartdns 2>&1")
+	case models.InstallModeDocker:
+		configDir := path.Dir(node.ConfigPath)
+		if node.ConfigPath == "" {
+			configDir = "/etc/smartdns"
+		}
+		mkdirCmd := fmt.Sprintf("sudo mkdir -p %s %s /var/log/smartdns", smartDNSComposeDir, configDir)
+		if output, err := client.ExecuteCommand(mkdirCmd); err != nil {
+			return output, err
+		}
+		compose := smartDNSComposeFile(node)
+		if err := client.WriteFile(smartDNSComposeDir+"/docker-compose.yml", compose); err != nil {
+			return "", fmt.Errorf("写入 compose 文件失败: %w", err)
+		}
+		return compose, nil
+	default:
+		_, install, ok := smartDNSPackageCommands(node.OSType)
+		if !ok {
+			return "", fmt.Errorf("%s 不支持通过系统软件源安装 SmartDNS", node.OSType)
+		}
+		return s.executeStreamed(client, node.ID, "install", install+" 2>&1")
+	}
+}
+
+// startSmartDNSPackage opkg 和 docker 方式启动服务前的准备：启用 UCI 配置或创建容器
+func (s *InitService) startSmartDNSPackage(client *SSHClient, node *models.Node) (string, error) {
+	switch node.InstalledVia {
+	case models.InstallModeOpkg:
+		// 监听端口和与 dnsmasq 的配合由 UCI 配置决定
+		return s.executeStreamed(client, node.ID, "start",
+			"uci set smartdns.@smartdns[0].enabled='1' && uci commit smartdns 2>&1")
+	case models.InstallModeDocker:
+		return s.executeStreamed(client, node.ID, "start", smartDNSComposeCommand("up -d"))
+	}
+	return "", nil
+}
+
+// opkgSmartDNSConfig OpenWrt 上由 UCI 生成主配置并包含 custom.conf，端口由 UCI 设置，custom.conf 中不能再绑定端口
+func opkgSmartDNSConfig(defaultConfig string) string {
+	lines := strings.Split(defaultConfig, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.HasPrefix(line, "bind ") || line == "# 绑定端口" {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
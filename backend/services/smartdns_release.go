@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+const (
+	// smartDNSCatalogTTL 版本目录的缓存时间，GitHub 未认证请求每小时限 60 次
+	smartDNSCatalogTTL = time.Hour
+	// smartDNSCatalogRetry 获取失败后再次请求 GitHub 的间隔
+	smartDNSCatalogRetry = 5 * time.Minute
+)
+
+// smartDNSAssetPattern Linux 通用安装包，如 smartdns.1.2024.06.12-2222.x86_64-linux-all.tar.gz
+var smartDNSAssetPattern = regexp.MustCompile(`^smartdns\.(.+)\.([a-z0-9_]+)-linux-all\.tar\.gz$`)
+
+// smartDNSArchAliases uname -m 的输出与安装包架构名不一致的情况
+var smartDNSArchAliases = map[string]string{
+	"amd64":  "x86_64",
+	"arm64":  "aarch64",
+	"armv7l": "arm",
+	"armv6l": "arm",
+}
+
+// SmartDNSReleaseCatalog SmartDNS 版本目录，从 GitHub Releases 获取并缓存
+type SmartDNSReleaseCatalog struct {
+	mu        sync.Mutex
+	releases  []models.SmartDNSRelease
+	fetchedAt time.Time
+	failedAt  time.Time
+	lastErr   error
+	client    *http.Client
+}
+
+var (
+	smartDNSCatalog     *SmartDNSReleaseCatalog
+	smartDNSCatalogOnce sync.Once
+)
+
+// GetSmartDNSReleaseCatalog 获取版本目录
+func GetSmartDNSReleaseCatalog() *SmartDNSReleaseCatalog {
+	smartDNSCatalogOnce.Do(func() {
+		smartDNSCatalog = &SmartDNSReleaseCatalog{client: &http.Client{Timeout: 15 * time.Second}}
+	})
+	return smartDNSCatalog
+}
+
+// Releases 返回版本目录，最新发布的排在前面，refresh 为 true 时忽略缓存。
+// GitHub 不可访问时返回上次获取的目录；从未获取成功时只包含 INIT_VERSION 对应的版本，同时返回错误说明原因
+func (c *SmartDNSReleaseCatalog) Releases(ctx context.Context, refresh bool) ([]models.SmartDNSRelease, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !refresh && c.releases != nil && time.Since(c.fetchedAt) < smartDNSCatalogTTL {
+		return c.releases, nil
+	}
+	if !refresh && c.lastErr != nil && time.Since(c.failedAt) < smartDNSCatalogRetry {
+		return c.fallback(), c.lastErr
+	}
+
+	releases, err := c.fetch(ctx)
+	if err != nil {
+		log.Printf("⚠️ 获取 SmartDNS 版本目录失败: %v", err)
+		c.failedAt = time.Now()
+		c.lastErr = err
+		return c.fallback(), err
+	}
+
+	c.releases = releases
+	c.fetchedAt = time.Now()
+	c.lastErr = nil
+	return releases, nil
+}
+
+// fallback 获取失败时使用的目录，调用方须持有锁
+func (c *SmartDNSReleaseCatalog) fallback() []models.SmartDNSRelease {
+	if c.releases != nil {
+		return c.releases
+	}
+	if release, ok := configuredSmartDNSRelease(); ok {
+		return []models.SmartDNSRelease{release}
+	}
+	return nil
+}
+
+// Find 按版本号或 Release 标签查找发行版
+func (c *SmartDNSReleaseCatalog) Find(ctx context.Context, version string) (*models.SmartDNSRelease, error) {
+	version = strings.TrimSpace(version)
+	// INIT_BASE_URL 可能指向内网镜像，INIT_VERSION 始终从这里下载
+	if release, ok := configuredSmartDNSRelease(); ok && release.Version == version {
+		return &release, nil
+	}
+
+	releases, fetchErr := c.Releases(ctx, false)
+	for i := range releases {
+		if releases[i].Version == version || releases[i].Tag == version {
+			return &releases[i], nil
+		}
+	}
+	if fetchErr != nil {
+		return nil, fmt.Errorf("版本目录中没有 %s（获取目录失败: %v）", version, fetchErr)
+	}
+	return nil, fmt.Errorf("版本目录中没有 %s", version)
+}
+
+// Latest 最新的正式版
+func (c *SmartDNSReleaseCatalog) Latest(ctx context.Context) (*models.SmartDNSRelease, error) {
+	releases, err := c.Releases(ctx, false)
+	for i := range releases {
+		if !releases[i].Prerelease {
+			return &releases[i], nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取最新版本失败: %w", err)
+	}
+	return nil, fmt.Errorf("版本目录中没有正式版")
+}
+
+// githubRelease GitHub Releases API 返回的发行版
+type githubRelease struct {
+	TagName     string     `json:"tag_name"`
+	Name        string     `json:"name"`
+	Body        string     `json:"body"`
+	Draft       bool       `json:"draft"`
+	Prerelease  bool       `json:"prerelease"`
+	PublishedAt *time.Time `json:"published_at"`
+	Assets      []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+		Size               int64  `json:"size"`
+	} `json:"assets"`
+}
+
+func (c *SmartDNSReleaseCatalog) fetch(ctx context.Context) ([]models.SmartDNSRelease, error) {
+	url := config.GetConfig().SmartDNSReleasesURL
+	if url == "" {
+		return nil, fmt.Errorf("未配置 SMARTDNS_RELEASES_URL")
+	}
+	if !strings.Contains(url, "?") {
+		url += "?per_page=30"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "smartdns-manager")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 GitHub API 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API 返回错误状态码: %d", resp.StatusCode)
+	}
+
+	var items []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("解析 GitHub API 响应失败: %w", err)
+	}
+
+	releases := make([]models.SmartDNSRelease, 0, len(items))
+	for _, item := range items {
+		if item.Draft {
+			continue
+		}
+		release := models.SmartDNSRelease{
+			Tag:         item.TagName,
+			Name:        item.Name,
+			PublishedAt: item.PublishedAt,
+			Prerelease:  item.Prerelease,
+			Notes:       item.Body,
+		}
+		for _, asset := range item.Assets {
+			match := smartDNSAssetPattern.FindStringSubmatch(asset.Name)
+			if match == nil {
+				continue
+			}
+			if release.Version == "" {
+				release.Version = match[1]
+			}
+			release.Assets = append(release.Assets, models.SmartDNSReleaseAsset{
+				Architecture: match[2],
+				Name:         asset.Name,
+				DownloadURL:  asset.BrowserDownloadURL,
+				Size:         asset.Size,
+			})
+		}
+		// 只有 OpenWrt、Debian 等专用安装包的发行版无法通过 SSH 安装，不列入目录
+		if len(release.Assets) > 0 {
+			releases = append(releases, release)
+		}
+	}
+	return releases, nil
+}
+
+// configuredSmartDNSRelease 按 INIT_VERSION 和 INIT_BASE_URL 拼接的发行版，INIT_VERSION 为 latest 时没有
+func configuredSmartDNSRelease() (models.SmartDNSRelease, bool) {
+	cfg := config.GetConfig()
+	version := strings.TrimSpace(cfg.InitVersion)
+	if version == "" || strings.EqualFold(version, "latest") {
+		return models.SmartDNSRelease{}, false
+	}
+
+	baseURL := strings.TrimSuffix(cfg.InitBaseURL, "/")
+	release := models.SmartDNSRelease{
+		Tag:     path.Base(baseURL),
+		Version: version,
+		Name:    "INIT_VERSION",
+	}
+	for _, arch := range []string{"x86_64", "aarch64", "arm"} {
+		name := fmt.Sprintf("smartdns.%s.%s-linux-all.tar.gz", version, arch)
+		release.Assets = append(release.Assets, models.SmartDNSReleaseAsset{
+			Architecture: arch,
+			Name:         name,
+			DownloadURL:  baseURL + "/" + name,
+		})
+	}
+	return release, true
+}
+
+// smartDNSAssetFor 选择与节点架构匹配的安装包
+func smartDNSAssetFor(release *models.SmartDNSRelease, architecture string) (*models.SmartDNSReleaseAsset, error) {
+	arch := strings.ToLower(strings.TrimSpace(architecture))
+	if alias, ok := smartDNSArchAliases[arch]; ok {
+		arch = alias
+	}
+	for i := range release.Assets {
+		if release.Assets[i].Architecture == arch {
+			return &release.Assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("SmartDNS %s 没有适用于 %s 架构的安装包", release.Version, architecture)
+}
+
+// resolveSmartDNSPackage 选择节点要安装的发行版和安装包，version 为空时使用节点的目标版本
+func resolveSmartDNSPackage(ctx context.Context, node *models.Node, version string) (*models.SmartDNSRelease, *models.SmartDNSReleaseAsset, error) {
+	if version == "" {
+		target, _, err := ResolveSmartDNSTarget(ctx, node)
+		if err != nil {
+			return nil, nil, err
+		}
+		version = target
+	}
+	release, err := GetSmartDNSReleaseCatalog().Find(ctx, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	asset, err := smartDNSAssetFor(release, node.Architecture)
+	if err != nil {
+		return nil, nil, err
+	}
+	return release, asset, nil
+}
+
+// smartDNSDownloadCommand 在节点上下载安装包到 dir，wget 不可用时使用 curl
+func smartDNSDownloadCommand(dir string, asset *models.SmartDNSReleaseAsset) string {
+	return fmt.Sprintf("cd %s && (wget --tries=3 --timeout=30 -q '%s' -O %s 2>&1 || curl -sSL --retry 3 --max-time 30 -o %s '%s' 2>&1)",
+		dir, asset.DownloadURL, asset.Name, asset.Name, asset.DownloadURL)
+}
+
+// ListSmartDNSVersionPins 按标签固定的版本
+func ListSmartDNSVersionPins() ([]models.SmartDNSVersionPin, error) {
+	var pins []models.SmartDNSVersionPin
+	err := database.DB.Order("id").Find(&pins).Error
+	return pins, err
+}
+
+// SaveSmartDNSVersionPin 为标签固定目标版本，标签已固定时修改版本；版本须在版本目录中
+func SaveSmartDNSVersionPin(ctx context.Context, req models.SmartDNSVersionPinRequest) (*models.SmartDNSVersionPin, error) {
+	tag := strings.TrimSpace(req.Tag)
+	if tag == "" {
+		return nil, fmt.Errorf("标签不能为空")
+	}
+	release, err := GetSmartDNSReleaseCatalog().Find(ctx, req.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	var pin models.SmartDNSVersionPin
+	if err := database.DB.Where("tag = ?", tag).First(&pin).Error; err != nil {
+		pin = models.SmartDNSVersionPin{Tag: tag}
+	}
+	pin.Version = release.Version
+	pin.Description = req.Description
+	if err := database.DB.Save(&pin).Error; err != nil {
+		return nil, fmt.Errorf("保存版本固定失败: %w", err)
+	}
+	return &pin, nil
+}
+
+// ResolveSmartDNSTarget 节点的目标版本：按标签固定的版本优先，节点的多个标签都固定了版本时取最早创建的固定；
+// 没有固定时为 INIT_VERSION，INIT_VERSION 为 latest 时取最新的正式版
+func ResolveSmartDNSTarget(ctx context.Context, node *models.Node) (version, pinnedBy string, err error) {
+	pins, err := ListSmartDNSVersionPins()
+	if err != nil {
+		return "", "", fmt.Errorf("读取版本固定失败: %w", err)
+	}
+	for _, pin := range pins {
+		if nodeHasTag(*node, pin.Tag) {
+			return pin.Version, pin.Tag, nil
+		}
+	}
+
+	if release, ok := configuredSmartDNSRelease(); ok {
+		return release.Version, "", nil
+	}
+	latest, err := GetSmartDNSReleaseCatalog().Latest(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return latest.Version, "", nil
+}
+
+// GetSmartDNSNodeVersion 节点当前版本、目标版本和版本目录中的最新正式版
+func GetSmartDNSNodeVersion(ctx context.Context, node *models.Node) (*models.SmartDNSNodeVersion, error) {
+	target, pinnedBy, err := ResolveSmartDNSTarget(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.SmartDNSNodeVersion{
+		NodeID:         node.ID,
+		CurrentVersion: node.SmartDNSVersion,
+		TargetVersion:  target,
+		PinnedBy:       pinnedBy,
+	}
+	if latest, err := GetSmartDNSReleaseCatalog().Latest(ctx); err == nil {
+		status.LatestVersion = latest.Version
+	}
+	status.InstalledVia = smartDNSInstalledVia(node)
+	status.UpgradeAvailable = node.InitStatus == "installed" && status.InstalledVia == models.InstallModeTarball &&
+		node.SmartDNSVersion != target
+	return status, nil
+}
+
+// DeleteSmartDNSVersionPin 取消标签的版本固定，带该标签的节点恢复使用默认版本
+func DeleteSmartDNSVersionPin(id uint) (*models.SmartDNSVersionPin, error) {
+	var pin models.SmartDNSVersionPin
+	if err := database.DB.First(&pin, id).Error; err != nil {
+		return nil, fmt.Errorf("版本固定不存在")
+	}
+	if err := database.DB.Delete(&pin).Error; err != nil {
+		return nil, fmt.Errorf("删除版本固定失败: %w", err)
+	}
+	return &pin, nil
+}
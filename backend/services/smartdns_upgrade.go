@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// smartDNSUpgradeDir 节点上存放升级安装包和配置备份的临时目录
+const smartDNSUpgradeDir = "/tmp/smartdns-upgrade"
+
+var smartDNSVersionPattern = regexp.MustCompile(`(?i)smartdns\s+([\d.]+[^\s]*)`)
+
+func init() {
+	RegisterJobHandler(models.BackgroundJobSmartDNSUpgrade, JobHandler{
+		// 下载完成前可以取消，之后的步骤须执行完或回退，不再检查取消
+		Cancellable: true,
+		Run: func(job *JobContext) error {
+			var req models.SmartDNSUpgradeRequest
+			if err := job.Payload(&req); err != nil {
+				return fmt.Errorf("解析任务参数失败: %w", err)
+			}
+			result, err := NewInitService().UpgradeSmartDNS(job.Context(), job.NodeID(), req, job.SetProgress)
+			if result != nil {
+				job.SetResult(result)
+			}
+			return err
+		},
+		Guidance: "SmartDNS 升级在服务重启时中断，请在节点上执行 smartdns -v 和 systemctl status smartdns 确认版本和服务状态；" +
+			"程序异常时可用升级前备份的 <程序路径>.prev（通常为 /usr/sbin/smartdns.prev）恢复",
+	})
+}
+
+// UpgradeSmartDNS 升级节点上的 SmartDNS：下载并校验目标版本，备份当前程序和配置目录后只替换程序文件，
+// 重启后检查服务状态、程序版本、配置是否保留和解析是否正常，任一检查失败时恢复原程序和配置。
+// progress 记录执行进度，可为空
+func (s *InitService) UpgradeSmartDNS(ctx context.Context, nodeID uint, req models.SmartDNSUpgradeRequest, progress func(percent int, message string)) (*models.SmartDNSUpgradeResult, error) {
+	if progress == nil {
+		progress = func(int, string) {}
+	}
+
+	var node models.Node
+	if err := database.DB.First(&node, nodeID).Error; err != nil {
+		return nil, fmt.Errorf("节点不存在: %w", err)
+	}
+	if node.InitStatus != "installed" {
+		return nil, fmt.Errorf("节点尚未安装 SmartDNS，请先初始化节点")
+	}
+	if hint := smartDNSUpgradeHint(smartDNSInstalledVia(&node)); hint != "" {
+		return nil, fmt.Errorf("%s", hint)
+	}
+
+	release, asset, err := resolveSmartDNSPackage(ctx, &node, strings.TrimSpace(req.Version))
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewSSHClient(&node)
+	if err != nil {
+		return nil, fmt.Errorf("连接节点失败: %w", err)
+	}
+	defer client.Close()
+
+	result := &models.SmartDNSUpgradeResult{
+		FromVersion: smartDNSVersionOf(client),
+		ToVersion:   release.Version,
+	}
+	if result.FromVersion == "" {
+		result.FromVersion = node.SmartDNSVersion
+	}
+	if result.FromVersion == release.Version && !req.Force {
+		return result, fmt.Errorf("节点已是 SmartDNS %s，如需重新安装请选择强制升级", release.Version)
+	}
+
+	binary, _ := client.ExecuteCommand("command -v smartdns 2>/dev/null || echo /usr/sbin/smartdns")
+	result.BinaryPath = strings.TrimSpace(binary)
+	configPath := node.ConfigPath
+	if configPath == "" {
+		configPath = "/etc/smartdns/smartdns.conf"
+	}
+	configDir := path.Dir(configPath)
+	configSum := remoteFileChecksum(client, configPath)
+
+	slog.InfoContext(ctx, "开始升级 SmartDNS", "node", node.Name, "from", result.FromVersion, "to", release.Version)
+
+	// 下载并确认新程序可以运行，此时节点上的服务不受影响
+	progress(10, "download")
+	client.ExecuteCommand(fmt.Sprintf("rm -rf %s && mkdir -p %s", smartDNSUpgradeDir, smartDNSUpgradeDir))
+	if output, err := client.ExecuteCommand(smartDNSDownloadCommand(smartDNSUpgradeDir, asset)); err != nil {
+		return result, fmt.Errorf("下载失败: %v %s", err, strings.TrimSpace(output))
+	}
+	if output, err := client.ExecuteCommand(fmt.Sprintf("cd %s && tar zxf %s", smartDNSUpgradeDir, asset.Name)); err != nil {
+		return result, fmt.Errorf("解压失败: %v %s", err, strings.TrimSpace(output))
+	}
+	newBinary := smartDNSUpgradeDir + "/smartdns/usr/sbin/smartdns"
+	output, err := client.ExecuteCommand(newBinary + " -v 2>&1")
+	if err != nil {
+		return result, fmt.Errorf("新版本程序无法在节点上运行: %v %s", err, strings.TrimSpace(output))
+	}
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+
+	// 备份当前程序和配置目录，程序备份保留在节点上，便于之后手动回退
+	progress(40, "backup")
+	backupCmd := fmt.Sprintf("sudo cp -a %s %s.prev && sudo cp -a %s %s/config-backup",
+		result.BinaryPath, result.BinaryPath, configDir, smartDNSUpgradeDir)
+	if output, err := client.ExecuteCommand(backupCmd); err != nil {
+		return result, fmt.Errorf("备份当前程序和配置失败: %v %s", err, strings.TrimSpace(output))
+	}
+
+	// 只替换程序文件，不执行安装脚本，配置文件和服务单元保持不变
+	progress(55, "install")
+	if output, err := client.ExecuteCommand(fmt.Sprintf("sudo install -m 0755 %s %s", newBinary, result.BinaryPath)); err != nil {
+		return result, fmt.Errorf("替换程序失败: %v %s", err, strings.TrimSpace(output))
+	}
+
+	progress(70, "restart")
+	restartErr := client.RestartService("smartdns")
+	time.Sleep(3 * time.Second)
+
+	progress(85, "verify")
+	checks, verifyErr := s.verifySmartDNSUpgrade(ctx, client, &node, release.Version, configPath, configSum)
+	result.Checks = checks
+	if restartErr != nil && verifyErr == nil {
+		verifyErr = fmt.Errorf("重启服务失败: %w", restartErr)
+	}
+
+	if verifyErr != nil {
+		progress(90, "rollback")
+		result.RolledBack = true
+		result.Error = verifyErr.Error()
+		if err := s.rollbackSmartDNS(client, result.BinaryPath, configDir); err != nil {
+			s.notificationService.SendNotification(node.ID, "node_upgrade_failed", "❌ SmartDNS 升级失败且回退失败",
+				fmt.Sprintf("节点 `%s` 升级到 %s 失败: %s\n回退失败: %s", node.Name, release.Version, verifyErr, err))
+			return result, fmt.Errorf("升级后检查失败（%v），回退也失败: %v。请在节点上执行 sudo install -m 0755 %s.prev %s 并重启 smartdns",
+				verifyErr, err, result.BinaryPath, result.BinaryPath)
+		}
+		s.notificationService.SendNotification(node.ID, "node_upgrade_failed", "⚠️ SmartDNS 升级失败，已回退",
+			fmt.Sprintf("节点 `%s` 升级到 %s 失败，已恢复到 %s\n原因: %s", node.Name, release.Version, result.FromVersion, verifyErr))
+		return result, fmt.Errorf("升级后检查失败，已恢复到 %s: %w", result.FromVersion, verifyErr)
+	}
+
+	node.SmartDNSVersion = release.Version
+	database.DB.Model(&node).Update("smartdns_version", release.Version)
+	client.ExecuteCommand(fmt.Sprintf("rm -rf %s", smartDNSUpgradeDir))
+
+	progress(100, "done")
+	s.notificationService.SendNotification(node.ID, "node_upgrade_success", "✅ SmartDNS 升级完成",
+		fmt.Sprintf("节点 `%s` SmartDNS 已从 %s 升级到 %s", node.Name, result.FromVersion, release.Version))
+	slog.InfoContext(ctx, "SmartDNS 升级完成", "node", node.Name, "version", release.Version)
+	return result, nil
+}
+
+// verifySmartDNSUpgrade 升级后的健康检查，返回每项检查的结果和第一个失败的原因
+func (s *InitService) verifySmartDNSUpgrade(ctx context.Context, client *SSHClient, node *models.Node, version, configPath, configSum string) ([]string, error) {
+	var checks []string
+	var failed error
+	check := func(name string, err error) {
+		if err != nil {
+			checks = append(checks, fmt.Sprintf("%s: 失败（%v）", name, err))
+			if failed == nil {
+				failed = fmt.Errorf("%s: %w", name, err)
+			}
+			return
+		}
+		checks = append(checks, name+": 通过")
+	}
+
+	running, err := client.GetServiceStatus("smartdns")
+	if err == nil && !running {
+		err = fmt.Errorf("服务未运行")
+	}
+	check("服务状态", err)
+
+	if actual := smartDNSVersionOf(client); actual != version {
+		check("程序版本", fmt.Errorf("期望 %s，实际 %s", version, actual))
+	} else {
+		check("程序版本", nil)
+	}
+
+	if configSum != "" && remoteFileChecksum(client, configPath) != configSum {
+		check("配置保留", fmt.Errorf("%s 在升级后发生变化", configPath))
+	} else {
+		check("配置保留", nil)
+	}
+
+	if DNSProbeEnabled() {
+		probe := ProbeNodeDNS(ctx, node)
+		if !probe.OK {
+			check("解析探测", errors.New(probe.Error))
+		} else {
+			check("解析探测", nil)
+		}
+	}
+	return checks, failed
+}
+
+// rollbackSmartDNS 恢复升级前的程序和配置目录并重启服务
+func (s *InitService) rollbackSmartDNS(client *SSHClient, binary, configDir string) error {
+	restoreCmd := fmt.Sprintf("sudo install -m 0755 %s.prev %s && sudo cp -a %s/config-backup/. %s/",
+		binary, binary, smartDNSUpgradeDir, configDir)
+	if output, err := client.ExecuteCommand(restoreCmd); err != nil {
+		return fmt.Errorf("恢复程序和配置失败: %v %s", err, strings.TrimSpace(output))
+	}
+	if err := client.RestartService("smartdns"); err != nil {
+		return fmt.Errorf("重启服务失败: %w", err)
+	}
+	time.Sleep(3 * time.Second)
+	if running, err := client.GetServiceStatus("smartdns"); err != nil || !running {
+		return fmt.Errorf("恢复后服务未运行")
+	}
+	return nil
+}
+
+// smartDNSVersionOf 节点上 smartdns -v 输出的版本号，无法获取时为空
+func smartDNSVersionOf(client *SSHClient) string {
+	output, err := client.ExecuteCommand(smartDNSVersionCommand(client.node))
+	if err != nil {
+		return ""
+	}
+	if match := smartDNSVersionPattern.FindStringSubmatch(output); len(match) > 1 {
+		return match[1]
+	}
+	return ""
+}
+
+// remoteFileChecksum 节点上文件的 SHA-256，文件不存在时为空
+func remoteFileChecksum(client *SSHClient, file string) string {
+	output, err := client.ExecuteCommand(fmt.Sprintf("sudo sha256sum %s 2>/dev/null | cut -d' ' -f1", file))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
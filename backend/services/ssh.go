@@ -3,12 +3,15 @@ package services
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	_ "io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"smartdns-manager/models"
@@ -20,21 +23,13 @@ import (
 type SSHClient struct {
 	client     *ssh.Client
 	jumpClient *ssh.Client
+	node       *models.Node // 写入节点配置文件时记录快照
 }
 
 func NewSSHClient(node *models.Node) (*SSHClient, error) {
-	var auth []ssh.AuthMethod
-
-	if node.PrivateKey != "" {
-		signer, err := ssh.ParsePrivateKey([]byte(node.PrivateKey))
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse private key: %w", err)
-		}
-		auth = append(auth, ssh.PublicKeys(signer))
-	} else if node.Password != "" {
-		auth = append(auth, ssh.Password(node.Password))
-	} else {
-		return nil, fmt.Errorf("no authentication method provided")
+	auth, err := sshAuthMethods(node)
+	if err != nil {
+		return nil, err
 	}
 
 	config := &ssh.ClientConfig{
@@ -54,7 +49,7 @@ func NewSSHClient(node *models.Node) (*SSHClient, error) {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
 
-	return &SSHClient{client: client}, nil
+	return &SSHClient{client: client, node: node}, nil
 }
 
 func NewSSHClientWithProxy(node *models.Node, config *ssh.ClientConfig) (*SSHClient, error) {
@@ -106,7 +101,7 @@ func newSSHClientWithSOCKS5(node *models.Node, config *ssh.ClientConfig, proxyCo
 	}
 
 	client := ssh.NewClient(sshConn, chans, reqs)
-	return &SSHClient{client: client}, nil
+	return &SSHClient{client: client, node: node}, nil
 }
 
 func newSSHClientWithHTTP(node *models.Node, config *ssh.ClientConfig, proxy *models.ProxyConfig) (*SSHClient, error) {
@@ -157,7 +152,7 @@ func newSSHClientWithHTTP(node *models.Node, config *ssh.ClientConfig, proxy *mo
 	}
 
 	sshClient := ssh.NewClient(sshConn, chans, reqs)
-	return &SSHClient{client: sshClient}, nil
+	return &SSHClient{client: sshClient, node: node}, nil
 }
 
 // SSH跳板机连接
@@ -198,6 +193,7 @@ func newSSHClientWithJumpHost(node *models.Node, config *ssh.ClientConfig, proxy
 	return &SSHClient{
 		client:     client,
 		jumpClient: jumpClient, // 保存跳板机连接，用于后续关闭
+		node:       node,
 	}, nil
 }
 
@@ -233,6 +229,26 @@ func (c *SSHClient) ExecuteCommand(cmd string) (string, error) {
 	return stdout.String(), nil
 }
 
+// ExecuteCommandStream 与 ExecuteCommand 相同，执行过程中同时把 stdout 和 stderr 写入 output
+func (c *SSHClient) ExecuteCommandStream(cmd string, output io.Writer) (string, error) {
+	session, err := c.client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	session.Stdout = io.MultiWriter(&stdout, output)
+	session.Stderr = io.MultiWriter(&stderr, output)
+
+	if err := session.Run(cmd); err != nil {
+		return "", fmt.Errorf("command failed: %s, error: %w", stderr.String(), err)
+	}
+
+	return stdout.String(), nil
+}
+
 func (c *SSHClient) ReadFile(path string) (string, error) {
 	cmd := fmt.Sprintf("cat %s", path)
 	return c.ExecuteCommand(cmd)
@@ -255,21 +271,60 @@ func (c *SSHClient) WriteFile(path, content string) error {
 	}
 
 	// 移动到目标位置（需要 sudo）
-	_, err = c.ExecuteCommand(fmt.Sprintf("sudo mv %s %s", tmpFile, path))
-	return err
+	if _, err := c.ExecuteCommand(fmt.Sprintf("sudo mv %s %s", tmpFile, path)); err != nil {
+		return err
+	}
+	if c.node != nil && path == c.node.ConfigPath {
+		recordManagerWrite(c.node, content)
+	}
+	return nil
 }
 
 func (c *SSHClient) RestartService(serviceName string) error {
-	_, err := c.ExecuteCommand(fmt.Sprintf("sudo systemctl restart %s", serviceName))
-	return err
+	return c.ServiceManager(serviceName).Restart(serviceName)
 }
 
-func (c *SSHClient) GetServiceStatus(serviceName string) (bool, error) {
-	output, err := c.ExecuteCommand(fmt.Sprintf("systemctl is-active %s", serviceName))
-	if err != nil {
-		return false, nil
+// ReloadService 按 mode 平滑重载服务，重载不可用或失败时回退为重启，返回实际使用的方式
+func (c *SSHClient) ReloadService(serviceName, mode string) (string, error) {
+	manager := c.ServiceManager(serviceName)
+	switch mode {
+	case models.ReloadModeReload:
+		if err := manager.Reload(serviceName); err == nil {
+			return models.ReloadModeReload, nil
+		}
+	case models.ReloadModeSighup:
+		before := manager.MainPID(serviceName)
+		if before != "" {
+			if _, err := c.ExecuteCommand(fmt.Sprintf("sudo kill -HUP %s", before)); err == nil {
+				// 进程若不处理 SIGHUP 会退出，主进程未变化且解析结果与当前配置一致才算重载成功；
+				// 进程忽略 SIGHUP 时 PID 同样不变，因此必须核对生效的配置
+				time.Sleep(2 * time.Second)
+				if manager.MainPID(serviceName) == before {
+					err := c.verifyReload()
+					if err == nil {
+						return models.ReloadModeSighup, nil
+					}
+					log.Printf("⚠️ SIGHUP 重载后无法确认配置已生效，改为重启: %v", err)
+				}
+			}
+		}
 	}
-	return output == "active\n", nil
+
+	return models.ReloadModeRestart, manager.Restart(serviceName)
+}
+
+// verifyReload 确认节点已按新配置解析，未关联节点时无法核对
+func (c *SSHClient) verifyReload() error {
+	if c.node == nil {
+		return fmt.Errorf("未关联节点")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	return VerifyNodeConfig(ctx, c.node)
+}
+
+func (c *SSHClient) GetServiceStatus(serviceName string) (bool, error) {
+	return c.ServiceManager(serviceName).IsActive(serviceName)
 }
 
 func (c *SSHClient) GetSystemInfo() (*models.NodeStatus, error) {
@@ -370,3 +425,46 @@ func (client *SSHClient) ExecuteCommandWithTimeout(command string, timeout time.
 		return "", fmt.Errorf("命令执行超时")
 	}
 }
+
+// RunScript 通过标准输入把脚本交给远程命令执行，分别返回 stdout、stderr 和退出码
+//
+// 命令正常结束但退出码非 0 时 err 为 nil，由调用方根据退出码判断；ctx 取消时关闭会话并返回 -1。
+func (c *SSHClient) RunScript(ctx context.Context, command, script string) (stdout, stderr string, exitCode int, err error) {
+	session, err := c.client.NewSession()
+	if err != nil {
+		return "", "", -1, err
+	}
+	defer session.Close()
+
+	var outBuf, errBuf bytes.Buffer
+	session.Stdin = strings.NewReader(script)
+	session.Stdout = &outBuf
+	session.Stderr = &errBuf
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(command)
+	}()
+
+	select {
+	case runErr := <-done:
+		if runErr == nil {
+			return outBuf.String(), errBuf.String(), 0, nil
+		}
+		var exitErr *ssh.ExitError
+		if errors.As(runErr, &exitErr) {
+			return outBuf.String(), errBuf.String(), exitErr.ExitStatus(), nil
+		}
+		return outBuf.String(), errBuf.String(), -1, runErr
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+		// 等待 Run 返回后再读取输出，避免与写入并发
+		select {
+		case <-done:
+			return outBuf.String(), errBuf.String(), -1, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return "", "", -1, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/ssh"
+
+	"smartdns-manager/models"
+)
+
+// SSHCredentialProvider 为节点提供 SSH 认证方式，按节点的 CredentialSource 选择
+type SSHCredentialProvider interface {
+	// AuthMethods 返回连接节点时依次尝试的认证方式
+	AuthMethods(node *models.Node) ([]ssh.AuthMethod, error)
+}
+
+var sshCredentialProviders = make(map[string]SSHCredentialProvider)
+
+// RegisterSSHCredentialProvider 注册凭据来源，通常在实现文件的 init 中调用
+func RegisterSSHCredentialProvider(source string, provider SSHCredentialProvider) {
+	if _, exists := sshCredentialProviders[source]; exists {
+		panic(fmt.Sprintf("SSH 凭据来源重复注册: %s", source))
+	}
+	sshCredentialProviders[source] = provider
+}
+
+// SSHCredentialSources 已注册的凭据来源名称
+func SSHCredentialSources() []string {
+	names := make([]string, 0, len(sshCredentialProviders))
+	for name := range sshCredentialProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sshAuthMethods 按节点配置的凭据来源获取认证方式，未设置时使用本地凭据
+func sshAuthMethods(node *models.Node) ([]ssh.AuthMethod, error) {
+	source := node.CredentialSource
+	if source == "" {
+		source = models.CredentialSourceLocal
+	}
+	provider, ok := sshCredentialProviders[source]
+	if !ok {
+		return nil, fmt.Errorf("不支持的凭据来源: %s（可用: %v）", source, SSHCredentialSources())
+	}
+	return provider.AuthMethods(node)
+}
+
+// localCredentialProvider 使用节点上保存的私钥或密码
+type localCredentialProvider struct{}
+
+func init() {
+	RegisterSSHCredentialProvider(models.CredentialSourceLocal, localCredentialProvider{})
+}
+
+func (localCredentialProvider) AuthMethods(node *models.Node) ([]ssh.AuthMethod, error) {
+	if node.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(node.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+	}
+	if node.Password != "" {
+		return []ssh.AuthMethod{ssh.Password(node.Password)}, nil
+	}
+	return nil, fmt.Errorf("no authentication method provided")
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// 授权请求和一次性登录码的有效期
+const (
+	ssoStateTTL     = 10 * time.Minute
+	ssoLoginCodeTTL = time.Minute
+)
+
+// ssoMaxPending 未完成的授权请求上限，发起登录的接口无需认证，避免被刷满内存
+const ssoMaxPending = 1000
+
+// SSOStateCookie 发起 OIDC 登录时写入浏览器的 state，回调时必须与地址中的 state 一致，防止登录 CSRF
+const (
+	SSOStateCookie       = "smartdns_oidc_state"
+	SSOStateCookieMaxAge = int(ssoStateTTL / time.Second)
+)
+
+// ErrSSOForbidden 身份认证通过但不允许登录（分组未授权、未开通或账号被禁用）
+var ErrSSOForbidden = errors.New("无权登录")
+
+// ssoPending 跳转 IdP 前保存的授权请求
+type ssoPending struct {
+	nonce     string
+	verifier  string
+	expiresAt time.Time
+}
+
+// ssoLoginCode 回调成功后发给前端的一次性登录码，避免令牌出现在地址栏
+type ssoLoginCode struct {
+	userID    uint
+	expiresAt time.Time
+}
+
+// SSOService 单点登录服务
+type SSOService struct {
+	oidc *OIDCProvider
+
+	mu      sync.Mutex
+	pending map[string]ssoPending
+	codes   map[string]ssoLoginCode
+}
+
+// NewSSOService 创建单点登录服务
+func NewSSOService() *SSOService {
+	return &SSOService{
+		oidc:    NewOIDCProvider(),
+		pending: make(map[string]ssoPending),
+		codes:   make(map[string]ssoLoginCode),
+	}
+}
+
+// Config 登录页展示的单点登录选项
+func (s *SSOService) Config() models.SSOConfigResponse {
+	return models.SSOConfigResponse{
+		OIDCEnabled:      OIDCEnabled(),
+		OIDCProviderName: config.GetConfig().OIDCProviderName,
+		LDAPEnabled:      LDAPEnabled(),
+		WebhookEnabled:   AuthWebhookEnabled(),
+	}
+}
+
+func randomToken(size int) string {
+	buf := make([]byte, size)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// cleanupLocked 清理过期的授权请求和登录码，调用方需持有锁
+func (s *SSOService) cleanupLocked(now time.Time) {
+	for state, pending := range s.pending {
+		if now.After(pending.expiresAt) {
+			delete(s.pending, state)
+		}
+	}
+	for code, login := range s.codes {
+		if now.After(login.expiresAt) {
+			delete(s.codes, code)
+		}
+	}
+}
+
+// BeginOIDC 生成 state/nonce/PKCE 并返回 IdP 授权地址和 state，调用方需把 state 写入浏览器 Cookie
+func (s *SSOService) BeginOIDC(ctx context.Context) (string, string, error) {
+	if !OIDCEnabled() {
+		return "", "", fmt.Errorf("未启用 OIDC 登录")
+	}
+
+	state := randomToken(16)
+	pending := ssoPending{
+		nonce:     randomToken(16),
+		verifier:  randomToken(32),
+		expiresAt: time.Now().Add(ssoStateTTL),
+	}
+
+	authURL, err := s.oidc.AuthURL(ctx, state, pending.nonce, pending.verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanupLocked(time.Now())
+	if len(s.pending) >= ssoMaxPending {
+		return "", "", fmt.Errorf("进行中的登录请求过多，请稍后重试")
+	}
+	s.pending[state] = pending
+	return authURL, state, nil
+}
+
+// CompleteOIDC 校验 state 与发起登录的浏览器 Cookie 一致，再用授权码完成登录，返回本地用户
+func (s *SSOService) CompleteOIDC(ctx context.Context, state, cookieState, code string) (*models.User, error) {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		return nil, fmt.Errorf("登录请求与当前浏览器不匹配，请重新登录")
+	}
+
+	s.mu.Lock()
+	pending, ok := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(pending.expiresAt) {
+		return nil, fmt.Errorf("登录请求已过期，请重新登录")
+	}
+
+	identity, err := s.oidc.Exchange(ctx, code, pending.nonce, pending.verifier)
+	if err != nil {
+		return nil, err
+	}
+	return s.ProvisionUser(identity)
+}
+
+// IssueLoginCode 为已认证的用户生成一次性登录码
+func (s *SSOService) IssueLoginCode(userID uint) string {
+	code := randomToken(24)
+
+	s.mu.Lock()
+	s.cleanupLocked(time.Now())
+	s.codes[code] = ssoLoginCode{userID: userID, expiresAt: time.Now().Add(ssoLoginCodeTTL)}
+	s.mu.Unlock()
+	return code
+}
+
+// RedeemLoginCode 兑换一次性登录码，兑换后立即失效
+func (s *SSOService) RedeemLoginCode(code string) (uint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login, ok := s.codes[code]
+	delete(s.codes, code)
+	if !ok || time.Now().After(login.expiresAt) {
+		return 0, false
+	}
+	return login.userID, true
+}
+
+// ssoGroupRoles 解析 SSO_GROUP_ROLE_MAP，键统一小写
+func ssoGroupRoles() map[string]string {
+	roles := make(map[string]string)
+	for _, item := range strings.Split(config.GetConfig().SSOGroupRoleMap, ",") {
+		group, role, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		group = strings.ToLower(strings.TrimSpace(group))
+		role = strings.TrimSpace(role)
+		if group != "" && role != "" {
+			roles[group] = role
+		}
+	}
+	return roles
+}
+
+// ldapGroupCN 从组 DN 中取出 CN，如 cn=dns-admins,ou=groups,dc=example,dc=com -> dns-admins
+func ldapGroupCN(dn string) string {
+	first, _, _ := strings.Cut(dn, ",")
+	key, value, ok := strings.Cut(first, "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(key), "cn") {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// MapSSORole 按分组映射角色，命中多个分组时 admin 优先；未命中时使用 SSO_DEFAULT_ROLE
+func MapSSORole(groups []string) string {
+	roles := ssoGroupRoles()
+	role := ""
+	for _, group := range groups {
+		candidates := []string{strings.ToLower(group)}
+		if cn := ldapGroupCN(group); cn != "" {
+			candidates = append(candidates, strings.ToLower(cn))
+		}
+		for _, candidate := range candidates {
+			mapped, ok := roles[candidate]
+			if !ok {
+				continue
+			}
+			if mapped == "admin" || role == "" {
+				role = mapped
+			}
+		}
+	}
+
+	if role == "" {
+		role = config.GetConfig().SSODefaultRole
+	}
+	return role
+}
+
+// ProvisionUser 按外部身份查找或创建本地账号，每次登录按分组（或认证服务返回的角色）同步角色
+func (s *SSOService) ProvisionUser(identity *models.SSOIdentity) (*models.User, error) {
+	role := identity.Role
+	if role == "" {
+		role = MapSSORole(identity.Groups)
+	}
+	if role == "" || role == models.SSORoleNone {
+		return nil, fmt.Errorf("%w: 账号 %s 不属于任何已授权的分组", ErrSSOForbidden, identity.Username)
+	}
+
+	var user models.User
+	err := database.DB.Where("auth_source = ? AND external_id = ?", identity.Source, identity.Subject).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		jit, _ := strconv.ParseBool(config.GetConfig().SSOJITProvisioning)
+		if !jit {
+			return nil, fmt.Errorf("%w: 账号 %s 尚未开通", ErrSSOForbidden, identity.Username)
+		}
+		return s.createUser(identity, role)
+	}
+
+	if !user.IsActive {
+		return nil, fmt.Errorf("%w: 账号已被禁用", ErrSSOForbidden)
+	}
+
+	updates := map[string]interface{}{"last_login": time.Now()}
+	if user.Role != role {
+		log.Printf("🔐 SSO 用户 %s 角色由 %s 变更为 %s", user.Username, user.Role, role)
+		updates["role"] = role
+	}
+	if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("更新用户失败: %w", err)
+	}
+	user.Role = role
+	return &user, nil
+}
+
+// createUser 首次登录时创建账号，用户名或邮箱被其他账号占用时拒绝，避免接管本地账号；
+// 账号归属 SSO_DEFAULT_ORGANIZATION，组织 0 表示超级管理员，不会自动开通
+func (s *SSOService) createUser(identity *models.SSOIdentity, role string) (*models.User, error) {
+	orgName := strings.TrimSpace(config.GetConfig().SSODefaultOrganization)
+	if orgName == "" {
+		return nil, fmt.Errorf("%w: 未配置 SSO_DEFAULT_ORGANIZATION，账号 %s 需由管理员开通", ErrSSOForbidden, identity.Username)
+	}
+	var org models.Organization
+	if err := database.DB.Where("name = ?", orgName).First(&org).Error; err != nil {
+		return nil, fmt.Errorf("%w: 默认组织 %s 不存在", ErrSSOForbidden, orgName)
+	}
+
+	var count int64
+	database.DB.Model(&models.User{}).Where("username = ?", identity.Username).Count(&count)
+	if count > 0 {
+		return nil, fmt.Errorf("%w: 用户名 %s 已被其他账号占用", ErrSSOForbidden, identity.Username)
+	}
+
+	email := identity.Email
+	if email == "" {
+		email = fmt.Sprintf("%s@%s.sso", identity.Username, identity.Source)
+	}
+	database.DB.Model(&models.User{}).Where("email = ?", email).Count(&count)
+	if count > 0 {
+		return nil, fmt.Errorf("%w: 邮箱 %s 已被其他账号占用", ErrSSOForbidden, email)
+	}
+
+	// 单点登录账号不使用本地密码，写入随机哈希占位
+	hashed, err := bcrypt.GenerateFromPassword([]byte(randomToken(32)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("生成密码失败: %w", err)
+	}
+
+	user := models.User{
+		Username:       identity.Username,
+		Password:       string(hashed),
+		Email:          email,
+		Role:           role,
+		IsActive:       true,
+		OrganizationID: org.ID,
+		AuthSource:     identity.Source,
+		ExternalID:     identity.Subject,
+		LastLogin:      time.Now(),
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		return nil, fmt.Errorf("创建用户失败: %w", err)
+	}
+
+	log.Printf("🔐 SSO 首次登录，已创建用户 %s (来源: %s, 角色: %s, 组织: %s)", user.Username, user.AuthSource, user.Role, org.Name)
+	return &user, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+
+	"smartdns-manager/models"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Azure Blob 存储实现（azblob SDK，SharedKey 或 SAS 认证）
+// ═══════════════════════════════════════════════════════════════
+
+type AzureBlobBackupStorage struct {
+	client    *container.Client
+	container string
+	sharedKey bool
+	prefix    string
+}
+
+func NewAzureBlobBackupStorage(cfg *models.AzureStorageConfig) (*AzureBlobBackupStorage, error) {
+	if cfg.Account == "" || cfg.Container == "" {
+		return nil, fmt.Errorf("Azure 存储账号和容器不能为空")
+	}
+
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", cfg.Account)
+	}
+	containerURL := endpoint + "/" + cfg.Container
+
+	storage := &AzureBlobBackupStorage{container: cfg.Container, prefix: cfg.Prefix}
+	switch {
+	case cfg.AccountKey != "":
+		credential, err := container.NewSharedKeyCredential(cfg.Account, cfg.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("Azure 访问密钥不是有效的 Base64: %w", err)
+		}
+		client, err := container.NewClientWithSharedKeyCredential(containerURL, credential, nil)
+		if err != nil {
+			return nil, fmt.Errorf("创建 Azure 客户端失败: %w", err)
+		}
+		storage.client = client
+		storage.sharedKey = true
+	case cfg.SASToken != "":
+		client, err := container.NewClientWithNoCredential(containerURL+"?"+strings.TrimPrefix(cfg.SASToken, "?"), nil)
+		if err != nil {
+			return nil, fmt.Errorf("创建 Azure 客户端失败: %w", err)
+		}
+		storage.client = client
+	default:
+		return nil, fmt.Errorf("Azure 存储需要访问密钥或 SAS 令牌")
+	}
+	return storage, nil
+}
+
+func (a *AzureBlobBackupStorage) Save(ctx context.Context, content []byte, filename string) (string, error) {
+	return a.SaveStream(ctx, bytes.NewReader(content), int64(len(content)), filename)
+}
+
+// SaveStream 按块流式上传，不需要预先知道大小
+func (a *AzureBlobBackupStorage) SaveStream(ctx context.Context, r io.Reader, size int64, filename string) (string, error) {
+	prefix := a.prefix
+	if prefix == "" {
+		prefix = "smartdns-backups"
+	}
+	name := backupObjectKey(prefix, filename)
+
+	contentType := "application/octet-stream"
+	_, err := a.client.NewBlockBlobClient(name).UploadStream(ctx, r, &blockblob.UploadStreamOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	})
+	if err != nil {
+		return "", fmt.Errorf("上传到 Azure 失败 [container=%s, blob=%s]: %w", a.container, name, err)
+	}
+	return name, nil
+}
+
+func (a *AzureBlobBackupStorage) OpenRange(ctx context.Context, name string, offset, length int64) (io.ReadCloser, int64, error) {
+	options := &blob.DownloadStreamOptions{}
+	if offset > 0 || length >= 0 {
+		options.Range = blob.HTTPRange{Offset: offset}
+		if length >= 0 {
+			options.Range.Count = length
+		}
+	}
+	resp, err := a.client.NewBlobClient(name).DownloadStream(ctx, options)
+	if err != nil {
+		return nil, 0, fmt.Errorf("从 Azure 下载失败 [container=%s, blob=%s]: %w", a.container, name, err)
+	}
+
+	size := int64(-1)
+	if resp.ContentRange != nil {
+		// Content-Range: bytes 0-99/1234
+		if _, total, ok := strings.Cut(*resp.ContentRange, "/"); ok {
+			if parsed, err := strconv.ParseInt(total, 10, 64); err == nil {
+				size = parsed
+			}
+		}
+	} else if resp.ContentLength != nil {
+		size = *resp.ContentLength
+	}
+	return resp.Body, size, nil
+}
+
+func (a *AzureBlobBackupStorage) Load(ctx context.Context, name string) ([]byte, error) {
+	body, _, err := a.OpenRange(ctx, name, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func (a *AzureBlobBackupStorage) Delete(ctx context.Context, name string) error {
+	_, err := a.client.NewBlobClient(name).Delete(ctx, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("从 Azure 删除失败 [container=%s, blob=%s]: %w", a.container, name, err)
+	}
+	return nil
+}
+
+// GetDownloadURL 使用访问密钥时签发只读 SAS，使用 SAS 令牌时返回带令牌的地址，有效期由令牌决定
+func (a *AzureBlobBackupStorage) GetDownloadURL(ctx context.Context, name string, expiration time.Duration) (string, error) {
+	blobClient := a.client.NewBlobClient(name)
+	if !a.sharedKey {
+		return blobClient.URL(), nil
+	}
+	downloadURL, err := blobClient.GetSASURL(sas.BlobPermissions{Read: true}, time.Now().Add(expiration), nil)
+	if err != nil {
+		return "", fmt.Errorf("生成 Azure 下载链接失败: %w", err)
+	}
+	return downloadURL, nil
+}
+
+func (a *AzureBlobBackupStorage) Close() error {
+	return nil
+}
+
+func init() {
+	RegisterBackupStorage(models.StorageTypeAzure, func(cfg *models.StorageBackendConfig) (BackupStorage, error) {
+		if cfg.Azure == nil {
+			return nil, fmt.Errorf("缺少 Azure Blob 存储配置")
+		}
+		return NewAzureBlobBackupStorage(cfg.Azure)
+	})
+}
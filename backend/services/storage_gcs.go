@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+
+	"smartdns-manager/models"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// Google Cloud Storage 存储实现（GCS SDK，服务账号认证）
+// ═══════════════════════════════════════════════════════════════
+
+type GCSBackupStorage struct {
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+func NewGCSBackupStorage(cfg *models.GCSStorageConfig) (*GCSBackupStorage, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("GCS 存储桶不能为空")
+	}
+	if cfg.CredentialsJSON == "" {
+		return nil, fmt.Errorf("GCS 需要服务账号密钥")
+	}
+
+	options := []option.ClientOption{
+		option.WithCredentialsJSON([]byte(cfg.CredentialsJSON)),
+		option.WithScopes(storage.ScopeReadWrite),
+	}
+	// 自定义端点（如 fake-gcs-server）只填写服务地址，JSON API 路径由这里补全
+	if endpoint := strings.TrimRight(cfg.Endpoint, "/"); endpoint != "" {
+		options = append(options, option.WithEndpoint(endpoint+"/storage/v1/"))
+	}
+
+	client, err := storage.NewClient(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("创建 GCS 客户端失败: %w", err)
+	}
+	return &GCSBackupStorage{client: client, bucket: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+func (g *GCSBackupStorage) Save(ctx context.Context, content []byte, filename string) (string, error) {
+	return g.SaveStream(ctx, bytes.NewReader(content), int64(len(content)), filename)
+}
+
+// SaveStream 以可续传上传按块流式写入
+func (g *GCSBackupStorage) SaveStream(ctx context.Context, r io.Reader, size int64, filename string) (string, error) {
+	prefix := g.prefix
+	if prefix == "" {
+		prefix = "smartdns-backups"
+	}
+	object := backupObjectKey(prefix, filename)
+
+	writer := g.client.Bucket(g.bucket).Object(object).NewWriter(ctx)
+	writer.ContentType = "application/octet-stream"
+	if _, err := io.Copy(writer, r); err != nil {
+		writer.Close()
+		return "", fmt.Errorf("上传到 GCS 失败 [bucket=%s, object=%s]: %w", g.bucket, object, err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("上传到 GCS 失败 [bucket=%s, object=%s]: %w", g.bucket, object, err)
+	}
+	return object, nil
+}
+
+func (g *GCSBackupStorage) OpenRange(ctx context.Context, object string, offset, length int64) (io.ReadCloser, int64, error) {
+	reader, err := g.client.Bucket(g.bucket).Object(object).NewRangeReader(ctx, offset, length)
+	if err != nil {
+		return nil, 0, fmt.Errorf("从 GCS 下载失败 [bucket=%s, object=%s]: %w", g.bucket, object, err)
+	}
+	return reader, reader.Attrs.Size, nil
+}
+
+func (g *GCSBackupStorage) Load(ctx context.Context, object string) ([]byte, error) {
+	reader, _, err := g.OpenRange(ctx, object, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func (g *GCSBackupStorage) Delete(ctx context.Context, object string) error {
+	err := g.client.Bucket(g.bucket).Object(object).Delete(ctx)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("从 GCS 删除失败 [bucket=%s, object=%s]: %w", g.bucket, object, err)
+	}
+	return nil
+}
+
+// GetDownloadURL 生成 V4 签名的下载地址，最长 7 天
+func (g *GCSBackupStorage) GetDownloadURL(ctx context.Context, object string, expiration time.Duration) (string, error) {
+	if expiration > 7*24*time.Hour {
+		expiration = 7 * 24 * time.Hour
+	}
+	downloadURL, err := g.client.Bucket(g.bucket).SignedURL(object, &storage.SignedURLOptions{
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(expiration),
+		Scheme:  storage.SigningSchemeV4,
+	})
+	if err != nil {
+		return "", fmt.Errorf("签名下载链接失败: %w", err)
+	}
+	return downloadURL, nil
+}
+
+func (g *GCSBackupStorage) Close() error {
+	return g.client.Close()
+}
+
+func init() {
+	RegisterBackupStorage(models.StorageTypeGCS, func(cfg *models.StorageBackendConfig) (BackupStorage, error) {
+		if cfg.GCS == nil {
+			return nil, fmt.Errorf("缺少 GCS 存储配置")
+		}
+		return NewGCSBackupStorage(cfg.GCS)
+	})
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"smartdns-manager/models"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// SFTP 存储实现
+// ═══════════════════════════════════════════════════════════════
+
+// SFTPBackupStorage 通过 SSH 的 sftp 子系统保存备份
+type SFTPBackupStorage struct {
+	conn   *ssh.Client
+	client *sftp.Client
+	root   string
+}
+
+func NewSFTPBackupStorage(cfg *models.SFTPStorageConfig) (*SFTPBackupStorage, error) {
+	if cfg.Host == "" || cfg.Username == "" {
+		return nil, fmt.Errorf("SFTP 主机和用户名不能为空")
+	}
+	hostKeyCallback, err := sftpHostKeyCallback(cfg.HostKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth []ssh.AuthMethod
+	if cfg.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(cfg.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("解析 SFTP 私钥失败: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auth = append(auth, ssh.Password(cfg.Password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("SFTP 需要密码或私钥")
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+	conn, err := ssh.Dial("tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(port)), &ssh.ClientConfig{
+		User:            cfg.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("连接 SFTP 服务器失败: %w", err)
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SFTP 握手失败: %w", err)
+	}
+
+	root := cfg.Path
+	if root == "" {
+		root = "smartdns-backups"
+	}
+	return &SFTPBackupStorage{conn: conn, client: client, root: root}, nil
+}
+
+// sftpHostKeyCallback 校验服务器主机密钥与配置的固定值一致，支持 authorized_keys 格式的公钥
+// 或 ssh-keygen -lf 输出的 SHA256 指纹，未配置时拒绝连接
+func sftpHostKeyCallback(hostKey string) (ssh.HostKeyCallback, error) {
+	hostKey = strings.TrimSpace(hostKey)
+	if hostKey == "" {
+		return nil, fmt.Errorf("SFTP 需要配置服务器主机密钥（公钥或 SHA256 指纹），可通过 ssh-keyscan 获取")
+	}
+
+	if strings.HasPrefix(hostKey, "SHA256:") {
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if subtle.ConstantTimeCompare([]byte(ssh.FingerprintSHA256(key)), []byte(hostKey)) != 1 {
+				return fmt.Errorf("SFTP 服务器主机密钥不匹配: %s", ssh.FingerprintSHA256(key))
+			}
+			return nil
+		}, nil
+	}
+
+	expected, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err != nil {
+		return nil, fmt.Errorf("解析 SFTP 主机密钥失败: %w", err)
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if !bytes.Equal(key.Marshal(), expected.Marshal()) {
+			return fmt.Errorf("SFTP 服务器主机密钥不匹配: %s", ssh.FingerprintSHA256(key))
+		}
+		return nil
+	}, nil
+}
+
+func (s *SFTPBackupStorage) Save(ctx context.Context, content []byte, filename string) (string, error) {
+	return s.SaveStream(ctx, bytes.NewReader(content), int64(len(content)), filename)
+}
+
+func (s *SFTPBackupStorage) SaveStream(ctx context.Context, r io.Reader, size int64, filename string) (string, error) {
+	remotePath := path.Join(s.root, backupObjectKey("", filename))
+	if err := s.client.MkdirAll(path.Dir(remotePath)); err != nil {
+		return "", fmt.Errorf("创建远程目录失败 [%s]: %w", path.Dir(remotePath), err)
+	}
+
+	file, err := s.client.Create(remotePath)
+	if err != nil {
+		return "", fmt.Errorf("创建远程文件失败 [%s]: %w", remotePath, err)
+	}
+	if _, err := file.ReadFrom(r); err != nil {
+		file.Close()
+		return "", fmt.Errorf("写入远程文件失败 [%s]: %w", remotePath, err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("关闭远程文件失败 [%s]: %w", remotePath, err)
+	}
+	return remotePath, nil
+}
+
+func (s *SFTPBackupStorage) OpenRange(ctx context.Context, remotePath string, offset, length int64) (io.ReadCloser, int64, error) {
+	file, err := s.client.Open(remotePath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("打开远程文件失败 [%s]: %w", remotePath, err)
+	}
+	size := int64(-1)
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	if offset > 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return nil, 0, fmt.Errorf("定位远程文件失败 [%s]: %w", remotePath, err)
+		}
+	}
+	if length < 0 {
+		return file, size, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, size, nil
+}
+
+func (s *SFTPBackupStorage) Load(ctx context.Context, remotePath string) ([]byte, error) {
+	file, err := s.client.Open(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开远程文件失败 [%s]: %w", remotePath, err)
+	}
+	defer file.Close()
+
+	var content bytes.Buffer
+	if _, err := file.WriteTo(&content); err != nil {
+		return nil, fmt.Errorf("读取远程文件失败 [%s]: %w", remotePath, err)
+	}
+	return content.Bytes(), nil
+}
+
+func (s *SFTPBackupStorage) Delete(ctx context.Context, remotePath string) error {
+	if err := s.client.Remove(remotePath); err != nil {
+		return fmt.Errorf("删除远程文件失败 [%s]: %w", remotePath, err)
+	}
+	return nil
+}
+
+func (s *SFTPBackupStorage) GetDownloadURL(ctx context.Context, remotePath string, expiration time.Duration) (string, error) {
+	return "", fmt.Errorf("SFTP 存储不支持生成下载链接")
+}
+
+func (s *SFTPBackupStorage) Close() error {
+	s.client.Close()
+	return s.conn.Close()
+}
+
+func init() {
+	RegisterBackupStorage(models.StorageTypeSFTP, func(cfg *models.StorageBackendConfig) (BackupStorage, error) {
+		if cfg.SFTP == nil {
+			return nil, fmt.Errorf("缺少 SFTP 存储配置")
+		}
+		return NewSFTPBackupStorage(cfg.SFTP)
+	})
+}
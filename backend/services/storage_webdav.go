@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"smartdns-manager/models"
+)
+
+// ═══════════════════════════════════════════════════════════════
+// WebDAV 存储实现
+// ═══════════════════════════════════════════════════════════════
+
+// WebDAVBackupStorage 通过 WebDAV 保存备份，返回的路径相对于配置的目录地址
+type WebDAVBackupStorage struct {
+	baseURL  *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+func NewWebDAVBackupStorage(cfg *models.WebDAVStorageConfig) (*WebDAVBackupStorage, error) {
+	baseURL, err := url.Parse(strings.TrimRight(cfg.URL, "/"))
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("无效的 WebDAV 地址: %s", cfg.URL)
+	}
+	return &WebDAVBackupStorage{
+		baseURL:  baseURL,
+		username: cfg.Username,
+		password: cfg.Password,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (w *WebDAVBackupStorage) Save(ctx context.Context, content []byte, filename string) (string, error) {
+	return w.SaveStream(ctx, bytes.NewReader(content), int64(len(content)), filename)
+}
+
+func (w *WebDAVBackupStorage) SaveStream(ctx context.Context, r io.Reader, size int64, filename string) (string, error) {
+	key := backupObjectKey("", filename)
+
+	// 逐级创建目录，已存在时服务器返回 405，忽略
+	dir := ""
+	for _, part := range strings.Split(path.Dir(key), "/") {
+		dir = path.Join(dir, part)
+		resp, err := w.do(ctx, "MKCOL", dir+"/", nil, 0, nil)
+		if err != nil {
+			return "", fmt.Errorf("创建 WebDAV 目录失败: %w", err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := w.do(ctx, http.MethodPut, key, r, size, nil)
+	if err != nil {
+		return "", fmt.Errorf("上传到 WebDAV 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("上传到 WebDAV 失败 [%s]: HTTP %d", key, resp.StatusCode)
+	}
+	return key, nil
+}
+
+func (w *WebDAVBackupStorage) OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, int64, error) {
+	headers := map[string]string{}
+	if rangeHeader := httpRangeHeader(offset, length); rangeHeader != "" {
+		headers["Range"] = rangeHeader
+	}
+	resp, err := w.do(ctx, http.MethodGet, key, nil, 0, headers)
+	if err != nil {
+		return nil, 0, fmt.Errorf("从 WebDAV 下载失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("从 WebDAV 下载失败 [%s]: HTTP %d", key, resp.StatusCode)
+	}
+	body, err := httpRangeBody(resp, offset, length)
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, httpObjectSize(resp), nil
+}
+
+func (w *WebDAVBackupStorage) Load(ctx context.Context, key string) ([]byte, error) {
+	resp, err := w.do(ctx, http.MethodGet, key, nil, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("从 WebDAV 下载失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("从 WebDAV 下载失败 [%s]: HTTP %d", key, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (w *WebDAVBackupStorage) Delete(ctx context.Context, key string) error {
+	resp, err := w.do(ctx, http.MethodDelete, key, nil, 0, nil)
+	if err != nil {
+		return fmt.Errorf("从 WebDAV 删除失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("从 WebDAV 删除失败 [%s]: HTTP %d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *WebDAVBackupStorage) GetDownloadURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return "", fmt.Errorf("WebDAV 存储不支持生成下载链接")
+}
+
+func (w *WebDAVBackupStorage) Close() error {
+	return nil
+}
+
+func (w *WebDAVBackupStorage) do(ctx context.Context, method, key string, body io.Reader, size int64, headers map[string]string) (*http.Response, error) {
+	target := *w.baseURL
+	target.Path = w.baseURL.Path + "/" + strings.TrimLeft(key, "/")
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return w.client.Do(req)
+}
+
+func init() {
+	RegisterBackupStorage(models.StorageTypeWebDAV, func(cfg *models.StorageBackendConfig) (BackupStorage, error) {
+		if cfg.WebDAV == nil {
+			return nil, fmt.Errorf("缺少 WebDAV 存储配置")
+		}
+		return NewWebDAVBackupStorage(cfg.WebDAV)
+	})
+}
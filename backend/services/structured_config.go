@@ -0,0 +1,144 @@
+package services
+
+import (
+	"strings"
+
+	"smartdns-manager/models"
+)
+
+// ToStructuredConfig 把解析后的配置转换为稳定的结构化格式
+func ToStructuredConfig(config *models.SmartDNSConfig, source string) *models.StructuredConfig {
+	structured := &models.StructuredConfig{
+		SchemaVersion: models.StructuredConfigSchemaVersion,
+		Source:        source,
+		Settings:      map[string]string{},
+		Servers:       []models.StructuredServer{},
+		Addresses:     []models.StructuredAddress{},
+		DomainSets:    []models.StructuredDomainSet{},
+		DomainRules:   []models.StructuredDomainRule{},
+		Nameservers:   []models.StructuredNameserver{},
+		ClientRules:   []models.StructuredClientRule{},
+		Groups:        []models.StructuredGroup{},
+		ConfFiles:     []string{},
+		Directives:    []models.StructuredDirective{},
+		Unknown:       []models.StructuredUnknownDirective{},
+	}
+
+	for key, value := range config.BasicSettings {
+		structured.Settings[key] = value
+	}
+
+	for _, server := range config.Servers {
+		groups := server.Groups
+		if groups == nil {
+			groups = []string{}
+		}
+		structured.Servers = append(structured.Servers, models.StructuredServer{
+			ManagedID:      server.ID,
+			Address:        server.Address,
+			Protocol:       server.Type,
+			Groups:         groups,
+			ExcludeDefault: server.ExcludeDefault,
+			Options:        server.Options,
+		})
+	}
+
+	for _, address := range config.Addresses {
+		item := models.StructuredAddress{
+			ManagedID:      address.ID,
+			Domain:         address.Domain,
+			Type:           address.Type,
+			CNAME:          address.CNAME,
+			TTL:            address.TTL,
+			NoServeExpired: address.NoServeExpired,
+		}
+		if item.Type == "" {
+			item.Type = "address"
+		}
+		if address.IP != "" {
+			item.IPs = splitAddressIPs(address.IP)
+		}
+		structured.Addresses = append(structured.Addresses, item)
+	}
+
+	for _, domainSet := range config.DomainSets {
+		structured.DomainSets = append(structured.DomainSets, models.StructuredDomainSet{
+			ManagedID: domainSet.ID,
+			Name:      domainSet.Name,
+			File:      domainSet.FilePath,
+		})
+	}
+
+	for _, rule := range config.DomainRules {
+		item := models.StructuredDomainRule{
+			ManagedID:      rule.ID,
+			Address:        rule.Address,
+			Nameserver:     rule.Nameserver,
+			SpeedCheckMode: rule.SpeedCheckMode,
+			Options:        rule.OtherOptions,
+		}
+		if rule.IsDomainSet {
+			item.DomainSet = rule.DomainSetName
+		} else {
+			item.Domain = rule.Domain
+		}
+		structured.DomainRules = append(structured.DomainRules, item)
+	}
+
+	for _, ns := range config.Nameservers {
+		item := models.StructuredNameserver{
+			ManagedID: ns.ID,
+			Group:     ns.Group,
+		}
+		if ns.IsDomainSet {
+			item.DomainSet = ns.DomainSetName
+		} else {
+			item.Domain = ns.Domain
+		}
+		structured.Nameservers = append(structured.Nameservers, item)
+	}
+
+	for _, rule := range config.ClientRules {
+		structured.ClientRules = append(structured.ClientRules, models.StructuredClientRule{
+			ManagedID: rule.ID,
+			Client:    rule.Client,
+			Group:     rule.Group,
+			Blocked:   rule.Blocked,
+			Options:   rule.Options,
+		})
+	}
+
+	for _, group := range config.Groups {
+		lines := group.Lines
+		if lines == nil {
+			lines = []string{}
+		}
+		structured.Groups = append(structured.Groups, models.StructuredGroup{Name: group.Name, Lines: lines})
+	}
+
+	structured.ConfFiles = append(structured.ConfFiles, config.ConfFiles...)
+
+	for _, directive := range config.Directives {
+		structured.Directives = append(structured.Directives, models.StructuredDirective{
+			Name:  directive.Name,
+			Value: directive.Value,
+		})
+	}
+
+	for _, line := range config.OtherLines {
+		content := strings.TrimSpace(line.Content)
+		name, value := splitDirective(content)
+		item := models.StructuredUnknownDirective{
+			Name:    name,
+			Value:   value,
+			Content: content,
+		}
+		// 期望配置由合并生成，行号不对应任何文件
+		if source == models.StructuredConfigSourceActual {
+			item.Line = line.LineNumber
+		}
+		structured.Unknown = append(structured.Unknown, item)
+	}
+
+	return structured
+}
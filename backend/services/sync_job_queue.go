@@ -0,0 +1,512 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/database"
+	"smartdns-manager/logging"
+	"smartdns-manager/models"
+)
+
+const (
+	// syncJobWorkers 同时处理的同步任务数
+	syncJobWorkers = 2
+	// syncJobNodeConcurrency 单个任务同时同步的节点数
+	syncJobNodeConcurrency = 5
+)
+
+// syncRetryPayload 重试任务的内容：需要由完整同步补齐的同步记录
+type syncRetryPayload struct {
+	SyncLogIDs []uint `json:"sync_log_ids"`
+}
+
+// SyncJobQueue 配置同步任务队列，任务和各节点状态持久化到数据库，可通过任务ID查询进度。
+// 节点同步失败后按指数退避重试，等待重试的状态同样持久化，服务重启后继续
+type SyncJobQueue struct {
+	syncService *ConfigSyncService
+	jobs        chan uint
+}
+
+var (
+	defaultSyncJobQueue *SyncJobQueue
+	syncJobQueueOnce    sync.Once
+)
+
+// GetSyncJobQueue 获取全局同步任务队列，首次调用时启动处理协程并恢复未完成的任务
+func GetSyncJobQueue() *SyncJobQueue {
+	syncJobQueueOnce.Do(func() {
+		defaultSyncJobQueue = NewSyncJobQueue(NewConfigSyncService(), syncJobWorkers)
+		defaultSyncJobQueue.recoverJobs()
+		defaultSyncJobQueue.recoverSyncLogRetries()
+		go defaultSyncJobQueue.retryLoop(syncRetryPollInterval)
+	})
+	return defaultSyncJobQueue
+}
+
+// NewSyncJobQueue 创建同步任务队列并启动 workers 个处理协程
+func NewSyncJobQueue(syncService *ConfigSyncService, workers int) *SyncJobQueue {
+	q := &SyncJobQueue{
+		syncService: syncService,
+		jobs:        make(chan uint, 1000),
+	}
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+	return q
+}
+
+// EnqueueAddressSync 创建同步地址映射的任务，未启用的地址映射不会同步
+func (q *SyncJobQueue) EnqueueAddressSync(ctx context.Context, description string, addresses ...models.AddressMap) (*models.SyncJob, error) {
+	enabled := make([]models.AddressMap, 0, len(addresses))
+	for _, addr := range addresses {
+		if addr.Enabled {
+			enabled = append(enabled, addr)
+		}
+	}
+	return q.enqueue(ctx, models.SyncJobTypeAddressSync, description, enabled)
+}
+
+// EnqueueAddressDelete 创建从节点删除地址映射的任务
+func (q *SyncJobQueue) EnqueueAddressDelete(ctx context.Context, description string, addresses ...models.AddressMap) (*models.SyncJob, error) {
+	return q.enqueue(ctx, models.SyncJobTypeAddressDelete, description, addresses)
+}
+
+// EnqueueSyncLogRetry 创建以完整同步重试失败记录的任务，任务结束后同步记录随之更新。
+// 删除操作不能通过完整同步补齐，调用方需先排除
+func (q *SyncJobQueue) EnqueueSyncLogRetry(ctx context.Context, nodeID uint, logs []models.ConfigSyncLog) (*models.SyncJob, error) {
+	var node models.Node
+	if err := database.DB.First(&node, nodeID).Error; err != nil {
+		return nil, fmt.Errorf("节点不存在: %w", err)
+	}
+
+	payload := syncRetryPayload{}
+	for _, l := range logs {
+		payload.SyncLogIDs = append(payload.SyncLogIDs, l.ID)
+	}
+	data, _ := json.Marshal(payload)
+
+	description := fmt.Sprintf("重试 %d 条失败的同步", len(logs))
+	if len(logs) == 1 {
+		description = fmt.Sprintf("重试同步 %s", logs[0].Content)
+	}
+	job, err := q.create(ctx, models.SyncJobTypeSyncRetry, description, string(data), []models.Node{node})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	database.DB.Model(&models.ConfigSyncLog{}).Where("id IN ?", payload.SyncLogIDs).Updates(map[string]interface{}{
+		"next_retry_at": &now,
+		"retry_job_id":  job.ID,
+	})
+	return job, nil
+}
+
+// GetJob 获取同步任务及各节点状态
+func (q *SyncJobQueue) GetJob(id uint) (*models.SyncJob, error) {
+	var job models.SyncJob
+	if err := database.DB.Preload("Nodes").First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// enqueue 记录任务和目标节点后放入队列
+func (q *SyncJobQueue) enqueue(ctx context.Context, jobType, description string, addresses []models.AddressMap) (*models.SyncJob, error) {
+	nodes, err := q.targetNodes(addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, _ := json.Marshal(addresses)
+	return q.create(ctx, jobType, description, string(payload), nodes)
+}
+
+// create 记录任务和各节点状态后放入队列，任务执行时的日志带上 ctx 中的请求 ID
+func (q *SyncJobQueue) create(ctx context.Context, jobType, description, payload string, nodes []models.Node) (*models.SyncJob, error) {
+	job := &models.SyncJob{
+		Type:        jobType,
+		Description: description,
+		Payload:     payload,
+		Status:      models.SyncJobStatusQueued,
+		TotalNodes:  len(nodes),
+		RequestID:   logging.RequestID(ctx),
+	}
+
+	// 没有需要同步的节点，直接完成
+	if len(nodes) == 0 {
+		now := time.Now()
+		job.Status = models.SyncJobStatusSucceeded
+		job.FinishedAt = &now
+	}
+
+	if err := database.DB.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("创建同步任务失败: %w", err)
+	}
+
+	for _, node := range nodes {
+		jobNode := models.SyncJobNode{
+			JobID:    job.ID,
+			NodeID:   node.ID,
+			NodeName: node.Name,
+			Status:   models.SyncNodeStatusQueued,
+		}
+		database.DB.Create(&jobNode)
+		job.Nodes = append(job.Nodes, jobNode)
+	}
+
+	if len(nodes) > 0 {
+		q.jobs <- job.ID
+	}
+	return job, nil
+}
+
+// targetNodes 汇总所有地址映射的目标节点
+func (q *SyncJobQueue) targetNodes(addresses []models.AddressMap) ([]models.Node, error) {
+	byID := make(map[uint]models.Node)
+	for _, addr := range addresses {
+		nodes, err := q.syncService.getTargetNodes(addr.NodeIDs)
+		if err != nil {
+			return nil, fmt.Errorf("解析节点列表失败: %w", err)
+		}
+		nodes = nodesInOrganization(nodes, addr.OrganizationID)
+		for _, node := range nodes {
+			byID[node.ID] = node
+		}
+	}
+
+	nodes := make([]models.Node, 0, len(byID))
+	for _, node := range byID {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+	return nodes, nil
+}
+
+// recoverJobs 服务重启后重新处理未完成的任务，等待重试的节点仍按原定时间重试
+func (q *SyncJobQueue) recoverJobs() {
+	var ids []uint
+	database.DB.Model(&models.SyncJob{}).
+		Where("status IN ?", []string{models.SyncJobStatusQueued, models.SyncJobStatusRunning}).
+		Order("id").Pluck("id", &ids)
+	if len(ids) == 0 {
+		return
+	}
+
+	database.DB.Model(&models.SyncJobNode{}).
+		Where("job_id IN ? AND status = ?", ids, models.SyncNodeStatusRunning).
+		Update("status", models.SyncNodeStatusQueued)
+
+	log.Printf("恢复 %d 个未完成的同步任务", len(ids))
+	go func() {
+		for _, id := range ids {
+			q.jobs <- id
+		}
+	}()
+}
+
+// recoverSyncLogRetries 为等待自动重试但还没有对应重试任务的同步记录（如升级前失败的记录）创建重试任务
+func (q *SyncJobQueue) recoverSyncLogRetries() {
+	var logs []models.ConfigSyncLog
+	database.DB.Where("status = ? AND next_retry_at IS NOT NULL AND retry_job_id = ?", "failed", 0).
+		Order("id").Find(&logs)
+
+	byNode := make(map[uint][]models.ConfigSyncLog)
+	for _, l := range logs {
+		if l.Action == "delete" {
+			database.DB.Model(&models.ConfigSyncLog{}).Where("id = ?", l.ID).Update("next_retry_at", nil)
+			continue
+		}
+		byNode[l.NodeID] = append(byNode[l.NodeID], l)
+	}
+
+	for nodeID, nodeLogs := range byNode {
+		if _, err := q.EnqueueSyncLogRetry(context.Background(), nodeID, nodeLogs); err != nil {
+			ids := make([]uint, 0, len(nodeLogs))
+			for _, l := range nodeLogs {
+				ids = append(ids, l.ID)
+			}
+			database.DB.Model(&models.ConfigSyncLog{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+				"error":         "节点不存在，停止重试",
+				"next_retry_at": nil,
+			})
+		}
+	}
+}
+
+// retryLoop 定期把到期重试的节点放回队列
+func (q *SyncJobQueue) retryLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		q.requeueDue()
+	}
+}
+
+// requeueDue 将到期的等待重试节点改回排队状态，并把所属任务重新放入队列
+func (q *SyncJobQueue) requeueDue() {
+	var due []models.SyncJobNode
+	if err := database.DB.Where("status = ? AND next_retry_at <= ?", models.SyncNodeStatusRetrying, time.Now()).
+		Order("next_retry_at").Find(&due).Error; err != nil {
+		log.Printf("查询待重试的同步节点失败: %v", err)
+		return
+	}
+
+	queued := make(map[uint]bool)
+	var jobIDs []uint
+	for _, jobNode := range due {
+		// 只有仍在等待重试的记录才放回队列，避免同一节点被重复处理
+		result := database.DB.Model(&models.SyncJobNode{}).
+			Where("id = ? AND status = ?", jobNode.ID, models.SyncNodeStatusRetrying).
+			Update("status", models.SyncNodeStatusQueued)
+		if result.RowsAffected > 0 && !queued[jobNode.JobID] {
+			queued[jobNode.JobID] = true
+			jobIDs = append(jobIDs, jobNode.JobID)
+		}
+	}
+	for _, id := range jobIDs {
+		q.jobs <- id
+	}
+}
+
+func (q *SyncJobQueue) worker() {
+	for id := range q.jobs {
+		q.process(id)
+	}
+}
+
+// process 执行同步任务
+func (q *SyncJobQueue) process(id uint) {
+	job, err := q.GetJob(id)
+	if err != nil {
+		log.Printf("获取同步任务 #%d 失败: %v", id, err)
+		return
+	}
+
+	var addresses []models.AddressMap
+	if job.Type != models.SyncJobTypeSyncRetry {
+		if err := json.Unmarshal([]byte(job.Payload), &addresses); err != nil {
+			log.Printf("解析同步任务 #%d 内容失败: %v", id, err)
+		}
+	}
+
+	// 同一任务可能被 recoverJobs 和 requeueDue 重复放入队列，
+	// 逐个节点原子地从排队改为执行中，只有改成功的 worker 才执行该节点。
+	// 等待重试的节点由 retryLoop 到期后放回队列
+	var claimed []*models.SyncJobNode
+	for i := range job.Nodes {
+		jobNode := &job.Nodes[i]
+		if jobNode.Status != models.SyncNodeStatusQueued {
+			continue
+		}
+		result := database.DB.Model(&models.SyncJobNode{}).
+			Where("id = ? AND status = ?", jobNode.ID, models.SyncNodeStatusQueued).
+			Update("status", models.SyncNodeStatusRunning)
+		if result.Error != nil {
+			log.Printf("领取同步任务 #%d 的节点 %d 失败: %v", id, jobNode.NodeID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		jobNode.Status = models.SyncNodeStatusRunning
+		claimed = append(claimed, jobNode)
+	}
+	if len(claimed) == 0 {
+		// 没有可执行的节点：重启前已全部执行完但未来得及收尾的任务在这里补上结果
+		if job.Status == models.SyncJobStatusQueued || job.Status == models.SyncJobStatusRunning {
+			q.finish(context.Background(), job)
+		}
+		return
+	}
+
+	updates := map[string]interface{}{"status": models.SyncJobStatusRunning}
+	if job.StartedAt == nil {
+		now := time.Now()
+		updates["started_at"] = &now
+	}
+	database.DB.Model(job).Updates(updates)
+
+	ctx := context.Background()
+	if job.RequestID != "" {
+		ctx = logging.WithRequestID(ctx, job.RequestID)
+	}
+	sem := make(chan struct{}, syncJobNodeConcurrency)
+	var wg sync.WaitGroup
+	for _, jobNode := range claimed {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			q.runNode(ctx, job, jobNode, addresses)
+		}()
+	}
+	wg.Wait()
+
+	q.finish(ctx, job)
+}
+
+// runNode 在单个节点上执行一次同步，失败时按指数退避安排下次重试，超过最大次数后标记失败
+func (q *SyncJobQueue) runNode(ctx context.Context, job *models.SyncJob, jobNode *models.SyncJobNode, addresses []models.AddressMap) {
+	var node models.Node
+	if err := database.DB.First(&node, jobNode.NodeID).Error; err != nil {
+		now := time.Now()
+		jobNode.Status = models.SyncNodeStatusFailed
+		jobNode.Error = "节点不存在"
+		jobNode.NextRetryAt = nil
+		jobNode.FinishedAt = &now
+		database.DB.Save(jobNode)
+		q.nodeFinished(job, jobNode)
+		return
+	}
+
+	now := time.Now()
+	jobNode.Status = models.SyncNodeStatusRunning
+	jobNode.Attempts++
+	jobNode.NextRetryAt = nil
+	if jobNode.StartedAt == nil {
+		jobNode.StartedAt = &now
+	}
+	database.DB.Save(jobNode)
+
+	err := q.apply(ctx, job.Type, &node, addresses)
+
+	if err == nil {
+		finished := time.Now()
+		jobNode.Status = models.SyncNodeStatusSucceeded
+		jobNode.Error = ""
+		jobNode.FinishedAt = &finished
+		database.DB.Save(jobNode)
+		q.nodeFinished(job, jobNode)
+		return
+	}
+
+	jobNode.Error = err.Error()
+	if jobNode.Attempts >= syncRetryMaxAttempts {
+		finished := time.Now()
+		jobNode.Status = models.SyncNodeStatusFailed
+		jobNode.FinishedAt = &finished
+		database.DB.Save(jobNode)
+		q.nodeFinished(job, jobNode)
+		slog.ErrorContext(ctx, "同步任务在节点上失败", "job_id", jobNode.JobID, "node", node.Name, "error", err)
+		q.syncService.notificationService.SendNotification(node.ID, "sync_failed", "❌ 配置同步失败",
+			fmt.Sprintf("%s 自动重试 %d 次后仍然失败，请手动处理\n\n错误: %s", job.Description, syncRetryMaxAttempts, err.Error()))
+		return
+	}
+
+	next := time.Now().Add(syncRetryBackoff(jobNode.Attempts))
+	jobNode.Status = models.SyncNodeStatusRetrying
+	jobNode.NextRetryAt = &next
+	database.DB.Save(jobNode)
+	slog.WarnContext(ctx, "同步任务在节点上失败，稍后重试", "job_id", jobNode.JobID, "node", node.Name, "retry_in", next.Sub(now).Round(time.Second), "error", err)
+}
+
+// nodeFinished 节点不再重试后的收尾：重试任务把结果写回对应的同步记录
+func (q *SyncJobQueue) nodeFinished(job *models.SyncJob, jobNode *models.SyncJobNode) {
+	if job.Type != models.SyncJobTypeSyncRetry {
+		return
+	}
+
+	var payload syncRetryPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil || len(payload.SyncLogIDs) == 0 {
+		return
+	}
+
+	updates := map[string]interface{}{
+		"attempts":      gorm.Expr("attempts + ?", jobNode.Attempts),
+		"next_retry_at": nil,
+	}
+	if jobNode.Status == models.SyncNodeStatusSucceeded {
+		updates["status"] = "success"
+		updates["error"] = ""
+	} else {
+		updates["error"] = jobNode.Error
+	}
+	database.DB.Model(&models.ConfigSyncLog{}).
+		Where("id IN ? AND retry_job_id = ?", payload.SyncLogIDs, job.ID).
+		Updates(updates)
+}
+
+// apply 将任务内容应用到节点。地址映射按原操作重放，重试任务通过完整同步补齐管理端的记录
+func (q *SyncJobQueue) apply(ctx context.Context, jobType string, node *models.Node, addresses []models.AddressMap) error {
+	if jobType == models.SyncJobTypeSyncRetry {
+		return q.syncService.FullSyncToNode(ctx, node.ID)
+	}
+
+	for i := range addresses {
+		addr := &addresses[i]
+		if !nodeIDsContain(addr.NodeIDs, node.ID) {
+			continue
+		}
+
+		var err error
+		switch jobType {
+		case models.SyncJobTypeAddressSync:
+			err = q.syncService.syncAddressToNode(ctx, addr, node)
+		case models.SyncJobTypeAddressDelete:
+			err = q.syncService.deleteAddressFromNode(ctx, addr, node)
+		default:
+			return fmt.Errorf("不支持的同步任务类型: %s", jobType)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", addr.Domain, err)
+		}
+	}
+	return nil
+}
+
+// finish 汇总节点结果，更新任务状态
+func (q *SyncJobQueue) finish(ctx context.Context, job *models.SyncJob) {
+	var nodes []models.SyncJobNode
+	database.DB.Where("job_id = ?", job.ID).Find(&nodes)
+
+	succeeded, failed, pending := 0, 0, 0
+	for _, n := range nodes {
+		switch n.Status {
+		case models.SyncNodeStatusSucceeded:
+			succeeded++
+		case models.SyncNodeStatusFailed:
+			failed++
+		default:
+			pending++
+		}
+	}
+
+	// 还有节点等待重试，任务保持执行中
+	if pending > 0 {
+		database.DB.Model(job).Updates(map[string]interface{}{
+			"succeeded_nodes": succeeded,
+			"failed_nodes":    failed,
+		})
+		return
+	}
+
+	status := models.SyncJobStatusSucceeded
+	if failed > 0 {
+		status = models.SyncJobStatusPartial
+		if succeeded == 0 {
+			status = models.SyncJobStatusFailed
+		}
+	}
+
+	now := time.Now()
+	database.DB.Model(job).Updates(map[string]interface{}{
+		"status":          status,
+		"succeeded_nodes": succeeded,
+		"failed_nodes":    failed,
+		"finished_at":     &now,
+	})
+	slog.InfoContext(ctx, "同步任务完成", "job_id", job.ID, "succeeded", succeeded, "failed", failed)
+}
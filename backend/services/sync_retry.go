@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+const (
+	// syncRetryMaxAttempts 同步失败后的最大尝试次数（含首次）
+	syncRetryMaxAttempts = 5
+	// syncRetryBaseBackoff 首次重试间隔，之后按指数增长
+	syncRetryBaseBackoff = time.Minute
+	// syncRetryMaxBackoff 重试间隔上限
+	syncRetryMaxBackoff = time.Hour
+	// syncRetryPollInterval 检查到期重试的间隔
+	syncRetryPollInterval = 30 * time.Second
+)
+
+// syncRetryBackoff 计算第 attempts 次失败后的重试间隔
+func syncRetryBackoff(attempts int) time.Duration {
+	backoff := syncRetryBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= syncRetryMaxBackoff {
+			return syncRetryMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// failSyncLog 标记同步失败。同步任务队列中的同步由队列按任务内容重试，这里不安排重试
+func failSyncLog(syncLog *models.ConfigSyncLog, err error) {
+	syncLog.Status = "failed"
+	syncLog.Error = err.Error()
+	syncLog.Attempts++
+	syncLog.NextRetryAt = nil
+	database.DB.Save(syncLog)
+}
+
+// failSyncLogWithRetry 标记同步失败，并交给同步任务队列以完整同步重试。
+// 完整同步只能补齐管理端的记录，无法从节点上移除配置，删除操作失败后不自动重试
+func failSyncLogWithRetry(ctx context.Context, syncLog *models.ConfigSyncLog, err error) {
+	failSyncLog(syncLog, err)
+	if syncLog.Action == "delete" {
+		return
+	}
+	if _, err := GetSyncJobQueue().EnqueueSyncLogRetry(ctx, syncLog.NodeID, []models.ConfigSyncLog{*syncLog}); err != nil {
+		slog.ErrorContext(ctx, "安排同步重试失败", "sync_log_id", syncLog.ID, "error", err)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"smartdns-manager/database"
@@ -19,12 +20,17 @@ type NodeHealthChecker struct {
 	nodeStatusCache     map[uint]string        // 节点状态缓存
 	mu                  sync.RWMutex           // 保护并发访问
 	batchUpdateChan     chan *nodeStatusUpdate // 批量更新通道
+	flushed             chan struct{}          // 批量更新协程写完剩余更新后关闭
+	lastMetricAt        map[uint]time.Time     // 节点上次采集资源指标的时间
+	lastMetricPurge     time.Time              // 上次清理过期资源指标的时间
 }
 
 type nodeStatusUpdate struct {
 	nodeID    uint
-	status    string
+	status    string // 为空时只更新解析探测结果
 	lastCheck time.Time
+	probe     *DNSProbeResult
+	metric    *models.NodeMetric // 不为空时写入一条资源指标
 }
 
 // NewNodeHealthChecker 创建健康检查器
@@ -36,6 +42,8 @@ func NewNodeHealthChecker(interval time.Duration) *NodeHealthChecker {
 		lastErrorStatus:     make(map[uint]string),
 		nodeStatusCache:     make(map[uint]string),
 		batchUpdateChan:     make(chan *nodeStatusUpdate, 100),
+		flushed:             make(chan struct{}),
+		lastMetricAt:        make(map[uint]time.Time),
 	}
 
 	// 启动批量更新协程
@@ -67,11 +75,12 @@ func (checker *NodeHealthChecker) Start() {
 	}()
 }
 
-// Stop 停止定时检查
+// Stop 停止定时检查，等待正在进行的检查结束并写入尚未提交的状态更新
 func (checker *NodeHealthChecker) Stop() {
 	checker.ticker.Stop()
 	checker.stopChan <- true
 	close(checker.batchUpdateChan)
+	<-checker.flushed
 }
 
 // initCache 初始化状态缓存
@@ -94,6 +103,7 @@ func (checker *NodeHealthChecker) initCache() {
 func (checker *NodeHealthChecker) batchUpdateWorker() {
 	ticker := time.NewTicker(5 * time.Second) // 每5秒批量更新一次
 	defer ticker.Stop()
+	defer close(checker.flushed)
 
 	updates := make([]*nodeStatusUpdate, 0, 50)
 
@@ -140,12 +150,29 @@ func (checker *NodeHealthChecker) executeBatchUpdate(updates []*nodeStatusUpdate
 	}()
 
 	for _, update := range updates {
+		if update.metric != nil {
+			if err := tx.Create(update.metric).Error; err != nil {
+				log.Printf("写入节点资源指标失败: %v", err)
+				tx.Rollback()
+				return
+			}
+			continue
+		}
+
+		fields := map[string]interface{}{}
+		if update.status != "" {
+			fields["status"] = update.status
+			fields["last_check"] = update.lastCheck
+		}
+		if update.probe != nil {
+			fields["dns_probe_ok"] = update.probe.OK
+			fields["dns_probe_latency"] = update.probe.Latency.Milliseconds()
+			fields["dns_probe_error"] = update.probe.Error
+			fields["dns_probe_at"] = update.lastCheck
+		}
 		if err := tx.Model(&models.Node{}).
 			Where("id = ?", update.nodeID).
-			Updates(map[string]interface{}{
-				"status":     update.status,
-				"last_check": update.lastCheck,
-			}).Error; err != nil {
+			Updates(fields).Error; err != nil {
 			log.Printf("批量更新节点状态失败: %v", err)
 			tx.Rollback()
 			return
@@ -163,7 +190,8 @@ func (checker *NodeHealthChecker) executeBatchUpdate(updates []*nodeStatusUpdate
 func (checker *NodeHealthChecker) checkAllNodes() {
 	var nodes []models.Node
 	// 只查询必要的字段
-	if err := database.DB.Select("id, name, host, port, username, password, config_path, status").Find(&nodes).Error; err != nil {
+	if err := database.DB.Select("id, name, host, port, username, password, private_key, config_path, status, proxy_config, " +
+		"credential_source, vault_secret_path, vault_ssh_role").Find(&nodes).Error; err != nil {
 		log.Printf("获取节点列表失败: %v", err)
 		return
 	}
@@ -185,6 +213,12 @@ func (checker *NodeHealthChecker) checkAllNodes() {
 	}
 
 	wg.Wait()
+
+	// 每小时清理一次过期的资源指标
+	if nodeMetricsInterval() > 0 && time.Since(checker.lastMetricPurge) >= time.Hour {
+		checker.lastMetricPurge = time.Now()
+		PurgeExpiredNodeMetrics()
+	}
 }
 
 // checkNode 检查单个节点
@@ -206,6 +240,9 @@ func (checker *NodeHealthChecker) checkNode(node *models.Node) {
 	}
 	defer client.Close()
 
+	// SmartDNS 未运行时也采集资源指标
+	checker.collectMetricIfDue(node, client)
+
 	// 检查配置文件
 	_, err = client.ReadFile(node.ConfigPath)
 	if err != nil {
@@ -219,27 +256,44 @@ func (checker *NodeHealthChecker) checkNode(node *models.Node) {
 	}
 
 	// 检查 SmartDNS 服务状态
-	output, err := client.ExecuteCommand("systemctl is-active smartdns 2>&1")
-	if err != nil || strings.TrimSpace(output) != "active" {
+	manager := client.ServiceManager("smartdns")
+	if running, _ := manager.IsActive("smartdns"); !running {
 		checker.updateNodeStatusAsync(node, oldStatus, "stopped")
-		log.Printf("节点 %s SmartDNS服务未运行: %s", node.Name, output)
+		log.Printf("节点 %s SmartDNS服务未运行（%s）", node.Name, manager.Name())
 		checker.sendNotificationIfNeeded(node, oldStatus, "stopped",
 			"🛑 SmartDNS服务已停止",
-			fmt.Sprintf("节点：%s\n状态：服务未运行\n时间：%s\n详情：%s",
-				node.Name, time.Now().Format("2006-01-02 15:04:05"), strings.TrimSpace(output)))
+			fmt.Sprintf("节点：%s\n状态：服务未运行\n时间：%s\n服务管理：%s",
+				node.Name, time.Now().Format("2006-01-02 15:04:05"), manager.Name()))
 		return
 	}
 
-	// 检查服务运行状态（简化检查，避免额外的SSH命令）
-	statusOutput, err := client.ExecuteCommand("systemctl status smartdns 2>&1")
-	if err != nil || !strings.Contains(statusOutput, "active (running)") {
-		checker.updateNodeStatusAsync(node, oldStatus, "error")
-		log.Printf("节点 %s SmartDNS服务状态异常", node.Name)
-		checker.sendNotificationIfNeeded(node, oldStatus, "error",
-			"⚠️ SmartDNS服务异常",
-			fmt.Sprintf("节点：%s\n状态：服务状态异常\n时间：%s",
-				node.Name, time.Now().Format("2006-01-02 15:04:05")))
-		return
+	// 检查服务运行状态（简化检查，避免额外的SSH命令），只有 systemd 区分 active (running) 和 active (exited)
+	if manager.Name() == models.ServiceManagerSystemd {
+		statusOutput, err := client.ExecuteCommand("systemctl status smartdns 2>&1")
+		if err != nil || !strings.Contains(statusOutput, "active (running)") {
+			checker.updateNodeStatusAsync(node, oldStatus, "error")
+			log.Printf("节点 %s SmartDNS服务状态异常", node.Name)
+			checker.sendNotificationIfNeeded(node, oldStatus, "error",
+				"⚠️ SmartDNS服务异常",
+				fmt.Sprintf("节点：%s\n状态：服务状态异常\n时间：%s",
+					node.Name, time.Now().Format("2006-01-02 15:04:05")))
+			return
+		}
+	}
+
+	// 深度检查：服务运行不代表能正常应答，通过节点实际解析探测域名
+	if DNSProbeEnabled() {
+		probe := ProbeNodeDNS(context.Background(), node)
+		checker.recordProbeAsync(node, probe)
+		if !probe.OK {
+			checker.updateNodeStatusAsync(node, oldStatus, "error")
+			log.Printf("节点 %s 解析探测失败: %s", node.Name, probe.Error)
+			checker.sendNotificationIfNeeded(node, oldStatus, "error",
+				"⚠️ SmartDNS解析异常",
+				fmt.Sprintf("节点：%s\n状态：服务运行但解析探测失败\n时间：%s\n原因：%s",
+					node.Name, time.Now().Format("2006-01-02 15:04:05"), probe.Error))
+			return
+		}
 	}
 
 	// 所有检查通过
@@ -268,6 +322,42 @@ func (checker *NodeHealthChecker) updateNodeStatusAsync(node *models.Node, oldSt
 	}
 }
 
+// recordProbeAsync 记录解析探测的延迟和结果，每次检查都更新
+func (checker *NodeHealthChecker) recordProbeAsync(node *models.Node, probe DNSProbeResult) {
+	checker.batchUpdateChan <- &nodeStatusUpdate{
+		nodeID:    node.ID,
+		lastCheck: time.Now(),
+		probe:     &probe,
+	}
+}
+
+// collectMetricIfDue 距上次采集超过 NODE_METRICS_INTERVAL 时采集节点资源指标
+func (checker *NodeHealthChecker) collectMetricIfDue(node *models.Node, client *SSHClient) {
+	interval := nodeMetricsInterval()
+	if interval <= 0 {
+		return
+	}
+
+	checker.mu.Lock()
+	if time.Since(checker.lastMetricAt[node.ID]) < interval {
+		checker.mu.Unlock()
+		return
+	}
+	checker.lastMetricAt[node.ID] = time.Now()
+	checker.mu.Unlock()
+
+	metric, err := CollectNodeMetric(client)
+	if err != nil {
+		log.Printf("节点 %s %v", node.Name, err)
+		return
+	}
+	metric.NodeID = node.ID
+	checker.batchUpdateChan <- &nodeStatusUpdate{
+		nodeID: node.ID,
+		metric: metric,
+	}
+}
+
 // sendNotificationIfNeeded 仅在状态改变时发送通知
 func (checker *NodeHealthChecker) sendNotificationIfNeeded(node *models.Node, oldStatus, newStatus, title, message string) {
 	// 如果状态没有变化，不发送通知
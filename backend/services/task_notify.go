@@ -0,0 +1,82 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"smartdns-manager/models"
+)
+
+// taskNotifyOutputLimit 通知中任务输出摘要的最大字符数
+const taskNotifyOutputLimit = 500
+
+var taskTriggerLabels = map[string]string{
+	models.TaskTriggerSchedule:      "定时调度",
+	models.TaskTriggerManual:        "手动执行",
+	models.TaskTriggerCatchUp:       "错过调度补执行",
+	models.TaskTriggerResourceRetry: "资源保护重试",
+}
+
+// notifyTaskResult 按任务的通知设置发送执行结果，包含耗时和输出摘要
+func (s *SchedulerService) notifyTaskResult(task models.ScheduledTask, trigger string, status models.TaskStatus, output string, taskErr error, duration time.Duration) {
+	succeeded := status == models.TaskStatusSuccess
+	if (succeeded && !task.NotifyOnSuccess) || (!succeeded && !task.NotifyOnFailure) {
+		return
+	}
+
+	eventType := "task_success"
+	title := fmt.Sprintf("✅ 定时任务执行成功: %s", task.Name)
+	if status == models.TaskStatusTimeout {
+		eventType = "task_failed"
+		title = fmt.Sprintf("⏱️ 定时任务执行超时: %s", task.Name)
+	} else if !succeeded {
+		eventType = "task_failed"
+		title = fmt.Sprintf("❌ 定时任务执行失败: %s", task.Name)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "任务: %s（%s）\n", task.Name, task.Type)
+	label := taskTriggerLabels[trigger]
+	if label == "" {
+		label = trigger
+	}
+	fmt.Fprintf(&b, "触发方式: %s\n", label)
+	fmt.Fprintf(&b, "耗时: %s\n", formatTaskDuration(duration))
+	if taskErr != nil {
+		fmt.Fprintf(&b, "错误: %s\n", taskErr.Error())
+	}
+	if excerpt := excerptTaskOutput(output); excerpt != "" {
+		fmt.Fprintf(&b, "\n输出摘要:\n%s\n", excerpt)
+	}
+
+	var channelIDs []uint
+	if task.NotifyChannelIDs != "" {
+		if err := json.Unmarshal([]byte(task.NotifyChannelIDs), &channelIDs); err != nil {
+			log.Printf("⚠️ 任务 [%s] 通知渠道配置无效: %v", task.Name, err)
+		}
+	}
+	if err := s.notification.SendToChannels(channelIDs, eventType, title, b.String()); err != nil {
+		log.Printf("⚠️ 发送任务 [%s] 执行通知失败: %v", task.Name, err)
+	}
+}
+
+// excerptTaskOutput 截取任务输出的开头部分
+func excerptTaskOutput(output string) string {
+	output = strings.TrimSpace(output)
+	runes := []rune(output)
+	if len(runes) <= taskNotifyOutputLimit {
+		return output
+	}
+	return fmt.Sprintf("%s\n...（共 %d 字，完整输出见执行历史）", string(runes[:taskNotifyOutputLimit]), len(runes))
+}
+
+// formatTaskDuration 格式化执行耗时
+func formatTaskDuration(duration time.Duration) string {
+	if duration < time.Second {
+		return fmt.Sprintf("%dms", duration.Milliseconds())
+	}
+	return duration.Round(100 * time.Millisecond).String()
+}
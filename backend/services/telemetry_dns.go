@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"smartdns-manager/models"
+)
+
+// telemetryDNSTypes DNS 检测支持的记录类型
+var telemetryDNSTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"TXT":   dnsmessage.TypeTXT,
+	"NS":    dnsmessage.TypeNS,
+}
+
+// telemetryDNSRcodes 可作为期望值的响应码
+var telemetryDNSRcodes = map[string]dnsmessage.RCode{
+	"NOERROR":  dnsmessage.RCodeSuccess,
+	"FORMERR":  dnsmessage.RCodeFormatError,
+	"SERVFAIL": dnsmessage.RCodeServerFailure,
+	"NXDOMAIN": dnsmessage.RCodeNameError,
+	"NOTIMP":   dnsmessage.RCodeNotImplemented,
+	"REFUSED":  dnsmessage.RCodeRefused,
+}
+
+// ParseTelemetryDNSQuery 解析并校验 DNS 检测参数，缺省值会被补全
+func ParseTelemetryDNSQuery(raw string) (*models.TelemetryDNSQuery, error) {
+	var query models.TelemetryDNSQuery
+	if err := json.Unmarshal([]byte(raw), &query); err != nil {
+		return nil, fmt.Errorf("DNS 检测参数格式错误: %w", err)
+	}
+
+	query.Domain = strings.TrimSuffix(strings.TrimSpace(query.Domain), ".")
+	if query.Domain == "" {
+		return nil, fmt.Errorf("DNS 检测需要填写查询域名")
+	}
+
+	query.RecordType = strings.ToUpper(strings.TrimSpace(query.RecordType))
+	if query.RecordType == "" {
+		query.RecordType = "A"
+	}
+	if _, ok := telemetryDNSTypes[query.RecordType]; !ok {
+		return nil, fmt.Errorf("不支持的记录类型: %s（可选 A/AAAA/CNAME/MX/TXT/NS）", query.RecordType)
+	}
+
+	query.Protocol = strings.ToLower(strings.TrimSpace(query.Protocol))
+	if query.Protocol == "" {
+		query.Protocol = DNSProbeUDP
+	}
+	if query.Protocol != DNSProbeUDP && query.Protocol != DNSProbeTCP && query.Protocol != DNSProbeDoT {
+		return nil, fmt.Errorf("不支持的查询协议: %s（可选 udp/tcp/dot）", query.Protocol)
+	}
+
+	query.ExpectRcode = strings.ToUpper(strings.TrimSpace(query.ExpectRcode))
+	if query.ExpectRcode == "" {
+		query.ExpectRcode = "NOERROR"
+	}
+	if _, ok := telemetryDNSRcodes[query.ExpectRcode]; !ok {
+		return nil, fmt.Errorf("不支持的响应码: %s（可选 NOERROR/NXDOMAIN/SERVFAIL/REFUSED 等）", query.ExpectRcode)
+	}
+
+	if len(query.ExpectAnswers) > 0 && (query.RecordType == "A" || query.RecordType == "AAAA") {
+		if _, err := parseAnswerExpect(query.ExpectAnswers); err != nil {
+			return nil, err
+		}
+	}
+	return &query, nil
+}
+
+// telemetryDNSServer 补全 DNS 服务器端口，DoT 默认 853
+func telemetryDNSServer(server, protocol string) (string, error) {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return "", fmt.Errorf("DNS 检测需要填写 DNS 服务器地址")
+	}
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server, nil
+	}
+	port := "53"
+	if protocol == DNSProbeDoT {
+		port = "853"
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), port), nil
+}
+
+// telemetryDNSResult DNS 检测结果
+type telemetryDNSResult struct {
+	connectLatency time.Duration
+	resolveLatency time.Duration
+	rcode          string
+	answers        []string
+}
+
+// summary 检测结果摘要，保存在 TelemetryResult.Response
+func (r *telemetryDNSResult) summary() string {
+	if r.rcode == "" {
+		return ""
+	}
+	if len(r.answers) == 0 {
+		return r.rcode
+	}
+	return r.rcode + " " + strings.Join(r.answers, ", ")
+}
+
+// dnsCheck 向目标 DNS 服务器发送查询并校验响应码和应答，连接耗时与查询耗时分开统计
+func (s *TelemetryService) dnsCheck(ctx context.Context, target models.TelemetryTarget) (*telemetryDNSResult, error) {
+	query, err := ParseTelemetryDNSQuery(target.DNSQuery)
+	if err != nil {
+		return nil, err
+	}
+	server, err := telemetryDNSServer(target.Target, query.Protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := 5 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	dial, err := dnsProbeDialer(query.Protocol, server, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &telemetryDNSResult{}
+	start := time.Now()
+	conn, err := dial(ctx, "", server)
+	result.connectLatency = time.Since(start)
+	if err != nil {
+		return result, fmt.Errorf("连接 %s 失败: %w", server, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	id := uint16(rand.Intn(1 << 16))
+	name, err := dnsmessage.NewName(query.Domain + ".")
+	if err != nil {
+		return result, fmt.Errorf("无效的域名: %s", query.Domain)
+	}
+	packet, err := (&dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  telemetryDNSTypes[query.RecordType],
+			Class: dnsmessage.ClassINET,
+		}},
+	}).Pack()
+	if err != nil {
+		return result, fmt.Errorf("构造查询失败: %w", err)
+	}
+
+	start = time.Now()
+	response, err := exchangeDNS(conn, query.Protocol, packet)
+	result.resolveLatency = time.Since(start)
+	if err != nil {
+		return result, fmt.Errorf("%s 查询 %s 失败: %w", strings.ToUpper(query.Protocol), query.Domain, err)
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(response); err != nil {
+		return result, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if msg.Header.ID != id {
+		return result, fmt.Errorf("响应ID不匹配")
+	}
+
+	result.rcode = fmt.Sprintf("RCODE%d", msg.Header.RCode)
+	for rcodeName, rcode := range telemetryDNSRcodes {
+		if rcode == msg.Header.RCode {
+			result.rcode = rcodeName
+		}
+	}
+	result.answers = dnsAnswerStrings(msg.Answers, telemetryDNSTypes[query.RecordType])
+
+	if result.rcode != query.ExpectRcode {
+		return result, fmt.Errorf("响应码 %s，期望 %s", result.rcode, query.ExpectRcode)
+	}
+	if query.ExpectRcode != "NOERROR" {
+		return result, nil
+	}
+	if len(result.answers) == 0 {
+		if query.AllowEmpty {
+			return result, nil
+		}
+		return result, fmt.Errorf("%s 没有 %s 记录", query.Domain, query.RecordType)
+	}
+	if len(query.ExpectAnswers) > 0 && !matchDNSAnswers(query, result.answers) {
+		return result, fmt.Errorf("应答 %s 不包含期望值 %s",
+			strings.Join(result.answers, ", "), strings.Join(query.ExpectAnswers, ", "))
+	}
+	return result, nil
+}
+
+// exchangeDNS 发送查询并读取响应，TCP/DoT 使用两字节长度前缀
+func exchangeDNS(conn net.Conn, protocol string, packet []byte) ([]byte, error) {
+	if protocol == DNSProbeUDP {
+		if _, err := conn.Write(packet); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	frame := make([]byte, 2+len(packet))
+	binary.BigEndian.PutUint16(frame, uint16(len(packet)))
+	copy(frame[2:], packet)
+	if _, err := conn.Write(frame); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// dnsAnswerStrings 提取与查询类型一致的应答记录（忽略 CNAME 链中的中间记录）
+func dnsAnswerStrings(answers []dnsmessage.Resource, qtype dnsmessage.Type) []string {
+	var result []string
+	for _, answer := range answers {
+		if answer.Header.Type != qtype {
+			continue
+		}
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			result = append(result, net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			result = append(result, net.IP(body.AAAA[:]).String())
+		case *dnsmessage.CNAMEResource:
+			result = append(result, strings.TrimSuffix(body.CNAME.String(), "."))
+		case *dnsmessage.NSResource:
+			result = append(result, strings.TrimSuffix(body.NS.String(), "."))
+		case *dnsmessage.MXResource:
+			result = append(result, fmt.Sprintf("%d %s", body.Pref, strings.TrimSuffix(body.MX.String(), ".")))
+		case *dnsmessage.TXTResource:
+			result = append(result, strings.Join(body.TXT, ""))
+		}
+	}
+	return result
+}
+
+// matchDNSAnswers A/AAAA 按地址或网段匹配，其他类型按内容包含匹配（忽略大小写）
+func matchDNSAnswers(query *models.TelemetryDNSQuery, answers []string) bool {
+	if query.RecordType == "A" || query.RecordType == "AAAA" {
+		ranges, err := parseAnswerExpect(query.ExpectAnswers)
+		if err != nil {
+			return false
+		}
+		for _, answer := range answers {
+			ip := net.ParseIP(answer)
+			for _, r := range ranges {
+				if ip != nil && r.Contains(ip) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, answer := range answers {
+		for _, expected := range query.ExpectAnswers {
+			expected = strings.TrimSpace(expected)
+			if expected != "" && strings.Contains(strings.ToLower(answer), strings.ToLower(expected)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"smartdns-manager/config"
+)
+
+// errICMPUnavailable 当前进程没有发送 ICMP 的权限（未开放非特权 ICMP 且没有 CAP_NET_RAW）
+var errICMPUnavailable = errors.New("无法发送 ICMP")
+
+// icmpPingResult 一次 PING 检测的统计
+type icmpPingResult struct {
+	address  string
+	sent     int
+	received int
+	minRTT   time.Duration
+	maxRTT   time.Duration
+	totalRTT time.Duration
+}
+
+// loss 丢包率（百分比）
+func (r *icmpPingResult) loss() float64 {
+	if r.sent == 0 {
+		return 0
+	}
+	return float64(r.sent-r.received) / float64(r.sent) * 100
+}
+
+// avgRTT 收到应答的平均往返时间
+func (r *icmpPingResult) avgRTT() time.Duration {
+	if r.received == 0 {
+		return 0
+	}
+	return r.totalRTT / time.Duration(r.received)
+}
+
+// summary 与系统 ping 类似的统计摘要
+func (r *icmpPingResult) summary() string {
+	text := fmt.Sprintf("%s: %d/%d 收到应答，丢包 %.0f%%", r.address, r.received, r.sent, r.loss())
+	if r.received > 0 {
+		text += fmt.Sprintf("，rtt min/avg/max = %.1f/%.1f/%.1f ms",
+			float64(r.minRTT.Microseconds())/1000, float64(r.avgRTT().Microseconds())/1000, float64(r.maxRTT.Microseconds())/1000)
+	}
+	return text
+}
+
+// telemetryPingCount 每次检测发送的回显请求数
+func telemetryPingCount() int {
+	count, err := strconv.Atoi(config.GetConfig().TelemetryPingCount)
+	if err != nil || count <= 0 {
+		return 4
+	}
+	if count > 20 {
+		return 20
+	}
+	return count
+}
+
+// listenICMP 优先使用非特权 ICMP（udp 套接字），失败时尝试原始套接字
+func listenICMP(ipv6Target bool) (*icmp.PacketConn, bool, error) {
+	udpNetwork, rawNetwork, address := "udp4", "ip4:icmp", "0.0.0.0"
+	if ipv6Target {
+		udpNetwork, rawNetwork, address = "udp6", "ip6:ipv6-icmp", "::"
+	}
+	if conn, err := icmp.ListenPacket(udpNetwork, address); err == nil {
+		return conn, true, nil
+	}
+	conn, err := icmp.ListenPacket(rawNetwork, address)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", errICMPUnavailable, err)
+	}
+	return conn, false, nil
+}
+
+// icmpPing 向目标发送 count 个 ICMP 回显请求，统计往返时间和丢包
+func icmpPing(ctx context.Context, host string, count int) (*icmpPingResult, error) {
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("DNS解析失败: %w", err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("DNS解析未返回IP地址")
+	}
+	// 优先 IPv4
+	ip := ips[0].IP
+	for _, addr := range ips {
+		if addr.IP.To4() != nil {
+			ip = addr.IP
+			break
+		}
+	}
+	isIPv6 := ip.To4() == nil
+
+	conn, unprivileged, err := listenICMP(isIPv6)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	if unprivileged {
+		dst = &net.UDPAddr{IP: ip}
+	}
+	var echoType icmp.Type = ipv4.ICMPTypeEcho
+	var replyType icmp.Type = ipv4.ICMPTypeEchoReply
+	protocol := 1
+	if isIPv6 {
+		echoType, replyType, protocol = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, 58
+	}
+
+	// 非特权套接字的 ID 由内核改写，用随机负载区分本次检测的应答
+	token := make([]byte, 16)
+	rand.Read(token)
+	id := os.Getpid() & 0xffff
+
+	result := &icmpPingResult{address: ip.String()}
+	buf := make([]byte, 1500)
+	for seq := 1; seq <= count; seq++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		packet, err := (&icmp.Message{
+			Type: echoType,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: token},
+		}).Marshal(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// 每个请求最多等待 1 秒，且不超过整体超时
+		deadline := time.Now().Add(time.Second)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		conn.SetReadDeadline(deadline)
+
+		start := time.Now()
+		if _, err := conn.WriteTo(packet, dst); err != nil {
+			return nil, fmt.Errorf("发送 ICMP 请求失败: %w", err)
+		}
+		result.sent++
+
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				break // 超时视为丢包
+			}
+			msg, err := icmp.ParseMessage(protocol, buf[:n])
+			if err != nil || msg.Type != replyType {
+				continue
+			}
+			echo, ok := msg.Body.(*icmp.Echo)
+			if !ok || echo.Seq != seq || string(echo.Data) != string(token) {
+				continue
+			}
+
+			rtt := time.Since(start)
+			result.received++
+			result.totalRTT += rtt
+			if result.minRTT == 0 || rtt < result.minRTT {
+				result.minRTT = rtt
+			}
+			if rtt > result.maxRTT {
+				result.maxRTT = rtt
+			}
+			break
+		}
+
+		// 与系统 ping 一样间隔发送，最后一个请求后不再等待
+		if seq < count {
+			wait := 200*time.Millisecond - time.Since(start)
+			if wait > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(wait):
+				}
+			}
+		}
+	}
+
+	if result.sent == 0 {
+		return nil, fmt.Errorf("检查超时: %w", ctx.Err())
+	}
+	return result, nil
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"smartdns-manager/models"
+)
+
+// 遥测目标导入格式
+const (
+	TelemetryImportAuto = "auto"
+	TelemetryImportCSV  = "csv"
+	TelemetryImportYAML = "yaml"
+)
+
+// telemetryCSVColumns 没有表头时 CSV 的默认列顺序
+var telemetryCSVColumns = []string{"name", "type", "target", "group", "timeout", "description"}
+
+// telemetryTargetTypes 支持的检测类型
+var telemetryTargetTypes = map[string]bool{"ping": true, "http": true, "https": true, "tcp": true}
+
+// telemetryTargetEntry 导入文件中的单个目标
+type telemetryTargetEntry struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	Target      string `yaml:"target"`
+	Group       string `yaml:"group"`
+	Timeout     int    `yaml:"timeout"`
+	Description string `yaml:"description"`
+	Enabled     *bool  `yaml:"enabled"`
+}
+
+// ParseTelemetryTargets 解析 CSV 或 YAML 格式的目标列表，defaultGroup 用于未指定分组的目标
+//
+// CSV 第一行包含 target 列时视为表头，否则按 name,type,target,group,timeout,description 读取；
+// YAML 可以是目标数组，也可以是 {targets: [...]}。返回可导入的目标和逐行错误。
+func ParseTelemetryTargets(content, format, defaultGroup string) ([]models.TelemetryTarget, []string) {
+	if format == "" || format == TelemetryImportAuto {
+		format = detectTelemetryImportFormat(content)
+	}
+
+	var entries []telemetryTargetEntry
+	var errors []string
+	switch format {
+	case TelemetryImportCSV:
+		entries, errors = parseTelemetryCSV(content)
+	case TelemetryImportYAML:
+		var err error
+		if entries, err = parseTelemetryYAML(content); err != nil {
+			return nil, []string{err.Error()}
+		}
+	default:
+		return nil, []string{"不支持的导入格式: " + format}
+	}
+
+	targets := make([]models.TelemetryTarget, 0, len(entries))
+	for i, entry := range entries {
+		target, err := entry.toTarget(defaultGroup)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("第 %d 个目标: %v", i+1, err))
+			continue
+		}
+		targets = append(targets, *target)
+	}
+	return targets, errors
+}
+
+// detectTelemetryImportFormat 含有 YAML 键值或列表标记时按 YAML 解析，否则按 CSV
+func detectTelemetryImportFormat(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "targets:") {
+			return TelemetryImportYAML
+		}
+		return TelemetryImportCSV
+	}
+	return TelemetryImportCSV
+}
+
+func parseTelemetryCSV(content string) ([]telemetryTargetEntry, []string) {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, []string{fmt.Sprintf("CSV 解析失败: %v", err)}
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := telemetryCSVColumns
+	for _, cell := range records[0] {
+		if strings.EqualFold(strings.TrimSpace(cell), "target") {
+			columns = make([]string, len(records[0]))
+			for i, name := range records[0] {
+				columns[i] = strings.ToLower(strings.TrimSpace(name))
+			}
+			records = records[1:]
+			break
+		}
+	}
+
+	var entries []telemetryTargetEntry
+	var errors []string
+	for i, record := range records {
+		var entry telemetryTargetEntry
+		for j, value := range record {
+			if j >= len(columns) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch columns[j] {
+			case "name":
+				entry.Name = value
+			case "type":
+				entry.Type = value
+			case "target":
+				entry.Target = value
+			case "group":
+				entry.Group = value
+			case "timeout":
+				if value == "" {
+					continue
+				}
+				timeout, err := strconv.Atoi(value)
+				if err != nil {
+					errors = append(errors, fmt.Sprintf("第 %d 行: 超时时间无效 %q", i+1, value))
+					continue
+				}
+				entry.Timeout = timeout
+			case "description":
+				entry.Description = value
+			case "enabled":
+				if enabled, err := strconv.ParseBool(value); err == nil {
+					entry.Enabled = &enabled
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, errors
+}
+
+func parseTelemetryYAML(content string) ([]telemetryTargetEntry, error) {
+	var entries []telemetryTargetEntry
+	if err := yaml.Unmarshal([]byte(content), &entries); err == nil {
+		return entries, nil
+	}
+
+	var wrapped struct {
+		Targets []telemetryTargetEntry `yaml:"targets"`
+	}
+	if err := yaml.Unmarshal([]byte(content), &wrapped); err != nil {
+		return nil, fmt.Errorf("YAML 解析失败: %w", err)
+	}
+	return wrapped.Targets, nil
+}
+
+// toTarget 校验并补全默认值
+func (e telemetryTargetEntry) toTarget(defaultGroup string) (*models.TelemetryTarget, error) {
+	target := &models.TelemetryTarget{
+		Name:        strings.TrimSpace(e.Name),
+		Type:        strings.ToLower(strings.TrimSpace(e.Type)),
+		Target:      strings.TrimSpace(e.Target),
+		Group:       strings.TrimSpace(e.Group),
+		Timeout:     e.Timeout,
+		Description: e.Description,
+		Enabled:     true,
+	}
+
+	if target.Target == "" {
+		return nil, fmt.Errorf("缺少目标地址")
+	}
+	if target.Type == "" {
+		target.Type = guessTelemetryType(target.Target)
+	}
+	if !telemetryTargetTypes[target.Type] {
+		return nil, fmt.Errorf("不支持的检测类型 %q（可选 ping/http/https/tcp）", target.Type)
+	}
+	if target.Name == "" {
+		target.Name = target.Target
+	}
+	if target.Group == "" {
+		target.Group = defaultGroup
+	}
+	if target.Timeout <= 0 {
+		target.Timeout = 5000
+	}
+	if e.Enabled != nil {
+		target.Enabled = *e.Enabled
+	}
+	return target, nil
+}
+
+// guessTelemetryType 未指定类型时按地址推断：URL 按协议，带端口为 tcp，其余为 ping
+func guessTelemetryType(target string) string {
+	if strings.HasPrefix(target, "https://") {
+		return "https"
+	}
+	if strings.HasPrefix(target, "http://") {
+		return "http"
+	}
+	if strings.Contains(target, ":") && !strings.Contains(target, "::") {
+		return "tcp"
+	}
+	return "ping"
+}
+
+// ImportTargets 批量创建遥测目标，已存在相同类型和地址的目标跳过
+func (s *TelemetryService) ImportTargets(targets []models.TelemetryTarget) (*models.TelemetryImportResult, error) {
+	result := &models.TelemetryImportResult{Errors: []string{}}
+
+	var existing []models.TelemetryTarget
+	if err := s.db.Select("type", "target").Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("查询遥测目标失败: %w", err)
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, target := range existing {
+		seen[target.Type+"|"+target.Target] = true
+	}
+
+	var toCreate []models.TelemetryTarget
+	for _, target := range targets {
+		key := target.Type + "|" + target.Target
+		if seen[key] {
+			result.Skipped++
+			continue
+		}
+		seen[key] = true
+		toCreate = append(toCreate, target)
+	}
+
+	if len(toCreate) > 0 {
+		if err := s.db.CreateInBatches(&toCreate, 100).Error; err != nil {
+			return nil, fmt.Errorf("创建遥测目标失败: %w", err)
+		}
+	}
+	result.Created = len(toCreate)
+	return result, nil
+}
+
+// GetGroupStats 按分组汇总遥测目标状态，未分组的目标归入空分组
+func (s *TelemetryService) GetGroupStats() ([]models.TelemetryGroupStats, error) {
+	var targets []models.TelemetryTarget
+	if err := s.db.Find(&targets).Error; err != nil {
+		return nil, fmt.Errorf("查询遥测目标失败: %w", err)
+	}
+
+	type accumulator struct {
+		stats        models.TelemetryGroupStats
+		latencySum   int64
+		checkCount   int
+		successCount int
+	}
+	groups := make(map[string]*accumulator)
+	for _, target := range targets {
+		acc, ok := groups[target.Group]
+		if !ok {
+			acc = &accumulator{stats: models.TelemetryGroupStats{Group: target.Group}}
+			groups[target.Group] = acc
+		}
+
+		acc.stats.TotalTargets++
+		acc.checkCount += target.CheckCount
+		acc.successCount += target.SuccessCount
+		if target.LastCheckAt != nil && (acc.stats.LastCheckAt == nil || target.LastCheckAt.After(*acc.stats.LastCheckAt)) {
+			lastCheck := *target.LastCheckAt
+			acc.stats.LastCheckAt = &lastCheck
+		}
+		if !target.Enabled {
+			continue
+		}
+		acc.stats.EnabledTargets++
+		if target.LastStatus {
+			acc.stats.OnlineTargets++
+			acc.latencySum += target.LastLatency
+		}
+	}
+
+	stats := make([]models.TelemetryGroupStats, 0, len(groups))
+	for _, acc := range groups {
+		if acc.stats.OnlineTargets > 0 {
+			acc.stats.AvgLatency = float64(acc.latencySum) / float64(acc.stats.OnlineTargets)
+		}
+		if acc.checkCount > 0 {
+			acc.stats.SuccessRate = float64(acc.successCount) / float64(acc.checkCount) * 100
+		}
+		stats = append(stats, acc.stats)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Group < stats[j].Group
+	})
+	return stats, nil
+}
+
+// telemetryGroupRun 单次检测中一个分组的结果
+type telemetryGroupRun struct {
+	total  int
+	failed []string
+}
+
+// checkGroupAlerts 分组内失败目标占比达到阈值时告警，恢复后再通知一次
+func (s *TelemetryService) checkGroupAlerts(runs map[string]*telemetryGroupRun, alertPercent int) {
+	if alertPercent <= 0 {
+		alertPercent = 50
+	}
+
+	s.groupMu.Lock()
+	defer s.groupMu.Unlock()
+
+	for group, run := range runs {
+		if group == "" || run.total == 0 {
+			continue
+		}
+		failedPercent := len(run.failed) * 100 / run.total
+
+		if failedPercent >= alertPercent {
+			if s.alertedGroups[group] {
+				continue
+			}
+			s.alertedGroups[group] = true
+			s.notificationService.SendNotification(0, "telemetry_group_down", "🚨 遥测分组异常",
+				fmt.Sprintf("分组 %s 中 %d/%d 个目标检测失败（阈值 %d%%）\n失败目标: %s\n时间: %s",
+					group, len(run.failed), run.total, alertPercent, strings.Join(run.failed, ", "),
+					time.Now().Format("2006-01-02 15:04:05")))
+			continue
+		}
+
+		if s.alertedGroups[group] {
+			delete(s.alertedGroups, group)
+			s.notificationService.SendNotification(0, "telemetry_group_recovered", "✅ 遥测分组恢复",
+				fmt.Sprintf("分组 %s 已恢复，%d/%d 个目标检测成功", group, run.total-len(run.failed), run.total))
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smartdns-manager/models"
+)
+
+// ParseTelemetryScenario 解析并校验场景定义
+func ParseTelemetryScenario(raw string) (*models.TelemetryScenario, error) {
+	var scenario models.TelemetryScenario
+	if err := json.Unmarshal([]byte(raw), &scenario); err != nil {
+		return nil, fmt.Errorf("场景格式错误: %w", err)
+	}
+	if len(scenario.Steps) == 0 {
+		return nil, fmt.Errorf("场景至少需要一个步骤")
+	}
+
+	hostname := ""
+	for i, step := range scenario.Steps {
+		switch step.Type {
+		case models.ScenarioStepResolve:
+			if step.Hostname == "" {
+				return nil, fmt.Errorf("第 %d 步: 解析步骤需要填写域名", i+1)
+			}
+			if rt := strings.ToUpper(step.RecordType); rt != "" && rt != "A" && rt != "AAAA" {
+				return nil, fmt.Errorf("第 %d 步: 记录类型只支持 A/AAAA", i+1)
+			}
+			hostname = step.Hostname
+		case models.ScenarioStepConnect:
+			if hostname == "" && step.Hostname == "" {
+				return nil, fmt.Errorf("第 %d 步: 连接步骤前需要解析步骤或填写域名", i+1)
+			}
+			if step.Port <= 0 || step.Port > 65535 {
+				return nil, fmt.Errorf("第 %d 步: 端口无效", i+1)
+			}
+		case models.ScenarioStepHTTP:
+			if hostname == "" && step.Hostname == "" {
+				return nil, fmt.Errorf("第 %d 步: HTTP 步骤前需要解析步骤或填写域名", i+1)
+			}
+			if scheme := strings.ToLower(step.Scheme); scheme != "" && scheme != "http" && scheme != "https" {
+				return nil, fmt.Errorf("第 %d 步: 协议只支持 http/https", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("第 %d 步: 不支持的步骤类型 %q（可选 resolve/connect/http）", i+1, step.Type)
+		}
+	}
+	return &scenario, nil
+}
+
+// ScenarioSummary 场景目标在列表中显示的地址，取第一个解析步骤的域名
+func ScenarioSummary(scenario *models.TelemetryScenario) string {
+	for _, step := range scenario.Steps {
+		if step.Hostname != "" {
+			return step.Hostname
+		}
+	}
+	return ""
+}
+
+// scenarioState 步骤之间传递的解析结果
+type scenarioState struct {
+	hostname string
+	ip       string
+	port     int
+}
+
+// scenarioCheck 依次执行场景步骤，返回每一步的结果
+func (s *TelemetryService) scenarioCheck(ctx context.Context, target models.TelemetryTarget) ([]models.TelemetryStepResult, error) {
+	scenario, err := ParseTelemetryScenario(target.Scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &scenarioState{}
+	results := make([]models.TelemetryStepResult, 0, len(scenario.Steps))
+	for i, step := range scenario.Steps {
+		result := models.TelemetryStepResult{Name: step.Name, Type: step.Type}
+		if result.Name == "" {
+			result.Name = fmt.Sprintf("步骤 %d", i+1)
+		}
+
+		start := time.Now()
+		var detail string
+		switch step.Type {
+		case models.ScenarioStepResolve:
+			detail, err = s.scenarioResolve(ctx, step, state)
+		case models.ScenarioStepConnect:
+			detail, err = s.scenarioConnect(ctx, step, state)
+		case models.ScenarioStepHTTP:
+			detail, err = s.scenarioHTTP(ctx, step, state)
+		}
+		result.Latency = time.Since(start).Milliseconds()
+		result.Detail = detail
+		result.Success = err == nil
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			log.Printf("❌ 场景步骤失败 [%s] %s: %v", target.Name, result.Name, err)
+			return results, fmt.Errorf("%s 失败: %w", result.Name, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// scenarioDNSServer 确定解析使用的 DNS 服务器，空字符串表示系统解析
+func (s *TelemetryService) scenarioDNSServer(step models.TelemetryScenarioStep) (string, error) {
+	server := step.Server
+	if server == "" && step.NodeID != 0 {
+		var node models.Node
+		if err := s.db.Select("id", "host").First(&node, step.NodeID).Error; err != nil {
+			return "", fmt.Errorf("节点 %d 不存在", step.NodeID)
+		}
+		server = node.Host
+	}
+	if server == "" {
+		return "", nil
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return server, nil
+}
+
+func (s *TelemetryService) scenarioResolve(ctx context.Context, step models.TelemetryScenarioStep, state *scenarioState) (string, error) {
+	server, err := s.scenarioDNSServer(step)
+	if err != nil {
+		return "", err
+	}
+
+	resolver := net.DefaultResolver
+	if server != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	network := "ip4"
+	if strings.EqualFold(step.RecordType, "AAAA") {
+		network = "ip6"
+	}
+	ips, err := resolver.LookupIP(ctx, network, step.Hostname)
+	if err != nil {
+		return "", fmt.Errorf("解析 %s 失败: %w", step.Hostname, err)
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("解析 %s 无结果", step.Hostname)
+	}
+
+	answers := make([]string, len(ips))
+	for i, ip := range ips {
+		answers[i] = ip.String()
+	}
+	detail := strings.Join(answers, ", ")
+	if server != "" {
+		detail += " (@" + server + ")"
+	}
+
+	state.hostname = step.Hostname
+	state.ip = answers[0]
+	if len(step.ExpectIPs) > 0 {
+		state.ip = ""
+		for _, answer := range answers {
+			for _, expected := range step.ExpectIPs {
+				if answer == expected {
+					state.ip = answer
+					break
+				}
+			}
+			if state.ip != "" {
+				break
+			}
+		}
+		if state.ip == "" {
+			return detail, fmt.Errorf("解析结果 %s 不包含期望地址 %s", strings.Join(answers, ", "), strings.Join(step.ExpectIPs, ", "))
+		}
+	}
+	return detail, nil
+}
+
+// scenarioAddress 后续步骤使用的主机：未解析时直接使用步骤中填写的域名
+func scenarioAddress(step models.TelemetryScenarioStep, state *scenarioState) (hostname, host string) {
+	hostname = state.hostname
+	if step.Hostname != "" {
+		hostname = step.Hostname
+	}
+	host = state.ip
+	if host == "" || (step.Hostname != "" && step.Hostname != state.hostname) {
+		host = hostname
+	}
+	return hostname, host
+}
+
+func (s *TelemetryService) scenarioConnect(ctx context.Context, step models.TelemetryScenarioStep, state *scenarioState) (string, error) {
+	_, host := scenarioAddress(step, state)
+	address := net.JoinHostPort(host, strconv.Itoa(step.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", fmt.Errorf("连接 %s 失败: %w", address, err)
+	}
+	conn.Close()
+
+	state.port = step.Port
+	return "已连接 " + address, nil
+}
+
+func (s *TelemetryService) scenarioHTTP(ctx context.Context, step models.TelemetryScenarioStep, state *scenarioState) (string, error) {
+	hostname, host := scenarioAddress(step, state)
+
+	scheme := strings.ToLower(step.Scheme)
+	if scheme == "" {
+		scheme = "https"
+	}
+	port := step.Port
+	if port == 0 {
+		port = state.port
+	}
+	if port == 0 {
+		port = 443
+		if scheme == "http" {
+			port = 80
+		}
+	}
+	path := step.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	// 请求地址保留域名（Host 头和 SNI），实际连接到解析得到的地址
+	dialAddress := net.JoinHostPort(host, strconv.Itoa(port))
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, dialAddress)
+		},
+		TLSClientConfig:   &tls.Config{ServerName: hostname},
+		DisableKeepAlives: true,
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(hostname, strconv.Itoa(port)), path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "SmartDNS-Manager-Telemetry/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求 %s 失败: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	detail := fmt.Sprintf("HTTP %d %s (via %s)", resp.StatusCode, url, dialAddress)
+	if step.ExpectStatus != 0 {
+		if resp.StatusCode != step.ExpectStatus {
+			return detail, fmt.Errorf("状态码 %d，期望 %d", resp.StatusCode, step.ExpectStatus)
+		}
+	} else if resp.StatusCode >= 400 {
+		return detail, fmt.Errorf("状态码 %d", resp.StatusCode)
+	}
+
+	if step.ExpectBody != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return detail, fmt.Errorf("读取响应失败: %w", err)
+		}
+		if !strings.Contains(string(body), step.ExpectBody) {
+			return detail, fmt.Errorf("响应内容不包含 %q", step.ExpectBody)
+		}
+	}
+	return detail, nil
+}
@@ -2,11 +2,14 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
@@ -16,9 +19,13 @@ import (
 
 // TelemetryService 遥测服务
 type TelemetryService struct {
-	db     *gorm.DB
-	config *config.Config
-	client *http.Client
+	db                  *gorm.DB
+	config              *config.Config
+	client              *http.Client
+	notificationService *NotificationService
+
+	groupMu       sync.Mutex
+	alertedGroups map[string]bool // 已发送异常告警、尚未恢复的分组
 }
 
 // NewTelemetryService 创建遥测服务
@@ -49,9 +56,11 @@ func NewTelemetryService(db *gorm.DB, config *config.Config) (*TelemetryService,
 	}
 
 	return &TelemetryService{
-		db:     db,
-		config: config,
-		client: client,
+		db:                  db,
+		config:              config,
+		client:              client,
+		notificationService: NewNotificationService(),
+		alertedGroups:       make(map[string]bool),
 	}, nil
 }
 
@@ -64,6 +73,9 @@ func (s *TelemetryService) CheckTargets(ctx context.Context, config models.Telem
 	if len(config.Targets) > 0 {
 		query = query.Where("id IN ?", config.Targets)
 	}
+	if len(config.Groups) > 0 {
+		query = query.Where("`group` IN ?", config.Groups)
+	}
 	
 	if err := query.Find(&targets).Error; err != nil {
 		return "", fmt.Errorf("查询遥测目标失败: %w", err)
@@ -75,6 +87,7 @@ func (s *TelemetryService) CheckTargets(ctx context.Context, config models.Telem
 	
 	successCount := 0
 	var results []string
+	groupRuns := make(map[string]*telemetryGroupRun)
 	
 	for _, target := range targets {
 		log.Printf("🎯 开始检查遥测目标: %s (类型: %s, 地址: %s)",
@@ -82,6 +95,16 @@ func (s *TelemetryService) CheckTargets(ctx context.Context, config models.Telem
 		
 		result, err := s.CheckSingleTarget(ctx, target)
 		
+		run, ok := groupRuns[target.Group]
+		if !ok {
+			run = &telemetryGroupRun{}
+			groupRuns[target.Group] = run
+		}
+		run.total++
+		if err != nil {
+			run.failed = append(run.failed, target.Name)
+		}
+		
 		// 构建结果描述
 		if err != nil {
 			errorMsg := err.Error()
@@ -125,6 +148,9 @@ func (s *TelemetryService) CheckTargets(ctx context.Context, config models.Telem
 		}
 	}
 	
+	// 分组级告警
+	s.checkGroupAlerts(groupRuns, config.GroupAlertPercent)
+	
 	// 清理过期结果
 	if config.ResultRetention > 0 {
 		if err := s.cleanupResults(config.ResultRetention); err != nil {
@@ -168,15 +194,25 @@ func (s *TelemetryService) CheckSingleTarget(ctx context.Context, target models.
 	
 	switch strings.ToLower(target.Type) {
 	case "ping":
-		err = s.pingCheck(checkCtx, target.Target)
+		pingResult, err := s.pingCheck(checkCtx, target.Target)
 		result.Latency = time.Since(startTime).Milliseconds()
 		result.Success = err == nil
+		if pingResult != nil {
+			// 使用平均往返时间作为延迟，不足 1ms 按 1ms 记录
+			if pingResult.received > 0 {
+				result.Latency = max(1, pingResult.avgRTT().Milliseconds())
+			}
+			result.PacketLoss = pingResult.loss()
+			result.Response = pingResult.summary()
+		} else if err == nil {
+			result.Response = "无 ICMP 权限，已通过端口连通性检测"
+		}
 		if err != nil {
 			result.Error = err.Error()
 			log.Printf("❌ PING检测失败 [%s]: %v (耗时: %dms)", target.Name, err, result.Latency)
 			return result, err
 		}
-		log.Printf("✅ PING检测成功 [%s]: 延迟 %dms", target.Name, result.Latency)
+		log.Printf("✅ PING检测成功 [%s]: 延迟 %dms, 丢包 %.0f%%", target.Name, result.Latency, result.PacketLoss)
 		
 	case "http", "https":
 		resp, err := s.httpCheck(checkCtx, target.Target)
@@ -201,6 +237,37 @@ func (s *TelemetryService) CheckSingleTarget(ctx context.Context, target models.
 		}
 		log.Printf("✅ TCP检测成功 [%s]: 延迟 %dms", target.Name, result.Latency)
 		
+	case "scenario":
+		steps, err := s.scenarioCheck(checkCtx, target)
+		result.Latency = time.Since(startTime).Milliseconds()
+		result.Success = err == nil
+		if data, marshalErr := json.Marshal(steps); marshalErr == nil && len(steps) > 0 {
+			result.Response = string(data)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			log.Printf("❌ 场景检测失败 [%s]: %v (耗时: %dms)", target.Name, err, result.Latency)
+			return result, err
+		}
+		log.Printf("✅ 场景检测成功 [%s]: %d 个步骤 (耗时: %dms)", target.Name, len(steps), result.Latency)
+		
+	case "dns":
+		dnsResult, err := s.dnsCheck(checkCtx, target)
+		result.Latency = time.Since(startTime).Milliseconds()
+		result.Success = err == nil
+		if dnsResult != nil {
+			result.ConnectLatency = dnsResult.connectLatency.Milliseconds()
+			result.ResolveLatency = dnsResult.resolveLatency.Milliseconds()
+			result.Response = dnsResult.summary()
+		}
+		if err != nil {
+			result.Error = err.Error()
+			log.Printf("❌ DNS检测失败 [%s]: %v (耗时: %dms)", target.Name, err, result.Latency)
+			return result, err
+		}
+		log.Printf("✅ DNS检测成功 [%s]: %s (连接: %dms, 解析: %dms)",
+			target.Name, result.Response, result.ConnectLatency, result.ResolveLatency)
+		
 	default:
 		result.Success = false
 		result.Error = fmt.Sprintf("不支持的检查类型: %s", target.Type)
@@ -211,8 +278,8 @@ func (s *TelemetryService) CheckSingleTarget(ctx context.Context, target models.
 	return result, nil
 }
 
-// pingCheck PING检查（基于网络连通性的检查，不是真正的ICMP ping）
-func (s *TelemetryService) pingCheck(ctx context.Context, target string) error {
+// pingCheck PING检查：发送 ICMP 回显请求并统计丢包，没有 ICMP 权限时回退到端口连通性检测（返回 nil 统计）
+func (s *TelemetryService) pingCheck(ctx context.Context, target string) (*icmpPingResult, error) {
 	host := target
 	
 	// 如果目标包含端口，提取主机部分（但ping不应该有端口）
@@ -224,9 +291,25 @@ func (s *TelemetryService) pingCheck(ctx context.Context, target string) error {
 		}
 	}
 	
-	log.Printf("🏓 开始PING检查（网络连通性测试）: %s", host)
+	log.Printf("🏓 开始PING检查: %s", host)
 	
-	// 由于Go程序通常无法发送ICMP包（需要特殊权限），我们使用多种方式测试连通性：
+	result, err := icmpPing(ctx, host, telemetryPingCount())
+	if errors.Is(err, errICMPUnavailable) {
+		log.Printf("⚠️ %v，回退到端口连通性检测: %s", err, host)
+		return nil, s.reachabilityCheck(ctx, host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result.received == 0 {
+		return result, fmt.Errorf("PING 无应答: %s", result.summary())
+	}
+	return result, nil
+}
+
+// reachabilityCheck 基于网络连通性的检查，用于无法发送 ICMP 时（需要非特权 ICMP 或 CAP_NET_RAW）
+func (s *TelemetryService) reachabilityCheck(ctx context.Context, host string) error {
+	// 无法发送ICMP包时，我们使用多种方式测试连通性：
 	// 1. 首先尝试DNS解析
 	// 2. 然后尝试常用端口的TCP连接
 	
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"smartdns-manager/config"
+	"smartdns-manager/database"
+	"smartdns-manager/models"
+)
+
+// 基线算法
+const (
+	TrafficBaselineZScore   = "zscore"
+	TrafficBaselineSeasonal = "seasonal"
+)
+
+// TrafficAnomalyService 节点查询量与解析失败率异常检测服务
+type TrafficAnomalyService struct {
+	db                  *gorm.DB
+	config              *config.Config
+	notificationService *NotificationService
+
+	mutex    sync.Mutex
+	alerting map[string]models.TrafficAnomaly // 当前处于告警状态的 节点|指标
+}
+
+// NewTrafficAnomalyService 创建查询量异常检测服务
+func NewTrafficAnomalyService(db *gorm.DB, config *config.Config) (*TrafficAnomalyService, error) {
+	return &TrafficAnomalyService{
+		db:                  db,
+		config:              config,
+		notificationService: NewNotificationService(),
+		alerting:            make(map[string]models.TrafficAnomaly),
+	}, nil
+}
+
+func (s *TrafficAnomalyService) applyDefaults(cfg *models.TrafficAnomalyConfig) error {
+	if cfg.Method == "" {
+		cfg.Method = TrafficBaselineZScore
+	}
+	if cfg.WindowMinutes <= 0 {
+		cfg.WindowMinutes = 15
+	}
+	if cfg.BaselineHours <= 0 {
+		cfg.BaselineHours = 24
+	}
+	if cfg.SeasonalDays <= 0 {
+		cfg.SeasonalDays = 7
+	}
+	if cfg.ZThreshold <= 0 {
+		cfg.ZThreshold = 3
+	}
+	if cfg.MinBaselineQPS <= 0 {
+		cfg.MinBaselineQPS = 0.1
+	}
+	if cfg.MinFailureChange <= 0 {
+		cfg.MinFailureChange = 0.05
+	}
+
+	switch cfg.Method {
+	case TrafficBaselineZScore:
+		if cfg.BaselineHours*60 < cfg.WindowMinutes*3 {
+			return fmt.Errorf("基线时长至少需要覆盖 3 个统计窗口")
+		}
+	case TrafficBaselineSeasonal:
+		if 1440%cfg.WindowMinutes != 0 {
+			return fmt.Errorf("seasonal 基线要求统计窗口能整除 1440 分钟")
+		}
+	default:
+		return fmt.Errorf("不支持的基线算法: %s（可选 zscore/seasonal）", cfg.Method)
+	}
+	return nil
+}
+
+// trafficBucket 单个统计窗口的查询数和失败数
+type trafficBucket struct {
+	total  uint64
+	failed uint64
+}
+
+// baselineIndexes 基线窗口的序号，序号 0 为当前窗口，越大越早
+func baselineIndexes(cfg models.TrafficAnomalyConfig) []int {
+	var indexes []int
+	if cfg.Method == TrafficBaselineSeasonal {
+		perDay := 1440 / cfg.WindowMinutes
+		for day := 1; day <= cfg.SeasonalDays; day++ {
+			indexes = append(indexes, day*perDay)
+		}
+		return indexes
+	}
+
+	for i := 1; i <= cfg.BaselineHours*60/cfg.WindowMinutes; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// loadBuckets 按窗口序号统计各节点的查询数和失败数（按 nxdomainCondition 判定失败）
+func (s *TrafficAnomalyService) loadBuckets(ctx context.Context, cfg models.TrafficAnomalyConfig, end time.Time, indexes []int) (map[uint]map[int]trafficBucket, error) {
+	window := time.Duration(cfg.WindowMinutes) * time.Minute
+
+	var where string
+	var args []interface{}
+	if cfg.Method == TrafficBaselineSeasonal {
+		// 只扫描当前窗口和过去每天同一时段，避免读取整段历史
+		ranges := make([]string, 0, len(indexes)+1)
+		for _, index := range append([]int{0}, indexes...) {
+			rangeEnd := end.Add(-time.Duration(index) * window)
+			ranges = append(ranges, "(timestamp >= ? AND timestamp < ?)")
+			args = append(args, rangeEnd.Add(-window), rangeEnd)
+		}
+		where = "(" + strings.Join(ranges, " OR ") + ")"
+	} else {
+		where = "timestamp >= ? AND timestamp < ?"
+		args = append(args, end.Add(-time.Duration(indexes[len(indexes)-1]+1)*window), end)
+	}
+	if len(cfg.NodeIDs) > 0 {
+		ids := make([]uint32, 0, len(cfg.NodeIDs))
+		for _, id := range cfg.NodeIDs {
+			ids = append(ids, uint32(id))
+		}
+		where += " AND node_id IN (?)"
+		args = append(args, ids)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	rows, err := database.CHConn.Query(queryCtx, fmt.Sprintf(`
+        SELECT node_id, intDiv(%d - toInt64(toUnixTimestamp(timestamp)) - 1, %d) AS idx,
+               count(), countIf(%s)
+        FROM dns_query_log WHERE %s
+        GROUP BY node_id, idx`, end.Unix(), int64(window.Seconds()), nxdomainCondition, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询节点查询量失败: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make(map[uint]map[int]trafficBucket)
+	for rows.Next() {
+		var nodeID uint32
+		var index int64
+		var bucket trafficBucket
+		if err := rows.Scan(&nodeID, &index, &bucket.total, &bucket.failed); err != nil {
+			log.Printf("⚠️ 扫描行失败: %v", err)
+			continue
+		}
+		if buckets[uint(nodeID)] == nil {
+			buckets[uint(nodeID)] = make(map[int]trafficBucket)
+		}
+		buckets[uint(nodeID)][int(index)] = bucket
+	}
+	return buckets, rows.Err()
+}
+
+// meanStdDev 计算均值和总体标准差
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+// DetectAnomalies 对比当前窗口与基线，返回异常列表和参与检测的节点（基线流量过低的节点不参与）
+func (s *TrafficAnomalyService) DetectAnomalies(ctx context.Context, cfg models.TrafficAnomalyConfig) ([]models.TrafficAnomaly, map[uint]bool, error) {
+	if database.CHConn == nil {
+		return nil, nil, fmt.Errorf("ClickHouse 未连接")
+	}
+	if err := s.applyDefaults(&cfg); err != nil {
+		return nil, nil, err
+	}
+
+	var nodes []models.Node
+	query := s.db.Select("id", "name")
+	if len(cfg.NodeIDs) > 0 {
+		query = query.Where("id IN ?", cfg.NodeIDs)
+	}
+	if err := query.Find(&nodes).Error; err != nil {
+		return nil, nil, fmt.Errorf("查询节点失败: %w", err)
+	}
+
+	indexes := baselineIndexes(cfg)
+	buckets, err := s.loadBuckets(ctx, cfg, time.Now().Truncate(time.Second), indexes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seconds := float64(cfg.WindowMinutes * 60)
+	anomalies := []models.TrafficAnomaly{}
+	checked := make(map[uint]bool)
+	for _, node := range nodes {
+		nodeBuckets := buckets[node.ID]
+
+		// 缺失的窗口按 0 次查询计入基线，失败率只统计有查询的窗口
+		qps := make([]float64, 0, len(indexes))
+		var ratios []float64
+		for _, index := range indexes {
+			bucket := nodeBuckets[index]
+			qps = append(qps, float64(bucket.total)/seconds)
+			if bucket.total > 0 {
+				ratios = append(ratios, float64(bucket.failed)/float64(bucket.total))
+			}
+		}
+
+		mean, std := meanStdDev(qps)
+		if mean < cfg.MinBaselineQPS {
+			continue
+		}
+		checked[node.ID] = true
+
+		current := nodeBuckets[0]
+		currentQPS := float64(current.total) / seconds
+		// 标准差过小时（流量非常平稳）以均值的 10% 兜底，避免轻微波动触发告警
+		std = math.Max(std, mean*0.1)
+		z := (currentQPS - mean) / std
+
+		anomaly := models.TrafficAnomaly{
+			NodeID:   node.ID,
+			NodeName: node.Name,
+			Metric:   models.TrafficMetricQPS,
+			Current:  currentQPS,
+			Baseline: mean,
+			StdDev:   std,
+			ZScore:   z,
+			Samples:  len(qps),
+		}
+		switch {
+		case current.total == 0:
+			anomaly.Reason = "查询量降为 0，上游或客户端可能已无法访问该节点"
+			anomalies = append(anomalies, anomaly)
+		case z <= -cfg.ZThreshold:
+			anomaly.Reason = "查询量明显低于基线"
+			anomalies = append(anomalies, anomaly)
+		case z >= cfg.ZThreshold:
+			anomaly.Reason = "查询量明显高于基线"
+			anomalies = append(anomalies, anomaly)
+		}
+
+		if current.total == 0 || len(ratios) == 0 {
+			continue
+		}
+		currentRatio := float64(current.failed) / float64(current.total)
+		ratioMean, ratioStd := meanStdDev(ratios)
+		ratioStd = math.Max(ratioStd, 0.01)
+		ratioZ := (currentRatio - ratioMean) / ratioStd
+		if ratioZ >= cfg.ZThreshold && currentRatio-ratioMean >= cfg.MinFailureChange {
+			anomalies = append(anomalies, models.TrafficAnomaly{
+				NodeID:   node.ID,
+				NodeName: node.Name,
+				Metric:   models.TrafficMetricFailureRatio,
+				Current:  currentRatio,
+				Baseline: ratioMean,
+				StdDev:   ratioStd,
+				ZScore:   ratioZ,
+				Samples:  len(ratios),
+				Reason:   "解析失败率明显高于基线",
+			})
+		}
+	}
+
+	return anomalies, checked, nil
+}
+
+// formatTrafficAnomaly 通知中的单行描述
+func formatTrafficAnomaly(a models.TrafficAnomaly) string {
+	if a.Metric == models.TrafficMetricFailureRatio {
+		return fmt.Sprintf("- 失败率 %.2f%%，基线 %.2f%%±%.2f%%，z=%.1f（%s）",
+			a.Current*100, a.Baseline*100, a.StdDev*100, a.ZScore, a.Reason)
+	}
+	return fmt.Sprintf("- 查询量 %.2f QPS，基线 %.2f±%.2f QPS，z=%.1f（%s）",
+		a.Current, a.Baseline, a.StdDev, a.ZScore, a.Reason)
+}
+
+// CheckAnomalies 检测异常并发送通知，同一节点同一指标持续异常只通知一次，恢复时再通知
+func (s *TrafficAnomalyService) CheckAnomalies(ctx context.Context, cfg models.TrafficAnomalyConfig) (string, error) {
+	if err := s.applyDefaults(&cfg); err != nil {
+		return "", err
+	}
+	anomalies, checked, err := s.DetectAnomalies(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	baseline := fmt.Sprintf("过去 %d 小时滚动基线", cfg.BaselineHours)
+	if cfg.Method == TrafficBaselineSeasonal {
+		baseline = fmt.Sprintf("过去 %d 天同一时段基线", cfg.SeasonalDays)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current := make(map[string]bool, len(anomalies))
+	newByNode := make(map[uint][]models.TrafficAnomaly)
+	var nodeOrder []uint
+	for _, a := range anomalies {
+		key := fmt.Sprintf("%d|%s", a.NodeID, a.Metric)
+		current[key] = true
+		if _, ok := s.alerting[key]; ok {
+			continue
+		}
+		s.alerting[key] = a
+		if _, ok := newByNode[a.NodeID]; !ok {
+			nodeOrder = append(nodeOrder, a.NodeID)
+		}
+		newByNode[a.NodeID] = append(newByNode[a.NodeID], a)
+	}
+
+	for _, nodeID := range nodeOrder {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		var lines []string
+		for _, a := range newByNode[nodeID] {
+			lines = append(lines, formatTrafficAnomaly(a))
+		}
+		content := fmt.Sprintf("节点 %s 最近 %d 分钟偏离%s：\n%s",
+			newByNode[nodeID][0].NodeName, cfg.WindowMinutes, baseline, strings.Join(lines, "\n"))
+		if err := s.notificationService.SendNotification(nodeID, "traffic_anomaly", "⚠️ 节点查询量异常", content); err != nil {
+			log.Printf("⚠️ 发送查询量异常通知失败: %v", err)
+		}
+	}
+
+	// 基线流量过低而未参与检测的节点保持告警状态，不视为恢复
+	recovered := 0
+	for key, a := range s.alerting {
+		if current[key] || !checked[a.NodeID] {
+			continue
+		}
+		recovered++
+		delete(s.alerting, key)
+
+		metric := "查询量"
+		if a.Metric == models.TrafficMetricFailureRatio {
+			metric = "解析失败率"
+		}
+		content := fmt.Sprintf("节点 %s 的%s已恢复到基线范围内", a.NodeName, metric)
+		if err := s.notificationService.SendNotification(a.NodeID, "traffic_anomaly", "✅ 节点查询量恢复", content); err != nil {
+			log.Printf("⚠️ 发送查询量恢复通知失败: %v", err)
+		}
+	}
+
+	return fmt.Sprintf("检测 %d 个节点（%s），%d 项异常（新增 %d 个节点告警），%d 项已恢复",
+		len(checked), baseline, len(anomalies), len(nodeOrder), recovered), nil
+}
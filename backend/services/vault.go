@@ -0,0 +1,273 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"smartdns-manager/config"
+	"smartdns-manager/models"
+)
+
+// VaultClient 通过 HTTP API 访问 HashiCorp Vault
+type VaultClient struct {
+	client *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time // AppRole 登录令牌的过期时间，静态令牌为零值
+}
+
+// NewVaultClient 创建 Vault 客户端
+func NewVaultClient() *VaultClient {
+	return &VaultClient{client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// VaultEnabled 是否配置了 Vault 地址和认证方式
+func VaultEnabled() bool {
+	cfg := config.GetConfig()
+	return cfg.VaultAddr != "" && (cfg.VaultToken != "" || (cfg.VaultRoleID != "" && cfg.VaultSecretID != ""))
+}
+
+// vaultResponse Vault API 通用响应
+type vaultResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []string        `json:"errors"`
+	Auth   *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+}
+
+// do 发送请求，token 为空时不携带令牌（用于登录）
+func (v *VaultClient) do(method, path, token string, body interface{}) (*vaultResponse, error) {
+	cfg := config.GetConfig()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(cfg.VaultAddr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), reader)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if cfg.VaultNamespace != "" {
+		req.Header.Set("X-Vault-Namespace", cfg.VaultNamespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Vault 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result vaultResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("解析 Vault 响应失败: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("Vault 返回 HTTP %d: %s", resp.StatusCode, strings.Join(result.Errors, "; "))
+		}
+		return nil, fmt.Errorf("Vault 返回 HTTP %d", resp.StatusCode)
+	}
+	return &result, nil
+}
+
+// clientToken 返回可用令牌，AppRole 令牌在过期前 30 秒重新登录
+func (v *VaultClient) clientToken() (string, error) {
+	cfg := config.GetConfig()
+	if cfg.VaultToken != "" {
+		return cfg.VaultToken, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.token != "" && time.Now().Before(v.tokenExpiry) {
+		return v.token, nil
+	}
+
+	result, err := v.do(http.MethodPost, "auth/approle/login", "", map[string]string{
+		"role_id":   cfg.VaultRoleID,
+		"secret_id": cfg.VaultSecretID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Vault AppRole 登录失败: %w", err)
+	}
+	if result.Auth == nil || result.Auth.ClientToken == "" {
+		return "", fmt.Errorf("Vault AppRole 登录未返回令牌")
+	}
+
+	// lease_duration 为 0 表示令牌不过期，仍每小时重新登录一次
+	ttl := time.Hour
+	if lease := time.Duration(result.Auth.LeaseDuration) * time.Second; lease > 0 {
+		ttl = lease - 30*time.Second
+		if ttl <= 0 {
+			ttl = lease / 2
+		}
+	}
+	v.token = result.Auth.ClientToken
+	v.tokenExpiry = time.Now().Add(ttl)
+	return v.token, nil
+}
+
+// ReadKV 读取 KV v2 密钥
+func (v *VaultClient) ReadKV(path string) (map[string]string, error) {
+	token, err := v.clientToken()
+	if err != nil {
+		return nil, err
+	}
+
+	mount := strings.Trim(config.GetConfig().VaultKVMount, "/")
+	result, err := v.do(http.MethodGet, fmt.Sprintf("%s/data/%s", mount, strings.Trim(path, "/")), token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(result.Data, &payload); err != nil {
+		return nil, fmt.Errorf("解析 Vault 密钥失败: %w", err)
+	}
+
+	values := make(map[string]string, len(payload.Data))
+	for key, value := range payload.Data {
+		if s, ok := value.(string); ok {
+			values[key] = s
+		}
+	}
+	return values, nil
+}
+
+// SignSSHKey 使用 SSH 引擎为公钥签发用户证书
+func (v *VaultClient) SignSSHKey(role string, publicKey ssh.PublicKey, principal string) (*ssh.Certificate, error) {
+	token, err := v.clientToken()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.GetConfig()
+	mount := strings.Trim(cfg.VaultSSHMount, "/")
+	result, err := v.do(http.MethodPost, fmt.Sprintf("%s/sign/%s", mount, role), token, map[string]string{
+		"public_key":       string(ssh.MarshalAuthorizedKey(publicKey)),
+		"valid_principals": principal,
+		"cert_type":        "user",
+		"ttl":              cfg.VaultSSHCertTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		SignedKey string `json:"signed_key"`
+	}
+	if err := json.Unmarshal(result.Data, &payload); err != nil || payload.SignedKey == "" {
+		return nil, fmt.Errorf("Vault 未返回签名证书")
+	}
+
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(payload.SignedKey))
+	if err != nil {
+		return nil, fmt.Errorf("解析 SSH 证书失败: %w", err)
+	}
+	cert, ok := parsed.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("Vault 返回的不是 SSH 证书")
+	}
+	return cert, nil
+}
+
+// vaultCredentialProvider 连接时从 Vault 获取凭据：优先使用临时密钥 + 短期证书，其次是 KV 中的私钥或密码
+type vaultCredentialProvider struct {
+	client *VaultClient
+}
+
+func init() {
+	RegisterSSHCredentialProvider(models.CredentialSourceVault, &vaultCredentialProvider{client: NewVaultClient()})
+}
+
+func (p *vaultCredentialProvider) AuthMethods(node *models.Node) ([]ssh.AuthMethod, error) {
+	if !VaultEnabled() {
+		return nil, fmt.Errorf("节点 %s 使用 Vault 凭据，但未配置 VAULT_ADDR 和认证方式", node.Name)
+	}
+	if node.VaultSSHRole == "" && node.VaultSecretPath == "" {
+		return nil, fmt.Errorf("节点 %s 未配置 Vault SSH 角色或密钥路径", node.Name)
+	}
+
+	// 同名认证方式只会尝试一次，证书和私钥需要合并到同一个 PublicKeys 中
+	var signers []ssh.Signer
+	var password string
+	if node.VaultSSHRole != "" {
+		signer, err := p.certificateSigner(node)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+
+	if node.VaultSecretPath != "" {
+		secret, err := p.client.ReadKV(node.VaultSecretPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取 Vault 密钥 %s 失败: %w", node.VaultSecretPath, err)
+		}
+		if key := secret["private_key"]; key != "" {
+			signer, err := ssh.ParsePrivateKey([]byte(key))
+			if err != nil {
+				return nil, fmt.Errorf("解析 Vault 中的私钥失败: %w", err)
+			}
+			signers = append(signers, signer)
+		}
+		password = secret["password"]
+		if len(signers) == 0 && password == "" {
+			return nil, fmt.Errorf("Vault 密钥 %s 中没有 private_key 或 password", node.VaultSecretPath)
+		}
+	}
+
+	var auth []ssh.AuthMethod
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+	if password != "" {
+		auth = append(auth, ssh.Password(password))
+	}
+	return auth, nil
+}
+
+// certificateSigner 生成一次性 ed25519 密钥并请求 Vault 签发证书，私钥只保存在内存中
+func (p *vaultCredentialProvider) certificateSigner(node *models.Node) (ssh.Signer, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成临时密钥失败: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := p.client.SignSSHKey(node.VaultSSHRole, signer.PublicKey(), node.Username)
+	if err != nil {
+		return nil, fmt.Errorf("Vault 签发 SSH 证书失败: %w", err)
+	}
+	return ssh.NewCertSigner(cert, signer)
+}
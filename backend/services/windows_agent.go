@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"smartdns-manager/models"
+)
+
+// windowsInstallScriptURL Windows 节点的 Agent 安装脚本
+const windowsInstallScriptURL = "https://raw.githubusercontent.com/almightyyantao/smartdns-manager/main/agent/install.ps1"
+
+// windowsRemote 在 Windows 节点上执行 PowerShell 脚本
+type windowsRemote interface {
+	RunPowerShell(script string, timeout time.Duration) (string, error)
+	Close() error
+}
+
+// newWindowsRemote 按节点的连接方式连接 Windows 节点，默认通过 OpenSSH Server
+func newWindowsRemote(node *models.Node) (windowsRemote, error) {
+	if node.AgentTransport == models.AgentTransportWinRM {
+		return NewWinRMClient(node)
+	}
+	client, err := NewSSHClient(node)
+	if err != nil {
+		return nil, fmt.Errorf("SSH连接失败: %w", err)
+	}
+	return &sshPowerShell{client: client}, nil
+}
+
+// sshPowerShell 通过 Windows 的 OpenSSH Server 执行 PowerShell，默认 shell 为 cmd.exe 或 PowerShell 都可以
+type sshPowerShell struct {
+	client *SSHClient
+}
+
+func (s *sshPowerShell) RunPowerShell(script string, timeout time.Duration) (string, error) {
+	return s.client.ExecuteCommandWithTimeout("powershell.exe "+powerShellArgs(script), timeout)
+}
+
+func (s *sshPowerShell) Close() error {
+	return s.client.Close()
+}
+
+// powerShellArgs 以 -EncodedCommand 传入脚本，避免 cmd.exe 和 PowerShell 的引号转义问题，输出统一为 UTF-8
+func powerShellArgs(script string) string {
+	script = "$ProgressPreference = 'SilentlyContinue'\n[Console]::OutputEncoding = [Text.Encoding]::UTF8\n" + script
+	units := utf16.Encode([]rune(script))
+	buf := make([]byte, len(units)*2)
+	for i, u := range units {
+		buf[2*i] = byte(u)
+		buf[2*i+1] = byte(u >> 8)
+	}
+	return "-NoProfile -NonInteractive -ExecutionPolicy Bypass -EncodedCommand " + base64.StdEncoding.EncodeToString(buf)
+}
+
+// psQuote PowerShell 单引号字符串
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// windowsInstallScript 下载 install.ps1 并以指定参数执行
+func windowsInstallScript(proxyURL string, args []string) string {
+	proxyArg := ""
+	if proxyURL != "" {
+		proxyArg = " -Proxy " + psQuote(proxyURL)
+	}
+	return fmt.Sprintf(`$ErrorActionPreference = 'Stop'
+[Net.ServicePointManager]::SecurityProtocol = [Net.SecurityProtocolType]::Tls12
+$script = (Invoke-RestMethod -UseBasicParsing -Uri %s%s).TrimStart([char]0xFEFF)
+& ([scriptblock]::Create($script)) %s
+`, psQuote(windowsInstallScriptURL), proxyArg, strings.Join(args, " "))
+}
+
+// deployWindowsAgent 在 Windows 节点上安装 Agent 并注册为服务
+func (s *AgentDeployService) deployWindowsAgent(ctx context.Context, node *models.Node, req *models.DeployAgentRequest) (*DeployResponse, error) {
+	// Invoke-WebRequest 只支持 HTTP 代理
+	proxyURL := ""
+	if req.ProxyHost != "" && req.ProxyPort > 0 {
+		if req.ProxyType != "" && req.ProxyType != "http" {
+			return nil, fmt.Errorf("Windows 节点只支持 HTTP 代理")
+		}
+		proxyURL = s.buildProxyURL(&models.ProxyConfig{
+			ProxyType: "http",
+			ProxyHost: req.ProxyHost,
+			ProxyPort: req.ProxyPort,
+			ProxyUser: req.ProxyUser,
+			ProxyPass: req.ProxyPass,
+		})
+	}
+
+	remote, err := newWindowsRemote(node)
+	if err != nil {
+		return nil, err
+	}
+	defer remote.Close()
+
+	controlToken := ""
+	if req.ControlURL != "" || req.IngestURL != "" {
+		controlToken, err = IssueAgentControlToken(node)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	args := []string{
+		fmt.Sprintf("-NodeId %d", req.NodeID),
+		"-NodeName " + psQuote(node.Name),
+		"-ClickHouseHost " + psQuote(req.ClickHouseHost),
+		fmt.Sprintf("-ClickHousePort %d", req.ClickHousePort),
+		"-ClickHouseDb " + psQuote(req.ClickHouseDB),
+		"-ClickHouseUser " + psQuote(req.ClickHouseUser),
+	}
+	if req.ClickHousePassword != "" {
+		args = append(args, "-ClickHousePassword "+psQuote(req.ClickHousePassword))
+	}
+	if req.LogFilePath != "" {
+		args = append(args, "-LogFile "+psQuote(req.LogFilePath))
+	}
+	if req.ServiceManager != "" {
+		args = append(args, "-ServiceManager "+psQuote(req.ServiceManager))
+	}
+	if controlToken != "" {
+		if req.ControlURL != "" {
+			args = append(args, "-ControlUrl "+psQuote(req.ControlURL))
+		}
+		args = append(args, "-ControlToken "+psQuote(controlToken))
+	}
+	if req.IngestURL != "" {
+		args = append(args, "-IngestUrl "+psQuote(req.IngestURL))
+	}
+	if req.KafkaBrokers != "" {
+		args = append(args, "-KafkaBrokers "+psQuote(req.KafkaBrokers))
+		if req.KafkaCluster != "" {
+			args = append(args, "-KafkaCluster "+psQuote(req.KafkaCluster))
+		}
+		if req.KafkaTopic != "" {
+			args = append(args, "-KafkaTopic "+psQuote(req.KafkaTopic))
+		}
+		if req.KafkaFormat != "" {
+			args = append(args, "-KafkaFormat "+psQuote(req.KafkaFormat))
+		}
+	}
+	if req.LokiURL != "" {
+		args = append(args, "-LokiUrl "+psQuote(req.LokiURL))
+		if req.LokiLabels != "" {
+			args = append(args, "-LokiLabels "+psQuote(req.LokiLabels))
+		}
+		if req.LokiTenant != "" {
+			args = append(args, "-LokiTenant "+psQuote(req.LokiTenant))
+		}
+	}
+	if req.ElasticsearchURL != "" {
+		args = append(args, "-ElasticsearchUrl "+psQuote(req.ElasticsearchURL))
+		if req.ElasticsearchIndex != "" {
+			args = append(args, "-ElasticsearchIndex "+psQuote(req.ElasticsearchIndex))
+		}
+		if req.ElasticsearchAPIKey != "" {
+			args = append(args, "-ElasticsearchApiKey "+psQuote(req.ElasticsearchAPIKey))
+		}
+	}
+
+	slog.InfoContext(ctx, "在 Windows 节点上执行 Agent 安装脚本", "node", node.Name, "transport", windowsTransportName(node))
+	output, err := remote.RunPowerShell(windowsInstallScript(proxyURL, args), s.sshTimeout)
+	if err != nil && output == "" {
+		return nil, fmt.Errorf("安装失败: %w", err)
+	}
+
+	response := &DeployResponse{
+		Success:     err == nil && strings.Contains(output, "安装成功"),
+		Message:     "Agent 部署完成",
+		Output:      strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n"),
+		InstallPath: `%ProgramFiles%\smartdns-log-agent`,
+		ServiceName: "smartdns-log-agent",
+		ConfigPath:  `%ProgramData%\smartdns-log-agent\config.env`,
+	}
+	if !response.Success {
+		response.Message = "Agent 安装失败，请查看安装输出"
+	}
+	return response, nil
+}
+
+// windowsTransportName 日志中显示的连接方式
+func windowsTransportName(node *models.Node) string {
+	if node.AgentTransport == models.AgentTransportWinRM {
+		return "WinRM"
+	}
+	return "SSH"
+}
+
+// windowsAgentStatusScript 输出 KEY=VALUE 格式的服务状态，服务不存在时输出 NOT_INSTALLED
+const windowsAgentStatusScript = `$service = Get-Service -Name smartdns-log-agent -ErrorAction SilentlyContinue
+if (-not $service) { 'NOT_INSTALLED'; exit 0 }
+"STATUS=$($service.Status)"
+"START_TYPE=$($service.StartType)"
+$exe = Join-Path $env:ProgramFiles 'smartdns-log-agent\smartdns-log-agent.exe'
+if (Test-Path $exe) { "VERSION=$(& $exe -version)" }
+$process = Get-Process -Name smartdns-log-agent -ErrorAction SilentlyContinue | Select-Object -First 1
+if ($process) { "PROCESS=PID $($process.Id), 内存 $([math]::Round($process.WorkingSet64 / 1MB, 1)) MB, 启动于 $($process.StartTime)" }
+`
+
+// checkWindowsAgentStatus 查询 Windows 服务状态
+func (s *AgentDeployService) checkWindowsAgentStatus(node *models.Node) (*AgentStatus, error) {
+	status := &AgentStatus{
+		DeployMode: models.AgentDeployModeWindows,
+		LastCheck:  time.Now().Format("2006-01-02 15:04:05"),
+	}
+
+	remote, err := newWindowsRemote(node)
+	if err != nil {
+		status.ErrorMessage = err.Error()
+		return status, nil
+	}
+	defer remote.Close()
+
+	output, err := remote.RunPowerShell(windowsAgentStatusScript, 60*time.Second)
+	if err != nil {
+		status.ErrorMessage = "查询服务状态失败: " + err.Error()
+		return status, nil
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "NOT_INSTALLED":
+			return status, nil
+		case "STATUS":
+			status.Installed = true
+			status.Running = value == "Running"
+		case "START_TYPE":
+			status.AutoStart = value == "Automatic"
+		case "VERSION":
+			status.Version = strings.TrimSpace(value)
+		case "PROCESS":
+			status.ProcessInfo = value
+		}
+	}
+	return status, nil
+}
+
+// uninstallWindowsAgent 使用 install.ps1 -Uninstall 删除服务、程序和配置
+func (s *AgentDeployService) uninstallWindowsAgent(ctx context.Context, node *models.Node) error {
+	remote, err := newWindowsRemote(node)
+	if err != nil {
+		return err
+	}
+	defer remote.Close()
+
+	output, err := remote.RunPowerShell(windowsInstallScript("", []string{"-Uninstall"}), s.sshTimeout)
+	if err != nil {
+		return fmt.Errorf("卸载失败: %w %s", err, strings.TrimSpace(output))
+	}
+	slog.InfoContext(ctx, "Agent 卸载输出", "node", node.Name, "output", output)
+	return nil
+}
+
+// getWindowsAgentLogs 读取最新的 Agent 日志文件末尾，Windows 服务没有 journald
+func (s *AgentDeployService) getWindowsAgentLogs(node *models.Node, lines string) ([]string, error) {
+	n, err := strconv.Atoi(lines)
+	if err != nil || n <= 0 {
+		n = 100
+	}
+
+	remote, err := newWindowsRemote(node)
+	if err != nil {
+		return nil, err
+	}
+	defer remote.Close()
+
+	script := fmt.Sprintf(`$dir = Join-Path $env:ProgramData 'smartdns-log-agent\logs'
+$file = Get-ChildItem -Path $dir -Filter 'smartdns-agent-*.log' -ErrorAction SilentlyContinue | Sort-Object LastWriteTime | Select-Object -Last 1
+if (-not $file) { '未找到 Agent 日志'; exit 0 }
+Get-Content -Path $file.FullName -Tail %d -Encoding UTF8
+`, n)
+	output, err := remote.RunPowerShell(script, 60*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("获取日志失败: %w", err)
+	}
+	return strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n"), nil
+}
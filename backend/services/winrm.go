@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-ntlmssp"
+
+	"smartdns-manager/models"
+)
+
+// WinRM (WS-Management) 的 SOAP 命名空间和动作
+const (
+	winrmResourceCmd    = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/cmd"
+	winrmActionCreate   = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Create"
+	winrmActionDelete   = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Delete"
+	winrmActionCommand  = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Command"
+	winrmActionReceive  = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Receive"
+	winrmActionSignal   = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Signal"
+	winrmSignalTerm     = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/signal/terminate"
+	winrmCommandDone    = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/CommandState/Done"
+	winrmOperationLimit = 60 * time.Second
+)
+
+// WinRMClient 通过 WinRM 在 Windows 节点上执行命令，默认使用 HTTPS 5986 端口，优先 NTLM/Negotiate 认证。
+// 服务端只接受 Basic 认证时仅在 HTTPS 上发送，HTTP 连接只使用 NTLM，节点还需允许未加密传输
+type WinRMClient struct {
+	endpoint string
+	username string
+	password string
+	http     *http.Client
+	shellID  string
+}
+
+// NewWinRMClient 创建节点的 WinRM 客户端并打开远程 shell，密码来自节点或 Vault KV 中的 password
+func NewWinRMClient(node *models.Node) (*WinRMClient, error) {
+	password, err := winrmPassword(node)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, port := "https", node.WinRMPort
+	if !node.WinRMHTTPS {
+		scheme = "http"
+		if port == 0 {
+			port = 5985
+		}
+	} else if port == 0 {
+		port = 5986
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if node.WinRMHTTPS && node.WinRMInsecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	// Negotiator 先以 NTLM/Negotiate 完成认证，服务端只接受 Basic 时才回退发送 Basic 凭据
+	var roundTripper http.RoundTripper = transport
+	if !node.WinRMHTTPS {
+		roundTripper = winrmNoBasicOverHTTP{transport}
+	}
+
+	client := &WinRMClient{
+		endpoint: fmt.Sprintf("%s://%s:%d/wsman", scheme, node.Host, port),
+		username: node.Username,
+		password: password,
+		http: &http.Client{
+			Transport: ntlmssp.Negotiator{RoundTripper: roundTripper},
+			// 单次请求最长等待一个 Receive 操作超时
+			Timeout: winrmOperationLimit + 30*time.Second,
+		},
+	}
+
+	body, err := client.call(winrmActionCreate, "", `<w:OptionSet><w:Option Name="WINRS_NOPROFILE">TRUE</w:Option><w:Option Name="WINRS_CODEPAGE">65001</w:Option></w:OptionSet>`,
+		`<rsp:Shell><rsp:InputStreams>stdin</rsp:InputStreams><rsp:OutputStreams>stdout stderr</rsp:OutputStreams></rsp:Shell>`)
+	if err != nil {
+		return nil, fmt.Errorf("WinRM 连接失败: %w", err)
+	}
+
+	var created struct {
+		ShellID string `xml:"Body>Shell>ShellId"`
+	}
+	if err := xml.Unmarshal(body, &created); err != nil || created.ShellID == "" {
+		return nil, fmt.Errorf("WinRM 创建 shell 失败: 无法解析响应")
+	}
+	client.shellID = created.ShellID
+	return client, nil
+}
+
+// winrmNoBasicOverHTTP 拒绝通过 HTTP 明文发送 Basic 凭据，服务端未提供 NTLM/Negotiate 时连接失败
+type winrmNoBasicOverHTTP struct {
+	http.RoundTripper
+}
+
+func (t winrmNoBasicOverHTTP) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasPrefix(req.Header.Get("Authorization"), "Basic ") {
+		return nil, fmt.Errorf("WinRM 服务端只接受 Basic 认证，不允许通过 HTTP 明文发送凭据，请启用 HTTPS 或在节点上启用 Negotiate 认证")
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
+
+// winrmPassword WinRM 的 NTLM 和 Basic 认证都只能使用密码
+func winrmPassword(node *models.Node) (string, error) {
+	if node.CredentialSource != models.CredentialSourceVault {
+		if node.Password == "" {
+			return "", fmt.Errorf("WinRM 需要节点的登录密码")
+		}
+		return node.Password, nil
+	}
+
+	if !VaultEnabled() || node.VaultSecretPath == "" {
+		return "", fmt.Errorf("节点 %s 使用 Vault 凭据，但未配置 Vault 或密钥路径", node.Name)
+	}
+	secret, err := NewVaultClient().ReadKV(node.VaultSecretPath)
+	if err != nil {
+		return "", fmt.Errorf("读取 Vault 密钥 %s 失败: %w", node.VaultSecretPath, err)
+	}
+	if secret["password"] == "" {
+		return "", fmt.Errorf("Vault 密钥 %s 中没有 password，WinRM 只支持密码认证", node.VaultSecretPath)
+	}
+	return secret["password"], nil
+}
+
+// RunPowerShell 执行 PowerShell 脚本，返回标准输出和标准错误，退出码非 0 时返回错误
+func (c *WinRMClient) RunPowerShell(script string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	body, err := c.call(winrmActionCommand, c.shellID,
+		`<w:OptionSet><w:Option Name="WINRS_CONSOLEMODE_STDIN">TRUE</w:Option><w:Option Name="WINRS_SKIP_CMD_SHELL">FALSE</w:Option></w:OptionSet>`,
+		fmt.Sprintf(`<rsp:CommandLine><rsp:Command>powershell.exe</rsp:Command><rsp:Arguments>%s</rsp:Arguments></rsp:CommandLine>`,
+			xmlEscape(powerShellArgs(script))))
+	if err != nil {
+		return "", err
+	}
+	var started struct {
+		CommandID string `xml:"Body>CommandResponse>CommandId"`
+	}
+	if err := xml.Unmarshal(body, &started); err != nil || started.CommandID == "" {
+		return "", fmt.Errorf("WinRM 执行命令失败: 无法解析响应")
+	}
+
+	var output bytes.Buffer
+	for {
+		if ctx.Err() != nil {
+			c.call(winrmActionSignal, c.shellID, "",
+				fmt.Sprintf(`<rsp:Signal CommandId="%s"><rsp:Code>%s</rsp:Code></rsp:Signal>`, started.CommandID, winrmSignalTerm))
+			return output.String(), fmt.Errorf("命令执行超时（%v）", timeout)
+		}
+
+		body, err := c.call(winrmActionReceive, c.shellID, "",
+			fmt.Sprintf(`<rsp:Receive><rsp:DesiredStream CommandId="%s">stdout stderr</rsp:DesiredStream></rsp:Receive>`, started.CommandID))
+		if err != nil {
+			// 命令在 OperationTimeout 内没有输出时服务端返回超时错误，继续等待
+			if strings.Contains(err.Error(), "TimedOut") {
+				continue
+			}
+			return output.String(), err
+		}
+
+		var received struct {
+			Streams []struct {
+				Name  string `xml:"Name,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"Body>ReceiveResponse>Stream"`
+			State struct {
+				State    string `xml:"State,attr"`
+				ExitCode int    `xml:"ExitCode"`
+			} `xml:"Body>ReceiveResponse>CommandState"`
+		}
+		if err := xml.Unmarshal(body, &received); err != nil {
+			return output.String(), fmt.Errorf("解析 WinRM 输出失败: %w", err)
+		}
+		for _, stream := range received.Streams {
+			if data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(stream.Value)); err == nil {
+				output.Write(data)
+			}
+		}
+		if received.State.State == winrmCommandDone {
+			if received.State.ExitCode != 0 {
+				return output.String(), fmt.Errorf("命令退出码 %d", received.State.ExitCode)
+			}
+			return output.String(), nil
+		}
+	}
+}
+
+// Close 删除远程 shell
+func (c *WinRMClient) Close() error {
+	if c.shellID == "" {
+		return nil
+	}
+	_, err := c.call(winrmActionDelete, c.shellID, "", "")
+	c.shellID = ""
+	return err
+}
+
+// call 发送一次 WS-Management 请求，返回响应正文
+func (c *WinRMClient) call(action, shellID, options, body string) ([]byte, error) {
+	selector := ""
+	if shellID != "" {
+		selector = fmt.Sprintf(`<w:SelectorSet><w:Selector Name="ShellId">%s</w:Selector></w:SelectorSet>`, shellID)
+	}
+	envelope := fmt.Sprintf(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:w="http://schemas.dmtf.org/wbem/wsman/1/wsman.xsd" xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell">`+
+		`<s:Header>`+
+		`<a:To>%s</a:To>`+
+		`<a:ReplyTo><a:Address s:mustUnderstand="true">http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</a:Address></a:ReplyTo>`+
+		`<w:ResourceURI s:mustUnderstand="true">%s</w:ResourceURI>`+
+		`<a:Action s:mustUnderstand="true">%s</a:Action>`+
+		`<w:MaxEnvelopeSize s:mustUnderstand="true">153600</w:MaxEnvelopeSize>`+
+		`<a:MessageID>uuid:%s</a:MessageID>`+
+		`<w:OperationTimeout>PT%dS</w:OperationTimeout>`+
+		`%s%s`+
+		`</s:Header>`+
+		`<s:Body>%s</s:Body>`+
+		`</s:Envelope>`,
+		c.endpoint, winrmResourceCmd, action, newMessageID(), int(winrmOperationLimit/time.Second), selector, options, body)
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, strings.NewReader(envelope))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml;charset=UTF-8")
+	// 凭据交给 Negotiator 转换为 NTLM/Negotiate 认证
+	req.SetBasicAuth(c.username, c.password)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, fmt.Errorf("WinRM 认证失败，请确认用户名密码正确（域账号使用 DOMAIN\\user 格式）")
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("WinRM 请求失败 (HTTP %d): %s", resp.StatusCode, winrmFaultMessage(data))
+	}
+	return data, nil
+}
+
+// winrmFaultMessage 提取 SOAP 错误中的说明，超时错误保留 TimedOut 标记
+func winrmFaultMessage(data []byte) string {
+	var fault struct {
+		Subcode string `xml:"Body>Fault>Code>Subcode>Value"`
+		Reason  string `xml:"Body>Fault>Reason>Text"`
+	}
+	if err := xml.Unmarshal(data, &fault); err != nil || (fault.Subcode == "" && fault.Reason == "") {
+		return strings.TrimSpace(string(data))
+	}
+	return strings.TrimSpace(fault.Subcode + " " + fault.Reason)
+}
+
+func newMessageID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func xmlEscape(s string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
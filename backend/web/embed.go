@@ -0,0 +1,22 @@
+//go:build embedui
+
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// dist 前端构建产物，由 build-standalone.sh 复制 ui/build 后使用 -tags embedui 编译
+//
+//go:embed all:dist
+var dist embed.FS
+
+// Assets 返回内嵌的前端静态文件
+func Assets() fs.FS {
+	assets, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil
+	}
+	return assets
+}
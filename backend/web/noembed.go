@@ -0,0 +1,10 @@
+//go:build !embedui
+
+package web
+
+import "io/fs"
+
+// Assets 未内嵌前端时返回 nil，前端由 Nginx 等单独提供
+func Assets() fs.FS {
+	return nil
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Enabled 是否内嵌了前端静态文件
+func Enabled() bool {
+	return Assets() != nil
+}
+
+// Register 在未匹配的路由上提供前端页面，前端路由统一回退到 index.html
+func Register(r *gin.Engine) {
+	assets := Assets()
+	if assets == nil {
+		return
+	}
+	fileServer := http.FileServer(http.FS(assets))
+
+	r.NoRoute(func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if strings.HasPrefix(path, "/api/") || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "接口不存在",
+			})
+			return
+		}
+
+		if name := strings.TrimPrefix(path, "/"); name != "" {
+			if info, err := fs.Stat(assets, name); err == nil && !info.IsDir() {
+				// 带哈希的构建产物可长期缓存
+				if strings.HasPrefix(name, "static/") {
+					c.Header("Cache-Control", "public, max-age=31536000, immutable")
+				}
+				fileServer.ServeHTTP(c.Writer, c.Request)
+				return
+			}
+		}
+
+		c.Header("Cache-Control", "no-cache")
+		c.FileFromFS("/", http.FS(assets))
+	})
+}
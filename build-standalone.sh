@@ -0,0 +1,26 @@
+#!/bin/bash
+# build-standalone.sh
+# 构建内嵌前端的单文件后端程序，适用于不使用 Docker 的裸机部署
+
+set -e
+
+ROOT_DIR=$(cd "$(dirname "$0")" && pwd)
+OUTPUT=${1:-$ROOT_DIR/backend/smartdns-manager}
+
+echo "📦 Building frontend..."
+cd "$ROOT_DIR/ui"
+if [ ! -d node_modules ]; then
+    npm ci
+fi
+npm run build
+
+echo "📁 Copying frontend assets..."
+rm -rf "$ROOT_DIR/backend/web/dist"
+cp -r "$ROOT_DIR/ui/build" "$ROOT_DIR/backend/web/dist"
+
+echo "🔨 Building backend with embedded frontend..."
+cd "$ROOT_DIR/backend"
+CGO_ENABLED=1 go build -tags embedui -ldflags="-w -s" -o "$OUTPUT" .
+
+echo "✅ Done: $OUTPUT"
+echo "   Run '$OUTPUT help' to see available commands"
@@ -1,6 +1,6 @@
 import React from "react";
 import { BrowserRouter, Routes, Route, Navigate } from "react-router-dom";
-import { ConfigProvider, App as AntApp, Card } from "antd";
+import { ConfigProvider, App as AntApp, Card, Tabs } from "antd";
 import zhCN from "antd/locale/zh_CN";
 import dayjs from "dayjs";
 import "dayjs/locale/zh-cn";
@@ -17,13 +17,18 @@ import NodeConfig from "./pages/NodeConfig";
 import Settings from "./pages/Settings";
 import NotificationManager from "./components/Notification/NotificationManager";
 import DomainSetManager from "./components/DomainSet/DomainSetManager";
+import BlocklistSubscriptionManager from "./components/DomainSet/BlocklistSubscriptionManager";
 import DomainRuleManager from "./components/DomainRule/DomainRuleManager";
 import NameserverManager from "./components/Nameserver/NameserverManager";
 import GroupManager from "./components/GroupManager/GroupManager";
 import Backup from "./pages/Backup";
 import Logs from "./pages/Logs";
 import Tasks from "./pages/Tasks";
+import MaintenanceManager from "./components/Maintenance/MaintenanceManager";
+import ComplianceManager from "./components/Compliance/ComplianceManager";
+import BackgroundJobs from "./components/Jobs/BackgroundJobs";
 import Telemetry from "./pages/Telemetry";
+import SharedLogs from "./pages/SharedLogs";
 
 dayjs.locale("zh-cn");
 
@@ -42,6 +47,7 @@ function App() {
         <BrowserRouter>
           <Routes>
             <Route path="/login" element={<Login />} />
+            <Route path="/share/:token" element={<SharedLogs />} />
 
             <Route
               path="/"
@@ -63,7 +69,20 @@ function App() {
                 path="domain-sets"
                 element={
                   <Card title="域名集管理" bordered={false}>
-                    <DomainSetManager />
+                    <Tabs
+                      items={[
+                        {
+                          key: "sets",
+                          label: "域名集",
+                          children: <DomainSetManager />,
+                        },
+                        {
+                          key: "subscriptions",
+                          label: "屏蔽列表订阅",
+                          children: <BlocklistSubscriptionManager />,
+                        },
+                      ]}
+                    />
                   </Card>
                 }
               />
@@ -93,6 +112,30 @@ function App() {
               />
               <Route path="logs" element={<Logs />} />
               <Route path="tasks" element={<Tasks />} />
+              <Route
+                path="maintenance"
+                element={
+                  <Card title="维护窗口" bordered={false}>
+                    <MaintenanceManager />
+                  </Card>
+                }
+              />
+              <Route
+                path="compliance"
+                element={
+                  <Card title="配置合规" bordered={false}>
+                    <ComplianceManager />
+                  </Card>
+                }
+              />
+              <Route
+                path="jobs"
+                element={
+                  <Card title="后台任务" bordered={false}>
+                    <BackgroundJobs />
+                  </Card>
+                }
+              />
               <Route path="telemetry" element={<Telemetry />} />
             </Route>
             <Route path="*" element={<Navigate to="/" replace />} />
@@ -4,13 +4,29 @@ export * from "./modules/backups";
 export * from "./modules/addresses";
 export * from "./modules/servers";
 export * from "./modules/groups";
+export * from "./modules/groupBlocks";
 export * from "./modules/domainSets";
 export * from "./modules/domainRules";
 export * from "./modules/nameservers";
+export * from "./modules/clientRules";
 export * from "./modules/dashboard";
 export * from "./modules/sync";
 export * from "./modules/notifications";
 export * from './modules/logs';
 export * from './modules/agent';
 export * from './modules/databaseBackup';
-export * from './modules/scheduler';
\ No newline at end of file
+export * from './modules/scheduler';
+export * from './modules/audit';
+export * from './modules/drift';
+export * from './modules/nodeFacts';
+export * from './modules/shareLinks';
+
+
+export * from './modules/changes';
+export * from './modules/blocklists';
+export * from './modules/maintenance';
+export * from './modules/compliance';
+export * from './modules/apiTokens';
+export * from './modules/fleetReports';export * from './modules/jobs';
+export * from './modules/system';
+export * from './modules/smartdnsVersions';
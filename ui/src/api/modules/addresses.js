@@ -5,4 +5,6 @@ export const addAddress = (data) => request.post("/addresses", data);
 export const updateAddress = (id, data) => request.put(`/addresses/${id}`, data);
 export const deleteAddress = (id) => request.delete(`/addresses/${id}`);
 export const batchAddAddresses = (data) => request.post("/addresses/batch", data);
-export const importAddresses = (data) => request.post("/addresses/import", data);
\ No newline at end of file
+export const importAddresses = (data) => request.post("/addresses/import", data);
+export const previewImportAddresses = (data) =>
+  request.post("/addresses/import/preview", data);
@@ -34,6 +34,30 @@ export const getAgentLogs = (nodeId, params) => {
   });
 };
 
+// 获取控制通道连接状态
+export const getAgentControlStatus = (nodeId) => {
+  return request({
+    url: `/nodes/${nodeId}/agent/control`,
+    method: 'GET',
+  });
+};
+
+// 生成控制令牌，明文只返回一次
+export const createAgentControlToken = (nodeId) => {
+  return request({
+    url: `/nodes/${nodeId}/agent/control/token`,
+    method: 'POST',
+  });
+};
+
+// 吊销控制令牌
+export const revokeAgentControlToken = (nodeId) => {
+  return request({
+    url: `/nodes/${nodeId}/agent/control/token`,
+    method: 'DELETE',
+  });
+};
+
 // 重启 Agent
 export const restartAgent = (nodeId) => {
   return request({
@@ -51,6 +75,23 @@ export const updateAgentConfig = (nodeId, config) => {
   });
 };
 
+// 获取 Agent 日志过滤规则
+export const getAgentLogFilter = (nodeId) => {
+  return request({
+    url: `/nodes/${nodeId}/agent/filter`,
+    method: 'GET',
+  });
+};
+
+// 保存并下发 Agent 日志过滤规则
+export const updateAgentLogFilter = (nodeId, rules) => {
+  return request({
+    url: `/nodes/${nodeId}/agent/filter`,
+    method: 'PUT',
+    data: rules,
+  });
+};
+
 export const startAgentCollection = (nodeHost, port = 8888) => {
   return request({
     url: `http://${nodeHost}:${port}/api/v1/start`,
@@ -65,4 +106,22 @@ export const stopAgentCollection = (nodeHost, port = 8888) => {
     method: 'POST',
     timeout: 10000,
   });
-};
\ No newline at end of file
+};
+
+// 日志写入缺失报告
+export const getIngestGaps = (nodeId, params) => {
+  return request({
+    url: `/nodes/${nodeId}/ingest-gaps`,
+    method: 'GET',
+    params,
+  });
+};
+
+// 补读缺失区间，gaps 为空时补读全部
+export const rereadIngestGaps = (nodeId, gaps = []) => {
+  return request({
+    url: `/nodes/${nodeId}/ingest-gaps/reread`,
+    method: 'POST',
+    data: { gaps },
+  });
+};
@@ -0,0 +1,5 @@
+import request from "../../utils/request";
+
+export const getAPITokens = () => request.get("/tokens");
+export const createAPIToken = (data) => request.post("/tokens", data);
+export const revokeAPIToken = (id) => request.delete(`/tokens/${id}`);
@@ -0,0 +1,8 @@
+import request from "../../utils/request";
+
+export const getAuditLogs = (params) => request.get("/audit-logs", { params });
+export const getUserActivity = (params) =>
+  request.get("/audit-logs/activity", { params });
+// entity: addresses / servers / domain-sets / domain-rules / nameservers / client-rules / group-blocks
+export const getEntityHistory = (entity, id) =>
+  request.get(`/${entity}/${id}/history`);
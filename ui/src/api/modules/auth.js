@@ -2,4 +2,10 @@ import request from "../../utils/request";
 
 export const login = (data) => request.post("/login", data);
 export const register = (data) => request.post("/register", data);
-export const getCurrentUser = () => request.get("/user/current");
\ No newline at end of file
+export const getCurrentUser = () => request.get("/user/current");
+// 单点登录
+export const getSSOConfig = () => request.get("/auth/sso");
+export const exchangeSSOCode = (code) => request.post("/auth/sso/exchange", { code });
+export const webhookTokenLogin = (token) => request.post("/auth/webhook/token", { token });
+export const getOIDCLoginURL = () =>
+  `${process.env.REACT_APP_API_BASE_URL || "/api/v1"}/auth/oidc/login`;
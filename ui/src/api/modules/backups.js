@@ -18,4 +18,10 @@ export const deleteNodeBackup = (nodeId, data) =>
 export const downloadBackup = (nodeId, backupId) => {
   const url = `/nodes/${nodeId}/backups/download?backup_id=${backupId}`;
   return request.get(url, { responseType: "blob" });
-};
\ No newline at end of file
+};
+
+export const getNodeBackupRetention = (nodeId) =>
+  request.get(`/nodes/${nodeId}/backups/retention`);
+
+export const updateNodeBackupRetention = (nodeId, data) =>
+  request.put(`/nodes/${nodeId}/backups/retention`, data);
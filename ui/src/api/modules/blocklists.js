@@ -0,0 +1,14 @@
+import request from "../../utils/request";
+
+export const getBlocklistSubscriptions = () =>
+  request.get("/blocklist-subscriptions");
+export const addBlocklistSubscription = (data) =>
+  request.post("/blocklist-subscriptions", data);
+export const updateBlocklistSubscription = (id, data) =>
+  request.put(`/blocklist-subscriptions/${id}`, data);
+export const deleteBlocklistSubscription = (id) =>
+  request.delete(`/blocklist-subscriptions/${id}`);
+export const refreshBlocklistSubscription = (id, force = false) =>
+  request.post(`/blocklist-subscriptions/${id}/refresh`, null, {
+    params: { force },
+  });
@@ -0,0 +1,8 @@
+import request from "../../utils/request";
+
+export const createChangeSet = (data) => request.post("/changes", data);
+export const getChangeSets = (params) => request.get("/changes", { params });
+export const getChangeSet = (id) => request.get(`/changes/${id}`);
+export const applyChangeSet = (id) => request.post(`/changes/${id}/apply`);
+export const retryChangeSet = (id) => request.post(`/changes/${id}/retry`);
+export const discardChangeSet = (id) => request.delete(`/changes/${id}`);
@@ -0,0 +1,8 @@
+import request from "../../utils/request";
+
+export const getClientRules = (params) =>
+  request.get("/client-rules", { params });
+export const addClientRule = (data) => request.post("/client-rules", data);
+export const updateClientRule = (id, data) =>
+  request.put(`/client-rules/${id}`, data);
+export const deleteClientRule = (id) => request.delete(`/client-rules/${id}`);
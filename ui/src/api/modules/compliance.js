@@ -0,0 +1,14 @@
+import request from "../../utils/request";
+
+export const getComplianceSummary = () => request.get("/compliance/summary");
+export const getComplianceViolations = (params) =>
+  request.get("/compliance/violations", { params });
+export const getCompliancePolicies = () => request.get("/compliance/policies");
+export const addCompliancePolicy = (data) =>
+  request.post("/compliance/policies", data);
+export const updateCompliancePolicy = (id, data) =>
+  request.put(`/compliance/policies/${id}`, data);
+export const deleteCompliancePolicy = (id) =>
+  request.delete(`/compliance/policies/${id}`);
+export const checkNodeCompliance = (nodeId) =>
+  request.post(`/nodes/${nodeId}/compliance-check`);
@@ -84,4 +84,45 @@ export const testS3Connection = (data) => {
     method: 'post',
     data
   });
-};
\ No newline at end of file
+};
+// 获取加密密钥列表
+export const getBackupKeys = () => {
+  return request({
+    url: '/database-backup/keys',
+    method: 'get'
+  });
+};
+
+// 创建加密密钥
+export const createBackupKey = (data) => {
+  return request({
+    url: '/database-backup/keys',
+    method: 'post',
+    data
+  });
+};
+
+// 删除加密密钥
+export const deleteBackupKey = (id) => {
+  return request({
+    url: `/database-backup/keys/${id}`,
+    method: 'delete'
+  });
+};
+
+// 轮换加密密钥
+export const rotateBackupKey = (id, data = {}) => {
+  return request({
+    url: `/database-backup/keys/${id}/rotate`,
+    method: 'post',
+    data
+  });
+};
+
+// 获取加密密钥依赖情况
+export const getBackupKeyUsage = (id) => {
+  return request({
+    url: `/database-backup/keys/${id}/usage`,
+    method: 'get'
+  });
+};
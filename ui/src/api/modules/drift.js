@@ -0,0 +1,12 @@
+import request from "../../utils/request";
+
+export const getDriftReports = (params) =>
+  request.get("/drift-reports", { params });
+export const getDriftReport = (id) => request.get(`/drift-reports/${id}`);
+export const checkNodeDrift = (nodeId) =>
+  request.post(`/nodes/${nodeId}/drift-check`);
+export const getNodeConfigSnapshots = (nodeId, params) =>
+  request.get(`/nodes/${nodeId}/config-snapshots`, { params });
+export const captureNodeConfigSnapshot = (nodeId) =>
+  request.post(`/nodes/${nodeId}/config-snapshots`);
+export const getConfigSnapshot = (id) => request.get(`/config-snapshots/${id}`);
@@ -0,0 +1,19 @@
+import request from "../../utils/request";
+
+export const getFleetReport = (params) =>
+  request.get("/reports/fleet", { params });
+export const createFleetReport = (data) => request.post("/reports/fleet", data);
+export const getFleetReportHistory = (params) =>
+  request.get("/reports/fleet/history", { params });
+export const getSavedFleetReport = (id) =>
+  request.get(`/reports/fleet/history/${id}`);
+export const exportFleetReport = (params) =>
+  request.get("/reports/fleet", {
+    params: { ...params, format: "csv" },
+    responseType: "blob",
+  });
+export const exportSavedFleetReport = (id) =>
+  request.get(`/reports/fleet/history/${id}`, {
+    params: { format: "csv" },
+    responseType: "blob",
+  });
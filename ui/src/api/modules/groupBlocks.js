@@ -0,0 +1,12 @@
+import request from "../../utils/request";
+
+export const getGroupBlocks = () => request.get("/group-blocks");
+export const getGroupBlock = (id) => request.get(`/group-blocks/${id}`);
+export const addGroupBlock = (data) => request.post("/group-blocks", data);
+export const updateGroupBlock = (id, data) =>
+  request.put(`/group-blocks/${id}`, data);
+export const deleteGroupBlock = (id) => request.delete(`/group-blocks/${id}`);
+export const previewGroupBlock = (data) =>
+  request.post("/group-blocks/preview", data);
+export const importGroupBlocks = (nodeId) =>
+  request.post(`/group-blocks/import/${nodeId}`);
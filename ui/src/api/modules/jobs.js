@@ -0,0 +1,6 @@
+import request from "../../utils/request";
+
+export const getBackgroundJobs = (params) => request.get("/jobs", { params });
+export const getBackgroundJob = (id) => request.get(`/jobs/${id}`);
+export const retryBackgroundJob = (id) => request.post(`/jobs/${id}/retry`);
+export const cancelBackgroundJob = (id) => request.post(`/jobs/${id}/cancel`);
@@ -33,6 +33,14 @@ export const getDNSLogs = (params) => {
   });
 };
 
+// 获取查询类型编号与名称
+export const getQueryTypes = () => {
+  return request({
+    url: '/dns-logs/query-types',
+    method: 'GET',
+  });
+};
+
 // 获取节点日志统计
 export const getNodeLogStats = (nodeId, params) => {
   return request({
@@ -58,4 +66,45 @@ export const searchDomain = (keyword, limit = 10) => {
     method: 'GET',
     params: { keyword, limit },
   });
-};
\ No newline at end of file
+};
+
+// 获取日志存储信息（含表结构校对结果）
+export const getLogStorageInfo = () => {
+  return request({
+    url: '/dns-logs/storage',
+    method: 'GET',
+  });
+};
+
+// 重新校对日志表结构，补齐缺少的列
+export const reconcileLogSchema = () => {
+  return request({
+    url: '/dns-logs/storage/schema',
+    method: 'POST',
+  });
+};
+
+// 获取日志保留策略概览
+export const getLogRetentionOverview = () => {
+  return request({
+    url: '/dns-logs/retention',
+    method: 'GET',
+  });
+};
+
+// 获取节点日志保留天数
+export const getNodeLogRetention = (nodeId) => {
+  return request({
+    url: `/dns-logs/${nodeId}/retention`,
+    method: 'GET',
+  });
+};
+
+// 设置节点日志保留天数，0 表示恢复默认
+export const updateNodeLogRetention = (nodeId, days) => {
+  return request({
+    url: `/dns-logs/${nodeId}/retention`,
+    method: 'PUT',
+    data: { days },
+  });
+};
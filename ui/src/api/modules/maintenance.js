@@ -0,0 +1,15 @@
+import request from "../../utils/request";
+
+export const getMaintenanceWindows = () => request.get("/maintenance-windows");
+export const addMaintenanceWindow = (data) =>
+  request.post("/maintenance-windows", data);
+export const updateMaintenanceWindow = (id, data) =>
+  request.put(`/maintenance-windows/${id}`, data);
+export const deleteMaintenanceWindow = (id) =>
+  request.delete(`/maintenance-windows/${id}`);
+export const getDeferredActions = (status = "pending") =>
+  request.get("/deferred-actions", { params: { status } });
+export const cancelDeferredAction = (id) =>
+  request.delete(`/deferred-actions/${id}`);
+export const runDeferredAction = (id) =>
+  request.post(`/deferred-actions/${id}/run`);
@@ -0,0 +1,7 @@
+import request from "../../utils/request";
+
+export const getNodeFactsList = (params) =>
+  request.get("/node-facts", { params });
+export const getNodeFacts = (nodeId) => request.get(`/nodes/${nodeId}/facts`);
+export const refreshNodeFacts = (nodeId) =>
+  request.post(`/nodes/${nodeId}/facts/refresh`);
@@ -1,4 +1,5 @@
 import request from "../../utils/request";
+import { subscribeSSE } from "../../utils/sse";
 
 export const getNodes = (params) => request.get("/nodes", { params });
 export const addNode = (data) => request.post("/nodes", data);
@@ -6,20 +7,34 @@ export const updateNode = (id, data) => request.put(`/nodes/${id}`, data);
 export const deleteNode = (id) => request.delete(`/nodes/${id}`);
 export const testNodeConnection = (id) => request.post(`/nodes/${id}/test`);
 export const getNodeStatus = (id) => request.get(`/nodes/${id}/status`);
+export const getNodeMetrics = (id, params) => request.get(`/nodes/${id}/metrics`, { params });
 export const getNodeLogs = (id, params) => request.get(`/nodes/${id}/logs`, { params });
-export const restartNodeService = (id) => request.post(`/nodes/${id}/restart`);
+export const restartNodeService = (id, override = false) =>
+  request.post(`/nodes/${id}/restart`, null, { params: { override } });
+export const reloadNodeService = (id, override = false) =>
+  request.post(`/nodes/${id}/reload`, null, { params: { override } });
+
+// 缓存
+export const getNodeCacheStats = (id) => request.get(`/nodes/${id}/cache/stats`);
+export const flushNodeCache = (id, override = false) =>
+  request.post(`/nodes/${id}/cache/flush`, null, { params: { override } });
 
 // 配置
 export const getNodeConfig = (id) => request.get(`/nodes/${id}/config`);
 export const saveNodeConfig = (id, data) => request.post(`/nodes/${id}/config`, data);
+export const getNodeStructuredConfig = (id, source) =>
+  request.get(`/nodes/${id}/config/structured`, { params: { source } });
 
 // 批量
 export const batchUpdateConfig = (data) => request.post("/nodes/batch/config", data);
 export const batchRestart = (data) => request.post("/nodes/batch/restart", data);
+export const batchFlushCache = (data) => request.post("/nodes/batch/cache/flush", data);
 
 // 初始化
 export const initNode = (id) => request.post(`/nodes/${id}/init`);
 export const checkNodeInit = (id) => request.get(`/nodes/${id}/init/status`);
 export const getInitLogs = (id) => request.get(`/nodes/${id}/init/logs`);
+// 订阅初始化进度（SSE），handlers 见 subscribeSSE，返回取消订阅的函数
+export const streamInitProgress = (id, handlers) => subscribeSSE(`/nodes/${id}/init/stream`, handlers);
 export const uninstallSmartDNS = (id) => request.post(`/nodes/${id}/uninstall`);
 export const reinstallSmartDNS = (id) => request.post(`/nodes/${id}/reinstall`);
\ No newline at end of file
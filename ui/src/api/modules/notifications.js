@@ -11,4 +11,5 @@ export const deleteNotificationChannel = (id) =>
 export const testNotificationChannel = (id) =>
   request.post(`/notifications/channels/${id}/test`);
 export const getNotificationLogs = (params) =>
-  request.get("/notifications/logs", { params });
\ No newline at end of file
+  request.get("/notifications/logs", { params });export const getNotificationStats = (params) =>
+  request.get("/notifications/stats", { params });
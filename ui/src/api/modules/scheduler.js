@@ -76,6 +76,15 @@ export const getSchedulerStats = () => {
   });
 };
 
+// 校验 Cron 表达式并预览之后的执行时间
+export const validateCron = (expr) => {
+  return request({
+    url: '/scheduler/validate-cron',
+    method: 'GET',
+    params: { expr }
+  });
+};
+
 // 快速任务创建
 export const createQuickTask = (data) => {
   return request({
@@ -85,11 +94,20 @@ export const createQuickTask = (data) => {
   });
 };
 
+// 快速任务模板（配置由服务端按参数生成）
+export const getQuickTaskPresets = () => {
+  return request({
+    url: '/scheduler/quick-task/presets',
+    method: 'GET'
+  });
+};
+
 // 遥测目标管理
-export const getTelemetryTargets = () => {
+export const getTelemetryTargets = (params) => {
   return request({
     url: '/scheduler/telemetry/targets',
-    method: 'GET'
+    method: 'GET',
+    params
   });
 };
 
@@ -101,6 +119,15 @@ export const createTelemetryTarget = (data) => {
   });
 };
 
+// 批量导入遥测目标（CSV/YAML）
+export const importTelemetryTargets = (data) => {
+  return request({
+    url: '/scheduler/telemetry/targets/import',
+    method: 'POST',
+    data
+  });
+};
+
 export const updateTelemetryTarget = (id, data) => {
   return request({
     url: `/scheduler/telemetry/targets/${id}`,
@@ -132,6 +159,13 @@ export const getTelemetryStats = () => {
   });
 };
 
+export const getTelemetryGroups = () => {
+  return request({
+    url: '/scheduler/telemetry/groups',
+    method: 'GET'
+  });
+};
+
 // 测试遥测目标
 export const testTelemetryTarget = (id) => {
   return request({
@@ -140,11 +174,67 @@ export const testTelemetryTarget = (id) => {
   });
 };
 
+// 节点解析 SLA（Agent 本地探测）
+export const getProbeSLA = (params) => {
+  return request({
+    url: '/probe-sla',
+    method: 'GET',
+    params
+  });
+};
+
+export const collectProbeStats = () => {
+  return request({
+    url: '/probe-sla/collect',
+    method: 'POST'
+  });
+};
+
+export const getNodeProbeStats = (nodeId, params) => {
+  return request({
+    url: `/nodes/${nodeId}/probe-stats`,
+    method: 'GET',
+    params
+  });
+};
+
 // 脚本模板管理
-export const getScriptTemplates = () => {
+export const getScriptTemplates = (params) => {
   return request({
     url: '/scheduler/script-templates',
-    method: 'GET'
+    method: 'GET',
+    params
+  });
+};
+
+export const createScriptTemplate = (data) => {
+  return request({
+    url: '/scheduler/script-templates',
+    method: 'POST',
+    data
+  });
+};
+
+export const updateScriptTemplate = (id, data) => {
+  return request({
+    url: `/scheduler/script-templates/${id}`,
+    method: 'PUT',
+    data
+  });
+};
+
+export const deleteScriptTemplate = (id) => {
+  return request({
+    url: `/scheduler/script-templates/${id}`,
+    method: 'DELETE'
+  });
+};
+
+export const renderScriptTemplate = (id, params) => {
+  return request({
+    url: `/scheduler/script-templates/${id}/render`,
+    method: 'POST',
+    data: { params }
   });
 };
 
@@ -157,7 +247,7 @@ export const getTaskTemplates = () => {
         name: '数据库备份',
         description: '自动备份SQLite数据库到S3',
         icon: 'database',
-        defaultCron: '0 2 * * *', // 每天凌晨2点
+        defaultCron: '0 0 2 * * *', // 每天凌晨2点
         configSchema: {
           s3_config: {
             access_key: '',
@@ -177,7 +267,7 @@ export const getTaskTemplates = () => {
         name: '节点备份',
         description: '备份SmartDNS节点配置文件',
         icon: 'server',
-        defaultCron: '0 3 * * 0', // 每周日凌晨3点
+        defaultCron: '0 0 3 * * 0', // 每周日凌晨3点
         configSchema: {
           storage_type: 'local',
           local_path: '/etc/smartdns/backups',
@@ -201,12 +291,131 @@ export const getTaskTemplates = () => {
         name: '日志清理',
         description: '定时清理过期日志文件',
         icon: 'delete',
-        defaultCron: '0 4 * * *', // 每天凌晨4点
+        defaultCron: '0 0 4 * * *', // 每天凌晨4点
         configSchema: {
           agent_log_days: 7,
           backend_log_days: 30,
           smartdns_log_days: 7,
-          log_paths: []
+          log_paths: [],
+          notification_log_days: 90
+        }
+      },
+      {
+        type: 'log_retention',
+        name: 'DNS查询日志保留',
+        description: '按各节点的保留天数清理DNS查询日志，调整 ClickHouse 表 TTL',
+        icon: 'field-time',
+        defaultCron: '0 30 2 * * *', // 每天凌晨2点半
+        configSchema: {}
+      },
+      {
+        type: 'drift_check',
+        name: '配置漂移检测',
+        description: '对比节点实际配置与管理端期望配置，发现手动修改',
+        icon: 'diff',
+        defaultCron: '0 */30 * * * *', // 每30分钟
+        configSchema: {
+          node_ids: [],
+          retention_days: 30
+        }
+      },
+      {
+        type: 'config_snapshot',
+        name: '节点配置快照',
+        description: '定期读取节点配置，内容变化时保存快照，记录直接在节点上做的修改',
+        icon: 'camera',
+        defaultCron: '0 15 * * * *', // 每小时
+        configSchema: {
+          node_ids: [],
+          retention_days: 90
+        }
+      },
+      {
+        type: 'compliance',
+        name: '配置合规检查',
+        description: '按合规策略检查节点配置，记录违规，开启自动修复的策略通过完整同步修复',
+        icon: 'safety-certificate',
+        defaultCron: '0 45 * * * *', // 每小时
+        configSchema: {
+          node_ids: [],
+          retention_days: 90
+        }
+      },
+      {
+        type: 'patch_check',
+        name: '节点补丁检查',
+        description: '采集节点待安装的安全更新、是否需要重启，检查内核已知严重漏洞',
+        icon: 'safety',
+        defaultCron: '0 0 6 * * *', // 每天早上6点
+        configSchema: {
+          node_ids: [],
+          security_threshold: 1,
+          ignore_reboot: false
+        }
+      },
+      {
+        type: 'blocklist',
+        name: '屏蔽列表订阅刷新',
+        description: '按订阅的刷新间隔下载远程屏蔽列表，更新域名集并推送到节点',
+        icon: 'cloud-download',
+        defaultCron: '0 */10 * * * *', // 每10分钟检查一次
+        configSchema: {
+          subscription_ids: [],
+          force: false
+        }
+      },
+      {
+        type: 'traffic_anomaly',
+        name: '查询量异常检测',
+        description: '按历史基线检测节点查询量骤降/激增和解析失败率升高',
+        icon: 'line-chart',
+        defaultCron: '0 */5 * * * *', // 每5分钟
+        configSchema: {
+          node_ids: [],
+          method: 'zscore',
+          window_minutes: 15,
+          baseline_hours: 24,
+          seasonal_days: 7,
+          z_threshold: 3,
+          min_baseline_qps: 0.1,
+          min_failure_change: 0.05
+        }
+      },
+      {
+        type: 'answer_check',
+        name: 'DNS应答校验',
+        description: '通过各节点解析指定域名，应答不在期望地址或网段内时告警（劫持/污染检测）',
+        icon: 'safety-certificate',
+        defaultCron: '0 */10 * * * *', // 每10分钟
+        configSchema: {
+          node_ids: [],
+          monitors: [],
+          timeout: 5
+        }
+      },
+      {
+        type: 'agent_probe',
+        name: '节点解析探测',
+        description: '拉取各节点 Agent 通过本机 SmartDNS 解析探测域名的结果，汇总为节点解析 SLA',
+        icon: 'radar-chart',
+        defaultCron: '0 */5 * * * *', // 每5分钟
+        configSchema: {
+          node_ids: [],
+          domains: [],
+          interval_sec: 30,
+          retention_days: 7
+        }
+      },
+      {
+        type: 'fleet_report',
+        name: '节点资产报告',
+        description: '按云厂商/区域/成本中心汇总节点数量、资源使用和查询量，每月生成并保存',
+        icon: 'bar-chart',
+        defaultCron: '0 0 3 1 * *', // 每月1日凌晨3点
+        configSchema: {
+          month: '',
+          check_resources: true,
+          retention_count: 24
         }
       },
       {
@@ -214,7 +423,7 @@ export const getTaskTemplates = () => {
         name: '网络遥测',
         description: '定期检测网络连通性和延迟',
         icon: 'radar-chart',
-        defaultCron: '*/5 * * * *', // 每5分钟
+        defaultCron: '0 */5 * * * *', // 每5分钟
         configSchema: {
           targets: [],
           result_retention: 30,
@@ -256,16 +465,18 @@ export const getTaskTemplates = () => {
       {
         type: 'custom_script',
         name: '自定义脚本',
-        description: '在选定节点上执行自定义Shell脚本',
+        description: '通过SSH在选定节点或标签分组上执行自定义Shell脚本',
         icon: 'terminal',
-        defaultCron: '0 1 * * *', // 每天凌晨1点
+        defaultCron: '0 0 1 * * *', // 每天凌晨1点
         configSchema: {
           script: '#!/bin/bash\n# 在此处编写您的脚本\necho "Hello, SmartDNS!"\n',
           node_ids: [],
+          tag: '',
           timeout: 300,
-          run_as_user: 'root',
+          run_as_user: '',
           working_dir: '/tmp',
-          env_vars: {}
+          env_vars: {},
+          concurrency: 5
         },
         examples: [
           {
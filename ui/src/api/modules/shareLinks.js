@@ -0,0 +1,7 @@
+import request from "../../utils/request";
+
+export const createShareLink = (data) => request.post("/share-links", data);
+export const getShareLinks = () => request.get("/share-links");
+export const revokeShareLink = (id) => request.delete(`/share-links/${id}`);
+export const viewSharedLogs = (token, params) =>
+  request.get(`/share/${token}`, { params });
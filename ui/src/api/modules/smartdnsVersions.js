@@ -0,0 +1,11 @@
+import request from "../../utils/request";
+
+// SmartDNS 版本目录，refresh 为 true 时立即从 GitHub 更新
+export const getSmartDNSReleases = (refresh = false) =>
+  request.get("/smartdns/releases", { params: refresh ? { refresh: true } : undefined });
+export const getSmartDNSVersionPins = () => request.get("/smartdns/version-pins");
+export const saveSmartDNSVersionPin = (data) => request.post("/smartdns/version-pins", data);
+export const deleteSmartDNSVersionPin = (id) => request.delete(`/smartdns/version-pins/${id}`);
+export const getNodeSmartDNSVersion = (id) => request.get(`/nodes/${id}/smartdns/version`);
+// 升级作为后台任务执行，返回任务
+export const upgradeNodeSmartDNS = (id, data) => request.post(`/nodes/${id}/smartdns/upgrade`, data);
@@ -5,4 +5,6 @@ export const batchFullSync = (data) => request.post("/sync/batch", data);
 export const getSyncLogs = (params) => request.get("/sync/logs", { params });
 export const getSyncStats = () => request.get("/sync/stats");
 export const retrySyncLog = (id) => request.post(`/sync/logs/${id}/retry`);
-export const clearSyncLogs = (data) => request.delete("/sync/logs", { data });
\ No newline at end of file
+export const clearSyncLogs = (data) => request.delete("/sync/logs", { data });
+export const getSyncJobs = (params) => request.get("/sync/jobs", { params });
+export const getSyncJob = (id) => request.get(`/sync/jobs/${id}`);
@@ -0,0 +1,3 @@
+import request from "../../utils/request";
+
+export const getSystemLogs = (params) => request.get("/system/logs", { params });
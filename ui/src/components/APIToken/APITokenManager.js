@@ -0,0 +1,234 @@
+import React, { useState, useEffect } from 'react';
+import {
+  Table,
+  Button,
+  Space,
+  Tag,
+  Modal,
+  Form,
+  Input,
+  InputNumber,
+  Select,
+  Popconfirm,
+  Typography,
+  Alert,
+  message,
+} from 'antd';
+import { PlusOutlined, StopOutlined } from '@ant-design/icons';
+import dayjs from 'dayjs';
+import { getAPITokens, createAPIToken, revokeAPIToken } from '../../api';
+
+const { Option } = Select;
+const { Paragraph } = Typography;
+
+const scopeOptions = [
+  { value: 'read', label: '只读', color: 'blue', description: '仅允许 GET 请求' },
+  { value: 'sync', label: '同步', color: 'orange', description: '只读 + 触发同步、应用变更集、重载服务' },
+  { value: 'full', label: '完全', color: 'red', description: '与创建者相同的权限（令牌管理除外）' },
+];
+
+const APITokenManager = () => {
+  const [tokens, setTokens] = useState([]);
+  const [loading, setLoading] = useState(false);
+  const [modalVisible, setModalVisible] = useState(false);
+  const [submitLoading, setSubmitLoading] = useState(false);
+  const [createdToken, setCreatedToken] = useState(null);
+  const [form] = Form.useForm();
+
+  useEffect(() => {
+    loadTokens();
+  }, []);
+
+  const loadTokens = async () => {
+    try {
+      setLoading(true);
+      const response = await getAPITokens();
+      setTokens(response.data || []);
+    } catch (error) {
+      console.error('加载 API 令牌失败', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleCreate = () => {
+    form.resetFields();
+    form.setFieldsValue({ scope: 'read', expires_in_days: 90 });
+    setModalVisible(true);
+  };
+
+  const handleSubmit = async () => {
+    try {
+      const values = await form.validateFields();
+      setSubmitLoading(true);
+      const response = await createAPIToken(values);
+      setModalVisible(false);
+      setCreatedToken(response.token);
+      loadTokens();
+    } catch (error) {
+      if (error.errorFields) {
+        return;
+      }
+      console.error('创建 API 令牌失败', error);
+    } finally {
+      setSubmitLoading(false);
+    }
+  };
+
+  const handleRevoke = async (id) => {
+    try {
+      await revokeAPIToken(id);
+      message.success('令牌已撤销');
+      loadTokens();
+    } catch (error) {
+      console.error('撤销 API 令牌失败', error);
+    }
+  };
+
+  const columns = [
+    {
+      title: '名称',
+      dataIndex: 'name',
+      key: 'name',
+    },
+    {
+      title: '令牌',
+      dataIndex: 'prefix',
+      key: 'prefix',
+      render: (prefix) => <code>{prefix}…</code>,
+    },
+    {
+      title: '权限',
+      dataIndex: 'scope',
+      key: 'scope',
+      render: (scope) => {
+        const option = scopeOptions.find((o) => o.value === scope);
+        return <Tag color={option?.color}>{option?.label || scope}</Tag>;
+      },
+    },
+    {
+      title: '状态',
+      key: 'status',
+      render: (_, record) => {
+        if (record.revoked) return <Tag>已撤销</Tag>;
+        if (record.expires_at && dayjs(record.expires_at).isBefore(dayjs())) {
+          return <Tag color="warning">已过期</Tag>;
+        }
+        return <Tag color="success">有效</Tag>;
+      },
+    },
+    {
+      title: '过期时间',
+      dataIndex: 'expires_at',
+      key: 'expires_at',
+      render: (time) => (time ? dayjs(time).format('YYYY-MM-DD HH:mm') : '永不过期'),
+    },
+    {
+      title: '最近使用',
+      key: 'last_used_at',
+      render: (_, record) =>
+        record.last_used_at
+          ? `${dayjs(record.last_used_at).format('YYYY-MM-DD HH:mm')} (${record.last_used_ip})`
+          : '从未使用',
+    },
+    {
+      title: '创建者',
+      dataIndex: 'created_by',
+      key: 'created_by',
+    },
+    {
+      title: '操作',
+      key: 'action',
+      render: (_, record) =>
+        !record.revoked && (
+          <Popconfirm
+            title="撤销后使用该令牌的请求将立即失败，确定撤销吗？"
+            onConfirm={() => handleRevoke(record.id)}
+            okText="确定"
+            cancelText="取消"
+          >
+            <Button type="link" size="small" danger icon={<StopOutlined />}>
+              撤销
+            </Button>
+          </Popconfirm>
+        ),
+    },
+  ];
+
+  return (
+    <div>
+      <Alert
+        type="info"
+        showIcon
+        style={{ marginBottom: 16 }}
+        message="API 令牌用于 CI 流水线和外部工具，请求时携带 Authorization: Bearer <令牌>。令牌不能用于管理令牌本身。"
+      />
+      <div style={{ marginBottom: 16 }}>
+        <Button type="primary" icon={<PlusOutlined />} onClick={handleCreate}>
+          创建令牌
+        </Button>
+      </div>
+      <Table
+        columns={columns}
+        dataSource={tokens}
+        rowKey="id"
+        loading={loading}
+        pagination={false}
+      />
+
+      <Modal
+        title="创建 API 令牌"
+        open={modalVisible}
+        onOk={handleSubmit}
+        onCancel={() => setModalVisible(false)}
+        okText="创建"
+        cancelText="取消"
+        confirmLoading={submitLoading}
+      >
+        <Form form={form} layout="vertical">
+          <Form.Item
+            name="name"
+            label="名称"
+            rules={[{ required: true, message: '请输入令牌名称' }]}
+          >
+            <Input placeholder="例如: gitlab-ci" />
+          </Form.Item>
+          <Form.Item name="scope" label="权限范围">
+            <Select>
+              {scopeOptions.map((option) => (
+                <Option key={option.value} value={option.value}>
+                  {option.label} - {option.description}
+                </Option>
+              ))}
+            </Select>
+          </Form.Item>
+          <Form.Item
+            name="expires_in_days"
+            label="有效期（天）"
+            extra="填 0 表示永不过期"
+          >
+            <InputNumber min={0} style={{ width: '100%' }} />
+          </Form.Item>
+        </Form>
+      </Modal>
+
+      <Modal
+        title="令牌已创建"
+        open={!!createdToken}
+        onOk={() => setCreatedToken(null)}
+        onCancel={() => setCreatedToken(null)}
+        cancelButtonProps={{ style: { display: 'none' } }}
+        okText="我已保存"
+      >
+        <Space direction="vertical" style={{ width: '100%' }}>
+          <Alert type="warning" showIcon message="令牌只显示这一次，请立即复制保存" />
+          <Paragraph copyable code>
+            {createdToken}
+          </Paragraph>
+        </Space>
+      </Modal>
+    </div>
+  );
+};
+
+export default APITokenManager;
@@ -0,0 +1,177 @@
+import React, { useState, useEffect } from 'react';
+import {
+  Modal,
+  Form,
+  Select,
+  Input,
+  InputNumber,
+  Button,
+  Space,
+  Alert,
+  Descriptions,
+  Spin,
+  message,
+} from 'antd';
+import { PlusOutlined, MinusCircleOutlined } from '@ant-design/icons';
+import { getAgentLogFilter, updateAgentLogFilter } from '../../api';
+
+const AgentFilterModal = ({ visible, onCancel, node }) => {
+  const [form] = Form.useForm();
+  const [loading, setLoading] = useState(false);
+  const [saving, setSaving] = useState(false);
+  const [agentError, setAgentError] = useState(null);
+  const [agentStats, setAgentStats] = useState(null);
+
+  useEffect(() => {
+    if (visible && node?.id) {
+      fetchFilter();
+    }
+  }, [visible, node?.id]);
+
+  const fetchFilter = async () => {
+    try {
+      setLoading(true);
+      const result = await getAgentLogFilter(node.id);
+      if (result.success) {
+        const rules = result.data.rules || {};
+        form.setFieldsValue({
+          ignore_suffixes: rules.ignore_suffixes || [],
+          ignore_patterns: rules.ignore_patterns || [],
+          ignore_clients: rules.ignore_clients || [],
+          sample_rules: rules.sample_rules || [],
+        });
+        setAgentError(result.data.agent_error || null);
+        setAgentStats(result.data.agent_stats || null);
+      }
+    } catch (error) {
+      message.error('获取过滤规则失败');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleSave = async () => {
+    try {
+      const values = await form.validateFields();
+      setSaving(true);
+      const result = await updateAgentLogFilter(node.id, {
+        ignore_suffixes: values.ignore_suffixes || [],
+        ignore_patterns: values.ignore_patterns || [],
+        ignore_clients: values.ignore_clients || [],
+        sample_rules: values.sample_rules || [],
+      });
+      if (result.success) {
+        if (result.data?.applied) {
+          message.success(result.message);
+          onCancel();
+        } else {
+          message.warning(result.message);
+        }
+      } else {
+        message.error(result.message || '保存失败');
+      }
+    } catch (error) {
+      if (!error?.errorFields) {
+        message.error('保存失败: ' + (error.response?.data?.message || error.message));
+      }
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  return (
+    <Modal
+      title={`日志过滤 - ${node?.name || ''}`}
+      open={visible}
+      onCancel={onCancel}
+      onOk={handleSave}
+      confirmLoading={saving}
+      okText="保存并下发"
+      width={640}
+      destroyOnClose
+    >
+      <Spin spinning={loading}>
+        <Alert
+          type="info"
+          showIcon
+          style={{ marginBottom: 16 }}
+          message="Agent 在发送前丢弃匹配的日志，被丢弃和采样掉的日志不会写入存储，查询统计会相应减少。"
+        />
+        {agentError && (
+          <Alert
+            type="warning"
+            showIcon
+            style={{ marginBottom: 16 }}
+            message={`无法获取 Agent 当前规则: ${agentError}`}
+          />
+        )}
+
+        <Form form={form} layout="vertical">
+          <Form.Item
+            name="ignore_suffixes"
+            label="忽略域名后缀"
+            tooltip="域名等于后缀或以 .后缀 结尾时丢弃，如 in-addr.arpa"
+          >
+            <Select mode="tags" placeholder="in-addr.arpa、ip6.arpa" tokenSeparators={[',', ' ']} />
+          </Form.Item>
+          <Form.Item
+            name="ignore_patterns"
+            label="忽略域名正则"
+            tooltip="匹配小写域名，不含结尾的点"
+          >
+            <Select mode="tags" placeholder="^health-check\." />
+          </Form.Item>
+          <Form.Item
+            name="ignore_clients"
+            label="忽略客户端"
+            tooltip="IP 或 CIDR 网段，如健康检查探针的地址"
+          >
+            <Select mode="tags" placeholder="10.0.0.5、192.168.100.0/24" tokenSeparators={[',', ' ']} />
+          </Form.Item>
+
+          <Form.Item label="按域名后缀采样" tooltip="匹配后缀的域名只保留指定比例的日志，后缀为 * 时作用于其余所有域名">
+            <Form.List name="sample_rules">
+              {(fields, { add, remove }) => (
+                <>
+                  {fields.map(({ key, name, ...restField }) => (
+                    <Space key={key} align="baseline" style={{ display: 'flex' }}>
+                      <Form.Item
+                        {...restField}
+                        name={[name, 'suffix']}
+                        rules={[{ required: true, message: '请输入域名后缀' }]}
+                      >
+                        <Input placeholder="cdn.example.com 或 *" style={{ width: 260 }} />
+                      </Form.Item>
+                      <Form.Item
+                        {...restField}
+                        name={[name, 'rate']}
+                        rules={[{ required: true, message: '请输入保留比例' }]}
+                      >
+                        <InputNumber min={0.001} max={1} step={0.01} placeholder="保留比例" style={{ width: 140 }} />
+                      </Form.Item>
+                      <MinusCircleOutlined onClick={() => remove(name)} />
+                    </Space>
+                  ))}
+                  <Button type="dashed" onClick={() => add({ rate: 0.1 })} icon={<PlusOutlined />}>
+                    添加采样规则
+                  </Button>
+                </>
+              )}
+            </Form.List>
+          </Form.Item>
+        </Form>
+
+        {agentStats && (
+          <Descriptions title="Agent 启动以来丢弃的日志" size="small" column={2} bordered>
+            <Descriptions.Item label="域名后缀">{agentStats.ignored_by_suffix || 0}</Descriptions.Item>
+            <Descriptions.Item label="域名正则">{agentStats.ignored_by_pattern || 0}</Descriptions.Item>
+            <Descriptions.Item label="客户端">{agentStats.ignored_by_client || 0}</Descriptions.Item>
+            <Descriptions.Item label="采样">{agentStats.sampled_out || 0}</Descriptions.Item>
+          </Descriptions>
+        )}
+      </Spin>
+    </Modal>
+  );
+};
+
+export default AgentFilterModal;
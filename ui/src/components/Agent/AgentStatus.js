@@ -19,28 +19,87 @@ import {
   CheckCircleOutlined,
   ExclamationCircleOutlined,
   CloseCircleOutlined,
+  KeyOutlined,
+  FilterOutlined,
 } from "@ant-design/icons";
 import AgentDeployModal from "./AgentDeployModal";
 import AgentLogsModal from "./AgentLogsModal";
+import AgentFilterModal from "./AgentFilterModal";
 import {
   checkAgentStatus as checkAgentStatusApi,
   uninstallAgent,
+  getAgentControlStatus,
+  createAgentControlToken,
 } from "../../api";
 
 const { Text, Paragraph } = Typography;
 
 const AgentStatus = ({ node, onRefresh }) => {
   const [status, setStatus] = useState(null);
+  const [control, setControl] = useState(null);
   const [loading, setLoading] = useState(false);
   const [deployModalVisible, setDeployModalVisible] = useState(false);
   const [logsModalVisible, setLogsModalVisible] = useState(false);
+  const [filterModalVisible, setFilterModalVisible] = useState(false);
 
   useEffect(() => {
     if (node?.id) {
       checkAgentStatus();
+      loadControlStatus();
     }
   }, [node?.id]);
 
+  const loadControlStatus = async () => {
+    try {
+      const result = await getAgentControlStatus(node.id);
+      if (result.success) {
+        setControl(result.data);
+      }
+    } catch (error) {
+      setControl(null);
+    }
+  };
+
+  const handleCreateControlToken = () => {
+    Modal.confirm({
+      title: "生成控制令牌",
+      content: control?.token_issued
+        ? "重新生成后旧令牌立即失效，Agent 需要使用新令牌重新连接。"
+        : "Agent 使用控制令牌主动连接管理端，适用于管理端无法访问 Agent API 端口的节点。",
+      okText: "生成",
+      cancelText: "取消",
+      onOk: async () => {
+        try {
+          const result = await createAgentControlToken(node.id);
+          if (!result.success) {
+            message.error("生成失败: " + result.message);
+            return;
+          }
+          Modal.info({
+            title: "控制令牌",
+            width: 560,
+            content: (
+              <>
+                <Paragraph>
+                  将以下配置写入 Agent 的环境变量后重启 Agent，令牌关闭后将无法再次查看：
+                </Paragraph>
+                <Paragraph copyable code>
+                  {`CONTROL_URL=${window.location.origin.replace(/^http/, "ws")}/api/v1/agent/control`}
+                </Paragraph>
+                <Paragraph copyable code>
+                  {`CONTROL_TOKEN=${result.token}`}
+                </Paragraph>
+              </>
+            ),
+          });
+          loadControlStatus();
+        } catch (error) {
+          message.error("生成失败: " + error.message);
+        }
+      },
+    });
+  };
+
   const checkAgentStatus = async () => {
     if (!node?.id) return;
 
@@ -155,6 +214,25 @@ const AgentStatus = ({ node, onRefresh }) => {
               </div>
             )}
 
+            <div
+              style={{
+                display: "flex",
+                justifyContent: "space-between",
+                alignItems: "center",
+              }}
+            >
+              <Text>控制通道：</Text>
+              {control?.connected ? (
+                <Tooltip title={`${control.remote_addr} · v${control.agent_version}`}>
+                  <Tag color="success">已连接</Tag>
+                </Tooltip>
+              ) : (
+                <Tag color="default">
+                  {control?.token_issued ? "未连接" : "未启用"}
+                </Tag>
+              )}
+            </div>
+
             <div
               style={{
                 display: "flex",
@@ -199,6 +277,20 @@ const AgentStatus = ({ node, onRefresh }) => {
                 >
                   查看日志
                 </Button>
+                <Button
+                  size="small"
+                  icon={<KeyOutlined />}
+                  onClick={handleCreateControlToken}
+                >
+                  控制令牌
+                </Button>
+                <Button
+                  size="small"
+                  icon={<FilterOutlined />}
+                  onClick={() => setFilterModalVisible(true)}
+                >
+                  日志过滤
+                </Button>
                 <Button
                   size="small"
                   danger
@@ -229,6 +321,12 @@ const AgentStatus = ({ node, onRefresh }) => {
         onCancel={() => setLogsModalVisible(false)}
         node={node}
       />
+
+      <AgentFilterModal
+        visible={filterModalVisible}
+        onCancel={() => setFilterModalVisible(false)}
+        node={node}
+      />
     </>
   );
 };
@@ -15,6 +15,8 @@ import {
   Badge,
   Tooltip,
   Form,
+  Switch,
+  InputNumber,
 } from "antd";
 import {
   EyeOutlined,
@@ -25,6 +27,7 @@ import {
   ReloadOutlined,
   CloudServerOutlined,
   CloudDownloadOutlined,
+  SettingOutlined,
 } from "@ant-design/icons";
 import {
   getNodeBackups,
@@ -33,6 +36,8 @@ import {
   deleteNodeBackup,
   previewBackup as previewBackupApi,
   getNodes,
+  getNodeBackupRetention,
+  updateNodeBackupRetention,
 } from "../../api";
 import dayjs from "dayjs";
 
@@ -58,6 +63,8 @@ const BackupManager = () => {
     total: 0,
   });
   const [form] = Form.useForm();
+  const [retentionVisible, setRetentionVisible] = useState(false);
+  const [retentionForm] = Form.useForm();
 
   // 加载节点列表
   const loadNodes = async () => {
@@ -146,9 +153,13 @@ const BackupManager = () => {
       const values = await form.validateFields();
       message.loading({ content: "正在创建备份...", key: "backup" });
       
-      await createNodeBackup(selectedNodeId, values);
-      
-      message.success({ content: "备份创建成功", key: "backup" });
+      const response = await createNodeBackup(selectedNodeId, values);
+
+      if (response.unchanged) {
+        message.info({ content: response.message, key: "backup" });
+      } else {
+        message.success({ content: "备份创建成功", key: "backup" });
+      }
       setCreateVisible(false);
       loadBackups(1, pagination.pageSize); // 创建后回到第一页
     } catch (error) {
@@ -163,6 +174,32 @@ const BackupManager = () => {
     }
   };
 
+  // 备份保留策略
+  const handleOpenRetention = async () => {
+    try {
+      const response = await getNodeBackupRetention(selectedNodeId);
+      retentionForm.setFieldsValue(response.data);
+      setRetentionVisible(true);
+    } catch (error) {
+      message.error(error.response?.data?.error || "加载保留策略失败");
+    }
+  };
+
+  const confirmRetention = async () => {
+    try {
+      const values = await retentionForm.validateFields();
+      const response = await updateNodeBackupRetention(selectedNodeId, values);
+      message.success(response.message || "保留策略已更新");
+      setRetentionVisible(false);
+      loadBackups(1, pagination.pageSize);
+    } catch (error) {
+      if (error.errorFields) {
+        return;
+      }
+      message.error(error.response?.data?.error || "更新保留策略失败");
+    }
+  };
+
   // 预览备份
   const handlePreview = async (backup) => {
     try {
@@ -235,7 +272,7 @@ const BackupManager = () => {
 
       // 本地存储，通过 API 下载
       const token = localStorage.getItem("token");
-      const downloadUrl = `/api/nodes/${selectedNodeId}/backups/download?backup_id=${backup.id}&token=${token}`;
+      const downloadUrl = `/api/v1/nodes/${selectedNodeId}/backups/download?backup_id=${backup.id}&token=${token}`;
       
       const a = document.createElement("a");
       a.href = downloadUrl;
@@ -299,7 +336,18 @@ const BackupManager = () => {
       dataIndex: "created_at",
       key: "created_at",
       width: 180,
-      render: (time) => dayjs(time).format("YYYY-MM-DD HH:mm:ss"),
+      render: (time, record) => (
+        <Space direction="vertical" size={0}>
+          {dayjs(time).format("YYYY-MM-DD HH:mm:ss")}
+          {record.confirmed_at && (
+            <Tooltip title="此后的备份任务检测到配置未变化，未重复保存">
+              <Text type="secondary" style={{ fontSize: 12 }}>
+                确认于 {dayjs(record.confirmed_at).format("MM-DD HH:mm")}
+              </Text>
+            </Tooltip>
+          )}
+        </Space>
+      ),
       sorter: (a, b) => new Date(a.created_at) - new Date(b.created_at),
       defaultSortOrder: "descend",
     },
@@ -437,6 +485,13 @@ const BackupManager = () => {
           >
             刷新
           </Button>
+          <Button
+            icon={<SettingOutlined />}
+            onClick={handleOpenRetention}
+            disabled={!selectedNodeId}
+          >
+            保留策略
+          </Button>
         </Space>
         <Text type="secondary">
           共 {pagination.total} 个备份
@@ -513,6 +568,14 @@ const BackupManager = () => {
           >
             <Input placeholder="请输入标签（可选）" maxLength={100} />
           </Form.Item>
+          <Form.Item
+            label="强制创建"
+            name="force"
+            valuePropName="checked"
+            extra="默认配置与最近一次备份相同时不创建新备份"
+          >
+            <Switch />
+          </Form.Item>
         </Form>
         <Alert
           message="提示"
@@ -522,6 +585,39 @@ const BackupManager = () => {
         />
       </Modal>
 
+      {/* 保留策略 Modal */}
+      <Modal
+        title={
+          <Space>
+            <SettingOutlined />
+            <span>备份保留策略 - {selectedNodeName}</span>
+          </Space>
+        }
+        open={retentionVisible}
+        onOk={confirmRetention}
+        onCancel={() => setRetentionVisible(false)}
+        okText="保存并清理"
+        cancelText="取消"
+      >
+        <Alert
+          message="只清理自动备份，手动创建的备份需手动删除；三项都为 0 时不清理"
+          type="info"
+          showIcon
+          style={{ marginBottom: 16 }}
+        />
+        <Form form={retentionForm} layout="vertical">
+          <Form.Item label="保留最近" name="keep_last">
+            <InputNumber min={0} addonAfter="个" style={{ width: "100%" }} />
+          </Form.Item>
+          <Form.Item label="按天保留" name="keep_daily" extra="最近 N 天每天保留最新的一个">
+            <InputNumber min={0} addonAfter="天" style={{ width: "100%" }} />
+          </Form.Item>
+          <Form.Item label="按周保留" name="keep_weekly" extra="最近 N 周每周保留最新的一个">
+            <InputNumber min={0} addonAfter="周" style={{ width: "100%" }} />
+          </Form.Item>
+        </Form>
+      </Modal>
+
       {/* 预览备份 Modal */}
       <Modal
         title={
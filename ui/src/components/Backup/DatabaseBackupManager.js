@@ -43,6 +43,7 @@ import {
   getBackupStats,
   testS3Connection,
   getBackupHistory,
+  getBackupKeys,
 } from '../../api/modules/databaseBackup';
 import dayjs from 'dayjs';
 
@@ -59,6 +60,7 @@ const DatabaseBackupManager = () => {
   const [s3Testing, setS3Testing] = useState(false);
   const [history, setHistory] = useState([]);
   const [historyLoading, setHistoryLoading] = useState(false);
+  const [backupKeys, setBackupKeys] = useState([]);
   const [form] = Form.useForm();
 
   useEffect(() => {
@@ -69,6 +71,7 @@ const DatabaseBackupManager = () => {
     await Promise.all([
       loadBackupConfigs(),
       loadBackupStats(),
+      loadBackupKeys(),
     ]);
   };
 
@@ -93,6 +96,15 @@ const DatabaseBackupManager = () => {
     }
   };
 
+  const loadBackupKeys = async () => {
+    try {
+      const response = await getBackupKeys();
+      setBackupKeys(response.data || []);
+    } catch (error) {
+      console.error('加载加密密钥失败', error);
+    }
+  };
+
   const loadBackupHistory = async (configId = '') => {
     try {
       setHistoryLoading(true);
@@ -164,8 +176,8 @@ const DatabaseBackupManager = () => {
 
   const handleManualBackup = async (id) => {
     try {
-      await triggerManualBackup(id);
-      message.success('备份任务已启动');
+      const response = await triggerManualBackup(id);
+      message.success(`备份任务已提交（后台任务 #${response.data?.id}）`);
       // 延迟刷新数据
       setTimeout(loadData, 1000);
     } catch (error) {
@@ -648,11 +660,17 @@ const DatabaseBackupManager = () => {
                 {({ getFieldValue }) =>
                   getFieldValue('encryption_enabled') ? (
                     <Form.Item
-                      name="encryption_key"
+                      name="encryption_key_id"
                       label="加密密钥"
-                      rules={[{ required: true, message: '请输入加密密钥' }]}
+                      rules={[{ required: true, message: '请选择加密密钥' }]}
                     >
-                      <Input.Password placeholder="加密密钥" />
+                      <Select placeholder="选择加密密钥">
+                        {backupKeys.map(key => (
+                          <Option key={key.id} value={key.id}>
+                            {key.name} (v{key.version})
+                          </Option>
+                        ))}
+                      </Select>
                     </Form.Item>
                   ) : null
                 }
@@ -0,0 +1,724 @@
+import React, { useState, useEffect } from "react";
+import {
+  Table,
+  Button,
+  Space,
+  Tag,
+  Modal,
+  Form,
+  Input,
+  Select,
+  Switch,
+  message,
+  Popconfirm,
+  Tooltip,
+  Alert,
+  Row,
+  Col,
+  Card,
+  Statistic,
+  Tabs,
+} from "antd";
+import {
+  PlusOutlined,
+  EditOutlined,
+  DeleteOutlined,
+  ReloadOutlined,
+  SafetyCertificateOutlined,
+} from "@ant-design/icons";
+import {
+  getComplianceSummary,
+  getComplianceViolations,
+  getCompliancePolicies,
+  addCompliancePolicy,
+  updateCompliancePolicy,
+  deleteCompliancePolicy,
+  checkNodeCompliance,
+  getNodes,
+} from "../../api";
+import dayjs from "dayjs";
+
+const { Option } = Select;
+
+const operatorOptions = [
+  { value: "eq", label: "等于" },
+  { value: "ne", label: "不等于" },
+  { value: "in", label: "属于（逗号分隔）" },
+  { value: "gte", label: "不小于（数值）" },
+  { value: "lte", label: "不大于（数值）" },
+  { value: "level_gte", label: "日志级别不低于" },
+  { value: "contains", label: "包含（多值指令）" },
+  { value: "present", label: "必须配置" },
+  { value: "absent", label: "不允许配置" },
+];
+
+const operatorSymbols = {
+  eq: "=",
+  ne: "!=",
+  in: "∈",
+  gte: ">=",
+  lte: "<=",
+  level_gte: "≥ 级别",
+  contains: "包含",
+};
+
+const severityTags = {
+  critical: { color: "red", text: "严重" },
+  warning: { color: "orange", text: "警告" },
+  info: { color: "blue", text: "提示" },
+};
+
+const parseNodeIds = (value) => {
+  try {
+    return JSON.parse(value || "[]");
+  } catch (e) {
+    return [];
+  }
+};
+
+const describePolicy = (policy) => {
+  if (policy.operator === "present") return `必须配置 ${policy.directive}`;
+  if (policy.operator === "absent") return `不允许配置 ${policy.directive}`;
+  return `${policy.directive} ${operatorSymbols[policy.operator] || policy.operator} ${policy.value}`;
+};
+
+const renderSeverity = (severity) => {
+  const tag = severityTags[severity] || { color: "default", text: severity };
+  return <Tag color={tag.color}>{tag.text}</Tag>;
+};
+
+const ComplianceManager = () => {
+  const [summary, setSummary] = useState(null);
+  const [policies, setPolicies] = useState([]);
+  const [violations, setViolations] = useState([]);
+  const [nodes, setNodes] = useState([]);
+  const [loading, setLoading] = useState(false);
+  const [violationsLoading, setViolationsLoading] = useState(false);
+  const [violationStatus, setViolationStatus] = useState("open");
+  const [violationNode, setViolationNode] = useState();
+  const [checkingNode, setCheckingNode] = useState(null);
+  const [modalVisible, setModalVisible] = useState(false);
+  const [editing, setEditing] = useState(null);
+  const [submitLoading, setSubmitLoading] = useState(false);
+  const [form] = Form.useForm();
+  const operator = Form.useWatch("operator", form);
+  const autoRemediate = Form.useWatch("auto_remediate", form);
+
+  useEffect(() => {
+    loadAll();
+    loadNodes();
+  }, []);
+
+  useEffect(() => {
+    loadViolations();
+  }, [violationStatus, violationNode]);
+
+  const loadAll = async () => {
+    try {
+      setLoading(true);
+      const [summaryRes, policiesRes] = await Promise.all([
+        getComplianceSummary(),
+        getCompliancePolicies(),
+      ]);
+      setSummary(summaryRes.data);
+      setPolicies(policiesRes.data || []);
+    } catch (error) {
+      console.error("加载合规数据失败", error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const loadViolations = async () => {
+    try {
+      setViolationsLoading(true);
+      const response = await getComplianceViolations({
+        status: violationStatus,
+        node_id: violationNode,
+        page_size: 200,
+      });
+      setViolations(response.data || []);
+    } catch (error) {
+      console.error("加载违规记录失败", error);
+    } finally {
+      setViolationsLoading(false);
+    }
+  };
+
+  const loadNodes = async () => {
+    try {
+      const response = await getNodes();
+      setNodes(response.data || []);
+    } catch (error) {
+      console.error("加载节点列表失败", error);
+    }
+  };
+
+  const handleCheckNode = async (nodeId) => {
+    try {
+      setCheckingNode(nodeId);
+      const response = await checkNodeCompliance(nodeId);
+      message.info(response.message);
+      loadAll();
+      loadViolations();
+    } catch (error) {
+      console.error("合规检查失败", error);
+    } finally {
+      setCheckingNode(null);
+    }
+  };
+
+  const handleAdd = () => {
+    setEditing(null);
+    form.resetFields();
+    form.setFieldsValue({
+      operator: "eq",
+      severity: "warning",
+      enabled: true,
+      auto_remediate: false,
+    });
+    setModalVisible(true);
+  };
+
+  const handleEdit = (record) => {
+    setEditing(record);
+    form.setFieldsValue({
+      ...record,
+      node_ids: parseNodeIds(record.node_ids),
+    });
+    setModalVisible(true);
+  };
+
+  const handleDelete = async (id) => {
+    try {
+      await deleteCompliancePolicy(id);
+      message.success("删除成功");
+      loadAll();
+      loadViolations();
+    } catch (error) {
+      console.error("删除合规策略失败", error);
+    }
+  };
+
+  const handleSubmit = async () => {
+    try {
+      const values = await form.validateFields();
+      setSubmitLoading(true);
+      if (editing) {
+        await updateCompliancePolicy(editing.id, values);
+        message.success("更新成功");
+      } else {
+        await addCompliancePolicy(values);
+        message.success("添加成功");
+      }
+      setModalVisible(false);
+      loadAll();
+    } catch (error) {
+      if (error.errorFields) {
+        return;
+      }
+      console.error("保存合规策略失败", error);
+    } finally {
+      setSubmitLoading(false);
+    }
+  };
+
+  const renderScope = (record) => {
+    const ids = parseNodeIds(record.node_ids);
+    if (ids.length === 0 && !record.tag) {
+      return <Tag color="purple">全部节点</Tag>;
+    }
+    return (
+      <Space size={4} wrap>
+        {ids.map((id) => (
+          <Tag key={id}>{nodes.find((n) => n.id === id)?.name || `#${id}`}</Tag>
+        ))}
+        {record.tag && <Tag color="cyan">标签: {record.tag}</Tag>}
+      </Space>
+    );
+  };
+
+  const nodeColumns = [
+    {
+      title: "节点",
+      dataIndex: "node_name",
+      key: "node_name",
+      width: 160,
+    },
+    {
+      title: "结果",
+      key: "result",
+      render: (_, record) => {
+        if (record.error) {
+          return (
+            <Tooltip title={record.error}>
+              <Tag color="default">检查失败</Tag>
+            </Tooltip>
+          );
+        }
+        if (record.violations === 0) {
+          return <Tag color="success">合规（{record.policies} 条策略）</Tag>;
+        }
+        return (
+          <Space size={4}>
+            {record.critical > 0 && <Tag color="red">严重 {record.critical}</Tag>}
+            {record.warning > 0 && <Tag color="orange">警告 {record.warning}</Tag>}
+            {record.info > 0 && <Tag color="blue">提示 {record.info}</Tag>}
+            <span style={{ color: "#666", fontSize: "12px" }}>
+              {record.violations}/{record.policies} 条未通过
+            </span>
+          </Space>
+        );
+      },
+    },
+    {
+      title: "检查时间",
+      dataIndex: "checked_at",
+      key: "checked_at",
+      width: 140,
+      render: (time) => dayjs(time).format("MM-DD HH:mm"),
+    },
+    {
+      title: "操作",
+      key: "action",
+      width: 150,
+      render: (_, record) => (
+        <Space size="small">
+          <Button
+            type="link"
+            size="small"
+            loading={checkingNode === record.node_id}
+            onClick={() => handleCheckNode(record.node_id)}
+          >
+            立即检查
+          </Button>
+          <Button
+            type="link"
+            size="small"
+            onClick={() => setViolationNode(record.node_id)}
+          >
+            查看违规
+          </Button>
+        </Space>
+      ),
+    },
+  ];
+
+  const violationColumns = [
+    {
+      title: "节点",
+      dataIndex: "node_name",
+      key: "node_name",
+      width: 140,
+    },
+    {
+      title: "策略",
+      dataIndex: "policy_name",
+      key: "policy_name",
+      width: 160,
+    },
+    {
+      title: "级别",
+      dataIndex: "severity",
+      key: "severity",
+      width: 80,
+      render: renderSeverity,
+    },
+    {
+      title: "要求",
+      dataIndex: "expected",
+      key: "expected",
+      render: (text) => <code>{text}</code>,
+    },
+    {
+      title: "实际",
+      dataIndex: "actual",
+      key: "actual",
+      render: (text, record) => (
+        <Space direction="vertical" size={0}>
+          <code>{text}</code>
+          {record.remediation_error && (
+            <span style={{ color: "#cf1322", fontSize: "12px" }}>
+              {record.remediation_error}
+            </span>
+          )}
+        </Space>
+      ),
+    },
+    {
+      title: "状态",
+      key: "status",
+      width: 110,
+      render: (_, record) => {
+        if (record.status === "open") {
+          return <Tag color="error">未解决</Tag>;
+        }
+        return record.remediated_at ? (
+          <Tag color="green">已自动修复</Tag>
+        ) : (
+          <Tag color="success">已解决</Tag>
+        );
+      },
+    },
+    {
+      title: "首次发现 / 最近",
+      key: "seen",
+      width: 170,
+      render: (_, record) => (
+        <span style={{ fontSize: "12px" }}>
+          {dayjs(record.first_seen_at).format("MM-DD HH:mm")} /{" "}
+          {dayjs(record.resolved_at || record.last_seen_at).format("MM-DD HH:mm")}
+        </span>
+      ),
+    },
+  ];
+
+  const policyColumns = [
+    {
+      title: "名称",
+      dataIndex: "name",
+      key: "name",
+      width: 160,
+      render: (text, record) => (
+        <Tooltip title={record.description || undefined}>
+          <Tag color="blue">{text}</Tag>
+        </Tooltip>
+      ),
+    },
+    {
+      title: "要求",
+      key: "rule",
+      render: (_, record) => <code>{describePolicy(record)}</code>,
+    },
+    {
+      title: "级别",
+      dataIndex: "severity",
+      key: "severity",
+      width: 80,
+      render: renderSeverity,
+    },
+    {
+      title: "适用节点",
+      key: "scope",
+      render: (_, record) => renderScope(record),
+    },
+    {
+      title: "自动修复",
+      key: "auto_remediate",
+      width: 120,
+      render: (_, record) =>
+        record.auto_remediate ? (
+          <Tooltip title={record.fix_value ? `修复为 ${record.fix_value}` : "完整同步"}>
+            <Tag color="green">开启</Tag>
+          </Tooltip>
+        ) : (
+          <Tag>关闭</Tag>
+        ),
+    },
+    {
+      title: "违规节点",
+      dataIndex: "open_violations",
+      key: "open_violations",
+      width: 90,
+      render: (count, record) =>
+        !record.enabled ? (
+          <Tag>已禁用</Tag>
+        ) : count > 0 ? (
+          <Tag color="error">{count}</Tag>
+        ) : (
+          <Tag color="success">0</Tag>
+        ),
+    },
+    {
+      title: "操作",
+      key: "action",
+      width: 110,
+      render: (_, record) => (
+        <Space size="small">
+          <Tooltip title="编辑">
+            <Button
+              type="link"
+              size="small"
+              icon={<EditOutlined />}
+              onClick={() => handleEdit(record)}
+            />
+          </Tooltip>
+          <Popconfirm
+            title="确定删除该策略及其违规记录吗？"
+            onConfirm={() => handleDelete(record.id)}
+            okText="确定"
+            cancelText="取消"
+          >
+            <Tooltip title="删除">
+              <Button type="link" size="small" danger icon={<DeleteOutlined />} />
+            </Tooltip>
+          </Popconfirm>
+        </Space>
+      ),
+    },
+  ];
+
+  const needsValue = operator && operator !== "present" && operator !== "absent";
+
+  return (
+    <div>
+      <Row gutter={16} style={{ marginBottom: 16 }}>
+        <Col span={6}>
+          <Card size="small">
+            <Statistic title="启用的策略" value={summary?.policies || 0} />
+          </Card>
+        </Col>
+        <Col span={6}>
+          <Card size="small">
+            <Statistic
+              title="合规节点"
+              value={summary?.compliant_nodes || 0}
+              suffix={`/ ${summary?.checked_nodes || 0}`}
+              prefix={<SafetyCertificateOutlined />}
+              valueStyle={{ color: "#3f8600" }}
+            />
+          </Card>
+        </Col>
+        <Col span={6}>
+          <Card size="small">
+            <Statistic
+              title="未解决违规"
+              value={summary?.open_violations || 0}
+              valueStyle={{ color: summary?.open_violations ? "#cf1322" : undefined }}
+            />
+          </Card>
+        </Col>
+        <Col span={6}>
+          <Card size="small">
+            <Statistic
+              title="严重 / 警告 / 提示"
+              value={`${summary?.by_severity?.critical || 0} / ${
+                summary?.by_severity?.warning || 0
+              } / ${summary?.by_severity?.info || 0}`}
+            />
+          </Card>
+        </Col>
+      </Row>
+
+      <Tabs
+        items={[
+          {
+            key: "nodes",
+            label: "节点合规",
+            children: (
+              <>
+                <div style={{ marginBottom: 16 }}>
+                  <Space>
+                    <Button icon={<ReloadOutlined />} onClick={loadAll}>
+                      刷新
+                    </Button>
+                    <span style={{ color: "#666" }}>
+                      由定时任务「配置合规检查」定期检查，
+                      {summary?.failed_nodes ? `${summary.failed_nodes} 个节点检查失败` : "也可对单个节点立即检查"}
+                    </span>
+                  </Space>
+                </div>
+                <Table
+                  columns={nodeColumns}
+                  dataSource={summary?.nodes || []}
+                  rowKey="node_id"
+                  loading={loading}
+                  pagination={{ pageSize: 20 }}
+                />
+                {summary?.by_policy?.length > 0 && (
+                  <>
+                    <div style={{ margin: "24px 0 16px" }}>
+                      <strong>按策略统计</strong>
+                    </div>
+                    <Table
+                      size="small"
+                      columns={[
+                        { title: "策略", dataIndex: "policy_name", key: "policy_name" },
+                        { title: "级别", dataIndex: "severity", key: "severity", width: 80, render: renderSeverity },
+                        { title: "违规节点数", dataIndex: "violating_nodes", key: "violating_nodes", width: 110 },
+                      ]}
+                      dataSource={summary.by_policy}
+                      rowKey="policy_id"
+                      pagination={false}
+                    />
+                  </>
+                )}
+              </>
+            ),
+          },
+          {
+            key: "violations",
+            label: "违规记录",
+            children: (
+              <>
+                <div style={{ marginBottom: 16 }}>
+                  <Space>
+                    <Select
+                      value={violationNode}
+                      onChange={setViolationNode}
+                      allowClear
+                      placeholder="全部节点"
+                      style={{ width: 180 }}
+                    >
+                      {nodes.map((node) => (
+                        <Option key={node.id} value={node.id}>
+                          {node.name}
+                        </Option>
+                      ))}
+                    </Select>
+                    <Select value={violationStatus} onChange={setViolationStatus} style={{ width: 120 }}>
+                      <Option value="open">未解决</Option>
+                      <Option value="resolved">已解决</Option>
+                      <Option value="all">全部</Option>
+                    </Select>
+                    <Button icon={<ReloadOutlined />} onClick={loadViolations}>
+                      刷新
+                    </Button>
+                  </Space>
+                </div>
+                <Table
+                  columns={violationColumns}
+                  dataSource={violations}
+                  rowKey="id"
+                  loading={violationsLoading}
+                  pagination={{ pageSize: 20 }}
+                />
+              </>
+            ),
+          },
+          {
+            key: "policies",
+            label: "策略",
+            children: (
+              <>
+                <Alert
+                  type="info"
+                  showIcon
+                  style={{ marginBottom: 16 }}
+                  message="策略按节点解析后的配置求值。指令填写 SmartDNS 配置项名称（如 log-level、cache-size），server-group 表示所有上游服务器的分组。开启自动修复后，不合规的节点会执行一次完整同步，并把指令改为修复值（不允许配置的指令会被删除）。"
+                />
+                <div style={{ marginBottom: 16 }}>
+                  <Button type="primary" icon={<PlusOutlined />} onClick={handleAdd}>
+                    添加策略
+                  </Button>
+                  <span style={{ marginLeft: 16, color: "#666" }}>
+                    共 {policies.length} 条策略
+                  </span>
+                </div>
+                <Table
+                  columns={policyColumns}
+                  dataSource={policies}
+                  rowKey="id"
+                  loading={loading}
+                  pagination={false}
+                />
+              </>
+            ),
+          },
+        ]}
+      />
+
+      <Modal
+        title={editing ? "编辑合规策略" : "添加合规策略"}
+        open={modalVisible}
+        onOk={handleSubmit}
+        onCancel={() => setModalVisible(false)}
+        width={720}
+        okText="确定"
+        cancelText="取消"
+        confirmLoading={submitLoading}
+      >
+        <Form form={form} layout="vertical">
+          <Form.Item
+            name="name"
+            label="名称"
+            rules={[{ required: true, message: "请输入策略名称" }]}
+          >
+            <Input placeholder="例如: 日志级别不低于 info" />
+          </Form.Item>
+          <Form.Item name="description" label="描述">
+            <Input />
+          </Form.Item>
+          <Row gutter={16}>
+            <Col span={8}>
+              <Form.Item
+                name="directive"
+                label="配置指令"
+                rules={[{ required: true, message: "请输入配置指令" }]}
+              >
+                <Input placeholder="log-level / cache-size / server-group" />
+              </Form.Item>
+            </Col>
+            <Col span={8}>
+              <Form.Item name="operator" label="比较方式" rules={[{ required: true }]}>
+                <Select options={operatorOptions} />
+              </Form.Item>
+            </Col>
+            <Col span={8}>
+              {needsValue && (
+                <Form.Item
+                  name="value"
+                  label="比较值"
+                  rules={[{ required: true, message: "请输入比较值" }]}
+                >
+                  <Input placeholder={operator === "in" ? "info,notice,warn" : "例如 4096"} />
+                </Form.Item>
+              )}
+            </Col>
+          </Row>
+          <Form.Item name="severity" label="严重程度">
+            <Select>
+              <Option value="critical">严重</Option>
+              <Option value="warning">警告</Option>
+              <Option value="info">提示</Option>
+            </Select>
+          </Form.Item>
+          <Form.Item
+            name="node_ids"
+            label="适用节点"
+            extra="节点和标签都不填时对全部节点生效"
+          >
+            <Select
+              mode="multiple"
+              allowClear
+              placeholder="选择节点"
+              optionFilterProp="children"
+            >
+              {nodes.map((node) => (
+                <Option key={node.id} value={node.id}>
+                  {node.name}
+                </Option>
+              ))}
+            </Select>
+          </Form.Item>
+          <Form.Item name="tag" label="节点标签">
+            <Input placeholder="带有该标签的节点也适用此策略" />
+          </Form.Item>
+          <Row gutter={16}>
+            <Col span={8}>
+              <Form.Item name="auto_remediate" label="自动修复" valuePropName="checked">
+                <Switch />
+              </Form.Item>
+            </Col>
+            <Col span={16}>
+              {autoRemediate && operator !== "absent" && (
+                <Form.Item
+                  name="fix_value"
+                  label="修复值"
+                  extra="留空时只执行完整同步，不修改该指令"
+                >
+                  <Input placeholder="例如 info" />
+                </Form.Item>
+              )}
+            </Col>
+          </Row>
+          <Form.Item name="enabled" label="启用" valuePropName="checked">
+            <Switch />
+          </Form.Item>
+        </Form>
+      </Modal>
+    </div>
+  );
+};
+
+export default ComplianceManager;
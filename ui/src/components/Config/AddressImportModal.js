@@ -0,0 +1,344 @@
+import React, { useState } from "react";
+import {
+  Modal,
+  Form,
+  Select,
+  Input,
+  Upload,
+  Button,
+  Alert,
+  Table,
+  Tag,
+  Tabs,
+  Space,
+  Statistic,
+  Row,
+  Col,
+  message,
+} from "antd";
+import { UploadOutlined } from "@ant-design/icons";
+import { previewImportAddresses, importAddresses } from "../../api";
+
+const { Option } = Select;
+
+const formatNames = {
+  smartdns: "SmartDNS",
+  hosts: "hosts",
+  dnsmasq: "dnsmasq",
+  adguard: "AdGuard",
+  plain: "纯域名",
+};
+
+const statusColors = {
+  new: "green",
+  duplicate: "default",
+  conflict: "orange",
+};
+
+const statusNames = {
+  new: "新增",
+  duplicate: "重复",
+  conflict: "冲突",
+};
+
+// 导入 hosts / dnsmasq / AdGuard 列表，先预览冲突再确认导入
+const AddressImportModal = ({ visible, nodes, onClose, onImported }) => {
+  const [form] = Form.useForm();
+  const [content, setContent] = useState("");
+  const [fileName, setFileName] = useState("");
+  const [preview, setPreview] = useState(null);
+  const [previewing, setPreviewing] = useState(false);
+  const [importing, setImporting] = useState(false);
+
+  const reset = () => {
+    form.resetFields();
+    setContent("");
+    setFileName("");
+    setPreview(null);
+  };
+
+  const handleClose = () => {
+    reset();
+    onClose();
+  };
+
+  const loadPreview = async (text, format) => {
+    try {
+      setPreviewing(true);
+      const response = await previewImportAddresses({ content: text, format });
+      setPreview(response.data);
+      // AdGuard 列表通常是大量屏蔽规则，默认建议写入域名集
+      if (
+        response.data?.formats?.adguard > 0 &&
+        !form.getFieldValue("block_domain_set")
+      ) {
+        form.setFieldsValue({ block_domain_set: "adguard_block" });
+      }
+    } catch (error) {
+      message.error("解析失败: " + (error.response?.data?.message || error.message));
+      setPreview(null);
+    } finally {
+      setPreviewing(false);
+    }
+  };
+
+  const handleFile = (file) => {
+    const reader = new FileReader();
+    reader.onload = (e) => {
+      const text = e.target.result;
+      setContent(text);
+      setFileName(file.name);
+      loadPreview(text, form.getFieldValue("format"));
+    };
+    reader.readAsText(file);
+    return false;
+  };
+
+  const handleFormatChange = (format) => {
+    if (content) {
+      loadPreview(content, format);
+    }
+  };
+
+  const handleImport = async () => {
+    try {
+      const values = await form.validateFields();
+      setImporting(true);
+      const response = await importAddresses({
+        content,
+        format: values.format,
+        node_ids: values.node_ids || [],
+        conflict_mode: values.conflict_mode,
+        block_domain_set: values.block_domain_set || "",
+      });
+      message.success(
+        `导入完成：新增 ${response.created} 条，更新 ${response.updated} 条，跳过 ${response.skipped} 条` +
+          (response.blocked ? `，屏蔽 ${response.blocked} 个域名` : "")
+      );
+      reset();
+      onImported();
+    } catch (error) {
+      if (error.errorFields) return;
+      message.error("导入失败: " + (error.response?.data?.message || error.message));
+    } finally {
+      setImporting(false);
+    }
+  };
+
+  const entryColumns = [
+    { title: "行", dataIndex: "line", key: "line", width: 70 },
+    { title: "域名", dataIndex: "domain", key: "domain", ellipsis: true },
+    {
+      title: "值",
+      key: "value",
+      ellipsis: true,
+      render: (_, record) =>
+        record.type === "cname" ? `cname ${record.cname}` : record.ip,
+    },
+    {
+      title: "现有记录",
+      dataIndex: "existing",
+      key: "existing",
+      ellipsis: true,
+      render: (text) => text || "-",
+    },
+    {
+      title: "状态",
+      dataIndex: "status",
+      key: "status",
+      width: 80,
+      render: (status) => (
+        <Tag color={statusColors[status]}>{statusNames[status] || status}</Tag>
+      ),
+    },
+  ];
+
+  const issueColumns = [
+    { title: "行", dataIndex: "line", key: "line", width: 70 },
+    { title: "内容", dataIndex: "content", key: "content", ellipsis: true },
+    { title: "原因", dataIndex: "reason", key: "reason", width: 220 },
+  ];
+
+  const conflicts = (preview?.entries || []).filter(
+    (e) => e.status === "conflict"
+  );
+
+  return (
+    <Modal
+      title="导入地址映射"
+      open={visible}
+      onCancel={handleClose}
+      width={900}
+      footer={[
+        <Button key="cancel" onClick={handleClose}>
+          取消
+        </Button>,
+        <Button
+          key="import"
+          type="primary"
+          loading={importing}
+          disabled={!preview || preview.entries.length === 0}
+          onClick={handleImport}
+        >
+          确认导入
+        </Button>,
+      ]}
+    >
+      <Form
+        form={form}
+        layout="vertical"
+        initialValues={{ format: "auto", conflict_mode: "skip" }}
+      >
+        <Row gutter={16}>
+          <Col span={8}>
+            <Form.Item name="format" label="格式">
+              <Select onChange={handleFormatChange}>
+                <Option value="auto">自动识别</Option>
+                <Option value="smartdns">SmartDNS</Option>
+                <Option value="hosts">hosts 文件</Option>
+                <Option value="dnsmasq">dnsmasq</Option>
+                <Option value="adguard">AdGuard / ABP</Option>
+                <Option value="plain">纯域名列表（屏蔽）</Option>
+              </Select>
+            </Form.Item>
+          </Col>
+          <Col span={8}>
+            <Form.Item
+              name="conflict_mode"
+              label="冲突处理"
+              tooltip="域名已存在且值不同时的处理方式"
+            >
+              <Select>
+                <Option value="skip">跳过</Option>
+                <Option value="overwrite">覆盖现有记录</Option>
+                <Option value="merge">合并 IP</Option>
+              </Select>
+            </Form.Item>
+          </Col>
+          <Col span={8}>
+            <Form.Item
+              name="block_domain_set"
+              label="屏蔽域名写入域名集"
+              tooltip="填写后屏蔽类条目写入该域名集并生成一条屏蔽规则，适合大型屏蔽列表；留空则逐条导入为地址映射"
+            >
+              <Input placeholder="例如: adguard_block" />
+            </Form.Item>
+          </Col>
+        </Row>
+
+        <Form.Item name="node_ids" label="应用到节点" extra="不选择则应用到所有节点">
+          <Select mode="multiple" placeholder="选择节点" allowClear>
+            {nodes.map((node) => (
+              <Option key={node.id} value={node.id}>
+                {node.name}
+              </Option>
+            ))}
+          </Select>
+        </Form.Item>
+
+        <Form.Item label="列表文件">
+          <Space>
+            <Upload
+              accept=".conf,.txt,.hosts,.list"
+              showUploadList={false}
+              beforeUpload={handleFile}
+            >
+              <Button icon={<UploadOutlined />} loading={previewing}>
+                选择文件
+              </Button>
+            </Upload>
+            {fileName && <span>{fileName}</span>}
+          </Space>
+        </Form.Item>
+      </Form>
+
+      {preview && (
+        <>
+          <Row gutter={16} style={{ marginBottom: 16 }}>
+            <Col span={4}>
+              <Statistic title="新增" value={preview.new} />
+            </Col>
+            <Col span={4}>
+              <Statistic title="重复" value={preview.duplicate} />
+            </Col>
+            <Col span={4}>
+              <Statistic title="冲突" value={preview.conflict} />
+            </Col>
+            <Col span={4}>
+              <Statistic title="屏蔽" value={preview.blocked} />
+            </Col>
+            <Col span={4}>
+              <Statistic title="无法导入" value={preview.issues.length} />
+            </Col>
+          </Row>
+
+          {Object.keys(preview.formats || {}).length > 0 && (
+            <div style={{ marginBottom: 16 }}>
+              识别格式：
+              {Object.entries(preview.formats).map(([format, count]) => (
+                <Tag key={format}>
+                  {formatNames[format] || format}: {count}
+                </Tag>
+              ))}
+            </div>
+          )}
+
+          {preview.conflict > 0 && (
+            <Alert
+              type="warning"
+              showIcon
+              style={{ marginBottom: 16 }}
+              message={`${preview.conflict} 个域名与现有记录冲突，将按「冲突处理」设置导入`}
+            />
+          )}
+
+          <Tabs
+            items={[
+              {
+                key: "conflict",
+                label: `冲突 (${conflicts.length})`,
+                children: (
+                  <Table
+                    size="small"
+                    rowKey={(record) => `${record.line}-${record.domain}`}
+                    columns={entryColumns}
+                    dataSource={conflicts}
+                    pagination={{ pageSize: 10 }}
+                  />
+                ),
+              },
+              {
+                key: "entries",
+                label: `全部条目 (${preview.entries.length})`,
+                children: (
+                  <Table
+                    size="small"
+                    rowKey={(record) => `${record.line}-${record.domain}`}
+                    columns={entryColumns}
+                    dataSource={preview.entries}
+                    pagination={{ pageSize: 10 }}
+                  />
+                ),
+              },
+              {
+                key: "issues",
+                label: `无法导入 (${preview.issues.length})`,
+                children: (
+                  <Table
+                    size="small"
+                    rowKey={(record) => `${record.line}-${record.content}`}
+                    columns={issueColumns}
+                    dataSource={preview.issues}
+                    pagination={{ pageSize: 10 }}
+                  />
+                ),
+              },
+            ]}
+          />
+        </>
+      )}
+    </Modal>
+  );
+};
+
+export default AddressImportModal;
@@ -9,9 +9,9 @@ import {
   Input,
   message,
   Popconfirm,
-  Upload,
   Select,
   Switch,
+  InputNumber,
   Badge,
   Tooltip,
 } from "antd";
@@ -31,13 +31,13 @@ import {
   updateAddress,
   deleteAddress,
   batchAddAddresses,
-  importAddresses,
   getNodes,
   triggerFullSync,
   batchFullSync,
 } from "../../api";
 import dayjs from "dayjs";
 import SyncStatus from "./SyncStatus";
+import AddressImportModal from "./AddressImportModal";
 
 const { Option } = Select;
 
@@ -48,6 +48,7 @@ const AddressManager = () => {
   const [modalVisible, setModalVisible] = useState(false);
   const [batchModalVisible, setBatchModalVisible] = useState(false);
   const [syncStatusVisible, setSyncStatusVisible] = useState(false);
+  const [importModalVisible, setImportModalVisible] = useState(false);
   const [editingAddress, setEditingAddress] = useState(null);
   const [selectedNodeId, setSelectedNodeId] = useState(null);
   const [selectedRowKeys, setSelectedRowKeys] = useState([]);
@@ -182,20 +183,9 @@ const AddressManager = () => {
     }
   };
 
-  const handleImport = async (file) => {
-    const reader = new FileReader();
-    reader.onload = async (e) => {
-      try {
-        const content = e.target.result;
-        await importAddresses({ content, format: "smartdns" });
-        message.success("导入成功，正在同步到节点...");
-        loadAddresses();
-      } catch (error) {
-        message.error("导入失败");
-      }
-    };
-    reader.readAsText(file);
-    return false;
+  const handleImported = () => {
+    setImportModalVisible(false);
+    loadAddresses();
   };
 
   const handleExport = () => {
@@ -293,7 +283,19 @@ const AddressManager = () => {
       dataIndex: "ip",
       key: "ip",
       width: 200,
-      render: (text) => <code>{text}</code>,
+      render: (text, record) => (
+        <Space size={[0, 4]} wrap>
+          {(text || "")
+            .split(",")
+            .filter(Boolean)
+            .map((ip) => (
+              <Tag key={ip} color={ip.includes(":") ? "purple" : "default"}>
+                {ip}
+              </Tag>
+            ))}
+          {record.ttl > 0 && <Tag color="orange">TTL {record.ttl}</Tag>}
+        </Space>
+      ),
     },
   {
     title: "应用节点",
@@ -418,13 +420,12 @@ const AddressManager = () => {
           <Button icon={<PlusOutlined />} onClick={handleBatchAdd}>
             批量添加
           </Button>
-          <Upload
-            accept=".conf,.txt"
-            showUploadList={false}
-            beforeUpload={handleImport}
+          <Button
+            icon={<UploadOutlined />}
+            onClick={() => setImportModalVisible(true)}
           >
-            <Button icon={<UploadOutlined />}>导入</Button>
-          </Upload>
+            导入
+          </Button>
           <Button
             icon={<DownloadOutlined />}
             onClick={handleExport}
@@ -507,12 +508,13 @@ const AddressManager = () => {
               { required: true, message: "请输入域名" },
               {
                 pattern:
-                  /^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$/,
+                  /^(\*\.)?[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*$/,
                 message: "请输入有效的域名",
               },
             ]}
+            extra="*.a.com 仅匹配子域名"
           >
-            <Input placeholder="例如: a.com" />
+            <Input placeholder="例如: a.com 或 *.a.com" />
           </Form.Item>
 
           <Form.Item
@@ -524,19 +526,34 @@ const AddressManager = () => {
 
               if (type === "address") {
                 return (
-                  <Form.Item
-                    name="ip"
-                    label="IP地址"
-                    rules={[
-                      { required: true, message: "请输入IP地址" },
-                      {
-                        pattern: /^(\d{1,3}\.){3}\d{1,3}$/,
-                        message: "请输入有效的IP地址",
-                      },
-                    ]}
-                  >
-                    <Input placeholder="例如: 192.168.1.1" />
-                  </Form.Item>
+                  <>
+                    <Form.Item
+                      name="ip"
+                      label="IP地址"
+                      rules={[{ required: true, message: "请输入IP地址" }]}
+                      extra="多个 IP 用逗号或换行分隔，可同时填写 IPv4 和 IPv6；# 表示屏蔽，#6 表示仅屏蔽 AAAA"
+                    >
+                      <Input.TextArea
+                        rows={2}
+                        placeholder="例如: 192.168.1.1,192.168.1.2,fd00::1"
+                      />
+                    </Form.Item>
+                    <Form.Item
+                      name="ttl"
+                      label="TTL (秒)"
+                      extra="留空或 0 使用全局设置"
+                    >
+                      <InputNumber min={0} style={{ width: "100%" }} />
+                    </Form.Item>
+                    <Form.Item
+                      name="no_serve_expired"
+                      label="过期缓存"
+                      valuePropName="checked"
+                      extra="开启后不返回过期的缓存结果"
+                    >
+                      <Switch checkedChildren="不返回" unCheckedChildren="默认" />
+                    </Form.Item>
+                  </>
                 );
               } else {
                 return (
@@ -645,6 +662,14 @@ cdn.example.com cname cdn.cloudflare.com`}
         </Form>
       </Modal>
 
+      {/* 导入 Modal */}
+      <AddressImportModal
+        visible={importModalVisible}
+        nodes={nodes}
+        onClose={() => setImportModalVisible(false)}
+        onImported={handleImported}
+      />
+
       {/* 同步状态 Modal */}
       <SyncStatus
         visible={syncStatusVisible}
@@ -193,7 +193,7 @@ const SyncStatus = ({ visible, onClose, nodeId }) => {
       fixed: 'right',
       render: (_, record) => (
         <Space size="small">
-          {record.status === 'failed' && (
+          {record.status === 'failed' && record.action !== 'delete' && (
             <Button
               type="link"
               size="small"
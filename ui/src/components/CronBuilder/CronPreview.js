@@ -0,0 +1,56 @@
+import React, { useState, useEffect } from 'react';
+import { Typography, Space } from 'antd';
+import dayjs from 'dayjs';
+import { validateCron } from '../../api/modules/scheduler';
+
+const { Text } = Typography;
+
+// 按服务端调度器的解析规则校验 Cron 表达式，并显示之后几次执行时间
+const CronPreview = ({ expr }) => {
+  const [preview, setPreview] = useState(null);
+  const [error, setError] = useState('');
+
+  useEffect(() => {
+    if (!expr) {
+      setPreview(null);
+      setError('');
+      return undefined;
+    }
+    const timer = setTimeout(async () => {
+      try {
+        const response = await validateCron(expr);
+        if (response.data.valid) {
+          setPreview(response.data);
+          setError('');
+        } else {
+          setPreview(null);
+          setError(response.data.error);
+        }
+      } catch (err) {
+        setPreview(null);
+      }
+    }, 400);
+    return () => clearTimeout(timer);
+  }, [expr]);
+
+  if (error) {
+    return <Text type="danger" style={{ fontSize: 12 }}>{error}</Text>;
+  }
+  if (!preview) {
+    return null;
+  }
+  return (
+    <Space direction="vertical" size={0} style={{ fontSize: 12 }}>
+      <Text type="secondary" style={{ fontSize: 12 }}>
+        接下来的执行时间（服务器时区 {preview.timezone}）：
+      </Text>
+      {preview.next_runs.map((run) => (
+        <Text key={run} style={{ fontSize: 12 }}>
+          {dayjs(run).format('YYYY-MM-DD HH:mm:ss')}
+        </Text>
+      ))}
+    </Space>
+  );
+};
+
+export default CronPreview;
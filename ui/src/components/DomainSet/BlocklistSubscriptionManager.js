@@ -0,0 +1,370 @@
+import React, { useState, useEffect } from "react";
+import {
+  Table,
+  Button,
+  Space,
+  Tag,
+  Modal,
+  Form,
+  Input,
+  InputNumber,
+  Select,
+  Switch,
+  message,
+  Popconfirm,
+  Tooltip,
+  Alert,
+} from "antd";
+import {
+  PlusOutlined,
+  EditOutlined,
+  DeleteOutlined,
+  SyncOutlined,
+} from "@ant-design/icons";
+import {
+  getBlocklistSubscriptions,
+  addBlocklistSubscription,
+  updateBlocklistSubscription,
+  deleteBlocklistSubscription,
+  refreshBlocklistSubscription,
+  getDomainSets,
+} from "../../api";
+import dayjs from "dayjs";
+
+const { Option } = Select;
+
+const formatOptions = [
+  { value: "auto", label: "自动识别" },
+  { value: "hosts", label: "hosts（0.0.0.0 example.com）" },
+  { value: "adguard", label: "AdGuard（||example.com^）" },
+  { value: "dnsmasq", label: "dnsmasq（address=/example.com/）" },
+  { value: "plain", label: "纯域名列表" },
+];
+
+const statusTags = {
+  success: { color: "success", text: "已更新" },
+  unchanged: { color: "processing", text: "无变化" },
+  failed: { color: "error", text: "失败" },
+};
+
+const formatInterval = (minutes) => {
+  if (minutes % 1440 === 0) return `${minutes / 1440} 天`;
+  if (minutes % 60 === 0) return `${minutes / 60} 小时`;
+  return `${minutes} 分钟`;
+};
+
+const BlocklistSubscriptionManager = () => {
+  const [subscriptions, setSubscriptions] = useState([]);
+  const [domainSets, setDomainSets] = useState([]);
+  const [loading, setLoading] = useState(false);
+  const [modalVisible, setModalVisible] = useState(false);
+  const [editing, setEditing] = useState(null);
+  const [submitLoading, setSubmitLoading] = useState(false);
+  const [refreshingId, setRefreshingId] = useState(null);
+  const [form] = Form.useForm();
+
+  useEffect(() => {
+    loadSubscriptions();
+    loadDomainSets();
+  }, []);
+
+  const loadSubscriptions = async () => {
+    try {
+      setLoading(true);
+      const response = await getBlocklistSubscriptions();
+      setSubscriptions(response.data || []);
+    } catch (error) {
+      console.error("加载屏蔽列表订阅失败", error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const loadDomainSets = async () => {
+    try {
+      const response = await getDomainSets();
+      setDomainSets(response.data || []);
+    } catch (error) {
+      console.error("加载域名集失败", error);
+    }
+  };
+
+  const handleAdd = () => {
+    setEditing(null);
+    form.resetFields();
+    form.setFieldsValue({ format: "auto", refresh_interval: 1440, enabled: true });
+    setModalVisible(true);
+  };
+
+  const handleEdit = (record) => {
+    setEditing(record);
+    form.setFieldsValue({
+      name: record.name,
+      url: record.url,
+      format: record.format || "auto",
+      domain_set_id: record.domain_set_id,
+      refresh_interval: record.refresh_interval,
+      enabled: record.enabled,
+    });
+    setModalVisible(true);
+  };
+
+  const handleDelete = async (id) => {
+    try {
+      await deleteBlocklistSubscription(id);
+      message.success("删除成功");
+      loadSubscriptions();
+    } catch (error) {
+      console.error("删除订阅失败", error);
+    }
+  };
+
+  const handleRefresh = async (record) => {
+    try {
+      setRefreshingId(record.id);
+      const response = await refreshBlocklistSubscription(record.id, true);
+      const result = response.data;
+      message.success(
+        `${record.name}: 共 ${result.domain_count} 个域名（新增 ${result.added}，移除 ${result.removed}）`
+      );
+    } catch (error) {
+      console.error("刷新订阅失败", error);
+    } finally {
+      setRefreshingId(null);
+      loadSubscriptions();
+    }
+  };
+
+  const handleSubmit = async () => {
+    try {
+      const values = await form.validateFields();
+      setSubmitLoading(true);
+      if (editing) {
+        await updateBlocklistSubscription(editing.id, values);
+        message.success("更新成功");
+      } else {
+        await addBlocklistSubscription(values);
+        message.success("添加成功，正在下载列表...");
+      }
+      setModalVisible(false);
+      loadSubscriptions();
+    } catch (error) {
+      if (error.errorFields) {
+        return;
+      }
+      console.error("保存订阅失败", error);
+    } finally {
+      setSubmitLoading(false);
+    }
+  };
+
+  const columns = [
+    {
+      title: "名称",
+      dataIndex: "name",
+      key: "name",
+      width: 160,
+      render: (text) => <Tag color="blue">{text}</Tag>,
+    },
+    {
+      title: "订阅地址",
+      dataIndex: "url",
+      key: "url",
+      ellipsis: true,
+      render: (url) => (
+        <Tooltip title={url}>
+          <code style={{ fontSize: "12px" }}>{url}</code>
+        </Tooltip>
+      ),
+    },
+    {
+      title: "目标域名集",
+      dataIndex: "domain_set_name",
+      key: "domain_set_name",
+      width: 150,
+      render: (name) => name || <Tag color="error">已删除</Tag>,
+    },
+    {
+      title: "刷新间隔",
+      dataIndex: "refresh_interval",
+      key: "refresh_interval",
+      width: 100,
+      render: formatInterval,
+    },
+    {
+      title: "域名数量",
+      dataIndex: "domain_count",
+      key: "domain_count",
+      width: 100,
+    },
+    {
+      title: "最近刷新",
+      key: "last_status",
+      width: 220,
+      render: (_, record) => {
+        if (!record.last_fetched_at) {
+          return <Tag>未刷新</Tag>;
+        }
+        const status = statusTags[record.last_status] || {
+          color: "default",
+          text: record.last_status,
+        };
+        return (
+          <Space size={4}>
+            <Tooltip title={record.last_error || undefined}>
+              <Tag color={status.color}>{status.text}</Tag>
+            </Tooltip>
+            <span style={{ color: "#666", fontSize: "12px" }}>
+              {dayjs(record.last_fetched_at).format("MM-DD HH:mm")}
+            </span>
+          </Space>
+        );
+      },
+    },
+    {
+      title: "状态",
+      dataIndex: "enabled",
+      key: "enabled",
+      width: 80,
+      render: (enabled) => (
+        <Tag color={enabled ? "success" : "default"}>
+          {enabled ? "启用" : "禁用"}
+        </Tag>
+      ),
+    },
+    {
+      title: "操作",
+      key: "action",
+      fixed: "right",
+      width: 150,
+      render: (_, record) => (
+        <Space size="small">
+          <Tooltip title="立即刷新">
+            <Button
+              type="link"
+              size="small"
+              icon={<SyncOutlined spin={refreshingId === record.id} />}
+              disabled={refreshingId !== null}
+              onClick={() => handleRefresh(record)}
+            />
+          </Tooltip>
+          <Tooltip title="编辑">
+            <Button
+              type="link"
+              size="small"
+              icon={<EditOutlined />}
+              onClick={() => handleEdit(record)}
+            />
+          </Tooltip>
+          <Popconfirm
+            title="删除订阅后域名集保留当前内容，确定删除吗？"
+            onConfirm={() => handleDelete(record.id)}
+            okText="确定"
+            cancelText="取消"
+          >
+            <Tooltip title="删除">
+              <Button
+                type="link"
+                size="small"
+                danger
+                icon={<DeleteOutlined />}
+              />
+            </Tooltip>
+          </Popconfirm>
+        </Space>
+      ),
+    },
+  ];
+
+  return (
+    <div>
+      <Alert
+        type="info"
+        showIcon
+        style={{ marginBottom: 16 }}
+        message="订阅会按刷新间隔下载远程列表，去重后整体替换目标域名集的内容并推送到节点，文件有变化时重启 SmartDNS。需配合引用该域名集的域名规则（如 -address #）才能生效。"
+      />
+      <div style={{ marginBottom: 16 }}>
+        <Button type="primary" icon={<PlusOutlined />} onClick={handleAdd}>
+          添加订阅
+        </Button>
+        <span style={{ marginLeft: 16, color: "#666" }}>
+          共 {subscriptions.length} 个订阅
+        </span>
+      </div>
+      <Table
+        columns={columns}
+        dataSource={subscriptions}
+        rowKey="id"
+        loading={loading}
+        scroll={{ x: 1200 }}
+        pagination={false}
+      />
+      <Modal
+        title={editing ? "编辑屏蔽列表订阅" : "添加屏蔽列表订阅"}
+        open={modalVisible}
+        onOk={handleSubmit}
+        onCancel={() => setModalVisible(false)}
+        width={640}
+        okText="确定"
+        cancelText="取消"
+        confirmLoading={submitLoading}
+      >
+        <Form form={form} layout="vertical">
+          <Form.Item
+            name="name"
+            label="名称"
+            rules={[{ required: true, message: "请输入订阅名称" }]}
+          >
+            <Input placeholder="例如: anti-ad" />
+          </Form.Item>
+          <Form.Item
+            name="url"
+            label="订阅地址"
+            rules={[
+              { required: true, message: "请输入订阅地址" },
+              { type: "url", message: "请输入有效的 http/https 地址" },
+            ]}
+          >
+            <Input placeholder="https://example.com/blocklist.txt" />
+          </Form.Item>
+          <Form.Item name="format" label="列表格式">
+            <Select>
+              {formatOptions.map((opt) => (
+                <Option key={opt.value} value={opt.value}>
+                  {opt.label}
+                </Option>
+              ))}
+            </Select>
+          </Form.Item>
+          <Form.Item
+            name="domain_set_id"
+            label="目标域名集"
+            rules={[{ required: true, message: "请选择目标域名集" }]}
+            extra="域名集的内容将由订阅完全接管，手动添加的域名会在刷新时被覆盖"
+          >
+            <Select placeholder="选择域名集" showSearch optionFilterProp="children">
+              {domainSets.map((ds) => (
+                <Option key={ds.id} value={ds.id}>
+                  {ds.name}
+                </Option>
+              ))}
+            </Select>
+          </Form.Item>
+          <Form.Item
+            name="refresh_interval"
+            label="刷新间隔（分钟）"
+            extra="由「屏蔽列表订阅刷新」定时任务每10分钟检查一次，最小10分钟"
+          >
+            <InputNumber min={10} step={60} style={{ width: "100%" }} />
+          </Form.Item>
+          <Form.Item name="enabled" label="启用" valuePropName="checked">
+            <Switch />
+          </Form.Item>
+        </Form>
+      </Modal>
+    </div>
+  );
+};
+
+export default BlocklistSubscriptionManager;
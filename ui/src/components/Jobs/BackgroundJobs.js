@@ -0,0 +1,254 @@
+import React, { useState, useEffect } from "react";
+import {
+  Table,
+  Button,
+  Space,
+  Tag,
+  Select,
+  message,
+  Popconfirm,
+  Tooltip,
+  Alert,
+  Typography,
+  Progress,
+} from "antd";
+import { ReloadOutlined, RedoOutlined, StopOutlined } from "@ant-design/icons";
+import {
+  getBackgroundJobs,
+  retryBackgroundJob,
+  cancelBackgroundJob,
+} from "../../api";
+import dayjs from "dayjs";
+
+const { Text } = Typography;
+
+const typeLabels = {
+  node_init: "节点初始化",
+  node_uninstall: "卸载 SmartDNS",
+  node_reinstall: "重新安装 SmartDNS",
+  full_sync: "完整同步",
+  dns_log_export: "导出 DNS 日志",
+  agent_deploy: "部署 Agent",
+  database_backup: "数据库备份",
+  smartdns_upgrade: "升级 SmartDNS",
+};
+
+const statusTags = {
+  queued: <Tag>排队中</Tag>,
+  running: <Tag color="processing">执行中</Tag>,
+  succeeded: <Tag color="success">成功</Tag>,
+  failed: <Tag color="error">失败</Tag>,
+  interrupted: <Tag color="warning">已中断</Tag>,
+  cancelled: <Tag color="default">已取消</Tag>,
+};
+
+const retryableStatuses = ["failed", "interrupted", "cancelled"];
+
+const BackgroundJobs = () => {
+  const [jobs, setJobs] = useState([]);
+  const [total, setTotal] = useState(0);
+  const [page, setPage] = useState(1);
+  const [status, setStatus] = useState();
+  const [loading, setLoading] = useState(false);
+
+  useEffect(() => {
+    loadJobs();
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [page, status]);
+
+  const loadJobs = async () => {
+    setLoading(true);
+    try {
+      const response = await getBackgroundJobs({ page, page_size: 20, status });
+      setJobs(response.data || []);
+      setTotal(response.total || 0);
+    } catch (error) {
+      message.error("加载后台任务失败");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleRetry = async (id) => {
+    try {
+      await retryBackgroundJob(id);
+      message.success("任务已重新开始");
+      loadJobs();
+    } catch (error) {
+      message.error(error.response?.data?.message || "重试失败");
+    }
+  };
+
+  const handleCancel = async (id) => {
+    try {
+      const response = await cancelBackgroundJob(id);
+      message.success(response.message || "任务已取消");
+      loadJobs();
+    } catch (error) {
+      message.error(error.response?.data?.message || "取消失败");
+    }
+  };
+
+  const columns = [
+    {
+      title: "ID",
+      dataIndex: "id",
+      width: 70,
+    },
+    {
+      title: "类型",
+      dataIndex: "type",
+      width: 140,
+      render: (type) => typeLabels[type] || type,
+    },
+    {
+      title: "说明",
+      dataIndex: "description",
+    },
+    {
+      title: "状态",
+      dataIndex: "status",
+      width: 100,
+      render: (value) => statusTags[value] || <Tag>{value}</Tag>,
+    },
+    {
+      title: "进度",
+      dataIndex: "progress",
+      width: 160,
+      render: (progress, record) =>
+        record.status === "queued" ? (
+          <Text type="secondary">等待执行</Text>
+        ) : (
+          <Space direction="vertical" size={0} style={{ width: "100%" }}>
+            <Progress
+              percent={progress}
+              size="small"
+              status={
+                record.status === "failed"
+                  ? "exception"
+                  : record.status === "running"
+                  ? "active"
+                  : undefined
+              }
+            />
+            {record.progress_message && (
+              <Text type="secondary" style={{ fontSize: 12 }}>
+                {record.progress_message}
+              </Text>
+            )}
+          </Space>
+        ),
+    },
+    {
+      title: "检查点",
+      dataIndex: "checkpoint",
+      width: 110,
+      render: (checkpoint, record) => (
+        <Space direction="vertical" size={0}>
+          <Text>{checkpoint || "-"}</Text>
+          {record.resumes > 0 && (
+            <Text type="secondary" style={{ fontSize: 12 }}>
+              已自动恢复 {record.resumes} 次
+            </Text>
+          )}
+        </Space>
+      ),
+    },
+    {
+      title: "错误 / 处理建议",
+      dataIndex: "error",
+      render: (error, record) => (
+        <Space direction="vertical" size={0}>
+          {error && <Text type="danger">{error}</Text>}
+          {record.guidance && <Text type="warning">{record.guidance}</Text>}
+        </Space>
+      ),
+    },
+    {
+      title: "创建时间",
+      dataIndex: "created_at",
+      width: 170,
+      render: (time) => dayjs(time).format("YYYY-MM-DD HH:mm:ss"),
+    },
+    {
+      title: "操作",
+      width: 90,
+      render: (_, record) => {
+        if (record.status === "queued" || record.status === "running") {
+          return (
+            <Popconfirm
+              title={
+                record.status === "queued"
+                  ? "取消排队中的任务？"
+                  : "取消执行中的任务？支持取消的任务会在当前步骤完成后停止"
+              }
+              onConfirm={() => handleCancel(record.id)}
+            >
+              <Button type="link" size="small" danger icon={<StopOutlined />}>
+                取消
+              </Button>
+            </Popconfirm>
+          );
+        }
+        return (
+          retryableStatuses.includes(record.status) && (
+            <Popconfirm
+              title="从检查点重新执行该任务？"
+              onConfirm={() => handleRetry(record.id)}
+            >
+              <Tooltip title="从上次完成的步骤继续">
+                <Button type="link" size="small" icon={<RedoOutlined />}>
+                  重试
+                </Button>
+              </Tooltip>
+            </Popconfirm>
+          )
+        );
+      },
+    },
+  ];
+
+  return (
+    <div>
+      <Alert
+        type="info"
+        showIcon
+        style={{ marginBottom: 16 }}
+        message="节点初始化、Agent 部署、完整同步、日志导出和手动数据库备份在后台排队执行（同时执行的数量由 BACKGROUND_JOB_WORKERS 控制）。排队中的任务在服务重启后继续排队，可恢复的任务会从检查点自动继续，无法恢复的任务标记为已中断，请按处理建议检查后重试。"
+      />
+      <Space style={{ marginBottom: 16 }}>
+        <Select
+          allowClear
+          placeholder="全部状态"
+          style={{ width: 140 }}
+          value={status}
+          onChange={(value) => {
+            setPage(1);
+            setStatus(value);
+          }}
+          options={Object.keys(statusTags).map((key) => ({
+            value: key,
+            label: statusTags[key],
+          }))}
+        />
+        <Button icon={<ReloadOutlined />} onClick={loadJobs}>
+          刷新
+        </Button>
+      </Space>
+      <Table
+        rowKey="id"
+        columns={columns}
+        dataSource={jobs}
+        loading={loading}
+        pagination={{
+          current: page,
+          pageSize: 20,
+          total,
+          onChange: setPage,
+        }}
+      />
+    </div>
+  );
+};
+
+export default BackgroundJobs;
@@ -50,6 +50,18 @@ const MainLayout = () => {
           key: "/tasks",
           label: "任务管理",
         },
+        {
+          key: "/maintenance",
+          label: "维护窗口",
+        },
+        {
+          key: "/compliance",
+          label: "配置合规",
+        },
+        {
+          key: "/jobs",
+          label: "后台任务",
+        },
       ],
     },
     {
@@ -14,6 +14,8 @@ import {
   Tooltip,
   Empty,
   Switch,
+  Modal,
+  message,
 } from "antd";
 import {
   SearchOutlined,
@@ -24,8 +26,15 @@ import {
   PauseOutlined,
   GlobalOutlined,
   CloudServerOutlined,
+  ShareAltOutlined,
 } from "@ant-design/icons";
-import { getDNSLogs, getGroups, getServers } from "../../api";
+import {
+  getDNSLogs,
+  getGroups,
+  getServers,
+  getQueryTypes,
+  createShareLink,
+} from "../../api";
 import dayjs from "dayjs";
 
 const { RangePicker } = DatePicker;
@@ -35,6 +44,7 @@ const LogList = ({ nodeId, nodeName }) => {
   const [logs, setLogs] = useState([]);
   const [groups, setGroups] = useState([]);
   const [servers, setServers] = useState([]);
+  const [queryTypes, setQueryTypes] = useState([]);
   const [loading, setLoading] = useState(false);
   const [autoRefresh, setAutoRefresh] = useState(false);
   const [pagination, setPagination] = useState({
@@ -55,6 +65,20 @@ const LogList = ({ nodeId, nodeName }) => {
     loadLogs();
   }, [nodeId, pagination.current, pagination.pageSize, sortInfo.field, sortInfo.order]);
 
+  useEffect(() => {
+    loadQueryTypes();
+  }, []);
+
+  // 加载查询类型对照表
+  const loadQueryTypes = async () => {
+    try {
+      const response = await getQueryTypes();
+      setQueryTypes(response.data || []);
+    } catch (error) {
+      console.error("加载查询类型失败:", error);
+    }
+  };
+
   // 加载分组数据
   const loadGroups = async () => {
     try {
@@ -120,6 +144,10 @@ const LogList = ({ nodeId, nodeName }) => {
         params.end_time = values.time_range[1].toISOString();
         delete params.time_range;
       }
+      // 多个查询类型以逗号分隔，如 A,AAAA
+      if (Array.isArray(values.query_type)) {
+        params.query_type = values.query_type.join(",") || undefined;
+      }
       const response = await getDNSLogs(params);
       setLogs(response.data.logs || []);
       setPagination({
@@ -133,6 +161,44 @@ const LogList = ({ nodeId, nodeName }) => {
     }
   };
 
+  // 按当前过滤条件和时间范围生成只读分享链接
+  const handleShare = async () => {
+    const values = form.getFieldsValue();
+    const filters = { node_id: String(nodeId) };
+    ["client_ip", "domain", "query_type", "group", "rcode", "cache_hit"].forEach((key) => {
+      const value = values[key];
+      if (value !== undefined && value !== null && value !== "") {
+        filters[key] = String(value);
+      }
+    });
+    const data = { kind: "logs", filters, expires_in_hours: 24 };
+    if (values.time_range) {
+      data.start_time = values.time_range[0].toISOString();
+      data.end_time = values.time_range[1].toISOString();
+    }
+    try {
+      const response = await createShareLink(data);
+      const url = `${window.location.origin}${response.path}`;
+      Modal.success({
+        title: "分享链接已生成",
+        width: 560,
+        content: (
+          <div>
+            <p>
+              链接 24 小时内有效，只能查看当前过滤条件和时间范围内的日志。
+            </p>
+            <Input.TextArea value={url} rows={4} readOnly />
+          </div>
+        ),
+      });
+      navigator.clipboard
+        ?.writeText(url)
+        .then(() => message.success("已复制到剪贴板"));
+    } catch (error) {
+      console.error("生成分享链接失败:", error);
+    }
+  };
+
   const handleSearch = () => {
     setPagination({ ...pagination, current: 1 });
     loadLogs();
@@ -232,16 +298,31 @@ const LogList = ({ nodeId, nodeName }) => {
     }
   };
 
-  const getQueryTypeTag = (type) => {
-    const typeMap = {
-      1: { color: "blue", text: "A" },
-      28: { color: "cyan", text: "AAAA" },
-      65: { color: "purple", text: "HTTPS" },
-      5: { color: "green", text: "CNAME" },
-      15: { color: "orange", text: "MX" },
+  const getQueryTypeTag = (type, name) => {
+    const colorMap = {
+      A: "blue",
+      AAAA: "cyan",
+      HTTPS: "purple",
+      CNAME: "green",
+      MX: "orange",
+      PTR: "magenta",
+    };
+    const text = name || `TYPE${type}`;
+    return <Tag color={colorMap[text] || "default"}>{text}</Tag>;
+  };
+
+  // 响应码标签，rcode 为空表示日志未输出响应码且没有应答
+  const getRcodeTag = (rcode) => {
+    if (!rcode) {
+      return <span style={{ color: "#999" }}>-</span>;
+    }
+    const colorMap = {
+      NOERROR: "green",
+      NXDOMAIN: "orange",
+      SERVFAIL: "red",
+      REFUSED: "volcano",
     };
-    const config = typeMap[type] || { color: "default", text: `TYPE ${type}` };
-    return <Tag color={config.color}>{config.text}</Tag>;
+    return <Tag color={colorMap[rcode] || "default"}>{rcode}</Tag>;
   };
 
   // 根据分组名称获取对应的服务器列表
@@ -429,7 +510,7 @@ const LogList = ({ nodeId, nodeName }) => {
       key: "query_type",
       width: 80,
       align: "center",
-      render: (type) => getQueryTypeTag(type),
+      render: (type, record) => getQueryTypeTag(type, record.query_type_name),
     },
     {
       title: "耗时",
@@ -468,6 +549,23 @@ const LogList = ({ nodeId, nodeName }) => {
       width: 180,
       render: (groupName) => renderUpstreamInfo(groupName),
     },
+    {
+      title: "响应",
+      dataIndex: "rcode",
+      key: "rcode",
+      width: 140,
+      render: (rcode, record) => (
+        <Space size={4} wrap>
+          {getRcodeTag(rcode)}
+          {record.cache_hit && <Tag color="cyan">缓存</Tag>}
+          {record.matched_rule && (
+            <Tooltip title={`命中规则: ${record.matched_rule}`}>
+              <Tag color="purple">规则</Tag>
+            </Tooltip>
+          )}
+        </Space>
+      ),
+    },
     {
       title: "结果",
       dataIndex: "result",
@@ -520,12 +618,18 @@ const LogList = ({ nodeId, nodeName }) => {
               label="查询类型"
               style={{ marginBottom: 8 }}
             >
-              <Select placeholder="选择类型" allowClear>
-                <Option value={1}>A (IPv4)</Option>
-                <Option value={28}>AAAA (IPv6)</Option>
-                <Option value={65}>HTTPS</Option>
-                <Option value={5}>CNAME</Option>
-                <Option value={15}>MX</Option>
+              <Select
+                mode="multiple"
+                placeholder="选择类型"
+                allowClear
+                maxTagCount="responsive"
+                optionFilterProp="children"
+              >
+                {queryTypes.map((item) => (
+                  <Option key={item.type} value={item.name}>
+                    {item.name}
+                  </Option>
+                ))}
               </Select>
             </Form.Item>
           </Col>
@@ -560,6 +664,31 @@ const LogList = ({ nodeId, nodeName }) => {
           </Col>
         </Row>
 
+        <Row gutter={16}>
+          <Col xs={24} sm={12} md={6}>
+            <Form.Item name="rcode" label="响应码" style={{ marginBottom: 8 }}>
+              <Select placeholder="选择响应码" allowClear>
+                <Option value="NOERROR">NOERROR</Option>
+                <Option value="NXDOMAIN">NXDOMAIN</Option>
+                <Option value="SERVFAIL">SERVFAIL</Option>
+                <Option value="REFUSED">REFUSED</Option>
+              </Select>
+            </Form.Item>
+          </Col>
+          <Col xs={24} sm={12} md={6}>
+            <Form.Item
+              name="cache_hit"
+              label="缓存"
+              style={{ marginBottom: 8 }}
+            >
+              <Select placeholder="全部" allowClear>
+                <Option value="true">命中缓存</Option>
+                <Option value="false">未命中缓存</Option>
+              </Select>
+            </Form.Item>
+          </Col>
+        </Row>
+
         {/* 时间范围选择行 */}
         <Row gutter={16}>
           <Col xs={24} sm={12} md={8}>
@@ -644,6 +773,9 @@ const LogList = ({ nodeId, nodeName }) => {
               >
                 刷新
               </Button>
+              <Button icon={<ShareAltOutlined />} onClick={handleShare}>
+                分享
+              </Button>
               <Space>
                 <Switch
                   checked={autoRefresh}
@@ -0,0 +1,91 @@
+import React, { useState, useEffect } from 'react';
+import { Modal, InputNumber, Radio, Space, Alert, Spin, message } from 'antd';
+import { getNodeLogRetention, updateNodeLogRetention } from '../../api';
+
+const LogRetentionModal = ({ visible, onCancel, nodeId, nodeName }) => {
+  const [loading, setLoading] = useState(false);
+  const [saving, setSaving] = useState(false);
+  const [retention, setRetention] = useState(null);
+  const [custom, setCustom] = useState(false);
+  const [days, setDays] = useState(90);
+
+  useEffect(() => {
+    if (visible && nodeId) {
+      fetchRetention();
+    }
+  }, [visible, nodeId]);
+
+  const fetchRetention = async () => {
+    try {
+      setLoading(true);
+      const response = await getNodeLogRetention(nodeId);
+      const data = response.data;
+      setRetention(data);
+      setCustom(data.custom);
+      setDays(data.days);
+    } catch (error) {
+      message.error('获取日志保留天数失败');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleSave = async () => {
+    try {
+      setSaving(true);
+      const response = await updateNodeLogRetention(nodeId, custom ? days : 0);
+      if (response.data?.applied) {
+        message.success(response.message);
+        onCancel();
+      } else {
+        message.warning(response.message);
+      }
+    } catch (error) {
+      message.error('保存失败: ' + (error.response?.data?.message || error.message));
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  return (
+    <Modal
+      title={`日志保留策略 - ${nodeName || ''}`}
+      open={visible}
+      onCancel={onCancel}
+      onOk={handleSave}
+      confirmLoading={saving}
+      okText="保存"
+      destroyOnClose
+    >
+      <Spin spinning={loading}>
+        <Alert
+          type="info"
+          showIcon
+          style={{ marginBottom: 16 }}
+          message="DNS 查询日志保留任务每天按节点的保留天数删除过期日志，ClickHouse 表 TTL 按所有节点中最长的保留天数设置。"
+        />
+        <Radio.Group value={custom} onChange={(e) => setCustom(e.target.value)}>
+          <Space direction="vertical">
+            <Radio value={false}>使用默认（{retention?.default_days || '-'} 天）</Radio>
+            <Radio value={true}>
+              <Space>
+                <span>保留最近</span>
+                <InputNumber
+                  min={1}
+                  max={3650}
+                  value={days}
+                  onChange={(value) => setDays(value)}
+                  disabled={!custom}
+                  style={{ width: 100 }}
+                />
+                <span>天的日志</span>
+              </Space>
+            </Radio>
+          </Space>
+        </Radio.Group>
+      </Spin>
+    </Modal>
+  );
+};
+
+export default LogRetentionModal;
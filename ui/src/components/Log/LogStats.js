@@ -16,6 +16,8 @@ import {
   UserOutlined,
   GlobalOutlined,
   ClockCircleOutlined,
+  StopOutlined,
+  ThunderboltOutlined,
 } from '@ant-design/icons';
 import { getNodeLogStats } from '../../api';
 import dayjs from 'dayjs';
@@ -153,6 +155,84 @@ const LogStats = ({ nodeId }) => {
     },
   ];
 
+  const groupColumns = [
+    {
+      title: '分组',
+      dataIndex: 'group',
+      key: 'group',
+      render: (group) => group || '默认',
+    },
+    {
+      title: '查询次数',
+      dataIndex: 'count',
+      key: 'count',
+      width: 100,
+      align: 'right',
+      render: (count) => count?.toLocaleString() || 0,
+    },
+    {
+      title: '平均耗时',
+      dataIndex: 'avg_time_ms',
+      key: 'avg_time_ms',
+      width: 100,
+      align: 'right',
+      render: (ms) => `${(ms || 0).toFixed(1)} ms`,
+    },
+    {
+      title: '占比',
+      key: 'percentage',
+      width: 150,
+      render: (_, record) => {
+        if (!stats.total_queries || stats.total_queries === 0) {
+          return <span>0%</span>;
+        }
+        const percentage = (record.count / stats.total_queries) * 100;
+        return (
+          <Progress
+            percent={Math.min(percentage, 100)}
+            size="small"
+            format={(percent) => `${percent.toFixed(1)}%`}
+          />
+        );
+      },
+    },
+  ];
+
+  const rcodeColumns = [
+    {
+      title: '响应码',
+      dataIndex: 'rcode',
+      key: 'rcode',
+      render: (rcode) => rcode || '未知',
+    },
+    {
+      title: '查询次数',
+      dataIndex: 'count',
+      key: 'count',
+      width: 100,
+      align: 'right',
+      render: (count) => count?.toLocaleString() || 0,
+    },
+    {
+      title: '占比',
+      key: 'percentage',
+      width: 150,
+      render: (_, record) => {
+        if (!stats.total_queries || stats.total_queries === 0) {
+          return <span>0%</span>;
+        }
+        const percentage = (record.count / stats.total_queries) * 100;
+        return (
+          <Progress
+            percent={Math.min(percentage, 100)}
+            size="small"
+            format={(percent) => `${percent.toFixed(1)}%`}
+          />
+        );
+      },
+    },
+  ];
+
   return (
     <div>
       <Card style={{ marginBottom: 16 }}>
@@ -215,6 +295,33 @@ const LogStats = ({ nodeId }) => {
             </Card>
           </Col>
         </Row>
+
+        <Row gutter={16} style={{ marginTop: 16 }}>
+          <Col xs={24} sm={12} md={6}>
+            <Card>
+              <Statistic
+                title="NXDOMAIN 占比"
+                value={(stats.nxdomain_rate || 0) * 100}
+                suffix="%"
+                precision={2}
+                prefix={<StopOutlined />}
+                valueStyle={{ color: '#fa8c16' }}
+              />
+            </Card>
+          </Col>
+          <Col xs={24} sm={12} md={6}>
+            <Card>
+              <Statistic
+                title="缓存命中率"
+                value={(stats.cache_hit_ratio || 0) * 100}
+                suffix="%"
+                precision={2}
+                prefix={<ThunderboltOutlined />}
+                valueStyle={{ color: '#13c2c2' }}
+              />
+            </Card>
+          </Col>
+        </Row>
       </Card>
 
       <Row gutter={16}>
@@ -247,6 +354,32 @@ const LogStats = ({ nodeId }) => {
           </Card>
         </Col>
       </Row>
+
+      <Card title="按分组统计" style={{ marginBottom: 16 }}>
+        <Table
+          columns={groupColumns}
+          dataSource={stats.group_stats || []}
+          rowKey="group"
+          pagination={false}
+          size="small"
+          locale={{
+            emptyText: <Empty description="暂无数据" image={Empty.PRESENTED_IMAGE_SIMPLE} />,
+          }}
+        />
+      </Card>
+
+      <Card title="按响应码统计" style={{ marginBottom: 16 }}>
+        <Table
+          columns={rcodeColumns}
+          dataSource={stats.rcode_stats || []}
+          rowKey="rcode"
+          pagination={false}
+          size="small"
+          locale={{
+            emptyText: <Empty description="暂无数据" image={Empty.PRESENTED_IMAGE_SIMPLE} />,
+          }}
+        />
+      </Card>
     </div>
   );
 };
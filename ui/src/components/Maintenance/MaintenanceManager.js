@@ -0,0 +1,490 @@
+import React, { useState, useEffect } from "react";
+import {
+  Table,
+  Button,
+  Space,
+  Tag,
+  Modal,
+  Form,
+  Input,
+  InputNumber,
+  Select,
+  Switch,
+  message,
+  Popconfirm,
+  Tooltip,
+  Alert,
+} from "antd";
+import {
+  PlusOutlined,
+  EditOutlined,
+  DeleteOutlined,
+  PlayCircleOutlined,
+  StopOutlined,
+  ReloadOutlined,
+} from "@ant-design/icons";
+import {
+  getMaintenanceWindows,
+  addMaintenanceWindow,
+  updateMaintenanceWindow,
+  deleteMaintenanceWindow,
+  getDeferredActions,
+  cancelDeferredAction,
+  runDeferredAction,
+  getNodes,
+} from "../../api";
+import CronBuilder from "../CronBuilder/CronBuilder";
+import dayjs from "dayjs";
+
+const { Option } = Select;
+
+const actionLabels = {
+  restart: "重启服务",
+  reload: "重载配置",
+  cache_flush: "清空缓存",
+  task: "定时任务",
+};
+
+const deferredStatusTags = {
+  pending: { color: "processing", text: "待执行" },
+  done: { color: "success", text: "已执行" },
+  failed: { color: "error", text: "失败" },
+  cancelled: { color: "default", text: "已取消" },
+};
+
+const parseNodeIds = (value) => {
+  try {
+    return JSON.parse(value || "[]");
+  } catch (e) {
+    return [];
+  }
+};
+
+const MaintenanceManager = () => {
+  const [windows, setWindows] = useState([]);
+  const [actions, setActions] = useState([]);
+  const [nodes, setNodes] = useState([]);
+  const [loading, setLoading] = useState(false);
+  const [actionsLoading, setActionsLoading] = useState(false);
+  const [actionStatus, setActionStatus] = useState("pending");
+  const [modalVisible, setModalVisible] = useState(false);
+  const [editing, setEditing] = useState(null);
+  const [submitLoading, setSubmitLoading] = useState(false);
+  const [form] = Form.useForm();
+
+  useEffect(() => {
+    loadWindows();
+    loadNodes();
+  }, []);
+
+  useEffect(() => {
+    loadActions();
+  }, [actionStatus]);
+
+  const loadWindows = async () => {
+    try {
+      setLoading(true);
+      const response = await getMaintenanceWindows();
+      setWindows(response.data || []);
+    } catch (error) {
+      console.error("加载维护窗口失败", error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const loadActions = async () => {
+    try {
+      setActionsLoading(true);
+      const response = await getDeferredActions(actionStatus);
+      setActions(response.data || []);
+    } catch (error) {
+      console.error("加载推迟的操作失败", error);
+    } finally {
+      setActionsLoading(false);
+    }
+  };
+
+  const loadNodes = async () => {
+    try {
+      const response = await getNodes();
+      setNodes(response.data || []);
+    } catch (error) {
+      console.error("加载节点列表失败", error);
+    }
+  };
+
+  const handleAdd = () => {
+    setEditing(null);
+    form.resetFields();
+    form.setFieldsValue({
+      cron_expr: "0 0 3 * * *",
+      duration_minutes: 60,
+      enabled: true,
+    });
+    setModalVisible(true);
+  };
+
+  const handleEdit = (record) => {
+    setEditing(record);
+    form.setFieldsValue({
+      name: record.name,
+      description: record.description,
+      cron_expr: record.cron_expr,
+      duration_minutes: record.duration_minutes,
+      node_ids: parseNodeIds(record.node_ids),
+      tag: record.tag,
+      enabled: record.enabled,
+    });
+    setModalVisible(true);
+  };
+
+  const handleDelete = async (id) => {
+    try {
+      await deleteMaintenanceWindow(id);
+      message.success("删除成功");
+      loadWindows();
+    } catch (error) {
+      console.error("删除维护窗口失败", error);
+    }
+  };
+
+  const handleSubmit = async () => {
+    try {
+      const values = await form.validateFields();
+      setSubmitLoading(true);
+      if (editing) {
+        await updateMaintenanceWindow(editing.id, values);
+        message.success("更新成功");
+      } else {
+        await addMaintenanceWindow(values);
+        message.success("添加成功");
+      }
+      setModalVisible(false);
+      loadWindows();
+    } catch (error) {
+      if (error.errorFields) {
+        return;
+      }
+      console.error("保存维护窗口失败", error);
+    } finally {
+      setSubmitLoading(false);
+    }
+  };
+
+  const handleRunAction = async (id) => {
+    try {
+      await runDeferredAction(id);
+      message.success("执行成功");
+    } catch (error) {
+      console.error("执行推迟的操作失败", error);
+    } finally {
+      loadActions();
+    }
+  };
+
+  const handleCancelAction = async (id) => {
+    try {
+      await cancelDeferredAction(id);
+      message.success("已取消");
+      loadActions();
+    } catch (error) {
+      console.error("取消推迟的操作失败", error);
+    }
+  };
+
+  const renderScope = (record) => {
+    const ids = parseNodeIds(record.node_ids);
+    if (ids.length === 0 && !record.tag) {
+      return <Tag color="purple">全部节点</Tag>;
+    }
+    return (
+      <Space size={4} wrap>
+        {ids.map((id) => (
+          <Tag key={id}>{nodes.find((n) => n.id === id)?.name || `#${id}`}</Tag>
+        ))}
+        {record.tag && <Tag color="cyan">标签: {record.tag}</Tag>}
+      </Space>
+    );
+  };
+
+  const windowColumns = [
+    {
+      title: "名称",
+      dataIndex: "name",
+      key: "name",
+      width: 160,
+      render: (text, record) => (
+        <Tooltip title={record.description || undefined}>
+          <Tag color="blue">{text}</Tag>
+        </Tooltip>
+      ),
+    },
+    {
+      title: "开始时间 (Cron)",
+      dataIndex: "cron_expr",
+      key: "cron_expr",
+      width: 150,
+      render: (expr) => <code>{expr}</code>,
+    },
+    {
+      title: "时长",
+      dataIndex: "duration_minutes",
+      key: "duration_minutes",
+      width: 90,
+      render: (minutes) => `${minutes} 分钟`,
+    },
+    {
+      title: "适用节点",
+      key: "scope",
+      render: (_, record) => renderScope(record),
+    },
+    {
+      title: "当前状态",
+      key: "state",
+      width: 200,
+      render: (_, record) => {
+        if (!record.enabled) {
+          return <Tag>已禁用</Tag>;
+        }
+        if (record.is_open) {
+          return <Tag color="success">窗口开启中</Tag>;
+        }
+        return (
+          <span style={{ color: "#666", fontSize: "12px" }}>
+            下次开启:{" "}
+            {record.next_open
+              ? dayjs(record.next_open).format("MM-DD HH:mm")
+              : "-"}
+          </span>
+        );
+      },
+    },
+    {
+      title: "操作",
+      key: "action",
+      width: 110,
+      render: (_, record) => (
+        <Space size="small">
+          <Tooltip title="编辑">
+            <Button
+              type="link"
+              size="small"
+              icon={<EditOutlined />}
+              onClick={() => handleEdit(record)}
+            />
+          </Tooltip>
+          <Popconfirm
+            title="确定删除该维护窗口吗？"
+            onConfirm={() => handleDelete(record.id)}
+            okText="确定"
+            cancelText="取消"
+          >
+            <Tooltip title="删除">
+              <Button
+                type="link"
+                size="small"
+                danger
+                icon={<DeleteOutlined />}
+              />
+            </Tooltip>
+          </Popconfirm>
+        </Space>
+      ),
+    },
+  ];
+
+  const actionColumns = [
+    {
+      title: "对象",
+      key: "target",
+      width: 160,
+      render: (_, record) =>
+        record.action === "task"
+          ? record.task_name || `任务 #${record.task_id}`
+          : record.node_name || `节点 #${record.node_id}`,
+    },
+    {
+      title: "操作",
+      dataIndex: "action",
+      key: "action",
+      width: 100,
+      render: (action) => actionLabels[action] || action,
+    },
+    {
+      title: "原因",
+      dataIndex: "reason",
+      key: "reason",
+      ellipsis: true,
+    },
+    {
+      title: "状态",
+      dataIndex: "status",
+      key: "status",
+      width: 90,
+      render: (status, record) => {
+        const tag = deferredStatusTags[status] || { color: "default", text: status };
+        return (
+          <Tooltip title={record.error || undefined}>
+            <Tag color={tag.color}>{tag.text}</Tag>
+          </Tooltip>
+        );
+      },
+    },
+    {
+      title: "推迟时间",
+      dataIndex: "created_at",
+      key: "created_at",
+      width: 130,
+      render: (time) => dayjs(time).format("MM-DD HH:mm"),
+    },
+    {
+      title: "预计执行",
+      key: "when",
+      width: 130,
+      render: (_, record) => {
+        const time = record.executed_at || record.not_before;
+        return time ? dayjs(time).format("MM-DD HH:mm") : "-";
+      },
+    },
+    {
+      title: "操作",
+      key: "operations",
+      width: 110,
+      render: (_, record) =>
+        record.status === "pending" && (
+          <Space size="small">
+            <Popconfirm
+              title="忽略维护窗口立即执行吗？"
+              onConfirm={() => handleRunAction(record.id)}
+              okText="确定"
+              cancelText="取消"
+            >
+              <Tooltip title="立即执行">
+                <Button type="link" size="small" icon={<PlayCircleOutlined />} />
+              </Tooltip>
+            </Popconfirm>
+            <Popconfirm
+              title="确定取消该操作吗？"
+              onConfirm={() => handleCancelAction(record.id)}
+              okText="确定"
+              cancelText="取消"
+            >
+              <Tooltip title="取消">
+                <Button type="link" size="small" danger icon={<StopOutlined />} />
+              </Tooltip>
+            </Popconfirm>
+          </Space>
+        ),
+    },
+  ];
+
+  return (
+    <div>
+      <Alert
+        type="info"
+        showIcon
+        style={{ marginBottom: 16 }}
+        message="节点被启用的维护窗口覆盖时，重启、重载、清空缓存等中断性操作只在窗口开启期间执行，其余时间推迟到下一个窗口；未被任何窗口覆盖的节点不受限制。勾选「仅在维护窗口内执行」的定时任务只受全局窗口（不限节点和标签）约束。"
+      />
+      <div style={{ marginBottom: 16 }}>
+        <Button type="primary" icon={<PlusOutlined />} onClick={handleAdd}>
+          添加维护窗口
+        </Button>
+        <span style={{ marginLeft: 16, color: "#666" }}>
+          共 {windows.length} 个窗口
+        </span>
+      </div>
+      <Table
+        columns={windowColumns}
+        dataSource={windows}
+        rowKey="id"
+        loading={loading}
+        pagination={false}
+      />
+
+      <div style={{ margin: "24px 0 16px" }}>
+        <Space>
+          <strong>推迟的操作</strong>
+          <Select value={actionStatus} onChange={setActionStatus} style={{ width: 120 }}>
+            <Option value="pending">待执行</Option>
+            <Option value="done">已执行</Option>
+            <Option value="failed">失败</Option>
+            <Option value="cancelled">已取消</Option>
+            <Option value="all">全部</Option>
+          </Select>
+          <Button icon={<ReloadOutlined />} onClick={loadActions}>
+            刷新
+          </Button>
+        </Space>
+      </div>
+      <Table
+        columns={actionColumns}
+        dataSource={actions}
+        rowKey="id"
+        loading={actionsLoading}
+        pagination={{ pageSize: 10 }}
+      />
+
+      <Modal
+        title={editing ? "编辑维护窗口" : "添加维护窗口"}
+        open={modalVisible}
+        onOk={handleSubmit}
+        onCancel={() => setModalVisible(false)}
+        width={720}
+        okText="确定"
+        cancelText="取消"
+        confirmLoading={submitLoading}
+      >
+        <Form form={form} layout="vertical">
+          <Form.Item
+            name="name"
+            label="名称"
+            rules={[{ required: true, message: "请输入窗口名称" }]}
+          >
+            <Input placeholder="例如: 每晚维护" />
+          </Form.Item>
+          <Form.Item name="description" label="描述">
+            <Input />
+          </Form.Item>
+          <Form.Item
+            name="cron_expr"
+            label="开始时间"
+            rules={[{ required: true, message: "请设置窗口开始时间" }]}
+          >
+            <CronBuilder />
+          </Form.Item>
+          <Form.Item name="duration_minutes" label="持续时长（分钟）">
+            <InputNumber min={1} step={30} style={{ width: "100%" }} />
+          </Form.Item>
+          <Form.Item
+            name="node_ids"
+            label="适用节点"
+            extra="节点和标签都不填时对全部节点生效"
+          >
+            <Select
+              mode="multiple"
+              allowClear
+              placeholder="选择节点"
+              optionFilterProp="children"
+            >
+              {nodes.map((node) => (
+                <Option key={node.id} value={node.id}>
+                  {node.name}
+                </Option>
+              ))}
+            </Select>
+          </Form.Item>
+          <Form.Item name="tag" label="节点标签">
+            <Input placeholder="带有该标签的节点也适用此窗口" />
+          </Form.Item>
+          <Form.Item name="enabled" label="启用" valuePropName="checked">
+            <Switch />
+          </Form.Item>
+        </Form>
+      </Modal>
+    </div>
+  );
+};
+
+export default MaintenanceManager;
@@ -0,0 +1,278 @@
+import React, { useState, useEffect } from "react";
+import {
+  Modal,
+  Space,
+  Button,
+  DatePicker,
+  Checkbox,
+  Select,
+  Table,
+  Tabs,
+  Statistic,
+  Row,
+  Col,
+  Alert,
+  message,
+} from "antd";
+import { DownloadOutlined, SaveOutlined, SearchOutlined } from "@ant-design/icons";
+import dayjs from "dayjs";
+import {
+  getFleetReport,
+  createFleetReport,
+  getFleetReportHistory,
+  getSavedFleetReport,
+  exportFleetReport,
+  exportSavedFleetReport,
+} from "../../api";
+
+const formatUsage = (value) => (value < 0 ? "-" : `${value.toFixed(1)}%`);
+
+const groupColumns = (title) => [
+  { title, dataIndex: "name", key: "name" },
+  { title: "节点数", dataIndex: "nodes", key: "nodes", width: 90 },
+  { title: "在线", dataIndex: "online", key: "online", width: 80 },
+  {
+    title: "查询量",
+    dataIndex: "queries",
+    key: "queries",
+    render: (v) => v.toLocaleString(),
+  },
+  {
+    title: "查询占比",
+    dataIndex: "share",
+    key: "share",
+    width: 100,
+    render: (v) => `${(v * 100).toFixed(1)}%`,
+  },
+];
+
+const nodeColumns = [
+  { title: "节点", dataIndex: "name", key: "name", fixed: "left", width: 140 },
+  { title: "云厂商", dataIndex: "provider", key: "provider", render: (v) => v || "-" },
+  { title: "区域", dataIndex: "region", key: "region", render: (v) => v || "-" },
+  { title: "成本中心", dataIndex: "cost_center", key: "cost_center", render: (v) => v || "-" },
+  {
+    title: "查询量",
+    dataIndex: "queries",
+    key: "queries",
+    sorter: (a, b) => a.queries - b.queries,
+    render: (v) => v.toLocaleString(),
+  },
+  { title: "平均QPS", dataIndex: "avg_qps", key: "avg_qps", render: (v) => v.toFixed(2) },
+  { title: "CPU", dataIndex: "cpu_usage", key: "cpu_usage", render: formatUsage },
+  { title: "内存", dataIndex: "memory_usage", key: "memory_usage", render: formatUsage },
+  { title: "磁盘", dataIndex: "disk_usage", key: "disk_usage", render: formatUsage },
+];
+
+const FleetReport = ({ visible, onClose }) => {
+  const [month, setMonth] = useState(dayjs().subtract(1, "month"));
+  const [checkResources, setCheckResources] = useState(false);
+  const [report, setReport] = useState(null);
+  const [savedId, setSavedId] = useState(null);
+  const [history, setHistory] = useState([]);
+  const [loading, setLoading] = useState(false);
+
+  useEffect(() => {
+    if (visible) {
+      loadHistory();
+    }
+  }, [visible]);
+
+  const loadHistory = async () => {
+    try {
+      const res = await getFleetReportHistory();
+      setHistory(res.data || []);
+    } catch (error) {
+      console.error("加载资产报告历史失败:", error);
+    }
+  };
+
+  const params = () => ({
+    month: month ? month.format("YYYY-MM") : "",
+    check_resources: checkResources,
+  });
+
+  const handleGenerate = async () => {
+    setLoading(true);
+    try {
+      const res = await getFleetReport(params());
+      setReport(res.data);
+      setSavedId(null);
+    } catch (error) {
+      console.error("生成资产报告失败:", error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleSave = async () => {
+    setLoading(true);
+    try {
+      const res = await createFleetReport(params());
+      message.success(res.message || "资产报告已生成");
+      setReport(res.data.report);
+      setSavedId(res.data.id);
+      loadHistory();
+    } catch (error) {
+      console.error("保存资产报告失败:", error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleSelectHistory = async (id) => {
+    setLoading(true);
+    try {
+      const res = await getSavedFleetReport(id);
+      setReport(res.data);
+      setSavedId(id);
+    } catch (error) {
+      console.error("加载资产报告失败:", error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleExport = async () => {
+    try {
+      const blob = savedId
+        ? await exportSavedFleetReport(savedId)
+        : await exportFleetReport({ ...params(), month: report.month });
+      const url = URL.createObjectURL(blob);
+      const a = document.createElement("a");
+      a.href = url;
+      a.download = `fleet-report-${report.month}.csv`;
+      a.click();
+      URL.revokeObjectURL(url);
+    } catch (error) {
+      message.error("导出失败");
+    }
+  };
+
+  return (
+    <Modal
+      title="节点资产报告"
+      open={visible}
+      onCancel={onClose}
+      footer={null}
+      width={1000}
+      destroyOnClose
+    >
+      <Space wrap style={{ marginBottom: 16 }}>
+        <DatePicker
+          picker="month"
+          value={month}
+          onChange={setMonth}
+          disabledDate={(d) => d && d.isAfter(dayjs(), "month")}
+        />
+        <Checkbox
+          checked={checkResources}
+          onChange={(e) => setCheckResources(e.target.checked)}
+        >
+          采集资源使用率
+        </Checkbox>
+        <Button icon={<SearchOutlined />} loading={loading} onClick={handleGenerate}>
+          生成
+        </Button>
+        <Button icon={<SaveOutlined />} loading={loading} onClick={handleSave}>
+          生成并保存
+        </Button>
+        <Button icon={<DownloadOutlined />} disabled={!report} onClick={handleExport}>
+          导出 CSV
+        </Button>
+        <Select
+          placeholder="历史报告"
+          style={{ width: 240 }}
+          value={savedId}
+          onChange={handleSelectHistory}
+          options={history.map((h) => ({
+            value: h.id,
+            label: `${h.month}（${dayjs(h.generated_at).format("MM-DD HH:mm")}）`,
+          }))}
+        />
+      </Space>
+
+      {report && (
+        <>
+          {report.query_error && (
+            <Alert
+              type="warning"
+              showIcon
+              style={{ marginBottom: 16 }}
+              message={`查询量统计失败: ${report.query_error}`}
+            />
+          )}
+          <Row gutter={16} style={{ marginBottom: 16 }}>
+            <Col span={8}>
+              <Statistic title="统计月份" value={report.month} />
+            </Col>
+            <Col span={8}>
+              <Statistic title="节点总数" value={report.total_nodes} />
+            </Col>
+            <Col span={8}>
+              <Statistic title="服务查询量" value={report.queries} />
+            </Col>
+          </Row>
+          <Tabs
+            items={[
+              {
+                key: "provider",
+                label: "按云厂商",
+                children: (
+                  <Table
+                    size="small"
+                    rowKey="name"
+                    pagination={false}
+                    columns={groupColumns("云厂商")}
+                    dataSource={report.by_provider}
+                  />
+                ),
+              },
+              {
+                key: "region",
+                label: "按区域",
+                children: (
+                  <Table
+                    size="small"
+                    rowKey="name"
+                    pagination={false}
+                    columns={groupColumns("区域")}
+                    dataSource={report.by_region}
+                  />
+                ),
+              },
+              {
+                key: "cost_center",
+                label: "按成本中心",
+                children: (
+                  <Table
+                    size="small"
+                    rowKey="name"
+                    pagination={false}
+                    columns={groupColumns("成本中心")}
+                    dataSource={report.by_cost_center}
+                  />
+                ),
+              },
+              {
+                key: "nodes",
+                label: "节点明细",
+                children: (
+                  <Table
+                    size="small"
+                    rowKey="node_id"
+                    scroll={{ x: 900 }}
+                    columns={nodeColumns}
+                    dataSource={report.nodes}
+                  />
+                ),
+              },
+            ]}
+          />
+        </>
+      )}
+    </Modal>
+  );
+};
+
+export default FleetReport;
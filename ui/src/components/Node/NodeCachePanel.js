@@ -0,0 +1,177 @@
+import React, { useState, useEffect } from 'react';
+import {
+  Card,
+  Button,
+  Space,
+  Descriptions,
+  Statistic,
+  Row,
+  Col,
+  Tag,
+  Alert,
+  Modal,
+  Spin,
+  message,
+} from 'antd';
+import { ReloadOutlined, ClearOutlined } from '@ant-design/icons';
+import dayjs from 'dayjs';
+import { getNodeCacheStats, flushNodeCache } from '../../api';
+
+const formatBytes = (bytes) => {
+  if (!bytes) return '0 B';
+  const units = ['B', 'KB', 'MB', 'GB'];
+  let value = bytes;
+  let i = 0;
+  while (value >= 1024 && i < units.length - 1) {
+    value /= 1024;
+    i++;
+  }
+  return `${value.toFixed(i === 0 ? 0 : 1)} ${units[i]}`;
+};
+
+const NodeCachePanel = ({ nodeId }) => {
+  const [stats, setStats] = useState(null);
+  const [loading, setLoading] = useState(false);
+  const [flushing, setFlushing] = useState(false);
+
+  useEffect(() => {
+    loadStats();
+  }, [nodeId]);
+
+  const loadStats = async () => {
+    try {
+      setLoading(true);
+      const response = await getNodeCacheStats(nodeId);
+      setStats(response.data);
+    } catch (error) {
+      console.error('获取缓存状态失败', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleFlush = () => {
+    Modal.confirm({
+      title: '确认清空缓存',
+      content: '清空缓存需要重启 SmartDNS 并删除缓存文件，期间会短暂中断 DNS 解析。确定要继续吗？',
+      okText: '确定',
+      cancelText: '取消',
+      okType: 'danger',
+      onOk: async () => {
+        try {
+          setFlushing(true);
+          const response = await flushNodeCache(nodeId);
+          if (response.deferred) {
+            Modal.confirm({
+              title: '不在维护窗口内',
+              content: `${response.message}。是否忽略维护窗口立即清空？`,
+              okText: '立即清空',
+              cancelText: '等待窗口',
+              okType: 'danger',
+              onOk: async () => {
+                await flushNodeCache(nodeId, true);
+                message.success('缓存已清空');
+                loadStats();
+              },
+            });
+            return;
+          }
+          message.success('缓存已清空');
+          loadStats();
+        } catch (error) {
+          console.error('清空缓存失败', error);
+        } finally {
+          setFlushing(false);
+        }
+      },
+    });
+  };
+
+  return (
+    <Spin spinning={loading}>
+      <Space direction="vertical" style={{ width: '100%' }} size="large">
+        <Alert
+          message="缓存管理"
+          description="缓存条目数来自 SmartDNS 最近一次写入的缓存文件快照；命中率根据最近一小时日志中耗时为 0ms 的查询估算，需启用日志监控。"
+          type="info"
+          showIcon
+        />
+
+        <Space>
+          <Button icon={<ReloadOutlined />} onClick={loadStats}>
+            刷新
+          </Button>
+          <Button danger icon={<ClearOutlined />} loading={flushing} onClick={handleFlush}>
+            清空缓存
+          </Button>
+        </Space>
+
+        {stats && (
+          <>
+            <Row gutter={16}>
+              <Col span={6}>
+                <Card>
+                  <Statistic
+                    title="缓存条目（快照）"
+                    value={stats.cache_file_entries >= 0 ? stats.cache_file_entries : '-'}
+                  />
+                </Card>
+              </Col>
+              <Col span={6}>
+                <Card>
+                  <Statistic
+                    title="估算命中率"
+                    value={stats.hit_rate != null ? stats.hit_rate : '-'}
+                    precision={stats.hit_rate != null ? 1 : undefined}
+                    suffix={stats.hit_rate != null ? '%' : undefined}
+                  />
+                </Card>
+              </Col>
+              <Col span={6}>
+                <Card>
+                  <Statistic title="样本查询数" value={stats.sample_queries} />
+                </Card>
+              </Col>
+              <Col span={6}>
+                <Card>
+                  <Statistic title="进程内存" value={formatBytes(stats.memory_rss_kb * 1024)} />
+                </Card>
+              </Col>
+            </Row>
+
+            <Descriptions bordered size="small" column={2}>
+              <Descriptions.Item label="服务状态">
+                <Tag color={stats.service_up ? 'success' : 'error'}>
+                  {stats.service_up ? '运行中' : '已停止'}
+                </Tag>
+              </Descriptions.Item>
+              <Descriptions.Item label="cache-size">
+                {stats.cache_size || '默认'}
+              </Descriptions.Item>
+              <Descriptions.Item label="cache-persist">{stats.cache_persist}</Descriptions.Item>
+              <Descriptions.Item label="缓存文件">
+                {stats.cache_file ? <code>{stats.cache_file}</code> : '-'}
+              </Descriptions.Item>
+              <Descriptions.Item label="文件大小">
+                {stats.cache_file_exists ? formatBytes(stats.cache_file_bytes) : '不存在'}
+              </Descriptions.Item>
+              <Descriptions.Item label="快照时间">
+                {stats.cache_file_saved_at
+                  ? dayjs(stats.cache_file_saved_at).format('YYYY-MM-DD HH:mm:ss')
+                  : '-'}
+              </Descriptions.Item>
+              <Descriptions.Item label="预取域名">
+                {stats.prefetch_domain ? '开启' : '关闭'}
+              </Descriptions.Item>
+              <Descriptions.Item label="过期缓存服务">
+                {stats.serve_expired ? '开启' : '关闭'}
+              </Descriptions.Item>
+            </Descriptions>
+          </>
+        )}
+      </Space>
+    </Spin>
+  );
+};
+
+export default NodeCachePanel;
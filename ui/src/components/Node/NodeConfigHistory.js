@@ -0,0 +1,192 @@
+import React, { useState, useEffect } from 'react';
+import {
+  Card,
+  Button,
+  Space,
+  Tag,
+  Timeline,
+  Modal,
+  Spin,
+  Empty,
+  Switch,
+  Typography,
+  message,
+} from 'antd';
+import { CameraOutlined, WarningOutlined } from '@ant-design/icons';
+import dayjs from 'dayjs';
+import {
+  getNodeConfigSnapshots,
+  captureNodeConfigSnapshot,
+  getConfigSnapshot,
+} from '../../api';
+
+const { Text } = Typography;
+
+const sourceLabels = {
+  manager: '管理端写入',
+  scheduled: '定时采集',
+};
+
+const NodeConfigHistory = ({ nodeId }) => {
+  const [snapshots, setSnapshots] = useState([]);
+  const [loading, setLoading] = useState(false);
+  const [capturing, setCapturing] = useState(false);
+  const [outOfBandOnly, setOutOfBandOnly] = useState(false);
+  const [detail, setDetail] = useState(null);
+  const [detailLoading, setDetailLoading] = useState(false);
+
+  useEffect(() => {
+    loadSnapshots();
+  }, [nodeId, outOfBandOnly]);
+
+  const loadSnapshots = async () => {
+    try {
+      setLoading(true);
+      const response = await getNodeConfigSnapshots(nodeId, {
+        page_size: 100,
+        out_of_band: outOfBandOnly ? 'true' : undefined,
+      });
+      setSnapshots(response.data || []);
+    } catch (error) {
+      console.error('获取配置历史失败', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleCapture = async () => {
+    try {
+      setCapturing(true);
+      const response = await captureNodeConfigSnapshot(nodeId);
+      message.success(response.message);
+      loadSnapshots();
+    } catch (error) {
+      message.error(error.response?.data?.error || '采集配置快照失败');
+    } finally {
+      setCapturing(false);
+    }
+  };
+
+  const openDetail = async (id) => {
+    try {
+      setDetailLoading(true);
+      setDetail({});
+      const response = await getConfigSnapshot(id);
+      setDetail(response.data);
+    } catch (error) {
+      message.error('获取快照失败');
+      setDetail(null);
+    } finally {
+      setDetailLoading(false);
+    }
+  };
+
+  const renderDiff = () => {
+    if (!detail?.snapshot) return null;
+    if (!detail.previous) {
+      return <Text type="secondary">第一份快照，作为后续对比的基线</Text>;
+    }
+    const lines = [
+      ...detail.changed.map((c) => ({ sign: '~', text: `${c.expected}  →  ${c.actual}`, color: '#d48806' })),
+      ...detail.added.map((line) => ({ sign: '+', text: line, color: '#389e0d' })),
+      ...detail.removed.map((line) => ({ sign: '-', text: line, color: '#cf1322' })),
+    ];
+    if (lines.length === 0) {
+      return <Text type="secondary">有效配置行没有变化（仅注释或格式不同）</Text>;
+    }
+    return (
+      <pre style={{ background: '#fafafa', padding: 12, maxHeight: 300, overflow: 'auto', fontSize: 12 }}>
+        {lines.map((line, i) => (
+          <div key={i} style={{ color: line.color }}>
+            {line.sign} {line.text}
+          </div>
+        ))}
+      </pre>
+    );
+  };
+
+  return (
+    <Card
+      size="small"
+      title="配置历史"
+      extra={
+        <Space>
+          <Switch
+            size="small"
+            checked={outOfBandOnly}
+            onChange={setOutOfBandOnly}
+          />
+          <Text>只看直接修改</Text>
+          <Button
+            icon={<CameraOutlined />}
+            loading={capturing}
+            onClick={handleCapture}
+          >
+            立即采集
+          </Button>
+        </Space>
+      }
+    >
+      <Spin spinning={loading}>
+        {snapshots.length === 0 ? (
+          <Empty description="暂无配置快照" />
+        ) : (
+          <Timeline
+            items={snapshots.map((snapshot) => ({
+              color: snapshot.out_of_band ? 'red' : snapshot.source === 'manager' ? 'blue' : 'gray',
+              dot: snapshot.out_of_band ? <WarningOutlined /> : undefined,
+              children: (
+                <Space direction="vertical" size={0}>
+                  <Space>
+                    <a onClick={() => openDetail(snapshot.id)}>
+                      {dayjs(snapshot.captured_at).format('YYYY-MM-DD HH:mm:ss')}
+                    </a>
+                    <Tag>{sourceLabels[snapshot.source] || snapshot.source}</Tag>
+                    {snapshot.out_of_band && <Tag color="red">节点上直接修改</Tag>}
+                  </Space>
+                  <Text type="secondary" style={{ fontSize: 12 }}>
+                    +{snapshot.added_count} -{snapshot.removed_count} ~{snapshot.changed_count}
+                    ，{snapshot.hash.slice(0, 12)}，最后确认于{' '}
+                    {dayjs(snapshot.last_seen_at).format('MM-DD HH:mm')}
+                  </Text>
+                </Space>
+              ),
+            }))}
+          />
+        )}
+      </Spin>
+
+      <Modal
+        title="配置快照"
+        open={!!detail}
+        onCancel={() => setDetail(null)}
+        footer={null}
+        width={900}
+      >
+        <Spin spinning={detailLoading}>
+          {detail?.snapshot && (
+            <Space direction="vertical" style={{ width: '100%' }}>
+              <Text strong>与上一快照的差异</Text>
+              {renderDiff()}
+              <Text strong>完整配置</Text>
+              <pre
+                style={{
+                  background: '#1e1e1e',
+                  color: '#d4d4d4',
+                  padding: 12,
+                  maxHeight: 400,
+                  overflow: 'auto',
+                  fontSize: 12,
+                }}
+              >
+                {detail.snapshot.content}
+              </pre>
+            </Space>
+          )}
+        </Spin>
+      </Modal>
+    </Card>
+  );
+};
+
+export default NodeConfigHistory;
@@ -13,7 +13,11 @@ const NodeForm = ({ node, onSuccess, onCancel }) => {
   useEffect(() => {
     if (node) {
       form.setFieldsValue(node);
-      setAuthMethod(node.private_key ? 'key' : 'password');
+      if (node.credential_source === 'vault') {
+        setAuthMethod('vault');
+      } else {
+        setAuthMethod(node.private_key ? 'key' : 'password');
+      }
     }
   }, [node, form]);
 
@@ -21,10 +25,14 @@ const NodeForm = ({ node, onSuccess, onCancel }) => {
     try {
       setLoading(true);
       
+      values.credential_source = authMethod === 'vault' ? 'vault' : 'local';
       if (authMethod === 'password') {
         delete values.private_key;
+      } else if (authMethod === 'key') {
+        delete values.password;
       } else {
         delete values.password;
+        delete values.private_key;
       }
 
       if (node) {
@@ -52,6 +60,8 @@ const NodeForm = ({ node, onSuccess, onCancel }) => {
         port: 22,
         config_path: '/etc/smartdns/smartdns.conf',
         log_path: '/var/log/smartdns/smartdns.log', // 新增默认值
+        reload_mode: 'restart',
+        install_mode: 'auto',
       }}
     >
       <Form.Item
@@ -90,10 +100,38 @@ const NodeForm = ({ node, onSuccess, onCancel }) => {
         <Select value={authMethod} onChange={setAuthMethod}>
           <Option value="password">密码</Option>
           <Option value="key">私钥</Option>
+          <Option value="vault">HashiCorp Vault</Option>
         </Select>
       </Form.Item>
 
-      {authMethod === 'password' ? (
+      {authMethod === 'vault' ? (
+        <>
+          <Form.Item
+            name="vault_ssh_role"
+            label="SSH 签名角色"
+            extra="连接时生成临时密钥，由 Vault SSH 引擎签发短期证书（需节点信任 Vault CA）"
+          >
+            <Input placeholder="例如: smartdns-node" />
+          </Form.Item>
+          <Form.Item
+            name="vault_secret_path"
+            label="KV 密钥路径"
+            extra="从 KV v2 读取 private_key 或 password 字段，可与签名角色同时配置"
+            rules={[
+              ({ getFieldValue }) => ({
+                validator(_, value) {
+                  if (value || getFieldValue('vault_ssh_role')) {
+                    return Promise.resolve();
+                  }
+                  return Promise.reject(new Error('请填写 SSH 签名角色或 KV 密钥路径'));
+                },
+              }),
+            ]}
+          >
+            <Input placeholder="例如: smartdns/nodes/node-1" />
+          </Form.Item>
+        </>
+      ) : authMethod === 'password' ? (
         <Form.Item
           name="password"
           label="密码"
@@ -131,8 +169,57 @@ const NodeForm = ({ node, onSuccess, onCancel }) => {
         <Input placeholder="/var/log/smartdns/audit.log" />
       </Form.Item>
 
+      <Form.Item
+        name="reload_mode"
+        label="配置生效方式"
+        extra="重载方式下配置同步后自动生效，不支持重载时回退为重启；重启方式下同步后需手动重启"
+      >
+        <Select>
+          <Option value="restart">重启服务（会短暂中断解析）</Option>
+          <Option value="reload">systemctl reload（需服务配置 ExecReload）</Option>
+          <Option value="sighup">发送 SIGHUP 信号（通过解析确认生效，否则重启）</Option>
+        </Select>
+      </Form.Item>
+
+      <Form.Item
+        name="install_mode"
+        label="SmartDNS 安装方式"
+        extra="初始化时使用。自动选择时 OpenWrt 使用 opkg，其他系统优先使用官方安装包；Docker 方式需节点已安装 Docker"
+      >
+        <Select>
+          <Option value="auto">自动选择</Option>
+          <Option value="tarball">官方安装包（tar.gz）</Option>
+          <Option value="package">系统软件源（apt / yum）</Option>
+          <Option value="opkg">OpenWrt 软件源（opkg）</Option>
+          <Option value="docker">Docker Compose</Option>
+        </Select>
+      </Form.Item>
+
       <Divider orientation="left">其他信息</Divider>
 
+      <Form.Item
+        name="provider"
+        label="云厂商"
+        extra="用于资产报告按厂商统计"
+      >
+        <Input placeholder="例如: aliyun, aws, 自建机房" />
+      </Form.Item>
+
+      <Form.Item
+        name="region"
+        label="区域"
+      >
+        <Input placeholder="例如: cn-hangzhou" />
+      </Form.Item>
+
+      <Form.Item
+        name="cost_center"
+        label="成本中心"
+        extra="用于按部门或项目分摊费用"
+      >
+        <Input placeholder="例如: 基础架构部" />
+      </Form.Item>
+
       <Form.Item
         name="tags"
         label="标签"
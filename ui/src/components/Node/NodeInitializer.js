@@ -1,4 +1,4 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useRef } from 'react';
 import {
   Modal,
   Steps,
@@ -11,6 +11,8 @@ import {
   Space,
   Descriptions,
   Card,
+  Select,
+  Checkbox,
 } from 'antd';
 import {
   CheckCircleOutlined,
@@ -23,19 +25,43 @@ import {
   initNode,
   checkNodeInit,
   getInitLogs,
+  streamInitProgress,
   uninstallSmartDNS,
   reinstallSmartDNS,
+  getNodeSmartDNSVersion,
+  getSmartDNSReleases,
+  upgradeNodeSmartDNS,
 } from '../../api';
 import dayjs from "dayjs";
 
 const { Step } = Steps;
 
+// 控制台最多保留的命令输出行数
+const MAX_OUTPUT_LINES = 500;
+
+const installModeText = {
+  tarball: '官方安装包',
+  package: '系统软件源',
+  opkg: 'OpenWrt opkg',
+  docker: 'Docker',
+};
+
 const NodeInitializer = ({ visible, onClose, node }) => {
   const [loading, setLoading] = useState(false);
   const [initStatus, setInitStatus] = useState(null);
   const [logs, setLogs] = useState([]);
   const [currentStep, setCurrentStep] = useState(0);
-  const [polling, setPolling] = useState(false);
+  const [output, setOutput] = useState([]);
+  const [streaming, setStreaming] = useState(false);
+  const [versionInfo, setVersionInfo] = useState(null);
+  const [upgradeVisible, setUpgradeVisible] = useState(false);
+  const [releases, setReleases] = useState([]);
+  const [upgradeVersion, setUpgradeVersion] = useState(undefined);
+  const [forceUpgrade, setForceUpgrade] = useState(false);
+  const [upgrading, setUpgrading] = useState(false);
+  const unsubscribeRef = useRef(null);
+  const reconnectRef = useRef(null);
+  const consoleRef = useRef(null);
 
   const steps = [
     { key: 'detect', title: '检测系统', description: '检测操作系统和架构' },
@@ -47,23 +73,77 @@ const NodeInitializer = ({ visible, onClose, node }) => {
 
   useEffect(() => {
     if (visible && node) {
+      setOutput([]);
       loadInitStatus();
       loadInitLogs();
     }
+    return () => stopStream();
   }, [visible, node]);
 
   useEffect(() => {
-    let interval;
-    if (polling) {
-      interval = setInterval(() => {
-        loadInitStatus();
-        loadInitLogs();
-      }, 3000); // 每3秒轮询一次
+    if (consoleRef.current) {
+      consoleRef.current.scrollTop = consoleRef.current.scrollHeight;
     }
-    return () => {
-      if (interval) clearInterval(interval);
+  }, [output]);
+
+  const stopStream = () => {
+    clearTimeout(reconnectRef.current);
+    if (unsubscribeRef.current) {
+      unsubscribeRef.current();
+      unsubscribeRef.current = null;
+    }
+    setStreaming(false);
+  };
+
+  // 订阅初始化进度，步骤状态和命令输出实时推送，初始化结束后服务端关闭连接
+  const startStream = () => {
+    if (!node || unsubscribeRef.current) return;
+
+    let finished = false;
+    const reconnect = () => {
+      unsubscribeRef.current = null;
+      setStreaming(false);
+      // 连接意外断开（如服务重启），稍后按最新状态决定是否重新订阅
+      if (!finished) {
+        reconnectRef.current = setTimeout(() => {
+          loadInitStatus();
+          loadInitLogs();
+        }, 3000);
+      }
     };
-  }, [polling]);
+
+    setOutput([]);
+    setStreaming(true);
+    unsubscribeRef.current = streamInitProgress(node.id, {
+      onEvent: (event, data) => {
+        if (event === 'step' && data.log) {
+          upsertLog(data.log);
+        } else if (event === 'output') {
+          setOutput((prev) => [...prev, data].slice(-MAX_OUTPUT_LINES));
+        } else if (event === 'done') {
+          finished = true;
+          loadInitStatus();
+          loadInitLogs();
+        }
+      },
+      onClose: reconnect,
+      onError: (error) => {
+        console.error('初始化进度连接失败', error);
+        reconnect();
+      },
+    });
+  };
+
+  const upsertLog = (log) => {
+    setLogs((prev) => {
+      const index = prev.findIndex((l) => l.id === log.id);
+      if (index === -1) return [log, ...prev];
+      const next = [...prev];
+      next[index] = log;
+      return next;
+    });
+    updateCurrentStep(log.step);
+  };
 
   const loadInitStatus = async () => {
     if (!node) return;
@@ -71,13 +151,13 @@ const NodeInitializer = ({ visible, onClose, node }) => {
     try {
       const response = await checkNodeInit(node.id);
       setInitStatus(response.data);
+      if (response.data.init_status === 'installed') {
+        loadVersionInfo();
+      }
 
-      // 根据状态更新当前步骤
+      // 初始化中时订阅进度
       if (response.data.init_status === 'initializing') {
-        setPolling(true);
-        updateCurrentStep();
-      } else {
-        setPolling(false);
+        startStream();
       }
     } catch (error) {
       console.error('加载初始化状态失败', error);
@@ -89,17 +169,18 @@ const NodeInitializer = ({ visible, onClose, node }) => {
 
     try {
       const response = await getInitLogs(node.id);
-      setLogs(response.data || []);
+      const data = response.data || [];
+      setLogs(data);
+      if (data.length > 0) {
+        updateCurrentStep(data[0].step);
+      }
     } catch (error) {
       console.error('加载初始化日志失败', error);
     }
   };
 
-  const updateCurrentStep = () => {
-    if (logs.length === 0) return;
-
-    const latestLog = logs[0];
-    const stepIndex = steps.findIndex(s => s.key === latestLog.step);
+  const updateCurrentStep = (stepKey) => {
+    const stepIndex = steps.findIndex(s => s.key === stepKey);
     if (stepIndex !== -1) {
       setCurrentStep(stepIndex);
     }
@@ -110,8 +191,9 @@ const NodeInitializer = ({ visible, onClose, node }) => {
       setLoading(true);
       await initNode(node.id);
       message.success('初始化已开始');
-      setPolling(true);
-      loadInitLogs();
+      stopStream();
+      startStream();
+      loadInitStatus();
     } catch (error) {
       message.error('启动初始化失败');
     } finally {
@@ -119,6 +201,43 @@ const NodeInitializer = ({ visible, onClose, node }) => {
     }
   };
 
+  const loadVersionInfo = async () => {
+    try {
+      const response = await getNodeSmartDNSVersion(node.id);
+      setVersionInfo(response.data);
+    } catch (error) {
+      setVersionInfo(null);
+    }
+  };
+
+  const openUpgrade = async () => {
+    setUpgradeVersion(versionInfo?.target_version);
+    setForceUpgrade(false);
+    setUpgradeVisible(true);
+    try {
+      const response = await getSmartDNSReleases();
+      setReleases(response.data || []);
+    } catch (error) {
+      message.error('获取 SmartDNS 版本目录失败');
+    }
+  };
+
+  const handleUpgrade = async () => {
+    try {
+      setUpgrading(true);
+      const response = await upgradeNodeSmartDNS(node.id, {
+        version: upgradeVersion,
+        force: forceUpgrade,
+      });
+      message.success(`升级任务 #${response.data.id} 已开始，可在后台任务中查看进度，检查失败时会自动回退`);
+      setUpgradeVisible(false);
+    } catch (error) {
+      message.error('升级失败: ' + (error.response?.data?.message || error.message));
+    } finally {
+      setUpgrading(false);
+    }
+  };
+
   const handleUninstall = async () => {
     Modal.confirm({
       title: '确认卸载',
@@ -130,7 +249,6 @@ const NodeInitializer = ({ visible, onClose, node }) => {
         try {
           await uninstallSmartDNS(node.id);
           message.success('卸载任务已开始');
-          setPolling(true);
           setTimeout(() => {
             loadInitStatus();
             loadInitLogs();
@@ -152,11 +270,9 @@ const NodeInitializer = ({ visible, onClose, node }) => {
         try {
           await reinstallSmartDNS(node.id);
           message.success('重新安装任务已开始');
-          setPolling(true);
-          setTimeout(() => {
-            loadInitStatus();
-            loadInitLogs();
-          }, 2000);
+          stopStream();
+          startStream();
+          loadInitStatus();
         } catch (error) {
           message.error('重新安装失败');
         }
@@ -222,6 +338,11 @@ const NodeInitializer = ({ visible, onClose, node }) => {
         )}
         {status === 'installed' && (
           <>
+            {(!initStatus.installed_via || initStatus.installed_via === 'tarball') && (
+              <Button type={versionInfo?.upgrade_available ? 'primary' : 'default'} onClick={openUpgrade}>
+                升级
+              </Button>
+            )}
             <Button onClick={handleReinstall}>
               重新安装
             </Button>
@@ -281,6 +402,21 @@ const NodeInitializer = ({ visible, onClose, node }) => {
             <Descriptions.Item label="架构">
               {initStatus.architecture || '-'}
             </Descriptions.Item>
+            <Descriptions.Item label="安装方式">
+              {installModeText[initStatus.installed_via || initStatus.install_mode] || '自动选择'}
+            </Descriptions.Item>
+            <Descriptions.Item label="服务管理">
+              {initStatus.service_manager || '-'}
+            </Descriptions.Item>
+            {versionInfo && (
+              <Descriptions.Item label="目标版本">
+                <Space size={4}>
+                  {versionInfo.target_version}
+                  {versionInfo.pinned_by && <Tag color="blue">标签 {versionInfo.pinned_by}</Tag>}
+                  {versionInfo.upgrade_available && <Tag color="orange">可升级</Tag>}
+                </Space>
+              </Descriptions.Item>
+            )}
           </Descriptions>
         </Card>
       )}
@@ -327,6 +463,67 @@ const NodeInitializer = ({ visible, onClose, node }) => {
         ))}
       </Steps>
 
+      {(streaming || output.length > 0) && (
+        <Card
+          title="命令输出"
+          size="small"
+          style={{ marginBottom: 16 }}
+          extra={streaming && <Tag icon={<SyncOutlined spin />} color="processing">实时</Tag>}
+        >
+          <pre
+            ref={consoleRef}
+            style={{
+              maxHeight: 240,
+              overflow: 'auto',
+              margin: 0,
+              padding: 8,
+              background: '#1e1e1e',
+              color: '#d4d4d4',
+              fontSize: 12,
+              whiteSpace: 'pre-wrap',
+              wordBreak: 'break-all',
+            }}
+          >
+            {output.length > 0
+              ? output.map((line) => `[${line.step}] ${line.output}`).join('\n')
+              : '等待命令输出...'}
+          </pre>
+        </Card>
+      )}
+
+      <Modal
+        title="升级 SmartDNS"
+        open={upgradeVisible}
+        onCancel={() => setUpgradeVisible(false)}
+        onOk={handleUpgrade}
+        confirmLoading={upgrading}
+        okText="开始升级"
+        destroyOnClose
+      >
+        <Alert
+          type="info"
+          showIcon
+          style={{ marginBottom: 16 }}
+          message="升级只替换程序文件并保留配置，重启后检查服务状态、版本、配置和解析，任一检查失败时自动恢复原程序。"
+        />
+        <Space direction="vertical" style={{ width: '100%' }}>
+          <span>当前版本：{versionInfo?.current_version || initStatus?.smartdns_version || '-'}</span>
+          <Select
+            style={{ width: '100%' }}
+            value={upgradeVersion}
+            onChange={setUpgradeVersion}
+            placeholder="目标版本"
+            options={releases.map((release) => ({
+              value: release.version,
+              label: `${release.version}（${release.tag}）${release.version === versionInfo?.target_version ? ' - 目标版本' : ''}`,
+            }))}
+          />
+          <Checkbox checked={forceUpgrade} onChange={(e) => setForceUpgrade(e.target.checked)}>
+            已是该版本时仍重新安装
+          </Checkbox>
+        </Space>
+      </Modal>
+
       <Card title="初始化日志" size="small">
         {logs.length > 0 ? (
           <Timeline mode="left">
@@ -25,6 +25,7 @@ import {
   MoreOutlined,
   PlayCircleOutlined,
   ReloadOutlined,
+  BarChartOutlined,
 } from "@ant-design/icons";
 import dayjs from "dayjs";
 import {
@@ -38,6 +39,7 @@ import NodeForm from "./NodeForm";
 import NodeStatus from "./NodeStatus";
 import SyncStatus from "../Config/SyncStatus";
 import NodeInitializer from "./NodeInitializer";
+import FleetReport from "./FleetReport";
 import AgentStatus from "../Agent/AgentStatus";
 import { useNavigate } from "react-router-dom";
 
@@ -53,6 +55,7 @@ const NodeList = () => {
   const [selectedSyncNode, setSelectedSyncNode] = useState(null);
   const [initModalVisible, setInitModalVisible] = useState(false);
   const [selectedInitNode, setSelectedInitNode] = useState(null);
+  const [fleetReportVisible, setFleetReportVisible] = useState(false);
 
   const handleInitNode = (node) => {
     setSelectedInitNode(node);
@@ -266,6 +269,56 @@ const NodeList = () => {
         );
       },
     },
+    {
+      title: "日志采集",
+      dataIndex: "log_monitor_status",
+      key: "log_monitor_status",
+      width: 110,
+      render: (status, record) => {
+        if (!record.log_monitor_enabled || !status) return "-";
+        const colors = {
+          running: "success",
+          recovering: "warning",
+          down: "error",
+        };
+        const texts = {
+          running: "正常",
+          recovering: "恢复中",
+          down: "中断",
+        };
+        const tag = (
+          <Tag color={colors[status] || "default"}>{texts[status] || status}</Tag>
+        );
+        return record.log_monitor_down_since ? (
+          <Tooltip
+            title={`中断开始: ${dayjs(record.log_monitor_down_since).format("YYYY-MM-DD HH:mm:ss")}`}
+          >
+            {tag}
+          </Tooltip>
+        ) : (
+          tag
+        );
+      },
+    },
+    {
+      title: "解析探测",
+      dataIndex: "dns_probe_ok",
+      key: "dns_probe_ok",
+      width: 110,
+      render: (ok, record) => {
+        if (!record.dns_probe_at) return "-";
+        const probedAt = dayjs(record.dns_probe_at).format("YYYY-MM-DD HH:mm:ss");
+        return ok ? (
+          <Tooltip title={`探测时间: ${probedAt}`}>
+            <Tag color="success">{record.dns_probe_latency}ms</Tag>
+          </Tooltip>
+        ) : (
+          <Tooltip title={`${record.dns_probe_error}（${probedAt}）`}>
+            <Tag color="error">失败</Tag>
+          </Tooltip>
+        );
+      },
+    },
     {
       title: "最后检查",
       dataIndex: "last_check",
@@ -508,6 +561,12 @@ const NodeList = () => {
           <Button icon={<ReloadOutlined />} onClick={loadNodes}>
             刷新列表
           </Button>
+          <Button
+            icon={<BarChartOutlined />}
+            onClick={() => setFleetReportVisible(true)}
+          >
+            资产报告
+          </Button>
         </Space>
         <Space>
           <span style={{ color: "#666" }}>
@@ -562,6 +621,10 @@ const NodeList = () => {
       >
         {selectedNode && <NodeStatus node={selectedNode} />}
       </Modal>
+      <FleetReport
+        visible={fleetReportVisible}
+        onClose={() => setFleetReportVisible(false)}
+      />
       {/* 添加同步状态 Modal */}
       <SyncStatus
         visible={syncStatusVisible}
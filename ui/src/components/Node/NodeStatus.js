@@ -9,27 +9,54 @@ import {
   Button,
   Tabs,
   Empty,
+  Segmented,
 } from 'antd';
 import {
   CheckCircleOutlined,
   CloseCircleOutlined,
   SyncOutlined,
   CodeOutlined,
+  LineChartOutlined,
 } from '@ant-design/icons';
-import { getNodeStatus, getNodeLogs } from '../../api';
+import {
+  ResponsiveContainer,
+  LineChart,
+  Line,
+  XAxis,
+  YAxis,
+  Tooltip,
+  Legend,
+  CartesianGrid,
+} from 'recharts';
+import { getNodeStatus, getNodeLogs, getNodeMetrics } from '../../api';
 import dayjs from 'dayjs';
 
+const METRIC_RANGES = [
+  { label: '1小时', value: 1 },
+  { label: '6小时', value: 6 },
+  { label: '24小时', value: 24 },
+  { label: '7天', value: 168 },
+  { label: '30天', value: 720 },
+];
+
 const NodeStatus = ({ node }) => {
   const [status, setStatus] = useState(null);
   const [logs, setLogs] = useState('');
   const [loading, setLoading] = useState(false);
   const [logsLoading, setLogsLoading] = useState(false);
+  const [metrics, setMetrics] = useState([]);
+  const [metricsHours, setMetricsHours] = useState(24);
+  const [metricsLoading, setMetricsLoading] = useState(false);
 
   useEffect(() => {
     loadStatus();
     loadLogs();
   }, [node]);
 
+  useEffect(() => {
+    loadMetrics();
+  }, [node, metricsHours]);
+
   const loadStatus = async () => {
     try {
       setLoading(true);
@@ -54,6 +81,24 @@ const NodeStatus = ({ node }) => {
     }
   };
 
+  const loadMetrics = async () => {
+    try {
+      setMetricsLoading(true);
+      const response = await getNodeMetrics(node.id, { hours: metricsHours });
+      setMetrics(
+        (response.data?.points || []).map((point) => ({
+          ...point,
+          time: dayjs(point.time).format(metricsHours > 24 ? 'MM-DD HH:mm' : 'HH:mm'),
+          smartdns_rss: parseFloat((point.smartdns_rss / 1024 / 1024).toFixed(1)),
+        }))
+      );
+    } catch (error) {
+      console.error('获取资源指标失败', error);
+    } finally {
+      setMetricsLoading(false);
+    }
+  };
+
   const getProgressColor = (value) => {
     if (value < 60) return '#52c41a';
     if (value < 80) return '#faad14';
@@ -164,6 +209,68 @@ const NodeStatus = ({ node }) => {
     </Spin>
   );
 
+  const metricsTab = (
+    <Spin spinning={metricsLoading}>
+      <Space direction="vertical" style={{ width: '100%' }} size="large">
+        <Space>
+          <Segmented
+            options={METRIC_RANGES}
+            value={metricsHours}
+            onChange={setMetricsHours}
+          />
+          <Button icon={<SyncOutlined />} onClick={loadMetrics} loading={metricsLoading}>
+            刷新
+          </Button>
+        </Space>
+        {metrics.length > 0 ? (
+          <>
+            <Card title="资源使用率 (%)" size="small">
+              <ResponsiveContainer width="100%" height={240}>
+                <LineChart data={metrics}>
+                  <CartesianGrid strokeDasharray="3 3" />
+                  <XAxis dataKey="time" minTickGap={30} />
+                  <YAxis domain={[0, 100]} />
+                  <Tooltip formatter={(value) => value.toFixed(1)} />
+                  <Legend />
+                  <Line type="monotone" dataKey="cpu_usage" name="CPU" stroke="#1677ff" dot={false} />
+                  <Line type="monotone" dataKey="memory_usage" name="内存" stroke="#52c41a" dot={false} />
+                  <Line type="monotone" dataKey="disk_usage" name="磁盘" stroke="#faad14" dot={false} />
+                </LineChart>
+              </ResponsiveContainer>
+            </Card>
+            <Card title="系统负载" size="small">
+              <ResponsiveContainer width="100%" height={200}>
+                <LineChart data={metrics}>
+                  <CartesianGrid strokeDasharray="3 3" />
+                  <XAxis dataKey="time" minTickGap={30} />
+                  <YAxis />
+                  <Tooltip formatter={(value) => value.toFixed(2)} />
+                  <Legend />
+                  <Line type="monotone" dataKey="load1" name="1分钟" stroke="#1677ff" dot={false} />
+                  <Line type="monotone" dataKey="load5" name="5分钟" stroke="#722ed1" dot={false} />
+                  <Line type="monotone" dataKey="load15" name="15分钟" stroke="#13c2c2" dot={false} />
+                </LineChart>
+              </ResponsiveContainer>
+            </Card>
+            <Card title="SmartDNS 进程内存 (MB)" size="small">
+              <ResponsiveContainer width="100%" height={200}>
+                <LineChart data={metrics}>
+                  <CartesianGrid strokeDasharray="3 3" />
+                  <XAxis dataKey="time" minTickGap={30} />
+                  <YAxis />
+                  <Tooltip />
+                  <Line type="monotone" dataKey="smartdns_rss" name="RSS" stroke="#eb2f96" dot={false} />
+                </LineChart>
+              </ResponsiveContainer>
+            </Card>
+          </>
+        ) : (
+          <Empty description="暂无资源指标，健康检查会按 NODE_METRICS_INTERVAL 定期采集" />
+        )}
+      </Space>
+    </Spin>
+  );
+
   const logsTab = (
     <Spin spinning={logsLoading}>
       <Space direction="vertical" style={{ width: '100%' }}>
@@ -201,6 +308,12 @@ const NodeStatus = ({ node }) => {
           label: '状态信息',
           children: statusTab,
         },
+        {
+          key: 'metrics',
+          label: '资源曲线',
+          icon: <LineChartOutlined />,
+          children: metricsTab,
+        },
         {
           key: 'logs',
           label: '服务日志',
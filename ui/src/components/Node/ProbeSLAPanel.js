@@ -0,0 +1,186 @@
+import React, { useState, useEffect } from "react";
+import {
+  Card,
+  Table,
+  Space,
+  Select,
+  InputNumber,
+  Button,
+  Tag,
+  Tooltip,
+  message,
+} from "antd";
+import { ReloadOutlined, CloudDownloadOutlined } from "@ant-design/icons";
+import dayjs from "dayjs";
+import { getProbeSLA, collectProbeStats } from "../../api/modules/scheduler";
+
+const rangeOptions = [
+  { value: 1, label: "最近1小时" },
+  { value: 24, label: "最近24小时" },
+  { value: 168, label: "最近7天" },
+];
+
+const domainColumns = [
+  { title: "探测域名", dataIndex: "domain", key: "domain" },
+  { title: "探测次数", dataIndex: "probes", key: "probes" },
+  { title: "失败次数", dataIndex: "failures", key: "failures" },
+  {
+    title: "成功率",
+    dataIndex: "success_rate",
+    key: "success_rate",
+    render: (v) => `${v.toFixed(2)}%`,
+  },
+  {
+    title: "平均延迟",
+    dataIndex: "avg_latency_ms",
+    key: "avg_latency_ms",
+    render: (v) => `${v.toFixed(1)}ms`,
+  },
+  {
+    title: "最大延迟",
+    dataIndex: "max_latency_ms",
+    key: "max_latency_ms",
+    render: (v) => `${v.toFixed(1)}ms`,
+  },
+];
+
+// 节点解析 SLA：各节点 Agent 通过本机 SmartDNS 探测的结果
+const ProbeSLAPanel = () => {
+  const [data, setData] = useState([]);
+  const [hours, setHours] = useState(24);
+  const [target, setTarget] = useState(99.9);
+  const [loading, setLoading] = useState(false);
+  const [collecting, setCollecting] = useState(false);
+
+  useEffect(() => {
+    loadSLA();
+  }, [hours, target]);
+
+  const loadSLA = async () => {
+    setLoading(true);
+    try {
+      const response = await getProbeSLA({ hours, target });
+      setData(response.data || []);
+    } catch (error) {
+      console.error("加载节点解析SLA失败:", error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleCollect = async () => {
+    setCollecting(true);
+    try {
+      const response = await collectProbeStats();
+      message.success(response.message);
+      loadSLA();
+    } catch (error) {
+      console.error("拉取探测结果失败:", error);
+    } finally {
+      setCollecting(false);
+    }
+  };
+
+  const columns = [
+    { title: "节点", dataIndex: "node_name", key: "node_name" },
+    {
+      title: "成功率",
+      dataIndex: "success_rate",
+      key: "success_rate",
+      render: (v, record) => (
+        <Tag color={record.meets_target ? "green" : "red"}>{v.toFixed(3)}%</Tag>
+      ),
+    },
+    {
+      title: "探测/失败",
+      key: "probes",
+      render: (_, record) => `${record.probes} / ${record.failures}`,
+    },
+    {
+      title: "平均延迟",
+      dataIndex: "avg_latency_ms",
+      key: "avg_latency_ms",
+      render: (v) => `${v.toFixed(1)}ms`,
+    },
+    {
+      title: "最大延迟",
+      dataIndex: "max_latency_ms",
+      key: "max_latency_ms",
+      render: (v) => `${v.toFixed(1)}ms`,
+    },
+    {
+      title: (
+        <Tooltip title="半数以上探测失败的5分钟周期累计时长">异常时长</Tooltip>
+      ),
+      dataIndex: "error_minutes",
+      key: "error_minutes",
+      render: (v) => (v > 0 ? `${v} 分钟` : "-"),
+    },
+    {
+      title: "最近数据",
+      dataIndex: "last_bucket",
+      key: "last_bucket",
+      render: (v) => dayjs(v).format("MM-DD HH:mm"),
+    },
+  ];
+
+  return (
+    <Card
+      title="节点解析 SLA（Agent 本地探测）"
+      style={{ marginBottom: 16 }}
+      extra={
+        <Space>
+          <Select
+            value={hours}
+            onChange={setHours}
+            options={rangeOptions}
+            style={{ width: 130 }}
+          />
+          <InputNumber
+            value={target}
+            onChange={(v) => v && setTarget(v)}
+            min={90}
+            max={100}
+            step={0.1}
+            addonBefore="目标"
+            addonAfter="%"
+            style={{ width: 180 }}
+          />
+          <Button icon={<ReloadOutlined />} onClick={loadSLA}>
+            刷新
+          </Button>
+          <Button
+            icon={<CloudDownloadOutlined />}
+            loading={collecting}
+            onClick={handleCollect}
+          >
+            立即拉取
+          </Button>
+        </Space>
+      }
+    >
+      <Table
+        columns={columns}
+        dataSource={data}
+        loading={loading}
+        rowKey="node_id"
+        size="small"
+        pagination={false}
+        locale={{ emptyText: "暂无数据，请创建“节点解析探测”定时任务" }}
+        expandable={{
+          expandedRowRender: (record) => (
+            <Table
+              columns={domainColumns}
+              dataSource={record.domains}
+              rowKey="domain"
+              size="small"
+              pagination={false}
+            />
+          ),
+        }}
+      />
+    </Card>
+  );
+};
+
+export default ProbeSLAPanel;
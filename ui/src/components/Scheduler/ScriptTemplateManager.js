@@ -0,0 +1,338 @@
+import React, { useState, useEffect } from "react";
+import {
+  Table,
+  Button,
+  Space,
+  Tag,
+  Modal,
+  Form,
+  Input,
+  Select,
+  Switch,
+  message,
+  Popconfirm,
+  Typography,
+} from "antd";
+import {
+  PlusOutlined,
+  EditOutlined,
+  DeleteOutlined,
+  MinusCircleOutlined,
+  EyeOutlined,
+} from "@ant-design/icons";
+import {
+  getScriptTemplates,
+  createScriptTemplate,
+  updateScriptTemplate,
+  deleteScriptTemplate,
+  renderScriptTemplate,
+} from "../../api/modules/scheduler";
+
+const { TextArea } = Input;
+const { Text } = Typography;
+
+const paramTypes = [
+  { value: "string", label: "字符串" },
+  { value: "int", label: "整数" },
+  { value: "bool", label: "布尔" },
+  { value: "enum", label: "枚举" },
+];
+
+const parseParams = (parameters) => {
+  try {
+    return JSON.parse(parameters || "[]") || [];
+  } catch (e) {
+    return [];
+  }
+};
+
+const ScriptTemplateManager = () => {
+  const [templates, setTemplates] = useState([]);
+  const [loading, setLoading] = useState(false);
+  const [modalVisible, setModalVisible] = useState(false);
+  const [editing, setEditing] = useState(null);
+  const [preview, setPreview] = useState(null);
+  const [form] = Form.useForm();
+
+  useEffect(() => {
+    loadTemplates();
+  }, []);
+
+  const loadTemplates = async () => {
+    setLoading(true);
+    try {
+      const response = await getScriptTemplates();
+      setTemplates(Array.isArray(response.data) ? response.data : []);
+    } catch (error) {
+      message.error("加载脚本模板失败");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const openModal = (template) => {
+    setEditing(template);
+    form.resetFields();
+    if (template) {
+      form.setFieldsValue({
+        ...template,
+        parameters: parseParams(template.parameters).map((param) => ({
+          ...param,
+          options: (param.options || []).join(","),
+        })),
+      });
+    }
+    setModalVisible(true);
+  };
+
+  const handleSubmit = async (values) => {
+    const data = {
+      ...values,
+      parameters: (values.parameters || []).map((param) => ({
+        ...param,
+        options:
+          param.type === "enum" && param.options
+            ? param.options.split(",").map((option) => option.trim())
+            : undefined,
+      })),
+    };
+    try {
+      if (editing) {
+        await updateScriptTemplate(editing.id, data);
+        message.success("模板更新成功");
+      } else {
+        await createScriptTemplate(data);
+        message.success("模板创建成功");
+      }
+      setModalVisible(false);
+      loadTemplates();
+    } catch (error) {
+      message.error(error.response?.data?.message || "保存模板失败");
+    }
+  };
+
+  const handleDelete = async (id) => {
+    try {
+      await deleteScriptTemplate(id);
+      message.success("模板删除成功");
+      loadTemplates();
+    } catch (error) {
+      message.error(error.response?.data?.message || "删除模板失败");
+    }
+  };
+
+  const handlePreview = async (template) => {
+    try {
+      const response = await renderScriptTemplate(template.id, {});
+      setPreview({ name: template.name, script: response.data?.script });
+    } catch (error) {
+      message.error(error.response?.data?.message || "渲染模板失败");
+    }
+  };
+
+  const columns = [
+    {
+      title: "ID",
+      dataIndex: "id",
+      width: 60,
+    },
+    {
+      title: "名称",
+      dataIndex: "name",
+      render: (name, record) => (
+        <Space>
+          {name}
+          {record.built_in && <Tag>内置</Tag>}
+        </Space>
+      ),
+    },
+    {
+      title: "分类",
+      dataIndex: "category",
+      width: 100,
+    },
+    {
+      title: "参数",
+      dataIndex: "parameters",
+      render: (parameters) =>
+        parseParams(parameters).map((param) => (
+          <Tag key={param.name}>
+            {param.name}: {param.type}
+            {param.default !== "" && param.default !== undefined
+              ? ` = ${param.default}`
+              : ""}
+          </Tag>
+        )),
+    },
+    {
+      title: "操作",
+      width: 200,
+      render: (_, record) => (
+        <Space>
+          <Button
+            type="link"
+            size="small"
+            icon={<EyeOutlined />}
+            onClick={() => handlePreview(record)}
+          >
+            预览
+          </Button>
+          <Button
+            type="link"
+            size="small"
+            icon={<EditOutlined />}
+            onClick={() => openModal(record)}
+          >
+            编辑
+          </Button>
+          <Popconfirm
+            title="确定删除该模板？"
+            onConfirm={() => handleDelete(record.id)}
+          >
+            <Button type="link" size="small" danger icon={<DeleteOutlined />}>
+              删除
+            </Button>
+          </Popconfirm>
+        </Space>
+      ),
+    },
+  ];
+
+  return (
+    <div>
+      <Space style={{ marginBottom: 16 }}>
+        <Button
+          type="primary"
+          icon={<PlusOutlined />}
+          onClick={() => openModal(null)}
+        >
+          新建模板
+        </Button>
+        <Text type="secondary">
+          自定义脚本任务配置 template_id 和 params
+          后，每次执行时用模板的最新内容渲染脚本
+        </Text>
+      </Space>
+      <Table
+        rowKey="id"
+        size="small"
+        columns={columns}
+        dataSource={templates}
+        loading={loading}
+        pagination={{ pageSize: 10 }}
+      />
+
+      <Modal
+        title={editing ? "编辑脚本模板" : "新建脚本模板"}
+        open={modalVisible}
+        onCancel={() => setModalVisible(false)}
+        onOk={() => form.submit()}
+        width={800}
+        destroyOnClose
+      >
+        <Form form={form} layout="vertical" onFinish={handleSubmit}>
+          <Form.Item
+            name="name"
+            label="名称"
+            rules={[{ required: true, message: "请输入模板名称" }]}
+          >
+            <Input />
+          </Form.Item>
+          <Space style={{ display: "flex" }} align="start">
+            <Form.Item name="category" label="分类">
+              <Input placeholder="如 系统维护" />
+            </Form.Item>
+            <Form.Item name="description" label="说明" style={{ width: 480 }}>
+              <Input />
+            </Form.Item>
+          </Space>
+          <Form.Item
+            name="script"
+            label="脚本"
+            tooltip="用 {{参数名}} 引用参数，字符串和枚举参数会替换为单引号包裹的 shell 字符串"
+            rules={[{ required: true, message: "请输入脚本内容" }]}
+          >
+            <TextArea
+              rows={10}
+              style={{ fontFamily: "monospace" }}
+              placeholder={"#!/bin/bash\nfind {{path}} -mtime +{{days}} -delete"}
+            />
+          </Form.Item>
+          <Form.List name="parameters">
+            {(fields, { add, remove }) => (
+              <>
+                {fields.map(({ key, name, ...restField }) => (
+                  <Space key={key} align="baseline" wrap>
+                    <Form.Item
+                      {...restField}
+                      name={[name, "name"]}
+                      rules={[{ required: true, message: "参数名" }]}
+                    >
+                      <Input placeholder="参数名" style={{ width: 120 }} />
+                    </Form.Item>
+                    <Form.Item {...restField} name={[name, "label"]}>
+                      <Input placeholder="显示名称" style={{ width: 120 }} />
+                    </Form.Item>
+                    <Form.Item
+                      {...restField}
+                      name={[name, "type"]}
+                      initialValue="string"
+                    >
+                      <Select options={paramTypes} style={{ width: 90 }} />
+                    </Form.Item>
+                    <Form.Item {...restField} name={[name, "default"]}>
+                      <Input placeholder="默认值" style={{ width: 110 }} />
+                    </Form.Item>
+                    <Form.Item {...restField} name={[name, "options"]}>
+                      <Input
+                        placeholder="枚举可选值，逗号分隔"
+                        style={{ width: 160 }}
+                      />
+                    </Form.Item>
+                    <Form.Item
+                      {...restField}
+                      name={[name, "required"]}
+                      valuePropName="checked"
+                    >
+                      <Switch checkedChildren="必填" unCheckedChildren="可选" />
+                    </Form.Item>
+                    <MinusCircleOutlined onClick={() => remove(name)} />
+                  </Space>
+                ))}
+                <Button
+                  type="dashed"
+                  onClick={() => add()}
+                  block
+                  icon={<PlusOutlined />}
+                >
+                  添加参数
+                </Button>
+              </>
+            )}
+          </Form.List>
+        </Form>
+      </Modal>
+
+      <Modal
+        title={`渲染预览（默认参数）: ${preview?.name || ""}`}
+        open={!!preview}
+        onCancel={() => setPreview(null)}
+        footer={null}
+        width={800}
+      >
+        <pre
+          style={{
+            backgroundColor: "#f5f5f5",
+            padding: 12,
+            maxHeight: 500,
+            overflow: "auto",
+          }}
+        >
+          {preview?.script}
+        </pre>
+      </Modal>
+    </div>
+  );
+};
+
+export default ScriptTemplateManager;
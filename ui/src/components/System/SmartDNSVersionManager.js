@@ -0,0 +1,233 @@
+import React, { useState, useEffect } from "react";
+import {
+  Table,
+  Button,
+  Space,
+  Tag,
+  Select,
+  Input,
+  Form,
+  Modal,
+  Popconfirm,
+  message,
+  Alert,
+  Card,
+} from "antd";
+import { PlusOutlined, ReloadOutlined } from "@ant-design/icons";
+import {
+  getSmartDNSReleases,
+  getSmartDNSVersionPins,
+  saveSmartDNSVersionPin,
+  deleteSmartDNSVersionPin,
+} from "../../api";
+import dayjs from "dayjs";
+
+const SmartDNSVersionManager = () => {
+  const [releases, setReleases] = useState([]);
+  const [catalogWarning, setCatalogWarning] = useState("");
+  const [pins, setPins] = useState([]);
+  const [loading, setLoading] = useState(false);
+  const [modalVisible, setModalVisible] = useState(false);
+  const [saving, setSaving] = useState(false);
+  const [form] = Form.useForm();
+
+  useEffect(() => {
+    loadReleases(false);
+    loadPins();
+  }, []);
+
+  const loadReleases = async (refresh) => {
+    setLoading(true);
+    try {
+      const response = await getSmartDNSReleases(refresh);
+      setReleases(response.data || []);
+      setCatalogWarning(response.message || "");
+    } catch (error) {
+      message.error("获取 SmartDNS 版本目录失败");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const loadPins = async () => {
+    try {
+      const response = await getSmartDNSVersionPins();
+      setPins(response.data || []);
+    } catch (error) {
+      message.error("加载版本固定失败");
+    }
+  };
+
+  const openModal = (pin) => {
+    form.setFieldsValue(pin || { tag: "", version: undefined, description: "" });
+    setModalVisible(true);
+  };
+
+  const handleSave = async () => {
+    try {
+      const values = await form.validateFields();
+      setSaving(true);
+      await saveSmartDNSVersionPin(values);
+      message.success("版本固定已保存");
+      setModalVisible(false);
+      loadPins();
+    } catch (error) {
+      if (error.errorFields) return;
+      message.error("保存失败: " + (error.response?.data?.message || error.message));
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const handleDelete = async (id) => {
+    try {
+      await deleteSmartDNSVersionPin(id);
+      message.success("已取消版本固定");
+      loadPins();
+    } catch (error) {
+      message.error("删除失败");
+    }
+  };
+
+  const pinColumns = [
+    {
+      title: "节点标签",
+      dataIndex: "tag",
+      render: (tag) => <Tag color="blue">{tag}</Tag>,
+    },
+    { title: "目标版本", dataIndex: "version" },
+    { title: "说明", dataIndex: "description" },
+    {
+      title: "更新时间",
+      dataIndex: "updated_at",
+      render: (time) => dayjs(time).format("YYYY-MM-DD HH:mm:ss"),
+    },
+    {
+      title: "操作",
+      key: "action",
+      render: (_, pin) => (
+        <Space>
+          <Button size="small" onClick={() => openModal(pin)}>
+            修改
+          </Button>
+          <Popconfirm
+            title="取消固定后，带该标签的节点恢复使用默认版本"
+            onConfirm={() => handleDelete(pin.id)}
+          >
+            <Button size="small" danger>
+              删除
+            </Button>
+          </Popconfirm>
+        </Space>
+      ),
+    },
+  ];
+
+  const releaseColumns = [
+    { title: "Release", dataIndex: "tag", width: 120 },
+    {
+      title: "版本",
+      dataIndex: "version",
+      render: (version, release) => (
+        <Space>
+          {version}
+          {release.prerelease && <Tag color="orange">预发布</Tag>}
+        </Space>
+      ),
+    },
+    {
+      title: "发布时间",
+      dataIndex: "published_at",
+      width: 180,
+      render: (time) => (time ? dayjs(time).format("YYYY-MM-DD HH:mm") : "-"),
+    },
+    {
+      title: "支持架构",
+      dataIndex: "assets",
+      render: (assets) =>
+        (assets || []).map((asset) => <Tag key={asset.name}>{asset.architecture}</Tag>),
+    },
+  ];
+
+  return (
+    <Space direction="vertical" style={{ width: "100%" }} size="large">
+      <Card
+        size="small"
+        title="按节点标签固定版本"
+        extra={
+          <Button type="primary" icon={<PlusOutlined />} onClick={() => openModal(null)}>
+            固定版本
+          </Button>
+        }
+      >
+        <Alert
+          type="info"
+          showIcon
+          style={{ marginBottom: 16 }}
+          message="带有固定标签的节点在初始化和升级时安装固定的版本；节点的多个标签都固定了版本时使用最早创建的固定，没有固定的节点使用 INIT_VERSION。"
+        />
+        <Table rowKey="id" columns={pinColumns} dataSource={pins} pagination={false} size="small" />
+      </Card>
+
+      <Card
+        size="small"
+        title="版本目录"
+        extra={
+          <Button icon={<ReloadOutlined />} loading={loading} onClick={() => loadReleases(true)}>
+            从 GitHub 更新
+          </Button>
+        }
+      >
+        {catalogWarning && (
+          <Alert type="warning" showIcon style={{ marginBottom: 16 }} message={catalogWarning} />
+        )}
+        <Table
+          rowKey="tag"
+          columns={releaseColumns}
+          dataSource={releases}
+          loading={loading}
+          size="small"
+          pagination={{ pageSize: 10 }}
+        />
+      </Card>
+
+      <Modal
+        title="固定 SmartDNS 版本"
+        open={modalVisible}
+        onCancel={() => setModalVisible(false)}
+        onOk={handleSave}
+        confirmLoading={saving}
+        destroyOnClose
+      >
+        <Form form={form} layout="vertical">
+          <Form.Item
+            name="tag"
+            label="节点标签"
+            rules={[{ required: true, message: "请输入节点标签" }]}
+          >
+            <Input placeholder="如 prod、edge" />
+          </Form.Item>
+          <Form.Item
+            name="version"
+            label="目标版本"
+            rules={[{ required: true, message: "请选择目标版本" }]}
+          >
+            <Select
+              showSearch
+              placeholder="选择版本"
+              options={releases.map((release) => ({
+                value: release.version,
+                label: `${release.version}（${release.tag}${release.prerelease ? "，预发布" : ""}）`,
+              }))}
+            />
+          </Form.Item>
+          <Form.Item name="description" label="说明">
+            <Input placeholder="如 灰度验证通过后推广" />
+          </Form.Item>
+        </Form>
+      </Modal>
+    </Space>
+  );
+};
+
+export default SmartDNSVersionManager;
@@ -0,0 +1,164 @@
+import React, { useState, useEffect } from "react";
+import {
+  Table,
+  Button,
+  Space,
+  Tag,
+  Select,
+  Input,
+  Switch,
+  message,
+  Alert,
+  Typography,
+} from "antd";
+import { ReloadOutlined } from "@ant-design/icons";
+import { getSystemLogs } from "../../api";
+import dayjs from "dayjs";
+
+const { Text } = Typography;
+
+const levelColors = {
+  DEBUG: "default",
+  INFO: "blue",
+  WARN: "warning",
+  ERROR: "error",
+};
+
+const SystemLogs = () => {
+  const [logs, setLogs] = useState([]);
+  const [level, setLevel] = useState("info");
+  const [requestId, setRequestId] = useState("");
+  const [keyword, setKeyword] = useState("");
+  const [autoRefresh, setAutoRefresh] = useState(false);
+  const [loading, setLoading] = useState(false);
+
+  useEffect(() => {
+    loadLogs();
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [level, requestId]);
+
+  useEffect(() => {
+    if (!autoRefresh) {
+      return undefined;
+    }
+    const timer = setInterval(loadLogs, 5000);
+    return () => clearInterval(timer);
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [autoRefresh, level, requestId, keyword]);
+
+  const loadLogs = async () => {
+    setLoading(true);
+    try {
+      const response = await getSystemLogs({
+        level,
+        request_id: requestId || undefined,
+        keyword: keyword || undefined,
+        limit: 500,
+      });
+      setLogs(response.data || []);
+    } catch (error) {
+      message.error("加载后端日志失败");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const columns = [
+    {
+      title: "时间",
+      dataIndex: "time",
+      width: 170,
+      render: (time) => dayjs(time).format("YYYY-MM-DD HH:mm:ss"),
+    },
+    {
+      title: "级别",
+      dataIndex: "level",
+      width: 80,
+      render: (value) => <Tag color={levelColors[value]}>{value}</Tag>,
+    },
+    {
+      title: "消息",
+      dataIndex: "message",
+      render: (messageText, record) => (
+        <Space direction="vertical" size={0}>
+          <Text style={{ whiteSpace: "pre-wrap" }}>{messageText}</Text>
+          {record.attrs && (
+            <Text type="secondary" style={{ fontSize: 12 }}>
+              {Object.entries(record.attrs)
+                .map(([key, value]) => `${key}=${value}`)
+                .join(" ")}
+            </Text>
+          )}
+        </Space>
+      ),
+    },
+    {
+      title: "请求 ID",
+      dataIndex: "request_id",
+      width: 170,
+      render: (id) =>
+        id ? (
+          <Button type="link" size="small" onClick={() => setRequestId(id)}>
+            {id}
+          </Button>
+        ) : (
+          "-"
+        ),
+    },
+  ];
+
+  return (
+    <Space direction="vertical" style={{ width: "100%" }}>
+      <Alert
+        type="info"
+        showIcon
+        message="这里只保留最近的后端日志（LOG_BUFFER_SIZE 条），服务重启后清空。点击请求 ID 可查看同一请求及其后台任务的全部日志，接口响应头 X-Request-ID 中也会返回该 ID。"
+      />
+      <Space wrap>
+        <Select
+          value={level}
+          onChange={setLevel}
+          style={{ width: 120 }}
+          options={[
+            { value: "debug", label: "DEBUG 及以上" },
+            { value: "info", label: "INFO 及以上" },
+            { value: "warn", label: "WARN 及以上" },
+            { value: "error", label: "ERROR" },
+          ]}
+        />
+        <Input
+          placeholder="请求 ID"
+          value={requestId}
+          onChange={(e) => setRequestId(e.target.value.trim())}
+          allowClear
+          style={{ width: 200 }}
+        />
+        <Input.Search
+          placeholder="关键字"
+          value={keyword}
+          onChange={(e) => setKeyword(e.target.value)}
+          onSearch={loadLogs}
+          allowClear
+          style={{ width: 220 }}
+        />
+        <Button icon={<ReloadOutlined />} onClick={loadLogs} loading={loading}>
+          刷新
+        </Button>
+        <Space>
+          <Switch checked={autoRefresh} onChange={setAutoRefresh} size="small" />
+          <Text>自动刷新</Text>
+        </Space>
+      </Space>
+      <Table
+        rowKey={(record, index) => `${record.time}-${index}`}
+        columns={columns}
+        dataSource={logs}
+        loading={loading}
+        size="small"
+        pagination={{ pageSize: 50, showSizeChanger: false }}
+      />
+    </Space>
+  );
+};
+
+export default SystemLogs;
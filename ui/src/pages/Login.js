@@ -1,26 +1,68 @@
-import React, { useState } from 'react';
-import { Form, Input, Button, Card, message, Tabs } from 'antd';
-import { UserOutlined, LockOutlined, MailOutlined } from '@ant-design/icons';
-import { useNavigate } from 'react-router-dom';
-import { login, register } from '../api';
+import React, { useState, useEffect } from 'react';
+import { Form, Input, Button, Card, message, Tabs, Divider } from 'antd';
+import { UserOutlined, LockOutlined, MailOutlined, SafetyCertificateOutlined } from '@ant-design/icons';
+import { useNavigate, useSearchParams } from 'react-router-dom';
+import { login, register, getSSOConfig, exchangeSSOCode, getOIDCLoginURL, webhookTokenLogin } from '../api';
 import { setToken, setUserInfo } from '../utils/auth';
 import './Login.css';
 
 const Login = () => {
   const [loading, setLoading] = useState(false);
   const [activeTab, setActiveTab] = useState('login');
+  const [ssoConfig, setSSOConfig] = useState({});
+  const [searchParams, setSearchParams] = useSearchParams();
   const navigate = useNavigate();
 
+  useEffect(() => {
+    getSSOConfig()
+      .then((response) => setSSOConfig(response.data || {}))
+      .catch(() => setSSOConfig({}));
+  }, []);
+
+  // IdP 回调后带回一次性登录码或错误信息；内部门户可带 auth_token 跳转，由认证 Webhook 校验
+  useEffect(() => {
+    const code = searchParams.get('sso_code');
+    const ssoError = searchParams.get('sso_error');
+    const authToken = searchParams.get('auth_token');
+    if (!code && !ssoError && !authToken) {
+      return;
+    }
+    setSearchParams({}, { replace: true });
+
+    if (ssoError) {
+      message.error(ssoError);
+      return;
+    }
+
+    if (authToken) {
+      setLoading(true);
+      webhookTokenLogin(authToken)
+        .then(finishLogin)
+        .catch(() => message.error('令牌登录失败'))
+        .finally(() => setLoading(false));
+      return;
+    }
+
+    setLoading(true);
+    exchangeSSOCode(code)
+      .then(finishLogin)
+      .catch(() => message.error('单点登录失败'))
+      .finally(() => setLoading(false));
+  }, []);
+
+  const finishLogin = (response) => {
+    setToken(response.data.token);
+    setUserInfo(response.data.user);
+
+    message.success('登录成功');
+    navigate('/');
+  };
+
   const onLogin = async (values) => {
     try {
       setLoading(true);
       const response = await login(values);
-      
-      setToken(response.data.token);
-      setUserInfo(response.data.user);
-      
-      message.success('登录成功');
-      navigate('/');
+      finishLogin(response);
     } catch (error) {
       message.error('登录失败');
     } finally {
@@ -55,7 +97,13 @@ const Login = () => {
       >
         <Input 
           prefix={<UserOutlined />} 
-          placeholder="用户名" 
+          placeholder={
+            ssoConfig.ldap_enabled
+              ? '用户名（支持 LDAP 账号）'
+              : ssoConfig.webhook_enabled
+                ? '用户名（支持企业账号）'
+                : '用户名'
+          }
         />
       </Form.Item>
 
@@ -74,6 +122,19 @@ const Login = () => {
           登录
         </Button>
       </Form.Item>
+
+      {ssoConfig.oidc_enabled && (
+        <>
+          <Divider plain>或</Divider>
+          <Button
+            icon={<SafetyCertificateOutlined />}
+            href={getOIDCLoginURL()}
+            block
+          >
+            使用 {ssoConfig.oidc_provider_name || 'SSO'} 登录
+          </Button>
+        </>
+      )}
     </Form>
   );
 
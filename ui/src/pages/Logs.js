@@ -17,11 +17,13 @@ import {
   FileSearchOutlined,
   BarChartOutlined,
   PlayCircleOutlined,
+  FieldTimeOutlined,
 } from '@ant-design/icons';
-import { getNodes, cleanNodeLogs } from '../api';
+import { getNodes, cleanNodeLogs, getLogStorageInfo, reconcileLogSchema } from '../api';
 import LogMonitorControl from '../components/Log/LogMonitorControl';
 import LogList from '../components/Log/LogList';
 import LogStats from '../components/Log/LogStats';
+import LogRetentionModal from '../components/Log/LogRetentionModal';
 
 const { Option } = Select;
 
@@ -30,11 +32,39 @@ const Logs = () => {
   const [selectedNode, setSelectedNode] = useState(null);
   const [loading, setLoading] = useState(false);
   const [activeTab, setActiveTab] = useState('logs');
+  const [schemaReport, setSchemaReport] = useState(null);
+  const [reconciling, setReconciling] = useState(false);
+  const [retentionVisible, setRetentionVisible] = useState(false);
 
   useEffect(() => {
     loadNodes();
+    loadSchemaReport();
   }, []);
 
+  const loadSchemaReport = async () => {
+    try {
+      const response = await getLogStorageInfo();
+      setSchemaReport(response.data?.schema || null);
+    } catch (error) {
+      console.error('获取日志存储信息失败:', error);
+    }
+  };
+
+  const handleReconcileSchema = async () => {
+    try {
+      setReconciling(true);
+      const response = await reconcileLogSchema();
+      setSchemaReport(response.data?.schema || null);
+      message.success('表结构校对完成');
+    } catch (error) {
+      loadSchemaReport();
+    } finally {
+      setReconciling(false);
+    }
+  };
+
+  const schemaFailed = Object.keys(schemaReport?.failed || {});
+
   const loadNodes = async () => {
     try {
       setLoading(true);
@@ -160,6 +190,13 @@ const Logs = () => {
             >
               刷新
             </Button>
+            <Button
+              icon={<FieldTimeOutlined />}
+              onClick={() => setRetentionVisible(true)}
+              disabled={!selectedNode}
+            >
+              保留策略
+            </Button>
             <Button
               danger
               icon={<DeleteOutlined />}
@@ -171,6 +208,23 @@ const Logs = () => {
           </Space>
         }
       >
+        {schemaFailed.length > 0 && (
+          <Alert
+            type="warning"
+            showIcon
+            style={{ marginBottom: 16 }}
+            message={`日志表缺少列 ${schemaFailed.join('、')}，相关查询可能报错`}
+            description={schemaFailed
+              .map((column) => `${column}: ${schemaReport.failed[column]}`)
+              .join('；')}
+            action={
+              <Button size="small" loading={reconciling} onClick={handleReconcileSchema}>
+                重新校对
+              </Button>
+            }
+          />
+        )}
+
         {selectedNode && (
           <div style={{ marginBottom: 16 }}>
             <LogMonitorControl
@@ -234,6 +288,13 @@ const Logs = () => {
           </Card>
         )}
       </Card>
+
+      <LogRetentionModal
+        visible={retentionVisible}
+        onCancel={() => setRetentionVisible(false)}
+        nodeId={selectedNode}
+        nodeName={selectedNodeInfo?.name}
+      />
     </div>
   );
 };
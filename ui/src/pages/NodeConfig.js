@@ -23,12 +23,15 @@ import {
   getNodeConfig,
   saveNodeConfig,
   restartNodeService,
+  reloadNodeService,
   getNodeBackups,
   restoreNodeBackup,
   getNodes,
 } from '../api';
 import ServerManager from '../components/Config/ServerManager';
 import AddressManager from '../components/Config/AddressManager';
+import NodeCachePanel from '../components/Node/NodeCachePanel';
+import NodeConfigHistory from '../components/Node/NodeConfigHistory';
 
 const NodeConfig = () => {
   const { id } = useParams();
@@ -104,6 +107,19 @@ const NodeConfig = () => {
     });
   };
 
+  // 节点不在维护窗口内时操作会被推迟，询问是否忽略维护窗口立即执行
+  const confirmOverride = (response, key, run) => {
+    message.info({ content: response.message, key });
+    Modal.confirm({
+      title: '不在维护窗口内',
+      content: `${response.message}。是否忽略维护窗口立即执行？`,
+      okText: '立即执行',
+      cancelText: '等待窗口',
+      okType: 'danger',
+      onOk: run,
+    });
+  };
+
   const handleRestart = async () => {
     Modal.confirm({
       title: '确认重启服务',
@@ -114,7 +130,14 @@ const NodeConfig = () => {
       onOk: async () => {
         try {
           message.loading({ content: '正在重启服务...', key: 'restart' });
-          await restartNodeService(id);
+          const response = await restartNodeService(id);
+          if (response.deferred) {
+            confirmOverride(response, 'restart', async () => {
+              await restartNodeService(id, true);
+              message.success({ content: '服务重启成功', key: 'restart' });
+            });
+            return;
+          }
           message.success({ content: '服务重启成功', key: 'restart' });
         } catch (error) {
           message.error({ content: '服务重启失败', key: 'restart' });
@@ -123,6 +146,23 @@ const NodeConfig = () => {
     });
   };
 
+  const handleReload = async () => {
+    try {
+      message.loading({ content: '正在重载服务...', key: 'reload' });
+      const response = await reloadNodeService(id);
+      if (response.deferred) {
+        confirmOverride(response, 'reload', async () => {
+          const result = await reloadNodeService(id, true);
+          message.success({ content: result.message, key: 'reload' });
+        });
+        return;
+      }
+      message.success({ content: response.message, key: 'reload' });
+    } catch (error) {
+      message.error({ content: '服务重载失败', key: 'reload' });
+    }
+  };
+
   const handleRestore = (backupPath) => {
     Modal.confirm({
       title: '确认恢复备份',
@@ -147,7 +187,7 @@ const NodeConfig = () => {
       <Space direction="vertical" style={{ width: '100%' }} size="large">
         <Alert
           message="配置编辑器"
-          description="直接编辑 SmartDNS 配置文件。保存后不会自动重启服务，需要手动重启。"
+          description="直接编辑 SmartDNS 配置文件。保存后不会自动重启服务，需要手动重启或重载。"
           type="info"
           showIcon
         />
@@ -183,6 +223,9 @@ const NodeConfig = () => {
           >
             重启服务
           </Button>
+          <Button onClick={handleReload}>
+            重载服务
+          </Button>
           <Button onClick={loadConfig}>
             重新加载
           </Button>
@@ -307,6 +350,21 @@ const NodeConfig = () => {
               label: '地址映射',
               children: addressesTab,
             },
+            {
+              key: 'cache',
+              label: '缓存',
+              children: <NodeCachePanel nodeId={id} />,
+            },
+            {
+              key: 'history',
+              label: (
+                <span>
+                  <HistoryOutlined />
+                  配置历史
+                </span>
+              ),
+              children: <NodeConfigHistory nodeId={id} />,
+            },
           ]}
         />
       </Card>
@@ -17,9 +17,15 @@ import {
   SettingOutlined,
   BellOutlined,
   DatabaseOutlined,
+  KeyOutlined,
+  FileTextOutlined,
+  CloudUploadOutlined,
 } from '@ant-design/icons';
 import { getUserInfo } from '../utils/auth';
 import DatabaseBackupManager from '../components/Backup/DatabaseBackupManager';
+import APITokenManager from '../components/APIToken/APITokenManager';
+import SystemLogs from '../components/System/SystemLogs';
+import SmartDNSVersionManager from '../components/System/SmartDNSVersionManager';
 
 const Settings = () => {
   const [form] = Form.useForm();
@@ -187,6 +193,16 @@ const Settings = () => {
             ),
             children: securityTab,
           },
+          {
+            key: 'tokens',
+            label: (
+              <span>
+                <KeyOutlined />
+                API 令牌
+              </span>
+            ),
+            children: <APITokenManager />,
+          },
           {
             key: 'backup',
             label: (
@@ -207,6 +223,26 @@ const Settings = () => {
             ),
             children: systemTab,
           },
+          {
+            key: 'smartdns-versions',
+            label: (
+              <span>
+                <CloudUploadOutlined />
+                SmartDNS 版本
+              </span>
+            ),
+            children: <SmartDNSVersionManager />,
+          },
+          {
+            key: 'logs',
+            label: (
+              <span>
+                <FileTextOutlined />
+                后端日志
+              </span>
+            ),
+            children: <SystemLogs />,
+          },
         ]}
       />
     </Card>
@@ -0,0 +1,208 @@
+import React, { useState, useEffect } from "react";
+import { useParams } from "react-router-dom";
+import {
+  Card,
+  Table,
+  Tag,
+  Descriptions,
+  Alert,
+  Statistic,
+  Row,
+  Col,
+  Spin,
+} from "antd";
+import dayjs from "dayjs";
+import { viewSharedLogs } from "../api";
+
+// 通过分享链接只读查看日志，无需登录
+const SharedLogs = () => {
+  const { token } = useParams();
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState(null);
+  const [share, setShare] = useState(null);
+  const [data, setData] = useState(null);
+  const [pagination, setPagination] = useState({
+    current: 1,
+    pageSize: 20,
+    total: 0,
+  });
+
+  useEffect(() => {
+    loadData(pagination.current, pagination.pageSize);
+  }, [token]);
+
+  const loadData = async (page, pageSize) => {
+    setLoading(true);
+    try {
+      const response = await viewSharedLogs(token, {
+        page,
+        page_size: pageSize,
+      });
+      setShare(response.share);
+      setData(response.data);
+      if (response.share?.kind === "logs") {
+        setPagination({ current: page, pageSize, total: response.data.total });
+      }
+      setError(null);
+    } catch (err) {
+      setError(err.response?.data?.message || err.message);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const columns = [
+    {
+      title: "时间",
+      dataIndex: "timestamp",
+      key: "timestamp",
+      width: 180,
+      render: (text) => dayjs(text).format("YYYY-MM-DD HH:mm:ss"),
+    },
+    {
+      title: "客户端",
+      dataIndex: "client_ip",
+      key: "client_ip",
+      width: 150,
+    },
+    {
+      title: "域名",
+      dataIndex: "domain",
+      key: "domain",
+      ellipsis: true,
+    },
+    {
+      title: "类型",
+      dataIndex: "query_type",
+      key: "query_type",
+      width: 80,
+      render: (type, record) => <Tag>{record.query_type_name || type}</Tag>,
+    },
+    {
+      title: "应答",
+      dataIndex: "result_ips",
+      key: "result_ips",
+      ellipsis: true,
+    },
+    {
+      title: "耗时",
+      dataIndex: "time_ms",
+      key: "time_ms",
+      width: 90,
+      render: (ms) => `${ms} ms`,
+    },
+  ];
+
+  if (error) {
+    return (
+      <div style={{ maxWidth: 600, margin: "80px auto" }}>
+        <Alert
+          type="error"
+          showIcon
+          message="无法查看分享内容"
+          description={error}
+        />
+      </div>
+    );
+  }
+
+  return (
+    <div style={{ padding: 24 }}>
+      <Spin spinning={loading}>
+        {share && (
+          <Card
+            title={share.description || "DNS 日志分享"}
+            style={{ marginBottom: 16 }}
+          >
+            <Descriptions size="small" column={2}>
+              <Descriptions.Item label="时间范围">
+                {dayjs(share.start_time).format("YYYY-MM-DD HH:mm:ss")} ~{" "}
+                {dayjs(share.end_time).format("YYYY-MM-DD HH:mm:ss")}
+              </Descriptions.Item>
+              <Descriptions.Item label="链接有效期至">
+                {dayjs(share.expires_at).format("YYYY-MM-DD HH:mm:ss")}
+              </Descriptions.Item>
+              <Descriptions.Item label="过滤条件" span={2}>
+                {Object.entries(share.filters || {}).map(([key, value]) => (
+                  <Tag key={key}>
+                    {key}: {value}
+                  </Tag>
+                ))}
+              </Descriptions.Item>
+            </Descriptions>
+          </Card>
+        )}
+
+        {share?.kind === "stats" && data && (
+          <Card>
+            <Row gutter={16}>
+              <Col span={6}>
+                <Statistic title="查询总数" value={data.total_queries} />
+              </Col>
+              <Col span={6}>
+                <Statistic title="客户端数" value={data.unique_clients} />
+              </Col>
+              <Col span={6}>
+                <Statistic title="域名数" value={data.unique_domains} />
+              </Col>
+              <Col span={6}>
+                <Statistic
+                  title="平均耗时"
+                  value={data.avg_query_time}
+                  precision={1}
+                  suffix="ms"
+                />
+              </Col>
+            </Row>
+            <Row gutter={16} style={{ marginTop: 24 }}>
+              <Col span={12}>
+                <Table
+                  size="small"
+                  title={() => "热门域名"}
+                  rowKey="domain"
+                  pagination={false}
+                  dataSource={data.top_domains || []}
+                  columns={[
+                    { title: "域名", dataIndex: "domain" },
+                    { title: "次数", dataIndex: "count", width: 100 },
+                  ]}
+                />
+              </Col>
+              <Col span={12}>
+                <Table
+                  size="small"
+                  title={() => "活跃客户端"}
+                  rowKey="client_ip"
+                  pagination={false}
+                  dataSource={data.top_clients || []}
+                  columns={[
+                    { title: "客户端", dataIndex: "client_ip" },
+                    { title: "次数", dataIndex: "count", width: 100 },
+                  ]}
+                />
+              </Col>
+            </Row>
+          </Card>
+        )}
+
+        {share?.kind === "logs" && (
+          <Card>
+            <Table
+              rowKey={(record, index) => `${record.timestamp}-${index}`}
+              columns={columns}
+              dataSource={data?.logs || []}
+              pagination={{
+                ...pagination,
+                showTotal: (total) => `共 ${total} 条`,
+              }}
+              onChange={(p) => loadData(p.current, p.pageSize)}
+              size="small"
+            />
+          </Card>
+        )}
+      </Spin>
+    </div>
+  );
+};
+
+export default SharedLogs;
@@ -8,6 +8,7 @@ import {
   Modal,
   Form,
   Input,
+  InputNumber,
   Select,
   Switch,
   message,
@@ -21,6 +22,8 @@ import {
   Tooltip,
   Badge,
   Dropdown,
+  DatePicker,
+  Radio,
 } from "antd";
 import {
   PlusOutlined,
@@ -49,8 +52,13 @@ import {
   getSchedulerStats,
   getTaskTemplates,
   createQuickTask,
+  getQuickTaskPresets,
 } from "../api/modules/scheduler";
+import { getNotificationChannels } from "../api/modules/notifications";
 import CronBuilder from "../components/CronBuilder/CronBuilder";
+import CronPreview from "../components/CronBuilder/CronPreview";
+import ScriptTemplateManager from "../components/Scheduler/ScriptTemplateManager";
+import dayjs from "dayjs";
 
 const { Title, Text } = Typography;
 const { Option } = Select;
@@ -67,7 +75,12 @@ const Tasks = () => {
   const [executions, setExecutions] = useState([]);
   const [stats, setStats] = useState({});
   const [templates, setTemplates] = useState([]);
+  const [presets, setPresets] = useState([]);
+  const [selectedPreset, setSelectedPreset] = useState(null);
+  const [channels, setChannels] = useState([]);
+  const [scriptTemplatesVisible, setScriptTemplatesVisible] = useState(false);
   const [form] = Form.useForm();
+  const [presetForm] = Form.useForm();
 
   // 任务类型图标映射
   const typeIcons = {
@@ -84,6 +97,7 @@ const Tasks = () => {
     success: "success",
     failed: "error",
     skipped: "warning",
+    timeout: "magenta",
   };
 
   // 获取配置示例
@@ -92,13 +106,17 @@ const Tasks = () => {
       telemetry: `{
   "targets": [],
   "result_retention": 30,
-  "alert_threshold": 3
+  "alert_threshold": 3,
+  "groups": [],
+  "group_alert_percent": 50
 }
 
 遥测任务配置说明：
 - targets: 遥测目标ID列表，空数组表示检测所有启用的目标
 - result_retention: 结果保留天数，超过此天数的结果将被清理
 - alert_threshold: 连续失败告警阈值
+- groups: 只检测指定分组的目标，空数组表示不限分组
+- group_alert_percent: 分组内失败目标占比达到该百分比时发送分组告警，默认 50
 
 注意：需要先在遥测管理页面创建目标，然后在这里配置任务`,
 
@@ -129,7 +147,13 @@ const Tasks = () => {
   "backup_configs": true,
   "backup_logs": false,
   "compression": true,
-  "retention_days": 90
+  "retention_days": 90,
+  "resource_guard": {
+    "max_cpu": 70,
+    "max_disk": 90,
+    "retry_minutes": 15,
+    "max_retries": 4
+  }
 }
 
 节点备份配置说明：
@@ -140,6 +164,7 @@ const Tasks = () => {
 - backup_logs: 是否备份日志文件
 - compression: 是否压缩备份文件
 - retention_days: 备份保留天数
+- resource_guard: 节点资源保护（可选），执行前检查节点CPU/磁盘使用率，超过 max_cpu / max_disk（%）的节点推迟 retry_minutes 分钟后只对这些节点重试，最多重试 max_retries 次；disk_path 指定检查的磁盘路径，默认 /
 
 本地存储示例：
 {
@@ -156,33 +181,178 @@ const Tasks = () => {
   "agent_log_days": 7,
   "backend_log_days": 30,
   "smartdns_log_days": 7,
-  "log_paths": []
+  "log_paths": [],
+  "node_ids": [],
+  "notification_log_days": 90,
+  "resource_guard": {
+    "max_cpu": 70,
+    "retry_minutes": 15
+  }
 }
 
 日志清理配置说明：
 - agent_log_days: Agent日志保留天数
 - backend_log_days: 后端日志保留天数
 - smartdns_log_days: SmartDNS日志保留天数
-- log_paths: 自定义日志路径列表`,
+- log_paths: 自定义日志路径列表
+- node_ids: 只清理这些节点的SmartDNS日志，空数组表示所有节点
+- notification_log_days: 通知日志保留天数，默认90天
+- resource_guard: 节点资源保护（可选），资源占用过高的节点稍后重试SmartDNS日志清理，字段同节点备份`,
+
+      log_retention: `{}
+
+DNS查询日志保留说明：
+- 任务没有参数，保留天数在「DNS 日志管理」页面按节点设置，未设置的节点使用 DNS_LOG_RETENTION_DAYS（默认90天）
+- ClickHouse 表 TTL 调整为所有节点中最长的保留天数，整个分区都过期时直接删除分区
+- 保留天数更短的节点通过 ALTER TABLE ... DELETE 删除过期日志
+- 日志清理任务的 smartdns_log_days 不再作用于单独设置了保留天数的节点`,
+
+      drift_check: `{
+  "node_ids": [],
+  "retention_days": 30
+}
+
+配置漂移检测说明：
+- node_ids: 检测的节点ID列表，空数组表示所有节点
+- retention_days: 漂移报告保留天数，默认30天`,
+
+      config_snapshot: `{
+  "node_ids": [],
+  "retention_days": 90
+}
+
+节点配置快照说明：
+- node_ids: 采集的节点ID列表，空数组表示所有节点
+- retention_days: 快照保留天数，默认90天，每个节点的最新快照始终保留
+- 只有配置内容变化时才保存新快照；管理端写入配置时也会记录快照，
+  定时采集到不同内容即标记为节点上的直接修改`,
+
+      compliance: `{
+  "node_ids": [],
+  "retention_days": 90
+}
+
+配置合规检查说明：
+- node_ids: 检查的节点ID列表，空数组表示所有节点
+- retention_days: 已解决的违规记录保留天数，默认90天
+- 策略在「配置合规」页面维护，没有启用的策略时不连接节点`,
+
+      patch_check: `{
+  "node_ids": [],
+  "security_threshold": 1,
+  "ignore_reboot": false
+}
+
+节点补丁检查说明：
+- node_ids: 检查的节点ID列表，空数组表示所有节点
+- security_threshold: 待安装安全更新达到该数量时告警，默认1
+- ignore_reboot: 为 true 时不因需要重启而告警
+- 运行的内核存在已知严重漏洞或有待安装的内核安全更新时始终告警`,
+
+      blocklist: `{
+  "subscription_ids": [],
+  "force": false
+}
+
+屏蔽列表订阅刷新说明：
+- subscription_ids: 刷新的订阅ID列表，空数组表示所有启用的订阅
+- force: 为 true 时忽略各订阅的刷新间隔和缓存，每次执行都重新下载
+- 订阅在「域名集」页面的「屏蔽列表订阅」中管理`,
+
+      traffic_anomaly: `{
+  "node_ids": [],
+  "method": "zscore",
+  "window_minutes": 15,
+  "baseline_hours": 24,
+  "seasonal_days": 7,
+  "z_threshold": 3,
+  "min_baseline_qps": 0.1,
+  "min_failure_change": 0.05
+}
+
+查询量异常检测说明：
+- node_ids: 检测的节点ID列表，空数组表示所有节点
+- method: zscore 以过去 baseline_hours 小时的滚动窗口为基线；seasonal 以过去 seasonal_days 天同一时段为基线（适合昼夜流量差异大的节点）
+- window_minutes: 统计窗口（分钟），seasonal 要求能整除 1440
+- z_threshold: 偏离基线的标准差倍数阈值
+- min_baseline_qps: 基线平均QPS低于该值的节点不检测
+- min_failure_change: 失败率至少上升的比例(0-1)才告警
+- 当前窗口查询量降为 0 时始终告警（进程正常但上游异常时常见）`,
+
+      answer_check: `{
+  "node_ids": [],
+  "monitors": [
+    {
+      "domain": "www.example.com",
+      "record_type": "A",
+      "expect": ["93.184.215.14", "2.16.0.0/13"],
+      "match": "all"
+    }
+  ],
+  "timeout": 5
+}
+
+DNS应答校验说明：
+- node_ids: 校验的节点ID列表，空数组表示所有节点
+- monitors: 校验项列表，直接向节点的 53 端口查询
+  - record_type: A 或 AAAA，默认 A
+  - expect: 期望的 IP 或 CIDR 网段
+  - match: all 要求所有应答都在期望范围内（默认）；any 只要求至少一个应答命中
+- 域名返回 NXDOMAIN 视为不匹配，超时等解析失败不告警（由节点健康检查负责）
+- 同一节点同一域名持续不匹配只通知一次，恢复后再通知`,
+
+      agent_probe: `{
+  "node_ids": [],
+  "domains": ["www.baidu.com", "www.qq.com"],
+  "interval_sec": 30,
+  "retention_days": 7
+}
+
+节点解析探测说明：
+- node_ids: 拉取的节点ID列表，空数组表示所有已安装 Agent 的节点
+- domains: 下发给 Agent 的探测域名，为空时使用 Agent 的 PROBE_DOMAINS 配置
+- interval_sec: 下发的探测间隔（秒），0 表示不修改
+- retention_days: 探测结果保留天数
+- Agent 按 5 分钟聚合探测次数、失败次数和延迟，本任务增量拉取后可在网络遥测页查看节点解析 SLA`,
+
+      fleet_report: `{
+  "month": "",
+  "check_resources": true,
+  "retention_count": 24
+}
+
+节点资产报告说明：
+- month: 统计月份(YYYY-MM)，为空时统计上个月
+- check_resources: 是否通过 SSH 采集节点当前的 CPU/内存/磁盘使用率
+- retention_count: 保留的报告数量，超出时删除最早的报告
+- 节点按云厂商、区域、成本中心分组（在节点编辑页填写），未填写的归入"未设置"
+- 查询量来自 ClickHouse 日志，报告可在节点页导出为 CSV`,
 
       custom_script: `{
   "node_ids": [],
+  "tag": "",
   "script": "#!/bin/bash\\necho 'Hello World'\\ndate\\necho 'Script completed'",
   "timeout": 300,
   "working_dir": "/tmp",
   "env_vars": {
     "PATH": "/usr/local/bin:/usr/bin:/bin"
   },
-  "run_as_user": "root"
+  "run_as_user": "",
+  "concurrency": 5
 }
 
-自定义脚本配置说明：
-- node_ids: 要执行脚本的节点ID列表，空数组表示所有节点
-- script: 要执行的Shell脚本内容
-- timeout: 脚本执行超时时间（秒），默认300秒
+自定义脚本配置说明（脚本通过节点的SSH凭据在远程节点上执行）：
+- node_ids: 要执行脚本的节点ID列表，与 tag 都为空时表示所有节点
+- tag: 按节点标签选择一组节点，与 node_ids 取并集
+- script: 要执行的Shell脚本内容，保留脚本首行的解释器声明
+- timeout: 单个节点的脚本执行超时时间（秒），默认300秒
 - working_dir: 脚本执行的工作目录
 - env_vars: 环境变量设置
-- run_as_user: 执行脚本的用户，默认为root
+- run_as_user: 执行脚本的用户，为空时使用节点的SSH用户，其他用户通过 sudo 切换
+- concurrency: 同时执行的节点数，默认5
+- template_id: 使用脚本模板时填写模板ID（见「脚本模板」），执行时按模板最新内容渲染并覆盖 script
+- params: 模板参数值，如 {"retention_days": 14}，未填写的使用模板默认值
+执行结果按节点记录退出码、stdout 和 stderr，任一节点失败时任务记为失败
 
 常用脚本示例：
 
@@ -218,6 +388,8 @@ SmartDNS重启：
     fetchTasks();
     fetchStats();
     fetchTemplates();
+    fetchPresets();
+    fetchChannels();
   }, []);
 
   const fetchTasks = async () => {
@@ -256,6 +428,60 @@ SmartDNS重启：
     }
   };
 
+  const fetchPresets = async () => {
+    try {
+      const response = await getQuickTaskPresets();
+      setPresets(Array.isArray(response.data) ? response.data : []);
+    } catch (error) {
+      console.error("获取快速任务模板失败:", error);
+      setPresets([]);
+    }
+  };
+
+  const fetchChannels = async () => {
+    try {
+      const response = await getNotificationChannels();
+      setChannels(Array.isArray(response.data) ? response.data : []);
+    } catch (error) {
+      console.error("获取通知渠道失败:", error);
+      setChannels([]);
+    }
+  };
+
+  const openPreset = (preset) => {
+    const initialValues = { name: preset.name, cron: preset.default_cron };
+    preset.params.forEach((param) => {
+      initialValues[param.key] = param.default;
+    });
+    presetForm.resetFields();
+    presetForm.setFieldsValue(initialValues);
+    setSelectedPreset(preset);
+  };
+
+  const handlePresetSubmit = async (values) => {
+    const { name, cron, ...params } = values;
+    try {
+      await createQuickTask({ preset: selectedPreset.id, name, cron, params });
+      message.success("快速创建成功");
+      setSelectedPreset(null);
+      fetchTasks();
+      fetchStats();
+    } catch (error) {
+      message.error(error.response?.data?.message || "快速创建失败");
+    }
+  };
+
+  const renderPresetParam = (param) => {
+    switch (param.type) {
+      case "int":
+        return <InputNumber min={0} style={{ width: "100%" }} />;
+      case "bool":
+        return <Switch />;
+      default:
+        return <Input placeholder={param.description} />;
+    }
+  };
+
   const fetchExecutions = async (taskId) => {
     try {
       const response = await getTaskExecutions(taskId, {
@@ -274,6 +500,7 @@ SmartDNS重启：
     setEditingTask(null);
     setModalVisible(true);
     form.resetFields();
+    form.setFieldsValue({ schedule_mode: "cron" });
   };
 
   const handleEditTask = (task) => {
@@ -296,9 +523,19 @@ SmartDNS重启：
       configValue = task.config || "{}";
     }
 
+    let notifyChannelIDs = [];
+    try {
+      notifyChannelIDs = JSON.parse(task.notify_channel_ids || "[]");
+    } catch (e) {
+      notifyChannelIDs = [];
+    }
+
     form.setFieldsValue({
       ...task,
       config: configValue,
+      schedule_mode: task.run_at ? "once" : "cron",
+      run_at: task.run_at ? dayjs(task.run_at) : null,
+      notify_channel_ids: notifyChannelIDs,
     });
   };
 
@@ -348,10 +585,22 @@ SmartDNS重启：
         }
       }
 
+      const { schedule_mode, ...rest } = values;
       const data = {
-        ...values,
+        ...rest,
         config: JSON.stringify(config),
+        timeout_seconds: values.timeout_seconds || 0,
+        notify_channel_ids: values.notify_channel_ids?.length
+          ? JSON.stringify(values.notify_channel_ids)
+          : "",
       };
+      // 一次性任务只保留执行时间，周期任务清空执行时间
+      if (schedule_mode === "once") {
+        data.cron_expr = "";
+        data.run_at = values.run_at ? values.run_at.toISOString() : null;
+      } else {
+        data.run_at = null;
+      }
 
       if (editingTask) {
         await updateTask(editingTask.id, data);
@@ -423,7 +672,16 @@ SmartDNS重启：
       title: "Cron表达式",
       dataIndex: "cron_expr",
       key: "cron_expr",
-      render: (text) => <code>{text}</code>,
+      render: (text, record) =>
+        record.run_at ? (
+          <Tooltip title="一次性任务，执行后自动停用">
+            <Tag icon={<ClockCircleOutlined />}>
+              {dayjs(record.run_at).format("YYYY-MM-DD HH:mm:ss")}
+            </Tag>
+          </Tooltip>
+        ) : (
+          <code>{text}</code>
+        ),
     },
     {
       title: "状态",
@@ -562,6 +820,21 @@ SmartDNS重启：
       key: "status",
       render: (status) => <Tag color={statusColors[status]}>{status}</Tag>,
     },
+    {
+      title: "触发方式",
+      dataIndex: "trigger",
+      key: "trigger",
+      render: (trigger) =>
+        trigger === "catch_up" ? (
+          <Tag color="orange">补执行</Tag>
+        ) : trigger === "resource_retry" ? (
+          <Tag color="gold">资源保护重试</Tag>
+        ) : trigger === "manual" ? (
+          <Tag color="blue">手动</Tag>
+        ) : (
+          <Tag>调度</Tag>
+        ),
+    },
     {
       title: "耗时",
       dataIndex: "duration",
@@ -647,19 +920,99 @@ SmartDNS重启：
             </Button>
           ))}
         </Space>
+        {presets.length > 0 && (
+          <>
+            <Title level={5} style={{ marginTop: 16 }}>
+              常用任务模板
+            </Title>
+            <Space wrap>
+              {presets.map((preset) => (
+                <Tooltip key={preset.id} title={preset.description}>
+                  <Button
+                    icon={
+                      typeIcons[preset.task_type] || <ThunderboltOutlined />
+                    }
+                    onClick={() => openPreset(preset)}
+                  >
+                    {preset.name}
+                  </Button>
+                </Tooltip>
+              ))}
+            </Space>
+          </>
+        )}
       </Card>
 
+      {/* 快速任务模板参数 */}
+      <Modal
+        title={selectedPreset?.name}
+        open={!!selectedPreset}
+        onCancel={() => setSelectedPreset(null)}
+        onOk={() => presetForm.submit()}
+        destroyOnClose
+      >
+        {selectedPreset && (
+          <Form form={presetForm} layout="vertical" onFinish={handlePresetSubmit}>
+            <Text type="secondary">{selectedPreset.description}</Text>
+            <Form.Item
+              name="name"
+              label="任务名称"
+              rules={[{ required: true, message: "请输入任务名称" }]}
+              style={{ marginTop: 16 }}
+            >
+              <Input />
+            </Form.Item>
+            <Form.Item
+              name="cron"
+              label="Cron表达式"
+              extra="六段式，包含秒，如 0 0 2 * * * 表示每天凌晨2点"
+              rules={[{ required: true, message: "请输入Cron表达式" }]}
+            >
+              <Input />
+            </Form.Item>
+            {selectedPreset.params.map((param) => (
+              <Form.Item
+                key={param.key}
+                name={param.key}
+                label={param.label}
+                extra={
+                  ["int", "bool"].includes(param.type)
+                    ? param.description
+                    : undefined
+                }
+                valuePropName={param.type === "bool" ? "checked" : "value"}
+                rules={
+                  param.required
+                    ? [{ required: true, message: `请输入${param.label}` }]
+                    : []
+                }
+              >
+                {renderPresetParam(param)}
+              </Form.Item>
+            ))}
+          </Form>
+        )}
+      </Modal>
+
       {/* 任务列表 */}
       <Card
         title="定时任务"
         extra={
-          <Button
-            type="primary"
-            icon={<PlusOutlined />}
-            onClick={handleCreateTask}
-          >
-            创建任务
-          </Button>
+          <Space>
+            <Button
+              icon={<CodeOutlined />}
+              onClick={() => setScriptTemplatesVisible(true)}
+            >
+              脚本模板
+            </Button>
+            <Button
+              type="primary"
+              icon={<PlusOutlined />}
+              onClick={handleCreateTask}
+            >
+              创建任务
+            </Button>
+          </Space>
         }
       >
         <Table
@@ -705,12 +1058,53 @@ SmartDNS重启：
             </Select>
           </Form.Item>
 
+          <Form.Item name="schedule_mode" label="执行方式" initialValue="cron">
+            <Radio.Group>
+              <Radio.Button value="cron">周期执行</Radio.Button>
+              <Radio.Button value="once">一次性执行</Radio.Button>
+            </Radio.Group>
+          </Form.Item>
+
+          <Form.Item
+            noStyle
+            shouldUpdate={(prev, cur) =>
+              prev.schedule_mode !== cur.schedule_mode ||
+              prev.cron_expr !== cur.cron_expr
+            }
+          >
+            {({ getFieldValue }) =>
+              getFieldValue("schedule_mode") === "once" ? (
+                <Form.Item
+                  name="run_at"
+                  label="执行时间"
+                  tooltip="到达执行时间后执行一次并自动停用；服务停机期间错过的会在启动后补执行"
+                  rules={[{ required: true, message: "请选择执行时间" }]}
+                >
+                  <DatePicker showTime style={{ width: "100%" }} />
+                </Form.Item>
+              ) : (
+                <>
+                  <Form.Item
+                    name="cron_expr"
+                    label="执行时间设置"
+                    rules={[{ required: true, message: "请设置执行时间" }]}
+                  >
+                    <CronBuilder />
+                  </Form.Item>
+                  <div style={{ marginTop: -16, marginBottom: 16 }}>
+                    <CronPreview expr={getFieldValue("cron_expr")} />
+                  </div>
+                </>
+              )
+            }
+          </Form.Item>
+
           <Form.Item
-            name="cron_expr"
-            label="执行时间设置"
-            rules={[{ required: true, message: "请设置执行时间" }]}
+            name="timeout_seconds"
+            label="最长执行时间（秒）"
+            tooltip="超过后取消任务并记为超时，0 或留空表示不限制"
           >
-            <CronBuilder />
+            <InputNumber min={0} style={{ width: "100%" }} placeholder="不限制" />
           </Form.Item>
 
           <Form.Item name="description" label="描述">
@@ -745,6 +1139,58 @@ SmartDNS重启：
           <Form.Item name="enabled" valuePropName="checked" label="启用">
             <Switch />
           </Form.Item>
+          <Form.Item
+            name="maintenance_only"
+            valuePropName="checked"
+            label="仅在维护窗口内执行"
+            tooltip="开启后，不在全局维护窗口内的调度会推迟到下一个窗口执行；手动执行不受限制"
+          >
+            <Switch />
+          </Form.Item>
+          <Form.Item
+            name="catch_up"
+            valuePropName="checked"
+            label="错过后补执行"
+            tooltip="服务停机期间错过了调度时间（如凌晨备份时容器正在重启），启动后补执行一次；一次性任务总是会补执行"
+          >
+            <Switch />
+          </Form.Item>
+          <Row gutter={16}>
+            <Col span={12}>
+              <Form.Item
+                name="notify_on_failure"
+                valuePropName="checked"
+                label="失败时通知"
+                tooltip="执行失败或超时时发送通知，包含错误信息、耗时和输出摘要"
+              >
+                <Switch />
+              </Form.Item>
+            </Col>
+            <Col span={12}>
+              <Form.Item
+                name="notify_on_success"
+                valuePropName="checked"
+                label="成功时通知"
+              >
+                <Switch />
+              </Form.Item>
+            </Col>
+          </Row>
+          <Form.Item
+            name="notify_channel_ids"
+            label="通知渠道"
+            tooltip="不选择时发送到订阅了「定时任务成功/失败」事件的全局渠道"
+          >
+            <Select
+              mode="multiple"
+              allowClear
+              placeholder="默认使用订阅了任务事件的全局渠道"
+              options={channels.map((channel) => ({
+                label: `${channel.name}（${channel.type}）`,
+                value: channel.id,
+              }))}
+            />
+          </Form.Item>
         </Form>
       </Modal>
 
@@ -767,8 +1213,19 @@ SmartDNS重启：
                   selectedTask.type}
               </Space>
             </Descriptions.Item>
-            <Descriptions.Item label="Cron表达式">
-              <code>{selectedTask.cron_expr}</code>
+            {selectedTask.run_at ? (
+              <Descriptions.Item label="执行时间">
+                {dayjs(selectedTask.run_at).format("YYYY-MM-DD HH:mm:ss")}（一次性）
+              </Descriptions.Item>
+            ) : (
+              <Descriptions.Item label="Cron表达式">
+                <code>{selectedTask.cron_expr}</code>
+              </Descriptions.Item>
+            )}
+            <Descriptions.Item label="最长执行时间">
+              {selectedTask.timeout_seconds
+                ? `${selectedTask.timeout_seconds} 秒`
+                : "不限制"}
             </Descriptions.Item>
             <Descriptions.Item label="描述">
               {selectedTask.description || "-"}
@@ -830,6 +1287,17 @@ SmartDNS重启：
         )}
       </Drawer>
 
+      {/* 脚本模板抽屉 */}
+      <Drawer
+        title="脚本模板"
+        width={900}
+        open={scriptTemplatesVisible}
+        onClose={() => setScriptTemplatesVisible(false)}
+        destroyOnClose
+      >
+        <ScriptTemplateManager />
+      </Drawer>
+
       {/* 执行历史抽屉 */}
       <Drawer
         title="执行历史"
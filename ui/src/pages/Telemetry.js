@@ -24,6 +24,8 @@ import {
   Divider,
   Alert,
   Tabs,
+  Upload,
+  AutoComplete,
 } from "antd";
 import {
   PlusOutlined,
@@ -37,6 +39,8 @@ import {
   CheckCircleOutlined,
   ExclamationCircleOutlined,
   ThunderboltOutlined,
+  ImportOutlined,
+  UploadOutlined,
 } from "@ant-design/icons";
 import {
   getTelemetryTargets,
@@ -46,7 +50,10 @@ import {
   getTelemetryResults,
   getTelemetryStats,
   testTelemetryTarget,
+  importTelemetryTargets,
+  getTelemetryGroups,
 } from "../api/modules/scheduler";
+import ProbeSLAPanel from "../components/Node/ProbeSLAPanel";
 
 const { Title, Text } = Typography;
 const { Option } = Select;
@@ -64,14 +71,20 @@ const Telemetry = () => {
   const [editingTarget, setEditingTarget] = useState(null);
   const [selectedTarget, setSelectedTarget] = useState(null);
   const [testingTargets, setTestingTargets] = useState(new Set());
+  const [groups, setGroups] = useState([]);
+  const [groupFilter, setGroupFilter] = useState(undefined);
+  const [importVisible, setImportVisible] = useState(false);
+  const [importLoading, setImportLoading] = useState(false);
+  const [importResult, setImportResult] = useState(null);
   const [form] = Form.useForm();
+  const [importForm] = Form.useForm();
 
   // 检测类型配置
   const typeOptions = [
     {
       value: "ping",
       label: "PING检测",
-      description: "基于网络连通性的检测，不使用端口",
+      description: "ICMP回显检测并统计丢包率，不使用端口",
     },
     {
       value: "http",
@@ -88,8 +101,54 @@ const Telemetry = () => {
       label: "TCP检测",
       description: "TCP端口连接检测，需要指定端口",
     },
+    {
+      value: "scenario",
+      label: "多步场景",
+      description: "解析域名 → 连接端口 → 请求HTTP，逐步断言",
+    },
+    {
+      value: "dns",
+      label: "DNS检测",
+      description: "向DNS服务器发送查询，校验响应码和应答",
+    },
   ];
 
+  // 多步场景示例
+  const scenarioExample = JSON.stringify(
+    {
+      steps: [
+        {
+          type: "resolve",
+          name: "节点解析",
+          hostname: "www.example.com",
+          node_id: 1,
+          record_type: "A",
+        },
+        { type: "connect", name: "建立连接", port: 443 },
+        {
+          type: "http",
+          name: "健康检查",
+          scheme: "https",
+          path: "/health",
+          expect_status: 200,
+          expect_body: "ok",
+        },
+      ],
+    },
+    null,
+    2
+  );
+
+  // 场景结果保存为步骤数组的 JSON
+  const parseStepResults = (response) => {
+    try {
+      const steps = JSON.parse(response || "");
+      return Array.isArray(steps) ? steps : null;
+    } catch (e) {
+      return null;
+    }
+  };
+
   // 预设配置模板
   const presetTemplates = [
     {
@@ -129,16 +188,40 @@ const Telemetry = () => {
     },
   ];
 
+  // 导入示例
+  const importExamples = {
+    csv: `name,type,target,group,timeout,description
+Google DNS,ping,8.8.8.8,公共DNS,5000,
+Cloudflare DNS,ping,1.1.1.1,公共DNS,5000,
+官网,https,example.com,业务-华东,10000,官网首页`,
+    yaml: `targets:
+  - name: Google DNS
+    type: ping
+    target: 8.8.8.8
+    group: 公共DNS
+  - name: 官网
+    type: https
+    target: example.com
+    group: 业务-华东
+    timeout: 10000`,
+  };
+
   useEffect(() => {
-    fetchTargets();
     fetchStats();
     fetchResults();
+    fetchGroups();
   }, []);
 
+  useEffect(() => {
+    fetchTargets();
+  }, [groupFilter]);
+
   const fetchTargets = async () => {
     setLoading(true);
     try {
-      const response = await getTelemetryTargets();
+      const response = await getTelemetryTargets(
+        groupFilter !== undefined ? { group: groupFilter } : undefined
+      );
       setTargets(response.data || []);
     } catch (error) {
       message.error("获取遥测目标失败");
@@ -158,6 +241,16 @@ const Telemetry = () => {
     }
   };
 
+  const fetchGroups = async () => {
+    try {
+      const response = await getTelemetryGroups();
+      setGroups(response.data || []);
+    } catch (error) {
+      console.error("获取遥测分组失败:", error);
+      setGroups([]);
+    }
+  };
+
   const fetchResults = async (targetId = null) => {
     try {
       const params = targetId
@@ -186,7 +279,19 @@ const Telemetry = () => {
   const handleEditTarget = (target) => {
     setEditingTarget(target);
     setModalVisible(true);
-    form.setFieldsValue(target);
+    let scenario = target.scenario;
+    try {
+      scenario = scenario ? JSON.stringify(JSON.parse(scenario), null, 2) : scenario;
+    } catch (e) {
+      // 保留原始内容
+    }
+    let dns;
+    try {
+      dns = target.dns_query ? JSON.parse(target.dns_query) : undefined;
+    } catch (e) {
+      dns = undefined;
+    }
+    form.setFieldsValue({ ...target, scenario, dns });
   };
 
   const handleDeleteTarget = async (id) => {
@@ -195,6 +300,7 @@ const Telemetry = () => {
       message.success("删除成功");
       fetchTargets();
       fetchStats();
+      fetchGroups();
     } catch (error) {
       message.error("删除失败");
     }
@@ -237,6 +343,20 @@ const Telemetry = () => {
   const handleSubmit = async (values) => {
     try {
       // 验证配置
+      if (values.type === "scenario") {
+        try {
+          values.scenario = JSON.stringify(JSON.parse(values.scenario));
+        } catch (e) {
+          message.error("场景定义不是有效的 JSON");
+          return;
+        }
+      }
+
+      if (values.type === "dns") {
+        values.dns_query = JSON.stringify(values.dns || {});
+      }
+      delete values.dns;
+
       if (values.type === "ping" && values.target.includes(":")) {
         message.warning("PING检测不需要端口号，已自动移除");
         values.target = values.target.split(":")[0];
@@ -268,8 +388,58 @@ const Telemetry = () => {
       setModalVisible(false);
       fetchTargets();
       fetchStats();
+      fetchGroups();
     } catch (error) {
-      message.error(editingTarget ? "更新失败" : "创建失败");
+      message.error(
+        (editingTarget ? "更新失败" : "创建失败") +
+          (error.response?.data?.message ? `: ${error.response.data.message}` : "")
+      );
+    }
+  };
+
+  const handleOpenImport = () => {
+    importForm.resetFields();
+    importForm.setFieldsValue({ format: "auto" });
+    setImportResult(null);
+    setImportVisible(true);
+  };
+
+  // 读取本地文件内容到文本框，根据扩展名设置格式
+  const handleImportFile = (file) => {
+    const reader = new FileReader();
+    reader.onload = (e) => {
+      const name = file.name.toLowerCase();
+      const format = name.endsWith(".csv")
+        ? "csv"
+        : name.endsWith(".yaml") || name.endsWith(".yml")
+        ? "yaml"
+        : "auto";
+      importForm.setFieldsValue({ content: e.target.result, format });
+    };
+    reader.readAsText(file);
+    return false;
+  };
+
+  const handleImport = async () => {
+    try {
+      const values = await importForm.validateFields();
+      setImportLoading(true);
+      const response = await importTelemetryTargets(values);
+      setImportResult(response.data);
+      message.success(response.message || "导入完成");
+      fetchTargets();
+      fetchStats();
+      fetchGroups();
+    } catch (error) {
+      if (error.errorFields) {
+        return;
+      }
+      setImportResult(error.response?.data?.data || null);
+      message.error(
+        "导入失败: " + (error.response?.data?.message || error.message)
+      );
+    } finally {
+      setImportLoading(false);
     }
   };
 
@@ -297,6 +467,8 @@ const Telemetry = () => {
       http: "#1890ff",
       https: "#722ed1",
       tcp: "#fa8c16",
+      scenario: "#13c2c2",
+      dns: "#eb2f96",
     };
     return colors[type] || "#666";
   };
@@ -313,6 +485,12 @@ const Telemetry = () => {
         </Space>
       ),
     },
+    {
+      title: "分组",
+      dataIndex: "group",
+      key: "group",
+      render: (group) => (group ? <Tag color="geekblue">{group}</Tag> : "-"),
+    },
     {
       title: "检测类型",
       dataIndex: "type",
@@ -422,6 +600,61 @@ const Telemetry = () => {
     },
   ];
 
+  const groupColumns = [
+    {
+      title: "分组",
+      dataIndex: "group",
+      key: "group",
+      render: (group) =>
+        group ? <Tag color="geekblue">{group}</Tag> : <Text type="secondary">未分组</Text>,
+    },
+    {
+      title: "目标数",
+      key: "targets",
+      render: (_, record) => `${record.enabled_targets} / ${record.total_targets}`,
+    },
+    {
+      title: "在线",
+      key: "online",
+      render: (_, record) => {
+        const allOnline = record.online_targets === record.enabled_targets;
+        return (
+          <Badge
+            status={allOnline ? "success" : record.online_targets === 0 ? "error" : "warning"}
+            text={`${record.online_targets} / ${record.enabled_targets}`}
+          />
+        );
+      },
+    },
+    {
+      title: "平均延迟",
+      dataIndex: "avg_latency",
+      key: "avg_latency",
+      render: (latency) => (latency ? `${latency.toFixed(1)}ms` : "-"),
+    },
+    {
+      title: "成功率",
+      dataIndex: "success_rate",
+      key: "success_rate",
+      render: (rate) => `${(rate || 0).toFixed(1)}%`,
+    },
+    {
+      title: "最后检测",
+      dataIndex: "last_check_at",
+      key: "last_check_at",
+      render: (time) => (time ? new Date(time).toLocaleString() : "-"),
+    },
+    {
+      title: "操作",
+      key: "actions",
+      render: (_, record) => (
+        <Button type="link" size="small" onClick={() => setGroupFilter(record.group)}>
+          查看目标
+        </Button>
+      ),
+    },
+  ];
+
   const resultColumns = [
     {
       title: "检测时间",
@@ -444,7 +677,33 @@ const Telemetry = () => {
       title: "延迟",
       dataIndex: "latency",
       key: "latency",
-      render: (latency) => (latency ? `${latency}ms` : "-"),
+      render: (latency, record) =>
+        latency ? (
+          record.connect_latency || record.resolve_latency ? (
+            <Tooltip
+              title={`连接 ${record.connect_latency}ms / 解析 ${record.resolve_latency}ms`}
+            >
+              {`${latency}ms`}
+            </Tooltip>
+          ) : (
+            `${latency}ms`
+          )
+        ) : (
+          "-"
+        ),
+    },
+    {
+      title: "丢包",
+      dataIndex: "packet_loss",
+      key: "packet_loss",
+      render: (loss, record) =>
+        record.response?.includes("丢包") ? (
+          <Tag color={loss === 0 ? "green" : loss < 100 ? "orange" : "red"}>
+            {`${loss.toFixed(0)}%`}
+          </Tag>
+        ) : (
+          "-"
+        ),
     },
     {
       title: "响应",
@@ -452,6 +711,24 @@ const Telemetry = () => {
       key: "response",
       ellipsis: true,
       width: 150,
+      render: (response) => {
+        const steps = parseStepResults(response);
+        if (!steps) {
+          return response;
+        }
+        return (
+          <Space direction="vertical" size={0}>
+            {steps.map((step, index) => (
+              <Tooltip key={index} title={step.error || step.detail}>
+                <Badge
+                  status={step.success ? "success" : "error"}
+                  text={`${step.name} ${step.latency}ms`}
+                />
+              </Tooltip>
+            ))}
+          </Space>
+        );
+      },
     },
     {
       title: "错误信息",
@@ -512,17 +789,51 @@ const Telemetry = () => {
         </Col>
       </Row>
 
+      {/* 分组统计 */}
+      {groups.length > 0 && (
+        <Card title="分组概览" style={{ marginBottom: 16 }}>
+          <Table
+            columns={groupColumns}
+            dataSource={groups}
+            rowKey={(record) => record.group || "__ungrouped__"}
+            pagination={false}
+            size="small"
+          />
+        </Card>
+      )}
+
+      {/* 节点解析 SLA */}
+      <ProbeSLAPanel />
+
       {/* 遥测目标列表 */}
       <Card
         title="遥测目标管理"
         extra={
-          <Button
-            type="primary"
-            icon={<PlusOutlined />}
-            onClick={handleCreateTarget}
-          >
-            添加目标
-          </Button>
+          <Space>
+            <Select
+              allowClear
+              placeholder="全部分组"
+              value={groupFilter}
+              onChange={setGroupFilter}
+              style={{ width: 160 }}
+            >
+              {groups.map((group) => (
+                <Option key={group.group} value={group.group}>
+                  {group.group || "未分组"}
+                </Option>
+              ))}
+            </Select>
+            <Button icon={<ImportOutlined />} onClick={handleOpenImport}>
+              批量导入
+            </Button>
+            <Button
+              type="primary"
+              icon={<PlusOutlined />}
+              onClick={handleCreateTarget}
+            >
+              添加目标
+            </Button>
+          </Space>
         }
       >
         <Table
@@ -548,7 +859,7 @@ const Telemetry = () => {
             description={
               <div>
                 <p>
-                  <strong>PING检测:</strong> 不需要端口号，只填写IP地址或域名
+                  <strong>PING检测:</strong> 不需要端口号，只填写IP地址或域名；无 ICMP 权限时回退到端口连通性检测
                 </p>
                 <p>
                   <strong>HTTP/HTTPS检测:</strong>{" "}
@@ -558,6 +869,10 @@ const Telemetry = () => {
                   <strong>TCP检测:</strong>{" "}
                   必须指定端口，格式：IP:端口或域名:端口
                 </p>
+                <p>
+                  <strong>DNS检测:</strong>{" "}
+                  目标地址填DNS服务器，分别记录连接和解析耗时
+                </p>
               </div>
             }
             type="info"
@@ -594,13 +909,107 @@ const Telemetry = () => {
                 </Select>
               </Form.Item>
 
-              <Form.Item
-                name="target"
-                label="目标地址"
-                rules={[{ required: true, message: "请输入目标地址" }]}
-                extra="根据检测类型填写相应格式的地址"
-              >
-                <Input placeholder="例如: 8.8.8.8 或 baidu.com 或 192.168.1.1:80" />
+              <Form.Item noStyle shouldUpdate={(prev, cur) => prev.type !== cur.type}>
+                {({ getFieldValue }) =>
+                  getFieldValue("type") === "scenario" ? (
+                    <>
+                      <Form.Item
+                        name="target"
+                        label="目标地址"
+                        extra="留空时使用场景中第一个解析步骤的域名"
+                      >
+                        <Input placeholder="www.example.com" />
+                      </Form.Item>
+                      <Form.Item
+                        name="scenario"
+                        label={
+                          <Space>
+                            <span>场景步骤 (JSON)</span>
+                            <Button
+                              type="link"
+                              size="small"
+                              onClick={() => form.setFieldsValue({ scenario: scenarioExample })}
+                            >
+                              填入示例
+                            </Button>
+                          </Space>
+                        }
+                        rules={[{ required: true, message: "请填写场景步骤" }]}
+                        extra="步骤类型: resolve（向 node_id 节点或 server 解析，可用 expect_ips 断言）、connect（连接解析到的地址和 port）、http（经解析到的地址请求 path，可断言 expect_status / expect_body）；任一步失败即终止"
+                      >
+                        <TextArea rows={12} style={{ fontFamily: "monospace" }} />
+                      </Form.Item>
+                    </>
+                  ) : getFieldValue("type") === "dns" ? (
+                    <>
+                      <Form.Item
+                        name="target"
+                        label="DNS服务器"
+                        rules={[{ required: true, message: "请输入DNS服务器地址" }]}
+                        extra="IP 或 IP:端口，默认端口 53（DoT 为 853）"
+                      >
+                        <Input placeholder="例如: 192.168.1.10 或 192.168.1.10:5353" />
+                      </Form.Item>
+                      <Form.Item
+                        name={["dns", "domain"]}
+                        label="查询域名"
+                        rules={[{ required: true, message: "请输入查询域名" }]}
+                      >
+                        <Input placeholder="www.example.com" />
+                      </Form.Item>
+                      <Row gutter={16}>
+                        <Col span={8}>
+                          <Form.Item name={["dns", "record_type"]} label="记录类型" initialValue="A">
+                            <Select
+                              options={["A", "AAAA", "CNAME", "MX", "TXT", "NS"].map((v) => ({ value: v, label: v }))}
+                            />
+                          </Form.Item>
+                        </Col>
+                        <Col span={8}>
+                          <Form.Item name={["dns", "protocol"]} label="协议" initialValue="udp">
+                            <Select
+                              options={[
+                                { value: "udp", label: "UDP" },
+                                { value: "tcp", label: "TCP" },
+                                { value: "dot", label: "DoT" },
+                              ]}
+                            />
+                          </Form.Item>
+                        </Col>
+                        <Col span={8}>
+                          <Form.Item name={["dns", "expect_rcode"]} label="期望响应码" initialValue="NOERROR">
+                            <Select
+                              options={["NOERROR", "NXDOMAIN", "SERVFAIL", "REFUSED"].map((v) => ({ value: v, label: v }))}
+                            />
+                          </Form.Item>
+                        </Col>
+                      </Row>
+                      <Form.Item
+                        name={["dns", "expect_answers"]}
+                        label="期望应答"
+                        extra="A/AAAA 填地址或网段，其他类型填应答需包含的内容，命中其一即可；留空只要求有应答"
+                      >
+                        <Select mode="tags" placeholder="例如: 10.0.0.0/8" />
+                      </Form.Item>
+                      <Form.Item
+                        name={["dns", "allow_empty"]}
+                        label="允许空应答"
+                        valuePropName="checked"
+                      >
+                        <Switch />
+                      </Form.Item>
+                    </>
+                  ) : (
+                    <Form.Item
+                      name="target"
+                      label="目标地址"
+                      rules={[{ required: true, message: "请输入目标地址" }]}
+                      extra="根据检测类型填写相应格式的地址"
+                    >
+                      <Input placeholder="例如: 8.8.8.8 或 baidu.com 或 192.168.1.1:80" />
+                    </Form.Item>
+                  )
+                }
               </Form.Item>
 
               <Form.Item
@@ -618,6 +1027,20 @@ const Telemetry = () => {
                 />
               </Form.Item>
 
+              <Form.Item
+                name="group"
+                label="分组"
+                extra="按服务或区域分组，分组内多数目标失败时会发送分组告警"
+              >
+                <AutoComplete
+                  allowClear
+                  placeholder="例如: 业务-华东"
+                  options={groups
+                    .filter((group) => group.group)
+                    .map((group) => ({ value: group.group }))}
+                />
+              </Form.Item>
+
               <Form.Item name="description" label="描述">
                 <TextArea rows={2} placeholder="目标描述" />
               </Form.Item>
@@ -671,6 +1094,103 @@ const Telemetry = () => {
         </Form>
       </Modal>
 
+      {/* 批量导入对话框 */}
+      <Modal
+        title="批量导入遥测目标"
+        open={importVisible}
+        onCancel={() => setImportVisible(false)}
+        onOk={handleImport}
+        okText="导入"
+        cancelText="关闭"
+        confirmLoading={importLoading}
+        width={800}
+      >
+        <Alert
+          type="info"
+          showIcon
+          style={{ marginBottom: 16 }}
+          message="支持 CSV 和 YAML。CSV 首行可为表头（name,type,target,group,timeout,description,enabled），否则按该顺序读取；未填写类型时按地址推断，名称默认为地址。已存在相同类型和地址的目标会被跳过。"
+        />
+        <Form form={importForm} layout="vertical">
+          <Row gutter={16}>
+            <Col span={8}>
+              <Form.Item name="format" label="格式">
+                <Select>
+                  <Option value="auto">自动识别</Option>
+                  <Option value="csv">CSV</Option>
+                  <Option value="yaml">YAML</Option>
+                </Select>
+              </Form.Item>
+            </Col>
+            <Col span={10}>
+              <Form.Item
+                name="group"
+                label="默认分组"
+                extra="未指定分组的目标归入该分组"
+              >
+                <Input placeholder="可选" />
+              </Form.Item>
+            </Col>
+            <Col span={6}>
+              <Form.Item label="从文件读取">
+                <Upload
+                  accept=".csv,.yaml,.yml,.txt"
+                  showUploadList={false}
+                  beforeUpload={handleImportFile}
+                >
+                  <Button icon={<UploadOutlined />}>选择文件</Button>
+                </Upload>
+              </Form.Item>
+            </Col>
+          </Row>
+          <Form.Item
+            name="content"
+            label={
+              <Space>
+                <span>内容</span>
+                <Button
+                  type="link"
+                  size="small"
+                  onClick={() =>
+                    importForm.setFieldsValue({ content: importExamples.csv, format: "csv" })
+                  }
+                >
+                  CSV 示例
+                </Button>
+                <Button
+                  type="link"
+                  size="small"
+                  onClick={() =>
+                    importForm.setFieldsValue({ content: importExamples.yaml, format: "yaml" })
+                  }
+                >
+                  YAML 示例
+                </Button>
+              </Space>
+            }
+            rules={[{ required: true, message: "请粘贴或选择要导入的内容" }]}
+          >
+            <TextArea rows={10} style={{ fontFamily: "monospace" }} />
+          </Form.Item>
+        </Form>
+        {importResult && (
+          <Alert
+            type={importResult.errors?.length ? "warning" : "success"}
+            showIcon
+            message={`新增 ${importResult.created || 0} 个，跳过 ${importResult.skipped || 0} 个`}
+            description={
+              importResult.errors?.length > 0 && (
+                <ul style={{ margin: 0, paddingLeft: 20 }}>
+                  {importResult.errors.map((error, index) => (
+                    <li key={index}>{error}</li>
+                  ))}
+                </ul>
+              )
+            }
+          />
+        )}
+      </Modal>
+
       {/* 目标详情抽屉 */}
       <Drawer
         title="遥测目标详情"
@@ -691,6 +1211,9 @@ const Telemetry = () => {
             <Descriptions.Item label="目标地址">
               <code>{selectedTarget.target}</code>
             </Descriptions.Item>
+            <Descriptions.Item label="分组">
+              {selectedTarget.group || "-"}
+            </Descriptions.Item>
             <Descriptions.Item label="超时设置">
               {selectedTarget.timeout}ms
             </Descriptions.Item>
@@ -1,8 +1,8 @@
 import axios from 'axios';
-import { message } from 'antd';
+import { message, Modal } from 'antd';
 
 const request = axios.create({
-  baseURL: process.env.REACT_APP_API_BASE_URL || '/api',
+  baseURL: process.env.REACT_APP_API_BASE_URL || '/api/v1',
   timeout: 300000,
 });
 
@@ -20,10 +20,37 @@ request.interceptors.request.use(
   }
 );
 
+// 破坏性操作确认：先以 dry_run 预估影响范围，确认后携带令牌重新提交
+const confirmDestructive = (config) =>
+  request({ ...config, params: { ...config.params, dry_run: true } }).then(
+    (res) =>
+      new Promise((resolve, reject) => {
+        Modal.confirm({
+          title: '确认执行该操作？',
+          content: res.data.impact.summary,
+          okText: '确认执行',
+          okType: 'danger',
+          cancelText: '取消',
+          onOk: () =>
+            request({
+              ...config,
+              confirmed: true,
+              headers: { ...config.headers, 'X-Confirm-Token': res.data.confirm_token },
+            }).then(resolve, reject),
+          onCancel: () => reject(new Error('操作已取消')),
+        });
+      })
+  );
+
 // 响应拦截器
 request.interceptors.response.use(
   (response) => {
     const res = response.data;
+
+    // 文件下载直接返回内容
+    if (response.config.responseType === 'blob') {
+      return res;
+    }
     
     if (!res.success) {
       message.error(res.message || '请求失败');
@@ -37,6 +64,10 @@ request.interceptors.response.use(
     
     if (error.response) {
       const { status, data } = error.response;
+
+      if (status === 428 && data?.confirm_required && !error.config.confirmed) {
+        return confirmDestructive(error.config);
+      }
       
       if (status === 401) {
         message.error('认证失败，请重新登录');
@@ -0,0 +1,69 @@
+// 订阅后端的 SSE 接口。浏览器的 EventSource 不能携带 Authorization 头，
+// 这里用 fetch 读取响应流并按 SSE 格式解析事件
+const baseURL = process.env.REACT_APP_API_BASE_URL || '/api/v1';
+
+const parseEvent = (block) => {
+  let event = 'message';
+  const data = [];
+  block.split('\n').forEach((line) => {
+    if (line.startsWith('event:')) {
+      event = line.slice(6).trim();
+    } else if (line.startsWith('data:')) {
+      data.push(line.slice(5).replace(/^ /, ''));
+    }
+  });
+  if (data.length === 0) return null;
+
+  const raw = data.join('\n');
+  try {
+    return { event, data: JSON.parse(raw) };
+  } catch (e) {
+    return { event, data: raw };
+  }
+};
+
+/**
+ * 订阅 SSE 事件，返回取消订阅的函数
+ * @param {string} path 接口路径，如 /nodes/1/init/stream
+ * @param {object} handlers onEvent(event, data)、onClose()（服务端结束响应）、onError(error)
+ */
+export const subscribeSSE = (path, { onEvent, onClose, onError } = {}) => {
+  const controller = new AbortController();
+  const token = localStorage.getItem('token');
+
+  const run = async () => {
+    const response = await fetch(`${baseURL}${path}`, {
+      headers: {
+        Accept: 'text/event-stream',
+        ...(token ? { Authorization: `Bearer ${token}` } : {}),
+      },
+      signal: controller.signal,
+    });
+    if (!response.ok) {
+      throw new Error(`HTTP ${response.status}`);
+    }
+
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) break;
+      buffer += decoder.decode(value, { stream: true }).replace(/\r\n/g, '\n');
+
+      let index;
+      while ((index = buffer.indexOf('\n\n')) !== -1) {
+        const parsed = parseEvent(buffer.slice(0, index));
+        buffer = buffer.slice(index + 2);
+        if (parsed && onEvent) onEvent(parsed.event, parsed.data);
+      }
+    }
+    if (onClose) onClose();
+  };
+
+  run().catch((error) => {
+    if (error.name !== 'AbortError' && onError) onError(error);
+  });
+
+  return () => controller.abort();
+};